@@ -0,0 +1,182 @@
+package aster
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/astercloud/aster/pkg/agent"
+	"github.com/astercloud/aster/pkg/config"
+	"github.com/astercloud/aster/pkg/provider"
+	"github.com/astercloud/aster/pkg/sandbox"
+	"github.com/astercloud/aster/pkg/store"
+	"github.com/astercloud/aster/pkg/tools"
+	"github.com/astercloud/aster/pkg/tools/builtin"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+// DefaultSystemPrompt is the system prompt used when Options.SystemPrompt is empty
+const DefaultSystemPrompt = "You are a helpful assistant. Use the available tools to read, search and change files in the working directory when the task needs it."
+
+// DefaultTools are the builtin tools enabled when Options.Tools is nil
+var DefaultTools = []string{"Read", "Write", "Edit", "Glob", "Grep", "Bash", "TodoWrite", "WebFetch"}
+
+// quickstartTemplateID is the template New registers for its agent
+const quickstartTemplateID = "aster-quickstart"
+
+// Options configures New. The zero value is ready to use: the model and API
+// key come from the unified config file (aster.yaml), environment variables
+// and the secret store, data is kept in a JSON store under config.DataDir(),
+// and the builtin tools run in a local sandbox rooted at the current directory.
+type Options struct {
+	// Provider and Model select the model, e.g. "anthropic" and
+	// "claude-sonnet-4-5". Empty values fall back to the default model in
+	// aster.yaml.
+	Provider string
+	Model    string
+	// APIKey overrides the key resolved for Provider from the environment,
+	// aster.yaml and the secret store.
+	APIKey string
+
+	// SystemPrompt defaults to DefaultSystemPrompt.
+	SystemPrompt string
+	// Tools lists the builtin tools to enable; nil enables DefaultTools and an
+	// empty slice enables none.
+	Tools []string
+
+	// WorkDir is the sandbox working directory, "." when empty.
+	WorkDir string
+	// DataDir holds the JSON store, config.DataDir() when empty. Ignored when
+	// Store is set.
+	DataDir string
+	// Store overrides the JSON store, e.g. with a SQL or Redis store.
+	Store store.Store
+
+	// AgentID reuses an agent ID; its stored conversation is loaded so the
+	// chat continues where it left off.
+	AgentID string
+
+	// ProviderFactory overrides the default multi-provider factory, e.g. to
+	// add provider middleware.
+	ProviderFactory provider.Factory
+
+	// AgentOptions are passed to agent.Create, e.g. agent.WithTools or
+	// agent.WithEventSink.
+	AgentOptions []agent.CreateOption
+}
+
+// New creates a ready-to-use agent with sensible defaults:
+//
+//	ag, err := aster.New(ctx, aster.Options{})
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	defer ag.Close()
+//	result, err := ag.Chat(ctx, "Summarize README.md")
+//
+// Use agent.Create directly when you need full control over the dependencies.
+func New(ctx context.Context, opts Options) (*agent.Agent, error) {
+	modelConfig, err := resolveModel(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	st := opts.Store
+	if st == nil {
+		dataDir := opts.DataDir
+		if dataDir == "" {
+			dataDir = config.DataDir()
+		}
+		storeDir := filepath.Join(dataDir, "store")
+		if err := os.MkdirAll(storeDir, 0755); err != nil {
+			return nil, fmt.Errorf("create store directory: %w", err)
+		}
+		if st, err = store.NewJSONStore(storeDir); err != nil {
+			return nil, fmt.Errorf("create store: %w", err)
+		}
+	}
+
+	workDir := opts.WorkDir
+	if workDir == "" {
+		workDir = "."
+	}
+	if workDir, err = filepath.Abs(workDir); err != nil {
+		return nil, fmt.Errorf("resolve working directory: %w", err)
+	}
+
+	toolNames := opts.Tools
+	if toolNames == nil {
+		toolNames = DefaultTools
+	}
+	systemPrompt := opts.SystemPrompt
+	if systemPrompt == "" {
+		systemPrompt = DefaultSystemPrompt
+	}
+
+	toolRegistry := tools.NewRegistry()
+	builtin.RegisterAll(toolRegistry)
+	for _, name := range toolNames {
+		if !toolRegistry.Has(name) {
+			return nil, fmt.Errorf("unknown builtin tool: %s", name)
+		}
+	}
+
+	templates := agent.NewTemplateRegistry()
+	templates.Register(&types.AgentTemplateDefinition{
+		ID:           quickstartTemplateID,
+		SystemPrompt: systemPrompt,
+		Tools:        toolNames,
+	})
+
+	providerFactory := opts.ProviderFactory
+	if providerFactory == nil {
+		providerFactory = provider.NewMultiProviderFactory()
+	}
+
+	deps := &agent.Dependencies{
+		Store:            st,
+		SandboxFactory:   sandbox.NewFactory(),
+		ToolRegistry:     toolRegistry,
+		ProviderFactory:  providerFactory,
+		TemplateRegistry: templates,
+	}
+	agentConfig := &types.AgentConfig{
+		AgentID:     opts.AgentID,
+		TemplateID:  quickstartTemplateID,
+		ModelConfig: modelConfig,
+		Sandbox: &types.SandboxConfig{
+			Kind:    types.SandboxKindLocal,
+			WorkDir: workDir,
+		},
+	}
+	return agent.Create(ctx, agentConfig, deps, opts.AgentOptions...)
+}
+
+// resolveModel fills the model config from opts, falling back to aster.yaml,
+// the environment and the secret store
+func resolveModel(opts Options) (*types.ModelConfig, error) {
+	settings, err := config.LoadSettings(config.SettingsFile())
+	if err != nil {
+		return nil, fmt.Errorf("load settings: %w", err)
+	}
+	settings.UseSecretStore(config.DefaultSecretStore())
+
+	cfg := &types.ModelConfig{Provider: opts.Provider, Model: opts.Model, APIKey: opts.APIKey}
+	if cfg.Provider == "" {
+		aliases := settings.AliasConfig()
+		def, ok := aliases.Aliases[aliases.Default]
+		if !ok {
+			return nil, errors.New("no default model configured, set Options.Provider and Options.Model or run 'aster setup'")
+		}
+		cfg.Provider = def.Provider
+		if cfg.Model == "" {
+			cfg.Model = def.Model
+		}
+	}
+	if cfg.APIKey == "" {
+		cfg.APIKey = settings.APIKey(cfg.Provider)
+	}
+	return cfg, nil
+}
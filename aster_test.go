@@ -0,0 +1,38 @@
+package aster
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestNewRejectsUnknownTool(t *testing.T) {
+	_, err := New(context.Background(), Options{
+		Provider: "anthropic",
+		Model:    "claude-sonnet-4-5",
+		APIKey:   "test-key",
+		Tools:    []string{"NoSuchTool"},
+		DataDir:  t.TempDir(),
+		WorkDir:  t.TempDir(),
+	})
+	if err == nil || !strings.Contains(err.Error(), "NoSuchTool") {
+		t.Fatalf("expected unknown tool error, got %v", err)
+	}
+}
+
+func TestNewWithDefaults(t *testing.T) {
+	ag, err := New(context.Background(), Options{
+		Provider: "anthropic",
+		Model:    "claude-sonnet-4-5",
+		APIKey:   "test-key",
+		DataDir:  t.TempDir(),
+		WorkDir:  t.TempDir(),
+	})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	defer ag.Close()
+	if ag.ID() == "" {
+		t.Error("expected agent ID to be generated")
+	}
+}
@@ -6,11 +6,12 @@ import (
 	"log"
 	"os"
 	"os/signal"
-	"strings"
 	"syscall"
 	"time"
 
 	"github.com/astercloud/aster/pkg/agent"
+	"github.com/astercloud/aster/pkg/config"
+	"github.com/astercloud/aster/pkg/httpclient"
 	"github.com/astercloud/aster/pkg/provider"
 	"github.com/astercloud/aster/pkg/router"
 	"github.com/astercloud/aster/pkg/sandbox"
@@ -76,20 +77,20 @@ func resolveProviderConfig(providerName, modelName string) (provider, model, api
 	}
 
 	// 获取 provider 配置
-	config, ok := providerDefaults[providerName]
+	defaults, ok := providerDefaults[providerName]
 	if !ok {
 		// 未知 provider，使用通用方式获取 API Key
-		return providerName, modelName, os.Getenv(strings.ToUpper(providerName) + "_API_KEY")
+		return providerName, modelName, config.ProviderAPIKey(providerName)
 	}
 
 	// 使用默认模型（如果未指定）
 	if modelName == "" {
-		modelName = config.DefaultModel
+		modelName = defaults.DefaultModel
 	}
 
 	// 尝试获取 API Key
-	for _, envName := range config.APIKeyEnvs {
-		if key := os.Getenv(envName); key != "" {
+	for _, envName := range defaults.APIKeyEnvs {
+		if key := config.GetEnv(envName, ""); key != "" {
 			return providerName, modelName, key
 		}
 	}
@@ -105,19 +106,34 @@ func maskAPIKey(key string) string {
 	return key[:4] + "..." + key[len(key)-4:]
 }
 
+// configureHTTP 应用出站 HTTP 配置（代理、自定义 CA、TLS 版本、连接池）
+// 读取 aster.yaml 的 http 段和 ASTER_HTTP_* 环境变量；HTTPS_PROXY 等标准变量始终生效
+func configureHTTP() {
+	settings, err := config.LoadSettings(config.SettingsFile())
+	if err != nil {
+		log.Printf("[WARN] load settings for http client: %v", err)
+		return
+	}
+	if err := httpclient.Configure(&settings.HTTP); err != nil {
+		log.Fatalf("Failed to configure http client: %v", err)
+	}
+}
+
 func main() {
 	fmt.Println("🚀 aster 星尘云枢 Production Server")
 	fmt.Println("================================")
 
+	configureHTTP()
+
 	// Initialize store
 	// 支持通过环境变量配置存储类型: json (默认), mysql, redis
 	var st store.Store
 	var err error
 
-	storeType := os.Getenv("ASTER_STORE_TYPE")
+	storeType := config.GetEnv("ASTER_STORE_TYPE", "json")
 	switch storeType {
 	case "mysql":
-		mysqlDSN := os.Getenv("ASTER_MYSQL_DSN")
+		mysqlDSN := config.GetEnv("ASTER_MYSQL_DSN", "")
 		if mysqlDSN == "" {
 			log.Fatalf("ASTER_MYSQL_DSN is required when ASTER_STORE_TYPE=mysql")
 		}
@@ -127,19 +143,10 @@ func main() {
 		})
 
 	case "redis":
-		redisAddr := os.Getenv("ASTER_REDIS_ADDR")
-		if redisAddr == "" {
-			redisAddr = "localhost:6379"
-		}
-		redisPassword := os.Getenv("ASTER_REDIS_PASSWORD")
-		redisDB := 0
-		if dbStr := os.Getenv("ASTER_REDIS_DB"); dbStr != "" {
-			_, _ = fmt.Sscanf(dbStr, "%d", &redisDB)
-		}
-		redisPrefix := os.Getenv("ASTER_REDIS_PREFIX")
-		if redisPrefix == "" {
-			redisPrefix = "aster:"
-		}
+		redisAddr := config.GetEnv("ASTER_REDIS_ADDR", "localhost:6379")
+		redisPassword := config.GetEnv("ASTER_REDIS_PASSWORD", "")
+		redisDB := config.GetEnvInt("ASTER_REDIS_DB", 0)
+		redisPrefix := config.GetEnv("ASTER_REDIS_PREFIX", "aster:")
 		fmt.Printf("📁 Using Redis store: %s\n", redisAddr)
 		st, err = store.NewRedisStore(store.RedisConfig{
 			Addr:     redisAddr,
@@ -150,10 +157,7 @@ func main() {
 
 	default:
 		// 默认使用 JSON Store
-		dataDir := os.Getenv("ASTER_DATA_DIR")
-		if dataDir == "" {
-			dataDir = ".data"
-		}
+		dataDir := config.GetEnv("ASTER_DATA_DIR", ".data")
 		fmt.Printf("📁 Using JSON store: %s\n", dataDir)
 		st, err = store.NewJSONStore(dataDir)
 	}
@@ -176,11 +180,8 @@ func main() {
 	registerDefaultTemplates(templateRegistry)
 
 	// Initialize router with environment-based configuration
-	providerEnv := os.Getenv("PROVIDER")
-	if providerEnv == "" {
-		providerEnv = "anthropic"
-	}
-	modelEnv := os.Getenv("MODEL")
+	providerEnv := config.GetEnv("PROVIDER", "anthropic")
+	modelEnv := config.GetEnv("MODEL", "")
 
 	// 解析 provider 配置
 	resolvedProvider, resolvedModel, apiKey := resolveProviderConfig(providerEnv, modelEnv)
@@ -234,21 +235,23 @@ func main() {
 	}
 
 	// Load configuration (use default for now)
-	config := server.DefaultConfig()
+	serverConfig := server.DefaultConfig()
 
 	// Override with environment variables if needed
-	if port := os.Getenv("PORT"); port != "" {
-		_, _ = fmt.Sscanf(port, "%d", &config.Port)
+	serverConfig.Port = config.GetEnvInt("PORT", serverConfig.Port)
+	serverConfig.Host = config.GetEnv("HOST", serverConfig.Host)
+	if apiKey := config.GetEnv("API_KEY", ""); apiKey != "" {
+		serverConfig.Auth.APIKey.Keys = []string{apiKey}
 	}
-	if host := os.Getenv("HOST"); host != "" {
-		config.Host = host
+	if dir := config.GetEnv("TEMPLATES_DIR", ""); dir != "" {
+		serverConfig.Templates = server.TemplatesConfig{Dir: dir, Watch: true}
 	}
-	if apiKey := os.Getenv("API_KEY"); apiKey != "" {
-		config.Auth.APIKey.Keys = []string{apiKey}
+	if dir := config.GetEnv("UPDATES_DIR", ""); dir != "" {
+		serverConfig.Updates = server.UpdatesConfig{Dir: dir}
 	}
 
 	// Create server
-	srv, err := server.New(config, deps)
+	srv, err := server.New(serverConfig, deps)
 	if err != nil {
 		log.Fatalf("Failed to create server: %v", err)
 	}
@@ -281,11 +284,8 @@ func main() {
 // registerDefaultTemplates registers builtin agent templates
 func registerDefaultTemplates(registry *agent.TemplateRegistry) {
 	// Get provider and model from environment using the shared resolver
-	providerEnv := os.Getenv("PROVIDER")
-	if providerEnv == "" {
-		providerEnv = "anthropic"
-	}
-	modelEnv := os.Getenv("MODEL")
+	providerEnv := config.GetEnv("PROVIDER", "anthropic")
+	modelEnv := config.GetEnv("MODEL", "")
 
 	_, model, _ := resolveProviderConfig(providerEnv, modelEnv)
 
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/astercloud/aster/pkg/config"
+	"github.com/astercloud/aster/pkg/httpclient"
+	"gopkg.in/yaml.v3"
+)
+
+// runConfig 管理统一配置 (aster.yaml)
+func runConfig(args []string) error {
+	fs := flag.NewFlagSet("config", flag.ExitOnError)
+	file := fs.String("file", config.SettingsFile(), "Settings file path")
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: aster config [flags] <show|get|set|path> [key] [value]\n\n")
+		fmt.Fprintf(os.Stderr, "Manage layered configuration (defaults < aster.yaml < ASTER_* env < flags).\n\n")
+		fmt.Fprintf(os.Stderr, "Subcommands:\n")
+		fmt.Fprintf(os.Stderr, "  show             Print effective settings (API keys redacted)\n")
+		fmt.Fprintf(os.Stderr, "  get <key>        Print a single effective setting\n")
+		fmt.Fprintf(os.Stderr, "  set <key> <val>  Validate and persist a setting to the settings file\n")
+		fmt.Fprintf(os.Stderr, "  path             Print the settings file path\n")
+		fmt.Fprintf(os.Stderr, "\nKeys:\n")
+		for _, key := range config.SettingKeys() {
+			fmt.Fprintf(os.Stderr, "  %-18s env %s\n", key, config.SettingEnvName(key))
+		}
+		fmt.Fprintf(os.Stderr, "  %-18s env <PROVIDER>_API_KEY\n", "api_keys.<provider>")
+		fmt.Fprintf(os.Stderr, "  %-18s provider/model for a logical alias (fast, smart, cheap)\n", "models.<alias>")
+		fmt.Fprintf(os.Stderr, "  %-18s alias override per task (plan, execute, summarize)\n", "tasks.<task>")
+		fmt.Fprintf(os.Stderr, "\nFlags:\n")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	rest := fs.Args()
+	if len(rest) == 0 {
+		fs.Usage()
+		return errors.New("missing subcommand")
+	}
+
+	switch rest[0] {
+	case "show":
+		settings, err := config.LoadSettings(*file)
+		if err != nil {
+			return err
+		}
+		data, err := yaml.Marshal(settings.Redacted())
+		if err != nil {
+			return fmt.Errorf("marshal settings: %w", err)
+		}
+		fmt.Printf("# %s\n%s", *file, data)
+		return nil
+
+	case "get":
+		if len(rest) != 2 {
+			return errors.New("usage: aster config get <key>")
+		}
+		settings, err := config.LoadSettings(*file)
+		if err != nil {
+			return err
+		}
+		value, err := settings.Get(rest[1])
+		if err != nil {
+			return err
+		}
+		fmt.Println(value)
+		return nil
+
+	case "set":
+		if len(rest) != 3 {
+			return errors.New("usage: aster config set <key> <value>")
+		}
+		// 仅基于文件内容修改，避免把环境变量写入文件
+		settings, err := config.LoadSettingsFile(*file)
+		if err != nil {
+			return err
+		}
+		if err := settings.Set(rest[1], rest[2]); err != nil {
+			return err
+		}
+		if err := settings.Save(*file); err != nil {
+			return err
+		}
+		fmt.Printf("%s updated in %s\n", rest[1], *file)
+		return nil
+
+	case "path":
+		fmt.Println(*file)
+		return nil
+
+	default:
+		fs.Usage()
+		return fmt.Errorf("unknown config subcommand: %s", rest[0])
+	}
+}
+
+// loadCLISettings 加载默认值、aster.yaml 和环境变量，API Key 还会从密钥管理中读取
+func loadCLISettings() (*config.Settings, error) {
+	settings, err := config.LoadSettings(config.SettingsFile())
+	if err != nil {
+		return nil, fmt.Errorf("load settings: %w", err)
+	}
+	settings.UseSecretStore(config.DefaultSecretStore())
+	// 出站 HTTP 配置对本进程内所有 Provider、WebFetch、MCP 和 Webhook 生效
+	if err := httpclient.Configure(&settings.HTTP); err != nil {
+		return nil, fmt.Errorf("configure http client: %w", err)
+	}
+	return settings, nil
+}
+
+// applyFlagOverrides 将显式传入的命令行参数覆盖到配置
+// flagKeys 映射 flag 名称到配置键
+func applyFlagOverrides(fs *flag.FlagSet, settings *config.Settings, flagKeys map[string]string) error {
+	var err error
+	fs.Visit(func(f *flag.Flag) {
+		key, ok := flagKeys[f.Name]
+		if !ok || err != nil {
+			return
+		}
+		if setErr := settings.SetFrom(key, f.Value.String(), config.SettingSourceFlag); setErr != nil {
+			err = fmt.Errorf("-%s: %w", f.Name, setErr)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	return settings.Validate()
+}
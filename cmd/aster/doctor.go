@@ -0,0 +1,233 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/astercloud/aster/pkg/config"
+	"github.com/astercloud/aster/pkg/httpclient"
+	"github.com/astercloud/aster/pkg/provider"
+	"github.com/astercloud/aster/pkg/sandbox"
+)
+
+// doctorStatus 检查结果等级
+type doctorStatus int
+
+const (
+	doctorOK doctorStatus = iota
+	doctorWarn
+	doctorFail
+)
+
+// doctorReport 收集并打印检查结果
+type doctorReport struct {
+	failed, warned int
+}
+
+func (r *doctorReport) add(status doctorStatus, name, detail string) {
+	mark := "✓"
+	switch status {
+	case doctorWarn:
+		mark = "!"
+		r.warned++
+	case doctorFail:
+		mark = "✗"
+		r.failed++
+	}
+	fmt.Printf("  %s %-22s %s\n", mark, name, detail)
+}
+
+// runDoctor 检查配置、API Key、沙箱能力和模型服务连通性
+func runDoctor(args []string) error {
+	fs := flag.NewFlagSet("doctor", flag.ExitOnError)
+	offline := fs.Bool("offline", false, "Skip provider connectivity checks")
+	timeout := fs.Duration("timeout", 5*time.Second, "Timeout for each connectivity check")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: aster doctor [flags]\n\n")
+		fmt.Fprintf(os.Stderr, "Check configuration, API keys, sandbox capabilities and provider connectivity.\n\n")
+		fmt.Fprintf(os.Stderr, "Flags:\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	report := &doctorReport{}
+
+	fmt.Println("Configuration")
+	settings := doctorConfig(report)
+
+	fmt.Println("API keys")
+	providers := doctorAPIKeys(report, settings)
+
+	fmt.Println("Storage")
+	doctorDirs(report, settings)
+
+	fmt.Println("Sandbox")
+	doctorSandbox(report)
+
+	if !*offline {
+		fmt.Println("Connectivity")
+		doctorConnectivity(report, providers, *timeout)
+	}
+
+	fmt.Println()
+	if report.failed > 0 {
+		return fmt.Errorf("%d check(s) failed, %d warning(s)", report.failed, report.warned)
+	}
+	fmt.Printf("All checks passed (%d warning(s))\n", report.warned)
+	return nil
+}
+
+// doctorConfig 检查配置文件是否存在且合法，不合法时继续使用默认配置检查其余项
+func doctorConfig(r *doctorReport) *config.Settings {
+	path := config.SettingsFile()
+	if _, err := os.Stat(path); err != nil {
+		r.add(doctorOK, "settings file", path+" not found, using defaults")
+	} else {
+		r.add(doctorOK, "settings file", path)
+	}
+
+	settings, err := config.LoadSettings(path)
+	if err != nil {
+		r.add(doctorFail, "settings", err.Error())
+		settings = config.DefaultSettings()
+		settings.UseSecretStore(config.DefaultSecretStore())
+		return settings
+	}
+	settings.UseSecretStore(config.DefaultSecretStore())
+	if err := settings.Validate(); err != nil {
+		r.add(doctorFail, "settings", err.Error())
+		return settings
+	}
+	r.add(doctorOK, "settings", fmt.Sprintf("model %s/%s", settings.Provider, settings.Model))
+	return settings
+}
+
+// doctorAPIKeys 检查所有模型别名引用的 Provider 是否配置了 API Key，返回需要检查连通性的 Provider
+func doctorAPIKeys(r *doctorReport, settings *config.Settings) []string {
+	seen := make(map[string]bool)
+	for _, m := range settings.AliasConfig().Aliases {
+		seen[m.Provider] = true
+	}
+	providers := make([]string, 0, len(seen))
+	for name := range seen {
+		providers = append(providers, name)
+	}
+	sort.Strings(providers)
+
+	for _, name := range providers {
+		switch {
+		case name == "ollama":
+			r.add(doctorOK, name, "no API key required")
+		case settings.APIKey(name) != "":
+			_, source := settings.APIKeySource(name)
+			r.add(doctorOK, name, "API key set ("+source+")")
+		default:
+			r.add(doctorFail, name, config.APIKeyEnvName(name)+" not set; run 'aster setup'")
+		}
+	}
+	return providers
+}
+
+// doctorDirs 检查存储目录可写、会话工作目录存在
+func doctorDirs(r *doctorReport, settings *config.Settings) {
+	storeDir := settings.Serve.StoreDir
+	if err := os.MkdirAll(storeDir, 0o755); err != nil {
+		r.add(doctorFail, "store dir", err.Error())
+	} else if f, err := os.CreateTemp(storeDir, ".doctor-*"); err != nil {
+		r.add(doctorFail, "store dir", storeDir+" is not writable: "+err.Error())
+	} else {
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+		abs, _ := filepath.Abs(storeDir)
+		r.add(doctorOK, "store dir", abs)
+	}
+
+	workDir := settings.Session.WorkDir
+	if info, err := os.Stat(workDir); err != nil || !info.IsDir() {
+		r.add(doctorFail, "session work dir", workDir+" does not exist")
+	} else {
+		abs, _ := filepath.Abs(workDir)
+		r.add(doctorOK, "session work dir", abs)
+	}
+}
+
+// doctorSandbox 检查 Shell、OS 级隔离和常用外部工具
+func doctorSandbox(r *doctorReport) {
+	shell := ""
+	for _, name := range []string{"bash", "sh"} {
+		if path, err := exec.LookPath(name); err == nil {
+			shell = path
+			break
+		}
+	}
+	if shell == "" {
+		r.add(doctorFail, "shell", "neither bash nor sh found in PATH")
+	} else {
+		r.add(doctorOK, "shell", shell)
+	}
+
+	capability := sandbox.ProbeOSSandbox()
+	if capability.Available {
+		r.add(doctorOK, "os isolation", capability.Backend+" ("+capability.Path+")")
+	} else {
+		r.add(doctorWarn, "os isolation", capability.Reason+"; sandbox falls back to command filtering")
+	}
+
+	for _, tool := range []string{"git", "docker"} {
+		if path, err := exec.LookPath(tool); err == nil {
+			r.add(doctorOK, tool, path)
+		} else {
+			r.add(doctorWarn, tool, "not found in PATH")
+		}
+	}
+}
+
+// doctorConnectivity 检查各 Provider 默认地址是否可达，任何 HTTP 响应都视为可达
+func doctorConnectivity(r *doctorReport, providers []string, timeout time.Duration) {
+	client := httpclient.New(timeout)
+	for _, name := range providers {
+		baseURL := provider.DefaultBaseURL(name)
+		if baseURL == "" {
+			r.add(doctorWarn, name, "no default endpoint, skipped")
+			continue
+		}
+		if err := probeURL(client, baseURL); err != nil {
+			status := doctorFail
+			if name == "ollama" {
+				status = doctorWarn
+			}
+			r.add(status, name, baseURL+" unreachable: "+err.Error())
+			continue
+		}
+		r.add(doctorOK, name, baseURL+" reachable")
+	}
+}
+
+func probeURL(client *http.Client, url string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), client.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		var urlErr interface{ Timeout() bool }
+		if errors.As(err, &urlErr) && urlErr.Timeout() {
+			return fmt.Errorf("timed out after %s", client.Timeout)
+		}
+		return err
+	}
+	_ = resp.Body.Close()
+	return nil
+}
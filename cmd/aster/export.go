@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/astercloud/aster/pkg/config"
+	"github.com/astercloud/aster/pkg/session"
+	"github.com/astercloud/aster/pkg/session/sqlite"
+)
+
+// runSessionExport writes a stored session, its events and attachment contents as JSON
+func runSessionExport(args []string) error {
+	fs := flag.NewFlagSet("session export", flag.ExitOnError)
+	output := fs.String("o", "", "Output file (default: stdout)")
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: aster session export [flags] <session-id>\n\n")
+		fmt.Fprintf(os.Stderr, "Export a session with its events and attachments as JSON.\n\n")
+		fmt.Fprintf(os.Stderr, "Flags:\n")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("session id is required")
+	}
+	sessionID := fs.Arg(0)
+
+	sessionStore, err := sqlite.New(config.DatabaseFile())
+	if err != nil {
+		return fmt.Errorf("open session store: %w", err)
+	}
+	defer func() { _ = sessionStore.Close() }()
+
+	export, err := session.ExportSession(context.Background(), sessionStore, &session.GetRequest{
+		AppName:   "aster-cli",
+		UserID:    os.Getenv("USER"),
+		SessionID: sessionID,
+	})
+	if err != nil {
+		return fmt.Errorf("export session %s: %w", sessionID, err)
+	}
+
+	out := os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return fmt.Errorf("create output file: %w", err)
+		}
+		defer func() { _ = f.Close() }()
+		out = f
+	}
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(export); err != nil {
+		return fmt.Errorf("write export: %w", err)
+	}
+	if *output != "" {
+		fmt.Fprintf(os.Stderr, "Exported %d events and %d attachments to %s\n", len(export.Events), len(export.Attachments), *output)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/astercloud/aster/pkg/config"
+	"github.com/astercloud/aster/pkg/session"
+	"github.com/astercloud/aster/pkg/session/sqlite"
+)
+
+// runSessionFollow prints events of a session as another process appends them
+func runSessionFollow(args []string) error {
+	settings, err := loadCLISettings()
+	if err != nil {
+		return err
+	}
+
+	fs := flag.NewFlagSet("session follow", flag.ExitOnError)
+	history := fs.Int("history", 10, "Number of existing events to print first")
+	fs.Bool("no-color", settings.Session.NoColor, "Disable colored output")
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: aster session follow [flags] <session-id>\n\n")
+		fmt.Fprintf(os.Stderr, "Print new events of a session as they are written, e.g. by another aster session.\n\n")
+		fmt.Fprintf(os.Stderr, "Flags:\n")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("session id is required")
+	}
+	if err := applyFlagOverrides(fs, settings, map[string]string{
+		"no-color": "session.no_color",
+	}); err != nil {
+		return err
+	}
+	useColor := !settings.Session.NoColor && isTerminal(os.Stdout)
+	sessionID := fs.Arg(0)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	sessionStore, err := sqlite.New(config.DatabaseFile())
+	if err != nil {
+		return fmt.Errorf("open session store: %w", err)
+	}
+	defer func() { _ = sessionStore.Close() }()
+
+	// Subscribe before reading the backlog so nothing written in between is lost
+	events, err := sessionStore.Watch(ctx, sessionID)
+	if err != nil {
+		return fmt.Errorf("watch session %s: %w", sessionID, err)
+	}
+	// Events appended while the backlog is read arrive on both; print them once
+	printed := make(map[string]bool)
+	if *history > 0 {
+		backlog, err := sessionStore.GetEvents(ctx, sessionID, nil)
+		if err != nil {
+			return fmt.Errorf("load session %s: %w", sessionID, err)
+		}
+		for _, evt := range backlog[max(len(backlog)-*history, 0):] {
+			printFollowedEvent(useColor, &evt)
+			printed[evt.ID] = true
+		}
+	}
+	printColored(useColor, colorGray, "Following %s (Ctrl+C to stop)\n", sessionID)
+
+	for evt := range events {
+		if !printed[evt.ID] {
+			printFollowedEvent(useColor, &evt)
+		}
+	}
+	if ctx.Err() == nil {
+		printColored(useColor, colorGray, "Session %s was deleted\n", sessionID)
+	}
+	return nil
+}
+
+func printFollowedEvent(useColor bool, evt *session.Event) {
+	color := colorGreen
+	if evt.Author == "user" {
+		color = colorBlue
+	}
+	printColored(useColor, colorGray, "%s ", evt.Timestamp.Local().Format("15:04:05"))
+	printColored(useColor, color, "%s: ", evt.Author)
+	fmt.Println(evt.Content.Content)
+}
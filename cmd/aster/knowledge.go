@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+
+	"github.com/astercloud/aster/pkg/config"
+	"github.com/astercloud/aster/pkg/knowledge/core"
+	"github.com/astercloud/aster/pkg/knowledge/loader"
+	"github.com/astercloud/aster/pkg/vector"
+	"github.com/astercloud/aster/pkg/vector/factory"
+	"github.com/astercloud/aster/pkg/vector/pgvector"
+)
+
+// runKnowledge 管理本地知识库
+func runKnowledge(args []string) error {
+	if len(args) == 0 || args[0] != "ingest" {
+		fmt.Fprintf(os.Stderr, "Usage: aster knowledge ingest [flags] <path|url>...\n")
+		if len(args) == 0 {
+			return errors.New("missing subcommand")
+		}
+		return fmt.Errorf("unknown knowledge subcommand: %s", args[0])
+	}
+	return runKnowledgeIngest(args[1:])
+}
+
+// runKnowledgeIngest 加载文档并增量写入知识库
+func runKnowledgeIngest(args []string) error {
+	fs := flag.NewFlagSet("knowledge ingest", flag.ExitOnError)
+	dir := fs.String("dir", filepath.Join(config.DataDir(), "knowledge"), "Knowledge base directory (manifest, local vectors, embedding cache)")
+	namespace := fs.String("namespace", "default", "Knowledge base namespace")
+	embedderKind := fs.String("embedder", "openai", "Embedder: openai, voyage, ollama, mock")
+	model := fs.String("model", "", "Embedding model (default depends on the embedder)")
+	baseURL := fs.String("base-url", "", "Embedder base URL")
+	dsn := fs.String("dsn", "", "pgvector DSN; vectors are stored in a local file when empty")
+	dimension := fs.Int("dimension", 1536, "Vector dimension (pgvector only)")
+	chunkSize := fs.Int("chunk-size", loader.DefaultChunkSize, "Maximum characters per chunk")
+	force := fs.Bool("force", false, "Re-index every source even if its content is unchanged")
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: aster knowledge ingest [flags] <path|url>...\n\n")
+		fmt.Fprintf(os.Stderr, "Load PDF, DOCX, HTML, Markdown, text and source files into a knowledge base.\n")
+		fmt.Fprintf(os.Stderr, "Directories are walked recursively. Unchanged files are skipped and files\n")
+		fmt.Fprintf(os.Stderr, "deleted from an ingested directory are removed from the index.\n\n")
+		fmt.Fprintf(os.Stderr, "Flags:\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errors.New("missing path or url")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	base := filepath.Join(*dir, *namespace)
+	var store vector.VectorStore
+	var err error
+	if *dsn != "" {
+		store, err = pgvector.New(&pgvector.Config{DSN: *dsn, Dimension: *dimension})
+	} else {
+		store, err = vector.OpenFileStore(filepath.Join(base, "vectors.json"))
+	}
+	if err != nil {
+		return fmt.Errorf("open vector store: %w", err)
+	}
+	defer func() {
+		if err := store.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "close vector store: %v\n", err)
+		}
+	}()
+
+	embedderConfig := map[string]any{
+		"api_key":    config.ProviderAPIKey(*embedderKind),
+		"cache_dir":  filepath.Join(*dir, "embedding-cache"),
+		"batch_size": vector.DefaultEmbedBatchSize,
+	}
+	if *model != "" {
+		embedderConfig["model"] = *model
+	}
+	if *baseURL != "" {
+		embedderConfig["base_url"] = *baseURL
+	}
+	embedder, err := factory.NewEmbedderFactory().Create(*embedderKind, embedderConfig)
+	if err != nil {
+		if embedderConfig["api_key"] == "" {
+			return fmt.Errorf("create embedder: %w (set %s)", err, config.APIKeyEnvName(*embedderKind))
+		}
+		return fmt.Errorf("create embedder: %w", err)
+	}
+
+	pipeline, err := core.NewPipeline(core.PipelineConfig{Store: store, Embedder: embedder, Namespace: *namespace})
+	if err != nil {
+		return err
+	}
+	indexer, err := loader.NewIndexer(pipeline, filepath.Join(base, "manifest.json"), loader.IndexOptions{
+		Loader:    loader.Options{ChunkSize: *chunkSize},
+		Namespace: *namespace,
+		Force:     *force,
+		Progress: func(p loader.Progress) {
+			width := len(fmt.Sprint(p.Total))
+			line := fmt.Sprintf("[%*d/%d] %-9s %s", width, p.Current, p.Total, p.Status, p.Source)
+			if p.Status == loader.StatusIndexed {
+				line += fmt.Sprintf(" (%d chunks)", p.Chunks)
+			}
+			if p.Err != nil {
+				line += ": " + p.Err.Error()
+			}
+			fmt.Println(line)
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	summary, err := indexer.Index(ctx, fs.Args())
+	if summary != nil {
+		fmt.Printf("\nIndexed %d, unchanged %d, skipped %d, removed %d, failed %d (%d chunks written) into namespace %q\n",
+			summary.Indexed, summary.Unchanged, summary.Skipped, summary.Removed, summary.Failed, summary.Chunks, *namespace)
+	}
+	if err != nil {
+		return err
+	}
+	if summary.Failed > 0 {
+		return fmt.Errorf("%d sources failed", summary.Failed)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/astercloud/aster/pkg/types"
+)
+
+// lintResult is the report for one linted file
+type lintResult struct {
+	Path string `json:"path"`
+	*types.LintReport
+}
+
+// lintPaths expands directories into their YAML/JSON files (non-recursive) and lints each file
+func lintPaths(paths []string, lint func(path string, data []byte) *types.LintReport) ([]lintResult, error) {
+	var files []string
+	for _, p := range paths {
+		info, err := os.Stat(p)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, p)
+			continue
+		}
+		entries, err := os.ReadDir(p)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range entries {
+			if e.IsDir() || strings.HasPrefix(e.Name(), ".") {
+				continue
+			}
+			switch filepath.Ext(e.Name()) {
+			case ".yaml", ".yml", ".json":
+				files = append(files, filepath.Join(p, e.Name()))
+			}
+		}
+	}
+	sort.Strings(files)
+
+	results := make([]lintResult, 0, len(files))
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, lintResult{Path: f, LintReport: lint(f, data)})
+	}
+	return results, nil
+}
+
+// printLintResults prints the reports and returns an error when any file has errors,
+// or warnings in strict mode, so CI jobs fail with a non-zero exit code
+func printLintResults(results []lintResult, asJSON, strict bool) error {
+	errs, warnings := 0, 0
+	for _, r := range results {
+		errs += r.Count(types.LintError)
+		warnings += r.Count(types.LintWarning)
+	}
+
+	if asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(results); err != nil {
+			return err
+		}
+	} else {
+		for _, r := range results {
+			name := r.Path
+			if r.Name != "" {
+				name = fmt.Sprintf("%s (%s)", r.Path, r.Name)
+			}
+			tokens := fmt.Sprintf("~%d prompt tokens", r.PromptTokens)
+			if r.ToolTokens > 0 {
+				tokens += fmt.Sprintf(", ~%d tool tokens", r.ToolTokens)
+			}
+			fmt.Printf("%s: %s\n", name, tokens)
+			for _, issue := range r.Issues {
+				fmt.Printf("  %s\n", issue)
+			}
+		}
+		fmt.Printf("\n%d file(s), %d error(s), %d warning(s)\n", len(results), errs, warnings)
+	}
+
+	if errs > 0 || (strict && warnings > 0) {
+		return fmt.Errorf("%d error(s), %d warning(s)", errs, warnings)
+	}
+	return nil
+}
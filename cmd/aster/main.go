@@ -26,6 +26,42 @@ func main() {
 		if err := runSession(os.Args[2:]); err != nil {
 			log.Fatalf("aster session failed: %v", err)
 		}
+	case "config":
+		if err := runConfig(os.Args[2:]); err != nil {
+			log.Fatalf("aster config failed: %v", err)
+		}
+	case "permissions":
+		if err := runPermissions(os.Args[2:]); err != nil {
+			log.Fatalf("aster permissions failed: %v", err)
+		}
+	case "report":
+		if err := runReport(os.Args[2:]); err != nil {
+			log.Fatalf("aster report failed: %v", err)
+		}
+	case "audit":
+		if err := runAudit(os.Args[2:]); err != nil {
+			log.Fatalf("aster audit failed: %v", err)
+		}
+	case "knowledge":
+		if err := runKnowledge(os.Args[2:]); err != nil {
+			log.Fatalf("aster knowledge failed: %v", err)
+		}
+	case "recipe":
+		if err := runRecipe(os.Args[2:]); err != nil {
+			log.Fatalf("aster recipe failed: %v", err)
+		}
+	case "template":
+		if err := runTemplate(os.Args[2:]); err != nil {
+			log.Fatalf("aster template failed: %v", err)
+		}
+	case "setup":
+		if err := runSetup(os.Args[2:]); err != nil {
+			log.Fatalf("aster setup failed: %v", err)
+		}
+	case "doctor":
+		if err := runDoctor(os.Args[2:]); err != nil {
+			log.Fatalf("aster doctor: %v", err)
+		}
 	case "help", "-h", "--help":
 		printUsage()
 	default:
@@ -42,14 +78,33 @@ func printUsage() {
 	fmt.Println("  aster <command> [flags]")
 	fmt.Println()
 	fmt.Println("Commands:")
-	fmt.Println("  session    Start an interactive AI agent session")
-	fmt.Println("  serve      Start an HTTP server")
-	fmt.Println("  mcp-serve  Start an MCP HTTP server")
+	fmt.Println("  setup        First-run setup: provider, API key and workspace")
+	fmt.Println("  session      Start an interactive AI agent session")
+	fmt.Println("  serve        Start an HTTP server")
+	fmt.Println("  mcp-serve    Start an MCP HTTP server")
+	fmt.Println("  config       Show or edit configuration (aster.yaml)")
+	fmt.Println("  permissions  Import, export or validate permission policies")
+	fmt.Println("  report       Generate usage and cost reports")
+	fmt.Println("  audit        Export or verify the audit trail")
+	fmt.Println("  knowledge    Ingest documents into a knowledge base")
+	fmt.Println("  recipe       Distill a session into a reusable recipe, or lint recipes")
+	fmt.Println("  template     Lint agent template definitions")
+	fmt.Println("  doctor       Check configuration, API keys, sandbox and connectivity")
 	fmt.Println()
 	fmt.Println("Examples:")
+	fmt.Println("  aster setup                      # Choose a provider and store its API key")
 	fmt.Println("  aster session                    # Start interactive session")
 	fmt.Println("  aster session --recipe my.yaml   # Start with recipe")
+	fmt.Println("  aster session replay <id>        # Step through a stored session")
 	fmt.Println("  aster serve --port 8080          # Start HTTP server")
+	fmt.Println("  aster config set model gpt-4o    # Persist a setting")
+	fmt.Println("  aster permissions export         # Export permission rules as YAML")
+	fmt.Println("  aster report usage --month 2025-01  # Monthly usage and cost report")
+	fmt.Println("  aster audit export -tenant acme -o acme.jsonl  # Export a tenant's audit trail")
+	fmt.Println("  aster knowledge ingest ./docs    # Index a directory (incremental)")
+	fmt.Println("  aster recipe distill <id> -o r.yaml  # Turn a session into a recipe")
+	fmt.Println("  aster template lint templates/   # Validate templates in CI")
+	fmt.Println("  aster doctor --offline           # Diagnose the local setup")
 	fmt.Println()
 	fmt.Println("Use 'aster <command> -h' for command-specific help.")
 }
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/astercloud/aster/pkg/permission"
+)
+
+// runPermissions 导入、导出和校验权限策略文件
+func runPermissions(args []string) error {
+	fs := flag.NewFlagSet("permissions", flag.ExitOnError)
+	output := fs.String("o", "", "Output file for export (default stdout)")
+	workspace := fs.String("workspace", "", "Import into <dir>/"+permission.WorkspacePolicyPath+" instead of the global rules")
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: aster permissions <export|import|validate> [flags] [file]\n\n")
+		fmt.Fprintf(os.Stderr, "Manage permission policies as YAML rule bundles with named groups.\n\n")
+		fmt.Fprintf(os.Stderr, "Subcommands:\n")
+		fmt.Fprintf(os.Stderr, "  export           Print persisted permission rules as a policy\n")
+		fmt.Fprintf(os.Stderr, "  import <file>    Validate and apply a policy\n")
+		fmt.Fprintf(os.Stderr, "  validate <file>  Check a policy without applying it\n")
+		fmt.Fprintf(os.Stderr, "\nFlags:\n")
+		fs.PrintDefaults()
+	}
+
+	if len(args) == 0 {
+		fs.Usage()
+		return errors.New("missing subcommand")
+	}
+	// 子命令在前，标志在后: aster permissions import -workspace . policy.yaml
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	rest := append([]string{args[0]}, fs.Args()...)
+
+	switch rest[0] {
+	case "export":
+		policy := permission.NewInspector(permission.ModeSmartApprove).ExportPolicy()
+		// 全局规则不持久化模式，导出时不写入
+		policy.Mode = ""
+		data, err := policy.Encode()
+		if err != nil {
+			return fmt.Errorf("encode policy: %w", err)
+		}
+		if *output == "" {
+			_, err = os.Stdout.Write(data)
+			return err
+		}
+		if err := os.WriteFile(*output, data, 0o644); err != nil {
+			return err
+		}
+		fmt.Printf("Policy written to %s\n", *output)
+		return nil
+
+	case "import":
+		if len(rest) != 2 {
+			return errors.New("usage: aster permissions import [-workspace dir] <file>")
+		}
+		data, err := os.ReadFile(rest[1])
+		if err != nil {
+			return err
+		}
+		policy, err := permission.ParsePolicy(data)
+		if err != nil {
+			return fmt.Errorf("%s: %w", rest[1], err)
+		}
+
+		if *workspace != "" {
+			// 保留原文件内容，注释和评审历史随仓库一起提交
+			dest := filepath.Join(*workspace, permission.WorkspacePolicyPath)
+			if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+				return err
+			}
+			if err := os.WriteFile(dest, data, 0o644); err != nil {
+				return err
+			}
+			fmt.Printf("Policy installed at %s (%d groups)\n", dest, len(policy.Groups))
+			return nil
+		}
+
+		if err := permission.NewInspector(permission.ModeSmartApprove).ApplyPolicy(policy); err != nil {
+			return err
+		}
+		fmt.Printf("Imported %d rules from %d groups\n", len(policy.Rules()), len(policy.Groups))
+		if policy.Mode != "" || len(policy.ToolRisks) > 0 {
+			fmt.Println("Note: mode and tool_risks only take effect in workspace policies; use -workspace to apply them.")
+		}
+		return nil
+
+	case "validate":
+		if len(rest) != 2 {
+			return errors.New("usage: aster permissions validate <file>")
+		}
+		policy, err := permission.LoadPolicyFile(rest[1])
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s: OK (%d groups, %d rules)\n", rest[1], len(policy.Groups), len(policy.Rules()))
+		return nil
+
+	default:
+		fs.Usage()
+		return fmt.Errorf("unknown permissions subcommand: %s", rest[0])
+	}
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/astercloud/aster/pkg/agent"
+	"github.com/astercloud/aster/pkg/config"
+	"github.com/astercloud/aster/pkg/provider"
+	"github.com/astercloud/aster/pkg/recipe"
+	"github.com/astercloud/aster/pkg/session"
+	"github.com/astercloud/aster/pkg/session/sqlite"
+	"github.com/astercloud/aster/pkg/store"
+	"github.com/astercloud/aster/pkg/tools"
+	"github.com/astercloud/aster/pkg/tools/builtin"
+	"github.com/astercloud/aster/pkg/types"
+	"gopkg.in/yaml.v3"
+)
+
+// runRecipe manages recipes
+func runRecipe(args []string) error {
+	const usage = "Usage: aster recipe <distill|lint> [flags] <args>\n"
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, usage)
+		return errors.New("missing subcommand")
+	}
+	switch args[0] {
+	case "distill":
+		return runRecipeDistill(args[1:])
+	case "lint":
+		return runRecipeLint(args[1:])
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		return fmt.Errorf("unknown recipe subcommand: %s", args[0])
+	}
+}
+
+// runRecipeLint checks recipe files against the builtin tools and known templates
+func runRecipeLint(args []string) error {
+	fs := flag.NewFlagSet("recipe lint", flag.ExitOnError)
+	asJSON := fs.Bool("json", false, "Print reports as JSON")
+	strict := fs.Bool("strict", false, "Exit with an error on warnings too")
+	templatesDir := fs.String("templates", "", "Directory of template definitions to resolve template_id against (default: serve.templates_dir)")
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: aster recipe lint [flags] <file|dir>...\n\n")
+		fmt.Fprintf(os.Stderr, "Check recipes: tool and template references, {{placeholders}} against declared\n")
+		fmt.Fprintf(os.Stderr, "parameters and permission_mode, and estimate the prompt token count.\n")
+		fmt.Fprintf(os.Stderr, "Exits non-zero on errors.\n\n")
+		fmt.Fprintf(os.Stderr, "Flags:\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errors.New("at least one recipe file or directory is required")
+	}
+
+	registry := tools.NewRegistry()
+	builtin.RegisterAll(registry)
+	templates := agent.NewTemplateRegistry()
+	registerBuiltinTemplates(templates)
+	if *templatesDir == "" {
+		if settings, err := loadCLISettings(); err == nil {
+			*templatesDir = settings.Serve.TemplatesDir
+		}
+	}
+	if *templatesDir != "" {
+		if _, err := templates.LoadDir(*templatesDir); err != nil {
+			return fmt.Errorf("load templates: %w", err)
+		}
+	}
+	opts := recipe.LintOptions{
+		HasTool: registry.Has,
+		HasTemplate: func(id string) bool {
+			_, err := templates.Get(id)
+			return err == nil
+		},
+	}
+
+	results, err := lintPaths(fs.Args(), func(path string, data []byte) *types.LintReport {
+		var r recipe.Recipe
+		if err := yaml.Unmarshal(data, &r); err != nil {
+			report := &types.LintReport{}
+			report.Add(types.LintError, "invalid_recipe", "parse recipe: %v", err)
+			return report
+		}
+		return recipe.Lint(&r, opts)
+	})
+	if err != nil {
+		return err
+	}
+	return printLintResults(results, *asJSON, *strict)
+}
+
+// runRecipeDistill turns a stored CLI session into a reusable recipe
+func runRecipeDistill(args []string) error {
+	fs := flag.NewFlagSet("recipe distill", flag.ExitOnError)
+	output := fs.String("o", "", "Write the recipe to this file instead of stdout")
+	title := fs.String("title", "", "Recipe title (default: derived from the first prompt)")
+	useLLM := fs.Bool("llm", false, "Ask the configured model to rewrite the title, description and instructions")
+	includeFailed := fs.Bool("include-failed-tools", false, "Also enable tools whose every call failed")
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: aster recipe distill [flags] <session-id>\n\n")
+		fmt.Fprintf(os.Stderr, "Generate a recipe from a successful session: the first prompt, the tools used,\n")
+		fmt.Fprintf(os.Stderr, "instructions from the steps taken, and parameters for values that vary between runs.\n\n")
+		fmt.Fprintf(os.Stderr, "Flags:\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("session id is required")
+	}
+	sessionID := fs.Arg(0)
+
+	settings, err := loadCLISettings()
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+
+	sessionStore, err := sqlite.New(config.DatabaseFile())
+	if err != nil {
+		return fmt.Errorf("open session store: %w", err)
+	}
+	defer func() { _ = sessionStore.Close() }()
+
+	sess, err := sessionStore.Get(ctx, &session.GetRequest{
+		AppName:   "aster-cli",
+		UserID:    os.Getenv("USER"),
+		SessionID: sessionID,
+	})
+	if err != nil {
+		return fmt.Errorf("load session %s: %w", sessionID, err)
+	}
+	dataStore, err := store.NewJSONStore(filepath.Join(config.DataDir(), "store"))
+	if err != nil {
+		return fmt.Errorf("open data store: %w", err)
+	}
+	messages, err := dataStore.LoadMessages(ctx, sess.AgentID())
+	if err != nil {
+		return fmt.Errorf("load messages: %w", err)
+	}
+
+	r, err := recipe.Distill(messages, recipe.DistillOptions{
+		Title:              *title,
+		Source:             "session " + sessionID,
+		IncludeFailedTools: *includeFailed,
+	})
+	if err != nil {
+		return err
+	}
+
+	if *useLLM {
+		rt, err := settings.Router()
+		if err != nil {
+			return fmt.Errorf("build model router: %w", err)
+		}
+		modelConfig, err := buildModelConfig(settings, rt, nil)
+		if err != nil {
+			return err
+		}
+		prov, err := provider.NewMultiProviderFactory().Create(modelConfig)
+		if err != nil {
+			return fmt.Errorf("create provider: %w", err)
+		}
+		defer func() { _ = prov.Close() }()
+		if err := recipe.Refine(ctx, prov, r, messages); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: %v; keeping the distilled recipe as is\n", err)
+		} else if *title != "" {
+			r.Title = *title
+		}
+	}
+
+	data, err := r.ToYAML()
+	if err != nil {
+		return err
+	}
+	if *output == "" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	if err := os.WriteFile(*output, data, 0o644); err != nil {
+		return fmt.Errorf("write recipe: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "Wrote recipe %q with %d parameter(s) and %d tool(s) to %s\n", r.Title, len(r.Parameters), len(r.Tools), *output)
+	return nil
+}
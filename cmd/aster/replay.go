@@ -0,0 +1,315 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/astercloud/aster/pkg/agent"
+	"github.com/astercloud/aster/pkg/config"
+	"github.com/astercloud/aster/pkg/provider"
+	"github.com/astercloud/aster/pkg/recipe"
+	"github.com/astercloud/aster/pkg/session"
+	"github.com/astercloud/aster/pkg/session/sqlite"
+	"github.com/astercloud/aster/pkg/store"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+// replayPreviewLimit caps how much of each message block is printed without --full
+const replayPreviewLimit = 500
+
+// runSessionReplay steps through a stored CLI session turn by turn
+func runSessionReplay(args []string) error {
+	settings, err := loadCLISettings()
+	if err != nil {
+		return err
+	}
+
+	fs := flag.NewFlagSet("session replay", flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", false, "Re-execute read-only tool calls (and tools supporting dry_run) and compare results")
+	showSystem := fs.Bool("system", false, "Print the system prompt")
+	full := fs.Bool("full", false, "Print every request message in full instead of only new ones")
+	noPause := fs.Bool("no-pause", false, "Do not wait for Enter between turns")
+	recipeFile := fs.String("recipe", "", "Recipe the session was started with")
+	fs.Bool("no-color", settings.Session.NoColor, "Disable colored output")
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: aster session replay [flags] <session-id>\n\n")
+		fmt.Fprintf(os.Stderr, "Step through a stored session, showing the request sent to the model at each step.\n\n")
+		fmt.Fprintf(os.Stderr, "Flags:\n")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("session id is required")
+	}
+	if err := applyFlagOverrides(fs, settings, map[string]string{
+		"no-color": "session.no_color",
+	}); err != nil {
+		return err
+	}
+	useColor := !settings.Session.NoColor && isTerminal(os.Stdout)
+	pause := !*noPause && isTerminal(os.Stdin) && isTerminal(os.Stdout)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Locate the session and the agent that ran it
+	sessionStore, err := sqlite.New(config.DatabaseFile())
+	if err != nil {
+		return fmt.Errorf("open session store: %w", err)
+	}
+	defer func() { _ = sessionStore.Close() }()
+
+	sess, err := sessionStore.Get(ctx, &session.GetRequest{
+		AppName:   "aster-cli",
+		UserID:    os.Getenv("USER"),
+		SessionID: fs.Arg(0),
+	})
+	if err != nil {
+		return fmt.Errorf("load session %s: %w", fs.Arg(0), err)
+	}
+
+	dataStore, err := store.NewJSONStore(filepath.Join(config.DataDir(), "store"))
+	if err != nil {
+		return fmt.Errorf("open data store: %w", err)
+	}
+	messages, err := dataStore.LoadMessages(ctx, sess.AgentID())
+	if err != nil {
+		return fmt.Errorf("load messages: %w", err)
+	}
+	info, _ := dataStore.LoadInfo(ctx, sess.AgentID())
+
+	// Rebuild an equivalent agent that never calls the model
+	rt, err := settings.Router()
+	if err != nil {
+		return fmt.Errorf("build model router: %w", err)
+	}
+	var recipeConfig *recipe.Recipe
+	if *recipeFile != "" {
+		if recipeConfig, err = recipe.LoadFromFile(*recipeFile); err != nil {
+			return fmt.Errorf("load recipe: %w", err)
+		}
+	}
+	modelConfig, err := buildModelConfig(settings, rt, recipeConfig)
+	if err != nil {
+		return err
+	}
+	templateID := "default"
+	if info != nil {
+		if info.Model != "" {
+			modelConfig.Model = info.Model
+		}
+		if info.TemplateID != "" {
+			templateID = info.TemplateID
+		}
+	}
+
+	workDir, _ := sess.Metadata()["work_dir"].(string)
+	if workDir == "" {
+		workDir = settings.Session.WorkDir
+	}
+
+	// Replay state goes to a scratch store so the recorded session is left untouched
+	scratchDir, err := os.MkdirTemp("", "aster-replay-")
+	if err != nil {
+		return fmt.Errorf("create scratch store: %w", err)
+	}
+	defer func() { _ = os.RemoveAll(scratchDir) }()
+	scratchStore, err := store.NewJSONStore(scratchDir)
+	if err != nil {
+		return fmt.Errorf("create scratch store: %w", err)
+	}
+
+	agentDeps := createAgentDependencies(scratchStore, nil)
+	agentDeps.ProviderFactory = replayProviderFactory{}
+	agentConfig := &types.AgentConfig{
+		TemplateID:  templateID,
+		ModelConfig: modelConfig,
+		Sandbox: &types.SandboxConfig{
+			Kind:    types.SandboxKindLocal,
+			WorkDir: workDir,
+		},
+		Metadata: map[string]any{
+			"work_dir": workDir,
+		},
+	}
+	if recipeConfig != nil {
+		if err := applyRecipeToConfig(recipeConfig, agentConfig, agentDeps); err != nil {
+			return fmt.Errorf("apply recipe: %w", err)
+		}
+	}
+
+	ag, err := agent.Create(ctx, agentConfig, agentDeps)
+	if err != nil {
+		return fmt.Errorf("create replay agent: %w", err)
+	}
+	defer func() { _ = ag.Close() }()
+
+	steps, err := ag.Replay(ctx, messages, agent.ReplayOptions{DryRun: *dryRun})
+	if err != nil {
+		return fmt.Errorf("replay: %w", err)
+	}
+
+	printColored(useColor, colorBold+colorCyan, "\n⏪ Replaying session %s\n", sess.ID())
+	printColored(useColor, colorGray, "  Agent: %s  Template: %s  Model: %s/%s\n", sess.AgentID(), templateID, modelConfig.Provider, modelConfig.Model)
+	printColored(useColor, colorGray, "  Messages: %d  Steps: %d\n", len(messages), len(steps))
+	if len(steps) == 0 {
+		printColored(useColor, colorYellow, "No model calls recorded for this session.\n")
+		return nil
+	}
+	if *showSystem {
+		printColored(useColor, colorBold, "\nSystem prompt:\n")
+		fmt.Println(steps[0].Request.System)
+	}
+	printColored(useColor, colorGray, "  Tools: %s\n", strings.Join(steps[0].Request.Tools, ", "))
+
+	reader := bufio.NewReader(os.Stdin)
+	sent := 0
+	for i, step := range steps {
+		if pause && i > 0 && step.Turn != steps[i-1].Turn {
+			printColored(useColor, colorGray, "\n-- Enter for turn %d, q to quit -- ", step.Turn)
+			line, err := reader.ReadString('\n')
+			if err != nil || strings.TrimSpace(line) == "q" {
+				return nil
+			}
+		}
+		sent = printReplayStep(useColor, step, sent, *full)
+	}
+	return nil
+}
+
+// printReplayStep prints one model call. Without full, only request messages
+// added since the previous step (sent) are printed. Returns the request size.
+func printReplayStep(useColor bool, step agent.ReplayStep, sent int, full bool) int {
+	req := step.Request
+	printColored(useColor, colorBold+colorBlue, "\n━━ Turn %d · Step %d ━━\n", step.Turn, step.Step)
+	printColored(useColor, colorCyan, "▶ Request: %d messages, %d tools, system prompt %d chars\n", len(req.Messages), len(req.Tools), len(req.System))
+	if req.Error != "" {
+		printColored(useColor, colorYellow, "  ⚠ %s\n", req.Error)
+	}
+
+	from := sent
+	if full || from > len(req.Messages) {
+		// Context compaction rewrote earlier messages, show the whole request
+		from = 0
+	}
+	if from > 0 {
+		printColored(useColor, colorGray, "  … %d earlier messages\n", from)
+	}
+	for _, msg := range req.Messages[from:] {
+		printReplayMessage(useColor, msg, full)
+	}
+
+	printColored(useColor, colorCyan, "◀ Response:\n")
+	printReplayMessage(useColor, step.Response, full)
+
+	for _, call := range step.ToolCalls {
+		printColored(useColor, colorYellow, "  🔧 %s (%s)\n", call.Call.Name, call.Call.ID)
+		if call.Recorded != nil {
+			status := "ok"
+			if call.Recorded.IsError {
+				status = "error"
+			}
+			printColored(useColor, colorGray, "     recorded: %s, %d chars\n", status, len(call.Recorded.Content))
+		} else {
+			printColored(useColor, colorGray, "     recorded: missing\n")
+		}
+		if dr := call.DryRun; dr != nil {
+			switch {
+			case dr.Skipped != "":
+				printColored(useColor, colorGray, "     dry-run: skipped (%s)\n", dr.Skipped)
+			case dr.Matches:
+				printColored(useColor, colorGreen, "     dry-run: matches recorded result (%dms)\n", dr.DurationMs)
+			default:
+				printColored(useColor, colorYellow, "     dry-run: differs from recorded result (%dms)\n", dr.DurationMs)
+				printColored(useColor, colorGray, "%s\n", indent(preview(dr.Output, full), "       "))
+			}
+		}
+	}
+	return len(req.Messages) + 1
+}
+
+// printReplayMessage prints a message block by block
+func printReplayMessage(useColor bool, msg types.Message, full bool) {
+	printColored(useColor, colorBold, "  [%s]\n", msg.Role)
+	if msg.Content != "" {
+		fmt.Println(indent(preview(msg.Content, full), "    "))
+	}
+	for _, block := range msg.ContentBlocks {
+		switch b := block.(type) {
+		case *types.TextBlock:
+			fmt.Println(indent(preview(b.Text, full), "    "))
+		case *types.ThinkingBlock:
+			printColored(useColor, colorGray, "%s\n", indent("(thinking) "+preview(b.Thinking, full), "    "))
+		case *types.ToolUseBlock:
+			input, _ := json.Marshal(b.Input)
+			printColored(useColor, colorYellow, "    tool_use %s %s\n", b.Name, preview(string(input), full))
+		case *types.ToolResultBlock:
+			label := "tool_result"
+			if b.IsError {
+				label = "tool_error"
+			}
+			printColored(useColor, colorGray, "%s\n", indent(label+" "+preview(b.Content, full), "    "))
+		default:
+			printColored(useColor, colorGray, "    (%T)\n", block)
+		}
+	}
+}
+
+// preview truncates s unless full output was requested
+func preview(s string, full bool) string {
+	if full || len(s) <= replayPreviewLimit {
+		return s
+	}
+	return s[:replayPreviewLimit] + fmt.Sprintf("… (%d more chars)", len(s)-replayPreviewLimit)
+}
+
+// indent prefixes every line of s
+func indent(s, prefix string) string {
+	return prefix + strings.ReplaceAll(s, "\n", "\n"+prefix)
+}
+
+// replayProviderFactory creates providers that only carry the model config;
+// replay rebuilds requests without calling the model
+type replayProviderFactory struct{}
+
+func (replayProviderFactory) Create(config *types.ModelConfig) (provider.Provider, error) {
+	return &replayProvider{config: config}, nil
+}
+
+// errReplayModelCall is returned if anything tries to call the model during replay
+var errReplayModelCall = errors.New("model calls are disabled during replay")
+
+type replayProvider struct {
+	config *types.ModelConfig
+	system string
+}
+
+func (p *replayProvider) Stream(context.Context, []types.Message, *provider.StreamOptions) (<-chan provider.StreamChunk, error) {
+	return nil, errReplayModelCall
+}
+
+func (p *replayProvider) Complete(context.Context, []types.Message, *provider.StreamOptions) (*provider.CompleteResponse, error) {
+	return nil, errReplayModelCall
+}
+
+func (p *replayProvider) Config() *types.ModelConfig { return p.config }
+
+func (p *replayProvider) Capabilities() provider.ProviderCapabilities {
+	return provider.ProviderCapabilities{}
+}
+
+func (p *replayProvider) SetSystemPrompt(prompt string) error { p.system = prompt; return nil }
+func (p *replayProvider) GetSystemPrompt() string             { return p.system }
+func (p *replayProvider) Close() error                        { return nil }
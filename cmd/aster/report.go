@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/astercloud/aster/pkg/dashboard"
+	"github.com/astercloud/aster/pkg/store"
+	"github.com/astercloud/aster/pkg/usage"
+)
+
+// runReport 生成用量和成本报表
+func runReport(args []string) error {
+	settings, err := loadCLISettings()
+	if err != nil {
+		return err
+	}
+
+	fs := flag.NewFlagSet("report", flag.ExitOnError)
+	month := fs.String("month", time.Now().UTC().Format("2006-01"), "Report month (YYYY-MM)")
+	groupBy := fs.String("group-by", string(usage.GroupByTenant), "Group rows by: tenant, agent, model")
+	format := fs.String("format", string(usage.FormatCSV), "Output format: csv, json")
+	tenant := fs.String("tenant", "", "Only include this tenant")
+	storeDir := fs.String("store", settings.Serve.StoreDir, "Directory of the server JSON store")
+	pricing := fs.String("pricing", "", "Pricing file merged over the bundled pricing table")
+	currency := fs.String("currency", "", "Report currency (converted with the pricing table's exchange rates)")
+	output := fs.String("o", "", "Output file (default stdout)")
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: aster report usage [flags]\n\n")
+		fmt.Fprintf(os.Stderr, "Generate monthly per-tenant/per-agent usage and cost reports\n")
+		fmt.Fprintf(os.Stderr, "from the token usage recorded by 'aster serve'.\n\n")
+		fmt.Fprintf(os.Stderr, "Flags:\n")
+		fs.PrintDefaults()
+	}
+
+	if len(args) == 0 || args[0] != "usage" {
+		fs.Usage()
+		return errors.New("missing subcommand, want: usage")
+	}
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	m, err := usage.ParseMonth(*month)
+	if err != nil {
+		return err
+	}
+	if !usage.GroupBy(*groupBy).Valid() {
+		return fmt.Errorf("invalid --group-by %q, want tenant, agent or model", *groupBy)
+	}
+
+	table := dashboard.DefaultPricingTable()
+	if *pricing != "" {
+		override, err := dashboard.LoadPricingFile(*pricing)
+		if err != nil {
+			return err
+		}
+		if table, err = table.Merge(override); err != nil {
+			return err
+		}
+	}
+	calc := dashboard.NewCostCalculatorFromTable(table)
+	if *currency != "" {
+		calc.SetCurrency(*currency)
+	}
+
+	st, err := store.NewJSONStore(*storeDir)
+	if err != nil {
+		return fmt.Errorf("open store: %w", err)
+	}
+	report, err := usage.Generate(context.Background(), usage.NewLedger(st), usage.ReportOptions{
+		Month:      m,
+		GroupBy:    usage.GroupBy(*groupBy),
+		TenantID:   *tenant,
+		Calculator: calc,
+	})
+	if err != nil {
+		return err
+	}
+
+	if *output == "" {
+		return report.Write(os.Stdout, usage.Format(*format))
+	}
+	f, err := os.Create(*output)
+	if err != nil {
+		return err
+	}
+	if err := report.Write(f, usage.Format(*format)); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	fmt.Printf("Report written to %s\n", *output)
+	return nil
+}
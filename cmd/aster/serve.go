@@ -1,14 +1,17 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"log"
-	"os"
+	"path/filepath"
 
 	"github.com/astercloud/aster/pkg/agent"
+	"github.com/astercloud/aster/pkg/chaos"
+	"github.com/astercloud/aster/pkg/config"
+	"github.com/astercloud/aster/pkg/logging"
 	"github.com/astercloud/aster/pkg/provider"
-	"github.com/astercloud/aster/pkg/router"
 	"github.com/astercloud/aster/pkg/sandbox"
 	"github.com/astercloud/aster/pkg/store"
 	"github.com/astercloud/aster/pkg/tools"
@@ -19,18 +22,40 @@ import (
 
 // runServe 启动 HTTP Server（开发模式 - 使用简化配置）
 func runServe(args []string) error {
+	settings, err := loadCLISettings()
+	if err != nil {
+		return err
+	}
+
 	fs := flag.NewFlagSet("serve", flag.ExitOnError)
-	host := fs.String("host", "0.0.0.0", "HTTP listen host")
-	port := fs.Int("port", 8080, "HTTP listen port")
-	storeDir := fs.String("store", ".aster", "Directory for JSON store data")
-	mode := fs.String("mode", "debug", "Server mode: debug, release")
+	fs.String("host", settings.Serve.Host, "HTTP listen host")
+	fs.Int("port", settings.Serve.Port, "HTTP listen port")
+	fs.String("store", settings.Serve.StoreDir, "Directory for JSON store data")
+	fs.String("mode", settings.Serve.Mode, "Server mode: debug, release")
+	fs.String("templates", settings.Serve.TemplatesDir, "Directory of template definitions (YAML/JSON), reloaded on change")
+	fs.String("log-level", settings.Serve.LogLevel, "Log level with optional per-component overrides, e.g. info,Dashboard=debug")
+	fs.String("log-format", settings.Serve.LogFormat, "Log format: console, json")
+	fs.String("admin-addr", settings.Serve.AdminAddr, "Serve diagnostics (pprof, goroutines, EventBus, store latency) on this address, e.g. 127.0.0.1:6060")
 
 	if err := fs.Parse(args); err != nil {
 		return err
 	}
+	if err := applyFlagOverrides(fs, settings, map[string]string{
+		"host":       "serve.host",
+		"port":       "serve.port",
+		"store":      "serve.store_dir",
+		"mode":       "serve.mode",
+		"templates":  "serve.templates_dir",
+		"admin-addr": "serve.admin_addr",
+		"log-level":  "serve.log_level",
+		"log-format": "serve.log_format",
+	}); err != nil {
+		return err
+	}
+	host, port := settings.Serve.Host, settings.Serve.Port
 
 	// 创建 Store
-	jsonStore, err := store.NewJSONStore(*storeDir)
+	jsonStore, err := store.NewJSONStore(settings.Serve.StoreDir)
 	if err != nil {
 		return fmt.Errorf("create store: %w", err)
 	}
@@ -40,24 +65,29 @@ func runServe(args []string) error {
 	builtin.RegisterAll(toolRegistry)
 
 	sandboxFactory := sandbox.NewFactory()
-	providerFactory := provider.NewMultiProviderFactory()
+	var providerFactory provider.Factory = provider.NewMultiProviderFactory()
 	templateRegistry := agent.NewTemplateRegistry()
 	registerBuiltinTemplates(templateRegistry)
 
-	anthropicKey := os.Getenv("ANTHROPIC_API_KEY")
-	if anthropicKey == "" {
-		log.Println("[WARN] ANTHROPIC_API_KEY not set")
+	// 模型别名路由: models/tasks 配置决定默认模型和按任务覆盖
+	rt, err := settings.Router()
+	if err != nil {
+		return fmt.Errorf("build model router: %w", err)
 	}
-
-	defaultModel := &types.ModelConfig{
-		Provider: "anthropic",
-		Model:    "claude-sonnet-4-5",
-		APIKey:   anthropicKey,
+	defaultModel, err := rt.SelectModel(context.Background(), nil)
+	if err != nil {
+		return fmt.Errorf("resolve default model: %w", err)
 	}
-	routes := []router.StaticRouteEntry{
-		{Task: "chat", Priority: router.PriorityQuality, Model: defaultModel},
+	if defaultModel.APIKey == "" {
+		log.Printf("[WARN] %s not set", config.APIKeyEnvName(defaultModel.Provider))
+	}
+
+	// 多 Key 轮换: keys.pools 中的 Provider 遇到 401/429 时换 Key 重试，并定期检查 Key 健康
+	keys := settings.KeyManager()
+	if keys != nil {
+		keys.StartHealthChecks(context.Background(), settings.KeyHealthInterval(), provider.FactoryKeyCheck(providerFactory, keyCheckModels(settings)))
+		providerFactory = keys.WrapFactory(providerFactory)
 	}
-	rt := router.NewStaticRouter(defaultModel, routes)
 
 	agentDeps := &agent.Dependencies{
 		Store:            jsonStore,
@@ -67,6 +97,11 @@ func runServe(args []string) error {
 		TemplateRegistry: templateRegistry,
 		Router:           rt,
 	}
+	// 故障注入仅用于预发环境的韧性验证
+	if settings.Serve.Chaos != nil {
+		agentDeps.Faults = chaos.New(*settings.Serve.Chaos)
+		log.Printf("[WARN] fault injection enabled (serve.chaos), do not use in production")
+	}
 
 	// 初始化 SubAgentManager 并注入到 Task 工具
 	// 这使得 Task 工具可以创建真正的子 Agent 而不是进程级别的执行
@@ -78,11 +113,17 @@ func runServe(args []string) error {
 		AgentDeps: agentDeps,
 	}
 
+	// 日志默认输出到 stdout，log_to_file 时写入日志目录并轮转
+	logOutput := "stdout"
+	if settings.Serve.LogToFile {
+		logOutput = filepath.Join(config.LogDir(), "serve.log")
+	}
+
 	// 创建简化的开发配置
-	config := &server.Config{
-		Host: *host,
-		Port: *port,
-		Mode: *mode,
+	serverConfig := &server.Config{
+		Host: host,
+		Port: port,
+		Mode: settings.Serve.Mode,
 		Auth: server.AuthConfig{
 			APIKey: server.APIKeyConfig{
 				Enabled: false, // 开发模式默认不启用认证
@@ -98,24 +139,53 @@ func runServe(args []string) error {
 			Enabled: false, // 开发模式不启用速率限制
 		},
 		Logging: server.LoggingConfig{
-			Level:  "info",
-			Format: "text",
+			Level:    settings.Serve.LogLevel,
+			Format:   settings.Serve.LogFormat,
+			Output:   logOutput,
+			Rotation: logging.DefaultRotationConfig(),
+		},
+		Templates: server.TemplatesConfig{
+			Dir:   settings.Serve.TemplatesDir,
+			Watch: true,
+		},
+		Admin: server.AdminConfig{
+			Addr: settings.Serve.AdminAddr,
 		},
 	}
 
 	// 创建并启动 Server
-	srv, err := server.New(config, serverDeps)
+	var serverOpts []server.Option
+	if keys != nil {
+		serverOpts = append(serverOpts, server.WithKeyManager(keys))
+	}
+	srv, err := server.New(serverConfig, serverDeps, serverOpts...)
 	if err != nil {
 		return fmt.Errorf("create server: %w", err)
 	}
 
 	// 打印启动信息
-	printDevServerInfo(*host, *port)
+	printDevServerInfo(host, port)
+	if settings.Serve.LogToFile {
+		fmt.Printf("📝 Logs: %s\n\n", logOutput)
+	}
 
 	// 启动服务器（阻塞）
 	return srv.Start()
 }
 
+// keyCheckModels 返回健康检查使用的模型：每个 Provider 使用配置中引用的模型，默认模型优先
+func keyCheckModels(settings *config.Settings) map[string]string {
+	cfg := settings.AliasConfig()
+	models := make(map[string]string)
+	for _, m := range cfg.Aliases {
+		models[m.Provider] = m.Model
+	}
+	if m, ok := cfg.Aliases[cfg.Default]; ok {
+		models[m.Provider] = m.Model
+	}
+	return models
+}
+
 // registerBuiltinTemplates 注册内置模板
 func registerBuiltinTemplates(registry *agent.TemplateRegistry) {
 	registry.Register(&types.AgentTemplateDefinition{
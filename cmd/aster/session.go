@@ -3,20 +3,25 @@ package main
 import (
 	"bufio"
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"slices"
 	"strings"
 	"syscall"
 	"time"
 
 	"github.com/astercloud/aster/pkg/agent"
 	"github.com/astercloud/aster/pkg/config"
+	"github.com/astercloud/aster/pkg/dashboard"
+	"github.com/astercloud/aster/pkg/permission"
 	"github.com/astercloud/aster/pkg/provider"
 	"github.com/astercloud/aster/pkg/recipe"
+	"github.com/astercloud/aster/pkg/requestctx"
 	"github.com/astercloud/aster/pkg/router"
 	"github.com/astercloud/aster/pkg/sandbox"
 	"github.com/astercloud/aster/pkg/session"
@@ -24,6 +29,7 @@ import (
 	"github.com/astercloud/aster/pkg/store"
 	"github.com/astercloud/aster/pkg/tools"
 	"github.com/astercloud/aster/pkg/tools/builtin"
+	"github.com/astercloud/aster/pkg/tools/wasm"
 	"github.com/astercloud/aster/pkg/types"
 )
 
@@ -31,24 +37,54 @@ const (
 	colorReset  = "\033[0m"
 	colorGreen  = "\033[32m"
 	colorYellow = "\033[33m"
+	colorRed    = "\033[31m"
 	colorBlue   = "\033[34m"
 	colorCyan   = "\033[36m"
 	colorGray   = "\033[90m"
 	colorBold   = "\033[1m"
 )
 
+// defaultSessionModel aster session 在未配置 model 且使用默认 provider 时使用的模型
+const defaultSessionModel = "claude-sonnet-4-20250514"
+
 // runSession 启动交互式 CLI 会话
 func runSession(args []string) error {
+	if len(args) > 0 && args[0] == "replay" {
+		return runSessionReplay(args[1:])
+	}
+	if len(args) > 0 && args[0] == "follow" {
+		return runSessionFollow(args[1:])
+	}
+	if len(args) > 0 && args[0] == "export" {
+		return runSessionExport(args[1:])
+	}
+
+	settings, err := loadCLISettings()
+	if err != nil {
+		return err
+	}
+	// aster.yaml、环境变量和命令行都未指定模型时沿用会话一直以来的默认模型
+	useSessionModel := func() bool {
+		return settings.Source("model") == config.SettingSourceDefault && settings.Provider == config.DefaultSettings().Provider
+	}
+	modelDefault := settings.Model
+	if useSessionModel() {
+		modelDefault = defaultSessionModel
+	}
+
 	fs := flag.NewFlagSet("session", flag.ExitOnError)
 	recipeFile := fs.String("recipe", "", "Recipe file to use")
-	workDir := fs.String("dir", ".", "Working directory")
-	provider := fs.String("provider", "", "LLM provider (anthropic, openai, deepseek)")
-	model := fs.String("model", "", "Model name")
-	noColor := fs.Bool("no-color", false, "Disable colored output")
+	fs.String("dir", settings.Session.WorkDir, "Working directory")
+	fs.String("provider", settings.Provider, "LLM provider (anthropic, openai, deepseek)")
+	fs.String("model", modelDefault, "Model name")
+	fs.Bool("no-color", settings.Session.NoColor, "Disable colored output")
 
 	fs.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Usage: aster session [flags]\n\n")
-		fmt.Fprintf(os.Stderr, "Start an interactive AI agent session.\n\n")
+		fmt.Fprintf(os.Stderr, "Usage: aster session [flags]\n")
+		fmt.Fprintf(os.Stderr, "       aster session replay [flags] <session-id>\n")
+		fmt.Fprintf(os.Stderr, "       aster session follow [flags] <session-id>\n")
+		fmt.Fprintf(os.Stderr, "       aster session export [flags] <session-id>\n\n")
+		fmt.Fprintf(os.Stderr, "Start an interactive AI agent session, replay, follow or export a stored one.\n\n")
 		fmt.Fprintf(os.Stderr, "Flags:\n")
 		fs.PrintDefaults()
 		fmt.Fprintf(os.Stderr, "\nCommands during session:\n")
@@ -56,17 +92,30 @@ func runSession(args []string) error {
 		fmt.Fprintf(os.Stderr, "  /clear          Clear conversation history\n")
 		fmt.Fprintf(os.Stderr, "  /help           Show help\n")
 		fmt.Fprintf(os.Stderr, "  /status         Show agent status\n")
+		fmt.Fprintf(os.Stderr, "  /model [name]   Show or switch model (alias or provider/model)\n")
+		fmt.Fprintf(os.Stderr, "  /trust          Trust the working directory (enables shell and network tools)\n")
 	}
 
 	if err := fs.Parse(args); err != nil {
 		return err
 	}
+	if err := applyFlagOverrides(fs, settings, map[string]string{
+		"dir":      "session.work_dir",
+		"provider": "provider",
+		"model":    "model",
+		"no-color": "session.no_color",
+	}); err != nil {
+		return err
+	}
+	if useSessionModel() {
+		settings.Model = defaultSessionModel
+	}
 
 	// Disable colors if requested or not a terminal
-	useColor := !*noColor && isTerminal(os.Stdout)
+	useColor := !settings.Session.NoColor && isTerminal(os.Stdout)
 
 	// Resolve working directory
-	absWorkDir, err := filepath.Abs(*workDir)
+	absWorkDir, err := filepath.Abs(settings.Session.WorkDir)
 	if err != nil {
 		return fmt.Errorf("resolve working directory: %w", err)
 	}
@@ -105,18 +154,52 @@ func runSession(args []string) error {
 	}
 
 	// Build model config
-	modelConfig := buildModelConfig(*provider, *model, recipeConfig)
+	rt, err := settings.Router()
+	if err != nil {
+		return fmt.Errorf("build model router: %w", err)
+	}
+	modelConfig, err := buildModelConfig(settings, rt, recipeConfig)
+	if err != nil {
+		return err
+	}
+	reader := bufio.NewReader(os.Stdin)
 	if modelConfig.APIKey == "" {
-		return fmt.Errorf("API key not set. Please set %s_API_KEY environment variable", strings.ToUpper(modelConfig.Provider))
+		if !isTerminal(os.Stdin) {
+			return fmt.Errorf("API key not set. Please set %s environment variable or run 'aster setup'",
+				config.APIKeyEnvName(modelConfig.Provider))
+		}
+		// First run: ask for the key instead of failing
+		if modelConfig.APIKey, err = firstRunAPIKey(reader, modelConfig, useColor); err != nil {
+			return err
+		}
+		// Rebuild the router so aliases on the same provider pick up the stored key
+		if rt, err = settings.Router(); err != nil {
+			return fmt.Errorf("build model router: %w", err)
+		}
+	}
+
+	// Untrusted workspaces cannot run shell or network tools
+	trustStore, err := permission.DefaultTrustStore()
+	if err != nil {
+		return fmt.Errorf("load workspace trust: %w", err)
+	}
+	if err := confirmWorkspaceTrust(reader, trustStore, absWorkDir, useColor); err != nil {
+		return err
 	}
 
 	// Create agent dependencies
-	agentDeps := createAgentDependencies(dataStore, modelConfig)
+	agentDeps := createAgentDependencies(dataStore, rt)
+	agentDeps.TrustStore = trustStore
 
 	// Build agent config
 	agentConfig := &types.AgentConfig{
 		TemplateID:  "default",
 		ModelConfig: modelConfig,
+		Sandbox: &types.SandboxConfig{
+			Kind:       types.SandboxKindLocal,
+			WorkDir:    absWorkDir,
+			WatchFiles: true,
+		},
 		Metadata: map[string]any{
 			"work_dir": absWorkDir,
 		},
@@ -124,13 +207,23 @@ func runSession(args []string) error {
 
 	// Apply recipe settings
 	if recipeConfig != nil {
-		applyRecipeToConfig(recipeConfig, agentConfig, agentDeps)
+		if err := applyRecipeToConfig(recipeConfig, agentConfig, agentDeps); err != nil {
+			return fmt.Errorf("apply recipe: %w", err)
+		}
 	}
 
-	// Create context with cancellation
-	ctx, cancel := context.WithCancel(context.Background())
+	// Create context with cancellation; the local user, locale and a
+	// correlation ID attribute this session's events, tool calls and logs
+	ctx, cancel := context.WithCancel(requestctx.With(context.Background(), requestctx.Local()))
 	defer cancel()
 
+	// Load WASM tool plugins
+	plugins, err := loadWasmPlugins(ctx, agentConfig, agentDeps, useColor)
+	if err != nil {
+		return fmt.Errorf("load plugins: %w", err)
+	}
+	defer func() { _ = plugins.Close(context.Background()) }()
+
 	// Handle interrupt signals
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
@@ -167,64 +260,75 @@ func runSession(args []string) error {
 	}, nil)
 
 	// Start event handler
-	go handleAgentEvents(ctx, eventCh, useColor)
+	go handleAgentEvents(ctx, ag, eventCh, useColor)
 
 	// Print welcome message
 	printWelcome(useColor, modelConfig, recipeConfig, absWorkDir, sess.ID())
 
 	// Run REPL
-	return runREPL(ctx, ag, sessionStore, sess.ID(), useColor)
+	return runREPL(ctx, reader, ag, sessionStore, sess.ID(), useColor)
 }
 
-// buildModelConfig builds the model configuration
-func buildModelConfig(providerName, modelName string, recipeConfig *recipe.Recipe) *types.ModelConfig {
-	// Default values
-	if providerName == "" {
-		providerName = "anthropic"
-	}
-	if modelName == "" {
-		modelName = "claude-sonnet-4-20250514"
+// confirmWorkspaceTrust asks once whether to trust a workspace without a recorded decision
+func confirmWorkspaceTrust(reader *bufio.Reader, trustStore *permission.TrustStore, workDir string, useColor bool) error {
+	if trustStore.IsTrusted(workDir) || !isTerminal(os.Stdin) {
+		return nil
 	}
+	printColored(useColor, colorYellow, "🔒 Do you trust the files in %s?\n", workDir)
+	printColored(useColor, colorGray, "   Untrusted workspaces cannot run shell or network tools. Use /trust later to change this.\n")
+	fmt.Print("   Trust this folder? [y/N]: ")
 
-	// Override from recipe
-	if recipeConfig != nil && recipeConfig.Settings != nil {
-		if recipeConfig.Settings.Provider != "" {
-			providerName = recipeConfig.Settings.Provider
-		}
-		if recipeConfig.Settings.Model != "" {
-			modelName = recipeConfig.Settings.Model
-		}
+	answer, err := reader.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return fmt.Errorf("read input: %w", err)
 	}
-
-	// Get API key from environment
-	apiKey := getAPIKey(providerName)
-
-	return &types.ModelConfig{
-		Provider: providerName,
-		Model:    modelName,
-		APIKey:   apiKey,
+	if answer = strings.ToLower(strings.TrimSpace(answer)); answer != "y" && answer != "yes" {
+		return nil
+	}
+	if err := trustStore.SetLevel(workDir, permission.TrustLevelTrusted); err != nil {
+		return fmt.Errorf("save workspace trust: %w", err)
 	}
+	printColored(useColor, colorGreen, "✓ Workspace trusted\n")
+	return nil
 }
 
-// getAPIKey returns the API key for a provider
-func getAPIKey(providerName string) string {
-	envVars := map[string]string{
-		"anthropic": "ANTHROPIC_API_KEY",
-		"openai":    "OPENAI_API_KEY",
-		"deepseek":  "DEEPSEEK_API_KEY",
-		"google":    "GOOGLE_API_KEY",
+// buildModelConfig builds the model configuration
+// The default comes from the layered settings (model may be an alias);
+// recipe settings take precedence.
+func buildModelConfig(settings *config.Settings, rt *router.AliasRouter, recipeConfig *recipe.Recipe) (*types.ModelConfig, error) {
+	resolved, err := rt.SelectModel(context.Background(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("resolve model: %w", err)
 	}
+	modelConfig := *resolved
 
-	if envVar, ok := envVars[providerName]; ok {
-		return os.Getenv(envVar)
+	// Override from recipe
+	if recipeConfig != nil && recipeConfig.Settings != nil {
+		providerName := recipeConfig.Settings.Provider
+		modelName := recipeConfig.Settings.Model
+
+		if aliased, err := rt.Resolve(modelName); err == nil && providerName == "" {
+			modelConfig = *aliased
+		} else if providerName != "" || modelName != "" {
+			if providerName == "" {
+				providerName = modelConfig.Provider
+			}
+			if modelName == "" {
+				modelName = modelConfig.Model
+			}
+			modelConfig = types.ModelConfig{
+				Provider: providerName,
+				Model:    modelName,
+				APIKey:   settings.APIKey(providerName),
+			}
+		}
 	}
 
-	// Try generic format
-	return os.Getenv(strings.ToUpper(providerName) + "_API_KEY")
+	return &modelConfig, nil
 }
 
 // createAgentDependencies creates the agent dependencies
-func createAgentDependencies(dataStore *store.JSONStore, modelConfig *types.ModelConfig) *agent.Dependencies {
+func createAgentDependencies(dataStore *store.JSONStore, rt router.Router) *agent.Dependencies {
 	toolRegistry := tools.NewRegistry()
 	builtin.RegisterAll(toolRegistry)
 
@@ -233,11 +337,6 @@ func createAgentDependencies(dataStore *store.JSONStore, modelConfig *types.Mode
 	templateRegistry := agent.NewTemplateRegistry()
 	registerBuiltinTemplates(templateRegistry)
 
-	routes := []router.StaticRouteEntry{
-		{Task: "chat", Priority: router.PriorityQuality, Model: modelConfig},
-	}
-	rt := router.NewStaticRouter(modelConfig, routes)
-
 	return &agent.Dependencies{
 		Store:            dataStore,
 		ToolRegistry:     toolRegistry,
@@ -249,16 +348,92 @@ func createAgentDependencies(dataStore *store.JSONStore, modelConfig *types.Mode
 }
 
 // applyRecipeToConfig applies recipe settings to agent config
-func applyRecipeToConfig(r *recipe.Recipe, config *types.AgentConfig, deps *agent.Dependencies) {
+func applyRecipeToConfig(r *recipe.Recipe, config *types.AgentConfig, deps *agent.Dependencies) error {
 	if r.TemplateID != "" {
 		config.TemplateID = r.TemplateID
 	}
 
+	// Custom tools are registered for this session and enabled on top of the template's tools
+	names, err := r.RegisterCustomTools(deps.ToolRegistry)
+	if err != nil {
+		return err
+	}
+	enableTools(config, deps, names)
+
+	// Few-shot exemplars are injected ahead of the conversation on every request
+	if r.SeedConversation != nil {
+		config.SeedConversation = r.SeedConversation
+	}
+
 	// TODO: Apply tools filter, extensions, etc.
+	return nil
+}
+
+// loadWasmPlugins loads WASM tool plugins from the extensions directory and enables their tools.
+// Broken plugins are reported and skipped. The returned runtime must be closed by the caller.
+func loadWasmPlugins(ctx context.Context, agentConfig *types.AgentConfig, deps *agent.Dependencies, useColor bool) (*wasm.Runtime, error) {
+	rt, err := wasm.NewRuntime(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	plugins, err := rt.LoadDir(ctx, config.ExtensionsDir())
+	if err != nil {
+		printColored(useColor, colorYellow, "⚠️  Some plugins failed to load: %v\n", err)
+	}
+	for _, p := range plugins {
+		names, err := p.RegisterTools(deps.ToolRegistry)
+		if err != nil {
+			printColored(useColor, colorYellow, "⚠️  %v\n", err)
+		}
+		enableTools(agentConfig, deps, names)
+		printColored(useColor, colorCyan, "🧩 Loaded plugin: %s (%d tools)\n", p.Manifest.Name, len(names))
+	}
+	return rt, nil
+}
+
+// enableTools adds tools registered for this session on top of the template's tool list
+func enableTools(config *types.AgentConfig, deps *agent.Dependencies, names []string) {
+	if len(names) == 0 {
+		return
+	}
+	if config.Tools == nil {
+		config.Tools = templateToolNames(deps, config.TemplateID)
+	}
+	for _, name := range names {
+		if !slices.Contains(config.Tools, name) {
+			config.Tools = append(config.Tools, name)
+		}
+	}
+}
+
+// templateToolNames resolves the tool list of a template, as the agent does when config.Tools is nil
+func templateToolNames(deps *agent.Dependencies, templateID string) []string {
+	template, err := deps.TemplateRegistry.Get(templateID)
+	if err != nil {
+		return []string{}
+	}
+	switch v := template.Tools.(type) {
+	case []string:
+		return slices.Clone(v)
+	case []any:
+		names := make([]string, 0, len(v))
+		for _, item := range v {
+			if name, ok := item.(string); ok {
+				names = append(names, name)
+			}
+		}
+		return names
+	case string:
+		if v == "*" {
+			return deps.ToolRegistry.List()
+		}
+	}
+	return []string{}
 }
 
 // handleAgentEvents processes agent events and displays them
-func handleAgentEvents(ctx context.Context, eventCh <-chan types.AgentEventEnvelope, useColor bool) {
+func handleAgentEvents(ctx context.Context, ag *agent.Agent, eventCh <-chan types.AgentEventEnvelope, useColor bool) {
 	for {
 		select {
 		case <-ctx.Done():
@@ -308,6 +483,11 @@ func handleAgentEvents(ctx context.Context, eventCh <-chan types.AgentEventEnvel
 				// Note: In a real implementation, we'd wait for user input
 				// and send the decision back to the agent via e.Respond
 
+			case *types.ControlWorkspaceTrustRequiredEvent:
+				// The REPL cannot prompt mid-run; decline and let the user run /trust
+				printColored(useColor, colorYellow, "\n🔒 %s needs a trusted workspace; run /trust to allow shell and network tools in %s\n", e.Call.Name, e.WorkDir)
+				_ = ag.RespondToWorkspaceTrust(false)
+
 			case *types.MonitorErrorEvent:
 				printColored(useColor, colorYellow, "\n❌ Error: %s\n", e.Message)
 			}
@@ -316,9 +496,7 @@ func handleAgentEvents(ctx context.Context, eventCh <-chan types.AgentEventEnvel
 }
 
 // runREPL runs the read-eval-print loop
-func runREPL(ctx context.Context, ag *agent.Agent, sessionStore session.Service, sessionID string, useColor bool) error {
-	reader := bufio.NewReader(os.Stdin)
-
+func runREPL(ctx context.Context, reader *bufio.Reader, ag *agent.Agent, sessionStore session.Service, sessionID string, useColor bool) error {
 	for {
 		// Print prompt
 		printColored(useColor, colorBold+colorBlue, "\naster> ")
@@ -370,6 +548,40 @@ func runREPL(ctx context.Context, ag *agent.Agent, sessionStore session.Service,
 
 		// Wait for response to complete
 		waitForCompletion(ctx, ag)
+		printCostFooter(useColor, ag.SessionCost())
+	}
+}
+
+// printCostFooter prints the session cost HUD: tokens, cost and context usage,
+// colored by the warning thresholds from the budget config
+func printCostFooter(useColor bool, hud *types.MonitorSessionCostEvent) {
+	if hud.TotalTokens == 0 {
+		return
+	}
+	color := colorGray
+	switch hud.Level {
+	case types.CostLevelWarning:
+		color = colorYellow
+	case types.CostLevelCritical:
+		color = colorRed
+	}
+	line := fmt.Sprintf("\n── %s tokens · %s · context %.0f%%", formatTokenCount(hud.TotalTokens),
+		dashboard.FormatCost(dashboard.CostAmount{Amount: hud.Cost, Currency: hud.Currency}), hud.ContextPercent)
+	if hud.BudgetPercent > 0 {
+		line += fmt.Sprintf(" · budget %.0f%%", hud.BudgetPercent)
+	}
+	printColored(useColor, color, "%s ──\n", line)
+}
+
+// formatTokenCount formats a token count compactly, e.g. 12.3k
+func formatTokenCount(n int64) string {
+	switch {
+	case n >= 1_000_000:
+		return fmt.Sprintf("%.1fM", float64(n)/1_000_000)
+	case n >= 1_000:
+		return fmt.Sprintf("%.1fk", float64(n)/1_000)
+	default:
+		return fmt.Sprint(n)
 	}
 }
 
@@ -400,18 +612,131 @@ func handleCommand(ctx context.Context, cmd string, ag *agent.Agent, sessionStor
 		printColored(useColor, colorGray, "  ID: %s\n", status.AgentID)
 		printColored(useColor, colorGray, "  State: %s\n", status.State)
 		printColored(useColor, colorGray, "  Steps: %d\n", status.StepCount)
+		printColored(useColor, colorGray, "  Model: %s\n", status.Model)
+		printColored(useColor, colorGray, "  Context: %d / %d tokens (%d remaining)\n", status.ContextUsed, status.ContextWindow, status.ContextRemaining)
 		return true, nil
 
 	case "/session":
 		printColored(useColor, colorCyan, "Session ID: %s\n", sessionID)
 		return true, nil
 
+	case "/model":
+		if len(parts) < 2 {
+			printColored(useColor, colorCyan, "Model: %s\n", ag.Status().Model)
+			return true, nil
+		}
+		if err := ag.SwitchModel(ctx, parts[1], "cli"); err != nil {
+			printColored(useColor, colorYellow, "Switch model failed: %s\n", err)
+			return true, nil
+		}
+		printColored(useColor, colorGreen, "✓ Switched to %s\n", ag.Status().Model)
+		return true, nil
+
+	case "/trust":
+		if err := ag.RespondToWorkspaceTrust(true); err != nil {
+			printColored(useColor, colorYellow, "Trust workspace failed: %s\n", err)
+			return true, nil
+		}
+		printColored(useColor, colorGreen, "✓ Workspace trusted\n")
+		return true, nil
+
+	case "/undo":
+		result, err := ag.UndoLastTurn(ctx)
+		if errors.Is(err, agent.ErrNothingToUndo) {
+			printColored(useColor, colorYellow, "Nothing to undo\n")
+			return true, nil
+		}
+		if err != nil {
+			printColored(useColor, colorYellow, "Undo failed: %s\n", err)
+			return true, nil
+		}
+		prompt := result.Prompt
+		if len(prompt) > 60 {
+			prompt = prompt[:57] + "..."
+		}
+		printColored(useColor, colorGreen, "✓ Undid last turn: %s\n", prompt)
+		for _, path := range result.RestoredFiles {
+			printColored(useColor, colorGray, "  restored %s\n", path)
+		}
+		for _, path := range result.DeletedFiles {
+			printColored(useColor, colorGray, "  deleted  %s\n", path)
+		}
+		for path, msg := range result.FileErrors {
+			printColored(useColor, colorYellow, "  failed   %s: %s\n", path, msg)
+		}
+		return true, nil
+
+	case "/pin":
+		pinCommand(ctx, strings.TrimSpace(strings.TrimPrefix(cmd, parts[0])), ag, useColor)
+		return true, nil
+
+	case "/unpin":
+		if len(parts) < 2 {
+			printColored(useColor, colorYellow, "Usage: /unpin <id|all>\n")
+			return true, nil
+		}
+		var err error
+		if parts[1] == "all" {
+			err = ag.UnpinAll(ctx)
+		} else {
+			err = ag.Unpin(ctx, parts[1])
+		}
+		if err != nil {
+			printColored(useColor, colorYellow, "Unpin failed: %s\n", err)
+			return true, nil
+		}
+		printColored(useColor, colorGreen, "✓ Unpinned %s\n", parts[1])
+		return true, nil
+
 	default:
 		// Not a known command, let agent handle it (might be a slash command)
 		return false, nil
 	}
 }
 
+// pinCommand lists pins, or pins a file ("file <path>"), a requirement list
+// ("req <text>") or a note (any other text)
+func pinCommand(ctx context.Context, args string, ag *agent.Agent, useColor bool) {
+	if args == "" {
+		pins := ag.Pins()
+		if len(pins) == 0 {
+			printColored(useColor, colorGray, "Nothing pinned\n")
+			return
+		}
+		printColored(useColor, colorCyan, "Pinned context:\n")
+		for _, pin := range pins {
+			summary := pin.Path
+			if pin.Kind != agent.PinKindFile {
+				summary, _, _ = strings.Cut(pin.Content, "\n")
+			}
+			if len(summary) > 60 {
+				summary = summary[:57] + "..."
+			}
+			printColored(useColor, colorGray, "  [%s] %-12s %s\n", pin.ID, pin.Kind, summary)
+		}
+		return
+	}
+
+	item := agent.PinnedItem{Kind: agent.PinKindNote, Content: args}
+	if kind, rest, ok := strings.Cut(args, " "); ok {
+		switch kind {
+		case "file":
+			item = agent.PinnedItem{Kind: agent.PinKindFile, Path: strings.TrimSpace(rest)}
+		case "req", "requirements":
+			item = agent.PinnedItem{Kind: agent.PinKindRequirements, Content: rest}
+		}
+	}
+	pin, err := ag.Pin(ctx, item)
+	if err != nil {
+		printColored(useColor, colorYellow, "Pin failed: %s\n", err)
+		return
+	}
+	printColored(useColor, colorGreen, "✓ Pinned [%s] %s\n", pin.ID, pin.Kind)
+	if pin.Truncated {
+		printColored(useColor, colorYellow, "  file truncated to fit the pinned context limit\n")
+	}
+}
+
 // waitForCompletion waits for the agent to finish processing
 func waitForCompletion(ctx context.Context, ag *agent.Agent) {
 	for {
@@ -467,6 +792,11 @@ func printHelp(useColor bool) {
 		{"/help", "Show this help message"},
 		{"/status", "Show agent status"},
 		{"/session", "Show session ID"},
+		{"/model [name]", "Show or switch model (alias or provider/model)"},
+		{"/trust", "Trust the working directory"},
+		{"/undo", "Undo the last turn and its file edits"},
+		{"/pin [file|req]", "List pins, or pin a file, requirements or a note"},
+		{"/unpin <id|all>", "Remove pinned context"},
 	}
 
 	for _, c := range commands {
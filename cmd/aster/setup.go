@@ -0,0 +1,242 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/astercloud/aster/pkg/config"
+	"github.com/astercloud/aster/pkg/onboarding"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+// runSetup walks through first-run configuration: provider, model, API key
+// (validated with a test request and kept in the secret store) and the
+// default workspace.
+func runSetup(args []string) error {
+	fs := flag.NewFlagSet("setup", flag.ExitOnError)
+	providerName := fs.String("provider", "", "Provider to use (prompted when omitted)")
+	model := fs.String("model", "", "Model name (defaults to the provider's default model)")
+	apiKey := fs.String("api-key", "", "API key (prompted when omitted, which keeps it out of shell history)")
+	workspace := fs.String("workspace", "", "Default working directory for sessions")
+	skipValidation := fs.Bool("skip-validation", false, "Store the key without sending a test request")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: aster setup [flags]\n\n")
+		fmt.Fprintf(os.Stderr, "Choose a provider and model, validate and store the API key, and create the default configuration.\n")
+		fmt.Fprintf(os.Stderr, "API keys are kept in the system keychain when available, otherwise in %s.\n\n", config.SecretsFile())
+		fmt.Fprintf(os.Stderr, "Flags:\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	setup := onboarding.NewSetup()
+	status, err := setup.Status()
+	if err != nil {
+		return err
+	}
+	useColor := isTerminal(os.Stdout)
+	interactive := isTerminal(os.Stdin)
+	reader := bufio.NewReader(os.Stdin)
+
+	printColored(useColor, colorBold+colorCyan, "Aster setup\n")
+	if status.SettingsExists {
+		printColored(useColor, colorGray, "Settings: %s\n", status.SettingsFile)
+	} else {
+		printColored(useColor, colorGray, "Settings: %s (will be created)\n", status.SettingsFile)
+	}
+	if !status.NeedsSetup {
+		printColored(useColor, colorGray, "Current model: %s/%s (API key from %s)\n", status.Provider, status.Model, keySourceLabel(status.KeySource))
+	}
+	fmt.Println()
+
+	req := onboarding.Request{
+		Provider:  *providerName,
+		Model:     *model,
+		APIKey:    *apiKey,
+		Workspace: *workspace,
+	}
+	if req.Provider == "" {
+		if !interactive {
+			return errors.New("-provider is required when stdin is not a terminal")
+		}
+		if req.Provider, err = promptProvider(reader, status.Provider); err != nil {
+			return err
+		}
+	}
+	p, _ := onboarding.LookupProvider(req.Provider)
+	if req.Model == "" && interactive {
+		def := p.DefaultModel
+		if req.Provider == status.Provider {
+			def = status.Model
+		}
+		if req.Model, err = promptLine(reader, "Model", def); err != nil {
+			return err
+		}
+	}
+
+	if err := setupAPIKey(reader, setup, &req, p, interactive, *skipValidation, useColor); err != nil {
+		return err
+	}
+
+	if req.Workspace == "" && interactive {
+		fmt.Printf("Default workspace (leave blank to use the current directory of each session)\n")
+		if req.Workspace, err = promptLine(reader, "Workspace", status.Workspace); err != nil {
+			return err
+		}
+	}
+
+	// The key was validated above
+	req.SkipValidation = true
+	status, err = setup.Complete(context.Background(), req)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println()
+	printColored(useColor, colorGreen, "✓ Settings saved to %s\n", status.SettingsFile)
+	printColored(useColor, colorGreen, "✓ Model: %s/%s\n", status.Provider, status.Model)
+	if p.NeedsKey() {
+		printColored(useColor, colorGreen, "✓ API key from %s\n", keySourceLabel(status.KeySource))
+	}
+	if status.Workspace != "" {
+		printColored(useColor, colorGreen, "✓ Workspace: %s\n", status.Workspace)
+	}
+	fmt.Println("\nRun 'aster session' to start.")
+	return nil
+}
+
+// setupAPIKey asks for the provider's API key until one passes validation.
+// A key already set in the environment is used without asking.
+func setupAPIKey(reader *bufio.Reader, setup *onboarding.Setup, req *onboarding.Request, p onboarding.Provider, interactive, skipValidation, useColor bool) error {
+	fromFlag := req.APIKey != ""
+	for {
+		if p.NeedsKey() && req.APIKey == "" {
+			if config.ProviderAPIKey(req.Provider) != "" {
+				printColored(useColor, colorGray, "Using %s from the environment\n", p.KeyEnv)
+			} else {
+				if !interactive {
+					return fmt.Errorf("-api-key or %s is required when stdin is not a terminal", p.KeyEnv)
+				}
+				if p.KeyURL != "" {
+					printColored(useColor, colorGray, "Get a key at %s\n", p.KeyURL)
+				}
+				key, err := promptLine(reader, p.DisplayName+" API key", "")
+				if err != nil {
+					return err
+				}
+				if key == "" {
+					return onboarding.ErrAPIKeyRequired
+				}
+				req.APIKey = key
+			}
+		}
+		if skipValidation {
+			return nil
+		}
+
+		fmt.Print("Validating with a test request... ")
+		err := setup.ValidateKey(context.Background(), *req)
+		if err == nil {
+			printColored(useColor, colorGreen, "✓\n")
+			return nil
+		}
+		printColored(useColor, colorRed, "✗\n  %v\n", err)
+		if fromFlag || !interactive || req.APIKey == "" {
+			return err
+		}
+		retry, err := promptLine(reader, "Try another key? [Y/n]", "")
+		if err != nil {
+			return err
+		}
+		if retry = strings.ToLower(retry); retry == "n" || retry == "no" {
+			return errors.New("setup cancelled")
+		}
+		req.APIKey = ""
+	}
+}
+
+// firstRunAPIKey asks for the missing API key of the session's model,
+// validates it and keeps it in the secret store
+func firstRunAPIKey(reader *bufio.Reader, model *types.ModelConfig, useColor bool) (string, error) {
+	p, _ := onboarding.LookupProvider(model.Provider)
+	printColored(useColor, colorYellow, "🔑 No API key configured for %s.\n", p.DisplayName)
+	printColored(useColor, colorGray, "   It will be stored in the secret store; run 'aster setup' to change provider or model.\n")
+
+	setup := onboarding.NewSetup()
+	req := onboarding.Request{Provider: model.Provider, Model: model.Model}
+	if err := setupAPIKey(reader, setup, &req, p, true, false, useColor); err != nil {
+		return "", err
+	}
+	if err := setup.Secrets.Set(config.APIKeySecret(req.Provider), req.APIKey); err != nil {
+		return "", fmt.Errorf("store API key: %w", err)
+	}
+	return req.APIKey, nil
+}
+
+// promptProvider lists the onboarding providers and reads a choice by number or name
+func promptProvider(reader *bufio.Reader, current string) (string, error) {
+	def := ""
+	fmt.Println("Providers:")
+	for i, p := range onboarding.Providers {
+		marker := " "
+		if p.Name == current {
+			marker = "*"
+			def = strconv.Itoa(i + 1)
+		}
+		fmt.Printf("  %s %d) %-18s %s\n", marker, i+1, p.DisplayName, p.DefaultModel)
+	}
+	for {
+		answer, err := promptLine(reader, "Provider", def)
+		if err != nil {
+			return "", err
+		}
+		if n, err := strconv.Atoi(answer); err == nil && n >= 1 && n <= len(onboarding.Providers) {
+			return onboarding.Providers[n-1].Name, nil
+		}
+		if answer != "" {
+			return answer, nil
+		}
+	}
+}
+
+// promptLine reads a line, returning def when it is empty
+func promptLine(reader *bufio.Reader, label, def string) (string, error) {
+	if def != "" {
+		fmt.Printf("%s [%s]: ", label, def)
+	} else {
+		fmt.Printf("%s: ", label)
+	}
+	line, err := reader.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		if err == io.EOF {
+			return "", errors.New("setup cancelled")
+		}
+		return "", fmt.Errorf("read input: %w", err)
+	}
+	if line = strings.TrimSpace(line); line == "" {
+		return def, nil
+	}
+	return line, nil
+}
+
+// keySourceLabel describes where an API key was found
+func keySourceLabel(source string) string {
+	switch source {
+	case config.APIKeySourceEnv:
+		return "environment"
+	case config.APIKeySourceSettings:
+		return "aster.yaml"
+	case config.APIKeySourceSecrets:
+		return "secret store"
+	default:
+		return "nowhere"
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/astercloud/aster/pkg/agent"
+	"github.com/astercloud/aster/pkg/tools"
+	"github.com/astercloud/aster/pkg/tools/builtin"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+// runTemplate manages agent template definitions
+func runTemplate(args []string) error {
+	if len(args) == 0 || args[0] != "lint" {
+		fmt.Fprintf(os.Stderr, "Usage: aster template lint [flags] <file|dir>...\n")
+		if len(args) == 0 {
+			return errors.New("missing subcommand")
+		}
+		return fmt.Errorf("unknown template subcommand: %s", args[0])
+	}
+	return runTemplateLint(args[1:])
+}
+
+// runTemplateLint checks template files against the builtin tool registry
+func runTemplateLint(args []string) error {
+	fs := flag.NewFlagSet("template lint", flag.ExitOnError)
+	asJSON := fs.Bool("json", false, "Print reports as JSON")
+	strict := fs.Bool("strict", false, "Exit with an error on warnings too")
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: aster template lint [flags] <file|dir>...\n\n")
+		fmt.Fprintf(os.Stderr, "Check template definitions (YAML/JSON): tool references against the registry,\n")
+		fmt.Fprintf(os.Stderr, "unreachable prompt modules and missing permission configuration, and estimate\n")
+		fmt.Fprintf(os.Stderr, "the base system prompt token count. Exits non-zero on errors.\n\n")
+		fmt.Fprintf(os.Stderr, "Flags:\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errors.New("at least one template file or directory is required")
+	}
+
+	registry := tools.NewRegistry()
+	builtin.RegisterAll(registry)
+
+	results, err := lintPaths(fs.Args(), func(path string, data []byte) *types.LintReport {
+		t, err := agent.ParseTemplate(data, filepath.Ext(path))
+		if err != nil {
+			report := &types.LintReport{}
+			report.Add(types.LintError, "invalid_template", "%v", err)
+			return report
+		}
+		return agent.LintTemplate(t, agent.TemplateLintOptions{ToolRegistry: registry})
+	})
+	if err != nil {
+		return err
+	}
+	return printLintResults(results, *asJSON, *strict)
+}
@@ -4,10 +4,10 @@ import (
 	"context"
 	"fmt"
 	"os"
-	"strconv"
 	"time"
 
 	"github.com/astercloud/aster/pkg/agent"
+	"github.com/astercloud/aster/pkg/config"
 	"github.com/astercloud/aster/pkg/provider"
 	"github.com/astercloud/aster/pkg/sandbox"
 	"github.com/astercloud/aster/pkg/store"
@@ -29,24 +29,24 @@ type Config struct {
 
 func main() {
 	// 加载配置
-	config := loadConfig()
-	validateConfig(config)
+	cfg := loadConfig()
+	validateConfig(cfg)
 
 	fmt.Println("=== Aster AI Agent ===")
-	fmt.Printf("Model: %s\n", config.Model)
-	fmt.Printf("Base URL: %s\n", config.BaseURL)
-	fmt.Printf("Store: %s (max: %d messages)\n\n", config.StoreDir, config.StoreMaxMsg)
+	fmt.Printf("Model: %s\n", cfg.Model)
+	fmt.Printf("Base URL: %s\n", cfg.BaseURL)
+	fmt.Printf("Store: %s (max: %d messages)\n\n", cfg.StoreDir, cfg.StoreMaxMsg)
 
 	// 初始化组件
 	ctx := context.Background()
-	deps, err := initializeDependencies(config)
+	deps, err := initializeDependencies(cfg)
 	if err != nil {
 		fmt.Printf("❌ 初始化失败: %v\n", err)
 		os.Exit(1)
 	}
 
 	// 创建 Agent
-	ag, err := createAgent(ctx, config, deps)
+	ag, err := createAgent(ctx, cfg, deps)
 	if err != nil {
 		fmt.Printf("❌ 创建 Agent 失败: %v\n", err)
 		os.Exit(1)
@@ -69,15 +69,15 @@ func main() {
 // loadConfig 从环境变量加载配置
 func loadConfig() *Config {
 	return &Config{
-		APIKey:  getEnv("CLAUDE_API_KEY", ""),
-		BaseURL: getEnv("CLAUDE_BASE_URL", "https://api.anthropic.com"),
-		Model:   getEnv("CLAUDE_MODEL", "claude-sonnet-4-5-20250929"),
+		APIKey:  config.GetEnv("CLAUDE_API_KEY", ""),
+		BaseURL: config.GetEnv("CLAUDE_BASE_URL", "https://api.anthropic.com"),
+		Model:   config.GetEnv("CLAUDE_MODEL", "claude-sonnet-4-5-20250929"),
 
-		StoreDir:      getEnv("STORE_DIR", ".aster"),
-		StoreMaxMsg:   getEnvInt("STORE_MAX_MESSAGES", 20),
-		StoreAutoTrim: getEnvBool("STORE_AUTO_TRIM", true),
+		StoreDir:      config.GetEnv("STORE_DIR", ".aster"),
+		StoreMaxMsg:   config.GetEnvInt("STORE_MAX_MESSAGES", 20),
+		StoreAutoTrim: config.GetEnvBool("STORE_AUTO_TRIM", true),
 
-		SandboxWorkDir: getEnv("SANDBOX_WORK_DIR", "./workspace"),
+		SandboxWorkDir: config.GetEnv("SANDBOX_WORK_DIR", "./workspace"),
 	}
 }
 
@@ -197,30 +197,3 @@ func chat(ag *agent.Agent, message string) {
 
 	fmt.Printf("%s\n", result.Text)
 }
-
-// 辅助函数
-
-func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
-	}
-	return defaultValue
-}
-
-func getEnvInt(key string, defaultValue int) int {
-	if value := os.Getenv(key); value != "" {
-		if intVal, err := strconv.Atoi(value); err == nil {
-			return intVal
-		}
-	}
-	return defaultValue
-}
-
-func getEnvBool(key string, defaultValue bool) bool {
-	if value := os.Getenv(key); value != "" {
-		if boolVal, err := strconv.ParseBool(value); err == nil {
-			return boolVal
-		}
-	}
-	return defaultValue
-}
@@ -18,13 +18,16 @@ import (
 	"log"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"syscall"
 
 	"github.com/astercloud/aster/pkg/agent"
+	"github.com/astercloud/aster/pkg/config"
 	"github.com/astercloud/aster/pkg/desktop"
 	"github.com/astercloud/aster/pkg/permission"
 	"github.com/astercloud/aster/pkg/provider"
 	"github.com/astercloud/aster/pkg/sandbox"
+	"github.com/astercloud/aster/pkg/session/sqlite"
 	"github.com/astercloud/aster/pkg/store"
 	"github.com/astercloud/aster/pkg/tools"
 	"github.com/astercloud/aster/pkg/tools/builtin"
@@ -60,6 +63,7 @@ func main() {
 
 	// Create agent configuration
 	agentConfig := &types.AgentConfig{
+		AgentID:    "desktop-default", // Stable ID so history is found again after a restart
 		TemplateID: "default",
 		ModelConfig: &types.ModelConfig{
 			Provider: "anthropic",
@@ -82,6 +86,20 @@ func main() {
 		log.Fatalf("Failed to register agent: %v", err)
 	}
 
+	// Let windows create more agents from the same config with another template
+	app.SetAgentFactory(desktop.NewAgentFactory(deps, agentConfig))
+
+	// Keep conversation history in a local SQLite database
+	if err := config.EnsureDir(config.DataDir()); err != nil {
+		log.Fatalf("Failed to create data dir: %v", err)
+	}
+	sessions, err := sqlite.New(filepath.Join(config.DataDir(), "desktop-sessions.db"))
+	if err != nil {
+		log.Fatalf("Failed to open session store: %v", err)
+	}
+	defer func() { _ = sessions.Close() }()
+	app.SetSessionService(sessions)
+
 	// Start the app
 	if err := app.Start(ctx); err != nil {
 		log.Fatalf("Failed to start app: %v", err)
@@ -127,10 +145,18 @@ func main() {
 		fmt.Println("   POST /api/cancel   - Cancel operation")
 		fmt.Println("   POST /api/approve  - Respond to approval")
 		fmt.Println("   GET  /api/status   - Get agent status")
-		fmt.Println("   GET  /api/history  - Get conversation history")
+		fmt.Println("   GET  /api/history  - Get conversation history (?limit=&offset=&session_id=)")
+		fmt.Println("   DELETE /api/history - Archive history and start a new session")
 		fmt.Println("   GET  /api/config   - Get configuration")
 		fmt.Println("   POST /api/config   - Set configuration")
-		fmt.Println("   GET  /api/agents   - List agents")
+		fmt.Println("   GET  /api/agents   - List agents and templates")
+		fmt.Println("   POST /api/agents   - Create agent from template")
+		fmt.Println("   POST /api/windows  - Register window (bind agents via /api/windows/bind)")
+		fmt.Println("   GET  /api/connectivity - Online/offline state (POST to report)")
+		fmt.Println("   POST /api/ui/action    - Report an action on a rendered UI surface")
+		fmt.Println("   GET  /api/ui/catalogs  - UI component catalogs (/api/ui/catalogs/{id}[@version])")
+		fmt.Println("   POST /api/ui/export    - Export a UI surface as static HTML or React code")
+		fmt.Println("   POST /api/workspace/trust - Trust or decline the agent's workspace")
 		fmt.Println("   GET  /api/events   - SSE event stream")
 	}
 
@@ -160,10 +186,14 @@ func createDependencies(workDir string) *agent.Dependencies {
 	// Create provider factory
 	providerFactory := provider.NewMultiProviderFactory()
 
+	// Untrusted workspaces ask for trust before running shell or network tools
+	trustStore, _ := permission.DefaultTrustStore()
+
 	return &agent.Dependencies{
 		Store:           dataStore,
 		SandboxFactory:  sandboxFactory,
 		ToolRegistry:    toolRegistry,
 		ProviderFactory: providerFactory,
+		TrustStore:      trustStore,
 	}
 }
@@ -1,5 +1,6 @@
 // Logging 演示日志系统的使用，包括 StdoutTransport 和 FileTransport
-// 两种输出方式，支持 JSON 行格式日志输出。
+// 两种输出方式，支持 JSON 行格式日志输出；以及 console 格式、组件级别覆盖
+// 和关联 ID。
 package main
 
 import (
@@ -54,6 +55,16 @@ func main() {
 		"latency": 0.123,
 	})
 
+	// 4. console 格式 + 组件级别覆盖 + 关联 ID
+	consoleLogger := logging.NewLogger(logging.LevelInfo,
+		logging.NewStdoutTransportWithEncoder(logging.ConsoleEncoder{}))
+	if err := consoleLogger.SetLevelSpec("warn,Dashboard=debug"); err != nil {
+		panic(err)
+	}
+	runCtx := logging.WithCorrelation(ctx, logging.Correlation{SessionID: "agt-demo", TraceID: "agt-demo-1"})
+	consoleLogger.ForComponent("Dashboard").Debug(runCtx, "trace persisted", map[string]any{"spans": 3})
+	consoleLogger.ForComponent("AgentProcessor").Info(runCtx, "filtered out by warn level", nil)
+
 	// 刷新缓冲(如果有)
 	logging.Flush(ctx)
 	fileLogger.Flush(ctx)
@@ -159,12 +159,17 @@ func main() {
 		permMode = permission.ModeAutoApprove
 	case "always_ask":
 		permMode = permission.ModeAlwaysAsk
+	case "bypass":
+		// No approval prompts, but guardrails still block paths outside the
+		// workspace, credential files and excess destructive commands
+		permMode = permission.ModeBypass
 	}
 
 	inspector := permission.NewEnhancedInspector(&permission.EnhancedInspectorConfig{
 		Mode:          permMode,
 		SandboxConfig: sandboxConfig,
 		CanUseTool:    canUseTool,
+		Guardrails:    &permission.BypassGuardrails{WorkDir: *workspace},
 	})
 
 	// === 4. Setup Agent Dependencies ===
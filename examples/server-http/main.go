@@ -85,6 +85,7 @@ func main() {
 	mux := http.NewServeMux()
 	mux.Handle("/v1/agents/chat", srv.ChatHandler())
 	mux.Handle("/v1/agents/chat/stream", srv.ChatStreamHandler())
+	mux.Handle("/v1/agents/agui", srv.AGUIHandler("assistant"))
 
 	addr := ":8080"
 	s := &http.Server{
@@ -96,6 +97,7 @@ func main() {
 	fmt.Printf("HTTP server started at http://localhost%s\n", addr)
 	fmt.Println("POST /v1/agents/chat with JSON body for sync chat.")
 	fmt.Println("POST /v1/agents/chat/stream with JSON body for SSE streaming.")
+	fmt.Println("POST /v1/agents/agui with an AG-UI RunAgentInput for AG-UI compatible frontends.")
 
 	if err := s.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 		log.Fatalf("HTTP server failed: %v", err)
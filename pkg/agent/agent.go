@@ -1,6 +1,7 @@
 package agent
 
 import (
+	"cmp"
 	"context"
 	"errors"
 	"fmt"
@@ -11,9 +12,14 @@ import (
 	"sync"
 	"time"
 
+	"github.com/astercloud/aster/pkg/agenterr"
+	"github.com/astercloud/aster/pkg/browser"
 	"github.com/astercloud/aster/pkg/commands"
 	"github.com/astercloud/aster/pkg/events"
+	"github.com/astercloud/aster/pkg/guardrails"
+	"github.com/astercloud/aster/pkg/identity"
 	"github.com/astercloud/aster/pkg/logging"
+	"github.com/astercloud/aster/pkg/lsp"
 	"github.com/astercloud/aster/pkg/memory"
 	"github.com/astercloud/aster/pkg/middleware"
 	"github.com/astercloud/aster/pkg/multitenancy"
@@ -21,10 +27,12 @@ import (
 	"github.com/astercloud/aster/pkg/provider"
 	"github.com/astercloud/aster/pkg/router"
 	"github.com/astercloud/aster/pkg/sandbox"
+	"github.com/astercloud/aster/pkg/session"
 	"github.com/astercloud/aster/pkg/skills"
 	"github.com/astercloud/aster/pkg/tools"
 	"github.com/astercloud/aster/pkg/tools/builtin"
 	"github.com/astercloud/aster/pkg/types"
+	"github.com/astercloud/aster/pkg/uiproto"
 	"github.com/astercloud/aster/pkg/vector"
 	"github.com/google/uuid"
 )
@@ -46,6 +54,20 @@ type Agent struct {
 	executor *tools.Executor
 	toolMap  map[string]tools.Tool
 
+	// 模型管理：provider/modelConfig 可被 SwitchModel 热替换，由 modelMu 保护
+	modelMu       sync.RWMutex
+	modelConfig   *types.ModelConfig
+	taskProviders map[string]taskProviderEntry
+	escalation    *modelEscalator
+	loopGuard     *loopGuard
+	contextUsage  contextTracker
+	// modelSelected 用户通过 SwitchModel 选择过主模型，此后不再使用任务覆盖
+	modelSelected bool
+	// summarizeProvider summarization 中间件的任务覆盖 Provider，Agent 关闭时释放
+	summarizeProvider provider.Provider
+	// retiredProviders 切换模型或任务覆盖变化后被替换的 Provider，可能仍被进行中的调用持有，Agent 关闭时统一释放
+	retiredProviders []provider.Provider
+
 	// Middleware 支持 (Phase 6C)
 	middlewareStack *middleware.Stack
 
@@ -56,6 +78,10 @@ type Agent struct {
 	// RAG 和语义记忆支持
 	semanticMemory *memory.SemanticMemory
 
+	// 会话事件持久化（WithSessionService）
+	sessions  session.Service
+	sessionID string
+
 	// 状态管理
 	mu                  sync.RWMutex
 	state               types.AgentRuntimeState
@@ -71,19 +97,84 @@ type Agent struct {
 	lastBookmark        *types.Bookmark
 	createdAt           time.Time
 
+	// 运行限制（MaxTurns/MaxOutputTokens/MaxDuration）
+	run             runState                // 当前运行的用量
+	lastTermination types.TerminationReason // 最近一次运行的终止原因
+	lastSummary     string                  // 最近一次运行的部分结果摘要
+	lastError       *agenterr.Error         // 最近一次运行的结构化错误
+
+	// 会话标题（AutoTitle）
+	title        string
+	titlePending bool
+
 	// 权限管理
 	pendingPermissions  map[string]chan string        // callID -> decision channel
 	permissionInspector *permission.EnhancedInspector // Claude SDK 风格的权限检查器
+	trust               *workspaceTrust               // 工作区信任（未配置 TrustStore 时为 nil）
 
 	// Plan 模式管理
 	planMode *PlanModeManager
 
+	// RenderUI 工具创建的 Surface
+	uiSurfaces *uiproto.SurfaceManager
+
+	// Watch 工具创建的文件监听订阅
+	fileWatches *sandbox.WatchManager
+
+	// Read/Write/Edit 工具记录的文件内容摘要，用于检测写入冲突
+	fileVersions *sandbox.FileVersions
+
+	// 会话隔离工作目录（未启用隔离时为 nil）
+	isolatedWorkDir *sandbox.IsolatedWorkDir
+
+	// 代码智能工具使用的语言服务器（未启用时为 nil）
+	lspManager *lsp.Manager
+
+	// Browser 工具使用的浏览器（未启用时为 nil）
+	browserManager *browser.Manager
+
+	// 工具产出物的保存入口（未配置产出物存储时为 nil）
+	artifacts *artifactSink
+
+	// Agent 身份的签名者（未配置身份注册表时为 nil）
+	signer *identity.Signer
+
+	// 模板配置的内容安全过滤器（未配置时为 nil）
+	preSendFilter    *guardrails.Filter
+	preDisplayFilter *guardrails.Filter
+
 	// 执行计划管理
 	executionPlanMgr *ExecutionPlanManager
 
+	// 后台运行的工具任务（taskID -> 任务），由 mu 保护
+	backgroundTasks map[string]*types.BackgroundTask
+
+	// 最近几轮对话的文件检查点，供 UndoLastTurn 使用，由 mu 保护
+	turnCheckpoints []*turnCheckpoint
+
+	// 固定在上下文中的内容，由 mu 保护
+	pins []PinnedItem
+
+	// 每次请求注入在对话之前的示例对话；seedDropped 由 mu 保护
+	seed        *seedConversation
+	seedDropped bool
+
+	// 执行中途收到的引导消息
+	steering steerState
+
+	// 计划模式下已审批或拒绝的计划结果，随下一条用户消息告知模型；由 mu 保护
+	planNotices []string
+
+	// 工具白名单与黑名单，可在运行时修改
+	toolFilter *permission.ToolFilter
+
+	// 会话累计用量与费用，用于成本 HUD
+	sessionCost sessionCost
+
 	// 控制信号
 	stopCh              chan struct{}
 	iterationContinueCh chan bool // 迭代限制确认 channel
+	loopGuardCh         chan bool // 循环保护暂停确认 channel
 }
 
 // runningToolHandle 保存可中断工具的句柄
@@ -91,8 +182,34 @@ type runningToolHandle struct {
 	interruptible tools.Interruptible
 }
 
-// Create 创建新Agent
-func Create(ctx context.Context, config *types.AgentConfig, deps *Dependencies) (*Agent, error) {
+// newEventBus 创建 Agent 的事件总线，配置了 EventTransport 时同时按 Agent/租户发布到外部
+func newEventBus(config *types.AgentConfig, deps *Dependencies, sinks []EventSink) *events.EventBus {
+	if deps.EventTransport == nil && deps.Audit == nil && deps.Faults == nil && len(sinks) == 0 {
+		return events.NewEventBus()
+	}
+	busConfig := events.DefaultEventBusConfig()
+	if deps.EventTransport != nil {
+		busConfig.Transport = deps.EventTransport
+		busConfig.Source = events.EventSource{AgentID: config.AgentID}
+		if config.Multitenancy != nil && config.Multitenancy.Enabled {
+			busConfig.Source.TenantID = config.Multitenancy.TenantID
+		}
+	}
+	if deps.Audit != nil {
+		sinks = append([]EventSink{auditEventObserver(deps.Audit, config)}, sinks...)
+	}
+	busConfig.Observer = observer(sinks...)
+	if deps.Faults != nil {
+		busConfig.Drop = deps.Faults.DropEvent
+	}
+	return events.NewEventBusWithConfig(busConfig)
+}
+
+// Create 创建新Agent，opts 为只对该 Agent 生效的可选配置（如 WithTools、WithEventSink）
+func Create(ctx context.Context, config *types.AgentConfig, deps *Dependencies, opts ...CreateOption) (*Agent, error) {
+	options := newCreateOptions(opts)
+	deps = options.applyDeps(deps)
+
 	// 生成AgentID
 	if config.AgentID == "" {
 		config.AgentID = generateAgentID()
@@ -115,11 +232,29 @@ func Create(ctx context.Context, config *types.AgentConfig, deps *Dependencies)
 		}
 	}
 
+	// A/B 实验分组，可能替换模板和模型
+	if deps.Experiments != nil {
+		applyExperiment(ctx, config, deps.Experiments)
+	}
+
+	// 故障注入：在本 Agent 的依赖副本上包装 Provider 工厂和 Store，不影响共享的依赖
+	if deps.Faults != nil {
+		faulty := *deps
+		faulty.ProviderFactory = deps.Faults.WrapProviderFactory(deps.ProviderFactory)
+		if deps.Store != nil {
+			faulty.Store = deps.Faults.WrapStore(deps.Store)
+		}
+		deps = &faulty
+	}
+
 	// 获取模板
 	template, err := deps.TemplateRegistry.Get(config.TemplateID)
 	if err != nil {
 		return nil, fmt.Errorf("get template: %w", err)
 	}
+	// 复制模板：构建的 System Prompt（含固定内容）属于单个 Agent，不能写回注册表中共享的模板
+	templateCopy := *template
+	template = &templateCopy
 
 	// 创建Provider（支持可选 Router）
 	modelConfig := config.ModelConfig
@@ -178,16 +313,33 @@ func Create(ctx context.Context, config *types.AgentConfig, deps *Dependencies)
 		}
 	}
 
+	// 会话工作目录隔离：沙箱改用会话自己的子目录或 worktree
+	var isolatedDir *sandbox.IsolatedWorkDir
+	if iso := sandboxConfig.Isolation; iso != nil && iso.Mode != types.WorkDirIsolationNone &&
+		(sandboxConfig.Kind == types.SandboxKindLocal || sandboxConfig.Kind == types.SandboxKindDocker) {
+		isolatedDir, err = sandbox.IsolateWorkDir(ctx, sandboxConfig.WorkDir, config.AgentID, iso)
+		if err != nil {
+			return nil, fmt.Errorf("isolate work dir: %w", err)
+		}
+		isolatedConfig := *sandboxConfig
+		isolatedConfig.WorkDir = isolatedDir.Path
+		sandboxConfig = &isolatedConfig
+	}
+
 	sb, err := deps.SandboxFactory.Create(sandboxConfig)
 	if err != nil {
 		return nil, fmt.Errorf("create sandbox: %w", err)
 	}
 
 	// 创建工具执行器
-	executor := tools.NewExecutor(tools.ExecutorConfig{
+	executorConfig := tools.ExecutorConfig{
 		MaxConcurrency: 3,
 		DefaultTimeout: 60 * time.Second,
-	})
+	}
+	if deps.Faults != nil {
+		executorConfig.Fault = deps.Faults.ToolFault
+	}
+	executor := tools.NewExecutor(executorConfig)
 
 	// 解析工具列表
 	toolNames := config.Tools
@@ -241,10 +393,7 @@ func Create(ctx context.Context, config *types.AgentConfig, deps *Dependencies)
 			// 创建嵌入器
 			var embedder vector.Embedder
 			if config.Memory.Embedder != nil {
-				embedder, err = deps.EmbedderFactory.Create(
-					config.Memory.Embedder.Provider,
-					config.Memory.Embedder.Config,
-				)
+				embedder, err = deps.EmbedderFactory.CreateFromConfig(config.Memory.Embedder)
 				if err != nil {
 					agentLog.Warn(ctx, "failed to create embedder", map[string]any{"error": err})
 				}
@@ -384,6 +533,8 @@ func Create(ctx context.Context, config *types.AgentConfig, deps *Dependencies)
 		}
 	}
 
+	// summarization 中间件的任务覆盖 Provider，由 Agent 持有并在关闭时释放
+	var summarizeProv provider.Provider
 	if len(middlewareNames) > 0 {
 		middlewareList := make([]middleware.Middleware, 0, len(middlewareNames))
 		for _, name := range middlewareNames {
@@ -394,8 +545,15 @@ func Create(ctx context.Context, config *types.AgentConfig, deps *Dependencies)
 				}
 			}
 
+			mwProvider := prov
+			if name == "summarization" {
+				if summarizeProv = createTaskProvider(ctx, deps, router.TaskSummarize); summarizeProv != nil {
+					mwProvider = summarizeProv
+				}
+			}
+
 			mw, err := middleware.DefaultRegistry.Create(name, &middleware.MiddlewareFactoryConfig{
-				Provider:     prov,
+				Provider:     mwProvider,
 				AgentID:      config.AgentID,
 				Metadata:     config.Metadata,
 				Sandbox:      sb,
@@ -431,21 +589,32 @@ func Create(ctx context.Context, config *types.AgentConfig, deps *Dependencies)
 		}
 	}
 
+	// 选项追加的工具实例，同名时覆盖模板和中间件提供的工具
+	for _, tool := range options.tools {
+		toolMap[tool.Name()] = tool
+	}
+
 	// 创建Agent
 	agent := &Agent{
 		id:                  config.AgentID,
 		template:            template,
 		config:              config,
 		deps:                deps,
-		eventBus:            events.NewEventBus(),
+		eventBus:            newEventBus(config, deps, options.eventSinks),
 		provider:            prov,
+		modelConfig:         modelConfig,
+		taskProviders:       make(map[string]taskProviderEntry),
+		summarizeProvider:   summarizeProv,
 		sandbox:             sb,
+		isolatedWorkDir:     isolatedDir,
 		executor:            executor,
 		toolMap:             toolMap,
 		middlewareStack:     middlewareStack,
 		commandExecutor:     cmdExecutor,
 		skillInjector:       skillInjector,
 		semanticMemory:      semanticMem,
+		sessions:            options.sessions,
+		sessionID:           options.sessionID,
 		state:               types.AgentStateReady,
 		breakpoint:          types.BreakpointReady,
 		messages:            []types.Message{},
@@ -457,21 +626,60 @@ func Create(ctx context.Context, config *types.AgentConfig, deps *Dependencies)
 		createdAt:           time.Now(),
 		stopCh:              make(chan struct{}),
 		iterationContinueCh: make(chan bool, 1),
+		loopGuardCh:         make(chan bool, 1),
+	}
+	agent.uiSurfaces = agent.newUISurfaceManager()
+	agent.fileWatches = agent.newWatchManager()
+	agent.fileVersions = sandbox.NewFileVersions()
+	agent.lspManager = agent.newLSPManager(config.LSP)
+	agent.browserManager = agent.newBrowserManager(config.Browser, sandboxConfig)
+	if err := agent.initIdentity(ctx); err != nil {
+		return nil, err
+	}
+	agent.artifacts = agent.newArtifactSink()
+	agent.initAudit()
+	if err := agent.initSafety(); err != nil {
+		return nil, err
+	}
+	if reporter, ok := agent.sandbox.(sandbox.ResourceReporter); ok {
+		reporter.OnResourceEvent(func(e sandbox.ResourceEvent) {
+			agent.eventBus.EmitMonitor(e.ToMonitorEvent())
+		})
 	}
 
 	// 初始化 EnhancedInspector (Claude SDK 风格的权限检查器)
 	permMode := permission.ModeSmartApprove
 	if sandboxConfig != nil && sandboxConfig.PermissionMode == types.SandboxPermissionBypass {
-		permMode = permission.ModeAutoApprove
+		permMode = permission.ModeBypass
 	}
 	agent.permissionInspector = permission.NewEnhancedInspector(&permission.EnhancedInspectorConfig{
 		Mode:          permMode,
 		SandboxConfig: sandboxConfig,
 		CanUseTool:    config.CanUseTool,
+		Guardrails:    &permission.BypassGuardrails{WorkDir: sb.WorkDir()},
 	})
+	if agent.toolFilter, err = newToolFilter(template, config); err != nil {
+		return nil, err
+	}
+	if err := agent.initWorkspaceTrust(sandboxConfig); err != nil {
+		return nil, err
+	}
 	agentLog.Debug(ctx, "permission inspector created", map[string]any{"mode": permMode})
 
+	// 模型升级策略：从阶梯第一级起步
+	if err := agent.initEscalation(ctx); err != nil {
+		return nil, err
+	}
+	if config.LoopGuard != nil && config.LoopGuard.Enabled {
+		agent.loopGuard = newLoopGuard(config.LoopGuard)
+	}
+	var skipped int
+	if agent.seed, skipped = newSeedConversation(config.SeedConversation); skipped > 0 {
+		agentLog.Warn(ctx, "seed conversation turns skipped", map[string]any{"agent_id": agent.id, "skipped": skipped, "max_tokens": config.SeedConversation.MaxTokens})
+	}
+
 	// 使用 PromptBuilder 构建 System Prompt（在初始化之前，因为 initialize 会保存信息）
+	agent.loadPins(ctx)
 	if err := agent.buildSystemPrompt(ctx); err != nil {
 		return nil, fmt.Errorf("build system prompt: %w", err)
 	}
@@ -518,9 +726,12 @@ func (a *Agent) initialize(ctx context.Context) error {
 		}
 	}
 
+	// 恢复重启前未送达结果的后台任务
+	a.restoreBackgroundTasks(ctx)
+
 	// 注意：工具手册已在 Agent 创建时注入，这里不再重复注入
 
-	// 保存Agent信息
+	// 保存Agent信息（保留已有的元数据，例如会话标题）
 	info := types.AgentInfo{
 		AgentID:       a.id,
 		TemplateID:    a.template.ID,
@@ -529,6 +740,10 @@ func (a *Agent) initialize(ctx context.Context) error {
 		ConfigVersion: "v1.0.0",
 		MessageCount:  len(a.messages),
 	}
+	if existing, err := a.deps.Store.LoadInfo(ctx, a.id); err == nil && existing.Metadata != nil {
+		info.Metadata = existing.Metadata
+		a.title, _ = existing.Metadata[titleMetadataKey].(string)
+	}
 
 	if err := a.deps.Store.SaveInfo(ctx, a.id, info); err != nil {
 		return err
@@ -554,23 +769,10 @@ func (a *Agent) buildSystemPrompt(ctx context.Context) error {
 		builder = NewPromptBuilder()
 	}
 
-	// 添加基础模块
-	builder.AddModule(&BasePromptModule{})
-
-	// 添加能力说明模块（如果启用）
-	builder.AddModule(&CapabilitiesModule{})
-
-	// 添加专业客观性模块
-	builder.AddModule(&ProfessionalObjectivityModule{})
-
-	// 添加简洁性模块
-	builder.AddModule(&ConcisenessModule{})
-
-	// 添加避免过度工程化模块
-	builder.AddModule(&AvoidOverEngineeringModule{})
-
-	// 添加规划指南模块
-	builder.AddModule(&PlanningWithoutTimelinesModule{})
+	// 添加由模板决定的内置模块
+	for _, module := range builtinPromptModules(a.template) {
+		builder.AddModule(module)
+	}
 
 	// 收集环境信息
 	workDir := "."
@@ -579,38 +781,6 @@ func (a *Agent) buildSystemPrompt(ctx context.Context) error {
 	}
 	envInfo := collectEnvironmentInfo(ctx, workDir, a.createdAt)
 
-	// 添加环境信息模块
-	builder.AddModule(&EnvironmentModule{})
-
-	// 添加沙箱信息模块
-	builder.AddModule(&SandboxModule{})
-
-	// 添加工具手册模块
-	var toolsManualConfig *types.ToolsManualConfig
-	if a.template.Runtime != nil && a.template.Runtime.ToolsManual != nil {
-		toolsManualConfig = a.template.Runtime.ToolsManual
-	}
-	builder.AddModule(&ToolsManualModule{Config: toolsManualConfig})
-
-	// 添加 Todo 提醒模块
-	var todoConfig *types.TodoConfig
-	if a.template.Runtime != nil && a.template.Runtime.Todo != nil {
-		todoConfig = a.template.Runtime.Todo
-	}
-	builder.AddModule(&TodoReminderModule{Config: todoConfig})
-
-	// 添加代码引用模块
-	builder.AddModule(&CodeReferenceModule{})
-
-	// 添加 Git 安全模块
-	builder.AddModule(&GitSafetyModule{})
-
-	// 添加安全策略模块
-	builder.AddModule(&SecurityModule{})
-
-	// 添加性能优化模块
-	builder.AddModule(&PerformanceModule{})
-
 	// 添加协作模块（如果在 Room 中）
 	if roomInfo := a.extractRoomInfo(); roomInfo != nil {
 		builder.AddModule(&CollaborationModule{RoomInfo: roomInfo})
@@ -626,8 +796,13 @@ func (a *Agent) buildSystemPrompt(ctx context.Context) error {
 		builder.AddModule(&CustomInstructionsModule{Instructions: customInstructions})
 	}
 
-	// 添加限制说明模块
-	builder.AddModule(&LimitationsModule{})
+	// 添加会话摘要模块（恢复或分叉会话时）
+	if a.config.SeedSummary != nil {
+		builder.AddModule(&SessionSummaryModule{Summary: a.config.SeedSummary})
+	}
+
+	// 添加固定内容模块
+	builder.AddModule(&PinnedContextModule{Pins: a.pins})
 
 	// 添加上下文窗口管理模块
 	if contextConfig := a.extractContextWindowConfig(); contextConfig != nil {
@@ -637,6 +812,11 @@ func (a *Agent) buildSystemPrompt(ctx context.Context) error {
 		})
 	}
 
+	// 添加依赖中注册的额外模块
+	for _, module := range a.deps.PromptModules {
+		builder.AddModule(module)
+	}
+
 	// 收集沙箱信息
 	var sandboxInfo *SandboxInfo
 	if a.sandbox != nil && a.config.Sandbox != nil {
@@ -673,6 +853,34 @@ func (a *Agent) buildSystemPrompt(ctx context.Context) error {
 	return nil
 }
 
+// builtinPromptModules 返回只依赖模板配置的内置模块，
+// 协作、工作流、会话摘要等依赖会话状态的模块不在其中
+func builtinPromptModules(template *types.AgentTemplateDefinition) []PromptModule {
+	var toolsManualConfig *types.ToolsManualConfig
+	var todoConfig *types.TodoConfig
+	if template.Runtime != nil {
+		toolsManualConfig = template.Runtime.ToolsManual
+		todoConfig = template.Runtime.Todo
+	}
+	return []PromptModule{
+		&BasePromptModule{},
+		&CapabilitiesModule{},
+		&ProfessionalObjectivityModule{},
+		&ConcisenessModule{},
+		&AvoidOverEngineeringModule{},
+		&PlanningWithoutTimelinesModule{},
+		&EnvironmentModule{},
+		&SandboxModule{},
+		&ToolsManualModule{Config: toolsManualConfig},
+		&TodoReminderModule{Config: todoConfig},
+		&CodeReferenceModule{},
+		&GitSafetyModule{},
+		&SecurityModule{},
+		&PerformanceModule{},
+		&LimitationsModule{},
+	}
+}
+
 // extractRoomInfo 提取 Room 协作信息
 func (a *Agent) extractRoomInfo() *RoomCollaborationInfo {
 	if a.config.Metadata == nil {
@@ -889,6 +1097,11 @@ func (a *Agent) ID() string {
 	return a.id
 }
 
+// Sandbox 返回 Agent 使用的沙箱，测试中可断言为 *sandbox.MockSandbox 检查执行记录
+func (a *Agent) Sandbox() sandbox.Sandbox {
+	return a.sandbox
+}
+
 // ExecutionPlan 返回执行计划管理器
 // 首次调用时延迟初始化
 func (a *Agent) ExecutionPlan() *ExecutionPlanManager {
@@ -911,6 +1124,15 @@ func (a *Agent) Send(ctx context.Context, text string) error {
 		return a.handleSlashCommand(ctx, text)
 	}
 
+	// 内容安全：发送前检查
+	text, err := a.filterUserText(ctx, text)
+	if err != nil {
+		return err
+	}
+
+	// 模型升级：检测用户重试（升级在本轮模型调用前生效）
+	a.observeUserMessage(ctx, text)
+
 	// 准备消息内容
 	messageText := text
 
@@ -923,29 +1145,34 @@ func (a *Agent) Send(ctx context.Context, text string) error {
 		}
 
 		// 增强 system prompt（对于支持的模型）
-		caps := a.provider.Capabilities()
+		prov := a.currentProvider()
+		caps := prov.Capabilities()
 		if caps.SupportSystemPrompt {
 			enhancedSysPrompt := a.skillInjector.EnhanceSystemPrompt(
 				ctx,
 				a.template.SystemPrompt,
 				skillContext,
 			)
-			_ = a.provider.SetSystemPrompt(enhancedSysPrompt)
+			_ = prov.SetSystemPrompt(enhancedSysPrompt)
 		} else {
 			// 不支持 system prompt，增强 user message
 			messageText = a.skillInjector.PrepareUserMessage(text, skillContext)
 		}
 	}
 
-	// 创建用户消息
+	// 创建用户消息，附带上一轮之后的外部文件变更
+	blocks := []types.ContentBlock{&types.TextBlock{Text: messageText}}
+	if notice := a.fileChangeNotice(); notice != nil {
+		blocks = append([]types.ContentBlock{notice}, blocks...)
+	}
+	blocks = append(a.planNoticeBlocksLocked(), blocks...)
 	message := types.Message{
-		Role: types.MessageRoleUser,
-		ContentBlocks: []types.ContentBlock{
-			&types.TextBlock{Text: messageText},
-		},
+		Role:          types.MessageRoleUser,
+		ContentBlocks: blocks,
 	}
 
 	a.messages = append(a.messages, message)
+	a.checkpointTurn(message)
 
 	// ✅ 修复：保存前在内存中修剪
 	if a.shouldTrimMessages() {
@@ -960,7 +1187,7 @@ func (a *Agent) Send(ctx context.Context, text string) error {
 	a.stepCount++
 
 	// 持久化（已修剪的消息）
-	if err := a.deps.Store.SaveMessages(ctx, a.id, a.messages); err != nil {
+	if err := a.deps.Store.SaveMessages(ctx, a.id, a.messagesForStore(a.messages)); err != nil {
 		return fmt.Errorf("save messages: %w", err)
 	}
 
@@ -975,6 +1202,12 @@ func (a *Agent) SendWithContent(ctx context.Context, blocks []types.ContentBlock
 	a.mu.Lock()
 	defer a.mu.Unlock()
 
+	// 内容安全：发送前检查
+	blocks, err := a.filterUserBlocks(ctx, blocks)
+	if err != nil {
+		return err
+	}
+
 	// 创建用户消息
 	message := types.Message{
 		Role:          types.MessageRoleUser,
@@ -982,6 +1215,7 @@ func (a *Agent) SendWithContent(ctx context.Context, blocks []types.ContentBlock
 	}
 
 	a.messages = append(a.messages, message)
+	a.checkpointTurn(message)
 
 	// ✅ 修复：保存前在内存中修剪
 	if a.shouldTrimMessages() {
@@ -996,7 +1230,7 @@ func (a *Agent) SendWithContent(ctx context.Context, blocks []types.ContentBlock
 	a.stepCount++
 
 	// 持久化（已修剪的消息）
-	if err := a.deps.Store.SaveMessages(ctx, a.id, a.messages); err != nil {
+	if err := a.deps.Store.SaveMessages(ctx, a.id, a.messagesForStore(a.messages)); err != nil {
 		return fmt.Errorf("save multimodal messages: %w", err)
 	}
 
@@ -1057,9 +1291,12 @@ func (a *Agent) waitForCompletion(ctx context.Context) (*types.CompleteResult, e
 				}
 
 				return &types.CompleteResult{
-					Status: "ok",
-					Text:   text,
-					Last:   a.lastBookmark,
+					Status:            "ok",
+					Text:              text,
+					Last:              a.lastBookmark,
+					TerminationReason: cmp.Or(a.lastTermination, types.TerminationCompleted),
+					Summary:           a.lastSummary,
+					Error:             a.lastError,
 				}, nil
 			}
 		}
@@ -1086,15 +1323,74 @@ func (a *Agent) Status() *types.AgentStatus {
 	a.mu.RLock()
 	defer a.mu.RUnlock()
 
+	window, used, remaining := a.contextUsageLocked()
 	return &types.AgentStatus{
-		AgentID:      a.id,
-		State:        a.state,
-		StepCount:    a.stepCount,
-		LastSfpIndex: a.lastSfpIndex,
-		LastBookmark: a.lastBookmark,
-		Cursor:       a.eventBus.GetCursor(),
-		Breakpoint:   a.breakpoint,
+		AgentID:          a.id,
+		State:            a.state,
+		StepCount:        a.stepCount,
+		LastSfpIndex:     a.lastSfpIndex,
+		LastBookmark:     a.lastBookmark,
+		Cursor:           a.eventBus.GetCursor(),
+		Breakpoint:       a.breakpoint,
+		Model:            modelRef(a.CurrentModel()),
+		Title:            a.title,
+		ContextWindow:    window,
+		ContextUsed:      used,
+		ContextRemaining: remaining,
+	}
+}
+
+// ResetConversation 清空对话历史及所有会话级状态，使 Agent 可以被新的会话复用
+// 保留模板构建的 System Prompt、Provider 与 Sandbox，仅在 Agent 空闲时允许调用
+// 上一个会话的后台任务被取消，固定内容、UI Surface、文件版本、撤销检查点、
+// 成本累计、模型升级与循环检测历史全部清空
+func (a *Agent) ResetConversation(ctx context.Context) error {
+	a.mu.Lock()
+	if a.state == types.AgentStateWorking {
+		a.mu.Unlock()
+		return fmt.Errorf("agent %s is working", a.id)
+	}
+	a.messages = []types.Message{}
+	a.toolRecords = make(map[string]*types.ToolCallRecord)
+	a.stepCount = 0
+	a.iterationCount = 0
+	a.initialThinkingSent = false
+	a.lastSfpIndex = 0
+	a.lastBookmark = nil
+	a.title = ""
+	a.titlePending = false
+	a.run = runState{}
+	a.lastTermination = ""
+	a.lastSummary = ""
+	a.lastError = nil
+	a.turnCheckpoints = nil
+	a.planNotices = nil
+	a.seedDropped = false
+	hadPins := len(a.pins) > 0
+	a.pins = nil
+	a.applyPinsLocked()
+	a.state = types.AgentStateReady
+	a.mu.Unlock()
+
+	a.dropBackgroundTasks(ctx)
+	a.clearUISurfaces()
+	if a.fileVersions != nil {
+		a.fileVersions.Reset()
 	}
+	if a.loopGuard != nil {
+		a.loopGuard.reset()
+	}
+	a.clearEscalation(ctx)
+	a.sessionCost.reset()
+	a.ExitPlanMode()
+	a.contextUsage.reset()
+
+	if hadPins {
+		if err := a.savePins(ctx, nil); err != nil {
+			return err
+		}
+	}
+	return a.deps.Store.SaveMessages(ctx, a.id, []types.Message{})
 }
 
 // GetSystemPrompt 获取当前的 System Prompt
@@ -1217,10 +1513,42 @@ func (a *Agent) Close() error {
 		}
 	}
 
+	a.fileWatches.StopAll()
+	if a.lspManager != nil {
+		_ = a.lspManager.Close()
+	}
+	if a.browserManager != nil {
+		_ = a.browserManager.Close()
+	}
 	if err := a.sandbox.Dispose(); err != nil {
 		return err
 	}
+	if a.isolatedWorkDir != nil {
+		ctx := context.Background()
+		removed, err := a.isolatedWorkDir.Cleanup(ctx)
+		if err != nil {
+			agentLog.Warn(ctx, "work dir cleanup error", map[string]any{"path": a.isolatedWorkDir.Path, "error": err})
+		} else if !removed {
+			agentLog.Info(ctx, "isolated work dir kept", map[string]any{"path": a.isolatedWorkDir.Path})
+		}
+	}
 
+	if a.escalation != nil {
+		a.escalation.closeRetiredProviders()
+	}
+
+	a.modelMu.Lock()
+	defer a.modelMu.Unlock()
+	for _, entry := range a.taskProviders {
+		_ = entry.provider.Close()
+	}
+	for _, prov := range a.retiredProviders {
+		_ = prov.Close()
+	}
+	a.retiredProviders = nil
+	if a.summarizeProvider != nil {
+		_ = a.summarizeProvider.Close()
+	}
 	return a.provider.Close()
 }
 
@@ -1229,6 +1557,13 @@ func (a *Agent) Close() error {
 //   - tool_manuals: 工具手册映射，供 ToolHelp 等工具使用
 //   - skills_runtime: *skills.Runtime, 供 skill_call 工具使用 (仅当 Agent 配置了 SkillsPackage 时)
 //   - plan_mode_manager: *PlanModeManager, 供 EnterPlanMode/ExitPlanMode 工具使用
+//   - ui_surface_manager: *uiproto.SurfaceManager, 供 RenderUI 工具使用
+//   - watch_manager: *sandbox.WatchManager, 供 Watch 工具使用
+//   - file_versions: *sandbox.FileVersions, 供 Read/Write/Edit 工具检测写入冲突
+//   - lsp_manager: *lsp.Manager, 供 FindDefinition/FindReferences/Rename/Diagnostics 工具使用 (仅本地沙箱)
+//   - browser_manager: *browser.Manager, 供 Browser 工具使用
+//   - artifact_sink: artifact.Sink, 供工具保存产出物 (仅当配置了 Dependencies.Artifacts 时)
+//   - identity_signer: *identity.Signer, 供 Bash 工具为提交签名 (仅当配置了 Dependencies.Identity 时)
 func (a *Agent) buildToolContext(ctx context.Context) *tools.ToolContext {
 	tc := &tools.ToolContext{
 		AgentID:  a.id,
@@ -1271,6 +1606,34 @@ func (a *Agent) buildToolContext(ctx context.Context) *tools.ToolContext {
 		tc.Services["plan_mode_manager"] = a.planMode
 	}
 
+	if a.uiSurfaces != nil {
+		tc.Services["ui_surface_manager"] = a.uiSurfaces
+	}
+
+	if a.fileWatches != nil {
+		tc.Services["watch_manager"] = a.fileWatches
+	}
+
+	if a.fileVersions != nil {
+		tc.Services["file_versions"] = a.fileVersions
+	}
+
+	if a.lspManager != nil {
+		tc.Services["lsp_manager"] = a.lspManager
+	}
+
+	if a.browserManager != nil {
+		tc.Services["browser_manager"] = a.browserManager
+	}
+
+	if a.artifacts != nil {
+		tc.Services["artifact_sink"] = a.artifacts
+	}
+
+	if a.signer != nil {
+		tc.Services["identity_signer"] = a.signer
+	}
+
 	return tc
 }
 
@@ -1310,10 +1673,11 @@ func (a *Agent) handleSlashCommand(ctx context.Context, text string) error {
 	}
 
 	a.messages = append(a.messages, userMessage)
+	a.checkpointTurn(userMessage)
 	a.stepCount++
 
 	// 持久化
-	if err := a.deps.Store.SaveMessages(ctx, a.id, a.messages); err != nil {
+	if err := a.deps.Store.SaveMessages(ctx, a.id, a.messagesForStore(a.messages)); err != nil {
 		return fmt.Errorf("save messages: %w", err)
 	}
 
@@ -1414,7 +1778,7 @@ func (a *Agent) controlRunningTool(callID, action string) error {
 	case "resume":
 		return handle.interruptible.Resume()
 	case "cancel":
-		a.updateToolRecord(callID, types.ToolCallStateCancelling, "")
+		a.updateToolRecord(callID, types.ToolCallStateCancelling, nil)
 		return handle.interruptible.Cancel()
 	default:
 		return fmt.Errorf("unknown action: %s", action)
@@ -1556,11 +1920,16 @@ func (a *Agent) handleToolIntermediate(callID, toolName, label string, data any)
 	}
 	a.mu.Unlock()
 
-	a.eventBus.EmitProgress(&types.ProgressToolIntermediateEvent{
+	evt := &types.ProgressToolIntermediateEvent{
 		Call:  snapshot,
 		Label: label,
 		Data:  data,
-	})
+	}
+	// 工具产出的 UI 消息同时填入 UI 字段，供前端直接渲染
+	if ui, ok := data.(*types.AsterUIMessage); ok {
+		evt.UI = ui
+	}
+	a.eventBus.EmitProgress(evt)
 }
 
 // toolReporter 将工具回调转换为事件
@@ -1751,8 +2120,21 @@ func (a *Agent) GetIterationCount() int {
 // ModeAutoApprove: 自动批准所有工具调用（YOLO 模式）
 // ModeSmartApprove: 智能审批（低风险自动，高风险需审批）
 // ModeAlwaysAsk: 所有工具都需要审批
+// ModePlan: 只读工具照常执行，其余工具调用记入草稿计划，审批后统一执行
+// ModeBypass: 跳过审批但保留底线规则，需在配置中设置 AllowDangerouslySkipPermissions
 func (a *Agent) SetPermissionMode(mode permission.Mode) {
+	if mode == permission.ModeBypass && !a.config.AllowDangerouslySkipPermissions {
+		agentLog.Warn(context.Background(), "bypass mode refused: allow_dangerously_skip_permissions is not set", map[string]any{
+			"agent_id": a.id,
+		})
+		return
+	}
 	if a.permissionInspector != nil {
+		if mode == permission.ModeBypass {
+			agentLog.Warn(context.Background(), "BYPASS MODE ENABLED: tool calls run without approval, guardrails still apply", map[string]any{
+				"agent_id": a.id,
+			})
+		}
 		a.permissionInspector.SetMode(mode)
 		agentLog.Info(context.Background(), "permission mode changed", map[string]any{
 			"agent_id": a.id,
@@ -0,0 +1,53 @@
+package agent
+
+import (
+	"context"
+	"io"
+
+	"github.com/astercloud/aster/pkg/artifact"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+// artifactSink 工具保存产出物的入口，记录所属 Agent、以 Agent 身份签名并发布 MonitorArtifactCreatedEvent
+type artifactSink struct {
+	agent *Agent
+	store *artifact.Store
+}
+
+func (s *artifactSink) Save(ctx context.Context, req artifact.SaveRequest, r io.Reader) (*artifact.Artifact, error) {
+	if req.AgentID == "" {
+		req.AgentID = s.agent.id
+	}
+	if req.Signer == nil {
+		req.Signer = s.agent.signer
+	}
+	a, err := s.store.Save(ctx, req, r)
+	if err != nil {
+		return nil, err
+	}
+	s.agent.eventBus.EmitMonitor(&types.MonitorArtifactCreatedEvent{
+		ArtifactID: a.ID,
+		Name:       a.Name,
+		Kind:       string(a.Kind),
+		MIMEType:   a.MIMEType,
+		Size:       a.Size,
+		Source:     a.Source,
+	})
+	return a, nil
+}
+
+// newArtifactSink 未配置产出物存储时返回 nil
+func (a *Agent) newArtifactSink() *artifactSink {
+	if a.deps == nil || a.deps.Artifacts == nil {
+		return nil
+	}
+	return &artifactSink{agent: a, store: a.deps.Artifacts}
+}
+
+// Artifacts 列出该 Agent 产生的产出物，未配置产出物存储时返回 nil
+func (a *Agent) Artifacts(ctx context.Context) ([]*artifact.Artifact, error) {
+	if a.artifacts == nil {
+		return nil, nil
+	}
+	return a.artifacts.store.List(ctx, artifact.Filter{AgentID: a.id})
+}
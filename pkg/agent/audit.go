@@ -0,0 +1,87 @@
+package agent
+
+import (
+	"context"
+
+	"github.com/astercloud/aster/pkg/audit"
+	"github.com/astercloud/aster/pkg/multitenancy"
+	"github.com/astercloud/aster/pkg/sandbox"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+// auditSkippedEvents 流式输出类事件不进入审计轨迹，完整结果由 tool:end、done 等事件记录
+var auditSkippedEvents = map[string]bool{
+	"think_chunk_start": true,
+	"think_chunk":       true,
+	"think_chunk_end":   true,
+	"reasoning":         true,
+	"text_chunk_start":  true,
+	"text_chunk":        true,
+	"text_chunk_end":    true,
+	"tool:progress":     true,
+	"tool:intermediate": true,
+}
+
+// auditContext 审计记录使用的 context，携带 Agent 的租户
+func auditContext(config *types.AgentConfig) context.Context {
+	ctx := context.Background()
+	if config.Multitenancy != nil && config.Multitenancy.Enabled && config.Multitenancy.TenantID != "" {
+		ctx = multitenancy.WithTenantID(ctx, config.Multitenancy.TenantID)
+	}
+	return ctx
+}
+
+// auditEventObserver 把会话事件写入审计轨迹
+func auditEventObserver(trail *audit.Trail, config *types.AgentConfig) func(types.AgentEventEnvelope) {
+	ctx := auditContext(config)
+	return func(envelope types.AgentEventEnvelope) {
+		if auditSkippedEvents[envelope.Type] {
+			return
+		}
+		if err := trail.Record(ctx, audit.SourceSession, envelope.Type, config.AgentID, envelope); err != nil {
+			agentLog.Warn(ctx, "audit session event failed", map[string]any{"agent_id": config.AgentID, "error": err.Error()})
+		}
+	}
+}
+
+// initAudit 配置了审计轨迹时记录沙箱执行的命令
+func (a *Agent) initAudit() {
+	if a.deps == nil || a.deps.Audit == nil {
+		return
+	}
+	reporter, ok := a.sandbox.(sandbox.AuditReporter)
+	if !ok {
+		return
+	}
+	ctx := auditContext(a.config)
+	reporter.OnAudit(func(entry sandbox.AuditEntry) {
+		typ := "command_executed"
+		if entry.Blocked {
+			typ = "command_blocked"
+		}
+		if err := a.deps.Audit.Record(ctx, audit.SourceSandbox, typ, a.id, entry); err != nil {
+			agentLog.Warn(ctx, "audit sandbox command failed", map[string]any{"agent_id": a.id, "error": err.Error()})
+		}
+	})
+}
+
+// auditPermission 记录一次权限决策
+// decision 为 allowed、denied、approved、rejected 或 canceled
+func (a *Agent) auditPermission(tu *types.ToolUseBlock, decision, decidedBy, message string) {
+	if a.deps == nil || a.deps.Audit == nil {
+		return
+	}
+	ctx := auditContext(a.config)
+	data := map[string]any{
+		"call_id":    tu.ID,
+		"tool":       tu.Name,
+		"arguments":  tu.Input,
+		"decided_by": decidedBy,
+	}
+	if message != "" {
+		data["message"] = message
+	}
+	if err := a.deps.Audit.Record(ctx, audit.SourcePermission, decision, a.id, data); err != nil {
+		agentLog.Warn(ctx, "audit permission decision failed", map[string]any{"agent_id": a.id, "error": err.Error()})
+	}
+}
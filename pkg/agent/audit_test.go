@@ -0,0 +1,55 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/astercloud/aster/pkg/audit"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+func TestAgentAuditTrail(t *testing.T) {
+	deps := setupTestDeps(t)
+	trail, err := audit.Open(t.TempDir(), audit.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	deps.Audit = trail
+
+	ag, err := Create(context.Background(), &types.AgentConfig{
+		TemplateID: "test-template",
+		ModelConfig: &types.ModelConfig{
+			Provider: "anthropic",
+			Model:    "claude-sonnet-4-5",
+			APIKey:   "test-key",
+		},
+		Sandbox: &types.SandboxConfig{Kind: types.SandboxKindMock, WorkDir: "/tmp/test"},
+	}, deps)
+	if err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+	defer func() { _ = ag.Close() }()
+
+	ag.executeSingleTool(context.Background(), &types.ToolUseBlock{ID: "r1", Name: "Read", Input: map[string]any{"file_path": "/tmp/test/a.txt"}})
+	ag.eventBus.EmitProgress(&types.ProgressTextChunkEvent{Delta: "streamed"})
+
+	var buf bytes.Buffer
+	if _, err := trail.Export("", time.Time{}, time.Time{}, &buf); err != nil {
+		t.Fatal(err)
+	}
+	exported := buf.String()
+	for _, want := range []string{`"source":"permission","type":"allowed"`, `"source":"session","type":"tool:start"`} {
+		if !strings.Contains(exported, want) {
+			t.Errorf("audit trail missing %s:\n%s", want, exported)
+		}
+	}
+	if strings.Contains(exported, "streamed") {
+		t.Error("streaming chunks should not be audited")
+	}
+	if _, err := audit.Verify(&buf); err != nil {
+		t.Errorf("verify: %v", err)
+	}
+}
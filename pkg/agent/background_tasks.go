@@ -0,0 +1,333 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/astercloud/aster/pkg/store"
+	"github.com/astercloud/aster/pkg/tools"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+// backgroundTaskCollection 后台任务记录的存储集合，结果送达后删除
+const backgroundTaskCollection = "background_tasks"
+
+// backgroundPollInterval 后台任务状态轮询间隔
+var backgroundPollInterval = time.Second
+
+// isBackgroundTool 工具是否在后台运行（见 tools.AsyncTool）
+func isBackgroundTool(tool tools.Tool) bool {
+	at, ok := tool.(tools.AsyncTool)
+	return ok && at.RunInBackground()
+}
+
+// startBackgroundTask 启动后台工具任务，返回交给模型的任务句柄
+// 任务不受当前运行的取消影响，结束后结果作为新消息送达
+func (a *Agent) startBackgroundTask(ctx context.Context, callID, name string, input map[string]any, tool tools.LongRunningTool) (string, error) {
+	taskID, err := tool.StartAsync(context.WithoutCancel(ctx), input)
+	if err != nil {
+		return "", err
+	}
+	task := types.BackgroundTask{
+		ID:        taskID,
+		AgentID:   a.id,
+		ToolUseID: callID,
+		ToolName:  name,
+		Input:     input,
+		Status:    types.ToolCallStatusRunning,
+		StartedAt: time.Now(),
+	}
+	a.mu.Lock()
+	if a.backgroundTasks == nil {
+		a.backgroundTasks = make(map[string]*types.BackgroundTask)
+	}
+	a.backgroundTasks[taskID] = &task
+	a.mu.Unlock()
+
+	a.saveBackgroundTask(ctx, task)
+	a.eventBus.EmitProgress(&types.ProgressBackgroundTaskEvent{Task: task})
+	go a.watchBackgroundTask(taskID, tool)
+
+	handle, _ := json.Marshal(map[string]any{
+		"task_id": taskID,
+		"status":  task.Status,
+		"message": "The task is running in the background. Its result will be delivered in a later message; continue with other work or end your turn.",
+	})
+	return string(handle), nil
+}
+
+// watchBackgroundTask 轮询任务状态，推送进度，结束后送达结果；Agent 关闭时停止
+// （任务记录保留在存储中，重启后恢复）
+func (a *Agent) watchBackgroundTask(taskID string, tool tools.LongRunningTool) {
+	ctx := context.Background()
+	ticker := time.NewTicker(backgroundPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-a.stopCh:
+			return
+		case <-ticker.C:
+		}
+
+		status, err := tool.GetStatus(ctx, taskID)
+		if err != nil {
+			a.finishBackgroundTask(ctx, taskID, types.ToolCallStatusFailed, nil, err.Error())
+			a.deliverBackgroundTasks(ctx)
+			return
+		}
+		if status.State.IsTerminal() {
+			errMsg := ""
+			if status.Error != nil {
+				errMsg = status.Error.Error()
+			}
+			a.finishBackgroundTask(ctx, taskID, backgroundStatus(status.State), status.Result, errMsg)
+			a.deliverBackgroundTasks(ctx)
+			return
+		}
+
+		a.mu.Lock()
+		task, ok := a.backgroundTasks[taskID]
+		changed := ok && task.Progress != status.Progress
+		var snapshot types.BackgroundTask
+		if changed {
+			task.Progress = status.Progress
+			snapshot = *task
+		}
+		a.mu.Unlock()
+		if changed {
+			a.eventBus.EmitProgress(&types.ProgressBackgroundTaskEvent{Task: snapshot})
+		}
+	}
+}
+
+// finishBackgroundTask 记录任务结果，由调用方决定何时送达
+func (a *Agent) finishBackgroundTask(ctx context.Context, taskID string, status types.ToolCallStatus, result any, errMsg string) {
+	now := time.Now()
+	a.mu.Lock()
+	task, ok := a.backgroundTasks[taskID]
+	if !ok {
+		a.mu.Unlock()
+		return
+	}
+	task.Status = status
+	task.Result = result
+	task.Error = errMsg
+	task.EndedAt = &now
+	if status == types.ToolCallStatusCompleted {
+		task.Progress = 1
+	}
+	snapshot := *task
+	a.mu.Unlock()
+
+	a.saveBackgroundTask(ctx, snapshot)
+	a.eventBus.EmitProgress(&types.ProgressBackgroundTaskEvent{Task: snapshot})
+}
+
+// deliverBackgroundTasks 将已结束任务的结果作为新消息送达并触发处理
+// Agent 运行中时不送达，运行结束后由 processMessages 再次调用
+func (a *Agent) deliverBackgroundTasks(ctx context.Context) {
+	a.mu.Lock()
+	if a.state != types.AgentStateReady {
+		a.mu.Unlock()
+		return
+	}
+	ready := a.undeliveredBackgroundTasks()
+	if len(ready) == 0 {
+		a.mu.Unlock()
+		return
+	}
+
+	blocks := make([]types.ContentBlock, 0, len(ready))
+	delivered := make([]types.BackgroundTask, 0, len(ready))
+	for _, task := range ready {
+		task.Delivered = true
+		blocks = append(blocks, &types.TextBlock{Text: formatBackgroundResult(task)})
+		delivered = append(delivered, *task)
+	}
+	a.messages = append(a.messages, types.Message{Role: types.MessageRoleUser, ContentBlocks: blocks})
+	a.stepCount++
+	if err := a.deps.Store.SaveMessages(ctx, a.id, a.messagesForStore(a.messages)); err != nil {
+		agentLog.Warn(ctx, "failed to save background task results", map[string]any{"agent_id": a.id, "error": err.Error()})
+	}
+	a.mu.Unlock()
+
+	for _, task := range delivered {
+		if err := a.deps.Store.Delete(ctx, backgroundTaskCollection, task.ID); err != nil && !errors.Is(err, store.ErrNotFound) {
+			agentLog.Warn(ctx, "failed to delete background task", map[string]any{"task_id": task.ID, "error": err.Error()})
+		}
+		a.eventBus.EmitProgress(&types.ProgressBackgroundTaskEvent{Task: task})
+	}
+	go a.processMessages(context.Background())
+}
+
+// undeliveredBackgroundTasks 已结束但未送达的任务，按结束时间排序；调用方持有 a.mu
+func (a *Agent) undeliveredBackgroundTasks() []*types.BackgroundTask {
+	var ready []*types.BackgroundTask
+	for _, task := range a.backgroundTasks {
+		if task.Done() && !task.Delivered {
+			ready = append(ready, task)
+		}
+	}
+	slices.SortFunc(ready, func(x, y *types.BackgroundTask) int {
+		return x.EndedAt.Compare(*y.EndedAt)
+	})
+	return ready
+}
+
+// restoreBackgroundTasks 恢复存储中未送达的后台任务：
+// 已结束的任务直接送达；运行中的任务由 ResumableTool 恢复，或重新连接仍可查询的任务，
+// 否则视为在重启中丢失
+func (a *Agent) restoreBackgroundTasks(ctx context.Context) {
+	items, err := a.deps.Store.List(ctx, backgroundTaskCollection)
+	if err != nil {
+		return
+	}
+	var restored []types.BackgroundTask
+	for _, item := range items {
+		var task types.BackgroundTask
+		if err := store.DecodeValue(item, &task); err != nil || task.AgentID != a.id || task.Delivered {
+			continue
+		}
+		restored = append(restored, task)
+	}
+	if len(restored) == 0 {
+		return
+	}
+
+	a.mu.Lock()
+	if a.backgroundTasks == nil {
+		a.backgroundTasks = make(map[string]*types.BackgroundTask)
+	}
+	for i := range restored {
+		a.backgroundTasks[restored[i].ID] = &restored[i]
+	}
+	a.mu.Unlock()
+
+	for _, task := range restored {
+		if task.Done() {
+			continue
+		}
+		lrTool, ok := a.toolMap[task.ToolName].(tools.LongRunningTool)
+		if !ok {
+			a.finishBackgroundTask(ctx, task.ID, types.ToolCallStatusFailed, nil, "tool "+task.ToolName+" is no longer available")
+			continue
+		}
+		if rt, ok := lrTool.(tools.ResumableTool); ok {
+			if err := rt.Resume(context.WithoutCancel(ctx), task.ID, task.Input); err != nil {
+				a.finishBackgroundTask(ctx, task.ID, types.ToolCallStatusFailed, nil, "resume after restart: "+err.Error())
+				continue
+			}
+		} else if _, err := lrTool.GetStatus(ctx, task.ID); err != nil {
+			a.finishBackgroundTask(ctx, task.ID, types.ToolCallStatusFailed, nil, "the task was lost when the agent restarted; call the tool again if the result is still needed")
+			continue
+		}
+		agentLog.Info(ctx, "background task resumed", map[string]any{"agent_id": a.id, "task_id": task.ID, "tool": task.ToolName})
+		go a.watchBackgroundTask(task.ID, lrTool)
+	}
+	go a.deliverBackgroundTasks(context.Background())
+}
+
+// saveBackgroundTask 持久化任务记录，失败只记录日志
+func (a *Agent) saveBackgroundTask(ctx context.Context, task types.BackgroundTask) {
+	if err := a.deps.Store.Set(ctx, backgroundTaskCollection, task.ID, task); err != nil {
+		agentLog.Warn(ctx, "failed to save background task", map[string]any{"task_id": task.ID, "error": err.Error()})
+	}
+}
+
+// BackgroundTasks 返回本 Agent 的后台任务，按开始时间排序
+func (a *Agent) BackgroundTasks() []types.BackgroundTask {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	list := make([]types.BackgroundTask, 0, len(a.backgroundTasks))
+	for _, task := range a.backgroundTasks {
+		list = append(list, *task)
+	}
+	slices.SortFunc(list, func(x, y types.BackgroundTask) int {
+		return x.StartedAt.Compare(y.StartedAt)
+	})
+	return list
+}
+
+// CancelBackgroundTask 取消运行中的后台任务，取消结果同样会送达模型
+func (a *Agent) CancelBackgroundTask(ctx context.Context, taskID string) error {
+	a.mu.RLock()
+	task, ok := a.backgroundTasks[taskID]
+	var name string
+	var done bool
+	if ok {
+		name, done = task.ToolName, task.Done()
+	}
+	a.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("background task not found: %s", taskID)
+	}
+	if done {
+		return fmt.Errorf("background task already finished: %s", taskID)
+	}
+	lrTool, ok := a.toolMap[name].(tools.LongRunningTool)
+	if !ok {
+		return fmt.Errorf("tool %s is not a long-running tool", name)
+	}
+	return lrTool.Cancel(ctx, taskID)
+}
+
+// dropBackgroundTasks 取消运行中的后台任务并丢弃所有任务记录，结果不再送达
+// 用于 Agent 被新的会话复用，避免上一个会话的任务结果出现在新会话中
+func (a *Agent) dropBackgroundTasks(ctx context.Context) {
+	a.mu.Lock()
+	tasks := a.backgroundTasks
+	a.backgroundTasks = nil
+	a.mu.Unlock()
+
+	for _, task := range tasks {
+		if !task.Done() {
+			if lrTool, ok := a.toolMap[task.ToolName].(tools.LongRunningTool); ok {
+				if err := lrTool.Cancel(ctx, task.ID); err != nil {
+					agentLog.Warn(ctx, "failed to cancel background task", map[string]any{"task_id": task.ID, "error": err.Error()})
+				}
+			}
+		}
+		if err := a.deps.Store.Delete(ctx, backgroundTaskCollection, task.ID); err != nil && !errors.Is(err, store.ErrNotFound) {
+			agentLog.Warn(ctx, "failed to delete background task", map[string]any{"task_id": task.ID, "error": err.Error()})
+		}
+	}
+}
+
+// backgroundStatus 将任务终态映射为调用状态
+func backgroundStatus(state tools.TaskState) types.ToolCallStatus {
+	switch state {
+	case tools.TaskStateCompleted:
+		return types.ToolCallStatusCompleted
+	case tools.TaskStateCancelled:
+		return types.ToolCallStatusCancelled
+	default:
+		return types.ToolCallStatusFailed
+	}
+}
+
+// formatBackgroundResult 生成送达模型的任务结果文本
+func formatBackgroundResult(task *types.BackgroundTask) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "<background-task id=%q tool=%q tool_use_id=%q status=%q>\n", task.ID, task.ToolName, task.ToolUseID, task.Status)
+	if task.Error != "" {
+		fmt.Fprintf(&b, "Error: %s\n", task.Error)
+	}
+	if task.Result != nil {
+		if s, ok := task.Result.(string); ok {
+			b.WriteString(s)
+		} else if data, err := json.Marshal(task.Result); err == nil {
+			b.Write(data)
+		} else {
+			fmt.Fprintf(&b, "%v", task.Result)
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("</background-task>")
+	return b.String()
+}
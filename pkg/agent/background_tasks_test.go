@@ -0,0 +1,181 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/astercloud/aster/pkg/provider"
+	"github.com/astercloud/aster/pkg/tools"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+// fakeJobTool 状态由测试控制的后台工具
+type fakeJobTool struct {
+	tools.BaseTool
+	mu     sync.Mutex
+	status map[string]*tools.TaskStatus
+}
+
+func newFakeJobTool() *fakeJobTool {
+	return &fakeJobTool{
+		BaseTool: tools.BaseTool{ToolName: "Job", ToolDescription: "runs a job"},
+		status:   make(map[string]*tools.TaskStatus),
+	}
+}
+
+func (f *fakeJobTool) Execute(context.Context, map[string]any, *tools.ToolContext) (any, error) {
+	return nil, errors.New("not used")
+}
+func (f *fakeJobTool) IsLongRunning() bool   { return true }
+func (f *fakeJobTool) RunInBackground() bool { return true }
+
+func (f *fakeJobTool) StartAsync(context.Context, map[string]any) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.status["job-1"] = &tools.TaskStatus{TaskID: "job-1", State: tools.TaskStateRunning, StartTime: time.Now()}
+	return "job-1", nil
+}
+
+func (f *fakeJobTool) GetStatus(_ context.Context, id string) (*tools.TaskStatus, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	s, ok := f.status[id]
+	if !ok {
+		return nil, errors.New("task not found")
+	}
+	copied := *s
+	return &copied, nil
+}
+
+func (f *fakeJobTool) Cancel(context.Context, string) error { return nil }
+
+func (f *fakeJobTool) finish(id string, result any) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	now := time.Now()
+	f.status[id].State = tools.TaskStateCompleted
+	f.status[id].Result = result
+	f.status[id].EndTime = &now
+}
+
+func newBackgroundTestAgent(t *testing.T, deps *Dependencies, agentID string) *Agent {
+	t.Helper()
+	factory := NewMockProviderFactory()
+	factory.SetProvider("mock/bg", &MockProvider{
+		name: "bg",
+		completeFunc: func(context.Context, []types.Message, *provider.StreamOptions) (*provider.CompleteResponse, error) {
+			return &provider.CompleteResponse{Message: types.Message{
+				Role:          types.MessageRoleAssistant,
+				ContentBlocks: []types.ContentBlock{&types.TextBlock{Text: "noted"}},
+			}}, nil
+		},
+		capabilities: provider.ProviderCapabilities{SupportToolCalling: true, SupportSystemPrompt: true},
+	})
+	deps.ProviderFactory = factory
+	ag, err := Create(context.Background(), &types.AgentConfig{
+		AgentID:     agentID,
+		TemplateID:  "test-template",
+		ModelConfig: &types.ModelConfig{Provider: "mock", Model: "bg", ExecutionMode: types.ExecutionModeNonStreaming},
+		Sandbox:     &types.SandboxConfig{Kind: types.SandboxKindMock, WorkDir: "/tmp/test"},
+	}, deps)
+	if err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+	t.Cleanup(func() { _ = ag.Close() })
+	return ag
+}
+
+// waitDelivered 等待后台任务结果送达
+func waitDelivered(t *testing.T, ch <-chan types.AgentEventEnvelope, taskID string) types.BackgroundTask {
+	t.Helper()
+	deadline := time.After(5 * time.Second)
+	for {
+		select {
+		case env := <-ch:
+			if e, ok := env.Event.(*types.ProgressBackgroundTaskEvent); ok && e.Task.ID == taskID && e.Task.Delivered {
+				return e.Task
+			}
+		case <-deadline:
+			t.Fatalf("background task %s was not delivered", taskID)
+		}
+	}
+}
+
+func TestBackgroundTaskDelivery(t *testing.T) {
+	defer func(d time.Duration) { backgroundPollInterval = d }(backgroundPollInterval)
+	backgroundPollInterval = 10 * time.Millisecond
+
+	deps := setupTestDeps(t)
+	ag := newBackgroundTestAgent(t, deps, "")
+	job := newFakeJobTool()
+	ag.toolMap["Job"] = job
+	ch := ag.Subscribe([]types.AgentChannel{types.ChannelProgress}, nil)
+
+	ctx := context.Background()
+	handle, err := ag.startBackgroundTask(ctx, "call-1", "Job", map[string]any{"n": 1}, job)
+	if err != nil || !strings.Contains(handle, `"task_id":"job-1"`) {
+		t.Fatalf("startBackgroundTask() = %s, %v", handle, err)
+	}
+	var saved types.BackgroundTask
+	if err := deps.Store.Get(ctx, backgroundTaskCollection, "job-1", &saved); err != nil || saved.Status != types.ToolCallStatusRunning {
+		t.Fatalf("persisted task = %+v, %v", saved, err)
+	}
+
+	job.finish("job-1", map[string]any{"rows": 3})
+	task := waitDelivered(t, ch, "job-1")
+	if task.Status != types.ToolCallStatusCompleted || task.Progress != 1 {
+		t.Errorf("delivered task = %+v", task)
+	}
+
+	ag.mu.RLock()
+	var found bool
+	for _, msg := range ag.messages {
+		for _, block := range msg.ContentBlocks {
+			if tb, ok := block.(*types.TextBlock); ok && strings.Contains(tb.Text, `<background-task id="job-1"`) && strings.Contains(tb.Text, `{"rows":3}`) {
+				found = msg.Role == types.MessageRoleUser
+			}
+		}
+	}
+	ag.mu.RUnlock()
+	if !found {
+		t.Error("background task result not delivered as a user message")
+	}
+	if exists, _ := deps.Store.Exists(ctx, backgroundTaskCollection, "job-1"); exists {
+		t.Error("delivered task should be removed from the store")
+	}
+}
+
+func TestBackgroundTaskRestore(t *testing.T) {
+	deps := setupTestDeps(t)
+	ctx := context.Background()
+	// 重启前未完成的任务：工具无法重新连接时视为丢失，结果仍送达模型
+	if err := deps.Store.Set(ctx, backgroundTaskCollection, "task-lost", types.BackgroundTask{
+		ID:        "task-lost",
+		AgentID:   "agt-restore",
+		ToolUseID: "call-9",
+		ToolName:  "Read",
+		Status:    types.ToolCallStatusRunning,
+		StartedAt: time.Now(),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	ag := newBackgroundTestAgent(t, deps, "agt-restore")
+	tasks := ag.BackgroundTasks()
+	if len(tasks) != 1 || tasks[0].Status != types.ToolCallStatusFailed || !strings.Contains(tasks[0].Error, "no longer available") {
+		t.Fatalf("BackgroundTasks() = %+v", tasks)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if exists, _ := deps.Store.Exists(ctx, backgroundTaskCollection, "task-lost"); !exists {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Error("restored task result was not delivered")
+}
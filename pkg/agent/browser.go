@@ -0,0 +1,28 @@
+package agent
+
+import (
+	"time"
+
+	"github.com/astercloud/aster/pkg/browser"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+// newBrowserManager 创建 Browser 工具使用的浏览器管理器
+// 浏览器在首次使用时才启动，访问范围受沙箱网络策略（Settings.Network）限制
+func (a *Agent) newBrowserManager(config *types.BrowserConfig, sandboxConfig *types.SandboxConfig) *browser.Manager {
+	if config != nil && config.Disabled {
+		return nil
+	}
+	var network *types.NetworkSandboxSettings
+	if sandboxConfig != nil && sandboxConfig.Settings != nil {
+		network = sandboxConfig.Settings.Network
+	}
+	cfg := browser.Config{Policy: browser.NewPolicy(network)}
+	if config != nil {
+		cfg.ExecPath = config.ExecPath
+		cfg.Headful = config.Headful
+		cfg.Timeout = time.Duration(config.TimeoutSeconds) * time.Second
+		cfg.ScreenshotDir = config.ScreenshotDir
+	}
+	return browser.NewManager(cfg)
+}
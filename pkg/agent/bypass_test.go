@@ -0,0 +1,38 @@
+package agent
+
+import (
+	"context"
+	"testing"
+
+	"github.com/astercloud/aster/pkg/types"
+)
+
+func TestSandboxBypassKeepsGuardrails(t *testing.T) {
+	ag := newWriteTestAgent(t, &types.SandboxConfig{
+		Kind:           types.SandboxKindLocal,
+		WorkDir:        t.TempDir(),
+		PermissionMode: types.SandboxPermissionBypass,
+	})
+	ctx := context.Background()
+
+	for _, call := range []*types.ToolCallSnapshot{
+		{ID: "rm-home", Name: "Bash", Arguments: map[string]any{"command": "rm -rf ~"}},
+		{ID: "read-ssh", Name: "Read", Arguments: map[string]any{"file_path": "~/.ssh/id_ed25519"}},
+		{ID: "cat-ssh", Name: "Bash", Arguments: map[string]any{"command": "cat ~/.ssh/id_ed25519"}},
+	} {
+		result, err := ag.permissionInspector.Check(ctx, call)
+		if err != nil {
+			t.Fatalf("%s: %v", call.ID, err)
+		}
+		if result.Allowed {
+			t.Errorf("%s should be denied in sandbox bypass mode: %+v", call.ID, result)
+		}
+	}
+
+	result, err := ag.permissionInspector.Check(ctx, &types.ToolCallSnapshot{
+		ID: "write", Name: "Write", Arguments: map[string]any{"file_path": "a.txt", "content": "x"},
+	})
+	if err != nil || !result.Allowed {
+		t.Errorf("writes inside the work dir should run without approval: %+v %v", result, err)
+	}
+}
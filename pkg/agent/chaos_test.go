@@ -0,0 +1,118 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/astercloud/aster/pkg/agenterr"
+	"github.com/astercloud/aster/pkg/chaos"
+	"github.com/astercloud/aster/pkg/provider"
+	"github.com/astercloud/aster/pkg/sandbox"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+// TestAgent_FaultInjection 注入一次 Provider 503 和一次工具失败，验证 Agent 在重新发起对话和重试工具后完成任务
+func TestAgent_FaultInjection(t *testing.T) {
+	deps := setupTestDeps(t)
+	factory := NewMockProviderFactory()
+	factory.SetProvider("mock/chaos", &MockProvider{
+		name: "chaos",
+		completeFunc: func(_ context.Context, messages []types.Message, _ *provider.StreamOptions) (*provider.CompleteResponse, error) {
+			// 工具失败后重试一次 Bash，之后回复最后一次的工具结果
+			var results []string
+			for _, msg := range messages {
+				for _, block := range msg.ContentBlocks {
+					if tr, ok := block.(*types.ToolResultBlock); ok {
+						results = append(results, tr.Content)
+					}
+				}
+			}
+			var block types.ContentBlock = &types.ToolUseBlock{ID: "call", Name: "Bash", Input: map[string]any{"command": "make test"}}
+			if len(results) == 2 {
+				block = &types.TextBlock{Text: results[1]}
+			}
+			return &provider.CompleteResponse{Message: types.Message{
+				Role:          types.MessageRoleAssistant,
+				ContentBlocks: []types.ContentBlock{block},
+			}}, nil
+		},
+		capabilities: provider.ProviderCapabilities{SupportToolCalling: true, SupportSystemPrompt: true},
+	})
+	deps.ProviderFactory = factory
+	deps.Faults = chaos.New(chaos.Config{
+		Seed:          1,
+		ProviderError: &chaos.Fault{Rate: 1, Limit: 1},
+		ToolFailure:   &chaos.Fault{Rate: 1, Limit: 1, Targets: []string{"Bash"}},
+	})
+
+	ag, err := Create(context.Background(), &types.AgentConfig{
+		TemplateID:  "test-template",
+		ModelConfig: &types.ModelConfig{Provider: "mock", Model: "chaos", ExecutionMode: types.ExecutionModeNonStreaming},
+		Tools:       []string{"Bash"},
+		Sandbox: &types.SandboxConfig{
+			Kind:           types.SandboxKindMock,
+			PermissionMode: types.SandboxPermissionBypass,
+			Mock: &types.MockSandboxConfig{
+				Strict:   true,
+				Commands: []types.MockCommand{{Match: "*make test*", Stdout: "PASS\n"}},
+			},
+		},
+	}, deps)
+	if err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+	t.Cleanup(func() { _ = ag.Close() })
+
+	result, err := ag.Chat(context.Background(), "run the tests")
+	if err != nil {
+		t.Fatalf("first Chat() error = %v", err)
+	}
+	if result.Error == nil || result.Error.Code != agenterr.CodeProviderOverloaded || !errors.Is(result.Error, chaos.ErrInjected) {
+		t.Fatalf("first Chat() result error = %v, want injected provider fault", result.Error)
+	}
+	result, err = ag.Chat(context.Background(), "try again")
+	if err != nil {
+		t.Fatalf("second Chat() error = %v", err)
+	}
+	if result.Error != nil || !strings.Contains(result.Text, "PASS") {
+		t.Errorf("second Chat() = %q, %v", result.Text, result.Error)
+	}
+
+	// 第一次 Bash 被注入失败，没有到达沙箱
+	if n := ag.Sandbox().(*sandbox.MockSandbox).Executed("*make test*"); n != 1 {
+		t.Errorf("make test executed %d times", n)
+	}
+	stats := deps.Faults.Stats()
+	if stats[chaos.KindProviderError].Injected != 1 || stats[chaos.KindToolFailure] != (chaos.Stats{Calls: 2, Injected: 1}) {
+		t.Errorf("Stats() = %+v", stats)
+	}
+}
+
+// TestModelFallbackManager_InjectedFaults 主模型持续超时时降级到备用模型
+func TestModelFallbackManager_InjectedFaults(t *testing.T) {
+	faults := chaos.New(chaos.Config{
+		Seed:            1,
+		ProviderTimeout: &chaos.Fault{Rate: 1, Targets: []string{"mock/primary"}},
+	})
+	deps := &Dependencies{ProviderFactory: faults.WrapProviderFactory(NewMockProviderFactory())}
+
+	manager, err := NewModelFallbackManager([]*ModelFallback{
+		{Config: &types.ModelConfig{Provider: "mock", Model: "primary"}, MaxRetries: 1, Enabled: true, Priority: 1},
+		{Config: &types.ModelConfig{Provider: "mock", Model: "backup"}, Enabled: true, Priority: 2},
+	}, deps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := manager.Complete(context.Background(), []types.Message{{Role: types.MessageRoleUser, Content: "hi"}}, nil)
+	if err != nil {
+		t.Fatalf("Complete() error = %v", err)
+	}
+	if resp.Message.Content != "mock response from mock/backup" {
+		t.Errorf("Complete() content = %q", resp.Message.Content)
+	}
+	if s := faults.Stats()[chaos.KindProviderTimeout]; s.Injected != 2 {
+		t.Errorf("provider timeouts injected = %d, want 2 (initial call and retry)", s.Injected)
+	}
+}
@@ -0,0 +1,58 @@
+package agent
+
+import (
+	"fmt"
+
+	"github.com/astercloud/aster/pkg/lsp"
+	"github.com/astercloud/aster/pkg/permission"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+// newLSPManager 创建代码智能工具使用的语言服务器管理器
+// 语言服务器作为本地进程运行，只支持本地沙箱；服务器在首次查询对应文件时才启动
+func (a *Agent) newLSPManager(config *types.LSPConfig) *lsp.Manager {
+	if config != nil && config.Disabled {
+		return nil
+	}
+	if a.sandbox == nil || a.sandbox.Kind() != "local" {
+		return nil
+	}
+
+	var servers []lsp.ServerConfig
+	if config != nil {
+		for _, s := range config.Servers {
+			servers = append(servers, lsp.ServerConfig{
+				Name:                  s.Name,
+				Command:               s.Command,
+				Args:                  s.Args,
+				Extensions:            s.Extensions,
+				RootMarkers:           s.RootMarkers,
+				Env:                   s.Env,
+				InitializationOptions: s.InitializationOptions,
+			})
+		}
+	}
+	if config == nil || !config.DisableDefaults {
+		servers = append(servers, lsp.DefaultServers()...)
+	}
+	if len(servers) == 0 {
+		return nil
+	}
+	manager := lsp.NewManager(a.sandbox.WorkDir(), servers)
+	manager.SetStartGate(a.checkLanguageServerTrust)
+	return manager
+}
+
+// checkLanguageServerTrust 未受信任的工作区不启动语言服务器
+// 语言服务器会加载工作区中的配置和插件，等同于执行工作区代码
+func (a *Agent) checkLanguageServerTrust() error {
+	if a.trust == nil || a.WorkspaceTrust() == permission.TrustLevelTrusted {
+		return nil
+	}
+	return fmt.Errorf("workspace %s is not trusted: language servers are disabled until the user trusts it", a.trust.workDir)
+}
+
+// LanguageServers 返回代码智能工具使用的语言服务器管理器，未启用时为 nil
+func (a *Agent) LanguageServers() *lsp.Manager {
+	return a.lspManager
+}
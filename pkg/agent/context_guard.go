@@ -0,0 +1,322 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/astercloud/aster/pkg/agenterr"
+	"github.com/astercloud/aster/pkg/provider"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+// ErrContextWindowExceeded 请求超出模型上下文窗口且无法压缩到窗口内
+var ErrContextWindowExceeded = agenterr.New(agenterr.CodeContextWindowExceeded, "context window exceeded")
+
+const (
+	// defaultOutputReserve 默认为模型输出预留的 token 数上限
+	defaultOutputReserve = 8192
+	// defaultKeepRecentMessages 压缩时默认完整保留的最近消息数
+	defaultKeepRecentMessages = 4
+	// messageTokenOverhead 每条消息的结构开销估算
+	messageTokenOverhead = 4
+	// omittedToolOutputMinLen 短于该长度的工具输出不值得省略
+	omittedToolOutputMinLen = 200
+)
+
+// omittedToolOutput 被省略的旧工具输出占位内容
+const omittedToolOutput = "[tool output omitted to fit the model context window]"
+
+// contextTracker 记录最近一次模型调用的真实输入 Token，用于估算下一次请求的大小
+// 估算值 = 上次真实输入 + 之后新增消息的估算；没有真实用量时全部估算
+type contextTracker struct {
+	mu sync.Mutex
+
+	lastInputTokens  int
+	lastMessageCount int
+	pendingMessages  int
+
+	// toolTokens 已提供工具定义的 Token 估算缓存，toolTokensValid 为 false 时重新计算
+	toolTokens      int
+	toolTokensValid bool
+}
+
+// beginRequest 记录即将发送的消息数量，用于关联随后上报的 Token 用量
+func (t *contextTracker) beginRequest(messageCount int) {
+	t.mu.Lock()
+	t.pendingMessages = messageCount
+	t.mu.Unlock()
+}
+
+// observe 记录 Provider 上报的输入 Token（含缓存读写部分）
+func (t *contextTracker) observe(usage *provider.TokenUsage) {
+	input := int(usage.InputTokens + usage.CacheCreationTokens + usage.CacheReadTokens)
+	if input <= 0 {
+		return
+	}
+	t.mu.Lock()
+	t.lastInputTokens = input
+	t.lastMessageCount = t.pendingMessages
+	t.mu.Unlock()
+}
+
+// reset 消息历史被改写后，之前的真实用量不再可用
+func (t *contextTracker) reset() {
+	t.mu.Lock()
+	t.lastInputTokens = 0
+	t.lastMessageCount = 0
+	t.mu.Unlock()
+}
+
+// toolSchemaTokens 返回缓存的工具定义 Token 估算，没有缓存时调用 compute 计算
+func (t *contextTracker) toolSchemaTokens(compute func() int) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.toolTokensValid {
+		t.toolTokens = compute()
+		t.toolTokensValid = true
+	}
+	return t.toolTokens
+}
+
+// invalidateTools 提供给模型的工具变化后，工具定义的估算需要重新计算
+func (t *contextTracker) invalidateTools() {
+	t.mu.Lock()
+	t.toolTokensValid = false
+	t.mu.Unlock()
+}
+
+// estimate 估算一次请求的输入 Token 数，overhead 为消息历史之外的固定开销（工具定义、示例对话）
+func (t *contextTracker) estimate(system string, overhead int, messages []types.Message) int {
+	t.mu.Lock()
+	lastInput, lastCount := t.lastInputTokens, t.lastMessageCount
+	t.mu.Unlock()
+
+	if lastInput > 0 && lastCount <= len(messages) {
+		return lastInput + estimateMessagesTokens(messages[lastCount:])
+	}
+	return estimateTextTokens(system) + overhead + estimateMessagesTokens(messages)
+}
+
+// estimateTextTokens 按 4 字节 ≈ 1 token 估算（与 summarization 中间件一致）
+func estimateTextTokens(text string) int {
+	return len(text) / 4
+}
+
+// estimateMessagesTokens 估算消息列表的 Token 数
+func estimateMessagesTokens(messages []types.Message) int {
+	total := 0
+	for _, msg := range messages {
+		total += messageTokenOverhead + estimateTextTokens(msg.Content)
+		for _, block := range msg.ContentBlocks {
+			switch b := block.(type) {
+			case *types.TextBlock:
+				total += estimateTextTokens(b.Text)
+			case *types.ToolUseBlock:
+				input, _ := json.Marshal(b.Input)
+				total += estimateTextTokens(b.Name) + len(input)/4
+			case *types.ToolResultBlock:
+				total += estimateTextTokens(b.Content)
+			case *types.ThinkingBlock:
+				total += estimateTextTokens(b.Thinking)
+			}
+		}
+	}
+	return total
+}
+
+// estimateToolSchemaTokens 估算工具定义的 Token 数
+func estimateToolSchemaTokens(schemas []provider.ToolSchema) int {
+	total := 0
+	for _, schema := range schemas {
+		input, _ := json.Marshal(schema.InputSchema)
+		total += estimateTextTokens(schema.Name) + estimateTextTokens(schema.Description) + len(input)/4
+	}
+	return total
+}
+
+// outputReserve 返回为模型输出预留的 token 数
+func (a *Agent) outputReserve(window int) int {
+	if guard := a.config.ContextGuard; guard != nil && guard.OutputReserveTokens > 0 {
+		return guard.OutputReserveTokens
+	}
+	return min(defaultOutputReserve, window/4)
+}
+
+// contextUsageLocked 返回当前上下文窗口、已用和剩余 Token（估算），调用方需持有 a.mu
+func (a *Agent) contextUsageLocked() (window, used, remaining int) {
+	window = provider.ContextWindowFor(a.CurrentModel())
+
+	// 工具定义只在工具过滤变化时改变，不必每次查询状态都重新序列化
+	toolTokens := a.contextUsage.toolSchemaTokens(func() int {
+		return estimateToolSchemaTokens(a.providerToolSchemas())
+	})
+	used = a.contextUsage.estimate(a.template.SystemPrompt, toolTokens+a.seedLocked().size(), a.messages)
+	remaining = max(window-a.outputReserve(window)-used, 0)
+	return window, used, remaining
+}
+
+// fitContextWindow 在模型调用前检查请求是否超出模型上下文窗口
+// 超出时先丢弃可压缩的示例对话、再省略旧的工具输出、最后丢弃最早的对话轮次；
+// 仍无法容纳时返回 ErrContextWindowExceeded，而不是把请求发给 Provider 后才得到 400 错误。
+// 返回的消息包含示例对话，写回 a.messages 的压缩结果不包含
+func (a *Agent) fitContextWindow(ctx context.Context, system string, toolSchemas []provider.ToolSchema, messages []types.Message) ([]types.Message, error) {
+	guard := a.config.ContextGuard
+	seed := a.activeSeed()
+	if guard != nil && guard.Disabled {
+		a.contextUsage.beginRequest(len(messages))
+		return seed.prepend(messages), nil
+	}
+
+	window := provider.ContextWindowFor(a.modelProviderForStep(ctx).Config())
+	budget := window - a.outputReserve(window)
+	toolTokens := estimateToolSchemaTokens(toolSchemas)
+
+	used := a.contextUsage.estimate(system, toolTokens+seed.size(), messages)
+	if used <= budget {
+		a.contextUsage.beginRequest(len(messages))
+		return seed.prepend(messages), nil
+	}
+
+	if guard != nil && guard.RefuseOnly {
+		return nil, fmt.Errorf("%w: estimated %d tokens, budget %d of %d", ErrContextWindowExceeded, used, budget, window)
+	}
+
+	// 可压缩的示例对话视为最早的历史，最先丢弃
+	if seed != nil && !seed.pinned {
+		a.dropSeed(ctx)
+		seed = nil
+		if estimateTextTokens(system)+toolTokens+estimateMessagesTokens(messages) <= budget {
+			a.contextUsage.reset()
+			a.contextUsage.beginRequest(len(messages))
+			return messages, nil
+		}
+	}
+
+	keep := defaultKeepRecentMessages
+	if guard != nil && guard.KeepRecentMessages > 0 {
+		keep = guard.KeepRecentMessages
+	}
+
+	a.eventBus.EmitMonitor(&types.MonitorContextCompressionEvent{Phase: "start"})
+
+	// 压缩后的消息没有真实用量可参考，全部重新估算
+	fullEstimate := func(msgs []types.Message) int {
+		return estimateTextTokens(system) + toolTokens + seed.size() + estimateMessagesTokens(msgs)
+	}
+	compacted := compactForContext(messages, keep, func(msgs []types.Message) bool {
+		return fullEstimate(msgs) <= budget
+	})
+	after := fullEstimate(compacted)
+
+	a.eventBus.EmitMonitor(&types.MonitorContextCompressionEvent{
+		Phase:   "end",
+		Summary: fmt.Sprintf("compacted %d messages to %d to fit %d-token context window", len(messages), len(compacted), window),
+		Ratio:   float64(after) / float64(max(used, 1)),
+	})
+
+	if after > budget {
+		agentLog.Warn(ctx, "request exceeds context window after compaction", map[string]any{
+			"agent_id": a.id,
+			"window":   window,
+			"budget":   budget,
+			"tokens":   after,
+		})
+		return nil, fmt.Errorf("%w: estimated %d tokens after compaction, budget %d of %d", ErrContextWindowExceeded, after, budget, window)
+	}
+
+	a.mu.Lock()
+	a.messages = compacted
+	a.mu.Unlock()
+	a.contextUsage.reset()
+	a.contextUsage.beginRequest(len(compacted))
+
+	if err := a.deps.Store.SaveMessages(ctx, a.id, a.messagesForStore(compacted)); err != nil {
+		agentLog.Warn(ctx, "failed to save compacted messages", map[string]any{"agent_id": a.id, "error": err})
+	}
+
+	agentLog.Info(ctx, "compacted messages to fit context window", map[string]any{
+		"agent_id":      a.id,
+		"window":        window,
+		"tokens_before": used,
+		"tokens_after":  after,
+		"before":        len(messages),
+		"after":         len(compacted),
+	})
+	return seed.prepend(compacted), nil
+}
+
+// compactForContext 压缩消息直到 fits 返回 true：
+// 1. 省略最近 keep 条之前的长工具输出
+// 2. 按对话轮次（以用户输入开始）丢弃最早的历史，始终保留最后一轮
+// 返回新的切片，不修改传入的消息和内容块
+func compactForContext(messages []types.Message, keep int, fits func([]types.Message) bool) []types.Message {
+	result := make([]types.Message, len(messages))
+	copy(result, messages)
+
+	cutoff := len(result) - keep
+	for i := 0; i < cutoff; i++ {
+		result[i] = omitToolOutputs(result[i])
+	}
+	if fits(result) {
+		return result
+	}
+
+	// 开头的 system 消息（例如历史摘要）始终保留
+	head := 0
+	for head < len(result) && result[head].Role == types.MessageRoleSystem {
+		head++
+	}
+	for !fits(result) {
+		next := nextTurnStart(result, head+1)
+		if next < 0 {
+			break
+		}
+		result = append(result[:head:head], result[next:]...)
+	}
+	return result
+}
+
+// omitToolOutputs 用占位内容替换消息中的长工具输出
+func omitToolOutputs(msg types.Message) types.Message {
+	changed := false
+	blocks := make([]types.ContentBlock, len(msg.ContentBlocks))
+	for i, block := range msg.ContentBlocks {
+		tr, ok := block.(*types.ToolResultBlock)
+		if !ok || len(tr.Content) < omittedToolOutputMinLen {
+			blocks[i] = block
+			continue
+		}
+		omitted := *tr
+		omitted.Content = omittedToolOutput
+		omitted.Compressed = true
+		omitted.OriginalLength = len(tr.Content)
+		blocks[i] = &omitted
+		changed = true
+	}
+	if changed {
+		msg.ContentBlocks = blocks
+	}
+	return msg
+}
+
+// nextTurnStart 返回 from 之后第一条用户输入消息（非工具结果）的位置，不存在时返回 -1
+func nextTurnStart(messages []types.Message, from int) int {
+	for i := from; i < len(messages); i++ {
+		if messages[i].Role == types.MessageRoleUser && !hasToolResult(messages[i]) {
+			return i
+		}
+	}
+	return -1
+}
+
+// hasToolResult 判断消息是否包含工具结果
+func hasToolResult(msg types.Message) bool {
+	for _, block := range msg.ContentBlocks {
+		if _, ok := block.(*types.ToolResultBlock); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,220 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/astercloud/aster/pkg/provider"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+func createGuardedAgent(t *testing.T, window int, guard *types.ContextGuardConfig) *Agent {
+	t.Helper()
+	deps := setupTestDeps(t)
+
+	ag, err := Create(context.Background(), &types.AgentConfig{
+		TemplateID: "test-template",
+		ModelConfig: &types.ModelConfig{
+			Provider:      "anthropic",
+			Model:         "claude-sonnet-4-5",
+			APIKey:        "test-key",
+			ContextWindow: window,
+		},
+		Sandbox: &types.SandboxConfig{
+			Kind:    types.SandboxKindMock,
+			WorkDir: "/tmp/test",
+		},
+		ContextGuard: guard,
+	}, deps)
+	if err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+	t.Cleanup(func() { _ = ag.Close() })
+	return ag
+}
+
+// conversation 构造 n 轮带大工具输出的对话
+func conversation(turns int, outputSize int) []types.Message {
+	var msgs []types.Message
+	for i := range turns {
+		id := string(rune('a' + i))
+		msgs = append(msgs,
+			types.Message{Role: types.MessageRoleUser, Content: "question " + id},
+			types.Message{Role: types.MessageRoleAssistant, ContentBlocks: []types.ContentBlock{
+				&types.ToolUseBlock{ID: id, Name: "Read", Input: map[string]any{"path": id}},
+			}},
+			types.Message{Role: types.MessageRoleUser, ContentBlocks: []types.ContentBlock{
+				&types.ToolResultBlock{ToolUseID: id, Content: strings.Repeat("x", outputSize)},
+			}},
+			types.Message{Role: types.MessageRoleAssistant, Content: "answer " + id},
+		)
+	}
+	return msgs
+}
+
+func TestCompactForContextOmitsOldToolOutputs(t *testing.T) {
+	msgs := conversation(3, 4000)
+	budget := estimateMessagesTokens(msgs) / 2
+
+	compacted := compactForContext(msgs, 4, func(m []types.Message) bool {
+		return estimateMessagesTokens(m) <= budget
+	})
+	if len(compacted) != len(msgs) {
+		t.Fatalf("expected tool output omission to be enough, got %d messages", len(compacted))
+	}
+	old := compacted[2].ContentBlocks[0].(*types.ToolResultBlock)
+	if !old.Compressed || old.OriginalLength != 4000 {
+		t.Errorf("expected old tool output to be omitted, got %+v", old)
+	}
+	recent := compacted[len(compacted)-2].ContentBlocks[0].(*types.ToolResultBlock)
+	if recent.Compressed {
+		t.Error("recent tool output should be kept")
+	}
+	if msgs[2].ContentBlocks[0].(*types.ToolResultBlock).Compressed {
+		t.Error("original messages must not be modified")
+	}
+}
+
+func TestCompactForContextDropsOldTurns(t *testing.T) {
+	msgs := conversation(3, 100)
+	lastTurn := msgs[8:]
+	budget := estimateMessagesTokens(lastTurn)
+
+	compacted := compactForContext(msgs, 4, func(m []types.Message) bool {
+		return estimateMessagesTokens(m) <= budget
+	})
+	if len(compacted) != 4 || compacted[0].Content != "question c" {
+		t.Fatalf("expected only the last turn to remain, got %d messages starting with %q", len(compacted), compacted[0].Content)
+	}
+}
+
+func TestFitContextWindowCompacts(t *testing.T) {
+	ag := createGuardedAgent(t, 6000, nil)
+	msgs := conversation(4, 8000)
+	ag.messages = msgs
+
+	fitted, err := ag.fitContextWindow(context.Background(), "system", nil, msgs)
+	if err != nil {
+		t.Fatalf("fitContextWindow failed: %v", err)
+	}
+	if estimateMessagesTokens(fitted) >= estimateMessagesTokens(msgs) {
+		t.Error("expected messages to be compacted")
+	}
+	if len(ag.messages) != len(fitted) {
+		t.Error("agent history should be replaced by compacted messages")
+	}
+}
+
+func TestFitContextWindowRefuses(t *testing.T) {
+	ag := createGuardedAgent(t, 6000, &types.ContextGuardConfig{RefuseOnly: true})
+	msgs := conversation(4, 8000)
+
+	_, err := ag.fitContextWindow(context.Background(), "system", nil, msgs)
+	if !errors.Is(err, ErrContextWindowExceeded) {
+		t.Fatalf("expected ErrContextWindowExceeded, got %v", err)
+	}
+
+	// 单轮本身超出窗口时，压缩后依然拒绝
+	ag = createGuardedAgent(t, 6000, nil)
+	_, err = ag.fitContextWindow(context.Background(), "system", nil, conversation(1, 40000))
+	if !errors.Is(err, ErrContextWindowExceeded) {
+		t.Fatalf("expected ErrContextWindowExceeded for oversized turn, got %v", err)
+	}
+}
+
+func TestStatusReportsRemainingContext(t *testing.T) {
+	ag := createGuardedAgent(t, 50000, nil)
+
+	status := ag.Status()
+	if status.ContextWindow != 50000 {
+		t.Errorf("expected context window 50000, got %d", status.ContextWindow)
+	}
+	if status.ContextRemaining <= 0 || status.ContextRemaining >= 50000 {
+		t.Errorf("unexpected remaining context %d", status.ContextRemaining)
+	}
+
+	// 真实用量优先于估算
+	ag.contextUsage.beginRequest(0)
+	ag.observeUsage(&provider.TokenUsage{InputTokens: 30000})
+	if got := ag.Status().ContextUsed; got != 30000 {
+		t.Errorf("expected reported usage 30000, got %d", got)
+	}
+}
+
+// windowedProvider 报告指定上下文窗口的 MockProvider
+type windowedProvider struct {
+	*MockProvider
+	window int
+}
+
+func (p *windowedProvider) Config() *types.ModelConfig {
+	return &types.ModelConfig{Provider: "mock", Model: p.name, ContextWindow: p.window}
+}
+
+func (p *windowedProvider) Create(*types.ModelConfig) (provider.Provider, error) {
+	return p, nil
+}
+
+func TestFitContextWindowStream(t *testing.T) {
+	var sent []types.Message
+	model := &windowedProvider{window: 6000, MockProvider: &MockProvider{
+		name:         "small",
+		capabilities: provider.ProviderCapabilities{SupportStreaming: true},
+		streamFunc: func(_ context.Context, messages []types.Message, _ *provider.StreamOptions) (<-chan provider.StreamChunk, error) {
+			sent = messages
+			ch := make(chan provider.StreamChunk, 1)
+			ch <- provider.StreamChunk{Type: "text", TextDelta: "answer"}
+			close(ch)
+			return ch, nil
+		},
+	}}
+	deps := setupTestDeps(t)
+	deps.ProviderFactory = model
+
+	ag, err := Create(context.Background(), &types.AgentConfig{
+		TemplateID:  "test-template",
+		ModelConfig: &types.ModelConfig{Provider: "mock", Model: "small", ContextWindow: 6000},
+		Sandbox:     &types.SandboxConfig{Kind: types.SandboxKindMock, WorkDir: "/tmp/test"},
+	}, deps)
+	if err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+	defer func() { _ = ag.Close() }()
+	history := conversation(4, 8000)
+	ag.messages = history
+
+	if _, err := StreamCollect(ag.Stream(context.Background(), "next question")); err != nil {
+		t.Fatalf("Stream() error = %v", err)
+	}
+	// Stream 路径同样在请求前压缩到上下文窗口内
+	if estimateMessagesTokens(sent) >= estimateMessagesTokens(history) {
+		t.Errorf("expected streaming request to be compacted, sent %d messages", len(sent))
+	}
+}
+
+func TestStatusCachesToolSchemaTokens(t *testing.T) {
+	ag := createGuardedAgent(t, 50000, nil)
+
+	ag.contextUsage.invalidateTools()
+	calls := 0
+	compute := func() int {
+		calls++
+		return 100
+	}
+	ag.contextUsage.toolSchemaTokens(compute)
+	ag.contextUsage.toolSchemaTokens(compute)
+	if calls != 1 {
+		t.Errorf("tool schema tokens computed %d times, want 1", calls)
+	}
+
+	// 工具过滤变化后重新估算
+	used := ag.Status().ContextUsed
+	if err := ag.SetToolFilter(nil, []string{"Read", "Write"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := ag.Status().ContextUsed; got >= used {
+		t.Errorf("context used = %d after hiding all tools, want less than %d", got, used)
+	}
+}
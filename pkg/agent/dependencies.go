@@ -1,12 +1,26 @@
 package agent
 
 import (
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/astercloud/aster/pkg/artifact"
+	"github.com/astercloud/aster/pkg/audit"
+	"github.com/astercloud/aster/pkg/chaos"
+	"github.com/astercloud/aster/pkg/events"
+	"github.com/astercloud/aster/pkg/experiments"
+	"github.com/astercloud/aster/pkg/guardrails"
+	"github.com/astercloud/aster/pkg/identity"
+	"github.com/astercloud/aster/pkg/permission"
 	"github.com/astercloud/aster/pkg/provider"
 	"github.com/astercloud/aster/pkg/router"
 	"github.com/astercloud/aster/pkg/sandbox"
 	"github.com/astercloud/aster/pkg/store"
 	"github.com/astercloud/aster/pkg/tools"
 	"github.com/astercloud/aster/pkg/types"
+	"github.com/astercloud/aster/pkg/uiproto"
+	"github.com/astercloud/aster/pkg/usage"
 	"github.com/astercloud/aster/pkg/vector/factory"
 )
 
@@ -20,6 +34,10 @@ type Dependencies struct {
 	Router           router.Router
 	TemplateRegistry *TemplateRegistry
 
+	// PromptModules 可选的额外 Prompt 模块（例如由插件提供）
+	// 与内置模块一起按优先级注入 System Prompt，可通过模板的 DisabledPromptModules 按名称禁用
+	PromptModules []PromptModule
+
 	// PromptCompressor 可选的 Prompt 压缩器
 	// 如果配置了且模板启用了压缩，将用于压缩 System Prompt
 	PromptCompressor *EnhancedPromptCompressor
@@ -33,27 +51,105 @@ type Dependencies struct {
 
 	// EmbedderFactory 嵌入模型工厂（用于 RAG 和语义记忆）
 	EmbedderFactory *factory.EmbedderFactory
+
+	// UICatalogs 可选的 UI 组件目录注册表，用于校验 RenderUI 生成的 Surface
+	// 为 nil 时使用 uiproto.DefaultCatalogs
+	UICatalogs *uiproto.CatalogRegistry
+
+	// TrustStore 可选的工作区信任存储
+	// 配置后，本地 WorkDir 在用户明确信任前禁止 Bash/网络类工具
+	TrustStore *permission.TrustStore
+
+	// Artifacts 可选的产出物存储
+	// 配置后，工具（如 Browser 截图）可以保存产出物，并以 MonitorArtifactCreatedEvent 通知
+	Artifacts *artifact.Store
+
+	// Usage 可选的用量记录器
+	// 配置后，每次模型调用的 Token 用量会被持久化，用于计费和成本报表
+	Usage usage.Recorder
+
+	// ToolUsage 可选的工具调用记录器
+	// 配置后，每次工具调用的结果、耗时和结果 Token 会被持久化，用于按模板统计工具使用情况
+	ToolUsage usage.ToolRecorder
+
+	// Experiments 可选的 A/B 实验分组
+	// 配置后，新建 Agent 按实验比例替换模板或模型，并在 Metadata 中标记实验和变体，每次运行的耗时和用量按变体记录
+	Experiments experiments.Assigner
+
+	// EventTransport 可选的事件外部传输（如 events.NATSTransport、events.KafkaTransport）
+	// 配置后，每个 Agent 的事件在进程内分发之外按 Agent/租户发布到外部主题；为 nil 时只在进程内分发
+	EventTransport events.Transport
+
+	// Identity 可选的 Agent 身份注册表
+	// 配置后，每个 Agent 拥有自己的签名密钥对，产出物、Bash 工具产生的 git 提交和 Webhook 都附带可验证的签名
+	Identity *identity.Registry
+
+	// Audit 可选的审计轨迹
+	// 配置后，权限决策、沙箱执行的命令和会话事件按租户写入哈希链审计日志，可导出并独立校验
+	Audit *audit.Trail
+
+	// SafetyClassifiers 可选的内容安全分类器，按名称注册
+	// 模板的 Runtime.Safety 策略通过名称引用，如 guardrails.NewOpenAIModerationGuardrail()
+	SafetyClassifiers map[string]guardrails.Guardrail
+
+	// Faults 可选的故障注入器，仅用于测试和预发环境
+	// 配置后，新建 Agent 的 Provider、Store、工具执行和事件投递按规则注入超时、失败、延迟和丢失
+	Faults *chaos.Injector
 }
 
-// TemplateRegistry 模板注册表
+// TemplateRegistry 模板注册表，可并发读写以支持热加载
+// 带 Version 的模板同时以 "id@version" 注册，Get("id") 返回最近注册的版本
 type TemplateRegistry struct {
+	mu        sync.RWMutex
 	templates map[string]*types.AgentTemplateDefinition
+	// files 从模板目录加载的模板 ID → 文件路径，热加载时据此移除已删除的模板
+	files map[string]string
 }
 
 // NewTemplateRegistry 创建模板注册表
 func NewTemplateRegistry() *TemplateRegistry {
 	return &TemplateRegistry{
 		templates: make(map[string]*types.AgentTemplateDefinition),
+		files:     make(map[string]string),
 	}
 }
 
 // Register 注册模板
 func (tr *TemplateRegistry) Register(template *types.AgentTemplateDefinition) {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	tr.register(template)
+}
+
+func (tr *TemplateRegistry) register(template *types.AgentTemplateDefinition) {
 	tr.templates[template.ID] = template
+	if template.Version != "" {
+		tr.templates[template.ID+TemplateVersionSeparator+template.Version] = template
+	}
+}
+
+// Remove 移除模板及其所有版本
+func (tr *TemplateRegistry) Remove(id string) {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	tr.remove(id)
 }
 
-// Get 获取模板
+func (tr *TemplateRegistry) remove(id string) {
+	delete(tr.templates, id)
+	delete(tr.files, id)
+	prefix := id + TemplateVersionSeparator
+	for key := range tr.templates {
+		if strings.HasPrefix(key, prefix) {
+			delete(tr.templates, key)
+		}
+	}
+}
+
+// Get 获取模板，id 可以是 "id@version" 以固定版本
 func (tr *TemplateRegistry) Get(id string) (*types.AgentTemplateDefinition, error) {
+	tr.mu.RLock()
+	defer tr.mu.RUnlock()
 	template, ok := tr.templates[id]
 	if !ok {
 		return nil, &TemplateNotFoundError{ID: id}
@@ -61,15 +157,37 @@ func (tr *TemplateRegistry) Get(id string) (*types.AgentTemplateDefinition, erro
 	return template, nil
 }
 
-// List 列出所有模板
+// List 列出所有模板的当前版本，按 ID 排序
 func (tr *TemplateRegistry) List() []*types.AgentTemplateDefinition {
+	tr.mu.RLock()
+	defer tr.mu.RUnlock()
 	templates := make([]*types.AgentTemplateDefinition, 0, len(tr.templates))
-	for _, t := range tr.templates {
-		templates = append(templates, t)
+	for key, t := range tr.templates {
+		if !strings.Contains(key, TemplateVersionSeparator) {
+			templates = append(templates, t)
+		}
 	}
+	slices.SortFunc(templates, func(a, b *types.AgentTemplateDefinition) int {
+		return strings.Compare(a.ID, b.ID)
+	})
 	return templates
 }
 
+// Versions 列出模板已注册的版本
+func (tr *TemplateRegistry) Versions(id string) []string {
+	tr.mu.RLock()
+	defer tr.mu.RUnlock()
+	var versions []string
+	prefix := id + TemplateVersionSeparator
+	for key := range tr.templates {
+		if v, ok := strings.CutPrefix(key, prefix); ok {
+			versions = append(versions, v)
+		}
+	}
+	slices.Sort(versions)
+	return versions
+}
+
 // TemplateNotFoundError 模板未找到错误
 type TemplateNotFoundError struct {
 	ID string
@@ -0,0 +1,354 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/astercloud/aster/pkg/dashboard"
+	"github.com/astercloud/aster/pkg/provider"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+// defaultMaxToolFailures 默认连续工具失败升级阈值
+const defaultMaxToolFailures = 2
+
+// defaultLowConfidencePhrases 内置的低置信度回答短语（小写匹配）
+var defaultLowConfidencePhrases = []string{
+	"i'm not sure",
+	"i am not sure",
+	"i don't know",
+	"i do not know",
+	"i cannot determine",
+	"i'm unable to determine",
+	"not confident",
+	"我不确定",
+	"我不知道",
+	"无法确定",
+}
+
+// retryPhrases 识别用户重试意图的短语（小写匹配，仅在消息较短时生效）
+var retryPhrases = []string{
+	"try again",
+	"retry",
+	"one more time",
+	"重试",
+	"再试",
+	"再来一次",
+}
+
+// modelPricing 模型价格表，用于计算升级前后成本差异和会话成本
+var modelPricing = dashboard.NewCostCalculator(nil)
+
+// modelEscalator 按轮模型升级状态
+type modelEscalator struct {
+	mu  sync.Mutex
+	cfg *types.ModelEscalationConfig
+
+	level               int
+	consecutiveFailures int
+	lastInputTokens     int64
+	lastOutputTokens    int64
+	lastUserText        string
+
+	// retired 升级后被替换的 Provider，可能仍被进行中的调用持有，Agent 关闭时统一释放
+	retired []provider.Provider
+}
+
+func newModelEscalator(cfg *types.ModelEscalationConfig) *modelEscalator {
+	return &modelEscalator{cfg: cfg}
+}
+
+func (e *modelEscalator) maxToolFailures() int {
+	if e.cfg.MaxToolFailures > 0 {
+		return e.cfg.MaxToolFailures
+	}
+	return defaultMaxToolFailures
+}
+
+func (e *modelEscalator) lowConfidencePhrases() []string {
+	if len(e.cfg.LowConfidencePhrases) > 0 {
+		return e.cfg.LowConfidencePhrases
+	}
+	return defaultLowConfidencePhrases
+}
+
+// initEscalation 启用升级策略，并把主模型切换到阶梯第一级
+func (a *Agent) initEscalation(ctx context.Context) error {
+	cfg := a.config.Escalation
+	if cfg == nil || !cfg.Enabled || len(cfg.Ladder) == 0 {
+		return nil
+	}
+
+	target, err := a.resolveSwitchTarget(cfg.Ladder[0])
+	if err != nil {
+		return fmt.Errorf("escalation ladder[0]: %w", err)
+	}
+	old, err := a.swapModel(target)
+	if err != nil {
+		return fmt.Errorf("escalation ladder[0]: %w", err)
+	}
+	if old != nil {
+		_ = old.Close()
+	}
+
+	a.escalation = newModelEscalator(cfg)
+	agentLog.Debug(ctx, "model escalation enabled", map[string]any{
+		"agent_id": a.id,
+		"ladder":   cfg.Ladder,
+		"start":    modelRef(target),
+	})
+	return nil
+}
+
+// escalateModel 升级到阶梯的下一级；已在最高级时返回 false
+func (a *Agent) escalateModel(ctx context.Context, reason, detail string) bool {
+	e := a.escalation
+	if e == nil {
+		return false
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	next := e.level + 1
+	if next >= len(e.cfg.Ladder) {
+		agentLog.Debug(ctx, "model escalation requested at top of ladder", map[string]any{
+			"agent_id": a.id,
+			"reason":   reason,
+		})
+		return false
+	}
+
+	from := a.CurrentModel()
+	target, err := a.resolveSwitchTarget(e.cfg.Ladder[next])
+	if err != nil {
+		agentLog.Warn(ctx, "failed to resolve escalation target", map[string]any{"error": err, "target": e.cfg.Ladder[next]})
+		return false
+	}
+	old, err := a.swapModel(target)
+	if err != nil {
+		agentLog.Warn(ctx, "failed to escalate model", map[string]any{"error": err, "target": modelRef(target)})
+		return false
+	}
+	if old != nil {
+		e.retired = append(e.retired, old)
+	}
+	e.level = next
+	e.consecutiveFailures = 0
+
+	event := &types.MonitorModelEscalatedEvent{
+		From:   modelRef(from),
+		To:     modelRef(target),
+		Reason: reason,
+		Level:  next,
+		Detail: detail,
+	}
+	e.fillCostDelta(event, from, target)
+	a.eventBus.EmitMonitor(event)
+
+	agentLog.Info(ctx, "model escalated", map[string]any{
+		"agent_id": a.id,
+		"from":     event.From,
+		"to":       event.To,
+		"reason":   reason,
+		"level":    next,
+	})
+	return true
+}
+
+// resetEscalation 回到阶梯第一级（ResetEachTurn 时在新一轮开始前调用）
+func (a *Agent) resetEscalation(ctx context.Context) {
+	e := a.escalation
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.level == 0 {
+		return
+	}
+	target, err := a.resolveSwitchTarget(e.cfg.Ladder[0])
+	if err != nil {
+		agentLog.Warn(ctx, "failed to reset escalation", map[string]any{"error": err})
+		return
+	}
+	old, err := a.swapModel(target)
+	if err != nil {
+		agentLog.Warn(ctx, "failed to reset escalation", map[string]any{"error": err})
+		return
+	}
+	if old != nil {
+		e.retired = append(e.retired, old)
+	}
+	e.level = 0
+	e.consecutiveFailures = 0
+}
+
+// clearEscalation 回到阶梯第一级并清空失败计数与上一条用户消息（会话重置时调用）
+func (a *Agent) clearEscalation(ctx context.Context) {
+	e := a.escalation
+	if e == nil {
+		return
+	}
+	a.resetEscalation(ctx)
+
+	e.mu.Lock()
+	e.consecutiveFailures = 0
+	e.lastInputTokens = 0
+	e.lastOutputTokens = 0
+	e.lastUserText = ""
+	e.mu.Unlock()
+}
+
+// fillCostDelta 根据定价表计算升级前后的单价差与估算成本差
+func (e *modelEscalator) fillCostDelta(event *types.MonitorModelEscalatedEvent, from, to *types.ModelConfig) {
+	if from == nil || to == nil {
+		return
+	}
+	fromPricing := modelPricing.GetPricing(from.Model)
+	toPricing := modelPricing.GetPricing(to.Model)
+
+	event.InputPriceDeltaPerM = toPricing.InputPricePerM - fromPricing.InputPricePerM
+	event.OutputPriceDeltaPerM = toPricing.OutputPricePerM - fromPricing.OutputPricePerM
+	event.EstimatedCostDelta = modelPricing.Calculate(e.lastInputTokens, e.lastOutputTokens, to.Model).Amount -
+		modelPricing.Calculate(e.lastInputTokens, e.lastOutputTokens, from.Model).Amount
+	event.Currency = toPricing.Currency
+	if event.Currency == "" {
+		event.Currency = "USD"
+	}
+}
+
+// observeUsage 记录最近一次模型调用的 Token 用量，用于上下文窗口估算、运行输出额度和升级成本估算
+func (a *Agent) observeUsage(usage *provider.TokenUsage) {
+	if usage == nil {
+		return
+	}
+	a.contextUsage.observe(usage)
+
+	a.mu.Lock()
+	a.run.inputTokens += usage.InputTokens
+	a.run.outputTokens += usage.OutputTokens
+	a.mu.Unlock()
+
+	e := a.escalation
+	if e == nil {
+		return
+	}
+	e.mu.Lock()
+	e.lastInputTokens = usage.InputTokens
+	e.lastOutputTokens = usage.OutputTokens
+	e.mu.Unlock()
+}
+
+// observeToolResults 统计连续工具失败，达到阈值时升级
+func (a *Agent) observeToolResults(ctx context.Context, results []types.ContentBlock) {
+	e := a.escalation
+	if e == nil {
+		return
+	}
+
+	e.mu.Lock()
+	for _, block := range results {
+		tr, ok := block.(*types.ToolResultBlock)
+		if !ok {
+			continue
+		}
+		if tr.IsError {
+			e.consecutiveFailures++
+		} else {
+			e.consecutiveFailures = 0
+		}
+	}
+	failures := e.consecutiveFailures
+	threshold := e.maxToolFailures()
+	e.mu.Unlock()
+
+	if failures >= threshold {
+		a.escalateModel(ctx, types.EscalationReasonToolFailures, fmt.Sprintf("%d consecutive tool failures", failures))
+	}
+}
+
+// observeAssistantAnswer 检测低置信度的最终回答，升级后下一轮生效
+func (a *Agent) observeAssistantAnswer(ctx context.Context, msg types.Message) {
+	e := a.escalation
+	if e == nil {
+		return
+	}
+
+	text := strings.ToLower(extractText(msg))
+	if text == "" {
+		return
+	}
+	for _, phrase := range e.lowConfidencePhrases() {
+		if strings.Contains(text, strings.ToLower(phrase)) {
+			a.escalateModel(ctx, types.EscalationReasonLowConfidence, phrase)
+			return
+		}
+	}
+}
+
+// observeUserMessage 检测用户重试；非重试消息在 ResetEachTurn 时回到阶梯第一级
+func (a *Agent) observeUserMessage(ctx context.Context, text string) {
+	e := a.escalation
+	if e == nil {
+		return
+	}
+
+	normalized := strings.ToLower(strings.TrimSpace(text))
+	e.mu.Lock()
+	last := e.lastUserText
+	e.lastUserText = normalized
+	e.mu.Unlock()
+
+	if !e.cfg.DisableRetryDetection && isRetryMessage(normalized, last) {
+		a.escalateModel(ctx, types.EscalationReasonUserRetry, text)
+		return
+	}
+	if e.cfg.ResetEachTurn {
+		a.resetEscalation(ctx)
+	}
+}
+
+// isRetryMessage 判断用户消息是否为重试：与上一条消息相同，或是简短的重试指令
+func isRetryMessage(text, last string) bool {
+	if text == "" {
+		return false
+	}
+	if text == last {
+		return true
+	}
+	// 只把简短消息视为重试指令，避免误判正常提问中出现的 "retry"
+	if len([]rune(text)) > 40 {
+		return false
+	}
+	for _, phrase := range retryPhrases {
+		if strings.Contains(text, phrase) {
+			return true
+		}
+	}
+	return false
+}
+
+// extractText 拼接消息中的文本块
+func extractText(msg types.Message) string {
+	if len(msg.ContentBlocks) == 0 {
+		return msg.Content
+	}
+	var sb strings.Builder
+	for _, block := range msg.ContentBlocks {
+		if tb, ok := block.(*types.TextBlock); ok {
+			sb.WriteString(tb.Text)
+		}
+	}
+	return sb.String()
+}
+
+// closeRetiredProviders 释放升级过程中被替换的 Provider
+func (e *modelEscalator) closeRetiredProviders() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, prov := range e.retired {
+		_ = prov.Close()
+	}
+	e.retired = nil
+}
@@ -0,0 +1,177 @@
+package agent
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/astercloud/aster/pkg/provider"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+func createEscalatingAgent(t *testing.T, cfg *types.ModelEscalationConfig) *Agent {
+	t.Helper()
+	deps := setupTestDeps(t)
+
+	ag, err := Create(context.Background(), &types.AgentConfig{
+		TemplateID: "test-template",
+		ModelConfig: &types.ModelConfig{
+			Provider: "anthropic",
+			Model:    "claude-sonnet-4-5",
+			APIKey:   "test-key",
+		},
+		Sandbox: &types.SandboxConfig{
+			Kind:    types.SandboxKindMock,
+			WorkDir: "/tmp/test",
+		},
+		Escalation: cfg,
+	}, deps)
+	if err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+	t.Cleanup(func() { _ = ag.Close() })
+	return ag
+}
+
+func TestEscalationStartsOnFirstRung(t *testing.T) {
+	ag := createEscalatingAgent(t, &types.ModelEscalationConfig{
+		Enabled: true,
+		Ladder:  []string{"anthropic/claude-haiku-4-5", "anthropic/claude-opus-4"},
+	})
+
+	if got := ag.Status().Model; got != "anthropic/claude-haiku-4-5" {
+		t.Errorf("expected agent to start on cheapest rung, got %q", got)
+	}
+}
+
+func TestEscalationOnToolFailures(t *testing.T) {
+	ag := createEscalatingAgent(t, &types.ModelEscalationConfig{
+		Enabled:         true,
+		Ladder:          []string{"anthropic/claude-haiku-4-5", "anthropic/claude-opus-4"},
+		MaxToolFailures: 2,
+	})
+	ch := ag.Subscribe([]types.AgentChannel{types.ChannelMonitor}, nil)
+	ctx := context.Background()
+
+	ag.observeToolResults(ctx, []types.ContentBlock{&types.ToolResultBlock{IsError: true}})
+	if got := ag.Status().Model; got != "anthropic/claude-haiku-4-5" {
+		t.Fatalf("should not escalate after a single failure, got %q", got)
+	}
+
+	ag.observeToolResults(ctx, []types.ContentBlock{&types.ToolResultBlock{IsError: true}})
+	if got := ag.Status().Model; got != "anthropic/claude-opus-4" {
+		t.Fatalf("expected escalation after repeated failures, got %q", got)
+	}
+
+	deadline := time.After(time.Second)
+	for {
+		select {
+		case env := <-ch:
+			ev, ok := env.Event.(*types.MonitorModelEscalatedEvent)
+			if !ok {
+				continue
+			}
+			if ev.Reason != types.EscalationReasonToolFailures || ev.Level != 1 {
+				t.Errorf("unexpected escalation event %+v", ev)
+			}
+			if ev.InputPriceDeltaPerM <= 0 {
+				t.Errorf("expected positive input price delta, got %v", ev.InputPriceDeltaPerM)
+			}
+			return
+		case <-deadline:
+			t.Fatal("timed out waiting for model_escalated event")
+		}
+	}
+}
+
+func TestEscalationStopsAtTopOfLadder(t *testing.T) {
+	ag := createEscalatingAgent(t, &types.ModelEscalationConfig{
+		Enabled: true,
+		Ladder:  []string{"anthropic/claude-haiku-4-5"},
+	})
+
+	if ag.escalateModel(context.Background(), types.EscalationReasonUserRetry, "") {
+		t.Error("should not escalate beyond the last rung")
+	}
+}
+
+func TestEscalationLowConfidenceAndReset(t *testing.T) {
+	ag := createEscalatingAgent(t, &types.ModelEscalationConfig{
+		Enabled:       true,
+		Ladder:        []string{"anthropic/claude-haiku-4-5", "anthropic/claude-opus-4"},
+		ResetEachTurn: true,
+	})
+	ctx := context.Background()
+
+	ag.observeAssistantAnswer(ctx, types.Message{
+		Role:          types.MessageRoleAssistant,
+		ContentBlocks: []types.ContentBlock{&types.TextBlock{Text: "Honestly, I'm not sure about that."}},
+	})
+	if got := ag.Status().Model; got != "anthropic/claude-opus-4" {
+		t.Fatalf("expected low-confidence escalation, got %q", got)
+	}
+
+	ag.observeUserMessage(ctx, "Now write a haiku about Go")
+	if got := ag.Status().Model; got != "anthropic/claude-haiku-4-5" {
+		t.Errorf("expected reset to first rung on new turn, got %q", got)
+	}
+}
+
+func TestIsRetryMessage(t *testing.T) {
+	tests := []struct {
+		text string
+		last string
+		want bool
+	}{
+		{"try again", "", true},
+		{"请重试", "", true},
+		{"fix the bug", "fix the bug", true},
+		{"fix the bug", "write tests", false},
+		{"how do i configure the retry policy for http clients in this service?", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		if got := isRetryMessage(tt.text, tt.last); got != tt.want {
+			t.Errorf("isRetryMessage(%q, %q) = %v, want %v", tt.text, tt.last, got, tt.want)
+		}
+	}
+}
+
+func TestEscalationStream(t *testing.T) {
+	deps := setupTestDeps(t)
+	factory := NewMockProviderFactory()
+	for _, name := range []string{"cheap", "strong"} {
+		factory.SetProvider("mock/"+name, &MockProvider{
+			name:         name,
+			capabilities: provider.ProviderCapabilities{SupportStreaming: true},
+			streamFunc: func(context.Context, []types.Message, *provider.StreamOptions) (<-chan provider.StreamChunk, error) {
+				ch := make(chan provider.StreamChunk, 1)
+				ch <- provider.StreamChunk{Type: "text", TextDelta: "Honestly, I'm not sure about that."}
+				close(ch)
+				return ch, nil
+			},
+		})
+	}
+	deps.ProviderFactory = factory
+
+	ag, err := Create(context.Background(), &types.AgentConfig{
+		TemplateID:  "test-template",
+		ModelConfig: &types.ModelConfig{Provider: "mock", Model: "cheap"},
+		Sandbox:     &types.SandboxConfig{Kind: types.SandboxKindMock, WorkDir: "/tmp/test"},
+		Escalation:  &types.ModelEscalationConfig{Enabled: true, Ladder: []string{"mock/cheap", "mock/strong"}},
+	}, deps)
+	if err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+	defer func() { _ = ag.Close() }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := StreamCollect(ag.Stream(ctx, "which port does the server use?")); err != nil {
+		t.Fatalf("Stream() error = %v", err)
+	}
+	// Stream 路径的低置信度回答同样触发升级
+	if got := ag.Status().Model; got != "mock/strong" {
+		t.Errorf("expected low-confidence escalation through Stream, got %q", got)
+	}
+}
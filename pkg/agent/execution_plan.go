@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sync"
 
 	"github.com/astercloud/aster/pkg/executionplan"
 	"github.com/astercloud/aster/pkg/tools"
@@ -16,8 +17,9 @@ type ExecutionPlanManager struct {
 	generator *executionplan.Generator
 	executor  *executionplan.Executor
 
-	// 当前活动的执行计划
+	// 当前活动的执行计划；计划模式追加步骤和规划-执行模式更新步骤时由 mu 保护
 	currentPlan *executionplan.ExecutionPlan
+	mu          sync.Mutex
 
 	// 回调函数
 	onPlanGenerated func(plan *executionplan.ExecutionPlan)
@@ -237,11 +239,15 @@ func (m *ExecutionPlanManager) ExecutePlanDirect(ctx context.Context, plan *exec
 
 // GetCurrentPlan 获取当前执行计划
 func (m *ExecutionPlanManager) GetCurrentPlan() *executionplan.ExecutionPlan {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	return m.currentPlan
 }
 
 // GetPlanSummary 获取当前计划摘要
 func (m *ExecutionPlanManager) GetPlanSummary() *executionplan.PlanSummary {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	if m.currentPlan == nil {
 		return nil
 	}
@@ -251,6 +257,8 @@ func (m *ExecutionPlanManager) GetPlanSummary() *executionplan.PlanSummary {
 
 // FormatCurrentPlan 格式化当前计划为可读文本
 func (m *ExecutionPlanManager) FormatCurrentPlan() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	if m.currentPlan == nil {
 		return "No active execution plan"
 	}
@@ -295,5 +303,7 @@ func (m *ExecutionPlanManager) ResumePlan(ctx context.Context) error {
 
 // ClearPlan 清除当前计划
 func (m *ExecutionPlanManager) ClearPlan() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	m.currentPlan = nil
 }
@@ -0,0 +1,77 @@
+package agent
+
+import (
+	"context"
+	"time"
+
+	"github.com/astercloud/aster/pkg/experiments"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+// applyExperiment 为新建的 Agent 分配实验变体，替换模板和模型并在 Metadata 中标记
+// 分组失败只记录日志，Agent 按原配置创建
+func applyExperiment(ctx context.Context, config *types.AgentConfig, assigner experiments.Assigner) {
+	a, err := assigner.Assign(ctx, config)
+	if err != nil {
+		agentLog.Warn(ctx, "experiment assignment failed", map[string]any{"agent_id": config.AgentID, "error": err.Error()})
+		return
+	}
+	if a == nil {
+		return
+	}
+
+	config.TemplateID = a.TemplateID
+	if a.Model != "" {
+		if config.ModelConfig != nil {
+			mc := *config.ModelConfig
+			mc.Model = a.Model
+			config.ModelConfig = &mc
+		} else {
+			config.ModelConfig = &types.ModelConfig{Provider: inferProviderFromModel(a.Model), Model: a.Model}
+		}
+	}
+	if config.Metadata == nil {
+		config.Metadata = make(map[string]any)
+	}
+	config.Metadata[experiments.MetadataExperimentID] = a.ExperimentID
+	config.Metadata[experiments.MetadataExperimentVariant] = a.Variant
+	config.Metadata[experiments.MetadataExperimentVersion] = a.Version
+
+	agentLog.Info(ctx, "agent assigned to experiment", map[string]any{
+		"agent_id":      config.AgentID,
+		"experiment_id": a.ExperimentID,
+		"variant":       a.Variant,
+		"template_id":   a.TemplateID,
+	})
+}
+
+// recordExperimentRun 参与实验的 Agent 完成一次运行后，记录耗时和 Token 用量
+func (a *Agent) recordExperimentRun(ctx context.Context, termination types.TerminationReason) {
+	if a.deps.Experiments == nil {
+		return
+	}
+	experimentID, _ := a.config.Metadata[experiments.MetadataExperimentID].(string)
+	variant, _ := a.config.Metadata[experiments.MetadataExperimentVariant].(string)
+	if experimentID == "" || variant == "" {
+		return
+	}
+
+	a.mu.RLock()
+	run := experiments.Run{
+		ExperimentID: experimentID,
+		Variant:      variant,
+		AgentID:      a.id,
+		Completed:    termination == types.TerminationCompleted,
+		DurationMs:   time.Since(a.run.started).Milliseconds(),
+		InputTokens:  a.run.inputTokens,
+		OutputTokens: a.run.outputTokens,
+	}
+	a.mu.RUnlock()
+	if cfg := a.modelProviderForStep(ctx).Config(); cfg != nil {
+		run.Model = cfg.Model
+	}
+
+	if err := a.deps.Experiments.RecordRun(ctx, run); err != nil {
+		agentLog.Warn(ctx, "failed to record experiment run", map[string]any{"agent_id": a.id, "error": err.Error()})
+	}
+}
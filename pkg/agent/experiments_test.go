@@ -0,0 +1,78 @@
+package agent
+
+import (
+	"context"
+	"testing"
+
+	"github.com/astercloud/aster/pkg/experiments"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+type stubAssigner struct {
+	assignment *experiments.Assignment
+	runs       []experiments.Run
+}
+
+func (s *stubAssigner) Assign(_ context.Context, config *types.AgentConfig) (*experiments.Assignment, error) {
+	if s.assignment == nil {
+		return nil, nil
+	}
+	a := *s.assignment
+	a.AgentID = config.AgentID
+	return &a, nil
+}
+
+func (s *stubAssigner) RecordRun(_ context.Context, run experiments.Run) error {
+	s.runs = append(s.runs, run)
+	return nil
+}
+
+func TestCreate_AppliesExperimentVariant(t *testing.T) {
+	deps := setupTestDeps(t)
+	deps.TemplateRegistry.Register(&types.AgentTemplateDefinition{
+		ID:           "test-template-v2",
+		SystemPrompt: "You are a terse test assistant.",
+		Model:        "claude-sonnet-4-5",
+		Tools:        []any{"Read"},
+	})
+	assigner := &stubAssigner{assignment: &experiments.Assignment{
+		ExperimentID: "exp-1",
+		Variant:      "terse",
+		Version:      2,
+		TemplateID:   "test-template-v2",
+		Model:        "claude-haiku-4-5",
+	}}
+	deps.Experiments = assigner
+
+	ag, err := Create(context.Background(), &types.AgentConfig{
+		TemplateID:  "test-template",
+		ModelConfig: &types.ModelConfig{Provider: "anthropic", Model: "claude-sonnet-4-5", APIKey: "test-key"},
+		Sandbox:     &types.SandboxConfig{Kind: types.SandboxKindMock, WorkDir: "/tmp/test"},
+	}, deps)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	t.Cleanup(func() { _ = ag.Close() })
+
+	if ag.template.ID != "test-template-v2" {
+		t.Errorf("template = %s, want test-template-v2", ag.template.ID)
+	}
+	if cfg := ag.provider.Config(); cfg.Model != "claude-haiku-4-5" || cfg.APIKey != "test-key" {
+		t.Errorf("model config = %+v, want variant model with original credentials", cfg)
+	}
+	md := ag.config.Metadata
+	if md[experiments.MetadataExperimentID] != "exp-1" || md[experiments.MetadataExperimentVariant] != "terse" || md[experiments.MetadataExperimentVersion] != 2 {
+		t.Errorf("metadata = %v", md)
+	}
+
+	ag.mu.Lock()
+	ag.run.inputTokens, ag.run.outputTokens = 120, 30
+	ag.mu.Unlock()
+	ag.recordExperimentRun(context.Background(), types.TerminationCompleted)
+	if len(assigner.runs) != 1 {
+		t.Fatalf("recorded %d runs, want 1", len(assigner.runs))
+	}
+	if r := assigner.runs[0]; r.ExperimentID != "exp-1" || r.Variant != "terse" || r.AgentID != ag.ID() || !r.Completed || r.InputTokens != 120 || r.Model != "claude-haiku-4-5" {
+		t.Errorf("run = %+v", r)
+	}
+}
@@ -0,0 +1,60 @@
+package agent
+
+import (
+	"github.com/astercloud/aster/pkg/sandbox"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+// fileWriteTools 通过 file_path 参数写文件的工具，其写入不作为外部变更报告
+var fileWriteTools = map[string]bool{
+	"Write":      true,
+	"Edit":       true,
+	"MultiEdit":  true,
+	"write_file": true,
+	"edit_file":  true,
+}
+
+// newWatchManager 创建 Watch 工具使用的文件监听管理器
+// 每次变更以 MonitorFileChangedEvent 发到事件总线
+func (a *Agent) newWatchManager() *sandbox.WatchManager {
+	m := sandbox.NewWatchManager(a.sandbox)
+	m.OnChange(func(c sandbox.FileChange) {
+		a.eventBus.EmitMonitor(&types.MonitorFileChangedEvent{
+			Path:    c.Path,
+			Op:      string(c.Op),
+			WatchID: c.WatchID,
+			Mtime:   c.Time,
+		})
+	})
+	return m
+}
+
+// FileWatches 返回 Watch 工具创建的文件监听订阅
+func (a *Agent) FileWatches() *sandbox.WatchManager {
+	return a.fileWatches
+}
+
+// ignoreOwnWrites 忽略本轮工具调用自身写入的文件
+func (a *Agent) ignoreOwnWrites(toolUses []*types.ToolUseBlock) {
+	for _, tu := range toolUses {
+		if !fileWriteTools[tu.Name] {
+			continue
+		}
+		if path, ok := tu.Input["file_path"].(string); ok && path != "" {
+			a.fileWatches.Ignore(path)
+		}
+	}
+}
+
+// fileChangeNotice 取出 notify 订阅的外部变更，格式化为注入上下文的文本块
+// 没有变更时返回 nil
+func (a *Agent) fileChangeNotice() *types.TextBlock {
+	if a.fileWatches == nil {
+		return nil
+	}
+	text := sandbox.FormatFileChanges(a.fileWatches.DrainNotifications())
+	if text == "" {
+		return nil
+	}
+	return &types.TextBlock{Text: text}
+}
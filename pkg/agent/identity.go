@@ -0,0 +1,38 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/astercloud/aster/pkg/identity"
+)
+
+// initIdentity 配置了身份注册表时加载或生成 Agent 的密钥对
+func (a *Agent) initIdentity(ctx context.Context) error {
+	if a.deps == nil || a.deps.Identity == nil {
+		return nil
+	}
+	signer, err := a.deps.Identity.Signer(ctx, a.id)
+	if err != nil {
+		return fmt.Errorf("load agent identity: %w", err)
+	}
+	a.signer = signer
+	return nil
+}
+
+// Identity 返回 Agent 的公开身份，未配置身份注册表时返回 nil
+func (a *Agent) Identity() *identity.Identity {
+	if a.signer == nil {
+		return nil
+	}
+	id := a.signer.Identity()
+	return &id
+}
+
+// Sign 以 Agent 身份对内容签名（如发出的 Webhook），未配置身份注册表时返回 nil
+func (a *Agent) Sign(kind identity.Kind, content []byte) *identity.Signature {
+	if a.signer == nil {
+		return nil
+	}
+	return a.signer.Sign(kind, content)
+}
@@ -0,0 +1,68 @@
+package agent
+
+import (
+	"context"
+	"errors"
+
+	"github.com/astercloud/aster/pkg/agenterr"
+	"github.com/astercloud/aster/pkg/tools"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+// defaultMaxInputCorrections 默认允许的参数修正重试次数
+const defaultMaxInputCorrections = 3
+
+func (a *Agent) maxInputCorrections() int {
+	if a.config != nil && a.config.MaxInputCorrections > 0 {
+		return a.config.MaxInputCorrections
+	}
+	return defaultMaxInputCorrections
+}
+
+// inputValidationFailure 工具参数校验失败时返回结构化的工具结果：
+// 逐字段给出问题、期望类型和示例，并附带示例参数，让模型修正后重试；
+// 同一工具连续失败超过修正次数后不再给出示例，提示模型停止重试
+func (a *Agent) inputValidationFailure(ctx context.Context, tu *types.ToolUseBlock, tool tools.Tool, err error) *types.ToolResultBlock {
+	a.mu.Lock()
+	if a.run.invalidInputs == nil {
+		a.run.invalidInputs = make(map[string]int)
+	}
+	a.run.invalidInputs[tu.Name]++
+	attempt := a.run.invalidInputs[tu.Name]
+	a.mu.Unlock()
+
+	toolErr := agenterr.From(err)
+	a.updateToolRecord(tu.ID, types.ToolCallStateFailed, toolErr)
+	a.emitToolError(tu, toolErr)
+
+	maxAttempts := a.maxInputCorrections()
+	extra := map[string]any{
+		"attempt":      attempt,
+		"max_attempts": maxAttempts,
+	}
+	var inputErr *tools.InputValidationError
+	if errors.As(err, &inputErr) {
+		extra["validation_errors"] = inputErr.Issues
+	}
+	if attempt > maxAttempts {
+		extra["corrections_exhausted"] = true
+		extra["hint"] = "参数多次校验失败，请停止重试该工具，向用户说明问题或改用其他方式"
+		procLog.Warn(ctx, "tool input corrections exhausted", map[string]any{
+			"tool":     tu.Name,
+			"attempts": attempt,
+		})
+		return toolFailure(tu.ID, toolErr, extra)
+	}
+	if example := tools.ExampleInput(tool.InputSchema()); len(example) > 0 {
+		extra["example_input"] = example
+	}
+	extra["hint"] = "请按 validation_errors 修正参数后重新调用"
+	return toolFailure(tu.ID, toolErr, extra)
+}
+
+// resetInputCorrections 工具调用通过校验后清零该工具的修正计数
+func (a *Agent) resetInputCorrections(name string) {
+	a.mu.Lock()
+	delete(a.run.invalidInputs, name)
+	a.mu.Unlock()
+}
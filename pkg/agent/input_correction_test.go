@@ -0,0 +1,68 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/astercloud/aster/pkg/types"
+)
+
+func TestInputValidationFeedback(t *testing.T) {
+	deps := setupTestDeps(t)
+	ag, err := Create(context.Background(), &types.AgentConfig{
+		TemplateID: "test-template",
+		ModelConfig: &types.ModelConfig{
+			Provider: "anthropic",
+			Model:    "claude-sonnet-4-5",
+			APIKey:   "test-key",
+		},
+		Sandbox:             &types.SandboxConfig{Kind: types.SandboxKindMock, WorkDir: "/tmp/test"},
+		MaxInputCorrections: 1,
+	}, deps)
+	if err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+	defer func() { _ = ag.Close() }()
+
+	call := func(id string, input map[string]any) map[string]any {
+		t.Helper()
+		block, ok := ag.executeSingleTool(context.Background(), &types.ToolUseBlock{ID: id, Name: "Read", Input: input}).(*types.ToolResultBlock)
+		if !ok || !block.IsError {
+			t.Fatalf("result = %+v, want error result", block)
+		}
+		var payload map[string]any
+		if err := json.Unmarshal([]byte(block.Content), &payload); err != nil {
+			t.Fatalf("content is not JSON: %v", err)
+		}
+		return payload
+	}
+
+	payload := call("r1", map[string]any{"file_path": 42, "offset": "x"})
+	if payload["code"] != "tool_invalid_input" || payload["attempt"] != 1.0 {
+		t.Fatalf("payload = %v", payload)
+	}
+	issues, _ := payload["validation_errors"].([]any)
+	if len(issues) != 2 {
+		t.Fatalf("validation_errors = %v, want 2 issues", payload["validation_errors"])
+	}
+	first := issues[0].(map[string]any)
+	if first["field"] != "file_path" || first["expected"] != "string" || first["example"] != "string" {
+		t.Errorf("issue = %v", first)
+	}
+	if example, _ := payload["example_input"].(map[string]any); example["file_path"] != "string" {
+		t.Errorf("example_input = %v", payload["example_input"])
+	}
+
+	// 超过修正次数后不再给出示例
+	payload = call("r2", map[string]any{})
+	if payload["corrections_exhausted"] != true || payload["example_input"] != nil {
+		t.Errorf("payload after exhausting corrections = %v", payload)
+	}
+
+	// 通过校验的调用重置计数
+	ag.executeSingleTool(context.Background(), &types.ToolUseBlock{ID: "r3", Name: "Read", Input: map[string]any{"file_path": "/tmp/test/a.txt"}})
+	if payload = call("r4", map[string]any{}); payload["attempt"] != 1.0 {
+		t.Errorf("attempt after a valid call = %v, want 1", payload["attempt"])
+	}
+}
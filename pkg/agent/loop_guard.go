@@ -0,0 +1,348 @@
+package agent
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/astercloud/aster/pkg/agenterr"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+// 循环保护默认阈值
+const (
+	defaultLoopMaxRepeatedCalls = 3
+	defaultLoopMaxOscillations  = 2
+	defaultLoopMaxStallSteps    = 5
+	defaultLoopWindow           = 10
+)
+
+// defaultLoopInterventions 默认介入顺序：先提示反思，再升级模型，最后交给用户
+var defaultLoopInterventions = []string{
+	types.LoopInterventionReflect,
+	types.LoopInterventionEscalate,
+	types.LoopInterventionPause,
+}
+
+// loopDetection 一次循环检测结果
+type loopDetection struct {
+	kind   string
+	detail string
+}
+
+// fileHistory 单个文件最近的修改，用于识别来回修改
+type fileHistory struct {
+	hasEdit   bool
+	lastOld   string
+	lastNew   string
+	hashes    []string // 最近两次 Write 的内容摘要
+	reversals int
+}
+
+// loopGuard 按步骤检测重复调用、来回修改和无进展
+type loopGuard struct {
+	mu  sync.Mutex
+	cfg *types.LoopGuardConfig
+
+	recent     []string            // 窗口内最近的调用签名
+	seen       map[string]struct{} // 成功执行过的调用签名
+	files      map[string]*fileHistory
+	stallSteps int
+	level      int // 下一次介入在 Interventions 中的位置
+}
+
+func newLoopGuard(cfg *types.LoopGuardConfig) *loopGuard {
+	return &loopGuard{
+		cfg:   cfg,
+		seen:  make(map[string]struct{}),
+		files: make(map[string]*fileHistory),
+	}
+}
+
+// reset 清空检测历史，Agent 被新的会话复用时调用
+func (g *loopGuard) reset() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.recent = nil
+	g.seen = make(map[string]struct{})
+	g.files = make(map[string]*fileHistory)
+	g.stallSteps = 0
+	g.level = 0
+}
+
+func (g *loopGuard) maxRepeatedCalls() int {
+	if g.cfg.MaxRepeatedCalls > 0 {
+		return g.cfg.MaxRepeatedCalls
+	}
+	return defaultLoopMaxRepeatedCalls
+}
+
+func (g *loopGuard) maxOscillations() int {
+	if g.cfg.MaxOscillations > 0 {
+		return g.cfg.MaxOscillations
+	}
+	return defaultLoopMaxOscillations
+}
+
+func (g *loopGuard) maxStallSteps() int {
+	if g.cfg.MaxStallSteps > 0 {
+		return g.cfg.MaxStallSteps
+	}
+	return defaultLoopMaxStallSteps
+}
+
+func (g *loopGuard) window() int {
+	if g.cfg.Window > 0 {
+		return g.cfg.Window
+	}
+	return defaultLoopWindow
+}
+
+func (g *loopGuard) interventions() []string {
+	if len(g.cfg.Interventions) > 0 {
+		return g.cfg.Interventions
+	}
+	return defaultLoopInterventions
+}
+
+// callSignature 工具名与参数的规范化签名（json 编码 map 时键已排序）
+func callSignature(tu *types.ToolUseBlock) string {
+	input, err := json.Marshal(tu.Input)
+	if err != nil {
+		return tu.Name
+	}
+	return tu.Name + ":" + string(input)
+}
+
+// observe 记录一步的工具调用与结果，返回检测到的循环；未检测到时返回 nil
+func (g *loopGuard) observe(toolUses []*types.ToolUseBlock, results []types.ContentBlock) *loopDetection {
+	failed := make(map[string]bool, len(results))
+	for _, block := range results {
+		if tr, ok := block.(*types.ToolResultBlock); ok {
+			failed[tr.ToolUseID] = tr.IsError
+		}
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	var detection *loopDetection
+	progress := false
+	for _, tu := range toolUses {
+		sig := callSignature(tu)
+		g.recent = append(g.recent, sig)
+		if n := len(g.recent) - g.window(); n > 0 {
+			g.recent = g.recent[n:]
+		}
+
+		if failed[tu.ID] {
+			continue
+		}
+		if _, ok := g.seen[sig]; !ok {
+			g.seen[sig] = struct{}{}
+			progress = true
+		}
+		if d := g.observeFileChange(tu); d != nil && detection == nil {
+			detection = d
+		}
+	}
+
+	if detection == nil {
+		detection = g.detectRepeatedCall()
+	}
+
+	if progress {
+		g.stallSteps = 0
+	} else {
+		g.stallSteps++
+		if detection == nil && g.stallSteps >= g.maxStallSteps() {
+			detection = &loopDetection{
+				kind:   types.LoopKindStall,
+				detail: fmt.Sprintf("%d consecutive steps without a successful new tool call", g.stallSteps),
+			}
+		}
+	}
+
+	if detection == nil && progress {
+		g.level = 0
+	}
+	return detection
+}
+
+// observeFileChange 识别同一文件的来回修改：Edit 的新旧内容互换，或 Write 内容回到上上次
+func (g *loopGuard) observeFileChange(tu *types.ToolUseBlock) *loopDetection {
+	path, _ := tu.Input["file_path"].(string)
+	if path == "" {
+		return nil
+	}
+
+	h := g.files[path]
+	if h == nil {
+		h = &fileHistory{}
+	}
+	switch tu.Name {
+	case "Edit":
+		oldString, _ := tu.Input["old_string"].(string)
+		newString, _ := tu.Input["new_string"].(string)
+		if h.hasEdit && oldString == h.lastNew && newString == h.lastOld {
+			h.reversals++
+		}
+		h.hasEdit, h.lastOld, h.lastNew = true, oldString, newString
+	case "Write":
+		content, _ := tu.Input["content"].(string)
+		sum := sha256.Sum256([]byte(content))
+		hash := hex.EncodeToString(sum[:])
+		if n := len(h.hashes); n == 2 && h.hashes[0] == hash && h.hashes[1] != hash {
+			h.reversals++
+		}
+		h.hashes = append(h.hashes, hash)
+		if len(h.hashes) > 2 {
+			h.hashes = h.hashes[1:]
+		}
+	default:
+		return nil
+	}
+	g.files[path] = h
+
+	if h.reversals >= g.maxOscillations() {
+		return &loopDetection{
+			kind:   types.LoopKindOscillation,
+			detail: fmt.Sprintf("%s reverted back and forth %d times", path, h.reversals),
+		}
+	}
+	return nil
+}
+
+// detectRepeatedCall 检查窗口内是否有调用重复达到阈值
+func (g *loopGuard) detectRepeatedCall() *loopDetection {
+	counts := make(map[string]int, len(g.recent))
+	for _, sig := range g.recent {
+		counts[sig]++
+		if counts[sig] >= g.maxRepeatedCalls() {
+			name, _, _ := strings.Cut(sig, ":")
+			return &loopDetection{
+				kind:   types.LoopKindRepeatedCall,
+				detail: fmt.Sprintf("%s called %d times with identical input", name, counts[sig]),
+			}
+		}
+	}
+	return nil
+}
+
+// nextIntervention 取出本次介入方式，并把下一次介入加重一级（停留在最后一级）
+func (g *loopGuard) nextIntervention() string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	interventions := g.interventions()
+	intervention := interventions[min(g.level, len(interventions)-1)]
+	if g.level < len(interventions)-1 {
+		g.level++
+	}
+	return intervention
+}
+
+// clearWindow 介入后清空检测窗口，避免同一段历史立即再次触发
+func (g *loopGuard) clearWindow() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.recent = nil
+	g.files = make(map[string]*fileHistory)
+	g.stallSteps = 0
+}
+
+// checkLoopGuard 检测循环并介入；反思提示随工具结果返回，用户选择停止时返回错误
+func (a *Agent) checkLoopGuard(ctx context.Context, toolUses []*types.ToolUseBlock, results []types.ContentBlock) ([]types.ContentBlock, error) {
+	g := a.loopGuard
+	if g == nil {
+		return results, nil
+	}
+	detection := g.observe(toolUses, results)
+	if detection == nil {
+		return results, nil
+	}
+
+	a.mu.RLock()
+	step := a.stepCount
+	a.mu.RUnlock()
+
+	g.clearWindow()
+
+	// 升级不可用（未启用或已在最高级）时顺延到下一种介入，都不可用时退回反思提示
+	intervention := types.LoopInterventionReflect
+	for range g.interventions() {
+		next := g.nextIntervention()
+		if next == types.LoopInterventionEscalate &&
+			!a.escalateModel(ctx, types.EscalationReasonLoopDetected, detection.detail) {
+			continue
+		}
+		intervention = next
+		break
+	}
+
+	agentLog.Warn(ctx, "loop detected", map[string]any{
+		"agent_id":     a.id,
+		"kind":         detection.kind,
+		"detail":       detection.detail,
+		"intervention": intervention,
+	})
+	a.eventBus.EmitMonitor(&types.MonitorLoopDetectedEvent{
+		Kind:         detection.kind,
+		Detail:       detection.detail,
+		Step:         step,
+		Intervention: intervention,
+	})
+
+	switch intervention {
+	case types.LoopInterventionPause:
+		if err := a.waitLoopGuardDecision(ctx, detection, step); err != nil {
+			return results, err
+		}
+	case types.LoopInterventionReflect:
+		results = append(results, loopReflectionNotice(detection))
+	}
+	return results, nil
+}
+
+// waitLoopGuardDecision 暂停执行，等待用户通过 RespondToLoopGuard 决定是否继续
+func (a *Agent) waitLoopGuardDecision(ctx context.Context, detection *loopDetection, step int) error {
+	a.eventBus.EmitControl(&types.ControlLoopGuardPauseEvent{
+		Kind:    detection.kind,
+		Detail:  detection.detail,
+		Step:    step,
+		Message: fmt.Sprintf("检测到执行陷入循环（%s）。是否继续？", detection.detail),
+	})
+
+	select {
+	case decision := <-a.loopGuardCh:
+		if !decision {
+			return agenterr.New(agenterr.CodeCanceled, "stopped by user after loop detected: "+detection.detail)
+		}
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// loopReflectionNotice 提示模型停下来重新审视方案的文本块
+func loopReflectionNotice(detection *loopDetection) *types.TextBlock {
+	return &types.TextBlock{Text: fmt.Sprintf("<loop-guard>\n"+
+		"You appear to be stuck: %s.\n"+
+		"Stop repeating the same actions. Re-read the latest tool results, explain why the previous attempts did not work, "+
+		"and try a different approach. If you are blocked, ask the user for help.\n"+
+		"</loop-guard>", detection.detail)}
+}
+
+// RespondToLoopGuard 响应循环保护暂停事件
+// continueExecution: true 表示继续执行，false 表示停止
+func (a *Agent) RespondToLoopGuard(continueExecution bool) {
+	select {
+	case a.loopGuardCh <- continueExecution:
+	default:
+		// channel 已满或没有等待者，忽略
+	}
+}
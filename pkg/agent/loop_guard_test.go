@@ -0,0 +1,229 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/astercloud/aster/pkg/provider"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+func toolStep(id, name string, input map[string]any, isError bool) ([]*types.ToolUseBlock, []types.ContentBlock) {
+	return []*types.ToolUseBlock{{ID: id, Name: name, Input: input}},
+		[]types.ContentBlock{&types.ToolResultBlock{ToolUseID: id, IsError: isError}}
+}
+
+func TestLoopGuardRepeatedCalls(t *testing.T) {
+	g := newLoopGuard(&types.LoopGuardConfig{Enabled: true, MaxRepeatedCalls: 3})
+
+	for i := range 2 {
+		uses, results := toolStep("c", "Bash", map[string]any{"command": "go test", "timeout": 10}, false)
+		if d := g.observe(uses, results); d != nil {
+			t.Fatalf("step %d: unexpected detection %+v", i, d)
+		}
+	}
+	// 参数键顺序不同也视为同一调用
+	uses, results := toolStep("c", "Bash", map[string]any{"timeout": 10, "command": "go test"}, false)
+	d := g.observe(uses, results)
+	if d == nil || d.kind != types.LoopKindRepeatedCall || !strings.Contains(d.detail, "Bash called 3 times") {
+		t.Fatalf("expected repeated_call detection, got %+v", d)
+	}
+}
+
+func TestLoopGuardOscillation(t *testing.T) {
+	g := newLoopGuard(&types.LoopGuardConfig{Enabled: true, MaxOscillations: 2})
+	edit := func(oldString, newString string) *loopDetection {
+		uses, results := toolStep("e", "Edit", map[string]any{
+			"file_path": "/w/main.go", "old_string": oldString, "new_string": newString,
+		}, false)
+		return g.observe(uses, results)
+	}
+
+	if d := edit("a", "b"); d != nil {
+		t.Fatalf("unexpected detection %+v", d)
+	}
+	if d := edit("b", "a"); d != nil {
+		t.Fatalf("one reversal should not trigger, got %+v", d)
+	}
+	d := edit("a", "b")
+	if d == nil || d.kind != types.LoopKindOscillation {
+		t.Fatalf("expected oscillation detection, got %+v", d)
+	}
+
+	g = newLoopGuard(&types.LoopGuardConfig{Enabled: true, MaxOscillations: 1})
+	var last *loopDetection
+	for _, content := range []string{"v1", "v2", "v1"} {
+		uses, results := toolStep("w", "Write", map[string]any{"file_path": "/w/a.txt", "content": content}, false)
+		last = g.observe(uses, results)
+	}
+	if last == nil || last.kind != types.LoopKindOscillation {
+		t.Fatalf("expected write oscillation detection, got %+v", last)
+	}
+}
+
+func TestLoopGuardStall(t *testing.T) {
+	g := newLoopGuard(&types.LoopGuardConfig{Enabled: true, MaxStallSteps: 3, MaxRepeatedCalls: 100})
+
+	// 失败的调用不算进展
+	for i := range 2 {
+		uses, results := toolStep("r", "Read", map[string]any{"file_path": "/missing"}, true)
+		if d := g.observe(uses, results); d != nil {
+			t.Fatalf("step %d: unexpected detection %+v", i, d)
+		}
+	}
+	uses, results := toolStep("r", "Read", map[string]any{"file_path": "/missing"}, true)
+	if d := g.observe(uses, results); d == nil || d.kind != types.LoopKindStall {
+		t.Fatalf("expected stall detection, got %+v", d)
+	}
+
+	// 成功的新调用重置计数
+	uses, results = toolStep("r", "Read", map[string]any{"file_path": "/exists"}, false)
+	if d := g.observe(uses, results); d != nil || g.stallSteps != 0 {
+		t.Fatalf("progress should reset stall, got %+v (stall=%d)", d, g.stallSteps)
+	}
+}
+
+func createLoopGuardAgent(t *testing.T, loopGuard *types.LoopGuardConfig, escalation *types.ModelEscalationConfig) *Agent {
+	t.Helper()
+	deps := setupTestDeps(t)
+
+	ag, err := Create(context.Background(), &types.AgentConfig{
+		TemplateID: "test-template",
+		ModelConfig: &types.ModelConfig{
+			Provider: "anthropic",
+			Model:    "claude-sonnet-4-5",
+			APIKey:   "test-key",
+		},
+		Sandbox: &types.SandboxConfig{
+			Kind:    types.SandboxKindMock,
+			WorkDir: "/tmp/test",
+		},
+		LoopGuard:  loopGuard,
+		Escalation: escalation,
+	}, deps)
+	if err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+	t.Cleanup(func() { _ = ag.Close() })
+	return ag
+}
+
+func TestLoopGuardInterventions(t *testing.T) {
+	ag := createLoopGuardAgent(t, &types.LoopGuardConfig{Enabled: true, MaxRepeatedCalls: 2},
+		&types.ModelEscalationConfig{
+			Enabled: true,
+			Ladder:  []string{"anthropic/claude-haiku-4-5", "anthropic/claude-opus-4"},
+		})
+	ctx := context.Background()
+	repeat := func() ([]types.ContentBlock, error) {
+		var results []types.ContentBlock
+		var err error
+		for range 2 {
+			uses, res := toolStep("c", "Grep", map[string]any{"pattern": "TODO"}, false)
+			results, err = ag.checkLoopGuard(ctx, uses, res)
+		}
+		return results, err
+	}
+
+	// 第一次：注入反思提示
+	results, err := repeat()
+	if err != nil || len(results) != 2 {
+		t.Fatalf("expected reflection notice appended, got %d blocks (err=%v)", len(results), err)
+	}
+	if tb, ok := results[1].(*types.TextBlock); !ok || !strings.Contains(tb.Text, "<loop-guard>") {
+		t.Fatalf("unexpected reflection block %+v", results[1])
+	}
+
+	// 第二次：升级模型
+	if _, err := repeat(); err != nil {
+		t.Fatalf("escalate: %v", err)
+	}
+	if got := ag.Status().Model; got != "anthropic/claude-opus-4" {
+		t.Fatalf("expected escalation on repeated loop, got %q", got)
+	}
+
+	// 第三次：暂停，用户选择停止
+	ch := ag.Subscribe([]types.AgentChannel{types.ChannelControl}, nil)
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := repeat()
+		errCh <- err
+	}()
+
+	deadline := time.After(2 * time.Second)
+	for {
+		select {
+		case env := <-ch:
+			ev, ok := env.Event.(*types.ControlLoopGuardPauseEvent)
+			if !ok {
+				continue
+			}
+			if ev.Kind != types.LoopKindRepeatedCall {
+				t.Errorf("unexpected pause event %+v", ev)
+			}
+			ag.RespondToLoopGuard(false)
+			if err := <-errCh; err == nil {
+				t.Fatal("expected error after user stopped the run")
+			}
+			return
+		case <-deadline:
+			t.Fatal("timed out waiting for loop_guard_pause event")
+		}
+	}
+}
+
+func TestLoopGuardSkipsUnavailableEscalation(t *testing.T) {
+	ag := createLoopGuardAgent(t, &types.LoopGuardConfig{
+		Enabled:          true,
+		MaxRepeatedCalls: 2,
+		Interventions:    []string{types.LoopInterventionEscalate},
+	}, nil)
+
+	var results []types.ContentBlock
+	for range 2 {
+		uses, res := toolStep("c", "Grep", map[string]any{"pattern": "TODO"}, false)
+		results, _ = ag.checkLoopGuard(context.Background(), uses, res)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected fallback reflection notice when escalation is unavailable, got %d blocks", len(results))
+	}
+}
+
+func TestLoopGuardStream(t *testing.T) {
+	p := &loopingProvider{}
+	deps := setupTestDeps(t)
+	factory := NewMockProviderFactory()
+	factory.SetProvider("mock/looper", &MockProvider{
+		name:         "looper",
+		streamFunc:   p.stream,
+		capabilities: provider.ProviderCapabilities{SupportStreaming: true, SupportToolCalling: true},
+	})
+	deps.ProviderFactory = factory
+
+	ag, err := Create(context.Background(), &types.AgentConfig{
+		TemplateID:  "test-template",
+		ModelConfig: &types.ModelConfig{Provider: "mock", Model: "looper"},
+		Sandbox:     &types.SandboxConfig{Kind: types.SandboxKindMock, WorkDir: "/tmp/test"},
+		MaxTurns:    3,
+		LoopGuard:   &types.LoopGuardConfig{Enabled: true, MaxRepeatedCalls: 2},
+	}, deps)
+	if err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+	defer func() { _ = ag.Close() }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := StreamCollect(ag.Stream(ctx, "find the config")); err != nil {
+		t.Fatalf("Stream() error = %v", err)
+	}
+	// Stream 路径重复调用同一工具时同样注入反思提示
+	for _, msg := range ag.messages {
+		if msg.Role == types.MessageRoleUser && strings.Contains(extractText(msg), "<loop-guard>") {
+			return
+		}
+	}
+	t.Error("expected a loop guard reflection notice after repeated Stream tool calls")
+}
@@ -0,0 +1,84 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/astercloud/aster/pkg/provider"
+	"github.com/astercloud/aster/pkg/sandbox"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+// TestAgent_ScriptedMockSandbox 用脚本化的 mock 沙箱驱动 Bash 与 Read，验证结果确定且命令可断言
+func TestAgent_ScriptedMockSandbox(t *testing.T) {
+	deps := setupTestDeps(t)
+	factory := NewMockProviderFactory()
+	factory.SetProvider("mock/scripted", &MockProvider{
+		name: "scripted",
+		completeFunc: func(_ context.Context, messages []types.Message, _ *provider.StreamOptions) (*provider.CompleteResponse, error) {
+			// 依次调用 Bash、Read，最后回复 Read 的结果
+			var results []string
+			for _, msg := range messages {
+				for _, block := range msg.ContentBlocks {
+					if tr, ok := block.(*types.ToolResultBlock); ok {
+						results = append(results, tr.Content)
+					}
+				}
+			}
+			var block types.ContentBlock
+			switch len(results) {
+			case 0:
+				block = &types.ToolUseBlock{ID: "call-1", Name: "Bash", Input: map[string]any{"command": "go test -cover ./..."}}
+			case 1:
+				block = &types.ToolUseBlock{ID: "call-2", Name: "Read", Input: map[string]any{"file_path": "coverage.txt"}}
+			default:
+				block = &types.TextBlock{Text: results[1]}
+			}
+			return &provider.CompleteResponse{Message: types.Message{
+				Role:          types.MessageRoleAssistant,
+				ContentBlocks: []types.ContentBlock{block},
+			}}, nil
+		},
+		capabilities: provider.ProviderCapabilities{SupportToolCalling: true, SupportSystemPrompt: true},
+	})
+	deps.ProviderFactory = factory
+
+	ag, err := Create(context.Background(), &types.AgentConfig{
+		TemplateID:  "test-template",
+		ModelConfig: &types.ModelConfig{Provider: "mock", Model: "scripted", ExecutionMode: types.ExecutionModeNonStreaming},
+		Tools:       []string{"Bash", "Read"},
+		Sandbox: &types.SandboxConfig{
+			Kind:           types.SandboxKindMock,
+			PermissionMode: types.SandboxPermissionBypass,
+			Mock: &types.MockSandboxConfig{
+				Strict: true,
+				Commands: []types.MockCommand{{
+					Match:      "*go test -cover ./...*",
+					Stdout:     "ok  demo  coverage: 87.5% of statements\n",
+					WriteFiles: map[string]string{"coverage.txt": "total: 87.5%"},
+				}},
+			},
+		},
+	}, deps)
+	if err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+	t.Cleanup(func() { _ = ag.Close() })
+
+	result, err := ag.Chat(context.Background(), "run the tests")
+	if err != nil {
+		t.Fatalf("Chat() error = %v", err)
+	}
+	if !strings.Contains(result.Text, "total: 87.5%") {
+		t.Errorf("Chat() text = %q", result.Text)
+	}
+
+	ms := ag.Sandbox().(*sandbox.MockSandbox)
+	if n := ms.Executed("*go test -cover*"); n != 1 {
+		t.Errorf("go test executed %d times, commands = %v", n, ms.Commands())
+	}
+	if unused := ms.UnusedCommands(); len(unused) != 0 {
+		t.Errorf("UnusedCommands() = %v", unused)
+	}
+}
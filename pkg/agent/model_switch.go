@@ -0,0 +1,259 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/astercloud/aster/pkg/provider"
+	"github.com/astercloud/aster/pkg/router"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+// modelRef 返回 provider/model 形式的模型标识
+func modelRef(cfg *types.ModelConfig) string {
+	if cfg == nil {
+		return ""
+	}
+	return cfg.Provider + "/" + cfg.Model
+}
+
+// currentProvider 返回主模型 Provider（并发安全，可在运行中被 SwitchModel 替换）
+func (a *Agent) currentProvider() provider.Provider {
+	a.modelMu.RLock()
+	defer a.modelMu.RUnlock()
+	return a.provider
+}
+
+// CurrentModel 返回当前主模型配置
+func (a *Agent) CurrentModel() *types.ModelConfig {
+	a.modelMu.RLock()
+	defer a.modelMu.RUnlock()
+	return a.modelConfig
+}
+
+// modelProviderForStep 返回本轮模型调用应使用的 Provider
+// 用户通过 SwitchModel 或 /model 选择的模型优先；否则 Plan 模式下使用路由器为 plan 任务配置的模型，
+// 其余情况使用 execute 任务覆盖，最后回退到主模型
+func (a *Agent) modelProviderForStep(ctx context.Context) provider.Provider {
+	a.modelMu.RLock()
+	selected := a.modelSelected
+	a.modelMu.RUnlock()
+	if selected {
+		return a.currentProvider()
+	}
+
+	task := router.TaskExecute
+	if a.planMode != nil && a.planMode.IsActive() {
+		task = router.TaskPlan
+	}
+	if prov := a.taskProvider(ctx, task); prov != nil {
+		return prov
+	}
+	return a.currentProvider()
+}
+
+// taskProvider 返回任务显式覆盖的 Provider；未配置覆盖时返回 nil
+func (a *Agent) taskProvider(ctx context.Context, task string) provider.Provider {
+	resolver, ok := a.deps.Router.(router.TaskResolver)
+	if !ok {
+		return nil
+	}
+	cfg, ok := resolver.TaskModel(task)
+	if !ok {
+		return nil
+	}
+
+	a.modelMu.Lock()
+	defer a.modelMu.Unlock()
+
+	cached, ok := a.taskProviders[task]
+	if ok && cached.ref == modelRef(cfg) {
+		return cached.provider
+	}
+	prov, err := a.deps.ProviderFactory.Create(cfg)
+	if err != nil {
+		agentLog.Warn(ctx, "failed to create task provider, falling back to main model", map[string]any{
+			"task":  task,
+			"model": modelRef(cfg),
+			"error": err,
+		})
+		return nil
+	}
+	// 任务覆盖改成了其他模型，旧 Provider 可能仍在使用，关闭 Agent 时释放
+	if ok {
+		a.retiredProviders = append(a.retiredProviders, cached.provider)
+	}
+	a.taskProviders[task] = taskProviderEntry{ref: modelRef(cfg), provider: prov}
+	return prov
+}
+
+// createTaskProvider 在 Agent 创建阶段为任务覆盖创建独立 Provider（例如 summarization 中间件）
+// 路由器未配置该任务覆盖时返回 nil
+func createTaskProvider(ctx context.Context, deps *Dependencies, task string) provider.Provider {
+	resolver, ok := deps.Router.(router.TaskResolver)
+	if !ok {
+		return nil
+	}
+	cfg, ok := resolver.TaskModel(task)
+	if !ok {
+		return nil
+	}
+	prov, err := deps.ProviderFactory.Create(cfg)
+	if err != nil {
+		agentLog.Warn(ctx, "failed to create task provider", map[string]any{
+			"task":  task,
+			"model": modelRef(cfg),
+			"error": err,
+		})
+		return nil
+	}
+	return prov
+}
+
+// taskProviderEntry 按任务缓存的 Provider
+type taskProviderEntry struct {
+	ref      string
+	provider provider.Provider
+}
+
+// resolveModel 将逻辑别名或 provider/model 解析为 ModelConfig
+// 路由器实现 router.Resolver 时交给路由器处理；否则只接受 provider/model，并沿用当前模型的凭据
+func (a *Agent) resolveModel(name string) (*types.ModelConfig, error) {
+	if resolver, ok := a.deps.Router.(router.Resolver); ok {
+		return resolver.Resolve(name)
+	}
+
+	providerName, modelName, ok := router.ParseModelRef(name)
+	if !ok {
+		return nil, fmt.Errorf("model %q must be in provider/model form when no alias router is configured", name)
+	}
+	resolved := &types.ModelConfig{Provider: providerName, Model: modelName}
+	if current := a.CurrentModel(); current != nil && current.Provider == providerName {
+		resolved.APIKey = current.APIKey
+		resolved.BaseURL = current.BaseURL
+	}
+	return resolved, nil
+}
+
+// SwitchModel 在会话中热切换主模型
+// name 可以是逻辑别名（如 "fast"、"smart"）或 provider/model，新模型从下一次模型调用开始生效
+func (a *Agent) SwitchModel(ctx context.Context, name, note string) error {
+	// 记录入站控制事件
+	a.eventBus.EmitControl(&types.ControlModelSwitchEvent{
+		Model: name,
+		Note:  note,
+	})
+
+	from := modelRef(a.CurrentModel())
+	to, err := a.switchModel(ctx, name)
+	a.emitModelSwitchResponse(name, from, to, err)
+	return err
+}
+
+// SwitchModelConfig 使用完整的模型配置热切换主模型（不经过别名解析，配置中的凭据和 BaseURL 原样使用）
+// 适用于调用方自行持有模型配置的场景，例如离线时切到本地模型、恢复联网后还原原模型
+func (a *Agent) SwitchModelConfig(ctx context.Context, cfg *types.ModelConfig, note string) error {
+	if cfg == nil {
+		return errors.New("model config is required")
+	}
+	name := modelRef(cfg)
+	a.eventBus.EmitControl(&types.ControlModelSwitchEvent{
+		Model: name,
+		Note:  note,
+	})
+
+	from := modelRef(a.CurrentModel())
+	copied := *cfg
+	to, err := a.applyModel(ctx, name, &copied)
+	a.emitModelSwitchResponse(name, from, to, err)
+	return err
+}
+
+// emitModelSwitchResponse 发出模型切换结果事件
+func (a *Agent) emitModelSwitchResponse(name, from, to string, err error) {
+	resp := &types.ControlModelSwitchResponseEvent{
+		Requested: name,
+		From:      from,
+		To:        to,
+		OK:        err == nil,
+	}
+	if err != nil {
+		resp.Reason = err.Error()
+	}
+	a.eventBus.EmitControl(resp)
+}
+
+func (a *Agent) switchModel(ctx context.Context, name string) (string, error) {
+	cfg, err := a.resolveSwitchTarget(name)
+	if err != nil {
+		return "", err
+	}
+	return a.applyModel(ctx, name, cfg)
+}
+
+// applyModel 替换主模型并在空闲时关闭旧 Provider；用户选择的模型此后优先于任务覆盖
+func (a *Agent) applyModel(ctx context.Context, name string, cfg *types.ModelConfig) (string, error) {
+	old, err := a.swapModel(cfg)
+	if err != nil {
+		return "", err
+	}
+
+	a.mu.RLock()
+	working := a.state == types.AgentStateWorking
+	a.mu.RUnlock()
+
+	// 正在进行的调用可能仍持有旧 Provider，此时延迟到 Agent 关闭时释放
+	a.modelMu.Lock()
+	a.modelSelected = true
+	if old != nil && working {
+		a.retiredProviders = append(a.retiredProviders, old)
+	}
+	a.modelMu.Unlock()
+	if old != nil && !working {
+		_ = old.Close()
+	}
+
+	agentLog.Info(ctx, "model switched", map[string]any{
+		"agent_id":  a.id,
+		"requested": name,
+		"model":     modelRef(cfg),
+	})
+	return modelRef(cfg), nil
+}
+
+// resolveSwitchTarget 解析切换目标并补全凭据，返回独立副本
+func (a *Agent) resolveSwitchTarget(name string) (*types.ModelConfig, error) {
+	if name == "" {
+		return nil, errors.New("model name is required")
+	}
+
+	resolved, err := a.resolveModel(name)
+	if err != nil {
+		return nil, err
+	}
+	// 复制一份，避免修改路由器持有的别名配置
+	cfg := *resolved
+	if cfg.APIKey == "" {
+		if current := a.CurrentModel(); current != nil && current.Provider == cfg.Provider {
+			cfg.APIKey = current.APIKey
+		}
+	}
+	return &cfg, nil
+}
+
+// swapModel 创建新 Provider 并替换主模型，返回旧 Provider（由调用方决定是否关闭）
+// 不获取 a.mu，可在持有 a.mu 的路径中调用
+func (a *Agent) swapModel(cfg *types.ModelConfig) (provider.Provider, error) {
+	prov, err := a.deps.ProviderFactory.Create(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("create provider: %w", err)
+	}
+
+	a.modelMu.Lock()
+	defer a.modelMu.Unlock()
+	old := a.provider
+	a.provider = prov
+	a.modelConfig = cfg
+	return old, nil
+}
@@ -0,0 +1,154 @@
+package agent
+
+import (
+	"context"
+	"testing"
+
+	"github.com/astercloud/aster/pkg/provider"
+	"github.com/astercloud/aster/pkg/router"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+func TestAgentSwitchModel(t *testing.T) {
+	deps := setupTestDeps(t)
+	rt, err := router.NewAliasRouter(&router.AliasConfig{
+		Aliases: map[string]*types.ModelConfig{
+			"smart": {Provider: "anthropic", Model: "claude-sonnet-4-5", APIKey: "test-key"},
+			"fast":  {Provider: "anthropic", Model: "claude-haiku-4-5", APIKey: "test-key"},
+		},
+		Default: "smart",
+	})
+	if err != nil {
+		t.Fatalf("NewAliasRouter failed: %v", err)
+	}
+	deps.Router = rt
+
+	ag, err := Create(context.Background(), &types.AgentConfig{
+		TemplateID: "test-template",
+		ModelConfig: &types.ModelConfig{
+			Provider: "anthropic",
+			Model:    "claude-sonnet-4-5",
+			APIKey:   "test-key",
+		},
+		Sandbox: &types.SandboxConfig{
+			Kind:    types.SandboxKindMock,
+			WorkDir: "/tmp/test",
+		},
+	}, deps)
+	if err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+	defer func() { _ = ag.Close() }()
+
+	if got := ag.Status().Model; got != "anthropic/claude-sonnet-4-5" {
+		t.Fatalf("unexpected initial model %q", got)
+	}
+
+	if err := ag.SwitchModel(context.Background(), "fast", ""); err != nil {
+		t.Fatalf("SwitchModel(fast) failed: %v", err)
+	}
+	if got := ag.Status().Model; got != "anthropic/claude-haiku-4-5" {
+		t.Errorf("expected fast alias to be active, got %q", got)
+	}
+
+	if err := ag.SwitchModel(context.Background(), "anthropic/claude-opus-4", ""); err != nil {
+		t.Fatalf("SwitchModel(provider/model) failed: %v", err)
+	}
+	if got := ag.CurrentModel(); got.Model != "claude-opus-4" || got.APIKey != "test-key" {
+		t.Errorf("expected explicit model with inherited key, got %+v", got)
+	}
+
+	if err := ag.SwitchModel(context.Background(), "unknown", ""); err == nil {
+		t.Error("expected error for unknown alias")
+	}
+}
+
+func TestAgentSwitchModelConfig(t *testing.T) {
+	deps := setupTestDeps(t)
+	deps.ProviderFactory = provider.NewMultiProviderFactory()
+	original := &types.ModelConfig{
+		Provider: "anthropic",
+		Model:    "claude-sonnet-4-5",
+		APIKey:   "test-key",
+	}
+
+	ag, err := Create(context.Background(), &types.AgentConfig{
+		TemplateID:  "test-template",
+		ModelConfig: original,
+		Sandbox: &types.SandboxConfig{
+			Kind:    types.SandboxKindMock,
+			WorkDir: "/tmp/test",
+		},
+	}, deps)
+	if err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+	defer func() { _ = ag.Close() }()
+
+	local := &types.ModelConfig{Provider: "ollama", Model: "llama3.2", BaseURL: "http://127.0.0.1:11434/v1"}
+	if err := ag.SwitchModelConfig(context.Background(), local, "offline"); err != nil {
+		t.Fatalf("SwitchModelConfig(local) failed: %v", err)
+	}
+	if got := ag.CurrentModel(); got.Provider != "ollama" || got.BaseURL != local.BaseURL {
+		t.Errorf("expected local model, got %+v", got)
+	}
+	if got := ag.CurrentModel(); got == local {
+		t.Error("expected SwitchModelConfig to copy the config")
+	}
+
+	// 还原时凭据不依赖当前 Provider
+	if err := ag.SwitchModelConfig(context.Background(), original, "online"); err != nil {
+		t.Fatalf("SwitchModelConfig(original) failed: %v", err)
+	}
+	if got := ag.CurrentModel(); got.Model != "claude-sonnet-4-5" || got.APIKey != "test-key" {
+		t.Errorf("expected original model with its key, got %+v", got)
+	}
+
+	if err := ag.SwitchModelConfig(context.Background(), nil, ""); err == nil {
+		t.Error("expected error for nil config")
+	}
+}
+
+func TestSwitchModelOverridesTaskModel(t *testing.T) {
+	deps := setupTestDeps(t)
+	factory := NewMockProviderFactory()
+	for _, name := range []string{"main", "executor", "picked"} {
+		factory.SetProvider("mock/"+name, &MockProvider{name: name})
+	}
+	deps.ProviderFactory = factory
+	rt, err := router.NewAliasRouter(&router.AliasConfig{
+		Aliases: map[string]*types.ModelConfig{
+			"main":     {Provider: "mock", Model: "main"},
+			"executor": {Provider: "mock", Model: "executor"},
+			"picked":   {Provider: "mock", Model: "picked"},
+		},
+		Default: "main",
+		Tasks:   map[string]string{router.TaskExecute: "executor"},
+	})
+	if err != nil {
+		t.Fatalf("NewAliasRouter failed: %v", err)
+	}
+	deps.Router = rt
+
+	ag, err := Create(context.Background(), &types.AgentConfig{
+		TemplateID:  "test-template",
+		ModelConfig: &types.ModelConfig{Provider: "mock", Model: "main"},
+		Sandbox:     &types.SandboxConfig{Kind: types.SandboxKindMock, WorkDir: "/tmp/test"},
+	}, deps)
+	if err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+	defer func() { _ = ag.Close() }()
+
+	ctx := context.Background()
+	if got := ag.modelProviderForStep(ctx).(*MockProvider).name; got != "executor" {
+		t.Fatalf("expected execute task override before switching, got %q", got)
+	}
+	// 用户选择的模型优先于任务覆盖
+	if err := ag.SwitchModel(ctx, "picked", ""); err != nil {
+		t.Fatalf("SwitchModel failed: %v", err)
+	}
+	if got := ag.modelProviderForStep(ctx).(*MockProvider).name; got != "picked" {
+		t.Errorf("expected user-selected model to win over task override, got %q", got)
+	}
+}
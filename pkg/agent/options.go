@@ -0,0 +1,92 @@
+package agent
+
+import (
+	"slices"
+
+	"github.com/astercloud/aster/pkg/session"
+	"github.com/astercloud/aster/pkg/tools"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+// CreateOption Create 的可选配置
+// 只对单个 Agent 生效的高级配置通过选项传入，而不是继续扩充 types.AgentConfig 和 Dependencies
+type CreateOption func(*createOptions)
+
+type createOptions struct {
+	tools         []tools.Tool
+	sessions      session.Service
+	sessionID     string
+	promptModules []PromptModule
+	eventSinks    []EventSink
+}
+
+// EventSink 事件接收器，Agent 的每个事件按 cursor 顺序同步调用且不会丢弃
+// 在事件总线锁内调用，需要尽快返回，且不能再向同一 Agent 发送事件
+type EventSink func(envelope types.AgentEventEnvelope)
+
+// WithTools 为 Agent 追加工具实例，与模板中的工具一起提供给模型
+// 适合不便注册到 ToolRegistry 的工具（如持有外部连接的闭包），同名时覆盖模板中的工具
+func WithTools(ts ...tools.Tool) CreateOption {
+	return func(o *createOptions) {
+		o.tools = append(o.tools, ts...)
+	}
+}
+
+// WithSessionService 将用户消息和最终回复作为事件追加到 sessions 中的 sessionID 会话
+// 会话需由调用方预先创建；sessionID 为空时使用 Agent ID
+func WithSessionService(sessions session.Service, sessionID string) CreateOption {
+	return func(o *createOptions) {
+		o.sessions = sessions
+		o.sessionID = sessionID
+	}
+}
+
+// WithPromptModules 为 Agent 追加 Prompt 模块，与 Dependencies.PromptModules 一起按优先级注入
+func WithPromptModules(modules ...PromptModule) CreateOption {
+	return func(o *createOptions) {
+		o.promptModules = append(o.promptModules, modules...)
+	}
+}
+
+// WithEventSink 为 Agent 添加事件接收器，可多次使用
+func WithEventSink(sink EventSink) CreateOption {
+	return func(o *createOptions) {
+		o.eventSinks = append(o.eventSinks, sink)
+	}
+}
+
+func newCreateOptions(opts []CreateOption) *createOptions {
+	o := &createOptions{}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(o)
+		}
+	}
+	return o
+}
+
+// applyDeps 返回合并了选项中 Prompt 模块的依赖副本，不影响共享的依赖
+func (o *createOptions) applyDeps(deps *Dependencies) *Dependencies {
+	if len(o.promptModules) == 0 {
+		return deps
+	}
+	merged := *deps
+	merged.PromptModules = append(slices.Clone(deps.PromptModules), o.promptModules...)
+	return &merged
+}
+
+// observer 将多个事件接收器合并为事件总线的观察者，没有接收器时返回 nil
+func observer(sinks ...EventSink) func(types.AgentEventEnvelope) {
+	sinks = slices.DeleteFunc(slices.Clone(sinks), func(s EventSink) bool { return s == nil })
+	switch len(sinks) {
+	case 0:
+		return nil
+	case 1:
+		return sinks[0]
+	}
+	return func(envelope types.AgentEventEnvelope) {
+		for _, sink := range sinks {
+			sink(envelope)
+		}
+	}
+}
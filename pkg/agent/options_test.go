@@ -0,0 +1,85 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/astercloud/aster/pkg/session"
+	"github.com/astercloud/aster/pkg/tools"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+type optionTestTool struct{}
+
+func (optionTestTool) Name() string                { return "LookupOrder" }
+func (optionTestTool) Description() string         { return "Look up an order" }
+func (optionTestTool) InputSchema() map[string]any { return map[string]any{"type": "object"} }
+func (optionTestTool) Prompt() string              { return "" }
+func (optionTestTool) Execute(context.Context, map[string]any, *tools.ToolContext) (any, error) {
+	return "shipped", nil
+}
+
+type optionTestModule struct{}
+
+func (optionTestModule) Name() string { return "order_policy" }
+func (optionTestModule) Build(*PromptContext) (string, error) {
+	return "Refunds need manager approval.", nil
+}
+func (optionTestModule) Priority() int                 { return 50 }
+func (optionTestModule) Condition(*PromptContext) bool { return true }
+
+func TestCreateOptions(t *testing.T) {
+	deps := setupTestDeps(t)
+	sessions := session.NewInMemoryService()
+	sess, err := sessions.Create(context.Background(), &session.CreateRequest{AppName: "test", UserID: "u1"})
+	if err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+	var received []types.AgentEventEnvelope
+
+	ag, err := Create(context.Background(), &types.AgentConfig{
+		TemplateID:  "test-template",
+		ModelConfig: &types.ModelConfig{Provider: "anthropic", Model: "claude-sonnet-4-5", APIKey: "test-key"},
+		Sandbox:     &types.SandboxConfig{Kind: types.SandboxKindMock, WorkDir: "/tmp/test"},
+	}, deps,
+		WithTools(optionTestTool{}),
+		WithPromptModules(optionTestModule{}),
+		WithEventSink(func(e types.AgentEventEnvelope) { received = append(received, e) }),
+		WithSessionService(sessions, sess.ID()),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+	defer func() { _ = ag.Close() }()
+
+	if _, ok := ag.toolMap["LookupOrder"]; !ok {
+		t.Error("expected WithTools tool to be available")
+	}
+	if _, ok := ag.toolMap["Read"]; !ok {
+		t.Error("expected template tools to stay available")
+	}
+	if !strings.Contains(ag.GetSystemPrompt(), "Refunds need manager approval.") {
+		t.Error("expected WithPromptModules module in system prompt")
+	}
+	if len(deps.PromptModules) != 0 {
+		t.Error("expected shared dependencies to stay unchanged")
+	}
+
+	ag.eventBus.EmitMonitor(&types.MonitorStateChangedEvent{State: types.AgentStateWorking})
+	if len(received) == 0 {
+		t.Error("expected event sink to receive emitted event")
+	}
+
+	msg := types.Message{Role: types.RoleUser, Content: "where is my order?"}
+	if err := ag.persistMessage(context.Background(), &msg); err != nil {
+		t.Fatalf("persistMessage failed: %v", err)
+	}
+	events, err := sessions.GetEvents(context.Background(), sess.ID(), nil)
+	if err != nil {
+		t.Fatalf("GetEvents failed: %v", err)
+	}
+	if len(events) != 1 || events[0].Author != "user" || events[0].Content.Content != "where is my order?" {
+		t.Errorf("expected user message in session, got %+v", events)
+	}
+}
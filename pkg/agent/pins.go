@@ -0,0 +1,244 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// pinsMetadataKey 固定内容在 AgentInfo.Metadata 中的键
+	pinsMetadataKey = "pinned_context"
+	// maxPinnedFileBytes 单个固定文件保留的最大字节数
+	maxPinnedFileBytes = 16 * 1024
+	// maxPinnedTotalBytes 所有固定内容的总大小上限，避免 System Prompt 过度膨胀
+	maxPinnedTotalBytes = 64 * 1024
+)
+
+// ErrPinNotFound 固定内容不存在
+var ErrPinNotFound = errors.New("pin not found")
+
+// PinKind 固定内容类型
+type PinKind string
+
+const (
+	// PinKindFile 文件内容，固定时读取快照
+	PinKindFile PinKind = "file"
+	// PinKindNote 决定或说明
+	PinKindNote PinKind = "note"
+	// PinKindRequirements 需求列表
+	PinKindRequirements PinKind = "requirements"
+)
+
+// PinnedItem 固定在上下文中的内容
+// 固定内容渲染在 System Prompt 中，上下文压缩或摘要不会丢弃它们
+type PinnedItem struct {
+	ID        string    `json:"id"`
+	Kind      PinKind   `json:"kind"`
+	Title     string    `json:"title,omitempty"`
+	Path      string    `json:"path,omitempty"` // 仅 file 类型
+	Content   string    `json:"content"`
+	Truncated bool      `json:"truncated,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// label 返回固定内容在 Prompt 和列表中的标题
+func (p *PinnedItem) label() string {
+	switch {
+	case p.Kind == PinKindFile:
+		return "File: " + p.Path
+	case p.Title != "":
+		return p.Title
+	case p.Kind == PinKindRequirements:
+		return "Requirements"
+	default:
+		return "Note"
+	}
+}
+
+// Pins 返回当前固定的内容
+func (a *Agent) Pins() []PinnedItem {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return slices.Clone(a.pins)
+}
+
+// Pin 固定一项内容，使其在整个会话中保持在上下文里
+// file 类型未提供 Content 时从沙箱读取文件；同一路径再次固定会刷新快照
+func (a *Agent) Pin(ctx context.Context, item PinnedItem) (*PinnedItem, error) {
+	switch item.Kind {
+	case PinKindFile:
+		if item.Path == "" {
+			return nil, errors.New("file pin requires a path")
+		}
+		if item.Content == "" {
+			if a.sandbox == nil {
+				return nil, errors.New("no sandbox to read pinned file")
+			}
+			content, err := a.sandbox.FS().Read(ctx, item.Path)
+			if err != nil {
+				return nil, fmt.Errorf("read %s: %w", item.Path, err)
+			}
+			item.Content = content
+		}
+		if len(item.Content) > maxPinnedFileBytes {
+			item.Content = strings.ToValidUTF8(item.Content[:maxPinnedFileBytes], "")
+			item.Truncated = true
+		}
+	case PinKindNote, PinKindRequirements:
+		item.Path = ""
+	default:
+		return nil, fmt.Errorf("unknown pin kind %q", item.Kind)
+	}
+	item.Content = strings.TrimSpace(item.Content)
+	if item.Content == "" {
+		return nil, errors.New("pin content is empty")
+	}
+	item.CreatedAt = time.Now()
+
+	a.mu.Lock()
+	pins := slices.Clone(a.pins)
+	if i := slices.IndexFunc(pins, func(p PinnedItem) bool {
+		return item.Kind == PinKindFile && p.Kind == PinKindFile && p.Path == item.Path
+	}); i >= 0 {
+		item.ID = pins[i].ID
+		pins[i] = item
+	} else {
+		item.ID = nextPinID(pins)
+		pins = append(pins, item)
+	}
+	if size := pinnedSize(pins); size > maxPinnedTotalBytes {
+		a.mu.Unlock()
+		return nil, fmt.Errorf("pinned context would be %d bytes, limit is %d", size, maxPinnedTotalBytes)
+	}
+	a.pins = pins
+	a.applyPinsLocked()
+	a.mu.Unlock()
+
+	if err := a.savePins(ctx, pins); err != nil {
+		return nil, err
+	}
+	agentLog.Info(ctx, "context pinned", map[string]any{"agent_id": a.id, "pin_id": item.ID, "kind": item.Kind})
+	return &item, nil
+}
+
+// Unpin 取消固定一项内容
+func (a *Agent) Unpin(ctx context.Context, id string) error {
+	a.mu.Lock()
+	i := slices.IndexFunc(a.pins, func(p PinnedItem) bool { return p.ID == id })
+	if i < 0 {
+		a.mu.Unlock()
+		return fmt.Errorf("%w: %s", ErrPinNotFound, id)
+	}
+	pins := slices.Delete(slices.Clone(a.pins), i, i+1)
+	a.pins = pins
+	a.applyPinsLocked()
+	a.mu.Unlock()
+
+	return a.savePins(ctx, pins)
+}
+
+// UnpinAll 取消所有固定内容
+func (a *Agent) UnpinAll(ctx context.Context) error {
+	a.mu.Lock()
+	a.pins = nil
+	a.applyPinsLocked()
+	a.mu.Unlock()
+
+	return a.savePins(ctx, nil)
+}
+
+// nextPinID 生成递增的短 ID，便于在 /unpin 中输入
+func nextPinID(pins []PinnedItem) string {
+	next := 1
+	for _, p := range pins {
+		if n, err := strconv.Atoi(p.ID); err == nil && n >= next {
+			next = n + 1
+		}
+	}
+	return strconv.Itoa(next)
+}
+
+// pinnedSize 固定内容的总字节数
+func pinnedSize(pins []PinnedItem) int {
+	total := 0
+	for _, p := range pins {
+		total += len(p.Content)
+	}
+	return total
+}
+
+// applyPinsLocked 用当前固定内容替换 System Prompt 中的固定内容段落，调用方需持有 a.mu
+func (a *Agent) applyPinsLocked() {
+	module := &PinnedContextModule{Pins: a.pins}
+	section := ""
+	disabled := a.template.Runtime != nil && slices.Contains(a.template.Runtime.DisabledPromptModules, module.Name())
+	if !disabled && module.Condition(nil) {
+		section, _ = module.Build(nil)
+	}
+	a.template.SystemPrompt = replacePinnedSection(a.template.SystemPrompt, section)
+	a.contextUsage.reset()
+}
+
+// replacePinnedSection 替换或追加 System Prompt 中的固定内容段落，section 为空时移除
+func replacePinnedSection(prompt, section string) string {
+	start := strings.Index(prompt, pinnedSectionHeading)
+	end := strings.LastIndex(prompt, pinnedSectionEnd)
+	if start >= 0 && end > start {
+		rest := prompt[end+len(pinnedSectionEnd):]
+		prompt = strings.TrimRight(prompt[:start], "\n")
+		if section != "" {
+			prompt += "\n\n" + section
+		}
+		if rest = strings.TrimLeft(rest, "\n"); rest != "" {
+			prompt += "\n\n" + rest
+		}
+		return prompt
+	}
+	if section == "" {
+		return prompt
+	}
+	return strings.TrimRight(prompt, "\n") + "\n\n" + section
+}
+
+// loadPins 从 Agent 元信息恢复固定内容
+func (a *Agent) loadPins(ctx context.Context) {
+	info, err := a.deps.Store.LoadInfo(ctx, a.id)
+	if err != nil || info.Metadata == nil || info.Metadata[pinsMetadataKey] == nil {
+		return
+	}
+	// 元数据经过 JSON 存储后为通用类型，重新解码
+	data, err := json.Marshal(info.Metadata[pinsMetadataKey])
+	if err == nil {
+		err = json.Unmarshal(data, &a.pins)
+	}
+	if err != nil {
+		agentLog.Warn(ctx, "failed to load pinned context", map[string]any{"agent_id": a.id, "error": err.Error()})
+	}
+}
+
+// savePins 将固定内容写入 Agent 元信息
+func (a *Agent) savePins(ctx context.Context, pins []PinnedItem) error {
+	info, err := a.deps.Store.LoadInfo(ctx, a.id)
+	if err != nil {
+		return fmt.Errorf("load info: %w", err)
+	}
+	if info.Metadata == nil {
+		info.Metadata = make(map[string]any)
+	}
+	if len(pins) == 0 {
+		delete(info.Metadata, pinsMetadataKey)
+	} else {
+		info.Metadata[pinsMetadataKey] = pins
+	}
+	info.UpdatedAt = time.Now()
+	if err := a.deps.Store.SaveInfo(ctx, a.id, *info); err != nil {
+		return fmt.Errorf("save pins: %w", err)
+	}
+	return nil
+}
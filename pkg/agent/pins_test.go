@@ -0,0 +1,115 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/astercloud/aster/pkg/types"
+)
+
+func newPinTestAgent(t *testing.T, deps *Dependencies, agentID, workDir string) *Agent {
+	t.Helper()
+	ag, err := Create(context.Background(), &types.AgentConfig{
+		AgentID:     agentID,
+		TemplateID:  "test-template",
+		ModelConfig: &types.ModelConfig{Provider: "anthropic", Model: "claude-sonnet-4-5", APIKey: "test-key"},
+		Sandbox:     &types.SandboxConfig{Kind: types.SandboxKindLocal, WorkDir: workDir},
+	}, deps)
+	if err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+	t.Cleanup(func() { _ = ag.Close() })
+	return ag
+}
+
+func TestPinnedContext(t *testing.T) {
+	workDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(workDir, "spec.md"), []byte("# Spec\nMust support SSO."), 0644); err != nil {
+		t.Fatal(err)
+	}
+	deps := setupTestDeps(t)
+	ctx := context.Background()
+	ag := newPinTestAgent(t, deps, "agt-pins", workDir)
+
+	note, err := ag.Pin(ctx, PinnedItem{Kind: PinKindNote, Content: "Use PostgreSQL, not MySQL."})
+	if err != nil {
+		t.Fatalf("Pin(note) error = %v", err)
+	}
+	file, err := ag.Pin(ctx, PinnedItem{Kind: PinKindFile, Path: "spec.md"})
+	if err != nil {
+		t.Fatalf("Pin(file) error = %v", err)
+	}
+	if note.ID != "1" || file.ID != "2" || file.Content != "# Spec\nMust support SSO." {
+		t.Fatalf("pins = %+v, %+v", note, file)
+	}
+	if _, err := ag.Pin(ctx, PinnedItem{Kind: PinKindNote, Content: "  "}); err == nil {
+		t.Error("Pin() with empty content should fail")
+	}
+
+	prompt := ag.GetSystemPrompt()
+	for _, want := range []string{pinnedSectionHeading, "### [1] Note", "Use PostgreSQL", "### [2] File: spec.md", "Must support SSO."} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("system prompt missing %q", want)
+		}
+	}
+
+	// Re-pinning the same file refreshes it in place
+	if _, err := ag.Pin(ctx, PinnedItem{Kind: PinKindFile, Path: "spec.md", Content: "updated spec"}); err != nil {
+		t.Fatalf("Pin(file) again error = %v", err)
+	}
+	if pins := ag.Pins(); len(pins) != 2 || pins[1].Content != "updated spec" {
+		t.Errorf("pins after re-pin = %+v", pins)
+	}
+	if strings.Count(ag.GetSystemPrompt(), pinnedSectionHeading) != 1 {
+		t.Error("system prompt should contain exactly one pinned section")
+	}
+
+	if err := ag.Unpin(ctx, "1"); err != nil {
+		t.Fatalf("Unpin() error = %v", err)
+	}
+	if err := ag.Unpin(ctx, "1"); !errors.Is(err, ErrPinNotFound) {
+		t.Errorf("Unpin() of missing pin = %v, want ErrPinNotFound", err)
+	}
+	if strings.Contains(ag.GetSystemPrompt(), "PostgreSQL") {
+		t.Error("unpinned note still in system prompt")
+	}
+
+	// Pins are restored with the session, and stay out of other agents using the same template
+	resumed := newPinTestAgent(t, deps, "agt-pins", workDir)
+	if pins := resumed.Pins(); len(pins) != 1 || pins[0].ID != "2" {
+		t.Fatalf("resumed pins = %+v", pins)
+	}
+	if !strings.Contains(resumed.GetSystemPrompt(), "updated spec") {
+		t.Error("resumed system prompt missing pinned file")
+	}
+	other := newPinTestAgent(t, deps, "agt-other", workDir)
+	if strings.Contains(other.GetSystemPrompt(), pinnedSectionHeading) {
+		t.Error("pins leaked into another agent's system prompt")
+	}
+
+	if err := resumed.UnpinAll(ctx); err != nil {
+		t.Fatalf("UnpinAll() error = %v", err)
+	}
+	if strings.Contains(resumed.GetSystemPrompt(), pinnedSectionHeading) || len(resumed.Pins()) != 0 {
+		t.Error("UnpinAll() left pinned context behind")
+	}
+}
+
+func TestReplacePinnedSection(t *testing.T) {
+	section := pinnedSectionHeading + "\n\nbody\n" + pinnedSectionEnd
+	prompt := replacePinnedSection("base\n\n## Next\n", section)
+	if prompt != "base\n\n## Next\n\n"+section {
+		t.Fatalf("append = %q", prompt)
+	}
+	middle := "base\n\n" + section + "\n\n## Limits"
+	if got := replacePinnedSection(middle, pinnedSectionHeading+"\n\nnew\n"+pinnedSectionEnd); got != "base\n\n"+pinnedSectionHeading+"\n\nnew\n"+pinnedSectionEnd+"\n\n## Limits" {
+		t.Errorf("replace = %q", got)
+	}
+	if got := replacePinnedSection(middle, ""); got != "base\n\n## Limits" {
+		t.Errorf("remove = %q", got)
+	}
+}
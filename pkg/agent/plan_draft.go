@@ -0,0 +1,203 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/astercloud/aster/pkg/executionplan"
+	"github.com/astercloud/aster/pkg/permission"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+// planModeSource 计划模式草稿计划的来源标记
+const planModeSource = "plan_mode"
+
+// isDraftPlan 是否为计划模式下累积、等待审批的计划
+func isDraftPlan(plan *executionplan.ExecutionPlan) bool {
+	return plan != nil && plan.Status == executionplan.StatusPendingApproval && plan.Metadata["source"] == planModeSource
+}
+
+// draftStep 向草稿计划追加步骤，没有草稿计划时新建
+func (m *ExecutionPlanManager) draftStep(toolName, description string, params map[string]any) (*executionplan.ExecutionPlan, executionplan.Step) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !isDraftPlan(m.currentPlan) {
+		plan := executionplan.NewExecutionPlan("Changes drafted in plan mode")
+		plan.AgentID = m.agent.id
+		plan.Status = executionplan.StatusPendingApproval
+		plan.Metadata = map[string]any{"source": planModeSource}
+		m.currentPlan = plan
+	}
+	step := m.currentPlan.AddStep(toolName, description, params)
+	return m.currentPlan, *step
+}
+
+// resolveDraft 审批或拒绝等待审批的草稿计划，之后的工具调用会记入新计划
+func (m *ExecutionPlanManager) resolveDraft(resolve func(*executionplan.ExecutionPlan)) *executionplan.ExecutionPlan {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !isDraftPlan(m.currentPlan) {
+		return nil
+	}
+	resolve(m.currentPlan)
+	return m.currentPlan
+}
+
+// planModeDraft 计划模式下拦截变更类工具调用并记入计划，其余情况返回 nil
+func (a *Agent) planModeDraft(ctx context.Context, call types.ToolCall) *types.ToolResultBlock {
+	if a.permissionInspector == nil || a.permissionInspector.GetMode() != permission.ModePlan {
+		return nil
+	}
+	result, err := a.permissionInspector.Check(ctx, &types.ToolCallSnapshot{ID: call.ID, Name: call.Name, Arguments: call.Arguments})
+	if err != nil || result == nil || !result.Planned {
+		return nil
+	}
+	tu := &types.ToolUseBlock{ID: call.ID, Name: call.Name, Input: call.Arguments}
+	a.auditPermission(tu, "planned", result.DecidedBy, result.Message)
+	return a.draftPlanStep(ctx, tu)
+}
+
+// draftPlanStep 将工具调用记为草稿计划的一个步骤，返回告知模型的工具结果
+func (a *Agent) draftPlanStep(ctx context.Context, tu *types.ToolUseBlock) *types.ToolResultBlock {
+	plan, step := a.ExecutionPlan().draftStep(tu.Name, draftStepDescription(tu), tu.Input)
+
+	a.eventBus.EmitControl(&types.ControlPlanStepDraftedEvent{
+		PlanID:      plan.ID,
+		StepID:      step.ID,
+		Index:       step.Index,
+		CallID:      tu.ID,
+		ToolName:    tu.Name,
+		Description: step.Description,
+		Arguments:   tu.Input,
+	})
+	agentLog.Info(ctx, "tool call drafted into plan", map[string]any{
+		"agent_id": a.id, "plan_id": plan.ID, "step": step.Index + 1, "tool": tu.Name,
+	})
+
+	content, _ := json.Marshal(map[string]any{
+		"ok":      true,
+		"planned": true,
+		"plan_id": plan.ID,
+		"step":    step.Index + 1,
+		"message": fmt.Sprintf("Plan mode: this call was not executed. It was recorded as step %d of a plan "+
+			"the user will review. Continue as if it succeeded and finish planning.", step.Index+1),
+	})
+	return &types.ToolResultBlock{ToolUseID: tu.ID, Content: string(content)}
+}
+
+// draftStepDescription 根据工具参数生成步骤描述
+func draftStepDescription(tu *types.ToolUseBlock) string {
+	for _, key := range []string{"description", "command", "file_path", "path", "url"} {
+		if v, ok := tu.Input[key].(string); ok && v != "" {
+			return tu.Name + ": " + truncate(v, 120)
+		}
+	}
+	return tu.Name
+}
+
+// DraftPlan 返回计划模式下累积、等待审批的计划，没有时返回 nil
+func (a *Agent) DraftPlan() *executionplan.ExecutionPlan {
+	m := a.ExecutionPlan()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !isDraftPlan(m.currentPlan) {
+		return nil
+	}
+	return m.currentPlan
+}
+
+// ApproveDraftPlan 审批计划模式下累积的计划，由执行计划执行器逐步执行；
+// 执行结果随下一条用户消息告知模型
+func (a *Agent) ApproveDraftPlan(ctx context.Context, approvedBy string) (*executionplan.ExecutionPlan, error) {
+	m := a.ExecutionPlan()
+	plan := m.resolveDraft(func(p *executionplan.ExecutionPlan) { p.Approve(approvedBy) })
+	if plan == nil {
+		return nil, errors.New("no drafted plan pending approval")
+	}
+	if m.onPlanApproved != nil {
+		m.onPlanApproved(plan)
+	}
+
+	execErr := m.executor.Execute(ctx, plan, a.buildToolContext(ctx))
+	if m.onPlanCompleted != nil {
+		m.onPlanCompleted(plan)
+	}
+
+	summary := plan.Summary()
+	event := &types.ControlPlanResolvedEvent{
+		PlanID:    plan.ID,
+		Approved:  true,
+		Status:    string(plan.Status),
+		Completed: summary.Completed,
+		Failed:    summary.Failed,
+	}
+	if execErr != nil {
+		event.Error = execErr.Error()
+	}
+	a.eventBus.EmitControl(event)
+	a.addPlanNotice(formatPlanOutcome(plan))
+
+	agentLog.Info(ctx, "drafted plan executed", map[string]any{
+		"agent_id": a.id, "plan_id": plan.ID, "status": plan.Status, "completed": summary.Completed, "failed": summary.Failed,
+	})
+	return plan, execErr
+}
+
+// RejectDraftPlan 放弃计划模式下累积的计划
+func (a *Agent) RejectDraftPlan(reason string) error {
+	m := a.ExecutionPlan()
+	plan := m.resolveDraft(func(p *executionplan.ExecutionPlan) { p.Reject(reason) })
+	if plan == nil {
+		return errors.New("no drafted plan pending approval")
+	}
+	if m.onPlanRejected != nil {
+		m.onPlanRejected(plan, reason)
+	}
+
+	a.eventBus.EmitControl(&types.ControlPlanResolvedEvent{
+		PlanID: plan.ID,
+		Status: string(plan.Status),
+	})
+	notice := "<plan-result>\nThe user rejected your plan; none of its steps were executed."
+	if reason != "" {
+		notice += "\nReason: " + reason
+	}
+	a.addPlanNotice(notice + "\n</plan-result>")
+	return nil
+}
+
+// formatPlanOutcome 汇总已执行计划的各步骤结果
+func formatPlanOutcome(plan *executionplan.ExecutionPlan) string {
+	var sb strings.Builder
+	sb.WriteString("<plan-result>\nThe user approved your plan and it was executed:\n")
+	for _, step := range plan.Steps {
+		fmt.Fprintf(&sb, "%d. %s: %s", step.Index+1, step.Description, step.Status)
+		if step.Error != "" {
+			sb.WriteString(" (" + step.Error + ")")
+		}
+		sb.WriteString("\n")
+	}
+	sb.WriteString("</plan-result>")
+	return sb.String()
+}
+
+// addPlanNotice 记录计划结果，随下一条用户消息送达模型
+func (a *Agent) addPlanNotice(text string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.planNotices = append(a.planNotices, text)
+}
+
+// planNoticeBlocksLocked 取出待送达的计划结果，调用方须持有 mu
+func (a *Agent) planNoticeBlocksLocked() []types.ContentBlock {
+	blocks := make([]types.ContentBlock, 0, len(a.planNotices))
+	for _, text := range a.planNotices {
+		blocks = append(blocks, &types.TextBlock{Text: text})
+	}
+	a.planNotices = nil
+	return blocks
+}
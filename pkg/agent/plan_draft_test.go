@@ -0,0 +1,86 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/astercloud/aster/pkg/executionplan"
+	"github.com/astercloud/aster/pkg/permission"
+	"github.com/astercloud/aster/pkg/provider"
+	"github.com/astercloud/aster/pkg/tools"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+// countingTool 记录执行次数
+type countingTool struct {
+	tools.BaseTool
+	calls int
+}
+
+func (c *countingTool) Execute(_ context.Context, _ map[string]any, _ *tools.ToolContext) (any, error) {
+	c.calls++
+	return map[string]any{"ok": true}, nil
+}
+
+func TestPlanModeDraftsAndApproves(t *testing.T) {
+	p := &steerProvider{script: []func(context.Context, chan<- provider.StreamChunk){
+		func(_ context.Context, ch chan<- provider.StreamChunk) {
+			ch <- provider.StreamChunk{Type: "content_block_start", Index: 0, Delta: map[string]any{
+				"type": "tool_use", "id": "call-1", "name": "Deploy", "input": map[string]any{"description": "deploy v2"},
+			}}
+			ch <- provider.StreamChunk{Type: "content_block_stop", Index: 0}
+		},
+		textReply("Plan ready for review"),
+		textReply("Deployed"),
+	}}
+	ag := newSteerTestAgent(t, p)
+	tool := &countingTool{BaseTool: tools.BaseTool{ToolName: "Deploy", ToolDescription: "deploys"}}
+	ag.toolMap["Deploy"] = tool
+	ag.SetPermissionMode(permission.ModePlan)
+	events := ag.Subscribe([]types.AgentChannel{types.ChannelProgress, types.ChannelControl}, nil)
+	ctx := context.Background()
+
+	if err := ag.Send(ctx, "Deploy version 2"); err != nil {
+		t.Fatal(err)
+	}
+	collectSteerEvents(t, events)
+
+	if tool.calls != 0 {
+		t.Fatalf("tool executed in plan mode: calls = %d", tool.calls)
+	}
+	draft := ag.DraftPlan()
+	if draft == nil || len(draft.Steps) != 1 || draft.Steps[0].ToolName != "Deploy" {
+		t.Fatalf("draft plan = %+v", draft)
+	}
+	// 模型收到的是计划记录而非执行结果
+	req := p.request(1)
+	if r, ok := req[len(req)-1].ContentBlocks[0].(*types.ToolResultBlock); !ok || !strings.Contains(r.Content, `"planned":true`) {
+		t.Fatalf("tool result = %+v", req[len(req)-1].ContentBlocks)
+	}
+
+	plan, err := ag.ApproveDraftPlan(ctx, "tester")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tool.calls != 1 || plan.Status != executionplan.StatusCompleted {
+		t.Fatalf("approved plan: calls = %d, status = %s", tool.calls, plan.Status)
+	}
+	if ag.DraftPlan() != nil {
+		t.Error("draft plan still pending after approval")
+	}
+	if _, err := ag.ApproveDraftPlan(ctx, "tester"); err == nil {
+		t.Error("expected error approving without a draft plan")
+	}
+
+	// 执行结果随下一条用户消息送达模型
+	if err := ag.Send(ctx, "Is it done?"); err != nil {
+		t.Fatal(err)
+	}
+	collectSteerEvents(t, events)
+	req = p.request(2)
+	last := req[len(req)-1]
+	if len(last.ContentBlocks) != 2 || !strings.Contains(last.ContentBlocks[0].(*types.TextBlock).Text, "<plan-result>") {
+		t.Fatalf("follow-up message = %+v", last.ContentBlocks)
+	}
+}
@@ -0,0 +1,346 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/astercloud/aster/pkg/executionplan"
+	"github.com/astercloud/aster/pkg/provider"
+	"github.com/astercloud/aster/pkg/structured"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+// plannerExecutorSource 规划-执行模式生成的计划的来源标记
+const plannerExecutorSource = "planner_executor"
+
+// stepFailedPrefix 执行模型无法完成步骤时回复的前缀
+const stepFailedPrefix = "STEP FAILED:"
+
+// PlanExecuteConfig 规划-执行模式配置
+type PlanExecuteConfig struct {
+	// Planner 规划模型，负责生成和更新执行计划
+	Planner provider.Provider
+	// PlannerModel Planner 为空时通过 ProviderFactory 创建的规划模型；两者都为空时使用 Agent 自身的模型
+	PlannerModel *types.ModelConfig
+	// MaxStepFailures 同一步骤连续失败多少次后重新规划，默认 2
+	MaxStepFailures int
+	// MaxReplans 最多重新规划次数，超过后计划失败，默认 2
+	MaxReplans int
+}
+
+// plannerResponse 规划模型返回的 JSON
+type plannerResponse struct {
+	Description string        `json:"description"`
+	Steps       []plannedStep `json:"steps"`
+}
+
+type plannedStep struct {
+	Description string `json:"description"`
+}
+
+const plannerPrompt = `You are the planner. Break the task into a short ordered list of concrete steps for an executor agent that has these tools: %s.
+Each step must be achievable in one turn and verifiable from its result. Do not execute anything yourself.
+
+Task:
+%s
+%s
+Respond with JSON only: {"description": "<one line summary of the plan>", "steps": [{"description": "<what to do>"}]}`
+
+const replanContext = `
+Progress so far:
+%s
+Step %d failed %d time(s) in a row: %s
+Last error: %s
+
+Plan the remaining work again, starting from the failed step. Do not repeat completed steps; take a different approach to the failed one.
+`
+
+const executorPrompt = `You are executing a plan step by step.
+
+Task:
+%s
+
+Plan:
+%s
+Carry out only step %d: %s
+When done, reply with a short summary of the result. If the step cannot be completed, reply with "` + stepFailedPrefix + `" followed by the reason.`
+
+// PlanAndExecute 以规划-执行模式完成任务
+//
+// 规划模型生成执行计划，Agent 自身的模型作为执行模型逐步完成每个步骤（可使用其全部工具）；
+// 同一步骤连续失败 MaxStepFailures 次后把进度和错误交给规划模型重新规划剩余步骤，
+// 原有的未执行步骤标记为跳过。计划作为当前计划保存，执行过程中可通过 ExecutionPlan() 的
+// GetCurrentPlan、GetPlanSummary、FormatCurrentPlan 查看，每次生成或更新时发送 plan_updated 控制事件
+func (a *Agent) PlanAndExecute(ctx context.Context, task string, cfg *PlanExecuteConfig) (*executionplan.ExecutionPlan, error) {
+	if strings.TrimSpace(task) == "" {
+		return nil, errors.New("task cannot be empty")
+	}
+	if cfg == nil {
+		cfg = &PlanExecuteConfig{}
+	}
+	planner, owned, err := a.plannerProvider(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if owned {
+		defer func() { _ = planner.Close() }()
+	}
+	maxFailures := cfg.MaxStepFailures
+	if maxFailures <= 0 {
+		maxFailures = 2
+	}
+	maxReplans := cfg.MaxReplans
+	if maxReplans <= 0 {
+		maxReplans = 2
+	}
+
+	m := a.ExecutionPlan()
+	resp, err := a.requestPlan(ctx, planner, task, "")
+	if err != nil {
+		return nil, err
+	}
+	plan := executionplan.NewExecutionPlan(resp.Description)
+	plan.AgentID = a.id
+	plan.Options = &executionplan.ExecutionOptions{AutoApprove: true}
+	plan.Metadata = map[string]any{"source": plannerExecutorSource, "task": task, "revision": 1}
+	addPlannedSteps(plan, resp)
+	plan.Approve("planner")
+
+	start := time.Now()
+	m.mu.Lock()
+	m.currentPlan = plan
+	plan.StartedAt = &start
+	plan.Status = executionplan.StatusExecuting
+	m.mu.Unlock()
+	a.planUpdated(ctx, m, plan, 1, "")
+
+	// 计划在执行过程中可被 GetCurrentPlan 等读取，修改计划和读取步骤时都持有 m.mu
+	revision, failures := 1, 0
+	for i := 0; ; i++ {
+		m.mu.Lock()
+		if i >= len(plan.Steps) {
+			m.mu.Unlock()
+			break
+		}
+		if plan.Steps[i].Status != executionplan.StepStatusPending {
+			m.mu.Unlock()
+			continue
+		}
+		plan.MarkStepStarted(i)
+		prompt := fmt.Sprintf(executorPrompt, task, planProgress(plan), i+1, plan.Steps[i].Description)
+		m.mu.Unlock()
+
+		result, stepErr := a.executeStep(ctx, prompt)
+		if ctx.Err() != nil {
+			m.mu.Lock()
+			plan.MarkStepFailed(i, ctx.Err())
+			m.mu.Unlock()
+			return a.finishPlan(ctx, m, plan, revision, ctx.Err())
+		}
+		if stepErr == nil {
+			m.mu.Lock()
+			plan.MarkStepCompleted(i, result)
+			m.mu.Unlock()
+			failures = 0
+			continue
+		}
+
+		failures++
+		agentLog.Warn(ctx, "plan step failed", map[string]any{
+			"agent_id": a.id, "plan_id": plan.ID, "step": i + 1, "failures": failures, "error": stepErr.Error(),
+		})
+		m.mu.Lock()
+		step := plan.GetStep(i)
+		step.RetryCount = failures - 1
+		if failures < maxFailures {
+			step.Status = executionplan.StepStatusPending
+			m.mu.Unlock()
+			i-- // 重试同一步骤
+			continue
+		}
+		plan.MarkStepFailed(i, stepErr)
+		replanPrompt := fmt.Sprintf(replanContext, planProgress(plan), i+1, failures, step.Description, stepErr.Error())
+		m.mu.Unlock()
+
+		if revision-1 >= maxReplans {
+			return a.finishPlan(ctx, m, plan, revision, fmt.Errorf("step %d failed after %d re-plans: %w", i+1, maxReplans, stepErr))
+		}
+		reason := fmt.Sprintf("step %d failed %d time(s): %s", i+1, failures, stepErr.Error())
+		resp, err := a.requestPlan(ctx, planner, task, replanPrompt)
+		if err != nil {
+			return a.finishPlan(ctx, m, plan, revision, fmt.Errorf("re-plan: %w", err))
+		}
+		revision++
+		failures = 0
+		m.mu.Lock()
+		for j := i + 1; j < len(plan.Steps); j++ {
+			if plan.Steps[j].Status == executionplan.StepStatusPending {
+				plan.Steps[j].Status = executionplan.StepStatusSkipped
+			}
+		}
+		plan.Metadata["revision"] = revision
+		addPlannedSteps(plan, resp)
+		m.mu.Unlock()
+		a.planUpdated(ctx, m, plan, revision, reason)
+	}
+	return a.finishPlan(ctx, m, plan, revision, nil)
+}
+
+// plannerProvider 返回规划模型，owned 表示由此处根据 PlannerModel 创建、需由调用方关闭
+func (a *Agent) plannerProvider(cfg *PlanExecuteConfig) (p provider.Provider, owned bool, err error) {
+	if cfg.Planner != nil {
+		return cfg.Planner, false, nil
+	}
+	if cfg.PlannerModel != nil {
+		if a.deps == nil || a.deps.ProviderFactory == nil {
+			return nil, false, errors.New("planner model requires a provider factory")
+		}
+		p, err := a.deps.ProviderFactory.Create(cfg.PlannerModel)
+		if err != nil {
+			return nil, false, fmt.Errorf("create planner provider: %w", err)
+		}
+		return p, true, nil
+	}
+	return a.provider, false, nil
+}
+
+// requestPlan 调用规划模型生成步骤，extra 为重新规划时附加的进度和失败信息
+func (a *Agent) requestPlan(ctx context.Context, planner provider.Provider, task, extra string) (*plannerResponse, error) {
+	toolNames := make([]string, 0, len(a.toolMap))
+	for name := range a.toolMap {
+		toolNames = append(toolNames, name)
+	}
+	slices.Sort(toolNames)
+	available := strings.Join(toolNames, ", ")
+	if available == "" {
+		available = "none"
+	}
+
+	resp, err := planner.Complete(ctx, []types.Message{{
+		Role:    types.MessageRoleUser,
+		Content: fmt.Sprintf(plannerPrompt, available, task, extra),
+	}}, &provider.StreamOptions{MaxTokens: 4000, Temperature: 0.2})
+	if err != nil {
+		return nil, fmt.Errorf("generate plan: %w", err)
+	}
+	parsed, err := structured.NewJSONParser().Parse(ctx, resp.Message.GetContent(), structured.OutputSpec{Enabled: true})
+	if err != nil {
+		return nil, fmt.Errorf("parse plan: %w", err)
+	}
+	var plan plannerResponse
+	if err := json.Unmarshal([]byte(parsed.RawJSON), &plan); err != nil {
+		return nil, fmt.Errorf("parse plan: %w", err)
+	}
+	plan.Steps = slices.DeleteFunc(plan.Steps, func(s plannedStep) bool {
+		return strings.TrimSpace(s.Description) == ""
+	})
+	if len(plan.Steps) == 0 {
+		return nil, errors.New("planner returned no steps")
+	}
+	return &plan, nil
+}
+
+// addPlannedSteps 追加规划模型给出的步骤，步骤由执行模型完成，不指定工具
+func addPlannedSteps(plan *executionplan.ExecutionPlan, resp *plannerResponse) {
+	if plan.Description == "" {
+		plan.Description = resp.Description
+	}
+	for _, s := range resp.Steps {
+		plan.AddStep("", s.Description, nil)
+	}
+}
+
+// executeStep 让执行模型按 prompt 完成一个步骤，返回结果摘要
+func (a *Agent) executeStep(ctx context.Context, prompt string) (string, error) {
+	result, err := a.Chat(ctx, prompt)
+	if err != nil {
+		return "", err
+	}
+	if result.Error != nil {
+		return "", result.Error
+	}
+	text := strings.TrimSpace(result.Text)
+	if reason, failed := strings.CutPrefix(text, stepFailedPrefix); failed {
+		return "", errors.New(strings.TrimSpace(reason))
+	}
+	return text, nil
+}
+
+// planProgress 列出计划各步骤的状态和结果，提供给规划模型和执行模型，调用方须持有 m.mu
+func planProgress(plan *executionplan.ExecutionPlan) string {
+	var sb strings.Builder
+	for _, step := range plan.Steps {
+		if step.Status == executionplan.StepStatusSkipped {
+			continue
+		}
+		fmt.Fprintf(&sb, "%d. [%s] %s", step.Index+1, step.Status, step.Description)
+		if text, ok := step.Result.(string); ok && text != "" {
+			sb.WriteString(" => " + truncate(text, 200))
+		}
+		if step.Error != "" {
+			sb.WriteString(" (error: " + step.Error + ")")
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// planUpdated 通知计划已生成或更新
+func (a *Agent) planUpdated(ctx context.Context, m *ExecutionPlanManager, plan *executionplan.ExecutionPlan, revision int, reason string) {
+	if m.onPlanGenerated != nil {
+		m.onPlanGenerated(plan)
+	}
+	m.mu.Lock()
+	status, steps := plan.Status, len(plan.Steps)
+	m.mu.Unlock()
+	a.eventBus.EmitControl(&types.ControlPlanUpdatedEvent{
+		PlanID:   plan.ID,
+		Revision: revision,
+		Status:   string(status),
+		Steps:    steps,
+		Reason:   reason,
+	})
+	agentLog.Info(ctx, "execution plan updated by planner", map[string]any{
+		"agent_id": a.id, "plan_id": plan.ID, "revision": revision, "steps": steps, "reason": reason,
+	})
+}
+
+// finishPlan 记录计划的最终状态
+func (a *Agent) finishPlan(ctx context.Context, m *ExecutionPlanManager, plan *executionplan.ExecutionPlan, revision int, err error) (*executionplan.ExecutionPlan, error) {
+	now := time.Now()
+	m.mu.Lock()
+	plan.CompletedAt = &now
+	if plan.StartedAt != nil {
+		plan.TotalDurationMs = now.Sub(*plan.StartedAt).Milliseconds()
+	}
+	switch {
+	case errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded):
+		plan.Status = executionplan.StatusCancelled
+	case err != nil:
+		plan.Status = executionplan.StatusFailed
+	default:
+		plan.Status = executionplan.StatusCompleted
+	}
+	plan.UpdatedAt = now
+	status, summary := plan.Status, plan.Summary()
+	m.mu.Unlock()
+
+	if m.onPlanCompleted != nil {
+		m.onPlanCompleted(plan)
+	}
+	a.eventBus.EmitControl(&types.ControlPlanUpdatedEvent{
+		PlanID:   plan.ID,
+		Revision: revision,
+		Status:   string(status),
+		Steps:    summary.TotalSteps,
+	})
+	agentLog.Info(ctx, "planned task finished", map[string]any{
+		"agent_id": a.id, "plan_id": plan.ID, "status": status, "completed": summary.Completed, "failed": summary.Failed,
+	})
+	return plan, err
+}
@@ -0,0 +1,105 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/astercloud/aster/pkg/executionplan"
+	"github.com/astercloud/aster/pkg/provider"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+func TestPlanAndExecuteReplansAfterRepeatedFailures(t *testing.T) {
+	var mu sync.Mutex
+	var plannerPrompts []string
+	planner := &MockProvider{name: "planner", completeFunc: func(_ context.Context, messages []types.Message, _ *provider.StreamOptions) (*provider.CompleteResponse, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		plannerPrompts = append(plannerPrompts, messages[0].GetContent())
+		plan := `{"description": "Ship the release", "steps": [{"description": "read config"}, {"description": "deploy"}, {"description": "announce"}]}`
+		if len(plannerPrompts) > 1 {
+			plan = "Revised plan:\n" + `{"description": "Deploy with elevated rights", "steps": [{"description": "deploy with sudo"}, {"description": "announce"}]}`
+		}
+		return &provider.CompleteResponse{Message: types.Message{Role: types.MessageRoleAssistant, Content: plan}}, nil
+	}}
+
+	executor := &MockProvider{
+		name:         "executor",
+		capabilities: provider.ProviderCapabilities{SupportStreaming: true, SupportSystemPrompt: true},
+		streamFunc: func(_ context.Context, messages []types.Message, _ *provider.StreamOptions) (<-chan provider.StreamChunk, error) {
+			prompt := messages[len(messages)-1].GetContent()
+			reply := "done"
+			if strings.Contains(prompt, "Carry out only step 2: deploy\n") {
+				reply = "STEP FAILED: permission denied"
+			}
+			ch := make(chan provider.StreamChunk, 1)
+			ch <- provider.StreamChunk{Type: "text", TextDelta: reply}
+			close(ch)
+			return ch, nil
+		},
+	}
+	factory := NewMockProviderFactory()
+	factory.SetProvider("mock/executor", executor)
+	deps := setupTestDeps(t)
+	deps.ProviderFactory = factory
+
+	ag, err := Create(context.Background(), &types.AgentConfig{
+		TemplateID:  "test-template",
+		ModelConfig: &types.ModelConfig{Provider: "mock", Model: "executor"},
+		Sandbox:     &types.SandboxConfig{Kind: types.SandboxKindMock, WorkDir: "/tmp/test"},
+	}, deps)
+	if err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+	defer func() { _ = ag.Close() }()
+
+	var generated int
+	ag.ExecutionPlan().SetCallbacks(func(*executionplan.ExecutionPlan) { generated++ }, nil, nil, nil)
+	events := ag.Subscribe([]types.AgentChannel{types.ChannelControl}, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+	plan, err := ag.PlanAndExecute(ctx, "ship the release", &PlanExecuteConfig{Planner: planner, MaxStepFailures: 2})
+	if err != nil {
+		t.Fatalf("PlanAndExecute failed: %v", err)
+	}
+
+	if plan.Status != executionplan.StatusCompleted || plan.Metadata["revision"] != 2 || generated != 2 {
+		t.Fatalf("unexpected plan: status=%s revision=%v generated=%d", plan.Status, plan.Metadata["revision"], generated)
+	}
+	var statuses []string
+	for _, step := range plan.Steps {
+		statuses = append(statuses, step.Description+":"+string(step.Status))
+	}
+	want := "read config:completed,deploy:failed,announce:skipped,deploy with sudo:completed,announce:completed"
+	if got := strings.Join(statuses, ","); got != want {
+		t.Errorf("steps = %s, want %s", got, want)
+	}
+	if failed := plan.Steps[1]; failed.Error != "permission denied" || failed.RetryCount != 1 {
+		t.Errorf("unexpected failed step: %+v", failed)
+	}
+	if ag.ExecutionPlan().GetCurrentPlan() != plan {
+		t.Error("expected the plan to be the current execution plan")
+	}
+	if !strings.Contains(plannerPrompts[1], "Last error: permission denied") || !strings.Contains(plannerPrompts[1], "1. [completed] read config => done") {
+		t.Errorf("re-plan prompt missing progress:\n%s", plannerPrompts[1])
+	}
+
+	var revisions []int
+	for len(revisions) < 3 {
+		select {
+		case env := <-events:
+			if e, ok := env.Event.(*types.ControlPlanUpdatedEvent); ok {
+				revisions = append(revisions, e.Revision)
+			}
+		case <-ctx.Done():
+			t.Fatalf("expected three plan_updated events, got revisions %v", revisions)
+		}
+	}
+	if revisions[0] != 1 || revisions[1] != 2 || revisions[2] != 2 {
+		t.Errorf("unexpected plan_updated revisions: %v", revisions)
+	}
+}
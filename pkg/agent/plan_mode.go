@@ -7,6 +7,7 @@ import (
 	"strings"
 	"sync"
 
+	"github.com/astercloud/aster/pkg/agenterr"
 	"github.com/astercloud/aster/pkg/types"
 )
 
@@ -55,6 +56,9 @@ func (m *PlanModeManager) EnterPlanMode(planID, planFilePath, reason string) {
 		"Read":            true,
 		"Glob":            true,
 		"Grep":            true,
+		"FindDefinition":  true,
+		"FindReferences":  true,
+		"Diagnostics":     true,
 		"WebFetch":        true,
 		"WebSearch":       true,
 		"AskUserQuestion": true,
@@ -75,6 +79,9 @@ func (m *PlanModeManager) EnterPlanMode(planID, planFilePath, reason string) {
 			DisabledTools: []string{
 				"Bash",
 				"Edit",
+				"Rename",
+				"ApplyPatch",
+				"Browser",
 				"Delete",
 				"Move",
 				"Copy",
@@ -202,6 +209,10 @@ func (e *ToolCallDeniedError) Error() string {
 	return fmt.Sprintf("Tool call denied: %s - %s", e.ToolName, e.Reason)
 }
 
+func (e *ToolCallDeniedError) ErrorCode() agenterr.Code {
+	return agenterr.CodePlanModeRestricted
+}
+
 // Agent Plan Mode Methods
 
 // EnterPlanMode 让 Agent 进入 Plan 模式
@@ -8,9 +8,11 @@ import (
 	"strings"
 	"time"
 
+	"github.com/astercloud/aster/pkg/agenterr"
 	"github.com/astercloud/aster/pkg/logging"
 	"github.com/astercloud/aster/pkg/middleware"
 	"github.com/astercloud/aster/pkg/provider"
+	"github.com/astercloud/aster/pkg/requestctx"
 	"github.com/astercloud/aster/pkg/tools"
 	"github.com/astercloud/aster/pkg/types"
 )
@@ -45,43 +47,75 @@ func (a *Agent) processMessages(ctx context.Context) {
 			lastMsg := a.messages[len(a.messages)-1]
 			hasNewUserMessage = lastMsg.Role == types.MessageRoleUser
 		}
+		hasBackgroundResults := len(a.undeliveredBackgroundTasks()) > 0
 		a.mu.Unlock()
 
 		// 如果有新的用户消息，重新触发处理
-		// 注意：使用新的 context，而不是可能已取消的旧 context
+		// 注意：使用不随旧 context 取消的新 context（保留请求元数据）
 		// 这样即使用户点击了"停止"，新消息仍然可以被处理
 		if hasNewUserMessage {
-			newCtx := context.Background()
+			newCtx := requestctx.Detach(ctx)
 			go a.processMessages(newCtx)
+		} else if hasBackgroundResults {
+			// 运行期间结束的后台任务，结果在运行结束后送达
+			go a.deliverBackgroundTasks(requestctx.Detach(ctx))
 		}
 	}()
 
-	// 发送状态变更事件
-	a.eventBus.EmitMonitor(&types.MonitorStateChangedEvent{
+	// 本次运行的事件、日志、工具和模型调用都带上请求元数据
+	ctx, endRequest := a.beginRequest(ctx)
+	defer endRequest()
+
+	// 发送状态变更事件，本次运行的日志带上与 Dashboard 追踪一致的关联 ID
+	started := a.eventBus.EmitMonitor(&types.MonitorStateChangedEvent{
 		State: types.AgentStateWorking,
 	})
+	ctx = logging.WithCorrelation(ctx, logging.Correlation{
+		SessionID: a.id,
+		TraceID:   types.RunTraceID(a.id, started.Cursor),
+	})
 
 	// 设置断点
 	a.setBreakpoint(types.BreakpointPreModel)
 
 	procLog.Info(ctx, "calling runModelStep", map[string]any{"agent_id": a.id})
 
-	// 调用模型
-	if err := a.runModelStep(ctx); err != nil {
-		procLog.Error(ctx, "runModelStep failed", map[string]any{"agent_id": a.id, "error": err.Error()})
+	// 调用模型（受 MaxTurns/MaxOutputTokens/MaxDuration 限制）
+	runCtx, cancel := a.startRun(ctx)
+	defer cancel()
+	endSteering := a.beginSteering()
+	defer endSteering()
+	err := a.runModelStep(runCtx)
+	termination, summary, runErr := a.finishRun(runCtx, err)
+	a.recordExperimentRun(ctx, termination)
+	if termination.IsLimit() {
+		procLog.Info(ctx, "run stopped by limit", map[string]any{"agent_id": a.id, "reason": termination})
+	} else if runErr != nil {
+		procLog.Error(ctx, "runModelStep failed", map[string]any{"agent_id": a.id, "error": runErr.Error(), "code": runErr.Code})
 		a.eventBus.EmitMonitor(&types.MonitorErrorEvent{
-			Severity: "error",
-			Phase:    "model",
-			Message:  err.Error(),
+			Severity:  "error",
+			Phase:     "model",
+			Message:   runErr.Error(),
+			Code:      runErr.Code,
+			Class:     runErr.Class(),
+			Retryable: runErr.Retryable(),
 		})
 	}
 
+	if termination == types.TerminationCompleted {
+		a.maybeGenerateTitle()
+	}
+
 	procLog.Info(ctx, "runModelStep completed, sending done event", map[string]any{"agent_id": a.id})
 
+	a.flushSessionCost()
+
 	// 发送完成事件
 	a.eventBus.EmitProgress(&types.ProgressDoneEvent{
-		Step:   a.stepCount,
-		Reason: "completed",
+		Step:    a.stepCount,
+		Reason:  termination,
+		Summary: summary,
+		Error:   runErr,
 	})
 
 	// 发送状态变更事件
@@ -101,34 +135,15 @@ func (a *Agent) runModelStep(ctx context.Context) error {
 		return a.runNonStreamingStep(ctx)
 	}
 
+	if err := a.beginTurn(); err != nil {
+		return err
+	}
+
 	procLog.Info(ctx, "using STREAMING mode (real-time feedback)", map[string]any{"agent_id": a.id})
 	a.setBreakpoint(types.BreakpointStreamingModel)
 
 	// 准备工具Schema（包含使用示例）
-	toolSchemas := make([]provider.ToolSchema, 0, len(a.toolMap))
-	for _, tool := range a.toolMap {
-		schema := provider.ToolSchema{
-			Name:        tool.Name(),
-			Description: tool.Description(),
-			InputSchema: tool.InputSchema(),
-		}
-		// 检查工具是否实现了 ExampleableTool 接口
-		if exampleable, ok := tool.(tools.ExampleableTool); ok {
-			examples := exampleable.Examples()
-			if len(examples) > 0 {
-				providerExamples := make([]provider.ToolExample, len(examples))
-				for i, ex := range examples {
-					providerExamples[i] = provider.ToolExample{
-						Description: ex.Description,
-						Input:       ex.Input,
-						Output:      ex.Output,
-					}
-				}
-				schema.InputExamples = providerExamples
-			}
-		}
-		toolSchemas = append(toolSchemas, schema)
-	}
+	toolSchemas := a.buildToolSchemas()
 	toolNames := make([]string, len(toolSchemas))
 	for i, ts := range toolSchemas {
 		toolNames[i] = ts.Name
@@ -158,10 +173,19 @@ func (a *Agent) runModelStep(ctx context.Context) error {
 
 	procLog.Debug(ctx, "final system prompt", map[string]any{"agent_id": a.id, "length": len(currentSystemPrompt), "contains_manual": strings.Contains(currentSystemPrompt, "### Tools Manual")})
 
+	// 请求超出模型上下文窗口前主动压缩，无法容纳时直接拒绝
+	messages, err := a.fitContextWindow(ctx, currentSystemPrompt, toolSchemas, messages)
+	if err != nil {
+		return fmt.Errorf("model call: %w", err)
+	}
+
 	// 通过 Middleware Stack 调用模型 (Phase 6C)
 	var assistantMessage types.Message
 	var modelErr error
 
+	// 生成过程中收到引导消息时打断本次调用
+	modelCtx, modelDone := a.steerModelContext(ctx)
+
 	procLog.Info(ctx, "preparing to call LLM", map[string]any{"agent_id": a.id, "message_count": len(messages), "has_middleware": a.middlewareStack != nil})
 
 	if a.middlewareStack != nil {
@@ -194,11 +218,11 @@ func (a *Agent) runModelStep(ctx context.Context) error {
 			procLog.Info(ctx, "finalHandler: calling provider.Stream", map[string]any{"agent_id": a.id, "message_count": len(req.Messages)})
 			streamOpts := &provider.StreamOptions{
 				Tools:     toolSchemas,
-				MaxTokens: 32000, // Claude 4 Sonnet/Opus 最大支持 64000 output tokens
+				MaxTokens: a.outputTokenCap(32000), // Claude 4 Sonnet/Opus 最大支持 64000 output tokens
 				System:    req.SystemPrompt,
 			}
 
-			stream, err := a.provider.Stream(ctx, req.Messages, streamOpts)
+			stream, err := a.modelProviderForStep(ctx).Stream(ctx, req.Messages, streamOpts)
 			if err != nil {
 				procLog.Error(ctx, "provider.Stream failed", map[string]any{"agent_id": a.id, "error": err.Error()})
 				return nil, fmt.Errorf("stream model: %w", err)
@@ -219,7 +243,7 @@ func (a *Agent) runModelStep(ctx context.Context) error {
 
 		// 通过 middleware stack 执行
 		procLog.Info(ctx, "calling middlewareStack.ExecuteModelCall", map[string]any{"agent_id": a.id})
-		resp, err := a.middlewareStack.ExecuteModelCall(ctx, req, finalHandler)
+		resp, err := a.middlewareStack.ExecuteModelCall(modelCtx, req, finalHandler)
 		if err != nil {
 			procLog.Error(ctx, "middlewareStack.ExecuteModelCall failed", map[string]any{"agent_id": a.id, "error": err.Error()})
 			modelErr = err
@@ -231,24 +255,29 @@ func (a *Agent) runModelStep(ctx context.Context) error {
 		// 没有 middleware, 直接调用
 		streamOpts := &provider.StreamOptions{
 			Tools:     toolSchemas,
-			MaxTokens: 32000, // Claude 4 Sonnet/Opus 最大支持 64000 output tokens
+			MaxTokens: a.outputTokenCap(32000), // Claude 4 Sonnet/Opus 最大支持 64000 output tokens
 			System:    currentSystemPrompt,
 		}
 
-		stream, err := a.provider.Stream(ctx, messages, streamOpts)
+		stream, err := a.modelProviderForStep(ctx).Stream(modelCtx, messages, streamOpts)
 		if err != nil {
 			modelErr = err
 		} else {
-			assistantMessage, err = a.handleStreamResponse(ctx, stream)
+			assistantMessage, err = a.handleStreamResponse(modelCtx, stream)
 			if err != nil {
 				modelErr = err
 			}
 		}
 	}
 
+	// 被引导消息打断：丢弃不完整的输出，带着新指令重新调用
+	if modelDone() && a.applySteering(ctx, steerPhaseModel, nil) {
+		return a.runModelStep(ctx)
+	}
+
 	// 处理模型调用错误
 	if modelErr != nil {
-		return fmt.Errorf("model call: %w", modelErr)
+		return agenterr.Wrap(agenterr.CodeProviderError, fmt.Errorf("model call: %w", modelErr))
 	}
 
 	// 保存助手消息
@@ -267,7 +296,7 @@ func (a *Agent) runModelStep(ctx context.Context) error {
 	a.mu.Unlock()
 
 	// 持久化（已修剪的消息）
-	if err := a.deps.Store.SaveMessages(ctx, a.id, a.messages); err != nil {
+	if err := a.deps.Store.SaveMessages(ctx, a.id, a.messagesForStore(a.messages)); err != nil {
 		return fmt.Errorf("save messages: %w", err)
 	}
 
@@ -288,20 +317,86 @@ func (a *Agent) runModelStep(ctx context.Context) error {
 		return a.executeTools(ctx, toolUses)
 	} else {
 		procLog.Debug(ctx, "no tool uses found, only text response", map[string]any{"agent_id": a.id})
+		a.observeAssistantAnswer(ctx, assistantMessage)
+		// 回答期间收到的引导消息在本轮内继续处理
+		if a.applySteering(ctx, steerPhaseTurnEnd, nil) {
+			return a.runModelStep(ctx)
+		}
 	}
 
 	return nil
 }
 
+// buildToolSchemas 将已加载的工具转换为模型请求中的工具 Schema（包含使用示例）
+func (a *Agent) buildToolSchemas() []provider.ToolSchema {
+	toolSchemas := make([]provider.ToolSchema, 0, len(a.toolMap))
+	for name, tool := range a.toolMap {
+		if !a.toolOffered(name) {
+			continue
+		}
+		schema := provider.ToolSchema{
+			Name:        tool.Name(),
+			Description: tool.Description(),
+			InputSchema: tool.InputSchema(),
+		}
+		// 检查工具是否实现了 ExampleableTool 接口
+		if exampleable, ok := tool.(tools.ExampleableTool); ok {
+			examples := exampleable.Examples()
+			if len(examples) > 0 {
+				providerExamples := make([]provider.ToolExample, len(examples))
+				for i, ex := range examples {
+					providerExamples[i] = provider.ToolExample{
+						Description: ex.Description,
+						Input:       ex.Input,
+						Output:      ex.Output,
+					}
+				}
+				schema.InputExamples = providerExamples
+			}
+		}
+		toolSchemas = append(toolSchemas, schema)
+	}
+	return toolSchemas
+}
+
 // executeTools 执行工具
 func (a *Agent) executeTools(ctx context.Context, toolUses []*types.ToolUseBlock) error {
 	toolResults := make([]types.ContentBlock, 0, len(toolUses))
 
+	a.mu.Lock()
+	a.run.toolCalls += len(toolUses)
+	a.mu.Unlock()
+
+	var skipped []string
 	for _, tu := range toolUses {
-		result := a.executeSingleTool(ctx, tu)
+		// 收到引导消息后，同批尚未开始的工具调用不再执行
+		if a.steeringPending() {
+			toolResults = append(toolResults, skippedToolResult(tu.ID))
+			skipped = append(skipped, tu.ID)
+			continue
+		}
+		toolCtx, toolDone := a.steerToolContext(ctx, tu.ID)
+		result := a.executeSingleTool(toolCtx, tu)
+		toolDone()
 		toolResults = append(toolResults, result)
 	}
 
+	// 模型升级：连续工具失败时切换到更强的模型继续
+	a.observeToolResults(ctx, toolResults)
+
+	// 循环保护：重复调用、来回修改或无进展时介入
+	toolResults, loopErr := a.checkLoopGuard(ctx, toolUses, toolResults)
+
+	// Watch 订阅的外部文件变更随工具结果注入
+	a.ignoreOwnWrites(toolUses)
+	if notice := a.fileChangeNotice(); notice != nil {
+		toolResults = append(toolResults, notice)
+	}
+
+	// 执行期间收到的引导消息随工具结果注入
+	steering := a.takeSteering()
+	toolResults = append(toolResults, steeringBlocks(steering)...)
+
 	// 保存工具结果
 	a.mu.Lock()
 	a.messages = append(a.messages, types.Message{
@@ -322,8 +417,12 @@ func (a *Agent) executeTools(ctx context.Context, toolUses []*types.ToolUseBlock
 	a.stepCount++
 	a.mu.Unlock()
 
+	if len(steering) > 0 {
+		a.emitSteerApplied(ctx, steerPhaseTools, len(steering), skipped)
+	}
+
 	// 持久化（已修剪的消息）
-	if err := a.deps.Store.SaveMessages(ctx, a.id, a.messages); err != nil {
+	if err := a.deps.Store.SaveMessages(ctx, a.id, a.messagesForStore(a.messages)); err != nil {
 		return fmt.Errorf("save messages: %w", err)
 	}
 
@@ -336,6 +435,11 @@ func (a *Agent) executeTools(ctx context.Context, toolUses []*types.ToolUseBlock
 		return fmt.Errorf("save tool records: %w", err)
 	}
 
+	// 工具结果已保存，用户在循环保护暂停时选择停止
+	if loopErr != nil {
+		return loopErr
+	}
+
 	// 检查迭代限制（防止无限循环）
 	a.mu.Lock()
 	a.iterationCount++
@@ -362,7 +466,7 @@ func (a *Agent) executeTools(ctx context.Context, toolUses []*types.ToolUseBlock
 		select {
 		case decision := <-a.iterationContinueCh:
 			if !decision {
-				return fmt.Errorf("iteration stopped by user after %d iterations", currentIter)
+				return agenterr.New(agenterr.CodeIterationLimit, fmt.Sprintf("iteration stopped by user after %d iterations", currentIter))
 			}
 			// 用户确认继续，重置迭代计数并继续
 			a.mu.Lock()
@@ -392,44 +496,31 @@ func (a *Agent) executeSingleTool(ctx context.Context, tu *types.ToolUseBlock) t
 			"id":    tu.ID,
 			"error": errorMsg,
 		})
-		a.eventBus.EmitProgress(&types.ProgressToolErrorEvent{
-			Call: types.ToolCallSnapshot{
-				ID:        tu.ID,
-				Name:      tu.Name,
-				State:     types.ToolCallStateFailed,
-				Arguments: tu.Input,
-			},
-			Error: errorMsg,
-		})
-		return &types.ToolResultBlock{
-			ToolUseID: tu.ID,
-			Content:   fmt.Sprintf(`{"ok":false,"error":"%s","hint":"请重新调用工具，确保提供完整的参数"}`, errorMsg),
-			IsError:   true,
-		}
+		toolErr := agenterr.New(agenterr.CodeToolInvalidInput, errorMsg)
+		a.emitToolError(tu, toolErr)
+		return toolFailure(tu.ID, toolErr, map[string]any{"hint": "请重新调用工具，确保提供完整的参数"})
 	}
 
 	// Plan 模式检查：验证工具调用是否允许
 	if a.planMode != nil && a.planMode.IsActive() {
 		allowed, reason := a.planMode.ValidateToolCall(tu.Name, tu.Input)
 		if !allowed {
-			errorMsg := "Plan Mode restriction: " + reason
-			a.eventBus.EmitProgress(&types.ProgressToolErrorEvent{
-				Call: types.ToolCallSnapshot{
-					ID:        tu.ID,
-					Name:      tu.Name,
-					State:     types.ToolCallStateFailed,
-					Arguments: tu.Input,
-				},
-				Error: errorMsg,
-			})
-			return &types.ToolResultBlock{
-				ToolUseID: tu.ID,
-				Content:   fmt.Sprintf(`{"ok":false,"error":"%s","plan_mode":true}`, errorMsg),
-				IsError:   true,
-			}
+			toolErr := agenterr.New(agenterr.CodePlanModeRestricted, "Plan Mode restriction: "+reason)
+			a.emitToolError(tu, toolErr)
+			return toolFailure(tu.ID, toolErr, map[string]any{"plan_mode": true})
 		}
 	}
 
+	// 工具白名单与黑名单
+	if result := a.checkToolFilter(tu); result != nil {
+		return result
+	}
+
+	// 工作区信任检查
+	if result := a.checkWorkspaceTrust(ctx, tu); result != nil {
+		return result
+	}
+
 	// 权限检查
 	if a.permissionInspector != nil {
 		call := &types.ToolCallSnapshot{
@@ -439,30 +530,31 @@ func (a *Agent) executeSingleTool(ctx context.Context, tu *types.ToolUseBlock) t
 		}
 		checkResult, err := a.permissionInspector.Check(ctx, call)
 		if err != nil {
-			errorMsg := fmt.Sprintf("Permission check error: %v", err)
-			a.eventBus.EmitProgress(&types.ProgressToolErrorEvent{
-				Call: types.ToolCallSnapshot{
-					ID:        tu.ID,
-					Name:      tu.Name,
-					State:     types.ToolCallStateFailed,
-					Arguments: tu.Input,
-				},
-				Error: errorMsg,
-			})
-			return &types.ToolResultBlock{
-				ToolUseID: tu.ID,
-				Content:   fmt.Sprintf(`{"ok":false,"error":"%s"}`, errorMsg),
-				IsError:   true,
+			toolErr := &agenterr.Error{
+				Code:    agenterr.CodePermissionCheckError,
+				Message: fmt.Sprintf("Permission check error: %v", err),
+				Err:     err,
 			}
+			a.emitToolError(tu, toolErr)
+			return toolFailure(tu.ID, toolErr, nil)
 		}
 
-		if checkResult != nil {
+		if checkResult == nil {
+			a.auditPermission(tu, "allowed", "", "")
+		} else {
 			// 应用输入修改
 			if checkResult.UpdatedInput != nil {
 				tu.Input = checkResult.UpdatedInput
 			}
 
-			if !checkResult.Allowed {
+			if checkResult.Planned {
+				a.auditPermission(tu, "planned", checkResult.DecidedBy, checkResult.Message)
+				return a.draftPlanStep(ctx, tu)
+			}
+
+			if checkResult.Allowed {
+				a.auditPermission(tu, "allowed", checkResult.DecidedBy, checkResult.Message)
+			} else {
 				if checkResult.NeedsApproval {
 					// 创建等待 channel
 					decisionCh := make(chan string, 1)
@@ -489,43 +581,35 @@ func (a *Agent) executeSingleTool(ctx context.Context, tu *types.ToolUseBlock) t
 
 						if decision != "approved" {
 							// 用户拒绝
-							errorMsg := "Permission rejected by user for tool: " + tu.Name
-							return &types.ToolResultBlock{
-								ToolUseID: tu.ID,
-								Content:   fmt.Sprintf(`{"ok":false,"error":"%s"}`, errorMsg),
-								IsError:   true,
-							}
+							a.auditPermission(tu, "rejected", "user", decision)
+							toolErr := agenterr.New(agenterr.CodePermissionRejected, "Permission rejected by user for tool: "+tu.Name)
+							return toolFailure(tu.ID, toolErr, nil)
+						}
+						// 用户批准，学习模式下记录命令前缀，继续执行工具（跳出权限检查）
+						a.auditPermission(tu, "approved", "user", "")
+						if prefix, err := a.permissionInspector.LearnApprovedCommand(&types.ToolCallSnapshot{
+							Name:      tu.Name,
+							Arguments: tu.Input,
+						}); err != nil {
+							agentLog.Warn(ctx, "learn approved command failed", map[string]any{"error": err})
+						} else if prefix != "" {
+							agentLog.Info(ctx, "command prefix learned", map[string]any{"prefix": prefix})
 						}
-						// 用户批准，继续执行工具（跳出权限检查）
 					case <-ctx.Done():
 						// 上下文取消
 						a.mu.Lock()
 						delete(a.pendingPermissions, tu.ID)
 						a.mu.Unlock()
-						errorMsg := "Permission request canceled"
-						return &types.ToolResultBlock{
-							ToolUseID: tu.ID,
-							Content:   fmt.Sprintf(`{"ok":false,"error":"%s"}`, errorMsg),
-							IsError:   true,
-						}
+						a.auditPermission(tu, "canceled", "", "")
+						return toolFailure(tu.ID, agenterr.New(agenterr.CodeCanceled, "Permission request canceled"), nil)
 					}
 				} else {
 					// 直接拒绝（NeedsApproval 为 false）
-					errorMsg := fmt.Sprintf("Permission denied: %s (decided by: %s)", checkResult.Message, checkResult.DecidedBy)
-					a.eventBus.EmitProgress(&types.ProgressToolErrorEvent{
-						Call: types.ToolCallSnapshot{
-							ID:        tu.ID,
-							Name:      tu.Name,
-							State:     types.ToolCallStateFailed,
-							Arguments: tu.Input,
-						},
-						Error: errorMsg,
-					})
-					return &types.ToolResultBlock{
-						ToolUseID: tu.ID,
-						Content:   fmt.Sprintf(`{"ok":false,"error":"%s"}`, errorMsg),
-						IsError:   true,
-					}
+					a.auditPermission(tu, "denied", checkResult.DecidedBy, checkResult.Message)
+					toolErr := agenterr.New(agenterr.CodePermissionDenied,
+						fmt.Sprintf("Permission denied: %s (decided by: %s)", checkResult.Message, checkResult.DecidedBy))
+					a.emitToolError(tu, toolErr)
+					return toolFailure(tu.ID, toolErr, nil)
 				}
 			}
 		}
@@ -541,23 +625,18 @@ func (a *Agent) executeSingleTool(ctx context.Context, tu *types.ToolUseBlock) t
 	tool, ok := a.toolMap[tu.Name]
 	if !ok {
 		// 工具未找到
-		errorMsg := "tool not found: " + tu.Name
-		a.updateToolRecord(tu.ID, types.ToolCallStateFailed, errorMsg)
-		a.eventBus.EmitProgress(&types.ProgressToolErrorEvent{
-			Call: types.ToolCallSnapshot{
-				ID:        tu.ID,
-				Name:      tu.Name,
-				State:     types.ToolCallStateFailed,
-				Arguments: tu.Input,
-			},
-			Error: errorMsg,
-		})
-		return &types.ToolResultBlock{
-			ToolUseID: tu.ID,
-			IsError:   true,
-		}
+		toolErr := agenterr.From(&tools.ToolNotFoundError{Name: tu.Name})
+		a.updateToolRecord(tu.ID, types.ToolCallStateFailed, toolErr)
+		a.emitToolError(tu, toolErr)
+		return toolFailure(tu.ID, toolErr, nil)
 	}
 
+	// 执行前按输入 Schema 校验参数，校验失败时把结构化的问题反馈给模型修正
+	if err := tools.ValidateInput(tool, tu.Input); err != nil {
+		return a.inputValidationFailure(ctx, tu, tool, err)
+	}
+	a.resetInputCorrections(tu.Name)
+
 	startTime := time.Now()
 	record.StartTime = startTime
 	record.Progress = 0
@@ -584,8 +663,9 @@ func (a *Agent) executeSingleTool(ctx context.Context, tu *types.ToolUseBlock) t
 	// 设置断点
 	a.setBreakpoint(types.BreakpointPreTool)
 
-	// 执行工具
-	a.updateToolRecord(tu.ID, types.ToolCallStateExecuting, "")
+	// 执行工具，写文件前保存检查点供撤销
+	a.checkpointToolFiles(ctx, tu)
+	a.updateToolRecord(tu.ID, types.ToolCallStateExecuting, nil)
 	a.setBreakpoint(types.BreakpointToolExecuting)
 
 	// 构建工具执行上下文，包含必要的服务注入
@@ -611,7 +691,15 @@ func (a *Agent) executeSingleTool(ctx context.Context, tu *types.ToolUseBlock) t
 
 	// 通过 Middleware Stack 执行工具 (Phase 6C)
 	var execResult *tools.ExecuteResult
-	if isLongRunning {
+	if isLongRunning && isBackgroundTool(tool) {
+		// 后台任务立即返回任务句柄，结果在后续消息中送达
+		handle, err := a.startBackgroundTask(ctx, tu.ID, tu.Name, tu.Input, lrTool)
+		if err != nil {
+			execResult = &tools.ExecuteResult{Success: false, Error: err}
+		} else {
+			execResult = &tools.ExecuteResult{Success: true, Output: handle, StartedAt: startTime, EndedAt: time.Now()}
+		}
+	} else if isLongRunning {
 		// 长时任务走异步执行 + 轮询状态
 		taskID, err := lrTool.StartAsync(ctx, tu.Input)
 		if err != nil {
@@ -758,7 +846,7 @@ func (a *Agent) executeSingleTool(ctx context.Context, tu *types.ToolUseBlock) t
 
 	// 更新记录
 	if execResult.Success {
-		a.updateToolRecord(tu.ID, types.ToolCallStateCompleted, "")
+		a.updateToolRecord(tu.ID, types.ToolCallStateCompleted, nil)
 		a.mu.Lock()
 		a.toolRecords[tu.ID].Result = execResult.Output
 		if execResult.StartedAt.IsZero() {
@@ -779,11 +867,7 @@ func (a *Agent) executeSingleTool(ctx context.Context, tu *types.ToolUseBlock) t
 		a.toolRecords[tu.ID].Progress = 1
 		a.mu.Unlock()
 	} else {
-		errorMsg := ""
-		if execResult.Error != nil {
-			errorMsg = execResult.Error.Error()
-		}
-		a.updateToolRecord(tu.ID, types.ToolCallStateFailed, errorMsg)
+		a.updateToolRecord(tu.ID, types.ToolCallStateFailed, toolExecError(execResult))
 	}
 
 	// 发送工具结束事件
@@ -799,6 +883,7 @@ func (a *Agent) executeSingleTool(ctx context.Context, tu *types.ToolUseBlock) t
 			Arguments:  finalRecord.Input,
 			Result:     finalRecord.Result,
 			Error:      finalRecord.Error,
+			ErrorCode:  finalRecord.ErrorCode,
 			Progress:   finalRecord.Progress,
 			StartedAt:  finalRecord.StartTime,
 			UpdatedAt:  finalRecord.UpdatedAt,
@@ -811,23 +896,18 @@ func (a *Agent) executeSingleTool(ctx context.Context, tu *types.ToolUseBlock) t
 	a.setBreakpoint(types.BreakpointPostTool)
 
 	// 构建工具结果（压缩统一由 ToolResultOptimizerMiddleware 处理）
+	var result *types.ToolResultBlock
 	if execResult.Success {
-		return &types.ToolResultBlock{
+		result = &types.ToolResultBlock{
 			ToolUseID: tu.ID,
 			Content:   fmt.Sprintf("%v", execResult.Output),
 			IsError:   false,
 		}
 	} else {
-		errorMsg := ""
-		if execResult.Error != nil {
-			errorMsg = execResult.Error.Error()
-		}
-		return &types.ToolResultBlock{
-			ToolUseID: tu.ID,
-			Content:   fmt.Sprintf(`{"ok":false,"error":"%s"}`, errorMsg),
-			IsError:   true,
-		}
+		result = toolFailure(tu.ID, toolExecError(execResult), nil)
 	}
+	a.recordToolUsage(ctx, tu.Name, execResult, endTime.Sub(startTime), result)
+	return result
 }
 
 // setBreakpoint 设置断点
@@ -844,8 +924,16 @@ func (a *Agent) setBreakpoint(state types.BreakpointState) {
 	})
 }
 
-// updateToolRecord 更新工具记录
-func (a *Agent) updateToolRecord(id string, state types.ToolCallState, errorMsg string) {
+// toolExecError 工具执行失败的结构化错误，未分类的错误归为 tool_failed
+func toolExecError(result *tools.ExecuteResult) *agenterr.Error {
+	if result.Error == nil {
+		return agenterr.New(agenterr.CodeToolFailed, "")
+	}
+	return agenterr.Classify(result.Error, agenterr.CodeToolFailed)
+}
+
+// updateToolRecord 更新工具记录，toolErr 非空时记录失败原因
+func (a *Agent) updateToolRecord(id string, state types.ToolCallState, toolErr *agenterr.Error) {
 	a.mu.Lock()
 	defer a.mu.Unlock()
 
@@ -858,8 +946,9 @@ func (a *Agent) updateToolRecord(id string, state types.ToolCallState, errorMsg
 	record.State = state
 	record.UpdatedAt = now
 
-	if errorMsg != "" {
-		record.Error = errorMsg
+	if toolErr != nil {
+		record.Error = toolErr.Error()
+		record.ErrorCode = toolErr.Code
 		record.IsError = true
 	}
 
@@ -878,6 +967,28 @@ func (a *Agent) handleStreamResponse(ctx context.Context, stream <-chan provider
 	reasoningStarted := false           // 追踪是否已发送思考开始事件
 	var reasoningBuffer strings.Builder // 累积思考内容
 
+	// 配置了展示前安全策略时，文本不逐字发送，在块结束时整体过滤后一次性发出
+	bufferText := a.preDisplayFilter != nil
+	flushedText := make(map[int]bool)
+	flushText := func(index int) {
+		if !bufferText || index < 0 || index >= len(assistantContent) || flushedText[index] {
+			return
+		}
+		block, ok := assistantContent[index].(*types.TextBlock)
+		if !ok {
+			return
+		}
+		flushedText[index] = true
+		block.Text = a.filterDisplayText(ctx, block.Text)
+		textBuffers[index] = block.Text
+		if block.Text != "" {
+			a.eventBus.EmitProgress(&types.ProgressTextChunkEvent{
+				Step:  a.stepCount,
+				Delta: block.Text,
+			})
+		}
+	}
+
 	// 只在用户消息后的第一次 LLM 调用时发送初始的任务规划思考事件
 	// 使用 initialThinkingSent 标志而不是 iterationCount，因为：
 	// 1. iterationCount 在 processMessages 中重置，但 handleStreamResponse 可能被多次调用
@@ -903,6 +1014,27 @@ func (a *Agent) handleStreamResponse(ctx context.Context, stream <-chan provider
 		procLog.Debug(ctx, "sent initial task planning event", map[string]any{"step": a.stepCount})
 	}
 
+	// emitReasoning 累积模型原生推理增量，并同时发送 think_chunk（兼容现有前端）和 reasoning 事件
+	emitReasoning := func(delta string) {
+		if !reasoningStarted {
+			reasoningStarted = true
+			a.eventBus.EmitProgress(&types.ProgressThinkChunkStartEvent{
+				Step: a.stepCount,
+			})
+			procLog.Debug(ctx, "reasoning started", map[string]any{"step": a.stepCount})
+		}
+		reasoningBuffer.WriteString(delta)
+		a.eventBus.EmitProgress(&types.ProgressThinkChunkEvent{
+			Step:  a.stepCount,
+			Stage: types.ThinkingStageReasoning,
+			Delta: delta,
+		})
+		a.eventBus.EmitProgress(&types.ProgressReasoningEvent{
+			Step:  a.stepCount,
+			Delta: delta,
+		})
+	}
+
 	for chunk := range stream {
 		// 调试：打印收到的每个 chunk
 		procLog.Debug(ctx, "received stream chunk", map[string]any{
@@ -916,24 +1048,16 @@ func (a *Agent) handleStreamResponse(ctx context.Context, stream <-chan provider
 		case "reasoning_delta":
 			if delta, ok := chunk.Delta.(map[string]any); ok {
 				if content, ok := delta["content"].(string); ok && content != "" {
-					// 首次收到思考内容时，发送开始事件
-					if !reasoningStarted {
-						reasoningStarted = true
-						a.eventBus.EmitProgress(&types.ProgressThinkChunkStartEvent{
-							Step: a.stepCount,
-						})
-						procLog.Debug(ctx, "reasoning started", map[string]any{"step": a.stepCount})
-					}
-					// 累积并发送思考内容增量
-					reasoningBuffer.WriteString(content)
-					a.eventBus.EmitProgress(&types.ProgressThinkChunkEvent{
-						Step:  a.stepCount,
-						Stage: types.ThinkingStageReasoning,
-						Delta: content,
-					})
+					emitReasoning(content)
 				}
 			}
 
+		// OpenAI 兼容格式：推理内容（OpenAI reasoning、Kimi thinking 等）
+		case string(provider.ChunkTypeReasoning):
+			if chunk.Reasoning != nil && chunk.Reasoning.ThoughtDelta != "" {
+				emitReasoning(chunk.Reasoning.ThoughtDelta)
+			}
+
 		case "content_block_start":
 			currentBlockIndex = chunk.Index
 			if delta, ok := chunk.Delta.(map[string]any); ok {
@@ -949,8 +1073,16 @@ func (a *Agent) handleStreamResponse(ctx context.Context, stream <-chan provider
 						})
 						procLog.Debug(ctx, "extended thinking started", map[string]any{"step": a.stepCount, "index": currentBlockIndex})
 					}
-					// 初始化 thinking 块（不添加到 assistantContent，因为 thinking 不是最终输出）
-					textBuffers[currentBlockIndex] = ""
+					// 保留 thinking 块：启用 extended thinking 时，工具调用循环需要原样回传带签名的思考块
+					for len(assistantContent) <= currentBlockIndex {
+						assistantContent = append(assistantContent, nil)
+					}
+					thinking, _ := delta["thinking"].(string)
+					signature, _ := delta["signature"].(string)
+					assistantContent[currentBlockIndex] = &types.ThinkingBlock{
+						Thinking:  thinking,
+						Signature: signature,
+					}
 				case "text":
 					// 发送文本开始事件
 					a.eventBus.EmitProgress(&types.ProgressTextChunkStartEvent{
@@ -1041,22 +1173,26 @@ func (a *Agent) handleStreamResponse(ctx context.Context, stream <-chan provider
 					}
 
 					// 发送文本增量事件
-					a.eventBus.EmitProgress(&types.ProgressTextChunkEvent{
-						Step:  a.stepCount,
-						Delta: text,
-					})
+					if !bufferText {
+						a.eventBus.EmitProgress(&types.ProgressTextChunkEvent{
+							Step:  a.stepCount,
+							Delta: text,
+						})
+					}
 				case "thinking_delta":
 					// Extended Thinking 增量
 					thinking, _ := delta["thinking"].(string)
 					if thinking != "" {
-						// 累积思考内容
-						reasoningBuffer.WriteString(thinking)
-						// 发送思考增量事件
-						a.eventBus.EmitProgress(&types.ProgressThinkChunkEvent{
-							Step:  a.stepCount,
-							Stage: types.ThinkingStageReasoning,
-							Delta: thinking,
-						})
+						if block := thinkingBlockAt(assistantContent, currentBlockIndex); block != nil {
+							block.Thinking += thinking
+						}
+						emitReasoning(thinking)
+					}
+				case "signature_delta":
+					// Extended Thinking 签名
+					signature, _ := delta["signature"].(string)
+					if block := thinkingBlockAt(assistantContent, currentBlockIndex); block != nil {
+						block.Signature += signature
 					}
 				case "input_json_delta":
 					partialJSON, _ := delta["partial_json"].(string)
@@ -1083,6 +1219,7 @@ func (a *Agent) handleStreamResponse(ctx context.Context, stream <-chan provider
 
 		case "content_block_stop":
 			if currentBlockIndex >= 0 && currentBlockIndex < len(assistantContent) {
+				flushText(currentBlockIndex)
 				if block, ok := assistantContent[currentBlockIndex].(*types.TextBlock); ok {
 					a.eventBus.EmitProgress(&types.ProgressTextChunkEndEvent{
 						Step: a.stepCount,
@@ -1102,6 +1239,9 @@ func (a *Agent) handleStreamResponse(ctx context.Context, stream <-chan provider
 
 		case "message_delta":
 			if chunk.Usage != nil {
+				a.observeUsage(chunk.Usage)
+				a.recordUsage(ctx, chunk.Usage)
+				a.observeSessionCost(ctx, chunk.Usage)
 				a.eventBus.EmitMonitor(&types.MonitorTokenUsageEvent{
 					InputTokens:  chunk.Usage.InputTokens,
 					OutputTokens: chunk.Usage.OutputTokens,
@@ -1139,10 +1279,12 @@ func (a *Agent) handleStreamResponse(ctx context.Context, stream <-chan provider
 				}
 
 				// 发送文本增量事件
-				a.eventBus.EmitProgress(&types.ProgressTextChunkEvent{
-					Step:  a.stepCount,
-					Delta: text,
-				})
+				if !bufferText {
+					a.eventBus.EmitProgress(&types.ProgressTextChunkEvent{
+						Step:  a.stepCount,
+						Delta: text,
+					})
+				}
 			}
 
 		// OpenAI 兼容格式：处理 tool_call 类型
@@ -1181,6 +1323,7 @@ func (a *Agent) handleStreamResponse(ctx context.Context, stream <-chan provider
 		case "done":
 			// 发送文本结束事件（如果有文本）
 			if currentBlockIndex >= 0 && currentBlockIndex < len(assistantContent) {
+				flushText(currentBlockIndex)
 				if _, ok := assistantContent[currentBlockIndex].(*types.TextBlock); ok {
 					a.eventBus.EmitProgress(&types.ProgressTextChunkEndEvent{
 						Step: a.stepCount,
@@ -1191,6 +1334,9 @@ func (a *Agent) handleStreamResponse(ctx context.Context, stream <-chan provider
 		// OpenAI 兼容格式：处理 usage 类型
 		case "usage":
 			if chunk.Usage != nil {
+				a.observeUsage(chunk.Usage)
+				a.recordUsage(ctx, chunk.Usage)
+				a.observeSessionCost(ctx, chunk.Usage)
 				a.eventBus.EmitMonitor(&types.MonitorTokenUsageEvent{
 					InputTokens:  chunk.Usage.InputTokens,
 					OutputTokens: chunk.Usage.OutputTokens,
@@ -1200,6 +1346,11 @@ func (a *Agent) handleStreamResponse(ctx context.Context, stream <-chan provider
 		}
 	}
 
+	// 流未正常结束文本块时（如连接中断），仍需过滤后再发出
+	for i := range assistantContent {
+		flushText(i)
+	}
+
 	// 流式响应结束后，解析所有累积的工具输入
 	if len(inputJSONBuffers) > 0 {
 		procLog.Debug(ctx, "processing inputJSONBuffers", map[string]any{"buffer_count": len(inputJSONBuffers), "content_blocks": len(assistantContent)})
@@ -1245,6 +1396,11 @@ func (a *Agent) handleStreamResponse(ctx context.Context, stream <-chan provider
 			Step: a.stepCount,
 		})
 		procLog.Debug(ctx, "reasoning ended", map[string]any{"step": a.stepCount, "total_length": len(reasoningBuffer.String())})
+
+		// 非 Anthropic 模型的推理内容没有独立的块，统一记录为消息首个思考块（不带签名，不会回传给模型）
+		if !hasThinkingBlock(assistantContent) {
+			assistantContent = append([]types.ContentBlock{&types.ThinkingBlock{Thinking: reasoningBuffer.String()}}, assistantContent...)
+		}
 	}
 
 	return types.Message{
@@ -1255,32 +1411,13 @@ func (a *Agent) handleStreamResponse(ctx context.Context, stream <-chan provider
 
 // runNonStreamingStep 非流式执行模型步骤（快速模式）
 func (a *Agent) runNonStreamingStep(ctx context.Context) error {
-	// 准备工具Schema（包含使用示例）
-	toolSchemas := make([]provider.ToolSchema, 0, len(a.toolMap))
-	for _, tool := range a.toolMap {
-		schema := provider.ToolSchema{
-			Name:        tool.Name(),
-			Description: tool.Description(),
-			InputSchema: tool.InputSchema(),
-		}
-		// 检查工具是否实现了 ExampleableTool 接口
-		if exampleable, ok := tool.(tools.ExampleableTool); ok {
-			examples := exampleable.Examples()
-			if len(examples) > 0 {
-				providerExamples := make([]provider.ToolExample, len(examples))
-				for i, ex := range examples {
-					providerExamples[i] = provider.ToolExample{
-						Description: ex.Description,
-						Input:       ex.Input,
-						Output:      ex.Output,
-					}
-				}
-				schema.InputExamples = providerExamples
-			}
-		}
-		toolSchemas = append(toolSchemas, schema)
+	if err := a.beginTurn(); err != nil {
+		return err
 	}
 
+	// 准备工具Schema（包含使用示例）
+	toolSchemas := a.buildToolSchemas()
+
 	// 准备消息
 	a.mu.RLock()
 	messages := make([]types.Message, len(a.messages))
@@ -1288,21 +1425,36 @@ func (a *Agent) runNonStreamingStep(ctx context.Context) error {
 	currentSystemPrompt := a.template.SystemPrompt
 	a.mu.RUnlock()
 
+	messages, err := a.fitContextWindow(ctx, currentSystemPrompt, toolSchemas, messages)
+	if err != nil {
+		return fmt.Errorf("complete call failed: %w", err)
+	}
+
 	// 创建Provider选项
 	streamOpts := &provider.StreamOptions{
 		Tools:       toolSchemas,
 		System:      currentSystemPrompt,
 		Temperature: 0.7,
-		MaxTokens:   32000, // Claude 4 Sonnet/Opus 最大支持 64000 output tokens
+		MaxTokens:   a.outputTokenCap(32000), // Claude 4 Sonnet/Opus 最大支持 64000 output tokens
 	}
 
 	procLog.Debug(ctx, "calling Complete API", map[string]any{"messages": len(messages), "tools": len(toolSchemas)})
 
-	// 调用Complete API（非流式）
-	response, err := a.provider.Complete(ctx, messages, streamOpts)
+	// 调用Complete API（非流式），收到引导消息时打断
+	modelCtx, modelDone := a.steerModelContext(ctx)
+	response, err := a.modelProviderForStep(ctx).Complete(modelCtx, messages, streamOpts)
+	if modelDone() && a.applySteering(ctx, steerPhaseModel, nil) {
+		return a.runNonStreamingStep(ctx)
+	}
 	if err != nil {
-		return fmt.Errorf("complete call failed: %w", err)
+		return agenterr.Wrap(agenterr.CodeProviderError, fmt.Errorf("complete call failed: %w", err))
 	}
+	a.observeUsage(response.Usage)
+	a.recordUsage(ctx, response.Usage)
+	a.observeSessionCost(ctx, response.Usage)
+
+	// 内容安全：展示前检查
+	response.Message.ContentBlocks = a.filterDisplayBlocks(ctx, response.Message.ContentBlocks)
 
 	// 添加响应消息
 	a.mu.Lock()
@@ -1340,7 +1492,7 @@ func (a *Agent) runNonStreamingStep(ctx context.Context) error {
 			procLog.Error(ctx, "iteration limit exceeded in non-streaming mode", map[string]any{
 				"agent_id": a.id, "iteration": currentIter, "max": maxIter,
 			})
-			return fmt.Errorf("iteration limit exceeded: %d > %d", currentIter, maxIter)
+			return agenterr.New(agenterr.CodeIterationLimit, fmt.Sprintf("iteration limit exceeded: %d > %d", currentIter, maxIter))
 		}
 
 		// 递归调用继续处理
@@ -1348,6 +1500,11 @@ func (a *Agent) runNonStreamingStep(ctx context.Context) error {
 	}
 
 	// 没有工具调用，完成
+	a.observeAssistantAnswer(ctx, response.Message)
+	if a.applySteering(ctx, steerPhaseTurnEnd, nil) {
+		return a.runNonStreamingStep(ctx)
+	}
+
 	a.mu.Lock()
 	a.state = types.AgentStateReady
 	a.mu.Unlock()
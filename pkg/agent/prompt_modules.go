@@ -335,6 +335,57 @@ func (m *CustomInstructionsModule) Build(ctx *PromptContext) (string, error) {
 	return "## Custom Instructions\n\n" + m.Instructions, nil
 }
 
+// SessionSummaryModule 恢复或分叉会话时注入上一段会话的摘要
+type SessionSummaryModule struct {
+	Summary *types.ConversationSummary
+}
+
+func (m *SessionSummaryModule) Name() string  { return "session_summary" }
+func (m *SessionSummaryModule) Priority() int { return 57 }
+func (m *SessionSummaryModule) Condition(ctx *PromptContext) bool {
+	return m.Summary != nil
+}
+func (m *SessionSummaryModule) Build(ctx *PromptContext) (string, error) {
+	return "## Previous Session\n\n" + m.Summary.SeedPrompt(), nil
+}
+
+// pinnedSectionHeading 与 pinnedSectionEnd 标记固定内容段落，用于固定内容变化时原地替换
+const (
+	pinnedSectionHeading = "## Pinned Context"
+	pinnedSectionEnd     = "</pinned_context>"
+)
+
+// PinnedContextModule 用户固定的文件、决定和需求，始终保留在上下文中
+type PinnedContextModule struct {
+	Pins []PinnedItem
+}
+
+func (m *PinnedContextModule) Name() string  { return "pinned_context" }
+func (m *PinnedContextModule) Priority() int { return 58 }
+func (m *PinnedContextModule) Condition(ctx *PromptContext) bool {
+	return len(m.Pins) > 0
+}
+func (m *PinnedContextModule) Build(ctx *PromptContext) (string, error) {
+	var sb strings.Builder
+	sb.WriteString(pinnedSectionHeading + "\n\n")
+	sb.WriteString("The user pinned the following items. They stay in context for the whole session, even after earlier messages are compacted or summarized. Treat them as standing requirements and reference material.\n\n")
+	sb.WriteString("<pinned_context>\n")
+	for i := range m.Pins {
+		pin := &m.Pins[i]
+		fmt.Fprintf(&sb, "\n### [%s] %s\n\n", pin.ID, pin.label())
+		if pin.Kind == PinKindFile {
+			sb.WriteString("```\n" + pin.Content + "\n```\n")
+			if pin.Truncated {
+				sb.WriteString("(truncated; read the file for the rest)\n")
+			}
+			continue
+		}
+		sb.WriteString(pin.Content + "\n")
+	}
+	sb.WriteString(pinnedSectionEnd)
+	return sb.String(), nil
+}
+
 // CapabilitiesModule Agent 能力说明模块
 type CapabilitiesModule struct{}
 
@@ -0,0 +1,76 @@
+package agent
+
+import "github.com/astercloud/aster/pkg/types"
+
+// thinkingBlockAt 返回指定位置的思考块
+func thinkingBlockAt(blocks []types.ContentBlock, index int) *types.ThinkingBlock {
+	if index < 0 || index >= len(blocks) {
+		return nil
+	}
+	block, _ := blocks[index].(*types.ThinkingBlock)
+	return block
+}
+
+// hasThinkingBlock 判断内容块中是否已有思考块
+func hasThinkingBlock(blocks []types.ContentBlock) bool {
+	for _, block := range blocks {
+		if _, ok := block.(*types.ThinkingBlock); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// reasoningStoreMode 返回当前主模型的推理内容存储方式
+func (a *Agent) reasoningStoreMode() types.ReasoningStoreMode {
+	cfg := a.CurrentModel()
+	if cfg == nil || cfg.Reasoning == nil {
+		return types.ReasoningStoreNone
+	}
+	return cfg.Reasoning.Store
+}
+
+// messagesForStore 按推理存储策略处理待持久化的消息
+// 内存中的消息保持完整（工具循环需要回传签名），只影响写入 Store 的副本
+func (a *Agent) messagesForStore(messages []types.Message) []types.Message {
+	return applyReasoningStore(messages, a.reasoningStoreMode())
+}
+
+// applyReasoningStore 移除或清除消息中的思考块；不含思考块的消息原样复用
+func applyReasoningStore(messages []types.Message, mode types.ReasoningStoreMode) []types.Message {
+	if mode == types.ReasoningStoreFull {
+		return messages
+	}
+
+	var result []types.Message
+	for i, msg := range messages {
+		if !hasThinkingBlock(msg.ContentBlocks) {
+			if result != nil {
+				result = append(result, msg)
+			}
+			continue
+		}
+		if result == nil {
+			result = make([]types.Message, i, len(messages))
+			copy(result, messages[:i])
+		}
+
+		blocks := make([]types.ContentBlock, 0, len(msg.ContentBlocks))
+		for _, block := range msg.ContentBlocks {
+			if _, ok := block.(*types.ThinkingBlock); !ok {
+				blocks = append(blocks, block)
+				continue
+			}
+			if mode == types.ReasoningStoreRedacted {
+				blocks = append(blocks, &types.ThinkingBlock{Redacted: true})
+			}
+		}
+		msg.ContentBlocks = blocks
+		result = append(result, msg)
+	}
+
+	if result == nil {
+		return messages
+	}
+	return result
+}
@@ -0,0 +1,106 @@
+package agent
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/astercloud/aster/pkg/provider"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+func TestHandleStreamResponseKeepsThinkingBlock(t *testing.T) {
+	ag := createEscalatingAgent(t, nil)
+	ch := ag.Subscribe([]types.AgentChannel{types.ChannelProgress}, nil)
+
+	stream := make(chan provider.StreamChunk, 8)
+	stream <- provider.StreamChunk{Type: "content_block_start", Index: 0, Delta: map[string]any{"type": "thinking", "thinking": ""}}
+	stream <- provider.StreamChunk{Type: "content_block_delta", Index: 0, Delta: map[string]any{"type": "thinking_delta", "thinking": "step one"}}
+	stream <- provider.StreamChunk{Type: "content_block_delta", Index: 0, Delta: map[string]any{"type": "signature_delta", "signature": "sig"}}
+	stream <- provider.StreamChunk{Type: "content_block_stop", Index: 0}
+	stream <- provider.StreamChunk{Type: "content_block_start", Index: 1, Delta: map[string]any{"type": "text"}}
+	stream <- provider.StreamChunk{Type: "content_block_delta", Index: 1, Delta: map[string]any{"type": "text_delta", "text": "done"}}
+	stream <- provider.StreamChunk{Type: "content_block_stop", Index: 1}
+	close(stream)
+
+	msg, err := ag.handleStreamResponse(context.Background(), stream)
+	if err != nil {
+		t.Fatalf("handleStreamResponse failed: %v", err)
+	}
+	if len(msg.ContentBlocks) != 2 {
+		t.Fatalf("expected thinking and text blocks, got %d", len(msg.ContentBlocks))
+	}
+	tb, ok := msg.ContentBlocks[0].(*types.ThinkingBlock)
+	if !ok || tb.Thinking != "step one" || tb.Signature != "sig" {
+		t.Errorf("unexpected thinking block %+v", msg.ContentBlocks[0])
+	}
+
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case env := <-ch:
+			if ev, ok := env.Event.(*types.ProgressReasoningEvent); ok {
+				if ev.Delta != "step one" {
+					t.Errorf("unexpected reasoning delta %q", ev.Delta)
+				}
+				return
+			}
+		case <-timeout:
+			t.Fatal("expected reasoning event")
+		}
+	}
+}
+
+func TestHandleStreamResponseOpenAIReasoning(t *testing.T) {
+	ag := createEscalatingAgent(t, nil)
+
+	stream := make(chan provider.StreamChunk, 4)
+	stream <- provider.StreamChunk{Type: string(provider.ChunkTypeReasoning), Reasoning: &provider.ReasoningTrace{ThoughtDelta: "hmm"}}
+	stream <- provider.StreamChunk{Type: "text", TextDelta: "answer"}
+	close(stream)
+
+	msg, err := ag.handleStreamResponse(context.Background(), stream)
+	if err != nil {
+		t.Fatalf("handleStreamResponse failed: %v", err)
+	}
+	if len(msg.ContentBlocks) != 2 {
+		t.Fatalf("expected reasoning and text blocks, got %d", len(msg.ContentBlocks))
+	}
+	if tb, ok := msg.ContentBlocks[0].(*types.ThinkingBlock); !ok || tb.Thinking != "hmm" || tb.Signature != "" {
+		t.Errorf("unexpected reasoning block %+v", msg.ContentBlocks[0])
+	}
+}
+
+func TestApplyReasoningStore(t *testing.T) {
+	messages := []types.Message{
+		{Role: types.MessageRoleUser, Content: "hi"},
+		{Role: types.MessageRoleAssistant, ContentBlocks: []types.ContentBlock{
+			&types.ThinkingBlock{Thinking: "secret", Signature: "sig"},
+			&types.TextBlock{Text: "hello"},
+		}},
+	}
+
+	full := applyReasoningStore(messages, types.ReasoningStoreFull)
+	if tb := full[1].ContentBlocks[0].(*types.ThinkingBlock); tb.Thinking != "secret" {
+		t.Errorf("full mode should keep reasoning, got %+v", tb)
+	}
+
+	none := applyReasoningStore(messages, types.ReasoningStoreNone)
+	if len(none) != 2 || len(none[1].ContentBlocks) != 1 {
+		t.Fatalf("default mode should drop thinking blocks, got %+v", none)
+	}
+	if hasThinkingBlock(none[1].ContentBlocks) {
+		t.Error("default mode should drop thinking blocks")
+	}
+
+	redacted := applyReasoningStore(messages, types.ReasoningStoreRedacted)
+	tb, ok := redacted[1].ContentBlocks[0].(*types.ThinkingBlock)
+	if !ok || !tb.Redacted || tb.Thinking != "" || tb.Signature != "" {
+		t.Errorf("redacted mode should keep an empty placeholder, got %+v", redacted[1].ContentBlocks[0])
+	}
+
+	// 原始消息不受影响
+	if tb := messages[1].ContentBlocks[0].(*types.ThinkingBlock); tb.Thinking != "secret" {
+		t.Error("in-memory messages must not be modified")
+	}
+}
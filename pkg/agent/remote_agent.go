@@ -103,7 +103,10 @@ func (r *RemoteAgent) PushEvent(envelope types.AgentEventEnvelope) error {
 	} else if eventMap, ok := envelope.Event.(map[string]any); ok {
 		// 处理从 JSON 反序列化的事件（map[string]any 类型）
 		// 从事件 map 中推断 channel
-		channel := r.inferChannelFromEventMap(eventMap)
+		channel := string(envelope.Channel)
+		if channel == "" {
+			channel = r.inferChannelFromEventMap(eventMap)
+		}
 
 		switch types.AgentChannel(channel) {
 		case types.ChannelProgress:
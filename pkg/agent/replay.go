@@ -0,0 +1,191 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/astercloud/aster/pkg/tools"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+// ReplayOptions 会话回放选项
+type ReplayOptions struct {
+	// DryRun 以 dry-run 方式重新执行历史工具调用：
+	// 只读且不访问外部网络的工具直接执行，支持 dry_run 参数的工具带 dry_run=true 执行，其余跳过
+	DryRun bool
+}
+
+// ReplayStep 回放中的一次模型调用
+type ReplayStep struct {
+	// Turn 所属的用户轮次（从 1 开始）
+	Turn int
+	// Step 全局步骤序号（从 1 开始）
+	Step int
+	// Request 该步骤发送给模型的请求
+	Request ReplayRequest
+	// Response 模型返回的助手消息
+	Response  types.Message
+	ToolCalls []ReplayToolCall
+}
+
+// ReplayRequest 按当前 PromptBuilder 和上下文窗口规则重建的模型请求
+// 中间件（如摘要、工具结果压缩）对请求的修改不在其中
+type ReplayRequest struct {
+	System   string
+	Tools    []string
+	Messages []types.Message
+	// Error 重建请求失败的原因（例如超出上下文窗口）
+	Error string
+}
+
+// ReplayToolCall 历史工具调用及其回放结果
+type ReplayToolCall struct {
+	Call *types.ToolUseBlock
+	// Recorded 会话中记录的工具结果，缺失时为空
+	Recorded *types.ToolResultBlock
+	// DryRun 重新执行的结果，未开启 DryRun 时为空
+	DryRun *ReplayToolResult
+}
+
+// ReplayToolResult 工具重新执行的结果
+type ReplayToolResult struct {
+	Output  string
+	IsError bool
+	// Skipped 非空时表示未执行及原因
+	Skipped string
+	// Matches 输出是否与记录的结果一致
+	Matches    bool
+	DurationMs int64
+}
+
+// Replay 按步骤回放历史消息，重建每次模型调用的 System Prompt、工具列表和消息
+// 回放使用当前 Agent 的模板、工具和模型配置，不会调用模型，也不会修改 Agent 的消息历史
+func (a *Agent) Replay(ctx context.Context, messages []types.Message, opts ReplayOptions) ([]ReplayStep, error) {
+	a.mu.RLock()
+	hasManual := strings.Contains(a.template.SystemPrompt, "### Tools Manual")
+	toolNames := slices.Sorted(maps.Keys(a.toolMap))
+	a.mu.RUnlock()
+	if !hasManual && len(toolNames) > 0 {
+		a.injectToolManual()
+	}
+	system := a.GetSystemPrompt()
+	toolSchemas := a.buildToolSchemas()
+
+	var steps []ReplayStep
+	turn := 0
+	for i, msg := range messages {
+		if err := ctx.Err(); err != nil {
+			return steps, err
+		}
+		if msg.Role == types.MessageRoleUser && !isToolResultMessage(msg) {
+			turn++
+			continue
+		}
+		if msg.Role != types.MessageRoleAssistant {
+			continue
+		}
+
+		step := ReplayStep{
+			Turn:     max(turn, 1),
+			Step:     len(steps) + 1,
+			Request:  ReplayRequest{System: system, Tools: toolNames},
+			Response: msg,
+		}
+		history := slices.Clone(messages[:i])
+		if fitted, err := a.fitContextWindow(ctx, system, toolSchemas, history); err != nil {
+			step.Request.Messages = history
+			step.Request.Error = err.Error()
+		} else {
+			step.Request.Messages = fitted
+		}
+
+		var results map[string]*types.ToolResultBlock
+		if i+1 < len(messages) {
+			results = toolResultsByID(messages[i+1])
+		}
+		for _, block := range msg.ContentBlocks {
+			tu, ok := block.(*types.ToolUseBlock)
+			if !ok {
+				continue
+			}
+			call := ReplayToolCall{Call: tu, Recorded: results[tu.ID]}
+			if opts.DryRun {
+				call.DryRun = a.dryRunTool(ctx, tu, call.Recorded)
+			}
+			step.ToolCalls = append(step.ToolCalls, call)
+		}
+		steps = append(steps, step)
+	}
+	return steps, nil
+}
+
+// dryRunTool 以不产生副作用的方式重新执行一次工具调用
+func (a *Agent) dryRunTool(ctx context.Context, tu *types.ToolUseBlock, recorded *types.ToolResultBlock) *ReplayToolResult {
+	tool, ok := a.toolMap[tu.Name]
+	if !ok {
+		return &ReplayToolResult{Skipped: "tool not loaded"}
+	}
+
+	input := maps.Clone(tu.Input)
+	ann := tools.GetAnnotations(tool)
+	switch {
+	case ann.OpenWorld:
+		return &ReplayToolResult{Skipped: "accesses external systems"}
+	case ann.ReadOnly:
+	case supportsDryRun(tool):
+		input["dry_run"] = true
+	default:
+		return &ReplayToolResult{Skipped: "has side effects"}
+	}
+
+	res := a.executor.Execute(ctx, &tools.ExecuteRequest{
+		Tool:    tool,
+		Input:   input,
+		Context: a.buildToolContext(ctx),
+		Timeout: 60 * time.Second,
+	})
+	out := &ReplayToolResult{DurationMs: res.DurationMs}
+	if res.Success {
+		out.Output = fmt.Sprintf("%v", res.Output)
+	} else {
+		out.IsError = true
+		out.Output = toolFailure(tu.ID, toolExecError(res), nil).Content
+	}
+	out.Matches = recorded != nil && recorded.Content == out.Output
+	return out
+}
+
+// supportsDryRun 工具的输入 Schema 是否声明了 dry_run 参数
+func supportsDryRun(tool tools.Tool) bool {
+	props, _ := tool.InputSchema()["properties"].(map[string]any)
+	_, ok := props["dry_run"]
+	return ok
+}
+
+// isToolResultMessage 消息是否只包含工具结果
+func isToolResultMessage(msg types.Message) bool {
+	if len(msg.ContentBlocks) == 0 {
+		return false
+	}
+	for _, block := range msg.ContentBlocks {
+		if _, ok := block.(*types.ToolResultBlock); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// toolResultsByID 按工具调用 ID 索引消息中的工具结果
+func toolResultsByID(msg types.Message) map[string]*types.ToolResultBlock {
+	results := make(map[string]*types.ToolResultBlock)
+	for _, block := range msg.ContentBlocks {
+		if tr, ok := block.(*types.ToolResultBlock); ok {
+			results[tr.ToolUseID] = tr
+		}
+	}
+	return results
+}
@@ -0,0 +1,62 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/astercloud/aster/pkg/types"
+)
+
+func TestReplay(t *testing.T) {
+	ag := createGuardedAgent(t, 0, nil)
+
+	messages := []types.Message{
+		{Role: types.MessageRoleUser, ContentBlocks: []types.ContentBlock{&types.TextBlock{Text: "update notes.txt"}}},
+		{Role: types.MessageRoleAssistant, ContentBlocks: []types.ContentBlock{
+			&types.ToolUseBlock{ID: "r1", Name: "Read", Input: map[string]any{"path": "notes.txt"}},
+			&types.ToolUseBlock{ID: "w1", Name: "Write", Input: map[string]any{"path": "notes.txt", "content": "x"}},
+		}},
+		{Role: types.MessageRoleUser, ContentBlocks: []types.ContentBlock{
+			&types.ToolResultBlock{ToolUseID: "r1", Content: "old"},
+			&types.ToolResultBlock{ToolUseID: "w1", Content: "ok"},
+		}},
+		{Role: types.MessageRoleAssistant, ContentBlocks: []types.ContentBlock{&types.TextBlock{Text: "done"}}},
+		{Role: types.MessageRoleUser, ContentBlocks: []types.ContentBlock{&types.TextBlock{Text: "thanks"}}},
+		{Role: types.MessageRoleAssistant, ContentBlocks: []types.ContentBlock{&types.TextBlock{Text: "you're welcome"}}},
+	}
+
+	steps, err := ag.Replay(context.Background(), messages, ReplayOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("Replay() error = %v", err)
+	}
+	if len(steps) != 3 {
+		t.Fatalf("got %d steps, want 3", len(steps))
+	}
+
+	first := steps[0]
+	if first.Turn != 1 || len(first.Request.Messages) != 1 || !strings.Contains(first.Request.System, "You are a test assistant.") {
+		t.Errorf("first step = turn %d, %d messages", first.Turn, len(first.Request.Messages))
+	}
+	if len(first.ToolCalls) != 2 {
+		t.Fatalf("first step tool calls = %d, want 2", len(first.ToolCalls))
+	}
+	read, write := first.ToolCalls[0], first.ToolCalls[1]
+	if read.Recorded == nil || read.Recorded.Content != "old" || read.DryRun == nil || read.DryRun.Skipped != "" {
+		t.Errorf("Read call = %+v, dry run %+v", read, read.DryRun)
+	}
+	if write.DryRun == nil || write.DryRun.Skipped == "" {
+		t.Errorf("Write should be skipped in dry run, got %+v", write.DryRun)
+	}
+
+	if steps[1].Turn != 1 || len(steps[1].Request.Messages) != 3 {
+		t.Errorf("second step = turn %d, %d messages", steps[1].Turn, len(steps[1].Request.Messages))
+	}
+	if steps[2].Turn != 2 || steps[2].Step != 3 {
+		t.Errorf("third step = turn %d, step %d", steps[2].Turn, steps[2].Step)
+	}
+	// 回放不修改 Agent 的消息历史
+	if len(ag.messages) != 0 {
+		t.Errorf("replay should not change agent messages")
+	}
+}
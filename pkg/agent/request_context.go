@@ -0,0 +1,23 @@
+package agent
+
+import (
+	"context"
+
+	"github.com/astercloud/aster/pkg/requestctx"
+)
+
+// beginRequest 开始处理一次请求：补齐 ctx 中的请求元数据并写入事件总线，
+// 之后发出的事件都带上该元数据。返回的函数在处理结束时清除
+// 请求未携带租户时使用 Agent 配置的多租户租户
+func (a *Agent) beginRequest(ctx context.Context) (context.Context, func()) {
+	m, _ := requestctx.From(ctx)
+	if m.TenantID == "" && a.config.Multitenancy != nil && a.config.Multitenancy.Enabled {
+		m.TenantID = a.config.Multitenancy.TenantID
+	}
+	if m.IsZero() {
+		return ctx, func() {}
+	}
+	ctx = requestctx.With(ctx, m)
+	a.eventBus.SetRequest(m)
+	return ctx, func() { a.eventBus.SetRequest(requestctx.Metadata{}) }
+}
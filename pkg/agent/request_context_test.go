@@ -0,0 +1,40 @@
+package agent
+
+import (
+	"context"
+	"testing"
+
+	"github.com/astercloud/aster/pkg/requestctx"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+func TestBeginRequestStampsEvents(t *testing.T) {
+	deps := setupTestDeps(t)
+	ag, err := Create(context.Background(), &types.AgentConfig{
+		TemplateID:   "test-template",
+		ModelConfig:  &types.ModelConfig{Provider: "anthropic", Model: "claude-sonnet-4-5", APIKey: "test-key"},
+		Sandbox:      &types.SandboxConfig{Kind: types.SandboxKindMock, WorkDir: "/tmp/test"},
+		Multitenancy: &types.MultitenancyConfig{Enabled: true, TenantID: "acme"},
+	}, deps)
+	if err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+	defer func() { _ = ag.Close() }()
+
+	ctx := requestctx.With(context.Background(), requestctx.Metadata{UserID: "u1", CorrelationID: "corr-1"})
+	ctx, end := ag.beginRequest(ctx)
+	if m, _ := requestctx.From(ctx); m.TenantID != "acme" || m.UserID != "u1" {
+		t.Errorf("expected tenant from agent config, got %+v", m)
+	}
+
+	during := ag.eventBus.EmitMonitor(&types.MonitorStateChangedEvent{State: types.AgentStateWorking})
+	end()
+	after := ag.eventBus.EmitMonitor(&types.MonitorStateChangedEvent{State: types.AgentStateReady})
+
+	if during.Request == nil || during.Request.CorrelationID != "corr-1" || during.Request.TenantID != "acme" {
+		t.Errorf("expected event to carry request metadata, got %+v", during.Request)
+	}
+	if after.Request != nil {
+		t.Errorf("expected no request metadata after the request ended, got %+v", after.Request)
+	}
+}
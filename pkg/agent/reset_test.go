@@ -0,0 +1,94 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/astercloud/aster/pkg/types"
+)
+
+// cancelTrackingJob 记录被取消的任务
+type cancelTrackingJob struct {
+	*fakeJobTool
+	cancelled []string
+}
+
+func (c *cancelTrackingJob) Cancel(_ context.Context, id string) error {
+	c.cancelled = append(c.cancelled, id)
+	return nil
+}
+
+func TestResetConversationClearsSessionState(t *testing.T) {
+	ag := newUndoTestAgent(t, t.TempDir())
+	ctx := context.Background()
+
+	if _, err := ag.Chat(ctx, "a.txt=one"); err != nil {
+		t.Fatal(err)
+	}
+	if len(ag.turnCheckpoints) == 0 {
+		t.Fatal("expected a turn checkpoint")
+	}
+	if _, err := ag.Pin(ctx, PinnedItem{Kind: PinKindNote, Content: "previous user's secret"}); err != nil {
+		t.Fatal(err)
+	}
+
+	job := &cancelTrackingJob{fakeJobTool: newFakeJobTool()}
+	ag.toolMap["Job"] = job
+	if _, err := ag.startBackgroundTask(ctx, "call-job", "Job", nil, job); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ag.uiSurfaces.ApplyEvent(&types.ProgressUIDataUpdateEvent{SurfaceID: "form", Path: "/", Contents: map[string]any{"x": 1}}); err != nil {
+		t.Fatal(err)
+	}
+	ag.fileVersions.Record("/tmp/a.txt", "one")
+	ag.loopGuard = newLoopGuard(&types.LoopGuardConfig{})
+	ag.loopGuard.observe([]*types.ToolUseBlock{{ID: "1", Name: "Read", Input: map[string]any{"file_path": "a.txt"}}}, nil)
+	ag.escalation = newModelEscalator(&types.ModelEscalationConfig{Ladder: []string{"mock/undo"}})
+	ag.escalation.consecutiveFailures = 1
+	ag.escalation.lastUserText = "a.txt=one"
+	ag.mu.Lock()
+	ag.planNotices = []string{"plan approved"}
+	ag.seedDropped = true
+	ag.mu.Unlock()
+	ag.EnterPlanMode("plan-1", "plan.md", "test")
+	ag.sessionCost.inputTokens = 10
+
+	if err := ag.ResetConversation(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(ag.messages) != 0 {
+		t.Errorf("messages = %d", len(ag.messages))
+	}
+	if len(ag.turnCheckpoints) != 0 {
+		t.Error("turn checkpoints not cleared")
+	}
+	if len(ag.Pins()) != 0 || strings.Contains(ag.GetSystemPrompt(), "previous user's secret") {
+		t.Error("pins not cleared")
+	}
+	if len(ag.BackgroundTasks()) != 0 || len(job.cancelled) != 1 {
+		t.Errorf("background tasks = %v, cancelled = %v", ag.BackgroundTasks(), job.cancelled)
+	}
+	if items, _ := ag.deps.Store.List(ctx, backgroundTaskCollection); len(items) != 0 {
+		t.Errorf("stored background tasks = %d", len(items))
+	}
+	if ids := ag.uiSurfaces.SurfaceIDs(); len(ids) != 0 {
+		t.Errorf("ui surfaces = %v", ids)
+	}
+	if ag.fileVersions.Changed("/tmp/a.txt", "two") {
+		t.Error("file versions not cleared")
+	}
+	if len(ag.loopGuard.recent) != 0 || len(ag.loopGuard.seen) != 0 {
+		t.Error("loop guard history not cleared")
+	}
+	if ag.escalation.consecutiveFailures != 0 || ag.escalation.lastUserText != "" {
+		t.Error("escalation state not cleared")
+	}
+	if len(ag.planNotices) != 0 || ag.seedDropped || ag.IsInPlanMode() {
+		t.Error("plan state not cleared")
+	}
+	if cost := ag.SessionCost(); cost.TotalTokens != 0 || cost.Cost != 0 {
+		t.Errorf("session cost = %+v", cost)
+	}
+}
@@ -0,0 +1,163 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/astercloud/aster/pkg/agenterr"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+// runSummaryTextLimit 摘要中引用最后一次回答的最大字符数
+const runSummaryTextLimit = 500
+
+// runLimitError 运行达到 AgentConfig 中的限制
+type runLimitError struct {
+	reason types.TerminationReason
+	detail string
+}
+
+func (e *runLimitError) Error() string {
+	return fmt.Sprintf("run limit reached (%s): %s", e.reason, e.detail)
+}
+
+func (e *runLimitError) ErrorCode() agenterr.Code {
+	switch e.reason {
+	case types.TerminationMaxTurns:
+		return agenterr.CodeMaxTurns
+	case types.TerminationMaxOutputTokens:
+		return agenterr.CodeMaxOutputTokens
+	}
+	return agenterr.CodeMaxDuration
+}
+
+// runState 单次运行的用量，由 a.mu 保护
+type runState struct {
+	started      time.Time
+	turns        int
+	inputTokens  int64
+	outputTokens int64
+	toolCalls    int
+	// invalidInputs 各工具连续参数校验失败次数
+	invalidInputs map[string]int
+}
+
+// startRun 重置运行计数；配置了 MaxDuration 时返回带超时的 context
+func (a *Agent) startRun(ctx context.Context) (context.Context, context.CancelFunc) {
+	a.mu.Lock()
+	a.run = runState{started: time.Now()}
+	a.lastTermination = ""
+	a.lastSummary = ""
+	a.lastError = nil
+	a.mu.Unlock()
+
+	if a.config.MaxDuration > 0 {
+		return context.WithTimeout(ctx, a.config.MaxDuration)
+	}
+	return context.WithCancel(ctx)
+}
+
+// beginTurn 在每次模型调用前检查运行限制并计数
+func (a *Agent) beginTurn() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	cfg := a.config
+	if cfg.MaxDuration > 0 && time.Since(a.run.started) >= cfg.MaxDuration {
+		return &runLimitError{reason: types.TerminationMaxDuration, detail: fmt.Sprintf("exceeded max duration %s", cfg.MaxDuration)}
+	}
+	if cfg.MaxTurns > 0 && a.run.turns >= cfg.MaxTurns {
+		return &runLimitError{reason: types.TerminationMaxTurns, detail: fmt.Sprintf("reached max turns %d", cfg.MaxTurns)}
+	}
+	if cfg.MaxOutputTokens > 0 && a.run.outputTokens >= cfg.MaxOutputTokens {
+		return &runLimitError{reason: types.TerminationMaxOutputTokens, detail: fmt.Sprintf("used %d of %d output tokens", a.run.outputTokens, cfg.MaxOutputTokens)}
+	}
+	a.run.turns++
+	return nil
+}
+
+// outputTokenCap 单次调用的输出上限，不超过剩余的输出 Token 额度
+func (a *Agent) outputTokenCap(defaultMax int) int {
+	if a.config.MaxOutputTokens <= 0 {
+		return defaultMax
+	}
+	a.mu.RLock()
+	remaining := a.config.MaxOutputTokens - a.run.outputTokens
+	a.mu.RUnlock()
+	if remaining < int64(defaultMax) {
+		return int(max(remaining, 1))
+	}
+	return defaultMax
+}
+
+// finishRun 根据运行结果确定终止原因和结构化错误；达到限制时追加部分结果摘要作为助手消息，
+// 保证历史以助手消息结尾，不会被当作待处理的用户消息重新触发
+func (a *Agent) finishRun(runCtx context.Context, err error) (types.TerminationReason, string, *agenterr.Error) {
+	reason := types.TerminationCompleted
+	runErr := agenterr.From(err)
+	detail := ""
+	var limitErr *runLimitError
+	switch {
+	case errors.As(err, &limitErr):
+		reason, detail = limitErr.reason, limitErr.detail
+	case err != nil && a.config.MaxDuration > 0 && errors.Is(runCtx.Err(), context.DeadlineExceeded):
+		reason, detail = types.TerminationMaxDuration, fmt.Sprintf("exceeded max duration %s", a.config.MaxDuration)
+		runErr = &agenterr.Error{Code: agenterr.CodeMaxDuration, Message: detail, Err: err}
+	case runErr != nil && runErr.Class() == agenterr.ClassCanceled:
+		reason = types.TerminationInterrupted
+	case err != nil:
+		reason = types.TerminationError
+	}
+	if !reason.IsLimit() {
+		a.mu.Lock()
+		a.lastTermination = reason
+		a.lastError = runErr
+		a.mu.Unlock()
+		return reason, "", runErr
+	}
+
+	a.mu.Lock()
+	summary := a.runSummaryLocked(detail)
+	a.messages = append(a.messages, types.Message{
+		Role:          types.MessageRoleAssistant,
+		ContentBlocks: []types.ContentBlock{&types.TextBlock{Text: summary}},
+	})
+	messages := a.messagesForStore(a.messages)
+	a.lastTermination = reason
+	a.lastSummary = summary
+	a.lastError = runErr
+	a.mu.Unlock()
+
+	// 运行 context 可能已超时，摘要仍需持久化
+	if err := a.deps.Store.SaveMessages(context.Background(), a.id, messages); err != nil {
+		procLog.Warn(runCtx, "failed to save run summary", map[string]any{"agent_id": a.id, "error": err.Error()})
+	}
+	return reason, summary, runErr
+}
+
+// runSummaryLocked 生成部分结果摘要，调用方需持有 a.mu
+func (a *Agent) runSummaryLocked(detail string) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Run stopped before completion: %s.\n", detail)
+	fmt.Fprintf(&sb, "Progress: %d model turns, %d tool calls, %d output tokens in %s.",
+		a.run.turns, a.run.toolCalls, a.run.outputTokens, time.Since(a.run.started).Round(time.Second))
+
+	for i := len(a.messages) - 1; i >= 0; i-- {
+		if a.messages[i].Role != types.MessageRoleAssistant {
+			continue
+		}
+		text := strings.TrimSpace(extractText(a.messages[i]))
+		if text == "" {
+			continue
+		}
+		if r := []rune(text); len(r) > runSummaryTextLimit {
+			text = string(r[:runSummaryTextLimit]) + "..."
+		}
+		fmt.Fprintf(&sb, "\nLast response:\n%s", text)
+		break
+	}
+	return sb.String()
+}
@@ -0,0 +1,250 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/astercloud/aster/pkg/provider"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+// loopingProvider 每次调用都返回一次工具调用，模拟停不下来的 Agent
+type loopingProvider struct {
+	mu        sync.Mutex
+	calls     int
+	maxTokens []int
+	block     bool
+}
+
+func (p *loopingProvider) complete(ctx context.Context, _ []types.Message, opts *provider.StreamOptions) (*provider.CompleteResponse, error) {
+	p.mu.Lock()
+	p.calls++
+	p.maxTokens = append(p.maxTokens, opts.MaxTokens)
+	n := p.calls
+	p.mu.Unlock()
+
+	if p.block {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	return &provider.CompleteResponse{
+		Message: types.Message{
+			Role: types.MessageRoleAssistant,
+			ContentBlocks: []types.ContentBlock{
+				&types.TextBlock{Text: "still looking for the config file"},
+				&types.ToolUseBlock{ID: "call-" + string(rune('a'+n)), Name: "Read", Input: map[string]any{"file_path": "/tmp/test/missing.txt"}},
+			},
+		},
+		Usage: &provider.TokenUsage{InputTokens: 100, OutputTokens: 10},
+	}, nil
+}
+
+// stream 以流式块返回与 complete 相同的工具调用
+func (p *loopingProvider) stream(ctx context.Context, messages []types.Message, opts *provider.StreamOptions) (<-chan provider.StreamChunk, error) {
+	resp, err := p.complete(ctx, messages, opts)
+	if err != nil {
+		return nil, err
+	}
+	tu := resp.Message.ContentBlocks[1].(*types.ToolUseBlock)
+	ch := make(chan provider.StreamChunk, 4)
+	ch <- provider.StreamChunk{Type: "text", TextDelta: resp.Message.ContentBlocks[0].(*types.TextBlock).Text}
+	ch <- provider.StreamChunk{Type: "content_block_start", Delta: map[string]any{"name": tu.Name, "id": tu.ID}}
+	ch <- provider.StreamChunk{Type: "content_block_delta", Delta: map[string]any{"type": "arguments", "arguments": `{"file_path": "/tmp/test/missing.txt"}`}}
+	ch <- provider.StreamChunk{Type: "message_delta", Usage: resp.Usage}
+	close(ch)
+	return ch, nil
+}
+
+func runLimitedAgent(t *testing.T, p *loopingProvider, configure func(*types.AgentConfig)) (*types.ProgressDoneEvent, *types.CompleteResult) {
+	t.Helper()
+	deps := setupTestDeps(t)
+	factory := NewMockProviderFactory()
+	factory.SetProvider("mock/looper", &MockProvider{
+		name:         "looper",
+		completeFunc: p.complete,
+		capabilities: provider.ProviderCapabilities{SupportToolCalling: true, SupportSystemPrompt: true},
+	})
+	deps.ProviderFactory = factory
+
+	cfg := &types.AgentConfig{
+		TemplateID: "test-template",
+		ModelConfig: &types.ModelConfig{
+			Provider:      "mock",
+			Model:         "looper",
+			ExecutionMode: types.ExecutionModeNonStreaming,
+		},
+		Sandbox: &types.SandboxConfig{Kind: types.SandboxKindMock, WorkDir: "/tmp/test"},
+	}
+	configure(cfg)
+
+	ag, err := Create(context.Background(), cfg, deps)
+	if err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+	t.Cleanup(func() { _ = ag.Close() })
+
+	ch := ag.Subscribe([]types.AgentChannel{types.ChannelProgress}, nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := ag.Send(ctx, "find the config"); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	for {
+		select {
+		case env := <-ch:
+			done, ok := env.Event.(*types.ProgressDoneEvent)
+			if !ok {
+				continue
+			}
+			result, err := ag.waitForCompletion(ctx)
+			if err != nil {
+				t.Fatalf("waitForCompletion() error = %v", err)
+			}
+			return done, result
+		case <-ctx.Done():
+			t.Fatal("timed out waiting for done event")
+		}
+	}
+}
+
+func TestRunLimitMaxTurns(t *testing.T) {
+	p := &loopingProvider{}
+	done, result := runLimitedAgent(t, p, func(cfg *types.AgentConfig) { cfg.MaxTurns = 3 })
+
+	if p.calls != 3 {
+		t.Errorf("provider called %d times, want 3", p.calls)
+	}
+	if done.Reason != types.TerminationMaxTurns || result.TerminationReason != types.TerminationMaxTurns {
+		t.Errorf("termination = %q / %q, want max_turns", done.Reason, result.TerminationReason)
+	}
+	for _, want := range []string{"reached max turns 3", "3 model turns, 3 tool calls, 30 output tokens", "still looking for the config file"} {
+		if !strings.Contains(done.Summary, want) {
+			t.Errorf("summary %q missing %q", done.Summary, want)
+		}
+	}
+	// 摘要作为最后一条助手消息返回
+	if result.Text != done.Summary || result.Summary != done.Summary {
+		t.Errorf("result = %+v, want summary as text", result)
+	}
+}
+
+func TestRunLimitMaxOutputTokens(t *testing.T) {
+	p := &loopingProvider{}
+	done, _ := runLimitedAgent(t, p, func(cfg *types.AgentConfig) { cfg.MaxOutputTokens = 25 })
+
+	if done.Reason != types.TerminationMaxOutputTokens {
+		t.Fatalf("termination = %q, want max_output_tokens", done.Reason)
+	}
+	// 10 + 10 + 10 >= 25，第三次调用的输出上限收紧到剩余的 5
+	if p.calls != 3 || p.maxTokens[0] != 25 || p.maxTokens[2] != 5 {
+		t.Errorf("calls = %d, max tokens = %v", p.calls, p.maxTokens)
+	}
+}
+
+func TestRunLimitMaxDuration(t *testing.T) {
+	p := &loopingProvider{block: true}
+	done, result := runLimitedAgent(t, p, func(cfg *types.AgentConfig) { cfg.MaxDuration = 50 * time.Millisecond })
+
+	if done.Reason != types.TerminationMaxDuration || result.TerminationReason != types.TerminationMaxDuration {
+		t.Fatalf("termination = %q / %q, want max_duration", done.Reason, result.TerminationReason)
+	}
+	if !strings.Contains(done.Summary, "exceeded max duration 50ms") {
+		t.Errorf("summary = %q", done.Summary)
+	}
+}
+
+func TestRunWithoutLimitsCompletes(t *testing.T) {
+	deps := setupTestDeps(t)
+	factory := NewMockProviderFactory()
+	deps.ProviderFactory = factory
+
+	ag, err := Create(context.Background(), &types.AgentConfig{
+		TemplateID: "test-template",
+		ModelConfig: &types.ModelConfig{
+			Provider:      "mock",
+			Model:         "simple",
+			ExecutionMode: types.ExecutionModeNonStreaming,
+		},
+		Sandbox:  &types.SandboxConfig{Kind: types.SandboxKindMock, WorkDir: "/tmp/test"},
+		MaxTurns: 5,
+	}, deps)
+	if err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+	defer func() { _ = ag.Close() }()
+
+	ch := ag.Subscribe([]types.AgentChannel{types.ChannelProgress}, nil)
+	if err := ag.Send(context.Background(), "hello"); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	deadline := time.After(5 * time.Second)
+	for {
+		select {
+		case env := <-ch:
+			if done, ok := env.Event.(*types.ProgressDoneEvent); ok {
+				if done.Reason != types.TerminationCompleted || done.Summary != "" {
+					t.Errorf("done = %+v, want completed without summary", done)
+				}
+				return
+			}
+		case <-deadline:
+			t.Fatal("timed out waiting for done event")
+		}
+	}
+}
+
+func TestStreamRunLimitMaxTurns(t *testing.T) {
+	p := &loopingProvider{}
+	deps := setupTestDeps(t)
+	factory := NewMockProviderFactory()
+	factory.SetProvider("mock/looper", &MockProvider{
+		name:         "looper",
+		streamFunc:   p.stream,
+		capabilities: provider.ProviderCapabilities{SupportStreaming: true, SupportToolCalling: true, SupportSystemPrompt: true},
+	})
+	deps.ProviderFactory = factory
+
+	ag, err := Create(context.Background(), &types.AgentConfig{
+		TemplateID:  "test-template",
+		ModelConfig: &types.ModelConfig{Provider: "mock", Model: "looper"},
+		Sandbox:     &types.SandboxConfig{Kind: types.SandboxKindMock, WorkDir: "/tmp/test"},
+		MaxTurns:    3,
+	}, deps)
+	if err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+	defer func() { _ = ag.Close() }()
+
+	ch := ag.Subscribe([]types.AgentChannel{types.ChannelProgress}, nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	events, err := StreamCollect(ag.Stream(ctx, "find the config"))
+	if err != nil {
+		t.Fatalf("Stream() error = %v", err)
+	}
+
+	if p.calls != 3 || p.maxTokens[0] != 32000 {
+		t.Errorf("calls = %d, max tokens = %v", p.calls, p.maxTokens)
+	}
+	last := events[len(events)-1]
+	if last.Metadata["termination_reason"] != types.TerminationMaxTurns || !strings.Contains(last.Content.GetContent(), "reached max turns 3") {
+		t.Errorf("last event = %+v, want max_turns summary", last)
+	}
+	for {
+		select {
+		case env := <-ch:
+			if done, ok := env.Event.(*types.ProgressDoneEvent); ok {
+				if done.Reason != types.TerminationMaxTurns || !strings.Contains(done.Summary, "3 tool calls, 30 output tokens") {
+					t.Errorf("done = %+v, want max_turns with usage", done)
+				}
+				return
+			}
+		case <-ctx.Done():
+			t.Fatal("timed out waiting for done event")
+		}
+	}
+}
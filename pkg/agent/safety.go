@@ -0,0 +1,143 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/astercloud/aster/pkg/agenterr"
+	"github.com/astercloud/aster/pkg/guardrails"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+// ErrContentBlocked 用户消息被发送前安全策略拦截
+var ErrContentBlocked = agenterr.New(agenterr.CodeContentBlocked, "content blocked by safety policy")
+
+// initSafety 按模板的 Runtime.Safety 编译发送前和展示前的内容过滤器
+func (a *Agent) initSafety() error {
+	if a.template == nil || a.template.Runtime == nil || a.template.Runtime.Safety == nil {
+		return nil
+	}
+	cfg := a.template.Runtime.Safety
+	var classifiers map[string]guardrails.Guardrail
+	if a.deps != nil {
+		classifiers = a.deps.SafetyClassifiers
+	}
+	if cfg.PreSend != nil {
+		filter, err := guardrails.NewFilter(cfg.PreSend, classifiers)
+		if err != nil {
+			return fmt.Errorf("template %s: pre_send safety policy: %w", a.template.ID, err)
+		}
+		a.preSendFilter = filter
+	}
+	if cfg.PreDisplay != nil {
+		filter, err := guardrails.NewFilter(cfg.PreDisplay, classifiers)
+		if err != nil {
+			return fmt.Errorf("template %s: pre_display safety policy: %w", a.template.ID, err)
+		}
+		a.preDisplayFilter = filter
+	}
+	return nil
+}
+
+// applySafety 用过滤器检查文本，每次介入发出 safety_intervention 事件
+// 分类器调用失败时按拦截处理
+func (a *Agent) applySafety(ctx context.Context, filter *guardrails.Filter, stage, text string) (string, bool) {
+	result, err := filter.Apply(ctx, &guardrails.GuardrailInput{Content: text, SessionID: a.id})
+	if err != nil {
+		agentLog.Warn(ctx, "safety check failed, blocking content", map[string]any{
+			"agent_id": a.id,
+			"stage":    stage,
+			"error":    err.Error(),
+		})
+		a.eventBus.EmitMonitor(&types.MonitorSafetyInterventionEvent{
+			Stage:   stage,
+			Action:  types.SafetyActionBlock,
+			Source:  "error",
+			Message: err.Error(),
+			Step:    a.stepCount,
+		})
+		return filter.BlockMessage(), true
+	}
+	for _, iv := range result.Interventions {
+		a.eventBus.EmitMonitor(&types.MonitorSafetyInterventionEvent{
+			Stage:   stage,
+			Action:  iv.Action,
+			Source:  iv.Source,
+			Trigger: string(iv.Trigger),
+			Message: iv.Message,
+			Matches: iv.Matches,
+			Step:    a.stepCount,
+		})
+	}
+	return result.Content, result.Blocked
+}
+
+// filterUserText 发送前检查用户消息，拦截时返回 ErrContentBlocked，否则返回脱敏后的文本
+func (a *Agent) filterUserText(ctx context.Context, text string) (string, error) {
+	if a.preSendFilter == nil {
+		return text, nil
+	}
+	filtered, blocked := a.applySafety(ctx, a.preSendFilter, types.SafetyStagePreSend, text)
+	if blocked {
+		return "", fmt.Errorf("%w: %s", ErrContentBlocked, filtered)
+	}
+	return filtered, nil
+}
+
+// filterUserBlocks 发送前检查多模态消息中的文本块，返回替换了脱敏文本的副本
+func (a *Agent) filterUserBlocks(ctx context.Context, blocks []types.ContentBlock) ([]types.ContentBlock, error) {
+	if a.preSendFilter == nil {
+		return blocks, nil
+	}
+	filtered := make([]types.ContentBlock, len(blocks))
+	for i, block := range blocks {
+		filtered[i] = block
+		if tb, ok := block.(*types.TextBlock); ok {
+			text, err := a.filterUserText(ctx, tb.Text)
+			if err != nil {
+				return nil, err
+			}
+			filtered[i] = &types.TextBlock{Text: text}
+		}
+	}
+	return filtered, nil
+}
+
+// filterDisplayText 展示前检查模型输出，拦截时返回策略的提示文本
+func (a *Agent) filterDisplayText(ctx context.Context, text string) string {
+	if a.preDisplayFilter == nil || text == "" {
+		return text
+	}
+	filtered, _ := a.applySafety(ctx, a.preDisplayFilter, types.SafetyStagePreDisplay, text)
+	return filtered
+}
+
+// filterDisplayBlocks 展示前检查完整的助手消息：相邻的文本块合并后整体检查，
+// 避免违规内容被拆分到多个增量块中而漏检
+func (a *Agent) filterDisplayBlocks(ctx context.Context, blocks []types.ContentBlock) []types.ContentBlock {
+	if a.preDisplayFilter == nil {
+		return blocks
+	}
+	filtered := make([]types.ContentBlock, 0, len(blocks))
+	var text strings.Builder
+	inText := false
+	flush := func() {
+		if inText {
+			filtered = append(filtered, &types.TextBlock{Text: a.filterDisplayText(ctx, text.String())})
+			text.Reset()
+			inText = false
+		}
+	}
+	for _, block := range blocks {
+		if tb, ok := block.(*types.TextBlock); ok {
+			text.WriteString(tb.Text)
+			inText = true
+			continue
+		}
+		flush()
+		filtered = append(filtered, block)
+	}
+	flush()
+	return filtered
+}
@@ -0,0 +1,166 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/astercloud/aster/pkg/provider"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+func createSafetyAgent(t *testing.T, safety *types.SafetyConfig) (*Agent, error) {
+	t.Helper()
+	deps := setupTestDeps(t)
+	deps.TemplateRegistry.Register(&types.AgentTemplateDefinition{
+		ID:           "safe-template",
+		SystemPrompt: "You are a test assistant.",
+		Model:        "claude-sonnet-4-5",
+		Tools:        []any{"Read"},
+		Runtime:      &types.AgentTemplateRuntime{Safety: safety},
+	})
+	ag, err := Create(context.Background(), &types.AgentConfig{
+		TemplateID: "safe-template",
+		ModelConfig: &types.ModelConfig{
+			Provider: "anthropic",
+			Model:    "claude-sonnet-4-5",
+			APIKey:   "test-key",
+		},
+		Sandbox: &types.SandboxConfig{Kind: types.SandboxKindMock, WorkDir: "/tmp/test"},
+	}, deps)
+	if err == nil {
+		t.Cleanup(func() { _ = ag.Close() })
+	}
+	return ag, err
+}
+
+func TestSafetyPreSend(t *testing.T) {
+	ag, err := createSafetyAgent(t, &types.SafetyConfig{
+		PreSend: &types.SafetyPolicy{
+			Rules: []types.SafetyRule{
+				{Name: "sql", Pattern: `(?i)drop\s+table`, Action: types.SafetyActionBlock},
+				{Name: "ssn", Pattern: `\b\d{3}-\d{2}-\d{4}\b`},
+			},
+			BlockMessage: "not allowed",
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+	ctx := context.Background()
+
+	if err := ag.Send(ctx, "please DROP TABLE users"); !errors.Is(err, ErrContentBlocked) {
+		t.Fatalf("Send err = %v, want ErrContentBlocked", err)
+	}
+	if len(ag.messages) != 0 {
+		t.Error("blocked message should not be recorded")
+	}
+
+	text, err := ag.filterUserText(ctx, "my ssn is 123-45-6789")
+	if err != nil || text != "my ssn is [REDACTED]" {
+		t.Errorf("filterUserText = %q, %v", text, err)
+	}
+
+	original := &types.TextBlock{Text: "id 123-45-6789"}
+	blocks, err := ag.filterUserBlocks(ctx, []types.ContentBlock{original, &types.ImageContent{Type: "url", Source: "http://x/a.png"}})
+	if err != nil || len(blocks) != 2 || blocks[0].(*types.TextBlock).Text != "id [REDACTED]" {
+		t.Errorf("filterUserBlocks = %+v, %v", blocks, err)
+	}
+	if original.Text != "id 123-45-6789" {
+		t.Error("caller's blocks must not be modified")
+	}
+}
+
+func TestSafetyPreDisplayBuffersStream(t *testing.T) {
+	ag, err := createSafetyAgent(t, &types.SafetyConfig{
+		PreDisplay: &types.SafetyPolicy{
+			Rules: []types.SafetyRule{{Name: "token", Pattern: `sk-\w+`, Replacement: "sk-***"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+	progress := ag.Subscribe([]types.AgentChannel{types.ChannelProgress}, nil)
+	monitor := ag.Subscribe([]types.AgentChannel{types.ChannelMonitor}, nil)
+
+	// 违规内容被拆分到两个增量中
+	stream := make(chan provider.StreamChunk, 8)
+	stream <- provider.StreamChunk{Type: "content_block_start", Index: 0, Delta: map[string]any{"type": "text"}}
+	stream <- provider.StreamChunk{Type: "content_block_delta", Index: 0, Delta: map[string]any{"type": "text_delta", "text": "your key is sk-"}}
+	stream <- provider.StreamChunk{Type: "content_block_delta", Index: 0, Delta: map[string]any{"type": "text_delta", "text": "abc123 ok"}}
+	stream <- provider.StreamChunk{Type: "content_block_stop", Index: 0}
+	close(stream)
+
+	msg, err := ag.handleStreamResponse(context.Background(), stream)
+	if err != nil {
+		t.Fatalf("handleStreamResponse failed: %v", err)
+	}
+	if got := msg.ContentBlocks[0].(*types.TextBlock).Text; got != "your key is sk-*** ok" {
+		t.Errorf("message text = %q", got)
+	}
+
+	var deltas []string
+	deadline := time.After(time.Second)
+	for done := false; !done; {
+		select {
+		case env := <-progress:
+			switch ev := env.Event.(type) {
+			case *types.ProgressTextChunkEvent:
+				deltas = append(deltas, ev.Delta)
+			case *types.ProgressTextChunkEndEvent:
+				done = true
+			}
+		case <-deadline:
+			t.Fatal("expected text_chunk_end event")
+		}
+	}
+	if strings.Join(deltas, "|") != "your key is sk-*** ok" {
+		t.Errorf("text deltas = %q, want a single filtered chunk", deltas)
+	}
+
+	for {
+		select {
+		case env := <-monitor:
+			ev, ok := env.Event.(*types.MonitorSafetyInterventionEvent)
+			if !ok {
+				continue
+			}
+			if ev.Stage != types.SafetyStagePreDisplay || ev.Action != types.SafetyActionRedact || ev.Source != "token" || ev.Matches != 1 {
+				t.Errorf("unexpected intervention %+v", ev)
+			}
+			return
+		case <-deadline:
+			t.Fatal("expected safety_intervention event")
+		}
+	}
+}
+
+func TestSafetyDisplayBlocksMergesText(t *testing.T) {
+	ag, err := createSafetyAgent(t, &types.SafetyConfig{
+		PreDisplay: &types.SafetyPolicy{
+			Rules:        []types.SafetyRule{{Pattern: `forbidden`, Action: types.SafetyActionBlock}},
+			BlockMessage: "[blocked]",
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+	tool := &types.ToolUseBlock{ID: "t1", Name: "Read"}
+	blocks := ag.filterDisplayBlocks(context.Background(), []types.ContentBlock{
+		&types.TextBlock{Text: "forb"}, &types.TextBlock{Text: "idden"}, tool, &types.TextBlock{Text: "fine"},
+	})
+	if len(blocks) != 3 || blocks[0].(*types.TextBlock).Text != "[blocked]" || blocks[1] != tool || blocks[2].(*types.TextBlock).Text != "fine" {
+		t.Errorf("filterDisplayBlocks = %+v", blocks)
+	}
+}
+
+func TestSafetyUnknownClassifier(t *testing.T) {
+	_, err := createSafetyAgent(t, &types.SafetyConfig{
+		PreSend: &types.SafetyPolicy{Classifiers: []string{"moderation"}},
+	})
+	if err == nil || !strings.Contains(err.Error(), "moderation") {
+		t.Errorf("Create err = %v, want unregistered classifier error", err)
+	}
+}
@@ -0,0 +1,85 @@
+package agent
+
+import (
+	"context"
+	"slices"
+
+	"github.com/astercloud/aster/pkg/types"
+)
+
+// seedConversation 每次请求时注入在真实对话之前的示例对话（few-shot）
+// 示例不写入 a.messages，因此不会被持久化、撤销或生成标题和摘要
+type seedConversation struct {
+	messages []types.Message
+	tokens   int
+	pinned   bool // 不参与上下文压缩
+}
+
+// newSeedConversation 构建示例消息，超出 Token 预算的轮次从最后一轮起丢弃
+// 返回 nil 表示没有可注入的示例；skipped 为因预算或内容为空而丢弃的轮次数
+func newSeedConversation(cfg *types.SeedConversationConfig) (seed *seedConversation, skipped int) {
+	if cfg == nil {
+		return nil, 0
+	}
+	seed = &seedConversation{pinned: cfg.ExcludeFromCompaction}
+	for i, turn := range cfg.Turns {
+		if turn.User == "" || turn.Assistant == "" {
+			skipped++
+			continue
+		}
+		pair := []types.Message{
+			{Role: types.MessageRoleUser, ContentBlocks: []types.ContentBlock{&types.TextBlock{Text: turn.User}}},
+			{Role: types.MessageRoleAssistant, ContentBlocks: []types.ContentBlock{&types.TextBlock{Text: turn.Assistant}}},
+		}
+		tokens := estimateMessagesTokens(pair)
+		if cfg.MaxTokens > 0 && seed.tokens+tokens > cfg.MaxTokens {
+			skipped += len(cfg.Turns) - i
+			break
+		}
+		seed.messages = append(seed.messages, pair...)
+		seed.tokens += tokens
+	}
+	if len(seed.messages) == 0 {
+		return nil, skipped
+	}
+	return seed, skipped
+}
+
+// size 返回示例的估算 Token 数
+func (s *seedConversation) size() int {
+	if s == nil {
+		return 0
+	}
+	return s.tokens
+}
+
+// prepend 返回在 messages 之前加上示例的新切片
+func (s *seedConversation) prepend(messages []types.Message) []types.Message {
+	if s == nil {
+		return messages
+	}
+	return slices.Concat(s.messages, messages)
+}
+
+// seedLocked 返回当前应注入的示例，调用方需持有 a.mu
+func (a *Agent) seedLocked() *seedConversation {
+	if a.seedDropped {
+		return nil
+	}
+	return a.seed
+}
+
+// activeSeed 返回当前应注入的示例，已因上下文不足丢弃时返回 nil
+func (a *Agent) activeSeed() *seedConversation {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.seedLocked()
+}
+
+// dropSeed 上下文窗口不足时丢弃可压缩的示例，本会话之后不再注入
+func (a *Agent) dropSeed(ctx context.Context) {
+	a.mu.Lock()
+	a.seedDropped = true
+	a.mu.Unlock()
+	agentLog.Info(ctx, "dropped seed conversation to fit context window", map[string]any{"agent_id": a.id, "tokens": a.seed.size()})
+}
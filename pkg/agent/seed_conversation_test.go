@@ -0,0 +1,100 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/astercloud/aster/pkg/types"
+)
+
+func TestNewSeedConversationBudget(t *testing.T) {
+	turn := types.SeedTurn{User: strings.Repeat("u", 400), Assistant: strings.Repeat("a", 400)}
+	seed, skipped := newSeedConversation(&types.SeedConversationConfig{
+		Turns:     []types.SeedTurn{turn, {User: "no answer"}, turn, turn},
+		MaxTokens: 450,
+	})
+	if seed == nil || len(seed.messages) != 4 || skipped != 2 {
+		t.Fatalf("seed = %+v, skipped = %d; want 2 turns kept, 2 skipped", seed, skipped)
+	}
+	if seed.messages[0].Role != types.MessageRoleUser || seed.messages[1].Role != types.MessageRoleAssistant {
+		t.Errorf("unexpected roles: %s, %s", seed.messages[0].Role, seed.messages[1].Role)
+	}
+	if seed.tokens != estimateMessagesTokens(seed.messages) {
+		t.Errorf("tokens = %d, want %d", seed.tokens, estimateMessagesTokens(seed.messages))
+	}
+
+	if seed, _ := newSeedConversation(&types.SeedConversationConfig{}); seed != nil {
+		t.Error("empty seed conversation should be nil")
+	}
+	if got := (*seedConversation)(nil).prepend(conversation(1, 10)); len(got) != 4 {
+		t.Errorf("nil seed prepend changed messages: %d", len(got))
+	}
+}
+
+func TestFitContextWindowSeedConversation(t *testing.T) {
+	ctx := context.Background()
+	seedWith := func(size int, pinned bool) *seedConversation {
+		seed, _ := newSeedConversation(&types.SeedConversationConfig{
+			Turns:                 []types.SeedTurn{{User: "example question", Assistant: strings.Repeat("a", size)}},
+			ExcludeFromCompaction: pinned,
+		})
+		return seed
+	}
+
+	t.Run("fits", func(t *testing.T) {
+		ag := createGuardedAgent(t, 6000, nil)
+		ag.seed = seedWith(100, false)
+		msgs := conversation(1, 100)
+		ag.messages = msgs
+
+		fitted, err := ag.fitContextWindow(ctx, "system", nil, msgs)
+		if err != nil {
+			t.Fatalf("fitContextWindow failed: %v", err)
+		}
+		if len(fitted) != len(msgs)+2 || turnPrompt(fitted[0]) != "example question" {
+			t.Fatalf("expected seed ahead of %d messages, got %d", len(msgs), len(fitted))
+		}
+		if len(ag.messages) != len(msgs) {
+			t.Error("seed must not be written to agent history")
+		}
+	})
+
+	t.Run("compactable seed is dropped first", func(t *testing.T) {
+		ag := createGuardedAgent(t, 6000, nil)
+		ag.seed = seedWith(16000, false)
+		msgs := conversation(2, 2000)
+
+		fitted, err := ag.fitContextWindow(ctx, "system", nil, msgs)
+		if err != nil {
+			t.Fatalf("fitContextWindow failed: %v", err)
+		}
+		if len(fitted) != len(msgs) || fitted[0].Content != "question a" {
+			t.Fatalf("expected seed dropped and history intact, got %d messages", len(fitted))
+		}
+		if ag.activeSeed() != nil {
+			t.Error("dropped seed should not be injected again")
+		}
+	})
+
+	t.Run("excluded seed survives compaction", func(t *testing.T) {
+		ag := createGuardedAgent(t, 6000, nil)
+		ag.seed = seedWith(4000, true)
+		msgs := conversation(4, 8000)
+		ag.messages = msgs
+
+		fitted, err := ag.fitContextWindow(ctx, "system", nil, msgs)
+		if err != nil {
+			t.Fatalf("fitContextWindow failed: %v", err)
+		}
+		if turnPrompt(fitted[0]) != "example question" {
+			t.Fatal("pinned seed should lead the request")
+		}
+		if len(ag.messages) != len(fitted)-2 || turnPrompt(ag.messages[0]) == "example question" {
+			t.Error("compacted history should be saved without the seed")
+		}
+		if estimateMessagesTokens(ag.messages) >= estimateMessagesTokens(msgs) {
+			t.Error("expected history to be compacted")
+		}
+	})
+}
@@ -0,0 +1,150 @@
+package agent
+
+import (
+	"cmp"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/astercloud/aster/pkg/dashboard"
+	"github.com/astercloud/aster/pkg/provider"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+// 成本 HUD 默认告警阈值（百分比）
+const (
+	defaultCostWarnPercent     = 70
+	defaultCostCriticalPercent = 90
+)
+
+// sessionCost 会话累计的 Token 用量和费用
+type sessionCost struct {
+	mu           sync.Mutex
+	inputTokens  int64
+	outputTokens int64
+	cost         float64
+	currency     string
+	lastEmit     time.Time
+}
+
+// observeSessionCost 累计一次模型调用的用量和费用，按 HUDInterval 节流发送成本事件
+func (a *Agent) observeSessionCost(ctx context.Context, u *provider.TokenUsage) {
+	if u == nil {
+		return
+	}
+
+	usage := dashboard.Usage{
+		InputTokens:      u.InputTokens,
+		OutputTokens:     u.OutputTokens,
+		CacheReadTokens:  u.CacheReadTokens,
+		CacheWriteTokens: u.CacheCreationTokens,
+	}
+	// 与用量记录一致：包含在输入 token 中的缓存命中按缓存价格计费
+	if usage.CacheReadTokens == 0 && u.CachedTokens > 0 && u.CachedTokens <= u.InputTokens {
+		usage.CacheReadTokens = u.CachedTokens
+		usage.InputTokens -= u.CachedTokens
+	}
+	model := ""
+	if cfg := a.modelProviderForStep(ctx).Config(); cfg != nil {
+		model = cfg.Model
+	}
+	cost := modelPricing.CalculateUsage(model, usage).TotalCost
+
+	s := &a.sessionCost
+	s.mu.Lock()
+	s.inputTokens += u.InputTokens + u.CacheReadTokens + u.CacheCreationTokens
+	s.outputTokens += u.OutputTokens
+	s.cost += cost.Amount
+	s.currency = cmp.Or(cost.Currency, s.currency)
+	emit := true
+	if a.config.Budget != nil && a.config.Budget.HUDInterval > 0 {
+		emit = time.Since(s.lastEmit) >= a.config.Budget.HUDInterval
+	}
+	if emit {
+		s.lastEmit = time.Now()
+	}
+	s.mu.Unlock()
+
+	if emit {
+		a.eventBus.EmitMonitor(a.SessionCost())
+	}
+}
+
+// reset 清空会话累计用量，Agent 被新的会话复用时调用
+func (s *sessionCost) reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.inputTokens, s.outputTokens, s.cost = 0, 0, 0
+	s.currency = ""
+	s.lastEmit = time.Time{}
+}
+
+// flushSessionCost 一轮结束时发送最新的成本事件，补上被节流跳过的更新
+func (a *Agent) flushSessionCost() {
+	s := &a.sessionCost
+	s.mu.Lock()
+	if s.inputTokens+s.outputTokens == 0 {
+		s.mu.Unlock()
+		return
+	}
+	s.lastEmit = time.Now()
+	s.mu.Unlock()
+	a.eventBus.EmitMonitor(a.SessionCost())
+}
+
+// SessionCost 返回会话累计的 Token、费用和上下文占用，以及按预算计算的告警级别
+func (a *Agent) SessionCost() *types.MonitorSessionCostEvent {
+	s := &a.sessionCost
+	s.mu.Lock()
+	event := &types.MonitorSessionCostEvent{
+		InputTokens:  s.inputTokens,
+		OutputTokens: s.outputTokens,
+		TotalTokens:  s.inputTokens + s.outputTokens,
+		Cost:         s.cost,
+		Currency:     cmp.Or(s.currency, "USD"),
+	}
+	s.mu.Unlock()
+
+	a.mu.RLock()
+	window, used, _ := a.contextUsageLocked()
+	a.mu.RUnlock()
+	event.ContextUsed = used
+	event.ContextWindow = window
+	if window > 0 {
+		event.ContextPercent = float64(used) * 100 / float64(window)
+	}
+
+	warn, critical := float64(defaultCostWarnPercent), float64(defaultCostCriticalPercent)
+	if budget := a.config.Budget; budget != nil {
+		warn = cmp.Or(budget.WarnPercent, warn)
+		critical = cmp.Or(budget.CriticalPercent, critical)
+		if budget.MaxCost > 0 {
+			event.BudgetPercent = event.Cost * 100 / budget.MaxCost
+		}
+		if budget.MaxTokens > 0 {
+			event.BudgetPercent = max(event.BudgetPercent, float64(event.TotalTokens)*100/float64(budget.MaxTokens))
+		}
+	}
+	event.CostLevel = costLevel(event.BudgetPercent, warn, critical)
+	event.ContextLevel = costLevel(event.ContextPercent, warn, critical)
+	event.Level = event.CostLevel
+	if costLevelRank[event.ContextLevel] > costLevelRank[event.Level] {
+		event.Level = event.ContextLevel
+	}
+	return event
+}
+
+// costLevelRank 告警级别的严重程度
+var costLevelRank = map[string]int{types.CostLevelOK: 0, types.CostLevelWarning: 1, types.CostLevelCritical: 2}
+
+// costLevel 按百分比与阈值返回告警级别
+func costLevel(percent, warn, critical float64) string {
+	switch {
+	case percent >= critical:
+		return types.CostLevelCritical
+	case percent >= warn:
+		return types.CostLevelWarning
+	default:
+		return types.CostLevelOK
+	}
+}
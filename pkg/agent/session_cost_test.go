@@ -0,0 +1,85 @@
+package agent
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/astercloud/aster/pkg/provider"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+func TestSessionCostHUD(t *testing.T) {
+	ag := createGuardedAgent(t, 50000, nil)
+	ag.config.Budget = &types.BudgetConfig{MaxTokens: 10000}
+	events := ag.Subscribe([]types.AgentChannel{types.ChannelMonitor}, nil)
+	ctx := context.Background()
+
+	ag.contextUsage.beginRequest(0)
+	ag.observeUsage(&provider.TokenUsage{InputTokens: 5000, OutputTokens: 500})
+	ag.observeSessionCost(ctx, &provider.TokenUsage{InputTokens: 5000, OutputTokens: 500})
+
+	hud := waitSessionCost(t, events)
+	if hud.TotalTokens != 5500 || hud.Cost <= 0 || hud.Currency == "" {
+		t.Errorf("hud = %+v", hud)
+	}
+	if hud.ContextPercent != 10 || hud.ContextLevel != types.CostLevelOK {
+		t.Errorf("context usage = %.1f%% (%s), want 10%% ok", hud.ContextPercent, hud.ContextLevel)
+	}
+	if hud.BudgetPercent != 55 || hud.Level != types.CostLevelOK {
+		t.Errorf("budget = %.1f%% (%s), want 55%% ok", hud.BudgetPercent, hud.Level)
+	}
+
+	// 累计到预算的 75% 进入警告色
+	ag.observeSessionCost(ctx, &provider.TokenUsage{InputTokens: 2000})
+	hud = waitSessionCost(t, events)
+	if hud.TotalTokens != 7500 || hud.CostLevel != types.CostLevelWarning || hud.Level != types.CostLevelWarning {
+		t.Errorf("hud = %+v, want warning", hud)
+	}
+
+	// 上下文占用超过危险阈值时综合级别为 critical
+	ag.contextUsage.beginRequest(0)
+	ag.observeUsage(&provider.TokenUsage{InputTokens: 46000})
+	if hud := ag.SessionCost(); hud.ContextLevel != types.CostLevelCritical || hud.Level != types.CostLevelCritical {
+		t.Errorf("hud = %+v, want critical", hud)
+	}
+}
+
+func TestSessionCostHUDInterval(t *testing.T) {
+	ag := createGuardedAgent(t, 50000, nil)
+	ag.config.Budget = &types.BudgetConfig{HUDInterval: time.Hour}
+	events := ag.Subscribe([]types.AgentChannel{types.ChannelMonitor}, nil)
+	ctx := context.Background()
+
+	ag.observeSessionCost(ctx, &provider.TokenUsage{InputTokens: 100})
+	waitSessionCost(t, events)
+	ag.observeSessionCost(ctx, &provider.TokenUsage{InputTokens: 100})
+	select {
+	case env := <-events:
+		if _, ok := env.Event.(*types.MonitorSessionCostEvent); ok {
+			t.Fatal("session cost emitted within HUD interval")
+		}
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// 一轮结束时补发被节流的更新
+	ag.flushSessionCost()
+	if hud := waitSessionCost(t, events); hud.TotalTokens != 200 {
+		t.Errorf("flushed total = %d, want 200", hud.TotalTokens)
+	}
+}
+
+func waitSessionCost(t *testing.T, ch <-chan types.AgentEventEnvelope) *types.MonitorSessionCostEvent {
+	t.Helper()
+	deadline := time.After(2 * time.Second)
+	for {
+		select {
+		case env := <-ch:
+			if e, ok := env.Event.(*types.MonitorSessionCostEvent); ok {
+				return e
+			}
+		case <-deadline:
+			t.Fatal("no session cost event")
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/astercloud/aster/pkg/agenterr"
+	"github.com/astercloud/aster/pkg/provider"
+	"github.com/astercloud/aster/pkg/router"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+const (
+	// titleMaxRunes 标题最大字符数
+	titleMaxRunes = 60
+	// titleTimeout 后台生成标题的超时时间
+	titleTimeout = 30 * time.Second
+	// titleMetadataKey 标题在 AgentInfo.Metadata 中的键
+	titleMetadataKey = "title"
+)
+
+// titleSystemPrompt 生成标题的系统提示词
+const titleSystemPrompt = `Write a short title (at most 8 words) for the conversation below so the user can tell it apart from other sessions.
+Use the language of the user's request. Reply with the title only: no quotes, no trailing punctuation.`
+
+// Title 返回会话标题，尚未生成时返回空字符串
+func (a *Agent) Title() string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.title
+}
+
+// maybeGenerateTitle 启用 AutoTitle 时，在首轮对话完成后于后台生成标题
+func (a *Agent) maybeGenerateTitle() {
+	if !a.config.AutoTitle {
+		return
+	}
+
+	a.mu.Lock()
+	if a.title != "" || a.titlePending || !hasExchange(a.messages) {
+		a.mu.Unlock()
+		return
+	}
+	a.titlePending = true
+	messages := slices.Clone(a.messages)
+	a.mu.Unlock()
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), titleTimeout)
+		defer cancel()
+		go func() {
+			select {
+			case <-a.stopCh:
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+
+		title, err := GenerateTitle(ctx, a.titleProvider(ctx), messages)
+
+		a.mu.Lock()
+		a.titlePending = false
+		if err == nil {
+			a.title = title
+		}
+		a.mu.Unlock()
+
+		if err != nil {
+			agentLog.Warn(ctx, "failed to generate session title", map[string]any{"agent_id": a.id, "error": err.Error()})
+			return
+		}
+		if err := a.saveTitle(ctx, title); err != nil {
+			agentLog.Warn(ctx, "failed to save session title", map[string]any{"agent_id": a.id, "error": err.Error()})
+		}
+		a.eventBus.EmitMonitor(&types.MonitorSessionTitleEvent{Title: title})
+	}()
+}
+
+// titleProvider 优先使用路由器为 title 任务配置的模型，其次是 summarize 任务，最后回退到主模型
+func (a *Agent) titleProvider(ctx context.Context) provider.Provider {
+	for _, task := range []string{router.TaskTitle, router.TaskSummarize} {
+		if prov := a.taskProvider(ctx, task); prov != nil {
+			return prov
+		}
+	}
+	return a.currentProvider()
+}
+
+// saveTitle 将标题写入 Agent 元信息
+func (a *Agent) saveTitle(ctx context.Context, title string) error {
+	info, err := a.deps.Store.LoadInfo(ctx, a.id)
+	if err != nil {
+		return fmt.Errorf("load info: %w", err)
+	}
+	if info.Metadata == nil {
+		info.Metadata = make(map[string]any)
+	}
+	info.Metadata[titleMetadataKey] = title
+	info.UpdatedAt = time.Now()
+	return a.deps.Store.SaveInfo(ctx, a.id, *info)
+}
+
+// hasExchange 历史中是否已有一问一答
+func hasExchange(messages []types.Message) bool {
+	var user, assistant bool
+	for _, msg := range messages {
+		switch msg.Role {
+		case types.MessageRoleUser:
+			user = true
+		case types.MessageRoleAssistant:
+			assistant = assistant || user
+		}
+	}
+	return user && assistant
+}
+
+// GenerateTitle 根据对话的第一轮问答生成简短标题
+func GenerateTitle(ctx context.Context, prov provider.Provider, messages []types.Message) (string, error) {
+	transcript := renderTranscript(firstExchange(messages))
+	if transcript == "" {
+		return "", errors.New("no conversation to title")
+	}
+
+	resp, err := prov.Complete(ctx, []types.Message{{
+		Role:          types.MessageRoleUser,
+		ContentBlocks: []types.ContentBlock{&types.TextBlock{Text: transcript}},
+	}}, &provider.StreamOptions{
+		System:      titleSystemPrompt,
+		MaxTokens:   32,
+		Temperature: 0.2,
+	})
+	if err != nil {
+		return "", agenterr.Wrap(agenterr.CodeProviderError, fmt.Errorf("generate title: %w", err))
+	}
+
+	title := cleanTitle(extractText(resp.Message))
+	if title == "" {
+		return "", errors.New("model returned an empty title")
+	}
+	return title, nil
+}
+
+// firstExchange 截取第一条用户消息到其后第一条带文本的助手消息
+func firstExchange(messages []types.Message) []types.Message {
+	start := slices.IndexFunc(messages, func(m types.Message) bool { return m.Role == types.MessageRoleUser })
+	if start < 0 {
+		return nil
+	}
+	for i := start + 1; i < len(messages); i++ {
+		if messages[i].Role == types.MessageRoleAssistant && strings.TrimSpace(extractText(messages[i])) != "" {
+			return messages[start : i+1]
+		}
+	}
+	return messages[start:]
+}
+
+// cleanTitle 取首行，去掉引号、"Title:" 前缀和结尾标点，并限制长度
+func cleanTitle(text string) string {
+	title, _, _ := strings.Cut(strings.TrimSpace(text), "\n")
+	title = strings.TrimSpace(strings.TrimPrefix(strings.TrimPrefix(title, "Title:"), "title:"))
+	for range 2 { // 引号和结尾标点可能互相包裹
+		title = strings.TrimRight(strings.Trim(title, "\"'`*# "), ".。!！")
+	}
+	return truncateRunes(strings.TrimSpace(title), titleMaxRunes)
+}
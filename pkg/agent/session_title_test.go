@@ -0,0 +1,104 @@
+package agent
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/astercloud/aster/pkg/provider"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+func TestCleanTitle(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"Fix flaky login test", "Fix flaky login test"},
+		{"\"Add retries to HTTP client.\"\n", "Add retries to HTTP client"},
+		{"Title: Rename config package\nMore text", "Rename config package"},
+		{"**重构配置加载**。", "重构配置加载"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := cleanTitle(tt.in); got != tt.want {
+			t.Errorf("cleanTitle(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestFirstExchange(t *testing.T) {
+	messages := []types.Message{
+		{Role: types.MessageRoleUser, Content: "fix the build"},
+		{Role: types.MessageRoleAssistant, ContentBlocks: []types.ContentBlock{&types.ToolUseBlock{ID: "1", Name: "Bash"}}},
+		{Role: types.MessageRoleUser, ContentBlocks: []types.ContentBlock{&types.ToolResultBlock{ToolUseID: "1"}}},
+		{Role: types.MessageRoleAssistant, Content: "Fixed the missing import."},
+		{Role: types.MessageRoleUser, Content: "thanks, now add tests"},
+	}
+	if got := firstExchange(messages); len(got) != 4 {
+		t.Errorf("firstExchange() returned %d messages, want 4", len(got))
+	}
+	if !hasExchange(messages[:2]) || hasExchange(messages[:1]) {
+		t.Error("hasExchange() mismatch")
+	}
+}
+
+func TestAutoTitle(t *testing.T) {
+	deps := setupTestDeps(t)
+	factory := NewMockProviderFactory()
+	factory.SetProvider("mock/titled", &MockProvider{
+		name: "titled",
+		completeFunc: func(_ context.Context, _ []types.Message, opts *provider.StreamOptions) (*provider.CompleteResponse, error) {
+			text := "The build is fixed."
+			if opts.System == titleSystemPrompt {
+				text = "Fix the broken build"
+			}
+			return &provider.CompleteResponse{Message: types.Message{
+				Role:          types.MessageRoleAssistant,
+				ContentBlocks: []types.ContentBlock{&types.TextBlock{Text: text}},
+			}}, nil
+		},
+		capabilities: provider.ProviderCapabilities{SupportToolCalling: true, SupportSystemPrompt: true},
+	})
+	deps.ProviderFactory = factory
+
+	ag, err := Create(context.Background(), &types.AgentConfig{
+		TemplateID: "test-template",
+		ModelConfig: &types.ModelConfig{
+			Provider:      "mock",
+			Model:         "titled",
+			ExecutionMode: types.ExecutionModeNonStreaming,
+		},
+		Sandbox:   &types.SandboxConfig{Kind: types.SandboxKindMock, WorkDir: "/tmp/test"},
+		AutoTitle: true,
+	}, deps)
+	if err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+	defer func() { _ = ag.Close() }()
+
+	ch := ag.Subscribe([]types.AgentChannel{types.ChannelMonitor}, nil)
+	if err := ag.Send(context.Background(), "the build is broken, please fix it"); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	deadline := time.After(5 * time.Second)
+	for {
+		select {
+		case env := <-ch:
+			ev, ok := env.Event.(*types.MonitorSessionTitleEvent)
+			if !ok {
+				continue
+			}
+			if ev.Title != "Fix the broken build" || ag.Title() != ev.Title || ag.Status().Title != ev.Title {
+				t.Errorf("title = %q / %q", ev.Title, ag.Title())
+			}
+			info, err := deps.Store.LoadInfo(context.Background(), ag.ID())
+			if err != nil || info.Metadata["title"] != ev.Title {
+				t.Errorf("stored info = %+v (err=%v)", info, err)
+			}
+			return
+		case <-deadline:
+			t.Fatal("timed out waiting for session_title event")
+		}
+	}
+}
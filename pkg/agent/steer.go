@@ -0,0 +1,235 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/astercloud/aster/pkg/agenterr"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+// SteerMode 引导消息到达时对正在执行的工具调用的处理方式
+type SteerMode string
+
+const (
+	// SteerFinishTool 等待当前工具调用完成后再采纳引导消息（默认）
+	SteerFinishTool SteerMode = "finish"
+	// SteerAbortTool 取消当前工具调用，立即采纳引导消息
+	SteerAbortTool SteerMode = "abort"
+)
+
+// 引导消息注入对话的阶段
+const (
+	steerPhaseModel   = "model"
+	steerPhaseTools   = "tools"
+	steerPhaseTurnEnd = "turn_end"
+)
+
+// steeringPreamble 注入对话的引导消息前缀
+const steeringPreamble = "The user sent a new instruction while you were working. Follow it, adjusting or abandoning your current plan as needed:\n\n"
+
+// errSteered 模型生成或工具调用被引导消息打断时的取消原因
+var errSteered = errors.New("interrupted by steering message")
+
+// steerState 执行循环的引导状态
+type steerState struct {
+	mu          sync.Mutex
+	active      int      // 正在进行的执行循环数
+	pending     []string // 尚未注入对话的引导消息
+	cancelModel context.CancelCauseFunc
+	cancelTool  context.CancelCauseFunc
+	toolCallID  string
+}
+
+// Steer 在 Agent 执行中途注入用户的新指令：正在生成的模型输出被打断并丢弃，
+// 当前工具调用按 mode 执行完毕或被取消，同批尚未开始的工具调用被跳过，
+// 随后指令加入对话，Agent 带着新指令继续执行。Agent 空闲时等同于 Send。
+func (a *Agent) Steer(ctx context.Context, text string, mode SteerMode) error {
+	if strings.TrimSpace(text) == "" {
+		return errors.New("steering message is empty")
+	}
+	switch mode {
+	case "":
+		mode = SteerFinishTool
+	case SteerFinishTool, SteerAbortTool:
+	default:
+		return fmt.Errorf("unknown steer mode: %s", mode)
+	}
+
+	filtered, err := a.filterUserText(ctx, text)
+	if err != nil {
+		return err
+	}
+
+	s := &a.steering
+	s.mu.Lock()
+	if s.active == 0 {
+		s.mu.Unlock()
+		return a.Send(ctx, text)
+	}
+	s.pending = append(s.pending, filtered)
+	event := &types.ControlSteerEvent{Message: filtered, Mode: string(mode)}
+	switch {
+	case s.cancelModel != nil:
+		s.cancelModel(errSteered)
+		event.Interrupted = steerPhaseModel
+	case s.cancelTool != nil && mode == SteerAbortTool:
+		s.cancelTool(errSteered)
+		event.Interrupted = "tool"
+		event.CallID = s.toolCallID
+	}
+	s.mu.Unlock()
+
+	a.eventBus.EmitControl(event)
+	if event.CallID != "" {
+		// 可中断工具不一定监听 ctx，同时走工具自身的取消
+		_ = a.controlRunningTool(event.CallID, "cancel")
+		a.eventBus.EmitProgress(&types.ProgressToolCancelledEvent{
+			Call:   a.snapshotToolCall(event.CallID),
+			Reason: "steered",
+		})
+	}
+	agentLog.Info(ctx, "steering message received", map[string]any{
+		"agent_id": a.id, "mode": mode, "interrupted": event.Interrupted,
+	})
+	return nil
+}
+
+// beginSteering 标记执行循环开始，返回的函数在循环退出时调用
+func (a *Agent) beginSteering() (end func()) {
+	s := &a.steering
+	s.mu.Lock()
+	s.active++
+	s.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			s.mu.Lock()
+			s.active--
+			var leftover []string
+			if s.active == 0 {
+				leftover, s.pending = s.pending, nil
+			}
+			s.mu.Unlock()
+
+			// 循环出错退出时尚未注入的引导消息作为普通消息发送
+			if len(leftover) > 0 {
+				go func() {
+					if err := a.Send(context.Background(), strings.Join(leftover, "\n\n")); err != nil {
+						agentLog.Warn(context.Background(), "deliver steering message failed", map[string]any{"agent_id": a.id, "error": err.Error()})
+					}
+				}()
+			}
+		})
+	}
+}
+
+// steerModelContext 返回可被引导消息打断的模型调用 ctx；
+// 结束模型调用时调用 done，返回调用是否被引导消息打断
+func (a *Agent) steerModelContext(ctx context.Context) (modelCtx context.Context, done func() bool) {
+	modelCtx, cancel := context.WithCancelCause(ctx)
+	s := &a.steering
+	s.mu.Lock()
+	if len(s.pending) > 0 {
+		// 引导消息在上一步注入之后到达，无需再等模型输出
+		cancel(errSteered)
+	} else {
+		s.cancelModel = cancel
+	}
+	s.mu.Unlock()
+
+	return modelCtx, func() bool {
+		s.mu.Lock()
+		s.cancelModel = nil
+		s.mu.Unlock()
+		steered := errors.Is(context.Cause(modelCtx), errSteered)
+		cancel(nil)
+		return steered
+	}
+}
+
+// steerToolContext 返回 abort 模式下可被引导消息取消的工具调用 ctx
+func (a *Agent) steerToolContext(ctx context.Context, callID string) (toolCtx context.Context, done func()) {
+	toolCtx, cancel := context.WithCancelCause(ctx)
+	s := &a.steering
+	s.mu.Lock()
+	s.cancelTool = cancel
+	s.toolCallID = callID
+	s.mu.Unlock()
+
+	return toolCtx, func() {
+		s.mu.Lock()
+		s.cancelTool = nil
+		s.toolCallID = ""
+		s.mu.Unlock()
+		cancel(nil)
+	}
+}
+
+// steeringPending 是否有尚未注入的引导消息
+func (a *Agent) steeringPending() bool {
+	a.steering.mu.Lock()
+	defer a.steering.mu.Unlock()
+	return len(a.steering.pending) > 0
+}
+
+// takeSteering 取出尚未注入的引导消息
+func (a *Agent) takeSteering() []string {
+	a.steering.mu.Lock()
+	defer a.steering.mu.Unlock()
+	msgs := a.steering.pending
+	a.steering.pending = nil
+	return msgs
+}
+
+// steeringBlocks 将引导消息转为注入对话的文本块
+func steeringBlocks(msgs []string) []types.ContentBlock {
+	blocks := make([]types.ContentBlock, 0, len(msgs))
+	for _, msg := range msgs {
+		blocks = append(blocks, &types.TextBlock{Text: steeringPreamble + msg})
+	}
+	return blocks
+}
+
+// skippedToolResult 因引导消息跳过的工具调用结果
+func skippedToolResult(toolUseID string) *types.ToolResultBlock {
+	return toolFailure(toolUseID, agenterr.New(agenterr.CodeCanceled, "已跳过：用户在执行过程中发送了新指令"), nil)
+}
+
+// applySteering 将待处理的引导消息注入对话，返回是否有消息注入；
+// 最后一条是用户消息时合并进去，保持用户与助手消息交替
+func (a *Agent) applySteering(ctx context.Context, phase string, skipped []string) bool {
+	msgs := a.takeSteering()
+	if len(msgs) == 0 {
+		return false
+	}
+
+	blocks := steeringBlocks(msgs)
+	a.mu.Lock()
+	if n := len(a.messages); n > 0 && a.messages[n-1].Role == types.MessageRoleUser && len(a.messages[n-1].ContentBlocks) > 0 {
+		last := &a.messages[n-1]
+		last.ContentBlocks = append(append([]types.ContentBlock{}, last.ContentBlocks...), blocks...)
+	} else {
+		a.messages = append(a.messages, types.Message{Role: types.MessageRoleUser, ContentBlocks: blocks})
+	}
+	a.mu.Unlock()
+
+	a.emitSteerApplied(ctx, phase, len(msgs), skipped)
+	return true
+}
+
+// emitSteerApplied 发送引导消息已注入事件
+func (a *Agent) emitSteerApplied(ctx context.Context, phase string, count int, skipped []string) {
+	a.eventBus.EmitControl(&types.ControlSteerAppliedEvent{
+		Messages:     count,
+		Phase:        phase,
+		SkippedTools: skipped,
+	})
+	agentLog.Info(ctx, "steering message applied", map[string]any{
+		"agent_id": a.id, "phase": phase, "messages": count, "skipped_tools": len(skipped),
+	})
+}
@@ -0,0 +1,218 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/astercloud/aster/pkg/provider"
+	"github.com/astercloud/aster/pkg/tools"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+// blockingTool 执行后一直阻塞直到 ctx 取消
+type blockingTool struct {
+	tools.BaseTool
+	started chan struct{}
+	calls   int
+}
+
+func (b *blockingTool) Execute(ctx context.Context, _ map[string]any, _ *tools.ToolContext) (any, error) {
+	b.calls++
+	close(b.started)
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+// steerProvider 按调用次序返回脚本化的流式响应，并记录每次请求的消息
+type steerProvider struct {
+	mu       sync.Mutex
+	requests [][]types.Message
+	script   []func(ctx context.Context, ch chan<- provider.StreamChunk)
+}
+
+func (p *steerProvider) stream(ctx context.Context, messages []types.Message, _ *provider.StreamOptions) (<-chan provider.StreamChunk, error) {
+	p.mu.Lock()
+	n := len(p.requests)
+	p.requests = append(p.requests, append([]types.Message(nil), messages...))
+	p.mu.Unlock()
+
+	ch := make(chan provider.StreamChunk, 16)
+	go func() {
+		defer close(ch)
+		p.script[n](ctx, ch)
+	}()
+	return ch, nil
+}
+
+func (p *steerProvider) request(i int) []types.Message {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if i >= len(p.requests) {
+		return nil
+	}
+	return p.requests[i]
+}
+
+func textReply(text string) func(context.Context, chan<- provider.StreamChunk) {
+	return func(_ context.Context, ch chan<- provider.StreamChunk) {
+		ch <- provider.StreamChunk{Type: "content_block_start", Index: 0, Delta: map[string]any{"type": "text"}}
+		ch <- provider.StreamChunk{Type: "content_block_delta", Index: 0, Delta: map[string]any{"type": "text_delta", "text": text}}
+		ch <- provider.StreamChunk{Type: "content_block_stop", Index: 0}
+	}
+}
+
+func newSteerTestAgent(t *testing.T, p *steerProvider) *Agent {
+	t.Helper()
+	deps := setupTestDeps(t)
+	factory := NewMockProviderFactory()
+	factory.SetProvider("mock/steer", &MockProvider{
+		name:         "steer",
+		streamFunc:   p.stream,
+		capabilities: provider.ProviderCapabilities{SupportToolCalling: true, SupportSystemPrompt: true},
+	})
+	deps.ProviderFactory = factory
+	ag, err := Create(context.Background(), &types.AgentConfig{
+		TemplateID:  "test-template",
+		ModelConfig: &types.ModelConfig{Provider: "mock", Model: "steer"},
+		Sandbox:     &types.SandboxConfig{Kind: types.SandboxKindMock, WorkDir: "/tmp/test"},
+	}, deps)
+	if err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+	t.Cleanup(func() { _ = ag.Close() })
+	return ag
+}
+
+// collectSteerEvents 收集引导事件直到本轮结束
+func collectSteerEvents(t *testing.T, ch <-chan types.AgentEventEnvelope) (steer *types.ControlSteerEvent, applied *types.ControlSteerAppliedEvent) {
+	t.Helper()
+	deadline := time.After(5 * time.Second)
+	for {
+		select {
+		case env := <-ch:
+			switch e := env.Event.(type) {
+			case *types.ControlSteerEvent:
+				steer = e
+			case *types.ControlSteerAppliedEvent:
+				applied = e
+			case *types.ProgressDoneEvent:
+				return steer, applied
+			}
+		case <-deadline:
+			t.Fatal("turn did not finish")
+		}
+	}
+}
+
+func waitSignal(t *testing.T, ch <-chan struct{}, what string) {
+	t.Helper()
+	select {
+	case <-ch:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for %s", what)
+	}
+}
+
+func TestSteerInterruptsModel(t *testing.T) {
+	generating := make(chan struct{})
+	p := &steerProvider{script: []func(context.Context, chan<- provider.StreamChunk){
+		func(ctx context.Context, ch chan<- provider.StreamChunk) {
+			ch <- provider.StreamChunk{Type: "content_block_start", Index: 0, Delta: map[string]any{"type": "text"}}
+			ch <- provider.StreamChunk{Type: "content_block_delta", Index: 0, Delta: map[string]any{"type": "text_delta", "text": "Once upon a time"}}
+			close(generating)
+			<-ctx.Done()
+		},
+		textReply("Autumn moonlight"),
+	}}
+	ag := newSteerTestAgent(t, p)
+	events := ag.Subscribe([]types.AgentChannel{types.ChannelProgress, types.ChannelControl}, nil)
+	ctx := context.Background()
+
+	if err := ag.Send(ctx, "Write a long poem"); err != nil {
+		t.Fatal(err)
+	}
+	waitSignal(t, generating, "model generation")
+	if err := ag.Steer(ctx, "Make it a haiku", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	steer, applied := collectSteerEvents(t, events)
+	if steer == nil || steer.Interrupted != "model" || steer.Mode != string(SteerFinishTool) {
+		t.Errorf("steer event = %+v", steer)
+	}
+	if applied == nil || applied.Phase != "model" || applied.Messages != 1 {
+		t.Errorf("steer_applied event = %+v", applied)
+	}
+
+	// 不完整的输出被丢弃，引导消息并入原用户消息
+	req := p.request(1)
+	if len(req) != 1 || len(req[0].ContentBlocks) != 2 || !strings.Contains(req[0].ContentBlocks[1].(*types.TextBlock).Text, "Make it a haiku") {
+		t.Fatalf("second request = %+v", req)
+	}
+	ag.mu.RLock()
+	defer ag.mu.RUnlock()
+	if len(ag.messages) != 2 || ag.messages[1].GetContent() != "Autumn moonlight" {
+		t.Errorf("messages = %+v", ag.messages)
+	}
+}
+
+func TestSteerAbortsTool(t *testing.T) {
+	p := &steerProvider{script: []func(context.Context, chan<- provider.StreamChunk){
+		func(_ context.Context, ch chan<- provider.StreamChunk) {
+			for i, id := range []string{"call-1", "call-2"} {
+				ch <- provider.StreamChunk{Type: "content_block_start", Index: i, Delta: map[string]any{
+					"type": "tool_use", "id": id, "name": "Block", "input": map[string]any{"n": i},
+				}}
+				ch <- provider.StreamChunk{Type: "content_block_stop", Index: i}
+			}
+		},
+		textReply("Switched to the new task"),
+	}}
+	ag := newSteerTestAgent(t, p)
+	tool := &blockingTool{BaseTool: tools.BaseTool{ToolName: "Block", ToolDescription: "blocks"}, started: make(chan struct{})}
+	ag.toolMap["Block"] = tool
+	ag.permissionInspector = nil // 测试工具无需审批
+	events := ag.Subscribe([]types.AgentChannel{types.ChannelProgress, types.ChannelControl}, nil)
+	ctx := context.Background()
+
+	if err := ag.Send(ctx, "Run the slow jobs"); err != nil {
+		t.Fatal(err)
+	}
+	waitSignal(t, tool.started, "tool execution")
+	if err := ag.Steer(ctx, "Stop that and check the logs instead", SteerAbortTool); err != nil {
+		t.Fatal(err)
+	}
+
+	steer, applied := collectSteerEvents(t, events)
+	if steer == nil || steer.Interrupted != "tool" || steer.CallID != "call-1" {
+		t.Errorf("steer event = %+v", steer)
+	}
+	if applied == nil || applied.Phase != "tools" || len(applied.SkippedTools) != 1 || applied.SkippedTools[0] != "call-2" {
+		t.Errorf("steer_applied event = %+v", applied)
+	}
+	if tool.calls != 1 {
+		t.Errorf("tool calls = %d, want 1", tool.calls)
+	}
+
+	// 工具结果与引导消息在同一条用户消息中
+	req := p.request(1)
+	if len(req) != 3 {
+		t.Fatalf("second request = %+v", req)
+	}
+	blocks := req[2].ContentBlocks
+	if len(blocks) != 3 {
+		t.Fatalf("tool result message = %+v", blocks)
+	}
+	if r, ok := blocks[0].(*types.ToolResultBlock); !ok || !r.IsError {
+		t.Errorf("aborted tool result = %+v", blocks[0])
+	}
+	if r, ok := blocks[1].(*types.ToolResultBlock); !ok || !strings.Contains(r.Content, "已跳过") {
+		t.Errorf("skipped tool result = %+v", blocks[1])
+	}
+	if tb, ok := blocks[2].(*types.TextBlock); !ok || !strings.Contains(tb.Text, "check the logs") {
+		t.Errorf("steering block = %+v", blocks[2])
+	}
+}
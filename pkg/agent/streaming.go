@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"strings"
+	"time"
 
 	"github.com/astercloud/aster/pkg/logging"
 	"github.com/astercloud/aster/pkg/middleware"
@@ -57,6 +58,8 @@ func (a *Agent) Stream(ctx context.Context, message string, opts ...Option) *str
 
 	go func() {
 		defer writer.Close()
+		ctx, endRequest := a.beginRequest(ctx)
+		defer endRequest()
 
 		// 应用选项
 		config := &streamConfig{}
@@ -91,6 +94,20 @@ func (a *Agent) Stream(ctx context.Context, message string, opts ...Option) *str
 			}
 		}
 
+		// 3.5 内容安全：发送前检查
+		filtered, err := a.filterUserText(ctx, message)
+		if err != nil {
+			writer.Send(nil, err)
+			return
+		}
+		if filtered != message {
+			userMsg.Content = filtered
+			userMsg.ContentBlocks = []types.ContentBlock{&types.TextBlock{Text: filtered}}
+		}
+
+		// 模型升级：检测用户重试（升级在本轮模型调用前生效）
+		a.observeUserMessage(ctx, filtered)
+
 		// 4. 应用 Skills 增强
 		if a.skillInjector != nil {
 			skillContext := skills.SkillContext{
@@ -100,9 +117,11 @@ func (a *Agent) Stream(ctx context.Context, message string, opts ...Option) *str
 			a.template.SystemPrompt = enhancedPrompt
 		}
 
-		// 5. 入队消息
+		// 5. 入队消息（附带计划模式下已审批或拒绝的计划结果）
 		a.mu.Lock()
+		userMsg.ContentBlocks = append(a.planNoticeBlocksLocked(), userMsg.ContentBlocks...)
 		a.messages = append(a.messages, userMsg)
+		a.checkpointTurn(userMsg)
 		a.mu.Unlock()
 
 		// 6. 持久化消息
@@ -129,35 +148,75 @@ func (a *Agent) Stream(ctx context.Context, message string, opts ...Option) *str
 		}
 		streamLog.Debug(ctx, "sent task planning event", nil)
 
-		// 7. 流式执行模型步骤
+		// 7. 流式执行模型步骤（受 MaxTurns/MaxOutputTokens/MaxDuration 限制），执行期间可通过 Steer 注入新指令
+		runCtx, cancel := a.startRun(ctx)
+		defer cancel()
+		endSteering := a.beginSteering()
+		defer endSteering()
+
+		err = a.runStreamTurns(runCtx, writer)
+		termination, summary, runErr := a.finishRun(runCtx, err)
+		a.recordExperimentRun(ctx, termination)
+		a.flushSessionCost()
+		a.eventBus.EmitProgress(&types.ProgressDoneEvent{
+			Step:    a.stepCount,
+			Reason:  termination,
+			Summary: summary,
+			Error:   runErr,
+		})
+
+		switch {
+		case termination.IsLimit():
+			// 达到运行限制：部分结果摘要作为最后一个事件发送
+			streamLog.Info(ctx, "stream stopped by limit", map[string]any{"agent_id": a.id, "reason": termination})
+			writer.Send(&session.Event{
+				ID:        generateEventID(),
+				Timestamp: time.Now(),
+				AgentID:   a.id,
+				Author:    "assistant",
+				Content: types.Message{
+					Role:          types.RoleAssistant,
+					ContentBlocks: []types.ContentBlock{&types.TextBlock{Text: summary}},
+				},
+				Metadata: map[string]any{"termination_reason": termination},
+			}, nil)
+		case err != nil:
+			writer.Send(nil, err)
+		}
+	}()
+
+	return reader
+}
+
+// runStreamTurns 循环执行流式模型步骤，直到模型给出最终回答、达到运行限制或 context 取消
+func (a *Agent) runStreamTurns(ctx context.Context, writer *stream.Writer[*session.Event]) error {
+	for {
 		// 检查上下文是否已取消
-		for {
-			select {
-			case <-ctx.Done():
-				writer.Send(nil, ctx.Err())
-				return
-			default:
-			}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
 
-			// 执行流式模型推理
-			done, err := a.runModelStepStreaming(ctx, writer)
-			if err != nil {
-				writer.Send(nil, fmt.Errorf("model step: %w", err))
-				return
-			}
+		if err := a.beginTurn(); err != nil {
+			return err
+		}
 
-			// 检查是否完成
-			if done {
-				streamLog.Debug(ctx, "stream completed", nil)
-				return
-			}
+		// 执行流式模型推理
+		done, err := a.runModelStepStreaming(ctx, writer)
+		if err != nil {
+			return fmt.Errorf("model step: %w", err)
+		}
 
-			// 如果没有完成，继续下一轮模型推理（通常是因为有工具调用）
-			streamLog.Debug(ctx, "continuing to next model inference round", nil)
+		// 检查是否完成（回答期间收到的引导消息在本轮内继续处理）
+		if done && !a.applySteering(ctx, steerPhaseTurnEnd, nil) {
+			streamLog.Debug(ctx, "stream completed", nil)
+			return nil
 		}
-	}()
 
-	return reader
+		// 如果没有完成，继续下一轮模型推理（通常是因为有工具调用）
+		streamLog.Debug(ctx, "continuing to next model inference round", nil)
+	}
 }
 
 // StreamCollect 辅助函数 - 收集所有事件
@@ -304,15 +363,22 @@ func (a *Agent) handleSlashCommandForStream(ctx context.Context, msg *types.Mess
 // runModelStepStreaming 流式执行模型步骤
 // 返回: (done, error)
 func (a *Agent) runModelStepStreaming(ctx context.Context, writer *stream.Writer[*session.Event]) (bool, error) {
-	// 1. 准备消息
+	// 1. 准备消息，请求超出模型上下文窗口前主动压缩，无法容纳时直接拒绝（返回的消息示例对话在前）
 	a.mu.RLock()
 	messages := make([]types.Message, len(a.messages))
 	copy(messages, a.messages)
+	systemPrompt := a.template.SystemPrompt
 	a.mu.RUnlock()
 
-	// 2. 通过 Middleware 调用 LLM
+	toolSchemas := a.providerToolSchemas()
+	messages, err := a.fitContextWindow(ctx, systemPrompt, toolSchemas, messages)
+	if err != nil {
+		return false, fmt.Errorf("model call: %w", err)
+	}
+
+	// 2. 通过 Middleware 调用 LLM，生成过程中收到引导消息时打断
 	var resp *middleware.ModelResponse
-	var err error
+	modelCtx, modelDone := a.steerModelContext(ctx)
 
 	streamLog.Debug(ctx, "using middleware stack", map[string]any{"has_stack": a.middlewareStack != nil})
 
@@ -321,13 +387,15 @@ func (a *Agent) runModelStepStreaming(ctx context.Context, writer *stream.Writer
 		streamLog.Debug(ctx, "using middleware stack for streaming", nil)
 		// 转换工具列表
 		toolList := make([]tools.Tool, 0, len(a.toolMap))
-		for _, tool := range a.toolMap {
-			toolList = append(toolList, tool)
+		for name, tool := range a.toolMap {
+			if a.toolOffered(name) {
+				toolList = append(toolList, tool)
+			}
 		}
 
 		req := &middleware.ModelRequest{
 			Messages:     messages,
-			SystemPrompt: a.template.SystemPrompt,
+			SystemPrompt: systemPrompt,
 			Tools:        toolList,
 			Metadata:     make(map[string]any),
 		}
@@ -349,11 +417,12 @@ func (a *Agent) runModelStepStreaming(ctx context.Context, writer *stream.Writer
 				Tools:       toolSchemas,
 				System:      req.SystemPrompt,
 				Temperature: 0.7,
+				MaxTokens:   a.outputTokenCap(32000),
 			}
 
 			// 调用Provider - 使用Stream方法支持流式响应
 			streamLog.Debug(ctx, "calling provider.Stream() for middleware", nil)
-			chunkCh, err := a.provider.Stream(ctx, req.Messages, streamOpts)
+			chunkCh, err := a.modelProviderForStep(ctx).Stream(ctx, req.Messages, streamOpts)
 			if err != nil {
 				return nil, err
 			}
@@ -366,6 +435,7 @@ func (a *Agent) runModelStepStreaming(ctx context.Context, writer *stream.Writer
 			var reasoningContent strings.Builder
 			for chunk := range chunkCh {
 				streamLog.Debug(ctx, "middleware chunk", map[string]any{"type": chunk.Type, "text_delta": truncate(chunk.TextDelta, 30)})
+				a.observeStreamUsage(ctx, chunk.Usage)
 
 				switch chunk.Type {
 				// OpenAI 兼容格式 - 直接文本类型
@@ -411,28 +481,21 @@ func (a *Agent) runModelStepStreaming(ctx context.Context, writer *stream.Writer
 			}, nil
 		}
 
-		resp, err = a.middlewareStack.ExecuteModelCall(ctx, req, finalHandler)
+		resp, err = a.middlewareStack.ExecuteModelCall(modelCtx, req, finalHandler)
 	} else {
 		streamLog.Debug(ctx, "using direct provider call (no middleware)", nil)
-		// 转换工具定义
-		toolSchemas := make([]provider.ToolSchema, len(a.getToolsForProvider()))
-		for i, tool := range a.getToolsForProvider() {
-			toolSchemas[i] = provider.ToolSchema{
-				Name:        tool.Name,
-				Description: tool.Description,
-				InputSchema: tool.InputSchema,
-			}
-		}
 
 		// 直接调用 Provider - 使用Stream方法支持流式响应
 		streamOpts := &provider.StreamOptions{
 			Tools:       toolSchemas,
-			System:      a.template.SystemPrompt,
+			System:      systemPrompt,
 			Temperature: 0.7,
+			MaxTokens:   a.outputTokenCap(32000),
 		}
 		streamLog.Debug(ctx, "calling provider.Stream() directly", nil)
-		chunkCh, err := a.provider.Stream(ctx, messages, streamOpts)
+		chunkCh, err := a.modelProviderForStep(ctx).Stream(modelCtx, messages, streamOpts)
 		if err != nil {
+			modelDone()
 			return false, err
 		}
 
@@ -447,6 +510,7 @@ func (a *Agent) runModelStepStreaming(ctx context.Context, writer *stream.Writer
 
 		for chunk := range chunkCh {
 			streamLog.Debug(ctx, "processing chunk", map[string]any{"type": chunk.Type, "index": chunk.Index, "text_delta": truncate(chunk.TextDelta, 50)})
+			a.observeStreamUsage(ctx, chunk.Usage)
 
 			switch chunk.Type {
 			// OpenAI 兼容格式 - 直接文本类型
@@ -455,6 +519,11 @@ func (a *Agent) runModelStepStreaming(ctx context.Context, writer *stream.Writer
 					streamLog.Debug(ctx, "received text delta", map[string]any{"text": truncate(chunk.TextDelta, 50)})
 					contentBlocks = append(contentBlocks, &types.TextBlock{Text: chunk.TextDelta})
 
+					if a.preDisplayFilter != nil {
+						// 展示前安全检查：文本在响应结束后整体过滤再发送
+						continue
+					}
+
 					// 立即为这个text chunk生成事件并yield
 					event := &session.Event{
 						ID:        generateEventID(),
@@ -504,6 +573,9 @@ func (a *Agent) runModelStepStreaming(ctx context.Context, writer *stream.Writer
 							if text, ok := delta["text"].(string); ok {
 								streamLog.Debug(ctx, "received text delta from block", map[string]any{"text": truncate(text, 50)})
 								contentBlocks = append(contentBlocks, &types.TextBlock{Text: text})
+								if a.preDisplayFilter != nil {
+									continue
+								}
 
 								// 立即为这个text chunk生成事件并yield
 								event := &session.Event{
@@ -580,10 +652,35 @@ func (a *Agent) runModelStepStreaming(ctx context.Context, writer *stream.Writer
 		}
 	}
 
+	// 被引导消息打断：丢弃不完整的输出，带着新指令重新调用
+	if modelDone() && a.applySteering(ctx, steerPhaseModel, nil) {
+		return false, nil
+	}
 	if err != nil {
 		return false, err
 	}
 
+	// 内容安全：展示前检查，有工具调用时先单独发送过滤后的文本
+	if a.preDisplayFilter != nil {
+		resp.Message.ContentBlocks = a.filterDisplayBlocks(ctx, resp.Message.ContentBlocks)
+		if text := resp.Message.GetContent(); text != "" && len(resp.Message.ToolCalls) > 0 {
+			event := &session.Event{
+				ID:        generateEventID(),
+				Timestamp: a.createdAt,
+				AgentID:   a.id,
+				Author:    "assistant",
+				Content: types.Message{
+					Role:          types.RoleAssistant,
+					ContentBlocks: []types.ContentBlock{&types.TextBlock{Text: text}},
+				},
+				Actions: session.EventActions{},
+			}
+			if writer.Send(event, nil) {
+				return true, nil
+			}
+		}
+	}
+
 	// 3. 处理响应
 	a.mu.Lock()
 	a.messages = append(a.messages, resp.Message)
@@ -598,17 +695,35 @@ func (a *Agent) runModelStepStreaming(ctx context.Context, writer *stream.Writer
 		}
 
 		// 执行工具调用
-		if err := a.executeToolCalls(ctx, resp.Message.ToolCalls); err != nil {
+		longRunningIDs, err := a.executeToolCalls(ctx, resp.Message.ToolCalls)
+		if err != nil {
 			streamLog.Error(ctx, "tool call execution failed", map[string]any{"error": err})
 			return false, err
 		}
 
+		// 后台工具已返回任务句柄，通知调用方结果将在后续事件中送达
+		if len(longRunningIDs) > 0 {
+			event := &session.Event{
+				ID:                 generateEventID(),
+				Timestamp:          time.Now(),
+				AgentID:            a.id,
+				Author:             "assistant",
+				Content:            resp.Message,
+				LongRunningToolIDs: longRunningIDs,
+			}
+			if writer.Send(event, nil) {
+				streamLog.Debug(ctx, "client canceled stream during long-running tool event", nil)
+				return true, nil
+			}
+		}
+
 		streamLog.Debug(ctx, "tool call execution completed, continuing to next model inference", nil)
 		// 继续下一轮模型推理来处理工具执行结果
 		return false, nil
 	}
 
 	streamLog.Debug(ctx, "no tool calls, generating final response event", nil)
+	a.observeAssistantAnswer(ctx, resp.Message)
 
 	// 5. 生成事件
 	event := &session.Event{
@@ -637,75 +752,186 @@ func (a *Agent) runModelStepStreaming(ctx context.Context, writer *stream.Writer
 	return true, nil
 }
 
-// executeToolCalls 执行工具调用
-func (a *Agent) executeToolCalls(ctx context.Context, toolCalls []types.ToolCall) error {
-	results := make([]types.Message, len(toolCalls))
+// observeStreamUsage 记录流式响应中的 Token 用量，计入运行限制、用量统计和会话成本
+func (a *Agent) observeStreamUsage(ctx context.Context, usage *provider.TokenUsage) {
+	if usage == nil {
+		return
+	}
+	a.observeUsage(usage)
+	a.recordUsage(ctx, usage)
+	a.observeSessionCost(ctx, usage)
+	a.eventBus.EmitMonitor(&types.MonitorTokenUsageEvent{
+		InputTokens:  usage.InputTokens,
+		OutputTokens: usage.OutputTokens,
+		TotalTokens:  usage.InputTokens + usage.OutputTokens,
+	})
+}
+
+// executeToolCalls 执行工具调用，返回在后台运行的工具调用 ID
+func (a *Agent) executeToolCalls(ctx context.Context, toolCalls []types.ToolCall) ([]string, error) {
+	toolUses := make([]*types.ToolUseBlock, len(toolCalls))
+	results := make([]types.ContentBlock, len(toolCalls))
+	var longRunningIDs []string
+	var skipped []string
+
+	a.mu.Lock()
+	a.run.toolCalls += len(toolCalls)
+	a.mu.Unlock()
 
 	for i, call := range toolCalls {
+		tu := &types.ToolUseBlock{ID: call.ID, Name: call.Name, Input: call.Arguments}
+		toolUses[i] = tu
+
+		// 收到引导消息后，同批尚未开始的工具调用不再执行
+		if a.steeringPending() {
+			results[i] = skippedToolResult(call.ID)
+			skipped = append(skipped, call.ID)
+			continue
+		}
+
+		// 工具白名单与黑名单
+		if denied := a.checkToolFilter(tu); denied != nil {
+			results[i] = denied
+			continue
+		}
+
+		// 工作区信任检查
+		if denied := a.checkWorkspaceTrust(ctx, tu); denied != nil {
+			results[i] = denied
+			continue
+		}
+
+		// 计划模式：变更类工具调用不执行，记入待审批的计划
+		if drafted := a.planModeDraft(ctx, call); drafted != nil {
+			results[i] = drafted
+			continue
+		}
+
 		tool, ok := a.toolMap[call.Name]
 		if !ok {
-			results[i] = types.Message{
-				Role:       types.RoleTool,
-				ToolCallID: call.ID,
-				Content:    fmt.Sprintf("Error: tool '%s' not found", call.Name),
+			results[i] = &types.ToolResultBlock{
+				ToolUseID: call.ID,
+				Content:   fmt.Sprintf("Error: tool '%s' not found", call.Name),
+				IsError:   true,
 			}
 			continue
 		}
 
+		// 后台工具立即返回任务句柄
+		if lrTool, ok := tool.(tools.LongRunningTool); ok && isBackgroundTool(tool) {
+			result := &types.ToolResultBlock{ToolUseID: call.ID}
+			handle, err := a.startBackgroundTask(ctx, call.ID, call.Name, call.Arguments, lrTool)
+			if err != nil {
+				result.Content, result.IsError = fmt.Sprintf("Error: %v", err), true
+			} else {
+				result.Content = handle
+				longRunningIDs = append(longRunningIDs, call.ID)
+			}
+			results[i] = result
+			continue
+		}
+
 		// 执行工具
+		toolCtx, toolDone := a.steerToolContext(ctx, call.ID)
 		req := &tools.ExecuteRequest{
 			Tool:    tool,
 			Input:   call.Arguments,
-			Context: a.buildToolContext(ctx),
+			Context: a.buildToolContext(toolCtx),
 		}
-		execResult := a.executor.Execute(ctx, req)
+		execResult := a.executor.Execute(toolCtx, req)
+		toolDone()
 		if execResult.Error != nil {
-			results[i] = types.Message{
-				Role:       types.RoleTool,
-				ToolCallID: call.ID,
-				Content:    fmt.Sprintf("Error: %v", execResult.Error),
+			results[i] = &types.ToolResultBlock{
+				ToolUseID: call.ID,
+				Content:   fmt.Sprintf("Error: %v", execResult.Error),
+				IsError:   true,
 			}
 			continue
 		}
 
-		results[i] = types.Message{
-			Role:       types.RoleTool,
-			ToolCallID: call.ID,
-			Content:    fmt.Sprint(execResult.Output),
+		results[i] = &types.ToolResultBlock{
+			ToolUseID: call.ID,
+			Content:   fmt.Sprint(execResult.Output),
 		}
 	}
 
-	// 追加工具结果到消息历史
+	// 模型升级：连续工具失败时切换到更强的模型继续
+	a.observeToolResults(ctx, results)
+
+	// 循环保护：重复调用、来回修改或无进展时介入
+	results, loopErr := a.checkLoopGuard(ctx, toolUses, results)
+
+	// 追加工具结果到消息历史，循环保护的反思提示随后作为用户消息注入
+	messages := make([]types.Message, 0, len(results)+1)
+	var notices []types.ContentBlock
+	for _, block := range results {
+		if tr, ok := block.(*types.ToolResultBlock); ok {
+			messages = append(messages, types.Message{
+				Role:       types.RoleTool,
+				ToolCallID: tr.ToolUseID,
+				Content:    tr.Content,
+			})
+		} else {
+			notices = append(notices, block)
+		}
+	}
+	if len(notices) > 0 {
+		messages = append(messages, types.Message{Role: types.MessageRoleUser, ContentBlocks: notices})
+	}
 	a.mu.Lock()
-	a.messages = append(a.messages, results...)
+	a.messages = append(a.messages, messages...)
 	a.mu.Unlock()
 
-	return nil
+	// 执行期间收到的引导消息紧随工具结果注入
+	a.applySteering(ctx, steerPhaseTools, skipped)
+
+	// 工具结果已保存，用户在循环保护暂停时选择停止
+	if loopErr != nil {
+		return longRunningIDs, loopErr
+	}
+	return longRunningIDs, nil
 }
 
-// getToolsForProvider 获取 Provider 格式的工具定义
-func (a *Agent) getToolsForProvider() []types.ToolDefinition {
-	tools := make([]types.ToolDefinition, 0, len(a.toolMap))
-	for _, tool := range a.toolMap {
-		tools = append(tools, types.ToolDefinition{
+// providerToolSchemas 获取提供给模型的工具定义（不含使用示例）
+func (a *Agent) providerToolSchemas() []provider.ToolSchema {
+	schemas := make([]provider.ToolSchema, 0, len(a.toolMap))
+	for name, tool := range a.toolMap {
+		if !a.toolOffered(name) {
+			continue
+		}
+		schemas = append(schemas, provider.ToolSchema{
 			Name:        tool.Name(),
 			Description: tool.Description(),
 			InputSchema: tool.InputSchema(),
 		})
 	}
-	return tools
+	return schemas
 }
 
-// persistMessage 持久化消息
-func (a *Agent) persistMessage(_ context.Context, _ *types.Message) error {
-	// TODO: 实现消息持久化
-	return nil
+// persistMessage 将用户消息作为事件追加到 WithSessionService 配置的会话
+func (a *Agent) persistMessage(ctx context.Context, msg *types.Message) error {
+	if a.sessions == nil {
+		return nil
+	}
+	return a.persistEvent(ctx, &session.Event{
+		ID:        generateEventID(),
+		Timestamp: time.Now(),
+		AgentID:   a.id,
+		Author:    "user",
+		Content:   *msg,
+	})
 }
 
-// persistEvent 持久化事件
-func (a *Agent) persistEvent(_ context.Context, _ *session.Event) error {
-	// TODO: 实现事件持久化到 SessionService
-	return nil
+// persistEvent 将事件追加到 WithSessionService 配置的会话，未配置时不做任何事
+func (a *Agent) persistEvent(ctx context.Context, event *session.Event) error {
+	if a.sessions == nil {
+		return nil
+	}
+	sessionID := a.sessionID
+	if sessionID == "" {
+		sessionID = a.id
+	}
+	return a.sessions.AppendEvent(ctx, sessionID, event)
 }
 
 // truncate 截断字符串
@@ -0,0 +1,194 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/astercloud/aster/pkg/agenterr"
+	"github.com/astercloud/aster/pkg/provider"
+	"github.com/astercloud/aster/pkg/router"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+const (
+	// summaryMaxTokens 摘要调用的输出上限
+	summaryMaxTokens = 2048
+	// summaryBlockLimit 转录中单个文本或工具结果的最大字符数
+	summaryBlockLimit = 2000
+)
+
+// fileInputKeys 工具参数中表示文件路径的键
+var fileInputKeys = []string{"file_path", "path", "notebook_path"}
+
+// SummarizeOptions 会话摘要选项
+type SummarizeOptions struct {
+	// MaxMessages 只摘要最近的 N 条消息，0 表示全部
+	MaxMessages int
+	// Focus 额外的关注点，例如 "remaining work on the auth module"
+	Focus string
+}
+
+// Summarize 生成当前会话的结构化摘要（目标、决定、涉及文件、待办）
+// 路由器为 summarize 任务配置了模型时使用该模型，否则使用主模型
+func (a *Agent) Summarize(ctx context.Context, opts SummarizeOptions) (*types.ConversationSummary, error) {
+	a.mu.RLock()
+	messages := slices.Clone(a.messages)
+	a.mu.RUnlock()
+
+	prov := a.taskProvider(ctx, router.TaskSummarize)
+	if prov == nil {
+		prov = a.currentProvider()
+	}
+	return SummarizeMessages(ctx, prov, messages, opts)
+}
+
+// SummarizeStored 为已持久化的会话生成摘要，不需要创建 Agent 实例
+// 路由器为 summarize 任务配置了模型时使用该模型，否则使用 model
+func SummarizeStored(ctx context.Context, deps *Dependencies, model *types.ModelConfig, messages []types.Message, opts SummarizeOptions) (*types.ConversationSummary, error) {
+	prov := createTaskProvider(ctx, deps, router.TaskSummarize)
+	if prov == nil {
+		if model == nil {
+			return nil, errors.New("no model configured for summarization")
+		}
+		var err error
+		if prov, err = deps.ProviderFactory.Create(model); err != nil {
+			return nil, fmt.Errorf("create provider: %w", err)
+		}
+	}
+	defer func() { _ = prov.Close() }()
+	return SummarizeMessages(ctx, prov, messages, opts)
+}
+
+// SummarizeMessages 使用指定 Provider 为消息列表生成结构化摘要
+// 涉及文件以工具调用参数为准，并合并模型给出的文件
+func SummarizeMessages(ctx context.Context, prov provider.Provider, messages []types.Message, opts SummarizeOptions) (*types.ConversationSummary, error) {
+	if opts.MaxMessages > 0 && len(messages) > opts.MaxMessages {
+		messages = messages[len(messages)-opts.MaxMessages:]
+	}
+	if len(messages) == 0 {
+		return nil, errors.New("no messages to summarize")
+	}
+
+	system := summarySystemPrompt
+	if opts.Focus != "" {
+		system += "\n\nPay particular attention to: " + opts.Focus
+	}
+	resp, err := prov.Complete(ctx, []types.Message{{
+		Role:          types.MessageRoleUser,
+		ContentBlocks: []types.ContentBlock{&types.TextBlock{Text: renderTranscript(messages)}},
+	}}, &provider.StreamOptions{
+		System:      system,
+		MaxTokens:   summaryMaxTokens,
+		Temperature: 0.2,
+	})
+	if err != nil {
+		return nil, agenterr.Wrap(agenterr.CodeProviderError, fmt.Errorf("summarize: %w", err))
+	}
+
+	summary := parseSummary(extractText(resp.Message))
+	summary.FilesTouched = mergeUnique(filesTouched(messages), summary.FilesTouched)
+	summary.MessageCount = len(messages)
+	summary.Model = modelRef(prov.Config())
+	summary.GeneratedAt = time.Now()
+	return summary, nil
+}
+
+// summarySystemPrompt 要求模型输出固定结构的 JSON
+const summarySystemPrompt = `You summarize a conversation between a user and an AI agent so that the work can be resumed later in a fresh session.
+Respond with a single JSON object and nothing else, using exactly these fields:
+{
+  "overview": "one short paragraph describing what the session was about and where it stands",
+  "goals": ["what the user wants to achieve"],
+  "decisions": ["decisions made and approaches chosen or rejected, with the reason"],
+  "files_touched": ["paths of files read, created or modified"],
+  "open_todos": ["work that is still pending or was left unfinished"]
+}
+Be specific and concise. Use empty arrays when a field has nothing to report.`
+
+// renderTranscript 将消息渲染为纯文本转录，工具调用只保留名称和参数
+func renderTranscript(messages []types.Message) string {
+	var sb strings.Builder
+	for _, msg := range messages {
+		if len(msg.ContentBlocks) == 0 {
+			if msg.Content != "" {
+				fmt.Fprintf(&sb, "[%s] %s\n\n", msg.Role, truncateRunes(msg.Content, summaryBlockLimit))
+			}
+			continue
+		}
+		for _, block := range msg.ContentBlocks {
+			switch b := block.(type) {
+			case *types.TextBlock:
+				fmt.Fprintf(&sb, "[%s] %s\n\n", msg.Role, truncateRunes(b.Text, summaryBlockLimit))
+			case *types.ToolUseBlock:
+				input, _ := json.Marshal(b.Input)
+				fmt.Fprintf(&sb, "[tool call] %s %s\n\n", b.Name, truncateRunes(string(input), summaryBlockLimit))
+			case *types.ToolResultBlock:
+				status := "ok"
+				if b.IsError {
+					status = "error"
+				}
+				fmt.Fprintf(&sb, "[tool result: %s] %s\n\n", status, truncateRunes(b.Content, summaryBlockLimit))
+			}
+		}
+	}
+	return sb.String()
+}
+
+// parseSummary 解析模型输出；无法解析为 JSON 时整段文本作为概述
+func parseSummary(text string) *types.ConversationSummary {
+	summary := &types.ConversationSummary{}
+	start, end := strings.Index(text, "{"), strings.LastIndex(text, "}")
+	if start >= 0 && end > start {
+		if err := json.Unmarshal([]byte(text[start:end+1]), summary); err == nil {
+			return summary
+		}
+	}
+	return &types.ConversationSummary{Overview: strings.TrimSpace(text)}
+}
+
+// filesTouched 按首次出现顺序收集工具调用涉及的文件
+func filesTouched(messages []types.Message) []string {
+	var files []string
+	for _, msg := range messages {
+		for _, block := range msg.ContentBlocks {
+			tu, ok := block.(*types.ToolUseBlock)
+			if !ok {
+				continue
+			}
+			for _, key := range fileInputKeys {
+				if path, _ := tu.Input[key].(string); path != "" {
+					files = append(files, path)
+					break
+				}
+			}
+		}
+	}
+	return mergeUnique(files, nil)
+}
+
+// mergeUnique 合并两个列表并去重，保持顺序
+func mergeUnique(a, b []string) []string {
+	seen := make(map[string]struct{}, len(a)+len(b))
+	merged := make([]string, 0, len(a)+len(b))
+	for _, item := range slices.Concat(a, b) {
+		if _, ok := seen[item]; ok || item == "" {
+			continue
+		}
+		seen[item] = struct{}{}
+		merged = append(merged, item)
+	}
+	return merged
+}
+
+// truncateRunes 按字符截断文本
+func truncateRunes(text string, limit int) string {
+	if r := []rune(text); len(r) > limit {
+		return string(r[:limit]) + "..."
+	}
+	return text
+}
@@ -0,0 +1,95 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/astercloud/aster/pkg/provider"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+func summaryProvider(reply string, gotSystem *string) *MockProvider {
+	return &MockProvider{
+		name: "summarizer",
+		completeFunc: func(_ context.Context, _ []types.Message, opts *provider.StreamOptions) (*provider.CompleteResponse, error) {
+			if gotSystem != nil {
+				*gotSystem = opts.System
+			}
+			return &provider.CompleteResponse{Message: types.Message{
+				Role:          types.MessageRoleAssistant,
+				ContentBlocks: []types.ContentBlock{&types.TextBlock{Text: reply}},
+			}}, nil
+		},
+	}
+}
+
+func TestSummarizeMessages(t *testing.T) {
+	messages := []types.Message{
+		{Role: types.MessageRoleUser, Content: "Add retries to the HTTP client"},
+		{Role: types.MessageRoleAssistant, ContentBlocks: []types.ContentBlock{
+			&types.ToolUseBlock{ID: "1", Name: "Read", Input: map[string]any{"file_path": "client/http.go"}},
+			&types.ToolUseBlock{ID: "2", Name: "Edit", Input: map[string]any{"file_path": "client/http.go"}},
+		}},
+		{Role: types.MessageRoleAssistant, Content: "Added exponential backoff; tests still pending."},
+	}
+	reply := "```json\n" + `{"overview":"Adding retries.","goals":["retry failed requests"],` +
+		`"decisions":["use exponential backoff"],"files_touched":["client/http_test.go"],"open_todos":["write tests"]}` + "\n```"
+
+	var system string
+	summary, err := SummarizeMessages(context.Background(), summaryProvider(reply, &system), messages, SummarizeOptions{Focus: "testing"})
+	if err != nil {
+		t.Fatalf("SummarizeMessages() error = %v", err)
+	}
+	if summary.Overview != "Adding retries." || len(summary.Goals) != 1 || summary.OpenTODOs[0] != "write tests" {
+		t.Errorf("unexpected summary %+v", summary)
+	}
+	// 工具调用中的文件在前，模型给出的文件合并去重
+	if got := strings.Join(summary.FilesTouched, ","); got != "client/http.go,client/http_test.go" {
+		t.Errorf("files touched = %q", got)
+	}
+	if summary.MessageCount != 3 || summary.Model != "mock/summarizer" {
+		t.Errorf("message count = %d, model = %q", summary.MessageCount, summary.Model)
+	}
+	if !strings.Contains(system, "Pay particular attention to: testing") {
+		t.Errorf("focus missing from system prompt")
+	}
+
+	// 非 JSON 输出退回为概述
+	summary, err = SummarizeMessages(context.Background(), summaryProvider("Work on retries.", nil), messages, SummarizeOptions{MaxMessages: 1})
+	if err != nil || summary.Overview != "Work on retries." || summary.MessageCount != 1 || len(summary.FilesTouched) != 0 {
+		t.Errorf("fallback summary = %+v (err=%v)", summary, err)
+	}
+
+	if _, err := SummarizeMessages(context.Background(), summaryProvider("", nil), nil, SummarizeOptions{}); err == nil {
+		t.Error("expected error for empty conversation")
+	}
+}
+
+func TestSeedSummaryInSystemPrompt(t *testing.T) {
+	deps := setupTestDeps(t)
+	ag, err := Create(context.Background(), &types.AgentConfig{
+		TemplateID: "test-template",
+		ModelConfig: &types.ModelConfig{
+			Provider: "anthropic",
+			Model:    "claude-sonnet-4-5",
+			APIKey:   "test-key",
+		},
+		Sandbox: &types.SandboxConfig{Kind: types.SandboxKindMock, WorkDir: "/tmp/test"},
+		SeedSummary: &types.ConversationSummary{
+			Overview:  "Adding retries to the HTTP client.",
+			OpenTODOs: []string{"write tests"},
+		},
+	}, deps)
+	if err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+	defer func() { _ = ag.Close() }()
+
+	prompt := ag.template.SystemPrompt
+	for _, want := range []string{"<conversation-summary>", "Adding retries to the HTTP client.", "Open TODOs:\n- write tests"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("system prompt missing %q", want)
+		}
+	}
+}
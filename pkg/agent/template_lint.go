@@ -0,0 +1,221 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"runtime"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	astContext "github.com/astercloud/aster/pkg/context"
+	"github.com/astercloud/aster/pkg/permission"
+	"github.com/astercloud/aster/pkg/tools"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+// sessionPromptModules 依赖会话状态（Room、Workflow、恢复的会话等）注入的内置模块
+var sessionPromptModules = []string{
+	"collaboration", "workflow", "custom_instructions", "session_summary", "pinned_context", "context_window",
+}
+
+// TemplateLintOptions 模板检查配置
+type TemplateLintOptions struct {
+	// ToolRegistry 用于校验工具引用，为空时跳过工具相关检查
+	ToolRegistry *tools.Registry
+	// PromptModules 运行时通过 Dependencies 注册的额外模块
+	PromptModules []PromptModule
+	// Metadata 估算 token 时使用的 Agent 元数据，决定 enable_* 类模块是否注入
+	Metadata map[string]any
+}
+
+// LintTemplate 检查模板：工具引用是否已注册、禁用或配置的 Prompt 模块是否可达、
+// 是否缺少权限配置，并估算基础 System Prompt 与工具定义的 token 数
+func LintTemplate(t *types.AgentTemplateDefinition, opts TemplateLintOptions) *types.LintReport {
+	report := &types.LintReport{Name: t.ID}
+	if err := ValidateTemplate(t); err != nil {
+		report.Add(types.LintError, "invalid_template", "%v", err)
+		return report
+	}
+	if strings.TrimSpace(t.SystemPrompt) == "" {
+		report.Add(types.LintWarning, "empty_system_prompt", "system_prompt is empty")
+	}
+
+	toolMap := lintTemplateTools(t, opts.ToolRegistry, report)
+	if opts.ToolRegistry != nil {
+		lintToolPatterns(report, "allowed_tools", t.AllowedTools, opts.ToolRegistry)
+		lintToolPatterns(report, "disallowed_tools", t.DisallowedTools, opts.ToolRegistry)
+		if t.Permission != nil {
+			lintToolPatterns(report, "permission.allow", t.Permission.Allow, opts.ToolRegistry)
+			lintToolPatterns(report, "permission.deny", t.Permission.Deny, opts.ToolRegistry)
+			lintToolPatterns(report, "permission.ask", t.Permission.Ask, opts.ToolRegistry)
+		}
+	}
+	lintPermission(report, t, toolMap)
+
+	modules := append(builtinPromptModules(t), opts.PromptModules...)
+	lintPromptModules(report, t, modules, toolMap)
+
+	counter := astContext.NewSimpleTokenCounter(astContext.DefaultConfig)
+	builder := NewPromptBuilder()
+	for _, module := range modules {
+		builder.AddModule(module)
+	}
+	prompt, err := builder.Build(&PromptContext{
+		Template:    t,
+		Environment: &EnvironmentInfo{WorkingDir: ".", Platform: runtime.GOOS, Date: time.Now()},
+		Sandbox:     &SandboxInfo{Kind: types.SandboxKindLocal, WorkDir: "."},
+		Tools:       toolMap,
+		Metadata:    opts.Metadata,
+	})
+	if err != nil {
+		report.Add(types.LintError, "prompt_build_failed", "%v", err)
+	} else {
+		report.PromptTokens, _ = counter.Count(context.Background(), prompt)
+	}
+	for _, tool := range toolMap {
+		schema, _ := json.Marshal(tool.InputSchema())
+		n, _ := counter.Count(context.Background(), tool.Name()+"\n"+tool.Description()+"\n"+tool.Prompt()+"\n"+string(schema))
+		report.ToolTokens += n
+	}
+	return report
+}
+
+// lintTemplateTools 校验模板工具列表并创建已注册的工具
+func lintTemplateTools(t *types.AgentTemplateDefinition, registry *tools.Registry, report *types.LintReport) map[string]tools.Tool {
+	if registry == nil {
+		return nil
+	}
+	toolMap := make(map[string]tools.Tool)
+	var names []string
+	switch v := t.Tools.(type) {
+	case []string:
+		names = v
+	case []any:
+		for _, name := range v {
+			names = append(names, name.(string))
+		}
+	case string:
+		report.Add(types.LintInfo, "all_tools", "tools is \"*\": all %d registered tools are enabled", len(registry.List()))
+		names = registry.List()
+	}
+
+	seen := make(map[string]bool)
+	for _, name := range names {
+		if seen[name] {
+			report.Add(types.LintWarning, "duplicate_tool", "tool %q is listed more than once", name)
+			continue
+		}
+		seen[name] = true
+		if !registry.Has(name) {
+			report.Add(types.LintError, "unknown_tool", "tool %q is not registered", name)
+			continue
+		}
+		tool, err := registry.Create(name, nil)
+		if err != nil {
+			report.Add(types.LintError, "tool_create_failed", "create tool %q: %v", name, err)
+			continue
+		}
+		toolMap[name] = tool
+	}
+	return toolMap
+}
+
+// lintToolPatterns 校验工具匹配模式，模式不匹配任何已注册工具时告警
+func lintToolPatterns(report *types.LintReport, field string, patterns []string, registry *tools.Registry) {
+	registered := registry.List()
+	for _, s := range patterns {
+		p, err := permission.ParseToolPattern(s)
+		if err != nil {
+			report.Add(types.LintError, "invalid_tool_pattern", "%s: %v", field, err)
+			continue
+		}
+		if !slices.ContainsFunc(registered, p.MatchName) {
+			report.Add(types.LintWarning, "unknown_tool_pattern", "%s: %q matches no registered tool", field, s)
+		}
+	}
+}
+
+// lintPermission 启用了会修改状态的工具但没有任何权限配置时告警
+func lintPermission(report *types.LintReport, t *types.AgentTemplateDefinition, toolMap map[string]tools.Tool) {
+	if t.Permission != nil {
+		switch t.Permission.Mode {
+		case types.PermissionModeAuto, types.PermissionModeApproval, types.PermissionModeAllow, types.PermissionModeSmartApprove:
+		case "":
+			report.Add(types.LintWarning, "missing_permission_mode", "permission.mode is empty")
+		default:
+			report.Add(types.LintError, "invalid_permission_mode", "unknown permission.mode %q", t.Permission.Mode)
+		}
+		return
+	}
+	if len(t.AllowedTools) > 0 || len(t.DisallowedTools) > 0 {
+		return
+	}
+	var writable []string
+	for name, tool := range toolMap {
+		if !tools.GetAnnotations(tool).ReadOnly {
+			writable = append(writable, name)
+		}
+	}
+	if len(writable) > 0 {
+		sort.Strings(writable)
+		report.Add(types.LintWarning, "missing_permission",
+			"no permission, allowed_tools or disallowed_tools configured, but tools can modify state: %s", strings.Join(writable, ", "))
+	}
+}
+
+// lintPromptModules 检查禁用列表中的未知模块，以及已配置但永远不会注入的模块；
+// toolMap 为 nil（未提供工具注册表）时跳过依赖工具的检查
+func lintPromptModules(report *types.LintReport, t *types.AgentTemplateDefinition, modules []PromptModule, toolMap map[string]tools.Tool) {
+	known := slices.Clone(sessionPromptModules)
+	for _, module := range modules {
+		known = append(known, module.Name())
+	}
+	var disabled []string
+	if t.Runtime != nil {
+		disabled = t.Runtime.DisabledPromptModules
+	}
+	for _, name := range disabled {
+		if !slices.Contains(known, name) {
+			report.Add(types.LintWarning, "unknown_module", "disabled_prompt_modules: %q is not a known prompt module", name)
+		}
+	}
+	if slices.Contains(disabled, "base") {
+		report.Add(types.LintWarning, "unreachable_module", "module \"base\" is disabled, system_prompt will never be used")
+	}
+	if t.Runtime == nil {
+		return
+	}
+
+	if manual := t.Runtime.ToolsManual; manual != nil && !slices.Contains(disabled, "tools_manual") {
+		switch manual.Mode {
+		case "", "all", "listed", "none":
+		default:
+			report.Add(types.LintError, "invalid_tools_manual", "unknown tools_manual.mode %q", manual.Mode)
+		}
+		if manual.Mode != "none" && toolMap != nil && len(toolMap) == 0 {
+			report.Add(types.LintWarning, "unreachable_module", "tools_manual is configured but the template has no tools")
+		}
+		if manual.Mode == "listed" && toolMap != nil {
+			for _, name := range manual.Include {
+				if _, ok := toolMap[name]; !ok {
+					report.Add(types.LintWarning, "unreachable_module", "tools_manual.include: %q is not one of the template tools", name)
+				}
+			}
+		}
+	} else if manual != nil {
+		report.Add(types.LintWarning, "unreachable_module", "tools_manual is configured but the module is disabled")
+	}
+
+	if todo := t.Runtime.Todo; todo != nil {
+		switch {
+		case todo.ReminderOnStart && !todo.Enabled:
+			report.Add(types.LintWarning, "unreachable_module", "todo.reminder_on_start has no effect while todo.enabled is false")
+		case todo.Enabled && todo.ReminderOnStart && slices.Contains(disabled, "todo_reminder"):
+			report.Add(types.LintWarning, "unreachable_module", "todo reminder is enabled but the todo_reminder module is disabled")
+		case todo.Enabled && todo.ReminderOnStart && toolMap != nil && toolMap["TodoWrite"] == nil:
+			report.Add(types.LintWarning, "unreachable_module", "todo reminder refers to TodoWrite, which is not one of the template tools")
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package agent
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/astercloud/aster/pkg/tools"
+	"github.com/astercloud/aster/pkg/tools/builtin"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+func lintCodes(report *types.LintReport, severity types.LintSeverity) []string {
+	var codes []string
+	for _, issue := range report.Issues {
+		if issue.Severity == severity {
+			codes = append(codes, issue.Code)
+		}
+	}
+	return codes
+}
+
+func TestLintTemplate(t *testing.T) {
+	registry := tools.NewRegistry()
+	builtin.RegisterAll(registry)
+	opts := TemplateLintOptions{ToolRegistry: registry}
+
+	clean := &types.AgentTemplateDefinition{
+		ID:           "reader",
+		SystemPrompt: "You answer questions about the code base.",
+		Tools:        []string{"Read", "Grep"},
+		Runtime:      &types.AgentTemplateRuntime{Todo: &types.TodoConfig{Enabled: true}},
+	}
+	report := LintTemplate(clean, opts)
+	if len(report.Issues) != 0 {
+		t.Errorf("clean template issues = %v", report.Issues)
+	}
+	if report.PromptTokens == 0 || report.ToolTokens == 0 {
+		t.Errorf("tokens = %d prompt, %d tools", report.PromptTokens, report.ToolTokens)
+	}
+
+	broken := &types.AgentTemplateDefinition{
+		ID:           "coder",
+		SystemPrompt: "You write code.",
+		Tools:        []any{"Read", "Write", "Bash", "Teleport"},
+		AllowedTools: []string{"Bash(go test:*)", "Deploy"},
+		Runtime: &types.AgentTemplateRuntime{
+			DisabledPromptModules: []string{"git_safety", "tool_manual"},
+			ToolsManual:           &types.ToolsManualConfig{Mode: "listed", Include: []string{"Read", "Grep"}},
+			Todo:                  &types.TodoConfig{Enabled: true, ReminderOnStart: true},
+		},
+	}
+	report = LintTemplate(broken, opts)
+	if got := lintCodes(report, types.LintError); !slices.Equal(got, []string{"unknown_tool"}) {
+		t.Errorf("errors = %v", report.Issues)
+	}
+	warnings := lintCodes(report, types.LintWarning)
+	for _, code := range []string{"unknown_tool_pattern", "unknown_module", "unreachable_module"} {
+		if !slices.Contains(warnings, code) {
+			t.Errorf("missing warning %s in %v", code, report.Issues)
+		}
+	}
+	// 配置了 allowed_tools 时不再提示缺少权限配置
+	if slices.Contains(warnings, "missing_permission") {
+		t.Errorf("unexpected missing_permission: %v", report.Issues)
+	}
+	if n := report.Count(types.LintWarning); n != 4 {
+		t.Errorf("warnings = %d, want 4 (pattern, module, include Grep, TodoWrite): %v", n, report.Issues)
+	}
+
+	broken.AllowedTools = nil
+	report = LintTemplate(broken, opts)
+	if !slices.Contains(lintCodes(report, types.LintWarning), "missing_permission") {
+		t.Errorf("missing_permission not reported: %v", report.Issues)
+	}
+
+	disabled := &types.AgentTemplateDefinition{
+		ID:           "quiet",
+		SystemPrompt: "You write code.",
+		Tools:        []string{"Read"},
+		Runtime:      &types.AgentTemplateRuntime{DisabledPromptModules: []string{"base"}},
+	}
+	if report := LintTemplate(disabled, opts); !slices.Contains(lintCodes(report, types.LintWarning), "unreachable_module") {
+		t.Errorf("disabled base not reported: %v", report.Issues)
+	}
+
+	invalid := LintTemplate(&types.AgentTemplateDefinition{ID: "bad id", Tools: "all"}, opts)
+	if got := lintCodes(invalid, types.LintError); !slices.Equal(got, []string{"invalid_template"}) {
+		t.Errorf("invalid template issues = %v", invalid.Issues)
+	}
+}
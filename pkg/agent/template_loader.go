@@ -0,0 +1,243 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/astercloud/aster/pkg/types"
+	"github.com/fsnotify/fsnotify"
+	"gopkg.in/yaml.v3"
+)
+
+// TemplateVersionSeparator 带版本模板 ID 的分隔符，例如 "assistant@2"
+const TemplateVersionSeparator = "@"
+
+// templateReloadDelay 模板文件变更后等待的时间，合并编辑器保存时的多次写入
+const templateReloadDelay = 200 * time.Millisecond
+
+var templateIDPattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]{0,127}$`)
+
+// ValidateTemplate 校验模板定义
+func ValidateTemplate(t *types.AgentTemplateDefinition) error {
+	if !templateIDPattern.MatchString(t.ID) {
+		return fmt.Errorf("invalid template id %q", t.ID)
+	}
+	if strings.Contains(t.Version, TemplateVersionSeparator) || strings.ContainsAny(t.Version, `/\`) {
+		return fmt.Errorf("invalid template version %q", t.Version)
+	}
+	switch tools := t.Tools.(type) {
+	case nil, []string:
+	case string:
+		if tools != "*" {
+			return fmt.Errorf("template %s: tools must be a list or \"*\"", t.ID)
+		}
+	case []any:
+		for _, name := range tools {
+			if _, ok := name.(string); !ok {
+				return fmt.Errorf("template %s: tool names must be strings", t.ID)
+			}
+		}
+	default:
+		return fmt.Errorf("template %s: tools must be a list or \"*\"", t.ID)
+	}
+	return nil
+}
+
+// ParseTemplate 解析 YAML 或 JSON 模板定义，YAML 字段名与 JSON 一致
+func ParseTemplate(data []byte, ext string) (*types.AgentTemplateDefinition, error) {
+	if ext != ".json" {
+		var doc any
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return nil, fmt.Errorf("parse yaml: %w", err)
+		}
+		var err error
+		if data, err = json.Marshal(doc); err != nil {
+			return nil, fmt.Errorf("convert yaml: %w", err)
+		}
+	}
+	var t types.AgentTemplateDefinition
+	if err := json.Unmarshal(data, &t); err != nil {
+		return nil, fmt.Errorf("parse template: %w", err)
+	}
+	if err := ValidateTemplate(&t); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// isTemplateFile 是否为模板目录中识别的文件
+func isTemplateFile(name string) bool {
+	if strings.HasPrefix(filepath.Base(name), ".") {
+		return false
+	}
+	switch filepath.Ext(name) {
+	case ".yaml", ".yml", ".json":
+		return true
+	}
+	return false
+}
+
+// LoadDir 加载目录中的模板文件（.yaml/.yml/.json），返回加载的模板数
+// 可重复调用：之前从该目录加载、文件已删除的模板会被移除；
+// 解析失败的文件保留其上一次成功加载的模板，错误合并返回
+func (tr *TemplateRegistry) LoadDir(dir string) (int, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return 0, fmt.Errorf("read templates dir: %w", err)
+	}
+
+	var errs []error
+	loaded := make(map[string]*types.AgentTemplateDefinition)
+	paths := make(map[string]string)
+	for _, entry := range entries {
+		if entry.IsDir() || !isTemplateFile(entry.Name()) {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", entry.Name(), err))
+			continue
+		}
+		t, err := ParseTemplate(data, filepath.Ext(path))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", entry.Name(), err))
+			continue
+		}
+		if prev, dup := paths[t.ID]; dup {
+			errs = append(errs, fmt.Errorf("%s: template %s already defined in %s", entry.Name(), t.ID, filepath.Base(prev)))
+			continue
+		}
+		loaded[t.ID] = t
+		paths[t.ID] = path
+	}
+
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	for id, path := range tr.files {
+		if filepath.Dir(path) != filepath.Clean(dir) {
+			continue
+		}
+		if _, ok := loaded[id]; ok {
+			continue
+		}
+		if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+			tr.remove(id)
+		}
+	}
+	for id, t := range loaded {
+		tr.register(t)
+		tr.files[id] = paths[id]
+	}
+	return len(loaded), errors.Join(errs...)
+}
+
+// Save 将模板写入目录并注册，已从该目录加载的模板覆盖原文件，否则写入 <id>.yaml
+func (tr *TemplateRegistry) Save(dir string, t *types.AgentTemplateDefinition) error {
+	if err := ValidateTemplate(t); err != nil {
+		return err
+	}
+	// 经 JSON 转换，使 YAML 字段名与 JSON 一致
+	raw, err := json.Marshal(t)
+	if err != nil {
+		return fmt.Errorf("marshal template: %w", err)
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return fmt.Errorf("marshal template: %w", err)
+	}
+
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	path, ok := tr.files[t.ID]
+	if !ok || filepath.Dir(path) != filepath.Clean(dir) {
+		path = filepath.Join(dir, t.ID+".yaml")
+	}
+	data := raw
+	if filepath.Ext(path) != ".json" {
+		if data, err = yaml.Marshal(doc); err != nil {
+			return fmt.Errorf("marshal template: %w", err)
+		}
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("create templates dir: %w", err)
+	}
+	tmp, err := os.CreateTemp(dir, ".template-*")
+	if err != nil {
+		return fmt.Errorf("write template: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("write template: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("write template: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("write template: %w", err)
+	}
+
+	tr.register(t)
+	tr.files[t.ID] = path
+	return nil
+}
+
+// WatchDir 监听模板目录，文件变更时重新加载，直到 ctx 结束
+// onReload 在每次重新加载后调用（可为 nil），用于记录加载数量和错误
+func (tr *TemplateRegistry) WatchDir(ctx context.Context, dir string, onReload func(loaded int, err error)) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("create template watcher: %w", err)
+	}
+	if err := watcher.Add(dir); err != nil {
+		_ = watcher.Close()
+		return fmt.Errorf("watch templates dir: %w", err)
+	}
+
+	go func() {
+		defer func() { _ = watcher.Close() }()
+		timer := time.NewTimer(time.Hour)
+		timer.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if !isTemplateFile(event.Name) || event.Op == fsnotify.Chmod {
+					continue
+				}
+				timer.Reset(templateReloadDelay)
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				agentLog.Warn(ctx, "template watcher error", map[string]any{"dir": dir, "error": err.Error()})
+			case <-timer.C:
+				n, err := tr.LoadDir(dir)
+				if onReload != nil {
+					onReload(n, err)
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// Source 返回模板的来源文件，未从模板目录加载时为空
+func (tr *TemplateRegistry) Source(id string) string {
+	tr.mu.RLock()
+	defer tr.mu.RUnlock()
+	return tr.files[id]
+}
@@ -0,0 +1,102 @@
+package agent
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/astercloud/aster/pkg/types"
+)
+
+func writeTemplateFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTemplateRegistry_LoadDir(t *testing.T) {
+	dir := t.TempDir()
+	writeTemplateFile(t, dir, "coder.yaml", "id: coder\nversion: \"2\"\nsystem_prompt: You write code.\nmodel: claude-sonnet-4-5\ntools: [Read, Write]\nruntime:\n  tool_timeout_ms: 5000\n")
+	writeTemplateFile(t, dir, "chat.json", `{"id":"chat","system_prompt":"You chat.","tools":"*"}`)
+	writeTemplateFile(t, dir, "notes.txt", "ignored")
+
+	tr := NewTemplateRegistry()
+	tr.Register(&types.AgentTemplateDefinition{ID: "coder", Version: "1", SystemPrompt: "old"})
+	n, err := tr.LoadDir(dir)
+	if err != nil || n != 2 {
+		t.Fatalf("LoadDir() = %d, %v", n, err)
+	}
+
+	coder, err := tr.Get("coder")
+	if err != nil || coder.Version != "2" || coder.Runtime == nil || coder.Runtime.ToolTimeoutMs != 5000 {
+		t.Fatalf("Get(coder) = %+v, %v", coder, err)
+	}
+	if old, err := tr.Get("coder@1"); err != nil || old.SystemPrompt != "old" {
+		t.Errorf("Get(coder@1) = %+v, %v", old, err)
+	}
+	if got := tr.Versions("coder"); !slices.Equal(got, []string{"1", "2"}) {
+		t.Errorf("Versions(coder) = %v", got)
+	}
+	if ids := len(tr.List()); ids != 2 {
+		t.Errorf("List() returned %d templates, want 2", ids)
+	}
+
+	// 解析失败的文件保留上一次加载的模板，删除的文件对应模板被移除
+	writeTemplateFile(t, dir, "coder.yaml", "id: coder\ntools: 42\n")
+	if err := os.Remove(filepath.Join(dir, "chat.json")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tr.LoadDir(dir); err == nil {
+		t.Error("LoadDir() with invalid file should return an error")
+	}
+	if coder, _ := tr.Get("coder"); coder == nil || coder.SystemPrompt != "You write code." {
+		t.Errorf("coder after failed reload = %+v", coder)
+	}
+	if _, err := tr.Get("chat"); err == nil {
+		t.Error("chat should be removed after its file is deleted")
+	}
+}
+
+func TestTemplateRegistry_SaveAndWatch(t *testing.T) {
+	dir := t.TempDir()
+	tr := NewTemplateRegistry()
+
+	reloaded := make(chan int, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := tr.WatchDir(ctx, dir, func(n int, err error) { reloaded <- n }); err != nil {
+		t.Fatalf("WatchDir() error = %v", err)
+	}
+
+	if err := tr.Save(dir, &types.AgentTemplateDefinition{ID: "review", SystemPrompt: "Review code.", Tools: []string{"Read"}}); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if src := tr.Source("review"); src != filepath.Join(dir, "review.yaml") {
+		t.Errorf("Source(review) = %q", src)
+	}
+	// 保存的文件可以重新加载
+	other := NewTemplateRegistry()
+	if _, err := other.LoadDir(dir); err != nil {
+		t.Fatalf("LoadDir() after Save error = %v", err)
+	}
+	if got, err := other.Get("review"); err != nil || got.SystemPrompt != "Review code." {
+		t.Errorf("reloaded review = %+v, %v", got, err)
+	}
+
+	writeTemplateFile(t, dir, "triage.yml", "id: triage\nsystem_prompt: Triage issues.\n")
+	deadline := time.After(5 * time.Second)
+	for {
+		if _, err := tr.Get("triage"); err == nil {
+			break
+		}
+		select {
+		case <-reloaded:
+		case <-deadline:
+			t.Fatal("triage template was not hot reloaded")
+		}
+	}
+}
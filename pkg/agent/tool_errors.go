@@ -0,0 +1,38 @@
+package agent
+
+import (
+	"encoding/json"
+	"maps"
+
+	"github.com/astercloud/aster/pkg/agenterr"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+// toolFailure 构造失败的工具结果，错误码随结果返回给模型
+func toolFailure(toolUseID string, err *agenterr.Error, extra map[string]any) *types.ToolResultBlock {
+	payload := map[string]any{"ok": false, "error": err.Error(), "code": err.Code}
+	maps.Copy(payload, extra)
+	content, _ := json.Marshal(payload)
+	return &types.ToolResultBlock{
+		ToolUseID: toolUseID,
+		Content:   string(content),
+		IsError:   true,
+	}
+}
+
+// emitToolError 发送带错误码的工具失败事件
+func (a *Agent) emitToolError(tu *types.ToolUseBlock, err *agenterr.Error) {
+	a.eventBus.EmitProgress(&types.ProgressToolErrorEvent{
+		Call: types.ToolCallSnapshot{
+			ID:        tu.ID,
+			Name:      tu.Name,
+			State:     types.ToolCallStateFailed,
+			Arguments: tu.Input,
+			Error:     err.Error(),
+			ErrorCode: err.Code,
+		},
+		Error: err.Error(),
+		Code:  err.Code,
+		Class: err.Class(),
+	})
+}
@@ -0,0 +1,79 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/astercloud/aster/pkg/agenterr"
+	"github.com/astercloud/aster/pkg/provider"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+func TestToolFailure(t *testing.T) {
+	block := toolFailure("call-1", agenterr.New(agenterr.CodePlanModeRestricted, "Write is not allowed in plan mode"),
+		map[string]any{"plan_mode": true})
+
+	if !block.IsError || block.ToolUseID != "call-1" {
+		t.Fatalf("unexpected block %+v", block)
+	}
+	var payload map[string]any
+	if err := json.Unmarshal([]byte(block.Content), &payload); err != nil {
+		t.Fatalf("content is not JSON: %v", err)
+	}
+	if payload["ok"] != false || payload["code"] != "plan_mode_restricted" || payload["plan_mode"] != true {
+		t.Errorf("payload = %v", payload)
+	}
+}
+
+func TestRunErrorCarriesCode(t *testing.T) {
+	deps := setupTestDeps(t)
+	factory := NewMockProviderFactory()
+	factory.SetProvider("mock/failing", &MockProvider{
+		name: "failing",
+		completeFunc: func(context.Context, []types.Message, *provider.StreamOptions) (*provider.CompleteResponse, error) {
+			return nil, agenterr.New(agenterr.CodeProviderRateLimited, "rate limited")
+		},
+		capabilities: provider.ProviderCapabilities{SupportToolCalling: true, SupportSystemPrompt: true},
+	})
+	deps.ProviderFactory = factory
+
+	ag, err := Create(context.Background(), &types.AgentConfig{
+		TemplateID: "test-template",
+		ModelConfig: &types.ModelConfig{
+			Provider:      "mock",
+			Model:         "failing",
+			ExecutionMode: types.ExecutionModeNonStreaming,
+		},
+		Sandbox: &types.SandboxConfig{Kind: types.SandboxKindMock, WorkDir: "/tmp/test"},
+	}, deps)
+	if err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+	defer func() { _ = ag.Close() }()
+
+	ch := ag.Subscribe([]types.AgentChannel{types.ChannelProgress}, nil)
+	if err := ag.Send(context.Background(), "hello"); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	deadline := time.After(5 * time.Second)
+	for {
+		select {
+		case env := <-ch:
+			done, ok := env.Event.(*types.ProgressDoneEvent)
+			if !ok {
+				continue
+			}
+			if done.Reason != types.TerminationError || done.Error == nil {
+				t.Fatalf("done = %+v, want error termination", done)
+			}
+			if done.Error.Code != agenterr.CodeProviderRateLimited || !done.Error.Retryable() {
+				t.Errorf("error = %s (%s), want provider_rate_limited", done.Error, done.Error.Code)
+			}
+			return
+		case <-deadline:
+			t.Fatal("timed out waiting for done event")
+		}
+	}
+}
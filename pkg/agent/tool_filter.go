@@ -0,0 +1,84 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/astercloud/aster/pkg/agenterr"
+	"github.com/astercloud/aster/pkg/permission"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+// newToolFilter 合并模板与配置的工具白名单、黑名单：配置的白名单替换模板的，黑名单合并
+// 合并结果作为不可变底线，运行时的 SetToolFilter 只能在其上收紧
+func newToolFilter(template *types.AgentTemplateDefinition, config *types.AgentConfig) (*permission.ToolFilter, error) {
+	allowed := config.AllowedTools
+	var disallowed []string
+	if template != nil {
+		if allowed == nil {
+			allowed = template.AllowedTools
+		}
+		disallowed = append(disallowed, template.DisallowedTools...)
+	}
+	disallowed = append(disallowed, config.DisallowedTools...)
+
+	filter, err := permission.NewToolFilter(allowed, disallowed)
+	if err != nil {
+		return nil, fmt.Errorf("tool filter: %w", err)
+	}
+	return permission.NewLayeredToolFilter(filter), nil
+}
+
+// checkToolFilter 在权限检查之前执行工具白名单、黑名单，拒绝时返回工具结果
+func (a *Agent) checkToolFilter(tu *types.ToolUseBlock) *types.ToolResultBlock {
+	if a.toolFilter == nil {
+		return nil
+	}
+	allowed, reason := a.toolFilter.Check(tu.Name, tu.Input)
+	if allowed {
+		return nil
+	}
+	a.auditPermission(tu, "denied", "tool_filter", reason)
+	toolErr := agenterr.New(agenterr.CodePermissionDenied, fmt.Sprintf("Tool %s is not allowed in this session: %s", tu.Name, reason))
+	a.emitToolError(tu, toolErr)
+	return toolFailure(tu.ID, toolErr, nil)
+}
+
+// toolOffered 工具是否出现在发给模型的工具列表中
+func (a *Agent) toolOffered(name string) bool {
+	return a.toolFilter == nil || a.toolFilter.Offers(name)
+}
+
+// ToolFilter 返回当前的工具白名单与黑名单
+func (a *Agent) ToolFilter() (allowed, disallowed []string) {
+	if a.toolFilter == nil {
+		return nil, nil
+	}
+	return a.toolFilter.Lists()
+}
+
+// SetToolFilter 运行时替换工具白名单与黑名单，从下一次模型调用起生效
+// 模板与配置的黑名单、白名单仍然生效，无法通过运行时修改重新启用被禁用的工具
+func (a *Agent) SetToolFilter(allowed, disallowed []string) error {
+	if a.toolFilter == nil {
+		filter, err := permission.NewToolFilter(allowed, disallowed)
+		if err != nil {
+			return err
+		}
+		a.toolFilter = filter
+	} else if err := a.toolFilter.Set(allowed, disallowed); err != nil {
+		return err
+	}
+
+	a.contextUsage.invalidateTools()
+
+	allowed, disallowed = a.toolFilter.Lists()
+	a.eventBus.EmitControl(&types.ControlToolFilterUpdatedEvent{
+		AllowedTools:    allowed,
+		DisallowedTools: disallowed,
+	})
+	agentLog.Info(context.Background(), "tool filter updated", map[string]any{
+		"agent_id": a.id, "allowed": allowed, "disallowed": disallowed,
+	})
+	return nil
+}
@@ -0,0 +1,108 @@
+package agent
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/astercloud/aster/pkg/provider"
+	"github.com/astercloud/aster/pkg/tools"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+func TestToolFilterBlocksDisallowedTool(t *testing.T) {
+	p := &steerProvider{script: []func(context.Context, chan<- provider.StreamChunk){
+		func(_ context.Context, ch chan<- provider.StreamChunk) {
+			ch <- provider.StreamChunk{Type: "content_block_start", Index: 0, Delta: map[string]any{
+				"type": "tool_use", "id": "call-1", "name": "Deploy", "input": map[string]any{"command": "deploy prod"},
+			}}
+			ch <- provider.StreamChunk{Type: "content_block_stop", Index: 0}
+		},
+		textReply("Deploy is disabled"),
+	}}
+	ag := newSteerTestAgent(t, p)
+	tool := &countingTool{BaseTool: tools.BaseTool{ToolName: "Deploy", ToolDescription: "deploys"}}
+	ag.toolMap["Deploy"] = tool
+	ag.permissionInspector = nil // 测试工具无需审批
+	events := ag.Subscribe([]types.AgentChannel{types.ChannelProgress, types.ChannelControl}, nil)
+
+	if err := ag.SetToolFilter(nil, []string{"Deploy(deploy prod*)"}); err != nil {
+		t.Fatal(err)
+	}
+	// 只限制参数的模式不隐藏工具
+	if !ag.toolOffered("Deploy") {
+		t.Error("Deploy should still be offered to the model")
+	}
+
+	if err := ag.Send(context.Background(), "Ship it"); err != nil {
+		t.Fatal(err)
+	}
+	collectSteerEvents(t, events)
+
+	if tool.calls != 0 {
+		t.Fatalf("disallowed tool executed: calls = %d", tool.calls)
+	}
+	req := p.request(1)
+	if r, ok := req[len(req)-1].ContentBlocks[0].(*types.ToolResultBlock); !ok || !r.IsError || !strings.Contains(r.Content, "not allowed") {
+		t.Fatalf("tool result = %+v", req[len(req)-1].ContentBlocks)
+	}
+
+	if err := ag.SetToolFilter(nil, []string{"Deploy"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, schema := range ag.buildToolSchemas() {
+		if schema.Name == "Deploy" {
+			t.Error("disabled tool still offered to the model")
+		}
+	}
+	if _, disallowed := ag.ToolFilter(); len(disallowed) != 1 || disallowed[0] != "Deploy" {
+		t.Errorf("disallowed = %v", disallowed)
+	}
+	if err := ag.SetToolFilter([]string{"Bash(npm run"}, nil); err == nil {
+		t.Error("expected error for invalid pattern")
+	}
+}
+
+func TestSetToolFilterKeepsTemplateDenies(t *testing.T) {
+	deps := setupTestDeps(t)
+	deps.TemplateRegistry.Register(&types.AgentTemplateDefinition{
+		ID:              "no-bash",
+		SystemPrompt:    "You are a test assistant.",
+		Tools:           []any{"Read", "Write", "Bash"},
+		AllowedTools:    []string{"Read", "Bash"},
+		DisallowedTools: []string{"Bash"},
+	})
+	ag, err := Create(context.Background(), &types.AgentConfig{
+		TemplateID:  "no-bash",
+		ModelConfig: &types.ModelConfig{Provider: "anthropic", Model: "claude-sonnet-4-5", APIKey: "test-key"},
+		Sandbox:     &types.SandboxConfig{Kind: types.SandboxKindMock, WorkDir: "/tmp/test"},
+	}, deps)
+	if err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+	t.Cleanup(func() { _ = ag.Close() })
+
+	// 清空运行时列表、显式放开 Write 都不能绕过模板
+	if err := ag.SetToolFilter([]string{"Bash", "Write"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	for tool, want := range map[string]bool{"Bash": false, "Write": false, "Read": false} {
+		allowed, _ := ag.toolFilter.Check(tool, map[string]any{"command": "ls", "file_path": "a.txt"})
+		if allowed != want || ag.toolOffered(tool) != want {
+			t.Errorf("%s: allowed = %v, offered = %v, want %v", tool, allowed, ag.toolOffered(tool), want)
+		}
+	}
+	if err := ag.SetToolFilter(nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if allowed, _ := ag.toolFilter.Check("Bash", map[string]any{"command": "ls"}); allowed {
+		t.Error("template-denied Bash re-enabled by clearing the runtime filter")
+	}
+	if !ag.toolOffered("Read") {
+		t.Error("Read should still be offered")
+	}
+	if _, disallowed := ag.ToolFilter(); !slices.Contains(disallowed, "Bash") {
+		t.Errorf("disallowed = %v, want template deny included", disallowed)
+	}
+}
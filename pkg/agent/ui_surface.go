@@ -0,0 +1,44 @@
+package agent
+
+import (
+	"context"
+
+	"github.com/astercloud/aster/pkg/types"
+	"github.com/astercloud/aster/pkg/uiproto"
+)
+
+// newUISurfaceManager 创建 Agent 的 Surface 管理器
+// 用户动作作为 ControlUIActionEvent 发到 Agent 的事件总线
+func (a *Agent) newUISurfaceManager() *uiproto.SurfaceManager {
+	catalogs := uiproto.DefaultCatalogs
+	if a.deps != nil && a.deps.UICatalogs != nil {
+		catalogs = a.deps.UICatalogs
+	}
+	return uiproto.NewSurfaceManager(&uiproto.ManagerConfig{
+		OnAction: uiproto.EventBusActionHandler(a.eventBus),
+		Catalogs: catalogs,
+	})
+}
+
+// UISurfaces 返回 RenderUI 工具创建的 Surface 管理器
+func (a *Agent) UISurfaces() *uiproto.SurfaceManager {
+	return a.uiSurfaces
+}
+
+// clearUISurfaces 删除所有 Surface，并通知渲染端移除
+func (a *Agent) clearUISurfaces() {
+	if a.uiSurfaces == nil {
+		return
+	}
+	for _, id := range a.uiSurfaces.SurfaceIDs() {
+		event := &types.ProgressUIDeleteSurfaceEvent{SurfaceID: id}
+		_, _ = a.uiSurfaces.ApplyEvent(event)
+		a.eventBus.EmitProgress(event)
+	}
+}
+
+// HandleUIMessage 处理前端上报的 UI 客户端消息
+// UserAction 校验通过后唤醒等待中的 RenderUI 调用，并以 ControlUIActionEvent 发出
+func (a *Agent) HandleUIMessage(ctx context.Context, msg *types.ClientMessage) error {
+	return a.uiSurfaces.HandleClientMessage(ctx, msg)
+}
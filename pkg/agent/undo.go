@@ -0,0 +1,335 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/astercloud/aster/pkg/patch"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+// maxTurnCheckpoints 保留文件检查点的最近轮数
+const maxTurnCheckpoints = 20
+
+// ErrNothingToUndo 没有可撤销的对话轮次
+var ErrNothingToUndo = errors.New("nothing to undo")
+
+// ErrTurnNotFound 要撤销的轮次已不在上下文中（如已被压缩）
+var ErrTurnNotFound = errors.New("turn not found in context")
+
+// UndoResult 撤销一轮对话的结果
+type UndoResult struct {
+	// Prompt 被撤销的用户消息文本，可回填到输入框
+	Prompt string `json:"prompt"`
+	// RemovedMessages 移除的消息数（用户消息、助手回复和工具结果）
+	RemovedMessages int `json:"removed_messages"`
+	// RestoredFiles 恢复到本轮开始前内容的文件
+	RestoredFiles []string `json:"restored_files,omitempty"`
+	// DeletedFiles 本轮新建、撤销时删除的文件
+	DeletedFiles []string `json:"deleted_files,omitempty"`
+	// FileErrors 恢复失败的文件及原因
+	FileErrors map[string]string `json:"file_errors,omitempty"`
+}
+
+// fileSnapshot 文件在本轮首次被工具修改前的内容
+type fileSnapshot struct {
+	path    string // 工具参数中的路径，FS 操作使用
+	content string
+	existed bool
+}
+
+// turnCheckpoint 一轮对话的检查点
+// anchor 是本轮用户消息的第一个内容块，消息被修剪后仍可按指针定位本轮起点
+type turnCheckpoint struct {
+	anchor types.ContentBlock
+	files  map[string]fileSnapshot // 绝对路径 -> 修改前内容
+	order  []string
+}
+
+// checkpointTurn 为刚追加的用户消息创建检查点，调用方需持有 a.mu
+func (a *Agent) checkpointTurn(msg types.Message) {
+	if len(msg.ContentBlocks) == 0 {
+		return
+	}
+	a.turnCheckpoints = append(a.turnCheckpoints, &turnCheckpoint{
+		anchor: msg.ContentBlocks[0],
+		files:  make(map[string]fileSnapshot),
+	})
+	if n := len(a.turnCheckpoints); n > maxTurnCheckpoints {
+		a.turnCheckpoints = a.turnCheckpoints[n-maxTurnCheckpoints:]
+	}
+}
+
+// toolWritePaths 返回工具调用将写入的文件路径
+// Bash、Rename 等无法预知写入范围的工具不记录
+func toolWritePaths(tu *types.ToolUseBlock) []string {
+	if fileWriteTools[tu.Name] {
+		if path, ok := tu.Input["file_path"].(string); ok && path != "" {
+			return []string{path}
+		}
+		return nil
+	}
+	if tu.Name != "ApplyPatch" {
+		return nil
+	}
+	text, _ := tu.Input["patch"].(string)
+	files, err := patch.Parse(text)
+	if err != nil {
+		return nil
+	}
+	var paths []string
+	for _, fd := range files {
+		for _, p := range []string{fd.OldPath, fd.NewPath} {
+			if p != "" && p != patch.DevNull {
+				paths = append(paths, p)
+			}
+		}
+	}
+	return paths
+}
+
+// checkpointToolFiles 在工具写入前保存文件内容，每轮只保存首次修改前的版本
+func (a *Agent) checkpointToolFiles(ctx context.Context, tu *types.ToolUseBlock) {
+	paths := toolWritePaths(tu)
+	if len(paths) == 0 || a.sandbox == nil {
+		return
+	}
+	fs := a.sandbox.FS()
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if len(a.turnCheckpoints) == 0 {
+		return
+	}
+	cp := a.turnCheckpoints[len(a.turnCheckpoints)-1]
+	for _, p := range paths {
+		abs := fs.Resolve(p)
+		if _, ok := cp.files[abs]; ok {
+			continue
+		}
+		content, err := fs.Read(ctx, p)
+		cp.files[abs] = fileSnapshot{path: p, content: content, existed: err == nil}
+		cp.order = append(cp.order, abs)
+	}
+}
+
+// turnStarts 返回最后 n 轮对话中最早一轮的起始消息下标、各轮检查点（从新到旧）和剩余检查点数
+// 检查点对应的用户消息已被压缩时回退为非工具结果的用户消息，此时不恢复文件。调用方需持有 a.mu
+func (a *Agent) turnStarts(n int) (start int, cps []*turnCheckpoint, keep int, found int) {
+	keep = len(a.turnCheckpoints)
+	start = len(a.messages)
+	for found < n {
+		end, next := start, -1
+		for keep > 0 && next < 0 {
+			cp := a.turnCheckpoints[keep-1]
+			keep--
+			for i := end - 1; i >= 0; i-- {
+				if blocks := a.messages[i].ContentBlocks; len(blocks) > 0 && blocks[0] == cp.anchor {
+					next = i
+					cps = append(cps, cp)
+					break
+				}
+			}
+		}
+		if next < 0 {
+			for i := end - 1; i >= 0; i-- {
+				if a.messages[i].Role == types.MessageRoleUser && !hasToolResult(a.messages[i]) {
+					next = i
+					break
+				}
+			}
+		}
+		if next < 0 {
+			break
+		}
+		start = next
+		found++
+	}
+	return start, cps, keep, found
+}
+
+// turnPrompt 返回用户消息的输入文本，跳过前面注入的文件变更通知
+func turnPrompt(msg types.Message) string {
+	for i := len(msg.ContentBlocks) - 1; i >= 0; i-- {
+		if tb, ok := msg.ContentBlocks[i].(*types.TextBlock); ok {
+			return tb.Text
+		}
+	}
+	return msg.Content
+}
+
+// UndoLastTurn 撤销最后一轮对话：从上下文和存储中移除最后一条用户消息及之后的回复，
+// 并把本轮 Write/Edit/ApplyPatch 修改过的文件恢复到本轮开始前的内容。
+// Bash 等工具的副作用无法撤销。仅在 Agent 空闲时允许调用
+func (a *Agent) UndoLastTurn(ctx context.Context) (*UndoResult, error) {
+	return a.RewindTurns(ctx, 1)
+}
+
+// RewindTurns 撤销最后 n 轮对话，效果等同于连续调用 n 次 UndoLastTurn：
+// 文件按检查点从新到旧依次恢复，最终回到第 n 轮开始前的内容。
+// 上下文中不足 n 轮（如已被压缩）时返回 ErrTurnNotFound，不做任何修改
+func (a *Agent) RewindTurns(ctx context.Context, n int) (*UndoResult, error) {
+	if n < 1 {
+		return nil, fmt.Errorf("invalid turn count: %d", n)
+	}
+	a.mu.Lock()
+	if a.state == types.AgentStateWorking {
+		a.mu.Unlock()
+		return nil, fmt.Errorf("agent %s is working", a.id)
+	}
+	start, cps, keep, found := a.turnStarts(n)
+	switch {
+	case found == 0:
+		a.mu.Unlock()
+		return nil, ErrNothingToUndo
+	case found < n:
+		a.mu.Unlock()
+		return nil, fmt.Errorf("%w: %d of %d turns available", ErrTurnNotFound, found, n)
+	}
+	a.turnCheckpoints = a.turnCheckpoints[:keep]
+
+	removed := a.messages[start:]
+	result := &UndoResult{
+		Prompt:          turnPrompt(removed[0]),
+		RemovedMessages: len(removed),
+	}
+	for _, msg := range removed {
+		for _, block := range msg.ContentBlocks {
+			if tu, ok := block.(*types.ToolUseBlock); ok {
+				delete(a.toolRecords, tu.ID)
+			}
+		}
+	}
+	a.messages = append([]types.Message{}, a.messages[:start]...)
+	a.stepCount = max(a.stepCount-len(removed), 0)
+	messages := a.messagesForStore(a.messages)
+	records := make([]types.ToolCallRecord, 0, len(a.toolRecords))
+	for _, record := range a.toolRecords {
+		records = append(records, *record)
+	}
+	a.mu.Unlock()
+	a.contextUsage.reset()
+
+	if err := a.deps.Store.SaveMessages(ctx, a.id, messages); err != nil {
+		return nil, fmt.Errorf("save messages: %w", err)
+	}
+	if err := a.deps.Store.SaveToolCallRecords(ctx, a.id, records); err != nil {
+		return nil, fmt.Errorf("save tool records: %w", err)
+	}
+
+	// 从新到旧恢复，较早一轮的快照最后写入
+	for _, cp := range cps {
+		a.restoreFiles(ctx, cp, result)
+	}
+	if len(cps) > 1 {
+		result.compactFiles()
+	}
+	agentLog.Info(ctx, "turns undone", map[string]any{
+		"agent_id":       a.id,
+		"turns":          n,
+		"removed":        result.RemovedMessages,
+		"restored_files": len(result.RestoredFiles) + len(result.DeletedFiles),
+	})
+	return result, nil
+}
+
+// compactFiles 去除多轮恢复产生的重复路径，最终被删除的文件只出现在 DeletedFiles 中
+func (r *UndoResult) compactFiles() {
+	deleted := make(map[string]bool, len(r.DeletedFiles))
+	var files []string
+	for _, path := range r.DeletedFiles {
+		if !deleted[path] {
+			deleted[path] = true
+			files = append(files, path)
+		}
+	}
+	r.DeletedFiles = files
+
+	seen := make(map[string]bool, len(r.RestoredFiles))
+	files = nil
+	for _, path := range r.RestoredFiles {
+		if !seen[path] && !deleted[path] {
+			seen[path] = true
+			files = append(files, path)
+		}
+	}
+	r.RestoredFiles = files
+}
+
+// EditMessage 把倒数第 n 轮（1 表示最后一轮）的用户消息替换为 text 并重新生成：
+// 撤销该轮及之后的对话和文件修改，再以 text 开始新一轮。
+// 撤销成功但发送失败时同时返回撤销结果和错误
+func (a *Agent) EditMessage(ctx context.Context, n int, text string) (*UndoResult, error) {
+	result, err := a.RewindTurns(ctx, n)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.Send(ctx, text); err != nil {
+		return result, fmt.Errorf("send edited message: %w", err)
+	}
+	return result, nil
+}
+
+// Regenerate 从倒数第 n 轮重新生成：撤销该轮及之后的对话和文件修改，再重新发送该轮的用户消息
+func (a *Agent) Regenerate(ctx context.Context, n int) (*UndoResult, error) {
+	result, err := a.RewindTurns(ctx, n)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.Send(ctx, result.Prompt); err != nil {
+		return result, fmt.Errorf("resend message: %w", err)
+	}
+	return result, nil
+}
+
+// restoreFiles 将检查点中的文件恢复到本轮开始前的内容，本轮新建的文件被删除
+func (a *Agent) restoreFiles(ctx context.Context, cp *turnCheckpoint, result *UndoResult) {
+	if a.sandbox == nil {
+		return
+	}
+	fs := a.sandbox.FS()
+	fail := func(path string, err error) {
+		if result.FileErrors == nil {
+			result.FileErrors = make(map[string]string)
+		}
+		result.FileErrors[path] = err.Error()
+	}
+
+	for _, path := range cp.order {
+		snap := cp.files[path]
+		// 恢复操作不作为外部变更通知，且不与工具记录的版本冲突
+		if a.fileWatches != nil {
+			a.fileWatches.Ignore(path)
+		}
+		if a.fileVersions != nil {
+			a.fileVersions.Forget(path)
+		}
+
+		if snap.existed {
+			if current, err := fs.Read(ctx, snap.path); err == nil && current == snap.content {
+				continue
+			}
+			if err := fs.Write(ctx, snap.path, snap.content); err != nil {
+				fail(path, err)
+				continue
+			}
+			result.RestoredFiles = append(result.RestoredFiles, path)
+			continue
+		}
+
+		if _, err := fs.Stat(ctx, snap.path); err != nil {
+			continue
+		}
+		if a.sandbox.Kind() != "local" {
+			fail(path, fmt.Errorf("deleting files is not supported by the %s sandbox", a.sandbox.Kind()))
+			continue
+		}
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			fail(path, err)
+			continue
+		}
+		result.DeletedFiles = append(result.DeletedFiles, path)
+	}
+}
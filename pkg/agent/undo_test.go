@@ -0,0 +1,243 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/astercloud/aster/pkg/provider"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+// newUndoTestAgent 创建每轮先写文件再回复的 Agent
+func newUndoTestAgent(t *testing.T, workDir string) *Agent {
+	t.Helper()
+	return newWriteTestAgent(t, &types.SandboxConfig{
+		Kind:           types.SandboxKindLocal,
+		WorkDir:        workDir,
+		PermissionMode: types.SandboxPermissionBypass,
+	})
+}
+
+// newWriteTestAgent 使用指定沙箱配置创建每轮先写文件再回复的 Agent
+func newWriteTestAgent(t *testing.T, sandboxConfig *types.SandboxConfig) *Agent {
+	t.Helper()
+	deps := setupTestDeps(t)
+	factory := NewMockProviderFactory()
+	factory.SetProvider("mock/undo", &MockProvider{
+		name: "undo",
+		completeFunc: func(_ context.Context, messages []types.Message, _ *provider.StreamOptions) (*provider.CompleteResponse, error) {
+			last := messages[len(messages)-1]
+			if last.Role != types.MessageRoleUser || hasToolResult(last) {
+				return &provider.CompleteResponse{Message: types.Message{
+					Role:          types.MessageRoleAssistant,
+					ContentBlocks: []types.ContentBlock{&types.TextBlock{Text: "done"}},
+				}}, nil
+			}
+			// 用户消息格式为 "<文件名>=<内容>"
+			name, content, _ := cutPrompt(turnPrompt(last))
+			return &provider.CompleteResponse{Message: types.Message{
+				Role: types.MessageRoleAssistant,
+				ContentBlocks: []types.ContentBlock{&types.ToolUseBlock{
+					ID:    "call-" + name + content,
+					Name:  "Write",
+					Input: map[string]any{"file_path": name, "content": content},
+				}},
+			}}, nil
+		},
+		capabilities: provider.ProviderCapabilities{SupportToolCalling: true, SupportSystemPrompt: true},
+	})
+	deps.ProviderFactory = factory
+	ag, err := Create(context.Background(), &types.AgentConfig{
+		TemplateID:  "test-template",
+		ModelConfig: &types.ModelConfig{Provider: "mock", Model: "undo", ExecutionMode: types.ExecutionModeNonStreaming},
+		Sandbox:     sandboxConfig,
+	}, deps)
+	if err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+	t.Cleanup(func() { _ = ag.Close() })
+	return ag
+}
+
+func cutPrompt(prompt string) (string, string, bool) {
+	for i := range prompt {
+		if prompt[i] == '=' {
+			return prompt[:i], prompt[i+1:], true
+		}
+	}
+	return prompt, "", false
+}
+
+func TestUndoLastTurn(t *testing.T) {
+	workDir := t.TempDir()
+	existing := filepath.Join(workDir, "a.txt")
+	if err := os.WriteFile(existing, []byte("original"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ag := newUndoTestAgent(t, workDir)
+	ctx := context.Background()
+
+	if _, err := ag.UndoLastTurn(ctx); !errors.Is(err, ErrNothingToUndo) {
+		t.Fatalf("UndoLastTurn() on empty conversation = %v", err)
+	}
+
+	// counts[i] 为第 i 轮开始前的消息数
+	var counts []int
+	for _, prompt := range []string{"a.txt=first", "a.txt=second", "b.txt=new"} {
+		ag.mu.RLock()
+		counts = append(counts, len(ag.messages))
+		ag.mu.RUnlock()
+		if _, err := ag.Chat(ctx, prompt); err != nil {
+			t.Fatalf("Chat(%q): %v", prompt, err)
+		}
+	}
+	if got, _ := os.ReadFile(existing); string(got) != "second" {
+		t.Fatalf("a.txt = %q", got)
+	}
+
+	// 撤销新建文件的一轮：删除文件并移除用户消息及之后的工具调用、工具结果和回复
+	result, err := ag.UndoLastTurn(ctx)
+	if err != nil {
+		t.Fatalf("UndoLastTurn: %v", err)
+	}
+	if result.Prompt != "b.txt=new" || result.RemovedMessages < 4 || len(result.DeletedFiles) != 1 {
+		t.Errorf("unexpected result: %+v", result)
+	}
+	if _, err := os.Stat(filepath.Join(workDir, "b.txt")); !os.IsNotExist(err) {
+		t.Errorf("b.txt should be deleted, stat err = %v", err)
+	}
+
+	// 撤销修改已有文件的一轮：恢复本轮开始前的内容
+	result, err = ag.UndoLastTurn(ctx)
+	if err != nil {
+		t.Fatalf("UndoLastTurn: %v", err)
+	}
+	if len(result.RestoredFiles) != 1 || result.RestoredFiles[0] != existing {
+		t.Errorf("unexpected result: %+v", result)
+	}
+	if got, _ := os.ReadFile(existing); string(got) != "first" {
+		t.Errorf("a.txt = %q, want content before the undone turn", got)
+	}
+
+	ag.mu.RLock()
+	remaining := len(ag.messages)
+	records := len(ag.toolRecords)
+	ag.mu.RUnlock()
+	if remaining != counts[1] || records != 1 {
+		t.Errorf("messages = %d, tool records = %d after undo", remaining, records)
+	}
+	stored, err := ag.deps.Store.LoadMessages(ctx, ag.ID())
+	if err != nil || len(stored) != counts[1] {
+		t.Errorf("stored messages = %d, %v", len(stored), err)
+	}
+
+	// 撤销后的新一轮可以继续写入恢复过的文件
+	if _, err := ag.Chat(ctx, "a.txt=third"); err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+	if got, _ := os.ReadFile(existing); string(got) != "third" {
+		t.Errorf("a.txt = %q", got)
+	}
+}
+
+func TestEditMessageAndRegenerate(t *testing.T) {
+	workDir := t.TempDir()
+	existing := filepath.Join(workDir, "a.txt")
+	created := filepath.Join(workDir, "c.txt")
+	if err := os.WriteFile(existing, []byte("original"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ag := newUndoTestAgent(t, workDir)
+	ctx := context.Background()
+
+	var counts []int
+	for _, prompt := range []string{"a.txt=one", "a.txt=two", "c.txt=new"} {
+		ag.mu.RLock()
+		counts = append(counts, len(ag.messages))
+		ag.mu.RUnlock()
+		if _, err := ag.Chat(ctx, prompt); err != nil {
+			t.Fatalf("Chat(%q): %v", prompt, err)
+		}
+	}
+
+	// 超出上下文的轮次不做任何修改
+	if _, err := ag.RewindTurns(ctx, 4); !errors.Is(err, ErrTurnNotFound) {
+		t.Fatalf("RewindTurns(4) = %v", err)
+	}
+	if _, err := os.Stat(created); err != nil {
+		t.Fatalf("c.txt should still exist: %v", err)
+	}
+
+	// 编辑倒数第二轮：撤销两轮的文件修改后以新消息重新开始
+	result, err := ag.EditMessage(ctx, 2, "a.txt=edited")
+	if err != nil {
+		t.Fatalf("EditMessage: %v", err)
+	}
+	if _, err := ag.waitForCompletion(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if result.Prompt != "a.txt=two" {
+		t.Errorf("Prompt = %q", result.Prompt)
+	}
+	if len(result.DeletedFiles) != 1 || result.DeletedFiles[0] != created {
+		t.Errorf("DeletedFiles = %v", result.DeletedFiles)
+	}
+	if len(result.RestoredFiles) != 1 || result.RestoredFiles[0] != existing {
+		t.Errorf("RestoredFiles = %v", result.RestoredFiles)
+	}
+	if _, err := os.Stat(created); !os.IsNotExist(err) {
+		t.Errorf("c.txt should be deleted, stat err = %v", err)
+	}
+	if got, _ := os.ReadFile(existing); string(got) != "edited" {
+		t.Errorf("a.txt = %q", got)
+	}
+
+	// 重新生成最后一轮：重新发送同一条消息，上下文中只保留新的一轮
+	result, err = ag.Regenerate(ctx, 1)
+	if err != nil {
+		t.Fatalf("Regenerate: %v", err)
+	}
+	if _, err := ag.waitForCompletion(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if result.Prompt != "a.txt=edited" {
+		t.Errorf("Prompt = %q", result.Prompt)
+	}
+	ag.mu.RLock()
+	turn := len(ag.messages) - counts[1]
+	prompt := turnPrompt(ag.messages[counts[1]])
+	ag.mu.RUnlock()
+	if turn != counts[2]-counts[1] || prompt != "a.txt=edited" {
+		t.Errorf("last turn has %d messages starting with %q", turn, prompt)
+	}
+	if got, _ := os.ReadFile(existing); string(got) != "edited" {
+		t.Errorf("a.txt = %q", got)
+	}
+}
+
+func TestToolWritePaths(t *testing.T) {
+	patchText := "--- a/old.go\n+++ b/new.go\n@@ -1 +1 @@\n-a\n+b\n--- /dev/null\n+++ b/created.go\n@@ -0,0 +1 @@\n+x\n"
+	cases := []struct {
+		tool  *types.ToolUseBlock
+		paths []string
+	}{
+		{&types.ToolUseBlock{Name: "Edit", Input: map[string]any{"file_path": "main.go"}}, []string{"main.go"}},
+		{&types.ToolUseBlock{Name: "ApplyPatch", Input: map[string]any{"patch": patchText}}, []string{"old.go", "new.go", "created.go"}},
+		{&types.ToolUseBlock{Name: "Bash", Input: map[string]any{"command": "rm -rf build"}}, nil},
+	}
+	for _, tc := range cases {
+		got := toolWritePaths(tc.tool)
+		if len(got) != len(tc.paths) {
+			t.Errorf("%s: paths = %v, want %v", tc.tool.Name, got, tc.paths)
+			continue
+		}
+		for i := range got {
+			if got[i] != tc.paths[i] {
+				t.Errorf("%s: paths = %v, want %v", tc.tool.Name, got, tc.paths)
+			}
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package agent
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/astercloud/aster/pkg/multitenancy"
+	"github.com/astercloud/aster/pkg/provider"
+	"github.com/astercloud/aster/pkg/tools"
+	"github.com/astercloud/aster/pkg/types"
+	"github.com/astercloud/aster/pkg/usage"
+)
+
+// recordUsage 将一次模型调用的用量写入 Dependencies.Usage，用于计费报表
+// 写入失败只记录日志，不影响对话
+func (a *Agent) recordUsage(ctx context.Context, u *provider.TokenUsage) {
+	if a.deps.Usage == nil || u == nil {
+		return
+	}
+
+	rec := usage.Record{
+		AgentID:          a.id,
+		InputTokens:      u.InputTokens,
+		OutputTokens:     u.OutputTokens,
+		CacheReadTokens:  u.CacheReadTokens,
+		CacheWriteTokens: u.CacheCreationTokens,
+	}
+	// OpenAI 兼容接口和 Gemini 的缓存命中 token 包含在输入 token 中，拆出来按缓存价格计费
+	if rec.CacheReadTokens == 0 && u.CachedTokens > 0 && u.CachedTokens <= u.InputTokens {
+		rec.CacheReadTokens = u.CachedTokens
+		rec.InputTokens -= u.CachedTokens
+	}
+	if cfg := a.modelProviderForStep(ctx).Config(); cfg != nil {
+		rec.Provider = cfg.Provider
+		rec.Model = cfg.Model
+	}
+	tenantID := ""
+	if a.config.Multitenancy != nil && a.config.Multitenancy.Enabled {
+		tenantID = a.config.Multitenancy.TenantID
+	}
+	rec.TenantID = multitenancy.GetTenantIDOrDefault(ctx, tenantID)
+
+	if err := a.deps.Usage.Record(ctx, rec); err != nil {
+		agentLog.Warn(ctx, "failed to record token usage", map[string]any{"agent_id": a.id, "error": err.Error()})
+	}
+}
+
+// recordToolUsage 将一次工具调用写入 Dependencies.ToolUsage，用于按模板统计工具成功率、延迟和结果 Token
+// 工具以 {"ok": false} 形式返回的失败也计为失败
+func (a *Agent) recordToolUsage(ctx context.Context, name string, execResult *tools.ExecuteResult, elapsed time.Duration, result *types.ToolResultBlock) {
+	if a.deps.ToolUsage == nil {
+		return
+	}
+
+	rec := usage.ToolRecord{
+		AgentID:      a.id,
+		Tool:         name,
+		Success:      !result.IsError,
+		DurationMs:   cmp.Or(execResult.DurationMs, elapsed.Milliseconds()),
+		ResultTokens: int64(estimateTextTokens(result.Content)),
+	}
+	if a.template != nil {
+		rec.TemplateID = a.template.ID
+	}
+	if result.IsError {
+		rec.ErrorCode = string(toolExecError(execResult).Code)
+	} else if out, ok := execResult.Output.(map[string]any); ok && out["ok"] == false {
+		rec.Success = false
+		if code, ok := out["code"]; ok {
+			rec.ErrorCode = fmt.Sprint(code)
+		}
+	}
+	if cfg := a.modelProviderForStep(ctx).Config(); cfg != nil {
+		rec.Model = cfg.Model
+	}
+	tenantID := ""
+	if a.config.Multitenancy != nil && a.config.Multitenancy.Enabled {
+		tenantID = a.config.Multitenancy.TenantID
+	}
+	rec.TenantID = multitenancy.GetTenantIDOrDefault(ctx, tenantID)
+
+	if err := a.deps.ToolUsage.RecordTool(ctx, rec); err != nil {
+		agentLog.Warn(ctx, "failed to record tool usage", map[string]any{"agent_id": a.id, "tool": name, "error": err.Error()})
+	}
+}
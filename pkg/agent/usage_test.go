@@ -0,0 +1,78 @@
+package agent
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/astercloud/aster/pkg/multitenancy"
+	"github.com/astercloud/aster/pkg/provider"
+	"github.com/astercloud/aster/pkg/tools"
+	"github.com/astercloud/aster/pkg/types"
+	"github.com/astercloud/aster/pkg/usage"
+)
+
+type usageRecorderFunc func(ctx context.Context, rec usage.Record) error
+
+func (f usageRecorderFunc) Record(ctx context.Context, rec usage.Record) error { return f(ctx, rec) }
+
+func TestRecordUsage(t *testing.T) {
+	ag := createGuardedAgent(t, 50000, nil)
+	var got []usage.Record
+	ag.deps.Usage = usageRecorderFunc(func(_ context.Context, rec usage.Record) error {
+		got = append(got, rec)
+		return nil
+	})
+
+	ctx := multitenancy.WithTenantID(context.Background(), "acme")
+	ag.recordUsage(ctx, &provider.TokenUsage{InputTokens: 100, OutputTokens: 20, CacheReadTokens: 5, CacheCreationTokens: 7})
+	// OpenAI 兼容格式：缓存命中 token 包含在输入中
+	ag.recordUsage(ctx, &provider.TokenUsage{InputTokens: 100, OutputTokens: 20, CachedTokens: 40})
+
+	if len(got) != 2 {
+		t.Fatalf("recorded %d usage records, want 2", len(got))
+	}
+	if r := got[0]; r.AgentID != ag.ID() || r.TenantID != "acme" || r.Model != "claude-sonnet-4-5" || r.CacheReadTokens != 5 || r.CacheWriteTokens != 7 {
+		t.Errorf("first record = %+v", r)
+	}
+	if r := got[1]; r.InputTokens != 60 || r.CacheReadTokens != 40 {
+		t.Errorf("cached tokens should be split from input, got %+v", r)
+	}
+}
+
+type toolRecorderFunc func(ctx context.Context, rec usage.ToolRecord) error
+
+func (f toolRecorderFunc) RecordTool(ctx context.Context, rec usage.ToolRecord) error {
+	return f(ctx, rec)
+}
+
+func TestRecordToolUsage(t *testing.T) {
+	ag := createGuardedAgent(t, 50000, nil)
+	var got []usage.ToolRecord
+	ag.deps.ToolUsage = toolRecorderFunc(func(_ context.Context, rec usage.ToolRecord) error {
+		got = append(got, rec)
+		return nil
+	})
+
+	ctx := context.Background()
+	ag.recordToolUsage(ctx, "Read", &tools.ExecuteResult{Success: true, Output: "12345678"},
+		20*time.Millisecond, &types.ToolResultBlock{Content: "12345678"})
+	// 工具以 {"ok": false} 返回的失败
+	ag.recordToolUsage(ctx, "Edit", &tools.ExecuteResult{Success: true, Output: map[string]any{"ok": false, "code": "file_conflict"}},
+		time.Millisecond, &types.ToolResultBlock{Content: "{}"})
+	ag.recordToolUsage(ctx, "Bash", &tools.ExecuteResult{Success: false, Error: context.DeadlineExceeded, DurationMs: 60000},
+		time.Millisecond, toolFailure("t3", toolExecError(&tools.ExecuteResult{Error: context.DeadlineExceeded}), nil))
+
+	if len(got) != 3 {
+		t.Fatalf("recorded %d tool usage records, want 3", len(got))
+	}
+	if r := got[0]; !r.Success || r.AgentID != ag.ID() || r.TemplateID != ag.template.ID || r.DurationMs != 20 || r.ResultTokens != 2 || r.Model != "claude-sonnet-4-5" {
+		t.Errorf("Read record = %+v", r)
+	}
+	if r := got[1]; r.Success || r.ErrorCode != "file_conflict" {
+		t.Errorf("Edit record = %+v", r)
+	}
+	if r := got[2]; r.Success || r.ErrorCode == "" || r.DurationMs != 60000 {
+		t.Errorf("Bash record = %+v", r)
+	}
+}
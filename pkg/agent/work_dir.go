@@ -0,0 +1,31 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/astercloud/aster/pkg/sandbox"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+// ErrWorkDirNotIsolated Agent 未启用会话工作目录隔离
+var ErrWorkDirNotIsolated = errors.New("work dir isolation is not enabled")
+
+// IsolatedWorkDir 返回会话的隔离工作目录，未启用隔离时返回 nil
+func (a *Agent) IsolatedWorkDir() *sandbox.IsolatedWorkDir {
+	return a.isolatedWorkDir
+}
+
+// MergeWorkDir 把隔离工作目录中的改动合并回原工作目录，仅在 Agent 空闲时允许调用
+func (a *Agent) MergeWorkDir(ctx context.Context) (*sandbox.MergeResult, error) {
+	if a.isolatedWorkDir == nil {
+		return nil, ErrWorkDirNotIsolated
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.state == types.AgentStateWorking {
+		return nil, fmt.Errorf("agent %s is working", a.id)
+	}
+	return a.isolatedWorkDir.Merge(ctx)
+}
@@ -0,0 +1,68 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/astercloud/aster/pkg/types"
+)
+
+func TestAgentWorkDirIsolation(t *testing.T) {
+	workDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(workDir, "a.txt"), []byte("original"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var isoPath string
+	// 先注册的 Cleanup 在 Agent 关闭之后执行：合并后没有未合并的改动，按默认策略删除隔离目录
+	t.Cleanup(func() {
+		if _, err := os.Stat(isoPath); !os.IsNotExist(err) {
+			t.Errorf("isolated dir should be removed on close, stat err = %v", err)
+		}
+	})
+	ag := newWriteTestAgent(t, &types.SandboxConfig{
+		Kind:           types.SandboxKindLocal,
+		WorkDir:        workDir,
+		PermissionMode: types.SandboxPermissionBypass,
+		Isolation:      &types.WorkDirIsolation{Mode: types.WorkDirIsolationDir},
+	})
+	ctx := context.Background()
+
+	iso := ag.IsolatedWorkDir()
+	if iso == nil || iso.Path == workDir {
+		t.Fatalf("IsolatedWorkDir() = %+v", iso)
+	}
+	isoPath = iso.Path
+	if got := ag.sandbox.WorkDir(); got != iso.Path {
+		t.Fatalf("sandbox work dir = %q, want %q", got, iso.Path)
+	}
+
+	// 写入落在隔离目录，原工作目录不变
+	if _, err := ag.Chat(ctx, "a.txt=changed"); err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+	if got, _ := os.ReadFile(filepath.Join(workDir, "a.txt")); string(got) != "original" {
+		t.Fatalf("base a.txt = %q", got)
+	}
+
+	result, err := ag.MergeWorkDir(ctx)
+	if err != nil {
+		t.Fatalf("MergeWorkDir: %v", err)
+	}
+	if len(result.Applied) != 1 || result.Applied[0] != "a.txt" {
+		t.Errorf("unexpected merge result: %+v", result)
+	}
+	if got, _ := os.ReadFile(filepath.Join(workDir, "a.txt")); string(got) != "changed" {
+		t.Fatalf("merged a.txt = %q", got)
+	}
+
+}
+
+func TestAgentMergeWorkDir_NotIsolated(t *testing.T) {
+	ag := newUndoTestAgent(t, t.TempDir())
+	if _, err := ag.MergeWorkDir(context.Background()); !errors.Is(err, ErrWorkDirNotIsolated) {
+		t.Fatalf("MergeWorkDir() = %v, want ErrWorkDirNotIsolated", err)
+	}
+}
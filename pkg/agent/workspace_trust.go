@@ -0,0 +1,178 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/astercloud/aster/pkg/agenterr"
+	"github.com/astercloud/aster/pkg/permission"
+	"github.com/astercloud/aster/pkg/tools"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+// workspaceTrust 工作区信任状态
+// 未受信任的工作区禁止 Bash/网络类工具，直到用户通过控制通道明确信任
+type workspaceTrust struct {
+	store   *permission.TrustStore
+	workDir string
+
+	mu     sync.Mutex
+	level  permission.TrustLevel
+	denied bool          // 用户已拒绝信任，后续调用直接拒绝，不再重复询问
+	prompt chan struct{} // 等待中的信任请求，决策后关闭
+}
+
+// initWorkspaceTrust 初始化工作区信任并加载工作区权限配置
+// 未配置 TrustStore 时不启用信任模型；未受信任的工作区推迟加载 .aster 下的权限策略和命令白名单
+func (a *Agent) initWorkspaceTrust(sandboxConfig *types.SandboxConfig) error {
+	if sandboxConfig.WorkDir == "" || (sandboxConfig.Kind != "" && sandboxConfig.Kind != types.SandboxKindLocal) {
+		return nil
+	}
+	if a.deps.TrustStore != nil {
+		a.trust = &workspaceTrust{
+			store:   a.deps.TrustStore,
+			workDir: sandboxConfig.WorkDir,
+			level:   a.deps.TrustStore.Level(sandboxConfig.WorkDir),
+		}
+		if a.trust.level != permission.TrustLevelTrusted {
+			return nil
+		}
+	}
+	return a.applyWorkspacePermissions(sandboxConfig.WorkDir)
+}
+
+// applyWorkspacePermissions 应用工作区中提交的权限策略和命令白名单
+// 命令白名单会自动放行命令，只在启用信任模型（此时工作区已受信任）时加载
+func (a *Agent) applyWorkspacePermissions(workDir string) error {
+	if a.trust != nil {
+		allowlist, err := permission.LoadCommandAllowlist(workDir)
+		if err != nil {
+			return fmt.Errorf("load command allowlist: %w", err)
+		}
+		a.permissionInspector.SetCommandAllowlist(allowlist)
+	}
+
+	policy, err := permission.LoadWorkspacePolicy(workDir)
+	if err != nil {
+		return fmt.Errorf("load workspace permission policy: %w", err)
+	}
+	if policy == nil {
+		return nil
+	}
+	if err := a.permissionInspector.ApplyPolicy(policy); err != nil {
+		return fmt.Errorf("apply workspace permission policy: %w", err)
+	}
+	return nil
+}
+
+// WorkspaceTrust 返回当前工作区的信任级别，未启用信任模型时返回空字符串
+func (a *Agent) WorkspaceTrust() permission.TrustLevel {
+	if a.trust == nil {
+		return ""
+	}
+	a.trust.mu.Lock()
+	defer a.trust.mu.Unlock()
+	return a.trust.level
+}
+
+// RespondToWorkspaceTrust 响应工作区信任请求
+// trusted 为 true 时持久化信任决策并加载工作区权限配置；拒绝只对当前 Agent 生效
+func (a *Agent) RespondToWorkspaceTrust(trusted bool) error {
+	t := a.trust
+	if t == nil {
+		return errors.New("workspace trust is not enabled for this agent")
+	}
+
+	var policyErr error
+	if trusted {
+		if err := t.store.SetLevel(t.workDir, permission.TrustLevelTrusted); err != nil {
+			return fmt.Errorf("persist workspace trust: %w", err)
+		}
+		policyErr = a.applyWorkspacePermissions(t.workDir)
+	}
+
+	t.mu.Lock()
+	if trusted {
+		t.level = permission.TrustLevelTrusted
+	}
+	t.denied = !trusted
+	if t.prompt != nil {
+		close(t.prompt)
+		t.prompt = nil
+	}
+	t.mu.Unlock()
+
+	a.eventBus.EmitControl(&types.ControlWorkspaceTrustDecidedEvent{
+		WorkDir: t.workDir,
+		Trusted: trusted,
+	})
+	return policyErr
+}
+
+// checkWorkspaceTrust 在未受信任的工作区拦截受限工具
+// 首次调用时发出 ControlWorkspaceTrustRequiredEvent 并等待用户决策，允许时返回 nil
+func (a *Agent) checkWorkspaceTrust(ctx context.Context, tu *types.ToolUseBlock) *types.ToolResultBlock {
+	t := a.trust
+	if t == nil || !a.requiresWorkspaceTrust(tu.Name) {
+		return nil
+	}
+
+	t.mu.Lock()
+	if t.level == permission.TrustLevelTrusted {
+		t.mu.Unlock()
+		return nil
+	}
+	if t.denied {
+		t.mu.Unlock()
+		return a.workspaceTrustDenied(tu)
+	}
+	wait := t.prompt
+	first := wait == nil
+	if first {
+		wait = make(chan struct{})
+		t.prompt = wait
+	}
+	t.mu.Unlock()
+
+	// 并发的受限调用共享同一个信任请求
+	if first {
+		a.eventBus.EmitControl(&types.ControlWorkspaceTrustRequiredEvent{
+			WorkDir: t.workDir,
+			Call: types.ToolCallSnapshot{
+				ID:        tu.ID,
+				Name:      tu.Name,
+				Arguments: tu.Input,
+			},
+		})
+	}
+
+	select {
+	case <-wait:
+	case <-ctx.Done():
+		return toolFailure(tu.ID, agenterr.New(agenterr.CodeCanceled, "Workspace trust request canceled"), nil)
+	}
+
+	if a.WorkspaceTrust() == permission.TrustLevelTrusted {
+		return nil
+	}
+	return a.workspaceTrustDenied(tu)
+}
+
+// requiresWorkspaceTrust 工具是否在未受信任的工作区中受限
+func (a *Agent) requiresWorkspaceTrust(name string) bool {
+	if permission.RequiresWorkspaceTrust(name) {
+		return true
+	}
+	tool, ok := a.toolMap[name].(tools.TrustRestrictedTool)
+	return ok && tool.RequiresWorkspaceTrust()
+}
+
+// workspaceTrustDenied 构造未受信任工作区的拒绝结果
+func (a *Agent) workspaceTrustDenied(tu *types.ToolUseBlock) *types.ToolResultBlock {
+	toolErr := agenterr.New(agenterr.CodeWorkspaceUntrusted,
+		fmt.Sprintf("Workspace %s is not trusted: %s is disabled until the user trusts it", a.trust.workDir, tu.Name))
+	a.emitToolError(tu, toolErr)
+	return toolFailure(tu.ID, toolErr, nil)
+}
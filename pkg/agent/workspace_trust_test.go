@@ -0,0 +1,204 @@
+package agent
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/astercloud/aster/pkg/permission"
+	"github.com/astercloud/aster/pkg/provider"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+// newTrustTestAgent 创建启用工作区信任的本地 Agent
+func newTrustTestAgent(t *testing.T, workDir string, store *permission.TrustStore) *Agent {
+	t.Helper()
+	deps := setupTestDeps(t)
+	deps.TrustStore = store
+
+	ag, err := Create(context.Background(), &types.AgentConfig{
+		TemplateID: "test-template",
+		ModelConfig: &types.ModelConfig{
+			Provider: "anthropic",
+			Model:    "claude-sonnet-4-5",
+			APIKey:   "test-key",
+		},
+		Sandbox: &types.SandboxConfig{
+			Kind:    types.SandboxKindLocal,
+			WorkDir: workDir,
+		},
+	}, deps)
+	if err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+	t.Cleanup(func() { _ = ag.Close() })
+	return ag
+}
+
+func TestWorkspaceTrust(t *testing.T) {
+	workDir := t.TempDir()
+	policyPath := filepath.Join(workDir, permission.WorkspacePolicyPath)
+	if err := os.MkdirAll(filepath.Dir(policyPath), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(policyPath, []byte("mode: auto_approve\ngroups: []\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	trustFile := filepath.Join(t.TempDir(), "trust.yaml")
+	store, err := permission.NewTrustStore(trustFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ag := newTrustTestAgent(t, workDir, store)
+	if ag.WorkspaceTrust() != permission.TrustLevelUntrusted {
+		t.Fatalf("WorkspaceTrust() = %q, want untrusted", ag.WorkspaceTrust())
+	}
+	// 未受信任的工作区不加载其权限策略，也不启动语言服务器
+	if ag.GetPermissionMode() == permission.ModeAutoApprove {
+		t.Error("workspace policy applied before the workspace was trusted")
+	}
+	if _, err := ag.LanguageServers().Client(context.Background(), "main.go"); err == nil || !strings.Contains(err.Error(), "not trusted") {
+		t.Errorf("language server started in untrusted workspace: %v", err)
+	}
+
+	ctx := context.Background()
+	if result := ag.checkWorkspaceTrust(ctx, &types.ToolUseBlock{ID: "r1", Name: "Read"}); result != nil {
+		t.Errorf("Read blocked in untrusted workspace: %+v", result)
+	}
+
+	controlCh := ag.Subscribe([]types.AgentChannel{types.ChannelControl}, nil)
+	done := make(chan *types.ToolResultBlock, 1)
+	go func() {
+		done <- ag.checkWorkspaceTrust(ctx, &types.ToolUseBlock{ID: "b1", Name: "Bash", Input: map[string]any{"command": "ls"}})
+	}()
+
+	select {
+	case env := <-controlCh:
+		e, ok := env.Event.(*types.ControlWorkspaceTrustRequiredEvent)
+		if !ok || e.WorkDir != workDir || e.Call.Name != "Bash" {
+			t.Fatalf("control event = %#v", env.Event)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for workspace_trust_required")
+	}
+
+	if err := ag.RespondToWorkspaceTrust(true); err != nil {
+		t.Fatalf("RespondToWorkspaceTrust() error = %v", err)
+	}
+	select {
+	case result := <-done:
+		if result != nil {
+			t.Errorf("Bash denied after trusting: %+v", result)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("tool call still waiting after trust decision")
+	}
+
+	if ag.GetPermissionMode() != permission.ModeAutoApprove {
+		t.Error("workspace policy not applied after trusting")
+	}
+	if err := ag.checkLanguageServerTrust(); err != nil {
+		t.Errorf("language servers still gated after trusting: %v", err)
+	}
+	reloaded, err := permission.NewTrustStore(trustFile)
+	if err != nil || !reloaded.IsTrusted(filepath.Join(workDir, "sub")) {
+		t.Errorf("trust decision not persisted: %v", err)
+	}
+}
+
+func TestWorkspaceTrustDeclined(t *testing.T) {
+	store, err := permission.NewTrustStore(filepath.Join(t.TempDir(), "trust.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ag := newTrustTestAgent(t, t.TempDir(), store)
+
+	if err := ag.RespondToWorkspaceTrust(false); err != nil {
+		t.Fatal(err)
+	}
+	// 拒绝后直接拒绝受限工具，不再发出信任请求
+	result := ag.checkWorkspaceTrust(context.Background(), &types.ToolUseBlock{ID: "w1", Name: "WebFetch"})
+	if result == nil || !result.IsError {
+		t.Fatalf("WebFetch allowed in declined workspace: %+v", result)
+	}
+	if len(store.Entries()) != 0 {
+		t.Errorf("declining persisted a decision: %v", store.Entries())
+	}
+
+	// 未配置 TrustStore 时不启用信任模型
+	plain := newTrustTestAgent(t, t.TempDir(), nil)
+	if plain.WorkspaceTrust() != "" || plain.checkWorkspaceTrust(context.Background(), &types.ToolUseBlock{Name: "Bash"}) != nil {
+		t.Error("trust model enabled without a TrustStore")
+	}
+	if err := plain.RespondToWorkspaceTrust(true); err == nil {
+		t.Error("RespondToWorkspaceTrust() succeeded without a TrustStore")
+	}
+}
+
+func TestWorkspaceTrustStream(t *testing.T) {
+	store, err := permission.NewTrustStore(filepath.Join(t.TempDir(), "trust.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var calls int
+	model := &MockProvider{
+		name:         "trust",
+		capabilities: provider.ProviderCapabilities{SupportStreaming: true, SupportToolCalling: true},
+		streamFunc: func(context.Context, []types.Message, *provider.StreamOptions) (<-chan provider.StreamChunk, error) {
+			calls++
+			ch := make(chan provider.StreamChunk, 3)
+			if calls == 1 {
+				ch <- provider.StreamChunk{Type: "content_block_start", Delta: map[string]any{"name": "Bash", "id": "b1"}}
+				ch <- provider.StreamChunk{Type: "content_block_delta", Delta: map[string]any{"type": "arguments", "arguments": `{"command": "touch pwned"}`}}
+				ch <- provider.StreamChunk{Type: "message_delta"}
+			} else {
+				ch <- provider.StreamChunk{Type: "text", TextDelta: "done"}
+			}
+			close(ch)
+			return ch, nil
+		},
+	}
+	deps := setupTestDeps(t)
+	deps.TrustStore = store
+	factory := NewMockProviderFactory()
+	factory.SetProvider("mock/trust", model)
+	deps.ProviderFactory = factory
+	deps.TemplateRegistry.Register(&types.AgentTemplateDefinition{ID: "bash-template", Tools: []any{"Bash"}})
+
+	workDir := t.TempDir()
+	ag, err := Create(context.Background(), &types.AgentConfig{
+		TemplateID:  "bash-template",
+		ModelConfig: &types.ModelConfig{Provider: "mock", Model: "trust"},
+		Sandbox:     &types.SandboxConfig{Kind: types.SandboxKindLocal, WorkDir: workDir},
+	}, deps)
+	if err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+	defer func() { _ = ag.Close() }()
+	if err := ag.RespondToWorkspaceTrust(false); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := StreamCollect(ag.Stream(ctx, "create a file")); err != nil {
+		t.Fatalf("Stream() error = %v", err)
+	}
+	// Stream 路径同样在未受信任的工作区拦截 Bash
+	if _, err := os.Stat(filepath.Join(workDir, "pwned")); err == nil {
+		t.Error("Bash ran in a declined workspace through Stream")
+	}
+	var result string
+	for _, msg := range ag.messages {
+		if msg.Role == types.RoleTool && msg.ToolCallID == "b1" {
+			result = msg.Content
+		}
+	}
+	if !strings.Contains(result, "trust") {
+		t.Errorf("tool result = %q, want workspace trust denial", result)
+	}
+}
@@ -0,0 +1,279 @@
+// Package agenterr 定义 Agent 运行结果的结构化错误分类。
+//
+// 每个错误带有机器可读的错误码（Code）和所属类别（Class），随事件和 API 响应传递，
+// 客户端可以按类别分支处理（重试、提示授权、调整限制等），而不必解析错误文本。
+package agenterr
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// Class 错误类别
+type Class string
+
+const (
+	ClassProvider   Class = "provider"   // 模型服务错误
+	ClassTool       Class = "tool"       // 工具执行错误
+	ClassPermission Class = "permission" // 权限拒绝
+	ClassSandbox    Class = "sandbox"    // 沙箱违规
+	ClassLimit      Class = "limit"      // 预算与运行限制
+	ClassCanceled   Class = "canceled"   // 调用方取消
+	ClassInternal   Class = "internal"   // 其他内部错误
+)
+
+// Code 错误码
+type Code string
+
+const (
+	// 模型服务
+	CodeProviderAuth        Code = "provider_auth"         // 鉴权失败（401/403）
+	CodeProviderRateLimited Code = "provider_rate_limited" // 限流（429）
+	CodeProviderOverloaded  Code = "provider_overloaded"   // 服务过载（503/529）
+	CodeProviderUnavailable Code = "provider_unavailable"  // 服务端错误或网络不可达
+	CodeProviderBadRequest  Code = "provider_bad_request"  // 请求被拒绝（4xx）
+	CodeProviderError       Code = "provider_error"        // 其他模型调用错误
+
+	// 工具
+	CodeToolNotFound     Code = "tool_not_found"
+	CodeToolInvalidInput Code = "tool_invalid_input"
+	CodeToolFailed       Code = "tool_failed"
+	CodeToolTimeout      Code = "tool_timeout"
+	CodeFileConflict     Code = "file_conflict" // 文件自上次读取后在磁盘上被修改
+
+	// 权限
+	CodePermissionDenied     Code = "permission_denied"      // 策略直接拒绝
+	CodePermissionRejected   Code = "permission_rejected"    // 用户拒绝审批
+	CodePlanModeRestricted   Code = "plan_mode_restricted"   // Plan 模式下禁止的调用
+	CodeWorkspaceUntrusted   Code = "workspace_untrusted"    // 工作区未被信任
+	CodePermissionCheckError Code = "permission_check_error" // 权限检查本身出错
+	CodeContentBlocked       Code = "content_blocked"        // 内容安全策略拦截
+
+	// 沙箱
+	CodeSandboxViolation Code = "sandbox_violation" // 越过沙箱边界（路径、网络等）
+	CodeSandboxError     Code = "sandbox_error"
+
+	// 预算与运行限制
+	CodeMaxTurns              Code = "max_turns"
+	CodeMaxOutputTokens       Code = "max_output_tokens"
+	CodeMaxDuration           Code = "max_duration"
+	CodeIterationLimit        Code = "iteration_limit"
+	CodeContextWindowExceeded Code = "context_window_exceeded"
+	CodeBudgetExceeded        Code = "budget_exceeded"
+
+	CodeCanceled Code = "canceled"
+	CodeTimeout  Code = "timeout"
+	CodeInternal Code = "internal"
+)
+
+// codeClasses 错误码所属类别，未登记的错误码归为 internal
+var codeClasses = map[Code]Class{
+	CodeProviderAuth:        ClassProvider,
+	CodeProviderRateLimited: ClassProvider,
+	CodeProviderOverloaded:  ClassProvider,
+	CodeProviderUnavailable: ClassProvider,
+	CodeProviderBadRequest:  ClassProvider,
+	CodeProviderError:       ClassProvider,
+
+	CodeToolNotFound:     ClassTool,
+	CodeToolInvalidInput: ClassTool,
+	CodeToolFailed:       ClassTool,
+	CodeToolTimeout:      ClassTool,
+	CodeFileConflict:     ClassTool,
+
+	CodePermissionDenied:     ClassPermission,
+	CodePermissionRejected:   ClassPermission,
+	CodePlanModeRestricted:   ClassPermission,
+	CodeWorkspaceUntrusted:   ClassPermission,
+	CodePermissionCheckError: ClassPermission,
+	CodeContentBlocked:       ClassPermission,
+
+	CodeSandboxViolation: ClassSandbox,
+	CodeSandboxError:     ClassSandbox,
+
+	CodeMaxTurns:              ClassLimit,
+	CodeMaxOutputTokens:       ClassLimit,
+	CodeMaxDuration:           ClassLimit,
+	CodeIterationLimit:        ClassLimit,
+	CodeContextWindowExceeded: ClassLimit,
+	CodeBudgetExceeded:        ClassLimit,
+
+	CodeCanceled: ClassCanceled,
+	CodeTimeout:  ClassCanceled,
+}
+
+// Class 错误码所属类别
+func (c Code) Class() Class {
+	if class, ok := codeClasses[c]; ok {
+		return class
+	}
+	return ClassInternal
+}
+
+// Retryable 相同请求稍后重试是否可能成功
+func (c Code) Retryable() bool {
+	switch c {
+	case CodeProviderRateLimited, CodeProviderOverloaded, CodeProviderUnavailable, CodeToolTimeout, CodeTimeout:
+		return true
+	}
+	return false
+}
+
+// HTTPStatus 错误码对应的 HTTP 状态码
+func (c Code) HTTPStatus() int {
+	switch c {
+	case CodeProviderRateLimited:
+		return http.StatusTooManyRequests
+	case CodeProviderOverloaded, CodeProviderUnavailable:
+		return http.StatusServiceUnavailable
+	case CodeMaxDuration, CodeTimeout:
+		return http.StatusGatewayTimeout
+	case CodeFileConflict:
+		return http.StatusConflict
+	case CodeCanceled:
+		return 499 // Client Closed Request（nginx 约定）
+	}
+	switch c.Class() {
+	case ClassProvider:
+		return http.StatusBadGateway
+	case ClassTool, ClassLimit:
+		return http.StatusUnprocessableEntity
+	case ClassPermission, ClassSandbox:
+		return http.StatusForbidden
+	}
+	return http.StatusInternalServerError
+}
+
+// Error 结构化错误
+type Error struct {
+	Code    Code
+	Message string
+	// Details 供客户端使用的附加信息，例如 HTTP 状态码、工具名
+	Details map[string]any
+	Err     error
+}
+
+// New 创建错误
+func New(code Code, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+// Wrap 用错误码包装 err；err 已带有错误码时保留原错误码
+func Wrap(code Code, err error) *Error {
+	return Classify(err, code)
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil && e.Message == "" {
+		return e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is 按错误码匹配，便于以 New 创建的哨兵错误配合 errors.Is 使用
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// Class 错误类别
+func (e *Error) Class() Class {
+	return e.Code.Class()
+}
+
+// Retryable 是否可重试
+func (e *Error) Retryable() bool {
+	return e.Code.Retryable()
+}
+
+// WithDetail 设置附加信息，返回自身便于链式调用
+func (e *Error) WithDetail(key string, value any) *Error {
+	if e.Details == nil {
+		e.Details = make(map[string]any)
+	}
+	e.Details[key] = value
+	return e
+}
+
+// MarshalJSON 输出错误码、类别、消息和是否可重试
+func (e *Error) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Code      Code           `json:"code"`
+		Class     Class          `json:"class"`
+		Message   string         `json:"message"`
+		Retryable bool           `json:"retryable"`
+		Details   map[string]any `json:"details,omitempty"`
+	}{e.Code, e.Class(), e.Error(), e.Retryable(), e.Details})
+}
+
+// UnmarshalJSON 解析 MarshalJSON 的输出，类别和可重试由错误码推导
+func (e *Error) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Code    Code           `json:"code"`
+		Message string         `json:"message"`
+		Details map[string]any `json:"details,omitempty"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	e.Code, e.Message, e.Details = raw.Code, raw.Message, raw.Details
+	return nil
+}
+
+// Coder 由各包的错误类型实现，提供所属错误码
+type Coder interface {
+	ErrorCode() Code
+}
+
+// From 将任意错误转换为结构化错误；无法识别时归为 internal，err 为 nil 时返回 nil
+func From(err error) *Error {
+	return Classify(err, CodeInternal)
+}
+
+// Classify 同 From，无法识别的错误使用 fallback 错误码
+func Classify(err error, fallback Code) *Error {
+	if err == nil {
+		return nil
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		if err == error(e) {
+			return e
+		}
+		// 被 fmt.Errorf 等包装过：保留错误码，消息使用完整的错误链，避免修改哨兵错误
+		return &Error{Code: e.Code, Message: err.Error(), Details: e.Details, Err: err}
+	}
+	var coder Coder
+	if errors.As(err, &coder) {
+		return &Error{Code: coder.ErrorCode(), Message: err.Error(), Err: err}
+	}
+	switch {
+	case errors.Is(err, context.Canceled):
+		return &Error{Code: CodeCanceled, Message: err.Error(), Err: err}
+	case errors.Is(err, context.DeadlineExceeded):
+		return &Error{Code: CodeTimeout, Message: err.Error(), Err: err}
+	}
+	return &Error{Code: fallback, Message: err.Error(), Err: err}
+}
+
+// CodeOf 返回 err 的错误码，err 为 nil 时返回空
+func CodeOf(err error) Code {
+	if e := From(err); e != nil {
+		return e.Code
+	}
+	return ""
+}
+
+// ClassOf 返回 err 的错误类别，err 为 nil 时返回空
+func ClassOf(err error) Class {
+	if e := From(err); e != nil {
+		return e.Class()
+	}
+	return ""
+}
@@ -0,0 +1,97 @@
+package agenterr
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+type codedError struct{}
+
+func (codedError) Error() string   { return "coded" }
+func (codedError) ErrorCode() Code { return CodeToolNotFound }
+
+func TestClassify(t *testing.T) {
+	sentinel := New(CodeSandboxViolation, "path outside sandbox")
+
+	tests := []struct {
+		name     string
+		err      error
+		wantCode Code
+		wantMsg  string
+	}{
+		{"sentinel", sentinel, CodeSandboxViolation, "path outside sandbox"},
+		{"wrapped sentinel", fmt.Errorf("%w: /etc/passwd", sentinel), CodeSandboxViolation, "path outside sandbox: /etc/passwd"},
+		{"coder", fmt.Errorf("lookup: %w", codedError{}), CodeToolNotFound, "lookup: coded"},
+		{"canceled", fmt.Errorf("model call: %w", context.Canceled), CodeCanceled, "model call: context canceled"},
+		{"deadline", context.DeadlineExceeded, CodeTimeout, "context deadline exceeded"},
+		{"unknown", errors.New("boom"), CodeProviderError, "boom"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Classify(tt.err, CodeProviderError)
+			if got.Code != tt.wantCode || got.Error() != tt.wantMsg {
+				t.Errorf("Classify() = %q (%s), want %q (%s)", got.Error(), got.Code, tt.wantMsg, tt.wantCode)
+			}
+			if !errors.Is(got, tt.err) && got != tt.err {
+				t.Errorf("Classify() lost the original error chain")
+			}
+		})
+	}
+
+	if From(nil) != nil {
+		t.Error("From(nil) should be nil")
+	}
+	if sentinel.Message != "path outside sandbox" {
+		t.Errorf("sentinel was mutated: %q", sentinel.Message)
+	}
+	if !errors.Is(fmt.Errorf("wrap: %w", sentinel), New(CodeSandboxViolation, "")) {
+		t.Error("errors.Is should match by code")
+	}
+}
+
+func TestCodeProperties(t *testing.T) {
+	tests := []struct {
+		code      Code
+		class     Class
+		status    int
+		retryable bool
+	}{
+		{CodeProviderRateLimited, ClassProvider, http.StatusTooManyRequests, true},
+		{CodeProviderAuth, ClassProvider, http.StatusBadGateway, false},
+		{CodeToolTimeout, ClassTool, http.StatusUnprocessableEntity, true},
+		{CodePlanModeRestricted, ClassPermission, http.StatusForbidden, false},
+		{CodeSandboxViolation, ClassSandbox, http.StatusForbidden, false},
+		{CodeMaxDuration, ClassLimit, http.StatusGatewayTimeout, false},
+		{CodeCanceled, ClassCanceled, 499, false},
+		{Code("unknown"), ClassInternal, http.StatusInternalServerError, false},
+	}
+	for _, tt := range tests {
+		if tt.code.Class() != tt.class || tt.code.HTTPStatus() != tt.status || tt.code.Retryable() != tt.retryable {
+			t.Errorf("%s: class=%s status=%d retryable=%v", tt.code, tt.code.Class(), tt.code.HTTPStatus(), tt.code.Retryable())
+		}
+	}
+}
+
+func TestErrorJSON(t *testing.T) {
+	e := New(CodeProviderOverloaded, "anthropic api error: 529").WithDetail("status_code", 529)
+	data, err := json.Marshal(e)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	want := `{"code":"provider_overloaded","class":"provider","message":"anthropic api error: 529","retryable":true,"details":{"status_code":529}}`
+	if string(data) != want {
+		t.Errorf("Marshal() = %s, want %s", data, want)
+	}
+
+	var decoded Error
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if decoded.Code != e.Code || decoded.Message != e.Message || !decoded.Retryable() {
+		t.Errorf("Unmarshal() = %+v", decoded)
+	}
+}
@@ -42,7 +42,7 @@ type VectorStoreConfig struct {
 // EmbedderConfig 定义一个 embedder 配置。
 type EmbedderConfig struct {
 	Name      string `yaml:"name"`
-	Kind      string `yaml:"kind"` // "mock", 将来可扩展 "openai" 等
+	Kind      string `yaml:"kind"` // "mock", "openai", "voyage", "ollama"
 	Model     string `yaml:"model,omitempty"`
 	EnvAPIKey string `yaml:"env_api_key,omitempty"`
 }
@@ -0,0 +1,366 @@
+// Package artifact 管理工具产出的文件（截图、报告、导出文件等）
+// 元数据保存在 store.Store 的 artifacts 集合中，内容保存在 BlobStore（本地目录或对象存储）
+package artifact
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/astercloud/aster/pkg/identity"
+	"github.com/astercloud/aster/pkg/multitenancy"
+	"github.com/astercloud/aster/pkg/store"
+	"github.com/google/uuid"
+)
+
+// Collection 元数据在 store.Store 中的集合名
+const Collection = "artifacts"
+
+// ErrNotFound 产出物不存在或已过期
+var ErrNotFound = errors.New("artifact not found")
+
+// Kind 产出物类型
+type Kind string
+
+const (
+	KindFile   Kind = "file"
+	KindImage  Kind = "image"
+	KindReport Kind = "report"
+	KindData   Kind = "data"
+)
+
+// Artifact 产出物元数据
+type Artifact struct {
+	ID string `json:"id"`
+	// TenantID 保存时 context 中的租户，只有同一租户可以访问
+	TenantID  string `json:"tenant_id,omitempty"`
+	SessionID string `json:"session_id,omitempty"`
+	AgentID   string `json:"agent_id,omitempty"`
+	// Name 文件名，下载时使用
+	Name     string `json:"name"`
+	Kind     Kind   `json:"kind"`
+	MIMEType string `json:"mime_type"`
+	Size     int64  `json:"size"`
+	SHA256   string `json:"sha256"`
+	// Source 产生该产出物的工具
+	Source      string         `json:"source,omitempty"`
+	Description string         `json:"description,omitempty"`
+	Metadata    map[string]any `json:"metadata,omitempty"`
+	CreatedAt   time.Time      `json:"created_at"`
+	// ExpiresAt 过期时间，为空表示只受清理策略限制
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	// Pinned 固定的产出物不会被清理
+	Pinned bool `json:"pinned,omitempty"`
+	// Signature 产生该产出物的 Agent 对内容摘要的签名，未配置身份时为空
+	Signature *identity.Signature `json:"signature,omitempty"`
+}
+
+// Expired 在 now 时是否已过期
+func (a *Artifact) Expired(now time.Time) bool {
+	return a.ExpiresAt != nil && !a.ExpiresAt.After(now)
+}
+
+// SaveRequest 保存产出物的参数
+type SaveRequest struct {
+	SessionID   string
+	AgentID     string
+	Name        string
+	Kind        Kind   // 为空时按 MIME 类型推断
+	MIMEType    string // 为空时按文件名推断
+	Source      string
+	Description string
+	Metadata    map[string]any
+	// TTL 有效期，0 表示使用策略的默认有效期
+	TTL    time.Duration
+	Pinned bool
+	// Signer 设置时对内容摘要签名，签名保存在 Artifact.Signature
+	Signer *identity.Signer
+}
+
+// Filter 列出产出物的过滤条件，SessionID 和 AgentID 同时设置时满足任一即可
+type Filter struct {
+	SessionID string
+	AgentID   string
+	Kind      Kind
+	// IncludeExpired 包含已过期但尚未清理的产出物
+	IncludeExpired bool
+}
+
+func (f Filter) match(a *Artifact, now time.Time) bool {
+	if !f.IncludeExpired && a.Expired(now) {
+		return false
+	}
+	if f.Kind != "" && a.Kind != f.Kind {
+		return false
+	}
+	if f.SessionID == "" && f.AgentID == "" {
+		return true
+	}
+	return (f.SessionID != "" && a.SessionID == f.SessionID) || (f.AgentID != "" && a.AgentID == f.AgentID)
+}
+
+// Sink 工具保存产出物的入口
+// Agent 通过 Services["artifact_sink"] 注入，保存时补充会话和 Agent 信息并发布事件
+type Sink interface {
+	Save(ctx context.Context, req SaveRequest, r io.Reader) (*Artifact, error)
+}
+
+// Store 产出物存储
+type Store struct {
+	meta   store.Store
+	blobs  BlobStore
+	policy Policy
+	now    func() time.Time
+
+	// mu 保护元数据和内容的一致性（保存、删除与清理互斥）
+	mu sync.Mutex
+}
+
+// NewStore 创建产出物存储
+// meta 不应按租户包装（如 store.TenantStore），租户隔离通过 Artifact.TenantID 完成，清理时才能覆盖所有租户
+func NewStore(meta store.Store, blobs BlobStore, policy Policy) *Store {
+	return &Store{meta: meta, blobs: blobs, policy: policy, now: time.Now}
+}
+
+// Policy 返回清理策略
+func (s *Store) Policy() Policy {
+	return s.policy
+}
+
+// Save 保存产出物内容并记录元数据
+func (s *Store) Save(ctx context.Context, req SaveRequest, r io.Reader) (*Artifact, error) {
+	name := cleanName(req.Name)
+	if name == "" {
+		return nil, errors.New("artifact name is required")
+	}
+	mimeType := req.MIMEType
+	if mimeType == "" {
+		mimeType = mime.TypeByExtension(path.Ext(name))
+	}
+
+	var limited io.Reader = r
+	if s.policy.MaxArtifactBytes > 0 {
+		limited = io.LimitReader(r, s.policy.MaxArtifactBytes+1)
+	}
+	hash := sha256.New()
+	sniff := &sniffWriter{}
+	// 内容写入可能很慢，不持有锁；ID 唯一，元数据写入前其他操作看不到这份内容
+	id := uuid.New().String()
+
+	size, err := s.blobs.Put(ctx, id, io.TeeReader(limited, io.MultiWriter(hash, sniff)))
+	if err != nil {
+		return nil, fmt.Errorf("store artifact content: %w", err)
+	}
+	if s.policy.MaxArtifactBytes > 0 && size > s.policy.MaxArtifactBytes {
+		_ = s.blobs.Delete(ctx, id)
+		return nil, fmt.Errorf("artifact exceeds the size limit of %d bytes", s.policy.MaxArtifactBytes)
+	}
+	if mimeType == "" {
+		mimeType = http.DetectContentType(sniff.buf)
+	}
+
+	now := s.now()
+	a := &Artifact{
+		ID:          id,
+		TenantID:    tenantOf(ctx),
+		SessionID:   req.SessionID,
+		AgentID:     req.AgentID,
+		Name:        name,
+		Kind:        req.Kind,
+		MIMEType:    mimeType,
+		Size:        size,
+		SHA256:      hex.EncodeToString(hash.Sum(nil)),
+		Source:      req.Source,
+		Description: req.Description,
+		Metadata:    req.Metadata,
+		CreatedAt:   now,
+		Pinned:      req.Pinned,
+	}
+	if req.Signer != nil {
+		a.Signature = req.Signer.SignDigest(identity.KindArtifact, a.SHA256)
+	}
+	if a.Kind == "" {
+		a.Kind = kindOf(mimeType)
+	}
+	ttl := req.TTL
+	if ttl == 0 {
+		ttl = s.policy.DefaultTTL
+	}
+	if ttl > 0 && !req.Pinned {
+		expires := now.Add(ttl)
+		a.ExpiresAt = &expires
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.meta.Set(ctx, Collection, id, a); err != nil {
+		_ = s.blobs.Delete(ctx, id)
+		return nil, fmt.Errorf("save artifact metadata: %w", err)
+	}
+	return a, nil
+}
+
+// Get 返回产出物元数据，已过期的产出物返回 ErrNotFound
+func (s *Store) Get(ctx context.Context, id string) (*Artifact, error) {
+	var a Artifact
+	if err := s.meta.Get(ctx, Collection, id, &a); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if a.Expired(s.now()) || a.TenantID != tenantOf(ctx) {
+		return nil, ErrNotFound
+	}
+	return &a, nil
+}
+
+// Open 返回产出物内容，调用方负责关闭
+func (s *Store) Open(ctx context.Context, id string) (io.ReadCloser, *Artifact, error) {
+	a, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	rc, err := s.blobs.Open(ctx, id)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, nil, ErrNotFound
+		}
+		return nil, nil, fmt.Errorf("open artifact content: %w", err)
+	}
+	return rc, a, nil
+}
+
+// List 按创建时间倒序列出产出物
+func (s *Store) List(ctx context.Context, filter Filter) ([]*Artifact, error) {
+	all, err := s.all(ctx)
+	if err != nil {
+		return nil, err
+	}
+	now, tenant := s.now(), tenantOf(ctx)
+	result := make([]*Artifact, 0, len(all))
+	for _, a := range all {
+		if a.TenantID == tenant && filter.match(a, now) {
+			result = append(result, a)
+		}
+	}
+	return result, nil
+}
+
+// SetPinned 固定或取消固定产出物，固定后不会过期
+func (s *Store) SetPinned(ctx context.Context, id string, pinned bool) (*Artifact, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	a.Pinned = pinned
+	if pinned {
+		a.ExpiresAt = nil
+	} else if s.policy.DefaultTTL > 0 {
+		expires := s.now().Add(s.policy.DefaultTTL)
+		a.ExpiresAt = &expires
+	}
+	if err := s.meta.Set(ctx, Collection, id, a); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// Delete 删除产出物的内容和元数据
+func (s *Store) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var a Artifact
+	if err := s.meta.Get(ctx, Collection, id, &a); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return ErrNotFound
+		}
+		return err
+	}
+	if a.TenantID != tenantOf(ctx) {
+		return ErrNotFound
+	}
+	return s.deleteLocked(ctx, id)
+}
+
+func (s *Store) deleteLocked(ctx context.Context, id string) error {
+	if err := s.blobs.Delete(ctx, id); err != nil && !errors.Is(err, ErrNotFound) {
+		return fmt.Errorf("delete artifact content: %w", err)
+	}
+	return s.meta.Delete(ctx, Collection, id)
+}
+
+// all 读取全部元数据，按创建时间倒序
+func (s *Store) all(ctx context.Context) ([]*Artifact, error) {
+	items, err := s.meta.List(ctx, Collection)
+	if err != nil {
+		return nil, err
+	}
+	artifacts := make([]*Artifact, 0, len(items))
+	for _, item := range items {
+		var a Artifact
+		if err := store.DecodeValue(item, &a); err != nil || a.ID == "" {
+			continue
+		}
+		artifacts = append(artifacts, &a)
+	}
+	slices.SortFunc(artifacts, func(a, b *Artifact) int {
+		return b.CreatedAt.Compare(a.CreatedAt)
+	})
+	return artifacts, nil
+}
+
+// tenantOf 返回 context 中的租户，没有时为空
+func tenantOf(ctx context.Context) string {
+	return multitenancy.GetTenantIDOrDefault(ctx, "")
+}
+
+// cleanName 去掉目录部分，只保留文件名
+func cleanName(name string) string {
+	name = path.Base(strings.ReplaceAll(strings.TrimSpace(name), "\\", "/"))
+	if name == "." || name == "/" {
+		return ""
+	}
+	return name
+}
+
+// kindOf 按 MIME 类型推断产出物类型
+func kindOf(mimeType string) Kind {
+	media, _, _ := mime.ParseMediaType(mimeType)
+	switch {
+	case strings.HasPrefix(media, "image/"):
+		return KindImage
+	case media == "text/html", media == "text/markdown", media == "application/pdf":
+		return KindReport
+	case media == "application/json", media == "text/csv", strings.HasSuffix(media, "+json"):
+		return KindData
+	default:
+		return KindFile
+	}
+}
+
+// sniffWriter 保存内容开头用于识别 MIME 类型
+type sniffWriter struct {
+	buf []byte
+}
+
+func (w *sniffWriter) Write(p []byte) (int, error) {
+	if n := 512 - len(w.buf); n > 0 {
+		w.buf = append(w.buf, p[:min(n, len(p))]...)
+	}
+	return len(p), nil
+}
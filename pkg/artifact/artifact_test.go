@@ -0,0 +1,190 @@
+package artifact
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/astercloud/aster/pkg/multitenancy"
+	"github.com/astercloud/aster/pkg/store"
+)
+
+func newTestStore(t *testing.T, policy Policy) *Store {
+	t.Helper()
+	meta, err := store.NewJSONStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewJSONStore() error = %v", err)
+	}
+	blobs, err := NewFileBlobStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileBlobStore() error = %v", err)
+	}
+	return NewStore(meta, blobs, policy)
+}
+
+func save(t *testing.T, s *Store, ctx context.Context, req SaveRequest, content string) *Artifact {
+	t.Helper()
+	a, err := s.Save(ctx, req, strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("Save(%s) error = %v", req.Name, err)
+	}
+	return a
+}
+
+func TestStore_SaveOpenListDelete(t *testing.T) {
+	s := newTestStore(t, Policy{})
+	ctx := context.Background()
+
+	png := "\x89PNG\r\n\x1a\n" + strings.Repeat("x", 32)
+	shot := save(t, s, ctx, SaveRequest{SessionID: "s1", Name: "../../shot", Source: "Browser"}, png)
+	if shot.Name != "shot" || shot.MIMEType != "image/png" || shot.Kind != KindImage || shot.Size != int64(len(png)) || shot.SHA256 == "" {
+		t.Errorf("shot = %+v", shot)
+	}
+	report := save(t, s, ctx, SaveRequest{AgentID: "agent-1", Name: "report.html"}, "<h1>Report</h1>")
+	if report.Kind != KindReport {
+		t.Errorf("report kind = %s, want report", report.Kind)
+	}
+
+	rc, got, err := s.Open(ctx, shot.ID)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	data, _ := io.ReadAll(rc)
+	_ = rc.Close()
+	if string(data) != png || got.ID != shot.ID {
+		t.Errorf("Open() = %q, %+v", data, got)
+	}
+
+	list, err := s.List(ctx, Filter{SessionID: "s1", AgentID: "agent-1"})
+	if err != nil || len(list) != 2 || list[0].ID != report.ID {
+		t.Fatalf("List() = %v, %v; want both, newest first", list, err)
+	}
+	if list, _ := s.List(ctx, Filter{SessionID: "s1"}); len(list) != 1 {
+		t.Errorf("List(session) = %d artifacts, want 1", len(list))
+	}
+
+	if err := s.Delete(ctx, shot.ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, _, err := s.Open(ctx, shot.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Open() after delete error = %v, want ErrNotFound", err)
+	}
+	if err := s.Delete(ctx, shot.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Delete() twice error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestStore_TenantIsolationAndLimits(t *testing.T) {
+	s := newTestStore(t, Policy{MaxArtifactBytes: 8})
+	acme := multitenancy.WithTenantID(context.Background(), "acme")
+	other := multitenancy.WithTenantID(context.Background(), "other")
+
+	a := save(t, s, acme, SaveRequest{Name: "a.txt"}, "small")
+	if _, err := s.Get(other, a.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() from another tenant error = %v, want ErrNotFound", err)
+	}
+	if list, _ := s.List(other, Filter{}); len(list) != 0 {
+		t.Errorf("List() from another tenant = %v", list)
+	}
+	if err := s.Delete(other, a.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Delete() from another tenant error = %v", err)
+	}
+
+	if _, err := s.Save(acme, SaveRequest{Name: "big.txt"}, strings.NewReader("more than eight bytes")); err == nil {
+		t.Error("Save() should reject content over MaxArtifactBytes")
+	}
+	if _, err := s.Save(acme, SaveRequest{Name: " "}, strings.NewReader("x")); err == nil {
+		t.Error("Save() should require a name")
+	}
+}
+
+func TestStore_GC(t *testing.T) {
+	s := newTestStore(t, Policy{DefaultTTL: time.Hour, MaxPerSession: 2, MaxTotalBytes: 9})
+	ctx := context.Background()
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+
+	at := func(offset time.Duration, req SaveRequest, content string) *Artifact {
+		now = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC).Add(offset)
+		return save(t, s, ctx, req, content)
+	}
+	expired := at(-2*time.Hour, SaveRequest{SessionID: "s1", Name: "old.txt"}, "1")
+	pinned := at(-3*time.Hour, SaveRequest{SessionID: "s1", Name: "keep.txt", Pinned: true}, "22")
+	s1a := at(1*time.Minute, SaveRequest{SessionID: "s1", Name: "a.txt"}, "333")
+	s1b := at(2*time.Minute, SaveRequest{SessionID: "s1", Name: "b.txt"}, "4")
+	s1c := at(3*time.Minute, SaveRequest{SessionID: "s1", Name: "c.txt"}, "5")
+	s2 := at(4*time.Minute, SaveRequest{AgentID: "agent-2", Name: "d.txt"}, "666666")
+
+	now = time.Date(2026, 1, 1, 0, 5, 0, 0, time.UTC)
+	if _, err := s.Get(ctx, expired.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expired artifact should not be readable, err = %v", err)
+	}
+
+	result, err := s.GC(ctx)
+	if err != nil {
+		t.Fatalf("GC() error = %v", err)
+	}
+	// expired 已过期；s1a 超出每会话 2 个；剩余总大小 2+1+1+6=10 > 9，清理最早的未固定项 s1b
+	want := map[string]bool{expired.ID: true, s1a.ID: true, s1b.ID: true}
+	if result.Removed != len(want) {
+		t.Fatalf("GC() removed %v, want %d", result.IDs, len(want))
+	}
+	for _, id := range result.IDs {
+		if !want[id] {
+			t.Errorf("GC() removed unexpected artifact %s", id)
+		}
+	}
+	left, _ := s.List(ctx, Filter{IncludeExpired: true})
+	if len(left) != 3 {
+		t.Errorf("remaining = %d, want 3", len(left))
+	}
+	for _, a := range []*Artifact{pinned, s1c, s2} {
+		if _, err := s.Get(ctx, a.ID); err != nil {
+			t.Errorf("artifact %s should survive GC: %v", a.Name, err)
+		}
+	}
+
+	if _, err := s.SetPinned(ctx, pinned.ID, false); err != nil {
+		t.Fatalf("SetPinned() error = %v", err)
+	}
+	if a, _ := s.Get(ctx, pinned.ID); a.Pinned || a.ExpiresAt == nil {
+		t.Errorf("unpinned artifact = %+v, want an expiry", a)
+	}
+}
+
+func TestStore_SaveDoesNotBlockWhileWriting(t *testing.T) {
+	s := newTestStore(t, Policy{})
+	ctx := context.Background()
+
+	pr, pw := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		_, err := s.Save(ctx, SaveRequest{Name: "slow.txt"}, pr)
+		done <- err
+	}()
+	if _, err := pw.Write([]byte("partial")); err != nil {
+		t.Fatal(err)
+	}
+
+	gcDone := make(chan error, 1)
+	go func() {
+		_, err := s.GC(ctx)
+		gcDone <- err
+	}()
+	select {
+	case err := <-gcDone:
+		if err != nil {
+			t.Fatalf("GC() error = %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("GC() blocked behind a slow Save()")
+	}
+
+	_ = pw.Close()
+	if err := <-done; err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+}
@@ -0,0 +1,123 @@
+package artifact
+
+import (
+	"context"
+	"time"
+
+	"github.com/astercloud/aster/pkg/logging"
+)
+
+// Policy 产出物的有效期和清理策略，零值表示不限制
+// 固定（Pinned）的产出物不受任何清理规则影响
+type Policy struct {
+	// DefaultTTL 未指定有效期时的默认有效期
+	DefaultTTL time.Duration
+	// MaxAge 超过该时长的产出物会被清理
+	MaxAge time.Duration
+	// MaxPerSession 每个会话（没有会话时按 Agent）最多保留的产出物数，超出时清理最早的
+	MaxPerSession int
+	// MaxTotalBytes 所有产出物的总大小上限，超出时清理最早的
+	MaxTotalBytes int64
+	// MaxArtifactBytes 单个产出物的大小上限，超出时拒绝保存
+	MaxArtifactBytes int64
+}
+
+// GCResult 一次清理的结果
+type GCResult struct {
+	Removed    int      `json:"removed"`
+	FreedBytes int64    `json:"freed_bytes"`
+	IDs        []string `json:"ids,omitempty"`
+}
+
+// GC 按策略清理产出物：过期、超龄、超出会话数量上限、超出总大小上限
+func (s *Store) GC(ctx context.Context) (*GCResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	all, err := s.all(ctx)
+	if err != nil {
+		return nil, err
+	}
+	now := s.now()
+	remove := make(map[string]bool)
+
+	// all 按创建时间倒序，越靠后越早
+	perSession := make(map[string]int)
+	for _, a := range all {
+		if a.Pinned {
+			continue
+		}
+		switch {
+		case a.Expired(now):
+			remove[a.ID] = true
+		case s.policy.MaxAge > 0 && now.Sub(a.CreatedAt) > s.policy.MaxAge:
+			remove[a.ID] = true
+		case s.policy.MaxPerSession > 0:
+			owner := a.SessionID
+			if owner == "" {
+				owner = a.AgentID
+			}
+			perSession[owner]++
+			if perSession[owner] > s.policy.MaxPerSession {
+				remove[a.ID] = true
+			}
+		}
+	}
+
+	if s.policy.MaxTotalBytes > 0 {
+		var total int64
+		for _, a := range all {
+			if !remove[a.ID] {
+				total += a.Size
+			}
+		}
+		for i := len(all) - 1; i >= 0 && total > s.policy.MaxTotalBytes; i-- {
+			if a := all[i]; !a.Pinned && !remove[a.ID] {
+				remove[a.ID] = true
+				total -= a.Size
+			}
+		}
+	}
+
+	result := &GCResult{}
+	for _, a := range all {
+		if !remove[a.ID] {
+			continue
+		}
+		if err := s.deleteLocked(ctx, a.ID); err != nil {
+			logging.Warn(ctx, "artifact.gc.delete_failed", map[string]any{"id": a.ID, "error": err.Error()})
+			continue
+		}
+		result.Removed++
+		result.FreedBytes += a.Size
+		result.IDs = append(result.IDs, a.ID)
+	}
+	return result, nil
+}
+
+// RunGC 每隔 interval 执行一次清理，直到 ctx 结束
+func (s *Store) RunGC(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			result, err := s.GC(ctx)
+			if err != nil {
+				logging.Warn(ctx, "artifact.gc.failed", map[string]any{"error": err.Error()})
+				continue
+			}
+			if result.Removed > 0 {
+				logging.Info(ctx, "artifact.gc.completed", map[string]any{
+					"removed":     result.Removed,
+					"freed_bytes": result.FreedBytes,
+				})
+			}
+		}
+	}
+}
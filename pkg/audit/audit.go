@@ -0,0 +1,132 @@
+// Package audit 把权限决策、沙箱命令审计和会话事件合并为统一的审计轨迹
+// 每个租户一条哈希链：每条记录包含上一条记录的哈希，任何修改、删除或插入都会在校验时发现。
+// 记录以 JSONL 按天写入 <dir>/<tenant>/YYYY-MM-DD.jsonl，导出的文件可以用 Verify 独立校验
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// ErrTampered 审计链校验失败
+var ErrTampered = errors.New("audit trail tampered")
+
+// Source 审计记录来源
+type Source string
+
+const (
+	SourcePermission Source = "permission"
+	SourceSandbox    Source = "sandbox"
+	SourceSession    Source = "session"
+)
+
+// Entry 一条审计记录
+type Entry struct {
+	// Seq 在租户链中的序号，从 1 开始连续递增
+	Seq      int64     `json:"seq"`
+	Time     time.Time `json:"time"`
+	TenantID string    `json:"tenant_id,omitempty"`
+	AgentID  string    `json:"agent_id,omitempty"`
+	Source   Source    `json:"source"`
+	// Type 记录类型，如 permission 的 allowed/denied、session 的事件类型
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data,omitempty"`
+	// PrevHash 上一条记录的哈希，链的第一条为空
+	PrevHash string `json:"prev_hash"`
+	// Hash 本条记录（Hash 字段置空后）的 SHA-256
+	Hash string `json:"hash"`
+}
+
+// computeHash 计算记录哈希；PrevHash 参与计算，从而把记录串成链
+func computeHash(e Entry) (string, error) {
+	e.Hash = ""
+	data, err := json.Marshal(e)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// VerifyResult 校验结果
+type VerifyResult struct {
+	Entries  int    `json:"entries"`
+	TenantID string `json:"tenant_id,omitempty"`
+	FirstSeq int64  `json:"first_seq"`
+	LastSeq  int64  `json:"last_seq"`
+	// Anchor 第一条记录的 PrevHash；导出片段或按保留期清理后不从链头开始时非空，
+	// 可以与上一次导出的 LastHash 比对，确认片段之间没有缺失
+	Anchor    string    `json:"anchor,omitempty"`
+	LastHash  string    `json:"last_hash,omitempty"`
+	FirstTime time.Time `json:"first_time,omitzero"`
+	LastTime  time.Time `json:"last_time,omitzero"`
+}
+
+// Verify 校验 JSONL 审计链：每条记录的哈希、与上一条的链接和序号连续性
+// 出错时返回已校验部分的结果和包装了 ErrTampered 的错误（包含行号）
+func Verify(r io.Reader) (*VerifyResult, error) {
+	result := &VerifyResult{}
+	reader := bufio.NewReader(r)
+	var prev *Entry
+	for line := 1; ; line++ {
+		data, err := reader.ReadBytes('\n')
+		if len(bytes.TrimSpace(data)) > 0 {
+			var e Entry
+			if jerr := json.Unmarshal(data, &e); jerr != nil {
+				return result, fmt.Errorf("%w: line %d: %v", ErrTampered, line, jerr)
+			}
+			if verr := verifyEntry(&e, prev); verr != nil {
+				return result, fmt.Errorf("%w: line %d (seq %d): %v", ErrTampered, line, e.Seq, verr)
+			}
+			if prev == nil {
+				result.TenantID = e.TenantID
+				result.FirstSeq = e.Seq
+				result.Anchor = e.PrevHash
+				result.FirstTime = e.Time
+			}
+			result.Entries++
+			result.LastSeq = e.Seq
+			result.LastHash = e.Hash
+			result.LastTime = e.Time
+			prev = &e
+		}
+		if errors.Is(err, io.EOF) {
+			return result, nil
+		}
+		if err != nil {
+			return result, err
+		}
+	}
+}
+
+func verifyEntry(e, prev *Entry) error {
+	hash, err := computeHash(*e)
+	if err != nil {
+		return err
+	}
+	if hash != e.Hash {
+		return errors.New("hash mismatch")
+	}
+	if prev == nil {
+		if e.Seq < 1 || (e.Seq == 1 && e.PrevHash != "") {
+			return errors.New("invalid chain start")
+		}
+		return nil
+	}
+	switch {
+	case e.Seq != prev.Seq+1:
+		return fmt.Errorf("sequence gap after %d", prev.Seq)
+	case e.PrevHash != prev.Hash:
+		return errors.New("broken link to previous entry")
+	case e.TenantID != prev.TenantID:
+		return errors.New("tenant changed within chain")
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/astercloud/aster/pkg/multitenancy"
+)
+
+func TestTrailChainAndVerify(t *testing.T) {
+	dir := t.TempDir()
+	trail, err := Open(dir, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	acme := multitenancy.WithTenantID(ctx, "acme")
+
+	for i := range 3 {
+		if err := trail.Record(acme, SourceSandbox, "command_executed", "agt-1", map[string]any{"command": "ls", "i": i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := trail.Record(ctx, SourcePermission, "denied", "agt-2", map[string]any{"tool": "Bash"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	n, err := trail.Export("acme", time.Time{}, time.Time{}, &buf)
+	if err != nil || n != 3 {
+		t.Fatalf("export = %d, %v", n, err)
+	}
+	// 租户之间互相隔离，各自一条链
+	if strings.Contains(buf.String(), "agt-2") {
+		t.Error("export leaked another tenant's entries")
+	}
+	result, err := Verify(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	if result.Entries != 3 || result.FirstSeq != 1 || result.LastSeq != 3 || result.TenantID != "acme" || result.Anchor != "" {
+		t.Errorf("result = %+v", result)
+	}
+
+	// 重新打开后从链尾继续
+	trail.Close()
+	reopened, _ := Open(dir, Options{})
+	entry, err := reopened.Append(acme, Entry{Source: SourceSession, Type: "done", AgentID: "agt-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.Seq != 4 || entry.PrevHash != result.LastHash {
+		t.Errorf("appended seq=%d prev=%s, want 4 linked to %s", entry.Seq, entry.PrevHash, result.LastHash)
+	}
+	buf.Reset()
+	reopened.Export("acme", time.Time{}, time.Time{}, &buf)
+	if _, err := Verify(&buf); err != nil {
+		t.Fatalf("verify after reopen: %v", err)
+	}
+}
+
+func TestVerifyDetectsTampering(t *testing.T) {
+	trail, _ := Open(t.TempDir(), Options{})
+	ctx := context.Background()
+	for _, typ := range []string{"allowed", "denied", "approved"} {
+		trail.Record(ctx, SourcePermission, typ, "agt-1", map[string]any{"tool": "Bash"})
+	}
+	var buf bytes.Buffer
+	trail.Export("", time.Time{}, time.Time{}, &buf)
+	lines := strings.SplitAfter(strings.TrimSpace(buf.String()), "\n")
+
+	cases := map[string]string{
+		"edited":    strings.Replace(buf.String(), `"denied"`, `"allowed"`, 1),
+		"deleted":   lines[0] + lines[2],
+		"reordered": lines[1] + lines[0] + lines[2],
+	}
+	for name, trailData := range cases {
+		if _, err := Verify(strings.NewReader(trailData)); !errors.Is(err, ErrTampered) {
+			t.Errorf("%s: err = %v, want ErrTampered", name, err)
+		}
+	}
+
+	// 从中间开始的片段以锚点开始，仍然可以校验
+	result, err := Verify(strings.NewReader(lines[1] + lines[2]))
+	if err != nil || result.Anchor == "" {
+		t.Errorf("fragment: result = %+v, err = %v", result, err)
+	}
+}
+
+func TestTrailRetention(t *testing.T) {
+	dir := t.TempDir()
+	trail, _ := Open(dir, Options{Retention: 48 * time.Hour})
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	ctx := context.Background()
+	for _, day := range []int{-5, -3, -1, 0} {
+		trail.now = func() time.Time { return now.AddDate(0, 0, day) }
+		trail.Record(ctx, SourceSession, "done", "agt-1", nil)
+	}
+	trail.now = func() time.Time { return now }
+
+	removed, err := trail.Prune()
+	if err != nil || removed != 2 {
+		t.Fatalf("prune = %d, %v; want 2 files removed", removed, err)
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, defaultTenantDir, "*.jsonl"))
+	if len(files) != 2 {
+		t.Errorf("remaining files = %v", files)
+	}
+
+	var buf bytes.Buffer
+	trail.Export("", time.Time{}, time.Time{}, &buf)
+	result, err := Verify(&buf)
+	if err != nil || result.FirstSeq != 3 || result.Anchor == "" {
+		t.Errorf("after prune: result = %+v, err = %v", result, err)
+	}
+
+	buf.Reset()
+	n, _ := trail.Export("", now.AddDate(0, 0, -1), now, &buf)
+	if n != 1 {
+		t.Errorf("export range = %d entries, want 1", n)
+	}
+}
+
+func TestTenantDir(t *testing.T) {
+	trail, _ := Open(t.TempDir(), Options{})
+	if a, b := trail.tenantDir("a/b"), trail.tenantDir("a_b"); a == b {
+		t.Errorf("distinct tenants share directory %s", a)
+	}
+	if _, err := os.Stat(trail.tenantDir("")); !errors.Is(err, os.ErrNotExist) {
+		t.Error("tenant directories should be created lazily")
+	}
+}
@@ -0,0 +1,343 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/astercloud/aster/pkg/logging"
+	"github.com/astercloud/aster/pkg/multitenancy"
+)
+
+var auditLog = logging.ForComponent("Audit")
+
+const dayLayout = "2006-01-02"
+
+// defaultTenantDir 未设置租户时记录所在的目录
+const defaultTenantDir = "_default"
+
+// Options 审计轨迹配置
+type Options struct {
+	// Retention 保留时长，按天文件清理；0 表示永久保留
+	Retention time.Duration
+}
+
+// Trail 审计轨迹
+// 同一目录只能由一个进程写入，多个实例应使用各自的目录
+type Trail struct {
+	dir       string
+	retention time.Duration
+	now       func() time.Time
+
+	mu     sync.Mutex
+	chains map[string]*chain
+}
+
+// chain 租户链的写入状态
+type chain struct {
+	seq  int64
+	last string
+	day  string
+	file *os.File
+}
+
+// Open 打开审计轨迹目录，不存在时自动创建
+func Open(dir string, opts Options) (*Trail, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("create audit dir: %w", err)
+	}
+	return &Trail{
+		dir:       dir,
+		retention: opts.Retention,
+		now:       time.Now,
+		chains:    make(map[string]*chain),
+	}, nil
+}
+
+// Append 追加一条记录：填充序号、时间和哈希链字段，未设置租户时使用 context 中的租户
+func (t *Trail) Append(ctx context.Context, e Entry) (*Entry, error) {
+	if e.TenantID == "" {
+		e.TenantID = multitenancy.GetTenantIDOrDefault(ctx, "")
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	c, err := t.chainLocked(e.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	e.Seq = c.seq + 1
+	e.Time = t.now().UTC()
+	e.PrevHash = c.last
+	if e.Hash, err = computeHash(e); err != nil {
+		return nil, err
+	}
+	line, err := json.Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+
+	day := e.Time.Format(dayLayout)
+	if c.file == nil || c.day != day {
+		if c.file != nil {
+			_ = c.file.Close()
+		}
+		f, err := os.OpenFile(filepath.Join(t.tenantDir(e.TenantID), day+".jsonl"), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o640)
+		if err != nil {
+			return nil, fmt.Errorf("open audit file: %w", err)
+		}
+		c.file, c.day = f, day
+	}
+	if _, err := c.file.Write(append(line, '\n')); err != nil {
+		return nil, fmt.Errorf("write audit entry: %w", err)
+	}
+	c.seq, c.last = e.Seq, e.Hash
+	return &e, nil
+}
+
+// Record 追加一条记录，data 序列化为 JSON
+func (t *Trail) Record(ctx context.Context, source Source, typ, agentID string, data any) error {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("encode audit data: %w", err)
+	}
+	_, err = t.Append(ctx, Entry{AgentID: agentID, Source: source, Type: typ, Data: raw})
+	return err
+}
+
+// chainLocked 返回租户链的写入状态，首次使用时从最新的文件恢复链尾
+func (t *Trail) chainLocked(tenantID string) (*chain, error) {
+	if c, ok := t.chains[tenantID]; ok {
+		return c, nil
+	}
+	dir := t.tenantDir(tenantID)
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("create audit dir: %w", err)
+	}
+	c := &chain{}
+	days, err := t.days(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if len(days) > 0 {
+		last, err := lastEntry(filepath.Join(dir, days[len(days)-1]+".jsonl"))
+		if err != nil {
+			return nil, err
+		}
+		if last != nil {
+			c.seq, c.last = last.Seq, last.Hash
+		}
+	}
+	t.chains[tenantID] = c
+	return c, nil
+}
+
+// lastEntry 读取文件中最后一条记录
+func lastEntry(path string) (*Entry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read audit file: %w", err)
+	}
+	lines := bytes.Split(bytes.TrimSpace(data), []byte{'\n'})
+	if len(lines) == 0 || len(lines[len(lines)-1]) == 0 {
+		return nil, nil
+	}
+	var e Entry
+	if err := json.Unmarshal(lines[len(lines)-1], &e); err != nil {
+		return nil, fmt.Errorf("%w: %s: last entry: %v", ErrTampered, path, err)
+	}
+	return &e, nil
+}
+
+// tenantDir 租户目录；租户 ID 只保留安全字符，并附加哈希避免不同租户映射到同一目录
+func (t *Trail) tenantDir(tenantID string) string {
+	if tenantID == "" {
+		return filepath.Join(t.dir, defaultTenantDir)
+	}
+	safe := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-':
+			return r
+		}
+		return '_'
+	}, tenantID)
+	if safe == tenantID {
+		return filepath.Join(t.dir, safe)
+	}
+	sum := sha256.Sum256([]byte(tenantID))
+	return filepath.Join(t.dir, safe+"-"+hex.EncodeToString(sum[:4]))
+}
+
+// days 租户已有的日期文件，按日期升序
+func (t *Trail) days(tenantID string) ([]string, error) {
+	entries, err := os.ReadDir(t.tenantDir(tenantID))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var days []string
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), ".jsonl")
+		if !ok || entry.IsDir() {
+			continue
+		}
+		if _, err := time.Parse(dayLayout, name); err == nil {
+			days = append(days, name)
+		}
+	}
+	slices.Sort(days)
+	return days, nil
+}
+
+// Export 把租户在 [from, to) 之间的记录按原样写入 w；零值表示不限制
+// 导出的记录是链中连续的一段，可以用 Verify 校验
+func (t *Trail) Export(tenantID string, from, to time.Time, w io.Writer) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	days, err := t.days(tenantID)
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for _, day := range days {
+		if !from.IsZero() && day < from.UTC().Format(dayLayout) {
+			continue
+		}
+		if !to.IsZero() && day > to.UTC().Format(dayLayout) {
+			break
+		}
+		n, err := exportFile(filepath.Join(t.tenantDir(tenantID), day+".jsonl"), from, to, w)
+		count += n
+		if err != nil {
+			return count, err
+		}
+	}
+	return count, nil
+}
+
+func exportFile(path string, from, to time.Time, w io.Writer) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	count := 0
+	reader := bufio.NewReader(f)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			var e struct {
+				Time time.Time `json:"time"`
+			}
+			if jerr := json.Unmarshal(line, &e); jerr != nil {
+				return count, fmt.Errorf("%w: %s: %v", ErrTampered, path, jerr)
+			}
+			if (from.IsZero() || !e.Time.Before(from)) && (to.IsZero() || e.Time.Before(to)) {
+				if _, werr := w.Write(line); werr != nil {
+					return count, werr
+				}
+				count++
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return count, nil
+		}
+		if err != nil {
+			return count, err
+		}
+	}
+}
+
+// Prune 按保留期删除过期的日期文件，返回删除的文件数
+// 每个租户最新的文件总是保留，链尾因此可以恢复；清理后剩余记录的第一条以被删除记录的哈希为锚点
+func (t *Trail) Prune() (int, error) {
+	if t.retention <= 0 {
+		return 0, nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	cutoff := t.now().UTC().Add(-t.retention).Format(dayLayout)
+	tenants, err := os.ReadDir(t.dir)
+	if err != nil {
+		return 0, err
+	}
+	removed := 0
+	for _, tenant := range tenants {
+		if !tenant.IsDir() {
+			continue
+		}
+		dir := filepath.Join(t.dir, tenant.Name())
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return removed, err
+		}
+		var days []string
+		for _, entry := range entries {
+			if name, ok := strings.CutSuffix(entry.Name(), ".jsonl"); ok {
+				days = append(days, name)
+			}
+		}
+		slices.Sort(days)
+		for i, day := range days {
+			// 文件包含 day 当天的记录，day 早于截止日期时全部过期
+			if i == len(days)-1 || day >= cutoff {
+				break
+			}
+			if err := os.Remove(filepath.Join(dir, day+".jsonl")); err != nil {
+				return removed, err
+			}
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+// RunRetention 按 interval 周期清理，直到 ctx 取消
+func (t *Trail) RunRetention(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if n, err := t.Prune(); err != nil {
+				auditLog.Warn(ctx, "audit retention failed", map[string]any{"error": err.Error()})
+			} else if n > 0 {
+				auditLog.Info(ctx, "audit files pruned", map[string]any{"files": n})
+			}
+		}
+	}
+}
+
+// Close 关闭打开的文件
+func (t *Trail) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var errs []error
+	for _, c := range t.chains {
+		if c.file != nil {
+			errs = append(errs, c.file.Close())
+			c.file = nil
+		}
+	}
+	return errors.Join(errs...)
+}
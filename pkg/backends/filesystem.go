@@ -36,7 +36,7 @@ func (b *FilesystemBackend) ListInfo(ctx context.Context, path string) ([]FileIn
 
 	// 检查路径是否在沙箱内
 	if !b.fs.IsInside(absPath) {
-		return nil, fmt.Errorf("path outside sandbox: %s", path)
+		return nil, fmt.Errorf("%w: %s", sandbox.ErrOutsideSandbox, path)
 	}
 
 	// 读取目录
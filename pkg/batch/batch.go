@@ -0,0 +1,375 @@
+// Package batch 通过提供商的批处理接口（Anthropic Message Batches、OpenAI Batch API）执行离线任务
+// 提交的批次保存在 store.Store 中，结束后把结果写回各自的会话并按批处理价格记录用量；
+// 进程重启后可以用 Poll 继续跟踪未完成的批次
+package batch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/astercloud/aster/pkg/logging"
+	"github.com/astercloud/aster/pkg/multitenancy"
+	"github.com/astercloud/aster/pkg/provider"
+	"github.com/astercloud/aster/pkg/store"
+	"github.com/astercloud/aster/pkg/types"
+	"github.com/astercloud/aster/pkg/usage"
+)
+
+var batchLog = logging.ForComponent("Batch")
+
+// Collection 批次记录在 store.Store 中的集合名
+const Collection = "provider_batches"
+
+// DefaultPollInterval Wait 默认的轮询间隔
+const DefaultPollInterval = 30 * time.Second
+
+// ErrNotDone 批次尚未结束
+var ErrNotDone = errors.New("batch has not ended")
+
+// Job 批次中的一个离线任务
+type Job struct {
+	// ID 批次内唯一，对应提供商请求的 custom_id
+	ID string
+	// AgentID 可选：结果写回的会话。会话还没有消息时提交时先保存 Messages，结束后追加模型回复
+	AgentID  string
+	Messages []types.Message
+	Options  *provider.StreamOptions
+}
+
+// Record 持久化的批次记录
+type Record struct {
+	provider.Batch
+	TenantID string `json:"tenant_id,omitempty"`
+	Model    string `json:"model"`
+	// Jobs 任务 ID 到会话 ID 的映射（未关联会话时为空字符串）
+	Jobs        map[string]string `json:"jobs"`
+	SubmittedAt time.Time         `json:"submitted_at"`
+	// Applied 已写回的任务，部分任务回写失败后重试时跳过
+	Applied map[string]bool `json:"applied,omitempty"`
+	// Reconciled 全部结果已写回会话并记录用量
+	Reconciled   bool      `json:"reconciled,omitempty"`
+	ReconciledAt time.Time `json:"reconciled_at,omitzero"`
+}
+
+// Result 单个任务的结果
+type Result struct {
+	JobID   string
+	AgentID string
+	Message *types.Message
+	Usage   *provider.TokenUsage
+	Error   string
+}
+
+// Options Runner 配置
+type Options struct {
+	// Store 保存批次记录和会话消息，必填
+	Store store.Store
+	// Usage 可选的用量记录，记录标记为批处理调用
+	Usage usage.Recorder
+	// PollInterval Wait 的轮询间隔，默认 DefaultPollInterval
+	PollInterval time.Duration
+}
+
+// Runner 提交批次、等待完成并回写结果
+type Runner struct {
+	provider     provider.BatchProvider
+	config       *types.ModelConfig
+	store        store.Store
+	usage        usage.Recorder
+	pollInterval time.Duration
+	now          func() time.Time
+}
+
+// NewRunner 创建 Runner；提供商不支持批处理接口时返回 provider.ErrBatchNotSupported
+func NewRunner(p provider.Provider, opts Options) (*Runner, error) {
+	bp, ok := p.(provider.BatchProvider)
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", p.Config().Provider, provider.ErrBatchNotSupported)
+	}
+	if opts.Store == nil {
+		return nil, errors.New("batch runner requires a store")
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = DefaultPollInterval
+	}
+	return &Runner{
+		provider:     bp,
+		config:       p.Config(),
+		store:        opts.Store,
+		usage:        opts.Usage,
+		pollInterval: opts.PollInterval,
+		now:          time.Now,
+	}, nil
+}
+
+// Submit 提交批次并保存记录，不等待完成
+func (r *Runner) Submit(ctx context.Context, jobs []Job) (*Record, error) {
+	if len(jobs) == 0 {
+		return nil, errors.New("no jobs to submit")
+	}
+	requests := make([]provider.BatchRequest, len(jobs))
+	mapping := make(map[string]string, len(jobs))
+	for i, job := range jobs {
+		if job.ID == "" {
+			return nil, fmt.Errorf("job %d: id is required", i)
+		}
+		if _, dup := mapping[job.ID]; dup {
+			return nil, fmt.Errorf("duplicate job id %s", job.ID)
+		}
+		mapping[job.ID] = job.AgentID
+		requests[i] = provider.BatchRequest{CustomID: job.ID, Messages: job.Messages, Options: job.Options}
+	}
+
+	for _, job := range jobs {
+		if job.AgentID == "" {
+			continue
+		}
+		existing, err := r.loadMessages(ctx, job.AgentID)
+		if err != nil {
+			return nil, fmt.Errorf("load session %s: %w", job.AgentID, err)
+		}
+		if len(existing) == 0 {
+			if err := r.store.SaveMessages(ctx, job.AgentID, job.Messages); err != nil {
+				return nil, fmt.Errorf("save session %s: %w", job.AgentID, err)
+			}
+		}
+	}
+
+	batch, err := r.provider.CreateBatch(ctx, requests)
+	if err != nil {
+		return nil, fmt.Errorf("create batch: %w", err)
+	}
+	batch.Provider = r.Provider()
+	rec := &Record{
+		Batch:       *batch,
+		TenantID:    multitenancy.GetTenantIDOrDefault(ctx, ""),
+		Model:       r.config.Model,
+		Jobs:        mapping,
+		SubmittedAt: r.now().UTC(),
+	}
+	if err := r.save(ctx, rec); err != nil {
+		return nil, err
+	}
+	batchLog.Info(ctx, "batch submitted", map[string]any{"batch_id": rec.ID, "provider": rec.Provider, "jobs": len(jobs)})
+	return rec, nil
+}
+
+// Get 读取批次记录
+func (r *Runner) Get(ctx context.Context, id string) (*Record, error) {
+	var rec Record
+	if err := r.store.Get(ctx, Collection, id, &rec); err != nil {
+		return nil, fmt.Errorf("load batch %s: %w", id, err)
+	}
+	return &rec, nil
+}
+
+// List 返回全部批次记录
+func (r *Runner) List(ctx context.Context) ([]*Record, error) {
+	items, err := r.store.List(ctx, Collection)
+	if err != nil {
+		return nil, err
+	}
+	records := make([]*Record, 0, len(items))
+	for _, item := range items {
+		var rec Record
+		if err := store.DecodeValue(item, &rec); err != nil || rec.ID == "" {
+			continue
+		}
+		records = append(records, &rec)
+	}
+	return records, nil
+}
+
+// Refresh 从提供商查询最新状态并更新记录
+func (r *Runner) Refresh(ctx context.Context, id string) (*Record, error) {
+	rec, err := r.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if rec.Status.Done() {
+		return rec, nil
+	}
+	batch, err := r.provider.GetBatch(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("get batch %s: %w", id, err)
+	}
+	batch.Provider = rec.Provider
+	rec.Batch = *batch
+	if err := r.save(ctx, rec); err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
+
+// Wait 按轮询间隔查询，直到批次结束或 ctx 取消
+func (r *Runner) Wait(ctx context.Context, id string) (*Record, error) {
+	ticker := time.NewTicker(r.pollInterval)
+	defer ticker.Stop()
+	for {
+		rec, err := r.Refresh(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if rec.Status.Done() {
+			return rec, nil
+		}
+		select {
+		case <-ctx.Done():
+			return rec, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Reconcile 获取已结束批次的结果，写回会话并记录用量
+// 已回写过的批次只返回结果，不会重复写入
+func (r *Runner) Reconcile(ctx context.Context, id string) ([]Result, error) {
+	rec, err := r.Refresh(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !rec.Status.Done() {
+		return nil, fmt.Errorf("%w: %s is %s", ErrNotDone, id, rec.Status)
+	}
+	raw, err := r.provider.BatchResults(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("batch %s results: %w", id, err)
+	}
+
+	results := make([]Result, 0, len(raw))
+	seen := make(map[string]bool, len(raw))
+	for _, item := range raw {
+		result := Result{JobID: item.CustomID, AgentID: rec.Jobs[item.CustomID], Error: item.Error}
+		if item.Response != nil {
+			msg := item.Response.Message
+			result.Message = &msg
+			result.Usage = item.Response.Usage
+		}
+		seen[item.CustomID] = true
+		results = append(results, result)
+	}
+	// 批次失败或过期时部分任务可能没有结果
+	for _, jobID := range slices.Sorted(maps.Keys(rec.Jobs)) {
+		if !seen[jobID] {
+			results = append(results, Result{JobID: jobID, AgentID: rec.Jobs[jobID], Error: "no result: batch " + string(rec.Status)})
+		}
+	}
+	if rec.Reconciled {
+		return results, nil
+	}
+
+	if rec.TenantID != "" {
+		ctx = multitenancy.WithTenantID(ctx, rec.TenantID)
+	}
+	if rec.Applied == nil {
+		rec.Applied = make(map[string]bool, len(results))
+	}
+	var errs []error
+	for _, result := range results {
+		if rec.Applied[result.JobID] {
+			continue
+		}
+		if err := r.apply(ctx, rec, result); err != nil {
+			errs = append(errs, fmt.Errorf("job %s: %w", result.JobID, err))
+			continue
+		}
+		rec.Applied[result.JobID] = true
+	}
+	if len(errs) == 0 {
+		rec.Reconciled = true
+		rec.ReconciledAt = r.now().UTC()
+	}
+	if err := r.save(ctx, rec); err != nil {
+		errs = append(errs, err)
+	}
+	if len(errs) > 0 {
+		return results, errors.Join(errs...)
+	}
+	batchLog.Info(ctx, "batch reconciled", map[string]any{"batch_id": id, "results": len(results), "status": rec.Status})
+	return results, nil
+}
+
+// apply 把单个任务的回复追加到会话并记录用量
+func (r *Runner) apply(ctx context.Context, rec *Record, result Result) error {
+	if result.Message != nil && result.AgentID != "" {
+		messages, err := r.loadMessages(ctx, result.AgentID)
+		if err != nil {
+			return fmt.Errorf("load session: %w", err)
+		}
+		if err := r.store.SaveMessages(ctx, result.AgentID, append(messages, *result.Message)); err != nil {
+			return fmt.Errorf("save session: %w", err)
+		}
+	}
+	if result.Usage != nil && r.usage != nil {
+		err := r.usage.Record(ctx, usage.Record{
+			TenantID:         rec.TenantID,
+			AgentID:          result.AgentID,
+			Provider:         rec.Provider,
+			Model:            rec.Model,
+			InputTokens:      result.Usage.InputTokens,
+			OutputTokens:     result.Usage.OutputTokens,
+			CacheReadTokens:  result.Usage.CacheReadTokens,
+			CacheWriteTokens: result.Usage.CacheCreationTokens,
+			Batch:            true,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Run 提交批次，等待完成并回写结果
+func (r *Runner) Run(ctx context.Context, jobs []Job) ([]Result, error) {
+	rec, err := r.Submit(ctx, jobs)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := r.Wait(ctx, rec.ID); err != nil {
+		return nil, err
+	}
+	return r.Reconcile(ctx, rec.ID)
+}
+
+// Poll 检查所有未回写的批次，回写已结束的批次；可作为调度器的定时任务
+func (r *Runner) Poll(ctx context.Context) error {
+	records, err := r.List(ctx)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, rec := range records {
+		// 同一 store 中可能有其他提供商的批次，只处理本 Runner 提交的
+		if rec.Reconciled || rec.Provider != r.Provider() {
+			continue
+		}
+		if _, err := r.Reconcile(ctx, rec.ID); err != nil && !errors.Is(err, ErrNotDone) {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (r *Runner) loadMessages(ctx context.Context, agentID string) ([]types.Message, error) {
+	messages, err := r.store.LoadMessages(ctx, agentID)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, nil
+	}
+	return messages, err
+}
+
+// Provider 批次记录中的提供商名，与 ModelConfig.Provider 一致
+func (r *Runner) Provider() string {
+	return r.config.Provider
+}
+
+func (r *Runner) save(ctx context.Context, rec *Record) error {
+	if err := r.store.Set(ctx, Collection, rec.ID, rec); err != nil {
+		return fmt.Errorf("save batch %s: %w", rec.ID, err)
+	}
+	return nil
+}
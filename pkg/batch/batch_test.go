@@ -0,0 +1,137 @@
+package batch
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/astercloud/aster/pkg/provider"
+	"github.com/astercloud/aster/pkg/store"
+	"github.com/astercloud/aster/pkg/types"
+	"github.com/astercloud/aster/pkg/usage"
+)
+
+type fakeProvider struct {
+	provider.Provider
+	status   provider.BatchStatus
+	requests []provider.BatchRequest
+	fetches  int
+}
+
+func (f *fakeProvider) Config() *types.ModelConfig {
+	return &types.ModelConfig{Provider: "anthropic", Model: "claude-test"}
+}
+
+func (f *fakeProvider) CreateBatch(ctx context.Context, requests []provider.BatchRequest) (*provider.Batch, error) {
+	f.requests = requests
+	return &provider.Batch{ID: "batch_1", Status: provider.BatchStatusInProgress}, nil
+}
+
+func (f *fakeProvider) GetBatch(ctx context.Context, id string) (*provider.Batch, error) {
+	return &provider.Batch{ID: id, Status: f.status}, nil
+}
+
+func (f *fakeProvider) BatchResults(ctx context.Context, id string) ([]provider.BatchResult, error) {
+	f.fetches++
+	return []provider.BatchResult{{
+		CustomID: "a",
+		Response: &provider.CompleteResponse{
+			Message: types.Message{Role: types.RoleAssistant, Content: "answer a"},
+			Usage:   &provider.TokenUsage{InputTokens: 10, OutputTokens: 5},
+		},
+	}}, nil
+}
+
+func (f *fakeProvider) CancelBatch(ctx context.Context, id string) (*provider.Batch, error) {
+	return &provider.Batch{ID: id, Status: provider.BatchStatusCanceled}, nil
+}
+
+type recorder struct{ records []usage.Record }
+
+func (r *recorder) Record(ctx context.Context, rec usage.Record) error {
+	r.records = append(r.records, rec)
+	return nil
+}
+
+func newTestRunner(t *testing.T) (*Runner, *fakeProvider, store.Store, *recorder) {
+	t.Helper()
+	st, err := store.NewJSONStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	fp := &fakeProvider{status: provider.BatchStatusInProgress}
+	rec := &recorder{}
+	runner, err := NewRunner(fp, Options{Store: st, Usage: rec, PollInterval: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return runner, fp, st, rec
+}
+
+func TestRunnerReconcile(t *testing.T) {
+	ctx := context.Background()
+	runner, fp, st, rec := newTestRunner(t)
+
+	prompt := []types.Message{{Role: types.RoleUser, Content: "question a"}}
+	record, err := runner.Submit(ctx, []Job{
+		{ID: "a", AgentID: "agt-a", Messages: prompt},
+		{ID: "b", Messages: prompt},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.Provider != "anthropic" || record.Model != "claude-test" || len(fp.requests) != 2 {
+		t.Fatalf("record = %+v, requests = %d", record, len(fp.requests))
+	}
+	if msgs, _ := st.LoadMessages(ctx, "agt-a"); len(msgs) != 1 {
+		t.Fatalf("session should hold the prompt, got %d messages", len(msgs))
+	}
+
+	if _, err := runner.Reconcile(ctx, record.ID); !errors.Is(err, ErrNotDone) {
+		t.Fatalf("err = %v, want ErrNotDone", err)
+	}
+
+	fp.status = provider.BatchStatusCompleted
+	if err := runner.Poll(ctx); err != nil {
+		t.Fatal(err)
+	}
+	msgs, _ := st.LoadMessages(ctx, "agt-a")
+	if len(msgs) != 2 || msgs[1].Content != "answer a" {
+		t.Fatalf("session = %+v", msgs)
+	}
+	if len(rec.records) != 1 || !rec.records[0].Batch || rec.records[0].InputTokens != 10 || rec.records[0].AgentID != "agt-a" {
+		t.Fatalf("usage = %+v", rec.records)
+	}
+
+	results, err := runner.Reconcile(ctx, record.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || results[1].JobID != "b" || results[1].Error == "" {
+		t.Errorf("results = %+v", results)
+	}
+	if msgs, _ := st.LoadMessages(ctx, "agt-a"); len(msgs) != 2 || len(rec.records) != 1 {
+		t.Errorf("reconcile must be idempotent: %d messages, %d usage records", len(msgs), len(rec.records))
+	}
+
+	fetches := fp.fetches
+	if err := runner.Poll(ctx); err != nil || fp.fetches != fetches {
+		t.Errorf("poll should skip reconciled batches: err = %v, fetches = %d", err, fp.fetches)
+	}
+}
+
+func TestRunnerSubmitValidation(t *testing.T) {
+	ctx := context.Background()
+	runner, _, _, _ := newTestRunner(t)
+
+	if _, err := runner.Submit(ctx, nil); err == nil {
+		t.Error("empty job list should fail")
+	}
+	if _, err := runner.Submit(ctx, []Job{{ID: "a"}, {ID: "a"}}); err == nil {
+		t.Error("duplicate job ids should fail")
+	}
+	if _, err := NewRunner(struct{ provider.Provider }{&fakeProvider{}}, Options{}); !errors.Is(err, provider.ErrBatchNotSupported) {
+		t.Errorf("err = %v, want ErrBatchNotSupported", err)
+	}
+}
@@ -0,0 +1,292 @@
+package browser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/fetch"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+)
+
+// ErrNoBrowser 找不到 Chrome/Chromium
+var ErrNoBrowser = errors.New("chrome or chromium not found")
+
+const (
+	// DefaultTimeout 单个操作的默认超时
+	DefaultTimeout = 30 * time.Second
+	// DefaultScreenshotDir 默认截图保存目录
+	DefaultScreenshotDir = ".aster/screenshots"
+	// maxBlockedRequests 每次操作最多记录的被阻止请求数
+	maxBlockedRequests = 20
+)
+
+// Config 浏览器配置
+type Config struct {
+	// ExecPath Chrome 可执行文件路径，为空时由 chromedp 自动查找
+	ExecPath string
+	// Headful 显示浏览器窗口
+	Headful bool
+	// Timeout 单个操作的超时，默认 30 秒
+	Timeout time.Duration
+	// Policy 网络访问策略，为 nil 时允许所有 http(s) 地址
+	Policy *Policy
+	// ScreenshotDir 截图保存目录（相对于工作目录），默认 .aster/screenshots
+	ScreenshotDir string
+}
+
+// Page 当前页面信息
+type Page struct {
+	URL   string `json:"url"`
+	Title string `json:"title"`
+	// Blocked 本次操作中被网络策略阻止的请求
+	Blocked []string `json:"blocked,omitempty"`
+}
+
+// Manager 浏览器会话管理器
+// 每个 Agent 一个浏览器和一个标签页，首次使用时启动；所有操作串行执行
+type Manager struct {
+	config Config
+
+	mu          sync.Mutex
+	allocCancel context.CancelFunc
+	tabCtx      context.Context
+	tabCancel   context.CancelFunc
+
+	blockedMu sync.Mutex
+	blocked   []string
+}
+
+// NewManager 创建浏览器管理器，不会立即启动浏览器
+func NewManager(config Config) *Manager {
+	if config.Timeout <= 0 {
+		config.Timeout = DefaultTimeout
+	}
+	if config.Policy == nil {
+		config.Policy = NewPolicy(nil)
+	}
+	if config.ScreenshotDir == "" {
+		config.ScreenshotDir = DefaultScreenshotDir
+	}
+	return &Manager{config: config}
+}
+
+// Policy 返回网络访问策略
+func (m *Manager) Policy() *Policy {
+	return m.config.Policy
+}
+
+// ScreenshotDir 返回截图保存目录
+func (m *Manager) ScreenshotDir() string {
+	return m.config.ScreenshotDir
+}
+
+// Running 浏览器是否已启动
+func (m *Manager) Running() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.tabCtx != nil && m.tabCtx.Err() == nil
+}
+
+// Navigate 打开地址，waitFor 不为空时等待该元素出现
+func (m *Manager) Navigate(ctx context.Context, rawURL, waitFor string) (*Page, error) {
+	if err := m.config.Policy.CheckNavigation(rawURL); err != nil {
+		return nil, err
+	}
+	actions := []chromedp.Action{chromedp.Navigate(rawURL)}
+	if waitFor != "" {
+		actions = append(actions, chromedp.WaitVisible(waitFor, chromedp.ByQuery))
+	}
+	return m.run(ctx, actions...)
+}
+
+// Click 点击元素，waitFor 不为空时等待该元素出现（如点击后跳转的页面）
+func (m *Manager) Click(ctx context.Context, selector, waitFor string) (*Page, error) {
+	actions := []chromedp.Action{
+		chromedp.ScrollIntoView(selector, chromedp.ByQuery),
+		chromedp.Click(selector, chromedp.ByQuery, chromedp.NodeVisible),
+	}
+	if waitFor != "" {
+		actions = append(actions, chromedp.WaitVisible(waitFor, chromedp.ByQuery))
+	}
+	return m.run(ctx, actions...)
+}
+
+// Fill 清空输入框并输入内容，submit 为 true 时提交所在的表单
+func (m *Manager) Fill(ctx context.Context, selector, value string, submit bool) (*Page, error) {
+	actions := []chromedp.Action{
+		chromedp.WaitVisible(selector, chromedp.ByQuery),
+		chromedp.SetValue(selector, "", chromedp.ByQuery),
+		chromedp.SendKeys(selector, value, chromedp.ByQuery),
+	}
+	if submit {
+		actions = append(actions, chromedp.Submit(selector, chromedp.ByQuery))
+	}
+	return m.run(ctx, actions...)
+}
+
+// Screenshot 截取 PNG 图片；selector 为空时截取整个页面
+func (m *Manager) Screenshot(ctx context.Context, selector string) ([]byte, *Page, error) {
+	var buf []byte
+	action := chromedp.FullScreenshot(&buf, 100)
+	if selector != "" {
+		action = chromedp.Screenshot(selector, &buf, chromedp.ByQuery, chromedp.NodeVisible)
+	}
+	page, err := m.run(ctx, action)
+	if err != nil {
+		return nil, nil, err
+	}
+	return buf, page, nil
+}
+
+// Extract 提取元素的文本或 HTML（format 为 text 或 html）；selector 为空时提取整个页面
+func (m *Manager) Extract(ctx context.Context, selector, format string) (string, *Page, error) {
+	if selector == "" {
+		selector = "body"
+	}
+	var content string
+	var action chromedp.Action
+	switch format {
+	case "", "text":
+		action = chromedp.Text(selector, &content, chromedp.ByQuery)
+	case "html":
+		action = chromedp.OuterHTML(selector, &content, chromedp.ByQuery)
+	default:
+		return "", nil, fmt.Errorf("unsupported format: %s", format)
+	}
+	page, err := m.run(ctx, chromedp.WaitReady(selector, chromedp.ByQuery), action)
+	if err != nil {
+		return "", nil, err
+	}
+	return content, page, nil
+}
+
+// Close 关闭浏览器
+func (m *Manager) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.closeLocked()
+	return nil
+}
+
+func (m *Manager) closeLocked() {
+	if m.tabCancel != nil {
+		m.tabCancel()
+	}
+	if m.allocCancel != nil {
+		m.allocCancel()
+	}
+	m.tabCtx, m.tabCancel, m.allocCancel = nil, nil, nil
+}
+
+// run 在标签页中执行操作并返回当前页面信息
+// 操作受 Timeout 和调用方 ctx 限制，超时不会关闭标签页
+func (m *Manager) run(ctx context.Context, actions ...chromedp.Action) (*Page, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	tabCtx, err := m.ensureStarted()
+	if err != nil {
+		return nil, err
+	}
+	m.takeBlocked()
+
+	runCtx, cancel := context.WithTimeout(tabCtx, m.config.Timeout)
+	defer cancel()
+	stop := context.AfterFunc(ctx, cancel)
+	defer stop()
+
+	page := &Page{}
+	actions = append(actions, chromedp.Location(&page.URL), chromedp.Title(&page.Title))
+	err = chromedp.Run(runCtx, actions...)
+	page.Blocked = m.takeBlocked()
+	if err != nil {
+		if tabCtx.Err() != nil {
+			// 浏览器已退出，下次操作重新启动
+			m.closeLocked()
+		}
+		if len(page.Blocked) > 0 {
+			return nil, fmt.Errorf("%w (blocked by network policy: %s)", err, strings.Join(page.Blocked, ", "))
+		}
+		if ctx.Err() == nil && errors.Is(err, context.DeadlineExceeded) {
+			return nil, fmt.Errorf("browser action timed out after %v: %w", m.config.Timeout, context.DeadlineExceeded)
+		}
+		return nil, err
+	}
+	return page, nil
+}
+
+// ensureStarted 按需启动浏览器并开启请求拦截
+func (m *Manager) ensureStarted() (context.Context, error) {
+	if m.tabCtx != nil && m.tabCtx.Err() == nil {
+		return m.tabCtx, nil
+	}
+	m.closeLocked()
+
+	opts := append([]chromedp.ExecAllocatorOption{}, chromedp.DefaultExecAllocatorOptions[:]...)
+	if m.config.ExecPath != "" {
+		opts = append(opts, chromedp.ExecPath(m.config.ExecPath))
+	}
+	if m.config.Headful {
+		opts = append(opts, chromedp.Flag("headless", false))
+	}
+	allocCtx, allocCancel := chromedp.NewExecAllocator(context.Background(), opts...)
+	tabCtx, tabCancel := chromedp.NewContext(allocCtx)
+
+	chromedp.ListenTarget(tabCtx, func(ev any) {
+		if e, ok := ev.(*fetch.EventRequestPaused); ok {
+			go m.handleRequest(tabCtx, e)
+		}
+	})
+	// 第一次 Run 启动浏览器；拦截所有请求以执行网络策略
+	if err := chromedp.Run(tabCtx, fetch.Enable()); err != nil {
+		tabCancel()
+		allocCancel()
+		if errors.Is(err, exec.ErrNotFound) || errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("%w: install Chrome or set browser.exec_path", ErrNoBrowser)
+		}
+		return nil, fmt.Errorf("start browser: %w", err)
+	}
+	m.allocCancel, m.tabCtx, m.tabCancel = allocCancel, tabCtx, tabCancel
+	return tabCtx, nil
+}
+
+// handleRequest 放行或阻止被拦截的请求
+// 跳转后的请求会被再次拦截，每一跳都重新按策略检查
+func (m *Manager) handleRequest(tabCtx context.Context, e *fetch.EventRequestPaused) {
+	c := chromedp.FromContext(tabCtx)
+	if c == nil || c.Target == nil {
+		return
+	}
+	execCtx := cdp.WithExecutor(tabCtx, c.Target)
+	if err := m.config.Policy.CheckRequest(e.Request.URL); err != nil {
+		m.recordBlocked(e.Request.URL)
+		_ = fetch.FailRequest(e.RequestID, network.ErrorReasonBlockedByClient).Do(execCtx)
+		return
+	}
+	_ = fetch.ContinueRequest(e.RequestID).Do(execCtx)
+}
+
+func (m *Manager) recordBlocked(url string) {
+	m.blockedMu.Lock()
+	defer m.blockedMu.Unlock()
+	if len(m.blocked) < maxBlockedRequests {
+		m.blocked = append(m.blocked, url)
+	}
+}
+
+// takeBlocked 取出并清空被阻止的请求记录
+func (m *Manager) takeBlocked() []string {
+	m.blockedMu.Lock()
+	defer m.blockedMu.Unlock()
+	blocked := m.blocked
+	m.blocked = nil
+	return blocked
+}
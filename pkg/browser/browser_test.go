@@ -0,0 +1,125 @@
+package browser
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/astercloud/aster/pkg/types"
+)
+
+func TestPolicy(t *testing.T) {
+	open := NewPolicy(nil)
+	restricted := NewPolicy(&types.NetworkSandboxSettings{
+		AllowedHosts: []string{"example.com", "docs.go.dev"},
+		BlockedHosts: []string{"ads.example.com"},
+	})
+
+	tests := []struct {
+		name       string
+		policy     *Policy
+		url        string
+		navigation bool
+		wantErr    bool
+	}{
+		{"open https", open, "https://anything.test/page", true, false},
+		{"open file scheme", open, "file:///etc/passwd", true, true},
+		{"open javascript scheme", open, "javascript:alert(1)", true, true},
+		{"open missing host", open, "https:///path", true, true},
+		{"open localhost", open, "http://localhost:8080", true, false},
+		{"allowed host", restricted, "https://example.com/a", true, false},
+		{"allowed subdomain", restricted, "https://www.example.com", true, false},
+		{"allowed case insensitive", restricted, "https://Docs.Go.Dev", true, false},
+		{"blocked subdomain", restricted, "https://ads.example.com/x.js", false, true},
+		{"not allowed", restricted, "https://evil.test", true, true},
+		{"suffix is not a subdomain", restricted, "https://notexample.com", true, true},
+		{"localhost without local binding", restricted, "http://127.0.0.1:3000", true, true},
+		{"data subresource", restricted, "data:image/png;base64,AAAA", false, false},
+		{"file subresource", restricted, "file:///tmp/x", false, true},
+		{"websocket not allowed", restricted, "wss://evil.test/socket", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			check := tt.policy.CheckRequest
+			if tt.navigation {
+				check = tt.policy.CheckNavigation
+			}
+			err := check(tt.url)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("check(%q) error = %v, wantErr %v", tt.url, err, tt.wantErr)
+			}
+		})
+	}
+
+	local := NewPolicy(&types.NetworkSandboxSettings{AllowLocalBinding: true})
+	if err := local.CheckNavigation("http://localhost:5173"); err != nil {
+		t.Errorf("local binding allowed: %v", err)
+	}
+}
+
+func TestPolicy_ResolvedAddresses(t *testing.T) {
+	p := NewPolicy(&types.NetworkSandboxSettings{})
+	p.lookup = func(_ context.Context, host string) ([]net.IPAddr, error) {
+		switch host {
+		case "public.test":
+			return []net.IPAddr{{IP: net.ParseIP("93.184.216.34")}}, nil
+		case "intranet.test":
+			return []net.IPAddr{{IP: net.ParseIP("93.184.216.34")}, {IP: net.ParseIP("10.0.0.5")}}, nil
+		case "metadata.test":
+			return []net.IPAddr{{IP: net.ParseIP("169.254.169.254")}}, nil
+		case "loopback6.test":
+			return []net.IPAddr{{IP: net.ParseIP("::1")}}, nil
+		}
+		return nil, errors.New("no such host")
+	}
+
+	tests := []struct {
+		url     string
+		wantErr bool
+	}{
+		{"https://public.test/page", false},
+		{"https://intranet.test/", true},
+		{"http://metadata.test/latest/meta-data/", true},
+		{"wss://loopback6.test/socket", true},
+		{"https://unresolvable.test/", true},
+		{"http://192.168.1.1/", true},
+		{"http://169.254.169.254/", true},
+		{"http://[fd00::1]/", true},
+	}
+	for _, tt := range tests {
+		if err := p.CheckRequest(tt.url); (err != nil) != tt.wantErr {
+			t.Errorf("CheckRequest(%q) error = %v, wantErr %v", tt.url, err, tt.wantErr)
+		}
+	}
+	if err := p.CheckNavigation("http://10.1.2.3/"); !errors.Is(err, ErrBlocked) {
+		t.Errorf("CheckNavigation(private ip) error = %v, want ErrBlocked", err)
+	}
+
+	local := NewPolicy(&types.NetworkSandboxSettings{AllowLocalBinding: true})
+	local.lookup = p.lookup
+	if err := local.CheckRequest("https://intranet.test/"); err != nil {
+		t.Errorf("local binding allowed: %v", err)
+	}
+}
+
+func TestManager_BlockedNavigationDoesNotStartBrowser(t *testing.T) {
+	m := NewManager(Config{
+		ExecPath: "/nonexistent/chrome",
+		Policy:   NewPolicy(&types.NetworkSandboxSettings{AllowedHosts: []string{"example.com"}}),
+	})
+	defer m.Close()
+
+	_, err := m.Navigate(context.Background(), "https://evil.test", "")
+	if !errors.Is(err, ErrBlocked) {
+		t.Fatalf("Navigate() error = %v, want ErrBlocked", err)
+	}
+	if m.Running() {
+		t.Error("browser should not start for a blocked url")
+	}
+
+	_, err = m.Navigate(context.Background(), "https://example.com", "")
+	if !errors.Is(err, ErrNoBrowser) {
+		t.Fatalf("Navigate() error = %v, want ErrNoBrowser", err)
+	}
+}
@@ -0,0 +1,129 @@
+package browser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/astercloud/aster/pkg/types"
+)
+
+// ErrBlocked 地址被网络策略阻止
+var ErrBlocked = errors.New("blocked by network policy")
+
+// lookupTimeout 解析请求主机名的超时时间
+const lookupTimeout = 5 * time.Second
+
+// Policy 浏览器网络访问策略
+// 与本地沙箱的 CheckNetworkAccess 规则一致：阻止列表优先，配置了允许列表时只允许列表中的主机（含子域名）
+type Policy struct {
+	network *types.NetworkSandboxSettings
+	lookup  func(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+// NewPolicy 根据沙箱网络配置创建策略，network 为 nil 时允许所有 http(s) 地址
+func NewPolicy(network *types.NetworkSandboxSettings) *Policy {
+	return &Policy{network: network, lookup: net.DefaultResolver.LookupIPAddr}
+}
+
+// CheckNavigation 检查页面导航地址，只允许 http 和 https
+func (p *Policy) CheckNavigation(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid url: %w", err)
+	}
+	switch u.Scheme {
+	case "http", "https":
+	default:
+		return fmt.Errorf("%w: unsupported scheme %q", ErrBlocked, u.Scheme)
+	}
+	if u.Hostname() == "" {
+		return fmt.Errorf("invalid url: missing host")
+	}
+	return p.checkHost(u, false)
+}
+
+// CheckRequest 检查浏览器实际发出的每个请求（导航、子资源、脚本请求，以及跳转后的每一跳）
+// data:、blob:、about: 等不访问网络的地址直接放行，file: 等本地地址一律阻止
+// 不允许本地绑定时还会解析主机名，阻止解析到本机、内网或链路本地地址的域名
+func (p *Policy) CheckRequest(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("%w: invalid url", ErrBlocked)
+	}
+	switch u.Scheme {
+	case "http", "https", "ws", "wss":
+		return p.checkHost(u, true)
+	case "data", "blob", "about":
+		return nil
+	default:
+		return fmt.Errorf("%w: unsupported scheme %q", ErrBlocked, u.Scheme)
+	}
+}
+
+// checkHost 检查主机名，resolve 为 true 时解析域名并检查解析结果
+func (p *Policy) checkHost(u *url.URL, resolve bool) error {
+	if p.network == nil {
+		return nil
+	}
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+
+	if isLocalHost(host) && !p.network.AllowLocalBinding {
+		return fmt.Errorf("%w: local address %s", ErrBlocked, host)
+	}
+	for _, blocked := range p.network.BlockedHosts {
+		if matchHost(host, blocked) {
+			return fmt.Errorf("%w: %s", ErrBlocked, host)
+		}
+	}
+	if len(p.network.AllowedHosts) > 0 && !slices.ContainsFunc(p.network.AllowedHosts, func(allowed string) bool {
+		return matchHost(host, allowed)
+	}) {
+		return fmt.Errorf("%w: %s is not in the allowed hosts", ErrBlocked, host)
+	}
+	if resolve && !p.network.AllowLocalBinding && net.ParseIP(host) == nil {
+		return p.checkResolved(host)
+	}
+	return nil
+}
+
+// checkResolved 阻止解析到本地地址的域名，解析失败时同样阻止
+func (p *Policy) checkResolved(host string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), lookupTimeout)
+	defer cancel()
+	addrs, err := p.lookup(ctx, host)
+	if err != nil {
+		return fmt.Errorf("%w: cannot resolve %s", ErrBlocked, host)
+	}
+	for _, addr := range addrs {
+		if isLocalIP(addr.IP) {
+			return fmt.Errorf("%w: %s resolves to local address %s", ErrBlocked, host, addr.IP)
+		}
+	}
+	return nil
+}
+
+// matchHost 主机名相同或为其子域名
+func matchHost(host, pattern string) bool {
+	pattern = strings.ToLower(pattern)
+	return host == pattern || strings.HasSuffix(host, "."+pattern)
+}
+
+// isLocalHost 本机、内网或链路本地地址
+func isLocalHost(host string) bool {
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && isLocalIP(ip)
+}
+
+// isLocalIP 本机、内网（含云厂商元数据地址所在的链路本地网段）或未指定地址
+func isLocalIP(ip net.IP) bool {
+	return ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLoopback() || ip.IsUnspecified()
+}
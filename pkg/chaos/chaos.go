@@ -0,0 +1,201 @@
+// Package chaos 故障注入，在测试和预发环境中模拟 Provider 超时、工具失败、Store 慢写和事件丢失，
+// 为重试、模型故障转移和会话恢复提供自动化覆盖
+package chaos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/astercloud/aster/pkg/logging"
+)
+
+var chaosLog = logging.ForComponent("Chaos")
+
+// ErrInjected 所有注入的故障都包装此错误，可用 errors.Is 区分真实故障
+var ErrInjected = errors.New("chaos: injected fault")
+
+// Kind 故障类型
+type Kind string
+
+const (
+	KindProviderTimeout Kind = "provider_timeout" // Provider 调用在延迟后超时
+	KindProviderError   Kind = "provider_error"   // Provider 返回 503
+	KindToolFailure     Kind = "tool_failure"     // 工具执行失败
+	KindSlowStore       Kind = "slow_store"       // Store 写操作变慢
+	KindStoreFailure    Kind = "store_failure"    // Store 写操作失败
+	KindDroppedEvent    Kind = "dropped_event"    // 事件未投递给订阅者和外部传输
+)
+
+// Fault 单类故障的触发规则
+type Fault struct {
+	// Rate 每次调用触发的概率，0-1
+	Rate float64 `yaml:"rate" json:"rate"`
+	// DelayMs 触发时的延迟（毫秒）：超时、慢写在返回前等待，失败类故障在失败前等待
+	DelayMs int `yaml:"delay_ms,omitempty" json:"delay_ms,omitempty"`
+	// Targets 只作用于匹配的目标，支持 path.Match 通配符，为空时作用于全部
+	// Provider 为 "provider/model"，工具为工具名，Store 为方法名（如 SaveMessages），事件为事件类型
+	Targets []string `yaml:"targets,omitempty" json:"targets,omitempty"`
+	// After 前 After 次匹配的调用不触发
+	After int `yaml:"after,omitempty" json:"after,omitempty"`
+	// Limit 最多触发次数，0 表示不限
+	Limit int `yaml:"limit,omitempty" json:"limit,omitempty"`
+}
+
+// Config 故障注入配置，未设置的故障类型不注入
+type Config struct {
+	// Seed 随机种子，非 0 时结果可复现
+	Seed int64 `yaml:"seed,omitempty" json:"seed,omitempty"`
+
+	ProviderTimeout *Fault `yaml:"provider_timeout,omitempty" json:"provider_timeout,omitempty"`
+	ProviderError   *Fault `yaml:"provider_error,omitempty" json:"provider_error,omitempty"`
+	ToolFailure     *Fault `yaml:"tool_failure,omitempty" json:"tool_failure,omitempty"`
+	SlowStore       *Fault `yaml:"slow_store,omitempty" json:"slow_store,omitempty"`
+	StoreFailure    *Fault `yaml:"store_failure,omitempty" json:"store_failure,omitempty"`
+	DroppedEvents   *Fault `yaml:"dropped_events,omitempty" json:"dropped_events,omitempty"`
+}
+
+// faults 按类型返回故障规则
+func (c *Config) faults() map[Kind]*Fault {
+	return map[Kind]*Fault{
+		KindProviderTimeout: c.ProviderTimeout,
+		KindProviderError:   c.ProviderError,
+		KindToolFailure:     c.ToolFailure,
+		KindSlowStore:       c.SlowStore,
+		KindStoreFailure:    c.StoreFailure,
+		KindDroppedEvent:    c.DroppedEvents,
+	}
+}
+
+// Validate 校验配置
+func (c *Config) Validate() error {
+	for _, kind := range Kinds() {
+		f := c.faults()[kind]
+		if f == nil {
+			continue
+		}
+		switch {
+		case f.Rate < 0 || f.Rate > 1:
+			return fmt.Errorf("%s: rate must be between 0 and 1, got %v", kind, f.Rate)
+		case f.DelayMs < 0 || f.After < 0 || f.Limit < 0:
+			return fmt.Errorf("%s: delay_ms, after and limit must not be negative", kind)
+		}
+		for _, pattern := range f.Targets {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("%s: invalid target %q: %w", kind, pattern, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Kinds 返回所有故障类型
+func Kinds() []Kind {
+	return []Kind{KindProviderTimeout, KindProviderError, KindToolFailure, KindSlowStore, KindStoreFailure, KindDroppedEvent}
+}
+
+// Stats 单类故障的统计
+type Stats struct {
+	Calls    int `json:"calls"`    // 匹配目标的调用次数
+	Injected int `json:"injected"` // 实际注入次数
+}
+
+// Injector 故障注入器，可被多个 Agent 共享且并发安全
+type Injector struct {
+	config Config
+
+	mu    sync.Mutex
+	rng   *rand.Rand
+	stats map[Kind]*Stats
+}
+
+// New 创建故障注入器
+func New(config Config) *Injector {
+	seed := config.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return &Injector{
+		config: config,
+		rng:    rand.New(rand.NewSource(seed)),
+		stats:  make(map[Kind]*Stats),
+	}
+}
+
+// Config 返回注入器配置
+func (i *Injector) Config() Config {
+	return i.config
+}
+
+// Inject 判断本次调用是否注入 kind 类故障，返回触发的规则
+func (i *Injector) Inject(kind Kind, target string) (*Fault, bool) {
+	f := i.config.faults()[kind]
+	if f == nil || !matchTarget(f.Targets, target) {
+		return nil, false
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	s := i.stats[kind]
+	if s == nil {
+		s = &Stats{}
+		i.stats[kind] = s
+	}
+	s.Calls++
+	if s.Calls <= f.After || (f.Limit > 0 && s.Injected >= f.Limit) || i.rng.Float64() >= f.Rate {
+		return nil, false
+	}
+	s.Injected++
+	chaosLog.Debug(context.Background(), "fault injected", map[string]any{"kind": kind, "target": target, "count": s.Injected})
+	return f, true
+}
+
+// Stats 返回各类故障的统计
+func (i *Injector) Stats() map[Kind]Stats {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	result := make(map[Kind]Stats, len(i.stats))
+	for kind, s := range i.stats {
+		result[kind] = *s
+	}
+	return result
+}
+
+// Reset 清空统计，After 和 Limit 重新计数
+func (i *Injector) Reset() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.stats = make(map[Kind]*Stats)
+}
+
+// matchTarget 目标是否命中规则，规则为空时全部命中
+func matchTarget(patterns []string, target string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, target); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// sleep 等待故障延迟，ctx 取消时提前返回
+func sleep(ctx context.Context, f *Fault) error {
+	if f.DelayMs <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(time.Duration(f.DelayMs) * time.Millisecond)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
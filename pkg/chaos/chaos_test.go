@@ -0,0 +1,170 @@
+package chaos
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/astercloud/aster/pkg/agenterr"
+	"github.com/astercloud/aster/pkg/events"
+	"github.com/astercloud/aster/pkg/store"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+func TestInjector_Inject(t *testing.T) {
+	i := New(Config{
+		Seed:        1,
+		ToolFailure: &Fault{Rate: 1, After: 1, Limit: 2, Targets: []string{"Bash", "mcp__*"}},
+	})
+	var got []bool
+	for _, tool := range []string{"Bash", "Read", "Bash", "mcp__github__search", "Bash"} {
+		_, ok := i.Inject(KindToolFailure, tool)
+		got = append(got, ok)
+	}
+	// Read 不匹配目标；第一次匹配调用被 After 跳过；第二次注入后达到 Limit
+	want := []bool{false, false, true, true, false}
+	for n := range want {
+		if got[n] != want[n] {
+			t.Fatalf("Inject() = %v, want %v", got, want)
+		}
+	}
+	if s := i.Stats()[KindToolFailure]; s != (Stats{Calls: 4, Injected: 2}) {
+		t.Errorf("Stats() = %+v", s)
+	}
+	if _, ok := i.Inject(KindProviderError, "mock/model"); ok {
+		t.Error("unconfigured kind should not be injected")
+	}
+
+	i.Reset()
+	if _, ok := i.Inject(KindToolFailure, "Bash"); ok {
+		t.Error("After should apply again after Reset()")
+	}
+
+	// 相同种子结果可复现
+	sample := func() (hits int) {
+		i := New(Config{Seed: 42, ProviderError: &Fault{Rate: 0.5}})
+		for range 100 {
+			if _, ok := i.Inject(KindProviderError, ""); ok {
+				hits++
+			}
+		}
+		return hits
+	}
+	if a, b := sample(), sample(); a != b || a == 0 || a == 100 {
+		t.Errorf("seeded samples = %d, %d", a, b)
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	valid := Config{SlowStore: &Fault{Rate: 0.1, DelayMs: 200, Targets: []string{"Save*"}}}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("Validate() = %v", err)
+	}
+	for _, c := range []Config{
+		{ProviderError: &Fault{Rate: 1.5}},
+		{ToolFailure: &Fault{Rate: 1, Limit: -1}},
+		{DroppedEvents: &Fault{Rate: 1, Targets: []string{"["}}},
+	} {
+		if err := c.Validate(); err == nil {
+			t.Errorf("Validate(%+v) should fail", c)
+		}
+	}
+}
+
+func TestInjector_ToolFault(t *testing.T) {
+	i := New(Config{Seed: 1, ToolFailure: &Fault{Rate: 1, Limit: 1}})
+	if err := i.ToolFault(context.Background(), "Bash"); !errors.Is(err, ErrInjected) {
+		t.Errorf("ToolFault() = %v", err)
+	}
+	if err := i.ToolFault(context.Background(), "Bash"); err != nil {
+		t.Errorf("ToolFault() after limit = %v", err)
+	}
+}
+
+func TestWrapStore(t *testing.T) {
+	ctx := context.Background()
+	base, err := store.NewJSONStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	i := New(Config{
+		Seed:         1,
+		SlowStore:    &Fault{Rate: 1, DelayMs: 30, Targets: []string{"SaveMessages"}},
+		StoreFailure: &Fault{Rate: 1, Limit: 1, Targets: []string{"SaveInfo"}},
+	})
+	s := i.WrapStore(base)
+	if i.WrapStore(s) != s {
+		t.Error("WrapStore() should not wrap twice")
+	}
+
+	msgs := []types.Message{{Role: types.MessageRoleUser, Content: "hi"}}
+	start := time.Now()
+	if err := s.SaveMessages(ctx, "agt-1", msgs); err != nil {
+		t.Fatal(err)
+	}
+	if time.Since(start) < 30*time.Millisecond {
+		t.Error("SaveMessages should be delayed")
+	}
+	if loaded, err := s.LoadMessages(ctx, "agt-1"); err != nil || len(loaded) != 1 {
+		t.Errorf("LoadMessages() = %v, %v", loaded, err)
+	}
+
+	// 慢写在 ctx 取消时提前返回
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := s.SaveMessages(cancelled, "agt-1", msgs); !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled SaveMessages() = %v", err)
+	}
+
+	info := types.AgentInfo{AgentID: "agt-1"}
+	if err := s.SaveInfo(ctx, "agt-1", info); !errors.Is(err, ErrInjected) {
+		t.Errorf("SaveInfo() = %v", err)
+	}
+	if err := s.SaveInfo(ctx, "agt-1", info); err != nil {
+		t.Errorf("SaveInfo() after limit = %v", err)
+	}
+}
+
+func TestWrapProvider_ErrorCodes(t *testing.T) {
+	i := New(Config{Seed: 1, ProviderTimeout: &Fault{Rate: 1, Limit: 1}, ProviderError: &Fault{Rate: 1, Limit: 1}})
+	p := &faultyProvider{injector: i, target: "mock/model"}
+	for _, want := range []agenterr.Code{agenterr.CodeTimeout, agenterr.CodeProviderOverloaded} {
+		err := p.inject(context.Background())
+		if !errors.Is(err, ErrInjected) || agenterr.CodeOf(err) != want {
+			t.Errorf("inject() = %v (%s), want %s", err, agenterr.CodeOf(err), want)
+		}
+	}
+	if err := p.inject(context.Background()); err != nil {
+		t.Errorf("inject() after limits = %v", err)
+	}
+}
+
+func TestDropEvent(t *testing.T) {
+	i := New(Config{Seed: 1, DroppedEvents: &Fault{Rate: 1, Targets: []string{"text_chunk", "done"}}})
+	config := events.DefaultEventBusConfig()
+	config.Drop = i.DropEvent
+	bus := events.NewEventBusWithConfig(config)
+	defer bus.Close()
+	ch := bus.Subscribe([]types.AgentChannel{types.ChannelProgress}, nil)
+
+	bus.EmitProgress(&types.ProgressTextChunkEvent{Delta: "lost"})
+	bus.EmitProgress(&types.ProgressTextChunkStartEvent{})
+	bus.EmitProgress(&types.ProgressDoneEvent{})
+
+	// 被丢弃的 text_chunk 不投递；done 事件始终送达
+	for _, want := range []string{"text_chunk_start", "done"} {
+		select {
+		case env := <-ch:
+			if env.Type != want {
+				t.Errorf("received %q, want %q", env.Type, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("timed out waiting for %q", want)
+		}
+	}
+	// 时间线仍保留被丢弃的事件，可通过 bookmark 补回
+	if timeline := bus.GetTimelineSince(0); len(timeline) != 3 || timeline[0].Type != "text_chunk" {
+		t.Errorf("timeline = %+v", timeline)
+	}
+}
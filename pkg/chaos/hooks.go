@@ -0,0 +1,26 @@
+package chaos
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/astercloud/aster/pkg/types"
+)
+
+// ToolFault 工具执行前的故障钩子（tools.ExecutorConfig.Fault），按 ToolFailure 注入失败，目标名为工具名
+func (i *Injector) ToolFault(ctx context.Context, tool string) error {
+	f, ok := i.Inject(KindToolFailure, tool)
+	if !ok {
+		return nil
+	}
+	if err := sleep(ctx, f); err != nil {
+		return err
+	}
+	return fmt.Errorf("%w: tool %s failed", ErrInjected, tool)
+}
+
+// DropEvent 事件投递前的丢弃钩子（events.EventBusConfig.Drop），按 DroppedEvents 丢弃，目标名为事件类型
+func (i *Injector) DropEvent(envelope types.AgentEventEnvelope) bool {
+	_, ok := i.Inject(KindDroppedEvent, envelope.Type)
+	return ok
+}
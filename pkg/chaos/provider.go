@@ -0,0 +1,95 @@
+package chaos
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/astercloud/aster/pkg/provider"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+// providerFactory 为创建的 Provider 注入故障
+type providerFactory struct {
+	factory  provider.Factory
+	injector *Injector
+}
+
+// WrapProviderFactory 包装 Provider 工厂，创建的 Provider 按 ProviderTimeout、ProviderError 注入故障
+// 已被同一注入器包装时原样返回，避免重复注入
+func (i *Injector) WrapProviderFactory(factory provider.Factory) provider.Factory {
+	if f, ok := factory.(*providerFactory); ok && f.injector == i {
+		return f
+	}
+	return &providerFactory{factory: factory, injector: i}
+}
+
+func (f *providerFactory) Create(config *types.ModelConfig) (provider.Provider, error) {
+	p, err := f.factory.Create(config)
+	if err != nil {
+		return nil, err
+	}
+	if fp, ok := p.(*faultyProvider); ok && fp.injector == f.injector {
+		return fp, nil
+	}
+	return &faultyProvider{Provider: p, injector: f.injector, target: modelTarget(config)}, nil
+}
+
+// faultyProvider 在 Stream、Complete 调用前注入故障
+type faultyProvider struct {
+	provider.Provider
+	injector *Injector
+	target   string
+}
+
+// WrapProvider 包装 Provider，目标名为 "provider/model"
+func (i *Injector) WrapProvider(p provider.Provider) provider.Provider {
+	if fp, ok := p.(*faultyProvider); ok && fp.injector == i {
+		return fp
+	}
+	return &faultyProvider{Provider: p, injector: i, target: modelTarget(p.Config())}
+}
+
+// modelTarget 返回 "provider/model" 形式的目标名
+func modelTarget(config *types.ModelConfig) string {
+	if config == nil {
+		return ""
+	}
+	return config.Provider + "/" + config.Model
+}
+
+// inject 超时在延迟后返回 context.DeadlineExceeded，错误返回 503 APIError，均包装 ErrInjected
+func (p *faultyProvider) inject(ctx context.Context) error {
+	target := p.target
+	if f, ok := p.injector.Inject(KindProviderTimeout, target); ok {
+		if err := sleep(ctx, f); err != nil {
+			return err
+		}
+		return fmt.Errorf("%w: %s request: %w", ErrInjected, target, context.DeadlineExceeded)
+	}
+	if f, ok := p.injector.Inject(KindProviderError, target); ok {
+		if err := sleep(ctx, f); err != nil {
+			return err
+		}
+		return fmt.Errorf("%w: %w", ErrInjected, &provider.APIError{
+			Provider:   target,
+			StatusCode: http.StatusServiceUnavailable,
+			Body:       "injected fault",
+		})
+	}
+	return nil
+}
+
+func (p *faultyProvider) Stream(ctx context.Context, messages []types.Message, opts *provider.StreamOptions) (<-chan provider.StreamChunk, error) {
+	if err := p.inject(ctx); err != nil {
+		return nil, err
+	}
+	return p.Provider.Stream(ctx, messages, opts)
+}
+
+func (p *faultyProvider) Complete(ctx context.Context, messages []types.Message, opts *provider.StreamOptions) (*provider.CompleteResponse, error) {
+	if err := p.inject(ctx); err != nil {
+		return nil, err
+	}
+	return p.Provider.Complete(ctx, messages, opts)
+}
@@ -0,0 +1,103 @@
+package chaos
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/astercloud/aster/pkg/store"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+// faultyStore 为写操作注入延迟和失败，读操作不受影响
+type faultyStore struct {
+	store.Store
+	injector *Injector
+}
+
+// WrapStore 包装 Store，写操作按 SlowStore、StoreFailure 注入故障，目标名为方法名
+// 已被同一注入器包装时原样返回，避免重复注入
+func (i *Injector) WrapStore(s store.Store) store.Store {
+	if fs, ok := s.(*faultyStore); ok && fs.injector == i {
+		return fs
+	}
+	return &faultyStore{Store: s, injector: i}
+}
+
+// inject 先注入延迟再注入失败
+func (s *faultyStore) inject(ctx context.Context, op string) error {
+	if f, ok := s.injector.Inject(KindSlowStore, op); ok {
+		if err := sleep(ctx, f); err != nil {
+			return err
+		}
+	}
+	if f, ok := s.injector.Inject(KindStoreFailure, op); ok {
+		if err := sleep(ctx, f); err != nil {
+			return err
+		}
+		return fmt.Errorf("%w: store %s failed", ErrInjected, op)
+	}
+	return nil
+}
+
+func (s *faultyStore) SaveMessages(ctx context.Context, agentID string, messages []types.Message) error {
+	if err := s.inject(ctx, "SaveMessages"); err != nil {
+		return err
+	}
+	return s.Store.SaveMessages(ctx, agentID, messages)
+}
+
+func (s *faultyStore) TrimMessages(ctx context.Context, agentID string, maxMessages int) error {
+	if err := s.inject(ctx, "TrimMessages"); err != nil {
+		return err
+	}
+	return s.Store.TrimMessages(ctx, agentID, maxMessages)
+}
+
+func (s *faultyStore) SaveToolCallRecords(ctx context.Context, agentID string, records []types.ToolCallRecord) error {
+	if err := s.inject(ctx, "SaveToolCallRecords"); err != nil {
+		return err
+	}
+	return s.Store.SaveToolCallRecords(ctx, agentID, records)
+}
+
+func (s *faultyStore) SaveSnapshot(ctx context.Context, agentID string, snapshot types.Snapshot) error {
+	if err := s.inject(ctx, "SaveSnapshot"); err != nil {
+		return err
+	}
+	return s.Store.SaveSnapshot(ctx, agentID, snapshot)
+}
+
+func (s *faultyStore) SaveInfo(ctx context.Context, agentID string, info types.AgentInfo) error {
+	if err := s.inject(ctx, "SaveInfo"); err != nil {
+		return err
+	}
+	return s.Store.SaveInfo(ctx, agentID, info)
+}
+
+func (s *faultyStore) SaveTodos(ctx context.Context, agentID string, todos any) error {
+	if err := s.inject(ctx, "SaveTodos"); err != nil {
+		return err
+	}
+	return s.Store.SaveTodos(ctx, agentID, todos)
+}
+
+func (s *faultyStore) DeleteAgent(ctx context.Context, agentID string) error {
+	if err := s.inject(ctx, "DeleteAgent"); err != nil {
+		return err
+	}
+	return s.Store.DeleteAgent(ctx, agentID)
+}
+
+func (s *faultyStore) Set(ctx context.Context, collection, key string, value any) error {
+	if err := s.inject(ctx, "Set"); err != nil {
+		return err
+	}
+	return s.Store.Set(ctx, collection, key, value)
+}
+
+func (s *faultyStore) Delete(ctx context.Context, collection, key string) error {
+	if err := s.inject(ctx, "Delete"); err != nil {
+		return err
+	}
+	return s.Store.Delete(ctx, collection, key)
+}
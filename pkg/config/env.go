@@ -0,0 +1,57 @@
+package config
+
+import (
+	"os"
+	"strconv"
+	"strings"
+)
+
+// providerAPIKeyEnvs 各 Provider 对应的 API Key 环境变量
+var providerAPIKeyEnvs = map[string]string{
+	"anthropic": "ANTHROPIC_API_KEY",
+	"openai":    "OPENAI_API_KEY",
+	"deepseek":  "DEEPSEEK_API_KEY",
+	"google":    "GOOGLE_API_KEY",
+}
+
+// GetEnv 读取字符串环境变量，未设置时返回默认值
+func GetEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+// GetEnvInt 读取整数环境变量，未设置或无法解析时返回默认值
+func GetEnvInt(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if intVal, err := strconv.Atoi(value); err == nil {
+			return intVal
+		}
+	}
+	return defaultValue
+}
+
+// GetEnvBool 读取布尔环境变量，未设置或无法解析时返回默认值
+func GetEnvBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if boolVal, err := strconv.ParseBool(value); err == nil {
+			return boolVal
+		}
+	}
+	return defaultValue
+}
+
+// APIKeyEnvName 返回 Provider 的 API Key 环境变量名
+// 已知 Provider 使用固定名称，其余按 <PROVIDER>_API_KEY 推导
+func APIKeyEnvName(provider string) string {
+	if envName, ok := providerAPIKeyEnvs[provider]; ok {
+		return envName
+	}
+	return strings.ToUpper(provider) + "_API_KEY"
+}
+
+// ProviderAPIKey 从环境变量读取 Provider 的 API Key
+func ProviderAPIKey(provider string) string {
+	return os.Getenv(APIKeyEnvName(provider))
+}
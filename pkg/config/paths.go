@@ -120,6 +120,11 @@ func ConfigFile() string {
 	return filepath.Join(ConfigDir(), "config.yaml")
 }
 
+// TrustFile returns the path to the workspace trust decisions file.
+func TrustFile() string {
+	return filepath.Join(ConfigDir(), "trust.yaml")
+}
+
 // SessionsDir returns the path to the sessions directory.
 func SessionsDir() string {
 	return filepath.Join(DataDir(), "sessions")
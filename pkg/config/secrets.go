@@ -0,0 +1,208 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// SecretService 在系统钥匙串中保存密钥时使用的服务名
+const SecretService = "aster"
+
+// ErrSecretNotFound 密钥不存在
+var ErrSecretNotFound = errors.New("secret not found")
+
+// SecretStore 密钥管理，保存 API Key 等不应写入配置文件的敏感信息
+type SecretStore interface {
+	// Get 读取密钥，不存在时返回 ErrSecretNotFound
+	Get(name string) (string, error)
+	Set(name, value string) error
+	// Delete 删除密钥，不存在时不报错
+	Delete(name string) error
+}
+
+// APIKeySecret 返回 Provider 的 API Key 在密钥管理中的名称
+func APIKeySecret(provider string) string {
+	return "api_key." + provider
+}
+
+// SecretsFile 返回文件密钥库路径 (ConfigDir/secrets.json)
+func SecretsFile() string {
+	return filepath.Join(ConfigDir(), "secrets.json")
+}
+
+// DefaultSecretStore 返回当前平台的密钥管理
+// macOS 使用钥匙串（security），Linux 桌面环境使用 Secret Service（secret-tool），
+// 其余情况退回到仅当前用户可读写的 SecretsFile
+func DefaultSecretStore() SecretStore {
+	switch runtime.GOOS {
+	case "darwin":
+		if _, err := exec.LookPath("security"); err == nil {
+			return &keychainStore{backend: keychainMacOS}
+		}
+	case "linux":
+		if _, err := exec.LookPath("secret-tool"); err == nil && os.Getenv("DBUS_SESSION_BUS_ADDRESS") != "" {
+			return &keychainStore{backend: keychainSecretService}
+		}
+	}
+	return NewFileSecretStore(SecretsFile())
+}
+
+// FileSecretStore 基于 JSON 文件的密钥库，文件权限为 0600
+type FileSecretStore struct {
+	mu   sync.Mutex
+	path string
+}
+
+// NewFileSecretStore 创建文件密钥库
+func NewFileSecretStore(path string) *FileSecretStore {
+	return &FileSecretStore{path: path}
+}
+
+func (f *FileSecretStore) load() (map[string]string, error) {
+	secrets := make(map[string]string)
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return secrets, nil
+		}
+		return nil, fmt.Errorf("read secrets file: %w", err)
+	}
+	if err := json.Unmarshal(data, &secrets); err != nil {
+		return nil, fmt.Errorf("parse secrets file %s: %w", f.path, err)
+	}
+	return secrets, nil
+}
+
+func (f *FileSecretStore) save(secrets map[string]string) error {
+	if err := EnsureDir(filepath.Dir(f.path)); err != nil {
+		return fmt.Errorf("create secrets dir: %w", err)
+	}
+	data, err := json.MarshalIndent(secrets, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := f.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("write secrets file: %w", err)
+	}
+	return os.Rename(tmp, f.path)
+}
+
+// Get 读取密钥
+func (f *FileSecretStore) Get(name string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	secrets, err := f.load()
+	if err != nil {
+		return "", err
+	}
+	value, ok := secrets[name]
+	if !ok {
+		return "", ErrSecretNotFound
+	}
+	return value, nil
+}
+
+// Set 保存密钥
+func (f *FileSecretStore) Set(name, value string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	secrets, err := f.load()
+	if err != nil {
+		return err
+	}
+	secrets[name] = value
+	return f.save(secrets)
+}
+
+// Delete 删除密钥
+func (f *FileSecretStore) Delete(name string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	secrets, err := f.load()
+	if err != nil {
+		return err
+	}
+	if _, ok := secrets[name]; !ok {
+		return nil
+	}
+	delete(secrets, name)
+	return f.save(secrets)
+}
+
+// keychainBackend 系统钥匙串命令行工具
+type keychainBackend int
+
+const (
+	keychainMacOS keychainBackend = iota
+	keychainSecretService
+)
+
+// keychainStore 通过系统命令访问钥匙串
+type keychainStore struct {
+	backend keychainBackend
+}
+
+func (k *keychainStore) run(stdin string, args ...string) (string, error) {
+	name := "security"
+	if k.backend == keychainSecretService {
+		name = "secret-tool"
+	}
+	cmd := exec.Command(name, args...)
+	cmd.Stdin = strings.NewReader(stdin)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("%s: %w: %s", name, err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimRight(stdout.String(), "\n"), nil
+}
+
+// Get 读取密钥
+func (k *keychainStore) Get(name string) (string, error) {
+	var (
+		value string
+		err   error
+	)
+	if k.backend == keychainMacOS {
+		value, err = k.run("", "find-generic-password", "-s", SecretService, "-a", name, "-w")
+	} else {
+		value, err = k.run("", "lookup", "service", SecretService, "account", name)
+	}
+	// 两个工具在条目不存在时都以非零状态退出
+	if err != nil || value == "" {
+		return "", ErrSecretNotFound
+	}
+	return value, nil
+}
+
+// Set 保存密钥
+func (k *keychainStore) Set(name, value string) error {
+	if k.backend == keychainMacOS {
+		_, err := k.run("", "add-generic-password", "-U", "-s", SecretService, "-a", name, "-w", value)
+		return err
+	}
+	_, err := k.run(value, "store", "--label", SecretService+" "+name, "service", SecretService, "account", name)
+	return err
+}
+
+// Delete 删除密钥
+func (k *keychainStore) Delete(name string) error {
+	if _, err := k.Get(name); errors.Is(err, ErrSecretNotFound) {
+		return nil
+	}
+	if k.backend == keychainMacOS {
+		_, err := k.run("", "delete-generic-password", "-s", SecretService, "-a", name)
+		return err
+	}
+	_, err := k.run("", "clear", "service", SecretService, "account", name)
+	return err
+}
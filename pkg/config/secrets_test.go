@@ -0,0 +1,70 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileSecretStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secrets.json")
+	store := NewFileSecretStore(path)
+
+	if _, err := store.Get("api_key.openai"); !errors.Is(err, ErrSecretNotFound) {
+		t.Fatalf("Get on empty store: err = %v", err)
+	}
+	if err := store.Set("api_key.openai", "sk-test"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("secrets file not written: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Errorf("secrets file mode = %o, want 600", perm)
+	}
+
+	value, err := NewFileSecretStore(path).Get("api_key.openai")
+	if err != nil || value != "sk-test" {
+		t.Errorf("Get = %q, %v", value, err)
+	}
+
+	if err := store.Delete("api_key.openai"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if err := store.Delete("api_key.openai"); err != nil {
+		t.Errorf("Delete of a missing secret: %v", err)
+	}
+	if _, err := store.Get("api_key.openai"); !errors.Is(err, ErrSecretNotFound) {
+		t.Errorf("Get after Delete: err = %v", err)
+	}
+}
+
+func TestAPIKeySource(t *testing.T) {
+	t.Setenv("OPENAI_API_KEY", "")
+	s := DefaultSettings()
+	store := NewFileSecretStore(filepath.Join(t.TempDir(), "secrets.json"))
+	s.UseSecretStore(store)
+
+	if key, source := s.APIKeySource("openai"); key != "" || source != "" {
+		t.Errorf("unset key: %q from %q", key, source)
+	}
+
+	if err := store.Set(APIKeySecret("openai"), "from-secrets"); err != nil {
+		t.Fatal(err)
+	}
+	if key, source := s.APIKeySource("openai"); key != "from-secrets" || source != APIKeySourceSecrets {
+		t.Errorf("secret store key: %q from %q", key, source)
+	}
+
+	s.APIKeys["openai"] = "from-settings"
+	if key, source := s.APIKeySource("openai"); key != "from-settings" || source != APIKeySourceSettings {
+		t.Errorf("settings key: %q from %q", key, source)
+	}
+
+	t.Setenv("OPENAI_API_KEY", "from-env")
+	if got := s.APIKey("openai"); got != "from-env" {
+		t.Errorf("APIKey = %q, want the environment variable", got)
+	}
+}
@@ -0,0 +1,694 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/astercloud/aster/pkg/chaos"
+	"github.com/astercloud/aster/pkg/httpclient"
+	"github.com/astercloud/aster/pkg/logging"
+	"github.com/astercloud/aster/pkg/provider"
+	"github.com/astercloud/aster/pkg/router"
+	"github.com/astercloud/aster/pkg/types"
+	"gopkg.in/yaml.v3"
+)
+
+// SettingsEnvPrefix 配置项环境变量前缀
+// 配置键 serve.port 对应环境变量 ASTER_SERVE_PORT
+const SettingsEnvPrefix = "ASTER_"
+
+// Settings CLI 统一配置
+// 优先级（低 → 高）: 默认值 < aster.yaml < 环境变量 < 命令行参数
+type Settings struct {
+	Provider string            `yaml:"provider"`
+	Model    string            `yaml:"model"`
+	APIKeys  map[string]string `yaml:"api_keys,omitempty"`
+
+	// Models 逻辑别名 -> provider/model，例如 fast: anthropic/claude-haiku-4-5
+	// model 字段可以直接引用这里的别名
+	Models map[string]string `yaml:"models,omitempty"`
+	// Tasks 任务 -> 别名或 provider/model，例如 summarize: cheap
+	Tasks map[string]string `yaml:"tasks,omitempty"`
+
+	// Keys 每个 Provider 的多个 API Key，遇到 401/429 时自动轮换
+	Keys KeySettings `yaml:"keys,omitempty"`
+
+	// HTTP 出站 HTTP 配置（代理、自定义 CA、TLS 版本、连接池），所有 Provider、WebFetch、MCP 和 Webhook 共享
+	HTTP httpclient.Config `yaml:"http,omitempty"`
+
+	Serve   ServeSettings   `yaml:"serve"`
+	Session SessionSettings `yaml:"session"`
+
+	// secrets 密钥管理，api_keys 中没有的 API Key 从这里读取
+	secrets SecretStore
+	// sources 配置键 -> 设置该值的层（file、env、flag），未记录的键来自默认值
+	sources map[string]string
+}
+
+// 配置值来源
+const (
+	SettingSourceDefault = "default"
+	SettingSourceFile    = "file"
+	SettingSourceEnv     = "env"
+	SettingSourceFlag    = "flag"
+)
+
+// KeySettings 多 API Key 轮换与健康检查配置
+type KeySettings struct {
+	// Pools Provider -> Key 列表，api_keys 等来源解析出的 Key 排在最前
+	Pools map[string][]string `yaml:"pools,omitempty"`
+	// Cooldown 被限流的 Key 暂停使用的时长，如 "1m"
+	Cooldown string `yaml:"cooldown,omitempty"`
+	// HealthInterval 健康检查间隔，如 "10m"，为空时不检查
+	HealthInterval string `yaml:"health_interval,omitempty"`
+}
+
+// ServeSettings aster serve 配置
+type ServeSettings struct {
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port"`
+	StoreDir string `yaml:"store_dir"`
+	Mode     string `yaml:"mode"`
+	// TemplatesDir 模板定义目录（YAML/JSON），为空时只使用内置模板
+	TemplatesDir string `yaml:"templates_dir"`
+	// AdminAddr 诊断端点（pprof、goroutine、EventBus、Store 延迟）监听地址，为空时不启用
+	AdminAddr string `yaml:"admin_addr,omitempty"`
+	// LogLevel 日志级别，可带组件覆盖，如 "info,Dashboard=debug"
+	LogLevel string `yaml:"log_level"`
+	// LogFormat 日志格式: console、json
+	LogFormat string `yaml:"log_format"`
+	// LogToFile 日志写入 LogDir()/serve.log 并按大小轮转，而不是 stdout
+	LogToFile bool `yaml:"log_to_file,omitempty"`
+	// Chaos 故障注入配置，仅用于预发环境的韧性验证，为空时不注入
+	Chaos *chaos.Config `yaml:"chaos,omitempty"`
+}
+
+// SessionSettings aster session 配置
+type SessionSettings struct {
+	WorkDir string `yaml:"work_dir"`
+	NoColor bool   `yaml:"no_color"`
+}
+
+// settingKind 配置项值类型
+type settingKind int
+
+const (
+	kindString settingKind = iota
+	kindInt
+	kindBool
+)
+
+// settingField 配置项 schema
+type settingField struct {
+	kind     settingKind
+	allowed  []string
+	validate func(v string) error // 可选的额外校验
+	get      func(s *Settings) string
+	set      func(s *Settings, v string)
+}
+
+// 动态配置键前缀: api_keys.<provider>、models.<alias>、tasks.<task>
+const (
+	apiKeysPrefix = "api_keys."
+	modelsPrefix  = "models."
+	tasksPrefix   = "tasks."
+)
+
+// defaultAlias 未在 models 中引用别名时，由 provider + model 构成的隐式别名
+const defaultAlias = "default"
+
+var settingFields = map[string]settingField{
+	"provider": {
+		kind: kindString,
+		get:  func(s *Settings) string { return s.Provider },
+		set:  func(s *Settings, v string) { s.Provider = v },
+	},
+	"model": {
+		kind: kindString,
+		get:  func(s *Settings) string { return s.Model },
+		set:  func(s *Settings, v string) { s.Model = v },
+	},
+	"keys.cooldown": {
+		kind:     kindString,
+		validate: validateDuration,
+		get:      func(s *Settings) string { return s.Keys.Cooldown },
+		set:      func(s *Settings, v string) { s.Keys.Cooldown = v },
+	},
+	"keys.health_interval": {
+		kind:     kindString,
+		validate: validateDuration,
+		get:      func(s *Settings) string { return s.Keys.HealthInterval },
+		set:      func(s *Settings, v string) { s.Keys.HealthInterval = v },
+	},
+	"http.proxy": {
+		kind: kindString,
+		get:  func(s *Settings) string { return s.HTTP.Proxy },
+		set:  func(s *Settings, v string) { s.HTTP.Proxy = v },
+	},
+	"http.no_proxy": {
+		kind: kindString,
+		get:  func(s *Settings) string { return s.HTTP.NoProxy },
+		set:  func(s *Settings, v string) { s.HTTP.NoProxy = v },
+	},
+	"http.ca_bundle": {
+		kind: kindString,
+		get:  func(s *Settings) string { return s.HTTP.CABundle },
+		set:  func(s *Settings, v string) { s.HTTP.CABundle = v },
+	},
+	"http.tls_min_version": {
+		kind:    kindString,
+		allowed: []string{"", "1.0", "1.1", "1.2", "1.3"},
+		get:     func(s *Settings) string { return s.HTTP.TLSMinVersion },
+		set:     func(s *Settings, v string) { s.HTTP.TLSMinVersion = v },
+	},
+	"http.max_idle_conns": {
+		kind: kindInt,
+		get:  func(s *Settings) string { return strconv.Itoa(s.HTTP.MaxIdleConns) },
+		set:  func(s *Settings, v string) { s.HTTP.MaxIdleConns, _ = strconv.Atoi(v) },
+	},
+	"http.max_idle_conns_per_host": {
+		kind: kindInt,
+		get:  func(s *Settings) string { return strconv.Itoa(s.HTTP.MaxIdleConnsPerHost) },
+		set:  func(s *Settings, v string) { s.HTTP.MaxIdleConnsPerHost, _ = strconv.Atoi(v) },
+	},
+	"http.max_conns_per_host": {
+		kind: kindInt,
+		get:  func(s *Settings) string { return strconv.Itoa(s.HTTP.MaxConnsPerHost) },
+		set:  func(s *Settings, v string) { s.HTTP.MaxConnsPerHost, _ = strconv.Atoi(v) },
+	},
+	"http.idle_conn_timeout": {
+		kind:     kindString,
+		validate: validateDuration,
+		get:      func(s *Settings) string { return s.HTTP.IdleConnTimeout },
+		set:      func(s *Settings, v string) { s.HTTP.IdleConnTimeout = v },
+	},
+	"serve.host": {
+		kind: kindString,
+		get:  func(s *Settings) string { return s.Serve.Host },
+		set:  func(s *Settings, v string) { s.Serve.Host = v },
+	},
+	"serve.port": {
+		kind: kindInt,
+		get:  func(s *Settings) string { return strconv.Itoa(s.Serve.Port) },
+		set:  func(s *Settings, v string) { s.Serve.Port, _ = strconv.Atoi(v) },
+	},
+	"serve.store_dir": {
+		kind: kindString,
+		get:  func(s *Settings) string { return s.Serve.StoreDir },
+		set:  func(s *Settings, v string) { s.Serve.StoreDir = v },
+	},
+	"serve.mode": {
+		kind:    kindString,
+		allowed: []string{"debug", "release", "test"},
+		get:     func(s *Settings) string { return s.Serve.Mode },
+		set:     func(s *Settings, v string) { s.Serve.Mode = v },
+	},
+	"serve.templates_dir": {
+		kind: kindString,
+		get:  func(s *Settings) string { return s.Serve.TemplatesDir },
+		set:  func(s *Settings, v string) { s.Serve.TemplatesDir = v },
+	},
+	"serve.admin_addr": {
+		kind: kindString,
+		get:  func(s *Settings) string { return s.Serve.AdminAddr },
+		set:  func(s *Settings, v string) { s.Serve.AdminAddr = v },
+	},
+	"serve.log_level": {
+		kind: kindString,
+		validate: func(v string) error {
+			_, _, err := logging.ParseLevelSpec(v)
+			return err
+		},
+		get: func(s *Settings) string { return s.Serve.LogLevel },
+		set: func(s *Settings, v string) { s.Serve.LogLevel = v },
+	},
+	"serve.log_format": {
+		kind:    kindString,
+		allowed: []string{logging.FormatConsole, logging.FormatJSON},
+		get:     func(s *Settings) string { return s.Serve.LogFormat },
+		set:     func(s *Settings, v string) { s.Serve.LogFormat = v },
+	},
+	"serve.log_to_file": {
+		kind: kindBool,
+		get:  func(s *Settings) string { return strconv.FormatBool(s.Serve.LogToFile) },
+		set:  func(s *Settings, v string) { s.Serve.LogToFile, _ = strconv.ParseBool(v) },
+	},
+	"session.work_dir": {
+		kind: kindString,
+		get:  func(s *Settings) string { return s.Session.WorkDir },
+		set:  func(s *Settings, v string) { s.Session.WorkDir = v },
+	},
+	"session.no_color": {
+		kind: kindBool,
+		get:  func(s *Settings) string { return strconv.FormatBool(s.Session.NoColor) },
+		set:  func(s *Settings, v string) { s.Session.NoColor, _ = strconv.ParseBool(v) },
+	},
+}
+
+// validateDuration 校验可选的时长配置，空值表示使用默认值
+func validateDuration(v string) error {
+	if v == "" {
+		return nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return fmt.Errorf("expected duration such as 30s or 10m, got %q", v)
+	}
+	if d < 0 {
+		return fmt.Errorf("duration must not be negative, got %q", v)
+	}
+	return nil
+}
+
+// DefaultSettings 返回默认配置
+func DefaultSettings() *Settings {
+	return &Settings{
+		Provider: "anthropic",
+		Model:    "claude-sonnet-4-5",
+		APIKeys:  make(map[string]string),
+		Models:   make(map[string]string),
+		Tasks:    make(map[string]string),
+		Serve: ServeSettings{
+			Host:      "0.0.0.0",
+			Port:      8080,
+			StoreDir:  ".aster",
+			Mode:      "debug",
+			LogLevel:  "info",
+			LogFormat: logging.FormatConsole,
+		},
+		Session: SessionSettings{
+			WorkDir: ".",
+		},
+	}
+}
+
+// SettingsFile 返回统一配置文件路径 (ConfigDir/aster.yaml)
+func SettingsFile() string {
+	return filepath.Join(ConfigDir(), "aster.yaml")
+}
+
+// SettingKeys 返回所有静态配置键（已排序）
+func SettingKeys() []string {
+	keys := make([]string, 0, len(settingFields))
+	for key := range settingFields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// SettingEnvName 返回配置键对应的环境变量名
+func SettingEnvName(key string) string {
+	return SettingsEnvPrefix + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+// LoadSettings 加载配置: 默认值 → 配置文件 → 环境变量
+// 配置文件不存在时不报错；命令行参数由调用方通过 Set 覆盖
+func LoadSettings(path string) (*Settings, error) {
+	s, err := LoadSettingsFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.ApplyEnv(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// LoadSettingsFile 仅加载默认值与配置文件，不应用环境变量
+// 用于 aster config set 回写文件，避免把环境变量持久化
+func LoadSettingsFile(path string) (*Settings, error) {
+	s := DefaultSettings()
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return s, nil
+		}
+		return nil, fmt.Errorf("read settings file: %w", err)
+	}
+
+	content = []byte(NewLoader().expandVariables(string(content)))
+	if err := yaml.Unmarshal(content, s); err != nil {
+		return nil, fmt.Errorf("parse settings file %s: %w", path, err)
+	}
+	s.ensureMaps()
+	var raw map[string]any
+	if err := yaml.Unmarshal(content, &raw); err == nil {
+		s.recordFileSources(raw, "")
+	}
+
+	if err := s.Validate(); err != nil {
+		return nil, fmt.Errorf("settings file %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// ApplyEnv 用 ASTER_* 环境变量覆盖配置
+func (s *Settings) ApplyEnv() error {
+	for _, key := range SettingKeys() {
+		envName := SettingEnvName(key)
+		if value, ok := os.LookupEnv(envName); ok && value != "" {
+			if err := s.SetFrom(key, value, SettingSourceEnv); err != nil {
+				return fmt.Errorf("%s: %w", envName, err)
+			}
+		}
+	}
+	return nil
+}
+
+// recordFileSources 记录配置文件中出现的静态配置键
+func (s *Settings) recordFileSources(raw map[string]any, prefix string) {
+	for name, value := range raw {
+		key := prefix + name
+		if nested, ok := value.(map[string]any); ok {
+			s.recordFileSources(nested, key+".")
+			continue
+		}
+		if _, ok := settingFields[key]; ok && value != nil {
+			s.recordSource(key, SettingSourceFile)
+		}
+	}
+}
+
+func (s *Settings) recordSource(key, source string) {
+	if s.sources == nil {
+		s.sources = make(map[string]string)
+	}
+	s.sources[key] = source
+}
+
+// Source 返回配置键的值来自哪一层：default、file、env 或 flag
+func (s *Settings) Source(key string) string {
+	if source, ok := s.sources[key]; ok {
+		return source
+	}
+	return SettingSourceDefault
+}
+
+// SetFrom 与 Set 相同，并记录值的来源
+func (s *Settings) SetFrom(key, value, source string) error {
+	if err := s.Set(key, value); err != nil {
+		return err
+	}
+	s.recordSource(key, source)
+	return nil
+}
+
+func (s *Settings) ensureMaps() {
+	if s.APIKeys == nil {
+		s.APIKeys = make(map[string]string)
+	}
+	if s.Models == nil {
+		s.Models = make(map[string]string)
+	}
+	if s.Tasks == nil {
+		s.Tasks = make(map[string]string)
+	}
+}
+
+// dynamicMap 返回动态配置键对应的 map 和子键
+func (s *Settings) dynamicMap(key string) (map[string]string, string, bool) {
+	s.ensureMaps()
+	if name, ok := strings.CutPrefix(key, apiKeysPrefix); ok {
+		return s.APIKeys, name, true
+	}
+	if name, ok := strings.CutPrefix(key, modelsPrefix); ok {
+		return s.Models, name, true
+	}
+	if name, ok := strings.CutPrefix(key, tasksPrefix); ok {
+		return s.Tasks, name, true
+	}
+	return nil, "", false
+}
+
+// Get 按点分键读取配置值
+func (s *Settings) Get(key string) (string, error) {
+	if m, name, ok := s.dynamicMap(key); ok {
+		return m[name], nil
+	}
+	field, ok := settingFields[key]
+	if !ok {
+		return "", fmt.Errorf("unknown setting: %s", key)
+	}
+	return field.get(s), nil
+}
+
+// Set 按点分键设置配置值，并按 schema 校验
+func (s *Settings) Set(key, value string) error {
+	if m, name, ok := s.dynamicMap(key); ok {
+		if name == "" {
+			return fmt.Errorf("%s requires a name", strings.TrimSuffix(key, "."))
+		}
+		// 空值表示删除
+		if value == "" {
+			delete(m, name)
+			return nil
+		}
+		if strings.HasPrefix(key, modelsPrefix) {
+			if _, _, ok := router.ParseModelRef(value); !ok {
+				return fmt.Errorf("%s: expected provider/model, got %q", key, value)
+			}
+		}
+		m[name] = value
+		return nil
+	}
+
+	field, ok := settingFields[key]
+	if !ok {
+		return fmt.Errorf("unknown setting: %s", key)
+	}
+	if err := field.check(value); err != nil {
+		return fmt.Errorf("%s: %w", key, err)
+	}
+	field.set(s, value)
+	return nil
+}
+
+// check 校验值是否符合配置项 schema
+func (f settingField) check(value string) error {
+	switch f.kind {
+	case kindInt:
+		if _, err := strconv.Atoi(value); err != nil {
+			return fmt.Errorf("expected integer, got %q", value)
+		}
+	case kindBool:
+		if _, err := strconv.ParseBool(value); err != nil {
+			return fmt.Errorf("expected boolean, got %q", value)
+		}
+	}
+	if len(f.allowed) > 0 && !slices.Contains(f.allowed, value) {
+		return fmt.Errorf("must be one of %v, got %q", f.allowed, value)
+	}
+	if f.validate != nil {
+		return f.validate(value)
+	}
+	return nil
+}
+
+// Validate 校验配置整体合法性
+func (s *Settings) Validate() error {
+	if s.Provider == "" {
+		return errors.New("provider is required")
+	}
+	if s.Model == "" {
+		return errors.New("model is required")
+	}
+	if s.Serve.Port <= 0 || s.Serve.Port > 65535 {
+		return fmt.Errorf("serve.port must be between 1 and 65535, got %d", s.Serve.Port)
+	}
+	for _, key := range SettingKeys() {
+		field := settingFields[key]
+		if err := field.check(field.get(s)); err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+	}
+	if _, err := s.Router(); err != nil {
+		return fmt.Errorf("models: %w", err)
+	}
+	if err := s.HTTP.Validate(); err != nil {
+		return fmt.Errorf("http: %w", err)
+	}
+	if s.Serve.Chaos != nil {
+		if err := s.Serve.Chaos.Validate(); err != nil {
+			return fmt.Errorf("serve.chaos: %w", err)
+		}
+	}
+	return nil
+}
+
+// DefaultAlias 返回默认模型别名
+// model 引用了 models 中的别名时直接使用，否则为 provider + model 构成的隐式别名 "default"
+func (s *Settings) DefaultAlias() string {
+	if _, ok := s.Models[s.Model]; ok {
+		return s.Model
+	}
+	return defaultAlias
+}
+
+// AliasConfig 根据 models/tasks 构建别名路由配置，API Key 按 provider 自动填充
+func (s *Settings) AliasConfig() *router.AliasConfig {
+	cfg := &router.AliasConfig{
+		Aliases: make(map[string]*types.ModelConfig, len(s.Models)+1),
+		Default: s.DefaultAlias(),
+		Tasks:   maps.Clone(s.Tasks),
+	}
+	for alias, ref := range s.Models {
+		providerName, modelName, ok := router.ParseModelRef(ref)
+		if !ok {
+			continue
+		}
+		cfg.Aliases[alias] = &types.ModelConfig{
+			Provider: providerName,
+			Model:    modelName,
+			APIKey:   s.APIKey(providerName),
+		}
+	}
+	if cfg.Default == defaultAlias {
+		cfg.Aliases[defaultAlias] = &types.ModelConfig{
+			Provider: s.Provider,
+			Model:    s.Model,
+			APIKey:   s.APIKey(s.Provider),
+		}
+	}
+	return cfg
+}
+
+// Router 根据 models/tasks 构建别名路由器
+func (s *Settings) Router() (*router.AliasRouter, error) {
+	for alias, ref := range s.Models {
+		if _, _, ok := router.ParseModelRef(ref); !ok {
+			return nil, fmt.Errorf("alias %q: expected provider/model, got %q", alias, ref)
+		}
+	}
+	return router.NewAliasRouter(s.AliasConfig())
+}
+
+// API Key 来源
+const (
+	APIKeySourceEnv      = "env"
+	APIKeySourceSettings = "settings"
+	APIKeySourceSecrets  = "secrets"
+)
+
+// UseSecretStore 设置密钥管理，用于读取未写在配置文件中的 API Key
+func (s *Settings) UseSecretStore(store SecretStore) {
+	s.secrets = store
+}
+
+// APIKey 返回 Provider 的 API Key
+// 优先级：环境变量 > 配置文件中的 api_keys > 密钥管理
+func (s *Settings) APIKey(provider string) string {
+	key, _ := s.APIKeySource(provider)
+	return key
+}
+
+// APIKeySource 返回 Provider 的 API Key 及其来源，未配置时返回空字符串
+func (s *Settings) APIKeySource(provider string) (key, source string) {
+	if key := ProviderAPIKey(provider); key != "" {
+		return key, APIKeySourceEnv
+	}
+	if key := s.APIKeys[provider]; key != "" {
+		return key, APIKeySourceSettings
+	}
+	if s.secrets != nil {
+		if key, err := s.secrets.Get(APIKeySecret(provider)); err == nil && key != "" {
+			return key, APIKeySourceSecrets
+		}
+	}
+	return "", ""
+}
+
+// KeyManager 根据 keys.pools 构建多 Key 管理器，未配置 Key 池时返回 nil
+// 每个 Provider 通过 APIKey 解析出的 Key 排在池的最前面
+func (s *Settings) KeyManager() *provider.KeyManager {
+	if len(s.Keys.Pools) == 0 {
+		return nil
+	}
+	pools := make(map[string][]string, len(s.Keys.Pools))
+	for name, keys := range s.Keys.Pools {
+		if primary := s.APIKey(name); primary != "" {
+			pools[name] = append([]string{primary}, keys...)
+		} else {
+			pools[name] = slices.Clone(keys)
+		}
+	}
+	cooldown, _ := time.ParseDuration(s.Keys.Cooldown)
+	return provider.NewKeyManager(pools, provider.KeyManagerOptions{Cooldown: cooldown})
+}
+
+// KeyHealthInterval 返回 Key 健康检查间隔，未配置时返回 0
+func (s *Settings) KeyHealthInterval() time.Duration {
+	d, _ := time.ParseDuration(s.Keys.HealthInterval)
+	return d
+}
+
+// Save 将配置写入文件
+func (s *Settings) Save(path string) error {
+	if err := s.Validate(); err != nil {
+		return err
+	}
+	data, err := yaml.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("marshal settings: %w", err)
+	}
+	if err := EnsureDir(filepath.Dir(path)); err != nil {
+		return fmt.Errorf("create settings dir: %w", err)
+	}
+	// 配置文件可能包含 API Key，仅允许当前用户读写
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("write settings file: %w", err)
+	}
+	return nil
+}
+
+// Redacted 返回隐藏 API Key 的副本，用于展示
+func (s *Settings) Redacted() *Settings {
+	out := *s
+	out.Models = maps.Clone(s.Models)
+	out.Tasks = maps.Clone(s.Tasks)
+	out.APIKeys = make(map[string]string, len(s.APIKeys))
+	for provider, key := range s.APIKeys {
+		out.APIKeys[provider] = redact(key)
+	}
+	out.HTTP.Proxy = redactProxy(s.HTTP.Proxy)
+	if s.Keys.Pools != nil {
+		out.Keys.Pools = make(map[string][]string, len(s.Keys.Pools))
+		for provider, keys := range s.Keys.Pools {
+			redacted := make([]string, len(keys))
+			for i, key := range keys {
+				redacted[i] = redact(key)
+			}
+			out.Keys.Pools[provider] = redacted
+		}
+	}
+	return &out
+}
+
+// redactProxy 隐藏代理地址中的密码
+func redactProxy(proxy string) string {
+	u, err := url.Parse(proxy)
+	if err != nil || u.User == nil {
+		return proxy
+	}
+	if _, ok := u.User.Password(); ok {
+		u.User = url.UserPassword(u.User.Username(), "****")
+	}
+	return u.String()
+}
+
+func redact(value string) string {
+	if len(value) <= 8 {
+		return "****"
+	}
+	return value[:4] + "****" + value[len(value)-4:]
+}
@@ -0,0 +1,277 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadSettingsMissingFileUsesDefaults(t *testing.T) {
+	s, err := LoadSettingsFile(filepath.Join(t.TempDir(), "aster.yaml"))
+	if err != nil {
+		t.Fatalf("LoadSettingsFile failed: %v", err)
+	}
+	if s.Serve.Port != 8080 {
+		t.Errorf("expected default port 8080, got %d", s.Serve.Port)
+	}
+	if s.Provider != "anthropic" {
+		t.Errorf("expected default provider anthropic, got %q", s.Provider)
+	}
+}
+
+func TestLoadSettingsLayering(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "aster.yaml")
+	content := `
+provider: openai
+model: gpt-4o
+serve:
+  port: 9000
+  mode: release
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write settings: %v", err)
+	}
+
+	t.Setenv("ASTER_SERVE_PORT", "9100")
+	t.Setenv("ASTER_MODEL", "")
+
+	s, err := LoadSettings(path)
+	if err != nil {
+		t.Fatalf("LoadSettings failed: %v", err)
+	}
+	if s.Provider != "openai" || s.Model != "gpt-4o" {
+		t.Errorf("expected file values, got provider=%q model=%q", s.Provider, s.Model)
+	}
+	if s.Serve.Port != 9100 {
+		t.Errorf("expected env override port 9100, got %d", s.Serve.Port)
+	}
+	if s.Serve.Mode != "release" {
+		t.Errorf("expected mode release, got %q", s.Serve.Mode)
+	}
+	if s.Serve.Host != "0.0.0.0" {
+		t.Errorf("expected default host to survive partial file, got %q", s.Serve.Host)
+	}
+}
+
+func TestLoadSettingsSources(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "aster.yaml")
+	// 与默认值相同的模型也算显式配置
+	content := `
+model: claude-sonnet-4-5
+serve:
+  port: 9000
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write settings: %v", err)
+	}
+	t.Setenv("ASTER_SERVE_MODE", "release")
+
+	s, err := LoadSettings(path)
+	if err != nil {
+		t.Fatalf("LoadSettings failed: %v", err)
+	}
+	if err := s.SetFrom("session.work_dir", "/tmp", SettingSourceFlag); err != nil {
+		t.Fatal(err)
+	}
+	for key, want := range map[string]string{
+		"model":            SettingSourceFile,
+		"serve.port":       SettingSourceFile,
+		"serve.mode":       SettingSourceEnv,
+		"session.work_dir": SettingSourceFlag,
+		"provider":         SettingSourceDefault,
+	} {
+		if got := s.Source(key); got != want {
+			t.Errorf("Source(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestLoadSettingsInvalidEnv(t *testing.T) {
+	t.Setenv("ASTER_SERVE_PORT", "not-a-port")
+	if _, err := LoadSettings(filepath.Join(t.TempDir(), "aster.yaml")); err == nil {
+		t.Error("expected error for invalid ASTER_SERVE_PORT")
+	}
+}
+
+func TestSettingsSetValidation(t *testing.T) {
+	s := DefaultSettings()
+
+	tests := []struct {
+		key     string
+		value   string
+		wantErr bool
+	}{
+		{"serve.port", "9090", false},
+		{"serve.port", "abc", true},
+		{"serve.mode", "release", false},
+		{"serve.mode", "prod", true},
+		{"serve.log_level", "warn,Dashboard=debug", false},
+		{"serve.log_level", "info,Dashboard=verbose", true},
+		{"serve.log_format", "console", false},
+		{"serve.log_format", "xml", true},
+		{"session.no_color", "true", false},
+		{"session.no_color", "maybe", true},
+		{"api_keys.openai", "sk-test", false},
+		{"api_keys.", "sk-test", true},
+		{"models.fast", "anthropic/claude-haiku-4-5", false},
+		{"models.bad", "no-slash", true},
+		{"tasks.summarize", "fast", false},
+		{"unknown.key", "x", true},
+	}
+
+	for _, tt := range tests {
+		err := s.Set(tt.key, tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Set(%q, %q) error = %v, wantErr %v", tt.key, tt.value, err, tt.wantErr)
+		}
+	}
+
+	if got, _ := s.Get("serve.port"); got != "9090" {
+		t.Errorf("expected serve.port 9090, got %q", got)
+	}
+	if got, _ := s.Get("api_keys.openai"); got != "sk-test" {
+		t.Errorf("expected api_keys.openai sk-test, got %q", got)
+	}
+}
+
+func TestSettingsSaveRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "aster.yaml")
+
+	s := DefaultSettings()
+	_ = s.Set("model", "claude-opus-4")
+	_ = s.Set("api_keys.anthropic", "sk-ant-1234567890")
+	if err := s.Save(path); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	loaded, err := LoadSettingsFile(path)
+	if err != nil {
+		t.Fatalf("LoadSettingsFile failed: %v", err)
+	}
+	if loaded.Model != "claude-opus-4" {
+		t.Errorf("expected model claude-opus-4, got %q", loaded.Model)
+	}
+	if loaded.APIKeys["anthropic"] != "sk-ant-1234567890" {
+		t.Errorf("expected api key to round trip, got %q", loaded.APIKeys["anthropic"])
+	}
+	if redacted := loaded.Redacted().APIKeys["anthropic"]; redacted != "sk-a****7890" {
+		t.Errorf("unexpected redacted key %q", redacted)
+	}
+}
+
+func TestSettingsAPIKeyEnvPrecedence(t *testing.T) {
+	s := DefaultSettings()
+	s.APIKeys["deepseek"] = "from-file"
+
+	t.Setenv("DEEPSEEK_API_KEY", "")
+	if got := s.APIKey("deepseek"); got != "from-file" {
+		t.Errorf("expected file key, got %q", got)
+	}
+
+	t.Setenv("DEEPSEEK_API_KEY", "from-env")
+	if got := s.APIKey("deepseek"); got != "from-env" {
+		t.Errorf("expected env key, got %q", got)
+	}
+}
+
+func TestSettingsRouter(t *testing.T) {
+	s := DefaultSettings()
+	s.Models["fast"] = "anthropic/claude-haiku-4-5"
+	s.Models["smart"] = "anthropic/claude-opus-4"
+	s.Tasks["summarize"] = "fast"
+	s.Model = "smart"
+
+	rt, err := s.Router()
+	if err != nil {
+		t.Fatalf("Router failed: %v", err)
+	}
+	def, err := rt.SelectModel(t.Context(), nil)
+	if err != nil {
+		t.Fatalf("SelectModel failed: %v", err)
+	}
+	if def.Model != "claude-opus-4" {
+		t.Errorf("expected default alias smart, got %q", def.Model)
+	}
+	if m, ok := rt.TaskModel("summarize"); !ok || m.Model != "claude-haiku-4-5" {
+		t.Errorf("expected summarize override to resolve to fast alias, got %+v", m)
+	}
+
+	s.Tasks["plan"] = "missing"
+	if err := s.Validate(); err == nil {
+		t.Error("expected validation error for task referencing unknown alias")
+	}
+}
+
+func TestLoadSettingsChaos(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "aster.yaml")
+	content := `
+serve:
+  chaos:
+    seed: 7
+    provider_error:
+      rate: 0.2
+      targets: ["openai/*"]
+    slow_store:
+      rate: 1
+      delay_ms: 500
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write settings: %v", err)
+	}
+
+	s, err := LoadSettings(path)
+	if err != nil {
+		t.Fatalf("LoadSettings failed: %v", err)
+	}
+	c := s.Serve.Chaos
+	if c == nil || c.Seed != 7 || c.ProviderError.Rate != 0.2 || c.SlowStore.DelayMs != 500 || c.ToolFailure != nil {
+		t.Fatalf("unexpected chaos config: %+v", c)
+	}
+
+	c.ProviderError.Rate = 2
+	if err := s.Validate(); err == nil {
+		t.Error("expected validation error for chaos rate above 1")
+	}
+}
+
+func TestSettingsKeyPools(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "aster.yaml")
+	content := `
+provider: openai
+model: gpt-4o
+api_keys:
+  openai: sk-primary-key
+keys:
+  cooldown: 30s
+  health_interval: 10m
+  pools:
+    openai: [sk-backup-key-1, sk-backup-key-2]
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write settings: %v", err)
+	}
+	t.Setenv("OPENAI_API_KEY", "")
+
+	s, err := LoadSettingsFile(path)
+	if err != nil {
+		t.Fatalf("LoadSettingsFile failed: %v", err)
+	}
+	keys := s.KeyManager()
+	if keys == nil {
+		t.Fatal("expected key manager for configured pools")
+	}
+	usage := keys.Usage()
+	if len(usage) != 3 || !usage[0].Current {
+		t.Fatalf("expected primary key first plus two pooled keys, got %+v", usage)
+	}
+	if s.KeyHealthInterval().String() != "10m0s" {
+		t.Errorf("expected health interval 10m, got %s", s.KeyHealthInterval())
+	}
+	if got := s.Redacted().Keys.Pools["openai"][0]; got == "sk-backup-key-1" {
+		t.Error("expected pooled keys to be redacted")
+	}
+
+	if err := s.Set("keys.cooldown", "soon"); err == nil {
+		t.Error("expected error for invalid keys.cooldown")
+	}
+}
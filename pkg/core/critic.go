@@ -0,0 +1,218 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/astercloud/aster/pkg/agent"
+	"github.com/astercloud/aster/pkg/logging"
+	"github.com/astercloud/aster/pkg/recipe"
+	"github.com/astercloud/aster/pkg/structured"
+	"github.com/astercloud/aster/pkg/telemetry"
+)
+
+var criticLog = logging.ForComponent("CriticLoop")
+
+const defaultCriticIterations = 3
+
+// CriticOptions 生成-评审循环的配置
+type CriticOptions struct {
+	// Rubric 评审标准，每条草稿都需满足全部标准才能通过
+	Rubric []string
+	// MaxIterations 最多生成的草稿数，默认 3
+	MaxIterations int
+	// Instructions 给评审 Agent 的额外要求，如评审的严格程度
+	Instructions string
+	// Tracer 记录循环和每轮迭代的 Span，默认使用全局 Tracer
+	Tracer telemetry.Tracer
+}
+
+// CriticOptionsFromRecipe 读取 Recipe 中的评审配置，未配置 review 时返回空配置
+func CriticOptionsFromRecipe(r *recipe.Recipe) CriticOptions {
+	if r == nil || r.Review == nil {
+		return CriticOptions{}
+	}
+	return CriticOptions{
+		Rubric:        r.Review.Rubric,
+		MaxIterations: r.Review.MaxIterations,
+		Instructions:  r.Review.Instructions,
+	}
+}
+
+// CriticIteration 单轮迭代：一份草稿和对它的评审
+type CriticIteration struct {
+	Iteration int     `json:"iteration"`
+	Draft     string  `json:"draft"`
+	Approved  bool    `json:"approved"`
+	Score     float64 `json:"score"`
+	Feedback  string  `json:"feedback,omitempty"`
+	// Unmet 草稿未满足的评审标准
+	Unmet    []string      `json:"unmet,omitempty"`
+	Duration time.Duration `json:"duration"`
+}
+
+// CriticResult 生成-评审循环的结果
+type CriticResult struct {
+	// Output 最后一份草稿；未通过评审时为预算耗尽前的最后一稿
+	Output     string            `json:"output"`
+	Approved   bool              `json:"approved"`
+	Iterations []CriticIteration `json:"iterations"`
+}
+
+// criticVerdict 评审 Agent 返回的 JSON
+type criticVerdict struct {
+	Approved bool     `json:"approved"`
+	Score    float64  `json:"score"`
+	Feedback string   `json:"feedback"`
+	Unmet    []string `json:"unmet"`
+}
+
+const criticPrompt = `Review the draft below against the rubric. Approve it only if it meets every criterion.
+
+Task:
+%s
+
+Rubric:
+%s%s
+Draft:
+%s
+
+Respond with JSON only: {"approved": false, "score": 0.6, "unmet": ["<criterion>"], "feedback": "<concrete changes to make>"}`
+
+const revisePrompt = `A reviewer did not approve your previous draft.
+
+Feedback:
+%s
+%s
+Revise the draft to address the feedback and reply with the complete new version only.`
+
+// RunCriticLoop 运行生成-评审循环
+//
+// generator 完成 task 得到草稿，critic 按 Rubric 评审；未通过时把评审意见交给 generator
+// 修改，直到评审通过或达到 MaxIterations。预算耗尽时不返回错误，CriticResult.Approved 为 false。
+// 整个循环记录为一个 Span，每轮迭代为其子 Span
+func RunCriticLoop(ctx context.Context, generator, critic *agent.Agent, task string, opts CriticOptions) (*CriticResult, error) {
+	if len(opts.Rubric) == 0 {
+		return nil, errors.New("critic loop requires a rubric")
+	}
+	maxIterations := opts.MaxIterations
+	if maxIterations <= 0 {
+		maxIterations = defaultCriticIterations
+	}
+	tracer := opts.Tracer
+	if tracer == nil {
+		tracer = telemetry.GetGlobalTracer()
+	}
+
+	ctx, span := tracer.StartSpan(ctx, "critic_loop", telemetry.WithAttributes(
+		telemetry.String("aster.generator_id", generator.ID()),
+		telemetry.String("aster.critic_id", critic.ID()),
+		telemetry.Int("aster.critic.max_iterations", maxIterations),
+	))
+	defer span.End()
+
+	var rubric strings.Builder
+	for i, criterion := range opts.Rubric {
+		fmt.Fprintf(&rubric, "%d. %s\n", i+1, criterion)
+	}
+	var instructions string
+	if opts.Instructions != "" {
+		instructions = "\nReviewer instructions:\n" + opts.Instructions + "\n"
+	}
+
+	result := &CriticResult{}
+	prompt := task
+	for i := 1; i <= maxIterations; i++ {
+		iteration, err := runCriticIteration(ctx, tracer, generator, critic, i, prompt, func(draft string) string {
+			return fmt.Sprintf(criticPrompt, task, rubric.String(), instructions, draft)
+		})
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(telemetry.StatusCodeError, err.Error())
+			return result, err
+		}
+		result.Iterations = append(result.Iterations, *iteration)
+		result.Output = iteration.Draft
+		if iteration.Approved {
+			result.Approved = true
+			break
+		}
+
+		var unmet string
+		if len(iteration.Unmet) > 0 {
+			unmet = "\nUnmet criteria:\n- " + strings.Join(iteration.Unmet, "\n- ") + "\n"
+		}
+		prompt = fmt.Sprintf(revisePrompt, iteration.Feedback, unmet)
+	}
+
+	span.SetAttributes(
+		telemetry.Int("aster.critic.iterations", len(result.Iterations)),
+		telemetry.Bool("aster.critic.approved", result.Approved),
+	)
+	span.SetStatus(telemetry.StatusCodeOK, "")
+	criticLog.Info(ctx, "critic loop finished", map[string]any{
+		"generator_id": generator.ID(),
+		"critic_id":    critic.ID(),
+		"iterations":   len(result.Iterations),
+		"approved":     result.Approved,
+	})
+	return result, nil
+}
+
+// runCriticIteration 生成一份草稿并交给 critic 评审，reviewPrompt 根据草稿生成评审提示
+func runCriticIteration(ctx context.Context, tracer telemetry.Tracer, generator, critic *agent.Agent, n int, prompt string, reviewPrompt func(draft string) string) (*CriticIteration, error) {
+	ctx, span := tracer.StartSpan(ctx, "critic_loop.iteration", telemetry.WithAttributes(
+		telemetry.Int("aster.critic.iteration", n),
+	))
+	defer span.End()
+	start := time.Now()
+
+	draft, err := generator.Chat(ctx, prompt)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(telemetry.StatusCodeError, err.Error())
+		return nil, fmt.Errorf("generate draft %d: %w", n, err)
+	}
+	span.AddEvent("draft_generated", telemetry.Int("aster.critic.draft_length", len(draft.Text)))
+
+	review, err := critic.Chat(ctx, reviewPrompt(draft.Text))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(telemetry.StatusCodeError, err.Error())
+		return nil, fmt.Errorf("review draft %d: %w", n, err)
+	}
+	verdict := parseCriticVerdict(ctx, review.Text)
+
+	iteration := &CriticIteration{
+		Iteration: n,
+		Draft:     draft.Text,
+		Approved:  verdict.Approved,
+		Score:     verdict.Score,
+		Feedback:  verdict.Feedback,
+		Unmet:     verdict.Unmet,
+		Duration:  time.Since(start),
+	}
+	span.SetAttributes(
+		telemetry.Bool("aster.critic.approved", iteration.Approved),
+		telemetry.Float64("aster.critic.score", iteration.Score),
+		telemetry.Int("aster.critic.unmet", len(iteration.Unmet)),
+	)
+	span.SetStatus(telemetry.StatusCodeOK, "")
+	return iteration, nil
+}
+
+// parseCriticVerdict 解析评审结果；无法解析时视为未通过，原始回复作为修改意见
+func parseCriticVerdict(ctx context.Context, text string) criticVerdict {
+	parsed, err := structured.NewJSONParser().Parse(ctx, text, structured.OutputSpec{Enabled: true})
+	if err == nil {
+		var verdict criticVerdict
+		if json.Unmarshal([]byte(parsed.RawJSON), &verdict) == nil {
+			return verdict
+		}
+	}
+	return criticVerdict{Feedback: strings.TrimSpace(text)}
+}
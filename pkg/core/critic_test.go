@@ -0,0 +1,125 @@
+package core
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/astercloud/aster/pkg/provider"
+	"github.com/astercloud/aster/pkg/recipe"
+	"github.com/astercloud/aster/pkg/telemetry"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+// scriptedStubProvider 按顺序返回预设回复，用完后重复最后一条，并记录收到的最后一条消息
+type scriptedStubProvider struct {
+	inboxStubProvider
+	mu      sync.Mutex
+	replies []string
+	calls   int
+	prompts []string
+}
+
+func (p *scriptedStubProvider) Stream(ctx context.Context, messages []types.Message, opts *provider.StreamOptions) (<-chan provider.StreamChunk, error) {
+	p.mu.Lock()
+	reply := p.replies[min(p.calls, len(p.replies)-1)]
+	p.calls++
+	if len(messages) > 0 {
+		p.prompts = append(p.prompts, messages[len(messages)-1].GetContent())
+	}
+	p.mu.Unlock()
+
+	ch := make(chan provider.StreamChunk, 1)
+	ch <- provider.StreamChunk{Type: string(provider.ChunkTypeText), TextDelta: reply}
+	close(ch)
+	return ch, nil
+}
+
+// scriptedStubFactory 按模型名称返回对应的脚本化 Provider
+type scriptedStubFactory map[string]*scriptedStubProvider
+
+func (f scriptedStubFactory) Create(config *types.ModelConfig) (provider.Provider, error) {
+	p := f[config.Model]
+	p.config = config
+	return p, nil
+}
+
+func TestRunCriticLoop(t *testing.T) {
+	generator := &scriptedStubProvider{replies: []string{"draft one", "draft two"}}
+	critic := &scriptedStubProvider{replies: []string{
+		`{"approved": false, "score": 0.4, "unmet": ["cites sources"], "feedback": "add a source"}`,
+		`{"approved": true, "score": 0.9}`,
+	}}
+	deps := createTestDeps(t)
+	deps.ProviderFactory = scriptedStubFactory{"generator": generator, "critic": critic}
+	pool := NewPool(&PoolOptions{Dependencies: deps})
+	defer func() { _ = pool.Shutdown() }()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	genConfig := createTestConfig("writer")
+	genConfig.ModelConfig.Model = "generator"
+	writer, err := pool.Create(ctx, genConfig)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	criticConfig := createTestConfig("reviewer")
+	criticConfig.ModelConfig.Model = "critic"
+	reviewer, err := pool.Create(ctx, criticConfig)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	r, err := recipe.NewBuilder().Title("Essay").Description("Writes essays").
+		Review(3, "cites sources", "under 100% of the word limit").Build()
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	opts := CriticOptionsFromRecipe(r)
+	tracer := telemetry.NewSimpleTracer()
+	opts.Tracer = tracer
+
+	result, err := RunCriticLoop(ctx, writer, reviewer, "write an essay", opts)
+	if err != nil {
+		t.Fatalf("RunCriticLoop failed: %v", err)
+	}
+	if !result.Approved || result.Output != "draft two" || len(result.Iterations) != 2 {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+	if first := result.Iterations[0]; first.Approved || first.Feedback != "add a source" || first.Unmet[0] != "cites sources" {
+		t.Errorf("unexpected first iteration: %+v", first)
+	}
+
+	// 评审提示包含 Rubric，修改提示包含评审意见
+	if !strings.Contains(critic.prompts[0], "2. under 100% of the word limit") || !strings.Contains(critic.prompts[0], "draft one") {
+		t.Errorf("review prompt missing rubric or draft:\n%s", critic.prompts[0])
+	}
+	if !strings.Contains(generator.prompts[1], "add a source") || !strings.Contains(generator.prompts[1], "- cites sources") {
+		t.Errorf("revise prompt missing feedback:\n%s", generator.prompts[1])
+	}
+
+	var names []string
+	for _, span := range tracer.GetSpans() {
+		names = append(names, span.Name())
+	}
+	if strings.Join(names, ",") != "critic_loop,critic_loop.iteration,critic_loop.iteration" {
+		t.Errorf("unexpected spans: %v", names)
+	}
+
+	// 预算耗尽时返回最后一稿且不报错
+	critic.replies = []string{"needs work"}
+	opts.MaxIterations = 1
+	result, err = RunCriticLoop(ctx, writer, reviewer, "write another essay", opts)
+	if err != nil {
+		t.Fatalf("RunCriticLoop failed: %v", err)
+	}
+	if result.Approved || len(result.Iterations) != 1 || result.Iterations[0].Feedback != "needs work" {
+		t.Errorf("unexpected result after budget exhausted: %+v", result)
+	}
+
+	if _, err := RunCriticLoop(ctx, writer, reviewer, "task", CriticOptions{}); err == nil {
+		t.Error("expected error without rubric")
+	}
+}
@@ -0,0 +1,243 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/astercloud/aster/pkg/agent"
+	"github.com/astercloud/aster/pkg/logging"
+	"github.com/astercloud/aster/pkg/store"
+	"github.com/astercloud/aster/pkg/types"
+	"github.com/google/uuid"
+)
+
+var inboxLog = logging.ForComponent("Inbox")
+
+// inboxCollection 收件箱消息的存储集合，消息确认后删除
+const inboxCollection = "inbox"
+
+// InboxMessage 收件箱中等待投递或确认的消息
+type InboxMessage struct {
+	ID      string    `json:"id"`
+	AgentID string    `json:"agent_id"`
+	From    string    `json:"from,omitempty"`
+	Text    string    `json:"text"`
+	SentAt  time.Time `json:"sent_at"`
+	// Attempts 已投递次数，大于 1 表示确认前重新投递过
+	Attempts int `json:"attempts,omitempty"`
+	// DeliveredAt 最近一次投递时间，未投递时为空
+	DeliveredAt *time.Time `json:"delivered_at,omitempty"`
+}
+
+// InboxOptions 收件箱配置
+type InboxOptions struct {
+	// Store 持久化存储，默认使用 Pool 依赖中的 Store
+	Store store.Store
+	// AckTimeout 投递后等待 Agent 处理完成的最长时间，超时未确认的消息会重新投递，默认 10 分钟
+	AckTimeout time.Duration
+	// PollInterval Run 检查空闲 Agent 的间隔，默认 2 秒
+	PollInterval time.Duration
+}
+
+// Inbox 每个 Agent 的持久化收件箱
+//
+// 消息先写入存储，Agent 在池中且空闲时按发送顺序逐条投递；Agent 处理完一条消息
+// （本轮运行结束）后自动确认并从存储删除，再投递下一条。Agent 忙碌或不在池中时消息
+// 保留在收件箱，下次空闲时投递。投递语义为至少一次：进程重启或确认超时的消息会重新投递
+type Inbox struct {
+	pool  *Pool
+	store store.Store
+	opts  InboxOptions
+
+	mu       sync.Mutex
+	inflight map[string]string // agentID -> 在途消息 ID，每个 Agent 同时只投递一条
+}
+
+// NewInbox 创建收件箱
+func NewInbox(pool *Pool, opts InboxOptions) *Inbox {
+	if opts.Store == nil && pool.deps != nil {
+		opts.Store = pool.deps.Store
+	}
+	if opts.AckTimeout <= 0 {
+		opts.AckTimeout = 10 * time.Minute
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = 2 * time.Second
+	}
+	return &Inbox{
+		pool:     pool,
+		store:    opts.Store,
+		opts:     opts,
+		inflight: make(map[string]string),
+	}
+}
+
+// Post 将消息写入 agentID 的收件箱，Agent 空闲时立即投递
+func (ib *Inbox) Post(ctx context.Context, agentID, from, text string) (*InboxMessage, error) {
+	if ib.store == nil {
+		return nil, errors.New("inbox requires a store")
+	}
+	msg := &InboxMessage{
+		ID:      uuid.New().String(),
+		AgentID: agentID,
+		From:    from,
+		Text:    text,
+		SentAt:  time.Now(),
+	}
+	if err := ib.store.Set(ctx, inboxCollection, msg.ID, msg); err != nil {
+		return nil, fmt.Errorf("save inbox message: %w", err)
+	}
+	if _, err := ib.Deliver(ctx, agentID); err != nil {
+		inboxLog.Warn(ctx, "inbox delivery failed, message kept for retry", map[string]any{"agent_id": agentID, "message_id": msg.ID, "error": err.Error()})
+	}
+	return msg, nil
+}
+
+// Pending 返回 agentID 尚未确认的消息（含在途消息），按发送时间排序
+func (ib *Inbox) Pending(ctx context.Context, agentID string) ([]InboxMessage, error) {
+	if ib.store == nil {
+		return nil, nil
+	}
+	items, err := ib.store.List(ctx, inboxCollection)
+	if err != nil {
+		return nil, fmt.Errorf("list inbox: %w", err)
+	}
+	var pending []InboxMessage
+	for _, item := range items {
+		var msg InboxMessage
+		if err := store.DecodeValue(item, &msg); err != nil || msg.AgentID != agentID {
+			continue
+		}
+		pending = append(pending, msg)
+	}
+	slices.SortFunc(pending, func(a, b InboxMessage) int {
+		return a.SentAt.Compare(b.SentAt)
+	})
+	return pending, nil
+}
+
+// Ack 确认消息已处理并从收件箱删除，重复确认不会报错
+func (ib *Inbox) Ack(ctx context.Context, messageID string) error {
+	ib.mu.Lock()
+	for agentID, id := range ib.inflight {
+		if id == messageID {
+			delete(ib.inflight, agentID)
+		}
+	}
+	ib.mu.Unlock()
+
+	if err := ib.store.Delete(ctx, inboxCollection, messageID); err != nil && !errors.Is(err, store.ErrNotFound) {
+		return fmt.Errorf("ack inbox message: %w", err)
+	}
+	return nil
+}
+
+// Deliver Agent 在池中、空闲且没有在途消息时投递最早的一条消息，返回是否投递
+func (ib *Inbox) Deliver(ctx context.Context, agentID string) (bool, error) {
+	ib.mu.Lock()
+	defer ib.mu.Unlock()
+
+	if _, busy := ib.inflight[agentID]; busy {
+		return false, nil
+	}
+	ag, ok := ib.pool.Get(agentID)
+	if !ok || ag.Status().State != types.AgentStateReady {
+		return false, nil
+	}
+	pending, err := ib.Pending(ctx, agentID)
+	if err != nil || len(pending) == 0 {
+		return false, err
+	}
+
+	msg := pending[0]
+	now := time.Now()
+	msg.Attempts++
+	msg.DeliveredAt = &now
+	if err := ib.store.Set(ctx, inboxCollection, msg.ID, msg); err != nil {
+		return false, fmt.Errorf("save inbox message: %w", err)
+	}
+
+	// 先订阅再发送，避免错过本轮运行的完成事件
+	events := ag.Subscribe([]types.AgentChannel{types.ChannelProgress}, nil)
+	// 投递在后台完成，不随调用方（如 HTTP 请求）取消
+	runCtx := context.WithoutCancel(ctx)
+	if err := ag.Send(runCtx, msg.Text); err != nil {
+		ag.Unsubscribe(events)
+		return false, fmt.Errorf("deliver inbox message: %w", err)
+	}
+	ib.inflight[agentID] = msg.ID
+	inboxLog.Debug(ctx, "inbox message delivered", map[string]any{"agent_id": agentID, "message_id": msg.ID, "attempts": msg.Attempts})
+
+	go ib.awaitAck(runCtx, ag, events, msg)
+	return true, nil
+}
+
+// awaitAck 等待 Agent 本轮运行结束后确认消息并投递下一条；超时则释放在途状态等待重新投递
+func (ib *Inbox) awaitAck(ctx context.Context, ag *agent.Agent, events <-chan types.AgentEventEnvelope, msg InboxMessage) {
+	defer ag.Unsubscribe(events)
+	timer := time.NewTimer(ib.opts.AckTimeout)
+	defer timer.Stop()
+
+	for {
+		select {
+		case env, ok := <-events:
+			if !ok {
+				ib.release(msg)
+				return
+			}
+			if _, done := env.Event.(*types.ProgressDoneEvent); !done {
+				continue
+			}
+			if err := ib.Ack(ctx, msg.ID); err != nil {
+				inboxLog.Warn(ctx, "inbox ack failed", map[string]any{"agent_id": msg.AgentID, "message_id": msg.ID, "error": err.Error()})
+				ib.release(msg)
+				return
+			}
+			if _, err := ib.Deliver(ctx, msg.AgentID); err != nil {
+				inboxLog.Warn(ctx, "inbox delivery failed", map[string]any{"agent_id": msg.AgentID, "error": err.Error()})
+			}
+			return
+		case <-timer.C:
+			inboxLog.Warn(ctx, "inbox message not acknowledged in time, will redeliver", map[string]any{"agent_id": msg.AgentID, "message_id": msg.ID})
+			ib.release(msg)
+			return
+		}
+	}
+}
+
+// release 清除在途状态，消息保留在收件箱中等待重新投递
+func (ib *Inbox) release(msg InboxMessage) {
+	ib.mu.Lock()
+	defer ib.mu.Unlock()
+	if ib.inflight[msg.AgentID] == msg.ID {
+		delete(ib.inflight, msg.AgentID)
+	}
+}
+
+// DeliverAll 为池中所有空闲 Agent 投递收件箱消息
+func (ib *Inbox) DeliverAll(ctx context.Context) {
+	for _, agentID := range ib.pool.List("") {
+		if _, err := ib.Deliver(ctx, agentID); err != nil {
+			inboxLog.Warn(ctx, "inbox delivery failed", map[string]any{"agent_id": agentID, "error": err.Error()})
+		}
+	}
+}
+
+// Run 按 PollInterval 定期投递，直到 ctx 结束
+// 用于投递 Agent 上线（Pool.Resume）或确认超时后仍在收件箱中的消息
+func (ib *Inbox) Run(ctx context.Context) {
+	ticker := time.NewTicker(ib.opts.PollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			ib.DeliverAll(ctx)
+		}
+	}
+}
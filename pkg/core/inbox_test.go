@@ -0,0 +1,139 @@
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/astercloud/aster/pkg/provider"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+// inboxStubProvider 立即返回固定回复的 Provider
+type inboxStubProvider struct {
+	config *types.ModelConfig
+	reply  string
+}
+
+func (p *inboxStubProvider) Stream(ctx context.Context, messages []types.Message, opts *provider.StreamOptions) (<-chan provider.StreamChunk, error) {
+	ch := make(chan provider.StreamChunk, 1)
+	ch <- provider.StreamChunk{Type: string(provider.ChunkTypeText), TextDelta: p.reply}
+	close(ch)
+	return ch, nil
+}
+
+func (p *inboxStubProvider) Complete(ctx context.Context, messages []types.Message, opts *provider.StreamOptions) (*provider.CompleteResponse, error) {
+	return &provider.CompleteResponse{Message: types.Message{Role: types.RoleAssistant, Content: p.reply}}, nil
+}
+
+func (p *inboxStubProvider) Config() *types.ModelConfig { return p.config }
+func (p *inboxStubProvider) Capabilities() provider.ProviderCapabilities {
+	return provider.ProviderCapabilities{}
+}
+func (p *inboxStubProvider) SetSystemPrompt(prompt string) error { return nil }
+func (p *inboxStubProvider) GetSystemPrompt() string             { return "" }
+func (p *inboxStubProvider) Close() error                        { return nil }
+
+type inboxStubFactory struct{}
+
+func (inboxStubFactory) Create(config *types.ModelConfig) (provider.Provider, error) {
+	return &inboxStubProvider{config: config, reply: "noted"}, nil
+}
+
+func waitForEmptyInbox(t *testing.T, inbox *Inbox, agentID string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		pending, err := inbox.Pending(context.Background(), agentID)
+		if err != nil {
+			t.Fatalf("Pending failed: %v", err)
+		}
+		if len(pending) == 0 {
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatalf("inbox of %s was not drained", agentID)
+}
+
+func TestInbox_DeliversWhenAgentComesOnline(t *testing.T) {
+	deps := createTestDeps(t)
+	deps.ProviderFactory = inboxStubFactory{}
+	pool := NewPool(&PoolOptions{Dependencies: deps})
+	defer func() { _ = pool.Shutdown() }()
+	ctx := context.Background()
+
+	inbox := NewInbox(pool, InboxOptions{})
+	if _, err := inbox.Post(ctx, "agent-1", "alice", "first"); err != nil {
+		t.Fatalf("Post failed: %v", err)
+	}
+	if _, err := inbox.Post(ctx, "agent-1", "alice", "second"); err != nil {
+		t.Fatalf("Post failed: %v", err)
+	}
+
+	// 离线时消息保留，重启后（新的 Inbox 实例）仍然可见
+	pending, err := NewInbox(pool, InboxOptions{}).Pending(ctx, "agent-1")
+	if err != nil {
+		t.Fatalf("Pending failed: %v", err)
+	}
+	if len(pending) != 2 || pending[0].Text != "first" || pending[1].Text != "second" {
+		t.Fatalf("expected two pending messages in order, got %+v", pending)
+	}
+
+	if _, err := pool.Create(ctx, createTestConfig("agent-1")); err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+	inbox.DeliverAll(ctx)
+	waitForEmptyInbox(t, inbox, "agent-1")
+
+	messages, err := deps.Store.LoadMessages(ctx, "agent-1")
+	if err != nil {
+		t.Fatalf("LoadMessages failed: %v", err)
+	}
+	var delivered []string
+	for _, msg := range messages {
+		if msg.Role == types.RoleUser {
+			delivered = append(delivered, msg.GetContent())
+		}
+	}
+	if len(delivered) != 2 || delivered[0] != "first" || delivered[1] != "second" {
+		t.Errorf("expected messages delivered one at a time in order, got %v", delivered)
+	}
+}
+
+func TestRoom_UseInbox(t *testing.T) {
+	deps := createTestDeps(t)
+	deps.ProviderFactory = inboxStubFactory{}
+	pool := NewPool(&PoolOptions{Dependencies: deps})
+	defer func() { _ = pool.Shutdown() }()
+	ctx := context.Background()
+
+	for _, id := range []string{"agent-1", "agent-2"} {
+		if _, err := pool.Create(ctx, createTestConfig(id)); err != nil {
+			t.Fatalf("Failed to create agent: %v", err)
+		}
+	}
+	room := NewRoom(pool)
+	inbox := NewInbox(pool, InboxOptions{})
+	room.UseInbox(inbox)
+	_ = room.Join("alice", "agent-1")
+	_ = room.Join("bob", "agent-2")
+
+	// bob 离线：消息保留在收件箱
+	if err := pool.Remove("agent-2"); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+	if err := room.SendTo(ctx, "alice", "bob", "status?"); err != nil {
+		t.Fatalf("SendTo failed: %v", err)
+	}
+	pending, _ := inbox.Pending(ctx, "agent-2")
+	if len(pending) != 1 || pending[0].Text != "[from:alice] status?" || pending[0].From != "alice" {
+		t.Fatalf("expected message queued for offline member, got %+v", pending)
+	}
+
+	if _, err := pool.Resume(ctx, "agent-2", createTestConfig("agent-2")); err != nil {
+		t.Fatalf("Resume failed: %v", err)
+	}
+	inbox.DeliverAll(ctx)
+	waitForEmptyInbox(t, inbox, "agent-2")
+}
@@ -0,0 +1,236 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/astercloud/aster/pkg/logging"
+	"github.com/astercloud/aster/pkg/sandbox"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+var parallelLog = logging.ForComponent("ParallelRun")
+
+// ParallelOptions 并行运行配置
+type ParallelOptions struct {
+	// N 并行的 Agent 数量，默认 3
+	N int
+	// Config 各 Agent 共用的配置。AgentID 作为 ID 前缀（为空时自动生成），
+	// 第 i 个 Agent 的 ID 为 <前缀>-<i>，分支为 aster/<前缀>-<i>；
+	// Sandbox.WorkDir 须位于 git 仓库中
+	Config *types.AgentConfig
+	// BaseRef 各分支的起点，默认 HEAD
+	BaseRef string
+}
+
+// ParallelCandidate 一个并行运行的 Agent 及其结果
+type ParallelCandidate struct {
+	Index   int    `json:"index"`
+	Name    string `json:"name"` // Room 成员名
+	AgentID string `json:"agent_id"`
+	Branch  string `json:"branch"`
+	Path    string `json:"path"`
+
+	Result   *types.CompleteResult `json:"result,omitempty"`
+	Error    string                `json:"error,omitempty"`
+	Duration time.Duration         `json:"duration"`
+	// Diff 相对起点的改动
+	Diff *sandbox.WorkDirDiff `json:"diff,omitempty"`
+
+	workDir *sandbox.IsolatedWorkDir
+}
+
+// ParallelRun 在同一仓库的多个 git worktree 中并行运行 N 个 Agent，
+// 用于 best-of-N 代码生成：比较各自的改动后选择一个合并回仓库
+type ParallelRun struct {
+	mu         sync.Mutex
+	pool       *Pool
+	room       *Room
+	candidates []*ParallelCandidate
+	closed     bool
+}
+
+// NewParallelRun 在 Pool 中创建 N 个各自使用独立 worktree 的 Agent，并加入同一个 Room
+func NewParallelRun(ctx context.Context, pool *Pool, opts *ParallelOptions) (*ParallelRun, error) {
+	if opts == nil || opts.Config == nil {
+		return nil, errors.New("parallel run requires an agent config")
+	}
+	n := opts.N
+	if n <= 0 {
+		n = 3
+	}
+	prefix := opts.Config.AgentID
+	if prefix == "" {
+		prefix = "parallel-" + uuid.NewString()[:8]
+	}
+
+	baseSandbox := opts.Config.Sandbox
+	if baseSandbox == nil {
+		baseSandbox = &types.SandboxConfig{Kind: types.SandboxKindLocal, WorkDir: "."}
+	}
+	if baseSandbox.Kind != types.SandboxKindLocal && baseSandbox.Kind != types.SandboxKindDocker {
+		return nil, fmt.Errorf("parallel run requires a local or docker sandbox, got %q", baseSandbox.Kind)
+	}
+
+	run := &ParallelRun{pool: pool, room: NewRoom(pool)}
+	for i := 1; i <= n; i++ {
+		sb := *baseSandbox
+		// worktree 由 ParallelRun 管理，Agent 关闭时保留，Close 时统一删除
+		sb.Isolation = &types.WorkDirIsolation{
+			Mode:    types.WorkDirIsolationWorktree,
+			Cleanup: types.WorkDirCleanupNever,
+			BaseRef: opts.BaseRef,
+		}
+		if baseSandbox.Isolation != nil {
+			sb.Isolation.Root = baseSandbox.Isolation.Root
+		}
+		config := *opts.Config
+		config.AgentID = fmt.Sprintf("%s-%d", prefix, i)
+		config.Sandbox = &sb
+
+		ag, err := pool.Create(ctx, &config)
+		if err != nil {
+			_ = run.Close(ctx)
+			return nil, fmt.Errorf("create candidate %d: %w", i, err)
+		}
+		wd := ag.IsolatedWorkDir()
+		c := &ParallelCandidate{
+			Index:   i,
+			Name:    fmt.Sprintf("candidate%d", i),
+			AgentID: config.AgentID,
+			Branch:  wd.Branch,
+			Path:    wd.Path,
+			workDir: wd,
+		}
+		run.candidates = append(run.candidates, c)
+		if err := run.room.Join(c.Name, c.AgentID); err != nil {
+			_ = run.Close(ctx)
+			return nil, err
+		}
+	}
+	return run, nil
+}
+
+// Run 向所有 Agent 发送同一个任务并等待全部完成，随后计算各自的改动。
+// 单个 Agent 失败记录在其 Error 中，仅在全部失败时返回错误
+func (r *ParallelRun) Run(ctx context.Context, prompt string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return errors.New("parallel run is closed")
+	}
+
+	var wg sync.WaitGroup
+	for _, c := range r.candidates {
+		c.Result, c.Error, c.Diff = nil, "", nil
+		ag, ok := r.pool.Get(c.AgentID)
+		if !ok {
+			c.Error = "agent not found: " + c.AgentID
+			continue
+		}
+		wg.Add(1)
+		go func(c *ParallelCandidate) {
+			defer wg.Done()
+			start := time.Now()
+			result, err := ag.Chat(ctx, prompt)
+			c.Duration = time.Since(start)
+			c.Result = result
+			if err != nil {
+				c.Error = err.Error()
+			}
+		}(c)
+	}
+	wg.Wait()
+
+	failed := 0
+	for _, c := range r.candidates {
+		if c.Error != "" {
+			failed++
+		}
+		diff, err := c.workDir.Diff(ctx)
+		if err != nil {
+			parallelLog.Warn(ctx, "diff candidate failed", map[string]any{"agent_id": c.AgentID, "error": err.Error()})
+			continue
+		}
+		c.Diff = diff
+	}
+	if failed == len(r.candidates) {
+		return fmt.Errorf("all %d candidates failed: %s", failed, r.candidates[0].Error)
+	}
+	return nil
+}
+
+// Candidates 返回所有候选，按 Index 排序
+func (r *ParallelRun) Candidates() []*ParallelCandidate {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*ParallelCandidate(nil), r.candidates...)
+}
+
+// Room 返回候选 Agent 所在的 Room，可用于广播后续指令
+func (r *ParallelRun) Room() *Room {
+	return r.room
+}
+
+// Compare 比较两个候选当前的内容（from → to）
+func (r *ParallelRun) Compare(ctx context.Context, from, to int) (*sandbox.WorkDirDiff, error) {
+	a, err := r.candidate(from)
+	if err != nil {
+		return nil, err
+	}
+	b, err := r.candidate(to)
+	if err != nil {
+		return nil, err
+	}
+	return sandbox.CompareWorkDirs(ctx, a.workDir, b.workDir)
+}
+
+// Pick 把选中候选的改动合并回仓库当前分支
+func (r *ParallelRun) Pick(ctx context.Context, index int) (*sandbox.MergeResult, error) {
+	c, err := r.candidate(index)
+	if err != nil {
+		return nil, err
+	}
+	if ag, ok := r.pool.Get(c.AgentID); ok {
+		return ag.MergeWorkDir(ctx)
+	}
+	return c.workDir.Merge(ctx)
+}
+
+// Close 从 Pool 中移除所有候选 Agent，并删除它们的 worktree 和分支；未选中的改动会丢失
+func (r *ParallelRun) Close(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+
+	var errs []error
+	for _, c := range r.candidates {
+		_ = r.room.Leave(c.Name)
+		if _, ok := r.pool.Get(c.AgentID); ok {
+			if err := r.pool.Remove(c.AgentID); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		if err := c.workDir.Remove(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("remove %s: %w", c.Branch, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (r *ParallelRun) candidate(index int) (*ParallelCandidate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if index < 1 || index > len(r.candidates) {
+		return nil, fmt.Errorf("candidate %d out of range 1..%d", index, len(r.candidates))
+	}
+	return r.candidates[index-1], nil
+}
@@ -0,0 +1,331 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/astercloud/aster/pkg/evals"
+	"github.com/astercloud/aster/pkg/provider"
+	"github.com/astercloud/aster/pkg/sandbox"
+	"github.com/astercloud/aster/pkg/structured"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+const (
+	defaultCheckTimeout  = 5 * time.Minute
+	maxCheckOutput       = 4096
+	defaultMaxJudgePatch = 20000
+)
+
+// CheckSpec 在每个候选工作目录中执行的自动检查（测试、lint 等），退出码为 0 视为通过
+type CheckSpec struct {
+	Name    string
+	Command string // 通过 sh -c 在宿主机上执行
+	// Timeout 默认 5 分钟
+	Timeout time.Duration
+}
+
+// JudgeConfig LLM 评审配置；配置多个 Provider 时各自打分并投票
+type JudgeConfig struct {
+	Providers []provider.Provider
+	// Task 候选执行的原始任务，提供给评审模型
+	Task string
+	// MaxPatchBytes 每个候选提供给评审的 diff 长度上限，默认 20000
+	MaxPatchBytes int
+}
+
+// CompareOptions 比较候选结果的配置
+type CompareOptions struct {
+	Checks []CheckSpec
+	// Scorers 对候选的最终回答评分
+	Scorers []evals.Scorer
+	// Reference 提供给 Scorers 的参考答案
+	Reference string
+	// Judge 可选的 LLM 评审
+	Judge *JudgeConfig
+	// Merge 为 true 时把胜出候选合并回仓库
+	Merge bool
+}
+
+// CheckResult 单项检查结果
+type CheckResult struct {
+	Name     string        `json:"name"`
+	Passed   bool          `json:"passed"`
+	Output   string        `json:"output,omitempty"` // 输出末尾部分
+	Duration time.Duration `json:"duration"`
+}
+
+// CandidateReport 单个候选的比较结果
+type CandidateReport struct {
+	Index   int    `json:"index"`
+	AgentID string `json:"agent_id"`
+	Branch  string `json:"branch"`
+	Error   string `json:"error,omitempty"`
+
+	Answer       string `json:"answer,omitempty"`
+	FilesChanged int    `json:"files_changed"`
+	Added        int    `json:"added"`
+	Deleted      int    `json:"deleted"`
+
+	Checks []CheckResult        `json:"checks,omitempty"`
+	Scores []*evals.ScoreResult `json:"scores,omitempty"`
+	// JudgeScore 各评审打分的平均值，JudgeReasons 为各评审的理由
+	JudgeScore   float64  `json:"judge_score,omitempty"`
+	JudgeReasons []string `json:"judge_reasons,omitempty"`
+	Votes        int      `json:"votes"`
+
+	// Eligible 运行成功且所有检查通过
+	Eligible bool `json:"eligible"`
+	// Total 检查通过率、评分平均值和评审分数的平均值（0~1）
+	Total float64 `json:"total"`
+	Rank  int     `json:"rank"`
+}
+
+// ComparisonReport 候选结果的比较报告
+type ComparisonReport struct {
+	// Candidates 按 Rank 排序
+	Candidates []*CandidateReport `json:"candidates"`
+	// Winner 胜出候选的 Index，0 表示没有合格的候选
+	Winner int                  `json:"winner"`
+	Reason string               `json:"reason"`
+	Merge  *sandbox.MergeResult `json:"merge,omitempty"`
+}
+
+// judgeVerdict 评审模型返回的 JSON
+type judgeVerdict struct {
+	Scores map[string]float64 `json:"scores"`
+	Winner int                `json:"winner"`
+	Reason string             `json:"reason"`
+}
+
+// CompareResults 在 Run 之后比较各候选：执行检查、评分、可选的 LLM 评审，
+// 选出总分最高的合格候选（同分时改动更小者优先），按需合并回仓库
+func (r *ParallelRun) CompareResults(ctx context.Context, opts *CompareOptions) (*ComparisonReport, error) {
+	if opts == nil {
+		opts = &CompareOptions{}
+	}
+	candidates := r.Candidates()
+	reports := make([]*CandidateReport, len(candidates))
+	for i, c := range candidates {
+		rep := &CandidateReport{Index: c.Index, AgentID: c.AgentID, Branch: c.Branch, Error: c.Error}
+		if c.Result != nil {
+			rep.Answer = c.Result.Text
+		}
+		if c.Diff != nil {
+			rep.FilesChanged, rep.Added, rep.Deleted = len(c.Diff.Files), c.Diff.Added, c.Diff.Deleted
+		}
+		if c.Error == "" {
+			for _, check := range opts.Checks {
+				rep.Checks = append(rep.Checks, runCheck(ctx, c.Path, check))
+			}
+			for _, scorer := range opts.Scorers {
+				score, err := scorer.Score(ctx, &evals.TextEvalInput{Answer: rep.Answer, Reference: opts.Reference})
+				if err != nil {
+					parallelLog.Warn(ctx, "scorer failed", map[string]any{"agent_id": c.AgentID, "error": err.Error()})
+					continue
+				}
+				rep.Scores = append(rep.Scores, score)
+			}
+		}
+		reports[i] = rep
+	}
+
+	judges := 0
+	if opts.Judge != nil {
+		for _, p := range opts.Judge.Providers {
+			verdict, err := judgeCandidates(ctx, p, opts.Judge, candidates, reports)
+			if err != nil {
+				parallelLog.Warn(ctx, "judge failed", map[string]any{"error": err.Error()})
+				continue
+			}
+			judges++
+			for _, rep := range reports {
+				rep.JudgeScore += verdict.Scores[strconv.Itoa(rep.Index)]
+				if verdict.Winner == rep.Index {
+					rep.Votes++
+					if verdict.Reason != "" {
+						rep.JudgeReasons = append(rep.JudgeReasons, verdict.Reason)
+					}
+				}
+			}
+		}
+	}
+
+	for _, rep := range reports {
+		if judges > 0 {
+			rep.JudgeScore /= float64(judges)
+		}
+		rep.Eligible, rep.Total = scoreCandidate(rep, judges > 0)
+	}
+
+	sort.SliceStable(reports, func(i, j int) bool {
+		a, b := reports[i], reports[j]
+		if a.Eligible != b.Eligible {
+			return a.Eligible
+		}
+		if a.Total != b.Total {
+			return a.Total > b.Total
+		}
+		if a.Votes != b.Votes {
+			return a.Votes > b.Votes
+		}
+		if a.Added+a.Deleted != b.Added+b.Deleted {
+			return a.Added+a.Deleted < b.Added+b.Deleted
+		}
+		return a.Index < b.Index
+	})
+	for i, rep := range reports {
+		rep.Rank = i + 1
+	}
+
+	report := &ComparisonReport{Candidates: reports}
+	if len(reports) == 0 || !reports[0].Eligible {
+		report.Reason = "no candidate finished without errors and passed all checks"
+		return report, nil
+	}
+	best := reports[0]
+	report.Winner = best.Index
+	report.Reason = fmt.Sprintf("candidate %d has the highest score %.2f", best.Index, best.Total)
+	if best.Votes > 0 {
+		report.Reason += fmt.Sprintf(" with %d judge vote(s)", best.Votes)
+	}
+
+	if opts.Merge {
+		merge, err := r.Pick(ctx, best.Index)
+		if err != nil {
+			return report, fmt.Errorf("merge candidate %d: %w", best.Index, err)
+		}
+		report.Merge = merge
+	}
+	return report, nil
+}
+
+// scoreCandidate 计算是否合格与总分，总分为检查通过率、评分平均值和评审分数的平均值
+func scoreCandidate(rep *CandidateReport, judged bool) (bool, float64) {
+	if rep.Error != "" {
+		return false, 0
+	}
+	var parts []float64
+	eligible := true
+	if len(rep.Checks) > 0 {
+		passed := 0
+		for _, c := range rep.Checks {
+			if c.Passed {
+				passed++
+			} else {
+				eligible = false
+			}
+		}
+		parts = append(parts, float64(passed)/float64(len(rep.Checks)))
+	}
+	if len(rep.Scores) > 0 {
+		sum := 0.0
+		for _, s := range rep.Scores {
+			sum += s.Value
+		}
+		parts = append(parts, sum/float64(len(rep.Scores)))
+	}
+	if judged {
+		parts = append(parts, rep.JudgeScore)
+	}
+	if len(parts) == 0 {
+		return eligible, 1
+	}
+	total := 0.0
+	for _, p := range parts {
+		total += p
+	}
+	return eligible, total / float64(len(parts))
+}
+
+// runCheck 在候选工作目录中执行检查命令
+func runCheck(ctx context.Context, dir string, spec CheckSpec) CheckResult {
+	timeout := spec.Timeout
+	if timeout <= 0 {
+		timeout = defaultCheckTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	cmd := exec.CommandContext(ctx, "sh", "-c", spec.Command)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	result := CheckResult{Name: spec.Name, Passed: err == nil, Duration: time.Since(start)}
+	if len(out) > maxCheckOutput {
+		out = out[len(out)-maxCheckOutput:]
+	}
+	result.Output = string(out)
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		result.Output += fmt.Sprintf("\n[timed out after %s]", timeout)
+	}
+	return result
+}
+
+const judgePrompt = `You are reviewing %d candidate solutions produced independently for the same task.
+
+Task:
+%s
+
+%s
+Score every candidate from 0 to 1 for correctness, completeness and code quality, and pick the best one.
+Respond with JSON only: {"scores": {"1": 0.8, "2": 0.5}, "winner": 1, "reason": "..."}`
+
+// judgeCandidates 让评审模型对所有候选打分并选出最佳
+func judgeCandidates(ctx context.Context, p provider.Provider, cfg *JudgeConfig, candidates []*ParallelCandidate, reports []*CandidateReport) (*judgeVerdict, error) {
+	maxPatch := cfg.MaxPatchBytes
+	if maxPatch <= 0 {
+		maxPatch = defaultMaxJudgePatch
+	}
+	var b strings.Builder
+	for i, c := range candidates {
+		rep := reports[i]
+		fmt.Fprintf(&b, "## Candidate %d\n", c.Index)
+		if rep.Error != "" {
+			fmt.Fprintf(&b, "Run failed: %s\n\n", rep.Error)
+			continue
+		}
+		for _, check := range rep.Checks {
+			status := "passed"
+			if !check.Passed {
+				status = "failed"
+			}
+			fmt.Fprintf(&b, "Check %s: %s\n", check.Name, status)
+		}
+		if rep.Answer != "" {
+			fmt.Fprintf(&b, "Final answer:\n%s\n", rep.Answer)
+		}
+		if c.Diff != nil && c.Diff.Patch != "" {
+			patch := c.Diff.Patch
+			if len(patch) > maxPatch {
+				patch = patch[:maxPatch] + "\n... (truncated)"
+			}
+			fmt.Fprintf(&b, "Diff (+%d -%d):\n```diff\n%s\n```\n", c.Diff.Added, c.Diff.Deleted, patch)
+		}
+		b.WriteString("\n")
+	}
+
+	resp, err := p.Complete(ctx, []types.Message{{
+		Role:    types.RoleUser,
+		Content: fmt.Sprintf(judgePrompt, len(candidates), cfg.Task, b.String()),
+	}}, &provider.StreamOptions{MaxTokens: 1000})
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := structured.NewJSONParser().Parse(ctx, resp.Message.GetContent(), structured.OutputSpec{Enabled: true})
+	if err != nil {
+		return nil, err
+	}
+	var verdict judgeVerdict
+	if err := json.Unmarshal([]byte(parsed.RawJSON), &verdict); err != nil {
+		return nil, fmt.Errorf("parse judge verdict: %w", err)
+	}
+	return &verdict, nil
+}
@@ -0,0 +1,93 @@
+package core
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/astercloud/aster/pkg/evals"
+	"github.com/astercloud/aster/pkg/provider"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+// judgeProvider 返回固定评审结果
+type judgeProvider struct {
+	writerProvider
+	verdict string
+}
+
+func (p *judgeProvider) Complete(context.Context, []types.Message, *provider.StreamOptions) (*provider.CompleteResponse, error) {
+	return &provider.CompleteResponse{Message: types.Message{Role: types.MessageRoleAssistant, Content: p.verdict}}, nil
+}
+
+func TestParallelRun_CompareResults(t *testing.T) {
+	run, repo, _ := newParallelTestRun(t, 3)
+	ctx := context.Background()
+	if err := run.Run(ctx, "write the answer"); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	report, err := run.CompareResults(ctx, &CompareOptions{
+		// 候选 1 未通过检查，不参与胜出
+		Checks: []CheckSpec{{Name: "not-first", Command: "! grep -q 'answer 1' answer.txt"}},
+		Scorers: []evals.Scorer{evals.NewKeywordCoverageScorer(evals.KeywordCoverageConfig{
+			Keywords: []string{"done"},
+		})},
+		Judge: &JudgeConfig{
+			Task: "write the answer",
+			Providers: []provider.Provider{
+				&judgeProvider{verdict: `{"scores": {"1": 1, "2": 0.4, "3": 0.9}, "winner": 3, "reason": "clearest"}`},
+				&judgeProvider{verdict: "Verdict:\n" + `{"scores": {"1": 1, "2": 0.6, "3": 0.7}, "winner": 1, "reason": "shortest"}`},
+				&judgeProvider{verdict: "not json"},
+			},
+		},
+		Merge: true,
+	})
+	if err != nil {
+		t.Fatalf("CompareResults: %v", err)
+	}
+
+	if report.Winner != 3 {
+		t.Fatalf("winner = %d, report = %+v", report.Winner, report)
+	}
+	byIndex := map[int]*CandidateReport{}
+	for _, c := range report.Candidates {
+		byIndex[c.Index] = c
+	}
+	first, third := byIndex[1], byIndex[3]
+	if first.Eligible || first.Checks[0].Passed || first.Rank != 3 {
+		t.Errorf("candidate 1 = %+v", first)
+	}
+	if third.Rank != 1 || third.Votes != 1 || third.JudgeScore != 0.8 || len(third.Scores) != 1 {
+		t.Errorf("candidate 3 = %+v", third)
+	}
+	if report.Merge == nil || len(report.Merge.Conflicts) != 0 {
+		t.Fatalf("merge = %+v", report.Merge)
+	}
+	if got, _ := os.ReadFile(filepath.Join(repo, "answer.txt")); string(got) != "answer 3\n" {
+		t.Errorf("merged answer = %q", got)
+	}
+}
+
+func TestParallelRun_CompareResults_NoEligible(t *testing.T) {
+	run, repo, _ := newParallelTestRun(t, 2)
+	ctx := context.Background()
+	if err := run.Run(ctx, "write the answer"); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	report, err := run.CompareResults(ctx, &CompareOptions{
+		Checks: []CheckSpec{{Name: "fail", Command: "exit 1"}},
+		Merge:  true,
+	})
+	if err != nil {
+		t.Fatalf("CompareResults: %v", err)
+	}
+	if report.Winner != 0 || report.Merge != nil {
+		t.Errorf("report = %+v", report)
+	}
+	if _, err := os.Stat(filepath.Join(repo, "answer.txt")); !os.IsNotExist(err) {
+		t.Error("nothing should be merged")
+	}
+}
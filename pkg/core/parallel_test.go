@@ -0,0 +1,176 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/astercloud/aster/pkg/agent"
+	"github.com/astercloud/aster/pkg/provider"
+	"github.com/astercloud/aster/pkg/sandbox"
+	"github.com/astercloud/aster/pkg/store"
+	"github.com/astercloud/aster/pkg/tools"
+	"github.com/astercloud/aster/pkg/tools/builtin"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+// writerProvider 第一次回复写入 answer.txt，之后回复 done
+type writerProvider struct {
+	content string
+	prompt  string
+}
+
+func (p *writerProvider) Complete(_ context.Context, messages []types.Message, _ *provider.StreamOptions) (*provider.CompleteResponse, error) {
+	if len(messages) > 1 {
+		return &provider.CompleteResponse{Message: types.Message{
+			Role:          types.MessageRoleAssistant,
+			ContentBlocks: []types.ContentBlock{&types.TextBlock{Text: "done"}},
+		}}, nil
+	}
+	return &provider.CompleteResponse{Message: types.Message{
+		Role: types.MessageRoleAssistant,
+		ContentBlocks: []types.ContentBlock{&types.ToolUseBlock{
+			ID:    "call-write",
+			Name:  "Write",
+			Input: map[string]any{"file_path": "answer.txt", "content": p.content},
+		}},
+	}}, nil
+}
+
+func (p *writerProvider) Stream(context.Context, []types.Message, *provider.StreamOptions) (<-chan provider.StreamChunk, error) {
+	return nil, fmt.Errorf("stream not supported")
+}
+
+func (p *writerProvider) Config() *types.ModelConfig {
+	return &types.ModelConfig{Provider: "mock", Model: "writer"}
+}
+
+func (p *writerProvider) Capabilities() provider.ProviderCapabilities {
+	return provider.ProviderCapabilities{SupportToolCalling: true, SupportSystemPrompt: true}
+}
+
+func (p *writerProvider) SetSystemPrompt(prompt string) error { p.prompt = prompt; return nil }
+func (p *writerProvider) GetSystemPrompt() string             { return p.prompt }
+func (p *writerProvider) Close() error                        { return nil }
+
+// writerFactory 第 i 个 Provider 写入 "answer i"
+type writerFactory struct{ n atomic.Int32 }
+
+func (f *writerFactory) Create(*types.ModelConfig) (provider.Provider, error) {
+	return &writerProvider{content: fmt.Sprintf("answer %d\n", f.n.Add(1))}, nil
+}
+
+// newParallelTestRun 在新建的 git 仓库上创建 N 个候选，第 i 个候选写入 "answer i"
+func newParallelTestRun(t *testing.T, n int) (*ParallelRun, string, *Pool) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	repo := t.TempDir()
+	for _, args := range [][]string{{"init", "-q", "-b", "main"}, {"commit", "-q", "--allow-empty", "-m", "init"}} {
+		args = append([]string{"-C", repo, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)
+		if out, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+
+	jsonStore, err := store.NewJSONStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	toolRegistry := tools.NewRegistry()
+	builtin.RegisterAll(toolRegistry)
+	templates := agent.NewTemplateRegistry()
+	templates.Register(&types.AgentTemplateDefinition{ID: "writer", SystemPrompt: "test", Tools: []any{"Write"}})
+	pool := NewPool(&PoolOptions{Dependencies: &agent.Dependencies{
+		Store:            jsonStore,
+		SandboxFactory:   sandbox.NewFactory(),
+		ToolRegistry:     toolRegistry,
+		ProviderFactory:  &writerFactory{},
+		TemplateRegistry: templates,
+	}})
+	t.Cleanup(func() { _ = pool.Shutdown() })
+
+	run, err := NewParallelRun(context.Background(), pool, &ParallelOptions{
+		N: n,
+		Config: &types.AgentConfig{
+			AgentID:     "bestof",
+			TemplateID:  "writer",
+			ModelConfig: &types.ModelConfig{Provider: "mock", Model: "writer", ExecutionMode: types.ExecutionModeNonStreaming},
+			Sandbox: &types.SandboxConfig{
+				Kind:           types.SandboxKindLocal,
+				WorkDir:        repo,
+				PermissionMode: types.SandboxPermissionBypass,
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewParallelRun: %v", err)
+	}
+	return run, repo, pool
+}
+
+func TestParallelRun(t *testing.T) {
+	run, repo, pool := newParallelTestRun(t, 2)
+	ctx := context.Background()
+
+	candidates := run.Candidates()
+	if len(candidates) != 2 || candidates[0].Branch != "aster/bestof-1" || candidates[1].AgentID != "bestof-2" {
+		t.Fatalf("candidates = %+v", candidates)
+	}
+	if run.Room().GetMemberCount() != 2 {
+		t.Errorf("room members = %d", run.Room().GetMemberCount())
+	}
+
+	if err := run.Run(ctx, "write the answer"); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	contents := map[string]bool{}
+	for _, c := range run.Candidates() {
+		if c.Error != "" || c.Diff == nil || c.Diff.Added != 1 {
+			t.Fatalf("candidate %d: error=%q diff=%+v", c.Index, c.Error, c.Diff)
+		}
+		data, _ := os.ReadFile(filepath.Join(c.Path, "answer.txt"))
+		contents[string(data)] = true
+	}
+	if len(contents) != 2 {
+		t.Errorf("candidates should have independent results: %v", contents)
+	}
+
+	cmp, err := run.Compare(ctx, 1, 2)
+	if err != nil {
+		t.Fatalf("Compare: %v", err)
+	}
+	if cmp.Added != 1 || cmp.Deleted != 1 {
+		t.Errorf("compare = %+v", cmp)
+	}
+
+	winner := run.Candidates()[1]
+	want, _ := os.ReadFile(filepath.Join(winner.Path, "answer.txt"))
+	result, err := run.Pick(ctx, 2)
+	if err != nil || len(result.Conflicts) != 0 {
+		t.Fatalf("Pick = %+v, %v", result, err)
+	}
+	if got, _ := os.ReadFile(filepath.Join(repo, "answer.txt")); string(got) != string(want) {
+		t.Errorf("merged answer = %q, want %q", got, want)
+	}
+
+	if err := run.Close(ctx); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if pool.Size() != 0 {
+		t.Errorf("pool size after close = %d", pool.Size())
+	}
+	out, _ := exec.Command("git", "-C", repo, "branch", "--list", "aster/*").Output()
+	if strings.TrimSpace(string(out)) != "" {
+		t.Errorf("candidate branches not removed: %s", out)
+	}
+	if _, err := run.Pick(ctx, 3); err == nil {
+		t.Error("Pick out of range should fail")
+	}
+}
@@ -2,19 +2,33 @@ package core
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"maps"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/astercloud/aster/pkg/agent"
+	"github.com/astercloud/aster/pkg/lock"
+	"github.com/astercloud/aster/pkg/logging"
 	"github.com/astercloud/aster/pkg/types"
 )
 
+var poolLog = logging.ForComponent("Pool")
+
+// ErrAgentLocked Agent 正在其他实例上运行
+var ErrAgentLocked = errors.New("agent is running on another instance")
+
 // PoolOptions Agent 池配置
 type PoolOptions struct {
 	Dependencies *agent.Dependencies
 	MaxAgents    int // 最大 Agent 数量,默认 50
+
+	// Locker 可选的分布式锁，多实例部署时保证同一个 Agent 只在一个实例的池中运行
+	Locker lock.Locker
+	// LockTTL Agent 锁的租约时长 (默认 lock.DefaultTTL)，Agent 在池中期间自动续约
+	LockTTL time.Duration
 }
 
 // Pool Agent 池 - 管理多个 Agent 的生命周期
@@ -23,6 +37,16 @@ type Pool struct {
 	agents    map[string]*agent.Agent
 	deps      *agent.Dependencies
 	maxAgents int
+
+	locker  lock.Locker
+	lockTTL time.Duration
+	leases  map[string]*agentLease
+}
+
+// agentLease Agent 在池中期间持有的锁
+type agentLease struct {
+	lease lock.Lease
+	stop  func()
 }
 
 // NewPool 创建 Agent 池
@@ -32,10 +56,52 @@ func NewPool(opts *PoolOptions) *Pool {
 		maxAgents = 50
 	}
 
+	lockTTL := opts.LockTTL
+	if lockTTL <= 0 {
+		lockTTL = lock.DefaultTTL
+	}
+
 	return &Pool{
 		agents:    make(map[string]*agent.Agent),
 		deps:      opts.Dependencies,
 		maxAgents: maxAgents,
+		locker:    opts.Locker,
+		lockTTL:   lockTTL,
+		leases:    make(map[string]*agentLease),
+	}
+}
+
+// lockAgentLocked 获取 Agent 锁并自动续约，未配置 Locker 时不做任何事，调用方需持有 p.mu
+func (p *Pool) lockAgentLocked(ctx context.Context, agentID string) error {
+	if p.locker == nil || agentID == "" {
+		return nil
+	}
+	lease, err := p.locker.TryAcquire(ctx, "agent:"+agentID, p.lockTTL)
+	if errors.Is(err, lock.ErrNotAcquired) {
+		return fmt.Errorf("%w: %s", ErrAgentLocked, agentID)
+	}
+	if err != nil {
+		return fmt.Errorf("lock agent: %w", err)
+	}
+	stop := lock.KeepAlive(lease, p.lockTTL, func(err error) {
+		poolLog.Warn(context.Background(), "agent lock lost, another instance may resume it", map[string]any{"agent_id": agentID, "error": err.Error()})
+	})
+	p.leases[agentID] = &agentLease{lease: lease, stop: stop}
+	return nil
+}
+
+// unlockAgentLocked 释放 Agent 锁，调用方需持有 p.mu
+func (p *Pool) unlockAgentLocked(agentID string) {
+	l, ok := p.leases[agentID]
+	if !ok {
+		return
+	}
+	delete(p.leases, agentID)
+	l.stop()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := l.lease.Release(ctx); err != nil {
+		poolLog.Warn(ctx, "failed to release agent lock", map[string]any{"agent_id": agentID, "error": err.Error()})
 	}
 }
 
@@ -55,10 +121,22 @@ func (p *Pool) Create(ctx context.Context, config *types.AgentConfig) (*agent.Ag
 	}
 
 	// 创建 Agent
+	agentID := config.AgentID
+	if err := p.lockAgentLocked(ctx, agentID); err != nil {
+		return nil, err
+	}
 	ag, err := agent.Create(ctx, config, p.deps)
 	if err != nil {
+		p.unlockAgentLocked(agentID)
 		return nil, fmt.Errorf("create agent: %w", err)
 	}
+	if agentID == "" {
+		// 新生成的 ID 不会与其他实例冲突，创建后再加锁
+		if err := p.lockAgentLocked(ctx, ag.ID()); err != nil {
+			_ = ag.Close()
+			return nil, err
+		}
+	}
 
 	// 加入池
 	p.agents[config.AgentID] = ag
@@ -122,12 +200,16 @@ func (p *Pool) Resume(ctx context.Context, agentID string, config *types.AgentCo
 		return nil, fmt.Errorf("agent not found in store: %s", agentID)
 	}
 
-	// 4. 设置 AgentID
+	// 4. 设置 AgentID，并确保没有其他实例正在运行该 Agent
 	config.AgentID = agentID
+	if err := p.lockAgentLocked(ctx, agentID); err != nil {
+		return nil, err
+	}
 
 	// 5. 创建 Agent (会自动加载状态)
 	ag, err := agent.Create(ctx, config, p.deps)
 	if err != nil {
+		p.unlockAgentLocked(agentID)
 		return nil, fmt.Errorf("resume agent: %w", err)
 	}
 
@@ -153,6 +235,7 @@ func (p *Pool) Remove(agentID string) error {
 
 	// 从池中移除
 	delete(p.agents, agentID)
+	p.unlockAgentLocked(agentID)
 	return nil
 }
 
@@ -167,6 +250,7 @@ func (p *Pool) Delete(ctx context.Context, agentID string) error {
 			return fmt.Errorf("close agent: %w", err)
 		}
 		delete(p.agents, agentID)
+		p.unlockAgentLocked(agentID)
 	}
 
 	// 从存储中删除 (需要 Store 实现 Delete 方法)
@@ -191,6 +275,7 @@ func (p *Pool) Shutdown() error {
 		if err := ag.Close(); err != nil {
 			lastErr = fmt.Errorf("close agent %s: %w", id, err)
 		}
+		p.unlockAgentLocked(id)
 	}
 
 	// 清空池
@@ -2,11 +2,13 @@ package core
 
 import (
 	"context"
+	"errors"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/astercloud/aster/pkg/agent"
+	"github.com/astercloud/aster/pkg/lock"
 	"github.com/astercloud/aster/pkg/provider"
 	"github.com/astercloud/aster/pkg/sandbox"
 	"github.com/astercloud/aster/pkg/store"
@@ -485,3 +487,30 @@ func TestPool_Resume(t *testing.T) {
 		t.Error("Resumed agent not found in pool")
 	}
 }
+
+// TestPool_Locker 两个共享锁的池（模拟两个实例）不能同时运行同一个 Agent
+func TestPool_Locker(t *testing.T) {
+	deps := createTestDeps(t)
+	locker := lock.NewMemoryLocker()
+	ctx := context.Background()
+
+	pool1 := NewPool(&PoolOptions{Dependencies: deps, Locker: locker})
+	pool2 := NewPool(&PoolOptions{Dependencies: deps, Locker: locker})
+	defer pool2.Shutdown()
+
+	if _, err := pool1.Create(ctx, createTestConfig("shared-agent")); err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+	if _, err := pool2.Create(ctx, createTestConfig("shared-agent")); !errors.Is(err, ErrAgentLocked) {
+		t.Fatalf("Expected ErrAgentLocked, got %v", err)
+	}
+
+	// 第一个实例释放后，第二个实例可以接管
+	if err := pool1.Remove("shared-agent"); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+	if _, err := pool2.Create(ctx, createTestConfig("shared-agent")); err != nil {
+		t.Fatalf("Expected takeover after release, got %v", err)
+	}
+	pool1.Shutdown()
+}
@@ -32,6 +32,9 @@ type Room struct {
 
 	// 提及正则表达式
 	mentionRegex *regexp.Regexp
+
+	// inbox 可选的收件箱，设置后消息经收件箱投递，Agent 忙碌或离线时不会丢失
+	inbox *Inbox
 }
 
 // RoomMessage Room 消息记录
@@ -52,6 +55,25 @@ func NewRoom(pool *Pool) *Room {
 	}
 }
 
+// UseInbox 让 Room 的消息经收件箱投递：成员忙碌或不在池中时消息保留，空闲后按顺序送达
+func (r *Room) UseInbox(inbox *Inbox) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.inbox = inbox
+}
+
+// deliver 通过收件箱投递消息，返回是否已交给收件箱
+func (r *Room) deliver(ctx context.Context, agentID, from, text string) (bool, error) {
+	r.mu.RLock()
+	inbox := r.inbox
+	r.mu.RUnlock()
+	if inbox == nil {
+		return false, nil
+	}
+	_, err := inbox.Post(ctx, agentID, from, text)
+	return true, err
+}
+
 // Join 加入 Room
 func (r *Room) Join(name string, agentID string) error {
 	r.mu.Lock()
@@ -140,14 +162,20 @@ func (r *Room) Say(ctx context.Context, from string, text string) error {
 
 	// 发送消息
 	for name, agentID := range targets {
+		// 格式化消息: [from:sender] message
+		formattedText := fmt.Sprintf("[from:%s] %s", from, text)
+		if queued, err := r.deliver(ctx, agentID, from, formattedText); queued {
+			if err != nil {
+				roomLog.Warn(ctx, "failed to queue message for agent", map[string]any{"member": name, "error": err})
+			}
+			continue
+		}
+
 		ag, exists := r.pool.Get(agentID)
 		if !exists {
 			continue
 		}
 
-		// 格式化消息: [from:sender] message
-		formattedText := fmt.Sprintf("[from:%s] %s", from, text)
-
 		// 异步发送,避免阻塞
 		go func(agent *agent.Agent, txt string, memberName string) {
 			if err := agent.Send(ctx, txt); err != nil {
@@ -181,7 +209,14 @@ func (r *Room) Broadcast(ctx context.Context, text string) error {
 	r.mu.Unlock()
 
 	// 发送消息
-	for _, agentID := range targets {
+	for name, agentID := range targets {
+		if queued, err := r.deliver(ctx, agentID, "system", text); queued {
+			if err != nil {
+				roomLog.Warn(ctx, "failed to queue message for agent", map[string]any{"member": name, "error": err})
+			}
+			continue
+		}
+
 		ag, exists := r.pool.Get(agentID)
 		if !exists {
 			continue
@@ -226,13 +261,16 @@ func (r *Room) SendTo(ctx context.Context, from string, to string, text string)
 	r.history = append(r.history, msg)
 	r.mu.Unlock()
 
+	formattedText := fmt.Sprintf("[from:%s] %s", from, text)
+	if queued, err := r.deliver(ctx, agentID, from, formattedText); queued {
+		return err
+	}
+
 	// 获取 Agent 并发送
 	ag, exists := r.pool.Get(agentID)
 	if !exists {
 		return fmt.Errorf("agent not found for member %s", to)
 	}
-
-	formattedText := fmt.Sprintf("[from:%s] %s", from, text)
 	return ag.Send(ctx, formattedText)
 }
 
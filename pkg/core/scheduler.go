@@ -2,11 +2,14 @@ package core
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
 
 	"github.com/astercloud/aster/pkg/agent"
+	"github.com/astercloud/aster/pkg/batch"
+	"github.com/astercloud/aster/pkg/lock"
 	"github.com/astercloud/aster/pkg/logging"
 )
 
@@ -61,6 +64,11 @@ type IntervalTask struct {
 type SchedulerOptions struct {
 	// 触发回调 (用于监控和日志)
 	OnTrigger func(taskID string, spec string, kind TriggerKind)
+
+	// Locker 可选的分布式锁，多实例部署时保证 EveryIntervalExclusive 任务同一时刻只在一个实例上执行
+	Locker lock.Locker
+	// LockTTL 任务锁的租约时长 (默认 lock.DefaultTTL)，执行期间自动续约
+	LockTTL time.Duration
 }
 
 // Scheduler 任务调度器
@@ -260,6 +268,45 @@ func (s *Scheduler) EveryInterval(interval time.Duration, callback TaskCallback)
 	return id, nil
 }
 
+// EveryIntervalExclusive 每隔一段时间执行，多个实例中同一时刻只有一个执行
+// name 在所有实例间标识同一个任务（例如定时 Recipe 的 ID）；锁被其他实例持有时跳过本次执行。
+// 未配置 Locker 时与 EveryInterval 相同
+func (s *Scheduler) EveryIntervalExclusive(name string, interval time.Duration, callback TaskCallback) (string, error) {
+	if name == "" {
+		return "", errors.New("exclusive task requires a name")
+	}
+	if s.opts.Locker == nil {
+		return s.EveryInterval(interval, callback)
+	}
+	return s.EveryInterval(interval, func(ctx context.Context) error {
+		err := lock.Run(ctx, s.opts.Locker, "scheduler:"+name, s.opts.LockTTL, callback)
+		if errors.Is(err, lock.ErrNotAcquired) {
+			schedulerLog.Debug(ctx, "exclusive task is running on another instance, skipped", map[string]any{"task": name})
+			return nil
+		}
+		return err
+	})
+}
+
+// EveryIntervalBatch 每隔一段时间通过 build 生成离线任务并以批处理方式提交，不等待完成
+// 结果由 PollBatches 回写；build 返回空列表时跳过本次提交
+func (s *Scheduler) EveryIntervalBatch(name string, interval time.Duration, runner *batch.Runner, build func(ctx context.Context) ([]batch.Job, error)) (string, error) {
+	return s.EveryIntervalExclusive(name, interval, func(ctx context.Context) error {
+		jobs, err := build(ctx)
+		if err != nil || len(jobs) == 0 {
+			return err
+		}
+		_, err = runner.Submit(ctx, jobs)
+		return err
+	})
+}
+
+// PollBatches 定时检查 runner 提交的批次，把已结束批次的结果写回会话
+// 多实例部署时同一提供商的轮询同一时刻只在一个实例上执行
+func (s *Scheduler) PollBatches(runner *batch.Runner, interval time.Duration) (string, error) {
+	return s.EveryIntervalExclusive("batch-poll:"+runner.Provider(), interval, runner.Poll)
+}
+
 // Schedule 使用调度规格创建任务
 func (s *Scheduler) Schedule(spec string, callback TaskCallback) (string, error) {
 	// 解析规格
@@ -6,6 +6,8 @@ import (
 	"sync/atomic"
 	"testing"
 	"time"
+
+	"github.com/astercloud/aster/pkg/lock"
 )
 
 // TestScheduler_EverySteps 测试步骤触发
@@ -432,3 +434,39 @@ func TestScheduler_ConcurrentStepNotify(t *testing.T) {
 		t.Errorf("Expected 10 calls, got %d", count)
 	}
 }
+
+// TestScheduler_EveryIntervalExclusive 共享锁的两个调度器（模拟两个实例）不会同时执行同一任务
+func TestScheduler_EveryIntervalExclusive(t *testing.T) {
+	locker := lock.NewMemoryLocker()
+	var running, overlaps, runs int32
+
+	task := func(ctx context.Context) error {
+		if atomic.AddInt32(&running, 1) > 1 {
+			atomic.AddInt32(&overlaps, 1)
+		}
+		atomic.AddInt32(&runs, 1)
+		time.Sleep(30 * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+		return nil
+	}
+
+	for range 2 {
+		scheduler := NewScheduler(&SchedulerOptions{Locker: locker})
+		defer scheduler.Shutdown()
+		if _, err := scheduler.EveryIntervalExclusive("nightly-recipe", 10*time.Millisecond, task); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	time.Sleep(200 * time.Millisecond)
+	if atomic.LoadInt32(&runs) == 0 {
+		t.Fatal("expected the task to run")
+	}
+	if n := atomic.LoadInt32(&overlaps); n != 0 {
+		t.Errorf("task ran concurrently on two schedulers %d times", n)
+	}
+
+	if _, err := NewScheduler(nil).EveryIntervalExclusive("", time.Second, task); err == nil {
+		t.Error("expected error for unnamed exclusive task")
+	}
+}
@@ -0,0 +1,280 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/astercloud/aster/pkg/structured"
+)
+
+// Quorum 投票的法定人数和通过条件
+type Quorum struct {
+	// MinVotes 有效票的最少数量，0 表示需要过半成员有效投票
+	MinVotes int
+	// Threshold 胜出选项的权重占有效票总权重的比例需超过该值，如 0.5 表示过半数、
+	// 0.66 表示三分之二多数；0 表示相对多数即可
+	Threshold float64
+	// Weights 成员（Room 中的名称）的投票权重，未设置的成员为 1，权重不大于 0 的成员不参与投票
+	Weights map[string]float64
+}
+
+// Ballot 单个成员的投票
+type Ballot struct {
+	Member    string  `json:"member"`
+	AgentID   string  `json:"agent_id"`
+	Weight    float64 `json:"weight"`
+	Choice    string  `json:"choice,omitempty"`
+	Rationale string  `json:"rationale,omitempty"`
+	// Error 未能得到有效选项的原因（运行失败、选项不在候选中），该票视为弃权
+	Error string `json:"error,omitempty"`
+}
+
+// VoteTally 单个选项的计票结果
+type VoteTally struct {
+	Option string  `json:"option"`
+	Votes  int     `json:"votes"`
+	Weight float64 `json:"weight"`
+	// Share 权重占有效票总权重的比例
+	Share float64 `json:"share"`
+}
+
+// VoteResult 投票结果和理由报告
+type VoteResult struct {
+	Question string   `json:"question"`
+	Options  []string `json:"options"`
+	// Ballots 按成员名称排序
+	Ballots []Ballot `json:"ballots"`
+	// Tally 按权重从高到低排序，包含所有选项
+	Tally []VoteTally `json:"tally"`
+	// Decision 达成决议时的胜出选项，未达成时为空
+	Decision string `json:"decision,omitempty"`
+	Reached  bool   `json:"reached"`
+	// Reason 决议达成或未达成的原因
+	Reason string `json:"reason"`
+}
+
+const votePrompt = `The team is voting on the following question. Decide independently; you will not see the other members' answers.
+
+Question:
+%s
+
+Options:
+%s
+Choose exactly one option and explain your reasoning briefly.
+Respond with JSON only: {"choice": "<option text>", "rationale": "..."}`
+
+// voteAnswer 成员回复的 JSON
+type voteAnswer struct {
+	Choice    string `json:"choice"`
+	Rationale string `json:"rationale"`
+}
+
+// Vote 让 Room 成员独立回答 question 并按加权多数决出 options 中的一项
+//
+// 每个成员单独收到问题，互相看不到回答；回复不是有效选项的成员视为弃权。
+// 有效票达到法定人数、且得票最高的选项唯一并满足 Threshold 时达成决议。
+// 未达成决议不返回错误，原因记录在 VoteResult.Reason 中
+func (r *Room) Vote(ctx context.Context, question string, options []string, quorum Quorum) (*VoteResult, error) {
+	if len(options) < 2 {
+		return nil, errors.New("vote requires at least two options")
+	}
+
+	r.mu.RLock()
+	members := make([]RoomMember, 0, len(r.members))
+	for name, agentID := range r.members {
+		if weight, ok := quorum.Weights[name]; ok && weight <= 0 {
+			continue
+		}
+		members = append(members, RoomMember{Name: name, AgentID: agentID})
+	}
+	r.mu.RUnlock()
+	if len(members) == 0 {
+		return nil, errors.New("no members to vote")
+	}
+	slices.SortFunc(members, func(a, b RoomMember) int { return strings.Compare(a.Name, b.Name) })
+
+	var list strings.Builder
+	for i, option := range options {
+		fmt.Fprintf(&list, "%d. %s\n", i+1, option)
+	}
+	prompt := fmt.Sprintf(votePrompt, question, list.String())
+
+	r.mu.Lock()
+	r.history = append(r.history, RoomMessage{From: "system", Text: "[vote] " + question, Sent: nowTimestamp()})
+	r.mu.Unlock()
+
+	ballots := make([]Ballot, len(members))
+	var wg sync.WaitGroup
+	for i, m := range members {
+		ballots[i] = Ballot{Member: m.Name, AgentID: m.AgentID, Weight: 1}
+		if weight, ok := quorum.Weights[m.Name]; ok {
+			ballots[i].Weight = weight
+		}
+		wg.Add(1)
+		go func(b *Ballot) {
+			defer wg.Done()
+			r.castBallot(ctx, b, prompt, options)
+		}(&ballots[i])
+	}
+	wg.Wait()
+
+	r.mu.Lock()
+	for _, b := range ballots {
+		if b.Error == "" {
+			r.history = append(r.history, RoomMessage{From: b.Member, Text: "[vote] " + b.Choice, Sent: nowTimestamp()})
+		}
+	}
+	r.mu.Unlock()
+
+	result := tallyVotes(question, options, ballots, quorum, len(members))
+	roomLog.Info(ctx, "vote finished", map[string]any{"question": question, "reached": result.Reached, "decision": result.Decision})
+	return result, nil
+}
+
+// castBallot 向成员提问并解析其选择
+func (r *Room) castBallot(ctx context.Context, b *Ballot, prompt string, options []string) {
+	ag, ok := r.pool.Get(b.AgentID)
+	if !ok {
+		b.Error = "agent not found"
+		return
+	}
+	resp, err := ag.Chat(ctx, prompt)
+	if err != nil {
+		b.Error = err.Error()
+		return
+	}
+	choice, rationale := parseVoteAnswer(ctx, resp.Text, options)
+	if choice == "" {
+		b.Error = "answer did not name one of the options"
+		b.Rationale = resp.Text
+		return
+	}
+	b.Choice, b.Rationale = choice, rationale
+}
+
+// parseVoteAnswer 从回复中解析选项和理由
+// 优先解析 JSON，choice 可以是选项文本或序号；否则回复中恰好出现一个选项时采用该选项
+func parseVoteAnswer(ctx context.Context, text string, options []string) (string, string) {
+	if parsed, err := structured.NewJSONParser().Parse(ctx, text, structured.OutputSpec{Enabled: true}); err == nil {
+		var answer voteAnswer
+		if json.Unmarshal([]byte(parsed.RawJSON), &answer) == nil {
+			if choice := matchOption(answer.Choice, options); choice != "" {
+				return choice, answer.Rationale
+			}
+		}
+	}
+
+	lower := strings.ToLower(text)
+	found := ""
+	for _, option := range options {
+		if strings.Contains(lower, strings.ToLower(option)) {
+			if found != "" {
+				return "", ""
+			}
+			found = option
+		}
+	}
+	return found, strings.TrimSpace(text)
+}
+
+// matchOption 按文本（忽略大小写）或从 1 开始的序号匹配选项
+func matchOption(choice string, options []string) string {
+	choice = strings.TrimSpace(choice)
+	for _, option := range options {
+		if strings.EqualFold(choice, option) {
+			return option
+		}
+	}
+	if n, err := strconv.Atoi(strings.TrimSuffix(choice, ".")); err == nil && n >= 1 && n <= len(options) {
+		return options[n-1]
+	}
+	return ""
+}
+
+// tallyVotes 按权重计票并判断是否达成决议
+func tallyVotes(question string, options []string, ballots []Ballot, quorum Quorum, members int) *VoteResult {
+	result := &VoteResult{Question: question, Options: options, Ballots: ballots}
+
+	tally := make([]VoteTally, len(options))
+	valid, total := 0, 0.0
+	for i, option := range options {
+		tally[i].Option = option
+	}
+	for _, b := range ballots {
+		if b.Error != "" {
+			continue
+		}
+		i := slices.Index(options, b.Choice)
+		tally[i].Votes++
+		tally[i].Weight += b.Weight
+		valid++
+		total += b.Weight
+	}
+	for i := range tally {
+		if total > 0 {
+			tally[i].Share = tally[i].Weight / total
+		}
+	}
+	slices.SortStableFunc(tally, func(a, b VoteTally) int {
+		switch {
+		case a.Weight > b.Weight:
+			return -1
+		case a.Weight < b.Weight:
+			return 1
+		}
+		return b.Votes - a.Votes
+	})
+	result.Tally = tally
+
+	minVotes := quorum.MinVotes
+	if minVotes <= 0 {
+		minVotes = members/2 + 1
+	}
+	if valid < minVotes {
+		result.Reason = fmt.Sprintf("quorum not met: %d valid vote(s), %d required", valid, minVotes)
+		return result
+	}
+	top := tally[0]
+	if len(tally) > 1 && tally[1].Weight == top.Weight {
+		result.Reason = fmt.Sprintf("tie between %q and %q with weight %.2f", top.Option, tally[1].Option, top.Weight)
+		return result
+	}
+	if top.Share <= quorum.Threshold {
+		result.Reason = fmt.Sprintf("%q received %.0f%% of the weight, more than %.0f%% is required", top.Option, top.Share*100, quorum.Threshold*100)
+		return result
+	}
+	result.Reached = true
+	result.Decision = top.Option
+	result.Reason = fmt.Sprintf("%q won with %d of %d valid vote(s) (%.0f%% of the weight)", top.Option, top.Votes, valid, top.Share*100)
+	return result
+}
+
+// Report 生成 Markdown 格式的理由报告：决议、计票和每位成员的理由
+func (v *VoteResult) Report() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Vote: %s\n\n", v.Question)
+	if v.Reached {
+		fmt.Fprintf(&b, "**Decision:** %s\n\n", v.Decision)
+	} else {
+		b.WriteString("**Decision:** not reached\n\n")
+	}
+	fmt.Fprintf(&b, "%s\n\n## Tally\n\n| Option | Votes | Weight | Share |\n| --- | --- | --- | --- |\n", v.Reason)
+	for _, t := range v.Tally {
+		fmt.Fprintf(&b, "| %s | %d | %.2f | %.0f%% |\n", t.Option, t.Votes, t.Weight, t.Share*100)
+	}
+	b.WriteString("\n## Rationale\n\n")
+	for _, ballot := range v.Ballots {
+		if ballot.Error != "" {
+			fmt.Fprintf(&b, "- **%s** abstained: %s\n", ballot.Member, ballot.Error)
+			continue
+		}
+		fmt.Fprintf(&b, "- **%s** voted %s (weight %.2f): %s\n", ballot.Member, ballot.Choice, ballot.Weight, ballot.Rationale)
+	}
+	return b.String()
+}
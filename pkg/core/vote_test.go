@@ -0,0 +1,112 @@
+package core
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/astercloud/aster/pkg/provider"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+// voteStubFactory 按模型名称返回不同回复，用于区分各成员的投票
+type voteStubFactory map[string]string
+
+func (f voteStubFactory) Create(config *types.ModelConfig) (provider.Provider, error) {
+	return &inboxStubProvider{config: config, reply: f[config.Model]}, nil
+}
+
+func TestRoom_Vote(t *testing.T) {
+	deps := createTestDeps(t)
+	deps.ProviderFactory = voteStubFactory{
+		"model-a": `{"choice": "Postgres", "rationale": "mature and transactional"}`,
+		"model-b": "I would go with option 2.\n```json\n{\"choice\": \"2\", \"rationale\": \"schemaless\"}\n```",
+		"model-c": "Postgres, because the team already runs it.",
+		"model-d": "No strong opinion.",
+	}
+	pool := NewPool(&PoolOptions{Dependencies: deps})
+	defer func() { _ = pool.Shutdown() }()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	room := NewRoom(pool)
+	for _, m := range []struct{ name, model string }{
+		{"alice", "model-a"}, {"bob", "model-b"}, {"carol", "model-c"}, {"dave", "model-d"},
+	} {
+		config := createTestConfig("agent-" + m.name)
+		config.ModelConfig.Model = m.model
+		if _, err := pool.Create(ctx, config); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		if err := room.Join(m.name, "agent-"+m.name); err != nil {
+			t.Fatalf("Join failed: %v", err)
+		}
+	}
+
+	// bob 的权重超过 alice 与 carol 之和
+	result, err := room.Vote(ctx, "Which database?", []string{"Postgres", "MongoDB"}, Quorum{
+		Weights: map[string]float64{"bob": 3},
+	})
+	if err != nil {
+		t.Fatalf("Vote failed: %v", err)
+	}
+	if !result.Reached || result.Decision != "MongoDB" {
+		t.Fatalf("expected MongoDB to win, got %+v", result)
+	}
+	if result.Tally[0].Option != "MongoDB" || result.Tally[0].Weight != 3 || result.Tally[1].Votes != 2 {
+		t.Errorf("unexpected tally: %+v", result.Tally)
+	}
+	if len(result.Ballots) != 4 || result.Ballots[3].Member != "dave" || result.Ballots[3].Error == "" {
+		t.Errorf("expected dave to abstain, got %+v", result.Ballots)
+	}
+	if result.Ballots[0].Rationale != "mature and transactional" {
+		t.Errorf("unexpected rationale: %q", result.Ballots[0].Rationale)
+	}
+
+	report := result.Report()
+	for _, want := range []string{"**Decision:** MongoDB", "**bob** voted MongoDB", "**dave** abstained"} {
+		if !strings.Contains(report, want) {
+			t.Errorf("report missing %q:\n%s", want, report)
+		}
+	}
+
+	if _, err := room.Vote(ctx, "Which database?", []string{"Postgres"}, Quorum{}); err == nil {
+		t.Error("expected error for a single option")
+	}
+}
+
+func TestTallyVotes(t *testing.T) {
+	options := []string{"yes", "no"}
+	ballot := func(choice string) Ballot {
+		if choice == "" {
+			return Ballot{Weight: 1, Error: "abstained"}
+		}
+		return Ballot{Weight: 1, Choice: choice}
+	}
+
+	tests := []struct {
+		name     string
+		ballots  []Ballot
+		quorum   Quorum
+		decision string
+		reason   string
+	}{
+		{"plurality", []Ballot{ballot("yes"), ballot("yes"), ballot("no")}, Quorum{}, "yes", "won"},
+		{"tie", []Ballot{ballot("yes"), ballot("no")}, Quorum{}, "", "tie"},
+		{"quorum not met", []Ballot{ballot("yes"), ballot(""), ballot("")}, Quorum{}, "", "quorum not met"},
+		{"explicit min votes", []Ballot{ballot("yes"), ballot(""), ballot("")}, Quorum{MinVotes: 1}, "yes", "won"},
+		{"supermajority", []Ballot{ballot("yes"), ballot("yes"), ballot("no")}, Quorum{Threshold: 0.7}, "", "required"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := tallyVotes("q", options, tt.ballots, tt.quorum, len(tt.ballots))
+			if result.Decision != tt.decision || result.Reached != (tt.decision != "") {
+				t.Errorf("decision = %q, reached = %v, want %q", result.Decision, result.Reached, tt.decision)
+			}
+			if !strings.Contains(result.Reason, tt.reason) {
+				t.Errorf("reason = %q, want it to contain %q", result.Reason, tt.reason)
+			}
+		})
+	}
+}
@@ -0,0 +1,230 @@
+package dashboard
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/astercloud/aster/pkg/events"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+// defaultAgentTraceLimit Agent 详情默认返回的最近追踪数
+const defaultAgentTraceLimit = 20
+
+// toolAccum 单个工具的调用累计
+type toolAccum struct {
+	calls, errors     int64
+	latency, measured int64
+}
+
+// GetAgentDetail 从单个 Agent 的 EventBus 构建时间序列、工具分布和最近的运行追踪；
+// 一次运行从 Agent 进入 working 状态开始，到 done 事件结束
+func (a *Aggregator) GetAgentDetail(ctx context.Context, eb *events.EventBus, opts AgentDetailOpts) (*AgentDetail, error) {
+	if opts.Period == "" {
+		opts.Period = "24h"
+	}
+	if opts.TraceLimit <= 0 {
+		opts.TraceLimit = defaultAgentTraceLimit
+	}
+
+	now := time.Now()
+	startTime, _ := a.getPeriodRange(opts.Period, nil, nil)
+	bucket := a.getBucketSize(opts.Period)
+
+	var timeline []types.AgentEventEnvelope
+	if eb != nil {
+		timeline = eb.GetTimelineFiltered(func(env types.AgentEventEnvelope) bool {
+			// Bookmark.Timestamp 是秒级时间戳
+			return !time.Unix(env.Bookmark.Timestamp, 0).Before(startTime)
+		})
+	}
+
+	// 预先生成连续的时间桶，没有数据的时间段也返回零值
+	first := startTime.Truncate(bucket)
+	series := make([]AgentSeriesPoint, 0, int(now.Sub(first)/bucket)+1)
+	for ts := first; !ts.After(now); ts = ts.Add(bucket) {
+		series = append(series, AgentSeriesPoint{Timestamp: ts})
+	}
+	latencySum := make([]int64, len(series))
+	latencyCount := make([]int64, len(series))
+	bucketOf := func(ts time.Time) int {
+		return min(max(int(ts.Sub(first)/bucket), 0), len(series)-1)
+	}
+
+	summary := AgentSummary{}
+	tools := make(map[string]*toolAccum)
+	var runs []*TraceSummary
+	var run *TraceSummary
+	var runLatencies []int64
+	var toolCalls int64
+
+	startRun := func(env types.AgentEventEnvelope, ts time.Time) {
+		run = &TraceSummary{
+			ID:        runTraceID(opts.AgentID, env.Cursor),
+			Name:      "agent.run",
+			AgentID:   opts.AgentID,
+			StartTime: ts,
+			Status:    TraceStatusRunning,
+		}
+		runs = append(runs, run)
+	}
+
+	for _, env := range timeline {
+		ts := time.Unix(env.Bookmark.Timestamp, 0)
+		point := &series[bucketOf(ts)]
+
+		switch evt := normalizeEvent(env.Event).(type) {
+		case *types.MonitorStateChangedEvent:
+			if evt.State == types.AgentStateWorking && run == nil {
+				startRun(env, ts)
+			}
+
+		case *types.MonitorTokenUsageEvent:
+			if run == nil {
+				startRun(env, ts)
+			}
+			point.Requests++
+			point.TokenUsage.Input += evt.InputTokens
+			point.TokenUsage.Output += evt.OutputTokens
+			run.TokenUsage.Input += evt.InputTokens
+			run.TokenUsage.Output += evt.OutputTokens
+			run.SpanCount++
+
+		case *types.ProgressToolEndEvent:
+			if run == nil {
+				startRun(env, ts)
+			}
+			acc := tools[evt.Call.Name]
+			if acc == nil {
+				acc = &toolAccum{}
+				tools[evt.Call.Name] = acc
+			}
+			acc.calls++
+			toolCalls++
+			if evt.Call.Error != "" {
+				acc.errors++
+			}
+			if !evt.Call.StartedAt.IsZero() && evt.Call.UpdatedAt.After(evt.Call.StartedAt) {
+				acc.latency += evt.Call.UpdatedAt.Sub(evt.Call.StartedAt).Milliseconds()
+				acc.measured++
+			}
+			run.SpanCount++
+
+		case *types.MonitorErrorEvent:
+			if evt.Severity != "error" {
+				continue
+			}
+			point.Errors++
+			if run != nil {
+				run.Status = TraceStatusError
+				run.ErrorMessage = evt.Message
+			}
+
+		case *types.ProgressDoneEvent:
+			if run == nil {
+				continue
+			}
+			run.DurationMs = ts.Sub(run.StartTime).Milliseconds()
+			if evt.Error != nil {
+				run.Status = TraceStatusError
+				if run.ErrorMessage == "" {
+					run.ErrorMessage = evt.Error.Error()
+				}
+			} else if run.Status == TraceStatusRunning {
+				run.Status = TraceStatusOK
+			}
+			latencySum[bucketOf(ts)] += run.DurationMs
+			latencyCount[bucketOf(ts)]++
+			runLatencies = append(runLatencies, run.DurationMs)
+			run = nil
+		}
+	}
+	if run != nil {
+		run.DurationMs = now.Sub(run.StartTime).Milliseconds()
+	}
+
+	for i := range series {
+		p := &series[i]
+		p.TokenUsage.Total = p.TokenUsage.Input + p.TokenUsage.Output
+		p.Cost = a.costCalculator.Calculate(p.TokenUsage.Input, p.TokenUsage.Output, opts.Model).Amount
+		if p.Requests > 0 {
+			p.ErrorRate = float64(p.Errors) / float64(p.Requests)
+		}
+		if latencyCount[i] > 0 {
+			p.AvgLatencyMs = latencySum[i] / latencyCount[i]
+		}
+
+		summary.Requests += p.Requests
+		summary.Errors += p.Errors
+		summary.TokenUsage.Input += p.TokenUsage.Input
+		summary.TokenUsage.Output += p.TokenUsage.Output
+	}
+	summary.TokenUsage.Total = summary.TokenUsage.Input + summary.TokenUsage.Output
+	summary.Cost = a.costCalculator.Calculate(summary.TokenUsage.Input, summary.TokenUsage.Output, opts.Model)
+	summary.Runs = int64(len(runLatencies))
+	summary.Latency = CalculatePercentiles(runLatencies)
+	if summary.Requests > 0 {
+		summary.ErrorRate = float64(summary.Errors) / float64(summary.Requests)
+	}
+
+	toolMix := make([]ToolUsage, 0, len(tools))
+	for name, acc := range tools {
+		usage := ToolUsage{
+			Name:   name,
+			Calls:  acc.calls,
+			Errors: acc.errors,
+			Share:  float64(acc.calls) / float64(toolCalls),
+		}
+		if acc.measured > 0 {
+			usage.AvgLatencyMs = acc.latency / acc.measured
+		}
+		toolMix = append(toolMix, usage)
+	}
+	sort.Slice(toolMix, func(i, j int) bool {
+		if toolMix[i].Calls != toolMix[j].Calls {
+			return toolMix[i].Calls > toolMix[j].Calls
+		}
+		return toolMix[i].Name < toolMix[j].Name
+	})
+
+	// 最近的运行在前
+	for i := range runs {
+		runs[i].TokenUsage.Total = runs[i].TokenUsage.Input + runs[i].TokenUsage.Output
+	}
+	slices.Reverse(runs)
+	if len(runs) > opts.TraceLimit {
+		runs = runs[:opts.TraceLimit]
+	}
+
+	return &AgentDetail{
+		AgentID:      opts.AgentID,
+		Period:       opts.Period,
+		Summary:      summary,
+		Series:       series,
+		ToolMix:      toolMix,
+		RecentTraces: runs,
+		UpdatedAt:    now,
+	}, nil
+}
+
+// normalizeEvent 将远程 Agent 推送的 map 事件按 event_type 解码为具体事件结构，
+// 无法识别时原样返回
+func normalizeEvent(event any) any {
+	fields, ok := event.(map[string]any)
+	if !ok {
+		return event
+	}
+	eventType, _ := fields["event_type"].(string)
+	typed, ok := types.NewEvent(eventType)
+	if !ok {
+		return event
+	}
+	data, err := json.Marshal(fields)
+	if err != nil || json.Unmarshal(data, typed) != nil {
+		return event
+	}
+	return typed
+}
@@ -0,0 +1,106 @@
+package dashboard
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/astercloud/aster/pkg/events"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+func TestAggregator_GetAgentDetail(t *testing.T) {
+	eb := events.NewEventBus()
+	started := time.Now().Add(-2 * time.Second)
+
+	// 第一次运行：一次模型调用、两次工具调用，正常结束
+	eb.EmitMonitor(&types.MonitorStateChangedEvent{State: types.AgentStateWorking})
+	eb.EmitMonitor(&types.MonitorTokenUsageEvent{InputTokens: 1000, OutputTokens: 200})
+	eb.EmitProgress(&types.ProgressToolEndEvent{Call: types.ToolCallSnapshot{
+		Name: "Bash", StartedAt: started, UpdatedAt: started.Add(300 * time.Millisecond),
+	}})
+	eb.EmitProgress(&types.ProgressToolEndEvent{Call: types.ToolCallSnapshot{Name: "Read"}})
+	eb.EmitProgress(&types.ProgressDoneEvent{Step: 1, Reason: types.TerminationCompleted})
+	eb.EmitMonitor(&types.MonitorStateChangedEvent{State: types.AgentStateReady})
+
+	// 第二次运行：远程 Agent 推送的 map 事件，以错误结束
+	eb.EmitMonitor(&types.MonitorStateChangedEvent{State: types.AgentStateWorking})
+	eb.EmitMonitor(map[string]any{"event_type": "token_usage", "input_tokens": float64(500), "output_tokens": float64(100)})
+	eb.EmitProgress(&types.ProgressToolEndEvent{Call: types.ToolCallSnapshot{Name: "Bash", Error: "exit status 1"}})
+	eb.EmitMonitor(&types.MonitorErrorEvent{Severity: "error", Phase: "model", Message: "rate limited"})
+	eb.EmitProgress(&types.ProgressDoneEvent{Step: 2, Reason: types.TerminationError})
+
+	// 第三次运行仍在进行
+	eb.EmitMonitor(&types.MonitorStateChangedEvent{State: types.AgentStateWorking})
+
+	agg := NewAggregator(nil)
+	detail, err := agg.GetAgentDetail(context.Background(), eb, AgentDetailOpts{
+		AgentID: "agt-1",
+		Period:  "1h",
+		Model:   "claude-sonnet-4-5",
+	})
+	if err != nil {
+		t.Fatalf("GetAgentDetail: %v", err)
+	}
+
+	s := detail.Summary
+	if s.Requests != 2 || s.Errors != 1 || s.Runs != 2 {
+		t.Fatalf("summary = %+v, want 2 requests, 1 error, 2 runs", s)
+	}
+	if s.ErrorRate != 0.5 {
+		t.Errorf("error rate = %v, want 0.5", s.ErrorRate)
+	}
+	if s.TokenUsage != (TokenCount{Input: 1500, Output: 300, Total: 1800}) {
+		t.Errorf("token usage = %+v", s.TokenUsage)
+	}
+	if s.Cost.Amount <= 0 {
+		t.Errorf("cost = %+v, want > 0", s.Cost)
+	}
+
+	// 1h 周期按 5 分钟分桶
+	if len(detail.Series) < 12 || len(detail.Series) > 13 {
+		t.Errorf("series has %d points, want 12-13", len(detail.Series))
+	}
+	var seriesTokens int64
+	for _, p := range detail.Series {
+		seriesTokens += p.TokenUsage.Total
+	}
+	if seriesTokens != 1800 {
+		t.Errorf("series tokens = %d, want 1800", seriesTokens)
+	}
+
+	if len(detail.ToolMix) != 2 {
+		t.Fatalf("tool mix = %+v, want 2 tools", detail.ToolMix)
+	}
+	bash := detail.ToolMix[0]
+	if bash.Name != "Bash" || bash.Calls != 2 || bash.Errors != 1 || bash.AvgLatencyMs != 300 {
+		t.Errorf("bash usage = %+v", bash)
+	}
+	if bash.Share < 0.66 || bash.Share > 0.67 {
+		t.Errorf("bash share = %v, want 2/3", bash.Share)
+	}
+
+	if len(detail.RecentTraces) != 3 {
+		t.Fatalf("recent traces = %d, want 3", len(detail.RecentTraces))
+	}
+	wantStatus := []TraceStatus{TraceStatusRunning, TraceStatusError, TraceStatusOK}
+	for i, trace := range detail.RecentTraces {
+		if trace.Status != wantStatus[i] {
+			t.Errorf("trace %d status = %s, want %s", i, trace.Status, wantStatus[i])
+		}
+		if trace.AgentID != "agt-1" {
+			t.Errorf("trace %d agent = %q", i, trace.AgentID)
+		}
+	}
+	if failed := detail.RecentTraces[1]; failed.ErrorMessage != "rate limited" || failed.SpanCount != 2 {
+		t.Errorf("failed trace = %+v", failed)
+	}
+	if ok := detail.RecentTraces[2]; ok.TokenUsage.Total != 1200 || ok.SpanCount != 3 {
+		t.Errorf("completed trace = %+v", ok)
+	}
+
+	limited, _ := agg.GetAgentDetail(context.Background(), eb, AgentDetailOpts{AgentID: "agt-1", TraceLimit: 1})
+	if len(limited.RecentTraces) != 1 || limited.RecentTraces[0].Status != TraceStatusRunning {
+		t.Errorf("trace limit not applied: %+v", limited.RecentTraces)
+	}
+}
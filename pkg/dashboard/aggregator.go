@@ -18,6 +18,7 @@ type Aggregator struct {
 	store          store.Store
 	costCalculator *CostCalculator
 	traceBuilder   *TraceBuilder
+	traceStore     *TraceStore // 可选，持久化追踪
 
 	// 缓存
 	mu            sync.RWMutex
@@ -54,6 +55,13 @@ func (a *Aggregator) SetEventBus(eb *events.EventBus) {
 	a.eventBus = eb
 }
 
+// SetTraceStore 设置追踪存储，追踪查询会合并已持久化的追踪
+func (a *Aggregator) SetTraceStore(ts *TraceStore) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.traceStore = ts
+}
+
 // EventBusProvider 提供 EventBus 列表的接口
 type EventBusProvider interface {
 	GetEventBuses() []*events.EventBus
@@ -421,6 +429,18 @@ func (a *Aggregator) QueryTraces(ctx context.Context, opts TraceQueryOpts) (*Tra
 	// 构建追踪
 	traces := a.traceBuilder.BuildFromEvents(allEvents)
 
+	// 合并已持久化的追踪
+	a.mu.RLock()
+	traceStore := a.traceStore
+	a.mu.RUnlock()
+	if traceStore != nil {
+		stored, err := traceStore.List(ctx, TraceQueryOpts{StartTime: &startTime, EndTime: &endTime})
+		if err != nil {
+			return nil, err
+		}
+		traces = append(traces, stored...)
+	}
+
 	// 过滤
 	filtered := make([]*TraceSummary, 0)
 	for _, trace := range traces {
@@ -475,8 +495,20 @@ func (a *Aggregator) GetTraceDetail(ctx context.Context, traceID string) (*Trace
 		a.mu.RUnlock()
 		return cached, nil
 	}
+	traceStore := a.traceStore
 	a.mu.RUnlock()
 
+	// 按 ID 查找已持久化的追踪，EventBus 时间线过期后仍可查询
+	if traceStore != nil {
+		detail, err := traceStore.Get(ctx, traceID)
+		if err != nil {
+			return nil, err
+		}
+		if detail != nil {
+			return detail, nil
+		}
+	}
+
 	// 从 EventBus 获取所有事件
 	var allEvents []types.AgentEventEnvelope
 	if a.eventBus != nil {
@@ -589,7 +621,7 @@ func (a *Aggregator) GetPerformanceStats(ctx context.Context, period string) (*P
 	// 计算百分位数
 	toolLatencyStats := make(map[string]LatencyPercentiles)
 	for toolName, latencies := range toolLatencies {
-		toolLatencyStats[toolName] = calculatePercentiles(latencies)
+		toolLatencyStats[toolName] = CalculatePercentiles(latencies)
 	}
 
 	// 计算错误率
@@ -600,7 +632,7 @@ func (a *Aggregator) GetPerformanceStats(ctx context.Context, period string) (*P
 
 	return &PerformanceStats{
 		Period:       period,
-		TTFT:         calculatePercentiles(stepLatencies), // 简化处理
+		TTFT:         CalculatePercentiles(stepLatencies), // 简化处理
 		TPOT:         LatencyPercentiles{},                // 需要更细粒度的数据
 		ToolLatency:  toolLatencyStats,
 		AvgLoopCount: 0, // 需要从 agent 执行数据计算
@@ -691,7 +723,7 @@ func (a *Aggregator) getPeriodRange(period string, start, end *time.Time) (time.
 
 	var startTime time.Time
 	switch period {
-	case "hour":
+	case "hour", "1h":
 		startTime = now.Add(-1 * time.Hour)
 	case "day", "24h":
 		startTime = now.Add(-24 * time.Hour)
@@ -709,7 +741,7 @@ func (a *Aggregator) getPeriodRange(period string, start, end *time.Time) (time.
 // getBucketSize 获取时间桶大小
 func (a *Aggregator) getBucketSize(period string) time.Duration {
 	switch period {
-	case "hour":
+	case "hour", "1h":
 		return 5 * time.Minute
 	case "day", "24h":
 		return 1 * time.Hour
@@ -722,8 +754,8 @@ func (a *Aggregator) getBucketSize(period string) time.Duration {
 	}
 }
 
-// calculatePercentiles 计算百分位数
-func calculatePercentiles(values []int64) LatencyPercentiles {
+// CalculatePercentiles 计算延迟百分位数
+func CalculatePercentiles(values []int64) LatencyPercentiles {
 	if len(values) == 0 {
 		return LatencyPercentiles{}
 	}
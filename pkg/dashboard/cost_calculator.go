@@ -1,104 +1,213 @@
 package dashboard
 
 import (
+	"cmp"
+	"fmt"
 	"maps"
+	"slices"
 	"strings"
 	"sync"
+	"time"
 )
 
 // CostCalculator 成本计算器
+// 支持缓存读写计价、阶梯定价、批处理折扣和按生效日期选择定价，
+// 不同币种的定价按定价表中的汇率换算为计算器币种
 type CostCalculator struct {
-	mu       sync.RWMutex
-	pricing  map[string]ModelPricing
+	mu sync.RWMutex
+	// pricing 按模型保存定价历史，按生效日期升序
+	pricing map[string][]ModelPricing
+	// base 汇率的基准币种
+	base     string
+	rates    map[string]float64
 	currency string
 }
 
 // NewCostCalculator 创建成本计算器
+// 使用默认定价表（见 DefaultPricingTable），customPricing 覆盖同名模型的定价
 func NewCostCalculator(customPricing map[string]ModelPricing) *CostCalculator {
-	pricing := make(map[string]ModelPricing)
-
-	// 复制默认定价
-	maps.Copy(pricing, DefaultModelPricing)
+	cc := NewCostCalculatorFromTable(DefaultPricingTable())
 
 	// 合并自定义定价
-	maps.Copy(pricing, customPricing)
+	for model, pricing := range customPricing {
+		cc.SetPricing(model, pricing)
+	}
+
+	return cc
+}
 
-	return &CostCalculator{
-		pricing:  pricing,
-		currency: "USD",
+// NewCostCalculatorFromTable 使用指定定价表创建成本计算器，币种为定价表的基准币种
+// table 应已校验过（如 ParsePricingTable、LoadPricingFile 的返回值）
+func NewCostCalculatorFromTable(table *PricingTable) *CostCalculator {
+	base := cmp.Or(table.Currency, "USD")
+	cc := &CostCalculator{
+		pricing:  table.history(),
+		base:     base,
+		rates:    maps.Clone(table.ExchangeRates),
+		currency: base,
 	}
+	if cc.rates == nil {
+		cc.rates = make(map[string]float64)
+	}
+	return cc
+}
+
+// Usage 一次请求的 token 用量
+type Usage struct {
+	InputTokens      int64 `json:"input_tokens"`
+	OutputTokens     int64 `json:"output_tokens"`
+	CacheReadTokens  int64 `json:"cache_read_tokens,omitempty"`
+	CacheWriteTokens int64 `json:"cache_write_tokens,omitempty"`
+	// Batch 是否通过批处理 API 调用
+	Batch bool `json:"batch,omitempty"`
 }
 
 // Calculate 计算成本
 func (cc *CostCalculator) Calculate(inputTokens, outputTokens int64, model string) CostAmount {
+	return cc.CalculateUsage(model, Usage{InputTokens: inputTokens, OutputTokens: outputTokens}).TotalCost
+}
+
+// CalculateDetailed 计算详细成本
+func (cc *CostCalculator) CalculateDetailed(inputTokens, outputTokens int64, model string) *DetailedCost {
+	return cc.CalculateUsage(model, Usage{InputTokens: inputTokens, OutputTokens: outputTokens})
+}
+
+// CalculateUsage 按当前生效的定价计算一次请求的成本
+func (cc *CostCalculator) CalculateUsage(model string, usage Usage) *DetailedCost {
+	return cc.CalculateUsageAt(model, usage, time.Now())
+}
+
+// CalculateUsageAt 按 at 时刻生效的定价计算一次请求的成本
+func (cc *CostCalculator) CalculateUsageAt(model string, usage Usage, at time.Time) *DetailedCost {
 	cc.mu.RLock()
 	defer cc.mu.RUnlock()
 
-	pricing := cc.getPricing(model)
+	pricing := cc.lookup(model, at)
 
-	// 计算成本 (价格是每百万 token)
-	inputCost := float64(inputTokens) * pricing.InputPricePerM / 1_000_000
-	outputCost := float64(outputTokens) * pricing.OutputPricePerM / 1_000_000
+	// 提示 token 数超过阈值时整个请求按对应档位计价
+	input, output := pricing.InputPricePerM, pricing.OutputPricePerM
+	cacheRead, cacheWrite := pricing.CacheReadPricePerM, pricing.CacheWritePricePerM
+	var tierAbove int64
+	promptTokens := usage.InputTokens + usage.CacheReadTokens + usage.CacheWriteTokens
+	for _, tier := range pricing.Tiers {
+		if promptTokens <= tier.AboveInputTokens || tier.AboveInputTokens < tierAbove {
+			continue
+		}
+		tierAbove = tier.AboveInputTokens
+		input = cmp.Or(tier.InputPricePerM, pricing.InputPricePerM)
+		output = cmp.Or(tier.OutputPricePerM, pricing.OutputPricePerM)
+		cacheRead = cmp.Or(tier.CacheReadPricePerM, pricing.CacheReadPricePerM)
+		cacheWrite = cmp.Or(tier.CacheWritePricePerM, pricing.CacheWritePricePerM)
+	}
+	// 没有单独的缓存价格时按输入价格计算
+	cacheRead = cmp.Or(cacheRead, input)
+	cacheWrite = cmp.Or(cacheWrite, input)
 
-	return CostAmount{
-		Amount:   inputCost + outputCost,
-		Currency: cc.currency,
+	factor := 1.0
+	if usage.Batch && pricing.BatchDiscount > 0 {
+		factor = 1 - pricing.BatchDiscount
+	}
+	cost := func(tokens int64, pricePerM float64) CostAmount {
+		// 价格是每百万 token
+		return cc.convert(float64(tokens)*pricePerM*factor/1_000_000, pricing.Currency)
+	}
+
+	detail := &DetailedCost{
+		Model:            model,
+		InputTokens:      usage.InputTokens,
+		OutputTokens:     usage.OutputTokens,
+		CacheReadTokens:  usage.CacheReadTokens,
+		CacheWriteTokens: usage.CacheWriteTokens,
+		InputCost:        cost(usage.InputTokens, input),
+		OutputCost:       cost(usage.OutputTokens, output),
+		CacheReadCost:    cost(usage.CacheReadTokens, cacheRead),
+		CacheWriteCost:   cost(usage.CacheWriteTokens, cacheWrite),
+		TierAboveTokens:  tierAbove,
+		Batch:            usage.Batch && pricing.BatchDiscount > 0,
+		Pricing:          pricing,
 	}
+	detail.TotalCost = CostAmount{
+		Amount:   detail.InputCost.Amount + detail.OutputCost.Amount + detail.CacheReadCost.Amount + detail.CacheWriteCost.Amount,
+		Currency: detail.InputCost.Currency,
+	}
+	return detail
 }
 
-// CalculateDetailed 计算详细成本
-func (cc *CostCalculator) CalculateDetailed(inputTokens, outputTokens int64, model string) *DetailedCost {
-	cc.mu.RLock()
-	defer cc.mu.RUnlock()
+// convert 将 currency 币种的金额换算为计算器币种，缺少汇率时保留原币种
+func (cc *CostCalculator) convert(amount float64, currency string) CostAmount {
+	if currency == "" || currency == cc.currency {
+		return CostAmount{Amount: amount, Currency: cc.currency}
+	}
+	from, to := cc.rate(currency), cc.rate(cc.currency)
+	if from == 0 || to == 0 {
+		return CostAmount{Amount: amount, Currency: currency}
+	}
+	return CostAmount{Amount: amount * from / to, Currency: cc.currency}
+}
 
-	pricing := cc.getPricing(model)
-
-	inputCost := float64(inputTokens) * pricing.InputPricePerM / 1_000_000
-	outputCost := float64(outputTokens) * pricing.OutputPricePerM / 1_000_000
-
-	return &DetailedCost{
-		Model:        model,
-		InputTokens:  inputTokens,
-		OutputTokens: outputTokens,
-		InputCost: CostAmount{
-			Amount:   inputCost,
-			Currency: cc.currency,
-		},
-		OutputCost: CostAmount{
-			Amount:   outputCost,
-			Currency: cc.currency,
-		},
-		TotalCost: CostAmount{
-			Amount:   inputCost + outputCost,
-			Currency: cc.currency,
-		},
-		Pricing: pricing,
+// rate 返回 1 单位 currency 折合多少基准币种，未知时返回 0
+func (cc *CostCalculator) rate(currency string) float64 {
+	if currency == cc.base {
+		return 1
 	}
+	return cc.rates[currency]
 }
 
-// SetPricing 设置模型定价
+// SetPricing 设置模型定价，替换该模型的全部历史定价
 func (cc *CostCalculator) SetPricing(model string, pricing ModelPricing) {
 	cc.mu.Lock()
 	defer cc.mu.Unlock()
 
-	cc.pricing[model] = pricing
+	cc.pricing[model] = []ModelPricing{pricing}
+}
+
+// LoadPricingTable 合并定价表：同一模型和生效日期的定价被替换，汇率被合并
+func (cc *CostCalculator) LoadPricingTable(table *PricingTable) error {
+	if err := table.Validate(); err != nil {
+		return err
+	}
+
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+
+	if table.Currency != cc.base {
+		return fmt.Errorf("pricing table currency %s does not match %s", table.Currency, cc.base)
+	}
+	maps.Copy(cc.rates, table.ExchangeRates)
+	for model, history := range table.history() {
+		for _, p := range history {
+			i := slices.IndexFunc(cc.pricing[model], func(m ModelPricing) bool {
+				return m.EffectiveFrom == p.EffectiveFrom
+			})
+			if i >= 0 {
+				cc.pricing[model][i] = p
+			} else {
+				cc.pricing[model] = append(cc.pricing[model], p)
+			}
+		}
+		sortByEffectiveFrom(cc.pricing[model])
+	}
+	return nil
 }
 
-// GetPricing 获取模型定价
+// GetPricing 获取模型当前生效的定价
 func (cc *CostCalculator) GetPricing(model string) ModelPricing {
 	cc.mu.RLock()
 	defer cc.mu.RUnlock()
 
+	return cc.lookup(model, time.Now())
+}
+
+// lookup 返回 at 时刻生效的定价，没有该模型的定价时按模型名称推断（不加锁）
+func (cc *CostCalculator) lookup(model string, at time.Time) ModelPricing {
+	if history := cc.pricing[model]; len(history) > 0 {
+		return effectiveAt(history, at)
+	}
 	return cc.getPricing(model)
 }
 
 // getPricing 内部获取定价方法（不加锁）
 func (cc *CostCalculator) getPricing(model string) ModelPricing {
-	// 精确匹配
-	if pricing, ok := cc.pricing[model]; ok {
-		return pricing
-	}
-
 	// 模糊匹配（处理模型名称变体）
 	modelLower := strings.ToLower(model)
 
@@ -213,18 +322,21 @@ func (cc *CostCalculator) getPricing(model string) ModelPricing {
 	}
 }
 
-// ListPricing 列出所有定价
+// ListPricing 列出所有模型当前生效的定价
 func (cc *CostCalculator) ListPricing() map[string]ModelPricing {
 	cc.mu.RLock()
 	defer cc.mu.RUnlock()
 
-	result := make(map[string]ModelPricing)
-	maps.Copy(result, cc.pricing)
+	now := time.Now()
+	result := make(map[string]ModelPricing, len(cc.pricing))
+	for model, history := range cc.pricing {
+		result[model] = effectiveAt(history, now)
+	}
 
 	return result
 }
 
-// SetCurrency 设置货币单位
+// SetCurrency 设置货币单位，有汇率时计算结果换算为该币种
 func (cc *CostCalculator) SetCurrency(currency string) {
 	cc.mu.Lock()
 	defer cc.mu.Unlock()
@@ -232,15 +344,31 @@ func (cc *CostCalculator) SetCurrency(currency string) {
 	cc.currency = currency
 }
 
+// Currency 返回货币单位
+func (cc *CostCalculator) Currency() string {
+	cc.mu.RLock()
+	defer cc.mu.RUnlock()
+
+	return cc.currency
+}
+
 // DetailedCost 详细成本
 type DetailedCost struct {
-	Model        string       `json:"model"`
-	InputTokens  int64        `json:"input_tokens"`
-	OutputTokens int64        `json:"output_tokens"`
-	InputCost    CostAmount   `json:"input_cost"`
-	OutputCost   CostAmount   `json:"output_cost"`
-	TotalCost    CostAmount   `json:"total_cost"`
-	Pricing      ModelPricing `json:"pricing"`
+	Model            string     `json:"model"`
+	InputTokens      int64      `json:"input_tokens"`
+	OutputTokens     int64      `json:"output_tokens"`
+	CacheReadTokens  int64      `json:"cache_read_tokens,omitempty"`
+	CacheWriteTokens int64      `json:"cache_write_tokens,omitempty"`
+	InputCost        CostAmount `json:"input_cost"`
+	OutputCost       CostAmount `json:"output_cost"`
+	CacheReadCost    CostAmount `json:"cache_read_cost"`
+	CacheWriteCost   CostAmount `json:"cache_write_cost"`
+	TotalCost        CostAmount `json:"total_cost"`
+	// TierAboveTokens 适用的阶梯定价阈值，0 表示基础价格
+	TierAboveTokens int64 `json:"tier_above_tokens,omitempty"`
+	// Batch 是否应用了批处理折扣
+	Batch   bool         `json:"batch,omitempty"`
+	Pricing ModelPricing `json:"pricing"`
 }
 
 // EstimateCost 预估成本（用于预算规划）
@@ -248,26 +376,41 @@ func (cc *CostCalculator) EstimateCost(model string, estimatedInputTokens, estim
 	return cc.Calculate(estimatedInputTokens, estimatedOutputTokens, model)
 }
 
-// CalculateBatch 批量计算成本
+// CalculateBatch 批量计算成本，无法换算为计算器币种的成本不计入
 func (cc *CostCalculator) CalculateBatch(usages []TokenUsageWithModel) CostAmount {
+	cc.mu.RLock()
+	currency := cc.currency
+	cc.mu.RUnlock()
+
 	var totalAmount float64
 
 	for _, usage := range usages {
-		cost := cc.Calculate(usage.InputTokens, usage.OutputTokens, usage.Model)
-		totalAmount += cost.Amount
+		cost := cc.CalculateUsage(usage.Model, Usage{
+			InputTokens:      usage.InputTokens,
+			OutputTokens:     usage.OutputTokens,
+			CacheReadTokens:  usage.CacheReadTokens,
+			CacheWriteTokens: usage.CacheWriteTokens,
+			Batch:            usage.Batch,
+		}).TotalCost
+		if cost.Currency == currency {
+			totalAmount += cost.Amount
+		}
 	}
 
 	return CostAmount{
 		Amount:   totalAmount,
-		Currency: cc.currency,
+		Currency: currency,
 	}
 }
 
 // TokenUsageWithModel Token 使用量（带模型信息）
 type TokenUsageWithModel struct {
-	Model        string `json:"model"`
-	InputTokens  int64  `json:"input_tokens"`
-	OutputTokens int64  `json:"output_tokens"`
+	Model            string `json:"model"`
+	InputTokens      int64  `json:"input_tokens"`
+	OutputTokens     int64  `json:"output_tokens"`
+	CacheReadTokens  int64  `json:"cache_read_tokens,omitempty"`
+	CacheWriteTokens int64  `json:"cache_write_tokens,omitempty"`
+	Batch            bool   `json:"batch,omitempty"`
 }
 
 // FormatCost 格式化成本显示
@@ -1,7 +1,11 @@
 package dashboard
 
 import (
+	"math"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestCostCalculator_Calculate(t *testing.T) {
@@ -214,6 +218,119 @@ func TestCostCalculator_CalculateBatch(t *testing.T) {
 	}
 }
 
+func approxEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestCostCalculator_CacheTiersAndBatch(t *testing.T) {
+	cc := NewCostCalculator(nil)
+	model := "claude-sonnet-4-20250514"
+
+	// 基础价格：1M 输入 3 + 1M 输出 15 + 1M 缓存读取 0.3 + 1M 缓存写入 3.75；提示共 3M 超过 200k，按高档计价
+	// 高档：6 + 22.5 + 0.6 + 7.5 = 36.6
+	detail := cc.CalculateUsage(model, Usage{
+		InputTokens: 1_000_000, OutputTokens: 1_000_000, CacheReadTokens: 1_000_000, CacheWriteTokens: 1_000_000,
+	})
+	if detail.TierAboveTokens != 200_000 || !approxEqual(detail.TotalCost.Amount, 36.6) {
+		t.Errorf("tiered cost = %v (tier %d), want 36.6 above 200000", detail.TotalCost.Amount, detail.TierAboveTokens)
+	}
+
+	// 100k 提示 token 使用基础价格：(50k*3 + 50k*0.3 + 10k*15) / 1M
+	detail = cc.CalculateUsage(model, Usage{InputTokens: 50_000, CacheReadTokens: 50_000, OutputTokens: 10_000})
+	if detail.TierAboveTokens != 0 || !approxEqual(detail.TotalCost.Amount, 0.315) {
+		t.Errorf("base cost = %v (tier %d), want 0.315", detail.TotalCost.Amount, detail.TierAboveTokens)
+	}
+	if !approxEqual(detail.CacheReadCost.Amount, 0.015) {
+		t.Errorf("cache read cost = %v, want 0.015", detail.CacheReadCost.Amount)
+	}
+
+	// 批处理半价
+	batch := cc.CalculateUsage(model, Usage{InputTokens: 50_000, CacheReadTokens: 50_000, OutputTokens: 10_000, Batch: true})
+	if !batch.Batch || !approxEqual(batch.TotalCost.Amount, 0.1575) {
+		t.Errorf("batch cost = %v, want 0.1575", batch.TotalCost.Amount)
+	}
+
+	// 没有缓存价格时按输入价格计算
+	cc.SetPricing("plain", ModelPricing{Model: "plain", InputPricePerM: 2, OutputPricePerM: 4, Currency: "USD"})
+	if got := cc.CalculateUsage("plain", Usage{CacheReadTokens: 1_000_000, CacheWriteTokens: 1_000_000}); !approxEqual(got.TotalCost.Amount, 4) {
+		t.Errorf("cache cost without cache pricing = %v, want 4", got.TotalCost.Amount)
+	}
+}
+
+func TestCostCalculator_EffectiveDatesAndCurrency(t *testing.T) {
+	cc := NewCostCalculator(nil)
+
+	before := cc.CalculateUsageAt("gpt-4o", Usage{InputTokens: 1_000_000, OutputTokens: 1_000_000}, time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC))
+	after := cc.CalculateUsageAt("gpt-4o", Usage{InputTokens: 1_000_000, OutputTokens: 1_000_000}, time.Date(2024, 11, 1, 0, 0, 0, 0, time.UTC))
+	if !approxEqual(before.TotalCost.Amount, 20) || !approxEqual(after.TotalCost.Amount, 12.5) {
+		t.Errorf("gpt-4o cost before/after price change = %v/%v, want 20/12.5", before.TotalCost.Amount, after.TotalCost.Amount)
+	}
+
+	// deepseek 按人民币定价，换算为美元
+	got := cc.Calculate(1_000_000, 1_000_000, "deepseek-chat")
+	if got.Currency != "USD" || !approxEqual(got.Amount, 0.42) {
+		t.Errorf("deepseek cost = %+v, want 0.42 USD", got)
+	}
+
+	cc.SetCurrency("CNY")
+	got = cc.Calculate(1_000_000, 1_000_000, "deepseek-chat")
+	if got.Currency != "CNY" || !approxEqual(got.Amount, 3) {
+		t.Errorf("deepseek cost in CNY = %+v, want 3 CNY", got)
+	}
+
+	// 缺少汇率时保留原币种，批量计算不计入
+	cc.SetPricing("euro-model", ModelPricing{Model: "euro-model", InputPricePerM: 1, OutputPricePerM: 1, Currency: "EUR"})
+	if got := cc.Calculate(1_000_000, 0, "euro-model"); got.Currency != "EUR" || got.Amount != 1 {
+		t.Errorf("unconvertible cost = %+v, want 1 EUR", got)
+	}
+	total := cc.CalculateBatch([]TokenUsageWithModel{
+		{Model: "deepseek-chat", InputTokens: 1_000_000},
+		{Model: "euro-model", InputTokens: 1_000_000},
+	})
+	if total.Currency != "CNY" || !approxEqual(total.Amount, 1) {
+		t.Errorf("CalculateBatch() = %+v, want 1 CNY", total)
+	}
+}
+
+func TestLoadPricingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pricing.json")
+	override := `{
+		"currency": "USD",
+		"exchange_rates": {"EUR": 1.1},
+		"models": [
+			{"model": "gpt-4o", "input_price_per_m": 2.0, "output_price_per_m": 8.0, "effective_from": "2030-01-01"},
+			{"model": "in-house", "input_price_per_m": 1.0, "output_price_per_m": 1.0, "currency": "EUR"}
+		]
+	}`
+	if err := os.WriteFile(path, []byte(override), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	table := loadDefaultPricing(path)
+	cc := NewCostCalculatorFromTable(table)
+	usage := Usage{InputTokens: 1_000_000, OutputTokens: 1_000_000}
+	if got := cc.CalculateUsageAt("gpt-4o", usage, time.Date(2030, 2, 1, 0, 0, 0, 0, time.UTC)); !approxEqual(got.TotalCost.Amount, 10) {
+		t.Errorf("overridden gpt-4o cost = %v, want 10", got.TotalCost.Amount)
+	}
+	if got := cc.CalculateUsageAt("gpt-4o", usage, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)); !approxEqual(got.TotalCost.Amount, 12.5) {
+		t.Errorf("bundled gpt-4o cost = %v, want 12.5", got.TotalCost.Amount)
+	}
+	if got := cc.Calculate(1_000_000, 1_000_000, "in-house"); got.Currency != "USD" || !approxEqual(got.Amount, 2.2) {
+		t.Errorf("in-house cost = %+v, want 2.2 USD", got)
+	}
+
+	// 无效的覆盖文件回退到内置定价表
+	if err := os.WriteFile(path, []byte(`{"models":[{"model":"x","currency":"JPY"}]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadPricingFile(path); err == nil {
+		t.Error("LoadPricingFile() should reject a currency without exchange rate")
+	}
+	if table := loadDefaultPricing(path); len(table.Models) != len(loadDefaultPricing("").Models) {
+		t.Error("invalid override should fall back to the bundled table")
+	}
+}
+
 func TestFormatCost(t *testing.T) {
 	tests := []struct {
 		cost CostAmount
@@ -0,0 +1,256 @@
+package dashboard
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/astercloud/aster/pkg/logging"
+)
+
+// PricingFileEnv 指定覆盖内置定价表的文件路径
+const PricingFileEnv = "ASTER_PRICING_FILE"
+
+// effectiveDateLayout 生效日期格式
+const effectiveDateLayout = "2006-01-02"
+
+//go:embed pricing.json
+var bundledPricingJSON []byte
+
+// PricingTable 定价表
+// 同一模型可以有多条定价，按生效日期选择计费时刻适用的一条
+type PricingTable struct {
+	Version string `json:"version,omitempty"`
+	// Currency 基准币种，汇率以它为单位
+	Currency string `json:"currency"`
+	// ExchangeRates 1 单位其他币种折合多少基准币种，用于不同币种之间换算
+	ExchangeRates map[string]float64 `json:"exchange_rates,omitempty"`
+	Models        []ModelPricing     `json:"models"`
+}
+
+// ParsePricingTable 解析并校验 JSON 格式的定价表
+func ParsePricingTable(data []byte) (*PricingTable, error) {
+	var table PricingTable
+	if err := json.Unmarshal(data, &table); err != nil {
+		return nil, fmt.Errorf("parse pricing table: %w", err)
+	}
+	if err := table.Validate(); err != nil {
+		return nil, err
+	}
+	return &table, nil
+}
+
+// LoadPricingFile 从文件加载定价表
+func LoadPricingFile(path string) (*PricingTable, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read pricing file: %w", err)
+	}
+	table, err := ParsePricingTable(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return table, nil
+}
+
+// Validate 校验定价表，并为未指定币种的模型补上基准币种
+func (t *PricingTable) Validate() error {
+	if t.Currency == "" {
+		t.Currency = "USD"
+	}
+	for currency, rate := range t.ExchangeRates {
+		if rate <= 0 {
+			return fmt.Errorf("exchange rate for %s must be positive", currency)
+		}
+	}
+	for i := range t.Models {
+		p := &t.Models[i]
+		if p.Model == "" {
+			return fmt.Errorf("models[%d]: model is required", i)
+		}
+		if p.Currency == "" {
+			p.Currency = t.Currency
+		}
+		if p.Currency != t.Currency && t.ExchangeRates[p.Currency] == 0 {
+			return fmt.Errorf("%s: no exchange rate for currency %s", p.Model, p.Currency)
+		}
+		if _, err := effectiveFrom(*p); err != nil {
+			return fmt.Errorf("%s: invalid effective_from %q", p.Model, p.EffectiveFrom)
+		}
+		if p.InputPricePerM < 0 || p.OutputPricePerM < 0 || p.CacheReadPricePerM < 0 || p.CacheWritePricePerM < 0 {
+			return fmt.Errorf("%s: prices must not be negative", p.Model)
+		}
+		if p.BatchDiscount < 0 || p.BatchDiscount >= 1 {
+			return fmt.Errorf("%s: batch_discount must be in [0, 1)", p.Model)
+		}
+		for _, tier := range p.Tiers {
+			if tier.AboveInputTokens <= 0 {
+				return fmt.Errorf("%s: tier above_input_tokens must be positive", p.Model)
+			}
+		}
+	}
+	return nil
+}
+
+// Merge 返回合并 other 后的新定价表
+// other 中模型和生效日期都相同的定价替换原有定价，其余追加
+func (t *PricingTable) Merge(other *PricingTable) (*PricingTable, error) {
+	if other.Currency != "" && other.Currency != t.Currency {
+		return nil, fmt.Errorf("pricing table currency %s does not match %s", other.Currency, t.Currency)
+	}
+	merged := &PricingTable{
+		Version:       t.Version,
+		Currency:      t.Currency,
+		ExchangeRates: make(map[string]float64, len(t.ExchangeRates)+len(other.ExchangeRates)),
+		Models:        slices.Clone(t.Models),
+	}
+	if other.Version != "" {
+		merged.Version = other.Version
+	}
+	maps.Copy(merged.ExchangeRates, t.ExchangeRates)
+	maps.Copy(merged.ExchangeRates, other.ExchangeRates)
+	for _, p := range other.Models {
+		i := slices.IndexFunc(merged.Models, func(m ModelPricing) bool {
+			return m.Model == p.Model && m.EffectiveFrom == p.EffectiveFrom
+		})
+		if i >= 0 {
+			merged.Models[i] = p
+		} else {
+			merged.Models = append(merged.Models, p)
+		}
+	}
+	return merged, merged.Validate()
+}
+
+// Current 返回每个模型在 at 时刻生效的定价
+func (t *PricingTable) Current(at time.Time) map[string]ModelPricing {
+	result := make(map[string]ModelPricing)
+	for model, history := range t.history() {
+		result[model] = effectiveAt(history, at)
+	}
+	return result
+}
+
+// history 按模型分组，每组按生效日期升序
+func (t *PricingTable) history() map[string][]ModelPricing {
+	result := make(map[string][]ModelPricing)
+	for _, p := range t.Models {
+		result[p.Model] = append(result[p.Model], p)
+	}
+	for _, history := range result {
+		sortByEffectiveFrom(history)
+	}
+	return result
+}
+
+var (
+	defaultPricingOnce sync.Once
+	defaultPricingMu   sync.RWMutex
+	defaultPricing     *PricingTable
+)
+
+// DefaultPricingTable 返回默认定价表
+// 默认定价表为内置定价表，设置了 ASTER_PRICING_FILE 时合并该文件中的定价
+// 返回值应视为只读，修改请使用 SetDefaultPricingTable 或 SetDefaultPricing
+func DefaultPricingTable() *PricingTable {
+	defaultPricingOnce.Do(func() {
+		table := loadDefaultPricing(os.Getenv(PricingFileEnv))
+		defaultPricingMu.Lock()
+		if defaultPricing == nil {
+			defaultPricing = table
+		}
+		defaultPricingMu.Unlock()
+	})
+	defaultPricingMu.RLock()
+	defer defaultPricingMu.RUnlock()
+	return defaultPricing
+}
+
+// SetDefaultPricingTable 替换默认定价表，之后创建的 CostCalculator 使用新表
+func SetDefaultPricingTable(table *PricingTable) {
+	defaultPricingOnce.Do(func() {})
+	defaultPricingMu.Lock()
+	defer defaultPricingMu.Unlock()
+	defaultPricing = table
+}
+
+// SetDefaultPricing 在默认定价表中设置模型定价，替换该模型的全部历史定价
+func SetDefaultPricing(pricing ModelPricing) error {
+	current := DefaultPricingTable()
+	pricing.EffectiveFrom = ""
+	table := &PricingTable{
+		Version:       current.Version,
+		Currency:      current.Currency,
+		ExchangeRates: current.ExchangeRates,
+	}
+	for _, p := range current.Models {
+		if p.Model != pricing.Model {
+			table.Models = append(table.Models, p)
+		}
+	}
+	table.Models = append(table.Models, pricing)
+	if err := table.Validate(); err != nil {
+		return err
+	}
+	SetDefaultPricingTable(table)
+	return nil
+}
+
+// loadDefaultPricing 加载内置定价表，path 不为空时合并覆盖文件
+// 覆盖文件无效时记录警告并只使用内置定价表
+func loadDefaultPricing(path string) *PricingTable {
+	table, err := ParsePricingTable(bundledPricingJSON)
+	if err != nil {
+		panic(fmt.Sprintf("dashboard: invalid bundled pricing table: %v", err))
+	}
+	if path == "" {
+		return table
+	}
+	override, err := LoadPricingFile(path)
+	if err == nil {
+		var merged *PricingTable
+		if merged, err = table.Merge(override); err == nil {
+			return merged
+		}
+	}
+	logging.Warn(context.Background(), "dashboard.pricing.override_failed", map[string]any{
+		"path":  path,
+		"error": err.Error(),
+	})
+	return table
+}
+
+// effectiveFrom 解析生效日期，为空时返回零值
+func effectiveFrom(p ModelPricing) (time.Time, error) {
+	if p.EffectiveFrom == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(effectiveDateLayout, p.EffectiveFrom)
+}
+
+// sortByEffectiveFrom 按生效日期升序排序（日期已校验）
+func sortByEffectiveFrom(history []ModelPricing) {
+	slices.SortStableFunc(history, func(a, b ModelPricing) int {
+		ta, _ := effectiveFrom(a)
+		tb, _ := effectiveFrom(b)
+		return ta.Compare(tb)
+	})
+}
+
+// effectiveAt 返回 at 时刻生效的定价，早于所有生效日期时使用最早的定价
+func effectiveAt(history []ModelPricing, at time.Time) ModelPricing {
+	result := history[0]
+	for _, p := range history[1:] {
+		if from, _ := effectiveFrom(p); from.After(at) {
+			break
+		}
+		result = p
+	}
+	return result
+}
@@ -0,0 +1,168 @@
+package dashboard
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/astercloud/aster/pkg/types"
+)
+
+// runTraceID 一次运行的追踪 ID，见 types.RunTraceID
+func runTraceID(agentID string, cursor int64) string {
+	return types.RunTraceID(agentID, cursor)
+}
+
+// TraceRecorder 从 Agent 事件流构建运行追踪，运行结束时按采样策略写入 TraceStore；
+// 追踪 ID 与 Agent 详情中 recent_traces 的 ID 一致
+type TraceRecorder struct {
+	store *TraceStore
+	cost  *CostCalculator
+
+	mu   sync.Mutex
+	runs map[string]*TraceDetail // key: agentID，进行中的运行
+}
+
+// NewTraceRecorder 创建追踪记录器
+func NewTraceRecorder(ts *TraceStore) *TraceRecorder {
+	return &TraceRecorder{
+		store: ts,
+		cost:  NewCostCalculator(nil),
+		runs:  make(map[string]*TraceDetail),
+	}
+}
+
+// Observe 处理 Agent 的一个事件
+func (r *TraceRecorder) Observe(ctx context.Context, agentID string, env types.AgentEventEnvelope) {
+	finished := r.observe(agentID, env)
+	if finished == nil || !r.store.ShouldKeep(finished) {
+		return
+	}
+	if err := r.store.Save(ctx, finished); err != nil {
+		dashboardLog.Warn(ctx, "trace persist failed", map[string]any{
+			"agent_id": agentID, "trace_id": finished.ID, "error": err.Error(),
+		})
+	}
+}
+
+// Discard 丢弃 Agent 进行中的运行（Agent 注销时调用）
+func (r *TraceRecorder) Discard(agentID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.runs, agentID)
+}
+
+// observe 更新进行中的运行，运行结束时返回完成的追踪
+func (r *TraceRecorder) observe(agentID string, env types.AgentEventEnvelope) *TraceDetail {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ts := time.Unix(env.Bookmark.Timestamp, 0)
+	run := r.runs[agentID]
+	start := func() *TraceDetail {
+		id := runTraceID(agentID, env.Cursor)
+		run = &TraceDetail{
+			TraceSummary: TraceSummary{
+				ID:        id,
+				Name:      "agent.run",
+				AgentID:   agentID,
+				StartTime: ts,
+				Status:    TraceStatusRunning,
+			},
+			RootSpan: &TraceNode{
+				ID:        id,
+				Name:      "agent.run",
+				Type:      TraceNodeTypeAgent,
+				StartTime: ts,
+				Status:    TraceStatusRunning,
+			},
+		}
+		r.runs[agentID] = run
+		return run
+	}
+
+	switch evt := normalizeEvent(env.Event).(type) {
+	case *types.MonitorStateChangedEvent:
+		if evt.State == types.AgentStateWorking && run == nil {
+			start()
+		}
+
+	case *types.MonitorTokenUsageEvent:
+		if run == nil {
+			start()
+		}
+		run.TokenUsage.Input += evt.InputTokens
+		run.TokenUsage.Output += evt.OutputTokens
+		run.addSpan(&TraceNode{
+			ID:        fmt.Sprintf("%s-llm-%d", run.ID, env.Cursor),
+			Name:      "llm.call",
+			Type:      TraceNodeTypeLLM,
+			StartTime: ts,
+			Status:    TraceStatusOK,
+			Attributes: map[string]any{
+				"input_tokens":  evt.InputTokens,
+				"output_tokens": evt.OutputTokens,
+			},
+		})
+
+	case *types.ProgressToolEndEvent:
+		if run == nil {
+			start()
+		}
+		span := &TraceNode{
+			ID:         fmt.Sprintf("%s-tool-%d", run.ID, env.Cursor),
+			Name:       evt.Call.Name,
+			Type:       TraceNodeTypeTool,
+			StartTime:  ts,
+			Status:     TraceStatusOK,
+			Attributes: map[string]any{"call_id": evt.Call.ID, "arguments": evt.Call.Arguments},
+		}
+		if !evt.Call.StartedAt.IsZero() && evt.Call.UpdatedAt.After(evt.Call.StartedAt) {
+			end := evt.Call.UpdatedAt
+			span.StartTime, span.EndTime = evt.Call.StartedAt, &end
+			span.DurationMs = end.Sub(evt.Call.StartedAt).Milliseconds()
+		}
+		if evt.Call.Error != "" {
+			span.Status = TraceStatusError
+			span.Attributes["error"] = evt.Call.Error
+		}
+		run.addSpan(span)
+
+	case *types.MonitorErrorEvent:
+		if run != nil && evt.Severity == "error" {
+			run.Status = TraceStatusError
+			run.ErrorMessage = evt.Message
+		}
+
+	case *types.ProgressDoneEvent:
+		if run == nil {
+			return nil
+		}
+		delete(r.runs, agentID)
+		if evt.Error != nil {
+			run.Status = TraceStatusError
+			if run.ErrorMessage == "" {
+				run.ErrorMessage = evt.Error.Error()
+			}
+		} else if run.Status == TraceStatusRunning {
+			run.Status = TraceStatusOK
+		}
+		run.DurationMs = ts.Sub(run.StartTime).Milliseconds()
+		run.TokenUsage.Total = run.TokenUsage.Input + run.TokenUsage.Output
+		run.TraceSummary.TokenUsage = run.TokenUsage
+		run.Cost = r.cost.Calculate(run.TokenUsage.Input, run.TokenUsage.Output, "")
+
+		root := run.RootSpan
+		root.EndTime, root.DurationMs, root.Status = &ts, run.DurationMs, run.Status
+		root.Attributes = map[string]any{"step": evt.Step, "reason": string(evt.Reason)}
+		return run
+	}
+	return nil
+}
+
+// addSpan 向运行的根 span 追加子 span
+func (d *TraceDetail) addSpan(span *TraceNode) {
+	d.RootSpan.Children = append(d.RootSpan.Children, span)
+	d.SpanCount++
+}
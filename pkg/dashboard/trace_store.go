@@ -0,0 +1,209 @@
+package dashboard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"time"
+
+	"github.com/astercloud/aster/pkg/logging"
+	"github.com/astercloud/aster/pkg/store"
+)
+
+// traceCollection 持久化追踪所在的集合
+const traceCollection = "traces"
+
+var dashboardLog = logging.ForComponent("Dashboard")
+
+// TraceRetentionPolicy 追踪持久化的采样与保留策略
+type TraceRetentionPolicy struct {
+	// SampleRate 头部采样比例（0-1），按追踪 ID 哈希决定，同一追踪的结果稳定
+	SampleRate float64
+	// AlwaysSampleErrors 出错的追踪不受采样比例限制，一律保留
+	AlwaysSampleErrors bool
+	// Retention 保留时长，超过后由压缩任务删除；0 表示永久保留
+	Retention time.Duration
+	// CompactAfter 超过该时长的追踪去掉 span 属性（工具参数等）只保留结构和摘要；0 表示不压缩
+	CompactAfter time.Duration
+}
+
+// TraceCompaction 一次压缩任务的结果
+type TraceCompaction struct {
+	Removed   int `json:"removed"`
+	Compacted int `json:"compacted"`
+}
+
+// TraceStore 基于 Store 的追踪持久化，使 EventBus 内存时间线之外的追踪仍可查询
+type TraceStore struct {
+	store  store.Store
+	policy TraceRetentionPolicy
+	now    func() time.Time
+}
+
+// NewTraceStore 创建追踪存储
+func NewTraceStore(st store.Store, policy TraceRetentionPolicy) *TraceStore {
+	return &TraceStore{store: st, policy: policy, now: time.Now}
+}
+
+// Policy 返回采样与保留策略
+func (s *TraceStore) Policy() TraceRetentionPolicy {
+	return s.policy
+}
+
+// HeadSampled 追踪是否被头部采样选中
+func (s *TraceStore) HeadSampled(traceID string) bool {
+	if s.policy.SampleRate >= 1 {
+		return true
+	}
+	if s.policy.SampleRate <= 0 {
+		return false
+	}
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(traceID))
+	return float64(h.Sum64()%10000)/10000 < s.policy.SampleRate
+}
+
+// ShouldKeep 已完成的追踪是否需要持久化
+func (s *TraceStore) ShouldKeep(detail *TraceDetail) bool {
+	if s.policy.AlwaysSampleErrors && detail.Status == TraceStatusError {
+		return true
+	}
+	return s.HeadSampled(detail.ID)
+}
+
+// Save 保存追踪，不经过采样判断
+func (s *TraceStore) Save(ctx context.Context, detail *TraceDetail) error {
+	if err := s.store.Set(ctx, traceCollection, detail.ID, detail); err != nil {
+		return fmt.Errorf("save trace %s: %w", detail.ID, err)
+	}
+	return nil
+}
+
+// Get 读取追踪，不存在时返回 nil
+func (s *TraceStore) Get(ctx context.Context, traceID string) (*TraceDetail, error) {
+	var detail TraceDetail
+	if err := s.store.Get(ctx, traceCollection, traceID, &detail); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("load trace %s: %w", traceID, err)
+	}
+	return &detail, nil
+}
+
+// List 按时间范围、Agent 和状态列出已保存追踪的摘要，最新的在前
+func (s *TraceStore) List(ctx context.Context, opts TraceQueryOpts) ([]*TraceSummary, error) {
+	details, err := s.all(ctx)
+	if err != nil {
+		return nil, err
+	}
+	summaries := make([]*TraceSummary, 0, len(details))
+	for _, d := range details {
+		if opts.StartTime != nil && d.StartTime.Before(*opts.StartTime) {
+			continue
+		}
+		if opts.EndTime != nil && d.StartTime.After(*opts.EndTime) {
+			continue
+		}
+		if opts.AgentID != "" && d.AgentID != opts.AgentID {
+			continue
+		}
+		if opts.Status != "" && string(d.Status) != opts.Status {
+			continue
+		}
+		summary := d.TraceSummary
+		summaries = append(summaries, &summary)
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].StartTime.After(summaries[j].StartTime)
+	})
+	return summaries, nil
+}
+
+// all 读取全部已保存追踪
+func (s *TraceStore) all(ctx context.Context) ([]*TraceDetail, error) {
+	items, err := s.store.List(ctx, traceCollection)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("list traces: %w", err)
+	}
+	details := make([]*TraceDetail, 0, len(items))
+	for _, item := range items {
+		var d TraceDetail
+		if err := store.DecodeValue(item, &d); err != nil || d.ID == "" {
+			continue
+		}
+		details = append(details, &d)
+	}
+	return details, nil
+}
+
+// Compact 删除超过保留时长的追踪，并压缩超过 CompactAfter 的追踪
+func (s *TraceStore) Compact(ctx context.Context) (TraceCompaction, error) {
+	var result TraceCompaction
+	if s.policy.Retention <= 0 && s.policy.CompactAfter <= 0 {
+		return result, nil
+	}
+	details, err := s.all(ctx)
+	if err != nil {
+		return result, err
+	}
+
+	now := s.now()
+	for _, d := range details {
+		age := now.Sub(d.StartTime)
+		switch {
+		case s.policy.Retention > 0 && age > s.policy.Retention:
+			if err := s.store.Delete(ctx, traceCollection, d.ID); err != nil {
+				return result, fmt.Errorf("delete trace %s: %w", d.ID, err)
+			}
+			result.Removed++
+		case s.policy.CompactAfter > 0 && age > s.policy.CompactAfter && !d.Compacted:
+			stripAttributes(d.RootSpan)
+			d.Compacted = true
+			if err := s.Save(ctx, d); err != nil {
+				return result, err
+			}
+			result.Compacted++
+		}
+	}
+	return result, nil
+}
+
+// stripAttributes 去掉 span 树中的属性
+func stripAttributes(node *TraceNode) {
+	if node == nil {
+		return
+	}
+	node.Attributes = nil
+	for _, child := range node.Children {
+		stripAttributes(child)
+	}
+}
+
+// RunCompaction 按 interval 周期执行压缩，直到 ctx 取消
+func (s *TraceStore) RunCompaction(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			result, err := s.Compact(ctx)
+			if err != nil {
+				dashboardLog.Warn(ctx, "trace compaction failed", map[string]any{"error": err.Error()})
+				continue
+			}
+			if result.Removed > 0 || result.Compacted > 0 {
+				dashboardLog.Info(ctx, "trace compaction finished", map[string]any{
+					"removed": result.Removed, "compacted": result.Compacted,
+				})
+			}
+		}
+	}
+}
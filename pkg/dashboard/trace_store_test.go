@@ -0,0 +1,152 @@
+package dashboard
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/astercloud/aster/pkg/store"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+func newTestTraceStore(t *testing.T, policy TraceRetentionPolicy) *TraceStore {
+	t.Helper()
+	st, err := store.NewJSONStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewJSONStore: %v", err)
+	}
+	return NewTraceStore(st, policy)
+}
+
+// recordRun 向记录器送入一次完整运行的事件，返回运行的追踪 ID
+func recordRun(rec *TraceRecorder, agentID string, cursor int64, failed bool) string {
+	ctx := context.Background()
+	ts := time.Now().Unix()
+	emit := func(event any) {
+		rec.Observe(ctx, agentID, types.AgentEventEnvelope{
+			Cursor:   cursor,
+			Bookmark: types.Bookmark{Cursor: cursor, Timestamp: ts},
+			Event:    event,
+		})
+		cursor++
+	}
+	id := runTraceID(agentID, cursor)
+	emit(&types.MonitorStateChangedEvent{State: types.AgentStateWorking})
+	emit(&types.MonitorTokenUsageEvent{InputTokens: 100, OutputTokens: 20})
+	emit(&types.ProgressToolEndEvent{Call: types.ToolCallSnapshot{
+		ID: "call-1", Name: "Bash", Arguments: map[string]any{"command": "ls"},
+	}})
+	if failed {
+		emit(&types.MonitorErrorEvent{Severity: "error", Message: "provider unavailable"})
+	}
+	emit(&types.ProgressDoneEvent{Step: 1, Reason: types.TerminationCompleted})
+	return id
+}
+
+func TestTraceRecorder_PersistsSampledRuns(t *testing.T) {
+	ctx := context.Background()
+	ts := newTestTraceStore(t, TraceRetentionPolicy{SampleRate: 1})
+	rec := NewTraceRecorder(ts)
+
+	id := recordRun(rec, "agt-1", 1, false)
+	detail, err := ts.Get(ctx, id)
+	if err != nil || detail == nil {
+		t.Fatalf("Get(%s) = %v, %v", id, detail, err)
+	}
+	if detail.Status != TraceStatusOK || detail.AgentID != "agt-1" || detail.SpanCount != 2 {
+		t.Errorf("trace = %+v", detail.TraceSummary)
+	}
+	if detail.TokenUsage.Total != 120 || detail.Cost.Amount <= 0 {
+		t.Errorf("usage = %+v, cost = %+v", detail.TokenUsage, detail.Cost)
+	}
+	if len(detail.RootSpan.Children) != 2 || detail.RootSpan.Children[1].Type != TraceNodeTypeTool {
+		t.Fatalf("spans = %+v", detail.RootSpan.Children)
+	}
+
+	// 持久化的追踪在 EventBus 时间线之外仍可通过聚合器查询
+	agg := NewAggregator(nil)
+	agg.SetTraceStore(ts)
+	got, err := agg.GetTraceDetail(ctx, id)
+	if err != nil || got == nil || got.ID != id {
+		t.Fatalf("GetTraceDetail = %+v, %v", got, err)
+	}
+	list, err := agg.QueryTraces(ctx, TraceQueryOpts{AgentID: "agt-1"})
+	if err != nil || list.Total != 1 || list.Traces[0].ID != id {
+		t.Fatalf("QueryTraces = %+v, %v", list, err)
+	}
+}
+
+func TestTraceRecorder_Sampling(t *testing.T) {
+	ctx := context.Background()
+	ts := newTestTraceStore(t, TraceRetentionPolicy{SampleRate: 0, AlwaysSampleErrors: true})
+	rec := NewTraceRecorder(ts)
+
+	okID := recordRun(rec, "agt-1", 1, false)
+	failedID := recordRun(rec, "agt-1", 10, true)
+
+	if detail, _ := ts.Get(ctx, okID); detail != nil {
+		t.Errorf("unsampled successful run was persisted")
+	}
+	detail, _ := ts.Get(ctx, failedID)
+	if detail == nil || detail.Status != TraceStatusError || detail.ErrorMessage != "provider unavailable" {
+		t.Fatalf("failed run = %+v, want persisted with error", detail)
+	}
+
+	// 头部采样按追踪 ID 决定，比例接近配置值且结果稳定
+	half := NewTraceStore(nil, TraceRetentionPolicy{SampleRate: 0.5})
+	sampled := 0
+	for i := range 1000 {
+		id := fmt.Sprintf("agt-%d-1", i)
+		if half.HeadSampled(id) {
+			sampled++
+		}
+		if half.HeadSampled(id) != half.HeadSampled(id) {
+			t.Fatalf("sampling of %s is not stable", id)
+		}
+	}
+	if sampled < 400 || sampled > 600 {
+		t.Errorf("sampled %d of 1000 at rate 0.5", sampled)
+	}
+}
+
+func TestTraceStore_Compact(t *testing.T) {
+	ctx := context.Background()
+	ts := newTestTraceStore(t, TraceRetentionPolicy{SampleRate: 1, Retention: 72 * time.Hour, CompactAfter: 24 * time.Hour})
+	rec := NewTraceRecorder(ts)
+
+	ids := []string{recordRun(rec, "agt-1", 1, false), recordRun(rec, "agt-1", 10, false), recordRun(rec, "agt-1", 20, false)}
+	// 依次模拟 2 天前、4 天前的追踪
+	for i, age := range []time.Duration{48 * time.Hour, 96 * time.Hour} {
+		detail, _ := ts.Get(ctx, ids[i+1])
+		detail.StartTime = detail.StartTime.Add(-age)
+		if err := ts.Save(ctx, detail); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	result, err := ts.Compact(ctx)
+	if err != nil {
+		t.Fatalf("Compact: %v", err)
+	}
+	if result.Removed != 1 || result.Compacted != 1 {
+		t.Fatalf("compaction = %+v, want 1 removed, 1 compacted", result)
+	}
+
+	fresh, _ := ts.Get(ctx, ids[0])
+	if fresh.Compacted || fresh.RootSpan.Children[1].Attributes == nil {
+		t.Errorf("fresh trace should be untouched")
+	}
+	compacted, _ := ts.Get(ctx, ids[1])
+	if !compacted.Compacted || compacted.RootSpan.Children[1].Attributes != nil || len(compacted.RootSpan.Children) != 2 {
+		t.Errorf("compacted trace = %+v", compacted.RootSpan)
+	}
+	if expired, _ := ts.Get(ctx, ids[2]); expired != nil {
+		t.Errorf("expired trace was not removed")
+	}
+
+	// 再次压缩不重复处理
+	if again, _ := ts.Compact(ctx); again != (TraceCompaction{}) {
+		t.Errorf("second compaction = %+v", again)
+	}
+}
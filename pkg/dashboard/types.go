@@ -85,6 +85,7 @@ type TraceDetail struct {
 	RootSpan   *TraceNode `json:"root_span"`
 	TokenUsage TokenCount `json:"token_usage"`
 	Cost       CostAmount `json:"cost"`
+	Compacted  bool       `json:"compacted,omitempty"` // 持久化后已被压缩，span 不含属性
 }
 
 // TraceQueryOpts 追踪查询选项
@@ -153,52 +154,33 @@ type CostQueryOpts struct {
 	AgentID   string     `json:"agent_id,omitempty"`
 }
 
-// ModelPricing 模型定价
+// ModelPricing 模型定价（价格均为每百万 token）
 type ModelPricing struct {
 	Model           string  `json:"model"`
+	Provider        string  `json:"provider,omitempty"`
 	InputPricePerM  float64 `json:"input_price_per_m"`  // 每百万 token 价格
 	OutputPricePerM float64 `json:"output_price_per_m"` // 每百万 token 价格
-	Currency        string  `json:"currency"`
+	// CacheReadPricePerM 缓存读取价格，为 0 时按输入价格计算
+	CacheReadPricePerM float64 `json:"cache_read_price_per_m,omitempty"`
+	// CacheWritePricePerM 缓存写入价格，为 0 时按输入价格计算
+	CacheWritePricePerM float64 `json:"cache_write_price_per_m,omitempty"`
+	// Tiers 阶梯定价，请求的提示 token 数超过阈值时整个请求按该档计价
+	Tiers []PricingTier `json:"tiers,omitempty"`
+	// BatchDiscount 批处理 API 折扣比例，如 0.5 表示半价
+	BatchDiscount float64 `json:"batch_discount,omitempty"`
+	Currency      string  `json:"currency"`
+	// EffectiveFrom 生效日期（YYYY-MM-DD），为空表示一直有效
+	EffectiveFrom string `json:"effective_from,omitempty"`
 }
 
-// DefaultModelPricing 默认模型定价表（美元/百万 token）
-var DefaultModelPricing = map[string]ModelPricing{
-	"claude-3-5-sonnet-20241022": {
-		Model:           "claude-3-5-sonnet-20241022",
-		InputPricePerM:  3.0,
-		OutputPricePerM: 15.0,
-		Currency:        "USD",
-	},
-	"claude-3-5-haiku-20241022": {
-		Model:           "claude-3-5-haiku-20241022",
-		InputPricePerM:  0.8,
-		OutputPricePerM: 4.0,
-		Currency:        "USD",
-	},
-	"claude-sonnet-4-20250514": {
-		Model:           "claude-sonnet-4-20250514",
-		InputPricePerM:  3.0,
-		OutputPricePerM: 15.0,
-		Currency:        "USD",
-	},
-	"gpt-4o": {
-		Model:           "gpt-4o",
-		InputPricePerM:  2.5,
-		OutputPricePerM: 10.0,
-		Currency:        "USD",
-	},
-	"gpt-4o-mini": {
-		Model:           "gpt-4o-mini",
-		InputPricePerM:  0.15,
-		OutputPricePerM: 0.6,
-		Currency:        "USD",
-	},
-	"deepseek-chat": {
-		Model:           "deepseek-chat",
-		InputPricePerM:  0.14,
-		OutputPricePerM: 0.28,
-		Currency:        "USD",
-	},
+// PricingTier 阶梯定价档位，价格为 0 的项沿用基础价格
+type PricingTier struct {
+	// AboveInputTokens 提示 token 数（输入 + 缓存读写）超过该值时生效
+	AboveInputTokens    int64   `json:"above_input_tokens"`
+	InputPricePerM      float64 `json:"input_price_per_m,omitempty"`
+	OutputPricePerM     float64 `json:"output_price_per_m,omitempty"`
+	CacheReadPricePerM  float64 `json:"cache_read_price_per_m,omitempty"`
+	CacheWritePricePerM float64 `json:"cache_write_price_per_m,omitempty"`
 }
 
 // EventStreamMessage WebSocket 事件流消息
@@ -275,3 +257,53 @@ type SessionTimeline struct {
 	StartTime time.Time              `json:"start_time"`
 	EndTime   *time.Time             `json:"end_time,omitempty"`
 }
+
+// AgentDetail 单个 Agent 的下钻统计
+type AgentDetail struct {
+	AgentID      string             `json:"agent_id"`
+	Period       string             `json:"period"`
+	Summary      AgentSummary       `json:"summary"`
+	Series       []AgentSeriesPoint `json:"series"`
+	ToolMix      []ToolUsage        `json:"tool_mix"`
+	RecentTraces []*TraceSummary    `json:"recent_traces"`
+	UpdatedAt    time.Time          `json:"updated_at"`
+}
+
+// AgentSummary Agent 在统计周期内的汇总
+type AgentSummary struct {
+	Requests   int64              `json:"requests"` // 模型调用次数
+	Runs       int64              `json:"runs"`     // 完成的运行次数
+	Errors     int64              `json:"errors"`
+	ErrorRate  float64            `json:"error_rate"`
+	TokenUsage TokenCount         `json:"token_usage"`
+	Cost       CostAmount         `json:"cost"`
+	Latency    LatencyPercentiles `json:"latency"` // 单次运行耗时（毫秒）
+}
+
+// AgentSeriesPoint Agent 时间序列中的一个时间桶
+type AgentSeriesPoint struct {
+	Timestamp    time.Time  `json:"timestamp"`
+	Requests     int64      `json:"requests"`
+	Errors       int64      `json:"errors"`
+	ErrorRate    float64    `json:"error_rate"`
+	TokenUsage   TokenCount `json:"token_usage"`
+	Cost         float64    `json:"cost"`
+	AvgLatencyMs int64      `json:"avg_latency_ms"`
+}
+
+// ToolUsage 工具调用分布
+type ToolUsage struct {
+	Name         string  `json:"name"`
+	Calls        int64   `json:"calls"`
+	Errors       int64   `json:"errors"`
+	Share        float64 `json:"share"` // 占全部工具调用的比例
+	AvgLatencyMs int64   `json:"avg_latency_ms"`
+}
+
+// AgentDetailOpts Agent 下钻查询选项
+type AgentDetailOpts struct {
+	AgentID    string `json:"agent_id"`
+	Period     string `json:"period,omitempty"`
+	Model      string `json:"model,omitempty"` // 计算成本使用的模型，为空时按默认定价
+	TraceLimit int    `json:"trace_limit,omitempty"`
+}
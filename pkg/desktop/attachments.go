@@ -0,0 +1,584 @@
+package desktop
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultMaxAttachmentSize is the default size limit for a single attachment (25 MB)
+	DefaultMaxAttachmentSize int64 = 25 << 20
+
+	// DefaultDialogTimeout is how long the backend waits for the frontend to answer a dialog request
+	DefaultDialogTimeout = 5 * time.Minute
+
+	// attachmentsDir is the attachment directory relative to the working directory
+	attachmentsDir = ".aster/attachments"
+)
+
+// DialogKind identifies a native file dialog
+type DialogKind string
+
+const (
+	// DialogKindOpen is an open-file dialog
+	DialogKindOpen DialogKind = "open"
+
+	// DialogKindSave is a save-file dialog
+	DialogKindSave DialogKind = "save"
+)
+
+// FileFilter is a file type filter shown in native dialogs
+type FileFilter struct {
+	Name       string   `json:"name"`
+	Extensions []string `json:"extensions"` // without dot, e.g. ["png", "jpg"]
+}
+
+// FileDialogPayload is the payload for open/save file dialog messages
+type FileDialogPayload struct {
+	Title       string       `json:"title,omitempty"`
+	DefaultPath string       `json:"default_path,omitempty"`
+	Filters     []FileFilter `json:"filters,omitempty"`
+
+	// Multiple allows selecting multiple files (open dialog only)
+	Multiple bool `json:"multiple,omitempty"`
+
+	// Directory selects directories instead of files (open dialog only)
+	Directory bool `json:"directory,omitempty"`
+}
+
+// DialogRequest is sent to the frontend (EventTypeDialogRequest) when the
+// native layer must show a dialog on behalf of the backend
+type DialogRequest struct {
+	RequestID string     `json:"request_id"`
+	Kind      DialogKind `json:"kind"`
+	FileDialogPayload
+}
+
+// DialogResultPayload is the frontend's answer to a DialogRequest
+type DialogResultPayload struct {
+	RequestID string   `json:"request_id"`
+	Paths     []string `json:"paths,omitempty"`
+	Canceled  bool     `json:"canceled,omitempty"`
+}
+
+// DialogHandler shows a native dialog directly from Go.
+// Wails apps can set it to call runtime.OpenMultipleFilesDialog / runtime.SaveFileDialog;
+// without a handler the request is round-tripped through the frontend.
+type DialogHandler func(ctx context.Context, req *DialogRequest) (*DialogResultPayload, error)
+
+// AttachmentPayload is the payload for attachment messages.
+// Exactly one of Path (native dialog / drag-drop with file paths) or
+// Content (base64, drag-drop in a webview) must be set. HTTP bridges only
+// accept Content: any local web page can reach them, so a raw path would let
+// it copy arbitrary host files into the workspace.
+type AttachmentPayload struct {
+	Name     string `json:"name,omitempty"`
+	Path     string `json:"path,omitempty"`
+	Content  string `json:"content,omitempty"`
+	MimeType string `json:"mime_type,omitempty"`
+}
+
+// Attachment is a file copied into the agent's working directory
+type Attachment struct {
+	ID       string    `json:"id"`
+	AgentID  string    `json:"agent_id,omitempty"`
+	Name     string    `json:"name"`
+	Path     string    `json:"path"` // relative to the working directory, accessible from the sandbox
+	Size     int64     `json:"size"`
+	MimeType string    `json:"mime_type,omitempty"`
+	Created  time.Time `json:"created"`
+}
+
+// attachmentStore tracks uploaded attachments and pending dialog requests
+type attachmentStore struct {
+	mu          sync.RWMutex
+	attachments map[string]*Attachment
+
+	dialogsMu sync.Mutex
+	dialogs   map[string]chan *DialogResultPayload
+}
+
+func newAttachmentStore() *attachmentStore {
+	return &attachmentStore{
+		attachments: make(map[string]*Attachment),
+		dialogs:     make(map[string]chan *DialogResultPayload),
+	}
+}
+
+// SetDialogHandler sets a Go-side native dialog implementation
+func (a *App) SetDialogHandler(handler DialogHandler) {
+	a.dialogHandler = handler
+}
+
+// ShowDialog shows a native file dialog and returns the selected paths.
+// Uses the DialogHandler when set, otherwise emits EventTypeDialogRequest and
+// waits for the frontend to answer with MsgTypeDialogResult.
+func (a *App) ShowDialog(ctx context.Context, kind DialogKind, opts FileDialogPayload) (*DialogResultPayload, error) {
+	req := &DialogRequest{
+		RequestID:         generateID(),
+		Kind:              kind,
+		FileDialogPayload: opts,
+	}
+
+	if a.dialogHandler != nil {
+		return a.dialogHandler(ctx, req)
+	}
+
+	ch := make(chan *DialogResultPayload, 1)
+	a.files.dialogsMu.Lock()
+	a.files.dialogs[req.RequestID] = ch
+	a.files.dialogsMu.Unlock()
+	defer func() {
+		a.files.dialogsMu.Lock()
+		delete(a.files.dialogs, req.RequestID)
+		a.files.dialogsMu.Unlock()
+	}()
+
+	if err := a.bridge.SendEvent(&FrontendEvent{
+		Type: EventTypeDialogRequest,
+		Data: req,
+	}); err != nil {
+		return nil, fmt.Errorf("send dialog request: %w", err)
+	}
+
+	timeout := a.config.DialogTimeout
+	if timeout <= 0 {
+		timeout = DefaultDialogTimeout
+	}
+	select {
+	case result := <-ch:
+		return result, nil
+	case <-time.After(timeout):
+		return nil, errors.New("dialog request timed out")
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// resolveDialog delivers a frontend dialog result to the waiting request
+func (a *App) resolveDialog(result *DialogResultPayload) error {
+	a.files.dialogsMu.Lock()
+	ch, ok := a.files.dialogs[result.RequestID]
+	a.files.dialogsMu.Unlock()
+	if !ok {
+		return fmt.Errorf("no pending dialog request: %s", result.RequestID)
+	}
+	select {
+	case ch <- result:
+	default:
+	}
+	return nil
+}
+
+// Attach copies a file into the agent's attachment directory so the agent
+// (and its sandbox) can read it
+func (a *App) Attach(agentID string, payload *AttachmentPayload) (*Attachment, error) {
+	if (payload.Path == "") == (payload.Content == "") {
+		return nil, errors.New("exactly one of path or content is required")
+	}
+
+	name := payload.Name
+	if name == "" {
+		name = filepath.Base(payload.Path)
+	}
+	name = sanitizeFileName(name)
+	if name == "" {
+		return nil, errors.New("attachment name is required")
+	}
+
+	maxSize := a.config.MaxAttachmentSize
+	if maxSize <= 0 {
+		maxSize = DefaultMaxAttachmentSize
+	}
+
+	var src io.Reader
+	if payload.Path != "" {
+		info, err := os.Stat(payload.Path)
+		if err != nil {
+			return nil, fmt.Errorf("stat attachment: %w", err)
+		}
+		if info.IsDir() {
+			return nil, fmt.Errorf("attachment is a directory: %s", payload.Path)
+		}
+		if info.Size() > maxSize {
+			return nil, fmt.Errorf("attachment too large: %d bytes (max %d)", info.Size(), maxSize)
+		}
+		f, err := os.Open(payload.Path)
+		if err != nil {
+			return nil, fmt.Errorf("open attachment: %w", err)
+		}
+		defer func() { _ = f.Close() }()
+		src = f
+	} else {
+		data, err := base64.StdEncoding.DecodeString(payload.Content)
+		if err != nil {
+			return nil, fmt.Errorf("decode attachment content: %w", err)
+		}
+		src = strings.NewReader(string(data))
+	}
+
+	return a.storeAttachment(agentID, name, payload.MimeType, src, maxSize)
+}
+
+// storeAttachment writes the attachment under <WorkDir>/.aster/attachments/<agent>/<id>-<name>
+func (a *App) storeAttachment(agentID, name, mimeType string, src io.Reader, maxSize int64) (*Attachment, error) {
+	scope := sanitizeFileName(agentID)
+	if scope == "" {
+		scope = "shared"
+	}
+
+	id := generateID()[:12]
+	rel := filepath.Join(attachmentsDir, scope, id+"-"+name)
+	dst := filepath.Join(a.config.WorkDir, rel)
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return nil, fmt.Errorf("create attachment dir: %w", err)
+	}
+
+	f, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("create attachment: %w", err)
+	}
+	size, err := io.Copy(f, io.LimitReader(src, maxSize+1))
+	closeErr := f.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err == nil && size > maxSize {
+		err = fmt.Errorf("attachment too large (max %d bytes)", maxSize)
+	}
+	if err != nil {
+		_ = os.Remove(dst)
+		return nil, err
+	}
+
+	if mimeType == "" {
+		mimeType = mime.TypeByExtension(filepath.Ext(name))
+	}
+
+	att := &Attachment{
+		ID:       id,
+		AgentID:  agentID,
+		Name:     name,
+		Path:     filepath.ToSlash(rel),
+		Size:     size,
+		MimeType: mimeType,
+		Created:  time.Now(),
+	}
+
+	a.files.mu.Lock()
+	a.files.attachments[id] = att
+	a.files.mu.Unlock()
+
+	_ = a.bridge.SendEvent(&FrontendEvent{
+		Type:    EventTypeAttachmentAdded,
+		AgentID: agentID,
+		Data:    att,
+	})
+	return att, nil
+}
+
+// GetAttachment returns an attachment by ID
+func (a *App) GetAttachment(id string) (*Attachment, bool) {
+	a.files.mu.RLock()
+	defer a.files.mu.RUnlock()
+	att, ok := a.files.attachments[id]
+	return att, ok
+}
+
+// withAttachments appends the attached file paths to a chat message so the
+// agent knows which workspace files to read
+func (a *App) withAttachments(message string, ids []string) (string, error) {
+	if len(ids) == 0 {
+		return message, nil
+	}
+
+	var sb strings.Builder
+	sb.WriteString(message)
+	sb.WriteString("\n\nAttached files:")
+	for _, id := range ids {
+		att, ok := a.GetAttachment(id)
+		if !ok {
+			return "", fmt.Errorf("attachment not found: %s", id)
+		}
+		fmt.Fprintf(&sb, "\n- %s (%s, %d bytes)", att.Path, att.Name, att.Size)
+	}
+	return sb.String(), nil
+}
+
+// sanitizeFileName strips directory components and unsafe characters
+func sanitizeFileName(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	if name == "." || name == ".." || name == "/" {
+		return ""
+	}
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '/', '\\', ':', '*', '?', '"', '<', '>', '|', 0:
+			return '_'
+		}
+		return r
+	}, name)
+}
+
+// Message handlers
+
+func (a *App) handleFileDialog(msg *FrontendMessage, kind DialogKind) (*BackendResponse, error) {
+	var payload FileDialogPayload
+	if len(msg.Payload) > 0 {
+		if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+			return &BackendResponse{
+				ID:      msg.ID,
+				Success: false,
+				Error:   fmt.Sprintf("invalid payload: %v", err),
+			}, nil
+		}
+	}
+
+	result, err := a.ShowDialog(context.Background(), kind, payload)
+	if err != nil {
+		return &BackendResponse{
+			ID:      msg.ID,
+			Success: false,
+			Error:   err.Error(),
+		}, nil
+	}
+
+	data := map[string]any{
+		"paths":    result.Paths,
+		"canceled": result.Canceled,
+	}
+
+	// Files picked in an open dialog become attachments right away
+	if kind == DialogKindOpen && !result.Canceled && !payload.Directory {
+		attachments := make([]*Attachment, 0, len(result.Paths))
+		for _, path := range result.Paths {
+			att, err := a.Attach(msg.AgentID, &AttachmentPayload{Path: path})
+			if err != nil {
+				return &BackendResponse{
+					ID:      msg.ID,
+					Success: false,
+					Error:   err.Error(),
+				}, nil
+			}
+			attachments = append(attachments, att)
+		}
+		data["attachments"] = attachments
+	}
+
+	return &BackendResponse{
+		ID:      msg.ID,
+		Success: true,
+		Data:    data,
+	}, nil
+}
+
+func (a *App) handleDialogResult(msg *FrontendMessage) (*BackendResponse, error) {
+	var payload DialogResultPayload
+	if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+		return &BackendResponse{
+			ID:      msg.ID,
+			Success: false,
+			Error:   fmt.Sprintf("invalid payload: %v", err),
+		}, nil
+	}
+
+	if err := a.resolveDialog(&payload); err != nil {
+		return &BackendResponse{
+			ID:      msg.ID,
+			Success: false,
+			Error:   err.Error(),
+		}, nil
+	}
+
+	return &BackendResponse{
+		ID:      msg.ID,
+		Success: true,
+	}, nil
+}
+
+func (a *App) handleAttach(msg *FrontendMessage) (*BackendResponse, error) {
+	var payload AttachmentPayload
+	if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+		return &BackendResponse{
+			ID:      msg.ID,
+			Success: false,
+			Error:   fmt.Sprintf("invalid payload: %v", err),
+		}, nil
+	}
+
+	att, err := a.Attach(msg.AgentID, &payload)
+	if err != nil {
+		return &BackendResponse{
+			ID:      msg.ID,
+			Success: false,
+			Error:   err.Error(),
+		}, nil
+	}
+
+	return &BackendResponse{
+		ID:      msg.ID,
+		Success: true,
+		Data:    att,
+	}, nil
+}
+
+// HTTP handlers shared by the Tauri, Electron and Web bridges
+
+// fileRoutes registers dialog and attachment endpoints on an HTTP bridge mux
+func fileRoutes(mux *http.ServeMux, app *App, handler MessageHandler) {
+	mux.HandleFunc("/api/dialog/open", dialogHTTPHandler(handler, MsgTypeOpenFileDialog))
+	mux.HandleFunc("/api/dialog/save", dialogHTTPHandler(handler, MsgTypeSaveFileDialog))
+	mux.HandleFunc("/api/dialog/result", dialogResultHTTPHandler(handler))
+	mux.HandleFunc("/api/attachments", attachmentHTTPHandler(handler, app))
+}
+
+func dialogHTTPHandler(handler MessageHandler, msgType MessageType) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req struct {
+			AgentID string `json:"agent_id"`
+			FileDialogPayload
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, BackendResponse{
+				Success: false,
+				Error:   err.Error(),
+			})
+			return
+		}
+
+		resp, _ := handler(&FrontendMessage{
+			ID:      generateID(),
+			Type:    msgType,
+			AgentID: req.AgentID,
+			Payload: mustMarshal(req.FileDialogPayload),
+		})
+		writeJSON(w, http.StatusOK, resp)
+	}
+}
+
+func dialogResultHTTPHandler(handler MessageHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req DialogResultPayload
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, BackendResponse{
+				Success: false,
+				Error:   err.Error(),
+			})
+			return
+		}
+
+		resp, _ := handler(&FrontendMessage{
+			ID:      generateID(),
+			Type:    MsgTypeDialogResult,
+			Payload: mustMarshal(req),
+		})
+		writeJSON(w, http.StatusOK, resp)
+	}
+}
+
+// attachmentHTTPHandler accepts JSON (AttachmentPayload + agent_id) with
+// base64 content, or multipart/form-data with a "file" part (drag-drop uploads).
+// Paths are rejected; files picked in the open dialog are attached by the
+// dialog handler instead.
+func attachmentHTTPHandler(handler MessageHandler, app *App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+			uploadMultipart(w, r, app)
+			return
+		}
+
+		var req struct {
+			AgentID string `json:"agent_id"`
+			AttachmentPayload
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, BackendResponse{
+				Success: false,
+				Error:   err.Error(),
+			})
+			return
+		}
+		if req.Path != "" {
+			writeJSON(w, http.StatusBadRequest, BackendResponse{
+				Success: false,
+				Error:   "attaching by path is not supported over HTTP; upload the file content or use the open dialog",
+			})
+			return
+		}
+
+		resp, _ := handler(&FrontendMessage{
+			ID:      generateID(),
+			Type:    MsgTypeAttach,
+			AgentID: req.AgentID,
+			Payload: mustMarshal(req.AttachmentPayload),
+		})
+		writeJSON(w, http.StatusOK, resp)
+	}
+}
+
+func uploadMultipart(w http.ResponseWriter, r *http.Request, app *App) {
+	maxSize := app.config.MaxAttachmentSize
+	if maxSize <= 0 {
+		maxSize = DefaultMaxAttachmentSize
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxSize+(1<<20))
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, BackendResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+	defer func() { _ = file.Close() }()
+
+	name := sanitizeFileName(header.Filename)
+	if name == "" {
+		writeJSON(w, http.StatusBadRequest, BackendResponse{
+			Success: false,
+			Error:   "attachment name is required",
+		})
+		return
+	}
+
+	att, err := app.storeAttachment(r.FormValue("agent_id"), name, header.Header.Get("Content-Type"), file, maxSize)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, BackendResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+	writeJSON(w, http.StatusOK, BackendResponse{
+		ID:      generateID(),
+		Success: true,
+		Data:    att,
+	})
+}
@@ -0,0 +1,260 @@
+package desktop
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newAttachmentTestApp(t *testing.T) *App {
+	t.Helper()
+	app, err := NewApp(&AppConfig{
+		Framework: FrameworkWails,
+		WorkDir:   t.TempDir(),
+	})
+	if err != nil {
+		t.Fatalf("NewApp() error = %v", err)
+	}
+	return app
+}
+
+func TestAttachContent(t *testing.T) {
+	app := newAttachmentTestApp(t)
+	bridge := app.Bridge().(*WailsBridge)
+
+	resp, err := bridge.Attach("agent-1", AttachmentPayload{
+		Name:    "../notes.txt",
+		Content: base64.StdEncoding.EncodeToString([]byte("hello")),
+	})
+	if err != nil {
+		t.Fatalf("Attach() error = %v", err)
+	}
+	if !resp.Success {
+		t.Fatalf("Attach() failed: %s", resp.Error)
+	}
+
+	att := resp.Data.(*Attachment)
+	if att.Name != "notes.txt" {
+		t.Errorf("Name = %q, want notes.txt", att.Name)
+	}
+	if !strings.HasPrefix(att.Path, ".aster/attachments/agent-1/") {
+		t.Errorf("Path = %q, want under .aster/attachments/agent-1/", att.Path)
+	}
+	if att.MimeType == "" {
+		t.Error("expected mime type from extension")
+	}
+
+	data, err := os.ReadFile(filepath.Join(app.config.WorkDir, att.Path))
+	if err != nil {
+		t.Fatalf("read attachment: %v", err)
+	}
+	if string(data) != "hello" {
+		t.Errorf("content = %q, want hello", data)
+	}
+
+	event := <-bridge.GetEvents()
+	if event.Type != EventTypeAttachmentAdded {
+		t.Errorf("event type = %s, want %s", event.Type, EventTypeAttachmentAdded)
+	}
+}
+
+func TestAttachValidation(t *testing.T) {
+	app := newAttachmentTestApp(t)
+	app.config.MaxAttachmentSize = 4
+
+	src := filepath.Join(t.TempDir(), "big.bin")
+	if err := os.WriteFile(src, []byte("too large"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		payload *AttachmentPayload
+		wantErr string
+	}{
+		{"empty", &AttachmentPayload{}, "exactly one of"},
+		{"both", &AttachmentPayload{Path: src, Content: "aGk="}, "exactly one of"},
+		{"bad base64", &AttachmentPayload{Name: "a.txt", Content: "!!"}, "decode"},
+		{"too large path", &AttachmentPayload{Path: src}, "too large"},
+		{"too large content", &AttachmentPayload{Name: "a.txt", Content: base64.StdEncoding.EncodeToString([]byte("12345"))}, "too large"},
+		{"directory", &AttachmentPayload{Path: t.TempDir()}, "directory"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := app.Attach("agent-1", tt.payload)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Attach() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWithAttachments(t *testing.T) {
+	app := newAttachmentTestApp(t)
+
+	att, err := app.Attach("agent-1", &AttachmentPayload{Name: "a.txt", Content: "aGk="})
+	if err != nil {
+		t.Fatalf("Attach() error = %v", err)
+	}
+
+	msg, err := app.withAttachments("summarize", []string{att.ID})
+	if err != nil {
+		t.Fatalf("withAttachments() error = %v", err)
+	}
+	if !strings.Contains(msg, att.Path) {
+		t.Errorf("message %q does not mention %s", msg, att.Path)
+	}
+
+	if _, err := app.withAttachments("x", []string{"missing"}); err == nil {
+		t.Error("expected error for unknown attachment")
+	}
+}
+
+func TestOpenFileDialogWithHandler(t *testing.T) {
+	app := newAttachmentTestApp(t)
+
+	src := filepath.Join(t.TempDir(), "report.md")
+	if err := os.WriteFile(src, []byte("# report"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var got *DialogRequest
+	app.SetDialogHandler(func(ctx context.Context, req *DialogRequest) (*DialogResultPayload, error) {
+		got = req
+		return &DialogResultPayload{RequestID: req.RequestID, Paths: []string{src}}, nil
+	})
+
+	bridge := app.Bridge().(*WailsBridge)
+	resp, err := bridge.OpenFileDialog("agent-1", FileDialogPayload{Title: "Pick"})
+	if err != nil {
+		t.Fatalf("OpenFileDialog() error = %v", err)
+	}
+	if !resp.Success {
+		t.Fatalf("OpenFileDialog() failed: %s", resp.Error)
+	}
+	if got == nil || got.Kind != DialogKindOpen || got.Title != "Pick" {
+		t.Errorf("dialog request = %+v", got)
+	}
+
+	data := resp.Data.(map[string]any)
+	attachments := data["attachments"].([]*Attachment)
+	if len(attachments) != 1 || attachments[0].Name != "report.md" {
+		t.Errorf("attachments = %+v", attachments)
+	}
+
+	// Save dialogs return paths without attaching
+	resp, _ = bridge.SaveFileDialog("agent-1", FileDialogPayload{})
+	if _, ok := resp.Data.(map[string]any)["attachments"]; ok {
+		t.Error("save dialog should not create attachments")
+	}
+}
+
+func TestDialogRoundTrip(t *testing.T) {
+	app := newAttachmentTestApp(t)
+	bridge := app.Bridge().(*WailsBridge)
+
+	// Frontend: answer the dialog request as a native dialog would
+	go func() {
+		event := <-bridge.GetEvents()
+		req := event.Data.(*DialogRequest)
+		_, _ = bridge.DialogResult(DialogResultPayload{RequestID: req.RequestID, Canceled: true})
+	}()
+
+	result, err := app.ShowDialog(context.Background(), DialogKindSave, FileDialogPayload{})
+	if err != nil {
+		t.Fatalf("ShowDialog() error = %v", err)
+	}
+	if !result.Canceled {
+		t.Error("expected canceled result")
+	}
+
+	resp, _ := bridge.DialogResult(DialogResultPayload{RequestID: "unknown"})
+	if resp.Success {
+		t.Error("expected failure for unknown dialog request")
+	}
+}
+
+func TestDialogTimeout(t *testing.T) {
+	app := newAttachmentTestApp(t)
+	app.config.DialogTimeout = 10 * time.Millisecond
+
+	if _, err := app.ShowDialog(context.Background(), DialogKindOpen, FileDialogPayload{}); err == nil {
+		t.Error("expected timeout error")
+	}
+}
+
+func TestAttachmentHTTPMultipart(t *testing.T) {
+	app := newAttachmentTestApp(t)
+	mux := http.NewServeMux()
+	fileRoutes(mux, app, app.handleMessage)
+
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	_ = w.WriteField("agent_id", "agent-1")
+	part, _ := w.CreateFormFile("file", "data.csv")
+	_, _ = part.Write([]byte("a,b\n1,2\n"))
+	_ = w.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/attachments", &body)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+
+	var resp struct {
+		Success bool       `json:"success"`
+		Data    Attachment `json:"data"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if !resp.Success || resp.Data.Size != 8 || resp.Data.AgentID != "agent-1" {
+		t.Errorf("response = %+v", resp)
+	}
+	if _, ok := app.GetAttachment(resp.Data.ID); !ok {
+		t.Error("attachment not registered")
+	}
+
+	// JSON uploads go through the message handler
+	req = httptest.NewRequest(http.MethodPost, "/api/attachments",
+		strings.NewReader(`{"agent_id":"agent-1","name":"b.txt","content":"aGk="}`))
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if !strings.Contains(rec.Body.String(), `"success":true`) {
+		t.Errorf("JSON upload body = %s", rec.Body.String())
+	}
+
+	// Host paths cannot be attached over HTTP
+	secret := filepath.Join(t.TempDir(), "id_rsa")
+	if err := os.WriteFile(secret, []byte("key"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	req = httptest.NewRequest(http.MethodPost, "/api/attachments",
+		strings.NewReader(`{"agent_id":"agent-1","path":`+strconv.Quote(secret)+`}`))
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest || strings.Contains(rec.Body.String(), `"success":true`) {
+		t.Errorf("path upload status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/attachments", nil)
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("GET status = %d, want 405", rec.Code)
+	}
+}
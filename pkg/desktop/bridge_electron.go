@@ -42,6 +42,8 @@ import (
 type ElectronBridge struct {
 	app       *App
 	handler   MessageHandler
+	shell     shellState
+	ws        *wsHub
 	agents    map[string]*agent.Agent
 	agentsMu  sync.RWMutex
 	server    *http.Server
@@ -54,9 +56,10 @@ type ElectronBridge struct {
 
 // wsClient represents a WebSocket client
 type wsClient struct {
-	id      string
-	eventCh chan *FrontendEvent
-	closeCh chan struct{}
+	id       string
+	windowID string
+	eventCh  chan *FrontendEvent
+	closeCh  chan struct{}
 }
 
 // NewElectronBridge creates a new Electron bridge
@@ -67,6 +70,7 @@ func NewElectronBridge(app *App, port int) (*ElectronBridge, error) {
 
 	return &ElectronBridge{
 		app:       app,
+		ws:        newWSHub(app),
 		agents:    make(map[string]*agent.Agent),
 		port:      port,
 		wsClients: make(map[string]*wsClient),
@@ -91,8 +95,23 @@ func (b *ElectronBridge) Start(ctx context.Context) error {
 	mux.HandleFunc("/api/status", b.handleStatus)
 	mux.HandleFunc("/api/history", b.handleHistory)
 	mux.HandleFunc("/api/config", b.handleConfig)
+	fileRoutes(mux, b.app, b.handler)
+	trayRoutes(mux, &b.shell, b.handler)
+	windowAgentRoutes(mux, b.handler)
+	connectivityRoutes(mux, b.handler)
+	updateRoutes(mux, b.handler)
+	onboardingRoutes(mux, b.handler)
+	uiRoutes(mux, b.handler)
+	workspaceRoutes(mux, b.handler)
+	undoRoutes(mux, b.handler)
+	editRoutes(mux, b.handler)
+	pinRoutes(mux, b.handler)
+	windowRoutes(mux, b.handler)
 
 	// WebSocket endpoint
+	if b.ws != nil {
+		b.ws.setHandler(b.handler)
+	}
 	mux.HandleFunc("/ws", b.handleWebSocket)
 
 	// SSE endpoint (fallback)
@@ -133,6 +152,9 @@ func (b *ElectronBridge) Stop(ctx context.Context) error {
 	if b.cancel != nil {
 		b.cancel()
 	}
+	if b.ws != nil {
+		b.ws.close()
+	}
 
 	// Close all WebSocket clients
 	b.wsMu.Lock()
@@ -166,10 +188,17 @@ func (b *ElectronBridge) UnregisterAgent(agentID string) error {
 
 // SendEvent sends an event to all WebSocket clients
 func (b *ElectronBridge) SendEvent(event *FrontendEvent) error {
+	if b.ws != nil {
+		b.ws.broadcast(event)
+	}
+
 	b.wsMu.RLock()
 	defer b.wsMu.RUnlock()
 
 	for _, client := range b.wsClients {
+		if !b.app.windowAccepts(client.windowID, event) {
+			continue
+		}
 		select {
 		case client.eventCh <- event:
 		default:
@@ -179,6 +208,25 @@ func (b *ElectronBridge) SendEvent(event *FrontendEvent) error {
 	return nil
 }
 
+// SetTrayMenu stores the tray menu and pushes it to the Electron shell via SSE;
+// the shell renders it with Electron Tray and reports clicks to /api/tray/action
+func (b *ElectronBridge) SetTrayMenu(menu *TrayMenu) error {
+	b.shell.setMenu(menu)
+	return b.SendEvent(&FrontendEvent{
+		Type: EventTypeTrayUpdate,
+		Data: menu,
+	})
+}
+
+// Notify pushes a notification to the Electron shell via SSE (Electron Notification)
+func (b *ElectronBridge) Notify(n *Notification) error {
+	return b.SendEvent(&FrontendEvent{
+		Type:    EventTypeNotification,
+		AgentID: n.AgentID,
+		Data:    n,
+	})
+}
+
 // OnMessage sets the handler for messages from frontend
 func (b *ElectronBridge) OnMessage(handler MessageHandler) {
 	b.handler = handler
@@ -198,8 +246,9 @@ func (b *ElectronBridge) handleChat(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req struct {
-		AgentID string `json:"agent_id"`
-		Message string `json:"message"`
+		AgentID     string   `json:"agent_id"`
+		Message     string   `json:"message"`
+		Attachments []string `json:"attachments,omitempty"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -214,7 +263,7 @@ func (b *ElectronBridge) handleChat(w http.ResponseWriter, r *http.Request) {
 		ID:      generateID(),
 		Type:    MsgTypeChat,
 		AgentID: req.AgentID,
-		Payload: mustMarshal(ChatPayload{Message: req.Message}),
+		Payload: mustMarshal(ChatPayload{Message: req.Message, Attachments: req.Attachments}),
 	})
 
 	if err != nil {
@@ -316,6 +365,7 @@ func (b *ElectronBridge) handleHistory(w http.ResponseWriter, r *http.Request) {
 		ID:      generateID(),
 		Type:    msgType,
 		AgentID: agentID,
+		Payload: historyQuery(r),
 	})
 
 	writeJSON(w, http.StatusOK, resp)
@@ -352,12 +402,14 @@ func (b *ElectronBridge) handleConfig(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// handleWebSocket handles WebSocket connections
-// Note: This is a simplified implementation using SSE-style long polling
-// For production, use a proper WebSocket library like gorilla/websocket
+// handleWebSocket handles WebSocket connections.
+// With TransportWebSocket this is a bidirectional WebSocket (see wsHub);
+// otherwise it falls back to an SSE stream for older preload scripts.
 func (b *ElectronBridge) handleWebSocket(w http.ResponseWriter, r *http.Request) {
-	// For now, redirect to SSE
-	// TODO: Implement proper WebSocket with gorilla/websocket
+	if b.ws != nil {
+		b.ws.ServeHTTP(w, r)
+		return
+	}
 	b.handleSSE(w, r)
 }
 
@@ -371,9 +423,13 @@ func (b *ElectronBridge) handleSSE(w http.ResponseWriter, r *http.Request) {
 	// Create client
 	clientID := generateID()
 	client := &wsClient{
-		id:      clientID,
-		eventCh: make(chan *FrontendEvent, 100),
-		closeCh: make(chan struct{}),
+		id:       clientID,
+		windowID: r.URL.Query().Get("window_id"),
+		eventCh:  make(chan *FrontendEvent, 100),
+		closeCh:  make(chan struct{}),
+	}
+	if client.windowID != "" {
+		b.app.OpenWindow(client.windowID, "")
 	}
 
 	b.wsMu.Lock()
@@ -40,11 +40,14 @@ import (
 type TauriBridge struct {
 	app        *App
 	handler    MessageHandler
+	shell      shellState
+	ws         *wsHub
 	agents     map[string]*agent.Agent
 	agentsMu   sync.RWMutex
 	server     *http.Server
 	port       int
 	sseClients map[string]chan *FrontendEvent
+	sseWindows map[string]string // client ID -> window ID
 	sseMu      sync.RWMutex
 	ctx        context.Context
 	cancel     context.CancelFunc
@@ -58,9 +61,11 @@ func NewTauriBridge(app *App, port int) (*TauriBridge, error) {
 
 	return &TauriBridge{
 		app:        app,
+		ws:         newWSHub(app),
 		agents:     make(map[string]*agent.Agent),
 		port:       port,
 		sseClients: make(map[string]chan *FrontendEvent),
+		sseWindows: make(map[string]string),
 	}, nil
 }
 
@@ -82,10 +87,28 @@ func (b *TauriBridge) Start(ctx context.Context) error {
 	mux.HandleFunc("/api/status", b.handleStatus)
 	mux.HandleFunc("/api/history", b.handleHistory)
 	mux.HandleFunc("/api/config", b.handleConfig)
+	fileRoutes(mux, b.app, b.handler)
+	trayRoutes(mux, &b.shell, b.handler)
+	windowAgentRoutes(mux, b.handler)
+	connectivityRoutes(mux, b.handler)
+	updateRoutes(mux, b.handler)
+	onboardingRoutes(mux, b.handler)
+	uiRoutes(mux, b.handler)
+	workspaceRoutes(mux, b.handler)
+	undoRoutes(mux, b.handler)
+	editRoutes(mux, b.handler)
+	pinRoutes(mux, b.handler)
+	windowRoutes(mux, b.handler)
 
 	// SSE endpoint for events
 	mux.HandleFunc("/api/events", b.handleSSE)
 
+	// WebSocket endpoint (TransportWebSocket)
+	if b.ws != nil {
+		b.ws.setHandler(b.handler)
+		mux.Handle("/ws", b.ws)
+	}
+
 	// Health check
 	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
@@ -117,6 +140,9 @@ func (b *TauriBridge) Stop(ctx context.Context) error {
 	if b.cancel != nil {
 		b.cancel()
 	}
+	if b.ws != nil {
+		b.ws.close()
+	}
 
 	// Close all SSE clients
 	b.sseMu.Lock()
@@ -150,10 +176,17 @@ func (b *TauriBridge) UnregisterAgent(agentID string) error {
 
 // SendEvent sends an event to all SSE clients
 func (b *TauriBridge) SendEvent(event *FrontendEvent) error {
+	if b.ws != nil {
+		b.ws.broadcast(event)
+	}
+
 	b.sseMu.RLock()
 	defer b.sseMu.RUnlock()
 
-	for _, ch := range b.sseClients {
+	for id, ch := range b.sseClients {
+		if !b.app.windowAccepts(b.sseWindows[id], event) {
+			continue
+		}
 		select {
 		case ch <- event:
 		default:
@@ -163,6 +196,25 @@ func (b *TauriBridge) SendEvent(event *FrontendEvent) error {
 	return nil
 }
 
+// SetTrayMenu stores the tray menu and pushes it to the Tauri shell via SSE;
+// the shell renders it with its SystemTray API and reports clicks to /api/tray/action
+func (b *TauriBridge) SetTrayMenu(menu *TrayMenu) error {
+	b.shell.setMenu(menu)
+	return b.SendEvent(&FrontendEvent{
+		Type: EventTypeTrayUpdate,
+		Data: menu,
+	})
+}
+
+// Notify pushes a notification to the Tauri shell via SSE (tauri-plugin-notification)
+func (b *TauriBridge) Notify(n *Notification) error {
+	return b.SendEvent(&FrontendEvent{
+		Type:    EventTypeNotification,
+		AgentID: n.AgentID,
+		Data:    n,
+	})
+}
+
 // OnMessage sets the handler for messages from frontend
 func (b *TauriBridge) OnMessage(handler MessageHandler) {
 	b.handler = handler
@@ -182,8 +234,9 @@ func (b *TauriBridge) handleChat(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req struct {
-		AgentID string `json:"agent_id"`
-		Message string `json:"message"`
+		AgentID     string   `json:"agent_id"`
+		Message     string   `json:"message"`
+		Attachments []string `json:"attachments,omitempty"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -198,7 +251,7 @@ func (b *TauriBridge) handleChat(w http.ResponseWriter, r *http.Request) {
 		ID:      generateID(),
 		Type:    MsgTypeChat,
 		AgentID: req.AgentID,
-		Payload: mustMarshal(ChatPayload{Message: req.Message}),
+		Payload: mustMarshal(ChatPayload{Message: req.Message, Attachments: req.Attachments}),
 	})
 
 	if err != nil {
@@ -300,6 +353,7 @@ func (b *TauriBridge) handleHistory(w http.ResponseWriter, r *http.Request) {
 		ID:      generateID(),
 		Type:    msgType,
 		AgentID: agentID,
+		Payload: historyQuery(r),
 	})
 
 	writeJSON(w, http.StatusOK, resp)
@@ -349,11 +403,16 @@ func (b *TauriBridge) handleSSE(w http.ResponseWriter, r *http.Request) {
 
 	b.sseMu.Lock()
 	b.sseClients[clientID] = eventCh
+	if windowID := r.URL.Query().Get("window_id"); windowID != "" {
+		b.sseWindows[clientID] = windowID
+		b.app.OpenWindow(windowID, "")
+	}
 	b.sseMu.Unlock()
 
 	defer func() {
 		b.sseMu.Lock()
 		delete(b.sseClients, clientID)
+		delete(b.sseWindows, clientID)
 		b.sseMu.Unlock()
 		close(eventCh)
 	}()
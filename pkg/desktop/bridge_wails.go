@@ -5,6 +5,9 @@ import (
 	"sync"
 
 	"github.com/astercloud/aster/pkg/agent"
+	"github.com/astercloud/aster/pkg/onboarding"
+	"github.com/astercloud/aster/pkg/types"
+	"github.com/astercloud/aster/pkg/update"
 )
 
 // WailsBridge provides integration with Wails framework.
@@ -20,6 +23,7 @@ import (
 type WailsBridge struct {
 	app      *App
 	handler  MessageHandler
+	shell    shellState
 	agents   map[string]*agent.Agent
 	agentsMu sync.RWMutex
 	eventCh  chan *FrontendEvent
@@ -84,6 +88,25 @@ func (b *WailsBridge) SendEvent(event *FrontendEvent) error {
 	}
 }
 
+// SetTrayMenu stores the tray menu and emits it on the event channel;
+// the Wails host renders it (e.g. with a systray library) and calls TrayAction on clicks
+func (b *WailsBridge) SetTrayMenu(menu *TrayMenu) error {
+	b.shell.setMenu(menu)
+	return b.SendEvent(&FrontendEvent{
+		Type: EventTypeTrayUpdate,
+		Data: menu,
+	})
+}
+
+// Notify emits a notification on the event channel for the Wails host to show
+func (b *WailsBridge) Notify(n *Notification) error {
+	return b.SendEvent(&FrontendEvent{
+		Type:    EventTypeNotification,
+		AgentID: n.AgentID,
+		Data:    n,
+	})
+}
+
 // OnMessage sets the handler for messages from frontend
 func (b *WailsBridge) OnMessage(handler MessageHandler) {
 	b.handler = handler
@@ -104,6 +127,17 @@ func (b *WailsBridge) Chat(agentID, message string) (*BackendResponse, error) {
 	})
 }
 
+// ChatWithAttachments sends a chat message referencing previously attached files
+// Exposed to Wails frontend as: window.go.desktop.WailsBridge.ChatWithAttachments(agentID, message, attachmentIDs)
+func (b *WailsBridge) ChatWithAttachments(agentID, message string, attachments []string) (*BackendResponse, error) {
+	return b.handler(&FrontendMessage{
+		ID:      generateID(),
+		Type:    MsgTypeChat,
+		AgentID: agentID,
+		Payload: mustMarshal(ChatPayload{Message: message, Attachments: attachments}),
+	})
+}
+
 // Cancel cancels the current operation
 // Exposed to Wails frontend as: window.go.desktop.WailsBridge.Cancel(agentID)
 func (b *WailsBridge) Cancel(agentID string) (*BackendResponse, error) {
@@ -129,6 +163,17 @@ func (b *WailsBridge) Approve(agentID, callID, decision, note string) (*BackendR
 	})
 }
 
+// TrustWorkspace trusts or declines the agent's workspace
+// Exposed to Wails frontend as: window.go.desktop.WailsBridge.TrustWorkspace(agentID, trusted)
+func (b *WailsBridge) TrustWorkspace(agentID string, trusted bool) (*BackendResponse, error) {
+	return b.handler(&FrontendMessage{
+		ID:      generateID(),
+		Type:    MsgTypeWorkspaceTrust,
+		AgentID: agentID,
+		Payload: mustMarshal(WorkspaceTrustPayload{Trusted: trusted}),
+	})
+}
+
 // GetStatus gets the agent status
 // Exposed to Wails frontend as: window.go.desktop.WailsBridge.GetStatus(agentID)
 func (b *WailsBridge) GetStatus(agentID string) (*BackendResponse, error) {
@@ -149,7 +194,18 @@ func (b *WailsBridge) GetHistory(agentID string) (*BackendResponse, error) {
 	})
 }
 
-// ClearHistory clears conversation history
+// GetHistoryPage gets a page of conversation history, optionally from an archived session
+// Exposed to Wails frontend as: window.go.desktop.WailsBridge.GetHistoryPage(agentID, req)
+func (b *WailsBridge) GetHistoryPage(agentID string, req HistoryPayload) (*BackendResponse, error) {
+	return b.handler(&FrontendMessage{
+		ID:      generateID(),
+		Type:    MsgTypeGetHistory,
+		AgentID: agentID,
+		Payload: mustMarshal(req),
+	})
+}
+
+// ClearHistory archives the conversation history and starts a new session
 // Exposed to Wails frontend as: window.go.desktop.WailsBridge.ClearHistory(agentID)
 func (b *WailsBridge) ClearHistory(agentID string) (*BackendResponse, error) {
 	return b.handler(&FrontendMessage{
@@ -159,6 +215,69 @@ func (b *WailsBridge) ClearHistory(agentID string) (*BackendResponse, error) {
 	})
 }
 
+// Undo undoes the agent's last turn and reverts its file edits
+// Exposed to Wails frontend as: window.go.desktop.WailsBridge.Undo(agentID)
+func (b *WailsBridge) Undo(agentID string) (*BackendResponse, error) {
+	return b.handler(&FrontendMessage{
+		ID:      generateID(),
+		Type:    MsgTypeUndo,
+		AgentID: agentID,
+	})
+}
+
+// EditMessage replaces a prior user message and regenerates the conversation from it
+// Exposed to Wails frontend as: window.go.desktop.WailsBridge.EditMessage(agentID, req)
+func (b *WailsBridge) EditMessage(agentID string, req EditMessagePayload) (*BackendResponse, error) {
+	return b.handler(&FrontendMessage{
+		ID:      generateID(),
+		Type:    MsgTypeEditMessage,
+		AgentID: agentID,
+		Payload: mustMarshal(req),
+	})
+}
+
+// Regenerate regenerates the reply to a user message, or to the last one if entryID is empty
+// Exposed to Wails frontend as: window.go.desktop.WailsBridge.Regenerate(agentID, entryID)
+func (b *WailsBridge) Regenerate(agentID, entryID string) (*BackendResponse, error) {
+	return b.handler(&FrontendMessage{
+		ID:      generateID(),
+		Type:    MsgTypeRegenerate,
+		AgentID: agentID,
+		Payload: mustMarshal(RegeneratePayload{EntryID: entryID}),
+	})
+}
+
+// Exposed to Wails frontend as: window.go.desktop.WailsBridge.ListPins(agentID)
+func (b *WailsBridge) ListPins(agentID string) (*BackendResponse, error) {
+	return b.handler(&FrontendMessage{
+		ID:      generateID(),
+		Type:    MsgTypeListPins,
+		AgentID: agentID,
+	})
+}
+
+// Pin pins a file, note or requirement list to the agent's context
+// Exposed to Wails frontend as: window.go.desktop.WailsBridge.Pin(agentID, pin)
+func (b *WailsBridge) Pin(agentID string, pin PinPayload) (*BackendResponse, error) {
+	return b.handler(&FrontendMessage{
+		ID:      generateID(),
+		Type:    MsgTypePin,
+		AgentID: agentID,
+		Payload: mustMarshal(pin),
+	})
+}
+
+// Unpin removes a pinned context item, or all of them when pinID is "all"
+// Exposed to Wails frontend as: window.go.desktop.WailsBridge.Unpin(agentID, pinID)
+func (b *WailsBridge) Unpin(agentID, pinID string) (*BackendResponse, error) {
+	return b.handler(&FrontendMessage{
+		ID:      generateID(),
+		Type:    MsgTypeUnpin,
+		AgentID: agentID,
+		Payload: mustMarshal(UnpinPayload{ID: pinID, All: pinID == "all"}),
+	})
+}
+
 // SetConfig sets configuration
 // Exposed to Wails frontend as: window.go.desktop.WailsBridge.SetConfig(config)
 func (b *WailsBridge) SetConfig(cfg ConfigPayload) (*BackendResponse, error) {
@@ -178,12 +297,270 @@ func (b *WailsBridge) GetConfig() (*BackendResponse, error) {
 	})
 }
 
+// OpenFileDialog shows a native file picker and attaches the picked files
+// Exposed to Wails frontend as: window.go.desktop.WailsBridge.OpenFileDialog(agentID, opts)
+func (b *WailsBridge) OpenFileDialog(agentID string, opts FileDialogPayload) (*BackendResponse, error) {
+	return b.handler(&FrontendMessage{
+		ID:      generateID(),
+		Type:    MsgTypeOpenFileDialog,
+		AgentID: agentID,
+		Payload: mustMarshal(opts),
+	})
+}
+
+// SaveFileDialog shows a native save dialog
+// Exposed to Wails frontend as: window.go.desktop.WailsBridge.SaveFileDialog(agentID, opts)
+func (b *WailsBridge) SaveFileDialog(agentID string, opts FileDialogPayload) (*BackendResponse, error) {
+	return b.handler(&FrontendMessage{
+		ID:      generateID(),
+		Type:    MsgTypeSaveFileDialog,
+		AgentID: agentID,
+		Payload: mustMarshal(opts),
+	})
+}
+
+// DialogResult answers a dialog_request event
+// Exposed to Wails frontend as: window.go.desktop.WailsBridge.DialogResult(result)
+func (b *WailsBridge) DialogResult(result DialogResultPayload) (*BackendResponse, error) {
+	return b.handler(&FrontendMessage{
+		ID:      generateID(),
+		Type:    MsgTypeDialogResult,
+		Payload: mustMarshal(result),
+	})
+}
+
+// Attach uploads a dropped or picked file as an attachment
+// Exposed to Wails frontend as: window.go.desktop.WailsBridge.Attach(agentID, attachment)
+func (b *WailsBridge) Attach(agentID string, attachment AttachmentPayload) (*BackendResponse, error) {
+	return b.handler(&FrontendMessage{
+		ID:      generateID(),
+		Type:    MsgTypeAttach,
+		AgentID: agentID,
+		Payload: mustMarshal(attachment),
+	})
+}
+
+// TrayMenu returns the current tray menu
+// Exposed to Wails frontend as: window.go.desktop.WailsBridge.TrayMenu()
+func (b *WailsBridge) TrayMenu() *TrayMenu {
+	return b.shell.getMenu()
+}
+
+// TrayAction reports a tray menu click
+// Exposed to Wails frontend as: window.go.desktop.WailsBridge.TrayAction(itemID)
+func (b *WailsBridge) TrayAction(itemID string) (*BackendResponse, error) {
+	return b.handler(&FrontendMessage{
+		ID:      generateID(),
+		Type:    MsgTypeTrayAction,
+		Payload: mustMarshal(TrayActionPayload{ItemID: itemID}),
+	})
+}
+
+// SetWindowVisible reports main window visibility
+// Exposed to Wails frontend as: window.go.desktop.WailsBridge.SetWindowVisible(visible)
+func (b *WailsBridge) SetWindowVisible(visible bool) (*BackendResponse, error) {
+	return b.handler(&FrontendMessage{
+		ID:      generateID(),
+		Type:    MsgTypeWindowState,
+		Payload: mustMarshal(WindowStatePayload{Visible: visible}),
+	})
+}
+
+// OpenWindow registers a frontend window
+// Exposed to Wails frontend as: window.go.desktop.WailsBridge.OpenWindow(windowID, title)
+func (b *WailsBridge) OpenWindow(windowID, title string) (*BackendResponse, error) {
+	return b.handler(&FrontendMessage{
+		ID:       generateID(),
+		Type:     MsgTypeOpenWindow,
+		WindowID: windowID,
+		Payload:  mustMarshal(WindowPayload{WindowID: windowID, Title: title}),
+	})
+}
+
+// CloseWindow unregisters a frontend window
+// Exposed to Wails frontend as: window.go.desktop.WailsBridge.CloseWindow(windowID)
+func (b *WailsBridge) CloseWindow(windowID string) (*BackendResponse, error) {
+	return b.handler(&FrontendMessage{
+		ID:       generateID(),
+		Type:     MsgTypeCloseWindow,
+		WindowID: windowID,
+	})
+}
+
+// BindAgent routes an agent's events to a window
+// Exposed to Wails frontend as: window.go.desktop.WailsBridge.BindAgent(windowID, agentID)
+func (b *WailsBridge) BindAgent(windowID, agentID string) (*BackendResponse, error) {
+	return b.handler(&FrontendMessage{
+		ID:       generateID(),
+		Type:     MsgTypeBindAgent,
+		WindowID: windowID,
+		AgentID:  agentID,
+	})
+}
+
+// UnbindAgent stops routing an agent's events to a window
+// Exposed to Wails frontend as: window.go.desktop.WailsBridge.UnbindAgent(windowID, agentID)
+func (b *WailsBridge) UnbindAgent(windowID, agentID string) (*BackendResponse, error) {
+	return b.handler(&FrontendMessage{
+		ID:       generateID(),
+		Type:     MsgTypeUnbindAgent,
+		WindowID: windowID,
+		AgentID:  agentID,
+	})
+}
+
+// ListAgents lists registered agents and available templates
+// Exposed to Wails frontend as: window.go.desktop.WailsBridge.ListAgents()
+func (b *WailsBridge) ListAgents() (*BackendResponse, error) {
+	return b.handler(&FrontendMessage{
+		ID:   generateID(),
+		Type: MsgTypeListAgents,
+	})
+}
+
+// CreateAgent creates an agent from a template and binds it to the window
+// Exposed to Wails frontend as: window.go.desktop.WailsBridge.CreateAgent(windowID, req)
+func (b *WailsBridge) CreateAgent(windowID string, req CreateAgentPayload) (*BackendResponse, error) {
+	return b.handler(&FrontendMessage{
+		ID:       generateID(),
+		Type:     MsgTypeCreateAgent,
+		WindowID: windowID,
+		Payload:  mustMarshal(req),
+	})
+}
+
 // GetEvents returns the event channel for Wails runtime to consume
 // Usage: Use with wails runtime.EventsEmit in a goroutine
 func (b *WailsBridge) GetEvents() <-chan *FrontendEvent {
 	return b.eventCh
 }
 
+// Connectivity returns the online/offline state
+// Exposed to Wails frontend as: window.go.desktop.WailsBridge.Connectivity()
+func (b *WailsBridge) Connectivity() (*BackendResponse, error) {
+	return b.handler(&FrontendMessage{
+		ID:   generateID(),
+		Type: MsgTypeConnectivity,
+	})
+}
+
+// ReportConnectivity reports a browser online/offline change
+// Exposed to Wails frontend as: window.go.desktop.WailsBridge.ReportConnectivity(online)
+func (b *WailsBridge) ReportConnectivity(online bool) (*BackendResponse, error) {
+	return b.handler(&FrontendMessage{
+		ID:      generateID(),
+		Type:    MsgTypeConnectivity,
+		Payload: mustMarshal(ConnectivityPayload{Online: &online}),
+	})
+}
+
+// UpdateStatus returns the app update state
+// Exposed to Wails frontend as: window.go.desktop.WailsBridge.UpdateStatus()
+func (b *WailsBridge) UpdateStatus() (*BackendResponse, error) {
+	return b.handler(&FrontendMessage{
+		ID:   generateID(),
+		Type: MsgTypeUpdateStatus,
+	})
+}
+
+// CheckUpdate checks for an app update now
+// Exposed to Wails frontend as: window.go.desktop.WailsBridge.CheckUpdate()
+func (b *WailsBridge) CheckUpdate() (*BackendResponse, error) {
+	return b.handler(&FrontendMessage{
+		ID:   generateID(),
+		Type: MsgTypeCheckUpdate,
+	})
+}
+
+// SetUpdateChannel switches the release channel
+// Exposed to Wails frontend as: window.go.desktop.WailsBridge.SetUpdateChannel(channel)
+func (b *WailsBridge) SetUpdateChannel(channel string) (*BackendResponse, error) {
+	return b.handler(&FrontendMessage{
+		ID:      generateID(),
+		Type:    MsgTypeSetUpdateChannel,
+		Payload: mustMarshal(UpdateChannelPayload{Channel: update.Channel(channel)}),
+	})
+}
+
+// InstallUpdate installs the downloaded update and restarts the app
+// Exposed to Wails frontend as: window.go.desktop.WailsBridge.InstallUpdate()
+func (b *WailsBridge) InstallUpdate() (*BackendResponse, error) {
+	return b.handler(&FrontendMessage{
+		ID:   generateID(),
+		Type: MsgTypeInstallUpdate,
+	})
+}
+
+// OnboardingStatus reports whether first-run setup is needed
+// Exposed to Wails frontend as: window.go.desktop.WailsBridge.OnboardingStatus()
+func (b *WailsBridge) OnboardingStatus() (*BackendResponse, error) {
+	return b.handler(&FrontendMessage{
+		ID:   generateID(),
+		Type: MsgTypeOnboardingStatus,
+	})
+}
+
+// ValidateAPIKey validates a provider API key with a test request
+// Exposed to Wails frontend as: window.go.desktop.WailsBridge.ValidateAPIKey(req)
+func (b *WailsBridge) ValidateAPIKey(req onboarding.Request) (*BackendResponse, error) {
+	return b.handler(&FrontendMessage{
+		ID:      generateID(),
+		Type:    MsgTypeValidateAPIKey,
+		Payload: mustMarshal(req),
+	})
+}
+
+// CompleteOnboarding stores the API key and writes the default configuration
+// Exposed to Wails frontend as: window.go.desktop.WailsBridge.CompleteOnboarding(req)
+func (b *WailsBridge) CompleteOnboarding(req onboarding.Request) (*BackendResponse, error) {
+	return b.handler(&FrontendMessage{
+		ID:      generateID(),
+		Type:    MsgTypeCompleteOnboarding,
+		Payload: mustMarshal(req),
+	})
+}
+
+// SendUIAction reports a user action or client error on a rendered UI surface
+// Exposed to Wails frontend as: window.go.desktop.WailsBridge.SendUIAction(agentID, msg)
+func (b *WailsBridge) SendUIAction(agentID string, msg types.ClientMessage) (*BackendResponse, error) {
+	return b.handler(&FrontendMessage{
+		ID:      generateID(),
+		Type:    MsgTypeUIAction,
+		AgentID: agentID,
+		Payload: mustMarshal(msg),
+	})
+}
+
+// ListUICatalogs lists the registered UI component catalogs
+// Exposed to Wails frontend as: window.go.desktop.WailsBridge.ListUICatalogs()
+func (b *WailsBridge) ListUICatalogs() (*BackendResponse, error) {
+	return b.handler(&FrontendMessage{
+		ID:   generateID(),
+		Type: MsgTypeGetCatalog,
+	})
+}
+
+// GetUICatalog returns a UI component catalog by "id" (latest) or "id@version"
+// Exposed to Wails frontend as: window.go.desktop.WailsBridge.GetUICatalog(id)
+func (b *WailsBridge) GetUICatalog(id string) (*BackendResponse, error) {
+	return b.handler(&FrontendMessage{
+		ID:      generateID(),
+		Type:    MsgTypeGetCatalog,
+		Payload: mustMarshal(CatalogPayload{ID: id}),
+	})
+}
+
+// ExportUISurface exports an agent's UI surface as "html" or "react" code
+// Exposed to Wails frontend as: window.go.desktop.WailsBridge.ExportUISurface(agentID, surfaceID, format)
+func (b *WailsBridge) ExportUISurface(agentID, surfaceID, format string) (*BackendResponse, error) {
+	return b.handler(&FrontendMessage{
+		ID:      generateID(),
+		Type:    MsgTypeExportSurface,
+		AgentID: agentID,
+		Payload: mustMarshal(ExportSurfacePayload{SurfaceID: surfaceID, Format: format}),
+	})
+}
+
 // WailsInit is called by Wails during initialization
 func (b *WailsBridge) WailsInit(ctx context.Context) error {
 	b.ctx = ctx
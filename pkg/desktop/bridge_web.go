@@ -22,11 +22,13 @@ import (
 type WebBridge struct {
 	app        *App
 	handler    MessageHandler
+	ws         *wsHub
 	agents     map[string]*agent.Agent
 	agentsMu   sync.RWMutex
 	server     *http.Server
 	port       int
 	sseClients map[string]chan *FrontendEvent
+	sseWindows map[string]string // client ID -> window ID
 	sseMu      sync.RWMutex
 	ctx        context.Context
 	cancel     context.CancelFunc
@@ -40,9 +42,11 @@ func NewWebBridge(app *App, port int) (*WebBridge, error) {
 
 	return &WebBridge{
 		app:        app,
+		ws:         newWSHub(app),
 		agents:     make(map[string]*agent.Agent),
 		port:       port,
 		sseClients: make(map[string]chan *FrontendEvent),
+		sseWindows: make(map[string]string),
 	}, nil
 }
 
@@ -64,11 +68,27 @@ func (b *WebBridge) Start(ctx context.Context) error {
 	mux.HandleFunc("/api/status", b.handleStatus)
 	mux.HandleFunc("/api/history", b.handleHistory)
 	mux.HandleFunc("/api/config", b.handleConfig)
-	mux.HandleFunc("/api/agents", b.handleAgents)
+	fileRoutes(mux, b.app, b.handler)
+	windowRoutes(mux, b.handler)
+	windowAgentRoutes(mux, b.handler)
+	connectivityRoutes(mux, b.handler)
+	updateRoutes(mux, b.handler)
+	onboardingRoutes(mux, b.handler)
+	uiRoutes(mux, b.handler)
+	workspaceRoutes(mux, b.handler)
+	undoRoutes(mux, b.handler)
+	editRoutes(mux, b.handler)
+	pinRoutes(mux, b.handler)
 
 	// SSE endpoint for events
 	mux.HandleFunc("/api/events", b.handleSSE)
 
+	// WebSocket endpoint (TransportWebSocket)
+	if b.ws != nil {
+		b.ws.setHandler(b.handler)
+		mux.Handle("/ws", b.ws)
+	}
+
 	// Health check
 	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
 		_ = json.NewEncoder(w).Encode(map[string]any{
@@ -104,6 +124,9 @@ func (b *WebBridge) Stop(ctx context.Context) error {
 	if b.cancel != nil {
 		b.cancel()
 	}
+	if b.ws != nil {
+		b.ws.close()
+	}
 
 	// Close all SSE clients
 	b.sseMu.Lock()
@@ -137,10 +160,17 @@ func (b *WebBridge) UnregisterAgent(agentID string) error {
 
 // SendEvent sends an event to all SSE clients
 func (b *WebBridge) SendEvent(event *FrontendEvent) error {
+	if b.ws != nil {
+		b.ws.broadcast(event)
+	}
+
 	b.sseMu.RLock()
 	defer b.sseMu.RUnlock()
 
-	for _, ch := range b.sseClients {
+	for id, ch := range b.sseClients {
+		if !b.app.windowAccepts(b.sseWindows[id], event) {
+			continue
+		}
 		select {
 		case ch <- event:
 		default:
@@ -150,6 +180,20 @@ func (b *WebBridge) SendEvent(event *FrontendEvent) error {
 	return nil
 }
 
+// SetTrayMenu is not supported: browsers have no system tray
+func (b *WebBridge) SetTrayMenu(menu *TrayMenu) error {
+	return ErrTrayNotSupported
+}
+
+// Notify pushes a notification via SSE for the browser Notification API
+func (b *WebBridge) Notify(n *Notification) error {
+	return b.SendEvent(&FrontendEvent{
+		Type:    EventTypeNotification,
+		AgentID: n.AgentID,
+		Data:    n,
+	})
+}
+
 // OnMessage sets the handler for messages from frontend
 func (b *WebBridge) OnMessage(handler MessageHandler) {
 	b.handler = handler
@@ -169,8 +213,9 @@ func (b *WebBridge) handleChat(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req struct {
-		AgentID string `json:"agent_id"`
-		Message string `json:"message"`
+		AgentID     string   `json:"agent_id"`
+		Message     string   `json:"message"`
+		Attachments []string `json:"attachments,omitempty"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -185,7 +230,7 @@ func (b *WebBridge) handleChat(w http.ResponseWriter, r *http.Request) {
 		ID:      generateID(),
 		Type:    MsgTypeChat,
 		AgentID: req.AgentID,
-		Payload: mustMarshal(ChatPayload{Message: req.Message}),
+		Payload: mustMarshal(ChatPayload{Message: req.Message, Attachments: req.Attachments}),
 	})
 
 	if err != nil {
@@ -287,6 +332,7 @@ func (b *WebBridge) handleHistory(w http.ResponseWriter, r *http.Request) {
 		ID:      generateID(),
 		Type:    msgType,
 		AgentID: agentID,
+		Payload: historyQuery(r),
 	})
 
 	writeJSON(w, http.StatusOK, resp)
@@ -323,25 +369,6 @@ func (b *WebBridge) handleConfig(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func (b *WebBridge) handleAgents(w http.ResponseWriter, r *http.Request) {
-	b.agentsMu.RLock()
-	defer b.agentsMu.RUnlock()
-
-	agents := make([]map[string]any, 0, len(b.agents))
-	for id, ag := range b.agents {
-		status := ag.Status()
-		agents = append(agents, map[string]any{
-			"id":    id,
-			"state": status.State,
-		})
-	}
-
-	writeJSON(w, http.StatusOK, BackendResponse{
-		Success: true,
-		Data:    agents,
-	})
-}
-
 func (b *WebBridge) handleSSE(w http.ResponseWriter, r *http.Request) {
 	// Set SSE headers
 	w.Header().Set("Content-Type", "text/event-stream")
@@ -355,11 +382,16 @@ func (b *WebBridge) handleSSE(w http.ResponseWriter, r *http.Request) {
 
 	b.sseMu.Lock()
 	b.sseClients[clientID] = eventCh
+	if windowID := r.URL.Query().Get("window_id"); windowID != "" {
+		b.sseWindows[clientID] = windowID
+		b.app.OpenWindow(windowID, "")
+	}
 	b.sseMu.Unlock()
 
 	defer func() {
 		b.sseMu.Lock()
 		delete(b.sseClients, clientID)
+		delete(b.sseWindows, clientID)
 		b.sseMu.Unlock()
 		close(eventCh)
 	}()
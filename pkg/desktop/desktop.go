@@ -15,12 +15,17 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"slices"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/astercloud/aster/pkg/agent"
 	"github.com/astercloud/aster/pkg/config"
+	"github.com/astercloud/aster/pkg/onboarding"
 	"github.com/astercloud/aster/pkg/permission"
 	"github.com/astercloud/aster/pkg/types"
+	"github.com/astercloud/aster/pkg/uiproto"
 )
 
 // Framework represents the desktop framework type
@@ -66,6 +71,12 @@ type Bridge interface {
 
 	// OnMessage sets the handler for messages from frontend
 	OnMessage(handler MessageHandler)
+
+	// SetTrayMenu installs or replaces the system tray menu
+	SetTrayMenu(menu *TrayMenu) error
+
+	// Notify shows an OS notification
+	Notify(n *Notification) error
 }
 
 // MessageHandler handles messages from the frontend
@@ -82,6 +93,9 @@ type FrontendMessage struct {
 	// AgentID is the target agent ID (optional)
 	AgentID string `json:"agent_id,omitempty"`
 
+	// WindowID is the sending window (optional, for multi-window frontends)
+	WindowID string `json:"window_id,omitempty"`
+
 	// Payload is the message payload
 	Payload json.RawMessage `json:"payload,omitempty"`
 }
@@ -140,6 +154,96 @@ const (
 
 	// MsgTypeGetConfig gets current configuration
 	MsgTypeGetConfig MessageType = "get_config"
+
+	// MsgTypeOpenFileDialog opens a native file picker; picked files become attachments
+	MsgTypeOpenFileDialog MessageType = "open_file_dialog"
+
+	// MsgTypeSaveFileDialog opens a native save dialog
+	MsgTypeSaveFileDialog MessageType = "save_file_dialog"
+
+	// MsgTypeDialogResult answers a dialog_request event
+	MsgTypeDialogResult MessageType = "dialog_result"
+
+	// MsgTypeAttach uploads a file (path or base64 content) as an attachment
+	MsgTypeAttach MessageType = "attach"
+
+	// MsgTypeTrayAction reports a tray menu click
+	MsgTypeTrayAction MessageType = "tray_action"
+
+	// MsgTypeWindowState reports main window visibility
+	MsgTypeWindowState MessageType = "window_state"
+
+	// MsgTypeOpenWindow registers a frontend window
+	MsgTypeOpenWindow MessageType = "open_window"
+
+	// MsgTypeCloseWindow unregisters a frontend window
+	MsgTypeCloseWindow MessageType = "close_window"
+
+	// MsgTypeBindAgent routes an agent's events to the sending window
+	MsgTypeBindAgent MessageType = "bind_agent"
+
+	// MsgTypeUnbindAgent stops routing an agent's events to the sending window
+	MsgTypeUnbindAgent MessageType = "unbind_agent"
+
+	// MsgTypeListAgents lists registered agents and available templates
+	MsgTypeListAgents MessageType = "list_agents"
+
+	// MsgTypeCreateAgent creates an agent from a template
+	MsgTypeCreateAgent MessageType = "create_agent"
+
+	// MsgTypeConnectivity reports or queries online/offline state
+	MsgTypeConnectivity MessageType = "connectivity"
+
+	// MsgTypeUIAction reports a user action (or client error) on a rendered UI surface
+	MsgTypeUIAction MessageType = "ui_action"
+
+	// MsgTypeGetCatalog lists UI component catalogs or gets one by ID
+	MsgTypeGetCatalog MessageType = "get_catalog"
+
+	// MsgTypeExportSurface exports a rendered UI surface as static HTML or React code
+	MsgTypeExportSurface MessageType = "export_surface"
+
+	// MsgTypeWorkspaceTrust trusts or declines the agent's workspace
+	MsgTypeWorkspaceTrust MessageType = "workspace_trust"
+
+	// MsgTypeUndo undoes the agent's last turn and the file edits made in it
+	MsgTypeUndo MessageType = "undo"
+
+	// MsgTypeEditMessage replaces a prior user message and regenerates from it
+	MsgTypeEditMessage MessageType = "edit_message"
+
+	// MsgTypeRegenerate regenerates the reply to a user message
+	MsgTypeRegenerate MessageType = "regenerate"
+
+	// MsgTypeListPins lists the agent's pinned context items
+	MsgTypeListPins MessageType = "list_pins"
+
+	// MsgTypePin pins a file, note or requirement list to the agent's context
+	MsgTypePin MessageType = "pin"
+
+	// MsgTypeUnpin removes pinned context items
+	MsgTypeUnpin MessageType = "unpin"
+
+	// MsgTypeUpdateStatus gets the app update state
+	MsgTypeUpdateStatus MessageType = "update_status"
+
+	// MsgTypeCheckUpdate checks for an app update now
+	MsgTypeCheckUpdate MessageType = "check_update"
+
+	// MsgTypeSetUpdateChannel switches the release channel (stable or beta)
+	MsgTypeSetUpdateChannel MessageType = "set_update_channel"
+
+	// MsgTypeInstallUpdate installs the downloaded update and restarts the app
+	MsgTypeInstallUpdate MessageType = "install_update"
+
+	// MsgTypeOnboardingStatus reports whether first-run setup is needed
+	MsgTypeOnboardingStatus MessageType = "onboarding_status"
+
+	// MsgTypeValidateAPIKey validates a provider API key with a test request
+	MsgTypeValidateAPIKey MessageType = "validate_api_key"
+
+	// MsgTypeCompleteOnboarding stores the API key and writes the default configuration
+	MsgTypeCompleteOnboarding MessageType = "complete_onboarding"
 )
 
 // EventType defines backend event types
@@ -169,12 +273,54 @@ const (
 
 	// EventTypeStatusChange indicates agent status changed
 	EventTypeStatusChange EventType = "status_change"
+
+	// EventTypeDialogRequest asks the frontend to show a native file dialog
+	EventTypeDialogRequest EventType = "dialog_request"
+
+	// EventTypeAttachmentAdded indicates a file was attached
+	EventTypeAttachmentAdded EventType = "attachment_added"
+
+	// EventTypeTrayUpdate carries a new tray menu definition
+	EventTypeTrayUpdate EventType = "tray_update"
+
+	// EventTypeNotification asks the shell to show an OS notification
+	EventTypeNotification EventType = "notification"
+
+	// EventTypeHistoryUpdated indicates a message was added to or the history was cleared
+	EventTypeHistoryUpdated EventType = "history_updated"
+
+	// EventTypeConnectivity indicates the app went offline or came back online
+	EventTypeConnectivity EventType = "connectivity"
+
+	// EventTypeUIMessage carries a UI protocol message to render
+	EventTypeUIMessage EventType = "ui_message"
+
+	// EventTypeWorkspaceTrustRequired asks the user to trust the agent's workspace
+	EventTypeWorkspaceTrustRequired EventType = "workspace_trust_required"
+
+	// EventTypeSessionCost carries the footer HUD: tokens, cost, context usage and warning level
+	EventTypeSessionCost EventType = "session_cost"
+
+	// EventTypeUpdateAvailable indicates a newer release was found and is being downloaded
+	EventTypeUpdateAvailable EventType = "update_available"
+
+	// EventTypeUpdateReady indicates a verified update is downloaded; the frontend should offer a restart
+	EventTypeUpdateReady EventType = "update_ready"
+
+	// EventTypeOnboardingRequired asks the frontend to show the first-run flow
+	EventTypeOnboardingRequired EventType = "onboarding_required"
+
+	// EventTypeOnboardingCompleted indicates first-run setup finished
+	EventTypeOnboardingCompleted EventType = "onboarding_completed"
 )
 
 // ChatPayload is the payload for chat messages
 type ChatPayload struct {
 	Message string         `json:"message"`
 	Context map[string]any `json:"context,omitempty"`
+
+	// Attachments are attachment IDs returned by MsgTypeAttach or MsgTypeOpenFileDialog
+	Attachments []string `json:"attachments,omitempty"`
 }
 
 // ApprovalPayload is the payload for approval responses
@@ -199,6 +345,18 @@ type App struct {
 	agentsMu  sync.RWMutex
 	inspector *permission.Inspector
 	config    *AppConfig
+
+	files         *attachmentStore
+	dialogHandler DialogHandler
+	notify        *notifyState
+	windows       *windowRegistry
+	agentFactory  AgentFactory
+	subscriptions map[string]<-chan types.AgentEventEnvelope
+	offline       *offlineState
+	update        *updateState
+	setup         *onboarding.Setup
+	history       *historyState
+	catalogs      *uiproto.CatalogRegistry
 }
 
 // AppConfig is the application configuration
@@ -217,6 +375,33 @@ type AppConfig struct {
 
 	// DataDir is the data directory (defaults to platform-specific)
 	DataDir string `json:"data_dir,omitempty"`
+
+	// MaxAttachmentSize is the size limit for a single attachment (defaults to 25 MB)
+	MaxAttachmentSize int64 `json:"max_attachment_size,omitempty"`
+
+	// DialogTimeout is how long to wait for the frontend to answer a dialog request (defaults to 5 minutes)
+	DialogTimeout time.Duration `json:"dialog_timeout,omitempty"`
+
+	// Transport selects the event transport for HTTP bridges (defaults to SSE)
+	Transport Transport `json:"transport,omitempty"`
+
+	// HeartbeatInterval is the WebSocket ping interval (defaults to 15s)
+	HeartbeatInterval time.Duration `json:"heartbeat_interval,omitempty"`
+
+	// Notifications controls automatic notifications for agent activity
+	Notifications *NotificationConfig `json:"notifications,omitempty"`
+
+	// UserID identifies the local user in the session service (defaults to "local")
+	UserID string `json:"user_id,omitempty"`
+
+	// Offline enables connectivity monitoring with local model fallback (disabled when nil)
+	Offline *OfflineConfig `json:"offline,omitempty"`
+
+	// Update enables automatic update checks (disabled when nil)
+	Update *UpdateConfig `json:"update,omitempty"`
+
+	// SettingsFile is the settings file written by onboarding (defaults to config.SettingsFile())
+	SettingsFile string `json:"settings_file,omitempty"`
 }
 
 // NewApp creates a new desktop application
@@ -238,15 +423,33 @@ func NewApp(cfg *AppConfig) (*App, error) {
 
 	// Create permission inspector
 	inspector := permission.NewInspector(cfg.PermissionMode)
+	policy, err := permission.LoadWorkspacePolicy(cfg.WorkDir)
+	if err != nil {
+		return nil, fmt.Errorf("load workspace permission policy: %w", err)
+	}
+	if policy != nil {
+		if err := inspector.ApplyPolicy(policy); err != nil {
+			return nil, fmt.Errorf("apply workspace permission policy: %w", err)
+		}
+	}
 
 	app := &App{
 		agents:    make(map[string]*agent.Agent),
 		inspector: inspector,
 		config:    cfg,
+		files:     newAttachmentStore(),
+		notify:    newNotifyState(),
+		windows:   newWindowRegistry(),
+
+		subscriptions: make(map[string]<-chan types.AgentEventEnvelope),
+		offline:       newOfflineState(cfg),
+		update:        newUpdateState(cfg),
+		setup:         newOnboardingSetup(cfg),
+		history:       newHistoryState(),
+		catalogs:      uiproto.DefaultCatalogs,
 	}
 
 	// Create bridge based on framework
-	var err error
 	switch cfg.Framework {
 	case FrameworkWails:
 		app.bridge, err = NewWailsBridge(app)
@@ -272,17 +475,25 @@ func NewApp(cfg *AppConfig) (*App, error) {
 
 // Start starts the application
 func (a *App) Start(ctx context.Context) error {
+	a.startConnectivityMonitor(ctx)
+	a.startUpdateChecker(ctx)
+	a.promptOnboarding()
 	return a.bridge.Start(ctx)
 }
 
 // Stop stops the application
 func (a *App) Stop(ctx context.Context) error {
+	a.stopConnectivityMonitor()
+	a.stopUpdateChecker()
+
 	// Close all agents
 	a.agentsMu.Lock()
-	for _, ag := range a.agents {
+	for id, ag := range a.agents {
+		ag.Unsubscribe(a.subscriptions[id])
 		_ = ag.Close() // Best effort cleanup
 	}
 	a.agents = make(map[string]*agent.Agent)
+	a.subscriptions = make(map[string]<-chan types.AgentEventEnvelope)
 	a.agentsMu.Unlock()
 
 	return a.bridge.Stop(ctx)
@@ -291,12 +502,53 @@ func (a *App) Stop(ctx context.Context) error {
 // RegisterAgent registers an agent with the app
 func (a *App) RegisterAgent(ag *agent.Agent) error {
 	a.agentsMu.Lock()
-	defer a.agentsMu.Unlock()
+	id := ag.ID()
+	a.agents[id] = ag
+
+	// Forward events once per agent; windows pick them up via their bindings
+	if _, ok := a.subscriptions[id]; !ok {
+		eventCh := ag.Subscribe([]types.AgentChannel{
+			types.ChannelProgress,
+			types.ChannelControl,
+			types.ChannelMonitor,
+		}, nil)
+		a.subscriptions[id] = eventCh
+		go a.forwardAgentEvents(eventCh, id)
+	}
+	a.agentsMu.Unlock()
 
-	a.agents[ag.ID()] = ag
+	// Agents registered while offline start on the local model
+	if !a.Online() {
+		a.useLocalModel(context.Background(), id, ag)
+	}
 	return a.bridge.RegisterAgent(ag)
 }
 
+// UnregisterAgent removes an agent from the app and all window bindings.
+// The agent is not closed.
+func (a *App) UnregisterAgent(id string) error {
+	a.agentsMu.Lock()
+	ag, ok := a.agents[id]
+	if ok {
+		ag.Unsubscribe(a.subscriptions[id])
+		delete(a.subscriptions, id)
+		delete(a.agents, id)
+	}
+	a.agentsMu.Unlock()
+	if !ok {
+		return fmt.Errorf("agent not found: %s", id)
+	}
+	a.forgetAgent(id)
+
+	a.windows.mu.Lock()
+	for _, w := range a.windows.windows {
+		w.Agents = slices.DeleteFunc(w.Agents, func(agentID string) bool { return agentID == id })
+	}
+	a.windows.mu.Unlock()
+
+	return a.bridge.UnregisterAgent(id)
+}
+
 // GetAgent returns an agent by ID
 func (a *App) GetAgent(id string) (*agent.Agent, bool) {
 	a.agentsMu.RLock()
@@ -325,16 +577,76 @@ func (a *App) handleMessage(msg *FrontendMessage) (*BackendResponse, error) {
 		return a.handleCancel(msg)
 	case MsgTypeApproval:
 		return a.handleApproval(msg)
+	case MsgTypeWorkspaceTrust:
+		return a.handleWorkspaceTrust(msg)
 	case MsgTypeGetStatus:
 		return a.handleGetStatus(msg)
 	case MsgTypeGetHistory:
 		return a.handleGetHistory(msg)
 	case MsgTypeClearHistory:
 		return a.handleClearHistory(msg)
+	case MsgTypeUndo:
+		return a.handleUndo(msg)
+	case MsgTypeEditMessage:
+		return a.handleEditMessage(msg)
+	case MsgTypeRegenerate:
+		return a.handleRegenerate(msg)
+	case MsgTypeListPins:
+		return a.handleListPins(msg)
+	case MsgTypePin:
+		return a.handlePin(msg)
+	case MsgTypeUnpin:
+		return a.handleUnpin(msg)
 	case MsgTypeSetConfig:
 		return a.handleSetConfig(msg)
 	case MsgTypeGetConfig:
 		return a.handleGetConfig(msg)
+	case MsgTypeOpenFileDialog:
+		return a.handleFileDialog(msg, DialogKindOpen)
+	case MsgTypeSaveFileDialog:
+		return a.handleFileDialog(msg, DialogKindSave)
+	case MsgTypeDialogResult:
+		return a.handleDialogResult(msg)
+	case MsgTypeAttach:
+		return a.handleAttach(msg)
+	case MsgTypeTrayAction:
+		return a.handleTrayAction(msg)
+	case MsgTypeWindowState:
+		return a.handleWindowState(msg)
+	case MsgTypeOpenWindow:
+		return a.handleOpenWindow(msg)
+	case MsgTypeCloseWindow:
+		return a.handleCloseWindow(msg)
+	case MsgTypeBindAgent:
+		return a.handleBindAgent(msg, true)
+	case MsgTypeUnbindAgent:
+		return a.handleBindAgent(msg, false)
+	case MsgTypeListAgents:
+		return a.handleListAgents(msg)
+	case MsgTypeCreateAgent:
+		return a.handleCreateAgent(msg)
+	case MsgTypeConnectivity:
+		return a.handleConnectivity(msg)
+	case MsgTypeUIAction:
+		return a.handleUIAction(msg)
+	case MsgTypeGetCatalog:
+		return a.handleGetCatalog(msg)
+	case MsgTypeExportSurface:
+		return a.handleExportSurface(msg)
+	case MsgTypeUpdateStatus:
+		return a.handleUpdateStatus(msg)
+	case MsgTypeCheckUpdate:
+		return a.handleCheckUpdate(msg)
+	case MsgTypeSetUpdateChannel:
+		return a.handleSetUpdateChannel(msg)
+	case MsgTypeInstallUpdate:
+		return a.handleInstallUpdate(msg)
+	case MsgTypeOnboardingStatus:
+		return a.handleOnboardingStatus(msg)
+	case MsgTypeValidateAPIKey:
+		return a.handleValidateAPIKey(msg)
+	case MsgTypeCompleteOnboarding:
+		return a.handleCompleteOnboarding(msg)
 	default:
 		return &BackendResponse{
 			ID:      msg.ID,
@@ -363,13 +675,26 @@ func (a *App) handleChat(msg *FrontendMessage) (*BackendResponse, error) {
 		}, nil
 	}
 
-	// Subscribe to events and forward to frontend
-	go a.forwardAgentEvents(ag, msg.AgentID)
+	message, err := a.withAttachments(payload.Message, payload.Attachments)
+	if err != nil {
+		return &BackendResponse{
+			ID:      msg.ID,
+			Success: false,
+			Error:   err.Error(),
+		}, nil
+	}
+
+	a.markUnsynced(msg.AgentID)
+	_ = a.recordHistory(context.Background(), msg.AgentID, HistoryEntry{ // History is best effort
+		Role:        types.RoleUser,
+		Content:     payload.Message,
+		Attachments: payload.Attachments,
+	})
 
 	// Send message to agent (non-blocking, events will be sent via bridge)
 	go func() {
 		ctx := context.Background()
-		err := ag.Send(ctx, payload.Message)
+		err := ag.Send(ctx, message)
 		if err != nil {
 			_ = a.bridge.SendEvent(&FrontendEvent{
 				Type:    EventTypeError,
@@ -448,43 +773,6 @@ func (a *App) handleGetStatus(msg *FrontendMessage) (*BackendResponse, error) {
 	}, nil
 }
 
-func (a *App) handleGetHistory(msg *FrontendMessage) (*BackendResponse, error) {
-	_, ok := a.GetAgent(msg.AgentID)
-	if !ok {
-		return &BackendResponse{
-			ID:      msg.ID,
-			Success: false,
-			Error:   "agent not found: " + msg.AgentID,
-		}, nil
-	}
-
-	// Note: Message history is managed internally by the agent
-	// For now, return empty - in production, use session store
-	return &BackendResponse{
-		ID:      msg.ID,
-		Success: true,
-		Data:    []any{},
-	}, nil
-}
-
-func (a *App) handleClearHistory(msg *FrontendMessage) (*BackendResponse, error) {
-	_, ok := a.GetAgent(msg.AgentID)
-	if !ok {
-		return &BackendResponse{
-			ID:      msg.ID,
-			Success: false,
-			Error:   "agent not found: " + msg.AgentID,
-		}, nil
-	}
-
-	// Note: Clearing history requires recreating the agent
-	// For now, just acknowledge the request
-	return &BackendResponse{
-		ID:      msg.ID,
-		Success: true,
-	}, nil
-}
-
 func (a *App) handleSetConfig(msg *FrontendMessage) (*BackendResponse, error) {
 	var payload ConfigPayload
 	if err := json.Unmarshal(msg.Payload, &payload); err != nil {
@@ -518,12 +806,10 @@ func (a *App) handleGetConfig(msg *FrontendMessage) (*BackendResponse, error) {
 	}, nil
 }
 
-// forwardAgentEvents subscribes to agent events and forwards them to the frontend
-func (a *App) forwardAgentEvents(ag *agent.Agent, agentID string) {
-	eventCh := ag.Subscribe([]types.AgentChannel{
-		types.ChannelProgress,
-		types.ChannelControl,
-	}, nil)
+// forwardAgentEvents forwards agent events to the frontend until the subscription is closed
+func (a *App) forwardAgentEvents(eventCh <-chan types.AgentEventEnvelope, agentID string) {
+	// Reasoning streamed before a text block is stored with that block's history entry
+	var reasoning strings.Builder
 
 	for envelope := range eventCh {
 		var event *FrontendEvent
@@ -536,6 +822,19 @@ func (a *App) forwardAgentEvents(ag *agent.Agent, agentID string) {
 				Data:    map[string]string{"delta": e.Delta},
 			}
 
+		case *types.ProgressReasoningEvent:
+			reasoning.WriteString(e.Delta)
+
+		case *types.ProgressTextChunkEndEvent:
+			if e.Text != "" {
+				_ = a.recordHistory(context.Background(), agentID, HistoryEntry{
+					Role:      types.RoleAssistant,
+					Content:   e.Text,
+					Reasoning: reasoning.String(),
+				})
+			}
+			reasoning.Reset()
+
 		case *types.ProgressToolStartEvent:
 			event = &FrontendEvent{
 				Type:    EventTypeToolStart,
@@ -570,6 +869,33 @@ func (a *App) forwardAgentEvents(ag *agent.Agent, agentID string) {
 				},
 			}
 
+		case *types.ProgressToolIntermediateEvent:
+			if e.UI != nil {
+				event = &FrontendEvent{
+					Type:    EventTypeUIMessage,
+					AgentID: agentID,
+					Data:    &UIMessageEvent{CallID: e.Call.ID, Message: e.UI},
+				}
+			}
+
+		case *types.ProgressDoneEvent:
+			event = &FrontendEvent{
+				Type:    EventTypeDone,
+				AgentID: agentID,
+				Data: map[string]any{
+					"step":   e.Step,
+					"reason": e.Reason,
+				},
+			}
+			if e.Reason == "completed" {
+				a.notifyActivity(&Notification{
+					Kind:    NotificationTaskFinished,
+					AgentID: agentID,
+					Title:   "Task finished",
+					Body:    fmt.Sprintf("Agent %s finished after %d steps", agentID, e.Step),
+				})
+			}
+
 		case *types.ControlPermissionRequiredEvent:
 			event = &FrontendEvent{
 				Type:    EventTypeApprovalRequired,
@@ -580,6 +906,41 @@ func (a *App) forwardAgentEvents(ag *agent.Agent, agentID string) {
 					"arguments": e.Call.Arguments,
 				},
 			}
+			a.notifyActivity(&Notification{
+				Kind:    NotificationApprovalRequired,
+				AgentID: agentID,
+				Title:   "Approval required",
+				Body:    fmt.Sprintf("Agent %s wants to run %s", agentID, e.Call.Name),
+				Data:    map[string]any{"call_id": e.Call.ID},
+			})
+
+		case *types.ControlWorkspaceTrustRequiredEvent:
+			event = &FrontendEvent{
+				Type:    EventTypeWorkspaceTrustRequired,
+				AgentID: agentID,
+				Data: map[string]any{
+					"work_dir": e.WorkDir,
+					"call_id":  e.Call.ID,
+					"name":     e.Call.Name,
+				},
+			}
+			a.notifyActivity(&Notification{
+				Kind:    NotificationApprovalRequired,
+				AgentID: agentID,
+				Title:   "Trust this workspace?",
+				Body:    fmt.Sprintf("Agent %s wants to run %s in %s", agentID, e.Call.Name, e.WorkDir),
+				Data:    map[string]any{"work_dir": e.WorkDir},
+			})
+
+		case *types.MonitorTokenUsageEvent:
+			a.recordTokenUsage(agentID, e.TotalTokens)
+
+		case *types.MonitorSessionCostEvent:
+			event = &FrontendEvent{
+				Type:    EventTypeSessionCost,
+				AgentID: agentID,
+				Data:    e,
+			}
 
 		case *types.MonitorErrorEvent:
 			event = &FrontendEvent{
@@ -590,6 +951,12 @@ func (a *App) forwardAgentEvents(ag *agent.Agent, agentID string) {
 					"message":  e.Message,
 				},
 			}
+			a.notifyActivity(&Notification{
+				Kind:    NotificationError,
+				AgentID: agentID,
+				Title:   "Agent error",
+				Body:    e.Message,
+			})
 		}
 
 		if event != nil {
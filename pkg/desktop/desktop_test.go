@@ -119,6 +119,13 @@ func TestMessageTypes(t *testing.T) {
 		{MsgTypeClearHistory, "clear_history"},
 		{MsgTypeSetConfig, "set_config"},
 		{MsgTypeGetConfig, "get_config"},
+		{MsgTypeTrayAction, "tray_action"},
+		{MsgTypeWindowState, "window_state"},
+		{MsgTypeConnectivity, "connectivity"},
+		{MsgTypeUIAction, "ui_action"},
+		{MsgTypeGetCatalog, "get_catalog"},
+		{MsgTypeExportSurface, "export_surface"},
+		{MsgTypeWorkspaceTrust, "workspace_trust"},
 	}
 
 	for _, tt := range tests {
@@ -141,6 +148,12 @@ func TestEventTypes(t *testing.T) {
 		{EventTypeError, "error"},
 		{EventTypeDone, "done"},
 		{EventTypeStatusChange, "status_change"},
+		{EventTypeTrayUpdate, "tray_update"},
+		{EventTypeNotification, "notification"},
+		{EventTypeConnectivity, "connectivity"},
+		{EventTypeUIMessage, "ui_message"},
+		{EventTypeWorkspaceTrustRequired, "workspace_trust_required"},
+		{EventTypeSessionCost, "session_cost"},
 	}
 
 	for _, tt := range tests {
@@ -0,0 +1,370 @@
+package desktop
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/astercloud/aster/pkg/agent"
+	"github.com/astercloud/aster/pkg/session"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+// historyBranchesKey lists the branches left behind by edit_message and regenerate in the session metadata
+const historyBranchesKey = "branches"
+
+// ErrNothingToRewrite is returned when the conversation has no user message to edit or regenerate
+var ErrNothingToRewrite = errors.New("no user message to rewrite")
+
+// EditMessagePayload is the payload for edit_message messages
+type EditMessagePayload struct {
+	// EntryID is the history entry of the user message to replace; defaults to the last one
+	EntryID string `json:"entry_id,omitempty"`
+
+	// Message is the new message text
+	Message string `json:"message"`
+
+	// Attachments are attachment IDs sent with the new message
+	Attachments []string `json:"attachments,omitempty"`
+}
+
+// RegeneratePayload is the payload for regenerate messages
+type RegeneratePayload struct {
+	// EntryID is the history entry of the user message to regenerate from; defaults to the last one
+	EntryID string `json:"entry_id,omitempty"`
+}
+
+// EditMessageRequest is the body of POST /api/edit_message
+type EditMessageRequest struct {
+	AgentID string `json:"agent_id"`
+	EditMessagePayload
+}
+
+// RegenerateRequest is the body of POST /api/regenerate
+type RegenerateRequest struct {
+	AgentID string `json:"agent_id"`
+	RegeneratePayload
+}
+
+// HistoryBranch is a part of a conversation replaced by edit_message or regenerate.
+// Its entries stay in the session but are hidden from the history.
+type HistoryBranch struct {
+	ID string `json:"id"`
+
+	// ParentID is the visible entry the branch continued from; empty at the start of the conversation
+	ParentID string `json:"parent_id,omitempty"`
+
+	// EntryIDs are the replaced entries, oldest first
+	EntryIDs []string `json:"entry_ids"`
+
+	// Reason is "edited" or "regenerated"
+	Reason string `json:"reason"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// EditResult is the response data of edit_message and regenerate
+type EditResult struct {
+	*agent.UndoResult
+
+	// Branch records the replaced entries; nil without a session service
+	Branch *HistoryBranch `json:"branch,omitempty"`
+}
+
+// historyTurn locates a user message in the agent's active session
+type historyTurn struct {
+	sessionID string
+	entry     HistoryEntry
+	parentID  string
+	entryIDs  []string // the message and everything after it
+	turns     int      // user messages from the entry on, i.e. turns to rewind
+}
+
+// handleEditMessage replaces a prior user message and regenerates the
+// conversation from there, reverting the file edits of the replaced turns.
+func (a *App) handleEditMessage(msg *FrontendMessage) (*BackendResponse, error) {
+	var payload EditMessagePayload
+	if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+		return &BackendResponse{
+			ID:      msg.ID,
+			Success: false,
+			Error:   fmt.Sprintf("invalid payload: %v", err),
+		}, nil
+	}
+	message, err := a.withAttachments(payload.Message, payload.Attachments)
+	if err != nil {
+		return &BackendResponse{
+			ID:      msg.ID,
+			Success: false,
+			Error:   err.Error(),
+		}, nil
+	}
+	entry := HistoryEntry{Role: types.RoleUser, Content: payload.Message, Attachments: payload.Attachments}
+	return a.rewriteTurn(msg, payload.EntryID, "edited", &entry, message)
+}
+
+// handleRegenerate discards the reply to a user message, reverting its file
+// edits, and sends the same message again.
+func (a *App) handleRegenerate(msg *FrontendMessage) (*BackendResponse, error) {
+	var payload RegeneratePayload
+	if len(msg.Payload) > 0 {
+		if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+			return &BackendResponse{
+				ID:      msg.ID,
+				Success: false,
+				Error:   fmt.Sprintf("invalid payload: %v", err),
+			}, nil
+		}
+	}
+	return a.rewriteTurn(msg, payload.EntryID, "regenerated", nil, "")
+}
+
+// rewriteTurn rewinds the agent to before the user message entryID, moves the
+// replaced history entries to a branch and starts a new turn. A nil entry
+// resends the original message.
+func (a *App) rewriteTurn(msg *FrontendMessage, entryID, reason string, entry *HistoryEntry, message string) (*BackendResponse, error) {
+	ag, ok := a.GetAgent(msg.AgentID)
+	if !ok {
+		return &BackendResponse{
+			ID:      msg.ID,
+			Success: false,
+			Error:   "agent not found: " + msg.AgentID,
+		}, nil
+	}
+	fail := func(err error) (*BackendResponse, error) {
+		return &BackendResponse{
+			ID:      msg.ID,
+			Success: false,
+			Error:   err.Error(),
+		}, nil
+	}
+
+	ctx := context.Background()
+	turn, err := a.findHistoryTurn(ctx, msg.AgentID, entryID)
+	if errors.Is(err, ErrHistoryNotConfigured) && entryID == "" {
+		turn, err = nil, nil // without history only the last turn can be rewritten
+	}
+	if err != nil {
+		return fail(err)
+	}
+	if entry == nil && turn != nil {
+		// Resend the message as the user wrote it, attachments included
+		entry = &HistoryEntry{Role: types.RoleUser, Content: turn.entry.Content, Attachments: turn.entry.Attachments}
+		if message, err = a.withAttachments(entry.Content, entry.Attachments); err != nil {
+			return fail(err)
+		}
+	}
+
+	n := 1
+	if turn != nil {
+		n = turn.turns
+	}
+	undo, err := ag.RewindTurns(ctx, n)
+	if err != nil {
+		return fail(err)
+	}
+	if entry == nil {
+		message = undo.Prompt
+	}
+
+	result := &EditResult{UndoResult: undo}
+	if turn != nil {
+		if result.Branch, err = a.branchHistory(ctx, msg.AgentID, turn, reason); err != nil {
+			return fail(err)
+		}
+	}
+
+	a.markUnsynced(msg.AgentID)
+	if entry != nil {
+		_ = a.recordHistory(ctx, msg.AgentID, *entry) // History is best effort
+	}
+	go func() {
+		if err := ag.Send(context.Background(), message); err != nil {
+			_ = a.bridge.SendEvent(&FrontendEvent{
+				Type:    EventTypeError,
+				AgentID: msg.AgentID,
+				Data:    map[string]string{"error": err.Error()},
+			})
+		}
+	}()
+
+	return &BackendResponse{
+		ID:      msg.ID,
+		Success: true,
+		Data:    result,
+	}, nil
+}
+
+// findHistoryTurn locates the visible user message entryID (or the last one)
+// in the agent's active session
+func (a *App) findHistoryTurn(ctx context.Context, agentID, entryID string) (*historyTurn, error) {
+	h := a.history
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.service == nil {
+		return nil, ErrHistoryNotConfigured
+	}
+	sessionID, err := a.activeSessionLocked(ctx, agentID, false)
+	if err != nil {
+		return nil, err
+	}
+	if sessionID == "" {
+		return nil, ErrNothingToRewrite
+	}
+	undone, err := a.undoneEventsLocked(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	events, err := h.service.GetEvents(ctx, sessionID, nil)
+	if err != nil {
+		return nil, fmt.Errorf("get events: %w", err)
+	}
+	events = visibleEvents(events, undone)
+
+	start := -1
+	for i := len(events) - 1; i >= 0; i-- {
+		if events[i].Author == "user" && (entryID == "" || events[i].ID == entryID) {
+			start = i
+			break
+		}
+	}
+	if start < 0 {
+		if entryID == "" {
+			return nil, ErrNothingToRewrite
+		}
+		return nil, fmt.Errorf("user message not found: %s", entryID)
+	}
+
+	turn := &historyTurn{sessionID: sessionID, entry: historyEntry(&events[start])}
+	if start > 0 {
+		turn.parentID = events[start-1].ID
+	}
+	for _, evt := range events[start:] {
+		turn.entryIDs = append(turn.entryIDs, evt.ID)
+		if evt.Author == "user" {
+			turn.turns++
+		}
+	}
+	return turn, nil
+}
+
+// branchHistory hides the turn's entries and records them as a branch in the session metadata
+func (a *App) branchHistory(ctx context.Context, agentID string, turn *historyTurn, reason string) (*HistoryBranch, error) {
+	branch := &HistoryBranch{
+		ID:        generateID(),
+		ParentID:  turn.parentID,
+		EntryIDs:  turn.entryIDs,
+		Reason:    reason,
+		CreatedAt: time.Now(),
+	}
+
+	h := a.history
+	h.mu.Lock()
+	sess, err := h.service.Get(ctx, &session.GetRequest{
+		AppName:   historyAppName,
+		UserID:    a.historyUserID(),
+		SessionID: turn.sessionID,
+	})
+	if err == nil {
+		undone := undoneEvents(sess)
+		ids := slices.Sorted(maps.Keys(undone))
+		for _, id := range turn.entryIDs {
+			if !undone[id] {
+				ids = append(ids, id)
+			}
+		}
+		err = h.service.Update(ctx, &session.UpdateRequest{
+			SessionID: turn.sessionID,
+			Metadata: map[string]any{
+				historyUndoneKey:   ids,
+				historyBranchesKey: append(historyBranches(sess), *branch),
+			},
+		})
+	}
+	h.mu.Unlock()
+	if err != nil {
+		return nil, fmt.Errorf("branch history: %w", err)
+	}
+
+	_ = a.bridge.SendEvent(&FrontendEvent{
+		Type:    EventTypeHistoryUpdated,
+		AgentID: agentID,
+		Data:    &HistoryUpdate{SessionID: turn.sessionID, Reason: reason, EntryIDs: turn.entryIDs, Branch: branch},
+	})
+	return branch, nil
+}
+
+// historyBranches returns the branches recorded in the session metadata
+func historyBranches(sess session.Session) []HistoryBranch {
+	raw, ok := sess.Metadata()[historyBranchesKey]
+	if !ok {
+		return nil
+	}
+	if branches, ok := raw.([]HistoryBranch); ok {
+		return slices.Clone(branches)
+	}
+	// Metadata may have round-tripped through JSON
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil
+	}
+	var branches []HistoryBranch
+	_ = json.Unmarshal(data, &branches)
+	return branches
+}
+
+// editRoutes registers the edit_message and regenerate endpoints on an HTTP bridge mux
+func editRoutes(mux *http.ServeMux, handler MessageHandler) {
+	mux.HandleFunc("/api/edit_message", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req EditMessageRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, BackendResponse{
+				Success: false,
+				Error:   err.Error(),
+			})
+			return
+		}
+
+		resp, _ := handler(&FrontendMessage{
+			ID:      generateID(),
+			Type:    MsgTypeEditMessage,
+			AgentID: req.AgentID,
+			Payload: mustMarshal(req.EditMessagePayload),
+		})
+		writeJSON(w, http.StatusOK, resp)
+	})
+
+	mux.HandleFunc("/api/regenerate", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req RegenerateRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, BackendResponse{
+				Success: false,
+				Error:   err.Error(),
+			})
+			return
+		}
+
+		resp, _ := handler(&FrontendMessage{
+			ID:      generateID(),
+			Type:    MsgTypeRegenerate,
+			AgentID: req.AgentID,
+			Payload: mustMarshal(req.RegeneratePayload),
+		})
+		writeJSON(w, http.StatusOK, resp)
+	})
+}
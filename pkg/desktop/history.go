@@ -0,0 +1,435 @@
+package desktop
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/astercloud/aster/pkg/session"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+const (
+	// historyAppName is the session app name used for desktop conversations
+	historyAppName = "aster-desktop"
+
+	// DefaultHistoryPageSize is the page size of get_history when no limit is given
+	DefaultHistoryPageSize = 50
+
+	// MaxHistoryPageSize caps the page size of get_history
+	MaxHistoryPageSize = 500
+
+	// historyArchivedKey marks a session as archived in its metadata
+	historyArchivedKey = "archived"
+)
+
+// ErrHistoryNotConfigured is returned when no session service is set
+var ErrHistoryNotConfigured = errors.New("history requires a session service")
+
+// HistoryPayload is the payload for get_history messages
+type HistoryPayload struct {
+	// SessionID reads an archived session; defaults to the agent's active session
+	SessionID string `json:"session_id,omitempty"`
+
+	// Limit is the page size (defaults to 50, max 500)
+	Limit int `json:"limit,omitempty"`
+
+	// Offset skips the oldest messages
+	Offset int `json:"offset,omitempty"`
+
+	// After continues after the entry with this ID (a page's NextCursor);
+	// Offset is ignored when set
+	After string `json:"after,omitempty"`
+}
+
+// HistoryEntry is a single message in a conversation history
+type HistoryEntry struct {
+	ID          string     `json:"id"`
+	Role        types.Role `json:"role"`
+	Content     string     `json:"content"`
+	Reasoning   string     `json:"reasoning,omitempty"`
+	Attachments []string   `json:"attachments,omitempty"`
+	Timestamp   time.Time  `json:"timestamp"`
+}
+
+// HistoryPage is a page of conversation history, oldest first
+type HistoryPage struct {
+	SessionID string         `json:"session_id,omitempty"`
+	Entries   []HistoryEntry `json:"entries"`
+	Offset    int            `json:"offset"`
+	Limit     int            `json:"limit"`
+	HasMore   bool           `json:"has_more"`
+
+	// NextCursor is passed as After to read the next page; empty on the last page
+	NextCursor string `json:"next_cursor,omitempty"`
+
+	// Branches are the parts of the conversation replaced by edits and regenerations
+	Branches []HistoryBranch `json:"branches,omitempty"`
+}
+
+// HistoryUpdate is the data of a history_updated event
+type HistoryUpdate struct {
+	SessionID string         `json:"session_id"`
+	Reason    string         `json:"reason"` // "message", "cleared", "undone", "edited" or "regenerated"
+	Entry     *HistoryEntry  `json:"entry,omitempty"`
+	EntryIDs  []string       `json:"entry_ids,omitempty"` // entries removed by undo, edit or regenerate
+	Branch    *HistoryBranch `json:"branch,omitempty"`    // where edit or regenerate moved the removed entries
+}
+
+// historyState maps agents to their active session
+type historyState struct {
+	mu      sync.Mutex
+	service session.Service
+	active  map[string]string // agent ID -> session ID
+}
+
+func newHistoryState() *historyState {
+	return &historyState{active: make(map[string]string)}
+}
+
+// SetSessionService sets the session service that stores conversation history.
+// With a persistent service (e.g. session/sqlite) history survives restarts.
+func (a *App) SetSessionService(svc session.Service) {
+	a.history.mu.Lock()
+	defer a.history.mu.Unlock()
+	a.history.service = svc
+	a.history.active = make(map[string]string)
+}
+
+// historyUserID returns the session user ID for this app
+func (a *App) historyUserID() string {
+	if a.config.UserID != "" {
+		return a.config.UserID
+	}
+	return "local"
+}
+
+// activeSessionLocked returns the agent's unarchived session, creating one if create is set.
+// An empty ID without error means the agent has no history yet.
+func (a *App) activeSessionLocked(ctx context.Context, agentID string, create bool) (string, error) {
+	h := a.history
+	if id, ok := h.active[agentID]; ok {
+		return id, nil
+	}
+
+	// Look up a session left over from a previous run
+	const pageSize = 100
+	for offset := 0; ; offset += pageSize {
+		sessions, err := h.service.List(ctx, &session.ListRequest{
+			AppName: historyAppName,
+			UserID:  a.historyUserID(),
+			Limit:   pageSize,
+			Offset:  offset,
+		})
+		if err != nil {
+			return "", fmt.Errorf("list sessions: %w", err)
+		}
+		for _, s := range sessions {
+			sess := *s
+			if sess.AgentID() == agentID && !isArchived(sess.Metadata()) {
+				h.active[agentID] = sess.ID()
+				return sess.ID(), nil
+			}
+		}
+		if len(sessions) < pageSize {
+			break
+		}
+	}
+
+	if !create {
+		return "", nil
+	}
+	sess, err := h.service.Create(ctx, &session.CreateRequest{
+		AppName:  historyAppName,
+		UserID:   a.historyUserID(),
+		AgentID:  agentID,
+		Metadata: map[string]any{"source": "desktop"},
+	})
+	if err != nil {
+		return "", fmt.Errorf("create session: %w", err)
+	}
+	h.active[agentID] = sess.ID()
+	return sess.ID(), nil
+}
+
+func isArchived(metadata map[string]any) bool {
+	archived, _ := metadata[historyArchivedKey].(bool)
+	return archived
+}
+
+// recordHistory appends a message to the agent's active session and notifies the frontend.
+// It is a no-op without a session service.
+func (a *App) recordHistory(ctx context.Context, agentID string, entry HistoryEntry) error {
+	h := a.history
+	h.mu.Lock()
+	if h.service == nil {
+		h.mu.Unlock()
+		return nil
+	}
+	sessionID, err := a.activeSessionLocked(ctx, agentID, true)
+	if err == nil {
+		if entry.ID == "" {
+			entry.ID = generateID()
+		}
+		if entry.Timestamp.IsZero() {
+			entry.Timestamp = time.Now()
+		}
+		err = h.service.AppendEvent(ctx, sessionID, historyEvent(agentID, &entry))
+	}
+	h.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("record history: %w", err)
+	}
+
+	_ = a.bridge.SendEvent(&FrontendEvent{
+		Type:    EventTypeHistoryUpdated,
+		AgentID: agentID,
+		Data:    &HistoryUpdate{SessionID: sessionID, Reason: "message", Entry: &entry},
+	})
+	return nil
+}
+
+// historyEvent converts a history entry to a session event
+func historyEvent(agentID string, entry *HistoryEntry) *session.Event {
+	author := "agent"
+	if entry.Role == types.RoleUser {
+		author = "user"
+	}
+	evt := &session.Event{
+		ID:        entry.ID,
+		Timestamp: entry.Timestamp,
+		AgentID:   agentID,
+		Author:    author,
+		Content:   types.Message{Role: entry.Role, Content: entry.Content},
+		Reasoning: entry.Reasoning,
+	}
+	if len(entry.Attachments) > 0 {
+		evt.Metadata = map[string]any{"attachments": entry.Attachments}
+	}
+	return evt
+}
+
+// historyEntry converts a session event back to a history entry
+func historyEntry(evt *session.Event) HistoryEntry {
+	entry := HistoryEntry{
+		ID:        evt.ID,
+		Role:      evt.Content.Role,
+		Content:   evt.Content.Content,
+		Reasoning: evt.Reasoning,
+		Timestamp: evt.Timestamp,
+	}
+	// Metadata may have round-tripped through JSON
+	if ids, ok := evt.Metadata["attachments"].([]any); ok {
+		for _, id := range ids {
+			if s, ok := id.(string); ok {
+				entry.Attachments = append(entry.Attachments, s)
+			}
+		}
+	} else if ids, ok := evt.Metadata["attachments"].([]string); ok {
+		entry.Attachments = ids
+	}
+	return entry
+}
+
+// History returns a page of an agent's conversation history, oldest first
+func (a *App) History(ctx context.Context, agentID string, req HistoryPayload) (*HistoryPage, error) {
+	limit := req.Limit
+	if limit <= 0 {
+		limit = DefaultHistoryPageSize
+	}
+	limit = min(limit, MaxHistoryPageSize)
+	offset := max(req.Offset, 0)
+	page := &HistoryPage{Entries: []HistoryEntry{}, Offset: offset, Limit: limit}
+
+	h := a.history
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.service == nil {
+		return nil, ErrHistoryNotConfigured
+	}
+
+	sessionID := req.SessionID
+	if sessionID == "" {
+		id, err := a.activeSessionLocked(ctx, agentID, false)
+		if err != nil {
+			return nil, err
+		}
+		if id == "" {
+			return page, nil
+		}
+		sessionID = id
+	}
+	sess, err := h.service.Get(ctx, &session.GetRequest{
+		AppName:   historyAppName,
+		UserID:    a.historyUserID(),
+		SessionID: sessionID,
+	})
+	if err != nil || sess.AgentID() != agentID {
+		return nil, fmt.Errorf("session not found: %s", sessionID)
+	}
+	page.SessionID = sessionID
+	page.Branches = historyBranches(sess)
+
+	// Read events a page at a time so long histories are never loaded whole.
+	// Undone events are filtered before paging so offsets stay stable.
+	undone := undoneEvents(sess)
+	cursor, skip := req.After, offset
+	if cursor != "" {
+		skip = 0
+	}
+	var events []session.Event
+	// Collect one extra event to learn whether another page exists
+	for len(events) <= limit {
+		batch, err := sess.Events().EventsPage(cursor, max(limit+1, session.DefaultEventsPageSize))
+		if errors.Is(err, session.ErrInvalidCursor) {
+			return nil, fmt.Errorf("entry not found: %s", cursor)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("get events: %w", err)
+		}
+		for _, evt := range batch.Events {
+			switch {
+			case undone[evt.ID]:
+			case skip > 0:
+				skip--
+			default:
+				events = append(events, evt)
+			}
+		}
+		if batch.NextCursor == "" {
+			break
+		}
+		cursor = batch.NextCursor
+	}
+	if len(events) > limit {
+		page.HasMore = true
+		events = events[:limit]
+		page.NextCursor = events[limit-1].ID
+	}
+	for i := range events {
+		page.Entries = append(page.Entries, historyEntry(&events[i]))
+	}
+	return page, nil
+}
+
+// ArchiveHistory archives the agent's active session so the next message starts
+// a new one. It returns the archived session ID, or "" if there was no history.
+func (a *App) ArchiveHistory(ctx context.Context, agentID string) (string, error) {
+	h := a.history
+	h.mu.Lock()
+	if h.service == nil {
+		h.mu.Unlock()
+		return "", ErrHistoryNotConfigured
+	}
+	sessionID, err := a.activeSessionLocked(ctx, agentID, false)
+	if err == nil && sessionID != "" {
+		err = h.service.Update(ctx, &session.UpdateRequest{
+			SessionID: sessionID,
+			Metadata: map[string]any{
+				historyArchivedKey: true,
+				"archived_at":      time.Now().Format(time.RFC3339),
+			},
+		})
+		if err == nil {
+			delete(h.active, agentID)
+		}
+	}
+	h.mu.Unlock()
+	if err != nil {
+		return "", fmt.Errorf("archive history: %w", err)
+	}
+	if sessionID == "" {
+		return "", nil
+	}
+
+	_ = a.bridge.SendEvent(&FrontendEvent{
+		Type:    EventTypeHistoryUpdated,
+		AgentID: agentID,
+		Data:    &HistoryUpdate{SessionID: sessionID, Reason: "cleared"},
+	})
+	return sessionID, nil
+}
+
+// historyQuery builds a get_history payload from session_id, limit and offset query parameters
+func historyQuery(r *http.Request) json.RawMessage {
+	q := r.URL.Query()
+	payload := HistoryPayload{SessionID: q.Get("session_id")}
+	payload.Limit, _ = strconv.Atoi(q.Get("limit"))
+	payload.Offset, _ = strconv.Atoi(q.Get("offset"))
+	payload.After = q.Get("after")
+	return mustMarshal(payload)
+}
+
+func (a *App) handleGetHistory(msg *FrontendMessage) (*BackendResponse, error) {
+	if _, ok := a.GetAgent(msg.AgentID); !ok {
+		return &BackendResponse{
+			ID:      msg.ID,
+			Success: false,
+			Error:   "agent not found: " + msg.AgentID,
+		}, nil
+	}
+
+	var payload HistoryPayload
+	if len(msg.Payload) > 0 {
+		if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+			return &BackendResponse{
+				ID:      msg.ID,
+				Success: false,
+				Error:   fmt.Sprintf("invalid payload: %v", err),
+			}, nil
+		}
+	}
+
+	page, err := a.History(context.Background(), msg.AgentID, payload)
+	if errors.Is(err, ErrHistoryNotConfigured) {
+		// Without a session service there is no stored history
+		return &BackendResponse{
+			ID:      msg.ID,
+			Success: true,
+			Data:    &HistoryPage{Entries: []HistoryEntry{}, Limit: DefaultHistoryPageSize},
+		}, nil
+	}
+	if err != nil {
+		return &BackendResponse{
+			ID:      msg.ID,
+			Success: false,
+			Error:   err.Error(),
+		}, nil
+	}
+
+	return &BackendResponse{
+		ID:      msg.ID,
+		Success: true,
+		Data:    page,
+	}, nil
+}
+
+func (a *App) handleClearHistory(msg *FrontendMessage) (*BackendResponse, error) {
+	if _, ok := a.GetAgent(msg.AgentID); !ok {
+		return &BackendResponse{
+			ID:      msg.ID,
+			Success: false,
+			Error:   "agent not found: " + msg.AgentID,
+		}, nil
+	}
+
+	archived, err := a.ArchiveHistory(context.Background(), msg.AgentID)
+	if err != nil && !errors.Is(err, ErrHistoryNotConfigured) {
+		return &BackendResponse{
+			ID:      msg.ID,
+			Success: false,
+			Error:   err.Error(),
+		}, nil
+	}
+
+	return &BackendResponse{
+		ID:      msg.ID,
+		Success: true,
+		Data:    map[string]string{"archived_session_id": archived},
+	}, nil
+}
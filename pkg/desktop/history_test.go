@@ -0,0 +1,329 @@
+package desktop
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/astercloud/aster/pkg/session"
+	"github.com/astercloud/aster/pkg/session/sqlite"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+func recordTestHistory(t *testing.T, app *App, agentID string, contents ...string) {
+	t.Helper()
+	for i, content := range contents {
+		role := types.RoleUser
+		if i%2 == 1 {
+			role = types.RoleAssistant
+		}
+		if err := app.recordHistory(context.Background(), agentID, HistoryEntry{Role: role, Content: content}); err != nil {
+			t.Fatalf("recordHistory() error = %v", err)
+		}
+	}
+}
+
+func TestHistoryPersistsAcrossRestarts(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "sessions.db")
+	ctx := context.Background()
+
+	svc, err := sqlite.New(dbPath)
+	if err != nil {
+		t.Fatalf("sqlite.New() error = %v", err)
+	}
+	app, bridge := newWailsTestApp(t, nil)
+	app.SetSessionService(svc)
+	recordTestHistory(t, app, "agent-1", "hello", "hi there")
+
+	events := drainEvents(bridge)
+	if len(events) != 2 || events[0].Type != EventTypeHistoryUpdated {
+		t.Fatalf("events = %+v, want two history_updated", events)
+	}
+	if u := events[1].Data.(*HistoryUpdate); u.Reason != "message" || u.Entry.Content != "hi there" {
+		t.Errorf("history update = %+v", u)
+	}
+	_ = svc.Close()
+
+	// A new app on the same database continues the same session
+	svc, err = sqlite.New(dbPath)
+	if err != nil {
+		t.Fatalf("sqlite.New() error = %v", err)
+	}
+	defer func() { _ = svc.Close() }()
+	restarted, _ := newWailsTestApp(t, nil)
+	restarted.SetSessionService(svc)
+
+	page, err := restarted.History(ctx, "agent-1", HistoryPayload{})
+	if err != nil {
+		t.Fatalf("History() error = %v", err)
+	}
+	if len(page.Entries) != 2 || page.Entries[0].Role != types.RoleUser || page.Entries[1].Content != "hi there" {
+		t.Errorf("entries after restart = %+v", page.Entries)
+	}
+
+	recordTestHistory(t, restarted, "agent-1", "again")
+	page, _ = restarted.History(ctx, "agent-1", HistoryPayload{})
+	if len(page.Entries) != 3 {
+		t.Errorf("got %d entries, want 3 in the resumed session", len(page.Entries))
+	}
+}
+
+func TestHistoryPagination(t *testing.T) {
+	app, _ := newWailsTestApp(t, nil)
+	app.SetSessionService(session.NewInMemoryService())
+	recordTestHistory(t, app, "agent-1", "1", "2", "3", "4", "5")
+
+	tests := []struct {
+		name     string
+		req      HistoryPayload
+		want     []string
+		wantMore bool
+	}{
+		{"first page", HistoryPayload{Limit: 2}, []string{"1", "2"}, true},
+		{"middle page", HistoryPayload{Limit: 2, Offset: 2}, []string{"3", "4"}, true},
+		{"last page", HistoryPayload{Limit: 2, Offset: 4}, []string{"5"}, false},
+		{"default limit", HistoryPayload{}, []string{"1", "2", "3", "4", "5"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := app.History(context.Background(), "agent-1", tt.req)
+			if err != nil {
+				t.Fatalf("History() error = %v", err)
+			}
+			var got []string
+			for _, e := range page.Entries {
+				got = append(got, e.Content)
+			}
+			if len(got) != len(tt.want) || page.HasMore != tt.wantMore {
+				t.Fatalf("entries = %v, has_more = %v; want %v, %v", got, page.HasMore, tt.want, tt.wantMore)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("entries = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestClearHistoryArchivesSession(t *testing.T) {
+	app, bridge := newMultiWindowApp(t)
+	app.SetSessionService(session.NewInMemoryService())
+	summary := createTestAgent(t, bridge, "w1", "coder")
+	recordTestHistory(t, app, summary.ID, "old question", "old answer")
+	drainEvents(bridge)
+
+	resp, _ := bridge.ClearHistory(summary.ID)
+	if !resp.Success {
+		t.Fatalf("ClearHistory() failed: %s", resp.Error)
+	}
+	archived := resp.Data.(map[string]string)["archived_session_id"]
+	if archived == "" {
+		t.Fatal("no archived session ID returned")
+	}
+	events := drainEvents(bridge)
+	if len(events) != 1 || events[0].Data.(*HistoryUpdate).Reason != "cleared" {
+		t.Errorf("events = %+v, want one cleared update", events)
+	}
+
+	resp, _ = bridge.GetHistory(summary.ID)
+	if page := resp.Data.(*HistoryPage); len(page.Entries) != 0 {
+		t.Errorf("active history after clear = %+v, want empty", page.Entries)
+	}
+
+	// Archived sessions stay readable
+	resp, _ = bridge.GetHistoryPage(summary.ID, HistoryPayload{SessionID: archived})
+	if page := resp.Data.(*HistoryPage); len(page.Entries) != 2 {
+		t.Errorf("archived history = %+v, want 2 entries", page.Entries)
+	}
+	resp, _ = bridge.GetHistoryPage("other", HistoryPayload{SessionID: archived})
+	if resp.Success {
+		t.Error("expected failure reading history for an unknown agent")
+	}
+
+	recordTestHistory(t, app, summary.ID, "new question")
+	page, _ := app.History(context.Background(), summary.ID, HistoryPayload{})
+	if len(page.Entries) != 1 || page.SessionID == archived {
+		t.Errorf("new session = %+v", page)
+	}
+}
+
+func TestAssistantTextRecordedToHistory(t *testing.T) {
+	app, bridge := newMultiWindowApp(t)
+	app.SetSessionService(session.NewInMemoryService())
+	summary := createTestAgent(t, bridge, "w1", "coder")
+	ag, _ := app.GetAgent(summary.ID)
+
+	ag.GetEventBus().EmitProgress(&types.ProgressReasoningEvent{Step: 1, Delta: "thinking"})
+	ag.GetEventBus().EmitProgress(&types.ProgressTextChunkEndEvent{Step: 1, Text: "answer"})
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		page, err := app.History(context.Background(), summary.ID, HistoryPayload{})
+		if err != nil {
+			t.Fatalf("History() error = %v", err)
+		}
+		if len(page.Entries) == 1 {
+			e := page.Entries[0]
+			if e.Role != types.RoleAssistant || e.Content != "answer" || e.Reasoning != "thinking" {
+				t.Errorf("entry = %+v", e)
+			}
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("assistant text not recorded")
+}
+
+func TestHistoryWithoutSessionService(t *testing.T) {
+	_, bridge := newMultiWindowApp(t)
+	summary := createTestAgent(t, bridge, "w1", "coder")
+
+	resp, _ := bridge.GetHistory(summary.ID)
+	if !resp.Success || len(resp.Data.(*HistoryPage).Entries) != 0 {
+		t.Errorf("GetHistory() = %+v", resp)
+	}
+	if resp, _ := bridge.ClearHistory(summary.ID); !resp.Success {
+		t.Errorf("ClearHistory() failed: %s", resp.Error)
+	}
+}
+
+func TestHistoryCursor(t *testing.T) {
+	ctx := context.Background()
+	app, _ := newWailsTestApp(t, nil)
+	app.SetSessionService(session.NewInMemoryService())
+	recordTestHistory(t, app, "agent-1", "1", "2", "3", "4", "5")
+
+	var got []string
+	req := HistoryPayload{Limit: 2}
+	for pages := 0; pages < 5; pages++ {
+		page, err := app.History(ctx, "agent-1", req)
+		if err != nil {
+			t.Fatalf("History() error = %v", err)
+		}
+		for _, e := range page.Entries {
+			got = append(got, e.Content)
+		}
+		if page.HasMore != (page.NextCursor != "") {
+			t.Fatalf("has_more = %v with next_cursor %q", page.HasMore, page.NextCursor)
+		}
+		if !page.HasMore {
+			break
+		}
+		// Offset is ignored once a cursor is given
+		req = HistoryPayload{Limit: 2, Offset: 100, After: page.NextCursor}
+	}
+	if strings.Join(got, ",") != "1,2,3,4,5" {
+		t.Errorf("entries = %v", got)
+	}
+
+	if _, err := app.History(ctx, "agent-1", HistoryPayload{After: "no-such-entry"}); err == nil {
+		t.Error("History() with unknown cursor should fail")
+	}
+}
+
+func TestHistoryQuery(t *testing.T) {
+	r := httptest.NewRequest("GET", "/api/history?agent_id=a&session_id=s1&limit=10&offset=20&after=evt-1", nil)
+	var payload HistoryPayload
+	if err := json.Unmarshal(historyQuery(r), &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.SessionID != "s1" || payload.Limit != 10 || payload.Offset != 20 || payload.After != "evt-1" {
+		t.Errorf("payload = %+v", payload)
+	}
+}
+
+func TestUndoHistoryHidesLastTurn(t *testing.T) {
+	ctx := context.Background()
+	app, bridge := newWailsTestApp(t, nil)
+	app.SetSessionService(session.NewInMemoryService())
+	recordTestHistory(t, app, "agent-1", "first", "answer 1", "second", "answer 2")
+	drainEvents(bridge)
+
+	if err := app.undoHistory(ctx, "agent-1"); err != nil {
+		t.Fatalf("undoHistory() error = %v", err)
+	}
+	events := drainEvents(bridge)
+	if len(events) != 1 {
+		t.Fatalf("events = %+v, want one history_updated", events)
+	}
+	if u := events[0].Data.(*HistoryUpdate); u.Reason != "undone" || len(u.EntryIDs) != 2 {
+		t.Errorf("history update = %+v", u)
+	}
+
+	page, err := app.History(ctx, "agent-1", HistoryPayload{Limit: 1, Offset: 1})
+	if err != nil {
+		t.Fatalf("History() error = %v", err)
+	}
+	if len(page.Entries) != 1 || page.Entries[0].Content != "answer 1" || page.HasMore {
+		t.Errorf("page after undo = %+v", page)
+	}
+
+	// A second undo hides the previous turn; a third has nothing left to hide
+	if err := app.undoHistory(ctx, "agent-1"); err != nil {
+		t.Fatalf("undoHistory() error = %v", err)
+	}
+	if err := app.undoHistory(ctx, "agent-1"); err != nil {
+		t.Fatalf("undoHistory() error = %v", err)
+	}
+	recordTestHistory(t, app, "agent-1", "third")
+	page, _ = app.History(ctx, "agent-1", HistoryPayload{})
+	if len(page.Entries) != 1 || page.Entries[0].Content != "third" {
+		t.Errorf("entries = %+v, want only the new turn", page.Entries)
+	}
+}
+
+func TestBranchHistory(t *testing.T) {
+	ctx := context.Background()
+	svc, err := sqlite.New(filepath.Join(t.TempDir(), "sessions.db"))
+	if err != nil {
+		t.Fatalf("sqlite.New() error = %v", err)
+	}
+	defer func() { _ = svc.Close() }()
+	app, bridge := newWailsTestApp(t, nil)
+	app.SetSessionService(svc)
+	recordTestHistory(t, app, "agent-1", "first", "answer 1", "second", "answer 2")
+	page, _ := app.History(ctx, "agent-1", HistoryPayload{})
+	drainEvents(bridge)
+
+	if _, err := app.findHistoryTurn(ctx, "agent-1", page.Entries[1].ID); err == nil {
+		t.Error("findHistoryTurn() on an assistant entry should fail")
+	}
+	last, err := app.findHistoryTurn(ctx, "agent-1", "")
+	if err != nil || last.turns != 1 || last.entry.Content != "second" || last.parentID != page.Entries[1].ID {
+		t.Fatalf("last turn = %+v, %v", last, err)
+	}
+
+	// Editing the first message rewinds both turns and keeps them as a branch
+	turn, err := app.findHistoryTurn(ctx, "agent-1", page.Entries[0].ID)
+	if err != nil || turn.turns != 2 || len(turn.entryIDs) != 4 || turn.parentID != "" {
+		t.Fatalf("turn = %+v, %v", turn, err)
+	}
+	branch, err := app.branchHistory(ctx, "agent-1", turn, "edited")
+	if err != nil {
+		t.Fatalf("branchHistory() error = %v", err)
+	}
+	events := drainEvents(bridge)
+	if len(events) != 1 {
+		t.Fatalf("events = %+v, want one history_updated", events)
+	}
+	if u := events[0].Data.(*HistoryUpdate); u.Reason != "edited" || len(u.EntryIDs) != 4 || u.Branch.ID != branch.ID {
+		t.Errorf("history update = %+v", u)
+	}
+
+	recordTestHistory(t, app, "agent-1", "first, edited")
+	page, err = app.History(ctx, "agent-1", HistoryPayload{})
+	if err != nil {
+		t.Fatalf("History() error = %v", err)
+	}
+	if len(page.Entries) != 1 || page.Entries[0].Content != "first, edited" {
+		t.Errorf("entries = %+v, want only the edited message", page.Entries)
+	}
+	if len(page.Branches) != 1 || page.Branches[0].ID != branch.ID || len(page.Branches[0].EntryIDs) != 4 || page.Branches[0].Reason != "edited" {
+		t.Errorf("branches = %+v", page.Branches)
+	}
+}
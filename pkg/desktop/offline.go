@@ -0,0 +1,687 @@
+package desktop
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/astercloud/aster/pkg/agent"
+	"github.com/astercloud/aster/pkg/httpclient"
+	"github.com/astercloud/aster/pkg/identity"
+	"github.com/astercloud/aster/pkg/store"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+const (
+	// DefaultProbeURL is checked to detect connectivity when OfflineConfig.ProbeURL is empty
+	DefaultProbeURL = "https://api.anthropic.com"
+
+	// DefaultProbeInterval is the default connectivity check interval
+	DefaultProbeInterval = 30 * time.Second
+
+	// DefaultProbeTimeout is the default timeout of a single connectivity check
+	DefaultProbeTimeout = 5 * time.Second
+
+	// DefaultFailureThreshold is the number of failed checks before switching to offline mode
+	DefaultFailureThreshold = 2
+
+	// DefaultOutboxSize is the default number of queued outbound items kept while offline
+	DefaultOutboxSize = 1000
+)
+
+// OfflineConfig enables offline mode: connectivity monitoring, local model
+// fallback, queued outbound delivery and session reconciliation
+type OfflineConfig struct {
+	// ProbeURL is requested to detect connectivity; any HTTP response counts as online
+	ProbeURL string `json:"probe_url,omitempty"`
+
+	// ProbeInterval is the connectivity check interval (defaults to 30s)
+	ProbeInterval time.Duration `json:"probe_interval,omitempty"`
+
+	// ProbeTimeout bounds a single connectivity check (defaults to 5s)
+	ProbeTimeout time.Duration `json:"probe_timeout,omitempty"`
+
+	// FailureThreshold is the number of consecutive failed checks before going offline (defaults to 2)
+	FailureThreshold int `json:"failure_threshold,omitempty"`
+
+	// LocalModel is used by agents while offline (defaults to ollama/llama3.2)
+	LocalModel *types.ModelConfig `json:"local_model,omitempty"`
+
+	// MaxQueueSize caps queued outbound items; the oldest are dropped first (defaults to 1000)
+	MaxQueueSize int `json:"max_queue_size,omitempty"`
+}
+
+// ConnectivityProbe reports whether remote services are reachable
+type ConnectivityProbe func(ctx context.Context) error
+
+// OutboundItem is a telemetry or webhook payload delivered to a remote endpoint
+type OutboundItem struct {
+	ID   string `json:"id"`
+	Kind string `json:"kind"` // e.g. "telemetry", "webhook"
+	// AgentID is the registered agent emitting the item. When that agent has
+	// an identity, the body is signed and the signature sent in the
+	// identity.HeaderSignature header.
+	AgentID  string            `json:"agent_id,omitempty"`
+	URL      string            `json:"url"`
+	Headers  map[string]string `json:"headers,omitempty"`
+	Body     json.RawMessage   `json:"body,omitempty"`
+	Created  time.Time         `json:"created"`
+	Attempts int               `json:"attempts,omitempty"`
+}
+
+// OutboundSender delivers an outbound item
+type OutboundSender func(ctx context.Context, item *OutboundItem) error
+
+// SessionSyncer pushes an agent's local session state to a remote backend
+type SessionSyncer interface {
+	SyncSession(ctx context.Context, agentID string) error
+}
+
+// ConnectivityPayload is the payload for connectivity messages.
+// Online is set when the frontend reports a browser online/offline change.
+type ConnectivityPayload struct {
+	Online *bool `json:"online,omitempty"`
+}
+
+// ConnectivityStatus describes the current connectivity state
+type ConnectivityStatus struct {
+	Online     bool      `json:"online"`
+	Since      time.Time `json:"since"`
+	LocalModel string    `json:"local_model,omitempty"` // set while offline
+	Queued     int       `json:"queued"`
+	Unsynced   []string  `json:"unsynced,omitempty"` // agents with sessions changed while offline
+
+	// SyncErrors holds per-agent reconciliation failures from the last reconnect
+	SyncErrors map[string]string `json:"sync_errors,omitempty"`
+}
+
+// offlineState tracks connectivity and what has to be reconciled on reconnect
+type offlineState struct {
+	mu         sync.Mutex
+	online     bool
+	since      time.Time
+	failures   int
+	saved      map[string]*types.ModelConfig // agent ID -> model used before going offline
+	dirty      map[string]struct{}
+	syncErrors map[string]string
+
+	probe  ConnectivityProbe
+	sender OutboundSender
+	syncer SessionSyncer
+	outbox *outbox
+	cancel context.CancelFunc
+
+	// transition serializes online/offline switches
+	transition sync.Mutex
+}
+
+func newOfflineState(cfg *AppConfig) *offlineState {
+	size := DefaultOutboxSize
+	if cfg.Offline != nil && cfg.Offline.MaxQueueSize > 0 {
+		size = cfg.Offline.MaxQueueSize
+	}
+	return &offlineState{
+		online: true,
+		since:  time.Now(),
+		saved:  make(map[string]*types.ModelConfig),
+		dirty:  make(map[string]struct{}),
+		sender: httpSend,
+		outbox: &outbox{
+			path:  filepath.Join(cfg.DataDir, "offline", "outbox.json"),
+			limit: size,
+		},
+	}
+}
+
+// localModel returns the model used while offline
+func (a *App) localModel() *types.ModelConfig {
+	if cfg := a.config.Offline; cfg != nil && cfg.LocalModel != nil {
+		return cfg.LocalModel
+	}
+	return &types.ModelConfig{Provider: "ollama", Model: "llama3.2"}
+}
+
+// SetConnectivityProbe replaces the default HTTP connectivity check
+func (a *App) SetConnectivityProbe(probe ConnectivityProbe) {
+	a.offline.mu.Lock()
+	defer a.offline.mu.Unlock()
+	a.offline.probe = probe
+}
+
+// SetOutboundSender replaces the default HTTP POST delivery of outbound items
+func (a *App) SetOutboundSender(sender OutboundSender) {
+	a.offline.mu.Lock()
+	defer a.offline.mu.Unlock()
+	a.offline.sender = sender
+}
+
+// SetSessionSyncer sets the backend that sessions are reconciled to after reconnecting
+func (a *App) SetSessionSyncer(syncer SessionSyncer) {
+	a.offline.mu.Lock()
+	defer a.offline.mu.Unlock()
+	a.offline.syncer = syncer
+}
+
+// Online reports whether the app currently considers remote services reachable
+func (a *App) Online() bool {
+	a.offline.mu.Lock()
+	defer a.offline.mu.Unlock()
+	return a.offline.online
+}
+
+// ConnectivityStatus returns the current connectivity state
+func (a *App) ConnectivityStatus() *ConnectivityStatus {
+	queued := a.offline.outbox.len()
+
+	s := a.offline
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status := &ConnectivityStatus{
+		Online: s.online,
+		Since:  s.since,
+		Queued: queued,
+	}
+	if !s.online {
+		cfg := a.localModel()
+		status.LocalModel = cfg.Provider + "/" + cfg.Model
+	}
+	for id := range s.dirty {
+		status.Unsynced = append(status.Unsynced, id)
+	}
+	slices.Sort(status.Unsynced)
+	if len(s.syncErrors) > 0 {
+		status.SyncErrors = maps.Clone(s.syncErrors)
+	}
+	return status
+}
+
+// SetOnline switches between online and offline mode.
+// Going offline moves agents to the local model; coming back restores their
+// models, flushes queued outbound items and reconciles sessions changed offline.
+func (a *App) SetOnline(ctx context.Context, online bool) {
+	s := a.offline
+	s.transition.Lock()
+	defer s.transition.Unlock()
+
+	s.mu.Lock()
+	if s.online == online {
+		if online {
+			s.failures = 0
+		}
+		s.mu.Unlock()
+		return
+	}
+	s.online = online
+	s.since = time.Now()
+	s.failures = 0
+	s.mu.Unlock()
+
+	if online {
+		a.restoreRemoteModels(ctx)
+		_, _ = a.FlushOutbox(ctx)
+		a.reconcileSessions(ctx)
+	} else {
+		a.agentsMu.RLock()
+		agents := maps.Clone(a.agents)
+		a.agentsMu.RUnlock()
+		for id, ag := range agents {
+			a.useLocalModel(ctx, id, ag)
+		}
+		a.notifyActivity(&Notification{
+			Kind:  NotificationInfo,
+			Title: "Working offline",
+			Body:  "Agents switched to the local model; remote sync resumes when the connection is back",
+		})
+	}
+
+	_ = a.bridge.SendEvent(&FrontendEvent{
+		Type: EventTypeConnectivity,
+		Data: a.ConnectivityStatus(),
+	})
+}
+
+// useLocalModel switches an agent to the local model, remembering its remote model
+func (a *App) useLocalModel(ctx context.Context, id string, ag *agent.Agent) {
+	current := ag.CurrentModel()
+
+	a.offline.mu.Lock()
+	if _, ok := a.offline.saved[id]; ok {
+		a.offline.mu.Unlock()
+		return
+	}
+	a.offline.saved[id] = current
+	a.offline.mu.Unlock()
+
+	if err := ag.SwitchModelConfig(ctx, a.localModel(), "offline"); err != nil {
+		a.offline.mu.Lock()
+		delete(a.offline.saved, id)
+		a.offline.mu.Unlock()
+		_ = a.bridge.SendEvent(&FrontendEvent{
+			Type:    EventTypeError,
+			AgentID: id,
+			Data:    map[string]any{"error": fmt.Sprintf("switch to local model: %v", err)},
+		})
+	}
+}
+
+// restoreRemoteModels switches agents back to the models they used before going offline
+func (a *App) restoreRemoteModels(ctx context.Context) {
+	a.offline.mu.Lock()
+	saved := a.offline.saved
+	a.offline.saved = make(map[string]*types.ModelConfig)
+	a.offline.mu.Unlock()
+
+	for id, cfg := range saved {
+		ag, ok := a.GetAgent(id)
+		if !ok || cfg == nil {
+			continue
+		}
+		if err := ag.SwitchModelConfig(ctx, cfg, "online"); err != nil {
+			_ = a.bridge.SendEvent(&FrontendEvent{
+				Type:    EventTypeError,
+				AgentID: id,
+				Data:    map[string]any{"error": fmt.Sprintf("restore remote model: %v", err)},
+			})
+		}
+	}
+}
+
+// reconcileSessions pushes sessions changed while offline to the session syncer.
+// Agents that fail stay marked so the next reconnect retries them.
+func (a *App) reconcileSessions(ctx context.Context) {
+	s := a.offline
+	s.mu.Lock()
+	syncer := s.syncer
+	ids := make([]string, 0, len(s.dirty))
+	for id := range s.dirty {
+		ids = append(ids, id)
+	}
+	s.mu.Unlock()
+	if syncer == nil {
+		return
+	}
+
+	errs := make(map[string]string)
+	for _, id := range ids {
+		if err := syncer.SyncSession(ctx, id); err != nil {
+			errs[id] = err.Error()
+			continue
+		}
+		s.mu.Lock()
+		delete(s.dirty, id)
+		s.mu.Unlock()
+	}
+
+	s.mu.Lock()
+	s.syncErrors = errs
+	s.mu.Unlock()
+}
+
+// markUnsynced records that an agent's session changed while offline
+func (a *App) markUnsynced(agentID string) {
+	a.offline.mu.Lock()
+	defer a.offline.mu.Unlock()
+	if !a.offline.online {
+		a.offline.dirty[agentID] = struct{}{}
+	}
+}
+
+// forgetAgent drops offline bookkeeping for an unregistered agent
+func (a *App) forgetAgent(agentID string) {
+	a.offline.mu.Lock()
+	defer a.offline.mu.Unlock()
+	delete(a.offline.saved, agentID)
+	delete(a.offline.dirty, agentID)
+}
+
+// Deliver sends an outbound item now when online, or queues it until the
+// connection is back. Items that fail to send are queued as well.
+func (a *App) Deliver(ctx context.Context, item *OutboundItem) error {
+	if item.URL == "" {
+		return errors.New("outbound item URL is required")
+	}
+	if item.ID == "" {
+		item.ID = generateID()
+	}
+	if item.Created.IsZero() {
+		item.Created = time.Now()
+	}
+	a.signOutbound(item)
+
+	a.offline.mu.Lock()
+	online, sender := a.offline.online, a.offline.sender
+	a.offline.mu.Unlock()
+
+	if online {
+		item.Attempts++
+		if err := sender(ctx, item); err == nil {
+			return nil
+		}
+	}
+	return a.offline.outbox.push(item)
+}
+
+// signOutbound signs the item body with its agent's identity. Signing happens
+// before queueing, so items delivered later still carry the original signature.
+func (a *App) signOutbound(item *OutboundItem) {
+	if item.AgentID == "" {
+		return
+	}
+	ag, ok := a.GetAgent(item.AgentID)
+	if !ok {
+		return
+	}
+	sig := ag.Sign(identity.KindWebhook, item.Body)
+	if sig == nil {
+		return
+	}
+	if item.Headers == nil {
+		item.Headers = make(map[string]string)
+	}
+	item.Headers[identity.HeaderSignature] = sig.EncodeHeader()
+}
+
+// FlushOutbox delivers queued outbound items in order, stopping at the first failure
+func (a *App) FlushOutbox(ctx context.Context) (int, error) {
+	a.offline.mu.Lock()
+	sender := a.offline.sender
+	a.offline.mu.Unlock()
+	return a.offline.outbox.flush(ctx, sender)
+}
+
+// startConnectivityMonitor probes connectivity in the background until Stop
+func (a *App) startConnectivityMonitor(ctx context.Context) {
+	cfg := a.config.Offline
+	if cfg == nil {
+		return
+	}
+	interval := cfg.ProbeInterval
+	if interval <= 0 {
+		interval = DefaultProbeInterval
+	}
+
+	ctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	a.offline.mu.Lock()
+	if a.offline.cancel != nil {
+		a.offline.cancel()
+	}
+	a.offline.cancel = cancel
+	a.offline.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			a.checkConnectivity(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// stopConnectivityMonitor stops the background probe
+func (a *App) stopConnectivityMonitor() {
+	a.offline.mu.Lock()
+	defer a.offline.mu.Unlock()
+	if a.offline.cancel != nil {
+		a.offline.cancel()
+		a.offline.cancel = nil
+	}
+}
+
+// checkConnectivity runs one probe and switches mode once the failure threshold is reached
+func (a *App) checkConnectivity(ctx context.Context) {
+	cfg := a.config.Offline
+	timeout := cfg.ProbeTimeout
+	if timeout <= 0 {
+		timeout = DefaultProbeTimeout
+	}
+	threshold := cfg.FailureThreshold
+	if threshold <= 0 {
+		threshold = DefaultFailureThreshold
+	}
+
+	a.offline.mu.Lock()
+	probe := a.offline.probe
+	a.offline.mu.Unlock()
+	if probe == nil {
+		probe = httpProbe(cfg.ProbeURL)
+	}
+
+	probeCtx, cancel := context.WithTimeout(ctx, timeout)
+	err := probe(probeCtx)
+	cancel()
+	if ctx.Err() != nil {
+		return
+	}
+
+	if err == nil {
+		a.SetOnline(ctx, true)
+		return
+	}
+
+	a.offline.mu.Lock()
+	a.offline.failures++
+	reached := a.offline.online && a.offline.failures >= threshold
+	a.offline.mu.Unlock()
+	if reached {
+		a.SetOnline(ctx, false)
+	}
+}
+
+func (a *App) handleConnectivity(msg *FrontendMessage) (*BackendResponse, error) {
+	var payload ConnectivityPayload
+	if len(msg.Payload) > 0 {
+		if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+			return &BackendResponse{
+				ID:      msg.ID,
+				Success: false,
+				Error:   fmt.Sprintf("invalid payload: %v", err),
+			}, nil
+		}
+	}
+
+	if payload.Online != nil {
+		a.SetOnline(context.Background(), *payload.Online)
+	}
+
+	return &BackendResponse{
+		ID:      msg.ID,
+		Success: true,
+		Data:    a.ConnectivityStatus(),
+	}, nil
+}
+
+// connectivityRoutes registers the connectivity endpoint on an HTTP bridge mux
+func connectivityRoutes(mux *http.ServeMux, handler MessageHandler) {
+	report := shellMessageHandler(handler, MsgTypeConnectivity, func() any { return &ConnectivityPayload{} })
+	mux.HandleFunc("/api/connectivity", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			resp, _ := handler(&FrontendMessage{ID: generateID(), Type: MsgTypeConnectivity})
+			writeJSON(w, http.StatusOK, resp)
+			return
+		}
+		report(w, r)
+	})
+}
+
+// httpProbe treats any HTTP response from url as connectivity
+func httpProbe(url string) ConnectivityProbe {
+	if url == "" {
+		url = DefaultProbeURL
+	}
+	return func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := httpclient.New(0).Do(req)
+		if err != nil {
+			return err
+		}
+		_ = resp.Body.Close()
+		return nil
+	}
+}
+
+// httpSend POSTs the item body as JSON
+func httpSend(ctx context.Context, item *OutboundItem) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, item.URL, bytes.NewReader(item.Body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range item.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := httpclient.New(0).Do(req)
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("deliver %s: status %d", item.Kind, resp.StatusCode)
+	}
+	return nil
+}
+
+// outbox is a file-backed FIFO of outbound items
+type outbox struct {
+	mu     sync.Mutex
+	path   string
+	limit  int
+	items  []*OutboundItem
+	loaded bool
+}
+
+// loadLocked reads queued items left over from a previous run
+func (o *outbox) loadLocked() {
+	if o.loaded {
+		return
+	}
+	o.loaded = true
+	data, err := os.ReadFile(o.path)
+	if err != nil {
+		return
+	}
+	_ = json.Unmarshal(data, &o.items) // A corrupt outbox starts empty
+}
+
+func (o *outbox) saveLocked() error {
+	if err := os.MkdirAll(filepath.Dir(o.path), 0o755); err != nil {
+		return fmt.Errorf("create outbox dir: %w", err)
+	}
+	data, err := json.Marshal(o.items)
+	if err != nil {
+		return err
+	}
+	tmp := o.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("write outbox: %w", err)
+	}
+	return os.Rename(tmp, o.path)
+}
+
+func (o *outbox) push(item *OutboundItem) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.loadLocked()
+
+	o.items = append(o.items, item)
+	if over := len(o.items) - o.limit; over > 0 {
+		o.items = slices.Delete(o.items, 0, over)
+	}
+	return o.saveLocked()
+}
+
+func (o *outbox) len() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.loadLocked()
+	return len(o.items)
+}
+
+// flush sends a snapshot of the queue without holding the lock, so new items
+// can be queued meanwhile, then drops the delivered ones
+func (o *outbox) flush(ctx context.Context, send OutboundSender) (int, error) {
+	o.mu.Lock()
+	o.loadLocked()
+	pending := slices.Clone(o.items)
+	o.mu.Unlock()
+
+	sent := make(map[string]bool, len(pending))
+	var sendErr error
+	for _, item := range pending {
+		item.Attempts++
+		if err := send(ctx, item); err != nil {
+			sendErr = err
+			break
+		}
+		sent[item.ID] = true
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if len(pending) == 0 {
+		return 0, nil
+	}
+	o.items = slices.DeleteFunc(o.items, func(item *OutboundItem) bool { return sent[item.ID] })
+	if err := o.saveLocked(); err != nil && sendErr == nil {
+		sendErr = err
+	}
+	return len(sent), sendErr
+}
+
+// storeSyncer copies session state from a local store to a remote one
+type storeSyncer struct {
+	local  store.Store
+	remote store.Store
+}
+
+// NewStoreSyncer returns a SessionSyncer that copies messages, tool call
+// records and agent info from local to remote
+func NewStoreSyncer(local, remote store.Store) SessionSyncer {
+	return &storeSyncer{local: local, remote: remote}
+}
+
+// SyncSession implements SessionSyncer
+func (s *storeSyncer) SyncSession(ctx context.Context, agentID string) error {
+	messages, err := s.local.LoadMessages(ctx, agentID)
+	if err != nil {
+		return fmt.Errorf("load messages: %w", err)
+	}
+	if err := s.remote.SaveMessages(ctx, agentID, messages); err != nil {
+		return fmt.Errorf("save messages: %w", err)
+	}
+
+	records, err := s.local.LoadToolCallRecords(ctx, agentID)
+	if err != nil {
+		return fmt.Errorf("load tool call records: %w", err)
+	}
+	if err := s.remote.SaveToolCallRecords(ctx, agentID, records); err != nil {
+		return fmt.Errorf("save tool call records: %w", err)
+	}
+
+	// Agents that never persisted info have nothing more to sync
+	info, err := s.local.LoadInfo(ctx, agentID)
+	if err != nil || info == nil {
+		return nil
+	}
+	if err := s.remote.SaveInfo(ctx, agentID, *info); err != nil {
+		return fmt.Errorf("save info: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,258 @@
+package desktop
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/astercloud/aster/pkg/store"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+func newOfflineTestApp(t *testing.T, dataDir string) (*App, *WailsBridge) {
+	t.Helper()
+	app, err := NewApp(&AppConfig{
+		Framework: FrameworkWails,
+		WorkDir:   t.TempDir(),
+		DataDir:   dataDir,
+		Offline: &OfflineConfig{
+			FailureThreshold: 2,
+			LocalModel:       &types.ModelConfig{Provider: "ollama", Model: "qwen2.5", APIKey: "ollama"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewApp() error = %v", err)
+	}
+	t.Cleanup(func() { _ = app.Stop(context.Background()) })
+	return app, app.Bridge().(*WailsBridge)
+}
+
+type recordingSender struct {
+	mu   sync.Mutex
+	fail bool
+	sent []string
+}
+
+func (s *recordingSender) send(_ context.Context, item *OutboundItem) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.fail {
+		return errors.New("unreachable")
+	}
+	s.sent = append(s.sent, item.ID)
+	return nil
+}
+
+type recordingSyncer struct {
+	fail   bool
+	synced []string
+}
+
+func (s *recordingSyncer) SyncSession(_ context.Context, agentID string) error {
+	if s.fail {
+		return errors.New("remote unavailable")
+	}
+	s.synced = append(s.synced, agentID)
+	return nil
+}
+
+func TestOfflineLocalModelFallback(t *testing.T) {
+	app, bridge := newOfflineTestApp(t, t.TempDir())
+	app.SetAgentFactory(newTestAgentFactory(t))
+
+	before := createTestAgent(t, bridge, "w1", "coder")
+	drainEvents(bridge)
+
+	app.SetOnline(context.Background(), false)
+	if app.Online() {
+		t.Fatal("Online() = true after SetOnline(false)")
+	}
+	ag, _ := app.GetAgent(before.ID)
+	if got := ag.CurrentModel(); got.Provider != "ollama" || got.Model != "qwen2.5" {
+		t.Errorf("model while offline = %+v, want local model", got)
+	}
+
+	var status *ConnectivityStatus
+	for _, e := range drainEvents(bridge) {
+		if e.Type == EventTypeConnectivity {
+			status = e.Data.(*ConnectivityStatus)
+		}
+	}
+	if status == nil || status.Online || status.LocalModel != "ollama/qwen2.5" {
+		t.Errorf("connectivity event = %+v", status)
+	}
+
+	// Agents created while offline start on the local model too
+	during := createTestAgent(t, bridge, "w1", "writer")
+	ag2, _ := app.GetAgent(during.ID)
+	if got := ag2.CurrentModel(); got.Provider != "ollama" {
+		t.Errorf("new agent model while offline = %+v", got)
+	}
+
+	app.SetOnline(context.Background(), true)
+	for _, id := range []string{before.ID, during.ID} {
+		ag, _ := app.GetAgent(id)
+		if got := ag.CurrentModel(); got.Provider != "anthropic" || got.APIKey != "test-key" {
+			t.Errorf("agent %s model after reconnect = %+v, want original", id, got)
+		}
+	}
+}
+
+func TestCheckConnectivityThreshold(t *testing.T) {
+	app, _ := newOfflineTestApp(t, t.TempDir())
+
+	var probeErr error
+	app.SetConnectivityProbe(func(context.Context) error { return probeErr })
+
+	probeErr = errors.New("no route to host")
+	app.checkConnectivity(context.Background())
+	if !app.Online() {
+		t.Fatal("went offline after a single failed probe")
+	}
+	app.checkConnectivity(context.Background())
+	if app.Online() {
+		t.Fatal("still online after reaching the failure threshold")
+	}
+
+	probeErr = nil
+	app.checkConnectivity(context.Background())
+	if !app.Online() {
+		t.Error("not back online after a successful probe")
+	}
+}
+
+func TestDeliverQueuesWhileOffline(t *testing.T) {
+	dataDir := t.TempDir()
+	app, _ := newOfflineTestApp(t, dataDir)
+	sender := &recordingSender{}
+	app.SetOutboundSender(sender.send)
+
+	ctx := context.Background()
+	if err := app.Deliver(ctx, &OutboundItem{ID: "now", Kind: "webhook", URL: "http://hooks.example"}); err != nil {
+		t.Fatalf("Deliver() error = %v", err)
+	}
+	if len(sender.sent) != 1 {
+		t.Fatalf("sent = %v, want immediate delivery while online", sender.sent)
+	}
+
+	app.SetOnline(ctx, false)
+	for _, id := range []string{"a", "b"} {
+		if err := app.Deliver(ctx, &OutboundItem{ID: id, Kind: "telemetry", URL: "http://otel.example"}); err != nil {
+			t.Fatalf("Deliver() error = %v", err)
+		}
+	}
+	if len(sender.sent) != 1 {
+		t.Errorf("sent = %v, want nothing sent while offline", sender.sent)
+	}
+
+	// The queue survives a restart
+	restarted, _ := newOfflineTestApp(t, dataDir)
+	if got := restarted.ConnectivityStatus().Queued; got != 2 {
+		t.Fatalf("Queued after restart = %d, want 2", got)
+	}
+
+	// A failed flush keeps the items for the next reconnect
+	sender.fail = true
+	app.SetOnline(ctx, true)
+	if got := app.ConnectivityStatus().Queued; got != 2 {
+		t.Errorf("Queued after failed flush = %d, want 2", got)
+	}
+
+	sender.fail = false
+	n, err := app.FlushOutbox(ctx)
+	if err != nil || n != 2 {
+		t.Fatalf("FlushOutbox() = %d, %v", n, err)
+	}
+	if strings.Join(sender.sent, ",") != "now,a,b" {
+		t.Errorf("sent = %v, want in queue order", sender.sent)
+	}
+	if got := app.ConnectivityStatus().Queued; got != 0 {
+		t.Errorf("Queued after flush = %d, want 0", got)
+	}
+}
+
+func TestReconcileSessionsOnReconnect(t *testing.T) {
+	app, _ := newOfflineTestApp(t, t.TempDir())
+	syncer := &recordingSyncer{fail: true}
+	app.SetSessionSyncer(syncer)
+	ctx := context.Background()
+
+	app.markUnsynced("agent-0")
+	if got := app.ConnectivityStatus().Unsynced; len(got) != 0 {
+		t.Errorf("Unsynced while online = %v, want none", got)
+	}
+
+	app.SetOnline(ctx, false)
+	app.markUnsynced("agent-1")
+	app.markUnsynced("agent-2")
+
+	app.SetOnline(ctx, true)
+	status := app.ConnectivityStatus()
+	if len(status.Unsynced) != 2 || len(status.SyncErrors) != 2 {
+		t.Fatalf("status after failed sync = %+v", status)
+	}
+
+	// The next reconnect retries
+	syncer.fail = false
+	app.SetOnline(ctx, false)
+	app.SetOnline(ctx, true)
+	status = app.ConnectivityStatus()
+	if len(status.Unsynced) != 0 || len(status.SyncErrors) != 0 || len(syncer.synced) != 2 {
+		t.Errorf("status = %+v, synced = %v", status, syncer.synced)
+	}
+}
+
+func TestStoreSyncer(t *testing.T) {
+	local, err := store.NewJSONStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	remote, err := store.NewJSONStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	messages := []types.Message{{Role: types.RoleUser, Content: "hello"}}
+	if err := local.SaveMessages(ctx, "agent-1", messages); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := NewStoreSyncer(local, remote).SyncSession(ctx, "agent-1"); err != nil {
+		t.Fatalf("SyncSession() error = %v", err)
+	}
+	got, err := remote.LoadMessages(ctx, "agent-1")
+	if err != nil || len(got) != 1 || got[0].Content != "hello" {
+		t.Errorf("remote messages = %+v, %v", got, err)
+	}
+}
+
+func TestConnectivityHTTPRoute(t *testing.T) {
+	app, err := NewApp(&AppConfig{Framework: FrameworkWeb, DataDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("NewApp() error = %v", err)
+	}
+	mux := http.NewServeMux()
+	connectivityRoutes(mux, app.handleMessage)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/connectivity", strings.NewReader(`{"online":false}`)))
+	if app.Online() {
+		t.Fatalf("still online, body = %s", rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/connectivity", nil))
+	var resp struct {
+		Success bool               `json:"success"`
+		Data    ConnectivityStatus `json:"data"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil || !resp.Success || resp.Data.Online {
+		t.Errorf("GET /api/connectivity = %+v, %v", resp, err)
+	}
+}
@@ -0,0 +1,136 @@
+package desktop
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/astercloud/aster/pkg/config"
+	"github.com/astercloud/aster/pkg/onboarding"
+)
+
+// newOnboardingSetup creates the first-run setup for the app's settings file
+func newOnboardingSetup(cfg *AppConfig) *onboarding.Setup {
+	path := cfg.SettingsFile
+	if path == "" {
+		path = config.SettingsFile()
+	}
+	return &onboarding.Setup{
+		SettingsFile: path,
+		Secrets:      config.DefaultSecretStore(),
+	}
+}
+
+// SetSecretStore replaces the secret store API keys are saved to during onboarding
+func (a *App) SetSecretStore(store config.SecretStore) {
+	a.setup.Secrets = store
+}
+
+// SetKeyValidator replaces the test request used to validate API keys during onboarding
+func (a *App) SetKeyValidator(validate onboarding.KeyValidator) {
+	a.setup.Validate = validate
+}
+
+// OnboardingStatus reports whether first-run setup is needed
+func (a *App) OnboardingStatus() (*onboarding.Status, error) {
+	return a.setup.Status()
+}
+
+// promptOnboarding asks the frontend to show the first-run flow when the
+// default model has no API key
+func (a *App) promptOnboarding() {
+	status, err := a.setup.Status()
+	if err != nil || !status.NeedsSetup {
+		return
+	}
+	_ = a.bridge.SendEvent(&FrontendEvent{
+		Type: EventTypeOnboardingRequired,
+		Data: status,
+	})
+}
+
+func (a *App) handleOnboardingStatus(msg *FrontendMessage) (*BackendResponse, error) {
+	status, err := a.setup.Status()
+	if err != nil {
+		return &BackendResponse{
+			ID:      msg.ID,
+			Success: false,
+			Error:   err.Error(),
+		}, nil
+	}
+	return &BackendResponse{
+		ID:      msg.ID,
+		Success: true,
+		Data:    status,
+	}, nil
+}
+
+// handleValidateAPIKey sends a test request with the key without saving anything
+func (a *App) handleValidateAPIKey(msg *FrontendMessage) (*BackendResponse, error) {
+	var payload onboarding.Request
+	if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+		return &BackendResponse{
+			ID:      msg.ID,
+			Success: false,
+			Error:   fmt.Sprintf("invalid payload: %v", err),
+		}, nil
+	}
+	if err := a.setup.ValidateKey(context.Background(), payload); err != nil {
+		return &BackendResponse{
+			ID:      msg.ID,
+			Success: false,
+			Error:   err.Error(),
+		}, nil
+	}
+	return &BackendResponse{
+		ID:      msg.ID,
+		Success: true,
+	}, nil
+}
+
+// handleCompleteOnboarding validates and stores the key, then writes the
+// provider, model and workspace to the settings file
+func (a *App) handleCompleteOnboarding(msg *FrontendMessage) (*BackendResponse, error) {
+	var payload onboarding.Request
+	if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+		return &BackendResponse{
+			ID:      msg.ID,
+			Success: false,
+			Error:   fmt.Sprintf("invalid payload: %v", err),
+		}, nil
+	}
+	status, err := a.setup.Complete(context.Background(), payload)
+	if err != nil {
+		return &BackendResponse{
+			ID:      msg.ID,
+			Success: false,
+			Error:   err.Error(),
+		}, nil
+	}
+
+	_ = a.bridge.SendEvent(&FrontendEvent{
+		Type: EventTypeOnboardingCompleted,
+		Data: status,
+	})
+	return &BackendResponse{
+		ID:      msg.ID,
+		Success: true,
+		Data:    status,
+	}, nil
+}
+
+// onboardingRoutes registers the first-run endpoints on an HTTP bridge mux
+func onboardingRoutes(mux *http.ServeMux, handler MessageHandler) {
+	mux.HandleFunc("/api/onboarding", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		resp, _ := handler(&FrontendMessage{ID: generateID(), Type: MsgTypeOnboardingStatus})
+		writeJSON(w, http.StatusOK, resp)
+	})
+	newRequest := func() any { return &onboarding.Request{} }
+	mux.HandleFunc("/api/onboarding/validate", shellMessageHandler(handler, MsgTypeValidateAPIKey, newRequest))
+	mux.HandleFunc("/api/onboarding/complete", shellMessageHandler(handler, MsgTypeCompleteOnboarding, newRequest))
+}
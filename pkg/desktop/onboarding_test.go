@@ -0,0 +1,88 @@
+package desktop
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/astercloud/aster/pkg/config"
+	"github.com/astercloud/aster/pkg/onboarding"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+func TestOnboardingFlow(t *testing.T) {
+	t.Setenv("ANTHROPIC_API_KEY", "")
+	t.Setenv("DEEPSEEK_API_KEY", "")
+	dir := t.TempDir()
+	app, err := NewApp(&AppConfig{
+		Framework:    FrameworkWails,
+		WorkDir:      t.TempDir(),
+		DataDir:      dir,
+		SettingsFile: filepath.Join(dir, "aster.yaml"),
+	})
+	if err != nil {
+		t.Fatalf("NewApp() error = %v", err)
+	}
+	t.Cleanup(func() { _ = app.Stop(context.Background()) })
+	bridge := app.Bridge().(*WailsBridge)
+
+	secrets := config.NewFileSecretStore(filepath.Join(dir, "secrets.json"))
+	app.SetSecretStore(secrets)
+	app.SetKeyValidator(func(_ context.Context, cfg *types.ModelConfig) error {
+		if cfg.APIKey != "sk-valid" {
+			return errors.New("authentication failed")
+		}
+		return nil
+	})
+
+	app.promptOnboarding()
+	var prompted bool
+	for _, e := range drainEvents(bridge) {
+		prompted = prompted || e.Type == EventTypeOnboardingRequired
+	}
+	if !prompted {
+		t.Error("no onboarding_required event on first run")
+	}
+
+	resp, _ := bridge.OnboardingStatus()
+	status := resp.Data.(*onboarding.Status)
+	if !resp.Success || !status.NeedsSetup || len(status.Providers) == 0 {
+		t.Fatalf("OnboardingStatus() = %+v", resp)
+	}
+
+	if resp, _ = bridge.ValidateAPIKey(onboarding.Request{Provider: "deepseek", APIKey: "sk-wrong"}); resp.Success {
+		t.Error("invalid key accepted")
+	}
+	if resp, _ = bridge.ValidateAPIKey(onboarding.Request{Provider: "deepseek", APIKey: "sk-valid"}); !resp.Success {
+		t.Errorf("ValidateAPIKey() = %+v", resp)
+	}
+
+	workspace := filepath.Join(dir, "workspace")
+	resp, _ = bridge.CompleteOnboarding(onboarding.Request{Provider: "deepseek", APIKey: "sk-valid", Workspace: workspace})
+	if !resp.Success {
+		t.Fatalf("CompleteOnboarding() = %+v", resp)
+	}
+	status = resp.Data.(*onboarding.Status)
+	if status.NeedsSetup || status.Provider != "deepseek" || status.Workspace != workspace {
+		t.Errorf("status after onboarding = %+v", status)
+	}
+	if key, err := secrets.Get(config.APIKeySecret("deepseek")); err != nil || key != "sk-valid" {
+		t.Errorf("stored key = %q, %v", key, err)
+	}
+
+	var completed bool
+	for _, e := range drainEvents(bridge) {
+		completed = completed || e.Type == EventTypeOnboardingCompleted
+	}
+	if !completed {
+		t.Error("no onboarding_completed event")
+	}
+
+	app.promptOnboarding()
+	for _, e := range drainEvents(bridge) {
+		if e.Type == EventTypeOnboardingRequired {
+			t.Error("onboarding prompted again after setup")
+		}
+	}
+}
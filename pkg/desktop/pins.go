@@ -0,0 +1,166 @@
+package desktop
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/astercloud/aster/pkg/agent"
+)
+
+// PinPayload is the payload for pin messages
+type PinPayload struct {
+	Kind    agent.PinKind `json:"kind"`
+	Title   string        `json:"title,omitempty"`
+	Path    string        `json:"path,omitempty"`    // for file pins
+	Content string        `json:"content,omitempty"` // note or requirements text; file pins read Path when empty
+}
+
+// UnpinPayload is the payload for unpin messages
+type UnpinPayload struct {
+	ID  string `json:"id,omitempty"`
+	All bool   `json:"all,omitempty"`
+}
+
+// PinRequest is the body of POST /api/pins
+type PinRequest struct {
+	AgentID string `json:"agent_id"`
+	PinPayload
+}
+
+// handleListPins returns the agent's pinned context items
+func (a *App) handleListPins(msg *FrontendMessage) (*BackendResponse, error) {
+	ag, ok := a.GetAgent(msg.AgentID)
+	if !ok {
+		return &BackendResponse{
+			ID:      msg.ID,
+			Success: false,
+			Error:   "agent not found: " + msg.AgentID,
+		}, nil
+	}
+
+	return &BackendResponse{
+		ID:      msg.ID,
+		Success: true,
+		Data:    map[string]any{"pins": ag.Pins()},
+	}, nil
+}
+
+// handlePin pins a file, note or requirement list so compaction never drops it
+func (a *App) handlePin(msg *FrontendMessage) (*BackendResponse, error) {
+	var payload PinPayload
+	if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+		return &BackendResponse{
+			ID:      msg.ID,
+			Success: false,
+			Error:   fmt.Sprintf("invalid payload: %v", err),
+		}, nil
+	}
+
+	ag, ok := a.GetAgent(msg.AgentID)
+	if !ok {
+		return &BackendResponse{
+			ID:      msg.ID,
+			Success: false,
+			Error:   "agent not found: " + msg.AgentID,
+		}, nil
+	}
+
+	pin, err := ag.Pin(context.Background(), agent.PinnedItem{
+		Kind:    payload.Kind,
+		Title:   payload.Title,
+		Path:    payload.Path,
+		Content: payload.Content,
+	})
+	if err != nil {
+		return &BackendResponse{
+			ID:      msg.ID,
+			Success: false,
+			Error:   err.Error(),
+		}, nil
+	}
+
+	return &BackendResponse{
+		ID:      msg.ID,
+		Success: true,
+		Data:    map[string]any{"pin": pin, "pins": ag.Pins()},
+	}, nil
+}
+
+// handleUnpin removes one pinned item, or all of them
+func (a *App) handleUnpin(msg *FrontendMessage) (*BackendResponse, error) {
+	var payload UnpinPayload
+	if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+		return &BackendResponse{
+			ID:      msg.ID,
+			Success: false,
+			Error:   fmt.Sprintf("invalid payload: %v", err),
+		}, nil
+	}
+
+	ag, ok := a.GetAgent(msg.AgentID)
+	if !ok {
+		return &BackendResponse{
+			ID:      msg.ID,
+			Success: false,
+			Error:   "agent not found: " + msg.AgentID,
+		}, nil
+	}
+
+	var err error
+	if payload.All {
+		err = ag.UnpinAll(context.Background())
+	} else {
+		err = ag.Unpin(context.Background(), payload.ID)
+	}
+	if err != nil {
+		return &BackendResponse{
+			ID:      msg.ID,
+			Success: false,
+			Error:   err.Error(),
+		}, nil
+	}
+
+	return &BackendResponse{
+		ID:      msg.ID,
+		Success: true,
+		Data:    map[string]any{"pins": ag.Pins()},
+	}, nil
+}
+
+// pinRoutes registers the pinned context endpoints on an HTTP bridge mux:
+// GET lists pins, POST pins an item and DELETE (?id= or ?all=true) unpins
+func pinRoutes(mux *http.ServeMux, handler MessageHandler) {
+	mux.HandleFunc("/api/pins", func(w http.ResponseWriter, r *http.Request) {
+		msg := &FrontendMessage{ID: generateID(), AgentID: r.URL.Query().Get("agent_id")}
+		switch r.Method {
+		case http.MethodGet:
+			msg.Type = MsgTypeListPins
+		case http.MethodPost:
+			var req PinRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				writeJSON(w, http.StatusBadRequest, BackendResponse{
+					Success: false,
+					Error:   err.Error(),
+				})
+				return
+			}
+			msg.Type = MsgTypePin
+			msg.AgentID = req.AgentID
+			msg.Payload = mustMarshal(req.PinPayload)
+		case http.MethodDelete:
+			msg.Type = MsgTypeUnpin
+			msg.Payload = mustMarshal(UnpinPayload{
+				ID:  r.URL.Query().Get("id"),
+				All: r.URL.Query().Get("all") == "true",
+			})
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		resp, _ := handler(msg)
+		writeJSON(w, http.StatusOK, resp)
+	})
+}
@@ -0,0 +1,41 @@
+package desktop
+
+import (
+	"testing"
+
+	"github.com/astercloud/aster/pkg/agent"
+)
+
+func TestPins(t *testing.T) {
+	_, bridge := newMultiWindowApp(t)
+	ag := createTestAgent(t, bridge, "w1", "coder")
+
+	resp, _ := bridge.Pin(ag.ID, PinPayload{Kind: agent.PinKindRequirements, Content: "- SSO login\n- audit log"})
+	if !resp.Success {
+		t.Fatalf("Pin() = %+v", resp)
+	}
+	if pin := resp.Data.(map[string]any)["pin"].(*agent.PinnedItem); pin.ID != "1" || pin.Kind != agent.PinKindRequirements {
+		t.Errorf("pin = %+v", pin)
+	}
+	if resp, _ := bridge.Pin(ag.ID, PinPayload{Kind: "bogus", Content: "x"}); resp.Success {
+		t.Error("Pin() with unknown kind succeeded")
+	}
+	_, _ = bridge.Pin(ag.ID, PinPayload{Kind: agent.PinKindNote, Content: "Keep the public API stable"})
+
+	resp, _ = bridge.ListPins(ag.ID)
+	if pins := resp.Data.(map[string]any)["pins"].([]agent.PinnedItem); len(pins) != 2 {
+		t.Fatalf("ListPins() = %+v", pins)
+	}
+
+	resp, _ = bridge.Unpin(ag.ID, "1")
+	if pins := resp.Data.(map[string]any)["pins"].([]agent.PinnedItem); !resp.Success || len(pins) != 1 || pins[0].ID != "2" {
+		t.Errorf("Unpin() = %+v", resp)
+	}
+	if resp, _ := bridge.Unpin(ag.ID, "1"); resp.Success {
+		t.Error("Unpin() of a removed pin succeeded")
+	}
+	resp, _ = bridge.Unpin(ag.ID, "all")
+	if pins := resp.Data.(map[string]any)["pins"].([]agent.PinnedItem); !resp.Success || len(pins) != 0 {
+		t.Errorf("Unpin(all) = %+v", resp)
+	}
+}
@@ -0,0 +1,298 @@
+package desktop
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrTrayNotSupported is returned by bridges whose shell has no system tray
+var ErrTrayNotSupported = errors.New("system tray not supported by this framework")
+
+// NotificationKind identifies why a notification was raised
+type NotificationKind string
+
+const (
+	// NotificationApprovalRequired is raised when a tool call waits for approval
+	NotificationApprovalRequired NotificationKind = "approval_required"
+
+	// NotificationTaskFinished is raised when an agent finishes a turn
+	NotificationTaskFinished NotificationKind = "task_finished"
+
+	// NotificationBudgetExceeded is raised when an agent exceeds its token budget
+	NotificationBudgetExceeded NotificationKind = "budget_exceeded"
+
+	// NotificationError is raised when an agent fails
+	NotificationError NotificationKind = "error"
+
+	// NotificationInfo is a custom informational notification
+	NotificationInfo NotificationKind = "info"
+)
+
+// Notification is an OS notification shown by the desktop shell
+type Notification struct {
+	ID        string           `json:"id"`
+	Kind      NotificationKind `json:"kind"`
+	AgentID   string           `json:"agent_id,omitempty"`
+	Title     string           `json:"title"`
+	Body      string           `json:"body,omitempty"`
+	Timestamp time.Time        `json:"timestamp"`
+
+	// Data carries kind-specific details (e.g. call_id for approvals)
+	Data map[string]any `json:"data,omitempty"`
+}
+
+// TrayMenuItem is a single entry in the tray menu
+type TrayMenuItem struct {
+	ID        string         `json:"id,omitempty"`
+	Label     string         `json:"label,omitempty"`
+	Tooltip   string         `json:"tooltip,omitempty"`
+	Disabled  bool           `json:"disabled,omitempty"`
+	Checked   bool           `json:"checked,omitempty"`
+	Separator bool           `json:"separator,omitempty"`
+	Items     []TrayMenuItem `json:"items,omitempty"` // submenu
+}
+
+// TrayMenu is the tray icon definition rendered by the desktop shell
+type TrayMenu struct {
+	Tooltip string         `json:"tooltip,omitempty"`
+	Icon    string         `json:"icon,omitempty"` // path or data URL, shell-specific
+	Items   []TrayMenuItem `json:"items"`
+}
+
+// TrayActionPayload is the payload for tray menu clicks
+type TrayActionPayload struct {
+	ItemID string `json:"item_id"`
+}
+
+// WindowStatePayload reports main window visibility
+type WindowStatePayload struct {
+	Visible bool `json:"visible"`
+}
+
+// TrayActionHandler handles tray menu clicks
+type TrayActionHandler func(itemID string)
+
+// NotificationConfig controls automatic notifications for agent activity
+type NotificationConfig struct {
+	// Disabled turns off automatic notifications (App.Notify still works)
+	Disabled bool `json:"disabled,omitempty"`
+
+	// Always notifies even while the main window is visible
+	Always bool `json:"always,omitempty"`
+
+	// TokenBudget raises NotificationBudgetExceeded once an agent's total
+	// token usage exceeds it (0 disables)
+	TokenBudget int64 `json:"token_budget,omitempty"`
+}
+
+// shellState is the tray/notification state shared by all bridges
+type shellState struct {
+	mu   sync.RWMutex
+	menu *TrayMenu
+}
+
+func (s *shellState) setMenu(menu *TrayMenu) {
+	s.mu.Lock()
+	s.menu = menu
+	s.mu.Unlock()
+}
+
+func (s *shellState) getMenu() *TrayMenu {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.menu
+}
+
+// notifyState tracks window visibility and per-agent token usage
+type notifyState struct {
+	mu            sync.Mutex
+	hidden        bool
+	tokens        map[string]int64
+	budgetAlerted map[string]bool
+	trayHandler   TrayActionHandler
+}
+
+func newNotifyState() *notifyState {
+	return &notifyState{
+		tokens:        make(map[string]int64),
+		budgetAlerted: make(map[string]bool),
+	}
+}
+
+// SetTrayMenu installs or replaces the tray menu
+func (a *App) SetTrayMenu(menu *TrayMenu) error {
+	return a.bridge.SetTrayMenu(menu)
+}
+
+// OnTrayAction sets the handler for tray menu clicks
+func (a *App) OnTrayAction(handler TrayActionHandler) {
+	a.notify.mu.Lock()
+	a.notify.trayHandler = handler
+	a.notify.mu.Unlock()
+}
+
+// SetWindowVisible records main window visibility.
+// Automatic notifications are only shown while the window is hidden,
+// unless NotificationConfig.Always is set.
+func (a *App) SetWindowVisible(visible bool) {
+	a.notify.mu.Lock()
+	a.notify.hidden = !visible
+	a.notify.mu.Unlock()
+}
+
+// Notify shows an OS notification through the bridge
+func (a *App) Notify(n *Notification) error {
+	if n.ID == "" {
+		n.ID = generateID()
+	}
+	if n.Timestamp.IsZero() {
+		n.Timestamp = time.Now()
+	}
+	if n.Kind == "" {
+		n.Kind = NotificationInfo
+	}
+	return a.bridge.Notify(n)
+}
+
+// notifyActivity raises an automatic notification for agent activity
+func (a *App) notifyActivity(n *Notification) {
+	cfg := a.config.Notifications
+	if cfg != nil && cfg.Disabled {
+		return
+	}
+	a.notify.mu.Lock()
+	hidden := a.notify.hidden
+	a.notify.mu.Unlock()
+	if !hidden && (cfg == nil || !cfg.Always) {
+		return
+	}
+	_ = a.Notify(n) // Best effort, notifications must not block event forwarding
+}
+
+// recordTokenUsage accumulates token usage and raises a budget notification
+// the first time the agent crosses NotificationConfig.TokenBudget
+func (a *App) recordTokenUsage(agentID string, tokens int64) {
+	cfg := a.config.Notifications
+	if cfg == nil || cfg.TokenBudget <= 0 {
+		return
+	}
+
+	a.notify.mu.Lock()
+	a.notify.tokens[agentID] += tokens
+	total := a.notify.tokens[agentID]
+	exceeded := total > cfg.TokenBudget && !a.notify.budgetAlerted[agentID]
+	if exceeded {
+		a.notify.budgetAlerted[agentID] = true
+	}
+	a.notify.mu.Unlock()
+
+	if exceeded {
+		a.notifyActivity(&Notification{
+			Kind:    NotificationBudgetExceeded,
+			AgentID: agentID,
+			Title:   "Token budget exceeded",
+			Body:    fmt.Sprintf("Agent %s used %d tokens (budget %d)", agentID, total, cfg.TokenBudget),
+			Data: map[string]any{
+				"total_tokens": total,
+				"budget":       cfg.TokenBudget,
+			},
+		})
+	}
+}
+
+func (a *App) handleTrayAction(msg *FrontendMessage) (*BackendResponse, error) {
+	var payload TrayActionPayload
+	if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+		return &BackendResponse{
+			ID:      msg.ID,
+			Success: false,
+			Error:   fmt.Sprintf("invalid payload: %v", err),
+		}, nil
+	}
+
+	a.notify.mu.Lock()
+	handler := a.notify.trayHandler
+	a.notify.mu.Unlock()
+	if handler == nil {
+		return &BackendResponse{
+			ID:      msg.ID,
+			Success: false,
+			Error:   "no tray action handler",
+		}, nil
+	}
+
+	handler(payload.ItemID)
+	return &BackendResponse{
+		ID:      msg.ID,
+		Success: true,
+	}, nil
+}
+
+func (a *App) handleWindowState(msg *FrontendMessage) (*BackendResponse, error) {
+	var payload WindowStatePayload
+	if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+		return &BackendResponse{
+			ID:      msg.ID,
+			Success: false,
+			Error:   fmt.Sprintf("invalid payload: %v", err),
+		}, nil
+	}
+
+	a.SetWindowVisible(payload.Visible)
+	return &BackendResponse{
+		ID:      msg.ID,
+		Success: true,
+	}, nil
+}
+
+// HTTP handlers shared by the Tauri, Electron and Web bridges
+
+// trayRoutes registers tray endpoints on an HTTP bridge mux
+func trayRoutes(mux *http.ServeMux, shell *shellState, handler MessageHandler) {
+	mux.HandleFunc("/api/tray", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		writeJSON(w, http.StatusOK, BackendResponse{
+			ID:      generateID(),
+			Success: true,
+			Data:    shell.getMenu(),
+		})
+	})
+	mux.HandleFunc("/api/tray/action", shellMessageHandler(handler, MsgTypeTrayAction, func() any { return &TrayActionPayload{} }))
+}
+
+// windowRoutes registers the window-state endpoint on an HTTP bridge mux
+func windowRoutes(mux *http.ServeMux, handler MessageHandler) {
+	mux.HandleFunc("/api/window", shellMessageHandler(handler, MsgTypeWindowState, func() any { return &WindowStatePayload{} }))
+}
+
+func shellMessageHandler(handler MessageHandler, msgType MessageType, newPayload func() any) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		payload := newPayload()
+		if err := json.NewDecoder(r.Body).Decode(payload); err != nil {
+			writeJSON(w, http.StatusBadRequest, BackendResponse{
+				Success: false,
+				Error:   err.Error(),
+			})
+			return
+		}
+
+		resp, _ := handler(&FrontendMessage{
+			ID:      generateID(),
+			Type:    msgType,
+			Payload: mustMarshal(payload),
+		})
+		writeJSON(w, http.StatusOK, resp)
+	}
+}
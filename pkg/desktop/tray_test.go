@@ -0,0 +1,165 @@
+package desktop
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newWailsTestApp(t *testing.T, cfg *NotificationConfig) (*App, *WailsBridge) {
+	t.Helper()
+	app, err := NewApp(&AppConfig{
+		Framework:     FrameworkWails,
+		Notifications: cfg,
+	})
+	if err != nil {
+		t.Fatalf("NewApp() error = %v", err)
+	}
+	return app, app.Bridge().(*WailsBridge)
+}
+
+// drainEvents returns events queued on the Wails event channel
+func drainEvents(b *WailsBridge) []*FrontendEvent {
+	var events []*FrontendEvent
+	for {
+		select {
+		case e := <-b.eventCh:
+			events = append(events, e)
+		default:
+			return events
+		}
+	}
+}
+
+func TestWailsTrayMenu(t *testing.T) {
+	app, bridge := newWailsTestApp(t, nil)
+
+	menu := &TrayMenu{
+		Tooltip: "aster",
+		Items: []TrayMenuItem{
+			{ID: "show", Label: "Show"},
+			{Separator: true},
+			{ID: "quit", Label: "Quit"},
+		},
+	}
+	if err := app.SetTrayMenu(menu); err != nil {
+		t.Fatalf("SetTrayMenu() error = %v", err)
+	}
+	if bridge.TrayMenu() != menu {
+		t.Error("TrayMenu() did not return the installed menu")
+	}
+	events := drainEvents(bridge)
+	if len(events) != 1 || events[0].Type != EventTypeTrayUpdate {
+		t.Errorf("events = %+v, want one tray_update", events)
+	}
+
+	resp, _ := bridge.TrayAction("show")
+	if resp.Success {
+		t.Error("expected failure without tray action handler")
+	}
+
+	var clicked string
+	app.OnTrayAction(func(itemID string) { clicked = itemID })
+	resp, _ = bridge.TrayAction("quit")
+	if !resp.Success || clicked != "quit" {
+		t.Errorf("TrayAction() success = %v, clicked = %q", resp.Success, clicked)
+	}
+}
+
+func TestNotifyActivityOnlyWhenHidden(t *testing.T) {
+	app, bridge := newWailsTestApp(t, nil)
+
+	n := &Notification{Kind: NotificationTaskFinished, Title: "done"}
+	app.notifyActivity(n)
+	if events := drainEvents(bridge); len(events) != 0 {
+		t.Errorf("got %d events while window visible, want 0", len(events))
+	}
+
+	_, _ = bridge.SetWindowVisible(false)
+	app.notifyActivity(n)
+	events := drainEvents(bridge)
+	if len(events) != 1 || events[0].Type != EventTypeNotification {
+		t.Fatalf("events = %+v, want one notification", events)
+	}
+	got := events[0].Data.(*Notification)
+	if got.ID == "" || got.Timestamp.IsZero() {
+		t.Errorf("notification not filled in: %+v", got)
+	}
+
+	app.config.Notifications = &NotificationConfig{Disabled: true}
+	app.notifyActivity(n)
+	if events := drainEvents(bridge); len(events) != 0 {
+		t.Errorf("got %d events with notifications disabled, want 0", len(events))
+	}
+}
+
+func TestTokenBudgetNotification(t *testing.T) {
+	app, bridge := newWailsTestApp(t, &NotificationConfig{Always: true, TokenBudget: 100})
+
+	app.recordTokenUsage("agent-1", 60)
+	if events := drainEvents(bridge); len(events) != 0 {
+		t.Fatalf("got %d events under budget, want 0", len(events))
+	}
+
+	app.recordTokenUsage("agent-1", 60)
+	app.recordTokenUsage("agent-1", 60)
+	events := drainEvents(bridge)
+	if len(events) != 1 {
+		t.Fatalf("got %d events, want exactly one budget notification", len(events))
+	}
+	n := events[0].Data.(*Notification)
+	if n.Kind != NotificationBudgetExceeded || n.AgentID != "agent-1" {
+		t.Errorf("notification = %+v", n)
+	}
+}
+
+func TestWebBridgeTrayNotSupported(t *testing.T) {
+	app, err := NewApp(&AppConfig{Framework: FrameworkWeb})
+	if err != nil {
+		t.Fatalf("NewApp() error = %v", err)
+	}
+	if err := app.SetTrayMenu(&TrayMenu{}); !errors.Is(err, ErrTrayNotSupported) {
+		t.Errorf("SetTrayMenu() error = %v, want ErrTrayNotSupported", err)
+	}
+	if err := app.Notify(&Notification{Title: "hi"}); err != nil {
+		t.Errorf("Notify() error = %v", err)
+	}
+}
+
+func TestTrayHTTPRoutes(t *testing.T) {
+	app, err := NewApp(&AppConfig{Framework: FrameworkTauri})
+	if err != nil {
+		t.Fatalf("NewApp() error = %v", err)
+	}
+	bridge := app.Bridge().(*TauriBridge)
+	if err := app.SetTrayMenu(&TrayMenu{Items: []TrayMenuItem{{ID: "show", Label: "Show"}}}); err != nil {
+		t.Fatalf("SetTrayMenu() error = %v", err)
+	}
+
+	var clicked string
+	app.OnTrayAction(func(itemID string) { clicked = itemID })
+
+	mux := http.NewServeMux()
+	trayRoutes(mux, &bridge.shell, app.handleMessage)
+	windowRoutes(mux, app.handleMessage)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/tray", nil))
+	if !strings.Contains(rec.Body.String(), `"id":"show"`) {
+		t.Errorf("GET /api/tray body = %s", rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/tray/action", strings.NewReader(`{"item_id":"show"}`)))
+	if clicked != "show" {
+		t.Errorf("clicked = %q, body = %s", clicked, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/window", strings.NewReader(`{"visible":false}`)))
+	if !app.notify.hidden {
+		t.Errorf("window not marked hidden, body = %s", rec.Body.String())
+	}
+}
@@ -0,0 +1,81 @@
+package desktop
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// WorkspaceTrustPayload is the payload for workspace_trust messages
+type WorkspaceTrustPayload struct {
+	Trusted bool `json:"trusted"`
+}
+
+// WorkspaceTrustRequest is the body of POST /api/workspace/trust
+type WorkspaceTrustRequest struct {
+	AgentID string `json:"agent_id"`
+	WorkspaceTrustPayload
+}
+
+// handleWorkspaceTrust answers a workspace_trust_required event. Trusting
+// persists the decision for the agent's WorkDir; declining only lasts for the agent.
+func (a *App) handleWorkspaceTrust(msg *FrontendMessage) (*BackendResponse, error) {
+	var payload WorkspaceTrustPayload
+	if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+		return &BackendResponse{
+			ID:      msg.ID,
+			Success: false,
+			Error:   fmt.Sprintf("invalid payload: %v", err),
+		}, nil
+	}
+
+	ag, ok := a.GetAgent(msg.AgentID)
+	if !ok {
+		return &BackendResponse{
+			ID:      msg.ID,
+			Success: false,
+			Error:   "agent not found: " + msg.AgentID,
+		}, nil
+	}
+
+	if err := ag.RespondToWorkspaceTrust(payload.Trusted); err != nil {
+		return &BackendResponse{
+			ID:      msg.ID,
+			Success: false,
+			Error:   err.Error(),
+		}, nil
+	}
+
+	return &BackendResponse{
+		ID:      msg.ID,
+		Success: true,
+		Data:    map[string]any{"trust": ag.WorkspaceTrust()},
+	}, nil
+}
+
+// workspaceRoutes registers the workspace trust endpoint on an HTTP bridge mux
+func workspaceRoutes(mux *http.ServeMux, handler MessageHandler) {
+	mux.HandleFunc("/api/workspace/trust", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req WorkspaceTrustRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, BackendResponse{
+				Success: false,
+				Error:   err.Error(),
+			})
+			return
+		}
+
+		resp, _ := handler(&FrontendMessage{
+			ID:      generateID(),
+			Type:    MsgTypeWorkspaceTrust,
+			AgentID: req.AgentID,
+			Payload: mustMarshal(req.WorkspaceTrustPayload),
+		})
+		writeJSON(w, http.StatusOK, resp)
+	})
+}
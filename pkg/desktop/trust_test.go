@@ -0,0 +1,61 @@
+package desktop
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/astercloud/aster/pkg/agent"
+	"github.com/astercloud/aster/pkg/permission"
+	"github.com/astercloud/aster/pkg/provider"
+	"github.com/astercloud/aster/pkg/sandbox"
+	"github.com/astercloud/aster/pkg/store"
+	"github.com/astercloud/aster/pkg/tools"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+func TestWorkspaceTrust(t *testing.T) {
+	app, bridge := newMultiWindowApp(t)
+
+	// Agents without a trust store do not take part in the trust model
+	plain := createTestAgent(t, bridge, "w1", "coder")
+	if resp, _ := bridge.TrustWorkspace(plain.ID, true); resp.Success {
+		t.Error("TrustWorkspace() succeeded for an agent without workspace trust")
+	}
+
+	trust, err := permission.NewTrustStore(filepath.Join(t.TempDir(), "trust.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	jsonStore, err := store.NewJSONStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	templates := agent.NewTemplateRegistry()
+	templates.Register(&types.AgentTemplateDefinition{ID: "coder", Model: "claude-sonnet-4-5", Tools: []any{}})
+	app.SetAgentFactory(NewAgentFactory(&agent.Dependencies{
+		Store:            jsonStore,
+		SandboxFactory:   sandbox.NewFactory(),
+		ToolRegistry:     tools.NewRegistry(),
+		ProviderFactory:  &provider.AnthropicFactory{},
+		TemplateRegistry: templates,
+		TrustStore:       trust,
+	}, &types.AgentConfig{
+		ModelConfig: &types.ModelConfig{Provider: "anthropic", Model: "claude-sonnet-4-5", APIKey: "test-key"},
+		Sandbox:     &types.SandboxConfig{Kind: types.SandboxKindLocal},
+	}))
+
+	workDir := t.TempDir()
+	resp, _ := bridge.CreateAgent("w1", CreateAgentPayload{TemplateID: "coder", WorkDir: workDir})
+	if !resp.Success {
+		t.Fatalf("CreateAgent() = %+v", resp)
+	}
+	id := resp.Data.(AgentSummary).ID
+
+	resp, _ = bridge.TrustWorkspace(id, true)
+	if !resp.Success || resp.Data.(map[string]any)["trust"] != permission.TrustLevelTrusted {
+		t.Fatalf("TrustWorkspace() = %+v", resp)
+	}
+	if !trust.IsTrusted(workDir) {
+		t.Error("trust decision not persisted")
+	}
+}
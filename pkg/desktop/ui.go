@@ -0,0 +1,287 @@
+package desktop
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/astercloud/aster/pkg/types"
+	"github.com/astercloud/aster/pkg/uiproto"
+)
+
+// UIMessageEvent is the data of a ui_message event: a validated UI protocol
+// message produced by the RenderUI tool
+type UIMessageEvent struct {
+	CallID  string                `json:"call_id"`
+	Message *types.AsterUIMessage `json:"message"`
+}
+
+// UIActionRequest is the body of POST /api/ui/action
+type UIActionRequest struct {
+	AgentID string `json:"agent_id"`
+	types.ClientMessage
+}
+
+// CatalogPayload is the payload for get_catalog messages
+type CatalogPayload struct {
+	// ID is "id" for the latest version or "id@version"; empty lists all catalogs
+	ID string `json:"id,omitempty"`
+}
+
+// CatalogSummary describes a registered catalog in a get_catalog listing
+type CatalogSummary struct {
+	ID          string   `json:"id"`
+	Version     string   `json:"version"`
+	Versions    []string `json:"versions"`
+	Description string   `json:"description,omitempty"`
+}
+
+// ExportSurfacePayload is the payload for export_surface messages
+type ExportSurfacePayload struct {
+	SurfaceID string `json:"surface_id"`
+	// Format is "html" (default) or "react"
+	Format string `json:"format,omitempty"`
+	// Title is the HTML document title; defaults to the surface ID
+	Title string `json:"title,omitempty"`
+	// Fragment omits the HTML document and default stylesheet
+	Fragment bool `json:"fragment,omitempty"`
+}
+
+// ExportSurfaceRequest is the body of POST /api/ui/export
+type ExportSurfaceRequest struct {
+	AgentID string `json:"agent_id"`
+	ExportSurfacePayload
+}
+
+// ExportedSurface is the result of an export_surface message
+type ExportedSurface struct {
+	SurfaceID string `json:"surface_id"`
+	Format    string `json:"format"`
+	MimeType  string `json:"mime_type"`
+	Content   string `json:"content"`
+}
+
+// SetCatalogRegistry sets the UI component catalogs served to frontends
+// (defaults to uiproto.DefaultCatalogs). Agents validate against
+// agent.Dependencies.UICatalogs, so pass the same registry there.
+func (a *App) SetCatalogRegistry(r *uiproto.CatalogRegistry) {
+	a.catalogs = r
+}
+
+func (a *App) handleGetCatalog(msg *FrontendMessage) (*BackendResponse, error) {
+	var payload CatalogPayload
+	if len(msg.Payload) > 0 {
+		if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+			return &BackendResponse{
+				ID:      msg.ID,
+				Success: false,
+				Error:   fmt.Sprintf("invalid payload: %v", err),
+			}, nil
+		}
+	}
+
+	if payload.ID == "" {
+		catalogs := a.catalogs.List()
+		summaries := make([]CatalogSummary, 0, len(catalogs))
+		for _, c := range catalogs {
+			summaries = append(summaries, CatalogSummary{
+				ID:          c.ID,
+				Version:     c.Version,
+				Versions:    a.catalogs.Versions(c.ID),
+				Description: c.Description,
+			})
+		}
+		return &BackendResponse{
+			ID:      msg.ID,
+			Success: true,
+			Data:    map[string]any{"catalogs": summaries},
+		}, nil
+	}
+
+	catalog, err := a.catalogs.Get(payload.ID)
+	if err != nil {
+		return &BackendResponse{
+			ID:      msg.ID,
+			Success: false,
+			Error:   err.Error(),
+		}, nil
+	}
+	return &BackendResponse{
+		ID:      msg.ID,
+		Success: true,
+		Data:    catalog,
+	}, nil
+}
+
+func (a *App) handleUIAction(msg *FrontendMessage) (*BackendResponse, error) {
+	ag, ok := a.GetAgent(msg.AgentID)
+	if !ok {
+		return &BackendResponse{
+			ID:      msg.ID,
+			Success: false,
+			Error:   "agent not found: " + msg.AgentID,
+		}, nil
+	}
+
+	// Older frontends send the v1 action shape, which is upgraded here
+	payload, err := types.DecodeClientMessage(msg.Payload)
+	if err != nil {
+		return &BackendResponse{
+			ID:      msg.ID,
+			Success: false,
+			Error:   fmt.Sprintf("invalid payload: %v", err),
+		}, nil
+	}
+
+	// The agent validates the action against the surface it rendered and
+	// routes it to a waiting RenderUI call and the event bus
+	if err := ag.HandleUIMessage(context.Background(), payload); err != nil {
+		return &BackendResponse{
+			ID:      msg.ID,
+			Success: false,
+			Error:   err.Error(),
+		}, nil
+	}
+
+	return &BackendResponse{
+		ID:      msg.ID,
+		Success: true,
+	}, nil
+}
+
+func (a *App) handleExportSurface(msg *FrontendMessage) (*BackendResponse, error) {
+	ag, ok := a.GetAgent(msg.AgentID)
+	if !ok {
+		return &BackendResponse{
+			ID:      msg.ID,
+			Success: false,
+			Error:   "agent not found: " + msg.AgentID,
+		}, nil
+	}
+
+	var payload ExportSurfacePayload
+	if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+		return &BackendResponse{
+			ID:      msg.ID,
+			Success: false,
+			Error:   fmt.Sprintf("invalid payload: %v", err),
+		}, nil
+	}
+
+	surface, ok := ag.UISurfaces().Surface(payload.SurfaceID)
+	if !ok {
+		return &BackendResponse{
+			ID:      msg.ID,
+			Success: false,
+			Error:   "surface not found: " + payload.SurfaceID,
+		}, nil
+	}
+
+	format := uiproto.ExportFormat(payload.Format)
+	if format == "" {
+		format = uiproto.ExportHTML
+	}
+	content, err := uiproto.Export(surface, format, &uiproto.ExportOptions{
+		Title:    payload.Title,
+		Fragment: payload.Fragment,
+	})
+	if err != nil {
+		return &BackendResponse{
+			ID:      msg.ID,
+			Success: false,
+			Error:   err.Error(),
+		}, nil
+	}
+
+	mimeType := "text/html"
+	if format == uiproto.ExportReact {
+		mimeType = "text/jsx"
+	}
+	return &BackendResponse{
+		ID:      msg.ID,
+		Success: true,
+		Data: ExportedSurface{
+			SurfaceID: surface.ID,
+			Format:    string(format),
+			MimeType:  mimeType,
+			Content:   content,
+		},
+	}, nil
+}
+
+// uiRoutes registers the UI action, catalog and export endpoints on an HTTP bridge mux
+func uiRoutes(mux *http.ServeMux, handler MessageHandler) {
+	// GET /api/ui/catalogs lists catalogs; GET /api/ui/catalogs/{id} returns one
+	catalogs := func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		id := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/api/ui/catalogs"), "/")
+		if v := r.URL.Query().Get("version"); v != "" && id != "" && !strings.Contains(id, "@") {
+			id += "@" + v
+		}
+		resp, _ := handler(&FrontendMessage{
+			ID:      generateID(),
+			Type:    MsgTypeGetCatalog,
+			Payload: mustMarshal(CatalogPayload{ID: id}),
+		})
+		status := http.StatusOK
+		if !resp.Success {
+			status = http.StatusNotFound
+		}
+		writeJSON(w, status, resp)
+	}
+	mux.HandleFunc("/api/ui/catalogs", catalogs)
+	mux.HandleFunc("/api/ui/catalogs/", catalogs)
+
+	mux.HandleFunc("/api/ui/action", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req UIActionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, BackendResponse{
+				Success: false,
+				Error:   err.Error(),
+			})
+			return
+		}
+
+		resp, _ := handler(&FrontendMessage{
+			ID:      generateID(),
+			Type:    MsgTypeUIAction,
+			AgentID: req.AgentID,
+			Payload: mustMarshal(req.ClientMessage),
+		})
+		writeJSON(w, http.StatusOK, resp)
+	})
+
+	mux.HandleFunc("/api/ui/export", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req ExportSurfaceRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, BackendResponse{
+				Success: false,
+				Error:   err.Error(),
+			})
+			return
+		}
+
+		resp, _ := handler(&FrontendMessage{
+			ID:      generateID(),
+			Type:    MsgTypeExportSurface,
+			AgentID: req.AgentID,
+			Payload: mustMarshal(req.ExportSurfacePayload),
+		})
+		writeJSON(w, http.StatusOK, resp)
+	})
+}
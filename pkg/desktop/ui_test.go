@@ -0,0 +1,187 @@
+package desktop
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/astercloud/aster/pkg/types"
+	"github.com/astercloud/aster/pkg/uiproto"
+)
+
+func TestUIMessageRoundTrip(t *testing.T) {
+	app, bridge := newMultiWindowApp(t)
+	summary := createTestAgent(t, bridge, "w1", "coder")
+	ag, _ := app.GetAgent(summary.ID)
+	drainEvents(bridge)
+
+	surfaces := ag.UISurfaces()
+	msgs := []*types.AsterUIMessage{
+		{CreateSurface: &types.CreateSurfaceMessage{SurfaceID: "confirm"}},
+		{SurfaceUpdate: &types.SurfaceUpdateMessage{SurfaceID: "confirm", Components: []types.ComponentDefinition{
+			{ID: "ok", Component: types.ComponentSpec{Button: &types.ButtonProps{
+				Label:  types.NewLiteralString("OK"),
+				Action: "confirm",
+			}}},
+		}}},
+		{BeginRendering: &types.BeginRenderingMessage{SurfaceID: "confirm", Root: "ok"}},
+	}
+	for _, msg := range msgs {
+		if err := surfaces.Apply(msg); err != nil {
+			t.Fatalf("Apply() error = %v", err)
+		}
+	}
+
+	// UI messages reported by the RenderUI tool reach the frontend
+	ag.GetEventBus().EmitProgress(&types.ProgressToolIntermediateEvent{
+		Call:  types.ToolCallSnapshot{ID: "call-1", Name: "RenderUI"},
+		Label: "ui",
+		Data:  msgs[2],
+		UI:    msgs[2],
+	})
+	var got *UIMessageEvent
+	deadline := time.Now().Add(2 * time.Second)
+	for got == nil && time.Now().Before(deadline) {
+		for _, e := range drainEvents(bridge) {
+			if e.Type == EventTypeUIMessage {
+				got = e.Data.(*UIMessageEvent)
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got == nil || got.CallID != "call-1" || got.Message.BeginRendering == nil {
+		t.Fatalf("ui_message event = %+v", got)
+	}
+
+	// User actions are validated against the rendered surface
+	control := ag.Subscribe([]types.AgentChannel{types.ChannelControl}, nil)
+	defer ag.Unsubscribe(control)
+
+	resp, _ := bridge.SendUIAction(summary.ID, types.ClientMessage{UserAction: &types.UserActionMessage{
+		Name: "delete", SurfaceID: "confirm", SourceComponentID: "ok",
+	}})
+	if resp.Success {
+		t.Error("undeclared action was accepted")
+	}
+	resp, _ = bridge.SendUIAction(summary.ID, types.ClientMessage{UserAction: &types.UserActionMessage{
+		Name: "confirm", SurfaceID: "confirm", SourceComponentID: "ok",
+	}})
+	if !resp.Success {
+		t.Fatalf("SendUIAction() = %+v", resp)
+	}
+
+	select {
+	case env := <-control:
+		e, ok := env.Event.(*types.ControlUIActionEvent)
+		if !ok || e.Action != "confirm" || e.SurfaceID != "confirm" || e.ComponentID != "ok" {
+			t.Errorf("control event = %#v", env.Event)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("no ControlUIActionEvent emitted")
+	}
+
+	if resp, _ := bridge.SendUIAction("missing", types.ClientMessage{}); resp.Success {
+		t.Error("unknown agent accepted")
+	}
+}
+
+func TestUICatalogRoutes(t *testing.T) {
+	app, err := NewApp(&AppConfig{Framework: FrameworkWeb, DataDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("NewApp() error = %v", err)
+	}
+	registry := uiproto.NewCatalogRegistry()
+	for _, v := range []string{"1.0.0", "1.1.0"} {
+		err := registry.Register(&uiproto.Catalog{ID: "charts", Version: v, Components: map[string]*uiproto.ComponentSchema{
+			"Chart": {Custom: true, Schema: map[string]any{"type": "object"}},
+		}})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	app.SetCatalogRegistry(registry)
+	mux := http.NewServeMux()
+	uiRoutes(mux, app.handleMessage)
+
+	get := func(path string) (int, *BackendResponse) {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		var resp BackendResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		return rec.Code, &resp
+	}
+
+	_, resp := get("/api/ui/catalogs")
+	list, _ := resp.Data.(map[string]any)["catalogs"].([]any)
+	if len(list) != 2 {
+		t.Fatalf("GET /api/ui/catalogs = %+v", resp)
+	}
+	if first := list[0].(map[string]any); first["id"] != "charts" || first["version"] != "1.1.0" || len(first["versions"].([]any)) != 2 {
+		t.Errorf("charts summary = %v", first)
+	}
+
+	for path, want := range map[string]string{
+		"/api/ui/catalogs/charts":               "1.1.0",
+		"/api/ui/catalogs/charts@1.0.0":         "1.0.0",
+		"/api/ui/catalogs/charts?version=1.0.0": "1.0.0",
+	} {
+		code, resp := get(path)
+		data, _ := resp.Data.(map[string]any)
+		if code != http.StatusOK || data["version"] != want {
+			t.Errorf("GET %s = %d %+v, want version %s", path, code, resp, want)
+		}
+		if comps, _ := data["components"].(map[string]any); comps["Chart"] == nil {
+			t.Errorf("GET %s components = %v", path, data["components"])
+		}
+	}
+
+	if code, _ := get("/api/ui/catalogs/missing"); code != http.StatusNotFound {
+		t.Errorf("GET missing catalog = %d, want 404", code)
+	}
+}
+
+func TestExportSurface(t *testing.T) {
+	app, bridge := newMultiWindowApp(t)
+	summary := createTestAgent(t, bridge, "w1", "coder")
+	ag, _ := app.GetAgent(summary.ID)
+
+	msgs := []*types.AsterUIMessage{
+		{CreateSurface: &types.CreateSurfaceMessage{SurfaceID: "report"}},
+		{SurfaceUpdate: &types.SurfaceUpdateMessage{SurfaceID: "report", Components: []types.ComponentDefinition{
+			{ID: "title", Component: types.ComponentSpec{Text: &types.TextProps{Text: types.NewPathReference("/title")}}},
+		}}},
+		{DataModelUpdate: &types.DataModelUpdateMessage{SurfaceID: "report", Path: "/title", Contents: "Weekly summary"}},
+		{BeginRendering: &types.BeginRenderingMessage{SurfaceID: "report", Root: "title"}},
+	}
+	for _, msg := range msgs {
+		if err := ag.UISurfaces().Apply(msg); err != nil {
+			t.Fatalf("Apply() error = %v", err)
+		}
+	}
+
+	for format, want := range map[string]string{
+		"html":  "<!DOCTYPE html>",
+		"react": "export default function Report()",
+	} {
+		resp, _ := bridge.ExportUISurface(summary.ID, "report", format)
+		if !resp.Success {
+			t.Fatalf("ExportUISurface(%s) = %+v", format, resp)
+		}
+		exported := resp.Data.(ExportedSurface)
+		if exported.Format != format || !strings.Contains(exported.Content, want) || !strings.Contains(exported.Content, "Weekly summary") {
+			t.Errorf("ExportUISurface(%s) = %+v", format, exported)
+		}
+	}
+
+	if resp, _ := bridge.ExportUISurface(summary.ID, "missing", "html"); resp.Success {
+		t.Error("unknown surface exported")
+	}
+	if resp, _ := bridge.ExportUISurface(summary.ID, "report", "pdf"); resp.Success {
+		t.Error("unsupported format exported")
+	}
+}
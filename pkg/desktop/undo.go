@@ -0,0 +1,198 @@
+package desktop
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+
+	"github.com/astercloud/aster/pkg/session"
+)
+
+// historyUndoneKey lists the IDs of history events hidden by undo in the session metadata
+const historyUndoneKey = "undone_events"
+
+// UndoRequest is the body of POST /api/undo
+type UndoRequest struct {
+	AgentID string `json:"agent_id"`
+}
+
+// handleUndo undoes the agent's last turn, reverting its file edits, and hides
+// the turn's messages from the conversation history.
+func (a *App) handleUndo(msg *FrontendMessage) (*BackendResponse, error) {
+	ag, ok := a.GetAgent(msg.AgentID)
+	if !ok {
+		return &BackendResponse{
+			ID:      msg.ID,
+			Success: false,
+			Error:   "agent not found: " + msg.AgentID,
+		}, nil
+	}
+
+	ctx := context.Background()
+	result, err := ag.UndoLastTurn(ctx)
+	if err != nil {
+		return &BackendResponse{
+			ID:      msg.ID,
+			Success: false,
+			Error:   err.Error(),
+		}, nil
+	}
+	if err := a.undoHistory(ctx, msg.AgentID); err != nil && !errors.Is(err, ErrHistoryNotConfigured) {
+		return &BackendResponse{
+			ID:      msg.ID,
+			Success: false,
+			Error:   err.Error(),
+		}, nil
+	}
+
+	return &BackendResponse{
+		ID:      msg.ID,
+		Success: true,
+		Data:    result, // *agent.UndoResult
+	}, nil
+}
+
+// undoHistory hides the last user message and everything after it in the agent's
+// active session. Session events are append-only, so hidden IDs are kept in the
+// session metadata and filtered out by History.
+func (a *App) undoHistory(ctx context.Context, agentID string) error {
+	h := a.history
+	h.mu.Lock()
+	if h.service == nil {
+		h.mu.Unlock()
+		return ErrHistoryNotConfigured
+	}
+	sessionID, err := a.activeSessionLocked(ctx, agentID, false)
+	var hidden []string
+	if err == nil && sessionID != "" {
+		hidden, err = a.undoLastTurnLocked(ctx, sessionID)
+	}
+	h.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("undo history: %w", err)
+	}
+	if len(hidden) == 0 {
+		return nil
+	}
+
+	_ = a.bridge.SendEvent(&FrontendEvent{
+		Type:    EventTypeHistoryUpdated,
+		AgentID: agentID,
+		Data:    &HistoryUpdate{SessionID: sessionID, Reason: "undone", EntryIDs: hidden},
+	})
+	return nil
+}
+
+// undoLastTurnLocked marks the session's last visible turn as undone and returns its event IDs
+func (a *App) undoLastTurnLocked(ctx context.Context, sessionID string) ([]string, error) {
+	undone, err := a.undoneEventsLocked(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	events, err := a.history.service.GetEvents(ctx, sessionID, nil)
+	if err != nil {
+		return nil, fmt.Errorf("get events: %w", err)
+	}
+	events = visibleEvents(events, undone)
+
+	start := -1
+	for i := len(events) - 1; i >= 0; i-- {
+		if events[i].Author == "user" {
+			start = i
+			break
+		}
+	}
+	if start < 0 {
+		return nil, nil
+	}
+
+	hidden := make([]string, 0, len(events)-start)
+	for _, evt := range events[start:] {
+		hidden = append(hidden, evt.ID)
+	}
+	ids := append(slices.Sorted(maps.Keys(undone)), hidden...)
+	err = a.history.service.Update(ctx, &session.UpdateRequest{
+		SessionID: sessionID,
+		Metadata:  map[string]any{historyUndoneKey: ids},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("update session: %w", err)
+	}
+	return hidden, nil
+}
+
+// undoneEventsLocked returns the IDs of events hidden by undo in a session
+func (a *App) undoneEventsLocked(ctx context.Context, sessionID string) (map[string]bool, error) {
+	sess, err := a.history.service.Get(ctx, &session.GetRequest{
+		AppName:   historyAppName,
+		UserID:    a.historyUserID(),
+		SessionID: sessionID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("get session: %w", err)
+	}
+	return undoneEvents(sess), nil
+}
+
+// undoneEvents returns the IDs of events hidden by undo, read from the session metadata
+func undoneEvents(sess session.Session) map[string]bool {
+	undone := make(map[string]bool)
+	// Metadata may have round-tripped through JSON
+	switch ids := sess.Metadata()[historyUndoneKey].(type) {
+	case []string:
+		for _, id := range ids {
+			undone[id] = true
+		}
+	case []any:
+		for _, id := range ids {
+			if s, ok := id.(string); ok {
+				undone[s] = true
+			}
+		}
+	}
+	return undone
+}
+
+// visibleEvents drops events hidden by undo
+func visibleEvents(events []session.Event, undone map[string]bool) []session.Event {
+	if len(undone) == 0 {
+		return events
+	}
+	visible := make([]session.Event, 0, len(events))
+	for _, evt := range events {
+		if !undone[evt.ID] {
+			visible = append(visible, evt)
+		}
+	}
+	return visible
+}
+
+// undoRoutes registers the undo endpoint on an HTTP bridge mux
+func undoRoutes(mux *http.ServeMux, handler MessageHandler) {
+	mux.HandleFunc("/api/undo", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req UndoRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, BackendResponse{
+				Success: false,
+				Error:   err.Error(),
+			})
+			return
+		}
+
+		resp, _ := handler(&FrontendMessage{
+			ID:      generateID(),
+			Type:    MsgTypeUndo,
+			AgentID: req.AgentID,
+		})
+		writeJSON(w, http.StatusOK, resp)
+	})
+}
@@ -0,0 +1,517 @@
+package desktop
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/astercloud/aster/pkg/httpclient"
+	"github.com/astercloud/aster/pkg/update"
+)
+
+// DefaultUpdateCheckInterval is the default interval between update checks
+const DefaultUpdateCheckInterval = 6 * time.Hour
+
+// ErrUpdateNotReady is returned by install_update before an update has been downloaded and verified
+var ErrUpdateNotReady = errors.New("no verified update ready to install")
+
+// UpdateConfig enables automatic update checks against a release manifest
+// (see pkg/update and the server's /v1/updates routes)
+type UpdateConfig struct {
+	// ManifestURL is the release manifest, e.g. https://host/v1/updates/manifest
+	ManifestURL string `json:"manifest_url"`
+
+	// PublicKey is the base64 ed25519 key installers must be signed with
+	PublicKey string `json:"public_key"`
+
+	// CurrentVersion is the version of the running app
+	CurrentVersion string `json:"current_version"`
+
+	// Channel is the initial release channel (defaults to stable); set_update_channel overrides it
+	Channel update.Channel `json:"channel,omitempty"`
+
+	// CheckInterval is the interval between background checks (defaults to 6h)
+	CheckInterval time.Duration `json:"check_interval,omitempty"`
+
+	// Platform overrides the GOOS/GOARCH installers are picked for
+	Platform string `json:"platform,omitempty"`
+}
+
+// UpdateInstaller applies a downloaded, verified installer and restarts the
+// app. Shells that install updates natively can instead act on the path in
+// the update_ready event.
+type UpdateInstaller func(ctx context.Context, path string, release *update.Release) error
+
+// UpdateChannelPayload is the payload for set_update_channel messages
+type UpdateChannelPayload struct {
+	Channel update.Channel `json:"channel"`
+}
+
+// UpdateStatus describes the update state shown to the frontend
+type UpdateStatus struct {
+	CurrentVersion string         `json:"current_version"`
+	Channel        update.Channel `json:"channel"`
+
+	// Available is the release this install should update to; nil when up to date
+	Available *update.Result `json:"available,omitempty"`
+
+	// Ready is set once the installer is downloaded and its signature verified
+	Ready bool   `json:"ready"`
+	Path  string `json:"path,omitempty"`
+
+	CheckedAt time.Time `json:"checked_at,omitzero"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// updatePrefs is the update state persisted in the data directory
+type updatePrefs struct {
+	InstallID string         `json:"install_id"`
+	Channel   update.Channel `json:"channel,omitempty"`
+}
+
+// updateState tracks update checks and the downloaded installer
+type updateState struct {
+	mu        sync.Mutex
+	dir       string
+	prefs     *updatePrefs
+	status    UpdateStatus
+	installer UpdateInstaller
+	cancel    context.CancelFunc
+
+	// check serializes update checks and downloads
+	check sync.Mutex
+}
+
+func newUpdateState(cfg *AppConfig) *updateState {
+	s := &updateState{dir: filepath.Join(cfg.DataDir, "updates")}
+	if cfg.Update != nil {
+		s.status.CurrentVersion = cfg.Update.CurrentVersion
+		s.status.Channel = cfg.Update.Channel
+	}
+	if s.status.Channel == "" {
+		s.status.Channel = update.ChannelStable
+	}
+	return s
+}
+
+// loadPrefsLocked reads the install ID and channel, creating the install ID on first use
+func (s *updateState) loadPrefsLocked() *updatePrefs {
+	if s.prefs != nil {
+		return s.prefs
+	}
+	prefs := &updatePrefs{}
+	if data, err := os.ReadFile(filepath.Join(s.dir, "prefs.json")); err == nil {
+		_ = json.Unmarshal(data, prefs) // Corrupt prefs start over with a new install ID
+	}
+	if prefs.Channel != "" {
+		s.status.Channel = prefs.Channel
+	}
+	if prefs.InstallID == "" {
+		buf := make([]byte, 16)
+		_, _ = rand.Read(buf)
+		prefs.InstallID = hex.EncodeToString(buf)
+		_ = s.savePrefsLocked(prefs)
+	}
+	s.prefs = prefs
+	return prefs
+}
+
+func (s *updateState) savePrefsLocked(prefs *updatePrefs) error {
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return fmt.Errorf("create update dir: %w", err)
+	}
+	data, err := json.Marshal(prefs)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(s.dir, "prefs.json"), data, 0o600)
+}
+
+// SetUpdateInstaller sets how install_update applies a verified installer
+func (a *App) SetUpdateInstaller(installer UpdateInstaller) {
+	a.update.mu.Lock()
+	defer a.update.mu.Unlock()
+	a.update.installer = installer
+}
+
+// UpdateStatus returns the current update state
+func (a *App) UpdateStatus() *UpdateStatus {
+	s := a.update
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.loadPrefsLocked()
+	status := s.status
+	return &status
+}
+
+// SetUpdateChannel switches the release channel and remembers it across restarts.
+// A pending update from the previous channel is dropped.
+func (a *App) SetUpdateChannel(channel update.Channel) error {
+	channel, err := update.ParseChannel(string(channel))
+	if err != nil {
+		return err
+	}
+	s := a.update
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	prefs := *s.loadPrefsLocked()
+	prefs.Channel = channel
+	if err := s.savePrefsLocked(&prefs); err != nil {
+		return err
+	}
+	*s.prefs = prefs
+	if s.status.Channel != channel {
+		s.status.Channel = channel
+		s.status.Available, s.status.Ready, s.status.Path = nil, false, ""
+	}
+	return nil
+}
+
+// CheckForUpdate fetches the release manifest and, when a newer release is
+// available for this install, downloads and verifies its installer. The
+// frontend is told with update_available, then update_ready once it can offer
+// a restart.
+func (a *App) CheckForUpdate(ctx context.Context) (*UpdateStatus, error) {
+	cfg := a.config.Update
+	if cfg == nil || cfg.ManifestURL == "" {
+		return nil, errors.New("updates are not configured")
+	}
+	if !update.ValidVersion(cfg.CurrentVersion) {
+		return nil, fmt.Errorf("invalid current version %q", cfg.CurrentVersion)
+	}
+	key, err := update.ParsePublicKey(cfg.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	s := a.update
+	s.check.Lock()
+	defer s.check.Unlock()
+
+	s.mu.Lock()
+	prefs := *s.loadPrefsLocked()
+	query := update.Query{
+		Channel:   s.status.Channel,
+		Platform:  cfg.Platform,
+		Version:   cfg.CurrentVersion,
+		InstallID: prefs.InstallID,
+	}
+	previous := s.status.Available
+	s.mu.Unlock()
+	if query.Platform == "" {
+		query.Platform = runtime.GOOS + "/" + runtime.GOARCH
+	}
+
+	result, err := a.checkManifest(ctx, cfg.ManifestURL, query)
+	if err != nil {
+		return a.setUpdateError(err), err
+	}
+
+	s.mu.Lock()
+	if s.status.Channel != query.Channel {
+		// The channel changed while checking; the result is stale
+		s.mu.Unlock()
+		return a.UpdateStatus(), nil
+	}
+	s.status.Available = result
+	s.status.CheckedAt = time.Now()
+	s.status.Error = ""
+	if result == nil || previous == nil || previous.Release.Version != result.Release.Version {
+		s.status.Ready, s.status.Path = false, ""
+	}
+	ready := s.status.Ready
+	s.mu.Unlock()
+	if result == nil || ready {
+		return a.UpdateStatus(), nil
+	}
+
+	if previous == nil || previous.Release.Version != result.Release.Version {
+		_ = a.bridge.SendEvent(&FrontendEvent{
+			Type: EventTypeUpdateAvailable,
+			Data: a.UpdateStatus(),
+		})
+	}
+
+	path, err := a.downloadUpdate(ctx, cfg.ManifestURL, key, result)
+	if err != nil {
+		return a.setUpdateError(err), err
+	}
+
+	s.mu.Lock()
+	s.status.Ready, s.status.Path = true, path
+	s.mu.Unlock()
+
+	status := a.UpdateStatus()
+	_ = a.bridge.SendEvent(&FrontendEvent{
+		Type: EventTypeUpdateReady,
+		Data: status,
+	})
+	a.notifyActivity(&Notification{
+		Kind:  NotificationInfo,
+		Title: "Update ready",
+		Body:  fmt.Sprintf("Version %s is ready; restart to update", result.Release.Version),
+	})
+	return status, nil
+}
+
+// setUpdateError records a failed check and returns the resulting status
+func (a *App) setUpdateError(err error) *UpdateStatus {
+	a.update.mu.Lock()
+	a.update.status.CheckedAt = time.Now()
+	a.update.status.Error = err.Error()
+	a.update.mu.Unlock()
+	return a.UpdateStatus()
+}
+
+// checkManifest fetches the release manifest and picks the release for query
+func (a *App) checkManifest(ctx context.Context, manifestURL string, query update.Query) (*update.Result, error) {
+	data, err := httpGet(ctx, manifestURL, 0)
+	if err != nil {
+		return nil, fmt.Errorf("fetch release manifest: %w", err)
+	}
+	m, err := update.ParseManifest(data)
+	if err != nil {
+		return nil, err
+	}
+	return m.Check(query), nil
+}
+
+// downloadUpdate downloads the release installer into the data directory and
+// verifies its digest and signature. A verified installer left by an earlier
+// run is reused.
+func (a *App) downloadUpdate(ctx context.Context, manifestURL string, key []byte, result *update.Result) (string, error) {
+	assetURL, err := resolveAssetURL(manifestURL, result.Asset.URL)
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(a.update.dir, result.Release.Version)
+	dest := filepath.Join(dir, path.Base(assetURL.Path))
+
+	if data, err := os.ReadFile(dest); err == nil && update.VerifyAsset(key, result.Release.Version, result.Asset, data) == nil {
+		return dest, nil
+	}
+
+	data, err := httpGet(ctx, assetURL.String(), result.Asset.Size)
+	if err != nil {
+		return "", fmt.Errorf("download update: %w", err)
+	}
+	if err := update.VerifyAsset(key, result.Release.Version, result.Asset, data); err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("create update dir: %w", err)
+	}
+	tmp := dest + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o755); err != nil {
+		return "", fmt.Errorf("write update: %w", err)
+	}
+	return dest, os.Rename(tmp, dest)
+}
+
+// resolveAssetURL resolves an asset URL relative to the manifest URL
+func resolveAssetURL(manifestURL, assetURL string) (*url.URL, error) {
+	base, err := url.Parse(manifestURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid manifest URL: %w", err)
+	}
+	ref, err := url.Parse(assetURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid asset URL: %w", err)
+	}
+	return base.ResolveReference(ref), nil
+}
+
+// httpGet reads a URL, refusing bodies larger than limit when limit is set
+func httpGet(ctx context.Context, rawURL string, limit int64) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := httpclient.New(0).Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: status %d", rawURL, resp.StatusCode)
+	}
+	var body io.Reader = resp.Body
+	if limit > 0 {
+		body = io.LimitReader(resp.Body, limit+1)
+	}
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+	if limit > 0 && int64(len(data)) > limit {
+		return nil, fmt.Errorf("GET %s: larger than %d bytes", rawURL, limit)
+	}
+	return data, nil
+}
+
+// InstallUpdate hands the verified installer to the update installer, which restarts the app
+func (a *App) InstallUpdate(ctx context.Context) error {
+	s := a.update
+	s.mu.Lock()
+	installer, status := s.installer, s.status
+	s.mu.Unlock()
+	if !status.Ready || status.Available == nil {
+		return ErrUpdateNotReady
+	}
+	if installer == nil {
+		return errors.New("no update installer configured; install " + status.Path + " manually")
+	}
+	return installer(ctx, status.Path, status.Available.Release)
+}
+
+// startUpdateChecker checks for updates in the background until Stop
+func (a *App) startUpdateChecker(ctx context.Context) {
+	cfg := a.config.Update
+	if cfg == nil || cfg.ManifestURL == "" {
+		return
+	}
+	interval := cfg.CheckInterval
+	if interval <= 0 {
+		interval = DefaultUpdateCheckInterval
+	}
+
+	ctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	a.update.mu.Lock()
+	if a.update.cancel != nil {
+		a.update.cancel()
+	}
+	a.update.cancel = cancel
+	a.update.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if a.Online() {
+				_, _ = a.CheckForUpdate(ctx) // Failures are reported in UpdateStatus.Error
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// stopUpdateChecker stops background update checks
+func (a *App) stopUpdateChecker() {
+	a.update.mu.Lock()
+	defer a.update.mu.Unlock()
+	if a.update.cancel != nil {
+		a.update.cancel()
+		a.update.cancel = nil
+	}
+}
+
+func (a *App) handleUpdateStatus(msg *FrontendMessage) (*BackendResponse, error) {
+	return &BackendResponse{
+		ID:      msg.ID,
+		Success: true,
+		Data:    a.UpdateStatus(),
+	}, nil
+}
+
+func (a *App) handleCheckUpdate(msg *FrontendMessage) (*BackendResponse, error) {
+	status, err := a.CheckForUpdate(context.Background())
+	if err != nil {
+		return &BackendResponse{
+			ID:      msg.ID,
+			Success: false,
+			Error:   err.Error(),
+			Data:    status,
+		}, nil
+	}
+	return &BackendResponse{
+		ID:      msg.ID,
+		Success: true,
+		Data:    status,
+	}, nil
+}
+
+func (a *App) handleSetUpdateChannel(msg *FrontendMessage) (*BackendResponse, error) {
+	var payload UpdateChannelPayload
+	if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+		return &BackendResponse{
+			ID:      msg.ID,
+			Success: false,
+			Error:   fmt.Sprintf("invalid payload: %v", err),
+		}, nil
+	}
+	if err := a.SetUpdateChannel(payload.Channel); err != nil {
+		return &BackendResponse{
+			ID:      msg.ID,
+			Success: false,
+			Error:   err.Error(),
+		}, nil
+	}
+	if cfg := a.config.Update; cfg != nil && cfg.ManifestURL != "" && a.Online() {
+		go func() { _, _ = a.CheckForUpdate(context.Background()) }()
+	}
+	return &BackendResponse{
+		ID:      msg.ID,
+		Success: true,
+		Data:    a.UpdateStatus(),
+	}, nil
+}
+
+func (a *App) handleInstallUpdate(msg *FrontendMessage) (*BackendResponse, error) {
+	if err := a.InstallUpdate(context.Background()); err != nil {
+		return &BackendResponse{
+			ID:      msg.ID,
+			Success: false,
+			Error:   err.Error(),
+		}, nil
+	}
+	return &BackendResponse{
+		ID:      msg.ID,
+		Success: true,
+	}, nil
+}
+
+// updateRoutes registers the update endpoints on an HTTP bridge mux
+func updateRoutes(mux *http.ServeMux, handler MessageHandler) {
+	send := func(w http.ResponseWriter, msgType MessageType) {
+		resp, _ := handler(&FrontendMessage{ID: generateID(), Type: msgType})
+		writeJSON(w, http.StatusOK, resp)
+	}
+	mux.HandleFunc("/api/update", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		send(w, MsgTypeUpdateStatus)
+	})
+	mux.HandleFunc("/api/update/check", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		send(w, MsgTypeCheckUpdate)
+	})
+	mux.HandleFunc("/api/update/install", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		send(w, MsgTypeInstallUpdate)
+	})
+	mux.HandleFunc("/api/update/channel", shellMessageHandler(handler, MsgTypeSetUpdateChannel, func() any { return &UpdateChannelPayload{} }))
+}
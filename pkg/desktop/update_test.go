@@ -0,0 +1,174 @@
+package desktop
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/astercloud/aster/pkg/update"
+)
+
+// releaseServer hosts a release manifest and installers like the server's /v1/updates routes
+type releaseServer struct {
+	*httptest.Server
+	key       ed25519.PrivateKey
+	manifest  update.Manifest
+	installer []byte
+}
+
+func newReleaseServer(t *testing.T) *releaseServer {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs := &releaseServer{key: key, installer: []byte("aster 1.3.0 installer")}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/updates/manifest", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(rs.manifest)
+	})
+	mux.HandleFunc("/updates/assets/aster.dmg", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(rs.installer)
+	})
+	rs.Server = httptest.NewServer(mux)
+	t.Cleanup(rs.Close)
+	return rs
+}
+
+// publish replaces the manifest with a signed release for the test platform
+func (rs *releaseServer) publish(version string, channel update.Channel) {
+	asset := update.Asset{Platform: "test/amd64", URL: "assets/aster.dmg"}
+	update.SignAsset(rs.key, version, &asset, rs.installer)
+	rs.manifest = update.Manifest{Releases: []update.Release{{Version: version, Channel: channel, Assets: []update.Asset{asset}}}}
+}
+
+func (rs *releaseServer) publicKey() string {
+	return base64.StdEncoding.EncodeToString(rs.key.Public().(ed25519.PublicKey))
+}
+
+func newUpdateTestApp(t *testing.T, rs *releaseServer, dataDir string) (*App, *WailsBridge) {
+	t.Helper()
+	app, err := NewApp(&AppConfig{
+		Framework: FrameworkWails,
+		WorkDir:   t.TempDir(),
+		DataDir:   dataDir,
+		Update: &UpdateConfig{
+			ManifestURL:    rs.URL + "/updates/manifest",
+			PublicKey:      rs.publicKey(),
+			CurrentVersion: "1.2.0",
+			Platform:       "test/amd64",
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewApp() error = %v", err)
+	}
+	t.Cleanup(func() { _ = app.Stop(context.Background()) })
+	return app, app.Bridge().(*WailsBridge)
+}
+
+func TestCheckForUpdate(t *testing.T) {
+	rs := newReleaseServer(t)
+	rs.publish("1.3.0", update.ChannelStable)
+	app, bridge := newUpdateTestApp(t, rs, t.TempDir())
+
+	resp, err := bridge.CheckUpdate()
+	if err != nil || !resp.Success {
+		t.Fatalf("CheckUpdate() = %+v, %v", resp, err)
+	}
+	status := resp.Data.(*UpdateStatus)
+	if !status.Ready || status.Available.Release.Version != "1.3.0" {
+		t.Fatalf("status = %+v", status)
+	}
+	data, err := os.ReadFile(status.Path)
+	if err != nil || string(data) != string(rs.installer) {
+		t.Fatalf("downloaded installer = %q, %v", data, err)
+	}
+
+	var seen []EventType
+	for _, e := range drainEvents(bridge) {
+		if e.Type == EventTypeUpdateAvailable || e.Type == EventTypeUpdateReady {
+			seen = append(seen, e.Type)
+		}
+	}
+	if len(seen) != 2 || seen[0] != EventTypeUpdateAvailable || seen[1] != EventTypeUpdateReady {
+		t.Errorf("update events = %v", seen)
+	}
+
+	// A repeated check does not prompt again
+	if _, err := app.CheckForUpdate(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range drainEvents(bridge) {
+		if e.Type == EventTypeUpdateAvailable || e.Type == EventTypeUpdateReady {
+			t.Errorf("unexpected %s event on repeated check", e.Type)
+		}
+	}
+
+	resp, _ = bridge.InstallUpdate()
+	if resp.Success {
+		t.Error("InstallUpdate() without an installer should fail")
+	}
+	var installed string
+	app.SetUpdateInstaller(func(_ context.Context, path string, release *update.Release) error {
+		installed = path + "@" + release.Version
+		return nil
+	})
+	if resp, _ = bridge.InstallUpdate(); !resp.Success || installed != status.Path+"@1.3.0" {
+		t.Errorf("InstallUpdate() = %+v, installed %q", resp, installed)
+	}
+}
+
+func TestCheckForUpdateRejectsTamperedInstaller(t *testing.T) {
+	rs := newReleaseServer(t)
+	rs.publish("1.3.0", update.ChannelStable)
+	rs.installer = []byte("aster 1.3.0 INSTALLER") // same size, different content
+	app, _ := newUpdateTestApp(t, rs, t.TempDir())
+
+	status, err := app.CheckForUpdate(context.Background())
+	if !errors.Is(err, update.ErrInvalidSignature) {
+		t.Fatalf("CheckForUpdate() error = %v", err)
+	}
+	if status.Ready || status.Error == "" {
+		t.Errorf("status = %+v", status)
+	}
+	if err := app.InstallUpdate(context.Background()); !errors.Is(err, ErrUpdateNotReady) {
+		t.Errorf("InstallUpdate() error = %v", err)
+	}
+}
+
+func TestUpdateChannel(t *testing.T) {
+	rs := newReleaseServer(t)
+	rs.publish("1.3.0-beta.1", update.ChannelBeta)
+	dataDir := t.TempDir()
+	app, bridge := newUpdateTestApp(t, rs, dataDir)
+
+	status, err := app.CheckForUpdate(context.Background())
+	if err != nil || status.Available != nil {
+		t.Fatalf("stable install offered a beta: %+v, %v", status, err)
+	}
+	installID := app.update.prefs.InstallID
+
+	if resp, _ := bridge.SetUpdateChannel("nightly"); resp.Success {
+		t.Error("unknown channel accepted")
+	}
+	if err := app.SetUpdateChannel(update.ChannelBeta); err != nil {
+		t.Fatal(err)
+	}
+	status, err = app.CheckForUpdate(context.Background())
+	if err != nil || status.Available == nil || status.Available.Release.Version != "1.3.0-beta.1" {
+		t.Fatalf("beta check = %+v, %v", status, err)
+	}
+
+	// The channel and install ID survive a restart
+	restarted, _ := newUpdateTestApp(t, rs, dataDir)
+	status = restarted.UpdateStatus()
+	if status.Channel != update.ChannelBeta || restarted.update.prefs.InstallID != installID {
+		t.Errorf("after restart: channel %s, install ID %s (want %s)", status.Channel, restarted.update.prefs.InstallID, installID)
+	}
+}
@@ -0,0 +1,544 @@
+package desktop
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/astercloud/aster/pkg/agent"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+// Window is a frontend window bound to a set of agents.
+// A window only receives events of the agents it is bound to, plus
+// app-level events that carry no agent ID (tray, notifications, dialogs).
+type Window struct {
+	ID      string    `json:"id"`
+	Title   string    `json:"title,omitempty"`
+	Agents  []string  `json:"agents"`
+	Created time.Time `json:"created"`
+}
+
+// WindowPayload is the payload for window messages
+type WindowPayload struct {
+	WindowID string `json:"window_id,omitempty"`
+	Title    string `json:"title,omitempty"`
+}
+
+// BindAgentPayload is the payload for bind/unbind messages
+type BindAgentPayload struct {
+	AgentID string `json:"agent_id"`
+}
+
+// CreateAgentPayload is the payload for MsgTypeCreateAgent
+type CreateAgentPayload struct {
+	TemplateID string         `json:"template_id"`
+	Model      string         `json:"model,omitempty"`
+	WorkDir    string         `json:"work_dir,omitempty"`
+	Metadata   map[string]any `json:"metadata,omitempty"`
+}
+
+// AgentSummary describes an agent in the agent directory
+type AgentSummary struct {
+	ID      string                  `json:"id"`
+	State   types.AgentRuntimeState `json:"state"`
+	Model   string                  `json:"model,omitempty"`
+	Windows []string                `json:"windows,omitempty"`
+}
+
+// TemplateSummary describes a template offered by MsgTypeCreateAgent
+type TemplateSummary struct {
+	ID      string `json:"id"`
+	Version string `json:"version,omitempty"`
+	Model   string `json:"model,omitempty"`
+}
+
+// AgentFactory creates agents on behalf of the frontend
+type AgentFactory interface {
+	// CreateAgent creates an agent from the requested template
+	CreateAgent(ctx context.Context, req *CreateAgentPayload) (*agent.Agent, error)
+
+	// Templates lists the templates that can be selected
+	Templates() []*types.AgentTemplateDefinition
+}
+
+// NewAgentFactory returns an AgentFactory that creates agents with agent.Create.
+// base supplies the model, sandbox and other defaults; each request overrides
+// the template, model and work dir.
+func NewAgentFactory(deps *agent.Dependencies, base *types.AgentConfig) AgentFactory {
+	return &templateAgentFactory{deps: deps, base: base}
+}
+
+type templateAgentFactory struct {
+	deps *agent.Dependencies
+	base *types.AgentConfig
+}
+
+func (f *templateAgentFactory) CreateAgent(ctx context.Context, req *CreateAgentPayload) (*agent.Agent, error) {
+	cfg := types.AgentConfig{}
+	if f.base != nil {
+		cfg = *f.base
+	}
+	cfg.AgentID = ""
+
+	if req.TemplateID != "" {
+		cfg.TemplateID = req.TemplateID
+	}
+	if cfg.TemplateID == "" {
+		return nil, errors.New("template_id is required")
+	}
+	if f.deps.TemplateRegistry != nil {
+		if _, err := f.deps.TemplateRegistry.Get(cfg.TemplateID); err != nil {
+			return nil, err
+		}
+	}
+
+	if req.Model != "" {
+		mc := types.ModelConfig{}
+		if cfg.ModelConfig != nil {
+			mc = *cfg.ModelConfig
+		}
+		mc.Model = req.Model
+		cfg.ModelConfig = &mc
+	}
+
+	metadata := make(map[string]any, len(cfg.Metadata)+len(req.Metadata)+1)
+	maps.Copy(metadata, cfg.Metadata)
+	maps.Copy(metadata, req.Metadata)
+	if req.WorkDir != "" {
+		metadata["work_dir"] = req.WorkDir
+		if cfg.Sandbox != nil {
+			sb := *cfg.Sandbox
+			sb.WorkDir = req.WorkDir
+			cfg.Sandbox = &sb
+		}
+	}
+	cfg.Metadata = metadata
+
+	return agent.Create(ctx, &cfg, f.deps)
+}
+
+func (f *templateAgentFactory) Templates() []*types.AgentTemplateDefinition {
+	if f.deps.TemplateRegistry == nil {
+		return nil
+	}
+	return f.deps.TemplateRegistry.List()
+}
+
+// windowRegistry tracks open windows and their agent bindings
+type windowRegistry struct {
+	mu      sync.RWMutex
+	windows map[string]*Window
+}
+
+func newWindowRegistry() *windowRegistry {
+	return &windowRegistry{windows: make(map[string]*Window)}
+}
+
+// SetAgentFactory enables MsgTypeCreateAgent
+func (a *App) SetAgentFactory(factory AgentFactory) {
+	a.agentFactory = factory
+}
+
+// OpenWindow registers a window; an existing window with the same ID is returned as is
+func (a *App) OpenWindow(id, title string) Window {
+	a.windows.mu.Lock()
+	defer a.windows.mu.Unlock()
+	return a.openWindowLocked(id, title).snapshot()
+}
+
+// openWindowLocked returns the window, creating it if needed; caller holds a.windows.mu
+func (a *App) openWindowLocked(id, title string) *Window {
+	if id == "" {
+		id = generateID()
+	}
+	if w, ok := a.windows.windows[id]; ok {
+		return w
+	}
+	w := &Window{ID: id, Title: title, Agents: []string{}, Created: time.Now()}
+	a.windows.windows[id] = w
+	return w
+}
+
+func (w *Window) snapshot() Window {
+	cp := *w
+	cp.Agents = slices.Clone(w.Agents)
+	return cp
+}
+
+// CloseWindow removes a window and its bindings
+func (a *App) CloseWindow(id string) {
+	a.windows.mu.Lock()
+	delete(a.windows.windows, id)
+	a.windows.mu.Unlock()
+}
+
+// Windows returns all open windows
+func (a *App) Windows() []Window {
+	a.windows.mu.RLock()
+	defer a.windows.mu.RUnlock()
+
+	result := make([]Window, 0, len(a.windows.windows))
+	for _, w := range a.windows.windows {
+		result = append(result, w.snapshot())
+	}
+	slices.SortFunc(result, func(x, y Window) int { return x.Created.Compare(y.Created) })
+	return result
+}
+
+// BindAgent routes an agent's events to a window (opening the window if needed)
+func (a *App) BindAgent(windowID, agentID string) error {
+	if windowID == "" {
+		return errors.New("window_id is required")
+	}
+	if _, ok := a.GetAgent(agentID); !ok {
+		return fmt.Errorf("agent not found: %s", agentID)
+	}
+
+	a.windows.mu.Lock()
+	defer a.windows.mu.Unlock()
+	w := a.openWindowLocked(windowID, "")
+	if !slices.Contains(w.Agents, agentID) {
+		w.Agents = append(w.Agents, agentID)
+	}
+	return nil
+}
+
+// UnbindAgent stops routing an agent's events to a window
+func (a *App) UnbindAgent(windowID, agentID string) {
+	a.windows.mu.Lock()
+	defer a.windows.mu.Unlock()
+	if w, ok := a.windows.windows[windowID]; ok {
+		w.Agents = slices.DeleteFunc(w.Agents, func(id string) bool { return id == agentID })
+	}
+}
+
+// WindowsForEvent returns the IDs of windows that should receive an event.
+// Frameworks that emit per window (e.g. Wails runtime.EventsEmit on a
+// specific window) use it to route events from Bridge.SendEvent.
+func (a *App) WindowsForEvent(event *FrontendEvent) []string {
+	a.windows.mu.RLock()
+	defer a.windows.mu.RUnlock()
+
+	var ids []string
+	for id, w := range a.windows.windows {
+		if event.AgentID == "" || slices.Contains(w.Agents, event.AgentID) {
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+// windowAccepts reports whether a client connected for windowID should
+// receive event. Clients without a window ID receive everything (single-window frontends).
+func (a *App) windowAccepts(windowID string, event *FrontendEvent) bool {
+	if a == nil || windowID == "" || event.AgentID == "" {
+		return true
+	}
+	a.windows.mu.RLock()
+	defer a.windows.mu.RUnlock()
+	w, ok := a.windows.windows[windowID]
+	return ok && slices.Contains(w.Agents, event.AgentID)
+}
+
+// windowsForAgentLocked returns windows bound to an agent; caller holds a.windows.mu
+func (a *App) windowsForAgentLocked(agentID string) []string {
+	var ids []string
+	for id, w := range a.windows.windows {
+		if slices.Contains(w.Agents, agentID) {
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+// ListAgents returns the agent directory
+func (a *App) ListAgents() []AgentSummary {
+	a.agentsMu.RLock()
+	agents := make([]*agent.Agent, 0, len(a.agents))
+	for _, ag := range a.agents {
+		agents = append(agents, ag)
+	}
+	a.agentsMu.RUnlock()
+
+	a.windows.mu.RLock()
+	defer a.windows.mu.RUnlock()
+
+	result := make([]AgentSummary, 0, len(agents))
+	for _, ag := range agents {
+		status := ag.Status()
+		result = append(result, AgentSummary{
+			ID:      ag.ID(),
+			State:   status.State,
+			Model:   status.Model,
+			Windows: a.windowsForAgentLocked(ag.ID()),
+		})
+	}
+	slices.SortFunc(result, func(x, y AgentSummary) int { return strings.Compare(x.ID, y.ID) })
+	return result
+}
+
+// Message handlers
+
+func (a *App) handleOpenWindow(msg *FrontendMessage) (*BackendResponse, error) {
+	var payload WindowPayload
+	if len(msg.Payload) > 0 {
+		if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+			return &BackendResponse{
+				ID:      msg.ID,
+				Success: false,
+				Error:   fmt.Sprintf("invalid payload: %v", err),
+			}, nil
+		}
+	}
+	if payload.WindowID == "" {
+		payload.WindowID = msg.WindowID
+	}
+
+	return &BackendResponse{
+		ID:      msg.ID,
+		Success: true,
+		Data:    a.OpenWindow(payload.WindowID, payload.Title),
+	}, nil
+}
+
+func (a *App) handleCloseWindow(msg *FrontendMessage) (*BackendResponse, error) {
+	if msg.WindowID == "" {
+		return &BackendResponse{
+			ID:      msg.ID,
+			Success: false,
+			Error:   "window_id is required",
+		}, nil
+	}
+
+	a.CloseWindow(msg.WindowID)
+	return &BackendResponse{
+		ID:      msg.ID,
+		Success: true,
+	}, nil
+}
+
+func (a *App) handleBindAgent(msg *FrontendMessage, bind bool) (*BackendResponse, error) {
+	agentID := msg.AgentID
+	if len(msg.Payload) > 0 {
+		var payload BindAgentPayload
+		if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+			return &BackendResponse{
+				ID:      msg.ID,
+				Success: false,
+				Error:   fmt.Sprintf("invalid payload: %v", err),
+			}, nil
+		}
+		if payload.AgentID != "" {
+			agentID = payload.AgentID
+		}
+	}
+
+	if bind {
+		if err := a.BindAgent(msg.WindowID, agentID); err != nil {
+			return &BackendResponse{
+				ID:      msg.ID,
+				Success: false,
+				Error:   err.Error(),
+			}, nil
+		}
+	} else {
+		a.UnbindAgent(msg.WindowID, agentID)
+	}
+
+	return &BackendResponse{
+		ID:      msg.ID,
+		Success: true,
+	}, nil
+}
+
+func (a *App) handleListAgents(msg *FrontendMessage) (*BackendResponse, error) {
+	data := map[string]any{
+		"agents": a.ListAgents(),
+	}
+	if a.agentFactory != nil {
+		templates := a.agentFactory.Templates()
+		summaries := make([]TemplateSummary, 0, len(templates))
+		for _, t := range templates {
+			summaries = append(summaries, TemplateSummary{ID: t.ID, Version: t.Version, Model: t.Model})
+		}
+		slices.SortFunc(summaries, func(x, y TemplateSummary) int { return strings.Compare(x.ID, y.ID) })
+		data["templates"] = summaries
+	}
+
+	return &BackendResponse{
+		ID:      msg.ID,
+		Success: true,
+		Data:    data,
+	}, nil
+}
+
+func (a *App) handleCreateAgent(msg *FrontendMessage) (*BackendResponse, error) {
+	if a.agentFactory == nil {
+		return &BackendResponse{
+			ID:      msg.ID,
+			Success: false,
+			Error:   "agent creation not enabled",
+		}, nil
+	}
+
+	var payload CreateAgentPayload
+	if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+		return &BackendResponse{
+			ID:      msg.ID,
+			Success: false,
+			Error:   fmt.Sprintf("invalid payload: %v", err),
+		}, nil
+	}
+	if payload.WorkDir == "" {
+		payload.WorkDir = a.config.WorkDir
+	}
+
+	ag, err := a.agentFactory.CreateAgent(context.Background(), &payload)
+	if err != nil {
+		return &BackendResponse{
+			ID:      msg.ID,
+			Success: false,
+			Error:   fmt.Sprintf("create agent: %v", err),
+		}, nil
+	}
+	if err := a.RegisterAgent(ag); err != nil {
+		_ = ag.Close()
+		return &BackendResponse{
+			ID:      msg.ID,
+			Success: false,
+			Error:   fmt.Sprintf("register agent: %v", err),
+		}, nil
+	}
+
+	// The creating window is bound to the new agent
+	if msg.WindowID != "" {
+		_ = a.BindAgent(msg.WindowID, ag.ID()) // Agent was just registered
+	}
+
+	a.windows.mu.RLock()
+	windows := a.windowsForAgentLocked(ag.ID())
+	a.windows.mu.RUnlock()
+
+	status := ag.Status()
+	return &BackendResponse{
+		ID:      msg.ID,
+		Success: true,
+		Data: AgentSummary{
+			ID:      ag.ID(),
+			State:   status.State,
+			Model:   status.Model,
+			Windows: windows,
+		},
+	}, nil
+}
+
+// HTTP handlers shared by the Tauri, Electron and Web bridges
+
+// windowAgentRoutes registers the agent directory and window endpoints
+func windowAgentRoutes(mux *http.ServeMux, handler MessageHandler) {
+	mux.HandleFunc("/api/agents", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			resp, _ := handler(&FrontendMessage{
+				ID:   generateID(),
+				Type: MsgTypeListAgents,
+			})
+			writeJSON(w, http.StatusOK, resp)
+		case http.MethodPost:
+			var req struct {
+				WindowID string `json:"window_id"`
+				CreateAgentPayload
+			}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				writeJSON(w, http.StatusBadRequest, BackendResponse{
+					Success: false,
+					Error:   err.Error(),
+				})
+				return
+			}
+			resp, _ := handler(&FrontendMessage{
+				ID:       generateID(),
+				Type:     MsgTypeCreateAgent,
+				WindowID: req.WindowID,
+				Payload:  mustMarshal(req.CreateAgentPayload),
+			})
+			writeJSON(w, http.StatusOK, resp)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	mux.HandleFunc("/api/windows", func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			WindowID string `json:"window_id"`
+			Title    string `json:"title"`
+		}
+
+		var msgType MessageType
+		switch r.Method {
+		case http.MethodPost:
+			msgType = MsgTypeOpenWindow
+		case http.MethodDelete:
+			msgType = MsgTypeCloseWindow
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, BackendResponse{
+				Success: false,
+				Error:   err.Error(),
+			})
+			return
+		}
+		resp, _ := handler(&FrontendMessage{
+			ID:       generateID(),
+			Type:     msgType,
+			WindowID: req.WindowID,
+			Payload:  mustMarshal(WindowPayload{WindowID: req.WindowID, Title: req.Title}),
+		})
+		writeJSON(w, http.StatusOK, resp)
+	})
+
+	mux.HandleFunc("/api/windows/bind", bindHTTPHandler(handler, MsgTypeBindAgent))
+	mux.HandleFunc("/api/windows/unbind", bindHTTPHandler(handler, MsgTypeUnbindAgent))
+}
+
+func bindHTTPHandler(handler MessageHandler, msgType MessageType) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req struct {
+			WindowID string `json:"window_id"`
+			AgentID  string `json:"agent_id"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, BackendResponse{
+				Success: false,
+				Error:   err.Error(),
+			})
+			return
+		}
+		resp, _ := handler(&FrontendMessage{
+			ID:       generateID(),
+			Type:     msgType,
+			WindowID: req.WindowID,
+			AgentID:  req.AgentID,
+		})
+		writeJSON(w, http.StatusOK, resp)
+	}
+}
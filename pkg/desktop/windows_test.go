@@ -0,0 +1,232 @@
+package desktop
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/astercloud/aster/pkg/agent"
+	"github.com/astercloud/aster/pkg/provider"
+	"github.com/astercloud/aster/pkg/sandbox"
+	"github.com/astercloud/aster/pkg/store"
+	"github.com/astercloud/aster/pkg/tools"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+func newTestAgentFactory(t *testing.T) AgentFactory {
+	t.Helper()
+	jsonStore, err := store.NewJSONStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+
+	templates := agent.NewTemplateRegistry()
+	for _, id := range []string{"coder", "writer"} {
+		templates.Register(&types.AgentTemplateDefinition{
+			ID:           id,
+			SystemPrompt: "You are a test assistant.",
+			Model:        "claude-sonnet-4-5",
+			Tools:        []any{},
+		})
+	}
+
+	return NewAgentFactory(&agent.Dependencies{
+		Store:            jsonStore,
+		SandboxFactory:   sandbox.NewFactory(),
+		ToolRegistry:     tools.NewRegistry(),
+		ProviderFactory:  &provider.AnthropicFactory{},
+		TemplateRegistry: templates,
+	}, &types.AgentConfig{
+		ModelConfig: &types.ModelConfig{
+			Provider: "anthropic",
+			Model:    "claude-sonnet-4-5",
+			APIKey:   "test-key",
+		},
+		Sandbox: &types.SandboxConfig{Kind: types.SandboxKindMock},
+	})
+}
+
+func newMultiWindowApp(t *testing.T) (*App, *WailsBridge) {
+	t.Helper()
+	app, err := NewApp(&AppConfig{Framework: FrameworkWails, WorkDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("NewApp() error = %v", err)
+	}
+	app.SetAgentFactory(newTestAgentFactory(t))
+	t.Cleanup(func() { _ = app.Stop(t.Context()) })
+	return app, app.Bridge().(*WailsBridge)
+}
+
+func createTestAgent(t *testing.T, bridge *WailsBridge, windowID, templateID string) AgentSummary {
+	t.Helper()
+	resp, err := bridge.CreateAgent(windowID, CreateAgentPayload{TemplateID: templateID})
+	if err != nil || !resp.Success {
+		t.Fatalf("CreateAgent() = %+v, %v", resp, err)
+	}
+	return resp.Data.(AgentSummary)
+}
+
+func TestCreateAndListAgents(t *testing.T) {
+	app, bridge := newMultiWindowApp(t)
+
+	resp, _ := bridge.CreateAgent("w1", CreateAgentPayload{TemplateID: "missing"})
+	if resp.Success {
+		t.Error("expected failure for unknown template")
+	}
+
+	a1 := createTestAgent(t, bridge, "w1", "coder")
+	a2 := createTestAgent(t, bridge, "w2", "writer")
+	if len(a1.Windows) != 1 || a1.Windows[0] != "w1" {
+		t.Errorf("creating window not bound: %+v", a1)
+	}
+
+	resp, _ = bridge.ListAgents()
+	data := resp.Data.(map[string]any)
+	agents := data["agents"].([]AgentSummary)
+	if len(agents) != 2 {
+		t.Fatalf("agents = %+v, want 2", agents)
+	}
+	templates := data["templates"].([]TemplateSummary)
+	if len(templates) != 2 || templates[0].ID != "coder" {
+		t.Errorf("templates = %+v", templates)
+	}
+
+	if err := app.UnregisterAgent(a2.ID); err != nil {
+		t.Fatalf("UnregisterAgent() error = %v", err)
+	}
+	if got := app.ListAgents(); len(got) != 1 || got[0].ID != a1.ID {
+		t.Errorf("ListAgents() after unregister = %+v", got)
+	}
+	for _, w := range app.Windows() {
+		if w.ID == "w2" && len(w.Agents) != 0 {
+			t.Errorf("window w2 still bound: %+v", w)
+		}
+	}
+}
+
+func TestCreateAgentWithoutFactory(t *testing.T) {
+	app, err := NewApp(&AppConfig{Framework: FrameworkWails})
+	if err != nil {
+		t.Fatalf("NewApp() error = %v", err)
+	}
+	resp, _ := app.handleMessage(&FrontendMessage{ID: "1", Type: MsgTypeCreateAgent, Payload: json.RawMessage(`{}`)})
+	if resp.Success {
+		t.Error("expected failure without agent factory")
+	}
+}
+
+func TestWindowEventRouting(t *testing.T) {
+	app, bridge := newMultiWindowApp(t)
+
+	a1 := createTestAgent(t, bridge, "w1", "coder")
+	a2 := createTestAgent(t, bridge, "w2", "writer")
+
+	tests := []struct {
+		window string
+		event  *FrontendEvent
+		want   bool
+	}{
+		{"w1", &FrontendEvent{AgentID: a1.ID}, true},
+		{"w1", &FrontendEvent{AgentID: a2.ID}, false},
+		{"w2", &FrontendEvent{AgentID: a2.ID}, true},
+		{"w1", &FrontendEvent{Type: EventTypeNotification}, true},
+		{"", &FrontendEvent{AgentID: a2.ID}, true},
+		{"unknown", &FrontendEvent{AgentID: a1.ID}, false},
+	}
+	for _, tt := range tests {
+		if got := app.windowAccepts(tt.window, tt.event); got != tt.want {
+			t.Errorf("windowAccepts(%q, agent %q) = %v, want %v", tt.window, tt.event.AgentID, got, tt.want)
+		}
+	}
+
+	// Binding a second agent to w1 routes both
+	if resp, _ := bridge.BindAgent("w1", a2.ID); !resp.Success {
+		t.Fatalf("BindAgent() failed: %s", resp.Error)
+	}
+	if got := app.WindowsForEvent(&FrontendEvent{AgentID: a2.ID}); len(got) != 2 {
+		t.Errorf("WindowsForEvent() = %v, want [w1 w2]", got)
+	}
+
+	_, _ = bridge.UnbindAgent("w1", a2.ID)
+	_, _ = bridge.CloseWindow("w2")
+	if got := app.WindowsForEvent(&FrontendEvent{AgentID: a2.ID}); len(got) != 0 {
+		t.Errorf("WindowsForEvent() after unbind/close = %v, want none", got)
+	}
+}
+
+func TestAgentEventsForwardedOnce(t *testing.T) {
+	app, bridge := newMultiWindowApp(t)
+	summary := createTestAgent(t, bridge, "w1", "coder")
+	ag, _ := app.GetAgent(summary.ID)
+	drainEvents(bridge)
+
+	ag.GetEventBus().EmitProgress(&types.ProgressDoneEvent{Step: 1, Reason: "completed"})
+
+	deadline := time.After(2 * time.Second)
+	for {
+		select {
+		case e := <-bridge.eventCh:
+			if e.Type != EventTypeDone {
+				continue
+			}
+			if e.AgentID != summary.ID {
+				t.Errorf("event agent = %s, want %s", e.AgentID, summary.ID)
+			}
+			time.Sleep(50 * time.Millisecond)
+			for _, extra := range drainEvents(bridge) {
+				if extra.Type == EventTypeDone {
+					t.Error("done event forwarded more than once")
+				}
+			}
+			return
+		case <-deadline:
+			t.Fatal("done event not forwarded")
+		}
+	}
+}
+
+func TestWebBridgeSSEWindowFilter(t *testing.T) {
+	app, err := NewApp(&AppConfig{Framework: FrameworkWeb})
+	if err != nil {
+		t.Fatalf("NewApp() error = %v", err)
+	}
+	app.SetAgentFactory(newTestAgentFactory(t))
+	t.Cleanup(func() { _ = app.Stop(t.Context()) })
+	bridge := app.Bridge().(*WebBridge)
+
+	mux := http.NewServeMux()
+	windowAgentRoutes(mux, app.handleMessage)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/agents",
+		strings.NewReader(`{"window_id":"w1","template_id":"coder"}`)))
+	var created struct {
+		Success bool         `json:"success"`
+		Data    AgentSummary `json:"data"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&created); err != nil || !created.Success {
+		t.Fatalf("POST /api/agents = %+v, %v", created, err)
+	}
+
+	w1 := make(chan *FrontendEvent, 10)
+	w2 := make(chan *FrontendEvent, 10)
+	bridge.sseMu.Lock()
+	bridge.sseClients["c1"], bridge.sseWindows["c1"] = w1, "w1"
+	bridge.sseClients["c2"], bridge.sseWindows["c2"] = w2, "w2"
+	bridge.sseMu.Unlock()
+	app.OpenWindow("w2", "")
+
+	_ = bridge.SendEvent(&FrontendEvent{Type: EventTypeTextChunk, AgentID: created.Data.ID})
+	if len(w1) != 1 || len(w2) != 0 {
+		t.Errorf("w1 got %d events, w2 got %d; want 1 and 0", len(w1), len(w2))
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/agents", nil))
+	if !strings.Contains(rec.Body.String(), created.Data.ID) {
+		t.Errorf("GET /api/agents body = %s", rec.Body.String())
+	}
+}
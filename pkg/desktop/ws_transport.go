@@ -0,0 +1,553 @@
+package desktop
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Transport selects how HTTP-based bridges (Tauri, Electron, Web) push events
+type Transport string
+
+const (
+	// TransportSSE uses HTTP requests inbound and Server-Sent Events outbound (default)
+	TransportSSE Transport = "sse"
+
+	// TransportWebSocket additionally serves a bidirectional WebSocket at /ws
+	TransportWebSocket Transport = "websocket"
+)
+
+const (
+	// DefaultHeartbeatInterval is the default WebSocket ping interval
+	DefaultHeartbeatInterval = 15 * time.Second
+
+	// DefaultEventBacklog is the number of events retained for cursor resume
+	DefaultEventBacklog = 1000
+
+	wsWriteTimeout = 10 * time.Second
+)
+
+// WSFrame kinds
+const (
+	// WSFrameHello is sent once per connection with the client ID and current cursor
+	WSFrameHello = "hello"
+
+	// WSFrameEvent carries a FrontendEvent with its sequence number
+	WSFrameEvent = "event"
+
+	// WSFrameMessage carries a FrontendMessage from the frontend
+	WSFrameMessage = "message"
+
+	// WSFrameResponse carries the BackendResponse for a WSFrameMessage
+	WSFrameResponse = "response"
+
+	// WSFrameResync tells the client events were lost and state must be reloaded
+	WSFrameResync = "resync"
+
+	// WSFramePing and WSFramePong are application-level heartbeats
+	WSFramePing = "ping"
+	WSFramePong = "pong"
+)
+
+// WSFrame is the envelope for every WebSocket message in both directions
+type WSFrame struct {
+	Kind     string           `json:"kind"`
+	Seq      uint64           `json:"seq,omitempty"`
+	ClientID string           `json:"client_id,omitempty"`
+	Event    *FrontendEvent   `json:"event,omitempty"`
+	Message  *FrontendMessage `json:"message,omitempty"`
+	Response *BackendResponse `json:"response,omitempty"`
+}
+
+var wsUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	CheckOrigin: func(r *http.Request) bool {
+		// Bridges listen on localhost only, same policy as corsMiddleware
+		return true
+	},
+}
+
+// wsHub serves the /ws endpoint for an HTTP bridge.
+// Events are numbered and kept in a bounded backlog so reconnecting clients
+// can pass ?cursor=<last seq> and receive what they missed.
+type wsHub struct {
+	mu        sync.RWMutex
+	app       *App
+	handler   MessageHandler
+	clients   map[string]*wsConn
+	seq       uint64
+	backlog   []WSFrame
+	capacity  int
+	heartbeat time.Duration
+	closed    bool
+}
+
+type wsConn struct {
+	id       string
+	windowID string
+	conn     *websocket.Conn
+	send     chan WSFrame
+	done     chan struct{}
+	once     sync.Once
+}
+
+func (c *wsConn) close() {
+	c.once.Do(func() { close(c.done) })
+}
+
+// newWSHub creates a hub for the app configuration, or nil when the
+// WebSocket transport is not enabled
+func newWSHub(app *App) *wsHub {
+	if app == nil || app.config.Transport != TransportWebSocket {
+		return nil
+	}
+	heartbeat := app.config.HeartbeatInterval
+	if heartbeat <= 0 {
+		heartbeat = DefaultHeartbeatInterval
+	}
+	return &wsHub{
+		app:       app,
+		clients:   make(map[string]*wsConn),
+		capacity:  DefaultEventBacklog,
+		heartbeat: heartbeat,
+	}
+}
+
+// setHandler sets the handler used for inbound messages
+func (h *wsHub) setHandler(handler MessageHandler) {
+	h.mu.Lock()
+	h.handler = handler
+	h.mu.Unlock()
+}
+
+// broadcast numbers the event, records it for resume and sends it to all clients
+func (h *wsHub) broadcast(event *FrontendEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.seq++
+	frame := WSFrame{Kind: WSFrameEvent, Seq: h.seq, Event: event}
+	h.backlog = append(h.backlog, frame)
+	if len(h.backlog) > h.capacity {
+		h.backlog = h.backlog[len(h.backlog)-h.capacity:]
+	}
+
+	for _, c := range h.clients {
+		if !h.app.windowAccepts(c.windowID, event) {
+			continue
+		}
+		select {
+		case c.send <- frame:
+		default:
+			// Slow client: drop the connection, it will resume from its cursor
+			c.close()
+		}
+	}
+}
+
+// close disconnects all clients
+func (h *wsHub) close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	for _, c := range h.clients {
+		c.close()
+	}
+	h.clients = make(map[string]*wsConn)
+}
+
+// ServeHTTP upgrades the request and serves one client connection
+func (h *wsHub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var cursor uint64
+	if v := r.URL.Query().Get("cursor"); v != "" {
+		n, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			http.Error(w, "invalid cursor", http.StatusBadRequest)
+			return
+		}
+		cursor = n
+	}
+
+	conn, err := wsUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return // Upgrade already wrote the error response
+	}
+
+	c := &wsConn{
+		id:       r.URL.Query().Get("client_id"),
+		windowID: r.URL.Query().Get("window_id"),
+		conn:     conn,
+		done:     make(chan struct{}),
+	}
+	if c.id == "" {
+		c.id = generateID()
+	}
+	if c.windowID != "" {
+		h.app.OpenWindow(c.windowID, "")
+	}
+
+	// Register and queue hello + replay under the lock so no event is missed or duplicated
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		_ = conn.Close()
+		return
+	}
+	replay := h.replayLocked(cursor, c.windowID)
+	c.send = make(chan WSFrame, len(replay)+256)
+	c.send <- WSFrame{Kind: WSFrameHello, ClientID: c.id, Seq: h.seq}
+	for _, f := range replay {
+		c.send <- f
+	}
+	if old, ok := h.clients[c.id]; ok {
+		old.close() // Same client reconnected, retire the stale connection
+	}
+	h.clients[c.id] = c
+	h.mu.Unlock()
+
+	go h.writeLoop(c)
+	h.readLoop(c)
+
+	h.mu.Lock()
+	if h.clients[c.id] == c {
+		delete(h.clients, c.id)
+	}
+	h.mu.Unlock()
+	c.close()
+}
+
+// replayLocked returns the window's frames after cursor; a resync frame is
+// returned first when the cursor is older than the retained backlog
+func (h *wsHub) replayLocked(cursor uint64, windowID string) []WSFrame {
+	if cursor == 0 || cursor >= h.seq {
+		return nil
+	}
+	var frames []WSFrame
+	if len(h.backlog) == 0 || h.backlog[0].Seq > cursor+1 {
+		frames = append(frames, WSFrame{Kind: WSFrameResync, Seq: h.seq})
+	}
+	for _, f := range h.backlog {
+		if f.Seq > cursor && h.app.windowAccepts(windowID, f.Event) {
+			frames = append(frames, f)
+		}
+	}
+	return frames
+}
+
+func (h *wsHub) readLoop(c *wsConn) {
+	deadline := 2 * h.heartbeat
+	_ = c.conn.SetReadDeadline(time.Now().Add(deadline))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(deadline))
+	})
+
+	for {
+		var frame WSFrame
+		if err := c.conn.ReadJSON(&frame); err != nil {
+			return
+		}
+		_ = c.conn.SetReadDeadline(time.Now().Add(deadline))
+
+		switch frame.Kind {
+		case WSFramePing:
+			c.enqueue(WSFrame{Kind: WSFramePong})
+		case WSFrameMessage:
+			if frame.Message == nil {
+				continue
+			}
+			// Handle in a goroutine so slow handlers (dialogs) don't stall heartbeats
+			go func(msg *FrontendMessage) {
+				h.mu.RLock()
+				handler := h.handler
+				h.mu.RUnlock()
+				if handler == nil {
+					return
+				}
+				resp, err := handler(msg)
+				if err != nil {
+					resp = &BackendResponse{ID: msg.ID, Success: false, Error: err.Error()}
+				}
+				c.enqueue(WSFrame{Kind: WSFrameResponse, Response: resp})
+			}(frame.Message)
+		}
+	}
+}
+
+func (c *wsConn) enqueue(frame WSFrame) {
+	select {
+	case c.send <- frame:
+	case <-c.done:
+	}
+}
+
+func (h *wsHub) writeLoop(c *wsConn) {
+	ticker := time.NewTicker(h.heartbeat)
+	defer func() {
+		ticker.Stop()
+		_ = c.conn.Close()
+	}()
+
+	for {
+		select {
+		case <-c.done:
+			_ = c.conn.WriteControl(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseGoingAway, ""), time.Now().Add(time.Second))
+			return
+		case frame := <-c.send:
+			_ = c.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+			if err := c.conn.WriteJSON(frame); err != nil {
+				c.close()
+				return
+			}
+		case <-ticker.C:
+			if err := c.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteTimeout)); err != nil {
+				c.close()
+				return
+			}
+		}
+	}
+}
+
+// WSClient is a Go client for the bridge WebSocket transport.
+// It reconnects automatically with exponential backoff and resumes from the
+// last received event sequence, so no events are lost across short disconnects.
+type WSClient struct {
+	url      string
+	clientID string
+
+	events chan *FrontendEvent
+	resync chan struct{}
+
+	mu      sync.Mutex
+	writeMu sync.Mutex // gorilla/websocket allows one concurrent writer
+	conn    *websocket.Conn
+	cursor  uint64
+	pending map[string]chan *BackendResponse
+
+	ready  chan struct{} // closed while connected
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// DialWS connects to a bridge WebSocket endpoint (e.g. ws://127.0.0.1:9527/ws)
+// and keeps the connection alive until ctx is canceled or Close is called.
+func DialWS(ctx context.Context, endpoint string) (*WSClient, error) {
+	if _, err := url.Parse(endpoint); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	c := &WSClient{
+		url:      endpoint,
+		clientID: generateID(),
+		events:   make(chan *FrontendEvent, 256),
+		resync:   make(chan struct{}, 1),
+		pending:  make(map[string]chan *BackendResponse),
+		ready:    make(chan struct{}),
+		cancel:   cancel,
+		done:     make(chan struct{}),
+	}
+
+	conn, err := c.dial(ctx)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	go c.run(ctx, conn)
+	return c, nil
+}
+
+// Events returns the stream of backend events
+func (c *WSClient) Events() <-chan *FrontendEvent {
+	return c.events
+}
+
+// Resync receives a value when events were lost during a disconnect and the
+// frontend should reload state (e.g. GetHistory)
+func (c *WSClient) Resync() <-chan struct{} {
+	return c.resync
+}
+
+// Cursor returns the sequence number of the last received event
+func (c *WSClient) Cursor() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.cursor
+}
+
+// Send sends a message and waits for its response, waiting for a
+// reconnect if the connection is currently down
+func (c *WSClient) Send(ctx context.Context, msg *FrontendMessage) (*BackendResponse, error) {
+	if msg.ID == "" {
+		msg.ID = generateID()
+	}
+	respCh := make(chan *BackendResponse, 1)
+
+	c.mu.Lock()
+	c.pending[msg.ID] = respCh
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, msg.ID)
+		c.mu.Unlock()
+	}()
+
+	for {
+		c.mu.Lock()
+		conn, ready := c.conn, c.ready
+		c.mu.Unlock()
+
+		select {
+		case <-ready:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-c.done:
+			return nil, errors.New("websocket client closed")
+		}
+
+		c.writeMu.Lock()
+		err := conn.WriteJSON(WSFrame{Kind: WSFrameMessage, Message: msg})
+		c.writeMu.Unlock()
+		if err == nil {
+			break
+		}
+		// Connection dropped between ready and write, wait for the reconnect
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	select {
+	case resp := <-respCh:
+		return resp, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-c.done:
+		return nil, errors.New("websocket client closed")
+	}
+}
+
+// Close stops reconnecting and closes the connection
+func (c *WSClient) Close() error {
+	c.cancel()
+	<-c.done
+	return nil
+}
+
+func (c *WSClient) dial(ctx context.Context) (*websocket.Conn, error) {
+	u, _ := url.Parse(c.url)
+	q := u.Query()
+	q.Set("client_id", c.clientID)
+	if cursor := c.Cursor(); cursor > 0 {
+		q.Set("cursor", strconv.FormatUint(cursor, 10))
+	}
+	u.RawQuery = q.Encode()
+
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.conn = conn
+	close(c.ready)
+	c.mu.Unlock()
+	return conn, nil
+}
+
+func (c *WSClient) run(ctx context.Context, conn *websocket.Conn) {
+	defer close(c.done)
+
+	go func() {
+		<-ctx.Done()
+		c.mu.Lock()
+		if c.conn != nil {
+			_ = c.conn.Close()
+		}
+		c.mu.Unlock()
+	}()
+
+	backoff := 100 * time.Millisecond
+	for {
+		c.readLoop(ctx, conn)
+
+		c.mu.Lock()
+		c.ready = make(chan struct{})
+		c.mu.Unlock()
+
+		for {
+			if ctx.Err() != nil {
+				return
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			next, err := c.dial(ctx)
+			if err == nil {
+				conn = next
+				backoff = 100 * time.Millisecond
+				break
+			}
+			backoff = min(backoff*2, 5*time.Second)
+		}
+	}
+}
+
+func (c *WSClient) readLoop(ctx context.Context, conn *websocket.Conn) {
+	defer func() { _ = conn.Close() }()
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		var frame WSFrame
+		if err := json.Unmarshal(data, &frame); err != nil {
+			continue
+		}
+
+		switch frame.Kind {
+		case WSFrameEvent:
+			c.mu.Lock()
+			if frame.Seq <= c.cursor {
+				c.mu.Unlock()
+				continue // Duplicate from replay
+			}
+			c.cursor = frame.Seq
+			c.mu.Unlock()
+			select {
+			case c.events <- frame.Event:
+			case <-ctx.Done():
+				return
+			}
+		case WSFrameResync:
+			select {
+			case c.resync <- struct{}{}:
+			default:
+			}
+		case WSFrameHello:
+			c.mu.Lock()
+			if c.cursor == 0 {
+				c.cursor = frame.Seq // Fresh client: start from the current position
+			}
+			c.mu.Unlock()
+		case WSFrameResponse:
+			if frame.Response == nil {
+				continue
+			}
+			c.mu.Lock()
+			ch, ok := c.pending[frame.Response.ID]
+			c.mu.Unlock()
+			if ok {
+				ch <- frame.Response
+			}
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package desktop
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func newWSTestServer(t *testing.T) (*App, *wsHub, string) {
+	t.Helper()
+	app, err := NewApp(&AppConfig{
+		Framework:         FrameworkWeb,
+		Transport:         TransportWebSocket,
+		HeartbeatInterval: time.Second,
+	})
+	if err != nil {
+		t.Fatalf("NewApp() error = %v", err)
+	}
+	hub := app.Bridge().(*WebBridge).ws
+	if hub == nil {
+		t.Fatal("expected WebSocket hub with TransportWebSocket")
+	}
+	hub.setHandler(app.handleMessage)
+
+	srv := httptest.NewServer(hub)
+	t.Cleanup(func() {
+		hub.close()
+		srv.Close()
+	})
+	return app, hub, "ws" + strings.TrimPrefix(srv.URL, "http")
+}
+
+func receiveEvent(t *testing.T, c *WSClient) *FrontendEvent {
+	t.Helper()
+	select {
+	case e := <-c.Events():
+		return e
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for event")
+		return nil
+	}
+}
+
+func TestNewWSHubDisabledByDefault(t *testing.T) {
+	app, err := NewApp(&AppConfig{Framework: FrameworkTauri})
+	if err != nil {
+		t.Fatalf("NewApp() error = %v", err)
+	}
+	if app.Bridge().(*TauriBridge).ws != nil {
+		t.Error("WebSocket hub should be nil without TransportWebSocket")
+	}
+}
+
+func TestWSClientMessagesAndEvents(t *testing.T) {
+	app, _, url := newWSTestServer(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	client, err := DialWS(ctx, url)
+	if err != nil {
+		t.Fatalf("DialWS() error = %v", err)
+	}
+	defer func() { _ = client.Close() }()
+
+	resp, err := client.Send(ctx, &FrontendMessage{Type: MsgTypeGetConfig})
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if !resp.Success {
+		t.Errorf("get_config failed: %s", resp.Error)
+	}
+
+	_ = app.Bridge().SendEvent(&FrontendEvent{Type: EventTypeDone, AgentID: "a1"})
+	if e := receiveEvent(t, client); e.Type != EventTypeDone || e.AgentID != "a1" {
+		t.Errorf("event = %+v", e)
+	}
+	if client.Cursor() != 1 {
+		t.Errorf("Cursor() = %d, want 1", client.Cursor())
+	}
+}
+
+func TestWSClientReconnectResumesFromCursor(t *testing.T) {
+	app, hub, url := newWSTestServer(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	client, err := DialWS(ctx, url)
+	if err != nil {
+		t.Fatalf("DialWS() error = %v", err)
+	}
+	defer func() { _ = client.Close() }()
+
+	_ = app.Bridge().SendEvent(&FrontendEvent{Type: EventTypeTextChunk, Data: "1"})
+	receiveEvent(t, client)
+
+	// Drop the connection server-side and emit while the client is away
+	hub.mu.Lock()
+	for _, c := range hub.clients {
+		c.close()
+	}
+	hub.mu.Unlock()
+	_ = app.Bridge().SendEvent(&FrontendEvent{Type: EventTypeTextChunk, Data: "2"})
+	_ = app.Bridge().SendEvent(&FrontendEvent{Type: EventTypeTextChunk, Data: "3"})
+
+	for _, want := range []string{"2", "3"} {
+		if e := receiveEvent(t, client); e.Data != want {
+			t.Errorf("event data = %v, want %s", e.Data, want)
+		}
+	}
+	if client.Cursor() != 3 {
+		t.Errorf("Cursor() = %d, want 3", client.Cursor())
+	}
+
+	// Messages keep working after the reconnect
+	resp, err := client.Send(ctx, &FrontendMessage{Type: MsgTypeGetConfig})
+	if err != nil || !resp.Success {
+		t.Errorf("Send() after reconnect = %+v, %v", resp, err)
+	}
+}
+
+func TestWSHubReplay(t *testing.T) {
+	hub := &wsHub{clients: make(map[string]*wsConn), capacity: 3, heartbeat: time.Second}
+	for range 5 {
+		hub.broadcast(&FrontendEvent{Type: EventTypeTextChunk})
+	}
+
+	tests := []struct {
+		name       string
+		cursor     uint64
+		wantSeqs   []uint64
+		wantResync bool
+	}{
+		{"fresh client", 0, nil, false},
+		{"up to date", 5, nil, false},
+		{"within backlog", 2, []uint64{3, 4, 5}, false},
+		{"older than backlog", 1, []uint64{3, 4, 5}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			frames := hub.replayLocked(tt.cursor, "")
+			resync := len(frames) > 0 && frames[0].Kind == WSFrameResync
+			if resync != tt.wantResync {
+				t.Errorf("resync = %v, want %v", resync, tt.wantResync)
+			}
+			var seqs []uint64
+			for _, f := range frames {
+				if f.Kind == WSFrameEvent {
+					seqs = append(seqs, f.Seq)
+				}
+			}
+			if len(seqs) != len(tt.wantSeqs) {
+				t.Fatalf("seqs = %v, want %v", seqs, tt.wantSeqs)
+			}
+			for i := range seqs {
+				if seqs[i] != tt.wantSeqs[i] {
+					t.Errorf("seqs = %v, want %v", seqs, tt.wantSeqs)
+				}
+			}
+		})
+	}
+}
+
+func TestWSHeartbeat(t *testing.T) {
+	_, _, url := newWSTestServer(t)
+
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	var hello WSFrame
+	if err := conn.ReadJSON(&hello); err != nil || hello.Kind != WSFrameHello || hello.ClientID == "" {
+		t.Fatalf("hello = %+v, err = %v", hello, err)
+	}
+
+	pinged := make(chan struct{}, 1)
+	conn.SetPingHandler(func(string) error {
+		select {
+		case pinged <- struct{}{}:
+		default:
+		}
+		return conn.WriteControl(websocket.PongMessage, nil, time.Now().Add(time.Second))
+	})
+
+	if err := conn.WriteJSON(WSFrame{Kind: WSFramePing}); err != nil {
+		t.Fatal(err)
+	}
+	var pong WSFrame
+	if err := conn.ReadJSON(&pong); err != nil || pong.Kind != WSFramePong {
+		t.Fatalf("pong = %+v, err = %v", pong, err)
+	}
+
+	// Keep reading so control frames are processed
+	go func() {
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+	select {
+	case <-pinged:
+	case <-time.After(3 * time.Second):
+		t.Error("no heartbeat ping from server")
+	}
+}
@@ -14,6 +14,8 @@ type BatchTestCase struct {
 	ID string `json:"id"`
 	// Input 评估输入
 	Input *TextEvalInput `json:"input"`
+	// Prompt 可选：由 GenerateAnswersBatch 生成 Input.Answer 时发送给模型的提示
+	Prompt string `json:"prompt,omitempty"`
 	// Metadata 可选的元数据
 	Metadata map[string]any `json:"metadata,omitempty"`
 }
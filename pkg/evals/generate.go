@@ -0,0 +1,56 @@
+package evals
+
+import (
+	"context"
+	"errors"
+
+	"github.com/astercloud/aster/pkg/batch"
+	"github.com/astercloud/aster/pkg/provider"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+// GenerateAnswersBatch 通过提供商批处理接口为设置了 Prompt 的测试用例生成 Input.Answer
+// 适合大规模离线评估：批处理价格约为实时调用的一半，但需要等待批次结束（通常数分钟到数小时）
+// 返回生成失败的用例 ID 到原因的映射，失败用例的 Answer 保持不变
+func GenerateAnswersBatch(ctx context.Context, runner *batch.Runner, testCases []*BatchTestCase, opts *provider.StreamOptions) (map[string]string, error) {
+	if runner == nil {
+		return nil, errors.New("batch runner is required")
+	}
+	cases := make(map[string]*BatchTestCase, len(testCases))
+	var jobs []batch.Job
+	for _, tc := range testCases {
+		if tc == nil || tc.Prompt == "" {
+			continue
+		}
+		cases[tc.ID] = tc
+		jobs = append(jobs, batch.Job{
+			ID:       tc.ID,
+			Messages: []types.Message{{Role: types.RoleUser, Content: tc.Prompt}},
+			Options:  opts,
+		})
+	}
+	if len(jobs) == 0 {
+		return nil, nil
+	}
+
+	results, err := runner.Run(ctx, jobs)
+	if err != nil && results == nil {
+		return nil, err
+	}
+	failures := make(map[string]string)
+	for _, result := range results {
+		tc := cases[result.JobID]
+		if tc == nil {
+			continue
+		}
+		if result.Message == nil {
+			failures[tc.ID] = result.Error
+			continue
+		}
+		if tc.Input == nil {
+			tc.Input = &TextEvalInput{}
+		}
+		tc.Input.Answer = extractMessageText(result.Message)
+	}
+	return failures, err
+}
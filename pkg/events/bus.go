@@ -6,6 +6,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/astercloud/aster/pkg/requestctx"
 	"github.com/astercloud/aster/pkg/types"
 )
 
@@ -18,6 +19,21 @@ type EventBusConfig struct {
 	MaxTimelineAge  time.Duration // 最大事件年龄 (默认 1小时)
 	CleanupInterval time.Duration // 清理间隔 (默认 5分钟)
 	EnableArchive   bool          // 是否启用归档 (预留)
+
+	// Transport 可选的外部传输（NATS、Kafka 等），为 nil 时只在进程内分发
+	Transport Transport
+	// Source 发布到外部时的事件来源（Agent、租户）
+	Source EventSource
+	// TransportQueueSize 外部发布队列长度 (默认 1024)，队列满时丢弃事件
+	TransportQueueSize int
+
+	// Observer 可选的同步观察者，每个事件按 cursor 顺序调用且不会丢弃（如审计记录）
+	// 在总线锁内调用，需要尽快返回且不能再向同一总线发送事件
+	Observer func(types.AgentEventEnvelope)
+
+	// Drop 可选的丢弃钩子（如 chaos.Injector.DropEvent），返回 true 的事件仍写入时间线，
+	// 可通过 bookmark 补回，但不分发给订阅者和外部传输；done 事件不会被丢弃
+	Drop func(types.AgentEventEnvelope) bool
 }
 
 // DefaultEventBusConfig 默认配置
@@ -55,6 +71,15 @@ type EventBus struct {
 	cleanupTicker *time.Ticker
 	cleanupDone   chan struct{}
 	cleanupWg     sync.WaitGroup // 等待清理 goroutine 退出
+
+	// 当前请求的元数据，写入之后每个事件的封装
+	request *requestctx.Metadata
+
+	// 外部发布
+	publishQueue   chan types.AgentEventEnvelope
+	publishWg      sync.WaitGroup
+	publishDropped int64
+	publishFailed  int64
 }
 
 // NewEventBus 创建新的事件总线（使用默认配置）
@@ -82,6 +107,7 @@ func NewEventBusWithConfig(config *EventBusConfig) *EventBus {
 
 	// 启动清理 worker
 	eb.startCleanupWorker()
+	eb.startPublisher()
 
 	return eb
 }
@@ -161,6 +187,9 @@ func (eb *EventBus) Close() {
 		eb.cleanupDone = nil
 	}
 
+	// 等待已入队的事件发布到外部
+	eb.stopPublisher()
+
 	// 然后清理所有资源（持锁）
 	eb.mu.Lock()
 	defer eb.mu.Unlock()
@@ -184,6 +213,17 @@ func (eb *EventBus) Close() {
 	eb.bookmarks = nil
 }
 
+// SetRequest 设置之后事件所属请求的元数据，零值表示清除
+func (eb *EventBus) SetRequest(m requestctx.Metadata) {
+	eb.mu.Lock()
+	defer eb.mu.Unlock()
+	if m.IsZero() {
+		eb.request = nil
+		return
+	}
+	eb.request = &m
+}
+
 // emit 发送事件到总线(内部方法)
 func (eb *EventBus) emit(channel types.AgentChannel, event any) types.AgentEventEnvelope {
 	eb.mu.Lock()
@@ -199,52 +239,62 @@ func (eb *EventBus) emit(channel types.AgentChannel, event any) types.AgentEvent
 	}
 
 	// 封装事件
-	envelope := types.AgentEventEnvelope{
-		Cursor:   eb.cursor,
-		Bookmark: bookmark,
-		Event:    event,
-	}
+	envelope := types.NewAgentEventEnvelope(eb.cursor, bookmark, event)
+	envelope.Request = eb.request
 
 	// 保存到时间线
 	eb.timeline = append(eb.timeline, envelope)
 	eb.bookmarks[eb.cursor] = bookmark
+	if eb.config.Observer != nil {
+		eb.config.Observer(envelope)
+	}
 
 	// 检查是否是重要事件（done事件必须送达）
 	_, isDoneEvent := event.(*types.ProgressDoneEvent)
+	dropped := !isDoneEvent && eb.config.Drop != nil && eb.config.Drop(envelope)
+	if !dropped {
+		eb.enqueuePublishLocked(envelope)
+	}
 
-	// 分发到对应通道的订阅者
+	// 分发到对应通道的订阅者（被丢弃的事件不投递，回调处理器照常调用）
 	switch channel {
 	case types.ChannelProgress:
-		for _, ch := range eb.progressSubs {
-			if isDoneEvent {
-				// done 事件使用带超时的发送，确保送达
-				select {
-				case ch <- envelope:
-				case <-time.After(5 * time.Second):
-					// 超时，记录日志但继续
+		if !dropped {
+			for _, ch := range eb.progressSubs {
+				if isDoneEvent {
+					// done 事件使用带超时的发送，确保送达
+					select {
+					case ch <- envelope:
+					case <-time.After(5 * time.Second):
+						// 超时，记录日志但继续
+					}
+				} else {
+					select {
+					case ch <- envelope:
+					default:
+						// 非阻塞发送,如果channel满了则跳过
+					}
 				}
-			} else {
+			}
+		}
+	case types.ChannelControl:
+		if !dropped {
+			for _, ch := range eb.controlSubs {
 				select {
 				case ch <- envelope:
 				default:
-					// 非阻塞发送,如果channel满了则跳过
 				}
 			}
 		}
-	case types.ChannelControl:
-		for _, ch := range eb.controlSubs {
-			select {
-			case ch <- envelope:
-			default:
-			}
-		}
 		// 调用Control回调处理器
 		eb.invokeHandlers(eb.controlHandlers, event)
 	case types.ChannelMonitor:
-		for _, ch := range eb.monitorSubs {
-			select {
-			case ch <- envelope:
-			default:
+		if !dropped {
+			for _, ch := range eb.monitorSubs {
+				select {
+				case ch <- envelope:
+				default:
+				}
 			}
 		}
 		// 调用Monitor回调处理器
@@ -551,6 +601,48 @@ func (eb *EventBus) GetTimelineCount() int {
 	return len(eb.timeline)
 }
 
+// BusStats 事件总线运行状态，用于诊断
+type BusStats struct {
+	Cursor       int64 `json:"cursor"`
+	TimelineSize int   `json:"timeline_size"`
+	Subscribers  int   `json:"subscribers"`
+	// SubscriberBacklog 所有订阅者缓冲区中尚未消费的事件数，MaxSubscriberBacklog 为单个订阅者的最大值
+	SubscriberBacklog    int `json:"subscriber_backlog"`
+	MaxSubscriberBacklog int `json:"max_subscriber_backlog"`
+	// PublishQueueDepth 外部发布队列中等待发布的事件数
+	PublishQueueDepth int            `json:"publish_queue_depth"`
+	PublishQueueCap   int            `json:"publish_queue_cap"`
+	Transport         TransportStats `json:"transport"`
+}
+
+// Stats 返回事件总线的时间线、订阅者积压和发布队列深度
+func (eb *EventBus) Stats() BusStats {
+	eb.mu.RLock()
+	defer eb.mu.RUnlock()
+
+	stats := BusStats{
+		Cursor:            eb.cursor,
+		TimelineSize:      len(eb.timeline),
+		PublishQueueDepth: len(eb.publishQueue),
+		PublishQueueCap:   cap(eb.publishQueue),
+		Transport:         TransportStats{Dropped: eb.publishDropped, Failed: eb.publishFailed},
+	}
+	// 同一订阅可能注册在多个通道，按订阅 ID 去重
+	seen := make(map[string]bool)
+	for _, subs := range []map[string]chan types.AgentEventEnvelope{eb.progressSubs, eb.controlSubs, eb.monitorSubs} {
+		for id, ch := range subs {
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+			stats.Subscribers++
+			stats.SubscriberBacklog += len(ch)
+			stats.MaxSubscriberBacklog = max(stats.MaxSubscriberBacklog, len(ch))
+		}
+	}
+	return stats
+}
+
 // Clear 清空事件总线(用于测试)
 func (eb *EventBus) Clear() {
 	eb.mu.Lock()
@@ -345,3 +345,33 @@ func TestUnsubscribeMultipleChannels(t *testing.T) {
 		t.Errorf("expected 0 total subscribers, got %d", totalSubs)
 	}
 }
+
+func TestEventBusStats(t *testing.T) {
+	eb := NewEventBus()
+	defer eb.Close()
+
+	both := eb.Subscribe([]types.AgentChannel{types.ChannelProgress, types.ChannelMonitor}, nil)
+	monitor := eb.Subscribe([]types.AgentChannel{types.ChannelMonitor}, nil)
+	defer eb.Unsubscribe(monitor)
+	defer eb.Unsubscribe(both)
+
+	eb.EmitProgress(&types.ProgressTextChunkEvent{Delta: "a"})
+	eb.EmitMonitor(&types.MonitorTokenUsageEvent{InputTokens: 1})
+	eb.EmitMonitor(&types.MonitorTokenUsageEvent{InputTokens: 2})
+	<-both
+
+	stats := eb.Stats()
+	if stats.Cursor != 3 || stats.TimelineSize != 3 {
+		t.Errorf("cursor/timeline = %d/%d, want 3/3", stats.Cursor, stats.TimelineSize)
+	}
+	if stats.Subscribers != 2 {
+		t.Errorf("subscribers = %d, want 2", stats.Subscribers)
+	}
+	// 第一个订阅者已消费 1 条，剩余 2 条；第二个订阅者积压 2 条
+	if stats.SubscriberBacklog != 4 || stats.MaxSubscriberBacklog != 2 {
+		t.Errorf("backlog = %d (max %d), want 4 (max 2)", stats.SubscriberBacklog, stats.MaxSubscriberBacklog)
+	}
+	if stats.PublishQueueCap != 0 {
+		t.Errorf("publish queue cap = %d without transport, want 0", stats.PublishQueueCap)
+	}
+}
@@ -0,0 +1,88 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+// KafkaProducer Kafka 生产者
+// 不同客户端库的 API 差异较大，由调用方适配，例如 segmentio/kafka-go：
+//
+//	func (p producer) Produce(ctx context.Context, topic string, key, value []byte, headers map[string]string) error {
+//		msg := kafka.Message{Topic: topic, Key: key, Value: value}
+//		for k, v := range headers {
+//			msg.Headers = append(msg.Headers, kafka.Header{Key: k, Value: []byte(v)})
+//		}
+//		return p.writer.WriteMessages(ctx, msg)
+//	}
+type KafkaProducer interface {
+	Produce(ctx context.Context, topic string, key, value []byte, headers map[string]string) error
+}
+
+// KafkaConfig Kafka 传输配置
+type KafkaConfig struct {
+	// TopicPrefix 主题前缀 (默认 "aster.events")
+	TopicPrefix string
+	// TopicPerAgent 为每个 Agent 使用独立主题 <prefix>.<tenant>.<agent>
+	// 默认每个租户一个主题 <prefix>.<tenant>，以 Agent ID 作为消息 Key 保证单个 Agent 的事件有序
+	TopicPerAgent bool
+}
+
+// KafkaTransport 把事件发布到 Kafka
+type KafkaTransport struct {
+	producer KafkaProducer
+	config   KafkaConfig
+}
+
+// NewKafkaTransport 创建 Kafka 传输，生产者由调用方创建和关闭
+func NewKafkaTransport(producer KafkaProducer, config KafkaConfig) (*KafkaTransport, error) {
+	if producer == nil {
+		return nil, errors.New("kafka producer is required")
+	}
+	if config.TopicPrefix == "" {
+		config.TopicPrefix = "aster.events"
+	}
+	return &KafkaTransport{producer: producer, config: config}, nil
+}
+
+// Topic 返回事件发布的主题
+func (t *KafkaTransport) Topic(msg *TransportMessage) string {
+	parts := []string{t.config.TopicPrefix, kafkaTopicName(msg.Source.tenantOrDefault())}
+	if t.config.TopicPerAgent {
+		parts = append(parts, kafkaTopicName(msg.Source.AgentID))
+	}
+	return strings.Join(parts, ".")
+}
+
+// Publish 发布事件，事件类型和通道写入消息 Header 便于消费者过滤
+func (t *KafkaTransport) Publish(ctx context.Context, msg *TransportMessage) error {
+	headers := map[string]string{
+		"agent_id": msg.Source.AgentID,
+		"channel":  string(msg.Channel),
+		"type":     msg.Type,
+	}
+	if msg.Source.TenantID != "" {
+		headers["tenant_id"] = msg.Source.TenantID
+	}
+	return t.producer.Produce(ctx, t.Topic(msg), []byte(msg.Source.AgentID), msg.Data, headers)
+}
+
+// Close 生产者由调用方管理，这里不做任何事
+func (t *KafkaTransport) Close() error {
+	return nil
+}
+
+// kafkaTopicName 把名称转换为合法的 Kafka 主题片段，只保留字母、数字、'-' 和 '_'
+func kafkaTopicName(s string) string {
+	if s == "" {
+		return "_"
+	}
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+			return r
+		}
+		return '_'
+	}, s)
+}
@@ -0,0 +1,75 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+// NATSConn NATS 连接，*nats.Conn 满足该接口
+type NATSConn interface {
+	Publish(subject string, data []byte) error
+}
+
+// NATSConfig NATS 传输配置
+type NATSConfig struct {
+	// SubjectPrefix 主题前缀 (默认 "aster.events")
+	SubjectPrefix string
+}
+
+// NATSTransport 把事件发布到 NATS
+// 主题为 <prefix>.<tenant>.<agent>.<channel>，消费者可以用通配符订阅，
+// 例如 "aster.events.acme.>" 订阅租户 acme 的全部事件
+type NATSTransport struct {
+	conn   NATSConn
+	prefix string
+}
+
+// NewNATSTransport 创建 NATS 传输，连接由调用方建立和关闭
+func NewNATSTransport(conn NATSConn, config NATSConfig) (*NATSTransport, error) {
+	if conn == nil {
+		return nil, errors.New("nats connection is required")
+	}
+	prefix := config.SubjectPrefix
+	if prefix == "" {
+		prefix = "aster.events"
+	}
+	return &NATSTransport{conn: conn, prefix: prefix}, nil
+}
+
+// Subject 返回事件发布的主题
+func (t *NATSTransport) Subject(msg *TransportMessage) string {
+	return strings.Join([]string{
+		t.prefix,
+		natsToken(msg.Source.tenantOrDefault()),
+		natsToken(msg.Source.AgentID),
+		natsToken(string(msg.Channel)),
+	}, ".")
+}
+
+// Publish 发布事件
+func (t *NATSTransport) Publish(ctx context.Context, msg *TransportMessage) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return t.conn.Publish(t.Subject(msg), msg.Data)
+}
+
+// Close 连接由调用方管理，这里不做任何事
+func (t *NATSTransport) Close() error {
+	return nil
+}
+
+// natsToken 把名称转换为合法的 NATS 主题片段：不能为空，不能包含 '.'、空白或通配符
+func natsToken(s string) string {
+	if s == "" {
+		return "_"
+	}
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '.', '*', '>', ' ', '\t', '\r', '\n':
+			return '_'
+		}
+		return r
+	}, s)
+}
@@ -0,0 +1,144 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/astercloud/aster/pkg/logging"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+var transportLog = logging.ForComponent("EventTransport")
+
+const (
+	// defaultTransportQueueSize 外部发布队列默认长度
+	defaultTransportQueueSize = 1024
+	// transportPublishTimeout 单条事件外部发布超时
+	transportPublishTimeout = 5 * time.Second
+)
+
+// Transport 事件外部传输
+// 配置后，EventBus 在进程内分发之外把每个事件发布到外部系统（NATS、Kafka 等），
+// 供分析、告警和多实例 Dashboard 等外部消费者订阅
+type Transport interface {
+	// Publish 发布一条事件，由 EventBus 的发布协程按发出顺序串行调用
+	Publish(ctx context.Context, msg *TransportMessage) error
+	// Close 释放连接；Transport 通常由多个 EventBus 共享，EventBus 关闭时不会调用
+	Close() error
+}
+
+// EventSource 事件来源，用于选择外部主题和分区
+type EventSource struct {
+	AgentID  string `json:"agent_id,omitempty"`
+	TenantID string `json:"tenant_id,omitempty"`
+}
+
+// TransportMessage 发布到外部的事件
+type TransportMessage struct {
+	Source  EventSource
+	Channel types.AgentChannel
+	Type    string
+	Cursor  int64
+	// Data 当前版本的 AgentEventEnvelope JSON
+	Data []byte
+}
+
+// tenantOrDefault 返回租户 ID，未设置多租户时为 "default"
+func (s EventSource) tenantOrDefault() string {
+	if s.TenantID == "" {
+		return "default"
+	}
+	return s.TenantID
+}
+
+// startPublisher 启动外部发布协程
+// 发布在独立协程中进行，外部系统变慢或不可用时不阻塞事件的进程内分发
+func (eb *EventBus) startPublisher() {
+	if eb.config.Transport == nil {
+		return
+	}
+	size := eb.config.TransportQueueSize
+	if size <= 0 {
+		size = defaultTransportQueueSize
+	}
+	queue := make(chan types.AgentEventEnvelope, size)
+	eb.publishQueue = queue
+	eb.publishWg.Add(1)
+
+	go func() {
+		defer eb.publishWg.Done()
+		for envelope := range queue {
+			eb.publish(envelope)
+		}
+	}()
+}
+
+// enqueuePublishLocked 把事件放入外部发布队列，调用方需持有 eb.mu
+// 队列已满时丢弃事件并计数，进程内订阅者不受影响
+func (eb *EventBus) enqueuePublishLocked(envelope types.AgentEventEnvelope) {
+	if eb.publishQueue == nil {
+		return
+	}
+	select {
+	case eb.publishQueue <- envelope:
+	default:
+		eb.publishDropped++
+	}
+}
+
+// publish 序列化并发布一条事件
+func (eb *EventBus) publish(envelope types.AgentEventEnvelope) {
+	data, err := json.Marshal(envelope)
+	if err != nil {
+		transportLog.Warn(context.Background(), "failed to encode event", map[string]any{"type": envelope.Type, "error": err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), transportPublishTimeout)
+	defer cancel()
+	err = eb.config.Transport.Publish(ctx, &TransportMessage{
+		Source:  eb.config.Source,
+		Channel: envelope.Channel,
+		Type:    envelope.Type,
+		Cursor:  envelope.Cursor,
+		Data:    data,
+	})
+	if err != nil {
+		eb.mu.Lock()
+		eb.publishFailed++
+		eb.mu.Unlock()
+		transportLog.Warn(ctx, "failed to publish event", map[string]any{
+			"agent_id": eb.config.Source.AgentID,
+			"type":     envelope.Type,
+			"cursor":   envelope.Cursor,
+			"error":    err.Error(),
+		})
+	}
+}
+
+// stopPublisher 关闭发布队列并等待已入队的事件发布完成
+func (eb *EventBus) stopPublisher() {
+	eb.mu.Lock()
+	queue := eb.publishQueue
+	eb.publishQueue = nil
+	eb.mu.Unlock()
+	if queue == nil {
+		return
+	}
+	close(queue)
+	eb.publishWg.Wait()
+}
+
+// TransportStats 外部发布统计
+type TransportStats struct {
+	Dropped int64 `json:"dropped"` // 队列已满被丢弃的事件数
+	Failed  int64 `json:"failed"`  // 发布失败的事件数
+}
+
+// TransportStats 返回外部发布统计，未配置 Transport 时均为 0
+func (eb *EventBus) TransportStats() TransportStats {
+	eb.mu.RLock()
+	defer eb.mu.RUnlock()
+	return TransportStats{Dropped: eb.publishDropped, Failed: eb.publishFailed}
+}
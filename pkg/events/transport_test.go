@@ -0,0 +1,120 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/astercloud/aster/pkg/types"
+)
+
+type recordingNATSConn struct {
+	mu       sync.Mutex
+	subjects []string
+	data     [][]byte
+}
+
+func (c *recordingNATSConn) Publish(subject string, data []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.subjects = append(c.subjects, subject)
+	c.data = append(c.data, data)
+	return nil
+}
+
+type recordingProducer struct {
+	topic   string
+	key     string
+	headers map[string]string
+	err     error
+}
+
+func (p *recordingProducer) Produce(_ context.Context, topic string, key, _ []byte, headers map[string]string) error {
+	p.topic, p.key, p.headers = topic, string(key), headers
+	return p.err
+}
+
+func TestEventBusPublishesToTransport(t *testing.T) {
+	conn := &recordingNATSConn{}
+	transport, err := NewNATSTransport(conn, NATSConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := DefaultEventBusConfig()
+	config.Transport = transport
+	config.Source = EventSource{AgentID: "agt-1", TenantID: "acme"}
+	eb := NewEventBusWithConfig(config)
+
+	eb.EmitProgress(&types.ProgressTextChunkEvent{Step: 1, Delta: "hi"})
+	eb.EmitMonitor(&types.MonitorStateChangedEvent{State: types.AgentStateWorking})
+	// Close 等待已入队的事件发布完成
+	eb.Close()
+
+	want := []string{"aster.events.acme.agt-1.progress", "aster.events.acme.agt-1.monitor"}
+	if len(conn.subjects) != len(want) {
+		t.Fatalf("published %v, want %v", conn.subjects, want)
+	}
+	for i := range want {
+		if conn.subjects[i] != want[i] {
+			t.Errorf("subject[%d] = %q, want %q", i, conn.subjects[i], want[i])
+		}
+	}
+
+	var env types.AgentEventEnvelope
+	if err := json.Unmarshal(conn.data[0], &env); err != nil {
+		t.Fatal(err)
+	}
+	if ev, ok := env.Event.(*types.ProgressTextChunkEvent); !ok || ev.Delta != "hi" || env.Cursor != 1 {
+		t.Errorf("published envelope = %+v", env)
+	}
+	if stats := eb.TransportStats(); stats != (TransportStats{}) {
+		t.Errorf("stats = %+v", stats)
+	}
+}
+
+func TestKafkaTransportTopics(t *testing.T) {
+	msg := &TransportMessage{
+		Source:  EventSource{AgentID: "agt-1"},
+		Channel: types.ChannelControl,
+		Type:    "permission_required",
+	}
+
+	producer := &recordingProducer{}
+	transport, _ := NewKafkaTransport(producer, KafkaConfig{})
+	if err := transport.Publish(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+	if producer.topic != "aster.events.default" || producer.key != "agt-1" {
+		t.Errorf("topic/key = %q/%q", producer.topic, producer.key)
+	}
+	if producer.headers["type"] != "permission_required" || producer.headers["channel"] != "control" {
+		t.Errorf("headers = %v", producer.headers)
+	}
+
+	perAgent, _ := NewKafkaTransport(producer, KafkaConfig{TopicPrefix: "events", TopicPerAgent: true})
+	msg.Source = EventSource{AgentID: "agt.2", TenantID: "acme corp"}
+	if got := perAgent.Topic(msg); got != "events.acme_corp.agt_2" {
+		t.Errorf("per-agent topic = %q", got)
+	}
+}
+
+func TestEventBusCountsPublishFailures(t *testing.T) {
+	transport, _ := NewKafkaTransport(&recordingProducer{err: errors.New("broker down")}, KafkaConfig{})
+	config := DefaultEventBusConfig()
+	config.Transport = transport
+	eb := NewEventBusWithConfig(config)
+
+	// 外部发布失败不影响进程内订阅者
+	ch := eb.Subscribe([]types.AgentChannel{types.ChannelProgress}, nil)
+	eb.EmitProgress(&types.ProgressTextChunkEvent{Delta: "hi"})
+	if env := <-ch; env.Type != "text_chunk" {
+		t.Errorf("subscriber got %+v", env)
+	}
+	eb.Close()
+
+	if stats := eb.TransportStats(); stats.Failed != 1 {
+		t.Errorf("stats = %+v, want 1 failure", stats)
+	}
+}
@@ -367,7 +367,10 @@ func FormatPlan(plan *ExecutionPlan) string {
 	for i, step := range plan.Steps {
 		statusIcon := getStatusIcon(step.Status)
 		sb.WriteString(fmt.Sprintf("### 步骤 %d: %s %s\n", i+1, step.Description, statusIcon))
-		sb.WriteString(fmt.Sprintf("- 工具: `%s`\n", step.ToolName))
+		// 规划-执行模式的步骤由执行模型完成，不指定工具
+		if step.ToolName != "" {
+			sb.WriteString(fmt.Sprintf("- 工具: `%s`\n", step.ToolName))
+		}
 
 		if step.Input != "" {
 			sb.WriteString(fmt.Sprintf("- 输入: %s\n", step.Input))
@@ -0,0 +1,184 @@
+// Package experiments 模板/配方的 A/B 实验
+//
+// 实验作用于一个基准模板：新建的 Agent 按稳定哈希分桶，按比例分配到变体（替换模板、配方或模型），
+// 其余保持基准配置作为对照组。分组结果写入 AgentConfig.Metadata，
+// 运行耗时、Token 用量和评估打分按变体汇总，用于比较各变体的效果。
+package experiments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"regexp"
+	"time"
+
+	"github.com/astercloud/aster/pkg/types"
+)
+
+// 写入 AgentConfig.Metadata 的实验标记
+const (
+	MetadataExperimentID      = "experiment_id"
+	MetadataExperimentVariant = "experiment_variant"
+	MetadataExperimentVersion = "experiment_version"
+)
+
+// ControlVariant 未分配到任何变体时的对照组名称
+const ControlVariant = "control"
+
+// Status 实验状态
+type Status string
+
+const (
+	StatusDraft     Status = "draft"
+	StatusRunning   Status = "running"
+	StatusPaused    Status = "paused"
+	StatusCompleted Status = "completed"
+)
+
+// Valid 是否为支持的状态
+func (s Status) Valid() bool {
+	switch s {
+	case StatusDraft, StatusRunning, StatusPaused, StatusCompleted:
+		return true
+	}
+	return false
+}
+
+// ErrNotFound 实验不存在
+var ErrNotFound = errors.New("experiment not found")
+
+var variantNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+// Experiment 一个 A/B 实验
+type Experiment struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	// TemplateID 基准模板，只有使用该模板创建的 Agent 参与实验
+	TemplateID string    `json:"template_id"`
+	Variants   []Variant `json:"variants"`
+	Status     Status    `json:"status"`
+	// Version 每次修改配置时递增，分组记录保存当时的版本，便于区分不同版本的结果
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Variant 实验变体，未设置的字段沿用基准配置
+type Variant struct {
+	Name string `json:"name"`
+	// Percent 分配到该变体的 Agent 百分比，所有变体之和不超过 100，剩余部分为对照组
+	Percent int `json:"percent"`
+	// TemplateID 替换的模板
+	TemplateID string `json:"template_id,omitempty"`
+	// Recipe 配方文件路径，使用其中的 template_id 和 settings.model
+	Recipe string `json:"recipe,omitempty"`
+	// Model 替换的模型
+	Model string `json:"model,omitempty"`
+}
+
+// Validate 校验实验配置
+func (e *Experiment) Validate() error {
+	if e.Name == "" {
+		return errors.New("experiment name is required")
+	}
+	if e.TemplateID == "" {
+		return errors.New("experiment template_id is required")
+	}
+	if len(e.Variants) == 0 {
+		return errors.New("experiment needs at least one variant")
+	}
+	if e.Status != "" && !e.Status.Valid() {
+		return fmt.Errorf("invalid status %q", e.Status)
+	}
+
+	total := 0
+	seen := map[string]bool{ControlVariant: true}
+	for _, v := range e.Variants {
+		if !variantNamePattern.MatchString(v.Name) {
+			return fmt.Errorf("invalid variant name %q", v.Name)
+		}
+		if seen[v.Name] {
+			return fmt.Errorf("duplicate or reserved variant name %q", v.Name)
+		}
+		seen[v.Name] = true
+		if v.Percent <= 0 {
+			return fmt.Errorf("variant %s: percent must be positive", v.Name)
+		}
+		if v.TemplateID == "" && v.Recipe == "" && v.Model == "" {
+			return fmt.Errorf("variant %s: one of template_id, recipe or model is required", v.Name)
+		}
+		total += v.Percent
+	}
+	if total > 100 {
+		return fmt.Errorf("variant percentages add up to %d, must not exceed 100", total)
+	}
+	return nil
+}
+
+// pick 按分桶选择变体，落在所有变体之外时返回 nil（对照组）
+func (e *Experiment) pick(key string) *Variant {
+	b := bucket(e.ID, key)
+	cumulative := 0
+	for i := range e.Variants {
+		cumulative += e.Variants[i].Percent
+		if b < cumulative {
+			return &e.Variants[i]
+		}
+	}
+	return nil
+}
+
+// bucket 将 key 稳定映射到 [0, 100)，同一 key 在同一实验中总是落在同一个桶
+func bucket(experimentID, key string) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(experimentID + ":" + key))
+	return int(h.Sum32() % 100)
+}
+
+// Assignment Agent 的分组结果
+type Assignment struct {
+	AgentID      string `json:"agent_id"`
+	ExperimentID string `json:"experiment_id"`
+	Variant      string `json:"variant"`
+	Version      int    `json:"version"`
+	// TemplateID/Model 变体生效后的模板和模型，Model 为空表示不替换
+	TemplateID string    `json:"template_id"`
+	Model      string    `json:"model,omitempty"`
+	AssignedAt time.Time `json:"assigned_at"`
+}
+
+// Run 实验中 Agent 的一次运行
+type Run struct {
+	ID           string    `json:"id"`
+	ExperimentID string    `json:"experiment_id"`
+	Variant      string    `json:"variant"`
+	AgentID      string    `json:"agent_id"`
+	Model        string    `json:"model,omitempty"`
+	Completed    bool      `json:"completed"`
+	DurationMs   int64     `json:"duration_ms"`
+	InputTokens  int64     `json:"input_tokens"`
+	OutputTokens int64     `json:"output_tokens"`
+	Timestamp    time.Time `json:"timestamp"`
+}
+
+// Grade 评估打分，通常来自 evals 中的 Scorer
+type Grade struct {
+	ID           string    `json:"id"`
+	ExperimentID string    `json:"experiment_id"`
+	Variant      string    `json:"variant"`
+	AgentID      string    `json:"agent_id"`
+	Grader       string    `json:"grader"`
+	Score        float64   `json:"score"`
+	Passed       bool      `json:"passed"`
+	Timestamp    time.Time `json:"timestamp"`
+}
+
+// Assigner 实验分组入口，Agent 通过 Dependencies.Experiments 注入
+type Assigner interface {
+	// Assign 为即将创建的 Agent 选择变体，不参与实验时返回 nil
+	Assign(ctx context.Context, config *types.AgentConfig) (*Assignment, error)
+	// RecordRun 记录一次运行
+	RecordRun(ctx context.Context, run Run) error
+}
@@ -0,0 +1,201 @@
+package experiments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/astercloud/aster/pkg/evals"
+	"github.com/astercloud/aster/pkg/store"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+func newTestManager(t *testing.T) *Manager {
+	t.Helper()
+	st, err := store.NewJSONStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewJSONStore() error = %v", err)
+	}
+	return NewManager(st)
+}
+
+func TestExperiment_Validate(t *testing.T) {
+	tests := []struct {
+		name     string
+		variants []Variant
+		wantErr  bool
+	}{
+		{"ok", []Variant{{Name: "b", Percent: 50, TemplateID: "t2"}}, false},
+		{"no variants", nil, true},
+		{"reserved name", []Variant{{Name: ControlVariant, Percent: 10, Model: "m"}}, true},
+		{"duplicate name", []Variant{{Name: "b", Percent: 10, Model: "m"}, {Name: "b", Percent: 10, Model: "m"}}, true},
+		{"over 100", []Variant{{Name: "b", Percent: 60, Model: "m"}, {Name: "c", Percent: 50, Model: "m"}}, true},
+		{"zero percent", []Variant{{Name: "b", Model: "m"}}, true},
+		{"no override", []Variant{{Name: "b", Percent: 10}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exp := &Experiment{Name: "exp", TemplateID: "base", Variants: tt.variants}
+			if err := exp.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestManager_Assign(t *testing.T) {
+	m := newTestManager(t)
+	ctx := context.Background()
+
+	recipePath := filepath.Join(t.TempDir(), "variant.yaml")
+	if err := os.WriteFile(recipePath, []byte("version: \"1.0\"\ntitle: Variant\ndescription: Shorter prompt\ninstructions: Be brief.\ntemplate_id: recipe-tpl\nsettings:\n  model: gpt-4o\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	exp := &Experiment{
+		Name:       "prompt-v2",
+		TemplateID: "base",
+		Status:     StatusRunning,
+		Variants:   []Variant{{Name: "v2", Percent: 50, Recipe: recipePath, Model: "claude-sonnet-4-5"}},
+	}
+	if err := m.Create(ctx, exp); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	// 其他模板不参与
+	if a, err := m.Assign(ctx, &types.AgentConfig{AgentID: "agt-x", TemplateID: "other"}); err != nil || a != nil {
+		t.Fatalf("Assign(other) = %+v, %v", a, err)
+	}
+
+	counts := map[string]int{}
+	for i := range 200 {
+		a, err := m.Assign(ctx, &types.AgentConfig{AgentID: fmt.Sprintf("agt-%d", i), TemplateID: "base"})
+		if err != nil || a == nil {
+			t.Fatalf("Assign() = %+v, %v", a, err)
+		}
+		counts[a.Variant]++
+		switch a.Variant {
+		case "v2":
+			// 显式模型优先于配方中的模型
+			if a.TemplateID != "recipe-tpl" || a.Model != "claude-sonnet-4-5" {
+				t.Errorf("v2 assignment = %+v", a)
+			}
+		case ControlVariant:
+			if a.TemplateID != "base" || a.Model != "" {
+				t.Errorf("control assignment = %+v", a)
+			}
+		}
+	}
+	if counts["v2"] < 60 || counts[ControlVariant] < 60 {
+		t.Errorf("variant split = %v, want roughly even", counts)
+	}
+
+	// 同一用户总是落在同一变体
+	first, _ := m.Assign(ctx, &types.AgentConfig{AgentID: "u-1", TemplateID: "base", Metadata: map[string]any{"user_id": "alice"}})
+	second, _ := m.Assign(ctx, &types.AgentConfig{AgentID: "u-2", TemplateID: "base", Metadata: map[string]any{"user_id": "alice"}})
+	if first.Variant != second.Variant {
+		t.Errorf("user alice assigned to %s and %s", first.Variant, second.Variant)
+	}
+
+	// 已带实验标记的配置不再分组
+	tagged := &types.AgentConfig{AgentID: "agt-t", TemplateID: "base", Metadata: map[string]any{MetadataExperimentID: exp.ID}}
+	if a, _ := m.Assign(ctx, tagged); a != nil {
+		t.Errorf("Assign(tagged) = %+v, want nil", a)
+	}
+}
+
+func TestManager_UpdateBumpsVersion(t *testing.T) {
+	m := newTestManager(t)
+	ctx := context.Background()
+	exp := &Experiment{Name: "exp", TemplateID: "base", Variants: []Variant{{Name: "b", Percent: 10, Model: "m1"}}}
+	if err := m.Create(ctx, exp); err != nil {
+		t.Fatal(err)
+	}
+
+	exp.Status = StatusRunning
+	if err := m.Update(ctx, exp); err != nil || exp.Version != 1 {
+		t.Fatalf("status update: version = %d, err = %v", exp.Version, err)
+	}
+	exp.Variants[0].Model = "m2"
+	if err := m.Update(ctx, exp); err != nil || exp.Version != 2 {
+		t.Fatalf("variant update: version = %d, err = %v", exp.Version, err)
+	}
+
+	if err := m.Delete(ctx, exp.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Get(ctx, exp.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() after delete error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestManager_Results(t *testing.T) {
+	m := newTestManager(t)
+	ctx := context.Background()
+	exp := &Experiment{Name: "exp", TemplateID: "base", Status: StatusRunning, Variants: []Variant{{Name: "b", Percent: 30, Model: "gpt-4o"}}}
+	if err := m.Create(ctx, exp); err != nil {
+		t.Fatal(err)
+	}
+
+	// 直接写入分组，避免依赖分桶结果
+	for agentID, variant := range map[string]string{"a1": ControlVariant, "a2": ControlVariant, "b1": "b"} {
+		a := Assignment{AgentID: agentID, ExperimentID: exp.ID, Variant: variant}
+		if err := m.store.Set(ctx, collectionAssignments, agentID, a); err != nil {
+			t.Fatal(err)
+		}
+	}
+	runs := []Run{
+		{ExperimentID: exp.ID, Variant: ControlVariant, AgentID: "a1", Model: "gpt-4o", Completed: true, DurationMs: 100, InputTokens: 1_000_000},
+		{ExperimentID: exp.ID, Variant: ControlVariant, AgentID: "a2", Model: "gpt-4o", Completed: false, DurationMs: 300, InputTokens: 1_000_000},
+		{ExperimentID: exp.ID, Variant: "b", AgentID: "b1", Model: "gpt-4o", Completed: true, DurationMs: 50},
+		{ExperimentID: "other", Variant: "b", AgentID: "x", Completed: true},
+	}
+	for _, run := range runs {
+		if err := m.RecordRun(ctx, run); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, g := range []Grade{
+		{AgentID: "a1", Grader: "manual", Score: 0.2},
+		{AgentID: "a2", Grader: "manual", Score: 0.9, Passed: true},
+	} {
+		if _, err := m.RecordGrade(ctx, g); err != nil {
+			t.Fatal(err)
+		}
+	}
+	scorer := evals.NewKeywordCoverageScorer(evals.KeywordCoverageConfig{Keywords: []string{"retry", "backoff"}, CaseInsensitive: true})
+	grade, err := m.Grade(ctx, "b1", scorer, &evals.TextEvalInput{Answer: "Use retry with exponential backoff"}, 0.5)
+	if err != nil || !grade.Passed || grade.Variant != "b" {
+		t.Fatalf("Grade() = %+v, %v", grade, err)
+	}
+	if _, err := m.RecordGrade(ctx, Grade{AgentID: "unknown", Grader: "manual"}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("RecordGrade(unknown) error = %v, want ErrNotFound", err)
+	}
+
+	results, err := m.Results(ctx, exp.ID, nil)
+	if err != nil {
+		t.Fatalf("Results() error = %v", err)
+	}
+	if len(results.Variants) != 2 {
+		t.Fatalf("Variants = %+v", results.Variants)
+	}
+	control, b := results.Variants[0], results.Variants[1]
+	if control.Variant != ControlVariant || control.Percent != 70 || control.Agents != 2 || control.Runs != 2 {
+		t.Errorf("control = %+v", control)
+	}
+	if control.CompletionRate != 0.5 || control.SuccessRate != 0.5 || control.AvgScore != 0.55 || control.Latency.Max != 300 {
+		t.Errorf("control metrics = %+v", control)
+	}
+	// gpt-4o 输入 $2.5/M
+	if control.Cost != 5 || control.AvgCost != 2.5 {
+		t.Errorf("control cost = %v avg %v, want 5 avg 2.5", control.Cost, control.AvgCost)
+	}
+	if b.Agents != 1 || b.Runs != 1 || b.SuccessRate != 1 || b.CompletionRate != 1 {
+		t.Errorf("b = %+v", b)
+	}
+	if best := results.Best(); best == nil || best.Variant != "b" {
+		t.Errorf("Best() = %+v, want b", best)
+	}
+}
@@ -0,0 +1,284 @@
+package experiments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/astercloud/aster/pkg/evals"
+	"github.com/astercloud/aster/pkg/recipe"
+	"github.com/astercloud/aster/pkg/store"
+	"github.com/astercloud/aster/pkg/types"
+	"github.com/google/uuid"
+)
+
+// 实验数据的集合名
+const (
+	collectionExperiments = "experiments"
+	collectionAssignments = "experiment_assignments"
+	collectionRuns        = "experiment_runs"
+	collectionGrades      = "experiment_grades"
+)
+
+// Manager 基于 store.Store 的实验管理器，实现 Assigner
+type Manager struct {
+	store store.Store
+	now   func() time.Time
+
+	// mu 保护实验的读改写
+	mu sync.Mutex
+}
+
+var _ Assigner = (*Manager)(nil)
+
+// NewManager 创建实验管理器
+// 传入 store.TenantStore 时使用其底层存储，实验对所有租户生效
+func NewManager(st store.Store) *Manager {
+	if ts, ok := st.(*store.TenantStore); ok {
+		st = ts.Unwrap()
+	}
+	return &Manager{store: st, now: time.Now}
+}
+
+// Create 创建实验，未设置状态时为 draft
+func (m *Manager) Create(ctx context.Context, exp *Experiment) error {
+	if err := exp.Validate(); err != nil {
+		return err
+	}
+	now := m.now().UTC()
+	exp.ID = uuid.New().String()
+	if exp.Status == "" {
+		exp.Status = StatusDraft
+	}
+	exp.Version = 1
+	exp.CreatedAt, exp.UpdatedAt = now, now
+	if err := m.store.Set(ctx, collectionExperiments, exp.ID, exp); err != nil {
+		return fmt.Errorf("save experiment: %w", err)
+	}
+	return nil
+}
+
+// Get 获取实验
+func (m *Manager) Get(ctx context.Context, id string) (*Experiment, error) {
+	var exp Experiment
+	if err := m.store.Get(ctx, collectionExperiments, id, &exp); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("load experiment: %w", err)
+	}
+	return &exp, nil
+}
+
+// List 按创建时间列出全部实验
+func (m *Manager) List(ctx context.Context) ([]*Experiment, error) {
+	items, err := m.store.List(ctx, collectionExperiments)
+	if err != nil {
+		return nil, fmt.Errorf("list experiments: %w", err)
+	}
+	experiments := make([]*Experiment, 0, len(items))
+	for _, item := range items {
+		var exp Experiment
+		if err := store.DecodeValue(item, &exp); err != nil || exp.ID == "" {
+			continue
+		}
+		experiments = append(experiments, &exp)
+	}
+	slices.SortFunc(experiments, func(a, b *Experiment) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+	return experiments, nil
+}
+
+// Update 修改实验配置，变体或基准模板变化时版本号加一
+func (m *Manager) Update(ctx context.Context, exp *Experiment) error {
+	if err := exp.Validate(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	current, err := m.Get(ctx, exp.ID)
+	if err != nil {
+		return err
+	}
+	if exp.Status == "" {
+		exp.Status = current.Status
+	}
+	exp.Version = current.Version
+	if exp.TemplateID != current.TemplateID || !slices.Equal(exp.Variants, current.Variants) {
+		exp.Version++
+	}
+	exp.CreatedAt = current.CreatedAt
+	exp.UpdatedAt = m.now().UTC()
+	if err := m.store.Set(ctx, collectionExperiments, exp.ID, exp); err != nil {
+		return fmt.Errorf("save experiment: %w", err)
+	}
+	return nil
+}
+
+// Delete 删除实验，已有的分组和结果保留
+func (m *Manager) Delete(ctx context.Context, id string) error {
+	if err := m.store.Delete(ctx, collectionExperiments, id); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return ErrNotFound
+		}
+		return fmt.Errorf("delete experiment: %w", err)
+	}
+	return nil
+}
+
+// Assign 为即将创建的 Agent 选择变体并记录分组
+// 已带实验标记的配置（例如复制自其他 Agent）不再重新分组；
+// 分桶优先使用 Metadata 中的 user_id/session_id，同一用户始终落在同一变体
+func (m *Manager) Assign(ctx context.Context, config *types.AgentConfig) (*Assignment, error) {
+	if _, tagged := config.Metadata[MetadataExperimentID]; tagged {
+		return nil, nil
+	}
+	experiments, err := m.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	idx := slices.IndexFunc(experiments, func(e *Experiment) bool {
+		return e.Status == StatusRunning && e.TemplateID == config.TemplateID
+	})
+	if idx < 0 {
+		return nil, nil
+	}
+	exp := experiments[idx]
+
+	a := &Assignment{
+		AgentID:      config.AgentID,
+		ExperimentID: exp.ID,
+		Variant:      ControlVariant,
+		Version:      exp.Version,
+		TemplateID:   exp.TemplateID,
+		AssignedAt:   m.now().UTC(),
+	}
+	if v := exp.pick(assignmentKey(config)); v != nil {
+		if err := resolveVariant(v, a); err != nil {
+			return nil, fmt.Errorf("experiment %s variant %s: %w", exp.ID, v.Name, err)
+		}
+	}
+	if err := m.store.Set(ctx, collectionAssignments, a.AgentID, a); err != nil {
+		return nil, fmt.Errorf("save assignment: %w", err)
+	}
+	return a, nil
+}
+
+// resolveVariant 将变体的配方、模板和模型合并到分组结果，显式字段优先于配方
+func resolveVariant(v *Variant, a *Assignment) error {
+	a.Variant = v.Name
+	if v.Recipe != "" {
+		r, err := recipe.LoadFromFile(v.Recipe)
+		if err != nil {
+			return err
+		}
+		if r.TemplateID != "" {
+			a.TemplateID = r.TemplateID
+		}
+		if r.Settings != nil && r.Settings.Model != "" {
+			a.Model = r.Settings.Model
+		}
+	}
+	if v.TemplateID != "" {
+		a.TemplateID = v.TemplateID
+	}
+	if v.Model != "" {
+		a.Model = v.Model
+	}
+	return nil
+}
+
+// assignmentKey 分桶使用的标识
+func assignmentKey(config *types.AgentConfig) string {
+	for _, key := range []string{"user_id", "session_id"} {
+		if s, ok := config.Metadata[key].(string); ok && s != "" {
+			return s
+		}
+	}
+	return config.AgentID
+}
+
+// GetAssignment 获取 Agent 的分组
+func (m *Manager) GetAssignment(ctx context.Context, agentID string) (*Assignment, error) {
+	var a Assignment
+	if err := m.store.Get(ctx, collectionAssignments, agentID, &a); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("load assignment: %w", err)
+	}
+	return &a, nil
+}
+
+// RecordRun 记录实验中 Agent 的一次运行
+func (m *Manager) RecordRun(ctx context.Context, run Run) error {
+	if run.ID == "" {
+		run.ID = uuid.New().String()
+	}
+	if run.Timestamp.IsZero() {
+		run.Timestamp = m.now()
+	}
+	run.Timestamp = run.Timestamp.UTC()
+	if err := m.store.Set(ctx, collectionRuns, run.ID, run); err != nil {
+		return fmt.Errorf("save experiment run: %w", err)
+	}
+	return nil
+}
+
+// RecordGrade 记录对实验中 Agent 的评估打分，实验和变体从分组中补全
+func (m *Manager) RecordGrade(ctx context.Context, grade Grade) (*Grade, error) {
+	a, err := m.GetAssignment(ctx, grade.AgentID)
+	if err != nil {
+		return nil, fmt.Errorf("agent %s: %w", grade.AgentID, err)
+	}
+	if grade.Grader == "" {
+		return nil, errors.New("grader is required")
+	}
+	grade.ID = uuid.New().String()
+	grade.ExperimentID = a.ExperimentID
+	grade.Variant = a.Variant
+	if grade.Timestamp.IsZero() {
+		grade.Timestamp = m.now()
+	}
+	grade.Timestamp = grade.Timestamp.UTC()
+	if err := m.store.Set(ctx, collectionGrades, grade.ID, grade); err != nil {
+		return nil, fmt.Errorf("save experiment grade: %w", err)
+	}
+	return &grade, nil
+}
+
+// Grade 使用 evals 中的 Scorer 为 Agent 的输出打分并记录，得分不低于 passScore 视为成功
+func (m *Manager) Grade(ctx context.Context, agentID string, scorer evals.Scorer, input *evals.TextEvalInput, passScore float64) (*Grade, error) {
+	result, err := scorer.Score(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("score: %w", err)
+	}
+	return m.RecordGrade(ctx, Grade{
+		AgentID: agentID,
+		Grader:  result.Name,
+		Score:   result.Value,
+		Passed:  result.Value >= passScore,
+	})
+}
+
+// listByExperiment 读取集合中属于实验的记录
+func listByExperiment[T any](ctx context.Context, st store.Store, collection, experimentID string, id func(*T) string) ([]T, error) {
+	items, err := st.List(ctx, collection)
+	if err != nil {
+		return nil, fmt.Errorf("list %s: %w", collection, err)
+	}
+	var records []T
+	for _, item := range items {
+		var rec T
+		if err := store.DecodeValue(item, &rec); err != nil || id(&rec) != experimentID {
+			continue
+		}
+		records = append(records, rec)
+	}
+	return records, nil
+}
@@ -0,0 +1,150 @@
+package experiments
+
+import (
+	"cmp"
+	"context"
+	"time"
+
+	"github.com/astercloud/aster/pkg/dashboard"
+)
+
+// VariantResult 单个变体（含对照组）的汇总指标
+type VariantResult struct {
+	Variant string `json:"variant"`
+	Percent int    `json:"percent"`
+	// Agents 分配到该变体的 Agent 数
+	Agents int `json:"agents"`
+	Runs   int `json:"runs"`
+	// CompletionRate 正常结束的运行占比
+	CompletionRate float64 `json:"completion_rate"`
+	// Graded/Passed/SuccessRate/AvgScore 来自评估打分
+	Graded      int     `json:"graded"`
+	Passed      int     `json:"passed"`
+	SuccessRate float64 `json:"success_rate"`
+	AvgScore    float64 `json:"avg_score"`
+	// Cost/AvgCost 按运行的 Token 用量和模型定价计算
+	InputTokens  int64                        `json:"input_tokens"`
+	OutputTokens int64                        `json:"output_tokens"`
+	Cost         float64                      `json:"cost"`
+	AvgCost      float64                      `json:"avg_cost"`
+	Latency      dashboard.LatencyPercentiles `json:"latency_ms"`
+}
+
+// Results 实验的对比结果
+type Results struct {
+	Experiment  *Experiment     `json:"experiment"`
+	Currency    string          `json:"currency"`
+	GeneratedAt time.Time       `json:"generated_at"`
+	Variants    []VariantResult `json:"variants"`
+}
+
+// Results 按变体汇总实验的分组、运行和评估打分，calc 为空时使用默认定价表
+func (m *Manager) Results(ctx context.Context, experimentID string, calc *dashboard.CostCalculator) (*Results, error) {
+	exp, err := m.Get(ctx, experimentID)
+	if err != nil {
+		return nil, err
+	}
+	if calc == nil {
+		calc = dashboard.NewCostCalculator(nil)
+	}
+
+	assignments, err := listByExperiment(ctx, m.store, collectionAssignments, exp.ID, func(a *Assignment) string { return a.ExperimentID })
+	if err != nil {
+		return nil, err
+	}
+	runs, err := listByExperiment(ctx, m.store, collectionRuns, exp.ID, func(r *Run) string { return r.ExperimentID })
+	if err != nil {
+		return nil, err
+	}
+	grades, err := listByExperiment(ctx, m.store, collectionGrades, exp.ID, func(g *Grade) string { return g.ExperimentID })
+	if err != nil {
+		return nil, err
+	}
+
+	// 对照组在前，其后按实验中的变体顺序；已删除的变体仍保留结果
+	results := map[string]*VariantResult{}
+	order := []string{ControlVariant}
+	control := 100
+	for _, v := range exp.Variants {
+		results[v.Name] = &VariantResult{Variant: v.Name, Percent: v.Percent}
+		order = append(order, v.Name)
+		control -= v.Percent
+	}
+	results[ControlVariant] = &VariantResult{Variant: ControlVariant, Percent: control}
+	get := func(name string) *VariantResult {
+		r, ok := results[name]
+		if !ok {
+			r = &VariantResult{Variant: name}
+			results[name] = r
+			order = append(order, name)
+		}
+		return r
+	}
+
+	for _, a := range assignments {
+		get(a.Variant).Agents++
+	}
+
+	completed := map[string]int{}
+	latencies := map[string][]int64{}
+	for _, run := range runs {
+		r := get(run.Variant)
+		r.Runs++
+		if run.Completed {
+			completed[run.Variant]++
+		}
+		r.InputTokens += run.InputTokens
+		r.OutputTokens += run.OutputTokens
+		usage := dashboard.Usage{InputTokens: run.InputTokens, OutputTokens: run.OutputTokens}
+		if cost := calc.CalculateUsageAt(run.Model, usage, run.Timestamp).TotalCost; cost.Currency == calc.Currency() {
+			r.Cost += cost.Amount
+		}
+		latencies[run.Variant] = append(latencies[run.Variant], run.DurationMs)
+	}
+
+	scores := map[string]float64{}
+	for _, g := range grades {
+		r := get(g.Variant)
+		r.Graded++
+		if g.Passed {
+			r.Passed++
+		}
+		scores[g.Variant] += g.Score
+	}
+
+	out := &Results{
+		Experiment:  exp,
+		Currency:    calc.Currency(),
+		GeneratedAt: m.now().UTC(),
+		Variants:    make([]VariantResult, 0, len(order)),
+	}
+	for _, name := range order {
+		r := results[name]
+		if r.Runs > 0 {
+			r.CompletionRate = float64(completed[name]) / float64(r.Runs)
+			r.AvgCost = r.Cost / float64(r.Runs)
+			r.Latency = dashboard.CalculatePercentiles(latencies[name])
+		}
+		if r.Graded > 0 {
+			r.SuccessRate = float64(r.Passed) / float64(r.Graded)
+			r.AvgScore = scores[name] / float64(r.Graded)
+		}
+		out.Variants = append(out.Variants, *r)
+	}
+	return out, nil
+}
+
+// Best 返回评估成功率最高的变体，成功率相同时成本更低者优先；没有评估数据时返回 nil
+func (r *Results) Best() *VariantResult {
+	var best *VariantResult
+	for i := range r.Variants {
+		v := &r.Variants[i]
+		if v.Graded == 0 {
+			continue
+		}
+		if best == nil || cmp.Or(cmp.Compare(v.SuccessRate, best.SuccessRate), cmp.Compare(best.AvgCost, v.AvgCost)) > 0 {
+			best = v
+		}
+	}
+	return best
+}
@@ -0,0 +1,149 @@
+package guardrails
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/astercloud/aster/pkg/types"
+)
+
+// DefaultReplacement 脱敏规则默认的替换文本
+const DefaultReplacement = "[REDACTED]"
+
+// DefaultBlockMessage 内容被拦截时默认的提示文本
+const DefaultBlockMessage = "This content was blocked by the safety policy."
+
+// Intervention 一次策略介入
+type Intervention struct {
+	Action  types.SafetyAction
+	Source  string // 规则名或分类器名
+	Trigger CheckTrigger
+	Message string
+	Matches int
+}
+
+// FilterResult 过滤结果
+type FilterResult struct {
+	// Content 脱敏后的内容；Blocked 时为 BlockMessage
+	Content       string
+	Blocked       bool
+	Interventions []Intervention
+}
+
+// Filter 由 SafetyPolicy 编译得到的内容过滤器
+// 先按顺序执行正则规则，再依次调用分类器；任一规则或分类器拦截时停止
+type Filter struct {
+	rules        []compiledRule
+	classifiers  []Guardrail
+	blockMessage string
+}
+
+type compiledRule struct {
+	name        string
+	re          *regexp.Regexp
+	action      types.SafetyAction
+	replacement string
+}
+
+// NewFilter 编译安全策略；classifiers 为按名称注册的分类器，策略引用未注册的分类器时返回错误
+func NewFilter(policy *types.SafetyPolicy, classifiers map[string]Guardrail) (*Filter, error) {
+	f := &Filter{blockMessage: policy.BlockMessage}
+	if f.blockMessage == "" {
+		f.blockMessage = DefaultBlockMessage
+	}
+	for i, rule := range policy.Rules {
+		name := rule.Name
+		if name == "" {
+			name = fmt.Sprintf("rule-%d", i+1)
+		}
+		re, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("safety rule %s: %w", name, err)
+		}
+		action := rule.Action
+		switch action {
+		case "":
+			action = types.SafetyActionRedact
+		case types.SafetyActionBlock, types.SafetyActionRedact:
+		default:
+			return nil, fmt.Errorf("safety rule %s: unknown action %q", name, action)
+		}
+		replacement := rule.Replacement
+		if replacement == "" {
+			replacement = DefaultReplacement
+		}
+		f.rules = append(f.rules, compiledRule{name: name, re: re, action: action, replacement: replacement})
+	}
+	for _, name := range policy.Classifiers {
+		g, ok := classifiers[name]
+		if !ok {
+			return nil, fmt.Errorf("safety classifier %s not registered", name)
+		}
+		f.classifiers = append(f.classifiers, g)
+	}
+	return f, nil
+}
+
+// Apply 检查并过滤内容
+// 分类器返回 ShouldMask 的 GuardrailError 时按脱敏处理，其他 GuardrailError 视为拦截；
+// 分类器本身出错时返回错误，由调用方决定是否放行
+func (f *Filter) Apply(ctx context.Context, input *GuardrailInput) (*FilterResult, error) {
+	result := &FilterResult{Content: input.Content}
+	for _, rule := range f.rules {
+		matches := len(rule.re.FindAllStringIndex(result.Content, -1))
+		if matches == 0 {
+			continue
+		}
+		result.Interventions = append(result.Interventions, Intervention{
+			Action:  rule.action,
+			Source:  rule.name,
+			Trigger: CheckTriggerCustom,
+			Matches: matches,
+		})
+		if rule.action == types.SafetyActionBlock {
+			return f.block(result), nil
+		}
+		result.Content = rule.re.ReplaceAllString(result.Content, rule.replacement)
+	}
+
+	for _, g := range f.classifiers {
+		checked := *input
+		checked.Content = result.Content
+		err := g.Check(ctx, &checked)
+		if err == nil {
+			continue
+		}
+		var gerr *GuardrailError
+		if !errors.As(err, &gerr) {
+			return nil, fmt.Errorf("safety classifier %s: %w", g.Name(), err)
+		}
+		intervention := Intervention{
+			Action:  types.SafetyActionBlock,
+			Source:  g.Name(),
+			Trigger: gerr.Trigger,
+			Message: gerr.Message,
+		}
+		if gerr.ShouldMask {
+			intervention.Action = types.SafetyActionRedact
+			result.Interventions = append(result.Interventions, intervention)
+			result.Content = gerr.MaskedContent
+			continue
+		}
+		result.Interventions = append(result.Interventions, intervention)
+		return f.block(result), nil
+	}
+	return result, nil
+}
+
+func (f *Filter) block(result *FilterResult) *FilterResult {
+	result.Blocked = true
+	result.Content = f.blockMessage
+	return result
+}
+
+// BlockMessage 内容被拦截时展示的文本
+func (f *Filter) BlockMessage() string {
+	return f.blockMessage
+}
@@ -0,0 +1,86 @@
+package guardrails
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/astercloud/aster/pkg/types"
+)
+
+type stubClassifier struct {
+	name string
+	err  error
+}
+
+func (s *stubClassifier) Check(ctx context.Context, input *GuardrailInput) error { return s.err }
+func (s *stubClassifier) Name() string                                           { return s.name }
+func (s *stubClassifier) Description() string                                    { return "stub" }
+
+func TestFilterRules(t *testing.T) {
+	f, err := NewFilter(&types.SafetyPolicy{
+		Rules: []types.SafetyRule{
+			{Name: "email", Pattern: `\w+@example\.com`, Replacement: "<email>"},
+			{Name: "secret", Pattern: `(?i)top secret`, Action: types.SafetyActionBlock},
+		},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	result, err := f.Apply(ctx, &GuardrailInput{Content: "mail a@example.com or b@example.com"})
+	if err != nil || result.Blocked || result.Content != "mail <email> or <email>" {
+		t.Fatalf("redact: result = %+v, err = %v", result, err)
+	}
+	if len(result.Interventions) != 1 || result.Interventions[0].Matches != 2 || result.Interventions[0].Action != types.SafetyActionRedact {
+		t.Errorf("interventions = %+v", result.Interventions)
+	}
+
+	result, _ = f.Apply(ctx, &GuardrailInput{Content: "this is TOP SECRET"})
+	if !result.Blocked || result.Content != DefaultBlockMessage {
+		t.Errorf("block: result = %+v", result)
+	}
+
+	result, _ = f.Apply(ctx, &GuardrailInput{Content: "hello"})
+	if result.Blocked || result.Content != "hello" || len(result.Interventions) != 0 {
+		t.Errorf("clean: result = %+v", result)
+	}
+}
+
+func TestFilterClassifiers(t *testing.T) {
+	classifiers := map[string]Guardrail{
+		"mask":  &stubClassifier{name: "mask", err: &GuardrailError{Trigger: CheckTriggerPIIDetected, Message: "pii", ShouldMask: true, MaskedContent: "***"}},
+		"toxic": &stubClassifier{name: "toxic", err: &GuardrailError{Trigger: CheckTriggerToxicContent, Message: "toxic"}},
+		"down":  &stubClassifier{name: "down", err: errors.New("connection refused")},
+	}
+	ctx := context.Background()
+
+	f, _ := NewFilter(&types.SafetyPolicy{Classifiers: []string{"mask", "toxic"}, BlockMessage: "nope"}, classifiers)
+	result, err := f.Apply(ctx, &GuardrailInput{Content: "x"})
+	if err != nil || !result.Blocked || result.Content != "nope" || len(result.Interventions) != 2 {
+		t.Fatalf("result = %+v, err = %v", result, err)
+	}
+	if iv := result.Interventions[1]; iv.Source != "toxic" || iv.Trigger != CheckTriggerToxicContent {
+		t.Errorf("intervention = %+v", iv)
+	}
+
+	f, _ = NewFilter(&types.SafetyPolicy{Classifiers: []string{"down"}}, classifiers)
+	if _, err := f.Apply(ctx, &GuardrailInput{Content: "x"}); err == nil {
+		t.Error("classifier failure should be returned")
+	}
+}
+
+func TestNewFilterErrors(t *testing.T) {
+	cases := map[string]*types.SafetyPolicy{
+		"pattern":    {Rules: []types.SafetyRule{{Name: "bad", Pattern: "("}}},
+		"action":     {Rules: []types.SafetyRule{{Name: "bad", Pattern: "x", Action: "warn"}}},
+		"classifier": {Classifiers: []string{"missing"}},
+	}
+	for name, policy := range cases {
+		if _, err := NewFilter(policy, nil); err == nil || !strings.Contains(err.Error(), "safety") {
+			t.Errorf("%s: err = %v", name, err)
+		}
+	}
+}
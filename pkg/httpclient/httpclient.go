@@ -0,0 +1,278 @@
+// Package httpclient 提供共享的出站 HTTP 配置
+// 模型 Provider、WebFetch、MCP 客户端和 Webhook 投递都通过这里创建 HTTP 客户端，
+// 因此代理、自定义 CA、TLS 最低版本和连接池参数只需在配置文件中设置一次
+package httpclient
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"maps"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/net/http/httpproxy"
+)
+
+// 连接池默认值
+const (
+	DefaultMaxIdleConns        = 100
+	DefaultMaxIdleConnsPerHost = 10
+	DefaultIdleConnTimeout     = 90 * time.Second
+)
+
+// TLS 最低版本可选值
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// Config 出站 HTTP 配置，零值表示使用环境变量代理、系统根证书和默认连接池
+type Config struct {
+	// Proxy 代理地址，如 http://proxy.corp:3128；为空时使用 HTTPS_PROXY、HTTP_PROXY 环境变量
+	Proxy string `yaml:"proxy,omitempty" json:"proxy,omitempty"`
+	// NoProxy 不走代理的主机，逗号分隔，语法同 NO_PROXY；仅在设置 Proxy 时生效
+	NoProxy string `yaml:"no_proxy,omitempty" json:"no_proxy,omitempty"`
+	// CABundle PEM 格式的 CA 证书文件，追加到系统根证书之后
+	CABundle string `yaml:"ca_bundle,omitempty" json:"ca_bundle,omitempty"`
+	// TLSMinVersion TLS 最低版本: 1.0、1.1、1.2、1.3，为空时使用 Go 默认值（1.2）
+	TLSMinVersion string `yaml:"tls_min_version,omitempty" json:"tls_min_version,omitempty"`
+	// MaxIdleConns 空闲连接总数上限，0 使用默认值
+	MaxIdleConns int `yaml:"max_idle_conns,omitempty" json:"max_idle_conns,omitempty"`
+	// MaxIdleConnsPerHost 每个主机的空闲连接上限，0 使用默认值
+	MaxIdleConnsPerHost int `yaml:"max_idle_conns_per_host,omitempty" json:"max_idle_conns_per_host,omitempty"`
+	// MaxConnsPerHost 每个主机的连接数上限，0 表示不限
+	MaxConnsPerHost int `yaml:"max_conns_per_host,omitempty" json:"max_conns_per_host,omitempty"`
+	// IdleConnTimeout 空闲连接保留时长，如 "90s"，为空使用默认值
+	IdleConnTimeout string `yaml:"idle_conn_timeout,omitempty" json:"idle_conn_timeout,omitempty"`
+}
+
+// Validate 校验配置，不读取 CA 文件
+func (c *Config) Validate() error {
+	if c.Proxy != "" {
+		if _, err := parseProxy(c.Proxy); err != nil {
+			return err
+		}
+	}
+	if c.TLSMinVersion != "" {
+		if _, ok := tlsVersions[c.TLSMinVersion]; !ok {
+			return fmt.Errorf("tls_min_version must be one of 1.0, 1.1, 1.2, 1.3, got %q", c.TLSMinVersion)
+		}
+	}
+	if c.MaxIdleConns < 0 || c.MaxIdleConnsPerHost < 0 || c.MaxConnsPerHost < 0 {
+		return errors.New("connection limits must not be negative")
+	}
+	if c.IdleConnTimeout != "" {
+		if d, err := time.ParseDuration(c.IdleConnTimeout); err != nil || d < 0 {
+			return fmt.Errorf("idle_conn_timeout: expected duration such as 90s, got %q", c.IdleConnTimeout)
+		}
+	}
+	return nil
+}
+
+// parseProxy 解析代理地址，缺少协议时按 http 处理
+func parseProxy(raw string) (*url.URL, error) {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		u, err = url.Parse("http://" + raw)
+	}
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid proxy %q", raw)
+	}
+	return u, nil
+}
+
+// NewTransport 按配置创建 Transport
+func (c *Config) NewTransport() (*http.Transport, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+
+	t := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		ForceAttemptHTTP2:     true,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+		MaxIdleConns:          DefaultMaxIdleConns,
+		MaxIdleConnsPerHost:   DefaultMaxIdleConnsPerHost,
+		MaxConnsPerHost:       c.MaxConnsPerHost,
+		IdleConnTimeout:       DefaultIdleConnTimeout,
+	}
+	if c.MaxIdleConns > 0 {
+		t.MaxIdleConns = c.MaxIdleConns
+	}
+	if c.MaxIdleConnsPerHost > 0 {
+		t.MaxIdleConnsPerHost = c.MaxIdleConnsPerHost
+	}
+	if c.IdleConnTimeout != "" {
+		t.IdleConnTimeout, _ = time.ParseDuration(c.IdleConnTimeout)
+	}
+
+	if c.Proxy != "" {
+		proxy := (&httpproxy.Config{
+			HTTPProxy:  c.Proxy,
+			HTTPSProxy: c.Proxy,
+			NoProxy:    c.NoProxy,
+		}).ProxyFunc()
+		t.Proxy = func(req *http.Request) (*url.URL, error) {
+			return proxy(req.URL)
+		}
+	}
+
+	if c.CABundle != "" || c.TLSMinVersion != "" {
+		tlsConfig := &tls.Config{MinVersion: tlsVersions[c.TLSMinVersion]}
+		if c.CABundle != "" {
+			pool, err := loadCABundle(c.CABundle)
+			if err != nil {
+				return nil, err
+			}
+			tlsConfig.RootCAs = pool
+		}
+		t.TLSClientConfig = tlsConfig
+	}
+	return t, nil
+}
+
+// loadCABundle 返回追加了 path 中证书的系统根证书池
+func loadCABundle(path string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read ca_bundle: %w", err)
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil || pool == nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("ca_bundle %s: no PEM certificates found", path)
+	}
+	return pool, nil
+}
+
+var (
+	mu     sync.RWMutex
+	shared = mustDefaultTransport()
+)
+
+func mustDefaultTransport() *http.Transport {
+	t, err := (&Config{}).NewTransport()
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// Configure 用 cfg 替换共享 Transport，之后的请求（包括已创建的客户端）都使用新配置
+// cfg 为 nil 时恢复默认配置
+func Configure(cfg *Config) error {
+	if cfg == nil {
+		cfg = &Config{}
+	}
+	t, err := cfg.NewTransport()
+	if err != nil {
+		return err
+	}
+	mu.Lock()
+	old := shared
+	shared = t
+	mu.Unlock()
+	old.CloseIdleConnections()
+	return nil
+}
+
+// Transport 返回当前共享的 Transport
+func Transport() *http.Transport {
+	mu.RLock()
+	defer mu.RUnlock()
+	return shared
+}
+
+// CloneTransport 返回共享 Transport 的副本，用于需要额外设置（如响应头超时）的调用方
+// 副本不共享连接池，也不跟随之后的 Configure
+func CloneTransport() *http.Transport {
+	return Transport().Clone()
+}
+
+// New 返回使用共享 Transport 的客户端，timeout 为 0 表示不限
+func New(timeout time.Duration) *http.Client {
+	return &http.Client{Timeout: timeout, Transport: RoundTripper()}
+}
+
+// RoundTripper 返回在每次请求时使用当前共享 Transport 的 RoundTripper
+// 用于替换第三方客户端（如 OIDC、SDK）的 Transport
+func RoundTripper() http.RoundTripper {
+	return sharedRoundTripper{}
+}
+
+// sharedRoundTripper 每次请求时读取当前共享 Transport，使 Configure 对已创建的客户端生效
+type sharedRoundTripper struct{}
+
+func (sharedRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	return Transport().RoundTrip(withContextHeaders(req))
+}
+
+// CloseIdleConnections 让 http.Client.CloseIdleConnections 作用于共享 Transport
+func (sharedRoundTripper) CloseIdleConnections() {
+	Transport().CloseIdleConnections()
+}
+
+type headersKey struct{}
+
+// WithHeaders 返回附带额外请求头的 ctx，经由本包客户端发出的请求会带上这些请求头
+// 多次调用时合并，后设置的同名请求头覆盖先前的值
+func WithHeaders(ctx context.Context, headers map[string]string) context.Context {
+	if len(headers) == 0 {
+		return ctx
+	}
+	merged := maps.Clone(HeadersFromContext(ctx))
+	if merged == nil {
+		merged = make(map[string]string, len(headers))
+	}
+	maps.Copy(merged, headers)
+	return context.WithValue(ctx, headersKey{}, merged)
+}
+
+// HeadersFromContext 返回 WithHeaders 设置的请求头
+func HeadersFromContext(ctx context.Context) map[string]string {
+	headers, _ := ctx.Value(headersKey{}).(map[string]string)
+	return headers
+}
+
+// ContextHeaders 包装 rt，使其发出的请求带上 WithHeaders 设置的请求头
+// 用于 CloneTransport 得到的独立 Transport
+func ContextHeaders(rt http.RoundTripper) http.RoundTripper {
+	return contextHeadersRoundTripper{rt}
+}
+
+type contextHeadersRoundTripper struct {
+	next http.RoundTripper
+}
+
+func (t contextHeadersRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	return t.next.RoundTrip(withContextHeaders(req))
+}
+
+// withContextHeaders 返回带上 ctx 请求头的请求副本，没有额外请求头时原样返回
+func withContextHeaders(req *http.Request) *http.Request {
+	headers := HeadersFromContext(req.Context())
+	if len(headers) == 0 {
+		return req
+	}
+	req = req.Clone(req.Context())
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	return req
+}
@@ -0,0 +1,108 @@
+package httpclient
+
+import (
+	"crypto/tls"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConfigValidate(t *testing.T) {
+	cases := []struct {
+		name string
+		cfg  Config
+		ok   bool
+	}{
+		{"zero", Config{}, true},
+		{"proxy without scheme", Config{Proxy: "proxy.corp:3128"}, true},
+		{"tls 1.3", Config{TLSMinVersion: "1.3"}, true},
+		{"bad tls", Config{TLSMinVersion: "2.0"}, false},
+		{"negative limit", Config{MaxConnsPerHost: -1}, false},
+		{"bad idle timeout", Config{IdleConnTimeout: "soon"}, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.cfg.Validate()
+			if tc.ok && err != nil {
+				t.Errorf("expected valid, got %v", err)
+			}
+			if !tc.ok && err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestConfigureProxy(t *testing.T) {
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.String()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer proxy.Close()
+
+	// 在 Configure 之前创建的客户端同样使用新配置
+	client := New(0)
+	if err := Configure(&Config{Proxy: proxy.URL, NoProxy: "internal.example"}); err != nil {
+		t.Fatalf("Configure failed: %v", err)
+	}
+	t.Cleanup(func() { _ = Configure(nil) })
+
+	resp, err := client.Get("http://api.example.com/v1/models")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+	if proxied != "http://api.example.com/v1/models" {
+		t.Errorf("expected request to go through proxy, got %q", proxied)
+	}
+
+	proxyURL, err := Transport().Proxy(httptest.NewRequest(http.MethodGet, "http://internal.example/", nil))
+	if err != nil || proxyURL != nil {
+		t.Errorf("expected no_proxy host to bypass proxy, got %v, %v", proxyURL, err)
+	}
+}
+
+func TestConfigureCABundle(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	// 未信任测试证书时握手失败
+	if _, err := New(0).Get(server.URL); err == nil {
+		t.Fatal("expected unknown authority error without ca_bundle")
+	}
+
+	bundle := filepath.Join(t.TempDir(), "ca.pem")
+	cert := server.Certificate()
+	if err := os.WriteFile(bundle, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}), 0600); err != nil {
+		t.Fatalf("write bundle: %v", err)
+	}
+	if err := Configure(&Config{CABundle: bundle, TLSMinVersion: "1.2"}); err != nil {
+		t.Fatalf("Configure failed: %v", err)
+	}
+	t.Cleanup(func() { _ = Configure(nil) })
+
+	resp, err := New(0).Get(server.URL)
+	if err != nil {
+		t.Fatalf("expected custom CA to be trusted: %v", err)
+	}
+	resp.Body.Close()
+	if Transport().TLSClientConfig.MinVersion != tls.VersionTLS12 {
+		t.Errorf("expected TLS 1.2 minimum, got %x", Transport().TLSClientConfig.MinVersion)
+	}
+}
+
+func TestConfigureRejectsMissingCABundle(t *testing.T) {
+	before := Transport()
+	if err := Configure(&Config{CABundle: filepath.Join(t.TempDir(), "missing.pem")}); err == nil {
+		t.Fatal("expected error for missing ca_bundle")
+	}
+	if Transport() != before {
+		t.Error("expected failed Configure to keep the previous transport")
+	}
+}
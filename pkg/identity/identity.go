@@ -0,0 +1,181 @@
+// Package identity 为每个 Agent 分配签名密钥对，对 Agent 产生的提交、Webhook、产出物和导出报告签名
+// 签名记录签名者、内容摘要和时间，任何持有公钥的一方都可以验证内容的来源和完整性
+package identity
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// AlgorithmEd25519 目前唯一支持的签名算法
+const AlgorithmEd25519 = "ed25519"
+
+// HeaderSignature Webhook 和导出报告携带签名的 HTTP 头
+const HeaderSignature = "X-Aster-Signature"
+
+// GitNotesRef 提交签名保存的 git notes 引用，推送时需要单独推送：git push origin refs/notes/aster-signatures
+const GitNotesRef = "refs/notes/aster-signatures"
+
+// ServerID 服务端自身的身份，用于签名导出报告等不属于某个 Agent 的内容
+const ServerID = "aster-server"
+
+var (
+	// ErrNotFound 身份不存在
+	ErrNotFound = errors.New("identity not found")
+	// ErrInvalidSignature 签名与内容或公钥不匹配
+	ErrInvalidSignature = errors.New("invalid signature")
+)
+
+// Kind 被签名内容的类型，参与签名，防止一种内容的签名被挪用到另一种内容
+type Kind string
+
+const (
+	KindCommit   Kind = "commit"
+	KindWebhook  Kind = "webhook"
+	KindArtifact Kind = "artifact"
+	KindReport   Kind = "report"
+)
+
+// Identity Agent 的公开身份
+type Identity struct {
+	// ID Agent ID，服务端身份为 ServerID
+	ID        string `json:"id"`
+	KeyID     string `json:"key_id"`
+	Algorithm string `json:"algorithm"`
+	// PublicKey 公钥原始字节，JSON 中为 base64
+	PublicKey []byte    `json:"public_key"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Signature 一次签名的结果
+type Signature struct {
+	Signer    string `json:"signer"`
+	KeyID     string `json:"key_id"`
+	Algorithm string `json:"algorithm"`
+	Kind      Kind   `json:"kind"`
+	// Digest 内容的 SHA-256（十六进制）
+	Digest   string    `json:"digest"`
+	SignedAt time.Time `json:"signed_at"`
+	// Value 签名值（base64）
+	Value string `json:"value"`
+}
+
+// Digest 计算内容摘要
+func Digest(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// keyID 公钥指纹，区分同一 Agent 轮换前后的密钥
+func keyID(pub ed25519.PublicKey) string {
+	sum := sha256.Sum256(pub)
+	return hex.EncodeToString(sum[:8])
+}
+
+// message 实际被签名的字节：签名的所有字段（除签名值外）按固定顺序拼接
+func (s *Signature) message() []byte {
+	return []byte(strings.Join([]string{
+		"aster-signature-v1",
+		s.Algorithm,
+		string(s.Kind),
+		s.Signer,
+		s.KeyID,
+		s.Digest,
+		s.SignedAt.UTC().Format(time.RFC3339Nano),
+	}, "\n"))
+}
+
+// Signer 持有私钥，对内容签名
+type Signer struct {
+	identity Identity
+	key      ed25519.PrivateKey
+	now      func() time.Time
+}
+
+// Identity 返回签名者的公开身份
+func (s *Signer) Identity() Identity {
+	return s.identity
+}
+
+// Sign 对内容签名
+func (s *Signer) Sign(kind Kind, content []byte) *Signature {
+	return s.SignDigest(kind, Digest(content))
+}
+
+// SignDigest 对已计算的 SHA-256 摘要签名，用于内容已经流式写出的场景（如产出物）
+func (s *Signer) SignDigest(kind Kind, digest string) *Signature {
+	sig := &Signature{
+		Signer:    s.identity.ID,
+		KeyID:     s.identity.KeyID,
+		Algorithm: AlgorithmEd25519,
+		Kind:      kind,
+		Digest:    digest,
+		SignedAt:  s.now().UTC(),
+	}
+	sig.Value = base64.StdEncoding.EncodeToString(ed25519.Sign(s.key, sig.message()))
+	return sig
+}
+
+// Verify 用 id 的公钥验证签名；content 不为 nil 时同时校验内容摘要
+func Verify(id *Identity, sig *Signature, content []byte) error {
+	if sig == nil {
+		return fmt.Errorf("%w: missing signature", ErrInvalidSignature)
+	}
+	if content != nil && Digest(content) != sig.Digest {
+		return fmt.Errorf("%w: content digest mismatch", ErrInvalidSignature)
+	}
+	return VerifyDigest(id, sig)
+}
+
+// VerifyDigest 只验证签名本身，摘要由调用方自行与内容比对
+func VerifyDigest(id *Identity, sig *Signature) error {
+	if sig == nil {
+		return fmt.Errorf("%w: missing signature", ErrInvalidSignature)
+	}
+	if id == nil || id.ID != sig.Signer {
+		return fmt.Errorf("%w: signer mismatch", ErrInvalidSignature)
+	}
+	if sig.Algorithm != AlgorithmEd25519 || id.Algorithm != AlgorithmEd25519 {
+		return fmt.Errorf("%w: unsupported algorithm %q", ErrInvalidSignature, sig.Algorithm)
+	}
+	if sig.KeyID != id.KeyID {
+		return fmt.Errorf("%w: signed with key %s, current key is %s", ErrInvalidSignature, sig.KeyID, id.KeyID)
+	}
+	if len(id.PublicKey) != ed25519.PublicKeySize {
+		return fmt.Errorf("%w: malformed public key", ErrInvalidSignature)
+	}
+	value, err := base64.StdEncoding.DecodeString(sig.Value)
+	if err != nil {
+		return fmt.Errorf("%w: malformed signature value", ErrInvalidSignature)
+	}
+	if !ed25519.Verify(ed25519.PublicKey(id.PublicKey), sig.message(), value) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// EncodeHeader 把签名编码为 HTTP 头的值（base64url 编码的 JSON）
+func (s *Signature) EncodeHeader() string {
+	data, _ := json.Marshal(s)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// ParseHeader 解析 EncodeHeader 生成的头部值
+func ParseHeader(value string) (*Signature, error) {
+	data, err := base64.RawURLEncoding.DecodeString(strings.TrimSpace(value))
+	if err != nil {
+		return nil, fmt.Errorf("decode signature header: %w", err)
+	}
+	var sig Signature
+	if err := json.Unmarshal(data, &sig); err != nil {
+		return nil, fmt.Errorf("decode signature header: %w", err)
+	}
+	return &sig, nil
+}
@@ -0,0 +1,104 @@
+package identity
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/astercloud/aster/pkg/store"
+)
+
+func newTestRegistry(t *testing.T) *Registry {
+	t.Helper()
+	st, err := store.NewJSONStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewRegistry(st)
+}
+
+func TestSignAndVerify(t *testing.T) {
+	ctx := context.Background()
+	reg := newTestRegistry(t)
+
+	signer, err := reg.Signer(ctx, "agt-1")
+	if err != nil {
+		t.Fatalf("signer: %v", err)
+	}
+	content := []byte(`{"event":"deployed"}`)
+	sig := signer.Sign(KindWebhook, content)
+
+	id, err := reg.Verify(ctx, sig, content)
+	if err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	if id.ID != "agt-1" || id.KeyID != sig.KeyID {
+		t.Errorf("identity = %+v", id)
+	}
+	// 不带内容时只验证签名本身
+	if _, err := reg.Verify(ctx, sig, nil); err != nil {
+		t.Fatalf("verify without content: %v", err)
+	}
+
+	if _, err := reg.Verify(ctx, sig, []byte("tampered")); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("tampered content err = %v, want ErrInvalidSignature", err)
+	}
+	// 签名类型参与签名，不能挪用到其他类型的内容
+	moved := *sig
+	moved.Kind = KindReport
+	if _, err := reg.Verify(ctx, &moved, content); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("changed kind err = %v, want ErrInvalidSignature", err)
+	}
+	// 其他 Agent 的密钥不能冒充
+	other, _ := reg.Signer(ctx, "agt-2")
+	forged := other.Sign(KindWebhook, content)
+	forged.Signer = "agt-1"
+	if _, err := reg.Verify(ctx, forged, content); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("forged signer err = %v, want ErrInvalidSignature", err)
+	}
+
+	unknown := *sig
+	unknown.Signer = "agt-missing"
+	if _, err := reg.Verify(ctx, &unknown, content); !errors.Is(err, ErrNotFound) {
+		t.Errorf("unknown signer err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestRegistryPersistsKeys(t *testing.T) {
+	ctx := context.Background()
+	st, err := store.NewJSONStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, _ := NewRegistry(st).Signer(ctx, "agt-1")
+	sig := first.Sign(KindArtifact, []byte("report"))
+
+	// 另一个实例（新的注册表）使用同一密钥
+	second, err := NewRegistry(st).Signer(ctx, "agt-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.Identity().KeyID != first.Identity().KeyID {
+		t.Fatalf("key id = %s, want %s", second.Identity().KeyID, first.Identity().KeyID)
+	}
+	if _, err := NewRegistry(st).Verify(ctx, sig, []byte("report")); err != nil {
+		t.Fatalf("verify on another registry: %v", err)
+	}
+}
+
+func TestSignatureHeader(t *testing.T) {
+	signer, _ := newTestRegistry(t).Signer(context.Background(), "agt-1")
+	sig := signer.Sign(KindReport, []byte("month,cost\n"))
+
+	parsed, err := ParseHeader(sig.EncodeHeader())
+	if err != nil {
+		t.Fatalf("parse header: %v", err)
+	}
+	id := signer.Identity()
+	if err := Verify(&id, parsed, []byte("month,cost\n")); err != nil {
+		t.Fatalf("verify parsed header: %v", err)
+	}
+	if _, err := ParseHeader("not a signature"); err == nil {
+		t.Error("expected error for malformed header")
+	}
+}
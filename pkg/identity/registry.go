@@ -0,0 +1,121 @@
+package identity
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/astercloud/aster/pkg/store"
+)
+
+// Collection 身份在 store.Store 中的集合名
+const Collection = "agent_identities"
+
+// record 持久化的身份，包含私钥
+type record struct {
+	Identity
+	PrivateKey []byte `json:"private_key"`
+}
+
+// Registry 管理 Agent 的密钥对
+// 私钥与公开身份一起保存在 store.Store 中，store 需要按敏感数据的要求保护（访问控制、静态加密）。
+// store 不应按租户包装，同一 Agent 在所有实例上使用同一密钥
+type Registry struct {
+	st  store.Store
+	now func() time.Time
+
+	mu      sync.Mutex
+	signers map[string]*Signer
+}
+
+// NewRegistry 创建身份注册表
+func NewRegistry(st store.Store) *Registry {
+	return &Registry{st: st, now: time.Now, signers: make(map[string]*Signer)}
+}
+
+// Signer 返回 Agent 的签名者，首次使用时生成并保存密钥对
+func (r *Registry) Signer(ctx context.Context, id string) (*Signer, error) {
+	if id == "" {
+		return nil, errors.New("identity id is required")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if s, ok := r.signers[id]; ok {
+		return s, nil
+	}
+	rec, err := r.load(ctx, id)
+	if errors.Is(err, ErrNotFound) {
+		rec, err = r.create(ctx, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(rec.PrivateKey) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("identity %s: malformed private key", id)
+	}
+	s := &Signer{identity: rec.Identity, key: ed25519.PrivateKey(rec.PrivateKey), now: r.now}
+	r.signers[id] = s
+	return s, nil
+}
+
+// Get 返回 Agent 的公开身份
+func (r *Registry) Get(ctx context.Context, id string) (*Identity, error) {
+	rec, err := r.load(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return &rec.Identity, nil
+}
+
+// Verify 按签名中的签名者查找公钥并验证；content 为 nil 时只验证签名本身
+func (r *Registry) Verify(ctx context.Context, sig *Signature, content []byte) (*Identity, error) {
+	if sig == nil {
+		return nil, fmt.Errorf("%w: missing signature", ErrInvalidSignature)
+	}
+	id, err := r.Get(ctx, sig.Signer)
+	if err != nil {
+		return nil, err
+	}
+	return id, Verify(id, sig, content)
+}
+
+func (r *Registry) load(ctx context.Context, id string) (*record, error) {
+	var rec record
+	if err := r.st.Get(ctx, Collection, id, &rec); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("load identity %s: %w", id, err)
+	}
+	return &rec, nil
+}
+
+func (r *Registry) create(ctx context.Context, id string) (*record, error) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("generate key: %w", err)
+	}
+	rec := &record{
+		Identity: Identity{
+			ID:        id,
+			KeyID:     keyID(pub),
+			Algorithm: AlgorithmEd25519,
+			PublicKey: pub,
+			CreatedAt: r.now().UTC(),
+		},
+		PrivateKey: priv,
+	}
+	if err := r.st.Set(ctx, Collection, id, rec); err != nil {
+		return nil, fmt.Errorf("save identity %s: %w", id, err)
+	}
+	// 多个实例同时创建时以最后写入的为准，重新读取保证各实例使用同一密钥
+	if stored, err := r.load(ctx, id); err == nil {
+		return stored, nil
+	}
+	return rec, nil
+}
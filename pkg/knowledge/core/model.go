@@ -6,6 +6,7 @@ type IngestRequest struct {
 	Text      string         // 原文文本
 	Namespace string         // 命名空间，可为空使用默认
 	Metadata  map[string]any // 自定义元数据
+	Chunks    []Chunk        // 可选：预先切分的文本块（仅使用 Text/Metadata），设置后不再按段落切分 Text
 }
 
 // Chunk 表示切分后的最小文本单元。
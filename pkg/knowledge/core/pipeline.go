@@ -8,15 +8,23 @@ import (
 	"strings"
 	"time"
 
+	"github.com/astercloud/aster/pkg/logging"
 	"github.com/astercloud/aster/pkg/vector"
 )
 
+var coreLog = logging.ForComponent("KnowledgeCore")
+
 // PipelineConfig 轻量 RAG 管线配置。
 type PipelineConfig struct {
 	Store       vector.VectorStore
 	Embedder    vector.Embedder
 	Namespace   string
 	DefaultTopK int
+
+	// Reranker 可选的重排序阶段：先向量检索 RerankCandidates 个候选，再按相关性重排取 topK
+	Reranker vector.Reranker
+	// RerankCandidates 交给 Reranker 的候选数量，默认 topK 的 4 倍（至少 20）
+	RerankCandidates int
 }
 
 // Pipeline 提供最小 ingest/search 能力，不依赖高级特性。
@@ -25,6 +33,9 @@ type Pipeline struct {
 	embedder  vector.Embedder
 	namespace string
 	defaultK  int
+
+	reranker   vector.Reranker
+	candidates int
 }
 
 // NewPipeline 创建管线实例。
@@ -43,16 +54,18 @@ func NewPipeline(cfg PipelineConfig) (*Pipeline, error) {
 		cfg.DefaultTopK = 5
 	}
 	return &Pipeline{
-		store:     cfg.Store,
-		embedder:  cfg.Embedder,
-		namespace: ns,
-		defaultK:  cfg.DefaultTopK,
+		store:      cfg.Store,
+		embedder:   cfg.Embedder,
+		namespace:  ns,
+		defaultK:   cfg.DefaultTopK,
+		reranker:   cfg.Reranker,
+		candidates: cfg.RerankCandidates,
 	}, nil
 }
 
 // Ingest 将文本切分并写入向量库。
 func (p *Pipeline) Ingest(ctx context.Context, req IngestRequest) ([]Chunk, error) {
-	if strings.TrimSpace(req.Text) == "" {
+	if strings.TrimSpace(req.Text) == "" && len(req.Chunks) == 0 {
 		return nil, errors.New("knowledge core: text is empty")
 	}
 	id := strings.TrimSpace(req.ID)
@@ -70,7 +83,19 @@ func (p *Pipeline) Ingest(ctx context.Context, req IngestRequest) ([]Chunk, erro
 		meta["namespace"] = ns
 	}
 
-	rawChunks := splitParagraphs(req.Text)
+	var rawChunks []string
+	var chunkMetas []map[string]any
+	if len(req.Chunks) > 0 {
+		for _, c := range req.Chunks {
+			if strings.TrimSpace(c.Text) == "" {
+				continue
+			}
+			rawChunks = append(rawChunks, c.Text)
+			chunkMetas = append(chunkMetas, c.Metadata)
+		}
+	} else {
+		rawChunks = splitParagraphs(req.Text)
+	}
 	if len(rawChunks) == 0 {
 		return nil, errors.New("knowledge core: no chunks after split")
 	}
@@ -89,6 +114,9 @@ func (p *Pipeline) Ingest(ctx context.Context, req IngestRequest) ([]Chunk, erro
 		chunkID := fmt.Sprintf("%s#%d", id, i)
 		chunkMeta := make(map[string]any, len(meta)+2)
 		maps.Copy(chunkMeta, meta)
+		if chunkMetas != nil {
+			maps.Copy(chunkMeta, chunkMetas[i])
+		}
 		chunkMeta["text"] = ctext
 		chunkMeta["chunk_index"] = i
 
@@ -114,6 +142,17 @@ func (p *Pipeline) Ingest(ctx context.Context, req IngestRequest) ([]Chunk, erro
 	return chunks, nil
 }
 
+// Delete 删除指定 ID 的文本块，用于重新索引时清理旧数据。
+func (p *Pipeline) Delete(ctx context.Context, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	if err := p.store.Delete(ctx, ids); err != nil {
+		return fmt.Errorf("delete vector docs: %w", err)
+	}
+	return nil
+}
+
 // Search 执行向量检索。
 func (p *Pipeline) Search(ctx context.Context, query string, topK int, metadata map[string]any) ([]SearchHit, error) {
 	if strings.TrimSpace(query) == "" {
@@ -138,9 +177,13 @@ func (p *Pipeline) Search(ctx context.Context, query string, topK int, metadata
 		return nil, errors.New("embedder returned empty vectors")
 	}
 
+	fetchK := topK
+	if p.reranker != nil {
+		fetchK = p.rerankCandidates(topK)
+	}
 	hits, err := p.store.Query(ctx, vector.Query{
 		Vector:    vecs[0],
-		TopK:      topK,
+		TopK:      fetchK,
 		Namespace: ns,
 		Filter:    metadata,
 	})
@@ -163,9 +206,48 @@ func (p *Pipeline) Search(ctx context.Context, query string, topK int, metadata
 			Metadata: h.Metadata,
 		})
 	}
+	if p.reranker != nil && len(out) > 0 {
+		return p.rerank(ctx, query, out, topK), nil
+	}
 	return out, nil
 }
 
+func (p *Pipeline) rerankCandidates(topK int) int {
+	if p.candidates > 0 {
+		return max(p.candidates, topK)
+	}
+	return max(topK*4, 20)
+}
+
+// rerank 按 Reranker 的分数重排候选；重排失败时退回向量检索的顺序，检索本身不失败。
+// 命中的 Score 替换为重排分数，原向量相似度保存在 Metadata["vector_score"]。
+func (p *Pipeline) rerank(ctx context.Context, query string, hits []SearchHit, topK int) []SearchHit {
+	docs := make([]string, len(hits))
+	for i, h := range hits {
+		docs[i] = h.Text
+	}
+	ranked, err := p.reranker.Rerank(ctx, query, docs, topK)
+	if err != nil {
+		coreLog.Warn(ctx, "rerank failed, falling back to vector order", map[string]any{"error": err.Error()})
+		if len(hits) > topK {
+			hits = hits[:topK]
+		}
+		return hits
+	}
+
+	out := make([]SearchHit, 0, len(ranked))
+	for _, r := range ranked {
+		h := hits[r.Index]
+		meta := make(map[string]any, len(h.Metadata)+1)
+		maps.Copy(meta, h.Metadata)
+		meta["vector_score"] = h.Score
+		h.Metadata = meta
+		h.Score = r.Score
+		out = append(out, h)
+	}
+	return out
+}
+
 // splitParagraphs 进行简单段落切分。
 func splitParagraphs(text string) []string {
 	segs := strings.Split(text, "\n\n")
@@ -2,6 +2,8 @@ package core
 
 import (
 	"context"
+	"errors"
+	"strings"
 	"testing"
 
 	"github.com/astercloud/aster/pkg/vector"
@@ -57,3 +59,55 @@ func TestPipeline_IngestEmpty(t *testing.T) {
 		t.Fatalf("expected error for empty text")
 	}
 }
+
+type keywordReranker struct {
+	err error
+}
+
+func (r *keywordReranker) Rerank(_ context.Context, query string, docs []string, topN int) ([]vector.RerankResult, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	var out []vector.RerankResult
+	for i, d := range docs {
+		if strings.Contains(d, query) {
+			out = append(out, vector.RerankResult{Index: i, Score: 0.9})
+		}
+	}
+	return out, nil
+}
+
+func TestPipeline_SearchRerank(t *testing.T) {
+	ctx := context.Background()
+	store := vector.NewMemoryStore()
+	reranker := &keywordReranker{}
+	pipe, _ := NewPipeline(PipelineConfig{
+		Store:    store,
+		Embedder: vector.NewMockEmbedder(16),
+		Reranker: reranker,
+	})
+	_, err := pipe.Ingest(ctx, IngestRequest{
+		ID:   "doc1",
+		Text: "alpha one\n\nbeta two\n\ngamma three\n\nworkflows live here",
+	})
+	if err != nil {
+		t.Fatalf("ingest: %v", err)
+	}
+
+	hits, err := pipe.Search(ctx, "workflows", 1, nil)
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	if len(hits) != 1 || hits[0].ID != "doc1#3" || hits[0].Score != 0.9 {
+		t.Fatalf("unexpected reranked hits: %+v", hits)
+	}
+	if _, ok := hits[0].Metadata["vector_score"]; !ok {
+		t.Errorf("vector score should be kept in metadata")
+	}
+
+	reranker.err = errors.New("unavailable")
+	hits, err = pipe.Search(ctx, "workflows", 2, nil)
+	if err != nil || len(hits) != 2 {
+		t.Fatalf("rerank failure should fall back to vector order: hits=%d err=%v", len(hits), err)
+	}
+}
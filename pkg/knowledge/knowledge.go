@@ -175,6 +175,10 @@ type ManagerConfig struct {
 	// 轻量核心管线
 	UseCorePipeline bool `json:"use_core_pipeline"` // 启用轻量 ingest/search 管线
 
+	// 可选重排序（仅作用于核心管线的向量检索）
+	Reranker         vector.Reranker `json:"-"`                 // 重排序器，nil 表示不重排
+	RerankCandidates int             `json:"rerank_candidates"` // 交给重排序器的候选数量
+
 	// 可选策略注入
 	PIIStrategy   PIIStrategy   `json:"-"`
 	AuditStrategy AuditStrategy `json:"-"`
@@ -0,0 +1,265 @@
+package loader
+
+import (
+	"maps"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// codeLanguages 源代码扩展名到语言的映射
+var codeLanguages = map[string]string{
+	".go":    "go",
+	".py":    "python",
+	".js":    "javascript",
+	".jsx":   "javascript",
+	".mjs":   "javascript",
+	".ts":    "typescript",
+	".tsx":   "typescript",
+	".java":  "java",
+	".kt":    "kotlin",
+	".rs":    "rust",
+	".rb":    "ruby",
+	".php":   "php",
+	".c":     "c",
+	".h":     "c",
+	".cc":    "cpp",
+	".cpp":   "cpp",
+	".hpp":   "cpp",
+	".cs":    "csharp",
+	".swift": "swift",
+	".scala": "scala",
+	".sh":    "shell",
+	".bash":  "shell",
+	".sql":   "sql",
+	".proto": "protobuf",
+	".yaml":  "yaml",
+	".yml":   "yaml",
+	".toml":  "toml",
+	".json":  "json",
+}
+
+// declPatterns 各语言顶层声明的起始行，作为代码切分边界
+var declPatterns = map[string]*regexp.Regexp{
+	"go":         regexp.MustCompile(`^(func|type|var|const)\b`),
+	"python":     regexp.MustCompile(`^(async\s+def|def|class)\s|^@`),
+	"javascript": regexp.MustCompile(`^(export\s+)?(default\s+)?(async\s+)?(function|class|const|let|var)\b`),
+	"typescript": regexp.MustCompile(`^(export\s+)?(default\s+)?(declare\s+)?(abstract\s+)?(async\s+)?(function|class|interface|type|enum|const|let|var|namespace)\b`),
+	"java":       regexp.MustCompile(`^\s{0,4}(public|private|protected|static|final|abstract|class|interface|enum|record|@)`),
+	"kotlin":     regexp.MustCompile(`^(public\s+|private\s+|internal\s+)?(data\s+|sealed\s+|abstract\s+|open\s+)?(fun|class|object|interface|val|var)\b`),
+	"rust":       regexp.MustCompile(`^(pub(\([a-z]+\))?\s+)?(async\s+)?(fn|struct|enum|trait|impl|mod|type|const|static)\b|^#\[`),
+	"ruby":       regexp.MustCompile(`^\s{0,2}(def|class|module)\s`),
+	"php":        regexp.MustCompile(`^\s{0,4}((public|private|protected|static|abstract|final)\s+)*(function|class|interface|trait)\b`),
+	"c":          regexp.MustCompile(`^[A-Za-z_][\w\s\*]*\([^;]*$|^(struct|typedef|enum|union)\b|^#define`),
+	"cpp":        regexp.MustCompile(`^[A-Za-z_][\w\s\*:<>,&]*\([^;]*$|^(class|struct|namespace|template|typedef|enum)\b`),
+	"csharp":     regexp.MustCompile(`^\s{0,8}(public|private|protected|internal|static|class|interface|enum|record|namespace|\[)`),
+	"swift":      regexp.MustCompile(`^\s{0,4}(public\s+|private\s+|internal\s+|open\s+)?(func|class|struct|enum|protocol|extension)\b`),
+	"scala":      regexp.MustCompile(`^\s{0,2}(def|class|object|trait|case\s+class)\b`),
+	"shell":      regexp.MustCompile(`^(function\s+)?[A-Za-z_][\w-]*\s*\(\)\s*\{?`),
+	"sql":        regexp.MustCompile(`(?i)^(create|alter|drop|insert|update|delete|select|with)\b`),
+	"protobuf":   regexp.MustCompile(`^(message|service|enum)\b`),
+	"yaml":       regexp.MustCompile(`^[A-Za-z_][\w-]*:`),
+	"toml":       regexp.MustCompile(`^\[`),
+}
+
+var headingPattern = regexp.MustCompile(`^(#{1,6})\s+(.+?)\s*#*\s*$`)
+
+// chunkParagraphs 按空行切分段落，并把相邻段落合并到不超过 size 的文本块中
+func chunkParagraphs(text string, size int, meta map[string]any) []Chunk {
+	var chunks []Chunk
+	var buf strings.Builder
+	flush := func() {
+		if s := strings.TrimSpace(buf.String()); s != "" {
+			chunks = append(chunks, Chunk{Text: s, Metadata: maps.Clone(meta)})
+		}
+		buf.Reset()
+	}
+	for _, para := range strings.Split(normalizeNewlines(text), "\n\n") {
+		para = strings.TrimSpace(para)
+		if para == "" {
+			continue
+		}
+		if buf.Len() > 0 && buf.Len()+len(para)+2 > size {
+			flush()
+		}
+		for len(para) > size {
+			flush()
+			cut := splitPoint(para, size)
+			chunks = append(chunks, Chunk{Text: strings.TrimSpace(para[:cut]), Metadata: maps.Clone(meta)})
+			para = strings.TrimSpace(para[cut:])
+		}
+		if buf.Len() > 0 {
+			buf.WriteString("\n\n")
+		}
+		buf.WriteString(para)
+	}
+	flush()
+	return chunks
+}
+
+// chunkMarkdown 按标题切分，每个文本块记录所在章节路径（如 "安装 > 配置"）
+func chunkMarkdown(text string, size int) []Chunk {
+	var chunks []Chunk
+	var headings []string
+	var section strings.Builder
+	inFence := false
+
+	flush := func() {
+		meta := map[string]any{}
+		if len(headings) > 0 {
+			meta["section"] = strings.Join(nonEmpty(headings), " > ")
+		}
+		body := section.String()
+		section.Reset()
+		if strings.TrimSpace(body) == "" {
+			return
+		}
+		// 标题作为块的首行，保留检索所需的上下文
+		title := ""
+		if len(headings) > 0 {
+			title = headings[len(headings)-1]
+		}
+		for _, c := range chunkParagraphs(body, size, meta) {
+			if title != "" && !strings.HasPrefix(c.Text, "#") {
+				c.Text = title + "\n\n" + c.Text
+			}
+			chunks = append(chunks, c)
+		}
+	}
+
+	for _, line := range strings.Split(normalizeNewlines(text), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inFence = !inFence
+		}
+		if !inFence {
+			if m := headingPattern.FindStringSubmatch(trimmed); m != nil {
+				flush()
+				level := len(m[1])
+				for len(headings) < level {
+					headings = append(headings, "")
+				}
+				headings = append(headings[:level-1], m[2])
+				continue
+			}
+		}
+		section.WriteString(line)
+		section.WriteByte('\n')
+	}
+	flush()
+	return chunks
+}
+
+// chunkCode 按顶层声明切分源代码；声明前紧邻的注释归入该声明，过小的声明会与相邻声明合并
+func chunkCode(text, lang string, size int) []Chunk {
+	lines := strings.Split(normalizeNewlines(text), "\n")
+	pattern := declPatterns[lang]
+
+	// 找出每个声明的起始行（含其上方紧邻的注释和装饰器）
+	starts := []int{0}
+	for i := 1; i < len(lines); i++ {
+		if pattern == nil || !pattern.MatchString(lines[i]) {
+			continue
+		}
+		start := i
+		for start > 0 && isCommentLine(lines[start-1]) {
+			start--
+		}
+		if start > starts[len(starts)-1] {
+			starts = append(starts, start)
+		}
+	}
+	starts = append(starts, len(lines))
+
+	var chunks []Chunk
+	begin, length := 0, 0
+	emit := func(from, to int) {
+		body := strings.Join(lines[from:to], "\n")
+		if strings.TrimSpace(body) == "" {
+			return
+		}
+		chunks = append(chunks, Chunk{Text: strings.TrimRight(body, "\n "), Metadata: map[string]any{
+			"language":   lang,
+			"start_line": from + 1,
+			"end_line":   to,
+		}})
+	}
+	for k := 0; k+1 < len(starts); k++ {
+		from, to := starts[k], starts[k+1]
+		unit := 0
+		for _, l := range lines[from:to] {
+			unit += len(l) + 1
+		}
+		if length > 0 && length+unit > size {
+			emit(begin, from)
+			begin, length = from, 0
+		}
+		if unit > size {
+			// 单个声明过长时按行切分
+			lineStart, acc := from, 0
+			for i := from; i < to; i++ {
+				if acc > 0 && acc+len(lines[i])+1 > size {
+					emit(lineStart, i)
+					lineStart, acc = i, 0
+				}
+				acc += len(lines[i]) + 1
+			}
+			emit(lineStart, to)
+			begin, length = to, 0
+			continue
+		}
+		length += unit
+	}
+	if begin < len(lines) {
+		emit(begin, len(lines))
+	}
+	return chunks
+}
+
+// chunkPages 切分 PDF 文本，页之间以换页符分隔，文本块记录起始页码
+func chunkPages(text string, size int) []Chunk {
+	var chunks []Chunk
+	for i, page := range strings.Split(text, "\f") {
+		chunks = append(chunks, chunkParagraphs(page, size, map[string]any{"page": i + 1})...)
+	}
+	return chunks
+}
+
+func isCommentLine(line string) bool {
+	t := strings.TrimSpace(line)
+	for _, prefix := range []string{"//", "#", "/*", "*", "--", "\"\"\"", "@"} {
+		if strings.HasPrefix(t, prefix) && !strings.HasPrefix(t, "#[") && !strings.HasPrefix(t, "#include") && !strings.HasPrefix(t, "#define") {
+			return true
+		}
+	}
+	return false
+}
+
+// splitPoint 在 size 以内寻找最后一个句子或空白边界
+func splitPoint(s string, size int) int {
+	// 避免截断多字节字符
+	for size > 0 && size < len(s) && !utf8.RuneStart(s[size]) {
+		size--
+	}
+	for _, seps := range []string{"。.!?！？\n", " \t"} {
+		if i := strings.LastIndexAny(s[:size], seps); i > size/2 {
+			_, n := utf8.DecodeRuneInString(s[i:])
+			return i + n
+		}
+	}
+	return size
+}
+
+func normalizeNewlines(s string) string {
+	return strings.ReplaceAll(s, "\r\n", "\n")
+}
+
+func nonEmpty(items []string) []string {
+	out := make([]string, 0, len(items))
+	for _, s := range items {
+		if s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
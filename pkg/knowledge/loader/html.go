@@ -0,0 +1,148 @@
+package loader
+
+import (
+	"bytes"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// skipHTMLElements 不包含正文的元素
+var skipHTMLElements = map[string]bool{
+	"script": true, "style": true, "noscript": true, "template": true, "svg": true,
+	"head": true, "nav": true, "footer": true, "iframe": true, "form": true,
+}
+
+// blockHTMLElements 块级元素，前后换行
+var blockHTMLElements = map[string]bool{
+	"p": true, "div": true, "section": true, "article": true, "main": true, "aside": true,
+	"li": true, "tr": true, "table": true, "ul": true, "ol": true, "dl": true, "dt": true, "dd": true,
+	"blockquote": true, "pre": true, "figure": true, "figcaption": true, "header": true, "br": true, "hr": true,
+}
+
+// extractHTML 提取标题和正文，h1-h6 转为 Markdown 标题以便按章节切分
+func extractHTML(data []byte) (title, text string) {
+	z := html.NewTokenizer(bytes.NewReader(data))
+	var out strings.Builder
+	skipDepth := 0
+	inTitle, inPre := false, false
+	heading := 0
+
+	paragraph := func() {
+		s := out.String()
+		if len(s) == 0 || strings.HasSuffix(s, "\n\n") {
+			return
+		}
+		if strings.HasSuffix(s, "\n") {
+			out.WriteByte('\n')
+		} else {
+			out.WriteString("\n\n")
+		}
+	}
+
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			return strings.TrimSpace(title), strings.TrimSpace(out.String())
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, _ := z.TagName()
+			tag := string(name)
+			if tag == "title" {
+				inTitle = true
+				continue
+			}
+			if skipHTMLElements[tag] {
+				if tt == html.StartTagToken {
+					skipDepth++
+				}
+				continue
+			}
+			if skipDepth > 0 {
+				continue
+			}
+			if level := htmlHeadingLevel(tag); level > 0 {
+				paragraph()
+				heading = level
+				out.WriteString(strings.Repeat("#", level) + " ")
+				continue
+			}
+			if tag == "pre" {
+				inPre = true
+			}
+			if blockHTMLElements[tag] {
+				paragraph()
+			}
+			if tag == "li" {
+				out.WriteString("- ")
+			}
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			tag := string(name)
+			if tag == "title" {
+				inTitle = false
+				continue
+			}
+			if skipHTMLElements[tag] {
+				if skipDepth > 0 {
+					skipDepth--
+				}
+				continue
+			}
+			if skipDepth > 0 {
+				continue
+			}
+			if htmlHeadingLevel(tag) > 0 && heading > 0 {
+				heading = 0
+				out.WriteString("\n\n")
+				continue
+			}
+			if tag == "pre" {
+				inPre = false
+			}
+			if blockHTMLElements[tag] {
+				paragraph()
+			}
+		case html.TextToken:
+			raw := string(z.Text())
+			if inTitle {
+				title += raw
+				continue
+			}
+			if skipDepth > 0 {
+				continue
+			}
+			if inPre {
+				out.WriteString(raw)
+				continue
+			}
+			words := strings.Fields(raw)
+			if len(words) == 0 {
+				continue
+			}
+			s := out.String()
+			if len(s) > 0 && !strings.HasSuffix(s, "\n") && !strings.HasSuffix(s, " ") && startsWithSpace(raw) {
+				out.WriteByte(' ')
+			}
+			out.WriteString(strings.Join(words, " "))
+			if endsWithSpace(raw) {
+				out.WriteByte(' ')
+			}
+		}
+	}
+}
+
+func htmlHeadingLevel(tag string) int {
+	if len(tag) == 2 && tag[0] == 'h' && tag[1] >= '1' && tag[1] <= '6' {
+		return int(tag[1] - '0')
+	}
+	return 0
+}
+
+func startsWithSpace(s string) bool {
+	return s != "" && strings.TrimLeft(s[:1], " \t\r\n") == ""
+}
+
+func endsWithSpace(s string) bool {
+	return s != "" && strings.TrimRight(s[len(s)-1:], " \t\r\n") == ""
+}
@@ -0,0 +1,325 @@
+package loader
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/astercloud/aster/pkg/knowledge/core"
+)
+
+// IndexStatus 单个来源的索引结果
+type IndexStatus string
+
+const (
+	StatusIndexed   IndexStatus = "indexed"   // 新增或内容变化后重新索引
+	StatusUnchanged IndexStatus = "unchanged" // 内容哈希未变化，跳过
+	StatusSkipped   IndexStatus = "skipped"   // 不支持的格式或没有文本
+	StatusRemoved   IndexStatus = "removed"   // 文件已删除，清理其文本块
+	StatusFailed    IndexStatus = "failed"
+)
+
+// Progress 索引进度，每处理完一个来源回调一次
+type Progress struct {
+	Current int
+	Total   int
+	Source  string
+	Status  IndexStatus
+	Chunks  int
+	Err     error
+}
+
+// Summary 一次索引的统计
+type Summary struct {
+	Indexed   int
+	Unchanged int
+	Skipped   int
+	Removed   int
+	Failed    int
+	Chunks    int
+}
+
+// Manifest 记录已索引来源的内容哈希和文本块 ID，用于增量重新索引
+type Manifest struct {
+	Namespace string                  `json:"namespace"`
+	Sources   map[string]*SourceState `json:"sources"`
+}
+
+// SourceState 单个来源的索引状态
+type SourceState struct {
+	Hash      string    `json:"hash"`
+	Format    Format    `json:"format"`
+	ChunkIDs  []string  `json:"chunk_ids"`
+	IndexedAt time.Time `json:"indexed_at"`
+}
+
+// IndexOptions 索引选项
+type IndexOptions struct {
+	Loader    Options
+	Namespace string
+	// Force 忽略内容哈希，全部重新索引
+	Force bool
+	// Progress 可选的进度回调
+	Progress func(Progress)
+}
+
+// Indexer 把文件、目录和 URL 增量写入知识库：内容未变化的来源跳过，
+// 变化的来源先写入新文本块再删除多余的旧块，目录中已删除的文件会清理其文本块
+type Indexer struct {
+	pipeline     *core.Pipeline
+	manifestPath string
+	manifest     *Manifest
+	opts         IndexOptions
+}
+
+// skipDirs 遍历目录时忽略的目录
+var skipDirs = map[string]bool{"node_modules": true, "vendor": true, "dist": true, "build": true, "target": true, "__pycache__": true}
+
+// NewIndexer 创建 Indexer；manifestPath 不存在时从空清单开始
+func NewIndexer(p *core.Pipeline, manifestPath string, opts IndexOptions) (*Indexer, error) {
+	if p == nil {
+		return nil, errors.New("knowledge loader: pipeline is required")
+	}
+	m := &Manifest{Namespace: opts.Namespace, Sources: map[string]*SourceState{}}
+	data, err := os.ReadFile(manifestPath)
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(data, m); err != nil {
+			return nil, fmt.Errorf("read manifest %s: %w", manifestPath, err)
+		}
+		if m.Sources == nil {
+			m.Sources = map[string]*SourceState{}
+		}
+	case !errors.Is(err, fs.ErrNotExist):
+		return nil, err
+	}
+	return &Indexer{pipeline: p, manifestPath: manifestPath, manifest: m, opts: opts}, nil
+}
+
+// Manifest 返回当前清单
+func (ix *Indexer) Manifest() *Manifest {
+	return ix.manifest
+}
+
+type indexTarget struct {
+	key  string // 清单中的键：文件绝对路径或 URL
+	path string
+	url  bool
+}
+
+// Index 索引目标列表，每项可以是文件、目录或 http(s) URL。
+// 单个来源失败不会中断整体流程，失败计入 Summary.Failed；清单在每个来源处理后保存。
+func (ix *Indexer) Index(ctx context.Context, targets []string) (*Summary, error) {
+	var items []indexTarget
+	var roots []string
+	seen := map[string]bool{}
+	for _, target := range targets {
+		if strings.HasPrefix(target, "http://") || strings.HasPrefix(target, "https://") {
+			if !seen[target] {
+				seen[target] = true
+				items = append(items, indexTarget{key: target, url: true})
+			}
+			continue
+		}
+		abs, err := filepath.Abs(target)
+		if err != nil {
+			return nil, err
+		}
+		info, err := os.Stat(abs)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			if !seen[abs] {
+				seen[abs] = true
+				items = append(items, indexTarget{key: abs, path: abs})
+			}
+			continue
+		}
+		roots = append(roots, abs)
+		err = filepath.WalkDir(abs, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			name := d.Name()
+			if d.IsDir() {
+				if p != abs && (strings.HasPrefix(name, ".") || skipDirs[name]) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if strings.HasPrefix(name, ".") || !d.Type().IsRegular() {
+				return nil
+			}
+			if format, _ := DetectFormat(name, ""); format == "" || seen[p] {
+				return nil
+			}
+			seen[p] = true
+			items = append(items, indexTarget{key: p, path: p})
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("walk %s: %w", target, err)
+		}
+	}
+
+	// 目录下清单中存在但本次未找到的文件视为已删除
+	var removed []string
+	for key := range ix.manifest.Sources {
+		if seen[key] {
+			continue
+		}
+		for _, root := range roots {
+			if strings.HasPrefix(key, root+string(filepath.Separator)) {
+				removed = append(removed, key)
+				break
+			}
+		}
+	}
+	slices.Sort(removed)
+
+	summary := &Summary{}
+	total := len(items) + len(removed)
+	for i, item := range items {
+		if err := ctx.Err(); err != nil {
+			return summary, err
+		}
+		p := ix.indexOne(ctx, item)
+		p.Current, p.Total = i+1, total
+		ix.record(summary, p)
+	}
+	for i, key := range removed {
+		p := Progress{Current: len(items) + i + 1, Total: total, Source: key, Status: StatusRemoved}
+		if err := ix.pipeline.Delete(ctx, ix.manifest.Sources[key].ChunkIDs); err != nil {
+			p.Status, p.Err = StatusFailed, err
+		} else {
+			delete(ix.manifest.Sources, key)
+			p.Err = ix.save()
+		}
+		ix.record(summary, p)
+	}
+	return summary, nil
+}
+
+func (ix *Indexer) record(summary *Summary, p Progress) {
+	switch p.Status {
+	case StatusIndexed:
+		summary.Indexed++
+		summary.Chunks += p.Chunks
+	case StatusUnchanged:
+		summary.Unchanged++
+	case StatusSkipped:
+		summary.Skipped++
+	case StatusRemoved:
+		summary.Removed++
+	case StatusFailed:
+		summary.Failed++
+	}
+	if ix.opts.Progress != nil {
+		ix.opts.Progress(p)
+	}
+}
+
+func (ix *Indexer) indexOne(ctx context.Context, item indexTarget) Progress {
+	p := Progress{Source: item.key}
+	var data []byte
+	var name, contentType string
+	var err error
+	if item.url {
+		data, name, contentType, err = fetch(ctx, item.key, ix.opts.Loader)
+	} else {
+		name = item.path
+		var info os.FileInfo
+		if info, err = os.Stat(item.path); err == nil && info.Size() > MaxFileSize {
+			p.Status, p.Err = StatusSkipped, fmt.Errorf("file too large (%d bytes)", info.Size())
+			return p
+		}
+		data, err = os.ReadFile(item.path)
+	}
+	if err != nil {
+		p.Status, p.Err = StatusFailed, err
+		return p
+	}
+
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+	prev := ix.manifest.Sources[item.key]
+	if prev != nil && prev.Hash == hash && !ix.opts.Force {
+		p.Status, p.Chunks = StatusUnchanged, len(prev.ChunkIDs)
+		return p
+	}
+
+	doc, err := Parse(name, contentType, data, ix.opts.Loader)
+	if err != nil {
+		p.Status, p.Err = StatusSkipped, err
+		return p
+	}
+	chunks := make([]core.Chunk, len(doc.Chunks))
+	for i, c := range doc.Chunks {
+		chunks[i] = core.Chunk{Text: c.Text, Metadata: c.Metadata}
+	}
+	meta := map[string]any{"source": item.key, "title": doc.Title, "format": string(doc.Format)}
+	if doc.Language != "" {
+		meta["language"] = doc.Language
+	}
+	ingested, err := ix.pipeline.Ingest(ctx, core.IngestRequest{
+		ID:        item.key,
+		Namespace: ix.opts.Namespace,
+		Metadata:  meta,
+		Chunks:    chunks,
+	})
+	if err != nil {
+		p.Status, p.Err = StatusFailed, err
+		return p
+	}
+
+	ids := make([]string, len(ingested))
+	for i, c := range ingested {
+		ids[i] = c.ID
+	}
+	// 新块 ID 与旧块相同则已被覆盖，只需删除多出来的旧块
+	if prev != nil {
+		var stale []string
+		for _, id := range prev.ChunkIDs {
+			if !slices.Contains(ids, id) {
+				stale = append(stale, id)
+			}
+		}
+		if err := ix.pipeline.Delete(ctx, stale); err != nil {
+			p.Status, p.Err = StatusFailed, err
+			return p
+		}
+	}
+
+	ix.manifest.Sources[item.key] = &SourceState{Hash: hash, Format: doc.Format, ChunkIDs: ids, IndexedAt: time.Now().UTC()}
+	p.Status, p.Chunks = StatusIndexed, len(ids)
+	if err := ix.save(); err != nil {
+		p.Status, p.Err = StatusFailed, err
+	}
+	return p
+}
+
+// save 原子写入清单
+func (ix *Indexer) save() error {
+	if err := os.MkdirAll(filepath.Dir(ix.manifestPath), 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(ix.manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := ix.manifestPath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, ix.manifestPath)
+}
@@ -0,0 +1,90 @@
+package loader
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/astercloud/aster/pkg/knowledge/core"
+	"github.com/astercloud/aster/pkg/vector"
+)
+
+func TestIndexerIncremental(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	docs := filepath.Join(root, "docs")
+	write := func(name, content string) {
+		t.Helper()
+		p := filepath.Join(docs, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("guide.md", "# Guide\n\nIntro.\n\n## Setup\n\nInstall it.")
+	write("notes.txt", "Plain notes.")
+	write("src/main.go", "package main\n\nfunc main() {}\n")
+	write("node_modules/dep/readme.md", "ignored")
+	write(".hidden.md", "ignored")
+
+	store := vector.NewMemoryStore()
+	embedder := vector.NewMockEmbedder(8)
+	count := func() int {
+		t.Helper()
+		vecs, _ := embedder.EmbedText(ctx, []string{"q"})
+		hits, err := store.Query(ctx, vector.Query{Vector: vecs[0], TopK: 100, Namespace: "kb"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(hits)
+	}
+	manifest := filepath.Join(root, "state", "manifest.json")
+	run := func() *Summary {
+		t.Helper()
+		p, err := core.NewPipeline(core.PipelineConfig{Store: store, Embedder: embedder, Namespace: "kb"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ix, err := NewIndexer(p, manifest, IndexOptions{Namespace: "kb"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		s, err := ix.Index(ctx, []string{docs})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+
+	s := run()
+	if s.Indexed != 3 || s.Chunks != 4 || count() != 4 {
+		t.Fatalf("first run: %+v, stored %d", s, count())
+	}
+
+	s = run()
+	if s.Indexed != 0 || s.Unchanged != 3 {
+		t.Fatalf("second run: %+v", s)
+	}
+
+	// guide.md 缩减为一个章节，旧的多余文本块应被删除；notes.txt 被删除
+	write("guide.md", "# Guide\n\nOnly intro now.")
+	if err := os.Remove(filepath.Join(docs, "notes.txt")); err != nil {
+		t.Fatal(err)
+	}
+	s = run()
+	if s.Indexed != 1 || s.Unchanged != 1 || s.Removed != 1 || count() != 2 {
+		t.Fatalf("third run: %+v, stored %d", s, count())
+	}
+
+	data, err := os.ReadFile(manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "notes.txt") || !strings.Contains(string(data), "main.go") {
+		t.Errorf("manifest = %s", data)
+	}
+}
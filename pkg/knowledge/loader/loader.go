@@ -0,0 +1,237 @@
+// Package loader 把 PDF、DOCX、HTML、Markdown、源代码等文件转换为可写入知识库的文本块。
+// 每种格式按其结构切分：Markdown/HTML/DOCX 按标题，源代码按顶层声明，其余按段落。
+package loader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/astercloud/aster/pkg/httpclient"
+)
+
+// Format 文档格式
+type Format string
+
+const (
+	FormatText     Format = "text"
+	FormatMarkdown Format = "markdown"
+	FormatHTML     Format = "html"
+	FormatPDF      Format = "pdf"
+	FormatDOCX     Format = "docx"
+	FormatCode     Format = "code"
+)
+
+// DefaultChunkSize 默认每个文本块的最大字符数
+const DefaultChunkSize = 1500
+
+// MaxFileSize 单个文件的最大字节数，超过时跳过
+const MaxFileSize = 32 << 20
+
+// ErrUnsupported 无法识别或不支持的文件（如二进制文件）
+var ErrUnsupported = errors.New("unsupported document format")
+
+// Chunk 切分后的文本块
+type Chunk struct {
+	Text string
+	// Metadata 文本块元数据，如 section、language、start_line、end_line、page
+	Metadata map[string]any
+}
+
+// Document 加载后的文档
+type Document struct {
+	// Source 文件路径或 URL
+	Source   string
+	Title    string
+	Format   Format
+	Language string // 源代码语言，仅 FormatCode
+	Text     string
+	Chunks   []Chunk
+}
+
+// Options 加载选项
+type Options struct {
+	// ChunkSize 每个文本块的最大字符数，默认 DefaultChunkSize
+	ChunkSize int
+	// HTTPClient 加载 URL 时使用，默认 30 秒超时
+	HTTPClient *http.Client
+}
+
+func (o Options) chunkSize() int {
+	if o.ChunkSize > 0 {
+		return o.ChunkSize
+	}
+	return DefaultChunkSize
+}
+
+// LoadFile 读取并解析本地文件
+func LoadFile(filePath string, opts Options) (*Document, error) {
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() > MaxFileSize {
+		return nil, fmt.Errorf("%s: file too large (%d bytes)", filePath, info.Size())
+	}
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(filePath, "", data, opts)
+}
+
+// LoadURL 下载并解析 URL 指向的文档，格式由 Content-Type 或路径扩展名决定
+func LoadURL(ctx context.Context, rawURL string, opts Options) (*Document, error) {
+	data, name, contentType, err := fetch(ctx, rawURL, opts)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := Parse(name, contentType, data, opts)
+	if err != nil {
+		return nil, err
+	}
+	doc.Source = rawURL
+	return doc, nil
+}
+
+// fetch 下载 URL 内容，返回内容、用于识别格式的路径和 Content-Type
+func fetch(ctx context.Context, rawURL string, opts Options) ([]byte, string, string, error) {
+	client := opts.HTTPClient
+	if client == nil {
+		client = httpclient.New(30 * time.Second)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, "", "", err
+	}
+	req.Header.Set("User-Agent", "Aster-Agent/1.0")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, "", "", fmt.Errorf("fetch %s: %w", rawURL, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, "", "", fmt.Errorf("fetch %s: %s", rawURL, resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, MaxFileSize+1))
+	if err != nil {
+		return nil, "", "", fmt.Errorf("read %s: %w", rawURL, err)
+	}
+	if len(data) > MaxFileSize {
+		return nil, "", "", fmt.Errorf("%s: document too large", rawURL)
+	}
+	name := rawURL
+	if u := resp.Request.URL; u != nil {
+		name = u.Path
+	}
+	return data, name, resp.Header.Get("Content-Type"), nil
+}
+
+// Parse 解析文档内容；name 用于按扩展名识别格式，contentType 可为空
+func Parse(name, contentType string, data []byte, opts Options) (*Document, error) {
+	format, lang := DetectFormat(name, contentType)
+	if format == "" {
+		if !isText(data) {
+			return nil, fmt.Errorf("%s: %w", name, ErrUnsupported)
+		}
+		format = FormatText
+	}
+
+	doc := &Document{Source: name, Format: format, Language: lang, Title: strings.TrimSuffix(path.Base(filepath.ToSlash(name)), path.Ext(name))}
+	size := opts.chunkSize()
+	switch format {
+	case FormatPDF:
+		text, err := extractPDF(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		doc.Text = text
+		doc.Chunks = chunkPages(text, size)
+	case FormatDOCX:
+		text, err := extractDOCX(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		doc.Text = text
+		doc.Chunks = chunkMarkdown(text, size)
+	case FormatHTML:
+		title, text := extractHTML(data)
+		if title != "" {
+			doc.Title = title
+		}
+		doc.Text = text
+		doc.Chunks = chunkMarkdown(text, size)
+	case FormatMarkdown:
+		doc.Text = string(data)
+		doc.Chunks = chunkMarkdown(doc.Text, size)
+	case FormatCode:
+		doc.Text = string(data)
+		doc.Chunks = chunkCode(doc.Text, lang, size)
+	default:
+		doc.Text = string(data)
+		doc.Chunks = chunkParagraphs(doc.Text, size, nil)
+	}
+	if len(doc.Chunks) == 0 {
+		return nil, fmt.Errorf("%s: no text content", name)
+	}
+	return doc, nil
+}
+
+// DetectFormat 识别格式：明确的 Content-Type 优先（URL 路径可能是 .php 等动态页面），其次是扩展名；
+// 无法识别时返回空字符串
+func DetectFormat(name, contentType string) (Format, string) {
+	mediaType := ""
+	if contentType != "" {
+		mediaType, _, _ = mime.ParseMediaType(contentType)
+	}
+	switch mediaType {
+	case "application/pdf":
+		return FormatPDF, ""
+	case "application/vnd.openxmlformats-officedocument.wordprocessingml.document":
+		return FormatDOCX, ""
+	case "text/html", "application/xhtml+xml":
+		return FormatHTML, ""
+	case "text/markdown", "text/x-markdown":
+		return FormatMarkdown, ""
+	}
+
+	ext := strings.ToLower(path.Ext(filepath.ToSlash(name)))
+	switch ext {
+	case ".pdf":
+		return FormatPDF, ""
+	case ".docx":
+		return FormatDOCX, ""
+	case ".html", ".htm", ".xhtml":
+		return FormatHTML, ""
+	case ".md", ".markdown", ".mdx":
+		return FormatMarkdown, ""
+	case ".txt", ".text", ".rst", ".log", ".csv":
+		return FormatText, ""
+	}
+	if lang, ok := codeLanguages[ext]; ok {
+		return FormatCode, lang
+	}
+	if mediaType == "text/plain" {
+		return FormatText, ""
+	}
+	return "", ""
+}
+
+// isText 粗略判断内容是否为文本（前 8KB 不含 NUL 字节）
+func isText(data []byte) bool {
+	n := min(len(data), 8192)
+	for _, b := range data[:n] {
+		if b == 0 {
+			return false
+		}
+	}
+	return true
+}
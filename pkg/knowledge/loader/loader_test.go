@@ -0,0 +1,122 @@
+package loader
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestChunkMarkdownSections(t *testing.T) {
+	md := "# Guide\n\nIntro.\n\n## Install\n\nRun `go get`.\n\n```sh\n# not a heading\n```\n\n## Usage\n\nCall it."
+	chunks := chunkMarkdown(md, 1000)
+	if len(chunks) != 3 {
+		t.Fatalf("chunks = %+v", chunks)
+	}
+	if chunks[1].Metadata["section"] != "Guide > Install" || !strings.Contains(chunks[1].Text, "# not a heading") {
+		t.Errorf("install chunk = %+v", chunks[1])
+	}
+	if !strings.HasPrefix(chunks[2].Text, "Usage\n\n") {
+		t.Errorf("chunk should start with its heading: %q", chunks[2].Text)
+	}
+}
+
+func TestChunkCodeDeclarations(t *testing.T) {
+	var src strings.Builder
+	src.WriteString("package demo\n\nimport \"fmt\"\n")
+	for i := range 6 {
+		fmt.Fprintf(&src, "\n// F%d does things.\nfunc F%d() {\n\tfmt.Println(%q)\n}\n", i, i, strings.Repeat("x", 60))
+	}
+	chunks := chunkCode(src.String(), "go", 250)
+	if len(chunks) < 3 {
+		t.Fatalf("expected several chunks, got %d", len(chunks))
+	}
+	for _, c := range chunks[1:] {
+		if !strings.HasPrefix(c.Text, "// F") {
+			t.Errorf("chunk should start at a declaration comment: %q", c.Text)
+		}
+		if c.Metadata["language"] != "go" || c.Metadata["start_line"].(int) <= 1 {
+			t.Errorf("metadata = %+v", c.Metadata)
+		}
+	}
+}
+
+func TestParseHTML(t *testing.T) {
+	page := `<html><head><title>Docs</title><style>p{}</style></head><body>
+<nav>menu</nav><h1>Intro</h1><p>Hello <b>world</b>.</p><script>alert(1)</script>
+<h2>Details</h2><ul><li>one</li><li>two</li></ul></body></html>`
+	doc, err := Parse("https://example.com/page.php", "text/html; charset=utf-8", []byte(page), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.Format != FormatHTML || doc.Title != "Docs" {
+		t.Errorf("doc = %+v", doc)
+	}
+	if strings.Contains(doc.Text, "alert") || strings.Contains(doc.Text, "menu") || !strings.Contains(doc.Text, "Hello world.") {
+		t.Errorf("text = %q", doc.Text)
+	}
+	if len(doc.Chunks) != 2 || doc.Chunks[1].Metadata["section"] != "Intro > Details" {
+		t.Errorf("chunks = %+v", doc.Chunks)
+	}
+}
+
+func TestParseDOCX(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, _ := zw.Create("word/document.xml")
+	_, _ = w.Write([]byte(`<?xml version="1.0"?>
+<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>
+<w:p><w:pPr><w:pStyle w:val="Heading1"/></w:pPr><w:r><w:t>Policy</w:t></w:r></w:p>
+<w:p><w:r><w:t xml:space="preserve">Refunds within </w:t></w:r><w:r><w:t>30 days.</w:t></w:r></w:p>
+</w:body></w:document>`))
+	_ = zw.Close()
+
+	doc, err := Parse("policy.docx", "", buf.Bytes(), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.Text != "# Policy\n\nRefunds within 30 days.\n\n" {
+		t.Errorf("text = %q", doc.Text)
+	}
+	if len(doc.Chunks) != 1 || doc.Chunks[0].Metadata["section"] != "Policy" {
+		t.Errorf("chunks = %+v", doc.Chunks)
+	}
+}
+
+func TestParsePDF(t *testing.T) {
+	page1 := "BT /F1 12 Tf 72 720 Td (Hello \\(PDF\\)) Tj 0 -14 Td [(Second) -300 (line)] TJ ET"
+	var compressed bytes.Buffer
+	zw := zlib.NewWriter(&compressed)
+	_, _ = zw.Write([]byte("BT (Page two) Tj ET"))
+	_ = zw.Close()
+
+	var pdf bytes.Buffer
+	pdf.WriteString("%PDF-1.4\n")
+	fmt.Fprintf(&pdf, "4 0 obj\n<< /Length %d >>\nstream\n%s\nendstream\nendobj\n", len(page1), page1)
+	fmt.Fprintf(&pdf, "5 0 obj\n<< /Length %d /Filter /FlateDecode >>\nstream\n", compressed.Len())
+	pdf.Write(compressed.Bytes())
+	pdf.WriteString("\nendstream\nendobj\n6 0 obj\n<< /Subtype /Image /Length 3 >>\nstream\n(x)\nendstream\nendobj\n%%EOF\n")
+
+	doc, err := Parse("report.pdf", "", pdf.Bytes(), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.Text != "Hello (PDF)\nSecond line\n\fPage two\n" && doc.Text != "Hello (PDF)\nSecond line\fPage two" {
+		t.Errorf("text = %q", doc.Text)
+	}
+	if len(doc.Chunks) != 2 || doc.Chunks[1].Metadata["page"] != 2 {
+		t.Errorf("chunks = %+v", doc.Chunks)
+	}
+
+	if _, err := Parse("scan.pdf", "", []byte("%PDF-1.4\n%%EOF"), Options{}); err == nil {
+		t.Error("PDF without text should fail")
+	}
+}
+
+func TestParseUnsupported(t *testing.T) {
+	if _, err := Parse("image.bin", "", []byte{0x89, 'P', 'N', 'G', 0, 0}, Options{}); err == nil {
+		t.Error("binary content should be rejected")
+	}
+}
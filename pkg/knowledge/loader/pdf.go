@@ -0,0 +1,361 @@
+package loader
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// errNoPDFText PDF 中没有可提取的文本
+var errNoPDFText = errors.New("no extractable text in PDF (scanned images or fonts without a standard encoding); convert it to text first")
+
+// skipStreamMarkers 非页面内容的流（图片、字体、交叉引用、对象流等）
+var skipStreamMarkers = []string{"/Image", "/XRef", "/ObjStm", "/FontFile", "/Length1", "/Length2", "/Type1C", "/CIDFontType0C", "/OpenType", "/Metadata", "/EmbeddedFile", "/ICCBased", "/DCTDecode", "/JPXDecode", "/CCITTFaxDecode", "/JBIG2Decode"}
+
+// extractPDF 从 PDF 页面内容流中提取文本，不依赖外部工具。
+// 支持未压缩和 FlateDecode 压缩的内容流，以及标准编码的字体；
+// 扫描件和使用自定义 CID 编码的字体无法提取。各内容流之间以换页符分隔。
+func extractPDF(data []byte) (string, error) {
+	if !bytes.HasPrefix(bytes.TrimLeft(data, "\x00\t\r\n "), []byte("%PDF")) {
+		return "", errors.New("not a PDF file")
+	}
+	if bytes.Contains(data, []byte("/Encrypt")) {
+		return "", errors.New("encrypted PDF is not supported")
+	}
+
+	var pages []string
+	pos := 0
+	for {
+		idx := bytes.Index(data[pos:], []byte("stream"))
+		if idx < 0 {
+			break
+		}
+		kw := pos + idx
+		pos = kw + len("stream")
+		// 排除 endstream
+		if kw >= 3 && string(data[kw-3:kw]) == "end" {
+			continue
+		}
+		start := pos
+		if start < len(data) && data[start] == '\r' {
+			start++
+		}
+		if start < len(data) && data[start] == '\n' {
+			start++
+		}
+		end := bytes.Index(data[start:], []byte("endstream"))
+		if end < 0 {
+			break
+		}
+		raw := bytes.TrimRight(data[start:start+end], "\r\n")
+		pos = start + end + len("endstream")
+
+		dictStart := bytes.LastIndex(data[:kw], []byte("obj"))
+		if dictStart < 0 {
+			continue
+		}
+		dict := string(data[dictStart:kw])
+		if skipStream(dict) {
+			continue
+		}
+		content := raw
+		if strings.Contains(dict, "/FlateDecode") {
+			content = inflate(raw)
+		} else if strings.Contains(dict, "/Filter") {
+			continue
+		}
+		if text := strings.TrimSpace(contentText(content)); text != "" {
+			pages = append(pages, text)
+		}
+	}
+	if len(pages) == 0 {
+		return "", errNoPDFText
+	}
+	return strings.Join(pages, "\f"), nil
+}
+
+func skipStream(dict string) bool {
+	for _, marker := range skipStreamMarkers {
+		if strings.Contains(dict, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// inflate 解压 FlateDecode 数据；数据损坏时返回已解压的部分
+func inflate(raw []byte) []byte {
+	r, err := zlib.NewReader(bytes.NewReader(raw))
+	if err != nil {
+		return nil
+	}
+	defer func() { _ = r.Close() }()
+	out, _ := io.ReadAll(r)
+	return out
+}
+
+// pdfToken 内容流中的词法单元
+type pdfToken struct {
+	kind  byte // 's' 字符串, 'n' 数字, 'o' 操作符, '[' ']' 数组边界
+	text  string
+	value float64
+}
+
+// contentText 解释内容流中的文本操作符（Tj、TJ、'、"、Td、TD、T*、ET）
+func contentText(content []byte) string {
+	var out strings.Builder
+	var operands []pdfToken
+	var inArray bool
+	var array strings.Builder
+
+	newline := func() {
+		s := out.String()
+		if len(s) > 0 && s[len(s)-1] != '\n' {
+			out.WriteByte('\n')
+		}
+	}
+
+	lex := pdfLexer{data: content}
+	for {
+		tok, ok := lex.next()
+		if !ok {
+			break
+		}
+		switch tok.kind {
+		case '[':
+			inArray = true
+			array.Reset()
+		case ']':
+			inArray = false
+			operands = append(operands, pdfToken{kind: 's', text: array.String()})
+		case 's':
+			if inArray {
+				array.WriteString(tok.text)
+			} else {
+				operands = append(operands, tok)
+			}
+		case 'n':
+			if inArray {
+				// TJ 数组中较大的负偏移通常表示单词间距
+				if tok.value < -200 {
+					array.WriteByte(' ')
+				}
+			} else {
+				operands = append(operands, tok)
+			}
+		case 'o':
+			switch tok.text {
+			case "Tj", "TJ":
+				if n := len(operands); n > 0 && operands[n-1].kind == 's' {
+					out.WriteString(operands[n-1].text)
+				}
+			case "'", "\"":
+				newline()
+				if n := len(operands); n > 0 && operands[n-1].kind == 's' {
+					out.WriteString(operands[n-1].text)
+				}
+			case "T*", "ET":
+				newline()
+			case "Td", "TD":
+				if n := len(operands); n >= 2 && operands[n-1].value != 0 {
+					newline()
+				} else if out.Len() > 0 {
+					out.WriteByte(' ')
+				}
+			}
+			operands = operands[:0]
+		}
+	}
+	return out.String()
+}
+
+// pdfLexer 内容流的最小词法分析器
+type pdfLexer struct {
+	data []byte
+	pos  int
+}
+
+func (l *pdfLexer) next() (pdfToken, bool) {
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		switch {
+		case isPDFSpace(c):
+			l.pos++
+		case c == '%':
+			for l.pos < len(l.data) && l.data[l.pos] != '\n' && l.data[l.pos] != '\r' {
+				l.pos++
+			}
+		case c == '(':
+			return pdfToken{kind: 's', text: decodePDFString(l.literal())}, true
+		case c == '<' && l.pos+1 < len(l.data) && l.data[l.pos+1] == '<':
+			l.pos += 2
+		case c == '>' && l.pos+1 < len(l.data) && l.data[l.pos+1] == '>':
+			l.pos += 2
+		case c == '<':
+			return pdfToken{kind: 's', text: decodeHexString(l.hex())}, true
+		case c == '[' || c == ']':
+			l.pos++
+			return pdfToken{kind: c}, true
+		case c == '/':
+			l.pos++
+			l.word()
+		case c == '{' || c == '}' || c == ')' || c == '>':
+			l.pos++
+		default:
+			w := l.word()
+			if w == "" {
+				l.pos++
+				continue
+			}
+			if v, err := strconv.ParseFloat(w, 64); err == nil {
+				return pdfToken{kind: 'n', text: w, value: v}, true
+			}
+			if w == "BI" {
+				l.skipInlineImage()
+				continue
+			}
+			return pdfToken{kind: 'o', text: w}, true
+		}
+	}
+	return pdfToken{}, false
+}
+
+func (l *pdfLexer) word() string {
+	start := l.pos
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		if isPDFSpace(c) || strings.IndexByte("()<>[]{}/%", c) >= 0 {
+			break
+		}
+		l.pos++
+	}
+	return string(l.data[start:l.pos])
+}
+
+// literal 读取 (...) 字符串，处理嵌套括号和转义
+func (l *pdfLexer) literal() []byte {
+	l.pos++ // (
+	depth := 1
+	var buf []byte
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		l.pos++
+		switch c {
+		case '\\':
+			if l.pos >= len(l.data) {
+				return buf
+			}
+			e := l.data[l.pos]
+			l.pos++
+			switch e {
+			case 'n':
+				buf = append(buf, '\n')
+			case 'r':
+				buf = append(buf, '\r')
+			case 't':
+				buf = append(buf, '\t')
+			case 'b':
+				buf = append(buf, '\b')
+			case 'f':
+				buf = append(buf, '\f')
+			case '\r', '\n':
+				// 行继续
+				if e == '\r' && l.pos < len(l.data) && l.data[l.pos] == '\n' {
+					l.pos++
+				}
+			default:
+				if e >= '0' && e <= '7' {
+					v := int(e - '0')
+					for i := 0; i < 2 && l.pos < len(l.data) && l.data[l.pos] >= '0' && l.data[l.pos] <= '7'; i++ {
+						v = v*8 + int(l.data[l.pos]-'0')
+						l.pos++
+					}
+					buf = append(buf, byte(v))
+				} else {
+					buf = append(buf, e)
+				}
+			}
+		case '(':
+			depth++
+			buf = append(buf, c)
+		case ')':
+			depth--
+			if depth == 0 {
+				return buf
+			}
+			buf = append(buf, c)
+		default:
+			buf = append(buf, c)
+		}
+	}
+	return buf
+}
+
+// hex 读取 <...> 十六进制字符串
+func (l *pdfLexer) hex() []byte {
+	l.pos++ // <
+	var digits []byte
+	for l.pos < len(l.data) && l.data[l.pos] != '>' {
+		if c := l.data[l.pos]; strings.IndexByte("0123456789abcdefABCDEF", c) >= 0 {
+			digits = append(digits, c)
+		}
+		l.pos++
+	}
+	l.pos++ // >
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	out := make([]byte, len(digits)/2)
+	for i := range out {
+		v, _ := strconv.ParseUint(string(digits[i*2:i*2+2]), 16, 8)
+		out[i] = byte(v)
+	}
+	return out
+}
+
+// skipInlineImage 跳过 BI ... ID <二进制> EI 内联图片
+func (l *pdfLexer) skipInlineImage() {
+	if idx := bytes.Index(l.data[l.pos:], []byte("EI")); idx >= 0 {
+		l.pos += idx + 2
+	} else {
+		l.pos = len(l.data)
+	}
+}
+
+func isPDFSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n' || c == '\f' || c == 0
+}
+
+// decodePDFString 解码字符串：带 BOM 的 UTF-16BE，否则按 Latin-1 处理
+func decodePDFString(b []byte) string {
+	if len(b) >= 2 && b[0] == 0xFE && b[1] == 0xFF {
+		u := make([]uint16, 0, (len(b)-2)/2)
+		for i := 2; i+1 < len(b); i += 2 {
+			u = append(u, uint16(b[i])<<8|uint16(b[i+1]))
+		}
+		return string(utf16.Decode(u))
+	}
+	runes := make([]rune, len(b))
+	for i, c := range b {
+		runes[i] = rune(c)
+	}
+	return string(runes)
+}
+
+// decodeHexString 十六进制字符串通常是 CID 字体的字形编号，无 ToUnicode 映射时无法还原；
+// 只保留带 BOM 的 UTF-16 或可打印 ASCII
+func decodeHexString(b []byte) string {
+	if len(b) >= 2 && b[0] == 0xFE && b[1] == 0xFF {
+		return decodePDFString(b)
+	}
+	for _, c := range b {
+		if c < 0x20 || c > 0x7E {
+			return ""
+		}
+	}
+	return string(b)
+}
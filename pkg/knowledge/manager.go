@@ -145,10 +145,12 @@ func NewManager(config *ManagerConfig) (Manager, error) {
 	// 可选：构建轻量核心管线
 	if config.UseCorePipeline && config.VectorStore != nil && config.Embedder != nil {
 		p, err := core.NewPipeline(core.PipelineConfig{
-			Store:       config.VectorStore,
-			Embedder:    config.Embedder,
-			Namespace:   config.Namespace,
-			DefaultTopK: config.MaxResults,
+			Store:            config.VectorStore,
+			Embedder:         config.Embedder,
+			Namespace:        config.Namespace,
+			DefaultTopK:      config.MaxResults,
+			Reranker:         config.Reranker,
+			RerankCandidates: config.RerankCandidates,
 		})
 		if err != nil {
 			return nil, fmt.Errorf("knowledge: init core pipeline: %w", err)
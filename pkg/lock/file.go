@@ -0,0 +1,97 @@
+package lock
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FileLocker 基于文件锁的锁，每个键对应目录下的一个锁文件
+// 锁绑定到打开的文件句柄，持有进程退出时由操作系统释放，不需要续约
+type FileLocker struct {
+	dir string
+}
+
+// NewFileLocker 创建文件锁，dir 不存在时自动创建
+func NewFileLocker(dir string) (*FileLocker, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create lock dir: %w", err)
+	}
+	return &FileLocker{dir: dir}, nil
+}
+
+// TryAcquire 尝试获取锁
+func (l *FileLocker) TryAcquire(_ context.Context, key string, _ time.Duration) (Lease, error) {
+	f, err := os.OpenFile(filepath.Join(l.dir, lockFileName(key)), os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("open lock file: %w", err)
+	}
+	held, err := tryLockFile(f)
+	if err != nil || !held {
+		_ = f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("lock %s: %w", key, err)
+		}
+		return nil, ErrNotAcquired
+	}
+	// 写入持有者信息便于排查，锁本身不依赖文件内容
+	if err := f.Truncate(0); err == nil {
+		host, _ := os.Hostname()
+		_, _ = fmt.Fprintf(f, "pid=%d host=%s key=%s acquired=%s\n", os.Getpid(), host, key, time.Now().Format(time.RFC3339))
+	}
+	return &fileLease{key: key, file: f}, nil
+}
+
+// lockFileName 把键转换为文件名：可读前缀加哈希，避免路径分隔符和冲突
+func lockFileName(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	prefix := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+			return r
+		}
+		return '_'
+	}, key)
+	if len(prefix) > 48 {
+		prefix = prefix[:48]
+	}
+	return prefix + "-" + hex.EncodeToString(sum[:8]) + ".lock"
+}
+
+type fileLease struct {
+	mu   sync.Mutex
+	key  string
+	file *os.File
+}
+
+func (f *fileLease) Key() string { return f.key }
+
+func (f *fileLease) Refresh(context.Context, time.Duration) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.file == nil {
+		return ErrLeaseLost
+	}
+	return nil
+}
+
+func (f *fileLease) Release(context.Context) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.file == nil {
+		return nil
+	}
+	// 关闭句柄即释放锁；锁文件保留，删除会让等待者锁住已经解除链接的文件
+	err := unlockFile(f.file)
+	if cerr := f.file.Close(); err == nil {
+		err = cerr
+	}
+	f.file = nil
+	return err
+}
@@ -0,0 +1,17 @@
+//go:build !unix && !windows
+
+package lock
+
+import (
+	"errors"
+	"os"
+)
+
+// tryLockFile 不支持文件锁的平台
+func tryLockFile(*os.File) (bool, error) {
+	return false, errors.New("file locks are not supported on this platform")
+}
+
+func unlockFile(*os.File) error {
+	return nil
+}
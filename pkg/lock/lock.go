@@ -0,0 +1,197 @@
+// Package lock 提供跨实例协调用的分布式锁
+//
+// 多个服务实例共享同一份存储时，用锁保证同一个定时任务只在一个实例上执行、
+// 同一个会话不会被两个实例同时恢复。提供以下后端：
+//   - Memory: 进程内锁，单实例部署和测试使用
+//   - File: 基于文件锁 (flock / LockFileEx)，同一主机或共享文件系统上的多进程
+//   - SQL: MySQL GET_LOCK / PostgreSQL pg_advisory_lock 会话级咨询锁
+//   - Redis: SET NX PX + 持有者令牌校验
+package lock
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrNotAcquired 锁已被其他持有者占用
+var ErrNotAcquired = errors.New("lock is held by another owner")
+
+// ErrLeaseLost 租约已过期或已被释放
+var ErrLeaseLost = errors.New("lock lease lost")
+
+// DefaultTTL 默认租约时长
+const DefaultTTL = 30 * time.Second
+
+// Locker 分布式锁
+type Locker interface {
+	// TryAcquire 尝试获取锁，锁已被占用时立即返回 ErrNotAcquired
+	// ttl 为租约时长，持有者需要在到期前 Refresh；
+	// File 和 SQL 后端的锁绑定到文件句柄或数据库连接，进程退出时自动释放，忽略 ttl
+	TryAcquire(ctx context.Context, key string, ttl time.Duration) (Lease, error)
+}
+
+// Lease 已获取的锁
+type Lease interface {
+	// Key 锁的键
+	Key() string
+	// Refresh 续约，租约已丢失时返回 ErrLeaseLost
+	Refresh(ctx context.Context, ttl time.Duration) error
+	// Release 释放锁，重复释放是安全的
+	Release(ctx context.Context) error
+}
+
+// Acquire 获取锁，被占用时每隔 retry 重试，直到获取成功或 ctx 结束
+func Acquire(ctx context.Context, locker Locker, key string, ttl, retry time.Duration) (Lease, error) {
+	if retry <= 0 {
+		retry = time.Second
+	}
+	for {
+		lease, err := locker.TryAcquire(ctx, key, ttl)
+		if !errors.Is(err, ErrNotAcquired) {
+			return lease, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("acquire %s: %w", key, ctx.Err())
+		case <-time.After(retry):
+		}
+	}
+}
+
+// KeepAlive 每隔 ttl/3 续约，直到返回的 stop 被调用或续约失败
+// 续约失败（租约丢失）时调用 onLost，持有者应停止受保护的工作
+func KeepAlive(lease Lease, ttl time.Duration, onLost func(error)) (stop func()) {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(ttl / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				ctx, cancel := context.WithTimeout(context.Background(), ttl/3)
+				err := lease.Refresh(ctx, ttl)
+				cancel()
+				if err != nil {
+					if onLost != nil {
+						onLost(err)
+					}
+					return
+				}
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(done)
+			<-stopped
+		})
+	}
+}
+
+// Run 在持有锁期间执行 fn，锁被占用时返回 ErrNotAcquired 且不执行 fn
+// 执行期间自动续约；租约丢失时取消传给 fn 的 ctx
+func Run(ctx context.Context, locker Locker, key string, ttl time.Duration, fn func(ctx context.Context) error) error {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	lease, err := locker.TryAcquire(ctx, key, ttl)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	stop := KeepAlive(lease, ttl, func(err error) { cancel(err) })
+	defer func() {
+		stop()
+		// 调用方的 ctx 可能已取消，释放使用独立的 ctx
+		releaseCtx, releaseCancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+		defer releaseCancel()
+		_ = lease.Release(releaseCtx)
+	}()
+
+	return fn(ctx)
+}
+
+// newToken 生成持有者令牌，用于确认释放和续约的是自己的锁
+func newToken() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// MemoryLocker 进程内锁
+type MemoryLocker struct {
+	mu    sync.Mutex
+	locks map[string]memoryEntry
+}
+
+type memoryEntry struct {
+	token   string
+	expires time.Time
+}
+
+// NewMemoryLocker 创建进程内锁
+func NewMemoryLocker() *MemoryLocker {
+	return &MemoryLocker{locks: make(map[string]memoryEntry)}
+}
+
+// TryAcquire 尝试获取锁
+func (l *MemoryLocker) TryAcquire(_ context.Context, key string, ttl time.Duration) (Lease, error) {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if entry, ok := l.locks[key]; ok && time.Now().Before(entry.expires) {
+		return nil, ErrNotAcquired
+	}
+	token := newToken()
+	l.locks[key] = memoryEntry{token: token, expires: time.Now().Add(ttl)}
+	return &memoryLease{locker: l, key: key, token: token}, nil
+}
+
+type memoryLease struct {
+	locker *MemoryLocker
+	key    string
+	token  string
+}
+
+func (m *memoryLease) Key() string { return m.key }
+
+func (m *memoryLease) Refresh(_ context.Context, ttl time.Duration) error {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	m.locker.mu.Lock()
+	defer m.locker.mu.Unlock()
+	entry, ok := m.locker.locks[m.key]
+	if !ok || entry.token != m.token || time.Now().After(entry.expires) {
+		return ErrLeaseLost
+	}
+	entry.expires = time.Now().Add(ttl)
+	m.locker.locks[m.key] = entry
+	return nil
+}
+
+func (m *memoryLease) Release(context.Context) error {
+	m.locker.mu.Lock()
+	defer m.locker.mu.Unlock()
+	if entry, ok := m.locker.locks[m.key]; ok && entry.token == m.token {
+		delete(m.locker.locks, m.key)
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func testLocker(t *testing.T, l Locker) {
+	t.Helper()
+	ctx := context.Background()
+
+	lease, err := l.TryAcquire(ctx, "scheduler:nightly", time.Minute)
+	if err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+	if _, err := l.TryAcquire(ctx, "scheduler:nightly", time.Minute); !errors.Is(err, ErrNotAcquired) {
+		t.Fatalf("second acquire err = %v, want ErrNotAcquired", err)
+	}
+	// 不同的键互不影响
+	other, err := l.TryAcquire(ctx, "session:abc", time.Minute)
+	if err != nil {
+		t.Fatalf("acquire other key: %v", err)
+	}
+	defer other.Release(ctx)
+
+	if err := lease.Refresh(ctx, time.Minute); err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	if err := lease.Release(ctx); err != nil {
+		t.Fatalf("release: %v", err)
+	}
+	if err := lease.Release(ctx); err != nil {
+		t.Fatalf("second release: %v", err)
+	}
+	if err := lease.Refresh(ctx, time.Minute); !errors.Is(err, ErrLeaseLost) {
+		t.Fatalf("refresh after release err = %v, want ErrLeaseLost", err)
+	}
+
+	again, err := l.TryAcquire(ctx, "scheduler:nightly", time.Minute)
+	if err != nil {
+		t.Fatalf("acquire after release: %v", err)
+	}
+	again.Release(ctx)
+}
+
+func TestMemoryLocker(t *testing.T) {
+	testLocker(t, NewMemoryLocker())
+}
+
+func TestFileLocker(t *testing.T) {
+	dir := t.TempDir()
+	l, err := NewFileLocker(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testLocker(t, l)
+
+	// 两个 FileLocker 指向同一目录时相当于两个实例
+	lease, err := l.TryAcquire(context.Background(), "agent:a/b", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lease.Release(context.Background())
+	other, _ := NewFileLocker(dir)
+	if _, err := other.TryAcquire(context.Background(), "agent:a/b", 0); !errors.Is(err, ErrNotAcquired) {
+		t.Fatalf("err = %v, want ErrNotAcquired", err)
+	}
+}
+
+func TestMemoryLockerExpires(t *testing.T) {
+	l := NewMemoryLocker()
+	lease, err := l.TryAcquire(context.Background(), "k", 10*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	// 租约过期后其他持有者可以获取，原持有者续约失败
+	if _, err := l.TryAcquire(context.Background(), "k", time.Minute); err != nil {
+		t.Fatalf("acquire expired lock: %v", err)
+	}
+	if err := lease.Refresh(context.Background(), time.Minute); !errors.Is(err, ErrLeaseLost) {
+		t.Fatalf("refresh err = %v, want ErrLeaseLost", err)
+	}
+}
+
+func TestRun(t *testing.T) {
+	l := NewMemoryLocker()
+	ctx := context.Background()
+
+	var ran atomic.Int32
+	err := Run(ctx, l, "job", 30*time.Millisecond, func(ctx context.Context) error {
+		// 执行时间超过 ttl，期间自动续约，其他持有者无法获取
+		time.Sleep(60 * time.Millisecond)
+		if err := Run(ctx, l, "job", time.Minute, func(context.Context) error { return nil }); !errors.Is(err, ErrNotAcquired) {
+			t.Errorf("nested run err = %v, want ErrNotAcquired", err)
+		}
+		ran.Add(1)
+		return nil
+	})
+	if err != nil || ran.Load() != 1 {
+		t.Fatalf("run err = %v, ran = %d", err, ran.Load())
+	}
+
+	// 执行结束后锁已释放
+	if err := Run(ctx, l, "job", time.Minute, func(context.Context) error { return nil }); err != nil {
+		t.Fatalf("run after release: %v", err)
+	}
+}
+
+func TestAcquireWaits(t *testing.T) {
+	l := NewMemoryLocker()
+	lease, _ := l.TryAcquire(context.Background(), "k", time.Minute)
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		lease.Release(context.Background())
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, err := Acquire(ctx, l, "k", time.Minute, 5*time.Millisecond); err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := Acquire(ctx, l, "k", time.Minute, 5*time.Millisecond); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want deadline exceeded", err)
+	}
+}
+
+func TestSQLLockKeys(t *testing.T) {
+	long := "session:" + string(make([]byte, 100))
+	if name := mysqlLockName(long); len(name) > 64 {
+		t.Errorf("mysql lock name too long: %d", len(name))
+	}
+	if mysqlLockName("agent:1") != "agent:1" {
+		t.Error("short mysql lock names should be kept")
+	}
+	if postgresLockID("agent:1") != postgresLockID("agent:1") || postgresLockID("agent:1") == postgresLockID("agent:2") {
+		t.Error("postgres lock ids should be stable and distinct")
+	}
+	if _, err := NewSQLLocker(nil, SQLDialectMySQL); err == nil {
+		t.Error("expected error for nil db")
+	}
+}
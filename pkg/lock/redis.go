@@ -0,0 +1,83 @@
+package lock
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// 只有令牌匹配时才续约或删除，避免误操作其他持有者在租约过期后获取的锁
+var (
+	redisRefreshScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`)
+	redisReleaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+)
+
+// RedisLocker 基于 Redis 的锁
+// 使用 SET NX PX 获取，持有者需要在 ttl 内续约，进程崩溃后锁在 ttl 到期时自动释放
+type RedisLocker struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+// NewRedisLocker 创建 Redis 锁，prefix 为键前缀 (默认 "aster:lock:")
+func NewRedisLocker(client redis.UniversalClient, prefix string) *RedisLocker {
+	if prefix == "" {
+		prefix = "aster:lock:"
+	}
+	return &RedisLocker{client: client, prefix: prefix}
+}
+
+// TryAcquire 尝试获取锁
+func (l *RedisLocker) TryAcquire(ctx context.Context, key string, ttl time.Duration) (Lease, error) {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	token := newToken()
+	ok, err := l.client.SetNX(ctx, l.prefix+key, token, ttl).Result()
+	if err != nil {
+		return nil, fmt.Errorf("lock %s: %w", key, err)
+	}
+	if !ok {
+		return nil, ErrNotAcquired
+	}
+	return &redisLease{locker: l, key: key, token: token}, nil
+}
+
+type redisLease struct {
+	locker *RedisLocker
+	key    string
+	token  string
+}
+
+func (r *redisLease) Key() string { return r.key }
+
+func (r *redisLease) Refresh(ctx context.Context, ttl time.Duration) error {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	n, err := redisRefreshScript.Run(ctx, r.locker.client, []string{r.locker.prefix + r.key}, r.token, ttl.Milliseconds()).Int()
+	if err != nil {
+		return fmt.Errorf("refresh %s: %w", r.key, err)
+	}
+	if n == 0 {
+		return ErrLeaseLost
+	}
+	return nil
+}
+
+func (r *redisLease) Release(ctx context.Context) error {
+	if err := redisReleaseScript.Run(ctx, r.locker.client, []string{r.locker.prefix + r.key}, r.token).Err(); err != nil {
+		return fmt.Errorf("release %s: %w", r.key, err)
+	}
+	return nil
+}
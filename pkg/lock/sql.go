@@ -0,0 +1,125 @@
+package lock
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// SQLDialect 咨询锁的数据库方言
+type SQLDialect string
+
+const (
+	SQLDialectMySQL    SQLDialect = "mysql"
+	SQLDialectPostgres SQLDialect = "postgres"
+)
+
+// SQLLocker 基于数据库会话级咨询锁的锁
+// MySQL 使用 GET_LOCK/RELEASE_LOCK，PostgreSQL 使用 pg_try_advisory_lock/pg_advisory_unlock。
+// 锁绑定到一个专用连接，持有期间该连接不会归还连接池；连接断开时数据库自动释放锁
+type SQLLocker struct {
+	db      *sql.DB
+	dialect SQLDialect
+}
+
+// NewSQLLocker 创建数据库咨询锁
+func NewSQLLocker(db *sql.DB, dialect SQLDialect) (*SQLLocker, error) {
+	if db == nil {
+		return nil, fmt.Errorf("sql locker requires a database")
+	}
+	switch dialect {
+	case SQLDialectMySQL, SQLDialectPostgres:
+	default:
+		return nil, fmt.Errorf("unsupported sql lock dialect: %s", dialect)
+	}
+	return &SQLLocker{db: db, dialect: dialect}, nil
+}
+
+// TryAcquire 尝试获取锁
+func (l *SQLLocker) TryAcquire(ctx context.Context, key string, _ time.Duration) (Lease, error) {
+	conn, err := l.db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("lock connection: %w", err)
+	}
+
+	var held bool
+	switch l.dialect {
+	case SQLDialectMySQL:
+		// GET_LOCK 超时为 0 表示不等待；返回 NULL 表示出错
+		var result sql.NullInt64
+		err = conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, 0)", mysqlLockName(key)).Scan(&result)
+		held = result.Valid && result.Int64 == 1
+	case SQLDialectPostgres:
+		err = conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", postgresLockID(key)).Scan(&held)
+	}
+	if err != nil || !held {
+		_ = conn.Close()
+		if err != nil {
+			return nil, fmt.Errorf("lock %s: %w", key, err)
+		}
+		return nil, ErrNotAcquired
+	}
+	return &sqlLease{locker: l, key: key, conn: conn}, nil
+}
+
+// mysqlLockName MySQL 锁名最长 64 字符，超长的键使用哈希
+func mysqlLockName(key string) string {
+	if len(key) <= 64 {
+		return key
+	}
+	sum := sha256.Sum256([]byte(key))
+	return "aster:" + hex.EncodeToString(sum[:16])
+}
+
+// postgresLockID PostgreSQL 咨询锁使用 64 位整数，由键的哈希得到
+func postgresLockID(key string) int64 {
+	sum := sha256.Sum256([]byte(key))
+	return int64(binary.BigEndian.Uint64(sum[:8]))
+}
+
+type sqlLease struct {
+	mu     sync.Mutex
+	locker *SQLLocker
+	key    string
+	conn   *sql.Conn
+}
+
+func (s *sqlLease) Key() string { return s.key }
+
+// Refresh 锁随连接存活，续约只检查连接是否仍然可用
+func (s *sqlLease) Refresh(ctx context.Context, _ time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		return ErrLeaseLost
+	}
+	if err := s.conn.PingContext(ctx); err != nil {
+		return fmt.Errorf("%w: %v", ErrLeaseLost, err)
+	}
+	return nil
+}
+
+func (s *sqlLease) Release(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		return nil
+	}
+	var err error
+	switch s.locker.dialect {
+	case SQLDialectMySQL:
+		_, err = s.conn.ExecContext(ctx, "SELECT RELEASE_LOCK(?)", mysqlLockName(s.key))
+	case SQLDialectPostgres:
+		_, err = s.conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", postgresLockID(s.key))
+	}
+	if cerr := s.conn.Close(); err == nil {
+		err = cerr
+	}
+	s.conn = nil
+	return err
+}
@@ -0,0 +1,132 @@
+package logging
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// LogFilter 日志流过滤条件
+type LogFilter struct {
+	// Level 最低级别，为空时不过滤
+	Level Level
+	// Components 组件名（不区分大小写），为空时不过滤
+	Components []string
+}
+
+// Match 记录是否满足过滤条件
+func (f LogFilter) Match(rec *LogRecord) bool {
+	if f.Level != "" && levelOrder[rec.Level] < levelOrder[f.Level] {
+		return false
+	}
+	if len(f.Components) == 0 {
+		return true
+	}
+	component, _ := rec.Fields["component"].(string)
+	return slices.ContainsFunc(f.Components, func(c string) bool {
+		return strings.EqualFold(c, component)
+	})
+}
+
+// Broadcaster 将日志记录广播给订阅者（如日志流 API），并保留最近的记录。
+// 只能收到 Logger 级别过滤后的记录；订阅者消费过慢时丢弃记录，不阻塞日志写入
+type Broadcaster struct {
+	mu      sync.Mutex
+	history []*LogRecord // 环形缓冲
+	next    int
+	full    bool
+	subs    map[int]*logSubscriber
+	nextID  int
+}
+
+type logSubscriber struct {
+	filter LogFilter
+	ch     chan *LogRecord
+}
+
+// NewBroadcaster 创建广播 transport，history 为保留的最近记录数
+func NewBroadcaster(history int) *Broadcaster {
+	if history <= 0 {
+		history = 500
+	}
+	return &Broadcaster{
+		history: make([]*LogRecord, history),
+		subs:    make(map[int]*logSubscriber),
+	}
+}
+
+func (b *Broadcaster) Name() string { return "broadcast" }
+
+func (b *Broadcaster) Log(ctx context.Context, rec *LogRecord) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.history[b.next] = rec
+	b.next = (b.next + 1) % len(b.history)
+	if b.next == 0 {
+		b.full = true
+	}
+	for _, sub := range b.subs {
+		if !sub.filter.Match(rec) {
+			continue
+		}
+		select {
+		case sub.ch <- rec:
+		default:
+		}
+	}
+	return nil
+}
+
+func (b *Broadcaster) Flush(ctx context.Context) error { return nil }
+
+// Recent 返回满足条件的最近 limit 条记录，按时间从旧到新
+func (b *Broadcaster) Recent(filter LogFilter, limit int) []*LogRecord {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ordered := b.history[:b.next]
+	if b.full {
+		ordered = append(slices.Clone(b.history[b.next:]), b.history[:b.next]...)
+	}
+	var out []*LogRecord
+	for i := len(ordered) - 1; i >= 0 && len(out) < limit; i-- {
+		if filter.Match(ordered[i]) {
+			out = append(out, ordered[i])
+		}
+	}
+	slices.Reverse(out)
+	return out
+}
+
+// Subscribe 订阅满足条件的新记录，返回的 cancel 取消订阅并关闭通道
+func (b *Broadcaster) Subscribe(filter LogFilter, buffer int) (<-chan *LogRecord, func()) {
+	if buffer <= 0 {
+		buffer = 256
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	id := b.nextID
+	b.nextID++
+	sub := &logSubscriber{filter: filter, ch: make(chan *LogRecord, buffer)}
+	b.subs[id] = sub
+
+	var once sync.Once
+	return sub.ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			delete(b.subs, id)
+			close(sub.ch)
+		})
+	}
+}
+
+// Subscribers 返回当前订阅者数量
+func (b *Broadcaster) Subscribers() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.subs)
+}
@@ -0,0 +1,60 @@
+package logging
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// Config Logger 配置
+type Config struct {
+	// Level 级别描述，如 "info" 或 "info,Dashboard=debug,AgentProcessor=warn"
+	Level string
+	// Format 输出格式: json、console
+	Format string
+	// Output 输出目标: stdout、stderr 或文件路径，为空时为 stdout
+	Output string
+	// Rotation 输出到文件时的轮转配置
+	Rotation RotationConfig
+}
+
+// Configure 按配置替换 Logger 的级别和输出，原有可关闭的 transport 会被关闭。
+// 已通过 ForComponent 创建的组件 Logger 引用同一实例，立即生效
+func (l *Logger) Configure(cfg Config) error {
+	level, overrides, err := ParseLevelSpec(cfg.Level)
+	if err != nil {
+		return err
+	}
+	enc, err := NewEncoder(cfg.Format)
+	if err != nil {
+		return err
+	}
+
+	var transport Transport
+	switch cfg.Output {
+	case "", "stdout":
+		transport = NewStdoutTransportWithEncoder(enc)
+	case "stderr":
+		t := NewStdoutTransportWithEncoder(enc)
+		t.out = os.Stderr
+		transport = t
+	default:
+		t, err := NewRotatingFileTransport(cfg.Output, cfg.Rotation, enc)
+		if err != nil {
+			return fmt.Errorf("configure log output: %w", err)
+		}
+		transport = t
+	}
+
+	l.mu.Lock()
+	old := l.transports
+	l.level, l.overrides, l.transports = level, overrides, []Transport{transport}
+	l.mu.Unlock()
+
+	for _, t := range old {
+		if c, ok := t.(io.Closer); ok {
+			_ = c.Close()
+		}
+	}
+	return nil
+}
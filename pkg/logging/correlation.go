@@ -0,0 +1,38 @@
+package logging
+
+import "context"
+
+// Correlation 关联 ID，写入 context 后该 context 下的日志都会带上，
+// 用于把日志行与事件中的会话、Dashboard 追踪和 HTTP 请求对应起来
+type Correlation struct {
+	SessionID string
+	TraceID   string
+	RequestID string
+}
+
+type correlationKey struct{}
+
+// WithCorrelation 返回携带关联 ID 的 context，空字段沿用 ctx 中已有的值
+func WithCorrelation(ctx context.Context, c Correlation) context.Context {
+	if prev, ok := CorrelationFrom(ctx); ok {
+		if c.SessionID == "" {
+			c.SessionID = prev.SessionID
+		}
+		if c.TraceID == "" {
+			c.TraceID = prev.TraceID
+		}
+		if c.RequestID == "" {
+			c.RequestID = prev.RequestID
+		}
+	}
+	return context.WithValue(ctx, correlationKey{}, c)
+}
+
+// CorrelationFrom 读取 context 中的关联 ID
+func CorrelationFrom(ctx context.Context) (Correlation, bool) {
+	if ctx == nil {
+		return Correlation{}, false
+	}
+	c, ok := ctx.Value(correlationKey{}).(Correlation)
+	return c, ok
+}
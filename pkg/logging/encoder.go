@@ -0,0 +1,145 @@
+package logging
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 日志输出格式
+const (
+	FormatJSON    = "json"
+	FormatConsole = "console"
+)
+
+// Encoder 将日志记录编码写入 w，每条记录一行
+type Encoder interface {
+	Encode(w io.Writer, rec *LogRecord) error
+}
+
+// NewEncoder 按格式名创建编码器，"text" 等同于 console，空字符串为 json
+func NewEncoder(format string) (Encoder, error) {
+	switch strings.ToLower(format) {
+	case "", FormatJSON:
+		return JSONEncoder{}, nil
+	case FormatConsole, "text":
+		return ConsoleEncoder{}, nil
+	default:
+		return nil, fmt.Errorf("unknown log format %q, want json or console", format)
+	}
+}
+
+// JSONEncoder JSON 行格式，便于日志平台采集
+type JSONEncoder struct{}
+
+// Encode 实现 Encoder
+func (JSONEncoder) Encode(w io.Writer, rec *LogRecord) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// ConsoleEncoder 便于人阅读的单行格式:
+// 2025-01-10T08:00:00.000Z INFO  [Dashboard] message trace_id=agt-1-3 key=value
+type ConsoleEncoder struct{}
+
+// Encode 实现 Encoder
+func (ConsoleEncoder) Encode(w io.Writer, rec *LogRecord) error {
+	var b strings.Builder
+	b.WriteString(rec.Timestamp.UTC().Format("2006-01-02T15:04:05.000Z"))
+	fmt.Fprintf(&b, " %-5s ", strings.ToUpper(string(rec.Level)))
+	if component, ok := rec.Fields["component"].(string); ok && component != "" {
+		b.WriteString("[" + component + "] ")
+	}
+	b.WriteString(rec.Message)
+
+	writePair := func(key string, value any) {
+		b.WriteString(" " + key + "=")
+		s := fmt.Sprint(value)
+		switch v := value.(type) {
+		case time.Duration:
+			s = v.String()
+		case error:
+			s = v.Error()
+		}
+		if strings.ContainsAny(s, " \t\n\"=") {
+			s = fmt.Sprintf("%q", s)
+		}
+		b.WriteString(s)
+	}
+	for _, id := range []struct{ key, value string }{
+		{"session_id", rec.SessionID}, {"trace_id", rec.TraceID}, {"request_id", rec.RequestID},
+		{"correlation_id", rec.CorrelationID}, {"user_id", rec.UserID}, {"tenant_id", rec.TenantID},
+	} {
+		if id.value != "" {
+			writePair(id.key, id.value)
+		}
+	}
+
+	keys := make([]string, 0, len(rec.Fields))
+	for k := range rec.Fields {
+		if k != "component" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		writePair(k, rec.Fields[k])
+	}
+	b.WriteByte('\n')
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// ParseLevel 解析日志级别，"warning" 等同于 warn
+func ParseLevel(s string) (Level, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "debug":
+		return LevelDebug, nil
+	case "", "info":
+		return LevelInfo, nil
+	case "warn", "warning":
+		return LevelWarn, nil
+	case "error":
+		return LevelError, nil
+	default:
+		return "", fmt.Errorf("unknown log level %q, want debug, info, warn or error", s)
+	}
+}
+
+// ParseLevelSpec 解析级别描述 "info,Dashboard=debug,AgentProcessor=warn"：
+// 不带 "=" 的项为全局级别（缺省 info），其余为组件级别覆盖
+func ParseLevelSpec(spec string) (Level, map[string]Level, error) {
+	level := LevelInfo
+	overrides := make(map[string]Level)
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		component, value, hasComponent := strings.Cut(part, "=")
+		lv, err := ParseLevel(value)
+		if !hasComponent {
+			lv, err = ParseLevel(component)
+		}
+		if err != nil {
+			return "", nil, err
+		}
+		if !hasComponent {
+			level = lv
+			continue
+		}
+		component = strings.TrimSpace(component)
+		if component == "" {
+			return "", nil, fmt.Errorf("missing component name in %q", part)
+		}
+		overrides[strings.ToLower(component)] = lv
+	}
+	return level, overrides, nil
+}
@@ -2,11 +2,15 @@ package logging
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
+	"io"
 	"os"
+	"slices"
+	"strings"
 	"sync"
 	"time"
+
+	"github.com/astercloud/aster/pkg/requestctx"
 )
 
 // Level 日志级别
@@ -19,12 +23,28 @@ const (
 	LevelError Level = "error"
 )
 
+// levelOrder 级别优先级: debug < info < warn < error
+var levelOrder = map[Level]int{
+	LevelDebug: 1,
+	LevelInfo:  2,
+	LevelWarn:  3,
+	LevelError: 4,
+}
+
 // LogRecord 标准化日志记录结构
 type LogRecord struct {
-	Timestamp time.Time      `json:"ts"`
-	Level     Level          `json:"level"`
-	Message   string         `json:"message"`
-	Fields    map[string]any `json:"fields,omitempty"`
+	Timestamp time.Time `json:"ts"`
+	Level     Level     `json:"level"`
+	Message   string    `json:"message"`
+	// 关联 ID，来自 context（见 WithCorrelation）
+	SessionID string `json:"session_id,omitempty"`
+	TraceID   string `json:"trace_id,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+	// 请求元数据，来自 context（见 requestctx.With）
+	UserID        string         `json:"user_id,omitempty"`
+	TenantID      string         `json:"tenant_id,omitempty"`
+	CorrelationID string         `json:"correlation_id,omitempty"`
+	Fields        map[string]any `json:"fields,omitempty"`
 }
 
 // Transport 日志输出通道接口
@@ -42,6 +62,7 @@ type Transport interface {
 type Logger struct {
 	mu         sync.RWMutex
 	level      Level
+	overrides  map[string]Level // key: 小写组件名
 	transports []Transport
 }
 
@@ -60,6 +81,29 @@ func (l *Logger) SetLevel(level Level) {
 	l.level = level
 }
 
+// SetComponentLevel 设置单个组件的日志级别，覆盖全局级别（组件名不区分大小写）
+func (l *Logger) SetComponentLevel(component string, level Level) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.overrides == nil {
+		l.overrides = make(map[string]Level)
+	}
+	l.overrides[strings.ToLower(component)] = level
+}
+
+// SetLevelSpec 按级别描述设置全局级别和组件覆盖，如 "info,Dashboard=debug,AgentProcessor=warn"
+func (l *Logger) SetLevelSpec(spec string) error {
+	level, overrides, err := ParseLevelSpec(spec)
+	if err != nil {
+		return err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.level = level
+	l.overrides = overrides
+	return nil
+}
+
 // AddTransport 动态添加 transport
 func (l *Logger) AddTransport(t Transport) {
 	l.mu.Lock()
@@ -67,9 +111,19 @@ func (l *Logger) AddTransport(t Transport) {
 	l.transports = append(l.transports, t)
 }
 
-// log 内部通用日志函数
-func (l *Logger) log(ctx context.Context, level Level, msg string, fields map[string]any) {
-	if !l.enabled(level) {
+// RemoveTransport 移除 transport
+func (l *Logger) RemoveTransport(t Transport) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.transports = slices.DeleteFunc(l.transports, func(existing Transport) bool { return existing == t })
+}
+
+// log 内部通用日志函数，component 为空时只按全局级别过滤
+func (l *Logger) log(ctx context.Context, component string, level Level, msg string, fields map[string]any) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	if !l.enabledLocked(component, level) {
 		return
 	}
 
@@ -79,46 +133,53 @@ func (l *Logger) log(ctx context.Context, level Level, msg string, fields map[st
 		Message:   msg,
 		Fields:    fields,
 	}
-
-	l.mu.RLock()
-	defer l.mu.RUnlock()
+	if c, ok := CorrelationFrom(ctx); ok {
+		rec.SessionID, rec.TraceID, rec.RequestID = c.SessionID, c.TraceID, c.RequestID
+	}
+	if m, ok := requestctx.From(ctx); ok {
+		rec.UserID, rec.TenantID, rec.CorrelationID = m.UserID, m.TenantID, m.CorrelationID
+	}
 
 	for _, t := range l.transports {
 		_ = t.Log(ctx, rec)
 	}
 }
 
-func (l *Logger) enabled(level Level) bool {
-	// 简单的级别优先级比较: debug < info < warn < error
-	order := map[Level]int{
-		LevelDebug: 1,
-		LevelInfo:  2,
-		LevelWarn:  3,
-		LevelError: 4,
-	}
+// Enabled 组件在该级别的日志是否会被输出
+func (l *Logger) Enabled(component string, level Level) bool {
 	l.mu.RLock()
 	defer l.mu.RUnlock()
-	return order[level] >= order[l.level]
+	return l.enabledLocked(component, level)
+}
+
+func (l *Logger) enabledLocked(component string, level Level) bool {
+	threshold := l.level
+	if component != "" {
+		if override, ok := l.overrides[strings.ToLower(component)]; ok {
+			threshold = override
+		}
+	}
+	return levelOrder[level] >= levelOrder[threshold]
 }
 
 // Debug 记录调试日志
 func (l *Logger) Debug(ctx context.Context, msg string, fields map[string]any) {
-	l.log(ctx, LevelDebug, msg, fields)
+	l.log(ctx, "", LevelDebug, msg, fields)
 }
 
 // Info 记录信息日志
 func (l *Logger) Info(ctx context.Context, msg string, fields map[string]any) {
-	l.log(ctx, LevelInfo, msg, fields)
+	l.log(ctx, "", LevelInfo, msg, fields)
 }
 
 // Warn 记录警告日志
 func (l *Logger) Warn(ctx context.Context, msg string, fields map[string]any) {
-	l.log(ctx, LevelWarn, msg, fields)
+	l.log(ctx, "", LevelWarn, msg, fields)
 }
 
 // Error 记录错误日志
 func (l *Logger) Error(ctx context.Context, msg string, fields map[string]any) {
-	l.log(ctx, LevelError, msg, fields)
+	l.log(ctx, "", LevelError, msg, fields)
 }
 
 // Flush 刷新所有 transports
@@ -134,17 +195,21 @@ func (l *Logger) Flush(ctx context.Context) {
 // Stdout Transport
 // =========================
 
-// StdoutTransport 将日志记录以 JSON 行的形式写到 stdout
+// StdoutTransport 将日志记录写到 stdout，默认为 JSON 行
 type StdoutTransport struct {
 	mu      sync.Mutex
-	encoder *json.Encoder
+	out     io.Writer
+	encoder Encoder
 }
 
-// NewStdoutTransport 创建 StdoutTransport
+// NewStdoutTransport 创建 JSON 行格式的 StdoutTransport
 func NewStdoutTransport() *StdoutTransport {
-	return &StdoutTransport{
-		encoder: json.NewEncoder(os.Stdout),
-	}
+	return NewStdoutTransportWithEncoder(JSONEncoder{})
+}
+
+// NewStdoutTransportWithEncoder 创建使用指定编码器的 StdoutTransport
+func NewStdoutTransportWithEncoder(enc Encoder) *StdoutTransport {
+	return &StdoutTransport{out: os.Stdout, encoder: enc}
 }
 
 func (t *StdoutTransport) Name() string { return "stdout" }
@@ -152,7 +217,7 @@ func (t *StdoutTransport) Name() string { return "stdout" }
 func (t *StdoutTransport) Log(ctx context.Context, rec *LogRecord) error {
 	t.mu.Lock()
 	defer t.mu.Unlock()
-	return t.encoder.Encode(rec)
+	return t.encoder.Encode(t.out, rec)
 }
 
 func (t *StdoutTransport) Flush(ctx context.Context) error {
@@ -164,11 +229,11 @@ func (t *StdoutTransport) Flush(ctx context.Context) error {
 // File Transport
 // =========================
 
-// FileTransport 将日志记录以 JSON 行写入到指定文件
+// FileTransport 将日志记录以 JSON 行写入到指定文件，不做轮转（见 RotatingFileTransport）
 type FileTransport struct {
 	mu      sync.Mutex
 	file    *os.File
-	encoder *json.Encoder
+	encoder Encoder
 }
 
 // NewFileTransport 创建 FileTransport, path 为日志文件路径
@@ -180,7 +245,7 @@ func NewFileTransport(path string) (*FileTransport, error) {
 
 	return &FileTransport{
 		file:    f,
-		encoder: json.NewEncoder(f),
+		encoder: JSONEncoder{},
 	}, nil
 }
 
@@ -189,7 +254,7 @@ func (t *FileTransport) Name() string { return "file" }
 func (t *FileTransport) Log(ctx context.Context, rec *LogRecord) error {
 	t.mu.Lock()
 	defer t.mu.Unlock()
-	return t.encoder.Encode(rec)
+	return t.encoder.Encode(t.file, rec)
 }
 
 func (t *FileTransport) Flush(ctx context.Context) error {
@@ -268,24 +333,27 @@ func (c *ComponentLogger) addComponent(fields map[string]any) map[string]any {
 }
 
 func (c *ComponentLogger) Debug(ctx context.Context, msg string, fields map[string]any) {
-	c.logger.Debug(ctx, msg, c.addComponent(fields))
+	c.logger.log(ctx, c.component, LevelDebug, msg, c.addComponent(fields))
 }
 
 func (c *ComponentLogger) Info(ctx context.Context, msg string, fields map[string]any) {
-	c.logger.Info(ctx, msg, c.addComponent(fields))
+	c.logger.log(ctx, c.component, LevelInfo, msg, c.addComponent(fields))
 }
 
 func (c *ComponentLogger) Warn(ctx context.Context, msg string, fields map[string]any) {
-	c.logger.Warn(ctx, msg, c.addComponent(fields))
+	c.logger.log(ctx, c.component, LevelWarn, msg, c.addComponent(fields))
 }
 
 func (c *ComponentLogger) Error(ctx context.Context, msg string, fields map[string]any) {
-	c.logger.Error(ctx, msg, c.addComponent(fields))
+	c.logger.log(ctx, c.component, LevelError, msg, c.addComponent(fields))
 }
 
 // Printf 兼容 log.Printf 的接口，便于迁移
 // 用法: logger.Printf("message %s", arg) 替代 log.Printf("[Component] message %s", arg)
 func (c *ComponentLogger) Printf(format string, args ...any) {
+	if !c.logger.Enabled(c.component, LevelInfo) {
+		return
+	}
 	msg := fmt.Sprintf(format, args...)
-	c.logger.Info(context.Background(), msg, map[string]any{"component": c.component})
+	c.logger.log(context.Background(), c.component, LevelInfo, msg, map[string]any{"component": c.component})
 }
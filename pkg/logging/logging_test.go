@@ -0,0 +1,188 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/astercloud/aster/pkg/requestctx"
+)
+
+// bufferTransport 收集日志记录用于断言
+type bufferTransport struct {
+	records []*LogRecord
+}
+
+func (t *bufferTransport) Name() string { return "buffer" }
+func (t *bufferTransport) Log(ctx context.Context, rec *LogRecord) error {
+	t.records = append(t.records, rec)
+	return nil
+}
+func (t *bufferTransport) Flush(ctx context.Context) error { return nil }
+
+func TestLogger_ComponentLevelOverrides(t *testing.T) {
+	buf := &bufferTransport{}
+	logger := NewLogger(LevelInfo, buf)
+	if err := logger.SetLevelSpec("warn,Dashboard=debug"); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	logger.ForComponent("dashboard").Debug(ctx, "dashboard debug", nil)
+	logger.ForComponent("AgentProcessor").Info(ctx, "processor info", nil)
+	logger.ForComponent("AgentProcessor").Warn(ctx, "processor warn", nil)
+	logger.Info(ctx, "plain info", nil)
+
+	var got []string
+	for _, rec := range buf.records {
+		got = append(got, rec.Message)
+	}
+	if strings.Join(got, ",") != "dashboard debug,processor warn" {
+		t.Errorf("logged %v", got)
+	}
+
+	if _, _, err := ParseLevelSpec("info,Dashboard=verbose"); err == nil {
+		t.Error("expected error for unknown level")
+	}
+}
+
+func TestLogger_Correlation(t *testing.T) {
+	buf := &bufferTransport{}
+	logger := NewLogger(LevelInfo, buf)
+
+	ctx := WithCorrelation(context.Background(), Correlation{RequestID: "req-1"})
+	ctx = WithCorrelation(ctx, Correlation{SessionID: "agt-1", TraceID: "agt-1-3"})
+	logger.ForComponent("Agent").Info(ctx, "run started", map[string]any{"step": 1})
+
+	rec := buf.records[0]
+	if rec.SessionID != "agt-1" || rec.TraceID != "agt-1-3" || rec.RequestID != "req-1" {
+		t.Fatalf("record = %+v", rec)
+	}
+
+	var out bytes.Buffer
+	if err := (ConsoleEncoder{}).Encode(&out, rec); err != nil {
+		t.Fatal(err)
+	}
+	line := out.String()
+	if !strings.Contains(line, "INFO  [Agent] run started session_id=agt-1 trace_id=agt-1-3 request_id=req-1 step=1") {
+		t.Errorf("console line = %q", line)
+	}
+
+	out.Reset()
+	_ = (JSONEncoder{}).Encode(&out, rec)
+	var decoded map[string]any
+	if err := json.Unmarshal(out.Bytes(), &decoded); err != nil || decoded["trace_id"] != "agt-1-3" {
+		t.Errorf("json line = %s", out.String())
+	}
+}
+
+func TestLogger_RequestMetadata(t *testing.T) {
+	buf := &bufferTransport{}
+	logger := NewLogger(LevelInfo, buf)
+
+	ctx := requestctx.With(context.Background(), requestctx.Metadata{UserID: "u1", TenantID: "acme", CorrelationID: "corr-1"})
+	logger.ForComponent("Agent").Info(ctx, "tool call", nil)
+
+	rec := buf.records[0]
+	if rec.UserID != "u1" || rec.TenantID != "acme" || rec.CorrelationID != "corr-1" {
+		t.Fatalf("record = %+v", rec)
+	}
+	var out bytes.Buffer
+	_ = (ConsoleEncoder{}).Encode(&out, rec)
+	if !strings.Contains(out.String(), "tool call correlation_id=corr-1 user_id=u1 tenant_id=acme") {
+		t.Errorf("console line = %q", out.String())
+	}
+}
+
+func TestRotatingFileTransport(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "serve.log")
+	tr, err := NewRotatingFileTransport(path, RotationConfig{MaxSize: 200, MaxBackups: 2}, JSONEncoder{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = tr.Close() }()
+
+	now := time.Date(2025, 1, 10, 8, 0, 0, 0, time.UTC)
+	tr.now = func() time.Time { now = now.Add(time.Second); return now }
+
+	logger := NewLogger(LevelInfo, tr)
+	for i := range 20 {
+		logger.Info(context.Background(), "request completed", map[string]any{"i": i})
+	}
+
+	backups := tr.Backups()
+	if len(backups) != 2 {
+		t.Fatalf("backups = %v, want 2 kept", backups)
+	}
+	if !strings.HasSuffix(backups[0], ".log") || backups[0] < backups[1] {
+		t.Errorf("backups not newest first: %v", backups)
+	}
+	for _, p := range append(backups, path) {
+		info, err := os.Stat(p)
+		if err != nil || info.Size() > 200 {
+			t.Errorf("%s: size %v, err %v", p, info.Size(), err)
+		}
+	}
+}
+
+func TestLogger_Configure(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "app.log")
+	logger := NewLogger(LevelInfo, NewStdoutTransport())
+	if err := logger.Configure(Config{Level: "error,Store=info", Format: "console", Output: path}); err != nil {
+		t.Fatal(err)
+	}
+	logger.ForComponent("Store").Info(context.Background(), "store opened", nil)
+	logger.Warn(context.Background(), "dropped", nil)
+	logger.Flush(context.Background())
+
+	data, _ := os.ReadFile(path)
+	if !strings.Contains(string(data), "[Store] store opened") || strings.Contains(string(data), "dropped") {
+		t.Errorf("log file = %q", data)
+	}
+	if err := logger.Configure(Config{Format: "xml"}); err == nil {
+		t.Error("expected error for unknown format")
+	}
+}
+
+func TestBroadcaster(t *testing.T) {
+	b := NewBroadcaster(3)
+	logger := NewLogger(LevelDebug, b)
+	ctx := context.Background()
+
+	for i := range 4 {
+		logger.ForComponent("Store").Info(ctx, "write", map[string]any{"i": i})
+	}
+	logger.ForComponent("Dashboard").Error(ctx, "trace persist failed", nil)
+
+	// 只保留最近 3 条，按时间从旧到新
+	recent := b.Recent(LogFilter{}, 10)
+	if len(recent) != 3 || recent[0].Fields["i"] != 2 || recent[2].Message != "trace persist failed" {
+		t.Fatalf("recent = %+v", recent)
+	}
+	if got := b.Recent(LogFilter{Components: []string{"store"}}, 1); len(got) != 1 || got[0].Fields["i"] != 3 {
+		t.Errorf("filtered recent = %+v", got)
+	}
+
+	ch, cancel := b.Subscribe(LogFilter{Level: LevelWarn}, 4)
+	logger.ForComponent("Store").Debug(ctx, "skipped", nil)
+	logger.ForComponent("Store").Warn(ctx, "slow write", nil)
+	if rec := <-ch; rec.Message != "slow write" {
+		t.Errorf("streamed %q", rec.Message)
+	}
+	cancel()
+	if _, ok := <-ch; ok || b.Subscribers() != 0 {
+		t.Error("subscription not closed")
+	}
+
+	logger.RemoveTransport(b)
+	logger.Info(ctx, "after remove", nil)
+	if last := b.Recent(LogFilter{}, 1); last[0].Message == "after remove" {
+		t.Error("removed transport still receives records")
+	}
+}
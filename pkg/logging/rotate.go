@@ -0,0 +1,172 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RotationConfig 日志文件轮转配置
+type RotationConfig struct {
+	// MaxSize 单个文件的最大字节数，超过后轮转；0 表示不按大小轮转
+	MaxSize int64
+	// MaxBackups 保留的历史文件数；0 表示不限
+	MaxBackups int
+	// MaxAge 历史文件的最长保留时间；0 表示不限
+	MaxAge time.Duration
+}
+
+// DefaultRotationConfig 默认轮转配置: 单文件 50MB，保留 5 个历史文件、7 天
+func DefaultRotationConfig() RotationConfig {
+	return RotationConfig{
+		MaxSize:    50 << 20,
+		MaxBackups: 5,
+		MaxAge:     7 * 24 * time.Hour,
+	}
+}
+
+// backupTimeFormat 历史文件名中的时间格式: serve-20250110T080000.000.log
+const backupTimeFormat = "20060102T150405.000"
+
+// RotatingFileTransport 写入文件并按大小轮转，历史文件按数量和时间清理
+type RotatingFileTransport struct {
+	mu      sync.Mutex
+	path    string
+	cfg     RotationConfig
+	encoder Encoder
+	file    *os.File
+	size    int64
+	buf     bytes.Buffer
+	now     func() time.Time
+}
+
+// NewRotatingFileTransport 创建轮转文件 transport，目录不存在时自动创建
+func NewRotatingFileTransport(path string, cfg RotationConfig, enc Encoder) (*RotatingFileTransport, error) {
+	if enc == nil {
+		enc = JSONEncoder{}
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("create log dir: %w", err)
+	}
+	t := &RotatingFileTransport{path: path, cfg: cfg, encoder: enc, now: time.Now}
+	if err := t.open(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (t *RotatingFileTransport) Name() string { return "rotating_file" }
+
+func (t *RotatingFileTransport) Log(ctx context.Context, rec *LogRecord) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.buf.Reset()
+	if err := t.encoder.Encode(&t.buf, rec); err != nil {
+		return err
+	}
+	if t.cfg.MaxSize > 0 && t.size > 0 && t.size+int64(t.buf.Len()) > t.cfg.MaxSize {
+		if err := t.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := t.file.Write(t.buf.Bytes())
+	t.size += int64(n)
+	return err
+}
+
+func (t *RotatingFileTransport) Flush(ctx context.Context) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.file.Sync()
+}
+
+// Close 关闭当前文件
+func (t *RotatingFileTransport) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.file.Close()
+}
+
+func (t *RotatingFileTransport) open() error {
+	f, err := os.OpenFile(t.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("open log file: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return fmt.Errorf("stat log file: %w", err)
+	}
+	t.file, t.size = f, info.Size()
+	return nil
+}
+
+// rotate 将当前文件重命名为带时间戳的历史文件，打开新文件并清理过期历史
+func (t *RotatingFileTransport) rotate() error {
+	if err := t.file.Close(); err != nil {
+		return err
+	}
+	ext := filepath.Ext(t.path)
+	backup := fmt.Sprintf("%s-%s%s", strings.TrimSuffix(t.path, ext), t.now().UTC().Format(backupTimeFormat), ext)
+	if err := os.Rename(t.path, backup); err != nil {
+		return fmt.Errorf("rotate log file: %w", err)
+	}
+	if err := t.open(); err != nil {
+		return err
+	}
+	t.prune()
+	return nil
+}
+
+// prune 按 MaxBackups、MaxAge 删除历史文件
+func (t *RotatingFileTransport) prune() {
+	backups := t.Backups()
+	cutoff := time.Time{}
+	if t.cfg.MaxAge > 0 {
+		cutoff = t.now().Add(-t.cfg.MaxAge)
+	}
+	// Backups 按时间从新到旧排列
+	for i, path := range backups {
+		expired := t.cfg.MaxBackups > 0 && i >= t.cfg.MaxBackups
+		if !expired && !cutoff.IsZero() {
+			if info, err := os.Stat(path); err == nil && info.ModTime().Before(cutoff) {
+				expired = true
+			}
+		}
+		if expired {
+			_ = os.Remove(path)
+		}
+	}
+}
+
+// Backups 返回历史文件路径，最新的在前
+func (t *RotatingFileTransport) Backups() []string {
+	ext := filepath.Ext(t.path)
+	prefix := strings.TrimSuffix(filepath.Base(t.path), ext) + "-"
+	entries, err := os.ReadDir(filepath.Dir(t.path))
+	if err != nil {
+		return nil
+	}
+	var backups []string
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, ext) {
+			continue
+		}
+		stamp := strings.TrimSuffix(strings.TrimPrefix(name, prefix), ext)
+		if _, err := time.Parse(backupTimeFormat, stamp); err != nil {
+			continue
+		}
+		backups = append(backups, filepath.Join(filepath.Dir(t.path), name))
+	}
+	// 时间戳定长，字典序即时间序
+	sort.Sort(sort.Reverse(sort.StringSlice(backups)))
+	return backups
+}
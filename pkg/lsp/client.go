@@ -0,0 +1,393 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// initializeTimeout 服务器初始化超时（首次加载大型工作区可能较慢）
+	initializeTimeout = 60 * time.Second
+	// shutdownTimeout 关闭服务器的等待时长
+	shutdownTimeout = 3 * time.Second
+	// stderrLimit 保留的服务器 stderr 字节数，用于错误信息
+	stderrLimit = 4096
+)
+
+// document 已打开的文档
+type document struct {
+	version int
+	text    string
+}
+
+// Client 单个语言服务器的客户端
+// 负责进程生命周期、文档同步和诊断缓存
+type Client struct {
+	config ServerConfig
+	root   string
+	conn   *Conn
+	cmd    *exec.Cmd
+	stdin  io.Closer
+	stderr *tailBuffer
+
+	mu       sync.Mutex
+	docs     map[string]*document // URI -> 文档
+	diags    map[string][]Diagnostic
+	diagSeq  map[string]int // URI -> 收到的诊断次数
+	diagWake chan struct{}  // 收到诊断时关闭并替换
+}
+
+// StartClient 启动语言服务器进程并完成初始化
+func StartClient(ctx context.Context, config ServerConfig, root string) (*Client, error) {
+	path, err := exec.LookPath(config.Command)
+	if err != nil {
+		return nil, fmt.Errorf("%s language server %q not found: %w", config.Name, config.Command, err)
+	}
+
+	cmd := exec.Command(path, config.Args...)
+	cmd.Dir = root
+	cmd.Env = os.Environ()
+	for k, v := range config.Env {
+		cmd.Env = append(cmd.Env, k+"="+v)
+	}
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	stderr := &tailBuffer{limit: stderrLimit}
+	cmd.Stderr = stderr
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("start %s language server: %w", config.Name, err)
+	}
+
+	c := newClient(config, root, stdout, stdin)
+	c.cmd = cmd
+	c.stdin = stdin
+	c.stderr = stderr
+	go func() {
+		_ = cmd.Wait()
+	}()
+
+	if err := c.initialize(ctx); err != nil {
+		c.kill()
+		if tail := stderr.String(); tail != "" {
+			err = fmt.Errorf("%w (stderr: %s)", err, tail)
+		}
+		return nil, fmt.Errorf("initialize %s language server: %w", config.Name, err)
+	}
+	return c, nil
+}
+
+// newClient 基于已建立的流创建客户端
+func newClient(config ServerConfig, root string, r io.Reader, w io.Writer) *Client {
+	c := &Client{
+		config:   config,
+		root:     root,
+		docs:     make(map[string]*document),
+		diags:    make(map[string][]Diagnostic),
+		diagSeq:  make(map[string]int),
+		diagWake: make(chan struct{}),
+	}
+	c.conn = NewConn(r, w, c.handle)
+	return c
+}
+
+// Name 返回服务器名称
+func (c *Client) Name() string {
+	return c.config.Name
+}
+
+// Root 返回工作区根目录
+func (c *Client) Root() string {
+	return c.root
+}
+
+// Alive 服务器连接是否仍然可用
+func (c *Client) Alive() bool {
+	return c.conn.Err() == nil
+}
+
+// initialize 发送 initialize 请求和 initialized 通知
+func (c *Client) initialize(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, initializeTimeout)
+	defer cancel()
+
+	rootURI := PathToURI(c.root)
+	params := map[string]any{
+		"processId": os.Getpid(),
+		"clientInfo": map[string]any{
+			"name": "aster",
+		},
+		"rootUri":  rootURI,
+		"rootPath": c.root,
+		"workspaceFolders": []map[string]any{
+			{"uri": rootURI, "name": filepath.Base(c.root)},
+		},
+		"capabilities": map[string]any{
+			"textDocument": map[string]any{
+				"synchronization":    map[string]any{"didSave": false, "dynamicRegistration": false},
+				"definition":         map[string]any{"linkSupport": true},
+				"references":         map[string]any{},
+				"rename":             map[string]any{"prepareSupport": false},
+				"publishDiagnostics": map[string]any{"versionSupport": true},
+			},
+			"workspace": map[string]any{
+				"workspaceFolders": true,
+				"configuration":    true,
+				"workspaceEdit":    map[string]any{"documentChanges": true},
+			},
+			"window": map[string]any{"workDoneProgress": false},
+		},
+	}
+	if c.config.InitializationOptions != nil {
+		params["initializationOptions"] = c.config.InitializationOptions
+	}
+	if err := c.conn.Call(ctx, "initialize", params, nil); err != nil {
+		return err
+	}
+	return c.conn.Notify("initialized", map[string]any{})
+}
+
+// handle 处理服务器发来的请求和通知
+func (c *Client) handle(method string, params json.RawMessage, isRequest bool) (any, error) {
+	switch method {
+	case "textDocument/publishDiagnostics":
+		var p publishDiagnosticsParams
+		if err := json.Unmarshal(params, &p); err == nil {
+			c.setDiagnostics(p.URI, p.Diagnostics)
+		}
+		return nil, nil
+	case "workspace/configuration":
+		// 按请求的配置项数量返回空配置
+		var p struct {
+			Items []json.RawMessage `json:"items"`
+		}
+		_ = json.Unmarshal(params, &p)
+		return make([]any, len(p.Items)), nil
+	case "workspace/workspaceFolders":
+		return []map[string]any{{"uri": PathToURI(c.root), "name": filepath.Base(c.root)}}, nil
+	case "window/workDoneProgress/create", "client/registerCapability", "client/unregisterCapability":
+		return nil, nil
+	case "workspace/applyEdit":
+		// 编辑只由工具显式应用
+		return map[string]any{"applied": false, "failureReason": "client does not apply server-initiated edits"}, nil
+	}
+	if isRequest {
+		return nil, &ResponseError{Code: codeMethodNotFound, Message: "method not found: " + method}
+	}
+	return nil, nil
+}
+
+// setDiagnostics 更新文档诊断并唤醒等待者
+func (c *Client) setDiagnostics(uri string, diags []Diagnostic) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(diags) == 0 {
+		delete(c.diags, uri)
+	} else {
+		c.diags[uri] = diags
+	}
+	c.diagSeq[uri]++
+	close(c.diagWake)
+	c.diagWake = make(chan struct{})
+}
+
+// Sync 将文件的磁盘内容同步到服务器，返回文件内容和是否发送了更新
+func (c *Client) Sync(path string) (string, bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", false, err
+	}
+	text := string(data)
+	uri := PathToURI(path)
+
+	c.mu.Lock()
+	doc, ok := c.docs[uri]
+	if ok && doc.text == text {
+		c.mu.Unlock()
+		return text, false, nil
+	}
+	if !ok {
+		doc = &document{}
+		c.docs[uri] = doc
+	}
+	doc.version++
+	doc.text = text
+	version := doc.version
+	c.mu.Unlock()
+
+	if !ok {
+		err = c.conn.Notify("textDocument/didOpen", map[string]any{
+			"textDocument": map[string]any{
+				"uri":        uri,
+				"languageId": c.config.languageID(path),
+				"version":    version,
+				"text":       text,
+			},
+		})
+	} else {
+		err = c.conn.Notify("textDocument/didChange", map[string]any{
+			"textDocument":   map[string]any{"uri": uri, "version": version},
+			"contentChanges": []map[string]any{{"text": text}},
+		})
+	}
+	return text, true, err
+}
+
+// Definition 查找符号定义
+func (c *Client) Definition(ctx context.Context, path string, pos Position) ([]Location, error) {
+	return c.locations(ctx, "textDocument/definition", textDocumentPositionParams{
+		TextDocument: textDocumentIdentifier{URI: PathToURI(path)},
+		Position:     pos,
+	})
+}
+
+// References 查找符号引用
+func (c *Client) References(ctx context.Context, path string, pos Position, includeDeclaration bool) ([]Location, error) {
+	return c.locations(ctx, "textDocument/references", map[string]any{
+		"textDocument": textDocumentIdentifier{URI: PathToURI(path)},
+		"position":     pos,
+		"context":      map[string]any{"includeDeclaration": includeDeclaration},
+	})
+}
+
+func (c *Client) locations(ctx context.Context, method string, params any) ([]Location, error) {
+	var raw json.RawMessage
+	if err := c.conn.Call(ctx, method, params, &raw); err != nil {
+		return nil, err
+	}
+	return parseLocations(raw)
+}
+
+// Rename 计算符号重命名的工作区编辑（不应用）
+func (c *Client) Rename(ctx context.Context, path string, pos Position, newName string) (*WorkspaceEdit, error) {
+	var edit *WorkspaceEdit
+	err := c.conn.Call(ctx, "textDocument/rename", map[string]any{
+		"textDocument": textDocumentIdentifier{URI: PathToURI(path)},
+		"position":     pos,
+		"newName":      newName,
+	}, &edit)
+	if err != nil {
+		return nil, err
+	}
+	if edit == nil {
+		return nil, errors.New("no symbol to rename at this position")
+	}
+	return edit, nil
+}
+
+// Diagnostics 同步文件并返回其诊断
+// 文件内容有变化或尚未收到诊断时，最多等待 wait 让服务器发布新的诊断
+func (c *Client) Diagnostics(ctx context.Context, path string, wait time.Duration) ([]Diagnostic, error) {
+	uri := PathToURI(path)
+	c.mu.Lock()
+	seq, seen := c.diagSeq[uri]
+	c.mu.Unlock()
+
+	_, changed, err := c.Sync(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if changed || !seen {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		for {
+			c.mu.Lock()
+			current, wake := c.diagSeq[uri], c.diagWake
+			c.mu.Unlock()
+			if current > seq {
+				break
+			}
+			select {
+			case <-wake:
+				continue
+			case <-timer.C:
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-c.conn.Done():
+				return nil, c.conn.Err()
+			}
+			break
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Diagnostic(nil), c.diags[uri]...), nil
+}
+
+// AllDiagnostics 返回已收到的所有文件诊断（按文件路径）
+func (c *Client) AllDiagnostics() map[string][]Diagnostic {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := make(map[string][]Diagnostic, len(c.diags))
+	for uri, diags := range c.diags {
+		out[URIToPath(uri)] = append([]Diagnostic(nil), diags...)
+	}
+	return out
+}
+
+// Close 关闭语言服务器
+func (c *Client) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	err := c.conn.Call(ctx, "shutdown", nil, nil)
+	if err == nil {
+		_ = c.conn.Notify("exit", nil)
+	}
+	if c.cmd == nil {
+		return nil
+	}
+	if c.stdin != nil {
+		_ = c.stdin.Close()
+	}
+	select {
+	case <-c.conn.Done():
+	case <-ctx.Done():
+		c.kill()
+	}
+	return nil
+}
+
+func (c *Client) kill() {
+	if c.cmd != nil && c.cmd.Process != nil {
+		_ = c.cmd.Process.Kill()
+	}
+}
+
+// tailBuffer 只保留最后 limit 字节的写入缓冲
+type tailBuffer struct {
+	mu    sync.Mutex
+	limit int
+	buf   []byte
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.buf = append(b.buf, p...)
+	if over := len(b.buf) - b.limit; over > 0 {
+		b.buf = b.buf[over:]
+	}
+	return len(p), nil
+}
+
+func (b *tailBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return strings.TrimSpace(string(b.buf))
+}
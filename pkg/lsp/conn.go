@@ -0,0 +1,270 @@
+package lsp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ErrClosed 连接已关闭
+var ErrClosed = errors.New("lsp: connection closed")
+
+// ResponseError JSON-RPC 错误
+type ResponseError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *ResponseError) Error() string {
+	return fmt.Sprintf("lsp error %d: %s", e.Code, e.Message)
+}
+
+// codeMethodNotFound JSON-RPC 方法不存在
+const codeMethodNotFound = -32601
+
+// message JSON-RPC 消息（请求、响应、通知共用）
+type message struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id,omitempty"`
+	Method  string           `json:"method,omitempty"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+	Result  json.RawMessage  `json:"result,omitempty"`
+	Error   *ResponseError   `json:"error,omitempty"`
+}
+
+// Handler 处理服务端发来的请求和通知
+// 请求（isRequest 为 true）的返回值作为响应结果
+type Handler func(method string, params json.RawMessage, isRequest bool) (any, error)
+
+// Conn 基于 Content-Length 分帧的 JSON-RPC 2.0 连接
+type Conn struct {
+	w       io.Writer
+	handler Handler
+
+	writeMu sync.Mutex
+
+	mu      sync.Mutex
+	nextID  int64
+	pending map[string]chan *message
+	closed  bool
+	err     error
+	done    chan struct{}
+}
+
+// NewConn 创建连接并开始读取消息
+func NewConn(r io.Reader, w io.Writer, handler Handler) *Conn {
+	c := &Conn{
+		w:       w,
+		handler: handler,
+		pending: make(map[string]chan *message),
+		done:    make(chan struct{}),
+	}
+	go c.readLoop(bufio.NewReader(r))
+	return c
+}
+
+// Call 发送请求并等待响应，result 为 nil 时丢弃结果
+func (c *Conn) Call(ctx context.Context, method string, params, result any) error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return ErrClosed
+	}
+	c.nextID++
+	n := c.nextID
+	id := strconv.FormatInt(n, 10)
+	ch := make(chan *message, 1)
+	c.pending[id] = ch
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+	}()
+
+	rawID := json.RawMessage(id)
+	if err := c.send(&message{ID: &rawID, Method: method}, params); err != nil {
+		return err
+	}
+
+	select {
+	case resp := <-ch:
+		if resp.Error != nil {
+			return resp.Error
+		}
+		if result == nil || len(resp.Result) == 0 {
+			return nil
+		}
+		return json.Unmarshal(resp.Result, result)
+	case <-ctx.Done():
+		// 通知服务端取消请求
+		_ = c.Notify("$/cancelRequest", map[string]any{"id": n})
+		return ctx.Err()
+	case <-c.done:
+		return c.closeErr()
+	}
+}
+
+// Notify 发送通知
+func (c *Conn) Notify(method string, params any) error {
+	return c.send(&message{Method: method}, params)
+}
+
+// Done 连接关闭时关闭
+func (c *Conn) Done() <-chan struct{} {
+	return c.done
+}
+
+// Err 返回连接关闭的原因
+func (c *Conn) Err() error {
+	select {
+	case <-c.done:
+		return c.closeErr()
+	default:
+		return nil
+	}
+}
+
+func (c *Conn) send(msg *message, params any) error {
+	msg.JSONRPC = "2.0"
+	if params != nil {
+		raw, err := json.Marshal(params)
+		if err != nil {
+			return fmt.Errorf("marshal params: %w", err)
+		}
+		msg.Params = raw
+	}
+	return c.write(msg)
+}
+
+func (c *Conn) write(msg *message) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if _, err := fmt.Fprintf(c.w, "Content-Length: %d\r\n\r\n", len(body)); err != nil {
+		return err
+	}
+	_, err = c.w.Write(body)
+	return err
+}
+
+func (c *Conn) readLoop(r *bufio.Reader) {
+	for {
+		msg, err := readMessage(r)
+		if err != nil {
+			c.close(err)
+			return
+		}
+		switch {
+		case msg.Method == "" && msg.ID != nil:
+			c.mu.Lock()
+			ch, ok := c.pending[string(*msg.ID)]
+			c.mu.Unlock()
+			if ok {
+				ch <- msg
+			}
+		case msg.Method != "" && msg.ID == nil:
+			// 通知按顺序处理，保证同一文档的诊断不会乱序
+			c.handle(msg)
+		case msg.Method != "":
+			// 服务端请求可能阻塞（如等待配置），不阻塞读循环
+			go c.handle(msg)
+		}
+	}
+}
+
+// handle 处理服务端请求或通知
+func (c *Conn) handle(msg *message) {
+	var (
+		result any
+		err    error
+	)
+	if c.handler != nil {
+		result, err = c.handler(msg.Method, msg.Params, msg.ID != nil)
+	} else if msg.ID != nil {
+		err = &ResponseError{Code: codeMethodNotFound, Message: "method not found: " + msg.Method}
+	}
+	if msg.ID == nil {
+		return
+	}
+
+	resp := &message{JSONRPC: "2.0", ID: msg.ID}
+	if err != nil {
+		var re *ResponseError
+		if !errors.As(err, &re) {
+			re = &ResponseError{Code: -32603, Message: err.Error()}
+		}
+		resp.Error = re
+	} else {
+		raw, mErr := json.Marshal(result)
+		if mErr != nil {
+			raw = []byte("null")
+		}
+		resp.Result = raw
+	}
+	_ = c.write(resp)
+}
+
+func (c *Conn) close(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return
+	}
+	c.closed = true
+	if err == nil || errors.Is(err, io.EOF) {
+		err = ErrClosed
+	}
+	c.err = err
+	close(c.done)
+}
+
+func (c *Conn) closeErr() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+// readMessage 读取一条 Content-Length 分帧的消息
+func readMessage(r *bufio.Reader) (*message, error) {
+	length := -1
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			break
+		}
+		name, value, ok := strings.Cut(line, ":")
+		if ok && strings.EqualFold(strings.TrimSpace(name), "Content-Length") {
+			length, err = strconv.Atoi(strings.TrimSpace(value))
+			if err != nil {
+				return nil, fmt.Errorf("invalid Content-Length: %w", err)
+			}
+		}
+	}
+	if length < 0 {
+		return nil, errors.New("missing Content-Length header")
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+	var msg message
+	if err := json.Unmarshal(body, &msg); err != nil {
+		return nil, fmt.Errorf("decode message: %w", err)
+	}
+	return &msg, nil
+}
@@ -0,0 +1,322 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestMain 设置 LSP_FAKE_SERVER 时作为假语言服务器运行
+func TestMain(m *testing.M) {
+	if os.Getenv("LSP_FAKE_SERVER") == "1" {
+		conn := runFakeServer(os.Stdin, os.Stdout)
+		<-conn.Done()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// runFakeServer 一个最小的语言服务器：
+// 定义指向文件第一行，引用返回符号在文件中的每次出现，
+// 重命名替换所有出现，包含 "bad" 的行报告错误诊断
+func runFakeServer(r *os.File, w *os.File) *Conn {
+	var (
+		conn *Conn
+		mu   sync.Mutex
+	)
+	docs := map[string]string{}
+	publish := func(uri, text string) {
+		diags := []Diagnostic{}
+		for i, line := range strings.Split(text, "\n") {
+			if idx := strings.Index(line, "bad"); idx >= 0 {
+				diags = append(diags, Diagnostic{
+					Range:    Range{Start: Position{Line: i, Character: idx}, End: Position{Line: i, Character: idx + 3}},
+					Severity: SeverityError,
+					Source:   "fake",
+					Message:  "bad code",
+				})
+			}
+		}
+		_ = conn.Notify("textDocument/publishDiagnostics", publishDiagnosticsParams{URI: uri, Diagnostics: diags})
+	}
+	// 测试文本只含 BMP 字符，rune 列与 UTF-16 列相同
+	occurrences := func(uri string, pos Position) []Location {
+		text := docs[uri]
+		line := []rune(LineText(text, pos.Line))
+		end := pos.Character
+		for end < len(line) && isIdentRune(line[end]) {
+			end++
+		}
+		symbol := string(line[pos.Character:end])
+		var locs []Location
+		for i, l := range strings.Split(text, "\n") {
+			runes := []rune(l)
+			for c := 0; c+len([]rune(symbol)) <= len(runes); c++ {
+				if string(runes[c:c+len([]rune(symbol))]) == symbol {
+					locs = append(locs, Location{URI: uri, Range: Range{Start: Position{Line: i, Character: c}, End: Position{Line: i, Character: c + len([]rune(symbol))}}})
+				}
+			}
+		}
+		return locs
+	}
+
+	conn = NewConn(r, w, func(method string, params json.RawMessage, isRequest bool) (any, error) {
+		var p struct {
+			TextDocument struct {
+				URI  string `json:"uri"`
+				Text string `json:"text"`
+			} `json:"textDocument"`
+			ContentChanges []struct {
+				Text string `json:"text"`
+			} `json:"contentChanges"`
+			Position Position `json:"position"`
+			NewName  string   `json:"newName"`
+		}
+		_ = json.Unmarshal(params, &p)
+		uri := p.TextDocument.URI
+		mu.Lock()
+		defer mu.Unlock()
+		switch method {
+		case "initialize":
+			return map[string]any{"capabilities": map[string]any{}}, nil
+		case "shutdown":
+			return nil, nil
+		case "exit":
+			os.Exit(0)
+		case "textDocument/didOpen":
+			docs[uri] = p.TextDocument.Text
+			publish(uri, docs[uri])
+		case "textDocument/didChange":
+			docs[uri] = p.ContentChanges[0].Text
+			publish(uri, docs[uri])
+		case "textDocument/definition":
+			return []map[string]any{{
+				"targetUri":            uri,
+				"targetRange":          Range{},
+				"targetSelectionRange": Range{Start: Position{Character: 5}},
+			}}, nil
+		case "textDocument/references":
+			return occurrences(uri, p.Position), nil
+		case "textDocument/rename":
+			var edits []TextEdit
+			for _, loc := range occurrences(uri, p.Position) {
+				edits = append(edits, TextEdit{Range: loc.Range, NewText: p.NewName})
+			}
+			return WorkspaceEdit{Changes: map[string][]TextEdit{uri: edits}}, nil
+		}
+		if isRequest {
+			return nil, &ResponseError{Code: codeMethodNotFound, Message: method}
+		}
+		return nil, nil
+	})
+	return conn
+}
+
+func fakeServerConfig() ServerConfig {
+	return ServerConfig{
+		Name:        "fake",
+		Command:     os.Args[0],
+		Args:        []string{"-test.run=^$"},
+		Extensions:  []string{".fake"},
+		RootMarkers: []string{"fake.mod"},
+		Env:         map[string]string{"LSP_FAKE_SERVER": "1"},
+	}
+}
+
+func TestManager_Queries(t *testing.T) {
+	dir := t.TempDir()
+	_ = os.MkdirAll(filepath.Join(dir, "mod"), 0o755)
+	_ = os.WriteFile(filepath.Join(dir, "mod", "fake.mod"), nil, 0o644)
+	path := filepath.Join(dir, "mod", "main.fake")
+	_ = os.WriteFile(path, []byte("func greet()\ngreet()\ngreet() // 你好 greet\n"), 0o644)
+
+	m := NewManager(dir, []ServerConfig{fakeServerConfig()})
+	defer m.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if _, err := m.Client(ctx, "notes.txt"); err == nil {
+		t.Fatal("Client(notes.txt) should fail without a server")
+	}
+	c, err := m.Client(ctx, "mod/main.fake")
+	if err != nil {
+		t.Fatalf("Client() error = %v", err)
+	}
+	if c.Root() != filepath.Join(dir, "mod") {
+		t.Errorf("Root() = %s, want the directory containing fake.mod", c.Root())
+	}
+	if again, _ := m.Client(ctx, path); again != c {
+		t.Error("Client() should reuse the running server")
+	}
+
+	text, _, err := c.Sync(path)
+	if err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	pos, err := FindSymbol(text, 3, "greet", 2)
+	if err != nil || pos.Character != 14 {
+		t.Fatalf("FindSymbol() = %+v, %v; want UTF-16 column 14", pos, err)
+	}
+
+	defs, err := c.Definition(ctx, path, pos)
+	if err != nil || len(defs) != 1 || defs[0].Range.Start.Character != 5 {
+		t.Fatalf("Definition() = %+v, %v", defs, err)
+	}
+	refs, err := c.References(ctx, path, pos, true)
+	if err != nil || len(refs) != 4 {
+		t.Fatalf("References() = %+v, %v", refs, err)
+	}
+
+	edit, err := c.Rename(ctx, path, pos, "welcome")
+	if err != nil {
+		t.Fatalf("Rename() error = %v", err)
+	}
+	if _, err := m.ApplyEdit(edit, true); err != nil {
+		t.Fatalf("ApplyEdit(dry run) error = %v", err)
+	}
+	if data, _ := os.ReadFile(path); !strings.Contains(string(data), "greet") {
+		t.Fatal("dry run should not write files")
+	}
+	counts, err := m.ApplyEdit(edit, false)
+	if err != nil || counts["mod/main.fake"] != 4 {
+		t.Fatalf("ApplyEdit() = %v, %v", counts, err)
+	}
+	data, _ := os.ReadFile(path)
+	if want := "func welcome()\nwelcome()\nwelcome() // 你好 welcome\n"; string(data) != want {
+		t.Errorf("renamed file = %q, want %q", data, want)
+	}
+
+	_ = os.WriteFile(path, []byte("ok\nbad()\n"), 0o644)
+	diags, err := c.Diagnostics(ctx, path, 5*time.Second)
+	if err != nil || len(diags) != 1 || diags[0].Range.Start.Line != 1 {
+		t.Fatalf("Diagnostics() = %+v, %v", diags, err)
+	}
+	if all := c.AllDiagnostics(); len(all[path]) != 1 {
+		t.Errorf("AllDiagnostics() = %+v", all)
+	}
+
+	if status := m.Status(); len(status) != 1 || !status[0].Alive {
+		t.Errorf("Status() = %+v", status)
+	}
+}
+
+func TestManager_ApplyEditOutsideWorkDir(t *testing.T) {
+	dir := t.TempDir()
+	outside := filepath.Join(t.TempDir(), "x.fake")
+	_ = os.WriteFile(outside, []byte("x"), 0o644)
+
+	m := NewManager(dir, []ServerConfig{fakeServerConfig()})
+	edit := &WorkspaceEdit{Changes: map[string][]TextEdit{PathToURI(outside): {{NewText: "y"}}}}
+	if _, err := m.ApplyEdit(edit, false); err == nil {
+		t.Fatal("ApplyEdit() should reject files outside the working directory")
+	}
+	if data, _ := os.ReadFile(outside); string(data) != "x" {
+		t.Errorf("outside file was modified: %q", data)
+	}
+}
+
+func TestManager_MissingServer(t *testing.T) {
+	m := NewManager(t.TempDir(), []ServerConfig{{Name: "none", Command: "aster-no-such-lsp", Extensions: []string{".none"}}})
+	if _, err := m.Client(context.Background(), "a.none"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Fatalf("Client() error = %v, want not found", err)
+	}
+}
+
+func TestManager_StartGate(t *testing.T) {
+	dir := t.TempDir()
+	m := NewManager(dir, []ServerConfig{fakeServerConfig()})
+	defer m.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	denied := errors.New("workspace is not trusted")
+	allowed := false
+	m.SetStartGate(func() error {
+		if !allowed {
+			return denied
+		}
+		return nil
+	})
+	if _, err := m.Client(ctx, "main.fake"); !errors.Is(err, denied) {
+		t.Fatalf("Client() error = %v, want the gate error", err)
+	}
+	if status := m.Status(); len(status) != 0 {
+		t.Fatalf("Status() = %+v, want no servers started", status)
+	}
+
+	// 拒绝不记为启动失败
+	allowed = true
+	if _, err := m.Client(ctx, "main.fake"); err != nil {
+		t.Fatalf("Client() after the gate opens error = %v", err)
+	}
+}
+
+func TestApplyEdits(t *testing.T) {
+	text := "a := 1\nb := a + a\n"
+	edits := []TextEdit{
+		{Range: Range{Start: Position{1, 9}, End: Position{1, 10}}, NewText: "x"},
+		{Range: Range{Start: Position{0, 0}, End: Position{0, 1}}, NewText: "x"},
+		{Range: Range{Start: Position{1, 5}, End: Position{1, 6}}, NewText: "x"},
+	}
+	got, err := ApplyEdits(text, edits)
+	if err != nil || got != "x := 1\nb := x + x\n" {
+		t.Fatalf("ApplyEdits() = %q, %v", got, err)
+	}
+
+	overlap := []TextEdit{
+		{Range: Range{Start: Position{0, 0}, End: Position{0, 3}}, NewText: ""},
+		{Range: Range{Start: Position{0, 2}, End: Position{0, 4}}, NewText: ""},
+	}
+	if _, err := ApplyEdits(text, overlap); err == nil {
+		t.Error("ApplyEdits() should reject overlapping edits")
+	}
+}
+
+func TestFindSymbol(t *testing.T) {
+	text := "x := fooBar(foo, foo)\n"
+	tests := []struct {
+		symbol     string
+		occurrence int
+		want       int
+		wantErr    bool
+	}{
+		{"foo", 1, 12, false},
+		{"foo", 2, 17, false},
+		{"fooBar", 1, 5, false},
+		{"foo", 3, 0, true},
+		{"oo", 1, 0, true},
+	}
+	for _, tt := range tests {
+		pos, err := FindSymbol(text, 1, tt.symbol, tt.occurrence)
+		if (err != nil) != tt.wantErr || (!tt.wantErr && pos.Character != tt.want) {
+			t.Errorf("FindSymbol(%q, %d) = %+v, %v; want %d", tt.symbol, tt.occurrence, pos, err, tt.want)
+		}
+	}
+}
+
+func TestParseLocations(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want int
+	}{
+		{`null`, 0},
+		{`{"uri":"file:///a.go","range":{"start":{"line":1,"character":2},"end":{"line":1,"character":3}}}`, 1},
+		{`[{"uri":"file:///a.go","range":{}},{"uri":"file:///b.go","range":{}}]`, 2},
+		{`[{"targetUri":"file:///a.go","targetRange":{},"targetSelectionRange":{}}]`, 1},
+	}
+	for _, tt := range tests {
+		locs, err := parseLocations(json.RawMessage(tt.raw))
+		if err != nil || len(locs) != tt.want {
+			t.Errorf("parseLocations(%s) = %+v, %v", tt.raw, locs, err)
+		}
+	}
+	if got := URIToPath(PathToURI("/tmp/a b/c.go")); got != filepath.FromSlash("/tmp/a b/c.go") {
+		t.Errorf("URI round trip = %q", got)
+	}
+}
@@ -0,0 +1,344 @@
+package lsp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// ErrNoServer 没有语言服务器支持该文件
+var ErrNoServer = errors.New("no language server configured for this file type")
+
+// ServerConfig 语言服务器配置
+type ServerConfig struct {
+	// Name 服务器名称，如 "gopls"
+	Name string `json:"name" yaml:"name"`
+	// Command 可执行文件（在 PATH 中查找）
+	Command string `json:"command" yaml:"command"`
+	// Args 启动参数
+	Args []string `json:"args,omitempty" yaml:"args,omitempty"`
+	// Extensions 处理的文件扩展名，如 [".go"]
+	Extensions []string `json:"extensions" yaml:"extensions"`
+	// RootMarkers 确定工作区根目录的标记文件，如 ["go.mod"]
+	RootMarkers []string `json:"root_markers,omitempty" yaml:"root_markers,omitempty"`
+	// Env 额外的环境变量
+	Env map[string]string `json:"env,omitempty" yaml:"env,omitempty"`
+	// InitializationOptions 传给 initialize 请求的服务器选项
+	InitializationOptions map[string]any `json:"initialization_options,omitempty" yaml:"initialization_options,omitempty"`
+}
+
+// handles 检查服务器是否处理该文件
+func (s ServerConfig) handles(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext != "" && slices.Contains(s.Extensions, ext)
+}
+
+// languageIDs 扩展名到 LSP languageId 的映射
+var languageIDs = map[string]string{
+	".go":   "go",
+	".ts":   "typescript",
+	".tsx":  "typescriptreact",
+	".js":   "javascript",
+	".jsx":  "javascriptreact",
+	".mjs":  "javascript",
+	".cjs":  "javascript",
+	".py":   "python",
+	".rs":   "rust",
+	".c":    "c",
+	".h":    "c",
+	".cc":   "cpp",
+	".cpp":  "cpp",
+	".hpp":  "cpp",
+	".java": "java",
+	".rb":   "ruby",
+	".lua":  "lua",
+}
+
+// languageID 返回文件的 languageId，未知扩展名时使用服务器名称
+func (s ServerConfig) languageID(path string) string {
+	if id, ok := languageIDs[strings.ToLower(filepath.Ext(path))]; ok {
+		return id
+	}
+	return s.Name
+}
+
+// DefaultServers 返回内置的常用语言服务器配置
+// 服务器只在处理对应文件时按需启动，未安装的服务器不影响其他语言
+func DefaultServers() []ServerConfig {
+	return []ServerConfig{
+		{
+			Name:        "gopls",
+			Command:     "gopls",
+			Extensions:  []string{".go"},
+			RootMarkers: []string{"go.work", "go.mod"},
+		},
+		{
+			Name:        "typescript",
+			Command:     "typescript-language-server",
+			Args:        []string{"--stdio"},
+			Extensions:  []string{".ts", ".tsx", ".js", ".jsx", ".mjs", ".cjs"},
+			RootMarkers: []string{"tsconfig.json", "jsconfig.json", "package.json"},
+		},
+		{
+			Name:        "pyright",
+			Command:     "pyright-langserver",
+			Args:        []string{"--stdio"},
+			Extensions:  []string{".py"},
+			RootMarkers: []string{"pyproject.toml", "setup.py", "setup.cfg", "requirements.txt"},
+		},
+		{
+			Name:        "rust-analyzer",
+			Command:     "rust-analyzer",
+			Extensions:  []string{".rs"},
+			RootMarkers: []string{"Cargo.toml"},
+		},
+		{
+			Name:        "clangd",
+			Command:     "clangd",
+			Extensions:  []string{".c", ".h", ".cc", ".cpp", ".hpp"},
+			RootMarkers: []string{"compile_commands.json", "CMakeLists.txt", "Makefile"},
+		},
+	}
+}
+
+// ServerStatus 语言服务器运行状态
+type ServerStatus struct {
+	Name  string `json:"name"`
+	Root  string `json:"root"`
+	Alive bool   `json:"alive"`
+}
+
+// Manager 按工作区管理语言服务器
+// 每个（服务器, 工作区根目录）组合按需启动一个进程，失败的启动会被记住避免反复重试
+type Manager struct {
+	workDir string
+	servers []ServerConfig
+
+	// gate 启动服务器前的检查，返回错误时拒绝启动（如工作区未受信任）
+	gate func() error
+
+	mu      sync.Mutex
+	clients map[string]*Client
+	failed  map[string]error
+	closed  bool
+}
+
+// NewManager 创建语言服务器管理器
+// servers 为空时使用 DefaultServers
+func NewManager(workDir string, servers []ServerConfig) *Manager {
+	if len(servers) == 0 {
+		servers = DefaultServers()
+	}
+	return &Manager{
+		workDir: workDir,
+		servers: servers,
+		clients: make(map[string]*Client),
+		failed:  make(map[string]error),
+	}
+}
+
+// SetStartGate 设置启动服务器前的检查，拒绝不会被记为启动失败，检查通过后可以再次启动
+func (m *Manager) SetStartGate(gate func() error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.gate = gate
+}
+
+// WorkDir 返回工作目录
+func (m *Manager) WorkDir() string {
+	return m.workDir
+}
+
+// Resolve 相对路径按工作目录转为绝对路径
+func (m *Manager) Resolve(path string) string {
+	if filepath.IsAbs(path) {
+		return filepath.Clean(path)
+	}
+	return filepath.Join(m.workDir, path)
+}
+
+// Rel 工作目录内的路径转为相对路径，其他路径原样返回
+func (m *Manager) Rel(path string) string {
+	if rel, err := filepath.Rel(m.workDir, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return filepath.ToSlash(rel)
+	}
+	return path
+}
+
+// IsInside 检查路径是否在工作目录内
+func (m *Manager) IsInside(path string) bool {
+	rel, err := filepath.Rel(m.workDir, m.Resolve(path))
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// ServerFor 返回处理该文件的服务器配置
+func (m *Manager) ServerFor(path string) (ServerConfig, bool) {
+	for _, s := range m.servers {
+		if s.handles(path) {
+			return s, true
+		}
+	}
+	return ServerConfig{}, false
+}
+
+// Client 返回处理该文件的客户端，必要时启动服务器
+func (m *Manager) Client(ctx context.Context, path string) (*Client, error) {
+	path = m.Resolve(path)
+	config, ok := m.ServerFor(path)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNoServer, filepath.Ext(path))
+	}
+	root := m.rootFor(config, path)
+	key := config.Name + "\x00" + root
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return nil, errors.New("language server manager is closed")
+	}
+	if c, ok := m.clients[key]; ok {
+		if c.Alive() {
+			return c, nil
+		}
+		delete(m.clients, key)
+	}
+	if err, ok := m.failed[key]; ok {
+		return nil, err
+	}
+	if m.gate != nil {
+		if err := m.gate(); err != nil {
+			return nil, err
+		}
+	}
+
+	c, err := StartClient(ctx, config, root)
+	if err != nil {
+		m.failed[key] = err
+		return nil, err
+	}
+	m.clients[key] = c
+	return c, nil
+}
+
+// rootFor 从文件所在目录向上查找根目录标记，找不到时使用工作目录
+func (m *Manager) rootFor(config ServerConfig, path string) string {
+	dir := filepath.Dir(path)
+	inside := m.IsInside(path)
+	for {
+		for _, marker := range config.RootMarkers {
+			if _, err := os.Stat(filepath.Join(dir, marker)); err == nil {
+				return dir
+			}
+		}
+		if inside && dir == filepath.Clean(m.workDir) {
+			break
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+	if inside {
+		return filepath.Clean(m.workDir)
+	}
+	return filepath.Dir(path)
+}
+
+// Clients 返回正在运行的客户端
+func (m *Manager) Clients() []*Client {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	clients := make([]*Client, 0, len(m.clients))
+	for _, c := range m.clients {
+		clients = append(clients, c)
+	}
+	slices.SortFunc(clients, func(a, b *Client) int {
+		return strings.Compare(a.Name()+a.Root(), b.Name()+b.Root())
+	})
+	return clients
+}
+
+// Status 返回正在运行的服务器状态
+func (m *Manager) Status() []ServerStatus {
+	clients := m.Clients()
+	status := make([]ServerStatus, 0, len(clients))
+	for _, c := range clients {
+		status = append(status, ServerStatus{Name: c.Name(), Root: c.Root(), Alive: c.Alive()})
+	}
+	return status
+}
+
+// ApplyEdit 将工作区编辑写入文件，返回每个文件的编辑数
+// 编辑涉及工作目录外的文件时整体拒绝，任何文件出错时不写入
+func (m *Manager) ApplyEdit(edit *WorkspaceEdit, dryRun bool) (map[string]int, error) {
+	files, err := edit.FileEdits()
+	if err != nil {
+		return nil, err
+	}
+
+	type pending struct {
+		path string
+		text string
+		mode os.FileMode
+	}
+	updates := make([]pending, 0, len(files))
+	counts := make(map[string]int, len(files))
+	for path, edits := range files {
+		if !m.IsInside(path) {
+			return nil, fmt.Errorf("edit touches a file outside the working directory: %s", path)
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		text, err := ApplyEdits(string(data), edits)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", m.Rel(path), err)
+		}
+		updates = append(updates, pending{path: path, text: text, mode: info.Mode().Perm()})
+		counts[m.Rel(path)] = len(edits)
+	}
+
+	if dryRun {
+		return counts, nil
+	}
+	for _, u := range updates {
+		if err := os.WriteFile(u.path, []byte(u.text), u.mode); err != nil {
+			return nil, err
+		}
+	}
+	return counts, nil
+}
+
+// Close 关闭所有语言服务器
+func (m *Manager) Close() error {
+	m.mu.Lock()
+	clients := make([]*Client, 0, len(m.clients))
+	for _, c := range m.clients {
+		clients = append(clients, c)
+	}
+	m.clients = make(map[string]*Client)
+	m.closed = true
+	m.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for _, c := range clients {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = c.Close()
+		}()
+	}
+	wg.Wait()
+	return nil
+}
@@ -0,0 +1,212 @@
+package lsp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// lineAt 返回文本第 line 行（0 起始）的内容，不含换行符
+func lineAt(text string, line int) (string, bool) {
+	for i := 0; i < line; i++ {
+		idx := strings.IndexByte(text, '\n')
+		if idx < 0 {
+			return "", false
+		}
+		text = text[idx+1:]
+	}
+	if idx := strings.IndexByte(text, '\n'); idx >= 0 {
+		text = text[:idx]
+	}
+	return strings.TrimSuffix(text, "\r"), true
+}
+
+// LineText 返回文本第 line 行（0 起始）的内容
+func LineText(text string, line int) string {
+	s, _ := lineAt(text, line)
+	return s
+}
+
+// utf16Column 行内 rune 偏移转为 UTF-16 码元偏移
+func utf16Column(line string, runes int) int {
+	n := 0
+	for i, r := range []rune(line) {
+		if i >= runes {
+			break
+		}
+		n += len(utf16.Encode([]rune{r}))
+	}
+	return n
+}
+
+// RuneColumn 行内 UTF-16 码元偏移转为 rune 偏移
+func RuneColumn(line string, units int) int {
+	n, col := 0, 0
+	for _, r := range line {
+		if n >= units {
+			break
+		}
+		n += len(utf16.Encode([]rune{r}))
+		col++
+	}
+	return col
+}
+
+// PositionOf 以 1 起始的行号和字符列构造 LSP 位置
+func PositionOf(text string, line, column int) (Position, error) {
+	if line < 1 || column < 1 {
+		return Position{}, fmt.Errorf("line and column must be >= 1")
+	}
+	lineText, ok := lineAt(text, line-1)
+	if !ok {
+		return Position{}, fmt.Errorf("line %d is out of range", line)
+	}
+	return Position{Line: line - 1, Character: utf16Column(lineText, column-1)}, nil
+}
+
+// FindSymbol 在第 line 行（1 起始）查找标识符 symbol 的第 occurrence 次出现（1 起始）
+// 只匹配完整的标识符，返回符号起始位置
+func FindSymbol(text string, line int, symbol string, occurrence int) (Position, error) {
+	if symbol == "" {
+		return Position{}, errors.New("symbol is empty")
+	}
+	if line < 1 {
+		return Position{}, fmt.Errorf("line %d is out of range", line)
+	}
+	lineText, ok := lineAt(text, line-1)
+	if !ok {
+		return Position{}, fmt.Errorf("line %d is out of range", line)
+	}
+	if occurrence < 1 {
+		occurrence = 1
+	}
+	seen := 0
+	for from := 0; from <= len(lineText); {
+		idx := strings.Index(lineText[from:], symbol)
+		if idx < 0 {
+			break
+		}
+		start := from + idx
+		end := start + len(symbol)
+		if !identBefore(lineText, start) && !identAfter(lineText, end) {
+			seen++
+			if seen == occurrence {
+				return Position{Line: line - 1, Character: utf16Column(lineText, utf8.RuneCountInString(lineText[:start]))}, nil
+			}
+		}
+		from = start + 1
+	}
+	return Position{}, fmt.Errorf("symbol %q not found on line %d", symbol, line)
+}
+
+func identBefore(s string, i int) bool {
+	if i == 0 {
+		return false
+	}
+	r, _ := utf8.DecodeLastRuneInString(s[:i])
+	return isIdentRune(r)
+}
+
+func identAfter(s string, i int) bool {
+	if i >= len(s) {
+		return false
+	}
+	r, _ := utf8.DecodeRuneInString(s[i:])
+	return isIdentRune(r)
+}
+
+func isIdentRune(r rune) bool {
+	return r == '_' || r == '$' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r > utf8.RuneSelf
+}
+
+// offsetOf LSP 位置转为文本字节偏移
+func offsetOf(text string, pos Position) (int, error) {
+	offset := 0
+	for i := 0; i < pos.Line; i++ {
+		idx := strings.IndexByte(text[offset:], '\n')
+		if idx < 0 {
+			return 0, fmt.Errorf("line %d is out of range", pos.Line+1)
+		}
+		offset += idx + 1
+	}
+	units := 0
+	for i, r := range text[offset:] {
+		if units >= pos.Character || r == '\n' {
+			return offset + i, nil
+		}
+		units += len(utf16.Encode([]rune{r}))
+	}
+	return len(text), nil
+}
+
+// ApplyEdits 将文本编辑应用到文本，编辑范围不能重叠
+func ApplyEdits(text string, edits []TextEdit) (string, error) {
+	type span struct {
+		start, end int
+		newText    string
+	}
+	spans := make([]span, 0, len(edits))
+	for _, e := range edits {
+		start, err := offsetOf(text, e.Range.Start)
+		if err != nil {
+			return "", err
+		}
+		end, err := offsetOf(text, e.Range.End)
+		if err != nil {
+			return "", err
+		}
+		if end < start {
+			return "", errors.New("invalid edit range")
+		}
+		spans = append(spans, span{start, end, e.NewText})
+	}
+	slices.SortStableFunc(spans, func(a, b span) int { return a.start - b.start })
+
+	var sb strings.Builder
+	last := 0
+	for _, s := range spans {
+		if s.start < last {
+			return "", errors.New("overlapping edits")
+		}
+		sb.WriteString(text[last:s.start])
+		sb.WriteString(s.newText)
+		last = s.end
+	}
+	sb.WriteString(text[last:])
+	return sb.String(), nil
+}
+
+// FileEdits 按文件路径整理 WorkspaceEdit 中的文本编辑
+// 不支持创建、重命名、删除文件等资源操作
+func (e *WorkspaceEdit) FileEdits() (map[string][]TextEdit, error) {
+	files := make(map[string][]TextEdit)
+	if e == nil {
+		return files, nil
+	}
+	for uri, edits := range e.Changes {
+		path := URIToPath(uri)
+		files[path] = append(files[path], edits...)
+	}
+	for _, raw := range e.DocumentChanges {
+		var probe struct {
+			Kind string `json:"kind"`
+		}
+		if err := json.Unmarshal(raw, &probe); err != nil {
+			return nil, err
+		}
+		if probe.Kind != "" {
+			return nil, fmt.Errorf("unsupported resource operation: %s", probe.Kind)
+		}
+		var doc textDocumentEdit
+		if err := json.Unmarshal(raw, &doc); err != nil {
+			return nil, err
+		}
+		path := URIToPath(doc.TextDocument.URI)
+		files[path] = append(files[path], doc.Edits...)
+	}
+	return files, nil
+}
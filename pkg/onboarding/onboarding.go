@@ -0,0 +1,284 @@
+// Package onboarding 首次运行引导：检测缺失的 API Key，选择 Provider，
+// 用一次最小请求验证 Key，通过密钥管理保存，并写入默认配置和工作区
+// CLI（aster setup）和桌面端共用同一流程
+package onboarding
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/astercloud/aster/pkg/config"
+	"github.com/astercloud/aster/pkg/provider"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+// DefaultValidateTimeout 验证 API Key 的默认超时
+const DefaultValidateTimeout = 30 * time.Second
+
+// ErrAPIKeyRequired 所选 Provider 需要 API Key 但没有提供
+var ErrAPIKeyRequired = errors.New("API key is required")
+
+// Provider 引导中可选择的模型服务
+type Provider struct {
+	Name         string `json:"name"`
+	DisplayName  string `json:"display_name"`
+	DefaultModel string `json:"default_model"`
+
+	// KeyEnv 读取 API Key 的环境变量，不需要 Key 时为空
+	KeyEnv string `json:"key_env,omitempty"`
+
+	// KeyURL 申请 API Key 的页面
+	KeyURL string `json:"key_url,omitempty"`
+}
+
+// NeedsKey 是否需要 API Key
+func (p Provider) NeedsKey() bool {
+	return p.KeyEnv != ""
+}
+
+// Providers 引导中列出的 Provider，其他 Provider 可通过 aster config 手动配置
+var Providers = []Provider{
+	{Name: "anthropic", DisplayName: "Anthropic Claude", DefaultModel: "claude-sonnet-4-5", KeyEnv: config.APIKeyEnvName("anthropic"), KeyURL: "https://console.anthropic.com/settings/keys"},
+	{Name: "openai", DisplayName: "OpenAI", DefaultModel: "gpt-4o", KeyEnv: config.APIKeyEnvName("openai"), KeyURL: "https://platform.openai.com/api-keys"},
+	{Name: "deepseek", DisplayName: "DeepSeek", DefaultModel: "deepseek-chat", KeyEnv: config.APIKeyEnvName("deepseek"), KeyURL: "https://platform.deepseek.com/api_keys"},
+	{Name: "google", DisplayName: "Google Gemini", DefaultModel: "gemini-2.5-flash", KeyEnv: config.APIKeyEnvName("google"), KeyURL: "https://aistudio.google.com/apikey"},
+	{Name: "ollama", DisplayName: "Ollama (local)", DefaultModel: "llama3.2"},
+}
+
+// LookupProvider 按名称查找 Provider，未列出的 Provider 按需要 API Key 处理
+func LookupProvider(name string) (Provider, bool) {
+	for _, p := range Providers {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return Provider{Name: name, DisplayName: name, KeyEnv: config.APIKeyEnvName(name)}, false
+}
+
+// DefaultWorkspace 默认工作区目录
+func DefaultWorkspace() string {
+	return filepath.Join(config.DataDir(), "workspace")
+}
+
+// Status 首次运行检测结果
+type Status struct {
+	// NeedsSetup 默认模型的 Provider 缺少 API Key 时为 true
+	NeedsSetup bool `json:"needs_setup"`
+
+	Provider string `json:"provider"`
+	Model    string `json:"model"`
+
+	// KeySource API Key 的来源：env、settings 或 secrets，未配置时为空
+	KeySource string `json:"key_source,omitempty"`
+
+	SettingsFile   string `json:"settings_file"`
+	SettingsExists bool   `json:"settings_exists"`
+
+	// Workspace 配置的工作目录；使用当前目录时为空
+	Workspace string `json:"workspace,omitempty"`
+
+	// DefaultWorkspace 建议的默认工作区
+	DefaultWorkspace string `json:"default_workspace"`
+
+	Providers []Provider `json:"providers"`
+}
+
+// KeyValidator 验证模型配置（含 API Key）可用
+type KeyValidator func(ctx context.Context, cfg *types.ModelConfig) error
+
+// ValidateKey 创建 Provider 并发送一次最小请求，失败时返回服务端的错误
+func ValidateKey(ctx context.Context, cfg *types.ModelConfig) error {
+	p, err := provider.NewMultiProviderFactory().Create(cfg)
+	if err != nil {
+		return fmt.Errorf("create provider: %w", err)
+	}
+	defer p.Close()
+
+	_, err = p.Complete(ctx, []types.Message{{Role: types.RoleUser, Content: "ping"}}, &provider.StreamOptions{MaxTokens: 1})
+	if err != nil {
+		return fmt.Errorf("test request to %s failed: %w", cfg.Provider, err)
+	}
+	return nil
+}
+
+// Request 完成引导的参数
+type Request struct {
+	Provider string `json:"provider"`
+
+	// Model 为空时使用 Provider 的默认模型
+	Model string `json:"model,omitempty"`
+
+	// APIKey 为空时沿用已配置的 Key（如环境变量）
+	APIKey string `json:"api_key,omitempty"`
+
+	// Workspace 默认工作目录，会被创建并写入 session.work_dir；为空时不修改
+	Workspace string `json:"workspace,omitempty"`
+
+	// SkipValidation 跳过测试请求，用于离线环境
+	SkipValidation bool `json:"skip_validation,omitempty"`
+}
+
+// Setup 执行首次运行引导
+type Setup struct {
+	// SettingsFile 配置文件路径
+	SettingsFile string
+
+	// Secrets 保存 API Key 的密钥管理
+	Secrets config.SecretStore
+
+	// Validate 验证 API Key，为空时使用 ValidateKey
+	Validate KeyValidator
+
+	// ValidateTimeout 验证超时，默认 30 秒
+	ValidateTimeout time.Duration
+}
+
+// NewSetup 使用默认配置文件和默认密钥管理创建引导
+func NewSetup() *Setup {
+	return &Setup{
+		SettingsFile: config.SettingsFile(),
+		Secrets:      config.DefaultSecretStore(),
+	}
+}
+
+// Settings 加载配置（含环境变量和密钥管理中的 API Key）
+func (s *Setup) Settings() (*config.Settings, error) {
+	settings, err := config.LoadSettings(s.SettingsFile)
+	if err != nil {
+		return nil, err
+	}
+	settings.UseSecretStore(s.Secrets)
+	return settings, nil
+}
+
+// Status 检测是否需要引导
+func (s *Setup) Status() (*Status, error) {
+	settings, err := s.Settings()
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := settings.AliasConfig()
+	model := cfg.Aliases[cfg.Default]
+	status := &Status{
+		Provider:         model.Provider,
+		Model:            model.Model,
+		SettingsFile:     s.SettingsFile,
+		DefaultWorkspace: DefaultWorkspace(),
+		Providers:        Providers,
+	}
+	if _, err := os.Stat(s.SettingsFile); err == nil {
+		status.SettingsExists = true
+	}
+	if dir := settings.Session.WorkDir; dir != "" && dir != "." {
+		status.Workspace = dir
+	}
+
+	var key string
+	key, status.KeySource = settings.APIKeySource(model.Provider)
+	p, _ := LookupProvider(model.Provider)
+	status.NeedsSetup = p.NeedsKey() && key == ""
+	return status, nil
+}
+
+// resolve 补全请求中的模型和 API Key，返回要验证的模型配置
+func (s *Setup) resolve(req *Request) (*types.ModelConfig, error) {
+	if req.Provider == "" {
+		return nil, errors.New("provider is required")
+	}
+	p, known := LookupProvider(req.Provider)
+	if req.Model == "" {
+		if !known {
+			return nil, fmt.Errorf("model is required for provider %s", req.Provider)
+		}
+		req.Model = p.DefaultModel
+	}
+	if req.APIKey == "" && p.NeedsKey() {
+		settings, err := s.Settings()
+		if err != nil {
+			return nil, err
+		}
+		if req.APIKey = settings.APIKey(req.Provider); req.APIKey == "" {
+			return nil, fmt.Errorf("%w for %s (get one at %s)", ErrAPIKeyRequired, p.DisplayName, p.KeyURL)
+		}
+	}
+	return &types.ModelConfig{Provider: req.Provider, Model: req.Model, APIKey: req.APIKey}, nil
+}
+
+// ValidateKey 验证请求中的 Provider、模型和 API Key
+func (s *Setup) ValidateKey(ctx context.Context, req Request) error {
+	cfg, err := s.resolve(&req)
+	if err != nil {
+		return err
+	}
+	return s.validate(ctx, cfg)
+}
+
+func (s *Setup) validate(ctx context.Context, cfg *types.ModelConfig) error {
+	validate := s.Validate
+	if validate == nil {
+		validate = ValidateKey
+	}
+	timeout := s.ValidateTimeout
+	if timeout <= 0 {
+		timeout = DefaultValidateTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	return validate(ctx, cfg)
+}
+
+// Complete 验证 API Key，保存到密钥管理，并把 Provider、模型和工作区写入配置文件
+// 配置文件中同一 Provider 的明文 api_keys 会被移除，改由密钥管理保存
+func (s *Setup) Complete(ctx context.Context, req Request) (*Status, error) {
+	cfg, err := s.resolve(&req)
+	if err != nil {
+		return nil, err
+	}
+	if !req.SkipValidation {
+		if err := s.validate(ctx, cfg); err != nil {
+			return nil, err
+		}
+	}
+
+	// 只修改文件内容，避免把环境变量写入配置
+	settings, err := config.LoadSettingsFile(s.SettingsFile)
+	if err != nil {
+		return nil, err
+	}
+	if err := settings.Set("provider", cfg.Provider); err != nil {
+		return nil, err
+	}
+	if err := settings.Set("model", cfg.Model); err != nil {
+		return nil, err
+	}
+	if req.Workspace != "" {
+		workspace, err := filepath.Abs(req.Workspace)
+		if err != nil {
+			return nil, fmt.Errorf("resolve workspace: %w", err)
+		}
+		if err := config.EnsureDir(workspace); err != nil {
+			return nil, fmt.Errorf("create workspace: %w", err)
+		}
+		if err := settings.Set("session.work_dir", workspace); err != nil {
+			return nil, err
+		}
+	}
+
+	// 环境变量中的 Key 不复制到密钥管理
+	if req.APIKey != "" && req.APIKey != config.ProviderAPIKey(cfg.Provider) {
+		if err := s.Secrets.Set(config.APIKeySecret(cfg.Provider), req.APIKey); err != nil {
+			return nil, fmt.Errorf("store API key: %w", err)
+		}
+		delete(settings.APIKeys, cfg.Provider)
+	}
+
+	if err := settings.Save(s.SettingsFile); err != nil {
+		return nil, err
+	}
+	return s.Status()
+}
@@ -0,0 +1,119 @@
+package onboarding
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/astercloud/aster/pkg/config"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+func newTestSetup(t *testing.T) (*Setup, *[]*types.ModelConfig) {
+	t.Helper()
+	t.Setenv("ANTHROPIC_API_KEY", "")
+	t.Setenv("OPENAI_API_KEY", "")
+	dir := t.TempDir()
+	var validated []*types.ModelConfig
+	return &Setup{
+		SettingsFile: filepath.Join(dir, "aster.yaml"),
+		Secrets:      config.NewFileSecretStore(filepath.Join(dir, "secrets.json")),
+		Validate: func(_ context.Context, cfg *types.ModelConfig) error {
+			validated = append(validated, cfg)
+			if cfg.APIKey == "bad-key" {
+				return errors.New("401 invalid x-api-key")
+			}
+			return nil
+		},
+	}, &validated
+}
+
+func TestSetupFirstRun(t *testing.T) {
+	setup, validated := newTestSetup(t)
+
+	status, err := setup.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !status.NeedsSetup || status.SettingsExists || status.Provider != "anthropic" {
+		t.Fatalf("first run status = %+v", status)
+	}
+
+	_, err = setup.Complete(context.Background(), Request{Provider: "openai", APIKey: "bad-key"})
+	if err == nil || !strings.Contains(err.Error(), "invalid x-api-key") {
+		t.Fatalf("invalid key: err = %v", err)
+	}
+	if _, err := os.Stat(setup.SettingsFile); !os.IsNotExist(err) {
+		t.Error("settings written although the key was rejected")
+	}
+
+	workspace := filepath.Join(t.TempDir(), "workspace")
+	status, err = setup.Complete(context.Background(), Request{Provider: "openai", APIKey: "sk-good", Workspace: workspace})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.NeedsSetup || status.Provider != "openai" || status.Model != "gpt-4o" || status.KeySource != config.APIKeySourceSecrets {
+		t.Errorf("status after setup = %+v", status)
+	}
+	if status.Workspace != workspace {
+		t.Errorf("workspace = %q, want %q", status.Workspace, workspace)
+	}
+	if info, err := os.Stat(workspace); err != nil || !info.IsDir() {
+		t.Errorf("workspace not created: %v", err)
+	}
+	if last := (*validated)[len(*validated)-1]; last.Model != "gpt-4o" || last.APIKey != "sk-good" {
+		t.Errorf("validated %+v", last)
+	}
+
+	// The key lives in the secret store, not in the settings file
+	data, err := os.ReadFile(setup.SettingsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "sk-good") {
+		t.Error("API key written to the settings file")
+	}
+	settings, err := setup.Settings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := settings.APIKey("openai"); got != "sk-good" {
+		t.Errorf("APIKey = %q", got)
+	}
+}
+
+func TestSetupMissingKey(t *testing.T) {
+	setup, validated := newTestSetup(t)
+
+	if _, err := setup.Complete(context.Background(), Request{Provider: "anthropic"}); !errors.Is(err, ErrAPIKeyRequired) {
+		t.Errorf("missing key: err = %v", err)
+	}
+	if _, err := setup.Complete(context.Background(), Request{Provider: "acme"}); err == nil {
+		t.Error("unknown provider without a model accepted")
+	}
+
+	// Local providers need no key
+	status, err := setup.Complete(context.Background(), Request{Provider: "ollama"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.NeedsSetup || status.Model != "llama3.2" || len(*validated) != 1 {
+		t.Errorf("ollama status = %+v, validations = %d", status, len(*validated))
+	}
+
+	// A key from the environment is used as is and not copied to the secret store
+	t.Setenv("ANTHROPIC_API_KEY", "sk-env")
+	status, err = setup.Complete(context.Background(), Request{Provider: "anthropic", SkipValidation: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.KeySource != config.APIKeySourceEnv {
+		t.Errorf("key source = %q", status.KeySource)
+	}
+	if _, err := setup.Secrets.Get(config.APIKeySecret("anthropic")); !errors.Is(err, config.ErrSecretNotFound) {
+		t.Errorf("environment key copied to the secret store: %v", err)
+	}
+}
@@ -0,0 +1,326 @@
+package patch
+
+import (
+	"slices"
+	"strings"
+)
+
+// HunkStatus 块的应用结果
+type HunkStatus string
+
+const (
+	HunkApplied  HunkStatus = "applied"
+	HunkConflict HunkStatus = "conflict" // 已写入冲突标记
+	HunkRejected HunkStatus = "rejected"
+	HunkSkipped  HunkStatus = "skipped" // 已应用过或没有变更
+)
+
+// Method 块的定位方式
+type Method string
+
+const (
+	MethodExact          Method = "exact"           // 在期望的行号处精确匹配
+	MethodOffset         Method = "offset"          // 在其他位置精确匹配
+	MethodWhitespace     Method = "whitespace"      // 忽略空白差异后匹配
+	MethodFuzz           Method = "fuzz"            // 忽略部分上下文行后匹配
+	MethodMerge          Method = "merge"           // 三方合并
+	MethodAlreadyApplied Method = "already_applied" // 文件中已是修改后的内容
+)
+
+// HunkResult 单个块的应用结果
+type HunkResult struct {
+	Index  int        `json:"index"` // 块序号（1 起始）
+	Header string     `json:"header"`
+	Status HunkStatus `json:"status"`
+	Method Method     `json:"method,omitempty"`
+	// Line 块在结果文件中的起始行（1 起始）
+	Line int `json:"line,omitempty"`
+	// Offset 实际位置与 @@ 行中行号的偏差
+	Offset int `json:"offset,omitempty"`
+	// Fuzz 忽略的上下文行数
+	Fuzz   int    `json:"fuzz,omitempty"`
+	Reason string `json:"reason,omitempty"`
+	// Expected 被拒绝的块期望的原内容
+	Expected []string `json:"expected,omitempty"`
+	// ClosestLine/Closest 文件中与期望内容最接近的片段
+	ClosestLine int      `json:"closest_line,omitempty"`
+	Closest     []string `json:"closest,omitempty"`
+}
+
+// Options 应用选项
+type Options struct {
+	// MaxFuzz 最多忽略的首尾上下文行数，默认 2，负数表示不允许
+	MaxFuzz int
+	// NoMerge 关闭三方合并
+	NoMerge bool
+	// ConflictMarkers 合并冲突时写入冲突标记，否则拒绝该块
+	ConflictMarkers bool
+}
+
+func (o Options) maxFuzz() int {
+	switch {
+	case o.MaxFuzz < 0:
+		return 0
+	case o.MaxFuzz == 0:
+		return 2
+	default:
+		return o.MaxFuzz
+	}
+}
+
+// Result 单个文件的应用结果
+type Result struct {
+	Content string
+	Hunks   []HunkResult
+}
+
+// Applied 返回成功应用（含冲突标记）的块数
+func (r *Result) Applied() int {
+	n := 0
+	for _, h := range r.Hunks {
+		if h.Status == HunkApplied || h.Status == HunkConflict {
+			n++
+		}
+	}
+	return n
+}
+
+// Count 返回指定状态的块数
+func (r *Result) Count(status HunkStatus) int {
+	n := 0
+	for _, h := range r.Hunks {
+		if h.Status == status {
+			n++
+		}
+	}
+	return n
+}
+
+// text 按行拆分的文件内容
+type text struct {
+	lines    []string
+	crlf     bool
+	trailing bool // 末尾有换行
+}
+
+func splitText(s string) *text {
+	t := &text{crlf: strings.Contains(s, "\r\n")}
+	if s == "" {
+		return t
+	}
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	t.trailing = strings.HasSuffix(s, "\n")
+	t.lines = strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+	return t
+}
+
+func (t *text) String() string {
+	if len(t.lines) == 0 {
+		return ""
+	}
+	sep := "\n"
+	if t.crlf {
+		sep = "\r\n"
+	}
+	s := strings.Join(t.lines, sep)
+	if t.trailing {
+		s += sep
+	}
+	return s
+}
+
+// Apply 将块依次应用到文件内容
+// 每个块先按行号精确匹配，再依次尝试偏移、忽略空白、模糊（减少上下文）和三方合并
+func Apply(original string, hunks []*Hunk, opts Options) *Result {
+	t := splitText(original)
+	result := &Result{}
+	delta, from := 0, 0
+
+	for i, h := range hunks {
+		hr := HunkResult{Index: i + 1, Header: h.String()}
+		oldLines, newLines := h.OldLines(), h.NewLines()
+		expected := from
+		if h.OldStart > 0 {
+			expected = max(h.OldStart-1+delta, 0)
+		}
+
+		if slices.Equal(oldLines, newLines) {
+			hr.Status, hr.Reason = HunkSkipped, "hunk has no changes"
+			result.Hunks = append(result.Hunks, hr)
+			continue
+		}
+
+		pos, method, fuzz, hunk := locate(t.lines, h, expected, from, opts.maxFuzz())
+		if pos < 0 && len(oldLines) > 0 && findBlock(t.lines, newLines, expected, from, exactEqual) >= 0 {
+			hr.Status, hr.Method = HunkSkipped, MethodAlreadyApplied
+			result.Hunks = append(result.Hunks, hr)
+			continue
+		}
+
+		if pos >= 0 {
+			replacement := replaceLines(t.lines[pos:pos+len(hunk.OldLines())], hunk)
+			atEnd := pos+len(hunk.OldLines()) == len(t.lines)
+			t.lines = slices.Concat(t.lines[:pos], replacement, t.lines[pos+len(hunk.OldLines()):])
+			if atEnd && len(replacement) > 0 {
+				t.trailing = !h.NoNewlineAtEnd
+			}
+			hr.Status, hr.Method, hr.Fuzz, hr.Line = HunkApplied, method, fuzz, pos+1
+			if h.OldStart > 0 {
+				hr.Offset = pos - expected
+			}
+			delta += len(replacement) - len(hunk.OldLines())
+			from = pos + len(replacement)
+			result.Hunks = append(result.Hunks, hr)
+			continue
+		}
+
+		// 三方合并：以块的原内容为基准，合并文件当前内容和块的新内容
+		start, end := closestWindow(t.lines, oldLines, expected, from)
+		if !opts.NoMerge && start >= 0 {
+			merged, conflict := merge3(oldLines, t.lines[start:end], newLines)
+			if !conflict || opts.ConflictMarkers {
+				t.lines = slices.Concat(t.lines[:start], merged, t.lines[end:])
+				hr.Status, hr.Method, hr.Line = HunkApplied, MethodMerge, start+1
+				if conflict {
+					hr.Status, hr.Reason = HunkConflict, "conflicting changes, resolve the conflict markers"
+				}
+				if h.OldStart > 0 {
+					hr.Offset = start - expected
+				}
+				delta += len(merged) - (end - start)
+				from = start + len(merged)
+				result.Hunks = append(result.Hunks, hr)
+				continue
+			}
+		}
+
+		hr.Status = HunkRejected
+		hr.Reason = "context not found in file"
+		if !opts.NoMerge && start >= 0 {
+			hr.Reason = "conflicting changes in file"
+		}
+		hr.Expected = oldLines
+		if start >= 0 {
+			hr.ClosestLine = start + 1
+			hr.Closest = slices.Clone(t.lines[start:end])
+		}
+		result.Hunks = append(result.Hunks, hr)
+	}
+
+	result.Content = t.String()
+	return result
+}
+
+// locate 按精确、偏移、空白、模糊的顺序定位块，返回位置、方式、模糊行数和实际使用的块
+func locate(lines []string, h *Hunk, expected, from, maxFuzz int) (int, Method, int, *Hunk) {
+	oldLines := h.OldLines()
+	if len(oldLines) == 0 {
+		// 纯插入：新建文件或没有上下文的块
+		return min(max(expected, from), len(lines)), MethodExact, 0, h
+	}
+
+	if pos := findBlock(lines, oldLines, expected, from, exactEqual); pos >= 0 {
+		if pos == expected {
+			return pos, MethodExact, 0, h
+		}
+		return pos, MethodOffset, 0, h
+	}
+	if pos := findBlock(lines, oldLines, expected, from, looseEqual); pos >= 0 {
+		return pos, MethodWhitespace, 0, h
+	}
+	for fuzz := 1; fuzz <= maxFuzz; fuzz++ {
+		trimmed, head := trimContext(h, fuzz)
+		if trimmed == nil {
+			break
+		}
+		if pos := findBlock(lines, trimmed.OldLines(), expected+head, from, looseEqual); pos >= 0 {
+			return pos, MethodFuzz, fuzz, trimmed
+		}
+	}
+	return -1, "", 0, nil
+}
+
+// trimContext 去掉块首尾各最多 fuzz 行上下文，返回新块和去掉的开头行数
+// 没有可去掉的上下文或去掉后没有剩余上下文时返回 nil
+func trimContext(h *Hunk, fuzz int) (*Hunk, int) {
+	head := 0
+	for head < fuzz && head < len(h.Lines) && h.Lines[head].Kind == LineContext {
+		head++
+	}
+	tail := 0
+	for tail < fuzz && tail < len(h.Lines)-head && h.Lines[len(h.Lines)-1-tail].Kind == LineContext {
+		tail++
+	}
+	if head < fuzz && tail < fuzz {
+		return nil, 0
+	}
+	lines := h.Lines[head : len(h.Lines)-tail]
+	hasContext := slices.ContainsFunc(lines, func(l Line) bool { return l.Kind == LineContext })
+	hasDelete := slices.ContainsFunc(lines, func(l Line) bool { return l.Kind == LineDelete })
+	if !hasContext && !hasDelete {
+		return nil, 0
+	}
+	trimmed := *h
+	trimmed.Lines = lines
+	return &trimmed, head
+}
+
+// replaceLines 生成替换内容：上下文行保留文件中的原样，新增行取自块
+func replaceLines(current []string, h *Hunk) []string {
+	out := make([]string, 0, len(current))
+	i := 0
+	for _, l := range h.Lines {
+		switch l.Kind {
+		case LineContext:
+			out = append(out, current[i])
+			i++
+		case LineDelete:
+			i++
+		case LineAdd:
+			out = append(out, l.Text)
+		}
+	}
+	return out
+}
+
+type lineEqual func(a, b string) bool
+
+func exactEqual(a, b string) bool {
+	return a == b
+}
+
+// looseEqual 忽略空白差异比较两行
+func looseEqual(a, b string) bool {
+	return normalize(a) == normalize(b)
+}
+
+func normalize(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// findBlock 查找 block 在 lines 中的位置（不早于 from），多处匹配时取最接近 expected 的
+func findBlock(lines, block []string, expected, from int, eq lineEqual) int {
+	best, bestDist := -1, 0
+	for pos := from; pos+len(block) <= len(lines); pos++ {
+		if !blockEqual(lines[pos:pos+len(block)], block, eq) {
+			continue
+		}
+		dist := pos - expected
+		if dist < 0 {
+			dist = -dist
+		}
+		if best < 0 || dist < bestDist {
+			best, bestDist = pos, dist
+		}
+	}
+	return best
+}
+
+func blockEqual(a, b []string, eq lineEqual) bool {
+	for i := range b {
+		if !eq(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,192 @@
+package patch
+
+// 冲突标记（diff3 风格，包含基准内容便于判断双方的修改）
+const (
+	markerCurrent = "<<<<<<< current"
+	markerBase    = "||||||| patch base"
+	markerSep     = "======="
+	markerPatch   = ">>>>>>> patch"
+)
+
+// HasConflictMarkers 检查内容中是否有未解决的冲突标记
+func HasConflictMarkers(content string) bool {
+	for _, line := range splitText(content).lines {
+		if line == markerCurrent || line == markerPatch {
+			return true
+		}
+	}
+	return false
+}
+
+// closestWindow 查找与 block 最相似的片段 [start, end)
+// 相似度为忽略空白后的最长公共子序列长度，至少匹配一半的行才视为找到，否则返回 -1
+func closestWindow(lines, block []string, expected, from int) (int, int) {
+	n := len(block)
+	if n == 0 || len(lines) == 0 {
+		return -1, -1
+	}
+	normBlock := make([]string, n)
+	for i, l := range block {
+		normBlock[i] = normalize(l)
+	}
+	normLines := make([]string, len(lines))
+	for i, l := range lines {
+		normLines[i] = normalize(l)
+	}
+
+	// 候选起点：与块开头几行相同的行，以及期望位置附近
+	heads := make(map[string]bool)
+	for _, l := range normBlock[:min(3, n)] {
+		heads[l] = true
+	}
+	minWidth, maxWidth := max(1, n-n/3), n+n/3+1
+
+	bestStart, bestEnd, bestScore := -1, -1, 0
+	better := func(start, width, score int) bool {
+		if score != bestScore {
+			return score > bestScore
+		}
+		if dw, bw := abs(width-n), abs(bestEnd-bestStart-n); dw != bw {
+			return dw < bw
+		}
+		return abs(start-expected) < abs(bestStart-expected)
+	}
+	for start := from; start < len(lines); start++ {
+		if !heads[normLines[start]] && abs(start-expected) > n {
+			continue
+		}
+		for width := minWidth; width <= maxWidth && start+width <= len(lines); width++ {
+			score := len(lcs(normBlock, normLines[start:start+width]))
+			if score > 0 && better(start, width, score) {
+				bestStart, bestEnd, bestScore = start, start+width, score
+			}
+		}
+	}
+	if bestScore*2 < n {
+		return -1, -1
+	}
+	return bestStart, bestEnd
+}
+
+// lcs 返回 a、b 最长公共子序列的下标对
+func lcs(a, b []string) [][2]int {
+	m, n := len(a), len(b)
+	dp := make([][]int, m+1)
+	for i := range dp {
+		dp[i] = make([]int, n+1)
+	}
+	for i := m - 1; i >= 0; i-- {
+		for j := n - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				dp[i][j] = dp[i+1][j+1] + 1
+			} else {
+				dp[i][j] = max(dp[i+1][j], dp[i][j+1])
+			}
+		}
+	}
+	pairs := make([][2]int, 0, dp[0][0])
+	for i, j := 0, 0; i < m && j < n; {
+		switch {
+		case a[i] == b[j]:
+			pairs = append(pairs, [2]int{i, j})
+			i++
+			j++
+		case dp[i+1][j] >= dp[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+	return pairs
+}
+
+// matchIndex 返回 base 每一行在 other 中对应的行号（未匹配为 -1）
+func matchIndex(base, other []string) []int {
+	nb, no := make([]string, len(base)), make([]string, len(other))
+	for i, l := range base {
+		nb[i] = normalize(l)
+	}
+	for i, l := range other {
+		no[i] = normalize(l)
+	}
+	match := make([]int, len(base))
+	for i := range match {
+		match[i] = -1
+	}
+	for _, p := range lcs(nb, no) {
+		match[p[0]] = p[1]
+	}
+	return match
+}
+
+// merge3 以 base 为基准合并 current（文件当前内容）和 patched（块的新内容）
+// 双方修改了同一区域且结果不同时写入冲突标记并返回 conflict
+func merge3(base, current, patched []string) ([]string, bool) {
+	matchA := matchIndex(base, current)
+	matchB := matchIndex(base, patched)
+
+	var out []string
+	conflict := false
+	i, a, b := 0, 0, 0
+	for {
+		// 三方一致的稳定行直接输出（使用文件中的原样）
+		for i < len(base) && matchA[i] == a && matchB[i] == b {
+			out = append(out, current[a])
+			i, a, b = i+1, a+1, b+1
+		}
+
+		// 找到下一个三方都存在的稳定行，其间为不稳定块
+		next := i
+		for next < len(base) && (matchA[next] < a || matchB[next] < b) {
+			next++
+		}
+		endA, endB := len(current), len(patched)
+		if next < len(base) {
+			endA, endB = matchA[next], matchB[next]
+		}
+		if i == next && a == endA && b == endB {
+			break
+		}
+
+		baseChunk, chunkA, chunkB := base[i:next], current[a:endA], patched[b:endB]
+		switch {
+		case linesEqual(chunkA, baseChunk):
+			out = append(out, chunkB...)
+		case linesEqual(chunkB, baseChunk), linesEqual(chunkA, chunkB):
+			out = append(out, chunkA...)
+		default:
+			conflict = true
+			out = append(out, markerCurrent)
+			out = append(out, chunkA...)
+			out = append(out, markerBase)
+			out = append(out, baseChunk...)
+			out = append(out, markerSep)
+			out = append(out, chunkB...)
+			out = append(out, markerPatch)
+		}
+		i, a, b = next, endA, endB
+		if next >= len(base) {
+			break
+		}
+	}
+	return out, conflict
+}
+
+func linesEqual(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if normalize(a[i]) != normalize(b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
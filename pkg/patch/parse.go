@@ -0,0 +1,247 @@
+package patch
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// DevNull 新建或删除文件时 diff 中使用的空路径
+const DevNull = "/dev/null"
+
+// FileDiff 单个文件的 diff
+type FileDiff struct {
+	OldPath string
+	NewPath string
+	Hunks   []*Hunk
+}
+
+// IsNew 是否为新建文件
+func (f *FileDiff) IsNew() bool {
+	return f.OldPath == DevNull
+}
+
+// IsDelete 是否为删除文件
+func (f *FileDiff) IsDelete() bool {
+	return f.NewPath == DevNull
+}
+
+// IsRename 是否为重命名
+func (f *FileDiff) IsRename() bool {
+	return !f.IsNew() && !f.IsDelete() && f.OldPath != f.NewPath
+}
+
+// Path 返回 diff 作用的文件路径（删除时为旧路径）
+func (f *FileDiff) Path() string {
+	if f.IsDelete() {
+		return f.OldPath
+	}
+	return f.NewPath
+}
+
+// LineKind diff 行类型
+type LineKind byte
+
+const (
+	LineContext LineKind = ' '
+	LineDelete  LineKind = '-'
+	LineAdd     LineKind = '+'
+)
+
+// Line diff 中的一行
+type Line struct {
+	Kind LineKind
+	Text string
+}
+
+// Hunk 一个 diff 块
+type Hunk struct {
+	// OldStart 原文件起始行（1 起始，0 表示未知，按内容定位）
+	OldStart int
+	NewStart int
+	// Header @@ 行中的函数上下文
+	Header string
+	Lines  []Line
+	// NoNewlineAtEnd 新内容末尾没有换行（"\ No newline at end of file"）
+	NoNewlineAtEnd bool
+}
+
+// OldLines 返回块的原内容（上下文和删除行）
+func (h *Hunk) OldLines() []string {
+	return h.collect(LineDelete)
+}
+
+// NewLines 返回块的新内容（上下文和新增行）
+func (h *Hunk) NewLines() []string {
+	return h.collect(LineAdd)
+}
+
+func (h *Hunk) collect(kind LineKind) []string {
+	lines := make([]string, 0, len(h.Lines))
+	for _, l := range h.Lines {
+		if l.Kind == LineContext || l.Kind == kind {
+			lines = append(lines, l.Text)
+		}
+	}
+	return lines
+}
+
+// String 返回块的 @@ 行
+func (h *Hunk) String() string {
+	old, added := len(h.OldLines()), len(h.NewLines())
+	s := fmt.Sprintf("@@ -%d,%d +%d,%d @@", h.OldStart, old, h.NewStart, added)
+	if h.Header != "" {
+		s += " " + h.Header
+	}
+	return s
+}
+
+// hunkHeader 匹配 @@ -a,b +c,d @@ header，数字可省略（模型常写 "@@ ... @@"）
+var hunkHeader = regexp.MustCompile(`^@@+\s*(?:-(\d+)(?:,\d+)?)?\s*(?:\+(\d+)(?:,\d+)?)?\s*@@+\s?(.*)$`)
+
+// Parse 解析统一 diff（unified diff），支持多文件、git diff 头和新建/删除/重命名文件
+// 块的行数以实际内容为准，不要求 @@ 行中的计数正确
+func Parse(diff string) ([]*FileDiff, error) {
+	var (
+		files   []*FileDiff
+		current *FileDiff
+		hunk    *Hunk
+		git     *FileDiff // diff --git 头声明的文件，用于只有重命名、没有块的 diff
+	)
+
+	lines := strings.Split(strings.ReplaceAll(diff, "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		lineNo := i + 1
+		next := ""
+		if i+1 < len(lines) {
+			next = lines[i+1]
+		}
+
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			hunk, current = nil, nil
+			oldPath, newPath := parseGitHeader(line)
+			git = &FileDiff{OldPath: oldPath, NewPath: newPath}
+
+		// 块内删除的 "-- x" 行也以 "--- " 开头，只有紧跟 "+++ " 时才是文件头
+		case strings.HasPrefix(line, "--- ") && strings.HasPrefix(next, "+++ "):
+			hunk = nil
+			current = &FileDiff{OldPath: parsePath(line[4:])}
+			if current.OldPath == "" && git != nil {
+				current.OldPath = git.OldPath
+			}
+
+		case strings.HasPrefix(line, "+++ ") && current != nil && current.NewPath == "":
+			current.NewPath = parsePath(line[4:])
+			if current.NewPath == "" && git != nil {
+				current.NewPath = git.NewPath
+			}
+			files = append(files, current)
+			git = nil
+
+		case strings.HasPrefix(line, "@@"):
+			m := hunkHeader.FindStringSubmatch(line)
+			if m == nil {
+				return nil, fmt.Errorf("line %d: invalid hunk header: %s", lineNo, line)
+			}
+			if current == nil || current.NewPath == "" {
+				return nil, fmt.Errorf("line %d: hunk without file header", lineNo)
+			}
+			oldStart, _ := strconv.Atoi(m[1])
+			newStart, _ := strconv.Atoi(m[2])
+			hunk = &Hunk{OldStart: oldStart, NewStart: newStart, Header: strings.TrimSpace(m[3])}
+			current.Hunks = append(current.Hunks, hunk)
+
+		case strings.HasPrefix(line, `\`):
+			// "\ No newline at end of file" 作用于前一行
+			if hunk != nil && len(hunk.Lines) > 0 && hunk.Lines[len(hunk.Lines)-1].Kind != LineDelete {
+				hunk.NoNewlineAtEnd = true
+			}
+
+		case hunk != nil && line == "":
+			// 部分编辑器会去掉空上下文行的前导空格
+			hunk.Lines = append(hunk.Lines, Line{Kind: LineContext})
+
+		case hunk != nil && (line[0] == ' ' || line[0] == '-' || line[0] == '+'):
+			hunk.Lines = append(hunk.Lines, Line{Kind: LineKind(line[0]), Text: line[1:]})
+
+		case git != nil && strings.HasPrefix(line, "rename to "):
+			git.NewPath = strings.TrimPrefix(line, "rename to ")
+			if strings.HasPrefix(next, "--- ") {
+				continue
+			}
+			files = append(files, git)
+			git = nil
+
+		case git != nil && strings.HasPrefix(line, "rename from "):
+			git.OldPath = strings.TrimPrefix(line, "rename from ")
+
+		default:
+			// git 扩展头（index、new file mode 等）和说明文字
+			hunk = nil
+			if current != nil && current.NewPath != "" {
+				current = nil
+			}
+		}
+	}
+
+	if len(files) == 0 {
+		return nil, errors.New("no file diffs found (expected --- and +++ headers)")
+	}
+	for _, f := range files {
+		trimTrailingEmptyContext(f)
+		if len(f.Hunks) == 0 && !f.IsDelete() && !f.IsRename() {
+			return nil, fmt.Errorf("%s: diff has no hunks", f.Path())
+		}
+	}
+	return files, nil
+}
+
+// trimTrailingEmptyContext 去掉块末尾因 diff 结尾空行产生的空上下文行
+func trimTrailingEmptyContext(f *FileDiff) {
+	for _, h := range f.Hunks {
+		for len(h.Lines) > 0 {
+			last := h.Lines[len(h.Lines)-1]
+			if last.Kind != LineContext || last.Text != "" {
+				break
+			}
+			h.Lines = h.Lines[:len(h.Lines)-1]
+		}
+	}
+}
+
+// parsePath 解析 ---/+++ 行中的路径，去掉时间戳和 a/、b/ 前缀
+func parsePath(s string) string {
+	if i := strings.IndexByte(s, '\t'); i >= 0 {
+		s = s[:i]
+	}
+	s = strings.TrimSpace(s)
+	if unquoted, err := strconv.Unquote(s); err == nil {
+		s = unquoted
+	}
+	if s == DevNull {
+		return s
+	}
+	return stripPrefix(s)
+}
+
+// parseGitHeader 解析 "diff --git a/x b/y" 中的路径
+func parseGitHeader(line string) (string, string) {
+	rest := strings.TrimPrefix(line, "diff --git ")
+	if i := strings.Index(rest, " b/"); i >= 0 {
+		return stripPrefix(rest[:i]), stripPrefix(rest[i+1:])
+	}
+	if old, newPath, ok := strings.Cut(rest, " "); ok {
+		return stripPrefix(old), stripPrefix(newPath)
+	}
+	return "", ""
+}
+
+func stripPrefix(p string) string {
+	if strings.HasPrefix(p, "a/") || strings.HasPrefix(p, "b/") {
+		return p[2:]
+	}
+	return p
+}
@@ -0,0 +1,283 @@
+package patch
+
+import (
+	"strings"
+	"testing"
+)
+
+const source = `package main
+
+import "fmt"
+
+func main() {
+	fmt.Println("hello")
+	fmt.Println("world")
+}
+
+func helper() int {
+	return 1
+}
+`
+
+func mustParse(t *testing.T, diff string) []*FileDiff {
+	t.Helper()
+	files, err := Parse(diff)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	return files
+}
+
+func TestParse(t *testing.T) {
+	diff := `Some explanation from the model.
+
+diff --git a/main.go b/main.go
+index 1111111..2222222 100644
+--- a/main.go
++++ b/main.go
+@@ -5,3 +5,3 @@ func main() {
+ func main() {
+-	fmt.Println("hello")
++	fmt.Println("hi")
+ 	fmt.Println("world")
+--- /dev/null
++++ b/notes.txt
+@@ -0,0 +1,2 @@
++-- not a header
++last
+\ No newline at end of file
+diff --git a/old.txt b/new.txt
+similarity index 100%
+rename from old.txt
+rename to new.txt
+`
+	files := mustParse(t, diff)
+	if len(files) != 3 {
+		t.Fatalf("len(files) = %d, want 3", len(files))
+	}
+	if f := files[0]; f.Path() != "main.go" || len(f.Hunks) != 1 || f.Hunks[0].OldStart != 5 || f.Hunks[0].Header != "func main() {" {
+		t.Errorf("files[0] = %+v, hunk = %+v", f, f.Hunks[0])
+	}
+	if f := files[1]; !f.IsNew() || f.Path() != "notes.txt" || !f.Hunks[0].NoNewlineAtEnd || f.Hunks[0].NewLines()[0] != "-- not a header" {
+		t.Errorf("files[1] = %+v", f)
+	}
+	if f := files[2]; !f.IsRename() || f.OldPath != "old.txt" || f.NewPath != "new.txt" {
+		t.Errorf("files[2] = %+v", f)
+	}
+
+	if _, err := Parse("just some text"); err == nil {
+		t.Error("Parse() should fail without file headers")
+	}
+}
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name   string
+		diff   string
+		opts   Options
+		status HunkStatus
+		method Method
+		want   string // 结果中应包含的内容
+	}{
+		{
+			name: "exact",
+			diff: `--- a/main.go
++++ b/main.go
+@@ -5,4 +5,4 @@
+ func main() {
+-	fmt.Println("hello")
++	fmt.Println("hi")
+ 	fmt.Println("world")
+ }
+`,
+			status: HunkApplied, method: MethodExact, want: `fmt.Println("hi")`,
+		},
+		{
+			name: "offset with wrong line numbers",
+			diff: `--- a/main.go
++++ b/main.go
+@@ -40,3 +40,3 @@
+ func helper() int {
+-	return 1
++	return 2
+ }
+`,
+			status: HunkApplied, method: MethodOffset, want: "return 2",
+		},
+		{
+			name: "whitespace differences",
+			diff: `--- a/main.go
++++ b/main.go
+@@ -10,3 +10,3 @@
+ func helper()   int {
+-    return 1
++	return 3
+ }
+`,
+			status: HunkApplied, method: MethodWhitespace, want: "func helper() int {\n\treturn 3",
+		},
+		{
+			name: "fuzz ignores stale context",
+			diff: `--- a/main.go
++++ b/main.go
+@@ -5,5 +5,5 @@
+ func main() {
+ 	fmt.Println("hello")
+-	fmt.Println("world")
++	fmt.Println("everyone")
+ }
+ // stale context line
+`,
+			status: HunkApplied, method: MethodFuzz, want: `fmt.Println("everyone")`,
+		},
+		{
+			name: "already applied",
+			diff: `--- a/main.go
++++ b/main.go
+@@ -3,1 +3,1 @@
+-import "os"
++import "fmt"
+`,
+			status: HunkSkipped, method: MethodAlreadyApplied,
+		},
+		{
+			name: "three-way merge with a concurrent edit",
+			diff: `--- a/main.go
++++ b/main.go
+@@ -5,4 +5,5 @@
+ func main() {
+ 	fmt.Println("greetings")
+ 	fmt.Println("world")
++	fmt.Println("!")
+ }
+`,
+			opts:   Options{MaxFuzz: -1},
+			status: HunkApplied, method: MethodMerge, want: "\tfmt.Println(\"hello\")\n\tfmt.Println(\"world\")\n\tfmt.Println(\"!\")\n}",
+		},
+		{
+			name: "conflict markers",
+			diff: `--- a/main.go
++++ b/main.go
+@@ -5,4 +5,4 @@
+ func main() {
+-	fmt.Println("greetings")
++	fmt.Println("hey")
+ 	fmt.Println("world")
+ }
+`,
+			opts:   Options{MaxFuzz: -1, ConflictMarkers: true},
+			status: HunkConflict, method: MethodMerge, want: "<<<<<<< current\n\tfmt.Println(\"hello\")\n||||||| patch base\n\tfmt.Println(\"greetings\")\n=======\n\tfmt.Println(\"hey\")\n>>>>>>> patch",
+		},
+		{
+			name: "conflict rejected",
+			diff: `--- a/main.go
++++ b/main.go
+@@ -5,4 +5,4 @@
+ func main() {
+-	fmt.Println("greetings")
++	fmt.Println("hey")
+ 	fmt.Println("world")
+ }
+`,
+			opts:   Options{MaxFuzz: -1},
+			status: HunkRejected,
+		},
+		{
+			name: "unrelated context rejected",
+			diff: `--- a/main.go
++++ b/main.go
+@@ -1,3 +1,3 @@
+ alpha
+-beta
++gamma
+ delta
+`,
+			status: HunkRejected,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files := mustParse(t, tt.diff)
+			result := Apply(source, files[0].Hunks, tt.opts)
+			h := result.Hunks[0]
+			if h.Status != tt.status || h.Method != tt.method {
+				t.Fatalf("hunk = %+v, want status %s method %q", h, tt.status, tt.method)
+			}
+			if tt.want != "" && !strings.Contains(result.Content, tt.want) {
+				t.Errorf("Content = %s\nwant it to contain %q", result.Content, tt.want)
+			}
+			if h.Status == HunkRejected || h.Status == HunkSkipped {
+				if result.Content != source {
+					t.Errorf("Content changed for a %s hunk:\n%s", h.Status, result.Content)
+				}
+				if h.Status == HunkRejected && len(h.Expected) == 0 {
+					t.Error("rejected hunk should report the expected lines")
+				}
+			}
+		})
+	}
+}
+
+func TestApply_MultipleHunksAndNewline(t *testing.T) {
+	diff := `--- a/main.go
++++ b/main.go
+@@ -3,1 +3,4 @@
+-import "fmt"
++import (
++	"fmt"
++	"os"
++)
+@@ -11,2 +14,2 @@
+-	return 1
+-}
++	return len(os.Args)
++}
+\ No newline at end of file
+`
+	files := mustParse(t, diff)
+	result := Apply(source, files[0].Hunks, Options{})
+	if result.Applied() != 2 {
+		t.Fatalf("hunks = %+v", result.Hunks)
+	}
+	if !strings.HasSuffix(result.Content, "return len(os.Args)\n}") {
+		t.Errorf("Content should end without a newline: %q", result.Content[len(result.Content)-30:])
+	}
+	if result.Hunks[1].Line != 14 || result.Hunks[1].Method != MethodExact {
+		t.Errorf("second hunk = %+v, want exact at line 14", result.Hunks[1])
+	}
+
+	created := Apply("", mustParse(t, "--- /dev/null\n+++ b/a.txt\n@@ -0,0 +1,2 @@\n+one\n+two\n")[0].Hunks, Options{})
+	if created.Content != "one\ntwo\n" {
+		t.Errorf("new file content = %q", created.Content)
+	}
+
+	crlf := Apply("a\r\nb\r\n", mustParse(t, "--- a/x\n+++ b/x\n@@ -1,2 +1,2 @@\n a\n-b\n+c\n")[0].Hunks, Options{})
+	if crlf.Content != "a\r\nc\r\n" {
+		t.Errorf("CRLF content = %q", crlf.Content)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		path    string
+		content string
+		checked bool
+		wantErr bool
+	}{
+		{"main.go", source, true, false},
+		{"main.go", "package main\nfunc {", true, true},
+		{"a.json", `{"a": 1}`, true, false},
+		{"a.json", `{"a": }`, true, true},
+		{"a.yaml", "a: 1\n---\nb: 2\n", true, false},
+		{"a.yml", "a: [1\n", true, true},
+		{"a.txt", "anything", false, false},
+		{"main.go", "<<<<<<< current\nx\n=======\ny\n>>>>>>> patch\n", false, false},
+	}
+	for _, tt := range tests {
+		checked, err := Validate(tt.path, tt.content)
+		if checked != tt.checked || (err != nil) != tt.wantErr {
+			t.Errorf("Validate(%s, %q) = %v, %v", tt.path, tt.content, checked, err)
+		}
+	}
+}
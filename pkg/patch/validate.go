@@ -0,0 +1,42 @@
+package patch
+
+import (
+	"encoding/json"
+	"errors"
+	"go/parser"
+	"go/token"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Validate 检查修改后的文件能否被解析
+// 返回是否支持检查该文件类型；有冲突标记的内容不检查
+func Validate(path, content string) (bool, error) {
+	if HasConflictMarkers(content) {
+		return false, nil
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".go":
+		_, err := parser.ParseFile(token.NewFileSet(), filepath.Base(path), content, parser.AllErrors)
+		return true, err
+	case ".json":
+		var v any
+		return true, json.Unmarshal([]byte(content), &v)
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(strings.NewReader(content))
+		for {
+			var v any
+			if err := dec.Decode(&v); err != nil {
+				if errors.Is(err, io.EOF) {
+					return true, nil
+				}
+				return true, err
+			}
+		}
+	default:
+		return false, nil
+	}
+}
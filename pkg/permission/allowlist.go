@@ -0,0 +1,215 @@
+package permission
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// CommandAllowlistPath is the project-local allowlist, relative to the workspace root
+var CommandAllowlistPath = filepath.Join(".aster", "allowed-commands.yaml")
+
+// commandAllowlistHeader is written above the learned commands
+const commandAllowlistHeader = `# Command prefixes approved in this project. Matching Bash commands run
+# without asking. Review before committing; delete an entry to require
+# approval again.
+`
+
+// shellMetacharacters make a command compound or redirected; such commands
+// are never learned and never matched, so "npm test && rm -rf ." still asks.
+var shellMetacharacters = []string{";", "&", "|", ">", "<", "`", "$(", "\n"}
+
+// unlearnableCommands are too dangerous to approve by prefix
+var unlearnableCommands = map[string]bool{
+	"rm": true, "rmdir": true, "sudo": true, "su": true, "doas": true,
+	"chmod": true, "chown": true, "dd": true, "mkfs": true, "shred": true,
+	"kill": true, "killall": true, "pkill": true, "reboot": true, "shutdown": true,
+	"curl": true, "wget": true, "ssh": true, "scp": true, "rsync": true,
+	"sh": true, "bash": true, "zsh": true, "eval": true, "exec": true, "xargs": true,
+	"env": true, "nohup": true, "time": true, "timeout": true, "watch": true,
+	"python": true, "python3": true, "node": true, "ruby": true, "perl": true, "php": true,
+	// These run arbitrary commands through their arguments (find -exec,
+	// awk system(), sed's e command), so no prefix of them is safe
+	"find": true, "awk": true, "gawk": true, "mawk": true, "nawk": true, "sed": true,
+	"parallel": true, "nice": true, "ionice": true, "stdbuf": true, "setsid": true,
+	"chroot": true, "command": true, "builtin": true, "busybox": true, "strace": true,
+	"flock": true, "unbuffer": true,
+}
+
+// commandRunners can run other commands through a subcommand or flag (npx
+// <pkg>, git -c alias..., make <target>). They are learned only together
+// with a subcommand, never as a bare program name.
+var commandRunners = map[string]bool{
+	"npx": true, "pnpx": true, "bunx": true, "uvx": true, "pipx": true,
+	"npm": true, "pnpm": true, "yarn": true, "bun": true, "git": true, "make": true,
+}
+
+// AllowedCommand is a learned command prefix
+type AllowedCommand struct {
+	Prefix    string    `yaml:"prefix"`
+	LearnedAt time.Time `yaml:"learned_at,omitempty"`
+}
+
+// CommandAllowlist is a project-local list of Bash command prefixes the user
+// has approved. Commands matching a prefix no longer require approval.
+type CommandAllowlist struct {
+	path     string
+	mu       sync.RWMutex
+	commands []AllowedCommand
+}
+
+// commandAllowlistFile is the on-disk layout of a CommandAllowlist
+type commandAllowlistFile struct {
+	Commands []AllowedCommand `yaml:"commands"`
+}
+
+// LoadCommandAllowlist loads the allowlist of a workspace. A missing file
+// yields an empty allowlist that is created on the first Learn. Entries that
+// could never have been learned (compound commands, dangerous programs, bare
+// command runners) are dropped, so a committed file cannot allow them.
+func LoadCommandAllowlist(workDir string) (*CommandAllowlist, error) {
+	path := filepath.Join(workDir, CommandAllowlistPath)
+	a := &CommandAllowlist{path: path}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return a, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var f commandAllowlistFile
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for _, c := range f.Commands {
+		if prefix := strings.Join(strings.Fields(c.Prefix), " "); validPrefix(prefix) {
+			a.commands = append(a.commands, AllowedCommand{Prefix: prefix, LearnedAt: c.LearnedAt})
+		}
+	}
+	return a, nil
+}
+
+// Path returns the allowlist file path
+func (a *CommandAllowlist) Path() string {
+	return a.path
+}
+
+// Prefixes returns the allowed command prefixes
+func (a *CommandAllowlist) Prefixes() []string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	prefixes := make([]string, len(a.commands))
+	for i, c := range a.commands {
+		prefixes[i] = c.Prefix
+	}
+	return prefixes
+}
+
+// Allows reports whether a command matches an allowed prefix
+func (a *CommandAllowlist) Allows(command string) bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.allows(command)
+}
+
+// allows matches a command against the prefixes; callers hold a.mu
+func (a *CommandAllowlist) allows(command string) bool {
+	if isCompoundCommand(command) {
+		return false
+	}
+	command = strings.Join(strings.Fields(command), " ")
+	for _, c := range a.commands {
+		if command == c.Prefix || strings.HasPrefix(command, c.Prefix+" ") {
+			return true
+		}
+	}
+	return false
+}
+
+// Learn records the prefix of an approved command and persists the allowlist.
+// It returns the learned prefix, or "" when the command cannot be learned or
+// is already allowed.
+func (a *CommandAllowlist) Learn(command string) (string, error) {
+	prefix := CommandPrefix(command)
+	if prefix == "" {
+		return "", nil
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.allows(command) {
+		return "", nil
+	}
+	a.commands = append(a.commands, AllowedCommand{Prefix: prefix, LearnedAt: time.Now()})
+	slices.SortFunc(a.commands, func(x, y AllowedCommand) int { return strings.Compare(x.Prefix, y.Prefix) })
+	if err := a.save(); err != nil {
+		return "", err
+	}
+	return prefix, nil
+}
+
+// save writes the allowlist to disk; callers hold a.mu
+func (a *CommandAllowlist) save() error {
+	data, err := yaml.Marshal(&commandAllowlistFile{Commands: a.commands})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(a.path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(a.path, append([]byte(commandAllowlistHeader), data...), 0o644)
+}
+
+// CommandPrefix returns the prefix learned from an approved command: the
+// program plus its subcommand when there is one ("go build ./..." gives
+// "go build", "ls -la" gives "ls"). Compound commands, environment
+// assignments, dangerous programs and command runners without a subcommand
+// yield "".
+func CommandPrefix(command string) string {
+	if isCompoundCommand(command) {
+		return ""
+	}
+	fields := strings.Fields(command)
+	if len(fields) == 0 || strings.Contains(fields[0], "=") || unlearnableCommands[filepath.Base(fields[0])] {
+		return ""
+	}
+	if len(fields) > 1 && isSubcommand(fields[1]) {
+		return fields[0] + " " + fields[1]
+	}
+	if commandRunners[filepath.Base(fields[0])] {
+		return ""
+	}
+	return fields[0]
+}
+
+// validPrefix reports whether a stored prefix is at least as specific as one
+// CommandPrefix could have learned
+func validPrefix(prefix string) bool {
+	learned := CommandPrefix(prefix)
+	return learned != "" && (prefix == learned || strings.HasPrefix(prefix, learned+" "))
+}
+
+// isCompoundCommand reports whether a command chains, pipes, redirects or substitutes
+func isCompoundCommand(command string) bool {
+	for _, m := range shellMetacharacters {
+		if strings.Contains(command, m) {
+			return true
+		}
+	}
+	return false
+}
+
+// isSubcommand reports whether an argument looks like a subcommand rather than a flag or path
+func isSubcommand(arg string) bool {
+	if arg == "" || strings.HasPrefix(arg, "-") || strings.ContainsAny(arg, `/\.:="'*?~$`) {
+		return false
+	}
+	return true
+}
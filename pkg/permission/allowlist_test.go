@@ -0,0 +1,157 @@
+package permission
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/astercloud/aster/pkg/types"
+)
+
+func TestCommandPrefix(t *testing.T) {
+	tests := map[string]string{
+		"npm test":              "npm test",
+		"go build ./...":        "go build",
+		"  git   status  ":      "git status",
+		"ls -la":                "ls",
+		"make":                  "",
+		"make test":             "make test",
+		"npx --yes cowsay":      "",
+		"find . -name x":        "",
+		"awk '{print $1}' f":    "",
+		"git --version":         "",
+		"cat ./README.md":       "cat",
+		"npm test && rm -rf .":  "",
+		"go test ./... | tee x": "",
+		"echo $(whoami)":        "",
+		"FOO=1 npm test":        "",
+		"rm -rf build":          "",
+		"/usr/bin/sudo make":    "",
+		"python3 -c 'print(1)'": "",
+		"":                      "",
+	}
+	for command, want := range tests {
+		if got := CommandPrefix(command); got != want {
+			t.Errorf("CommandPrefix(%q) = %q, want %q", command, got, want)
+		}
+	}
+}
+
+func TestCommandAllowlist(t *testing.T) {
+	dir := t.TempDir()
+	allowlist, err := LoadCommandAllowlist(dir)
+	if err != nil {
+		t.Fatalf("LoadCommandAllowlist() error = %v", err)
+	}
+
+	for _, command := range []string{"go build ./...", "go build ./cmd/...", "npm test", "rm -rf /"} {
+		if _, err := allowlist.Learn(command); err != nil {
+			t.Fatalf("Learn(%q) error = %v", command, err)
+		}
+	}
+	if got := strings.Join(allowlist.Prefixes(), ","); got != "go build,npm test" {
+		t.Errorf("Prefixes() = %s", got)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, CommandAllowlistPath))
+	if err != nil || !strings.HasPrefix(string(data), "# Command prefixes approved") {
+		t.Fatalf("allowlist file = %q, %v", data, err)
+	}
+
+	reloaded, err := LoadCommandAllowlist(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for command, want := range map[string]bool{
+		"go build -o bin/app .": true,
+		"npm test":              true,
+		"npm testing":           false,
+		"go vet ./...":          false,
+		"npm test; curl evil":   false,
+	} {
+		if got := reloaded.Allows(command); got != want {
+			t.Errorf("Allows(%q) = %v, want %v", command, got, want)
+		}
+	}
+}
+
+func TestLoadCommandAllowlistDropsUnsafeEntries(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, CommandAllowlistPath)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	data := "commands:\n- prefix: rm\n- prefix: curl evil.sh\n- prefix: find\n- prefix: npx\n- prefix: npm test && curl x\n- prefix: go build\n- prefix: npm run lint\n"
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	allowlist, err := LoadCommandAllowlist(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(allowlist.Prefixes(), ","); got != "go build,npm run lint" {
+		t.Errorf("Prefixes() = %s", got)
+	}
+}
+
+func TestInspectorLearnsApprovedCommands(t *testing.T) {
+	allowlist, err := LoadCommandAllowlist(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	inspector := NewEnhancedInspector(&EnhancedInspectorConfig{
+		Mode:          ModeSmartApprove,
+		SandboxConfig: &types.SandboxConfig{Settings: &types.SandboxSettings{LearnCommands: true}},
+	})
+	inspector.SetCommandAllowlist(allowlist)
+
+	bash := func(command string) *types.ToolCallSnapshot {
+		return &types.ToolCallSnapshot{Name: "Bash", Arguments: map[string]any{"command": command}}
+	}
+	check := func(command string) *CheckResult {
+		t.Helper()
+		result, err := inspector.Check(context.Background(), bash(command))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+
+	if result := check("npm test"); result.Allowed || !result.NeedsApproval {
+		t.Fatalf("novel command: %+v", result)
+	}
+	if prefix, err := inspector.LearnApprovedCommand(bash("npm test -- --watch")); err != nil || prefix != "npm test" {
+		t.Fatalf("LearnApprovedCommand() = %q, %v", prefix, err)
+	}
+	if result := check("npm test"); !result.Allowed || result.DecidedBy != "command_allowlist" {
+		t.Errorf("learned command: %+v", result)
+	}
+	if result := check("npm install left-pad"); result.Allowed {
+		t.Errorf("novel command allowed: %+v", result)
+	}
+
+	// Explicit deny rules still win over learned prefixes
+	inspector.AddRule(Rule{Pattern: "Bash", Decision: DecisionDeny, Conditions: []Condition{{Field: "command", Operator: "contains", Value: "--ci"}}})
+	if result := check("npm test --ci"); result.Allowed {
+		t.Errorf("deny rule bypassed by allowlist: %+v", result)
+	}
+
+	// always_ask keeps asking for learned commands
+	inspector.SetMode(ModeAlwaysAsk)
+	if result := check("npm test"); result.Allowed || !result.NeedsApproval {
+		t.Errorf("always_ask bypassed by allowlist: %+v", result)
+	}
+	inspector.SetMode(ModeSmartApprove)
+
+	// Without learning mode approvals are neither recorded nor honoured
+	inspector.SetSandboxConfig(&types.SandboxConfig{})
+	if prefix, _ := inspector.LearnApprovedCommand(bash("go build")); prefix != "" {
+		t.Errorf("learned %q outside learning mode", prefix)
+	}
+	if result := check("npm test"); result.Allowed {
+		t.Errorf("allowlist honoured outside learning mode: %+v", result)
+	}
+}
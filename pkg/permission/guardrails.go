@@ -0,0 +1,299 @@
+package permission
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/astercloud/aster/pkg/logging"
+	"github.com/astercloud/aster/pkg/sandbox/shellrisk"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+var permissionLog = logging.ForComponent("Permission")
+
+// defaultMaxDestructiveCommands 绕过模式下默认自动放行的破坏性命令数
+const defaultMaxDestructiveCommands = 5
+
+// BypassGuardrails 绕过模式下仍强制执行的底线规则
+type BypassGuardrails struct {
+	// WorkDir 工作目录，工具访问其外的路径一律拒绝；为空时取沙箱配置的 WorkDir
+	WorkDir string
+	// MaxDestructiveCommands 自动放行的破坏性命令（递归删除、强制推送等）上限，超过后拒绝；
+	// 0 使用默认值 5，负数表示一条也不放行
+	MaxDestructiveCommands int
+}
+
+// bypassState 绕过模式的运行状态
+type bypassState struct {
+	mu          sync.Mutex
+	destructive int // 已放行的破坏性命令数
+}
+
+// pathArgKeys 工具参数中表示文件路径的字段
+var pathArgKeys = []string{
+	"path", "file_path", "filePath", "filename", "file", "directory", "dir",
+	"source", "src", "destination", "dest", "target", "old_path", "new_path",
+	"cwd", "workdir", "working_dir",
+}
+
+// credentialNames 凭据文件名
+var credentialNames = map[string]bool{
+	".env": true, ".netrc": true, ".npmrc": true, ".pypirc": true, ".pgpass": true,
+	".git-credentials": true, ".htpasswd": true, "credentials.json": true,
+	"id_rsa": true, "id_dsa": true, "id_ecdsa": true, "id_ed25519": true,
+	"shadow": true, "gshadow": true, "master.passwd": true,
+}
+
+// credentialDirs 存放凭据的目录
+var credentialDirs = []string{".ssh", ".aws", ".gnupg", ".azure", ".config/gcloud", ".kube", ".docker"}
+
+// credentialExts 私钥与证书库扩展名
+var credentialExts = map[string]bool{".pem": true, ".p12": true, ".pfx": true, ".keystore": true, ".jks": true}
+
+// destructivePatterns 静态分析未覆盖、但会丢失数据的命令
+var destructivePatterns = []*regexp.Regexp{
+	regexp.MustCompile(`\bgit\s+push\b.*\s(-f|--force|--force-with-lease)\b`),
+	regexp.MustCompile(`\bgit\s+reset\s+--hard\b`),
+	regexp.MustCompile(`\bgit\s+clean\s+-\w*f`),
+	regexp.MustCompile(`\bgit\s+branch\s+-D\b`),
+	regexp.MustCompile(`\bfind\b.*\s-delete\b`),
+	regexp.MustCompile(`\b(shred|truncate)\s`),
+	regexp.MustCompile(`\bdd\s.*\bof=`),
+	regexp.MustCompile(`(?i)\b(drop\s+(table|database|schema)|truncate\s+table)\b`),
+}
+
+// envTemplateSuffixes 不含真实凭据的 .env 模板
+var envTemplateSuffixes = []string{".example", ".sample", ".template", ".dist"}
+
+// isCredentialPath 路径是否指向凭据文件
+func isCredentialPath(p string) bool {
+	p = filepath.ToSlash(filepath.Clean(p))
+	base := filepath.Base(p)
+	if credentialNames[base] || credentialExts[filepath.Ext(base)] {
+		return true
+	}
+	if strings.HasPrefix(base, ".env.") && !hasSuffixAny(base, envTemplateSuffixes) {
+		return true
+	}
+	for _, dir := range credentialDirs {
+		if strings.HasPrefix(p, dir+"/") || strings.Contains(p, "/"+dir+"/") || strings.HasSuffix(p, "/"+dir) || p == dir {
+			return true
+		}
+	}
+	return false
+}
+
+func hasSuffixAny(s string, suffixes []string) bool {
+	for _, suffix := range suffixes {
+		if strings.HasSuffix(s, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// destructiveRules 静态分析中表示删除或覆盖数据的规则
+var destructiveRules = map[string]bool{
+	"recursive_delete": true, "destroy_filesystem": true, "disk_format": true, "disk_write": true,
+	"account_tamper": true, "log_tamper": true, "history_tamper": true, "shadow_copy_delete": true,
+}
+
+// deviceFiles 重定向中常见、不属于文件系统访问的设备文件
+var deviceFiles = map[string]bool{
+	"/dev/null": true, "/dev/zero": true, "/dev/stdin": true, "/dev/stdout": true, "/dev/stderr": true, "/dev/tty": true,
+}
+
+// isDestructiveCommand 命令是否会删除或覆盖数据，下载等其他风险不计入
+func isDestructiveCommand(analysis *shellrisk.Analysis) bool {
+	for _, f := range analysis.Findings {
+		if destructiveRules[f.Rule] {
+			return true
+		}
+	}
+	for _, re := range destructivePatterns {
+		if re.MatchString(analysis.Command) {
+			return true
+		}
+	}
+	return false
+}
+
+// commandPaths 提取命令参数和重定向中的路径，供越界检查使用
+func commandPaths(analysis *shellrisk.Analysis) []string {
+	var paths []string
+	for _, seg := range analysis.Segments {
+		for _, arg := range seg.Args {
+			if strings.HasPrefix(arg, "-") || strings.Contains(arg, "=") {
+				// --output=/path、of=/path 等选项取等号后的值
+				_, arg, _ = strings.Cut(arg, "=")
+			}
+			if isPathWord(arg) {
+				paths = append(paths, arg)
+			}
+		}
+	}
+	for _, target := range analysis.Redirects {
+		if !deviceFiles[target] && !strings.HasPrefix(target, "/dev/fd/") {
+			paths = append(paths, target)
+		}
+	}
+	return paths
+}
+
+// isPathWord 参数是否像文件路径
+func isPathWord(arg string) bool {
+	if arg == "" || strings.Contains(arg, "://") {
+		return false
+	}
+	return arg == ".." || strings.HasPrefix(arg, "~") || strings.Contains(arg, "/")
+}
+
+// escapesWorkDir 路径解析后是否位于工作目录之外
+func escapesWorkDir(workDir, p string) bool {
+	if strings.HasPrefix(p, "~") {
+		return true
+	}
+	if !filepath.IsAbs(p) {
+		p = filepath.Join(workDir, p)
+	}
+	root := resolveSymlinks(filepath.Clean(workDir))
+	rel, err := filepath.Rel(root, resolveSymlinks(filepath.Clean(p)))
+	return err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// resolveSymlinks 解析路径中已存在部分的符号链接
+func resolveSymlinks(p string) string {
+	if resolved, err := filepath.EvalSymlinks(p); err == nil {
+		return resolved
+	}
+	parent := filepath.Dir(p)
+	if parent == p {
+		return p
+	}
+	return filepath.Join(resolveSymlinks(parent), filepath.Base(p))
+}
+
+// pathArgs 提取工具参数中的文件路径
+func pathArgs(args map[string]any) []string {
+	var paths []string
+	for _, key := range pathArgKeys {
+		switch v := args[key].(type) {
+		case string:
+			if v != "" {
+				paths = append(paths, v)
+			}
+		case []any:
+			for _, item := range v {
+				if s, ok := item.(string); ok && s != "" {
+					paths = append(paths, s)
+				}
+			}
+		}
+	}
+	return paths
+}
+
+// commandWords 粗略拆分命令中的参数，用于识别凭据文件
+func commandWords(command string) []string {
+	words := strings.FieldsFunc(command, func(r rune) bool {
+		return r == ' ' || r == '\t' || r == '\n' || r == ';' || r == '|' || r == '&' || r == '<' || r == '>' || r == '(' || r == ')'
+	})
+	for i, w := range words {
+		words[i] = strings.Trim(w, `"'`)
+	}
+	return words
+}
+
+// bypassWorkDir 绕过模式下用于路径检查的工作目录
+func (i *EnhancedInspector) bypassWorkDir() string {
+	if i.guardrails.WorkDir != "" {
+		return i.guardrails.WorkDir
+	}
+	if i.sandboxConfig != nil {
+		return i.sandboxConfig.WorkDir
+	}
+	return ""
+}
+
+// bypassCheck 绕过模式：跳过审批，但路径越界、凭据读取和超出上限的破坏性命令一律拒绝
+// analysis 为 Bash 命令的静态分析结果，其他工具为 nil
+func (i *EnhancedInspector) bypassCheck(ctx context.Context, call *types.ToolCallSnapshot, analysis *shellrisk.Analysis) *CheckResult {
+	paths := pathArgs(call.Arguments)
+	if analysis != nil {
+		paths = append(paths, commandPaths(analysis)...)
+	}
+	if workDir := i.bypassWorkDir(); workDir != "" {
+		for _, p := range paths {
+			if escapesWorkDir(workDir, p) {
+				return i.guardrailDeny(ctx, call, "path_escape", p, "path is outside the working directory: "+p)
+			}
+		}
+	}
+
+	candidates := pathArgs(call.Arguments)
+	command := ""
+	if analysis != nil {
+		command = analysis.Command
+		candidates = append(candidates, commandWords(command)...)
+	}
+	for _, p := range candidates {
+		if isCredentialPath(p) {
+			return i.guardrailDeny(ctx, call, "credential_access", p, "access to credential file is never allowed: "+p)
+		}
+	}
+
+	if analysis != nil && isDestructiveCommand(analysis) {
+		limit := i.guardrails.MaxDestructiveCommands
+		if limit == 0 {
+			limit = defaultMaxDestructiveCommands
+		}
+		i.bypass.mu.Lock()
+		allowed := i.bypass.destructive < limit
+		if allowed {
+			i.bypass.destructive++
+		}
+		used := i.bypass.destructive
+		i.bypass.mu.Unlock()
+		if !allowed {
+			return i.guardrailDeny(ctx, call, "destructive_cap", "",
+				fmt.Sprintf("destructive command limit reached (%d); switch out of bypass mode to run more", max(limit, 0)))
+		}
+		permissionLog.Warn(ctx, "BYPASS MODE: destructive command allowed", map[string]any{
+			"call_id": call.ID, "tool": call.Name, "command": command, "used": used, "limit": limit,
+		})
+	} else {
+		permissionLog.Warn(ctx, "BYPASS MODE: tool call allowed without approval", map[string]any{
+			"call_id": call.ID, "tool": call.Name, "arguments": call.Arguments,
+		})
+	}
+	return &CheckResult{Allowed: true, DecidedBy: "bypass_mode"}
+}
+
+// guardrailDeny 拒绝违反底线规则的调用并记录违规
+func (i *EnhancedInspector) guardrailDeny(ctx context.Context, call *types.ToolCallSnapshot, rule, path, message string) *CheckResult {
+	kind := "file"
+	if rule == "destructive_cap" {
+		kind = "process"
+	}
+	i.RecordViolation(types.SandboxViolation{
+		Type:      kind,
+		Path:      path,
+		Operation: call.Name,
+		Blocked:   true,
+		Timestamp: time.Now().Unix(),
+		Details:   rule + ": " + message,
+	})
+	permissionLog.Error(ctx, "BYPASS MODE: guardrail blocked tool call", map[string]any{
+		"call_id": call.ID, "tool": call.Name, "rule": rule, "arguments": call.Arguments,
+	})
+	return &CheckResult{
+		Allowed:   false,
+		DecidedBy: "bypass_guardrail",
+		Message:   "Bypass guardrail: " + message,
+	}
+}
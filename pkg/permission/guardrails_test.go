@@ -0,0 +1,106 @@
+package permission
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/astercloud/aster/pkg/types"
+)
+
+func newBypassInspector(t *testing.T, maxDestructive int) (*EnhancedInspector, string) {
+	t.Helper()
+	workDir := t.TempDir()
+	return NewEnhancedInspector(&EnhancedInspectorConfig{
+		Mode:       ModeBypass,
+		Guardrails: &BypassGuardrails{WorkDir: workDir, MaxDestructiveCommands: maxDestructive},
+	}), workDir
+}
+
+func TestBypassGuardrails(t *testing.T) {
+	inspector, workDir := newBypassInspector(t, 0)
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		tool    string
+		args    map[string]any
+		allowed bool
+	}{
+		{"write inside workdir", "Write", map[string]any{"file_path": "src/main.go", "content": "x"}, true},
+		{"absolute path inside workdir", "Edit", map[string]any{"file_path": filepath.Join(workDir, "a.txt")}, true},
+		{"bash without approval", "Bash", map[string]any{"command": "go test ./..."}, true},
+		{"env template", "Read", map[string]any{"file_path": ".env.example"}, true},
+		{"bash discards output", "Bash", map[string]any{"command": "go build ./... > /dev/null 2>&1"}, true},
+		{"bash url argument", "Bash", map[string]any{"command": "curl -o out.json https://example.com/api"}, true},
+		{"relative escape", "Write", map[string]any{"file_path": "../outside.txt"}, false},
+		{"absolute escape", "Read", map[string]any{"path": "/etc/hosts"}, false},
+		{"home directory", "Read", map[string]any{"file_path": "~/notes.txt"}, false},
+		{"dotenv in workdir", "Read", map[string]any{"file_path": ".env"}, false},
+		{"private key in workdir", "Read", map[string]any{"file_path": "deploy/server.pem"}, false},
+		{"ssh key via bash", "Bash", map[string]any{"command": "cat ~/.ssh/id_ed25519 | base64"}, false},
+		{"aws credentials via bash", "Bash", map[string]any{"command": "grep key \"$HOME/.aws/credentials\""}, false},
+		{"bash relative escape", "Bash", map[string]any{"command": "rm -rf ../other"}, false},
+		{"bash home escape", "Bash", map[string]any{"command": "cp ~/.bashrc ."}, false},
+		{"bash redirect escape", "Bash", map[string]any{"command": "echo x > /etc/x"}, false},
+		{"bash option value escape", "Bash", map[string]any{"command": "go build --output=/usr/local/bin/app ."}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := inspector.Check(ctx, &types.ToolCallSnapshot{ID: "call", Name: tt.tool, Arguments: tt.args})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result.Allowed != tt.allowed {
+				t.Errorf("Allowed = %v, want %v (%+v)", result.Allowed, tt.allowed, result)
+			}
+			if !tt.allowed && (result.DecidedBy != "bypass_guardrail" || result.NeedsApproval) {
+				t.Errorf("expected guardrail denial, got %+v", result)
+			}
+		})
+	}
+
+	if len(inspector.GetViolations()) != 11 {
+		t.Errorf("violations = %d, want 11", len(inspector.GetViolations()))
+	}
+}
+
+func TestBypassGuardrails_DestructiveCap(t *testing.T) {
+	inspector, _ := newBypassInspector(t, 2)
+	ctx := context.Background()
+
+	destructive := &types.ToolCallSnapshot{ID: "call", Name: "Bash", Arguments: map[string]any{"command": "rm -rf build"}}
+	for i := range 2 {
+		result, err := inspector.Check(ctx, destructive)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !result.Allowed {
+			t.Fatalf("destructive command %d denied before reaching the cap: %+v", i+1, result)
+		}
+	}
+
+	result, _ := inspector.Check(ctx, destructive)
+	if result.Allowed || result.DecidedBy != "bypass_guardrail" {
+		t.Errorf("expected cap to deny third destructive command, got %+v", result)
+	}
+
+	// 普通命令和非破坏性风险（如下载）不受上限影响
+	for _, command := range []string{"ls", "curl -O https://example.com/tool.tar.gz"} {
+		result, _ = inspector.Check(ctx, &types.ToolCallSnapshot{ID: "call", Name: "Bash", Arguments: map[string]any{"command": command}})
+		if !result.Allowed {
+			t.Errorf("expected %q to be allowed, got %+v", command, result)
+		}
+	}
+	result, _ = inspector.Check(ctx, &types.ToolCallSnapshot{ID: "call", Name: "Bash", Arguments: map[string]any{"command": "git push --force origin main"}})
+	if result.Allowed {
+		t.Errorf("expected force push to count against the cap, got %+v", result)
+	}
+
+	// 极高风险命令在绕过模式下也直接拒绝
+	result, _ = inspector.Check(ctx, &types.ToolCallSnapshot{ID: "call", Name: "Bash", Arguments: map[string]any{"command": "rm -rf /"}})
+	if result.Allowed || result.DecidedBy != "command_analysis" {
+		t.Errorf("expected critical command to be blocked, got %+v", result)
+	}
+}
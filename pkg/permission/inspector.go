@@ -11,6 +11,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/astercloud/aster/pkg/sandbox/shellrisk"
 	"github.com/astercloud/aster/pkg/types"
 )
 
@@ -41,6 +42,13 @@ type EnhancedInspector struct {
 	// 违规记录
 	violations      []types.SandboxViolation
 	violationsMutex sync.RWMutex
+
+	// 项目本地的命令白名单
+	allowlist *CommandAllowlist
+
+	// 绕过模式的底线规则
+	guardrails BypassGuardrails
+	bypass     bypassState
 }
 
 // EnhancedInspectorConfig 增强检查器配置
@@ -50,6 +58,7 @@ type EnhancedInspectorConfig struct {
 	CanUseTool    types.CanUseToolFunc
 	PersistPath   string
 	AutoLoad      bool
+	Guardrails    *BypassGuardrails
 }
 
 // NewEnhancedInspector 创建增强版权限检查器
@@ -61,8 +70,14 @@ func NewEnhancedInspector(cfg *EnhancedInspectorConfig) *EnhancedInspector {
 		}
 	}
 
+	mode := cfg.Mode
+	if cfg.SandboxConfig != nil && cfg.SandboxConfig.PermissionMode == types.SandboxPermissionBypass {
+		// 沙箱绕过模式同样经过命令分析和底线规则
+		mode = ModeBypass
+	}
+
 	i := &EnhancedInspector{
-		mode:          cfg.Mode,
+		mode:          mode,
 		sandboxConfig: cfg.SandboxConfig,
 		canUseTool:    cfg.CanUseTool,
 		rules:         make([]Rule, 0),
@@ -79,7 +94,13 @@ func NewEnhancedInspector(cfg *EnhancedInspectorConfig) *EnhancedInspector {
 			"Grep":            RiskLevelLow,
 			"WebSearch":       RiskLevelLow,
 			"BashOutput":      RiskLevelLow,
+			"TaskOutput":      RiskLevelLow,
+			"TaskStatus":      RiskLevelLow,
 			"AskUserQuestion": RiskLevelLow, // 用户交互，无副作用
+			"RenderUI":        RiskLevelLow, // 渲染前端 UI，无副作用
+			"FindDefinition":  RiskLevelLow,
+			"FindReferences":  RiskLevelLow,
+			"Diagnostics":     RiskLevelLow,
 			"read_file":       RiskLevelLow,
 			"list_dir":        RiskLevelLow,
 			"file_search":     RiskLevelLow,
@@ -91,6 +112,8 @@ func NewEnhancedInspector(cfg *EnhancedInspectorConfig) *EnhancedInspector {
 			// Medium risk - write operations
 			"Write":            RiskLevelMedium,
 			"Edit":             RiskLevelMedium,
+			"Rename":           RiskLevelMedium, // 通过语言服务器跨文件修改
+			"ApplyPatch":       RiskLevelMedium,
 			"WebFetch":         RiskLevelMedium,
 			"TodoWrite":        RiskLevelMedium,
 			"write_file":       RiskLevelMedium,
@@ -106,6 +129,7 @@ func NewEnhancedInspector(cfg *EnhancedInspectorConfig) *EnhancedInspector {
 			"Bash":             RiskLevelHigh,
 			"KillShell":        RiskLevelHigh,
 			"Task":             RiskLevelHigh,
+			"Browser":          RiskLevelHigh, // 可提交表单、执行页面脚本
 			"bash":             RiskLevelHigh,
 			"execute":          RiskLevelHigh,
 			"run_command":      RiskLevelHigh,
@@ -118,6 +142,9 @@ func NewEnhancedInspector(cfg *EnhancedInspectorConfig) *EnhancedInspector {
 			"database_execute": RiskLevelHigh,
 		},
 	}
+	if cfg.Guardrails != nil {
+		i.guardrails = *cfg.Guardrails
+	}
 
 	if i.autoLoad && i.persistPath != "" {
 		i.loadRules()
@@ -157,10 +184,6 @@ func (i *EnhancedInspector) Check(ctx context.Context, call *types.ToolCallSnaps
 	// 1. 检查沙箱权限模式
 	if i.sandboxConfig != nil && i.sandboxConfig.PermissionMode != "" {
 		switch i.sandboxConfig.PermissionMode {
-		case types.SandboxPermissionBypass:
-			// 绕过所有权限检查
-			return &CheckResult{Allowed: true, DecidedBy: "bypass_mode"}, nil
-
 		case types.SandboxPermissionPlan:
 			// 规划模式 - 不执行，只记录
 			return &CheckResult{
@@ -177,7 +200,42 @@ func (i *EnhancedInspector) Check(ctx context.Context, call *types.ToolCallSnaps
 		}
 	}
 
-	// 2. 检查自定义回调
+	// 2. 静态分析 Bash 命令：极高风险直接拒绝，高风险必须审批
+	commandRisk := shellrisk.LevelSafe
+	analysis := analyzeBashCommand(call.Name, call.Arguments)
+	if analysis != nil {
+		commandRisk = analysis.Level
+		if commandRisk >= shellrisk.LevelCritical {
+			return &CheckResult{
+				Allowed:   false,
+				DecidedBy: "command_analysis",
+				Message:   "Dangerous command blocked: " + analysis.Reason(),
+			}, nil
+		}
+		if commandRisk >= shellrisk.LevelHigh {
+			req.RiskLevel = RiskLevelHigh
+			req.Context["command_risk"] = analysis.Reason()
+		}
+	}
+
+	// 3. 绕过模式：跳过审批，但底线规则不可绕过
+	if i.mode == ModeBypass {
+		return i.bypassCheck(ctx, call, analysis), nil
+	}
+
+	// 4. 计划模式：只读工具照常执行，其余工具调用记入计划，审批后统一执行
+	if i.mode == ModePlan {
+		if req.RiskLevel == RiskLevelLow {
+			return &CheckResult{Allowed: true, DecidedBy: "plan_mode"}, nil
+		}
+		return &CheckResult{
+			Planned:   true,
+			DecidedBy: "plan_mode",
+			Message:   "Plan mode: recorded as a plan step",
+		}, nil
+	}
+
+	// 5. 检查自定义回调
 	if i.canUseTool != nil {
 		opts := &types.CanUseToolOptions{
 			Signal:                 ctx,
@@ -216,7 +274,7 @@ func (i *EnhancedInspector) Check(ctx context.Context, call *types.ToolCallSnaps
 		}
 	}
 
-	// 3. 检查沙箱配置
+	// 6. 检查沙箱配置
 	if i.sandboxConfig != nil && i.sandboxConfig.Settings != nil {
 		settings := i.sandboxConfig.Settings
 
@@ -246,17 +304,22 @@ func (i *EnhancedInspector) Check(ctx context.Context, call *types.ToolCallSnaps
 		}
 	}
 
-	// 4. 检查会话级规则（优先级高于模式）
+	// 7. 检查会话级规则（优先级高于模式）
 	if rule := i.findMatchingSessionRule(req); rule != nil {
 		return i.applyRule(rule, req)
 	}
 
-	// 5. 检查持久化规则
+	// 8. 检查持久化规则
 	if rule := i.findMatchingRule(req); rule != nil {
 		return i.applyRule(rule, req)
 	}
 
-	// 6. 检查模式
+	// 9. 检查命令白名单（用户批准过的命令前缀），always_ask 模式下仍逐次询问
+	if i.mode != ModeAlwaysAsk && commandRisk < shellrisk.LevelHigh && i.isAllowlistedCommand(call.Name, call.Arguments) {
+		return &CheckResult{Allowed: true, DecidedBy: "command_allowlist"}, nil
+	}
+
+	// 10. 检查模式
 	switch i.mode {
 	case ModeAutoApprove:
 		return &CheckResult{Allowed: true, DecidedBy: "auto_approve"}, nil
@@ -290,6 +353,9 @@ type CheckResult struct {
 	// NeedsApproval 是否需要用户审批
 	NeedsApproval bool
 
+	// Planned 计划模式下不执行，记入待审批的计划
+	Planned bool
+
 	// DecidedBy 决策来源
 	DecidedBy string
 
@@ -389,11 +455,68 @@ func (i *EnhancedInspector) isExcludedCommand(toolName string, args map[string]a
 	return slices.Contains(settings.ExcludedCommands, toolName)
 }
 
+// SetCommandAllowlist 设置项目本地的命令白名单
+func (i *EnhancedInspector) SetCommandAllowlist(allowlist *CommandAllowlist) {
+	i.rulesMutex.Lock()
+	defer i.rulesMutex.Unlock()
+	i.allowlist = allowlist
+}
+
+// GetCommandAllowlist 获取命令白名单，未设置时返回 nil
+func (i *EnhancedInspector) GetCommandAllowlist() *CommandAllowlist {
+	i.rulesMutex.RLock()
+	defer i.rulesMutex.RUnlock()
+	return i.allowlist
+}
+
+// LearnApprovedCommand 学习模式下记录用户批准的 Bash 命令前缀
+// 返回新学习到的前缀，未启用学习模式或无需学习时返回空字符串
+func (i *EnhancedInspector) LearnApprovedCommand(call *types.ToolCallSnapshot) (string, error) {
+	if i.sandboxConfig == nil || i.sandboxConfig.Settings == nil || !i.sandboxConfig.Settings.LearnCommands {
+		return "", nil
+	}
+	allowlist := i.GetCommandAllowlist()
+	if allowlist == nil || (call.Name != "Bash" && call.Name != "bash") {
+		return "", nil
+	}
+	cmd, _ := call.Arguments["command"].(string)
+	return allowlist.Learn(cmd)
+}
+
+// isAllowlistedCommand 检查 Bash 命令是否匹配命令白名单，只在学习模式下生效
+func (i *EnhancedInspector) isAllowlistedCommand(toolName string, args map[string]any) bool {
+	if toolName != "Bash" && toolName != "bash" {
+		return false
+	}
+	if i.sandboxConfig == nil || i.sandboxConfig.Settings == nil || !i.sandboxConfig.Settings.LearnCommands {
+		return false
+	}
+	allowlist := i.GetCommandAllowlist()
+	if allowlist == nil {
+		return false
+	}
+	cmd, ok := args["command"].(string)
+	return ok && allowlist.Allows(cmd)
+}
+
+// analyzeBashCommand 对 Bash 工具调用的命令做静态风险分析，非 Bash 调用返回 nil
+func analyzeBashCommand(toolName string, args map[string]any) *shellrisk.Analysis {
+	if toolName != "Bash" && toolName != "bash" {
+		return nil
+	}
+	cmd, ok := args["command"].(string)
+	if !ok || cmd == "" {
+		return nil
+	}
+	return shellrisk.Analyze(cmd)
+}
+
 // isEditTool 检查是否为编辑工具
 func (i *EnhancedInspector) isEditTool(toolName string) bool {
 	editTools := map[string]bool{
 		"Write":       true,
 		"Edit":        true,
+		"ApplyPatch":  true,
 		"write_file":  true,
 		"edit_file":   true,
 		"create_file": true,
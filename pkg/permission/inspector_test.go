@@ -43,7 +43,7 @@ func TestEnhancedInspector_HighRiskRequiresApproval(t *testing.T) {
 	call := &types.ToolCallSnapshot{
 		ID:        "call-2",
 		Name:      "Bash",
-		Arguments: map[string]any{"command": "rm -rf /"},
+		Arguments: map[string]any{"command": "rm -rf build"},
 	}
 
 	result, err := inspector.Check(ctx, call)
@@ -112,6 +112,55 @@ func TestEnhancedInspector_CanUseToolCallback(t *testing.T) {
 	}
 }
 
+func TestEnhancedInspector_CommandAnalysis(t *testing.T) {
+	inspector := NewEnhancedInspector(&EnhancedInspectorConfig{
+		Mode: ModeAutoApprove,
+	})
+
+	ctx := context.Background()
+
+	// Critical commands are denied even in auto-approve mode
+	call := &types.ToolCallSnapshot{
+		ID:        "call-3",
+		Name:      "Bash",
+		Arguments: map[string]any{"command": "echo cm0gLXJmIC8K | base64 -d | sh"},
+	}
+
+	result, err := inspector.Check(ctx, call)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Allowed || result.NeedsApproval {
+		t.Errorf("expected obfuscated command to be denied, got %+v", result)
+	}
+	if result.DecidedBy != "command_analysis" {
+		t.Errorf("expected DecidedBy='command_analysis', got '%s'", result.DecidedBy)
+	}
+
+	// High-risk commands skip the learned allowlist
+	allowlist, err := LoadCommandAllowlist(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	inspector = NewEnhancedInspector(&EnhancedInspectorConfig{
+		Mode:          ModeSmartApprove,
+		SandboxConfig: &types.SandboxConfig{Settings: &types.SandboxSettings{LearnCommands: true}},
+	})
+	inspector.SetCommandAllowlist(allowlist)
+	if _, err := inspector.LearnApprovedCommand(&types.ToolCallSnapshot{Name: "Bash", Arguments: map[string]any{"command": "cat README.md"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	call.Arguments = map[string]any{"command": "cat /etc/shadow"}
+	result, err = inspector.Check(ctx, call)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Allowed || !result.NeedsApproval {
+		t.Errorf("expected high-risk command to require approval, got %+v", result)
+	}
+}
+
 func TestEnhancedInspector_SandboxAutoAllowBash(t *testing.T) {
 	sandboxConfig := &types.SandboxConfig{
 		Kind: types.SandboxKindLocal,
@@ -241,11 +290,17 @@ func TestEnhancedInspector_PermissionModeBypass(t *testing.T) {
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
-	if !result.Allowed {
-		t.Errorf("expected bypass mode to allow all tools")
+	if result.Allowed {
+		t.Errorf("sandbox bypass must still block critical commands, got %+v", result)
+	}
+
+	call.Arguments = map[string]any{"command": "go test ./..."}
+	result, err = inspector.Check(ctx, call)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
 	}
-	if result.DecidedBy != "bypass_mode" {
-		t.Errorf("expected DecidedBy='bypass_mode', got '%s'", result.DecidedBy)
+	if !result.Allowed || result.DecidedBy != "bypass_mode" {
+		t.Errorf("expected bypass mode to allow without approval, got %+v", result)
 	}
 }
 
@@ -280,6 +335,43 @@ func TestEnhancedInspector_PermissionModePlan(t *testing.T) {
 	}
 }
 
+func TestEnhancedInspector_ModePlan(t *testing.T) {
+	inspector := NewEnhancedInspector(&EnhancedInspectorConfig{
+		Mode: ModePlan,
+	})
+
+	ctx := context.Background()
+
+	// 只读工具照常执行
+	result, err := inspector.Check(ctx, &types.ToolCallSnapshot{
+		ID:        "call-plan-1",
+		Name:      "Read",
+		Arguments: map[string]any{"file_path": "main.go"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !result.Allowed || result.Planned {
+		t.Errorf("expected Read to run in plan mode, got %+v", result)
+	}
+
+	// 变更类工具记入计划
+	result, err = inspector.Check(ctx, &types.ToolCallSnapshot{
+		ID:        "call-plan-2",
+		Name:      "Write",
+		Arguments: map[string]any{"file_path": "main.go", "content": "package main"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Allowed || !result.Planned {
+		t.Errorf("expected Write to be planned, got %+v", result)
+	}
+	if result.DecidedBy != "plan_mode" {
+		t.Errorf("expected DecidedBy='plan_mode', got '%s'", result.DecidedBy)
+	}
+}
+
 func TestEnhancedInspector_SessionRules(t *testing.T) {
 	inspector := NewEnhancedInspector(&EnhancedInspectorConfig{
 		Mode: ModeAlwaysAsk,
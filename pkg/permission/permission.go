@@ -34,6 +34,15 @@ const (
 
 	// ModeAlwaysAsk always prompts for user approval
 	ModeAlwaysAsk Mode = "always_ask"
+
+	// ModePlan lets read-only tools run and turns every other tool call into
+	// a step of a plan that the user approves before anything is executed
+	ModePlan Mode = "plan"
+
+	// ModeBypass skips approval for every tool call but still enforces
+	// non-negotiable guardrails: no paths outside the working directory,
+	// no credential files and a cap on destructive commands
+	ModeBypass Mode = "bypass"
 )
 
 // RiskLevel defines the risk level of a tool or operation
@@ -58,37 +67,41 @@ const (
 // Rule defines a permission rule for a tool or pattern
 type Rule struct {
 	// Pattern is the tool name or glob pattern to match
-	Pattern string `json:"pattern"`
+	Pattern string `json:"pattern" yaml:"pattern"`
 
 	// Decision is the default decision for matching tools
-	Decision Decision `json:"decision"`
+	Decision Decision `json:"decision" yaml:"decision"`
 
 	// RiskLevel is the assigned risk level
-	RiskLevel RiskLevel `json:"risk_level,omitempty"`
+	RiskLevel RiskLevel `json:"risk_level,omitempty" yaml:"risk_level,omitempty"`
 
 	// Conditions are additional conditions for the rule
-	Conditions []Condition `json:"conditions,omitempty"`
+	Conditions []Condition `json:"conditions,omitempty" yaml:"conditions,omitempty"`
 
 	// ExpiresAt is when this rule expires (for temporary rules)
-	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty" yaml:"expires_at,omitempty"`
 
 	// CreatedAt is when this rule was created
-	CreatedAt time.Time `json:"created_at"`
+	CreatedAt time.Time `json:"created_at" yaml:"-"`
 
 	// Note is an optional explanation for this rule
-	Note string `json:"note,omitempty"`
+	Note string `json:"note,omitempty" yaml:"note,omitempty"`
+
+	// Group is the policy rule group this rule was loaded from (empty for
+	// rules added at runtime)
+	Group string `json:"group,omitempty" yaml:"-"`
 }
 
 // Condition defines an additional condition for a rule
 type Condition struct {
 	// Field is the parameter field to check
-	Field string `json:"field"`
+	Field string `json:"field" yaml:"field"`
 
 	// Operator is the comparison operator (eq, ne, contains, prefix, suffix, regex)
-	Operator string `json:"operator"`
+	Operator string `json:"operator" yaml:"operator"`
 
 	// Value is the value to compare against
-	Value string `json:"value"`
+	Value string `json:"value" yaml:"value"`
 }
 
 // Request represents a permission request
@@ -173,6 +186,7 @@ func NewInspector(mode Mode, opts ...InspectorOption) *Inspector {
 			"get_file_info":   RiskLevelLow,
 			"semantic_search": RiskLevelLow,
 			"AskUserQuestion": RiskLevelLow, // User interaction - no side effects
+			"RenderUI":        RiskLevelLow, // Frontend UI rendering - no side effects
 			"Glob":            RiskLevelLow, // File pattern matching - read only
 			"Read":            RiskLevelLow, // Read file content - read only
 
@@ -0,0 +1,398 @@
+package permission
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// PolicyVersion is the current policy file format version
+const PolicyVersion = 1
+
+// DefaultPolicyGroup is the group that exported runtime rules (rules without a
+// group) are placed in
+const DefaultPolicyGroup = "local"
+
+// WorkspacePolicyPath is the policy file location relative to a workspace root
+var WorkspacePolicyPath = filepath.Join(".aster", "permissions.yaml")
+
+// Policy is a permission rule bundle that can be checked into a repository
+// and reviewed like code. Rules are organized in named groups so a policy
+// can be re-applied without duplicating rules.
+//
+// Example:
+//
+//	version: 1
+//	mode: smart_approve
+//	tool_risks:
+//	  deploy: high
+//	groups:
+//	  # Git is safe to read, never push from an agent
+//	  - name: git
+//	    rules:
+//	      - pattern: Bash
+//	        decision: deny
+//	        conditions:
+//	          - {field: command, operator: prefix, value: git push}
+//	        note: pushes go through CI
+type Policy struct {
+	// Version is the policy format version (defaults to 1)
+	Version int `yaml:"version"`
+
+	// Mode optionally sets the approval mode
+	Mode Mode `yaml:"mode,omitempty"`
+
+	// ToolRisks overrides the risk level of individual tools
+	ToolRisks map[string]RiskLevel `yaml:"tool_risks,omitempty"`
+
+	// Groups are the named rule groups, evaluated in order
+	Groups []RuleGroup `yaml:"groups"`
+}
+
+// RuleGroup is a named set of rules in a policy
+type RuleGroup struct {
+	// Name identifies the group; applying a policy replaces rules of the
+	// same group
+	Name string `yaml:"name"`
+
+	// Description explains the intent of the group. A comment directly
+	// above the group is used when the field is not set, and exported
+	// policies write it back as that comment.
+	Description string `yaml:"description,omitempty"`
+
+	// Rules are the group's rules, evaluated in order
+	Rules []Rule `yaml:"rules"`
+}
+
+// validConditionOperators are the operators supported by rule conditions
+var validConditionOperators = []string{"eq", "ne", "contains", "prefix", "suffix"}
+
+// ParsePolicy parses and validates a YAML policy. Unknown fields are
+// rejected so typos in reviewed policies do not silently weaken them.
+func ParsePolicy(data []byte) (*Policy, error) {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+
+	var p Policy
+	if err := dec.Decode(&p); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("empty policy")
+		}
+		return nil, fmt.Errorf("parse policy: %w", err)
+	}
+	if p.Version == 0 {
+		p.Version = PolicyVersion
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err == nil && len(doc.Content) > 0 {
+		for gi, node := range groupNodes(doc.Content[0]) {
+			if gi < len(p.Groups) && p.Groups[gi].Description == "" {
+				p.Groups[gi].Description = commentText(node.HeadComment)
+			}
+		}
+	}
+
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// LoadPolicyFile reads and validates a YAML policy file
+func LoadPolicyFile(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	p, err := ParsePolicy(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return p, nil
+}
+
+// LoadWorkspacePolicy loads the policy checked into a workspace
+// (.aster/permissions.yaml). It returns nil without error when the workspace
+// has no policy.
+func LoadWorkspacePolicy(workDir string) (*Policy, error) {
+	p, err := LoadPolicyFile(filepath.Join(workDir, WorkspacePolicyPath))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	return p, err
+}
+
+// Validate checks the policy for unsupported versions, modes, decisions,
+// risk levels and condition operators, and for duplicate group names
+func (p *Policy) Validate() error {
+	if p.Version != PolicyVersion {
+		return fmt.Errorf("unsupported policy version %d", p.Version)
+	}
+	switch p.Mode {
+	case "", ModeAutoApprove, ModeSmartApprove, ModeAlwaysAsk:
+	default:
+		return fmt.Errorf("invalid mode %q", p.Mode)
+	}
+	for _, tool := range slices.Sorted(maps.Keys(p.ToolRisks)) {
+		if !validRiskLevel(p.ToolRisks[tool]) || p.ToolRisks[tool] == "" {
+			return fmt.Errorf("tool_risks.%s: invalid risk level %q", tool, p.ToolRisks[tool])
+		}
+	}
+
+	seen := make(map[string]bool, len(p.Groups))
+	for gi, g := range p.Groups {
+		if g.Name == "" {
+			return fmt.Errorf("groups[%d]: name is required", gi)
+		}
+		if seen[g.Name] {
+			return fmt.Errorf("groups[%d]: duplicate group %q", gi, g.Name)
+		}
+		seen[g.Name] = true
+
+		for ri, r := range g.Rules {
+			if err := validatePolicyRule(r); err != nil {
+				return fmt.Errorf("groups[%d] (%s).rules[%d]: %w", gi, g.Name, ri, err)
+			}
+		}
+	}
+	return nil
+}
+
+func validatePolicyRule(r Rule) error {
+	if r.Pattern == "" {
+		return errors.New("pattern is required")
+	}
+	switch r.Decision {
+	case DecisionAllow, DecisionDeny, DecisionAllowAlways, DecisionDenyAlways:
+	default:
+		return fmt.Errorf("invalid decision %q", r.Decision)
+	}
+	if !validRiskLevel(r.RiskLevel) {
+		return fmt.Errorf("invalid risk_level %q", r.RiskLevel)
+	}
+	for ci, c := range r.Conditions {
+		if c.Field == "" {
+			return fmt.Errorf("conditions[%d]: field is required", ci)
+		}
+		if !slices.Contains(validConditionOperators, c.Operator) {
+			return fmt.Errorf("conditions[%d]: unsupported operator %q", ci, c.Operator)
+		}
+	}
+	return nil
+}
+
+func validRiskLevel(level RiskLevel) bool {
+	switch level {
+	case "", RiskLevelLow, RiskLevelMedium, RiskLevelHigh:
+		return true
+	}
+	return false
+}
+
+// Rules returns the policy's rules in evaluation order, tagged with their group
+func (p *Policy) Rules() []Rule {
+	var rules []Rule
+	now := time.Now()
+	for _, g := range p.Groups {
+		for _, r := range g.Rules {
+			r.Group = g.Name
+			r.CreatedAt = now
+			rules = append(rules, r)
+		}
+	}
+	return rules
+}
+
+// Encode renders the policy as YAML. Group descriptions are written as
+// comments above each group so the file reads well in code review.
+func (p *Policy) Encode() ([]byte, error) {
+	var doc yaml.Node
+	if err := doc.Encode(p); err != nil {
+		return nil, err
+	}
+	doc.HeadComment = "Aster permission policy\nApply with: aster permissions import <file>"
+
+	for _, node := range groupNodes(&doc) {
+		for k := 0; k+1 < len(node.Content); k += 2 {
+			if node.Content[k].Value == "description" {
+				node.HeadComment = node.Content[k+1].Value
+				node.Content = slices.Delete(node.Content, k, k+2)
+				break
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// groupNodes returns the group nodes of a policy mapping node
+func groupNodes(policy *yaml.Node) []*yaml.Node {
+	if policy.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(policy.Content); i += 2 {
+		if policy.Content[i].Value == "groups" {
+			return policy.Content[i+1].Content
+		}
+	}
+	return nil
+}
+
+// commentText strips comment markers from a YAML comment block
+func commentText(comment string) string {
+	lines := strings.Split(comment, "\n")
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		line = strings.TrimPrefix(line, "#")
+		lines[i] = strings.TrimPrefix(line, " ")
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// mergePolicyRules places the policy's rules ahead of existing rules, so a
+// reviewed policy takes precedence over rules recorded at runtime. Existing
+// rules from groups the policy defines are replaced.
+func mergePolicyRules(existing []Rule, p *Policy) []Rule {
+	groups := make(map[string]bool, len(p.Groups))
+	for _, g := range p.Groups {
+		groups[g.Name] = true
+	}
+	merged := p.Rules()
+	for _, r := range existing {
+		if r.Group == "" || !groups[r.Group] {
+			merged = append(merged, r)
+		}
+	}
+	return merged
+}
+
+// policyFromRules groups unexpired rules into a policy, keeping the order in
+// which groups first appear. Rules without a group go into DefaultPolicyGroup.
+func policyFromRules(rules []Rule, mode Mode, toolRisks map[string]RiskLevel) *Policy {
+	p := &Policy{Version: PolicyVersion, Mode: mode}
+	if len(toolRisks) > 0 {
+		p.ToolRisks = maps.Clone(toolRisks)
+	}
+
+	index := make(map[string]int)
+	now := time.Now()
+	for _, r := range rules {
+		if r.ExpiresAt != nil && r.ExpiresAt.Before(now) {
+			continue
+		}
+		name := r.Group
+		if name == "" {
+			name = DefaultPolicyGroup
+		}
+		gi, ok := index[name]
+		if !ok {
+			gi = len(p.Groups)
+			index[name] = gi
+			p.Groups = append(p.Groups, RuleGroup{Name: name})
+		}
+		r.Group = ""
+		p.Groups[gi].Rules = append(p.Groups[gi].Rules, r)
+	}
+	return p
+}
+
+// removeGroupRules returns rules without the named group and how many were removed
+func removeGroupRules(rules []Rule, group string) ([]Rule, int) {
+	kept := slices.DeleteFunc(slices.Clone(rules), func(r Rule) bool { return r.Group == group })
+	return kept, len(rules) - len(kept)
+}
+
+// ApplyPolicy validates the policy and applies its mode, tool risks and rules.
+// Rules of groups in the policy replace previously applied rules of those
+// groups and are persisted like rules added with AddRule.
+func (i *Inspector) ApplyPolicy(p *Policy) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	i.rulesMutex.Lock()
+	defer i.rulesMutex.Unlock()
+
+	if p.Mode != "" {
+		i.mode = p.Mode
+	}
+	maps.Copy(i.toolRisks, p.ToolRisks)
+	i.rules = mergePolicyRules(i.rules, p)
+	i.saveRules()
+	return nil
+}
+
+// ExportPolicy returns the current rules, custom tool risks and mode as a policy
+func (i *Inspector) ExportPolicy() *Policy {
+	i.rulesMutex.RLock()
+	defer i.rulesMutex.RUnlock()
+	return policyFromRules(i.rules, i.mode, i.toolRisks)
+}
+
+// RemoveRuleGroup removes all rules of a policy group and returns how many were removed
+func (i *Inspector) RemoveRuleGroup(group string) int {
+	i.rulesMutex.Lock()
+	defer i.rulesMutex.Unlock()
+
+	var n int
+	i.rules, n = removeGroupRules(i.rules, group)
+	if n > 0 {
+		i.saveRules()
+	}
+	return n
+}
+
+// ApplyPolicy applies a policy to the enhanced inspector; see Inspector.ApplyPolicy
+func (i *EnhancedInspector) ApplyPolicy(p *Policy) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	i.rulesMutex.Lock()
+	defer i.rulesMutex.Unlock()
+
+	if p.Mode != "" {
+		i.mode = p.Mode
+	}
+	maps.Copy(i.toolRisks, p.ToolRisks)
+	i.rules = mergePolicyRules(i.rules, p)
+	i.saveRules()
+	return nil
+}
+
+// ExportPolicy returns the current rules, custom tool risks and mode as a policy
+func (i *EnhancedInspector) ExportPolicy() *Policy {
+	i.rulesMutex.RLock()
+	defer i.rulesMutex.RUnlock()
+	return policyFromRules(i.rules, i.mode, i.toolRisks)
+}
+
+// RemoveRuleGroup removes all rules of a policy group and returns how many were removed
+func (i *EnhancedInspector) RemoveRuleGroup(group string) int {
+	i.rulesMutex.Lock()
+	defer i.rulesMutex.Unlock()
+
+	var n int
+	i.rules, n = removeGroupRules(i.rules, group)
+	if n > 0 {
+		i.saveRules()
+	}
+	return n
+}
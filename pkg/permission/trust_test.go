@@ -0,0 +1,79 @@
+package permission
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTrustStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config", "trust.yaml")
+	store, err := NewTrustStore(path)
+	if err != nil {
+		t.Fatalf("NewTrustStore() error = %v", err)
+	}
+
+	root := t.TempDir()
+	project := filepath.Join(root, "project")
+	vendor := filepath.Join(project, "vendor")
+	if err := os.MkdirAll(vendor, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if store.Level(project) != TrustLevelUntrusted {
+		t.Errorf("unknown workspace level = %q", store.Level(project))
+	}
+	if err := store.SetLevel(project, TrustLevelTrusted); err != nil {
+		t.Fatalf("SetLevel() error = %v", err)
+	}
+	if err := store.SetLevel(vendor, TrustLevelUntrusted); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SetLevel(root, "maybe"); err == nil {
+		t.Error("SetLevel() accepted an invalid level")
+	}
+
+	// 最近的决策优先，子目录继承父目录的信任
+	reloaded, err := NewTrustStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for dir, want := range map[string]TrustLevel{
+		project:                                 TrustLevelTrusted,
+		filepath.Join(project, "cmd"):           TrustLevelTrusted,
+		filepath.Join(vendor, "lib"):            TrustLevelUntrusted,
+		root:                                    TrustLevelUntrusted,
+		filepath.Join(project, "..", "project"): TrustLevelTrusted,
+	} {
+		if got := reloaded.Level(dir); got != want {
+			t.Errorf("Level(%s) = %q, want %q", dir, got, want)
+		}
+	}
+
+	if err := reloaded.Forget(project); err != nil {
+		t.Fatal(err)
+	}
+	if reloaded.IsTrusted(project) || len(reloaded.Entries()) != 1 {
+		t.Errorf("after Forget: entries = %v", reloaded.Entries())
+	}
+
+	if err := os.WriteFile(path, []byte("workspaces: ["), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewTrustStore(path); err == nil {
+		t.Error("NewTrustStore() accepted a corrupt file")
+	}
+}
+
+func TestRequiresWorkspaceTrust(t *testing.T) {
+	for _, name := range []string{"Bash", "WebFetch", "WebSearch", "CodeExecute", "Browser", "FindDefinition", "Diagnostics"} {
+		if !RequiresWorkspaceTrust(name) {
+			t.Errorf("%s should require workspace trust", name)
+		}
+	}
+	for _, name := range []string{"Read", "Grep", "Write", "RenderUI"} {
+		if RequiresWorkspaceTrust(name) {
+			t.Errorf("%s should not require workspace trust", name)
+		}
+	}
+}
@@ -8,11 +8,11 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"net"
 	"net/http"
 	"strings"
 	"time"
 
+	"github.com/astercloud/aster/pkg/httpclient"
 	"github.com/astercloud/aster/pkg/logging"
 	"github.com/astercloud/aster/pkg/types"
 	"github.com/astercloud/aster/pkg/util"
@@ -46,20 +46,12 @@ func NewAnthropicProvider(config *types.ModelConfig) (*AnthropicProvider, error)
 	}
 
 	// 配置 HTTP 客户端超时，避免无限等待
+	// 基于共享出站配置（代理、CA、连接池），另加响应头超时
+	transport := httpclient.CloneTransport()
+	transport.ResponseHeaderTimeout = 30 * time.Second
 	client := &http.Client{
-		Timeout: 120 * time.Second, // 全局超时 120 秒
-		Transport: &http.Transport{
-			DialContext: (&net.Dialer{
-				Timeout:   30 * time.Second, // 连接超时 30 秒
-				KeepAlive: 30 * time.Second,
-			}).DialContext,
-			TLSHandshakeTimeout:   10 * time.Second, // TLS 握手超时
-			ResponseHeaderTimeout: 30 * time.Second, // 响应头超时
-			ExpectContinueTimeout: 1 * time.Second,
-			MaxIdleConns:          100,
-			MaxIdleConnsPerHost:   10,
-			IdleConnTimeout:       90 * time.Second,
-		},
+		Timeout:   120 * time.Second, // 全局超时 120 秒
+		Transport: httpclient.ContextHeaders(transport),
 	}
 
 	return &AnthropicProvider{
@@ -103,7 +95,7 @@ func (ap *AnthropicProvider) Complete(ctx context.Context, messages []types.Mess
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
 		anthropicLog.Error(ctx, "API error response", map[string]any{"status": resp.StatusCode, "body": string(body)})
-		return nil, fmt.Errorf("anthropic api error: %d - %s", resp.StatusCode, string(body))
+		return nil, newAPIError("anthropic", resp.StatusCode, body)
 	}
 
 	// 解析完整响应
@@ -181,7 +173,7 @@ func (ap *AnthropicProvider) Stream(ctx context.Context, messages []types.Messag
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
 		_ = resp.Body.Close()
-		return nil, fmt.Errorf("anthropic api error: %d - %s", resp.StatusCode, string(body))
+		return nil, newAPIError("anthropic", resp.StatusCode, body)
 	}
 
 	// 创建流式响应channel
@@ -210,15 +202,19 @@ func (ap *AnthropicProvider) buildRequest(messages []types.Message, opts *Stream
 			req["max_tokens"] = 4096 // 默认值
 		}
 
-		if opts.Temperature > 0 {
-			req["temperature"] = opts.Temperature
-		}
-
 		// 当有工具时，确保 max_tokens 足够大
 		if len(opts.Tools) > 0 && opts.MaxTokens == 0 {
 			req["max_tokens"] = 4096
 		}
 
+		// 启用 extended thinking 时不能设置 temperature
+		if thinking := resolveThinking(ap.config, opts); thinking != nil {
+			maxTokens, _ := req["max_tokens"].(int)
+			req["thinking"], req["max_tokens"] = thinkingRequest(thinking, maxTokens)
+		} else if opts.Temperature > 0 {
+			req["temperature"] = opts.Temperature
+		}
+
 		if opts.System != "" {
 			// 使用数组格式的 system，兼容更多代理服务
 			// Anthropic API 支持字符串和数组两种格式，数组格式兼容性更好
@@ -265,7 +261,7 @@ func (ap *AnthropicProvider) buildRequest(messages []types.Message, opts *Stream
 				toolMap := map[string]any{
 					"name":         tool.Name,
 					"description":  tool.Description,
-					"input_schema": tool.InputSchema,
+					"input_schema": toolParameters(tool, ToolFormatAnthropic),
 				}
 				// 添加工具使用示例（如果有）
 				// 参考 Anthropic 的 Tool Use Examples 功能
@@ -328,6 +324,9 @@ func (ap *AnthropicProvider) buildRequest(messages []types.Message, opts *Stream
 		if ap.systemPrompt != "" {
 			req["system"] = ap.systemPrompt
 		}
+		if thinking := resolveThinking(ap.config, nil); thinking != nil {
+			req["thinking"], req["max_tokens"] = thinkingRequest(thinking, 4096)
+		}
 	}
 
 	return req
@@ -355,6 +354,10 @@ func (ap *AnthropicProvider) convertMessages(messages []types.Message) []map[str
 						"type": "text",
 						"text": b.Text,
 					})
+				case *types.ThinkingBlock:
+					if thinking := thinkingBlockParam(b); thinking != nil {
+						blocks = append(blocks, thinking)
+					}
 				case *types.ToolUseBlock:
 					toolUse := map[string]any{
 						"type":  "tool_use",
@@ -544,6 +547,15 @@ func (ap *AnthropicProvider) parseCompleteResponse(apiResp map[string]any) (type
 		blockType, _ := block["type"].(string)
 
 		switch blockType {
+		case "thinking":
+			// Extended Thinking 块，签名需在后续请求中原样回传
+			thinking, _ := block["thinking"].(string)
+			signature, _ := block["signature"].(string)
+			assistantContent = append(assistantContent, &types.ThinkingBlock{
+				Thinking:  thinking,
+				Signature: signature,
+			})
+
 		case "text":
 			// 文本块
 			if text, ok := block["text"].(string); ok {
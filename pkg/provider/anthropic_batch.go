@@ -0,0 +1,238 @@
+package provider
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/astercloud/aster/pkg/util"
+)
+
+var _ BatchProvider = (*AnthropicProvider)(nil)
+
+// anthropicBatch Message Batches API 的批次对象
+type anthropicBatch struct {
+	ID               string    `json:"id"`
+	ProcessingStatus string    `json:"processing_status"` // in_progress, canceling, ended
+	CreatedAt        time.Time `json:"created_at"`
+	EndedAt          time.Time `json:"ended_at"`
+	ResultsURL       string    `json:"results_url"`
+	RequestCounts    struct {
+		Processing int `json:"processing"`
+		Succeeded  int `json:"succeeded"`
+		Errored    int `json:"errored"`
+		Canceled   int `json:"canceled"`
+		Expired    int `json:"expired"`
+	} `json:"request_counts"`
+}
+
+func (b *anthropicBatch) toBatch() *Batch {
+	c := b.RequestCounts
+	batch := &Batch{
+		ID:        b.ID,
+		Provider:  "anthropic",
+		Status:    BatchStatusInProgress,
+		CreatedAt: b.CreatedAt,
+		EndedAt:   b.EndedAt,
+		Counts: BatchCounts{
+			Total:     c.Processing + c.Succeeded + c.Errored + c.Canceled + c.Expired,
+			Succeeded: c.Succeeded,
+			Failed:    c.Errored + c.Canceled + c.Expired,
+		},
+	}
+	// ended 表示批次结束，单个请求的失败在结果中体现；全部请求都被取消或过期时按批次状态报告
+	if b.ProcessingStatus == "ended" {
+		switch {
+		case c.Succeeded+c.Errored == 0 && c.Canceled > 0:
+			batch.Status = BatchStatusCanceled
+		case c.Succeeded+c.Errored == 0 && c.Expired > 0:
+			batch.Status = BatchStatusExpired
+		default:
+			batch.Status = BatchStatusCompleted
+		}
+	}
+	return batch
+}
+
+// CreateBatch 提交 Message Batch
+func (ap *AnthropicProvider) CreateBatch(ctx context.Context, requests []BatchRequest) (*Batch, error) {
+	if len(requests) == 0 {
+		return nil, errors.New("batch requires at least one request")
+	}
+	items := make([]map[string]any, len(requests))
+	for i, r := range requests {
+		params := ap.buildRequest(r.Messages, r.Options)
+		delete(params, "stream")
+		if _, ok := params["max_tokens"]; !ok {
+			params["max_tokens"] = 4096
+		}
+		items[i] = map[string]any{"custom_id": r.CustomID, "params": params}
+	}
+	body, err := util.MarshalDeterministic(map[string]any{"requests": items})
+	if err != nil {
+		return nil, fmt.Errorf("marshal batch: %w", err)
+	}
+
+	var b anthropicBatch
+	if err := ap.batchCall(ctx, http.MethodPost, ap.baseURL+"/v1/messages/batches", body, &b); err != nil {
+		return nil, err
+	}
+	return b.toBatch(), nil
+}
+
+// GetBatch 查询 Message Batch 状态
+func (ap *AnthropicProvider) GetBatch(ctx context.Context, id string) (*Batch, error) {
+	b, err := ap.getBatch(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return b.toBatch(), nil
+}
+
+// CancelBatch 取消 Message Batch
+func (ap *AnthropicProvider) CancelBatch(ctx context.Context, id string) (*Batch, error) {
+	var b anthropicBatch
+	if err := ap.batchCall(ctx, http.MethodPost, ap.baseURL+"/v1/messages/batches/"+url.PathEscape(id)+"/cancel", nil, &b); err != nil {
+		return nil, err
+	}
+	return b.toBatch(), nil
+}
+
+// BatchResults 下载已结束批次的 JSONL 结果
+func (ap *AnthropicProvider) BatchResults(ctx context.Context, id string) ([]BatchResult, error) {
+	b, err := ap.getBatch(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if b.ProcessingStatus != "ended" || b.ResultsURL == "" {
+		return nil, fmt.Errorf("batch %s has not ended", id)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, b.ResultsURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	ap.setBatchHeaders(req)
+	resp, err := ap.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("send request: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(resp.Body)
+		return nil, newAPIError("anthropic", resp.StatusCode, data)
+	}
+
+	var results []BatchResult
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var item struct {
+			CustomID string `json:"custom_id"`
+			Result   struct {
+				Type    string         `json:"type"` // succeeded, errored, canceled, expired
+				Message map[string]any `json:"message"`
+				Error   struct {
+					Error struct {
+						Type    string `json:"type"`
+						Message string `json:"message"`
+					} `json:"error"`
+				} `json:"error"`
+			} `json:"result"`
+		}
+		if err := json.Unmarshal(line, &item); err != nil {
+			return nil, fmt.Errorf("decode batch result: %w", err)
+		}
+		result := BatchResult{CustomID: item.CustomID}
+		switch item.Result.Type {
+		case "succeeded":
+			message, err := ap.parseCompleteResponse(item.Result.Message)
+			if err != nil {
+				result.Error = err.Error()
+				break
+			}
+			result.Response = &CompleteResponse{Message: message, Usage: anthropicUsage(item.Result.Message)}
+		case "errored":
+			result.Error = fmt.Sprintf("%s: %s", item.Result.Error.Error.Type, item.Result.Error.Error.Message)
+		default:
+			result.Error = item.Result.Type
+		}
+		results = append(results, result)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read batch results: %w", err)
+	}
+	return results, nil
+}
+
+func (ap *AnthropicProvider) getBatch(ctx context.Context, id string) (*anthropicBatch, error) {
+	var b anthropicBatch
+	if err := ap.batchCall(ctx, http.MethodGet, ap.baseURL+"/v1/messages/batches/"+url.PathEscape(id), nil, &b); err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+func (ap *AnthropicProvider) setBatchHeaders(req *http.Request) {
+	req.Header.Set("X-Api-Key", ap.config.APIKey)
+	req.Header.Set("Anthropic-Version", ap.version)
+}
+
+// batchCall 调用 Batches API 并解码 JSON 响应
+func (ap *AnthropicProvider) batchCall(ctx context.Context, method, endpoint string, body []byte, dest any) error {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, reader)
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	ap.setBatchHeaders(req)
+
+	resp, err := ap.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("send request: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(resp.Body)
+		return newAPIError("anthropic", resp.StatusCode, data)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(dest); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	return nil
+}
+
+// anthropicUsage 解析响应中的 usage 字段
+func anthropicUsage(message map[string]any) *TokenUsage {
+	data, ok := message["usage"].(map[string]any)
+	if !ok {
+		return nil
+	}
+	number := func(key string) int64 {
+		v, _ := data[key].(float64)
+		return int64(v)
+	}
+	return &TokenUsage{
+		InputTokens:         number("input_tokens"),
+		OutputTokens:        number("output_tokens"),
+		CacheCreationTokens: number("cache_creation_input_tokens"),
+		CacheReadTokens:     number("cache_read_input_tokens"),
+	}
+}
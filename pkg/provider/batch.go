@@ -0,0 +1,78 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/astercloud/aster/pkg/types"
+)
+
+// ErrBatchNotSupported 提供商不支持批处理接口
+var ErrBatchNotSupported = errors.New("provider does not support batch requests")
+
+// BatchStatus 批处理任务状态（统一 Anthropic 与 OpenAI 的取值）
+type BatchStatus string
+
+const (
+	BatchStatusInProgress BatchStatus = "in_progress"
+	BatchStatusCompleted  BatchStatus = "completed"
+	BatchStatusFailed     BatchStatus = "failed"
+	BatchStatusCanceled   BatchStatus = "canceled"
+	BatchStatusExpired    BatchStatus = "expired"
+)
+
+// Done 批次是否已结束（不再变化）
+func (s BatchStatus) Done() bool {
+	return s != BatchStatusInProgress && s != ""
+}
+
+// BatchRequest 批次中的一个请求
+type BatchRequest struct {
+	// CustomID 批次内唯一，用于把结果对应回请求
+	CustomID string
+	Messages []types.Message
+	Options  *StreamOptions
+}
+
+// BatchCounts 批次请求计数
+type BatchCounts struct {
+	Total     int `json:"total"`
+	Succeeded int `json:"succeeded"`
+	Failed    int `json:"failed"`
+}
+
+// Batch 批处理任务
+type Batch struct {
+	ID        string      `json:"id"`
+	Provider  string      `json:"provider"`
+	Status    BatchStatus `json:"status"`
+	Counts    BatchCounts `json:"counts"`
+	CreatedAt time.Time   `json:"created_at"`
+	EndedAt   time.Time   `json:"ended_at,omitzero"`
+}
+
+// BatchResult 单个请求的结果
+type BatchResult struct {
+	CustomID string
+	// Response 请求成功时的完整响应
+	Response *CompleteResponse
+	// Error 请求失败、取消或过期时的原因
+	Error string
+}
+
+// BatchProvider 支持批处理接口的提供商（Anthropic Message Batches、OpenAI Batch API）
+// 批处理请求异步执行，通常在 24 小时内完成，价格约为实时调用的一半，适合评估、离线生成等非交互任务
+type BatchProvider interface {
+	// CreateBatch 提交批次
+	CreateBatch(ctx context.Context, requests []BatchRequest) (*Batch, error)
+
+	// GetBatch 查询批次状态
+	GetBatch(ctx context.Context, id string) (*Batch, error)
+
+	// BatchResults 获取已结束批次的全部结果
+	BatchResults(ctx context.Context, id string) ([]BatchResult, error)
+
+	// CancelBatch 取消批次，已完成的请求结果仍然可以获取
+	CancelBatch(ctx context.Context, id string) (*Batch, error)
+}
@@ -0,0 +1,113 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/astercloud/aster/pkg/types"
+)
+
+func TestAnthropicBatch(t *testing.T) {
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Api-Key") != "test-key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/v1/messages/batches":
+			var body struct {
+				Requests []struct {
+					CustomID string         `json:"custom_id"`
+					Params   map[string]any `json:"params"`
+				} `json:"requests"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			if len(body.Requests) != 1 || body.Requests[0].CustomID != "q1" || body.Requests[0].Params["stream"] != nil {
+				t.Errorf("unexpected batch body: %+v", body)
+			}
+			_, _ = io.WriteString(w, `{"id":"msgbatch_1","processing_status":"in_progress","request_counts":{"processing":1}}`)
+		case r.URL.Path == "/v1/messages/batches/msgbatch_1":
+			_, _ = io.WriteString(w, `{"id":"msgbatch_1","processing_status":"ended","results_url":"`+srv.URL+`/results","request_counts":{"succeeded":1,"errored":1}}`)
+		case r.URL.Path == "/results":
+			_, _ = io.WriteString(w, `{"custom_id":"q1","result":{"type":"succeeded","message":{"role":"assistant","content":[{"type":"text","text":"hi"}],"usage":{"input_tokens":3,"output_tokens":2}}}}
+{"custom_id":"q2","result":{"type":"errored","error":{"error":{"type":"invalid_request_error","message":"bad"}}}}
+`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	ap, err := NewAnthropicProvider(&types.ModelConfig{Provider: "anthropic", Model: "claude-sonnet-4-5", APIKey: "test-key", BaseURL: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	batch, err := ap.CreateBatch(ctx, []BatchRequest{{CustomID: "q1", Messages: []types.Message{{Role: types.RoleUser, Content: "hello"}}}})
+	if err != nil || batch.ID != "msgbatch_1" || batch.Status != BatchStatusInProgress {
+		t.Fatalf("batch = %+v, err = %v", batch, err)
+	}
+	if batch, _ = ap.GetBatch(ctx, "msgbatch_1"); batch.Status != BatchStatusCompleted || batch.Counts.Failed != 1 {
+		t.Errorf("batch = %+v", batch)
+	}
+	results, err := ap.BatchResults(ctx, "msgbatch_1")
+	if err != nil || len(results) != 2 {
+		t.Fatalf("results = %+v, err = %v", results, err)
+	}
+	if results[0].Response == nil || results[0].Response.Usage.OutputTokens != 2 {
+		t.Errorf("result[0] = %+v", results[0])
+	}
+	if !strings.Contains(results[1].Error, "bad") {
+		t.Errorf("result[1] = %+v", results[1])
+	}
+}
+
+func TestOpenAIBatch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/files":
+			if r.FormValue("purpose") != "batch" {
+				t.Errorf("purpose = %q", r.FormValue("purpose"))
+			}
+			_, _ = io.WriteString(w, `{"id":"file_in"}`)
+		case r.Method == http.MethodPost && r.URL.Path == "/batches":
+			var body map[string]any
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			if body["input_file_id"] != "file_in" || body["endpoint"] != openaiBatchEndpoint {
+				t.Errorf("unexpected batch body: %+v", body)
+			}
+			_, _ = io.WriteString(w, `{"id":"batch_1","status":"validating","created_at":1700000000}`)
+		case r.URL.Path == "/batches/batch_1":
+			_, _ = io.WriteString(w, `{"id":"batch_1","status":"completed","output_file_id":"file_out","completed_at":1700000100,"request_counts":{"total":1,"completed":1}}`)
+		case r.URL.Path == "/files/file_out/content":
+			_, _ = io.WriteString(w, `{"custom_id":"q1","response":{"status_code":200,"body":{"choices":[{"message":{"role":"assistant","content":"hi"}}],"usage":{"prompt_tokens":3,"completion_tokens":2}}}}`+"\n")
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	p, err := NewOpenAIProvider(&types.ModelConfig{Provider: "openai", Model: "gpt-4o-mini", APIKey: "test-key", BaseURL: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bp := p.(BatchProvider)
+	ctx := context.Background()
+	batch, err := bp.CreateBatch(ctx, []BatchRequest{{CustomID: "q1", Messages: []types.Message{{Role: types.RoleUser, Content: "hello"}}}})
+	if err != nil || batch.Status != BatchStatusInProgress {
+		t.Fatalf("batch = %+v, err = %v", batch, err)
+	}
+	results, err := bp.BatchResults(ctx, "batch_1")
+	if err != nil || len(results) != 1 || results[0].Response == nil {
+		t.Fatalf("results = %+v, err = %v", results, err)
+	}
+	if results[0].Response.Message.Content != "hi" && len(results[0].Response.Message.ContentBlocks) == 0 {
+		t.Errorf("message = %+v", results[0].Response.Message)
+	}
+}
@@ -0,0 +1,111 @@
+package provider
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/astercloud/aster/pkg/types"
+)
+
+// DefaultContextWindow 未登记模型的默认上下文窗口（token）
+const DefaultContextWindow = 128000
+
+var (
+	contextWindowMu sync.RWMutex
+
+	// contextWindows 按模型 ID 前缀登记的上下文窗口，匹配时取最长前缀
+	contextWindows = map[string]int{
+		// Anthropic
+		"claude": 200000,
+
+		// OpenAI
+		"gpt-3.5-turbo": 16385,
+		"gpt-4":         8192,
+		"gpt-4-32k":     32768,
+		"gpt-4-turbo":   128000,
+		"gpt-4o":        128000,
+		"gpt-4.1":       1047576,
+		"gpt-5":         400000,
+		"o1":            200000,
+		"o1-mini":       128000,
+		"o3":            200000,
+		"o4-mini":       200000,
+
+		// Google
+		"gemini-1.5-pro":   2097152,
+		"gemini-1.5-flash": 1048576,
+		"gemini-2":         1048576,
+
+		// DeepSeek
+		"deepseek": 128000,
+
+		// Moonshot / Kimi
+		"moonshot-v1-8k":   8192,
+		"moonshot-v1-32k":  32768,
+		"moonshot-v1-128k": 131072,
+		"kimi":             262144,
+
+		// 智谱 GLM
+		"glm-4":      128000,
+		"glm-4-long": 1000000,
+		"glm-4.5":    131072,
+
+		// 其他
+		"mistral-large": 131072,
+		"llama-3":       131072,
+		"qwen":          131072,
+		"doubao":        131072,
+	}
+)
+
+// RegisterContextWindow 登记（或覆盖）模型 ID 前缀对应的上下文窗口
+func RegisterContextWindow(modelPrefix string, tokens int) {
+	contextWindowMu.Lock()
+	defer contextWindowMu.Unlock()
+	contextWindows[strings.ToLower(modelPrefix)] = tokens
+}
+
+// ContextWindow 返回模型的上下文窗口大小，未登记时返回 DefaultContextWindow
+// 模型 ID 可以带 provider 前缀（如 "openrouter/anthropic/claude-sonnet-4-5"），匹配时只看最后一段
+func ContextWindow(model string) int {
+	name := strings.ToLower(model)
+	if idx := strings.LastIndex(name, "/"); idx >= 0 {
+		name = name[idx+1:]
+	}
+
+	contextWindowMu.RLock()
+	defer contextWindowMu.RUnlock()
+
+	best, size := "", DefaultContextWindow
+	for prefix, tokens := range contextWindows {
+		if strings.HasPrefix(name, prefix) && len(prefix) > len(best) {
+			best, size = prefix, tokens
+		}
+	}
+	return size
+}
+
+// ContextWindowFor 返回模型配置的上下文窗口：优先使用 ModelConfig.ContextWindow，否则查询登记表
+func ContextWindowFor(cfg *types.ModelConfig) int {
+	if cfg == nil {
+		return DefaultContextWindow
+	}
+	if cfg.ContextWindow > 0 {
+		return cfg.ContextWindow
+	}
+	return ContextWindow(cfg.Model)
+}
+
+// ContextWindowModels 返回已登记的模型前缀（按字母排序）
+func ContextWindowModels() []string {
+	contextWindowMu.RLock()
+	defer contextWindowMu.RUnlock()
+
+	prefixes := make([]string, 0, len(contextWindows))
+	for prefix := range contextWindows {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Strings(prefixes)
+	return prefixes
+}
@@ -0,0 +1,42 @@
+package provider
+
+import (
+	"testing"
+
+	"github.com/astercloud/aster/pkg/types"
+)
+
+func TestContextWindowLookup(t *testing.T) {
+	tests := []struct {
+		model string
+		want  int
+	}{
+		{"claude-sonnet-4-5", 200000},
+		{"gpt-4o-mini", 128000},
+		{"gpt-4", 8192},
+		{"gpt-4-turbo-2024-04-09", 128000},
+		{"o1-mini", 128000},
+		{"anthropic/claude-opus-4", 200000},
+		{"moonshot-v1-32k", 32768},
+		{"unknown-model", DefaultContextWindow},
+	}
+	for _, tt := range tests {
+		if got := ContextWindow(tt.model); got != tt.want {
+			t.Errorf("ContextWindow(%q) = %d, want %d", tt.model, got, tt.want)
+		}
+	}
+}
+
+func TestContextWindowForOverride(t *testing.T) {
+	if got := ContextWindowFor(&types.ModelConfig{Model: "claude-sonnet-4-5", ContextWindow: 1000000}); got != 1000000 {
+		t.Errorf("expected config override, got %d", got)
+	}
+	if got := ContextWindowFor(nil); got != DefaultContextWindow {
+		t.Errorf("expected default for nil config, got %d", got)
+	}
+
+	RegisterContextWindow("my-local-model", 4096)
+	if got := ContextWindow("my-local-model-q4"); got != 4096 {
+		t.Errorf("expected registered window, got %d", got)
+	}
+}
@@ -8,11 +8,11 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"net"
 	"net/http"
 	"strings"
 	"time"
 
+	"github.com/astercloud/aster/pkg/httpclient"
 	"github.com/astercloud/aster/pkg/logging"
 	"github.com/astercloud/aster/pkg/types"
 	"github.com/astercloud/aster/pkg/util"
@@ -41,20 +41,12 @@ func NewCustomClaudeProvider(config *types.ModelConfig) (*CustomClaudeProvider,
 	}
 
 	// 配置 HTTP 客户端超时，避免无限等待
+	// 基于共享出站配置（代理、CA、连接池），另加响应头超时
+	transport := httpclient.CloneTransport()
+	transport.ResponseHeaderTimeout = 30 * time.Second
 	client := &http.Client{
-		Timeout: 120 * time.Second, // 全局超时 120 秒
-		Transport: &http.Transport{
-			DialContext: (&net.Dialer{
-				Timeout:   30 * time.Second, // 连接超时 30 秒
-				KeepAlive: 30 * time.Second,
-			}).DialContext,
-			TLSHandshakeTimeout:   10 * time.Second, // TLS 握手超时
-			ResponseHeaderTimeout: 30 * time.Second, // 响应头超时
-			ExpectContinueTimeout: 1 * time.Second,
-			MaxIdleConns:          100,
-			MaxIdleConnsPerHost:   10,
-			IdleConnTimeout:       90 * time.Second,
-		},
+		Timeout:   120 * time.Second, // 全局超时 120 秒
+		Transport: httpclient.ContextHeaders(transport),
 	}
 
 	return &CustomClaudeProvider{
@@ -93,7 +85,7 @@ func (cp *CustomClaudeProvider) Complete(ctx context.Context, messages []types.M
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
 		customClaudeLog.Error(ctx, "API error response", map[string]any{"status": resp.StatusCode, "body": string(body)})
-		return nil, fmt.Errorf("api error: %d - %s", resp.StatusCode, string(body))
+		return nil, newAPIError("", resp.StatusCode, body)
 	}
 
 	var apiResp map[string]any
@@ -167,7 +159,7 @@ func (cp *CustomClaudeProvider) Stream(ctx context.Context, messages []types.Mes
 				"tail": string(jsonData[max(0, len(jsonData)-5000):]),
 			})
 		}
-		return nil, fmt.Errorf("api error: %d - %s", resp.StatusCode, string(body))
+		return nil, newAPIError("", resp.StatusCode, body)
 	}
 
 	chunkCh := make(chan StreamChunk, 10)
@@ -195,18 +187,12 @@ func (cp *CustomClaudeProvider) buildRequest(messages []types.Message, opts *Str
 
 		// Extended Thinking 配置
 		// 注意：启用 thinking 时，temperature 必须为 1（或不设置）
-		if opts.Thinking != nil && opts.Thinking.Enabled {
-			budgetTokens := opts.Thinking.BudgetTokens
-			if budgetTokens <= 0 {
-				budgetTokens = 10000 // 默认 10000 tokens 的思考预算
-			}
-			req["thinking"] = map[string]any{
-				"type":          "enabled",
-				"budget_tokens": budgetTokens,
-			}
+		if thinking := resolveThinking(cp.config, opts); thinking != nil {
+			maxTokens, _ := req["max_tokens"].(int)
+			req["thinking"], req["max_tokens"] = thinkingRequest(thinking, maxTokens)
 			// 启用 thinking 时不能设置 temperature（必须为默认值 1）
 			customClaudeLog.Info(context.Background(), "extended thinking enabled", map[string]any{
-				"budget_tokens": budgetTokens,
+				"thinking": req["thinking"],
 			})
 		} else if opts.Temperature > 0 {
 			req["temperature"] = opts.Temperature
@@ -224,7 +210,7 @@ func (cp *CustomClaudeProvider) buildRequest(messages []types.Message, opts *Str
 				toolMap := map[string]any{
 					"name":         tool.Name,
 					"description":  tool.Description,
-					"input_schema": tool.InputSchema,
+					"input_schema": toolParameters(tool, ToolFormatAnthropic),
 				}
 				tools = append(tools, toolMap)
 			}
@@ -263,6 +249,10 @@ func (cp *CustomClaudeProvider) convertMessages(messages []types.Message) []map[
 						"type": "text",
 						"text": b.Text,
 					})
+				case *types.ThinkingBlock:
+					if thinking := thinkingBlockParam(b); thinking != nil {
+						blocks = append(blocks, thinking)
+					}
 				case *types.ToolUseBlock:
 					// 确保 input 是有效的字典，避免 API 报错
 					input := b.Input
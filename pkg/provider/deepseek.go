@@ -11,6 +11,7 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/astercloud/aster/pkg/httpclient"
 	"github.com/astercloud/aster/pkg/logging"
 	"github.com/astercloud/aster/pkg/types"
 	"github.com/astercloud/aster/pkg/util"
@@ -45,7 +46,7 @@ func NewDeepseekProvider(config *types.ModelConfig) (*DeepseekProvider, error) {
 
 	return &DeepseekProvider{
 		config:  config,
-		client:  &http.Client{},
+		client:  httpclient.New(0),
 		baseURL: baseURL,
 		apiKey:  config.APIKey,
 	}, nil
@@ -103,7 +104,7 @@ func (dp *DeepseekProvider) Complete(ctx context.Context, messages []types.Messa
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
 		deepseekLog.Error(ctx, "API error response", map[string]any{"body": string(body)})
-		return nil, fmt.Errorf("deepseek api error: %d - %s", resp.StatusCode, string(body))
+		return nil, newAPIError("deepseek", resp.StatusCode, body)
 	}
 
 	deepseekLog.Debug(ctx, "parsing API response", nil)
@@ -189,7 +190,7 @@ func (dp *DeepseekProvider) Stream(ctx context.Context, messages []types.Message
 		body, _ := io.ReadAll(resp.Body)
 		_ = resp.Body.Close()
 		deepseekLog.Error(ctx, "API error response", map[string]any{"body": string(body)})
-		return nil, fmt.Errorf("deepseek api error: %d - %s", resp.StatusCode, string(body))
+		return nil, newAPIError("deepseek", resp.StatusCode, body)
 	}
 
 	deepseekLog.Debug(ctx, "API request successful", map[string]any{"status": resp.StatusCode})
@@ -255,7 +256,7 @@ func (dp *DeepseekProvider) buildRequest(messages []types.Message, opts *StreamO
 					"function": map[string]any{
 						"name":        tool.Name,
 						"description": tool.Description,
-						"parameters":  tool.InputSchema,
+						"parameters":  toolParameters(tool, ToolFormatOpenAI),
 					},
 					// TODO: Deepseek API 暂不支持 input_examples，待官方支持后启用
 					// 参考: https://api-docs.deepseek.com/
@@ -0,0 +1,44 @@
+package provider
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/astercloud/aster/pkg/agenterr"
+)
+
+// APIError 模型服务返回的非 2xx 响应
+type APIError struct {
+	Provider   string // 为空时不带前缀
+	StatusCode int
+	Body       string
+}
+
+// newAPIError 由 HTTP 响应创建 APIError
+func newAPIError(provider string, statusCode int, body []byte) *APIError {
+	return &APIError{Provider: provider, StatusCode: statusCode, Body: string(body)}
+}
+
+func (e *APIError) Error() string {
+	if e.Provider == "" {
+		return fmt.Sprintf("api error: %d - %s", e.StatusCode, e.Body)
+	}
+	return fmt.Sprintf("%s api error: %d - %s", e.Provider, e.StatusCode, e.Body)
+}
+
+// ErrorCode 按 HTTP 状态码分类
+func (e *APIError) ErrorCode() agenterr.Code {
+	switch {
+	case e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden:
+		return agenterr.CodeProviderAuth
+	case e.StatusCode == http.StatusTooManyRequests:
+		return agenterr.CodeProviderRateLimited
+	case e.StatusCode == http.StatusServiceUnavailable || e.StatusCode == 529: // 529: Anthropic overloaded
+		return agenterr.CodeProviderOverloaded
+	case e.StatusCode >= 500:
+		return agenterr.CodeProviderUnavailable
+	case e.StatusCode >= 400:
+		return agenterr.CodeProviderBadRequest
+	}
+	return agenterr.CodeProviderError
+}
@@ -88,3 +88,33 @@ func (f *MultiProviderFactory) Create(config *types.ModelConfig) (Provider, erro
 		return nil, fmt.Errorf("unsupported provider: %s", providerType)
 	}
 }
+
+// DefaultBaseURL 返回提供商未配置 BaseURL 时使用的默认地址，未知或需要自定义地址的提供商返回空字符串
+func DefaultBaseURL(providerType string) string {
+	switch providerType {
+	case "", "anthropic":
+		return defaultAnthropicBaseURL
+	case "glm", "zhipu", "bigmodel":
+		return defaultGLMBaseURL
+	case "deepseek":
+		return defaultDeepseekBaseURL
+	case "openai":
+		return OpenAIAPIBaseURL
+	case "groq":
+		return GroqAPIBaseURL
+	case "ollama":
+		return OllamaDefaultBaseURL
+	case "openrouter":
+		return OpenRouterAPIBaseURL
+	case "mistral":
+		return MistralAPIBaseURL
+	case "doubao", "bytedance":
+		return DoubaoAPIBaseURL
+	case "moonshot", "kimi":
+		return MoonshotAPIBaseURL
+	case "gemini", "google":
+		return GeminiAPIBaseURL
+	default:
+		return ""
+	}
+}
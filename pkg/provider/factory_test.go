@@ -295,3 +295,15 @@ func TestMultiProviderFactory_Create_OpenRouter(t *testing.T) {
 		t.Error("expected openrouter provider, got nil")
 	}
 }
+
+func TestDefaultBaseURL(t *testing.T) {
+	if got := DefaultBaseURL(""); got != defaultAnthropicBaseURL {
+		t.Errorf("DefaultBaseURL(\"\") = %q, want anthropic", got)
+	}
+	if got := DefaultBaseURL("zhipu"); got != defaultGLMBaseURL {
+		t.Errorf("DefaultBaseURL(zhipu) = %q, want GLM", got)
+	}
+	if got := DefaultBaseURL("custom"); got != "" {
+		t.Errorf("DefaultBaseURL(custom) = %q, want empty", got)
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/astercloud/aster/pkg/httpclient"
 	"github.com/astercloud/aster/pkg/types"
 	"github.com/astercloud/aster/pkg/util"
 )
@@ -101,7 +102,7 @@ func NewGeminiProvider(config *types.ModelConfig) (Provider, error) {
 	return &GeminiProvider{
 		config:     config,
 		baseURL:    baseURL,
-		httpClient: &http.Client{Timeout: 120 * time.Second},
+		httpClient: httpclient.New(120 * time.Second),
 	}, nil
 }
 
@@ -140,7 +141,7 @@ func (p *GeminiProvider) Stream(
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
 		_ = resp.Body.Close()
-		return nil, fmt.Errorf("gemini API error: %d - %s", resp.StatusCode, string(body))
+		return nil, newAPIError("gemini", resp.StatusCode, body)
 	}
 
 	// 创建流式响应 channel
@@ -187,7 +188,7 @@ func (p *GeminiProvider) Complete(
 	// 检查响应状态
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("gemini API error: %d - %s", resp.StatusCode, string(body))
+		return nil, newAPIError("gemini", resp.StatusCode, body)
 	}
 
 	// 解析响应
@@ -372,7 +373,7 @@ func (p *GeminiProvider) convertTools(tools []ToolSchema) GeminiTool {
 		declarations = append(declarations, GeminiFunctionDeclaration{
 			Name:        tool.Name,
 			Description: tool.Description,
-			Parameters:  tool.InputSchema,
+			Parameters:  toolParameters(tool, ToolFormatGemini),
 			// TODO: Gemini API 暂不支持 input_examples，待官方支持后启用
 			// 参考: https://ai.google.dev/api/caching
 			// 实现参考: pkg/provider/anthropic.go buildRequest() 中的 InputExamples 处理
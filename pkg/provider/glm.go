@@ -11,6 +11,7 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/astercloud/aster/pkg/httpclient"
 	"github.com/astercloud/aster/pkg/logging"
 	"github.com/astercloud/aster/pkg/types"
 	"github.com/astercloud/aster/pkg/util"
@@ -44,7 +45,7 @@ func NewGLMProvider(config *types.ModelConfig) (*GLMProvider, error) {
 
 	return &GLMProvider{
 		config:  config,
-		client:  &http.Client{},
+		client:  httpclient.New(0),
 		baseURL: baseURL,
 		apiKey:  config.APIKey,
 	}, nil
@@ -90,7 +91,7 @@ func (gp *GLMProvider) Complete(ctx context.Context, messages []types.Message, o
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
 		glmLog.Error(ctx, "API error response", map[string]any{"body": string(body)})
-		return nil, fmt.Errorf("glm api error: %d - %s", resp.StatusCode, string(body))
+		return nil, newAPIError("glm", resp.StatusCode, body)
 	}
 
 	// 解析完整响应
@@ -170,7 +171,7 @@ func (gp *GLMProvider) Stream(ctx context.Context, messages []types.Message, opt
 		body, _ := io.ReadAll(resp.Body)
 		_ = resp.Body.Close()
 		glmLog.Error(ctx, "API error response", map[string]any{"body": string(body)})
-		return nil, fmt.Errorf("glm api error: %d - %s", resp.StatusCode, string(body))
+		return nil, newAPIError("glm", resp.StatusCode, body)
 	}
 
 	glmLog.Debug(ctx, "API request successful", map[string]any{"status": resp.StatusCode})
@@ -221,7 +222,7 @@ func (gp *GLMProvider) buildRequest(messages []types.Message, opts *StreamOption
 					"function": map[string]any{
 						"name":        tool.Name,
 						"description": tool.Description,
-						"parameters":  tool.InputSchema,
+						"parameters":  toolParameters(tool, ToolFormatOpenAI),
 					},
 					// TODO: GLM API 暂不支持 input_examples，待官方支持后启用
 					// 参考: https://open.bigmodel.cn/dev/api
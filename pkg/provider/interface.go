@@ -154,6 +154,10 @@ type StreamOptions struct {
 	// Thinking Extended Thinking 配置（Claude 专属）
 	// 启用后模型会在响应前进行深度思考，思考过程会通过流式事件返回
 	Thinking *ThinkingConfig `json:"thinking,omitempty"`
+
+	// ReasoningEffort OpenAI 推理强度: "low", "medium", "high"
+	// 未设置时使用 ModelConfig.Reasoning.Effort
+	ReasoningEffort string `json:"reasoning_effort,omitempty"`
 }
 
 // ToolChoiceOption 工具选择选项
@@ -0,0 +1,512 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/astercloud/aster/pkg/agenterr"
+	"github.com/astercloud/aster/pkg/logging"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+var keyLog = logging.ForComponent("KeyManager")
+
+// DefaultKeyCooldown 被限流（429）的 Key 暂停使用的默认时长
+const DefaultKeyCooldown = time.Minute
+
+// ErrNoAvailableKey Provider 的所有 Key 均失效或处于冷却中
+var ErrNoAvailableKey = errors.New("no available api key")
+
+// Key 状态
+const (
+	KeyStatusActive  = "active"
+	KeyStatusCooling = "cooling" // 被限流，冷却结束后自动恢复
+	KeyStatusInvalid = "invalid" // 认证失败，健康检查通过后恢复
+)
+
+// KeyUsage 单个 API Key 的用量与状态，Key 已脱敏
+type KeyUsage struct {
+	Provider      string    `json:"provider"`
+	Key           string    `json:"key"`
+	Status        string    `json:"status"`
+	Current       bool      `json:"current"`
+	Requests      int64     `json:"requests"`
+	Failures      int64     `json:"failures"`
+	Rotations     int64     `json:"rotations"`
+	InputTokens   int64     `json:"input_tokens"`
+	OutputTokens  int64     `json:"output_tokens"`
+	LastUsed      time.Time `json:"last_used,omitzero"`
+	LastError     string    `json:"last_error,omitempty"`
+	LastChecked   time.Time `json:"last_checked,omitzero"`
+	CooldownUntil time.Time `json:"cooldown_until,omitzero"`
+}
+
+// KeyCheckFunc 检查 Provider 的某个 Key 是否可用
+type KeyCheckFunc func(ctx context.Context, provider, key string) error
+
+// KeyManagerOptions KeyManager 配置
+type KeyManagerOptions struct {
+	// Cooldown 被限流的 Key 暂停使用的时长，默认 DefaultKeyCooldown
+	Cooldown time.Duration
+}
+
+// keyState 单个 Key 的运行状态
+type keyState struct {
+	key   string
+	usage KeyUsage
+}
+
+// keyPool 单个 Provider 的 Key 列表，current 为当前使用的 Key
+type keyPool struct {
+	keys    []*keyState
+	current int
+}
+
+// KeyManager 管理每个 Provider 的多个 API Key
+// 当前 Key 遇到 401/403 时标记为失效、遇到 429 时进入冷却，并轮换到下一个可用 Key；
+// 健康检查定期重新验证所有 Key，恢复已修复的 Key
+type KeyManager struct {
+	mu       sync.Mutex
+	pools    map[string]*keyPool
+	cooldown time.Duration
+	now      func() time.Time
+}
+
+// NewKeyManager 创建 KeyManager，keys 为 Provider -> Key 列表，重复和空 Key 会被忽略
+func NewKeyManager(keys map[string][]string, opts KeyManagerOptions) *KeyManager {
+	if opts.Cooldown <= 0 {
+		opts.Cooldown = DefaultKeyCooldown
+	}
+	m := &KeyManager{
+		pools:    make(map[string]*keyPool, len(keys)),
+		cooldown: opts.Cooldown,
+		now:      time.Now,
+	}
+	for providerName, list := range keys {
+		pool := &keyPool{}
+		seen := make(map[string]bool, len(list))
+		for _, key := range list {
+			if key == "" || seen[key] {
+				continue
+			}
+			seen[key] = true
+			pool.keys = append(pool.keys, &keyState{
+				key: key,
+				usage: KeyUsage{
+					Provider: providerName,
+					Key:      maskKey(key),
+					Status:   KeyStatusActive,
+				},
+			})
+		}
+		if len(pool.keys) > 0 {
+			m.pools[providerName] = pool
+		}
+	}
+	return m
+}
+
+// Providers 返回配置了 Key 的 Provider（已排序）
+func (m *KeyManager) Providers() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return sortedKeys(m.pools)
+}
+
+// Has 返回 Provider 是否配置了 Key
+func (m *KeyManager) Has(provider string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, ok := m.pools[provider]
+	return ok
+}
+
+// Acquire 返回 Provider 当前可用的 Key
+// 当前 Key 不可用时按顺序轮换到下一个可用 Key，全部不可用时返回 ErrNoAvailableKey
+func (m *KeyManager) Acquire(provider string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	pool, ok := m.pools[provider]
+	if !ok {
+		return "", fmt.Errorf("%w: %s has no keys configured", ErrNoAvailableKey, provider)
+	}
+	now := m.now()
+	for i := range pool.keys {
+		idx := (pool.current + i) % len(pool.keys)
+		st := pool.keys[idx]
+		if !st.available(now) {
+			continue
+		}
+		if idx != pool.current {
+			pool.keys[pool.current].usage.Rotations++
+			keyLog.Info(context.Background(), "rotating api key", map[string]any{
+				"provider": provider,
+				"from":     pool.keys[pool.current].usage.Key,
+				"to":       st.usage.Key,
+			})
+			pool.current = idx
+		}
+		return st.key, nil
+	}
+	return "", fmt.Errorf("%w: all %d keys of %s are invalid or rate limited", ErrNoAvailableKey, len(pool.keys), provider)
+}
+
+// available 返回 Key 是否可用，冷却结束的 Key 恢复为 active
+func (st *keyState) available(now time.Time) bool {
+	switch st.usage.Status {
+	case KeyStatusInvalid:
+		return false
+	case KeyStatusCooling:
+		if now.Before(st.usage.CooldownUntil) {
+			return false
+		}
+		st.usage.Status = KeyStatusActive
+		st.usage.CooldownUntil = time.Time{}
+	}
+	return true
+}
+
+// Report 记录一次使用 key 的请求结果
+// 认证失败的 Key 标记为失效，被限流的 Key 进入冷却，返回 Key 是否需要轮换
+func (m *KeyManager) Report(provider, key string, usage *TokenUsage, err error) (rotate bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	st := m.lookup(provider, key)
+	if st == nil {
+		return false
+	}
+	now := m.now()
+	st.usage.Requests++
+	st.usage.LastUsed = now
+	if usage != nil {
+		st.usage.InputTokens += usage.InputTokens
+		st.usage.OutputTokens += usage.OutputTokens
+	}
+	if err == nil {
+		return false
+	}
+	st.usage.Failures++
+	st.usage.LastError = err.Error()
+	switch agenterr.CodeOf(err) {
+	case agenterr.CodeProviderAuth:
+		st.usage.Status = KeyStatusInvalid
+		return true
+	case agenterr.CodeProviderRateLimited:
+		st.usage.Status = KeyStatusCooling
+		st.usage.CooldownUntil = now.Add(m.cooldown)
+		return true
+	}
+	return false
+}
+
+// lookup 查找 Key 状态，调用方需持有锁
+func (m *KeyManager) lookup(provider, key string) *keyState {
+	pool, ok := m.pools[provider]
+	if !ok {
+		return nil
+	}
+	for _, st := range pool.keys {
+		if st.key == key {
+			return st
+		}
+	}
+	return nil
+}
+
+// Usage 返回所有 Key 的用量与状态，按 Provider 排序，同一 Provider 内保持配置顺序
+func (m *KeyManager) Usage() []KeyUsage {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.now()
+	var out []KeyUsage
+	for _, providerName := range sortedKeys(m.pools) {
+		pool := m.pools[providerName]
+		for i, st := range pool.keys {
+			st.available(now)
+			u := st.usage
+			u.Current = i == pool.current
+			out = append(out, u)
+		}
+	}
+	return out
+}
+
+// CheckKeys 用 check 验证所有 Key：认证失败的标记为失效，被限流的进入冷却，成功的恢复为 active
+func (m *KeyManager) CheckKeys(ctx context.Context, check KeyCheckFunc) {
+	type target struct{ provider, key string }
+	m.mu.Lock()
+	var targets []target
+	for _, providerName := range sortedKeys(m.pools) {
+		for _, st := range m.pools[providerName].keys {
+			targets = append(targets, target{providerName, st.key})
+		}
+	}
+	m.mu.Unlock()
+
+	for _, t := range targets {
+		if ctx.Err() != nil {
+			return
+		}
+		err := check(ctx, t.provider, t.key)
+
+		m.mu.Lock()
+		st := m.lookup(t.provider, t.key)
+		if st == nil {
+			m.mu.Unlock()
+			continue
+		}
+		now := m.now()
+		st.usage.LastChecked = now
+		switch code := agenterr.CodeOf(err); {
+		case err == nil:
+			if st.usage.Status != KeyStatusActive {
+				keyLog.Info(ctx, "api key recovered", map[string]any{"provider": t.provider, "key": st.usage.Key})
+			}
+			st.usage.Status = KeyStatusActive
+			st.usage.CooldownUntil = time.Time{}
+		case code == agenterr.CodeProviderAuth:
+			st.usage.Status = KeyStatusInvalid
+			st.usage.LastError = err.Error()
+		case code == agenterr.CodeProviderRateLimited:
+			st.usage.Status = KeyStatusCooling
+			st.usage.CooldownUntil = now.Add(m.cooldown)
+			st.usage.LastError = err.Error()
+		default:
+			// 网络等临时错误不改变状态，避免误判
+			st.usage.LastError = err.Error()
+		}
+		m.mu.Unlock()
+	}
+}
+
+// StartHealthChecks 每隔 interval 执行一次 CheckKeys，直到 ctx 取消
+func (m *KeyManager) StartHealthChecks(ctx context.Context, interval time.Duration, check KeyCheckFunc) {
+	if interval <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				m.CheckKeys(ctx, check)
+			}
+		}
+	}()
+}
+
+// FactoryKeyCheck 返回用 factory 创建 Provider 并发送一次最小请求的 KeyCheckFunc
+// model 为 Provider -> 用于检查的模型，未列出的 Provider 使用其默认模型
+func FactoryKeyCheck(factory Factory, model map[string]string) KeyCheckFunc {
+	return func(ctx context.Context, providerName, key string) error {
+		p, err := factory.Create(&types.ModelConfig{
+			Provider: providerName,
+			Model:    model[providerName],
+			APIKey:   key,
+		})
+		if err != nil {
+			return err
+		}
+		defer p.Close()
+		_, err = p.Complete(ctx, []types.Message{{Role: types.RoleUser, Content: "ping"}}, &StreamOptions{MaxTokens: 1})
+		return err
+	}
+}
+
+// WrapFactory 包装 Provider 工厂，配置了 Key 的 Provider 按 KeyManager 选择和轮换 Key
+// 未配置 Key 的 Provider 原样创建
+func (m *KeyManager) WrapFactory(factory Factory) Factory {
+	if f, ok := factory.(*keyedFactory); ok && f.keys == m {
+		return f
+	}
+	return &keyedFactory{factory: factory, keys: m}
+}
+
+// keyedFactory 为配置了 Key 的 Provider 创建 keyedProvider
+type keyedFactory struct {
+	factory Factory
+	keys    *KeyManager
+}
+
+func (f *keyedFactory) Create(config *types.ModelConfig) (Provider, error) {
+	if config == nil || !f.keys.Has(config.Provider) {
+		return f.factory.Create(config)
+	}
+	p := &keyedProvider{
+		factory:   f.factory,
+		keys:      f.keys,
+		config:    config,
+		providers: make(map[string]Provider),
+	}
+	// 立即创建一次，尽早暴露配置错误，同时提供 Capabilities
+	if _, _, err := p.acquire(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// keyedProvider 按 KeyManager 当前选择的 Key 转发请求，认证失败或被限流时换 Key 重试
+type keyedProvider struct {
+	factory Factory
+	keys    *KeyManager
+	config  *types.ModelConfig
+
+	mu           sync.Mutex
+	providers    map[string]Provider // Key -> 底层 Provider
+	last         Provider
+	systemPrompt string
+}
+
+// acquire 返回当前 Key 及其底层 Provider，首次使用某个 Key 时创建
+func (p *keyedProvider) acquire() (string, Provider, error) {
+	key, err := p.keys.Acquire(p.config.Provider)
+	if err != nil {
+		return "", nil, err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if prov, ok := p.providers[key]; ok {
+		p.last = prov
+		return key, prov, nil
+	}
+	cfg := *p.config
+	cfg.APIKey = key
+	prov, err := p.factory.Create(&cfg)
+	if err != nil {
+		return "", nil, err
+	}
+	if p.systemPrompt != "" {
+		if err := prov.SetSystemPrompt(p.systemPrompt); err != nil {
+			return "", nil, err
+		}
+	}
+	p.providers[key] = prov
+	p.last = prov
+	return key, prov, nil
+}
+
+// attempts 最多尝试的次数：每个 Key 一次
+func (p *keyedProvider) attempts() int {
+	p.keys.mu.Lock()
+	defer p.keys.mu.Unlock()
+	if pool, ok := p.keys.pools[p.config.Provider]; ok {
+		return len(pool.keys)
+	}
+	return 1
+}
+
+func (p *keyedProvider) Stream(ctx context.Context, messages []types.Message, opts *StreamOptions) (<-chan StreamChunk, error) {
+	var lastErr error
+	for range p.attempts() {
+		key, prov, err := p.acquire()
+		if err != nil {
+			return nil, errors.Join(lastErr, err)
+		}
+		ch, err := prov.Stream(ctx, messages, opts)
+		if err != nil {
+			lastErr = err
+			if p.keys.Report(p.config.Provider, key, nil, err) && ctx.Err() == nil {
+				continue
+			}
+			return nil, err
+		}
+		return p.account(key, ch), nil
+	}
+	return nil, lastErr
+}
+
+// account 转发流式响应块，流结束时记录该 Key 的用量
+func (p *keyedProvider) account(key string, in <-chan StreamChunk) <-chan StreamChunk {
+	return ObserveStream(in, func(r StreamResult) {
+		p.keys.Report(p.config.Provider, key, r.Usage, r.Err)
+	})
+}
+
+func (p *keyedProvider) Complete(ctx context.Context, messages []types.Message, opts *StreamOptions) (*CompleteResponse, error) {
+	var lastErr error
+	for range p.attempts() {
+		key, prov, err := p.acquire()
+		if err != nil {
+			return nil, errors.Join(lastErr, err)
+		}
+		resp, err := prov.Complete(ctx, messages, opts)
+		if err != nil {
+			lastErr = err
+			if p.keys.Report(p.config.Provider, key, nil, err) && ctx.Err() == nil {
+				continue
+			}
+			return nil, err
+		}
+		p.keys.Report(p.config.Provider, key, resp.Usage, nil)
+		return resp, nil
+	}
+	return nil, lastErr
+}
+
+func (p *keyedProvider) Config() *types.ModelConfig {
+	return p.config
+}
+
+func (p *keyedProvider) Capabilities() ProviderCapabilities {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.last == nil {
+		return ProviderCapabilities{}
+	}
+	return p.last.Capabilities()
+}
+
+// SetSystemPrompt 应用到所有已创建的底层 Provider，之后创建的也会使用
+func (p *keyedProvider) SetSystemPrompt(prompt string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.systemPrompt = prompt
+	for _, prov := range p.providers {
+		if err := prov.SetSystemPrompt(prompt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *keyedProvider) GetSystemPrompt() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.systemPrompt
+}
+
+func (p *keyedProvider) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var errs []error
+	for _, prov := range p.providers {
+		errs = append(errs, prov.Close())
+	}
+	clear(p.providers)
+	p.last = nil
+	return errors.Join(errs...)
+}
+
+// maskKey 脱敏 Key，保留前后各 4 个字符
+func maskKey(key string) string {
+	if len(key) <= 8 {
+		return "****"
+	}
+	return key[:4] + "****" + key[len(key)-4:]
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
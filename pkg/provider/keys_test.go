@@ -0,0 +1,148 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/astercloud/aster/pkg/types"
+)
+
+// keyStubProvider 按 Key 返回预设错误的 Provider
+type keyStubProvider struct {
+	config *types.ModelConfig
+	errs   map[string]error
+	calls  *[]string
+}
+
+func (p *keyStubProvider) Stream(ctx context.Context, messages []types.Message, opts *StreamOptions) (<-chan StreamChunk, error) {
+	*p.calls = append(*p.calls, p.config.APIKey)
+	if err := p.errs[p.config.APIKey]; err != nil {
+		return nil, err
+	}
+	ch := make(chan StreamChunk, 2)
+	ch <- StreamChunk{Type: string(ChunkTypeText), TextDelta: "ok"}
+	ch <- StreamChunk{Type: string(ChunkTypeUsage), Usage: &TokenUsage{InputTokens: 3, OutputTokens: 4}}
+	close(ch)
+	return ch, nil
+}
+
+func (p *keyStubProvider) Complete(ctx context.Context, messages []types.Message, opts *StreamOptions) (*CompleteResponse, error) {
+	*p.calls = append(*p.calls, p.config.APIKey)
+	if err := p.errs[p.config.APIKey]; err != nil {
+		return nil, err
+	}
+	return &CompleteResponse{Usage: &TokenUsage{InputTokens: 10, OutputTokens: 2}}, nil
+}
+
+func (p *keyStubProvider) Config() *types.ModelConfig          { return p.config }
+func (p *keyStubProvider) Capabilities() ProviderCapabilities  { return ProviderCapabilities{} }
+func (p *keyStubProvider) SetSystemPrompt(prompt string) error { return nil }
+func (p *keyStubProvider) GetSystemPrompt() string             { return "" }
+func (p *keyStubProvider) Close() error                        { return nil }
+
+type keyStubFactory struct {
+	errs  map[string]error
+	calls []string
+}
+
+func (f *keyStubFactory) Create(config *types.ModelConfig) (Provider, error) {
+	return &keyStubProvider{config: config, errs: f.errs, calls: &f.calls}, nil
+}
+
+func TestKeyManagerRotatesOnAuthAndRateLimit(t *testing.T) {
+	factory := &keyStubFactory{errs: map[string]error{
+		"key-one-0001": newAPIError("openai", http.StatusUnauthorized, []byte("bad key")),
+		"key-two-0002": newAPIError("openai", http.StatusTooManyRequests, []byte("slow down")),
+	}}
+	keys := NewKeyManager(map[string][]string{
+		"openai": {"key-one-0001", "key-two-0002", "key-three-03"},
+	}, KeyManagerOptions{})
+
+	p, err := keys.WrapFactory(factory).Create(&types.ModelConfig{Provider: "openai", Model: "gpt-4o"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if _, err := p.Complete(context.Background(), nil, nil); err != nil {
+		t.Fatalf("Complete failed: %v", err)
+	}
+	want := []string{"key-one-0001", "key-two-0002", "key-three-03"}
+	if len(factory.calls) != len(want) {
+		t.Fatalf("expected calls %v, got %v", want, factory.calls)
+	}
+	for i := range want {
+		if factory.calls[i] != want[i] {
+			t.Fatalf("expected calls %v, got %v", want, factory.calls)
+		}
+	}
+
+	usage := keys.Usage()
+	if usage[0].Status != KeyStatusInvalid || usage[1].Status != KeyStatusCooling || usage[2].Status != KeyStatusActive {
+		t.Errorf("unexpected statuses: %+v", usage)
+	}
+	if !usage[2].Current || usage[2].InputTokens != 10 || usage[2].OutputTokens != 2 {
+		t.Errorf("expected third key to be current with usage recorded, got %+v", usage[2])
+	}
+	if usage[0].Key == "key-one-0001" {
+		t.Error("expected key to be masked in usage")
+	}
+}
+
+func TestKeyManagerStreamAccountsUsage(t *testing.T) {
+	factory := &keyStubFactory{}
+	keys := NewKeyManager(map[string][]string{"anthropic": {"sk-ant-0001"}}, KeyManagerOptions{})
+	p, err := keys.WrapFactory(factory).Create(&types.ModelConfig{Provider: "anthropic"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	ch, err := p.Stream(context.Background(), nil, nil)
+	if err != nil {
+		t.Fatalf("Stream failed: %v", err)
+	}
+	for range ch {
+	}
+	usage := keys.Usage()
+	if usage[0].Requests != 1 || usage[0].InputTokens != 3 || usage[0].OutputTokens != 4 {
+		t.Errorf("unexpected usage: %+v", usage[0])
+	}
+}
+
+func TestKeyManagerCooldownAndHealthCheck(t *testing.T) {
+	now := time.Now()
+	keys := NewKeyManager(map[string][]string{"openai": {"key-a-000001", "key-b-000002"}}, KeyManagerOptions{Cooldown: time.Minute})
+	keys.now = func() time.Time { return now }
+
+	keys.Report("openai", "key-a-000001", nil, newAPIError("openai", http.StatusTooManyRequests, nil))
+	keys.Report("openai", "key-b-000002", nil, newAPIError("openai", http.StatusUnauthorized, nil))
+	if _, err := keys.Acquire("openai"); !errors.Is(err, ErrNoAvailableKey) {
+		t.Fatalf("expected ErrNoAvailableKey, got %v", err)
+	}
+
+	// 冷却结束后自动恢复
+	now = now.Add(2 * time.Minute)
+	if key, err := keys.Acquire("openai"); err != nil || key != "key-a-000001" {
+		t.Fatalf("expected cooled down key to recover, got %q, %v", key, err)
+	}
+
+	// 健康检查通过的失效 Key 恢复
+	keys.CheckKeys(context.Background(), func(ctx context.Context, provider, key string) error { return nil })
+	for _, u := range keys.Usage() {
+		if u.Status != KeyStatusActive || u.LastChecked.IsZero() {
+			t.Errorf("expected key to be active after health check, got %+v", u)
+		}
+	}
+}
+
+func TestKeyManagerPassesThroughUnpooledProviders(t *testing.T) {
+	factory := &keyStubFactory{}
+	keys := NewKeyManager(map[string][]string{"openai": {"key-a-000001"}}, KeyManagerOptions{})
+	p, err := keys.WrapFactory(factory).Create(&types.ModelConfig{Provider: "deepseek", APIKey: "own-key"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if _, ok := p.(*keyStubProvider); !ok {
+		t.Errorf("expected provider without key pool to be created as is, got %T", p)
+	}
+}
@@ -0,0 +1,160 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/astercloud/aster/pkg/types"
+)
+
+// Request 一次模型调用，中间件可以在调用下一层之前修改它
+type Request struct {
+	// Config 调用所用 Provider 的配置，只读
+	Config *types.ModelConfig
+	// SystemPrompt 调用时 Provider 的系统提示词，只读
+	SystemPrompt string
+	Messages     []types.Message
+	Options      *StreamOptions
+	// Stream 是否为流式调用
+	Stream bool
+}
+
+// CompleteHandler 非流式调用的下一层处理器
+type CompleteHandler func(ctx context.Context, req *Request) (*CompleteResponse, error)
+
+// StreamHandler 流式调用的下一层处理器
+type StreamHandler func(ctx context.Context, req *Request) (<-chan StreamChunk, error)
+
+// Middleware Provider 中间件，包装每一次模型调用（洋葱模型）
+// 通过 NewMiddlewareFactory 挂到 Provider 工厂上，所有经由该工厂创建的 Provider 都生效，
+// 无需修改具体的 Provider 实现
+type Middleware interface {
+	// Name 中间件名称
+	Name() string
+
+	// WrapComplete 包装非流式调用，next 为下一层（下一个中间件或 Provider）
+	WrapComplete(ctx context.Context, req *Request, next CompleteHandler) (*CompleteResponse, error)
+
+	// WrapStream 包装流式调用，next 为下一层（下一个中间件或 Provider）
+	WrapStream(ctx context.Context, req *Request, next StreamHandler) (<-chan StreamChunk, error)
+}
+
+// BaseMiddleware 直接调用下一层的中间件，嵌入后只需覆盖关心的方法
+type BaseMiddleware struct{}
+
+func (BaseMiddleware) WrapComplete(ctx context.Context, req *Request, next CompleteHandler) (*CompleteResponse, error) {
+	return next(ctx, req)
+}
+
+func (BaseMiddleware) WrapStream(ctx context.Context, req *Request, next StreamHandler) (<-chan StreamChunk, error) {
+	return next(ctx, req)
+}
+
+// NewMiddlewareFactory 包装 Provider 工厂，创建的 Provider 的每次调用依次经过 middlewares
+// 第一个中间件位于最外层
+func NewMiddlewareFactory(factory Factory, middlewares ...Middleware) Factory {
+	if len(middlewares) == 0 {
+		return factory
+	}
+	return &middlewareFactory{factory: factory, middlewares: middlewares}
+}
+
+type middlewareFactory struct {
+	factory     Factory
+	middlewares []Middleware
+}
+
+func (f *middlewareFactory) Create(config *types.ModelConfig) (Provider, error) {
+	p, err := f.factory.Create(config)
+	if err != nil {
+		return nil, err
+	}
+	return WithMiddleware(p, f.middlewares...), nil
+}
+
+// WithMiddleware 用 middlewares 包装单个 Provider，第一个中间件位于最外层
+func WithMiddleware(p Provider, middlewares ...Middleware) Provider {
+	if len(middlewares) == 0 {
+		return p
+	}
+	return &middlewareProvider{Provider: p, middlewares: middlewares}
+}
+
+// middlewareProvider 让 Stream、Complete 依次经过中间件，其余方法直接转发
+type middlewareProvider struct {
+	Provider
+	middlewares []Middleware
+}
+
+func (p *middlewareProvider) request(messages []types.Message, opts *StreamOptions, stream bool) *Request {
+	return &Request{
+		Config:       p.Provider.Config(),
+		SystemPrompt: p.Provider.GetSystemPrompt(),
+		Messages:     messages,
+		Options:      opts,
+		Stream:       stream,
+	}
+}
+
+func (p *middlewareProvider) Complete(ctx context.Context, messages []types.Message, opts *StreamOptions) (*CompleteResponse, error) {
+	handler := CompleteHandler(func(ctx context.Context, req *Request) (*CompleteResponse, error) {
+		return p.Provider.Complete(ctx, req.Messages, req.Options)
+	})
+	for i := len(p.middlewares) - 1; i >= 0; i-- {
+		mw, next := p.middlewares[i], handler
+		handler = func(ctx context.Context, req *Request) (*CompleteResponse, error) {
+			return mw.WrapComplete(ctx, req, next)
+		}
+	}
+	return handler(ctx, p.request(messages, opts, false))
+}
+
+func (p *middlewareProvider) Stream(ctx context.Context, messages []types.Message, opts *StreamOptions) (<-chan StreamChunk, error) {
+	handler := StreamHandler(func(ctx context.Context, req *Request) (<-chan StreamChunk, error) {
+		return p.Provider.Stream(ctx, req.Messages, req.Options)
+	})
+	for i := len(p.middlewares) - 1; i >= 0; i-- {
+		mw, next := p.middlewares[i], handler
+		handler = func(ctx context.Context, req *Request) (<-chan StreamChunk, error) {
+			return mw.WrapStream(ctx, req, next)
+		}
+	}
+	return handler(ctx, p.request(messages, opts, true))
+}
+
+// StreamResult 流式调用结束时汇总的结果
+type StreamResult struct {
+	Usage        *TokenUsage
+	FinishReason string
+	Err          error
+	// FirstChunk 收到第一个响应块的时间，未收到时为零值
+	FirstChunk time.Time
+}
+
+// ObserveStream 转发 in 中的响应块，流结束后以汇总结果调用 done
+// 用于需要在流式调用结束时记录用量、耗时或错误的中间件
+func ObserveStream(in <-chan StreamChunk, done func(StreamResult)) <-chan StreamChunk {
+	out := make(chan StreamChunk)
+	go func() {
+		defer close(out)
+		var result StreamResult
+		for chunk := range in {
+			if result.FirstChunk.IsZero() {
+				result.FirstChunk = time.Now()
+			}
+			if chunk.Usage != nil {
+				result.Usage = chunk.Usage
+			}
+			if chunk.FinishReason != "" {
+				result.FinishReason = chunk.FinishReason
+			}
+			if chunk.Error != nil {
+				result.Err = errors.New(chunk.Error.Message)
+			}
+			out <- chunk
+		}
+		done(result)
+	}()
+	return out
+}
@@ -0,0 +1,394 @@
+package provider
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/astercloud/aster/pkg/httpclient"
+	"github.com/astercloud/aster/pkg/logging"
+	"github.com/astercloud/aster/pkg/requestctx"
+	"github.com/astercloud/aster/pkg/telemetry"
+	"github.com/astercloud/aster/pkg/telemetry/genai"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+var callLog = logging.ForComponent("ProviderCall")
+
+// LoggingMiddleware 记录每次模型调用的 Provider、模型、耗时、Token 用量和错误
+type LoggingMiddleware struct{}
+
+// NewLoggingMiddleware 创建日志中间件
+func NewLoggingMiddleware() *LoggingMiddleware {
+	return &LoggingMiddleware{}
+}
+
+func (m *LoggingMiddleware) Name() string { return "logging" }
+
+func (m *LoggingMiddleware) WrapComplete(ctx context.Context, req *Request, next CompleteHandler) (*CompleteResponse, error) {
+	start := time.Now()
+	resp, err := next(ctx, req)
+	var usage *TokenUsage
+	if resp != nil {
+		usage = resp.Usage
+	}
+	logCall(ctx, req, start, usage, err)
+	return resp, err
+}
+
+func (m *LoggingMiddleware) WrapStream(ctx context.Context, req *Request, next StreamHandler) (<-chan StreamChunk, error) {
+	start := time.Now()
+	ch, err := next(ctx, req)
+	if err != nil {
+		logCall(ctx, req, start, nil, err)
+		return nil, err
+	}
+	return ObserveStream(ch, func(r StreamResult) {
+		logCall(ctx, req, start, r.Usage, r.Err)
+	}), nil
+}
+
+func logCall(ctx context.Context, req *Request, start time.Time, usage *TokenUsage, err error) {
+	fields := map[string]any{
+		"stream":     req.Stream,
+		"messages":   len(req.Messages),
+		"latency_ms": time.Since(start).Milliseconds(),
+	}
+	if req.Config != nil {
+		fields["provider"] = req.Config.Provider
+		fields["model"] = req.Config.Model
+	}
+	if usage != nil {
+		fields["input_tokens"] = usage.InputTokens
+		fields["output_tokens"] = usage.OutputTokens
+	}
+	if err != nil {
+		fields["error"] = err.Error()
+		callLog.Warn(ctx, "model call failed", fields)
+		return
+	}
+	callLog.Info(ctx, "model call", fields)
+}
+
+// Redactor 文本脱敏器，security.PIIRedactor 等实现了该接口
+type Redactor interface {
+	Redact(text string) string
+}
+
+// RedactionMiddleware 在发送给模型之前对消息文本和工具结果脱敏
+// 只修改发送的副本，不影响调用方持有的消息
+type RedactionMiddleware struct {
+	BaseMiddleware
+	redactor Redactor
+}
+
+// NewRedactionMiddleware 创建脱敏中间件
+func NewRedactionMiddleware(redactor Redactor) *RedactionMiddleware {
+	return &RedactionMiddleware{redactor: redactor}
+}
+
+func (m *RedactionMiddleware) Name() string { return "redaction" }
+
+func (m *RedactionMiddleware) WrapComplete(ctx context.Context, req *Request, next CompleteHandler) (*CompleteResponse, error) {
+	req.Messages = m.redact(req.Messages)
+	return next(ctx, req)
+}
+
+func (m *RedactionMiddleware) WrapStream(ctx context.Context, req *Request, next StreamHandler) (<-chan StreamChunk, error) {
+	req.Messages = m.redact(req.Messages)
+	return next(ctx, req)
+}
+
+func (m *RedactionMiddleware) redact(messages []types.Message) []types.Message {
+	out := make([]types.Message, len(messages))
+	for i, msg := range messages {
+		msg.Content = m.redactor.Redact(msg.Content)
+		if len(msg.ContentBlocks) > 0 {
+			blocks := make([]types.ContentBlock, len(msg.ContentBlocks))
+			for j, block := range msg.ContentBlocks {
+				switch b := block.(type) {
+				case *types.TextBlock:
+					redacted := *b
+					redacted.Text = m.redactor.Redact(b.Text)
+					block = &redacted
+				case *types.ToolResultBlock:
+					redacted := *b
+					redacted.Content = m.redactor.Redact(b.Content)
+					block = &redacted
+				}
+				blocks[j] = block
+			}
+			msg.ContentBlocks = blocks
+		}
+		out[i] = msg
+	}
+	return out
+}
+
+// HeaderMiddleware 为每次模型调用的 HTTP 请求附加固定请求头，如网关鉴权、租户标识
+// 请求头通过 httpclient.WithHeaders 传递，对使用共享 HTTP 客户端的 Provider 生效
+type HeaderMiddleware struct {
+	headers map[string]string
+}
+
+// NewHeaderMiddleware 创建请求头注入中间件
+func NewHeaderMiddleware(headers map[string]string) *HeaderMiddleware {
+	return &HeaderMiddleware{headers: headers}
+}
+
+func (m *HeaderMiddleware) Name() string { return "headers" }
+
+func (m *HeaderMiddleware) WrapComplete(ctx context.Context, req *Request, next CompleteHandler) (*CompleteResponse, error) {
+	return next(httpclient.WithHeaders(ctx, m.headers), req)
+}
+
+func (m *HeaderMiddleware) WrapStream(ctx context.Context, req *Request, next StreamHandler) (<-chan StreamChunk, error) {
+	return next(httpclient.WithHeaders(ctx, m.headers), req)
+}
+
+// RequestContextMiddleware 将请求元数据（见 requestctx）作为请求头转发给模型服务，
+// 便于在 LLM 网关侧按请求归因；默认只转发关联 ID
+type RequestContextMiddleware struct {
+	BaseMiddleware
+	includeIdentity bool
+}
+
+// NewRequestContextMiddleware 创建请求元数据转发中间件
+// includeIdentity 为 true 时同时转发用户和租户，仅建议用于自建网关
+func NewRequestContextMiddleware(includeIdentity bool) *RequestContextMiddleware {
+	return &RequestContextMiddleware{includeIdentity: includeIdentity}
+}
+
+func (m *RequestContextMiddleware) Name() string { return "request_context" }
+
+func (m *RequestContextMiddleware) WrapComplete(ctx context.Context, req *Request, next CompleteHandler) (*CompleteResponse, error) {
+	return next(m.withHeaders(ctx), req)
+}
+
+func (m *RequestContextMiddleware) WrapStream(ctx context.Context, req *Request, next StreamHandler) (<-chan StreamChunk, error) {
+	return next(m.withHeaders(ctx), req)
+}
+
+func (m *RequestContextMiddleware) withHeaders(ctx context.Context) context.Context {
+	rm, ok := requestctx.From(ctx)
+	if !ok {
+		return ctx
+	}
+	return httpclient.WithHeaders(ctx, rm.Headers(m.includeIdentity))
+}
+
+// TracingMiddleware 为每次模型调用创建 span，属性遵循 OpenTelemetry GenAI 语义约定
+type TracingMiddleware struct {
+	tracer telemetry.Tracer
+}
+
+// NewTracingMiddleware 创建追踪中间件
+func NewTracingMiddleware(tracer telemetry.Tracer) *TracingMiddleware {
+	return &TracingMiddleware{tracer: tracer}
+}
+
+func (m *TracingMiddleware) Name() string { return "tracing" }
+
+func (m *TracingMiddleware) start(ctx context.Context, req *Request) (context.Context, telemetry.Span) {
+	var providerName, model string
+	if req.Config != nil {
+		providerName, model = req.Config.Provider, req.Config.Model
+	}
+	attrs := []telemetry.Attribute{
+		telemetry.String(genai.AttrOperationName, genai.OpChat),
+		telemetry.String(genai.AttrProviderName, providerName),
+		telemetry.String(genai.AttrRequestModel, model),
+	}
+	if req.Options != nil && req.Options.MaxTokens > 0 {
+		attrs = append(attrs, telemetry.Int(genai.AttrRequestMaxTokens, req.Options.MaxTokens))
+	}
+	if rm, ok := requestctx.From(ctx); ok {
+		for _, attr := range []struct{ key, value string }{
+			{"enduser.id", rm.UserID}, {"aster.tenant_id", rm.TenantID}, {"aster.correlation_id", rm.CorrelationID},
+		} {
+			if attr.value != "" {
+				attrs = append(attrs, telemetry.String(attr.key, attr.value))
+			}
+		}
+	}
+	return m.tracer.StartSpan(ctx, genai.ChatSpanName(model),
+		telemetry.WithSpanKind(telemetry.SpanKindClient),
+		telemetry.WithAttributes(attrs...))
+}
+
+func (m *TracingMiddleware) finish(span telemetry.Span, start time.Time, usage *TokenUsage, err error) {
+	span.SetAttributes(telemetry.Int64(genai.AttrLatencyTotal, time.Since(start).Milliseconds()))
+	if usage != nil {
+		span.SetAttributes(
+			telemetry.Int64(genai.AttrUsageInputTokens, usage.InputTokens),
+			telemetry.Int64(genai.AttrUsageOutputTokens, usage.OutputTokens),
+		)
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(telemetry.StatusCodeError, err.Error())
+	} else {
+		span.SetStatus(telemetry.StatusCodeOK, "")
+	}
+	span.End()
+}
+
+func (m *TracingMiddleware) WrapComplete(ctx context.Context, req *Request, next CompleteHandler) (*CompleteResponse, error) {
+	start := time.Now()
+	ctx, span := m.start(ctx, req)
+	resp, err := next(ctx, req)
+	var usage *TokenUsage
+	if resp != nil {
+		usage = resp.Usage
+	}
+	m.finish(span, start, usage, err)
+	return resp, err
+}
+
+func (m *TracingMiddleware) WrapStream(ctx context.Context, req *Request, next StreamHandler) (<-chan StreamChunk, error) {
+	start := time.Now()
+	ctx, span := m.start(ctx, req)
+	ch, err := next(ctx, req)
+	if err != nil {
+		m.finish(span, start, nil, err)
+		return nil, err
+	}
+	return ObserveStream(ch, func(r StreamResult) {
+		if !r.FirstChunk.IsZero() {
+			span.SetAttributes(telemetry.Int64(genai.AttrLatencyTTFT, r.FirstChunk.Sub(start).Milliseconds()))
+		}
+		m.finish(span, start, r.Usage, r.Err)
+	}), nil
+}
+
+// CacheOptions 响应缓存配置
+type CacheOptions struct {
+	// TTL 缓存有效期，默认 10 分钟
+	TTL time.Duration
+	// MaxEntries 最多缓存的响应数，超出时淘汰最久未使用的，默认 1000
+	MaxEntries int
+}
+
+// CacheMiddleware 缓存非流式调用的响应，Provider、模型、系统提示词、消息和选项完全相同时直接返回
+// 适合评测、批处理等重复请求较多的场景；流式调用不缓存
+type CacheMiddleware struct {
+	BaseMiddleware
+	opts CacheOptions
+	now  func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List // 最近使用的在前
+	hits    int64
+	misses  int64
+}
+
+type cacheEntry struct {
+	key     string
+	resp    *CompleteResponse
+	expires time.Time
+}
+
+// NewCacheMiddleware 创建响应缓存中间件
+func NewCacheMiddleware(opts CacheOptions) *CacheMiddleware {
+	if opts.TTL <= 0 {
+		opts.TTL = 10 * time.Minute
+	}
+	if opts.MaxEntries <= 0 {
+		opts.MaxEntries = 1000
+	}
+	return &CacheMiddleware{
+		opts:    opts,
+		now:     time.Now,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+	}
+}
+
+func (m *CacheMiddleware) Name() string { return "cache" }
+
+// Stats 返回缓存命中与未命中次数
+func (m *CacheMiddleware) Stats() (hits, misses int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.hits, m.misses
+}
+
+func (m *CacheMiddleware) WrapComplete(ctx context.Context, req *Request, next CompleteHandler) (*CompleteResponse, error) {
+	key, ok := cacheKey(req)
+	if !ok {
+		return next(ctx, req)
+	}
+	if resp, ok := m.get(key); ok {
+		return resp, nil
+	}
+	resp, err := next(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	m.put(key, resp)
+	return resp, nil
+}
+
+func (m *CacheMiddleware) get(key string) (*CompleteResponse, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	el, ok := m.entries[key]
+	if !ok {
+		m.misses++
+		return nil, false
+	}
+	entry := el.Value.(*cacheEntry)
+	if m.now().After(entry.expires) {
+		m.order.Remove(el)
+		delete(m.entries, key)
+		m.misses++
+		return nil, false
+	}
+	m.order.MoveToFront(el)
+	m.hits++
+	resp := *entry.resp
+	return &resp, true
+}
+
+func (m *CacheMiddleware) put(key string, resp *CompleteResponse) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entry := &cacheEntry{key: key, resp: resp, expires: m.now().Add(m.opts.TTL)}
+	if el, ok := m.entries[key]; ok {
+		el.Value = entry
+		m.order.MoveToFront(el)
+		return
+	}
+	m.entries[key] = m.order.PushFront(entry)
+	for m.order.Len() > m.opts.MaxEntries {
+		oldest := m.order.Back()
+		m.order.Remove(oldest)
+		delete(m.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+// cacheKey 由请求内容计算缓存键（不含 API Key），无法序列化时不缓存
+func cacheKey(req *Request) (string, bool) {
+	var providerName, model, baseURL string
+	if req.Config != nil {
+		providerName, model, baseURL = req.Config.Provider, req.Config.Model, req.Config.BaseURL
+	}
+	data, err := json.Marshal(struct {
+		Provider string          `json:"provider"`
+		Model    string          `json:"model"`
+		BaseURL  string          `json:"base_url"`
+		System   string          `json:"system"`
+		Messages []types.Message `json:"messages"`
+		Options  *StreamOptions  `json:"options"`
+	}{providerName, model, baseURL, req.SystemPrompt, req.Messages, req.Options})
+	if err != nil {
+		return "", false
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), true
+}
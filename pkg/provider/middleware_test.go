@@ -0,0 +1,157 @@
+package provider
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/astercloud/aster/pkg/httpclient"
+	"github.com/astercloud/aster/pkg/requestctx"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+// recordingProvider 记录收到的消息和 ctx 中的请求头
+type recordingProvider struct {
+	keyStubProvider
+	messages []types.Message
+	headers  map[string]string
+	calls    int
+}
+
+func (p *recordingProvider) Complete(ctx context.Context, messages []types.Message, opts *StreamOptions) (*CompleteResponse, error) {
+	p.calls++
+	p.messages = messages
+	p.headers = httpclient.HeadersFromContext(ctx)
+	return &CompleteResponse{Message: types.Message{Role: types.RoleAssistant, Content: "ok"}}, nil
+}
+
+// orderMiddleware 记录进入和退出的顺序
+type orderMiddleware struct {
+	BaseMiddleware
+	name string
+	log  *[]string
+}
+
+func (m orderMiddleware) Name() string { return m.name }
+
+func (m orderMiddleware) WrapComplete(ctx context.Context, req *Request, next CompleteHandler) (*CompleteResponse, error) {
+	*m.log = append(*m.log, "enter "+m.name)
+	resp, err := next(ctx, req)
+	*m.log = append(*m.log, "exit "+m.name)
+	return resp, err
+}
+
+type wordRedactor struct{}
+
+func (wordRedactor) Redact(text string) string {
+	return strings.ReplaceAll(text, "secret", "[REDACTED]")
+}
+
+func TestMiddlewareOrder(t *testing.T) {
+	var log []string
+	base := &recordingProvider{keyStubProvider: keyStubProvider{config: &types.ModelConfig{Provider: "openai"}}}
+	p := WithMiddleware(base, orderMiddleware{name: "outer", log: &log}, orderMiddleware{name: "inner", log: &log})
+
+	if _, err := p.Complete(context.Background(), nil, nil); err != nil {
+		t.Fatalf("Complete failed: %v", err)
+	}
+	want := "enter outer,enter inner,exit inner,exit outer"
+	if got := strings.Join(log, ","); got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestRedactionMiddlewareCopiesMessages(t *testing.T) {
+	base := &recordingProvider{keyStubProvider: keyStubProvider{config: &types.ModelConfig{Provider: "openai"}}}
+	p := WithMiddleware(base, NewRedactionMiddleware(wordRedactor{}))
+
+	messages := []types.Message{
+		{Role: types.RoleUser, Content: "my secret"},
+		{Role: types.RoleUser, ContentBlocks: []types.ContentBlock{
+			&types.TextBlock{Text: "another secret"},
+			&types.ToolResultBlock{ToolUseID: "t1", Content: "secret output"},
+		}},
+	}
+	if _, err := p.Complete(context.Background(), messages, nil); err != nil {
+		t.Fatalf("Complete failed: %v", err)
+	}
+	if base.messages[0].Content != "my [REDACTED]" {
+		t.Errorf("expected content redacted, got %q", base.messages[0].Content)
+	}
+	if text := base.messages[1].ContentBlocks[0].(*types.TextBlock).Text; text != "another [REDACTED]" {
+		t.Errorf("expected text block redacted, got %q", text)
+	}
+	if out := base.messages[1].ContentBlocks[1].(*types.ToolResultBlock).Content; out != "[REDACTED] output" {
+		t.Errorf("expected tool result redacted, got %q", out)
+	}
+	if messages[0].Content != "my secret" || messages[1].ContentBlocks[0].(*types.TextBlock).Text != "another secret" {
+		t.Error("expected caller messages to stay unchanged")
+	}
+}
+
+func TestCacheMiddleware(t *testing.T) {
+	base := &recordingProvider{keyStubProvider: keyStubProvider{config: &types.ModelConfig{Provider: "openai", Model: "gpt-4o"}}}
+	cache := NewCacheMiddleware(CacheOptions{MaxEntries: 1})
+	p := WithMiddleware(base, cache)
+
+	ask := func(text string) {
+		t.Helper()
+		if _, err := p.Complete(context.Background(), []types.Message{{Role: types.RoleUser, Content: text}}, nil); err != nil {
+			t.Fatalf("Complete failed: %v", err)
+		}
+	}
+	ask("hello")
+	ask("hello")
+	if base.calls != 1 {
+		t.Errorf("expected identical request to be served from cache, got %d calls", base.calls)
+	}
+	ask("bye")   // 淘汰 hello
+	ask("hello") // 重新请求
+	if base.calls != 3 {
+		t.Errorf("expected evicted entry to be requested again, got %d calls", base.calls)
+	}
+	if hits, misses := cache.Stats(); hits != 1 || misses != 3 {
+		t.Errorf("expected 1 hit and 3 misses, got %d/%d", hits, misses)
+	}
+}
+
+func TestHeaderMiddlewareAndFactory(t *testing.T) {
+	base := &recordingProvider{keyStubProvider: keyStubProvider{config: &types.ModelConfig{Provider: "openai"}}}
+	factory := NewMiddlewareFactory(staticFactory{base}, NewHeaderMiddleware(map[string]string{"X-Tenant": "acme"}))
+
+	p, err := factory.Create(&types.ModelConfig{Provider: "openai"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if _, err := p.Complete(context.Background(), nil, nil); err != nil {
+		t.Fatalf("Complete failed: %v", err)
+	}
+	if base.headers["X-Tenant"] != "acme" {
+		t.Errorf("expected injected header, got %v", base.headers)
+	}
+}
+
+type staticFactory struct{ p Provider }
+
+func (f staticFactory) Create(*types.ModelConfig) (Provider, error) { return f.p, nil }
+
+func TestRequestContextMiddleware(t *testing.T) {
+	base := &recordingProvider{keyStubProvider: keyStubProvider{config: &types.ModelConfig{Provider: "openai"}}}
+	ctx := requestctx.With(context.Background(), requestctx.Metadata{UserID: "u1", CorrelationID: "corr-1"})
+
+	p := WithMiddleware(base, NewRequestContextMiddleware(false))
+	if _, err := p.Complete(ctx, nil, nil); err != nil {
+		t.Fatalf("Complete failed: %v", err)
+	}
+	if base.headers[requestctx.HeaderCorrelationID] != "corr-1" || base.headers[requestctx.HeaderUserID] != "" {
+		t.Errorf("expected only correlation header, got %v", base.headers)
+	}
+
+	p = WithMiddleware(base, NewRequestContextMiddleware(true))
+	if _, err := p.Complete(ctx, nil, nil); err != nil {
+		t.Fatalf("Complete failed: %v", err)
+	}
+	if base.headers[requestctx.HeaderUserID] != "u1" {
+		t.Errorf("expected user header, got %v", base.headers)
+	}
+}
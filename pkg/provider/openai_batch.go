@@ -0,0 +1,285 @@
+package provider
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+var _ BatchProvider = (*OpenAIProvider)(nil)
+
+// openaiBatchEndpoint 批处理请求调用的接口
+const openaiBatchEndpoint = "/v1/chat/completions"
+
+// openaiBatch Batch API 的批次对象
+type openaiBatch struct {
+	ID            string `json:"id"`
+	Status        string `json:"status"` // validating, in_progress, finalizing, completed, failed, expired, cancelling, cancelled
+	OutputFileID  string `json:"output_file_id"`
+	ErrorFileID   string `json:"error_file_id"`
+	CreatedAt     int64  `json:"created_at"`
+	CompletedAt   int64  `json:"completed_at"`
+	FailedAt      int64  `json:"failed_at"`
+	ExpiredAt     int64  `json:"expired_at"`
+	CancelledAt   int64  `json:"cancelled_at"`
+	RequestCounts struct {
+		Total     int `json:"total"`
+		Completed int `json:"completed"`
+		Failed    int `json:"failed"`
+	} `json:"request_counts"`
+}
+
+func (b *openaiBatch) toBatch() *Batch {
+	batch := &Batch{
+		ID:        b.ID,
+		Provider:  "openai",
+		CreatedAt: unixTime(b.CreatedAt),
+		Counts: BatchCounts{
+			Total:     b.RequestCounts.Total,
+			Succeeded: b.RequestCounts.Completed,
+			Failed:    b.RequestCounts.Failed,
+		},
+	}
+	switch b.Status {
+	case "completed":
+		batch.Status, batch.EndedAt = BatchStatusCompleted, unixTime(b.CompletedAt)
+	case "failed":
+		batch.Status, batch.EndedAt = BatchStatusFailed, unixTime(b.FailedAt)
+	case "expired":
+		batch.Status, batch.EndedAt = BatchStatusExpired, unixTime(b.ExpiredAt)
+	case "cancelled":
+		batch.Status, batch.EndedAt = BatchStatusCanceled, unixTime(b.CancelledAt)
+	default:
+		batch.Status = BatchStatusInProgress
+	}
+	return batch
+}
+
+func unixTime(sec int64) time.Time {
+	if sec == 0 {
+		return time.Time{}
+	}
+	return time.Unix(sec, 0).UTC()
+}
+
+// CreateBatch 上传 JSONL 请求文件并创建批次
+func (p *OpenAIProvider) CreateBatch(ctx context.Context, requests []BatchRequest) (*Batch, error) {
+	if len(requests) == 0 {
+		return nil, errors.New("batch requires at least one request")
+	}
+	var input bytes.Buffer
+	for _, r := range requests {
+		line, err := json.Marshal(map[string]any{
+			"custom_id": r.CustomID,
+			"method":    http.MethodPost,
+			"url":       openaiBatchEndpoint,
+			"body":      p.buildRequest(r.Messages, r.Options, false),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("marshal batch request: %w", err)
+		}
+		input.Write(line)
+		input.WriteByte('\n')
+	}
+
+	fileID, err := p.uploadBatchFile(ctx, input.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	body, _ := json.Marshal(map[string]any{
+		"input_file_id":     fileID,
+		"endpoint":          openaiBatchEndpoint,
+		"completion_window": "24h",
+	})
+	var b openaiBatch
+	if err := p.batchCall(ctx, http.MethodPost, "/batches", "application/json", body, &b); err != nil {
+		return nil, err
+	}
+	return b.toBatch(), nil
+}
+
+// GetBatch 查询批次状态
+func (p *OpenAIProvider) GetBatch(ctx context.Context, id string) (*Batch, error) {
+	b, err := p.getBatch(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return b.toBatch(), nil
+}
+
+// CancelBatch 取消批次
+func (p *OpenAIProvider) CancelBatch(ctx context.Context, id string) (*Batch, error) {
+	var b openaiBatch
+	if err := p.batchCall(ctx, http.MethodPost, "/batches/"+url.PathEscape(id)+"/cancel", "", nil, &b); err != nil {
+		return nil, err
+	}
+	return b.toBatch(), nil
+}
+
+// BatchResults 下载输出文件和错误文件中的结果
+func (p *OpenAIProvider) BatchResults(ctx context.Context, id string) ([]BatchResult, error) {
+	b, err := p.getBatch(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !b.toBatch().Status.Done() {
+		return nil, fmt.Errorf("batch %s has not ended", id)
+	}
+
+	var results []BatchResult
+	for _, fileID := range []string{b.OutputFileID, b.ErrorFileID} {
+		if fileID == "" {
+			continue
+		}
+		data, err := p.batchDownload(ctx, "/files/"+url.PathEscape(fileID)+"/content")
+		if err != nil {
+			return nil, err
+		}
+		parsed, err := p.parseBatchOutput(data)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, parsed...)
+	}
+	return results, nil
+}
+
+// parseBatchOutput 解析输出文件的 JSONL 行
+func (p *OpenAIProvider) parseBatchOutput(data []byte) ([]BatchResult, error) {
+	var results []BatchResult
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var item struct {
+			CustomID string `json:"custom_id"`
+			Response *struct {
+				StatusCode int            `json:"status_code"`
+				Body       map[string]any `json:"body"`
+			} `json:"response"`
+			Error *struct {
+				Code    string `json:"code"`
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if err := json.Unmarshal(line, &item); err != nil {
+			return nil, fmt.Errorf("decode batch result: %w", err)
+		}
+		result := BatchResult{CustomID: item.CustomID}
+		switch {
+		case item.Error != nil:
+			result.Error = fmt.Sprintf("%s: %s", item.Error.Code, item.Error.Message)
+		case item.Response == nil:
+			result.Error = "missing response"
+		case item.Response.StatusCode != http.StatusOK:
+			body, _ := json.Marshal(item.Response.Body)
+			result.Error = newAPIError(p.providerName, item.Response.StatusCode, body).Error()
+		default:
+			message, err := p.parseCompleteResponse(item.Response.Body)
+			if err != nil {
+				result.Error = err.Error()
+				break
+			}
+			result.Response = &CompleteResponse{Message: message, Usage: p.parseUsage(item.Response.Body)}
+		}
+		results = append(results, result)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read batch results: %w", err)
+	}
+	return results, nil
+}
+
+// uploadBatchFile 以 purpose=batch 上传请求文件，返回文件 ID
+func (p *OpenAIProvider) uploadBatchFile(ctx context.Context, data []byte) (string, error) {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	_ = form.WriteField("purpose", "batch")
+	part, err := form.CreateFormFile("file", "batch.jsonl")
+	if err != nil {
+		return "", err
+	}
+	if _, err := part.Write(data); err != nil {
+		return "", err
+	}
+	if err := form.Close(); err != nil {
+		return "", err
+	}
+
+	var file struct {
+		ID string `json:"id"`
+	}
+	if err := p.batchCall(ctx, http.MethodPost, "/files", form.FormDataContentType(), body.Bytes(), &file); err != nil {
+		return "", fmt.Errorf("upload batch file: %w", err)
+	}
+	return file.ID, nil
+}
+
+func (p *OpenAIProvider) getBatch(ctx context.Context, id string) (*openaiBatch, error) {
+	var b openaiBatch
+	if err := p.batchCall(ctx, http.MethodGet, "/batches/"+url.PathEscape(id), "", nil, &b); err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+// batchCall 调用 Batch/Files API 并解码 JSON 响应
+func (p *OpenAIProvider) batchCall(ctx context.Context, method, path, contentType string, body []byte, dest any) error {
+	data, err := p.batchDo(ctx, method, path, contentType, body)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, dest); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	return nil
+}
+
+func (p *OpenAIProvider) batchDownload(ctx context.Context, path string) ([]byte, error) {
+	return p.batchDo(ctx, http.MethodGet, path, "", nil)
+}
+
+func (p *OpenAIProvider) batchDo(ctx context.Context, method, path, contentType string, body []byte) ([]byte, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, p.baseURL+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if p.config.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.config.APIKey)
+	}
+	for key, value := range p.options.CustomHeaders {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("send request: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError(p.providerName, resp.StatusCode, data)
+	}
+	return data, nil
+}
@@ -12,6 +12,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/astercloud/aster/pkg/httpclient"
 	"github.com/astercloud/aster/pkg/logging"
 	"github.com/astercloud/aster/pkg/types"
 	"github.com/astercloud/aster/pkg/util"
@@ -121,9 +122,7 @@ func NewOpenAICompatibleProvider(
 	}
 
 	// 创建 HTTP 客户端
-	httpClient := &http.Client{
-		Timeout: options.Timeout,
-	}
+	httpClient := httpclient.New(options.Timeout)
 
 	// 设置默认模型
 	if config.Model == "" && options.DefaultModel != "" {
@@ -206,7 +205,7 @@ func (p *OpenAICompatibleProvider) Stream(
 			}
 		}
 
-		return nil, fmt.Errorf("%s API error: %d - %s", p.providerName, resp.StatusCode, string(body))
+		return nil, newAPIError(p.providerName, resp.StatusCode, body)
 	}
 
 	// 创建流式响应 channel
@@ -243,7 +242,7 @@ func (p *OpenAICompatibleProvider) Complete(
 	// 检查响应状态
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("%s API error: %d - %s", p.providerName, resp.StatusCode, string(body))
+		return nil, newAPIError(p.providerName, resp.StatusCode, body)
 	}
 
 	// 解析响应
@@ -298,6 +297,11 @@ func (p *OpenAICompatibleProvider) buildRequest(
 		requestBody["messages"] = msgs
 	}
 
+	// 推理强度（OpenAI o 系列 / gpt-5 等推理模型）
+	if effort := resolveReasoningEffort(p.config, opts); effort != "" {
+		requestBody["reasoning_effort"] = effort
+	}
+
 	// 添加可选参数
 	if opts != nil {
 		if opts.MaxTokens > 0 {
@@ -347,6 +351,9 @@ func (p *OpenAICompatibleProvider) convertMessages(messages []types.Message) []m
 				toolUseBlocks = append(toolUseBlocks, b)
 			case *types.ToolResultBlock:
 				toolResultBlocks = append(toolResultBlocks, b)
+			case *types.ThinkingBlock:
+				// 推理内容不回传给 OpenAI 兼容接口
+				continue
 			default:
 				otherBlocks = append(otherBlocks, block)
 			}
@@ -493,7 +500,7 @@ func (p *OpenAICompatibleProvider) convertTools(tools []ToolSchema) []map[string
 			"function": map[string]any{
 				"name":        tool.Name,
 				"description": tool.Description,
-				"parameters":  tool.InputSchema,
+				"parameters":  toolParameters(tool, ToolFormatOpenAI),
 			},
 			// TODO: OpenAI API 暂不支持 input_examples，待官方支持后启用
 			// 参考: https://platform.openai.com/docs/api-reference/chat/create
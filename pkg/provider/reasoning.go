@@ -0,0 +1,60 @@
+package provider
+
+import "github.com/astercloud/aster/pkg/types"
+
+// defaultThinkingBudget 未指定预算时的默认思考 token 数
+const defaultThinkingBudget = 10000
+
+// resolveThinking 返回本次请求的 extended thinking 配置
+// StreamOptions 显式指定时优先，否则使用 ModelConfig.Reasoning
+func resolveThinking(cfg *types.ModelConfig, opts *StreamOptions) *ThinkingConfig {
+	if opts != nil && opts.Thinking != nil {
+		if !opts.Thinking.Enabled {
+			return nil
+		}
+		return opts.Thinking
+	}
+	if cfg == nil || cfg.Reasoning == nil || !cfg.Reasoning.Enabled {
+		return nil
+	}
+	return &ThinkingConfig{Enabled: true, BudgetTokens: cfg.Reasoning.BudgetTokens}
+}
+
+// resolveReasoningEffort 返回本次请求的 OpenAI reasoning_effort
+func resolveReasoningEffort(cfg *types.ModelConfig, opts *StreamOptions) string {
+	if opts != nil && opts.ReasoningEffort != "" {
+		return opts.ReasoningEffort
+	}
+	if cfg == nil || cfg.Reasoning == nil {
+		return ""
+	}
+	return cfg.Reasoning.Effort
+}
+
+// thinkingRequest 构建 Anthropic thinking 请求参数，并保证 max_tokens 大于思考预算
+func thinkingRequest(thinking *ThinkingConfig, maxTokens int) (map[string]any, int) {
+	budget := thinking.BudgetTokens
+	if budget <= 0 {
+		budget = defaultThinkingBudget
+	}
+	if maxTokens <= budget {
+		maxTokens = budget + 4096
+	}
+	return map[string]any{
+		"type":          "enabled",
+		"budget_tokens": budget,
+	}, maxTokens
+}
+
+// thinkingBlockParam 将思考块转换为 Anthropic 消息格式
+// 没有签名或已被清除内容的思考块无法通过服务端校验，返回 nil 表示跳过
+func thinkingBlockParam(b *types.ThinkingBlock) map[string]any {
+	if b.Redacted || b.Signature == "" {
+		return nil
+	}
+	return map[string]any{
+		"type":      "thinking",
+		"thinking":  b.Thinking,
+		"signature": b.Signature,
+	}
+}
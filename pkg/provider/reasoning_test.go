@@ -0,0 +1,99 @@
+package provider
+
+import (
+	"testing"
+
+	"github.com/astercloud/aster/pkg/types"
+)
+
+func TestAnthropicThinkingFromModelConfig(t *testing.T) {
+	ap, err := NewAnthropicProvider(&types.ModelConfig{
+		Provider:  "anthropic",
+		Model:     "claude-sonnet-4-5",
+		APIKey:    "test-key",
+		Reasoning: &types.ReasoningConfig{Enabled: true, BudgetTokens: 8000},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+
+	req := ap.buildRequest(nil, &StreamOptions{MaxTokens: 4096, Temperature: 0.7})
+	thinking, ok := req["thinking"].(map[string]any)
+	if !ok {
+		t.Fatalf("expected thinking param, got %v", req["thinking"])
+	}
+	if thinking["budget_tokens"] != 8000 {
+		t.Errorf("expected budget 8000, got %v", thinking["budget_tokens"])
+	}
+	if _, ok := req["temperature"]; ok {
+		t.Error("temperature must not be set when thinking is enabled")
+	}
+	if maxTokens := req["max_tokens"].(int); maxTokens <= 8000 {
+		t.Errorf("max_tokens must exceed thinking budget, got %d", maxTokens)
+	}
+
+	// 显式关闭时覆盖模型配置
+	req = ap.buildRequest(nil, &StreamOptions{MaxTokens: 4096, Thinking: &ThinkingConfig{Enabled: false}})
+	if _, ok := req["thinking"]; ok {
+		t.Error("expected thinking disabled by stream options")
+	}
+}
+
+func TestAnthropicThinkingBlockConversion(t *testing.T) {
+	ap, err := NewAnthropicProvider(&types.ModelConfig{Provider: "anthropic", Model: "claude-sonnet-4-5", APIKey: "test-key"})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+
+	msgs := ap.convertMessages([]types.Message{{
+		Role: types.MessageRoleAssistant,
+		ContentBlocks: []types.ContentBlock{
+			&types.ThinkingBlock{Thinking: "signed", Signature: "sig"},
+			&types.ThinkingBlock{Thinking: "unsigned"},
+			&types.ThinkingBlock{Signature: "sig", Redacted: true},
+			&types.TextBlock{Text: "answer"},
+		},
+	}})
+
+	blocks := msgs[0]["content"].([]any)
+	if len(blocks) != 2 {
+		t.Fatalf("expected signed thinking and text blocks, got %d: %v", len(blocks), blocks)
+	}
+	first := blocks[0].(map[string]any)
+	if first["type"] != "thinking" || first["signature"] != "sig" {
+		t.Errorf("unexpected thinking block %v", first)
+	}
+}
+
+func TestOpenAIReasoningEffort(t *testing.T) {
+	p, err := NewOpenAIProvider(&types.ModelConfig{
+		Provider:  "openai",
+		Model:     "o3-mini",
+		APIKey:    "test-key",
+		Reasoning: &types.ReasoningConfig{Effort: "high"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+	op := p.(*OpenAIProvider)
+
+	req := op.buildRequest([]types.Message{{
+		Role: types.MessageRoleAssistant,
+		ContentBlocks: []types.ContentBlock{
+			&types.ThinkingBlock{Thinking: "hidden"},
+			&types.TextBlock{Text: "answer"},
+		},
+	}}, nil, false)
+	if req["reasoning_effort"] != "high" {
+		t.Errorf("expected reasoning_effort high, got %v", req["reasoning_effort"])
+	}
+	msgs := req["messages"].([]map[string]any)
+	if msgs[0]["content"] != "answer" {
+		t.Errorf("thinking block should not be sent back, got %v", msgs[0]["content"])
+	}
+
+	req = op.buildRequest(nil, &StreamOptions{ReasoningEffort: "low"}, false)
+	if req["reasoning_effort"] != "low" {
+		t.Errorf("expected stream options to override effort, got %v", req["reasoning_effort"])
+	}
+}
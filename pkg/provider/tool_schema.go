@@ -0,0 +1,357 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/astercloud/aster/pkg/logging"
+)
+
+var toolSchemaLog = logging.ForComponent("ToolSchema")
+
+// ToolFormat 工具定义格式，与 ProviderCapabilities.ToolCallingFormat 取值一致
+type ToolFormat string
+
+const (
+	ToolFormatAnthropic ToolFormat = "anthropic" // Anthropic tools.input_schema
+	ToolFormatOpenAI    ToolFormat = "openai"    // OpenAI functions.parameters
+	ToolFormatGemini    ToolFormat = "gemini"    // Gemini functionDeclarations.parameters
+)
+
+// maxRefDepth $ref 展开的最大深度，超过后降级为无约束对象（递归 Schema）
+const maxRefDepth = 8
+
+// geminiSchemaKeys Gemini 支持的 Schema 字段（OpenAPI 3.0 子集）
+var geminiSchemaKeys = map[string]bool{
+	"type": true, "format": true, "title": true, "description": true, "nullable": true,
+	"enum": true, "properties": true, "required": true, "items": true,
+	"minItems": true, "maxItems": true, "minimum": true, "maximum": true,
+	"minLength": true, "maxLength": true, "pattern": true,
+	"minProperties": true, "maxProperties": true, "anyOf": true,
+	"default": true, "example": true, "propertyOrdering": true,
+}
+
+// geminiFormats Gemini 支持的 format 取值
+var geminiFormats = map[string]bool{
+	"enum": true, "date-time": true, "int32": true, "int64": true, "float": true, "double": true,
+}
+
+// ConvertToolSchema 将工具注册表中的 JSON Schema 转换为指定提供商的参数格式
+// 返回深拷贝，不修改原 Schema；notes 记录被降级或丢弃的特性
+func ConvertToolSchema(schema map[string]any, format ToolFormat) (map[string]any, []string) {
+	c := &schemaConverter{format: format, root: schema}
+	out, _ := c.convert(schema, "", 0).(map[string]any)
+	if out == nil {
+		out = map[string]any{}
+	}
+
+	// 所有提供商都要求顶层为 object
+	if t, ok := out["type"]; ok && t != "object" {
+		c.note("", fmt.Sprintf("top-level type %v replaced with object", t))
+	}
+	out["type"] = "object"
+	if _, ok := out["properties"]; !ok {
+		out["properties"] = map[string]any{}
+	}
+	if format == ToolFormatOpenAI {
+		// OpenAI 不接受顶层组合关键字
+		for _, key := range []string{"anyOf", "oneOf", "allOf", "not", "enum"} {
+			if _, ok := out[key]; ok {
+				delete(out, key)
+				c.note("", "dropped top-level "+key)
+			}
+		}
+	}
+	// Schema 按 map 遍历，排序使结果稳定
+	sort.Strings(c.notes)
+	return out, c.notes
+}
+
+// toolParameters 按格式转换工具参数 Schema，降级信息记录到调试日志
+func toolParameters(tool ToolSchema, format ToolFormat) map[string]any {
+	schema, notes := ConvertToolSchema(tool.InputSchema, format)
+	if len(notes) > 0 {
+		toolSchemaLog.Debug(context.Background(), "tool schema adjusted", map[string]any{
+			"tool":   tool.Name,
+			"format": string(format),
+			"notes":  notes,
+		})
+	}
+	return schema
+}
+
+type schemaConverter struct {
+	format ToolFormat
+	root   map[string]any
+	notes  []string
+}
+
+func (c *schemaConverter) note(path, msg string) {
+	if path == "" {
+		path = "$"
+	}
+	c.notes = append(c.notes, path+": "+msg)
+}
+
+func (c *schemaConverter) convert(v any, path string, depth int) any {
+	switch node := v.(type) {
+	case map[string]any:
+		if c.format == ToolFormatGemini {
+			return c.convertGemini(node, path, depth)
+		}
+		out := make(map[string]any, len(node))
+		for k, val := range node {
+			if k == "$schema" || k == "$id" {
+				continue
+			}
+			out[k] = c.convert(val, joinSchemaPath(path, k), depth)
+		}
+		return out
+	case []any:
+		out := make([]any, len(node))
+		for i, val := range node {
+			out[i] = c.convert(val, fmt.Sprintf("%s[%d]", path, i), depth)
+		}
+		return out
+	case []string:
+		return append([]string(nil), node...)
+	default:
+		return v
+	}
+}
+
+// convertGemini 转换为 Gemini 支持的 OpenAPI 子集
+func (c *schemaConverter) convertGemini(node map[string]any, path string, depth int) map[string]any {
+	if ref, ok := node["$ref"].(string); ok {
+		resolved, ok := c.resolveRef(ref)
+		if !ok || depth >= maxRefDepth {
+			c.note(path, "unresolved or recursive $ref "+ref+" replaced with object")
+			out := map[string]any{"type": "object"}
+			if desc, ok := node["description"].(string); ok {
+				out["description"] = desc
+			}
+			return out
+		}
+		merged := make(map[string]any, len(resolved)+len(node))
+		for k, v := range resolved {
+			merged[k] = v
+		}
+		for k, v := range node {
+			if k != "$ref" {
+				merged[k] = v
+			}
+		}
+		return c.convertGemini(merged, path, depth+1)
+	}
+
+	node = c.flattenAllOf(node, path)
+	out := make(map[string]any, len(node))
+	for k, v := range node {
+		p := joinSchemaPath(path, k)
+		switch k {
+		case "type":
+			c.geminiType(out, v, p)
+		case "const":
+			out["enum"] = []any{v}
+		case "oneOf", "anyOf":
+			if k == "oneOf" {
+				c.note(p, "oneOf converted to anyOf")
+			}
+			out["anyOf"] = c.convert(v, p, depth)
+		case "exclusiveMinimum", "exclusiveMaximum":
+			if n, ok := v.(float64); ok {
+				key := "minimum"
+				if k == "exclusiveMaximum" {
+					key = "maximum"
+				}
+				if _, set := node[key]; !set {
+					out[key] = n
+				}
+			}
+			c.note(p, k+" relaxed to inclusive bound")
+		case "format":
+			if s, ok := v.(string); ok && geminiFormats[s] {
+				out[k] = s
+			} else {
+				c.note(p, fmt.Sprintf("unsupported format %v dropped", v))
+			}
+		case "properties":
+			props, ok := v.(map[string]any)
+			if !ok {
+				continue
+			}
+			converted := make(map[string]any, len(props))
+			for name, prop := range props {
+				converted[name] = c.convert(prop, joinSchemaPath(p, name), depth)
+			}
+			out[k] = converted
+		case "items":
+			if _, ok := v.([]any); ok {
+				c.note(p, "tuple items dropped")
+				continue
+			}
+			out[k] = c.convert(v, p, depth)
+		case "$schema", "$id", "$defs", "definitions", "$comment":
+		default:
+			if !geminiSchemaKeys[k] {
+				c.note(p, "unsupported keyword dropped")
+				continue
+			}
+			out[k] = c.convert(v, p, depth)
+		}
+	}
+
+	// Gemini 的 enum 只支持字符串，非字符串枚举移入描述，由执行前校验兜底
+	if enum, ok := out["enum"].([]any); ok {
+		if !allStrings(enum) {
+			delete(out, "enum")
+			values := make([]string, len(enum))
+			for i, e := range enum {
+				values[i] = fmt.Sprint(e)
+			}
+			desc, _ := out["description"].(string)
+			out["description"] = strings.TrimSpace(desc + " Allowed values: " + strings.Join(values, ", ") + ".")
+			c.note(joinSchemaPath(path, "enum"), "non-string enum moved to description")
+		} else if _, ok := out["type"]; !ok {
+			out["type"] = "string"
+		}
+	}
+	return out
+}
+
+// geminiType 处理类型数组：["string","null"] 转为 type + nullable，多种类型转为 anyOf
+func (c *schemaConverter) geminiType(out map[string]any, v any, path string) {
+	types, ok := v.([]any)
+	if !ok {
+		out["type"] = v
+		return
+	}
+	var nonNull []any
+	for _, t := range types {
+		if t == "null" {
+			out["nullable"] = true
+		} else {
+			nonNull = append(nonNull, t)
+		}
+	}
+	switch len(nonNull) {
+	case 0:
+		c.note(path, "null type replaced with nullable string")
+		out["type"] = "string"
+	case 1:
+		out["type"] = nonNull[0]
+	default:
+		variants := make([]any, len(nonNull))
+		for i, t := range nonNull {
+			variants[i] = map[string]any{"type": t}
+		}
+		out["anyOf"] = variants
+		c.note(path, "type union converted to anyOf")
+	}
+}
+
+// flattenAllOf 将 allOf 中的对象 Schema 合并为一个（仅合并 properties/required 与顶层字段）
+func (c *schemaConverter) flattenAllOf(node map[string]any, path string) map[string]any {
+	parts, ok := node["allOf"].([]any)
+	if !ok {
+		return node
+	}
+	merged := make(map[string]any, len(node))
+	for k, v := range node {
+		if k != "allOf" {
+			merged[k] = v
+		}
+	}
+	props := map[string]any{}
+	if p, ok := merged["properties"].(map[string]any); ok {
+		for k, v := range p {
+			props[k] = v
+		}
+	}
+	required := toAnySlice(merged["required"])
+	for _, part := range parts {
+		sub, ok := part.(map[string]any)
+		if !ok {
+			continue
+		}
+		if ref, ok := sub["$ref"].(string); ok {
+			if resolved, ok := c.resolveRef(ref); ok {
+				sub = resolved
+			}
+		}
+		for k, v := range sub {
+			switch k {
+			case "properties":
+				if p, ok := v.(map[string]any); ok {
+					for name, prop := range p {
+						props[name] = prop
+					}
+				}
+			case "required":
+				required = append(required, toAnySlice(v)...)
+			default:
+				if _, exists := merged[k]; !exists {
+					merged[k] = v
+				}
+			}
+		}
+	}
+	if len(props) > 0 {
+		merged["properties"] = props
+	}
+	if len(required) > 0 {
+		merged["required"] = required
+	}
+	c.note(joinSchemaPath(path, "allOf"), "allOf merged into a single schema")
+	return merged
+}
+
+// resolveRef 解析本地引用（#/$defs/x 或 #/definitions/x）
+func (c *schemaConverter) resolveRef(ref string) (map[string]any, bool) {
+	if !strings.HasPrefix(ref, "#/") {
+		return nil, false
+	}
+	var cur any = c.root
+	for _, part := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+		part = strings.ReplaceAll(strings.ReplaceAll(part, "~1", "/"), "~0", "~")
+		m, ok := cur.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		cur = m[part]
+	}
+	m, ok := cur.(map[string]any)
+	return m, ok
+}
+
+func joinSchemaPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func allStrings(values []any) bool {
+	for _, v := range values {
+		if _, ok := v.(string); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func toAnySlice(v any) []any {
+	switch s := v.(type) {
+	case []any:
+		return append([]any(nil), s...)
+	case []string:
+		out := make([]any, len(s))
+		for i, x := range s {
+			out[i] = x
+		}
+		return out
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package provider
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func testToolSchema() map[string]any {
+	return map[string]any{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"type":    "object",
+		"properties": map[string]any{
+			"path":  map[string]any{"type": "string", "format": "uri"},
+			"mode":  map[string]any{"type": []any{"string", "null"}, "enum": []any{"fast", "full"}},
+			"level": map[string]any{"type": "integer", "enum": []any{1, 2, 3}},
+			"kind":  map[string]any{"const": "file"},
+			"range": map[string]any{"$ref": "#/$defs/range"},
+			"node":  map[string]any{"$ref": "#/$defs/node"},
+		},
+		"required":             []string{"path"},
+		"additionalProperties": false,
+		"$defs": map[string]any{
+			"range": map[string]any{
+				"type":       "object",
+				"properties": map[string]any{"start": map[string]any{"type": "integer", "exclusiveMinimum": 0.0}},
+			},
+			"node": map[string]any{
+				"type":       "object",
+				"properties": map[string]any{"child": map[string]any{"$ref": "#/$defs/node"}},
+			},
+		},
+	}
+}
+
+func TestConvertToolSchema_Gemini(t *testing.T) {
+	schema := testToolSchema()
+	out, notes := ConvertToolSchema(schema, ToolFormatGemini)
+
+	for _, key := range []string{"$schema", "$defs", "additionalProperties"} {
+		if _, ok := out[key]; ok {
+			t.Errorf("%s should be dropped", key)
+		}
+	}
+	props := out["properties"].(map[string]any)
+	if _, ok := props["path"].(map[string]any)["format"]; ok {
+		t.Error("unsupported format should be dropped")
+	}
+	mode := props["mode"].(map[string]any)
+	if mode["type"] != "string" || mode["nullable"] != true {
+		t.Errorf("mode = %v, want nullable string", mode)
+	}
+	level := props["level"].(map[string]any)
+	if _, ok := level["enum"]; ok || !strings.Contains(level["description"].(string), "1, 2, 3") {
+		t.Errorf("level = %v, want non-string enum moved to description", level)
+	}
+	if kind := props["kind"].(map[string]any); !reflect.DeepEqual(kind["enum"], []any{"file"}) || kind["type"] != "string" {
+		t.Errorf("kind = %v, want const converted to string enum", kind)
+	}
+	start := props["range"].(map[string]any)["properties"].(map[string]any)["start"].(map[string]any)
+	if start["minimum"] != 0.0 {
+		t.Errorf("range.start = %v, want inlined $ref with minimum", start)
+	}
+	if len(notes) == 0 {
+		t.Error("expected notes for dropped features")
+	}
+
+	// 递归引用在有限深度内截断
+	node := props["node"].(map[string]any)
+	for range maxRefDepth + 2 {
+		child, ok := node["properties"].(map[string]any)["child"].(map[string]any)
+		if !ok {
+			t.Fatalf("node = %v", node)
+		}
+		if _, ok := child["properties"]; !ok {
+			break
+		}
+		node = child
+	}
+
+	// 原 Schema 不被修改
+	if !reflect.DeepEqual(schema, testToolSchema()) {
+		t.Error("ConvertToolSchema modified its input")
+	}
+}
+
+func TestConvertToolSchema_OpenAIAndAnthropic(t *testing.T) {
+	out, _ := ConvertToolSchema(map[string]any{
+		"anyOf":      []any{map[string]any{"required": []any{"a"}}},
+		"properties": map[string]any{"a": map[string]any{"$ref": "#/$defs/a"}},
+		"$defs":      map[string]any{"a": map[string]any{"type": "string"}},
+	}, ToolFormatOpenAI)
+	if out["type"] != "object" {
+		t.Errorf("type = %v, want object", out["type"])
+	}
+	if _, ok := out["anyOf"]; ok {
+		t.Error("OpenAI format should drop top-level anyOf")
+	}
+	if _, ok := out["$defs"]; !ok {
+		t.Error("OpenAI format should keep $defs")
+	}
+
+	out, notes := ConvertToolSchema(nil, ToolFormatAnthropic)
+	if !reflect.DeepEqual(out, map[string]any{"type": "object", "properties": map[string]any{}}) || len(notes) != 0 {
+		t.Errorf("ConvertToolSchema(nil) = %v, %v", out, notes)
+	}
+}
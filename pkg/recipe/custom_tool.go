@@ -0,0 +1,444 @@
+package recipe
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/astercloud/aster/pkg/httpclient"
+	"github.com/astercloud/aster/pkg/sandbox"
+	"github.com/astercloud/aster/pkg/tools"
+)
+
+const (
+	// defaultCustomToolTimeout applies when a custom tool does not set a timeout
+	defaultCustomToolTimeout = 60 * time.Second
+	// maxCustomToolOutput caps the output returned to the model
+	maxCustomToolOutput = 32 * 1024
+)
+
+var (
+	customToolNamePattern   = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_-]{0,63}$`)
+	customToolParamPattern  = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_]+)\s*\}\}`)
+	customToolHTTPMethods   = map[string]bool{"GET": true, "POST": true, "PUT": true, "PATCH": true, "DELETE": true}
+	customToolBodylessVerbs = map[string]bool{"GET": true, "DELETE": true}
+)
+
+// CustomTool declares a lightweight tool backed by a shell command or an HTTP endpoint.
+//
+// Inputs are substituted into the command or URL with {{name}} placeholders. Command
+// values are shell-quoted (so placeholders must not be wrapped in quotes) and the full
+// input is also exported as ASTER_TOOL_INPUT (JSON).
+// URL placeholders may only appear in the path or query, never in the scheme or host.
+// HTTP tools send the input as a JSON body for POST, PUT and PATCH requests.
+type CustomTool struct {
+	// Name is the tool name exposed to the model
+	Name string `yaml:"name" json:"name"`
+
+	// Description tells the model when to use the tool
+	Description string `yaml:"description" json:"description"`
+
+	// InputSchema is the JSON Schema of the tool input (defaults to an empty object)
+	InputSchema map[string]any `yaml:"input_schema,omitempty" json:"input_schema,omitempty"`
+
+	// Command is a shell command template, run inside the agent sandbox
+	Command string `yaml:"command,omitempty" json:"command,omitempty"`
+
+	// URL is an HTTP endpoint template
+	URL string `yaml:"url,omitempty" json:"url,omitempty"`
+
+	// Method is the HTTP method (default POST)
+	Method string `yaml:"method,omitempty" json:"method,omitempty"`
+
+	// Headers are HTTP headers; values may reference environment variables listed in Env as ${VAR}
+	Headers map[string]string `yaml:"headers,omitempty" json:"headers,omitempty"`
+
+	// Env lists the environment variables header values may reference
+	Env []string `yaml:"env,omitempty" json:"env,omitempty"`
+
+	// Timeout in seconds
+	Timeout int `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+
+	// ReadOnly marks tools without side effects so approval modes can treat them as safe
+	ReadOnly bool `yaml:"read_only,omitempty" json:"read_only,omitempty"`
+}
+
+// Validate checks if the custom tool is valid.
+func (c *CustomTool) Validate() error {
+	if !customToolNamePattern.MatchString(c.Name) {
+		return errors.New("name must start with a letter and contain only letters, digits, '_' or '-'")
+	}
+
+	if c.Description == "" {
+		return errors.New("description is required")
+	}
+
+	if (c.Command == "") == (c.URL == "") {
+		return errors.New("exactly one of command or url is required")
+	}
+
+	if c.URL != "" {
+		if !customToolHTTPMethods[c.method()] {
+			return fmt.Errorf("unsupported method: %s", c.Method)
+		}
+		if !strings.HasPrefix(c.URL, "http://") && !strings.HasPrefix(c.URL, "https://") {
+			return errors.New("url must start with http:// or https://")
+		}
+		if _, err := urlTemplateHost(c.URL); err != nil {
+			return err
+		}
+		for key, value := range c.Headers {
+			for _, name := range headerEnvRefs(value) {
+				if !slices.Contains(c.Env, name) {
+					return fmt.Errorf("header %s references ${%s}, which is not listed in env", key, name)
+				}
+			}
+		}
+	}
+
+	if c.Timeout < 0 {
+		return errors.New("timeout must not be negative")
+	}
+
+	return nil
+}
+
+// urlTemplateHost returns the scheme and host of a URL template, rejecting
+// placeholders before the path so inputs cannot choose the destination host.
+func urlTemplateHost(template string) (string, error) {
+	if loc := customToolParamPattern.FindStringIndex(template); loc != nil {
+		_, authority, _ := strings.Cut(template[:loc[0]], "://")
+		if !strings.ContainsAny(authority, "/?#") {
+			return "", errors.New("url placeholders are only allowed in the path or query")
+		}
+	}
+	u, err := url.Parse(customToolParamPattern.ReplaceAllString(template, "x"))
+	if err != nil {
+		return "", fmt.Errorf("invalid url: %w", err)
+	}
+	if u.Host == "" {
+		return "", errors.New("url must include a host")
+	}
+	return u.Scheme + "://" + u.Host, nil
+}
+
+// headerEnvRefs returns the environment variables a header value references.
+func headerEnvRefs(value string) []string {
+	var names []string
+	os.Expand(value, func(name string) string {
+		names = append(names, name)
+		return ""
+	})
+	return names
+}
+
+// expandHeader substitutes ${VAR} references, reading only the variables listed in Env.
+func (c *CustomTool) expandHeader(value string) string {
+	return os.Expand(value, func(name string) string {
+		if !slices.Contains(c.Env, name) {
+			return ""
+		}
+		return os.Getenv(name)
+	})
+}
+
+func (c *CustomTool) method() string {
+	if c.Method == "" {
+		return http.MethodPost
+	}
+	return strings.ToUpper(c.Method)
+}
+
+func (c *CustomTool) timeout() time.Duration {
+	if c.Timeout > 0 {
+		return time.Duration(c.Timeout) * time.Second
+	}
+	return defaultCustomToolTimeout
+}
+
+// RegisterCustomTools registers the recipe's custom tools into the registry and
+// returns their names. Custom tools must not shadow tools that are already registered.
+func (r *Recipe) RegisterCustomTools(registry *tools.Registry) ([]string, error) {
+	names := make([]string, 0, len(r.CustomTools))
+	for i := range r.CustomTools {
+		def := r.CustomTools[i]
+		if registry.Has(def.Name) {
+			return names, fmt.Errorf("custom tool %q conflicts with a registered tool", def.Name)
+		}
+		registry.Register(def.Name, func(map[string]any) (tools.Tool, error) {
+			return NewCustomTool(def)
+		})
+		names = append(names, def.Name)
+	}
+	return names, nil
+}
+
+// customTool adapts a CustomTool definition to the tools.Tool interface.
+type customTool struct {
+	def    CustomTool
+	client *http.Client
+}
+
+// NewCustomTool creates a tool from a custom tool definition.
+func NewCustomTool(def CustomTool) (tools.Tool, error) {
+	if err := def.Validate(); err != nil {
+		return nil, fmt.Errorf("custom tool %q: %w", def.Name, err)
+	}
+	return &customTool{def: def, client: httpclient.New(def.timeout())}, nil
+}
+
+func (t *customTool) Name() string {
+	return t.def.Name
+}
+
+func (t *customTool) Description() string {
+	return t.def.Description
+}
+
+func (t *customTool) InputSchema() map[string]any {
+	if t.def.InputSchema != nil {
+		return t.def.InputSchema
+	}
+	return map[string]any{"type": "object", "properties": map[string]any{}}
+}
+
+func (t *customTool) Prompt() string {
+	return ""
+}
+
+// Annotations marks command tools as execution and HTTP tools as network access.
+// Read-only command tools are treated as local and safe to auto-approve.
+func (t *customTool) Annotations() *tools.ToolAnnotations {
+	switch {
+	case t.def.Command != "" && t.def.ReadOnly:
+		annotations := tools.AnnotationsExecution.Clone().WithRiskLevel(tools.RiskLevelLow)
+		annotations.ReadOnly = true
+		annotations.Destructive = false
+		annotations.OpenWorld = false
+		return annotations
+	case t.def.Command != "":
+		return tools.AnnotationsExecution
+	case t.def.ReadOnly:
+		return tools.AnnotationsNetworkRead
+	default:
+		return tools.AnnotationsNetworkWrite
+	}
+}
+
+// RequiresWorkspaceTrust reports that custom tools run shell commands or reach the network.
+func (t *customTool) RequiresWorkspaceTrust() bool {
+	return true
+}
+
+func (t *customTool) Execute(ctx context.Context, input map[string]any, tc *tools.ToolContext) (any, error) {
+	if err := t.checkRequired(input); err != nil {
+		return nil, err
+	}
+	if t.def.Command != "" {
+		return t.runCommand(ctx, input, tc)
+	}
+	return t.callHTTP(ctx, input)
+}
+
+// checkRequired verifies that all required schema properties are present.
+func (t *customTool) checkRequired(input map[string]any) error {
+	var required []string
+	switch v := t.def.InputSchema["required"].(type) {
+	case []string:
+		required = v
+	case []any:
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				required = append(required, s)
+			}
+		}
+	}
+	for _, key := range required {
+		if _, ok := input[key]; !ok {
+			return fmt.Errorf("missing required parameter: %s", key)
+		}
+	}
+	return nil
+}
+
+func (t *customTool) runCommand(ctx context.Context, input map[string]any, tc *tools.ToolContext) (any, error) {
+	if tc == nil || tc.Sandbox == nil {
+		return nil, errors.New("custom command tools require a sandbox")
+	}
+
+	inputJSON, err := json.Marshal(input)
+	if err != nil {
+		return nil, fmt.Errorf("encode input: %w", err)
+	}
+	quote, err := shellQuoter(tc.Sandbox)
+	if err != nil {
+		return nil, fmt.Errorf("run %s: %w", t.def.Name, err)
+	}
+	cmd := expandTemplate(t.def.Command, input, quote)
+
+	result, err := tc.Sandbox.Exec(ctx, cmd, &sandbox.ExecOptions{
+		Timeout: t.def.timeout(),
+		Env:     map[string]string{"ASTER_TOOL_INPUT": string(inputJSON)},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("run %s: %w", t.def.Name, err)
+	}
+
+	return map[string]any{
+		"ok":        result.Code == 0,
+		"exit_code": result.Code,
+		"stdout":    truncateOutput(result.Stdout),
+		"stderr":    truncateOutput(result.Stderr),
+	}, nil
+}
+
+func (t *customTool) callHTTP(ctx context.Context, input map[string]any) (any, error) {
+	method := t.def.method()
+	endpoint, err := expandURL(t.def.URL, input)
+	if err != nil {
+		return nil, fmt.Errorf("call %s: %w", t.def.Name, err)
+	}
+
+	var body io.Reader
+	if !customToolBodylessVerbs[method] {
+		data, err := json.Marshal(input)
+		if err != nil {
+			return nil, fmt.Errorf("encode input: %w", err)
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, body)
+	if err != nil {
+		return nil, fmt.Errorf("build request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for key, value := range t.def.Headers {
+		req.Header.Set(key, t.def.expandHeader(value))
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("call %s: %w", t.def.Name, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxCustomToolOutput+1))
+	if err != nil {
+		return nil, fmt.Errorf("read response: %w", err)
+	}
+
+	return map[string]any{
+		"ok":     resp.StatusCode < 400,
+		"status": resp.StatusCode,
+		"body":   truncateOutput(string(data)),
+	}, nil
+}
+
+// expandTemplate replaces {{name}} placeholders with escaped input values; missing values become empty.
+func expandTemplate(template string, input map[string]any, escape func(string) string) string {
+	return customToolParamPattern.ReplaceAllStringFunc(template, func(match string) string {
+		return escape(templateValue(input, customToolParamPattern.FindStringSubmatch(match)[1]))
+	})
+}
+
+// templateValue returns the input value for a placeholder as a string.
+func templateValue(input map[string]any, key string) string {
+	value, ok := input[key]
+	if !ok || value == nil {
+		return ""
+	}
+	if s, ok := value.(string); ok {
+		return s
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
+
+// expandURL fills URL placeholders: path values are path-escaped and may not be
+// dot segments, query values are query-escaped. The expanded URL must keep the
+// template's scheme and host.
+func expandURL(template string, input map[string]any) (string, error) {
+	origin, err := urlTemplateHost(template)
+	if err != nil {
+		return "", err
+	}
+
+	path, query, hasQuery := strings.Cut(template, "?")
+	var expandErr error
+	path = customToolParamPattern.ReplaceAllStringFunc(path, func(match string) string {
+		value := templateValue(input, customToolParamPattern.FindStringSubmatch(match)[1])
+		if value == "." || value == ".." {
+			expandErr = fmt.Errorf("invalid path value %q", value)
+		}
+		return url.PathEscape(value)
+	})
+	if expandErr != nil {
+		return "", expandErr
+	}
+	endpoint := path
+	if hasQuery {
+		endpoint += "?" + expandTemplate(query, input, url.QueryEscape)
+	}
+
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", fmt.Errorf("invalid url: %w", err)
+	}
+	if u.Scheme+"://"+u.Host != origin {
+		return "", fmt.Errorf("url host changed after expansion: %s", u.Host)
+	}
+	return endpoint, nil
+}
+
+// shellQuoter returns the quoting function for the sandbox's shell. Local sandboxes
+// may run PowerShell or cmd.exe; cmd.exe has no safe quoting, so command tools fail there.
+// Container and remote sandboxes run a POSIX shell.
+func shellQuoter(sb sandbox.Sandbox) (func(string) string, error) {
+	ls, ok := sb.(*sandbox.LocalSandbox)
+	if !ok {
+		return shellQuote, nil
+	}
+	switch ls.Shell() {
+	case sandbox.ShellPowerShell:
+		return powerShellQuote, nil
+	case sandbox.ShellCmd:
+		return nil, errors.New("custom command tools are not supported with cmd.exe; configure PowerShell or a POSIX shell")
+	default:
+		return shellQuote, nil
+	}
+}
+
+// shellQuote quotes a value for POSIX shells.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// powerShellQuote quotes a value as a PowerShell verbatim string.
+func powerShellQuote(s string) string {
+	// PowerShell also treats typographic single quotes as quote characters
+	r := strings.NewReplacer("'", "''", "\u2018", "\u2018\u2018", "\u2019", "\u2019\u2019", "\u201a", "\u201a\u201a", "\u201b", "\u201b\u201b")
+	return "'" + r.Replace(s) + "'"
+}
+
+func truncateOutput(s string) string {
+	if len(s) <= maxCustomToolOutput {
+		return s
+	}
+	return s[:maxCustomToolOutput] + "\n... (output truncated)"
+}
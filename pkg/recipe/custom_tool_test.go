@@ -0,0 +1,185 @@
+package recipe
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/astercloud/aster/pkg/sandbox"
+	"github.com/astercloud/aster/pkg/tools"
+)
+
+func TestCustomToolValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		tool    CustomTool
+		wantErr bool
+	}{
+		{"command", CustomTool{Name: "lint", Description: "Run linter", Command: "make lint"}, false},
+		{"http", CustomTool{Name: "ticket", Description: "Get ticket", URL: "https://example.com/{{id}}", Method: "get"}, false},
+		{"bad name", CustomTool{Name: "1lint", Description: "Run linter", Command: "make lint"}, true},
+		{"no description", CustomTool{Name: "lint", Command: "make lint"}, true},
+		{"both backends", CustomTool{Name: "lint", Description: "x", Command: "make", URL: "https://example.com"}, true},
+		{"no backend", CustomTool{Name: "lint", Description: "x"}, true},
+		{"bad method", CustomTool{Name: "t", Description: "x", URL: "https://example.com", Method: "TRACE"}, true},
+		{"bad scheme", CustomTool{Name: "t", Description: "x", URL: "file:///etc/passwd"}, true},
+		{"host placeholder", CustomTool{Name: "t", Description: "x", URL: "https://{{host}}/api"}, true},
+		{"placeholder before path", CustomTool{Name: "t", Description: "x", URL: "https://example.com{{path}}"}, true},
+		{"undeclared header env", CustomTool{Name: "t", Description: "x", URL: "https://example.com", Headers: map[string]string{"Authorization": "Bearer ${AWS_SECRET_ACCESS_KEY}"}}, true},
+		{"declared header env", CustomTool{Name: "t", Description: "x", URL: "https://example.com", Headers: map[string]string{"Authorization": "Bearer ${TOKEN}"}, Env: []string{"TOKEN"}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.tool.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	_, err := LoadFromBytes([]byte(`
+title: Tools
+description: Duplicate tools
+custom_tools:
+  - {name: lint, description: Run linter, command: make lint}
+  - {name: lint, description: Run linter, command: make lint}
+`))
+	if err == nil {
+		t.Error("LoadFromBytes() should reject duplicate custom tool names")
+	}
+}
+
+func TestCustomToolCommand(t *testing.T) {
+	sb, err := sandbox.NewLocalSandbox(&sandbox.LocalSandboxConfig{WorkDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("NewLocalSandbox() error = %v", err)
+	}
+	t.Cleanup(func() { _ = sb.Dispose() })
+
+	tool, err := NewCustomTool(CustomTool{
+		Name:        "greet",
+		Description: "Greet someone",
+		InputSchema: map[string]any{"type": "object", "required": []any{"name"}},
+		Command:     `echo hello {{name}}; echo "$ASTER_TOOL_INPUT"`,
+		ReadOnly:    true,
+	})
+	if err != nil {
+		t.Fatalf("NewCustomTool() error = %v", err)
+	}
+	tc := &tools.ToolContext{Sandbox: sb}
+
+	result, err := tool.Execute(context.Background(), map[string]any{"name": "bob; rm -rf /"}, tc)
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	res := result.(map[string]any)
+	stdout, _ := res["stdout"].(string)
+	if res["ok"] != true || !strings.Contains(stdout, "hello bob; rm -rf /") || !strings.Contains(stdout, `{"name":"bob; rm -rf /"}`) {
+		t.Errorf("Execute() = %v", res)
+	}
+
+	if _, err := tool.Execute(context.Background(), map[string]any{}, tc); err == nil {
+		t.Error("Execute() should fail without required parameter")
+	}
+
+	annotations := tools.GetAnnotations(tool)
+	if !annotations.IsSafeForAutoApproval() || annotations.Category != tools.CategoryExecution {
+		t.Errorf("Annotations() = %+v", annotations)
+	}
+	if trusted, ok := tool.(tools.TrustRestrictedTool); !ok || !trusted.RequiresWorkspaceTrust() {
+		t.Error("custom tools should require workspace trust")
+	}
+}
+
+func TestCustomToolHTTP(t *testing.T) {
+	t.Setenv("TICKET_TOKEN", "secret")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"path":  r.URL.Path,
+			"query": r.URL.Query().Get("q"),
+			"auth":  r.Header.Get("Authorization"),
+			"body":  string(body),
+		})
+	}))
+	defer srv.Close()
+
+	tool, err := NewCustomTool(CustomTool{
+		Name:        "ticket",
+		Description: "Update a ticket",
+		URL:         srv.URL + "/tickets?q={{query}}",
+		Headers:     map[string]string{"Authorization": "Bearer ${TICKET_TOKEN}"},
+		Env:         []string{"TICKET_TOKEN"},
+	})
+	if err != nil {
+		t.Fatalf("NewCustomTool() error = %v", err)
+	}
+
+	result, err := tool.Execute(context.Background(), map[string]any{"query": "a&b"}, &tools.ToolContext{})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	res := result.(map[string]any)
+	var got map[string]string
+	if err := json.Unmarshal([]byte(res["body"].(string)), &got); err != nil {
+		t.Fatalf("response body = %v", res["body"])
+	}
+	var sent map[string]any
+	_ = json.Unmarshal([]byte(got["body"]), &sent)
+	if res["status"] != http.StatusOK || got["query"] != "a&b" || got["auth"] != "Bearer secret" || sent["query"] != "a&b" {
+		t.Errorf("Execute() = %v", got)
+	}
+	if tools.GetAnnotations(tool).IsSafeForAutoApproval() {
+		t.Error("HTTP tools should not be auto-approved")
+	}
+}
+
+func TestExpandURL(t *testing.T) {
+	template := "https://api.example.com/tickets/{{id}}?q={{query}}"
+	got, err := expandURL(template, map[string]any{"id": "../admin?x=1#", "query": "a&b"})
+	if err != nil {
+		t.Fatalf("expandURL() error = %v", err)
+	}
+	if want := "https://api.example.com/tickets/..%2Fadmin%3Fx=1%23?q=a%26b"; got != want {
+		t.Errorf("expandURL() = %q, want %q", got, want)
+	}
+	for _, id := range []string{"..", "."} {
+		if _, err := expandURL(template, map[string]any{"id": id}); err == nil {
+			t.Errorf("expandURL() should reject dot segment %q", id)
+		}
+	}
+}
+
+func TestShellQuoting(t *testing.T) {
+	if got := shellQuote("it's"); got != `'it'\''s'` {
+		t.Errorf("shellQuote() = %s", got)
+	}
+	if got := powerShellQuote("it's $(rm)"); got != `'it''s $(rm)'` {
+		t.Errorf("powerShellQuote() = %s", got)
+	}
+	if quote, err := shellQuoter(sandbox.NewMockSandbox()); err != nil || quote("x") != "'x'" {
+		t.Errorf("shellQuoter() = %v", err)
+	}
+}
+
+func TestRegisterCustomTools(t *testing.T) {
+	registry := tools.NewRegistry()
+	registry.Register("Bash", func(map[string]any) (tools.Tool, error) { return nil, nil })
+
+	r := &Recipe{CustomTools: []CustomTool{{Name: "lint", Description: "Run linter", Command: "make lint"}}}
+	names, err := r.RegisterCustomTools(registry)
+	if err != nil || len(names) != 1 || !registry.Has("lint") {
+		t.Fatalf("RegisterCustomTools() = %v, %v", names, err)
+	}
+	if tool, err := registry.Create("lint", nil); err != nil || tool.Name() != "lint" {
+		t.Errorf("Create() = %v, %v", tool, err)
+	}
+
+	r.CustomTools = []CustomTool{{Name: "Bash", Description: "Shadow", Command: "true"}}
+	if _, err := r.RegisterCustomTools(registry); err == nil {
+		t.Error("RegisterCustomTools() should reject names of registered tools")
+	}
+}
@@ -0,0 +1,431 @@
+package recipe
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/astercloud/aster/pkg/provider"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+// ErrUnsuccessfulSession is returned when a session has no final assistant answer to distill from.
+var ErrUnsuccessfulSession = errors.New("session did not finish with an assistant answer")
+
+const (
+	maxDistillSteps     = 20
+	maxStepArgLength    = 120
+	maxDistillTitle     = 60
+	maxExpectedResult   = 500
+	maxParameterValue   = 200
+	maxRefineTranscript = 12000
+)
+
+// DistillOptions controls how a session is turned into a recipe.
+type DistillOptions struct {
+	// Title overrides the title derived from the first prompt
+	Title string
+
+	// Source describes where the session came from (e.g. its ID) and is noted in the description
+	Source string
+
+	// IncludeFailedTools also lists tools whose every call failed
+	IncludeFailedTools bool
+}
+
+// Distill builds a reusable recipe from the messages of a successful session.
+// The first user message becomes the prompt, follow-up messages and the tool calls
+// that solved the task become the instructions, and values that vary between runs
+// (URLs, dates, quoted strings and paths the tools worked on) become parameters
+// whose defaults are the values used in the session.
+func Distill(messages []types.Message, opts DistillOptions) (*Recipe, error) {
+	var prompts []string
+	var final string
+	for i := range messages {
+		msg := &messages[i]
+		switch msg.Role {
+		case types.RoleUser:
+			if text := userText(msg); text != "" && msg.IsVisibleForUser() {
+				prompts = append(prompts, text)
+				final = ""
+			}
+		case types.RoleAssistant:
+			if text := strings.TrimSpace(msg.GetContent()); text != "" {
+				final = text
+			}
+		}
+	}
+	if len(prompts) == 0 {
+		return nil, errors.New("session has no user messages")
+	}
+	if final == "" {
+		return nil, ErrUnsuccessfulSession
+	}
+
+	calls := toolCalls(messages)
+	params := inferParameters(prompts, calls)
+
+	r := &Recipe{
+		Version:     "1.0",
+		Title:       opts.Title,
+		Description: describeRecipe(prompts[0], opts.Source),
+		Prompt:      applyPlaceholders(prompts[0], params),
+		Tools:       usedTools(calls, opts.IncludeFailedTools),
+	}
+	if r.Title == "" {
+		r.Title = truncateText(firstLine(prompts[0]), maxDistillTitle)
+	}
+	r.Instructions = applyPlaceholders(buildInstructions(prompts[1:], calls, final), params)
+	for _, p := range params {
+		r.Parameters = append(r.Parameters, p.Parameter)
+	}
+	if err := r.Validate(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// distilledCall is a tool call and whether it succeeded.
+type distilledCall struct {
+	Name   string
+	Input  map[string]any
+	Failed bool
+}
+
+// userText returns the typed text of a user message, ignoring tool results.
+func userText(msg *types.Message) string {
+	if msg.Content != "" {
+		return strings.TrimSpace(msg.Content)
+	}
+	var parts []string
+	for _, block := range msg.ContentBlocks {
+		if tb, ok := block.(*types.TextBlock); ok && strings.TrimSpace(tb.Text) != "" {
+			parts = append(parts, strings.TrimSpace(tb.Text))
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// toolCalls collects tool calls in order, marking those whose result was an error.
+func toolCalls(messages []types.Message) []distilledCall {
+	var calls []distilledCall
+	index := make(map[string]int)
+	for _, msg := range messages {
+		for _, block := range msg.ContentBlocks {
+			switch b := block.(type) {
+			case *types.ToolUseBlock:
+				index[b.ID] = len(calls)
+				calls = append(calls, distilledCall{Name: b.Name, Input: b.Input})
+			case *types.ToolResultBlock:
+				if i, ok := index[b.ToolUseID]; ok && b.IsError {
+					calls[i].Failed = true
+				}
+			}
+		}
+	}
+	return calls
+}
+
+// usedTools lists tool names in order of first use.
+func usedTools(calls []distilledCall, includeFailed bool) []string {
+	succeeded := make(map[string]bool)
+	for _, c := range calls {
+		if !c.Failed {
+			succeeded[c.Name] = true
+		}
+	}
+	var tools []string
+	seen := make(map[string]bool)
+	for _, c := range calls {
+		if seen[c.Name] || (!includeFailed && !succeeded[c.Name]) {
+			continue
+		}
+		seen[c.Name] = true
+		tools = append(tools, c.Name)
+	}
+	return tools
+}
+
+// buildInstructions describes the follow-up requirements, the steps that solved the task and the expected result.
+func buildInstructions(followUps []string, calls []distilledCall, final string) string {
+	var b strings.Builder
+	b.WriteString("Complete the task given in the prompt.\n")
+	if len(followUps) > 0 {
+		b.WriteString("\nAdditional requirements:\n")
+		for _, f := range followUps {
+			fmt.Fprintf(&b, "- %s\n", strings.ReplaceAll(f, "\n", " "))
+		}
+	}
+
+	var steps []string
+	for _, c := range calls {
+		if c.Failed {
+			continue
+		}
+		step := c.Name
+		if arg := stepArgument(c.Input); arg != "" {
+			step += ": " + arg
+		}
+		if len(steps) > 0 && steps[len(steps)-1] == step {
+			continue
+		}
+		steps = append(steps, step)
+	}
+	if len(steps) > 0 {
+		b.WriteString("\nA previous run solved it with these steps:\n")
+		for i, step := range steps {
+			if i == maxDistillSteps {
+				fmt.Fprintf(&b, "%d. ... (%d more steps)\n", i+1, len(steps)-i)
+				break
+			}
+			fmt.Fprintf(&b, "%d. %s\n", i+1, step)
+		}
+	}
+
+	fmt.Fprintf(&b, "\nExpected result, similar to:\n%s\n", truncateText(final, maxExpectedResult))
+	return b.String()
+}
+
+// stepArgKeys are the tool inputs that best describe what a call did.
+var stepArgKeys = []string{"file_path", "path", "command", "url", "pattern", "query", "skill", "name"}
+
+func stepArgument(input map[string]any) string {
+	for _, key := range stepArgKeys {
+		if v, ok := input[key].(string); ok && v != "" {
+			return truncateText(strings.ReplaceAll(v, "\n", " "), maxStepArgLength)
+		}
+	}
+	return ""
+}
+
+// inferredParameter is a parameter together with the session value it replaces.
+type inferredParameter struct {
+	Parameter
+	value string
+}
+
+var (
+	urlPattern    = regexp.MustCompile(`https?://[^\s"'<>()\x60]+[^\s"'<>()\x60.,;:!?]`)
+	datePattern   = regexp.MustCompile(`\b\d{4}-\d{2}-\d{2}\b`)
+	quotedPattern = regexp.MustCompile("[\"\x60]([^\"\x60\\n]{2,80})[\"\x60]")
+	pathPattern   = regexp.MustCompile(`(?:\.{0,2}/)?[\w.-]+(?:/[\w.-]+)+|\b[\w-]+\.[A-Za-z][A-Za-z0-9]{0,5}\b`)
+	keyCleanup    = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+// inferParameters finds the variable bits of the prompts. URLs, dates and quoted
+// strings always qualify; paths only when a tool actually worked on them.
+func inferParameters(prompts []string, calls []distilledCall) []inferredParameter {
+	type match struct {
+		start, end int
+		value      string
+		kind       string
+	}
+	inputs := toolInputValues(calls)
+
+	var params []inferredParameter
+	byValue := make(map[string]bool)
+	keys := make(map[string]int)
+	for _, prompt := range prompts {
+		var matches []match
+		add := func(re *regexp.Regexp, kind string, group int, needsTool bool) {
+			for _, loc := range re.FindAllStringSubmatchIndex(prompt, -1) {
+				start, end := loc[2*group], loc[2*group+1]
+				value := prompt[start:end]
+				if needsTool && inputKey(inputs, value) == "" {
+					continue
+				}
+				matches = append(matches, match{start, end, value, kind})
+			}
+		}
+		// Earlier patterns win where matches overlap
+		add(urlPattern, "url", 0, false)
+		add(quotedPattern, "text", 1, false)
+		add(datePattern, "date", 0, false)
+		add(pathPattern, "path", 0, true)
+
+		var taken [][2]int
+		for _, m := range matches {
+			overlaps := false
+			for _, t := range taken {
+				if m.start < t[1] && t[0] < m.end {
+					overlaps = true
+					break
+				}
+			}
+			if overlaps || len(m.value) > maxParameterValue {
+				continue
+			}
+			taken = append(taken, [2]int{m.start, m.end})
+			if byValue[m.value] {
+				continue
+			}
+			byValue[m.value] = true
+
+			key := inputKey(inputs, m.value)
+			if key == "" || m.kind == "url" || m.kind == "date" {
+				key = m.kind
+			}
+			keys[key]++
+			if keys[key] > 1 {
+				key = fmt.Sprintf("%s_%d", key, keys[key])
+			}
+			p := Parameter{
+				Key:         key,
+				Type:        ParamTypeString,
+				Requirement: ParamOptional,
+				Description: fmt.Sprintf("Inferred from the session, where it was %q", m.value),
+				Default:     m.value,
+			}
+			if m.kind == "date" {
+				p.Type = ParamTypeDate
+			}
+			params = append(params, inferredParameter{Parameter: p, value: m.value})
+		}
+	}
+	return params
+}
+
+// toolInputValues maps single-line string values passed to tools to their normalized input key.
+func toolInputValues(calls []distilledCall) map[string]string {
+	values := make(map[string]string)
+	var walk func(key string, v any)
+	walk = func(key string, v any) {
+		switch val := v.(type) {
+		case string:
+			if val != "" && !strings.Contains(val, "\n") && len(val) <= maxParameterValue*4 {
+				if _, ok := values[val]; !ok {
+					values[val] = strings.Trim(keyCleanup.ReplaceAllString(strings.ToLower(key), "_"), "_")
+				}
+			}
+		case map[string]any:
+			keys := make([]string, 0, len(val))
+			for k := range val {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				walk(k, val[k])
+			}
+		case []any:
+			for _, item := range val {
+				walk(key, item)
+			}
+		}
+	}
+	for _, c := range calls {
+		walk("", c.Input)
+	}
+	return values
+}
+
+// inputKey returns the input key of a tool value containing v, or "" when no tool used it.
+func inputKey(inputs map[string]string, v string) string {
+	if key, ok := inputs[v]; ok {
+		return key
+	}
+	best := ""
+	for value, key := range inputs {
+		if strings.Contains(value, v) && (best == "" || key < best) {
+			best = key
+		}
+	}
+	return best
+}
+
+// applyPlaceholders replaces parameter values with {{key}} placeholders, longest values first.
+func applyPlaceholders(text string, params []inferredParameter) string {
+	sorted := append([]inferredParameter(nil), params...)
+	sort.SliceStable(sorted, func(i, j int) bool { return len(sorted[i].value) > len(sorted[j].value) })
+	pairs := make([]string, 0, 2*len(sorted))
+	for _, p := range sorted {
+		pairs = append(pairs, p.value, "{{"+p.Key+"}}")
+	}
+	return strings.NewReplacer(pairs...).Replace(text)
+}
+
+func describeRecipe(prompt, source string) string {
+	desc := "Distilled from a session"
+	if source != "" {
+		desc += " (" + source + ")"
+	}
+	return desc + ": " + truncateText(firstLine(prompt), 2*maxDistillTitle)
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(s), "\n")
+	return strings.TrimSpace(line)
+}
+
+func truncateText(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n]) + "..."
+}
+
+const refinePrompt = `Below is a recipe distilled automatically from a chat session, followed by the session transcript.
+Rewrite the title, description and instructions so the recipe reads as reusable guidance for running the same kind of task again.
+Keep every {{placeholder}} that appears in the recipe, and do not hard-code values that a placeholder stands for.
+
+Recipe:
+%s
+Transcript:
+%s
+Respond with JSON only: {"title": "...", "description": "...", "instructions": "..."}`
+
+// Refine asks a model to rewrite the title, description and instructions of a distilled recipe.
+// The recipe is left unchanged when the model fails or drops a placeholder.
+func Refine(ctx context.Context, p provider.Provider, r *Recipe, messages []types.Message) error {
+	data, err := r.ToYAML()
+	if err != nil {
+		return err
+	}
+	var transcript strings.Builder
+	for i := range messages {
+		msg := &messages[i]
+		text := msg.GetContent()
+		if msg.Role == types.RoleUser {
+			text = userText(msg)
+		}
+		if text != "" {
+			fmt.Fprintf(&transcript, "[%s] %s\n", msg.Role, text)
+		}
+	}
+
+	resp, err := p.Complete(ctx, []types.Message{{
+		Role:    types.RoleUser,
+		Content: fmt.Sprintf(refinePrompt, data, truncateText(transcript.String(), maxRefineTranscript)),
+	}}, &provider.StreamOptions{MaxTokens: 2000})
+	if err != nil {
+		return fmt.Errorf("refine recipe: %w", err)
+	}
+	text := resp.Message.GetContent()
+	start, end := strings.Index(text, "{"), strings.LastIndex(text, "}")
+	if start < 0 || end < start {
+		return errors.New("refine recipe: no JSON in model response")
+	}
+	var refined struct {
+		Title        string `json:"title"`
+		Description  string `json:"description"`
+		Instructions string `json:"instructions"`
+	}
+	if err := json.Unmarshal([]byte(text[start:end+1]), &refined); err != nil {
+		return fmt.Errorf("refine recipe: %w", err)
+	}
+	if refined.Title == "" || refined.Description == "" || refined.Instructions == "" {
+		return errors.New("refine recipe: incomplete model response")
+	}
+	for _, param := range r.Parameters {
+		placeholder := "{{" + param.Key + "}}"
+		if strings.Contains(r.Instructions, placeholder) && !strings.Contains(refined.Instructions, placeholder) {
+			return fmt.Errorf("refine recipe: model dropped placeholder %s", placeholder)
+		}
+	}
+	r.Title, r.Description, r.Instructions = refined.Title, refined.Description, refined.Instructions
+	return nil
+}
@@ -0,0 +1,119 @@
+package recipe
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/astercloud/aster/pkg/provider"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+func distillSession() []types.Message {
+	return []types.Message{
+		{Role: types.RoleUser, Content: "Summarize the changelog at https://example.com/changes.md since 2025-01-15 and write it to notes/summary.md"},
+		{Role: types.RoleAssistant, ContentBlocks: []types.ContentBlock{
+			&types.ToolUseBlock{ID: "1", Name: "WebFetch", Input: map[string]any{"url": "https://example.com/changes.md"}},
+		}},
+		{Role: types.RoleUser, ContentBlocks: []types.ContentBlock{
+			&types.ToolResultBlock{ToolUseID: "1", Content: "## 2025-02-01 ..."},
+		}},
+		{Role: types.RoleAssistant, ContentBlocks: []types.ContentBlock{
+			&types.ToolUseBlock{ID: "2", Name: "Bash", Input: map[string]any{"command": "missing-tool"}},
+		}},
+		{Role: types.RoleUser, ContentBlocks: []types.ContentBlock{
+			&types.ToolResultBlock{ToolUseID: "2", Content: "not found", IsError: true},
+		}},
+		{Role: types.RoleAssistant, ContentBlocks: []types.ContentBlock{
+			&types.ToolUseBlock{ID: "3", Name: "Write", Input: map[string]any{"file_path": "/work/notes/summary.md", "content": "summary"}},
+		}},
+		{Role: types.RoleUser, ContentBlocks: []types.ContentBlock{
+			&types.ToolResultBlock{ToolUseID: "3", Content: "ok"},
+		}},
+		{Role: types.RoleAssistant, Content: "Wrote the summary to notes/summary.md."},
+		{Role: types.RoleUser, Content: "Use bullet points"},
+		{Role: types.RoleAssistant, Content: "Done, the summary now uses bullet points."},
+	}
+}
+
+func TestDistill(t *testing.T) {
+	r, err := Distill(distillSession(), DistillOptions{Source: "sess-1"})
+	if err != nil {
+		t.Fatalf("Distill: %v", err)
+	}
+
+	if r.Prompt != "Summarize the changelog at {{url}} since {{date}} and write it to {{file_path}}" {
+		t.Errorf("prompt = %q", r.Prompt)
+	}
+	if strings.Join(r.Tools, ",") != "WebFetch,Write" {
+		t.Errorf("tools = %v", r.Tools)
+	}
+	want := map[string]string{"url": "https://example.com/changes.md", "date": "2025-01-15", "file_path": "notes/summary.md"}
+	if len(r.Parameters) != len(want) {
+		t.Fatalf("parameters = %+v", r.Parameters)
+	}
+	for _, p := range r.Parameters {
+		if want[p.Key] != p.Default || p.Requirement != ParamOptional {
+			t.Errorf("parameter %+v", p)
+		}
+		if p.Key == "date" && p.Type != ParamTypeDate {
+			t.Errorf("date parameter type = %s", p.Type)
+		}
+	}
+	for _, s := range []string{"- Use bullet points", "1. WebFetch: {{url}}", "2. Write: /work/{{file_path}}", "bullet points."} {
+		if !strings.Contains(r.Instructions, s) {
+			t.Errorf("instructions missing %q:\n%s", s, r.Instructions)
+		}
+	}
+	if strings.Contains(r.Instructions, "missing-tool") {
+		t.Error("failed tool call should not be a step")
+	}
+	if !strings.Contains(r.Description, "sess-1") || r.Title == "" {
+		t.Errorf("title = %q, description = %q", r.Title, r.Description)
+	}
+
+	// The distilled recipe loads back cleanly
+	data, _ := r.ToYAML()
+	if _, err := LoadFromBytes(data); err != nil {
+		t.Errorf("reload distilled recipe: %v", err)
+	}
+}
+
+func TestDistill_Unsuccessful(t *testing.T) {
+	messages := distillSession()[:3]
+	if _, err := Distill(messages, DistillOptions{}); !errors.Is(err, ErrUnsuccessfulSession) {
+		t.Errorf("Distill() error = %v, want ErrUnsuccessfulSession", err)
+	}
+}
+
+// refineProvider returns a fixed model response
+type refineProvider struct {
+	provider.Provider
+	response string
+}
+
+func (p *refineProvider) Complete(context.Context, []types.Message, *provider.StreamOptions) (*provider.CompleteResponse, error) {
+	return &provider.CompleteResponse{Message: types.Message{Role: types.RoleAssistant, Content: p.response}}, nil
+}
+
+func TestRefine(t *testing.T) {
+	r, err := Distill(distillSession(), DistillOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	original := r.Instructions
+
+	dropped := &refineProvider{response: `{"title": "T", "description": "D", "instructions": "Fetch the changelog."}`}
+	if err := Refine(context.Background(), dropped, r, distillSession()); err == nil || r.Instructions != original {
+		t.Errorf("refine dropping placeholders should fail and keep the recipe, err = %v", err)
+	}
+
+	good := &refineProvider{response: "Here it is:\n" + `{"title": "Changelog summary", "description": "Summarize a changelog", "instructions": "Fetch {{url}}, keep entries after {{date}}, write bullets to {{file_path}}."}`}
+	if err := Refine(context.Background(), good, r, distillSession()); err != nil {
+		t.Fatalf("Refine: %v", err)
+	}
+	if r.Title != "Changelog summary" || !strings.Contains(r.Instructions, "{{date}}") {
+		t.Errorf("refined recipe = %+v", r)
+	}
+}
@@ -0,0 +1,111 @@
+package recipe
+
+import (
+	"context"
+	"regexp"
+	"slices"
+	"strings"
+
+	astContext "github.com/astercloud/aster/pkg/context"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+// placeholderPattern matches {{key}} placeholders; block helpers such as {{#if x}} are ignored.
+var placeholderPattern = regexp.MustCompile(`\{\{\s*([A-Za-z_][\w.-]*)\s*\}\}`)
+
+// LintOptions configures Lint. Nil lookups skip the corresponding checks.
+type LintOptions struct {
+	// HasTool reports whether a tool name is registered
+	HasTool func(name string) bool
+	// HasTemplate reports whether a template ID is known
+	HasTemplate func(id string) bool
+}
+
+// Lint checks a recipe beyond Validate: tool and template references, placeholders
+// against declared parameters, and permission configuration. It also estimates the
+// token count of the instructions, prompt and seed conversation.
+func Lint(r *Recipe, opts LintOptions) *types.LintReport {
+	report := &types.LintReport{Name: r.Title}
+	if err := r.Validate(); err != nil {
+		report.Add(types.LintError, "invalid_recipe", "%v", err)
+	}
+
+	custom := make(map[string]bool, len(r.CustomTools))
+	for _, c := range r.CustomTools {
+		custom[c.Name] = true
+		if opts.HasTool != nil && opts.HasTool(c.Name) {
+			report.Add(types.LintWarning, "shadowed_tool", "custom tool %q shadows a registered tool", c.Name)
+		}
+	}
+	seen := make(map[string]bool, len(r.Tools))
+	for _, name := range r.Tools {
+		if seen[name] {
+			report.Add(types.LintWarning, "duplicate_tool", "tool %q is listed more than once", name)
+			continue
+		}
+		seen[name] = true
+		if !custom[name] && opts.HasTool != nil && !opts.HasTool(name) {
+			report.Add(types.LintError, "unknown_tool", "tool %q is not registered and is not a custom tool", name)
+		}
+	}
+	if r.TemplateID != "" && opts.HasTemplate != nil && !opts.HasTemplate(r.TemplateID) {
+		report.Add(types.LintError, "unknown_template", "template %q does not exist", r.TemplateID)
+	}
+
+	texts := []string{r.Instructions, r.Prompt}
+	if r.SeedConversation != nil {
+		for _, turn := range r.SeedConversation.Turns {
+			texts = append(texts, turn.User, turn.Assistant)
+		}
+	}
+	if r.Review != nil {
+		texts = append(texts, r.Review.Instructions)
+		texts = append(texts, r.Review.Rubric...)
+	}
+	lintPlaceholders(report, r.Parameters, texts)
+
+	switch r.PermissionMode {
+	case PermissionAutoApprove, PermissionSmartApprove, PermissionAlwaysAsk:
+	case "":
+		if len(r.Tools) > 0 || len(r.CustomTools) > 0 || len(r.Extensions) > 0 {
+			report.Add(types.LintWarning, "missing_permission", "permission_mode is not set; tools run with the template's permission settings")
+		}
+	default:
+		report.Add(types.LintError, "invalid_permission_mode", "unknown permission_mode %q", r.PermissionMode)
+	}
+
+	counter := astContext.NewSimpleTokenCounter(astContext.DefaultConfig)
+	report.PromptTokens, _ = counter.Count(context.Background(), strings.Join(texts, "\n\n"))
+	return report
+}
+
+// lintPlaceholders reports placeholders without a parameter, and parameters that are never used.
+func lintPlaceholders(report *types.LintReport, params []Parameter, texts []string) {
+	declared := make(map[string]bool, len(params))
+	for _, p := range params {
+		if declared[p.Key] {
+			report.Add(types.LintError, "duplicate_parameter", "parameter %q is declared more than once", p.Key)
+		}
+		declared[p.Key] = true
+	}
+
+	var used []string
+	for _, text := range texts {
+		for _, m := range placeholderPattern.FindAllStringSubmatch(text, -1) {
+			key := m[1]
+			if m[0] != "{{"+key+"}}" {
+				report.Add(types.LintWarning, "malformed_placeholder", "placeholder %q contains spaces and will not be substituted; use {{%s}}", m[0], key)
+			}
+			if !declared[key] {
+				report.Add(types.LintError, "undeclared_parameter", "placeholder {{%s}} has no matching parameter", key)
+				declared[key] = true // report each key once
+			}
+			used = append(used, key)
+		}
+	}
+	for _, p := range params {
+		if !slices.Contains(used, p.Key) {
+			report.Add(types.LintWarning, "unused_parameter", "parameter %q is not referenced by any {{placeholder}}", p.Key)
+		}
+	}
+}
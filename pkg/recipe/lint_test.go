@@ -0,0 +1,78 @@
+package recipe
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/astercloud/aster/pkg/types"
+)
+
+func TestLint(t *testing.T) {
+	registered := []string{"Read", "Bash"}
+	opts := LintOptions{
+		HasTool:     func(name string) bool { return slices.Contains(registered, name) },
+		HasTemplate: func(id string) bool { return id == "coder" },
+	}
+
+	clean, err := LoadFromBytes([]byte(`
+title: Release notes
+description: Summarize changes since a tag
+template_id: coder
+instructions: Summarize the commits since {{tag}}.
+prompt: Write release notes for {{tag}}
+tools: [Read, Bash, changelog]
+custom_tools:
+  - name: changelog
+    description: Print the changelog
+    command: cat CHANGELOG.md
+permission_mode: smart_approve
+parameters:
+  - key: tag
+    input_type: string
+    requirement: required
+    description: Previous release tag
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := Lint(clean, opts)
+	if len(report.Issues) != 0 {
+		t.Errorf("clean recipe issues = %v", report.Issues)
+	}
+	if report.PromptTokens == 0 {
+		t.Error("prompt tokens not estimated")
+	}
+
+	broken := &Recipe{
+		Title:        "Broken",
+		Description:  "Has problems",
+		TemplateID:   "writer",
+		Instructions: "Deploy {{service}} to {{ env }}",
+		Tools:        []string{"Read", "Deploy"},
+		Parameters: []Parameter{
+			{Key: "env", Type: ParamTypeString, Requirement: ParamRequired},
+			{Key: "region", Type: ParamTypeString, Requirement: ParamOptional},
+		},
+	}
+	report = Lint(broken, opts)
+	var errs, warnings []string
+	for _, issue := range report.Issues {
+		if issue.Severity == types.LintError {
+			errs = append(errs, issue.Code)
+		} else {
+			warnings = append(warnings, issue.Code)
+		}
+	}
+	if want := []string{"unknown_tool", "unknown_template", "undeclared_parameter"}; !slices.Equal(errs, want) {
+		t.Errorf("errors = %v, want %v", errs, want)
+	}
+	if want := []string{"malformed_placeholder", "unused_parameter", "missing_permission"}; !slices.Equal(warnings, want) {
+		t.Errorf("warnings = %v, want %v", warnings, want)
+	}
+
+	// Without lookups only the recipe itself is checked.
+	report = Lint(&Recipe{Title: "x", Description: "y", Tools: []string{"Anything"}, PermissionMode: "yolo"}, LintOptions{})
+	if len(report.Issues) != 1 || report.Issues[0].Code != "invalid_permission_mode" {
+		t.Errorf("issues = %v", report.Issues)
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/astercloud/aster/pkg/types"
 	"gopkg.in/yaml.v3"
 )
 
@@ -34,12 +35,19 @@ type Recipe struct {
 	// Prompt is the initial message to send to the agent
 	Prompt string `yaml:"prompt,omitempty" json:"prompt,omitempty"`
 
+	// SeedConversation declares user/assistant exemplar turns injected ahead of the
+	// live conversation on every request, for tasks that benefit from few-shot grounding
+	SeedConversation *types.SeedConversationConfig `yaml:"seed_conversation,omitempty" json:"seed_conversation,omitempty"`
+
 	// Tools is a list of tool names to enable
 	Tools []string `yaml:"tools,omitempty" json:"tools,omitempty"`
 
 	// Extensions defines MCP extensions to load
 	Extensions []ExtensionConfig `yaml:"extensions,omitempty" json:"extensions,omitempty"`
 
+	// CustomTools declares tools backed by shell commands or HTTP endpoints
+	CustomTools []CustomTool `yaml:"custom_tools,omitempty" json:"custom_tools,omitempty"`
+
 	// Parameters defines user-configurable parameters
 	Parameters []Parameter `yaml:"parameters,omitempty" json:"parameters,omitempty"`
 
@@ -54,6 +62,22 @@ type Recipe struct {
 
 	// PermissionMode controls tool approval behavior
 	PermissionMode PermissionMode `yaml:"permission_mode,omitempty" json:"permission_mode,omitempty"`
+
+	// Review enables a generator-critic loop: a reviewer agent checks each draft
+	// against the rubric and the draft is revised until it is approved
+	Review *ReviewConfig `yaml:"review,omitempty" json:"review,omitempty"`
+}
+
+// ReviewConfig configures the critic of a generator-critic loop.
+type ReviewConfig struct {
+	// Rubric lists the criteria every draft must meet
+	Rubric []string `yaml:"rubric" json:"rubric"`
+
+	// MaxIterations limits the number of drafts (default 3)
+	MaxIterations int `yaml:"max_iterations,omitempty" json:"max_iterations,omitempty"`
+
+	// Instructions are extra guidance for the critic, e.g. how strict to be
+	Instructions string `yaml:"instructions,omitempty" json:"instructions,omitempty"`
 }
 
 // ExtensionConfig defines an MCP extension.
@@ -220,6 +244,45 @@ func (r *Recipe) Validate() error {
 		}
 	}
 
+	// Validate seed conversation
+	if sc := r.SeedConversation; sc != nil {
+		if sc.MaxTokens < 0 {
+			return errors.New("seed_conversation: max_tokens must not be negative")
+		}
+		for i, turn := range sc.Turns {
+			if strings.TrimSpace(turn.User) == "" || strings.TrimSpace(turn.Assistant) == "" {
+				return fmt.Errorf("seed_conversation turn %d: user and assistant are required", i+1)
+			}
+		}
+	}
+
+	// Validate review
+	if rv := r.Review; rv != nil {
+		if len(rv.Rubric) == 0 {
+			return errors.New("review: rubric is required")
+		}
+		for i, criterion := range rv.Rubric {
+			if strings.TrimSpace(criterion) == "" {
+				return fmt.Errorf("review: rubric item %d is empty", i+1)
+			}
+		}
+		if rv.MaxIterations < 0 {
+			return errors.New("review: max_iterations must not be negative")
+		}
+	}
+
+	// Validate custom tools
+	seen := make(map[string]bool, len(r.CustomTools))
+	for _, c := range r.CustomTools {
+		if err := c.Validate(); err != nil {
+			return fmt.Errorf("custom tool %q: %w", c.Name, err)
+		}
+		if seen[c.Name] {
+			return fmt.Errorf("custom tool %q: duplicate name", c.Name)
+		}
+		seen[c.Name] = true
+	}
+
 	return nil
 }
 
@@ -299,6 +362,23 @@ func (r *Recipe) ApplyParameters(values map[string]string) error {
 		r.Prompt = substituteParams(r.Prompt, values)
 	}
 
+	// Substitute in seed conversation turns
+	if r.SeedConversation != nil {
+		for i := range r.SeedConversation.Turns {
+			turn := &r.SeedConversation.Turns[i]
+			turn.User = substituteParams(turn.User, values)
+			turn.Assistant = substituteParams(turn.Assistant, values)
+		}
+	}
+
+	// Substitute in review rubric and instructions
+	if r.Review != nil {
+		for i := range r.Review.Rubric {
+			r.Review.Rubric[i] = substituteParams(r.Review.Rubric[i], values)
+		}
+		r.Review.Instructions = substituteParams(r.Review.Instructions, values)
+	}
+
 	return nil
 }
 
@@ -383,6 +463,15 @@ func (b *Builder) Prompt(prompt string) *Builder {
 	return b
 }
 
+// AddSeedTurn adds a user/assistant exemplar turn to the seed conversation.
+func (b *Builder) AddSeedTurn(user, assistant string) *Builder {
+	if b.recipe.SeedConversation == nil {
+		b.recipe.SeedConversation = &types.SeedConversationConfig{}
+	}
+	b.recipe.SeedConversation.Turns = append(b.recipe.SeedConversation.Turns, types.SeedTurn{User: user, Assistant: assistant})
+	return b
+}
+
 // Tools sets the enabled tools.
 func (b *Builder) Tools(tools ...string) *Builder {
 	b.recipe.Tools = tools
@@ -395,12 +484,24 @@ func (b *Builder) AddExtension(ext ExtensionConfig) *Builder {
 	return b
 }
 
+// AddCustomTool adds a command or HTTP backed tool.
+func (b *Builder) AddCustomTool(tool CustomTool) *Builder {
+	b.recipe.CustomTools = append(b.recipe.CustomTools, tool)
+	return b
+}
+
 // AddParameter adds a configurable parameter.
 func (b *Builder) AddParameter(param Parameter) *Builder {
 	b.recipe.Parameters = append(b.recipe.Parameters, param)
 	return b
 }
 
+// Review enables a generator-critic loop with the given rubric.
+func (b *Builder) Review(maxIterations int, rubric ...string) *Builder {
+	b.recipe.Review = &ReviewConfig{Rubric: rubric, MaxIterations: maxIterations}
+	return b
+}
+
 // PermissionMode sets the permission mode.
 func (b *Builder) PermissionMode(mode PermissionMode) *Builder {
 	b.recipe.PermissionMode = mode
@@ -2,6 +2,8 @@ package recipe
 
 import (
 	"testing"
+
+	"github.com/astercloud/aster/pkg/types"
 )
 
 func TestLoadFromBytes(t *testing.T) {
@@ -370,3 +372,81 @@ func TestToYAML(t *testing.T) {
 		t.Errorf("Round-trip failed: expected title %q, got %q", recipe.Title, parsed.Title)
 	}
 }
+
+func TestSeedConversation(t *testing.T) {
+	yaml := `
+version: "1.0"
+title: Commit writer
+description: Writes commit messages in house style
+seed_conversation:
+  max_tokens: 2000
+  exclude_from_compaction: true
+  turns:
+    - user: "Summarize this {{vcs}} diff: fix nil check"
+      assistant: "Fix nil pointer check in parser"
+`
+	recipe, err := LoadFromBytes([]byte(yaml))
+	if err != nil {
+		t.Fatalf("LoadFromBytes failed: %v", err)
+	}
+	sc := recipe.SeedConversation
+	if sc == nil || len(sc.Turns) != 1 || sc.MaxTokens != 2000 || !sc.ExcludeFromCompaction {
+		t.Fatalf("unexpected seed conversation: %+v", sc)
+	}
+
+	if err := recipe.ApplyParameters(map[string]string{"vcs": "git"}); err != nil {
+		t.Fatalf("ApplyParameters failed: %v", err)
+	}
+	if sc.Turns[0].User != "Summarize this git diff: fix nil check" {
+		t.Errorf("parameters not applied to seed turn: %q", sc.Turns[0].User)
+	}
+
+	sc.Turns = append(sc.Turns, types.SeedTurn{User: "missing answer"})
+	if err := recipe.Validate(); err == nil {
+		t.Error("expected error for seed turn without assistant")
+	}
+
+	built, err := NewBuilder().Title("T").Description("D").AddSeedTurn("q", "a").Build()
+	if err != nil || len(built.SeedConversation.Turns) != 1 {
+		t.Errorf("AddSeedTurn() = %+v, %v", built, err)
+	}
+}
+
+func TestReviewConfig(t *testing.T) {
+	yaml := `
+version: "1.0"
+title: Release notes
+description: Drafts release notes and reviews them
+review:
+  max_iterations: 4
+  instructions: Be strict about {{audience}} tone
+  rubric:
+    - Mentions every breaking change
+    - Written for {{audience}}
+`
+	recipe, err := LoadFromBytes([]byte(yaml))
+	if err != nil {
+		t.Fatalf("LoadFromBytes failed: %v", err)
+	}
+	rv := recipe.Review
+	if rv == nil || len(rv.Rubric) != 2 || rv.MaxIterations != 4 {
+		t.Fatalf("unexpected review config: %+v", rv)
+	}
+
+	if err := recipe.ApplyParameters(map[string]string{"audience": "end users"}); err != nil {
+		t.Fatalf("ApplyParameters failed: %v", err)
+	}
+	if rv.Rubric[1] != "Written for end users" || rv.Instructions != "Be strict about end users tone" {
+		t.Errorf("parameters not applied to review: %+v", rv)
+	}
+
+	rv.Rubric = nil
+	if err := recipe.Validate(); err == nil {
+		t.Error("expected error for review without rubric")
+	}
+
+	built, err := NewBuilder().Title("T").Description("D").Review(2, "correct").Build()
+	if err != nil || built.Review.MaxIterations != 2 || built.Review.Rubric[0] != "correct" {
+		t.Errorf("Review() = %+v, %v", built, err)
+	}
+}
@@ -0,0 +1,141 @@
+// Package requestctx 在 context 中传递单次请求的元数据（用户、租户、关联 ID、语言）
+// 元数据由 Server 或 CLI 写入，经 Agent 循环传到工具、Provider、事件和日志，
+// 使多用户部署中的每个动作都能归属到具体的用户和请求
+package requestctx
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// 传递元数据的 HTTP 请求头
+const (
+	HeaderUserID        = "X-User-ID"
+	HeaderTenantID      = "X-Tenant-ID"
+	HeaderCorrelationID = "X-Correlation-ID"
+)
+
+// Metadata 单次请求的元数据
+type Metadata struct {
+	UserID   string `json:"user_id,omitempty"`
+	TenantID string `json:"tenant_id,omitempty"`
+	// CorrelationID 关联 ID，跨服务追踪同一次请求，未指定时由入口生成
+	CorrelationID string `json:"correlation_id,omitempty"`
+	// Locale 用户语言，如 zh-CN、en-US
+	Locale string `json:"locale,omitempty"`
+}
+
+// IsZero 是否没有任何字段
+func (m Metadata) IsZero() bool {
+	return m == Metadata{}
+}
+
+type metadataKey struct{}
+
+// With 返回携带元数据的 context，空字段沿用 ctx 中已有的值
+func With(ctx context.Context, m Metadata) context.Context {
+	if prev, ok := From(ctx); ok {
+		if m.UserID == "" {
+			m.UserID = prev.UserID
+		}
+		if m.TenantID == "" {
+			m.TenantID = prev.TenantID
+		}
+		if m.CorrelationID == "" {
+			m.CorrelationID = prev.CorrelationID
+		}
+		if m.Locale == "" {
+			m.Locale = prev.Locale
+		}
+	}
+	return context.WithValue(ctx, metadataKey{}, m)
+}
+
+// From 读取 context 中的元数据
+func From(ctx context.Context) (Metadata, bool) {
+	if ctx == nil {
+		return Metadata{}, false
+	}
+	m, ok := ctx.Value(metadataKey{}).(Metadata)
+	return m, ok
+}
+
+// Detach 返回携带 ctx 元数据、但不随 ctx 取消的 context
+// 用于请求结束后仍在运行的后台处理
+func Detach(ctx context.Context) context.Context {
+	detached := context.Background()
+	if m, ok := From(ctx); ok {
+		detached = With(detached, m)
+	}
+	return detached
+}
+
+// FromRequest 从入站 HTTP 请求头读取关联 ID 和语言，未携带关联 ID 时使用 fallbackID
+// 用户和租户应来自认证结果，不从请求头读取
+func FromRequest(r *http.Request, fallbackID string) Metadata {
+	m := Metadata{
+		CorrelationID: r.Header.Get(HeaderCorrelationID),
+		Locale:        parseAcceptLanguage(r.Header.Get("Accept-Language")),
+	}
+	if m.CorrelationID == "" {
+		m.CorrelationID = fallbackID
+	}
+	return m
+}
+
+// parseAcceptLanguage 返回 Accept-Language 中的第一个语言
+func parseAcceptLanguage(value string) string {
+	first, _, _ := strings.Cut(value, ",")
+	tag, _, _ := strings.Cut(first, ";")
+	tag = strings.TrimSpace(tag)
+	if tag == "*" {
+		return ""
+	}
+	return tag
+}
+
+// Local 返回本地 CLI 会话的元数据：当前系统用户、LANG 中的语言和新的关联 ID
+func Local() Metadata {
+	user := os.Getenv("USER")
+	if user == "" {
+		user = os.Getenv("USERNAME")
+	}
+	return Metadata{
+		UserID:        user,
+		CorrelationID: uuid.New().String(),
+		Locale:        localeFromEnv(),
+	}
+}
+
+// localeFromEnv 从 LC_ALL、LANG 读取语言，如 zh_CN.UTF-8 → zh-CN
+func localeFromEnv() string {
+	for _, key := range []string{"LC_ALL", "LANG"} {
+		value, _, _ := strings.Cut(os.Getenv(key), ".")
+		if value != "" && value != "C" && value != "POSIX" {
+			return strings.ReplaceAll(value, "_", "-")
+		}
+	}
+	return ""
+}
+
+// Headers 返回转发给下游服务的请求头
+// includeIdentity 为 false 时只转发关联 ID，不向第三方暴露用户和租户
+func (m Metadata) Headers(includeIdentity bool) map[string]string {
+	headers := make(map[string]string, 3)
+	if m.CorrelationID != "" {
+		headers[HeaderCorrelationID] = m.CorrelationID
+	}
+	if includeIdentity {
+		if m.UserID != "" {
+			headers[HeaderUserID] = m.UserID
+		}
+		if m.TenantID != "" {
+			headers[HeaderTenantID] = m.TenantID
+		}
+	}
+	return headers
+}
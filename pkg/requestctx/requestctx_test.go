@@ -0,0 +1,57 @@
+package requestctx
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWithMergesFields(t *testing.T) {
+	ctx := With(context.Background(), Metadata{CorrelationID: "req-1", Locale: "en-US"})
+	ctx = With(ctx, Metadata{UserID: "u1", TenantID: "acme"})
+
+	m, ok := From(ctx)
+	if !ok {
+		t.Fatal("expected metadata in context")
+	}
+	want := Metadata{UserID: "u1", TenantID: "acme", CorrelationID: "req-1", Locale: "en-US"}
+	if m != want {
+		t.Errorf("expected %+v, got %+v", want, m)
+	}
+}
+
+func TestDetachKeepsMetadata(t *testing.T) {
+	ctx, cancel := context.WithCancel(With(context.Background(), Metadata{UserID: "u1"}))
+	cancel()
+
+	detached := Detach(ctx)
+	if detached.Err() != nil {
+		t.Error("expected detached context not to be canceled")
+	}
+	if m, _ := From(detached); m.UserID != "u1" {
+		t.Errorf("expected metadata to be kept, got %+v", m)
+	}
+}
+
+func TestFromRequest(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Accept-Language", "zh-CN,zh;q=0.9,en;q=0.8")
+	if m := FromRequest(r, "req-1"); m.CorrelationID != "req-1" || m.Locale != "zh-CN" {
+		t.Errorf("unexpected metadata %+v", m)
+	}
+
+	r.Header.Set(HeaderCorrelationID, "upstream-7")
+	if m := FromRequest(r, "req-1"); m.CorrelationID != "upstream-7" {
+		t.Errorf("expected upstream correlation ID, got %q", m.CorrelationID)
+	}
+}
+
+func TestHeaders(t *testing.T) {
+	m := Metadata{UserID: "u1", TenantID: "acme", CorrelationID: "req-1"}
+	if h := m.Headers(false); len(h) != 1 || h[HeaderCorrelationID] != "req-1" {
+		t.Errorf("expected only correlation header, got %v", h)
+	}
+	if h := m.Headers(true); h[HeaderUserID] != "u1" || h[HeaderTenantID] != "acme" {
+		t.Errorf("expected identity headers, got %v", h)
+	}
+}
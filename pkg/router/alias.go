@@ -0,0 +1,203 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"strings"
+	"sync"
+
+	"github.com/astercloud/aster/pkg/types"
+)
+
+// 常用任务类型，用于按任务覆盖模型。
+const (
+	TaskChat      = "chat"
+	TaskPlan      = "plan"
+	TaskExecute   = "execute"
+	TaskSummarize = "summarize"
+	TaskTitle     = "title"
+)
+
+// 常用逻辑别名。
+const (
+	AliasFast  = "fast"
+	AliasSmart = "smart"
+	AliasCheap = "cheap"
+)
+
+// priorityAliases Priority 到默认逻辑别名的映射，
+// 在没有任务覆盖时，用于根据路由偏好挑选别名。
+var priorityAliases = map[Priority]string{
+	PriorityLatency: AliasFast,
+	PriorityQuality: AliasSmart,
+	PriorityCost:    AliasCheap,
+}
+
+// Resolver 能够把模型名称（逻辑别名或 provider/model）解析为 ModelConfig 的路由器。
+// Agent 在运行中切换模型时会优先使用该接口。
+type Resolver interface {
+	Resolve(name string) (*types.ModelConfig, error)
+}
+
+// TaskResolver 支持按任务显式覆盖模型的路由器。
+// 只有显式配置了覆盖的任务才返回 ok=true，其余任务沿用 Agent 的主模型。
+type TaskResolver interface {
+	Resolver
+	TaskModel(task string) (*types.ModelConfig, bool)
+}
+
+// AliasConfig 别名路由配置。
+type AliasConfig struct {
+	// Aliases 逻辑别名 -> 具体模型，比如 "fast" -> anthropic/claude-haiku-4-5。
+	Aliases map[string]*types.ModelConfig `json:"aliases" yaml:"aliases"`
+	// Default 默认别名（或 provider/model），没有其他规则匹配时使用。
+	Default string `json:"default" yaml:"default"`
+	// Tasks 任务 -> 别名的覆盖表，比如 "summarize" -> "cheap"。
+	Tasks map[string]string `json:"tasks,omitempty" yaml:"tasks,omitempty"`
+}
+
+// AliasRouter 基于逻辑别名的路由器。
+// 匹配规则：
+//  1. Task 命中 Tasks 覆盖表时，使用对应别名。
+//  2. Priority 对应的默认别名（latency→fast、quality→smart、cost→cheap）已定义时使用该别名。
+//  3. 否则使用 Default。
+type AliasRouter struct {
+	mu      sync.RWMutex
+	aliases map[string]*types.ModelConfig
+	tasks   map[string]string
+	def     string
+}
+
+var _ Router = (*AliasRouter)(nil)
+var _ TaskResolver = (*AliasRouter)(nil)
+
+// NewAliasRouter 创建别名路由器，并校验所有任务覆盖和默认别名都可解析。
+func NewAliasRouter(cfg *AliasConfig) (*AliasRouter, error) {
+	if cfg == nil {
+		return nil, errors.New("alias config is nil")
+	}
+
+	r := &AliasRouter{
+		aliases: make(map[string]*types.ModelConfig, len(cfg.Aliases)),
+		tasks:   make(map[string]string, len(cfg.Tasks)),
+		def:     cfg.Default,
+	}
+	for name, model := range cfg.Aliases {
+		if model == nil || model.Provider == "" || model.Model == "" {
+			return nil, fmt.Errorf("alias %q: provider and model are required", name)
+		}
+		r.aliases[name] = model
+	}
+	maps.Copy(r.tasks, cfg.Tasks)
+
+	if r.def == "" {
+		return nil, errors.New("default alias is required")
+	}
+	if _, err := r.Resolve(r.def); err != nil {
+		return nil, fmt.Errorf("default: %w", err)
+	}
+	for task, alias := range r.tasks {
+		if _, err := r.Resolve(alias); err != nil {
+			return nil, fmt.Errorf("task %q: %w", task, err)
+		}
+	}
+
+	return r, nil
+}
+
+// Resolve 把逻辑别名或 "provider/model" 解析为 ModelConfig。
+// 对 provider/model 形式，如果某个别名使用同一 provider，则沿用其 APIKey/BaseURL。
+func (r *AliasRouter) Resolve(name string) (*types.ModelConfig, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.resolveLocked(name)
+}
+
+func (r *AliasRouter) resolveLocked(name string) (*types.ModelConfig, error) {
+	if model, ok := r.aliases[name]; ok {
+		return model, nil
+	}
+
+	providerName, modelName, ok := ParseModelRef(name)
+	if !ok {
+		return nil, fmt.Errorf("unknown model alias %q", name)
+	}
+	resolved := &types.ModelConfig{Provider: providerName, Model: modelName}
+	for _, model := range r.aliases {
+		if model.Provider == providerName {
+			resolved.APIKey = model.APIKey
+			resolved.BaseURL = model.BaseURL
+			break
+		}
+	}
+	return resolved, nil
+}
+
+// SelectModel 实现 Router 接口。
+func (r *AliasRouter) SelectModel(_ context.Context, intent *RouteIntent) (*types.ModelConfig, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if intent != nil {
+		if alias, ok := r.tasks[intent.Task]; ok {
+			return r.resolveLocked(alias)
+		}
+		if alias, ok := priorityAliases[intent.Priority]; ok {
+			if model, ok := r.aliases[alias]; ok {
+				return model, nil
+			}
+		}
+	}
+	return r.resolveLocked(r.def)
+}
+
+// TaskModel 返回任务的显式覆盖模型。
+func (r *AliasRouter) TaskModel(task string) (*types.ModelConfig, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	alias, ok := r.tasks[task]
+	if !ok {
+		return nil, false
+	}
+	model, err := r.resolveLocked(alias)
+	if err != nil {
+		return nil, false
+	}
+	return model, true
+}
+
+// SetTaskAlias 运行时修改任务覆盖；alias 为空时移除覆盖。
+func (r *AliasRouter) SetTaskAlias(task, alias string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if alias == "" {
+		delete(r.tasks, task)
+		return nil
+	}
+	if _, err := r.resolveLocked(alias); err != nil {
+		return err
+	}
+	r.tasks[task] = alias
+	return nil
+}
+
+// Aliases 返回已定义的逻辑别名副本。
+func (r *AliasRouter) Aliases() map[string]*types.ModelConfig {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return maps.Clone(r.aliases)
+}
+
+// ParseModelRef 解析 "provider/model" 形式的模型引用。
+// 模型名本身可以包含 "/"（例如 OpenRouter 的 "openrouter/anthropic/claude-sonnet-4"）。
+func ParseModelRef(ref string) (providerName, modelName string, ok bool) {
+	providerName, modelName, ok = strings.Cut(ref, "/")
+	if !ok || providerName == "" || modelName == "" {
+		return "", "", false
+	}
+	return providerName, modelName, true
+}
@@ -0,0 +1,105 @@
+package router
+
+import (
+	"context"
+	"testing"
+
+	"github.com/astercloud/aster/pkg/types"
+)
+
+func newTestAliasRouter(t *testing.T) *AliasRouter {
+	t.Helper()
+	r, err := NewAliasRouter(&AliasConfig{
+		Aliases: map[string]*types.ModelConfig{
+			AliasFast:  {Provider: "anthropic", Model: "claude-haiku-4-5", APIKey: "k1"},
+			AliasSmart: {Provider: "anthropic", Model: "claude-opus-4", APIKey: "k1"},
+			AliasCheap: {Provider: "deepseek", Model: "deepseek-chat", APIKey: "k2"},
+		},
+		Default: AliasSmart,
+		Tasks: map[string]string{
+			TaskSummarize: AliasCheap,
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewAliasRouter failed: %v", err)
+	}
+	return r
+}
+
+func TestAliasRouterSelectModel(t *testing.T) {
+	r := newTestAliasRouter(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name   string
+		intent *RouteIntent
+		want   string
+	}{
+		{"nil intent uses default", nil, "claude-opus-4"},
+		{"task override", &RouteIntent{Task: TaskSummarize}, "deepseek-chat"},
+		{"priority alias", &RouteIntent{Task: TaskChat, Priority: PriorityLatency}, "claude-haiku-4-5"},
+		{"fallback default", &RouteIntent{Task: TaskChat}, "claude-opus-4"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := r.SelectModel(ctx, tt.intent)
+			if err != nil {
+				t.Fatalf("SelectModel failed: %v", err)
+			}
+			if m.Model != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, m.Model)
+			}
+		})
+	}
+}
+
+func TestAliasRouterResolveModelRef(t *testing.T) {
+	r := newTestAliasRouter(t)
+
+	m, err := r.Resolve("deepseek/deepseek-reasoner")
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+	if m.Provider != "deepseek" || m.Model != "deepseek-reasoner" || m.APIKey != "k2" {
+		t.Errorf("unexpected resolved model %+v", m)
+	}
+
+	if _, err := r.Resolve("nope"); err == nil {
+		t.Error("expected error for unknown alias")
+	}
+}
+
+func TestAliasRouterTaskOverrides(t *testing.T) {
+	r := newTestAliasRouter(t)
+
+	if _, ok := r.TaskModel(TaskPlan); ok {
+		t.Error("plan task should not have an override")
+	}
+	if err := r.SetTaskAlias(TaskPlan, AliasSmart); err != nil {
+		t.Fatalf("SetTaskAlias failed: %v", err)
+	}
+	if m, ok := r.TaskModel(TaskPlan); !ok || m.Model != "claude-opus-4" {
+		t.Errorf("expected plan override, got %+v", m)
+	}
+	if err := r.SetTaskAlias(TaskPlan, "missing"); err == nil {
+		t.Error("expected error for unknown alias")
+	}
+	if err := r.SetTaskAlias(TaskPlan, ""); err != nil {
+		t.Fatalf("clearing task alias failed: %v", err)
+	}
+	if _, ok := r.TaskModel(TaskPlan); ok {
+		t.Error("plan override should be cleared")
+	}
+}
+
+func TestNewAliasRouterValidation(t *testing.T) {
+	if _, err := NewAliasRouter(&AliasConfig{Default: "missing"}); err == nil {
+		t.Error("expected error for unresolvable default")
+	}
+	if _, err := NewAliasRouter(&AliasConfig{
+		Aliases: map[string]*types.ModelConfig{"fast": {Provider: "anthropic"}},
+		Default: "fast",
+	}); err == nil {
+		t.Error("expected error for alias without model")
+	}
+}
@@ -8,6 +8,8 @@ import (
 	"io"
 	"net/http"
 	"time"
+
+	"github.com/astercloud/aster/pkg/httpclient"
 )
 
 // MCPClient MCP 协议客户端
@@ -39,9 +41,7 @@ func NewMCPClient(config *MCPClientConfig) *MCPClient {
 		accessKeyID:     config.AccessKeyID,
 		accessKeySecret: config.AccessKeySecret,
 		securityToken:   config.SecurityToken,
-		httpClient: &http.Client{
-			Timeout: config.Timeout,
-		},
+		httpClient:      httpclient.New(config.Timeout),
 	}
 }
 
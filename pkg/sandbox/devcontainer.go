@@ -0,0 +1,395 @@
+package sandbox
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// devContainerPaths devcontainer.json 的查找位置（相对工作区）
+var devContainerPaths = []string{
+	filepath.Join(".devcontainer", "devcontainer.json"),
+	".devcontainer.json",
+}
+
+// DevContainerConfig devcontainer.json 中沙箱使用的字段
+// 参考 https://containers.dev/implementors/json_reference/
+type DevContainerConfig struct {
+	Name            string             `json:"name,omitempty"`
+	Image           string             `json:"image,omitempty"`
+	Build           *DevContainerBuild `json:"build,omitempty"`
+	DockerFile      string             `json:"dockerFile,omitempty"` // 旧格式，等同 build.dockerfile
+	Context         string             `json:"context,omitempty"`    // 旧格式，等同 build.context
+	WorkspaceFolder string             `json:"workspaceFolder,omitempty"`
+	WorkspaceMount  string             `json:"workspaceMount,omitempty"`
+	ContainerEnv    map[string]string  `json:"containerEnv,omitempty"`
+	RemoteEnv       map[string]string  `json:"remoteEnv,omitempty"`
+	ContainerUser   string             `json:"containerUser,omitempty"`
+	RemoteUser      string             `json:"remoteUser,omitempty"`
+	RunArgs         []string           `json:"runArgs,omitempty"`
+	Mounts          []json.RawMessage  `json:"mounts,omitempty"`
+	OverrideCommand *bool              `json:"overrideCommand,omitempty"`
+	Features        map[string]any     `json:"features,omitempty"`
+	Compose         json.RawMessage    `json:"dockerComposeFile,omitempty"`
+	OnCreate        LifecycleCommand   `json:"onCreateCommand,omitempty"`
+	UpdateContent   LifecycleCommand   `json:"updateContentCommand,omitempty"`
+	PostCreate      LifecycleCommand   `json:"postCreateCommand,omitempty"`
+	PostStart       LifecycleCommand   `json:"postStartCommand,omitempty"`
+
+	// 以下字段由 LoadDevContainer 填充
+	ConfigPath    string `json:"-"` // devcontainer.json 绝对路径
+	WorkspacePath string `json:"-"` // 宿主机工作区绝对路径
+}
+
+// DevContainerBuild 镜像构建配置
+type DevContainerBuild struct {
+	Dockerfile string            `json:"dockerfile,omitempty"`
+	Context    string            `json:"context,omitempty"`
+	Args       map[string]string `json:"args,omitempty"`
+	Target     string            `json:"target,omitempty"`
+	CacheFrom  stringList        `json:"cacheFrom,omitempty"`
+}
+
+// LifecycleCommand 生命周期命令
+// 字符串通过 shell 执行，数组直接执行，对象中的每个命令依次执行
+type LifecycleCommand struct {
+	Shell string
+	Args  []string
+	Named map[string]LifecycleCommand
+}
+
+// UnmarshalJSON 支持字符串、数组和对象三种格式
+func (c *LifecycleCommand) UnmarshalJSON(data []byte) error {
+	switch strings.TrimSpace(string(data))[0] {
+	case '"':
+		return json.Unmarshal(data, &c.Shell)
+	case '[':
+		return json.Unmarshal(data, &c.Args)
+	case '{':
+		return json.Unmarshal(data, &c.Named)
+	case 'n':
+		return nil
+	}
+	return fmt.Errorf("invalid lifecycle command: %s", data)
+}
+
+// IsZero 是否未配置命令
+func (c LifecycleCommand) IsZero() bool {
+	return c.Shell == "" && len(c.Args) == 0 && len(c.Named) == 0
+}
+
+// Commands 展开为待执行的命令列表，每项为 exec 参数
+// shell 字符串展开为 ["/bin/sh", "-c", cmd]，对象按名称排序保证执行顺序稳定
+func (c LifecycleCommand) Commands() [][]string {
+	switch {
+	case c.Shell != "":
+		return [][]string{{"/bin/sh", "-c", c.Shell}}
+	case len(c.Args) > 0:
+		return [][]string{c.Args}
+	}
+	var commands [][]string
+	names := make([]string, 0, len(c.Named))
+	for name := range c.Named {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		commands = append(commands, c.Named[name].Commands()...)
+	}
+	return commands
+}
+
+// stringList 兼容字符串或字符串数组
+type stringList []string
+
+func (l *stringList) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*l = stringList{single}
+		return nil
+	}
+	return json.Unmarshal(data, (*[]string)(l))
+}
+
+// FindDevContainer 在工作区中查找 devcontainer.json，未找到返回空字符串
+func FindDevContainer(workspace string) string {
+	for _, rel := range devContainerPaths {
+		p := filepath.Join(workspace, rel)
+		if info, err := os.Stat(p); err == nil && !info.IsDir() {
+			return p
+		}
+	}
+	return ""
+}
+
+// LoadDevContainer 读取并解析 devcontainer.json（支持注释和尾随逗号），
+// 替换 ${localWorkspaceFolder} 等变量
+func LoadDevContainer(configPath, workspace string) (*DevContainerConfig, error) {
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("read devcontainer.json: %w", err)
+	}
+	var config DevContainerConfig
+	if err := json.Unmarshal(stripJSONC(data), &config); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", configPath, err)
+	}
+	if len(config.Compose) > 0 {
+		return nil, errors.New("devcontainer.json: dockerComposeFile is not supported, use image or build")
+	}
+
+	config.ConfigPath, _ = filepath.Abs(configPath)
+	config.WorkspacePath, _ = filepath.Abs(workspace)
+	if config.Build == nil && config.DockerFile != "" {
+		config.Build = &DevContainerBuild{Dockerfile: config.DockerFile, Context: config.Context}
+	}
+	if config.Image == "" && (config.Build == nil || config.Build.Dockerfile == "") {
+		return nil, errors.New("devcontainer.json: image or build.dockerfile is required")
+	}
+	if config.WorkspaceFolder == "" {
+		config.WorkspaceFolder = "/workspaces/" + filepath.Base(config.WorkspacePath)
+	}
+	config.substitute()
+	return &config, nil
+}
+
+// substitute 替换配置中的变量
+func (c *DevContainerConfig) substitute() {
+	// workspaceFolder 本身可以引用本地变量，需要先替换
+	c.WorkspaceFolder = c.expand(c.WorkspaceFolder)
+	c.Image = c.expand(c.Image)
+	c.WorkspaceMount = c.expand(c.WorkspaceMount)
+	for k, v := range c.ContainerEnv {
+		c.ContainerEnv[k] = c.expand(v)
+	}
+	for k, v := range c.RemoteEnv {
+		c.RemoteEnv[k] = c.expand(v)
+	}
+	for i, arg := range c.RunArgs {
+		c.RunArgs[i] = c.expand(arg)
+	}
+	if c.Build != nil {
+		for k, v := range c.Build.Args {
+			c.Build.Args[k] = c.expand(v)
+		}
+	}
+}
+
+var devContainerVarPattern = regexp.MustCompile(`\$\{([^}]+)\}`)
+
+// expand 替换 ${localWorkspaceFolder}、${containerWorkspaceFolder}、
+// ${localWorkspaceFolderBasename}、${containerWorkspaceFolderBasename}、${localEnv:VAR[:default]}
+func (c *DevContainerConfig) expand(s string) string {
+	return devContainerVarPattern.ReplaceAllStringFunc(s, func(match string) string {
+		name := match[2 : len(match)-1]
+		switch name {
+		case "localWorkspaceFolder":
+			return c.WorkspacePath
+		case "localWorkspaceFolderBasename":
+			return filepath.Base(c.WorkspacePath)
+		case "containerWorkspaceFolder":
+			return c.WorkspaceFolder
+		case "containerWorkspaceFolderBasename":
+			return path.Base(c.WorkspaceFolder)
+		}
+		if rest, ok := strings.CutPrefix(name, "localEnv:"); ok {
+			key, def, _ := strings.Cut(rest, ":")
+			if v, ok := os.LookupEnv(key); ok {
+				return v
+			}
+			return def
+		}
+		// 未知变量（如 containerEnv:VAR）在容器内才能解析，保持原样
+		return match
+	})
+}
+
+// MountArgs 转换 mounts 为 docker run 参数
+// 字符串格式原样作为 --mount 参数，对象格式转换为 type=...,source=...,target=...
+func (c *DevContainerConfig) MountArgs() ([]string, error) {
+	var args []string
+	for _, raw := range c.Mounts {
+		var spec string
+		if err := json.Unmarshal(raw, &spec); err != nil {
+			var mount struct {
+				Type   string `json:"type"`
+				Source string `json:"source"`
+				Target string `json:"target"`
+			}
+			if err := json.Unmarshal(raw, &mount); err != nil {
+				return nil, fmt.Errorf("invalid mount %s: %w", raw, err)
+			}
+			spec = "type=" + mount.Type + ",target=" + mount.Target
+			if mount.Source != "" {
+				spec += ",source=" + mount.Source
+			}
+		}
+		args = append(args, "--mount", c.expand(spec))
+	}
+	return args, nil
+}
+
+// hostNamespaceFlags 取值为 host 时共享宿主机命名空间的 docker run 选项
+var hostNamespaceFlags = map[string]bool{
+	"--pid": true, "--network": true, "--net": true, "--ipc": true, "--uts": true, "--userns": true, "--cgroupns": true,
+}
+
+// escapeCapabilities 足以逃逸容器的能力
+var escapeCapabilities = map[string]bool{
+	"ALL": true, "SYS_ADMIN": true, "SYS_MODULE": true, "SYS_RAWIO": true, "SYS_BOOT": true,
+	"DAC_READ_SEARCH": true, "NET_ADMIN": true, "BPF": true,
+}
+
+// protectedHostPaths 不允许挂载进容器的宿主机路径（其子目录可以挂载）
+var protectedHostPaths = []string{
+	"/", "/root", "/home", "/usr", "/var", "/run", "/var/run/docker.sock", "/run/docker.sock",
+}
+
+// protectedHostTrees 自身及其下任何路径都不允许挂载的宿主机目录
+var protectedHostTrees = []string{"/etc", "/proc", "/sys", "/dev", "/boot"}
+
+// CheckHostAccess 拒绝让容器获得宿主机权限的配置：特权模式、宿主机命名空间、
+// 危险能力、设备透传以及挂载宿主机系统目录
+// devcontainer.json 来自工作区，不能信任其中的 runArgs、mounts 和 workspaceMount
+func (c *DevContainerConfig) CheckHostAccess() error {
+	args := slices.Clone(c.RunArgs)
+	if c.WorkspaceMount != "" {
+		args = append(args, "--mount", c.WorkspaceMount)
+	}
+	mounts, err := c.MountArgs()
+	if err != nil {
+		return err
+	}
+	args = append(args, mounts...)
+
+	for i := 0; i < len(args); i++ {
+		flag, value, hasValue := strings.Cut(args[i], "=")
+		if !hasValue && i+1 < len(args) && runArgTakesValue(flag) {
+			i++
+			value = args[i]
+		}
+		switch {
+		case flag == "--privileged":
+			return errors.New("devcontainer.json: --privileged is not allowed")
+		case hostNamespaceFlags[flag] && value == "host":
+			return fmt.Errorf("devcontainer.json: %s=host is not allowed", flag)
+		case flag == "--cap-add" && escapeCapabilities[strings.TrimPrefix(strings.ToUpper(value), "CAP_")]:
+			return fmt.Errorf("devcontainer.json: --cap-add=%s is not allowed", value)
+		case flag == "--device":
+			return errors.New("devcontainer.json: --device is not allowed")
+		case flag == "-v" || flag == "--volume":
+			source, _, _ := strings.Cut(value, ":")
+			if isProtectedHostPath(source) {
+				return fmt.Errorf("devcontainer.json: mounting %s is not allowed", source)
+			}
+		case flag == "--mount":
+			if source := mountSource(value); isProtectedHostPath(source) {
+				return fmt.Errorf("devcontainer.json: mounting %s is not allowed", source)
+			}
+		}
+	}
+	return nil
+}
+
+// runArgTakesValue 选项是否以下一个参数作为取值
+func runArgTakesValue(flag string) bool {
+	return hostNamespaceFlags[flag] || flag == "--cap-add" || flag == "--device" ||
+		flag == "-v" || flag == "--volume" || flag == "--mount"
+}
+
+// mountSource 返回 --mount 参数中的宿主机路径
+func mountSource(spec string) string {
+	for field := range strings.SplitSeq(spec, ",") {
+		key, value, _ := strings.Cut(field, "=")
+		if key == "source" || key == "src" {
+			return value
+		}
+	}
+	return ""
+}
+
+// isProtectedHostPath 宿主机路径是否为系统目录或 Docker socket（命名卷不是路径）
+func isProtectedHostPath(source string) bool {
+	if !strings.HasPrefix(source, "/") {
+		return false
+	}
+	source = filepath.Clean(source)
+	if slices.Contains(protectedHostPaths, source) {
+		return true
+	}
+	return slices.ContainsFunc(protectedHostTrees, func(dir string) bool { return isWithinDir(source, dir) })
+}
+
+// BuildPaths 返回 Dockerfile 和构建上下文的绝对路径（相对 devcontainer.json 所在目录）
+func (c *DevContainerConfig) BuildPaths() (dockerfile, buildContext string) {
+	dir := filepath.Dir(c.ConfigPath)
+	dockerfile = filepath.Join(dir, c.Build.Dockerfile)
+	buildContext = dir
+	if c.Build.Context != "" {
+		buildContext = filepath.Join(dir, c.Build.Context)
+	}
+	return dockerfile, buildContext
+}
+
+// ExecUser 执行命令使用的用户，remoteUser 优先于 containerUser
+func (c *DevContainerConfig) ExecUser() string {
+	if c.RemoteUser != "" {
+		return c.RemoteUser
+	}
+	return c.ContainerUser
+}
+
+// stripJSONC 移除 JSON 中的 // 和 /* */ 注释以及尾随逗号
+func stripJSONC(data []byte) []byte {
+	out := make([]byte, 0, len(data))
+	inString := false
+	for i := 0; i < len(data); i++ {
+		ch := data[i]
+		if inString {
+			out = append(out, ch)
+			if ch == '\\' && i+1 < len(data) {
+				i++
+				out = append(out, data[i])
+			} else if ch == '"' {
+				inString = false
+			}
+			continue
+		}
+		switch {
+		case ch == '"':
+			inString = true
+			out = append(out, ch)
+		case ch == '/' && i+1 < len(data) && data[i+1] == '/':
+			for i < len(data) && data[i] != '\n' {
+				i++
+			}
+			if i < len(data) {
+				out = append(out, '\n')
+			}
+		case ch == '/' && i+1 < len(data) && data[i+1] == '*':
+			i += 2
+			for i+1 < len(data) && (data[i] != '*' || data[i+1] != '/') {
+				i++
+			}
+			i++
+		case ch == '}' || ch == ']':
+			// 移除尾随逗号
+			j := len(out) - 1
+			for j >= 0 && (out[j] == ' ' || out[j] == '\t' || out[j] == '\n' || out[j] == '\r') {
+				j--
+			}
+			if j >= 0 && out[j] == ',' {
+				out = append(out[:j], out[j+1:]...)
+			}
+			out = append(out, ch)
+		default:
+			out = append(out, ch)
+		}
+	}
+	return out
+}
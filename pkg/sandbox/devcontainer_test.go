@@ -0,0 +1,161 @@
+package sandbox
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// writeDevContainer 在工作区写入 .devcontainer/devcontainer.json
+func writeDevContainer(t *testing.T, workspace, content string) string {
+	t.Helper()
+	dir := filepath.Join(workspace, ".devcontainer")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	p := filepath.Join(dir, "devcontainer.json")
+	if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestLoadDevContainer(t *testing.T) {
+	workspace := filepath.Join(t.TempDir(), "myproj")
+	t.Setenv("ASTER_DC_TOKEN", "secret")
+	p := writeDevContainer(t, workspace, `{
+  // Go toolchain
+  "name": "go",
+  "build": {
+    "dockerfile": "Dockerfile",
+    "context": "..",
+    "args": { "GO_VERSION": "1.23", "TOKEN": "${localEnv:ASTER_DC_TOKEN}" },
+  },
+  /* block comment with "quotes" */
+  "containerEnv": { "SRC": "${containerWorkspaceFolder}/src", "URL": "http://example.com//x" },
+  "remoteEnv": { "MISSING": "${localEnv:ASTER_DC_MISSING:fallback}" },
+  "remoteUser": "vscode",
+  "mounts": ["source=cache,target=/cache,type=volume", { "type": "bind", "source": "${localWorkspaceFolder}/.cache", "target": "/root/.cache" }],
+  "postCreateCommand": ["go", "mod", "download"],
+  "postStartCommand": { "b": "echo b", "a": ["echo", "a"] },
+}`)
+
+	if got := FindDevContainer(workspace); got != p {
+		t.Fatalf("FindDevContainer = %q, want %q", got, p)
+	}
+
+	dc, err := LoadDevContainer(p, workspace)
+	if err != nil {
+		t.Fatalf("LoadDevContainer: %v", err)
+	}
+	if dc.WorkspaceFolder != "/workspaces/myproj" {
+		t.Errorf("default workspaceFolder = %q", dc.WorkspaceFolder)
+	}
+	if dc.ContainerEnv["SRC"] != "/workspaces/myproj/src" || dc.ContainerEnv["URL"] != "http://example.com//x" {
+		t.Errorf("containerEnv = %v", dc.ContainerEnv)
+	}
+	if dc.Build.Args["TOKEN"] != "secret" || dc.RemoteEnv["MISSING"] != "fallback" {
+		t.Errorf("localEnv substitution failed: args=%v remoteEnv=%v", dc.Build.Args, dc.RemoteEnv)
+	}
+	if dc.ExecUser() != "vscode" {
+		t.Errorf("ExecUser = %q", dc.ExecUser())
+	}
+
+	dockerfile, buildContext := dc.BuildPaths()
+	if dockerfile != filepath.Join(workspace, ".devcontainer", "Dockerfile") || buildContext != workspace {
+		t.Errorf("BuildPaths = %q, %q", dockerfile, buildContext)
+	}
+
+	mounts, err := dc.MountArgs()
+	if err != nil {
+		t.Fatalf("MountArgs: %v", err)
+	}
+	want := []string{
+		"--mount", "source=cache,target=/cache,type=volume",
+		"--mount", "type=bind,target=/root/.cache,source=" + workspace + "/.cache",
+	}
+	if !slices.Equal(mounts, want) {
+		t.Errorf("MountArgs = %v, want %v", mounts, want)
+	}
+
+	if got := dc.PostCreate.Commands(); len(got) != 1 || !slices.Equal(got[0], []string{"go", "mod", "download"}) {
+		t.Errorf("postCreateCommand = %v", got)
+	}
+	if got := dc.PostStart.Commands(); len(got) != 2 || !slices.Equal(got[0], []string{"echo", "a"}) || !slices.Equal(got[1], []string{"/bin/sh", "-c", "echo b"}) {
+		t.Errorf("postStartCommand = %v", got)
+	}
+	if !dc.OnCreate.IsZero() {
+		t.Error("onCreateCommand should be empty")
+	}
+}
+
+func TestLoadDevContainer_Errors(t *testing.T) {
+	workspace := t.TempDir()
+
+	for name, content := range map[string]string{
+		"compose":  `{"dockerComposeFile": "docker-compose.yml", "service": "app"}`,
+		"no image": `{"name": "empty"}`,
+		"invalid":  `{"image": }`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			p := writeDevContainer(t, workspace, content)
+			if _, err := LoadDevContainer(p, workspace); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+
+	if got := FindDevContainer(t.TempDir()); got != "" {
+		t.Errorf("FindDevContainer in empty workspace = %q", got)
+	}
+}
+
+func TestDevContainer_CheckHostAccess(t *testing.T) {
+	allowed := []*DevContainerConfig{
+		{RunArgs: []string{"--cap-add=SYS_PTRACE", "--security-opt", "seccomp=unconfined", "--network", "bridge"}},
+		{WorkspaceMount: "source=/home/dev/proj,target=/workspace,type=bind"},
+		{Mounts: []json.RawMessage{json.RawMessage(`"source=node_modules,target=/workspace/node_modules,type=volume"`)}},
+		{RunArgs: []string{"-v", "/home/dev/cache:/cache"}},
+	}
+	for _, c := range allowed {
+		if err := c.CheckHostAccess(); err != nil {
+			t.Errorf("%+v should be allowed: %v", c, err)
+		}
+	}
+
+	denied := []*DevContainerConfig{
+		{RunArgs: []string{"--privileged"}},
+		{RunArgs: []string{"--pid=host"}},
+		{RunArgs: []string{"--network", "host"}},
+		{RunArgs: []string{"--cap-add", "SYS_ADMIN"}},
+		{RunArgs: []string{"--device=/dev/sda"}},
+		{RunArgs: []string{"-v", "/:/host"}},
+		{RunArgs: []string{"--volume=/var/run/docker.sock:/var/run/docker.sock"}},
+		{WorkspaceMount: "type=bind,source=/,target=/workspace"},
+		{Mounts: []json.RawMessage{json.RawMessage(`{"type": "bind", "source": "/etc", "target": "/host-etc"}`)}},
+	}
+	for _, c := range denied {
+		if err := c.CheckHostAccess(); err == nil {
+			t.Errorf("%+v should be rejected", c)
+		}
+	}
+
+	workspace := t.TempDir()
+	writeDevContainer(t, workspace, `{"image": "alpine", "runArgs": ["--privileged"]}`)
+	if _, err := NewDockerSandbox(&DockerSandboxConfig{WorkDir: workspace}); err == nil {
+		t.Error("NewDockerSandbox should reject a privileged devcontainer")
+	}
+}
+
+func TestStripJSONC(t *testing.T) {
+	in := `{"a": "// not a comment", /* c */ "b": [1, 2,], // trailing
+"c": "\"/*x*/\"",}`
+	got := strings.Join(strings.Fields(string(stripJSONC([]byte(in)))), "")
+	want := `{"a":"//notacomment","b":[1,2],"c":"\"/*x*/\""}`
+	if got != want {
+		t.Errorf("stripJSONC = %s, want %s", got, want)
+	}
+}
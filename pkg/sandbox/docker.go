@@ -0,0 +1,500 @@
+package sandbox
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"maps"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/astercloud/aster/pkg/sandbox/shellrisk"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+// DefaultContainerWorkDir 未使用 devcontainer 时容器内的工作区路径
+const DefaultContainerWorkDir = "/workspace"
+
+// keepAliveScript 覆盖镜像默认命令，保持容器运行以便执行命令
+const keepAliveScript = "trap 'exit 0' TERM INT; while sleep 1000; do :; done"
+
+// DockerSandboxConfig Docker 沙箱配置
+type DockerSandboxConfig struct {
+	WorkDir         string
+	EnforceBoundary bool
+	AllowPaths      []string
+	WatchFiles      bool
+	Settings        *types.SandboxSettings
+
+	// Image 未使用 devcontainer 时的容器镜像
+	Image string
+	// ContainerWorkDir 容器内工作区路径，默认 /workspace（devcontainer 使用 workspaceFolder）
+	ContainerWorkDir string
+	// DevContainer devcontainer.json 路径，为空时在工作区中自动查找
+	DevContainer string
+	// DisableDevContainer 忽略工作区中的 devcontainer.json
+	DisableDevContainer bool
+	// Env 容器内命令的额外环境变量
+	Env map[string]string
+	// Network 容器网络（docker run --network）
+	Network string
+	// KeepContainer Dispose 时保留容器
+	KeepContainer bool
+	// Resources 容器资源限制（docker run --cpus/--memory/--pids-limit）
+	Resources *types.ResourceLimits
+	// DockerPath docker 命令路径，默认从 PATH 查找
+	DockerPath string
+}
+
+// dockerRunner 执行 docker 命令，测试可替换
+type dockerRunner func(ctx context.Context, args ...string) (*ExecResult, error)
+
+// DockerSandbox Docker 容器沙箱
+// 工作区以 bind mount 挂载到容器中，文件操作和监听在宿主机上进行，命令在容器内执行；
+// 工作区存在 devcontainer.json 时按其配置构建镜像、创建容器并执行生命周期命令
+type DockerSandbox struct {
+	*LocalSandbox
+
+	config           *DockerSandboxConfig
+	devContainer     *DevContainerConfig
+	image            string
+	containerWorkDir string
+	user             string
+	env              map[string]string
+	docker           dockerRunner
+
+	mu          sync.Mutex
+	containerID string
+	shell       string
+}
+
+// NewDockerSandbox 创建 Docker 沙箱，容器在首次执行命令时启动
+func NewDockerSandbox(config *DockerSandboxConfig) (*DockerSandbox, error) {
+	if config == nil {
+		config = &DockerSandboxConfig{}
+	}
+
+	// 容器负责隔离，不使用宿主机的 OS 级隔离
+	settings := config.Settings
+	if settings != nil {
+		copied := *settings
+		copied.OSIsolation = types.OSIsolationOff
+		settings = &copied
+	}
+	local, err := NewLocalSandbox(&LocalSandboxConfig{
+		WorkDir:         config.WorkDir,
+		EnforceBoundary: config.EnforceBoundary,
+		AllowPaths:      config.AllowPaths,
+		WatchFiles:      config.WatchFiles,
+		Settings:        settings,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ds := &DockerSandbox{
+		LocalSandbox:     local,
+		config:           config,
+		image:            config.Image,
+		containerWorkDir: config.ContainerWorkDir,
+		env:              maps.Clone(config.Env),
+	}
+	if ds.env == nil {
+		ds.env = make(map[string]string)
+	}
+	ds.docker = ds.runDocker
+
+	if !config.DisableDevContainer {
+		configPath := config.DevContainer
+		if configPath == "" {
+			configPath = FindDevContainer(local.workDir)
+		} else if !filepath.IsAbs(configPath) {
+			configPath = filepath.Join(local.workDir, configPath)
+		}
+		if configPath != "" {
+			dc, err := LoadDevContainer(configPath, local.workDir)
+			if err != nil {
+				return nil, err
+			}
+			if err := dc.CheckHostAccess(); err != nil {
+				return nil, err
+			}
+			ds.applyDevContainer(dc)
+		}
+	}
+
+	if ds.image == "" && ds.devContainer == nil {
+		return nil, errors.New("docker sandbox requires an image or a devcontainer.json in the workspace")
+	}
+	if ds.containerWorkDir == "" {
+		ds.containerWorkDir = DefaultContainerWorkDir
+	}
+
+	sandboxLogger.Info(context.Background(), "DockerSandbox created", map[string]any{
+		"workDir":          local.workDir,
+		"containerWorkDir": ds.containerWorkDir,
+		"image":            ds.image,
+		"devContainer":     ds.devContainer != nil,
+	})
+
+	return ds, nil
+}
+
+// applyDevContainer 应用 devcontainer.json 配置
+func (ds *DockerSandbox) applyDevContainer(dc *DevContainerConfig) {
+	ds.devContainer = dc
+	if dc.Image != "" && dc.Build == nil {
+		ds.image = dc.Image
+	}
+	if ds.config.ContainerWorkDir == "" {
+		ds.containerWorkDir = dc.WorkspaceFolder
+	}
+	ds.user = dc.ExecUser()
+	for k, v := range dc.RemoteEnv {
+		if _, ok := ds.env[k]; !ok {
+			ds.env[k] = v
+		}
+	}
+	if len(dc.Features) > 0 {
+		sandboxLogger.Warn(context.Background(), "devcontainer features are not supported and will be ignored", map[string]any{
+			"config": dc.ConfigPath,
+		})
+	}
+}
+
+// Kind 返回沙箱类型
+func (ds *DockerSandbox) Kind() string {
+	return "docker"
+}
+
+// DevContainer 返回使用的 devcontainer 配置，未使用时为 nil
+func (ds *DockerSandbox) DevContainer() *DevContainerConfig {
+	return ds.devContainer
+}
+
+// ContainerID 返回容器 ID，容器未启动时为空
+func (ds *DockerSandbox) ContainerID() string {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	return ds.containerID
+}
+
+// ContainerWorkDir 返回容器内的工作区路径
+func (ds *DockerSandbox) ContainerWorkDir() string {
+	return ds.containerWorkDir
+}
+
+// Start 构建镜像（如需）、创建容器并执行 devcontainer 生命周期命令，已启动时直接返回
+func (ds *DockerSandbox) Start(ctx context.Context) error {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if ds.containerID != "" {
+		return nil
+	}
+
+	image := ds.image
+	if dc := ds.devContainer; dc != nil && dc.Build != nil {
+		built, err := ds.buildImage(ctx, dc)
+		if err != nil {
+			return err
+		}
+		image = built
+	}
+
+	args, err := ds.runArgs(image)
+	if err != nil {
+		return err
+	}
+	result, err := ds.docker(ctx, args...)
+	if err != nil {
+		return fmt.Errorf("start container: %w", err)
+	}
+	if result.Code != 0 {
+		return fmt.Errorf("start container: %s", strings.TrimSpace(result.Stderr))
+	}
+	ds.containerID = strings.TrimSpace(result.Stdout)
+
+	if err := ds.runLifecycle(ctx); err != nil {
+		ds.removeContainer()
+		return err
+	}
+
+	// 优先使用 bash，精简镜像回退到 sh
+	ds.shell = "/bin/sh"
+	if probe, err := ds.docker(ctx, "exec", ds.containerID, "/bin/sh", "-c", "command -v bash"); err == nil && probe.Code == 0 {
+		if bash := strings.TrimSpace(probe.Stdout); bash != "" {
+			ds.shell = bash
+		}
+	}
+
+	sandboxLogger.Info(ctx, "Docker sandbox container started", map[string]any{
+		"container": ds.containerID,
+		"image":     image,
+	})
+	return nil
+}
+
+// buildImage 按 devcontainer.json 的 build 配置构建镜像，标签由配置路径决定以复用构建缓存
+func (ds *DockerSandbox) buildImage(ctx context.Context, dc *DevContainerConfig) (string, error) {
+	sum := sha256.Sum256([]byte(dc.ConfigPath))
+	tag := "aster-devcontainer-" + hex.EncodeToString(sum[:])[:12]
+
+	dockerfile, buildContext := dc.BuildPaths()
+	args := []string{"build", "-f", dockerfile, "-t", tag}
+	for _, k := range slices.Sorted(maps.Keys(dc.Build.Args)) {
+		args = append(args, "--build-arg", k+"="+dc.Build.Args[k])
+	}
+	if dc.Build.Target != "" {
+		args = append(args, "--target", dc.Build.Target)
+	}
+	for _, cache := range dc.Build.CacheFrom {
+		args = append(args, "--cache-from", cache)
+	}
+	args = append(args, buildContext)
+
+	sandboxLogger.Info(ctx, "Building dev container image", map[string]any{
+		"dockerfile": dockerfile,
+		"tag":        tag,
+	})
+	result, err := ds.docker(ctx, args...)
+	if err != nil {
+		return "", fmt.Errorf("build dev container image: %w", err)
+	}
+	if result.Code != 0 {
+		return "", fmt.Errorf("build dev container image: %s", lastLines(result.Stderr, 20))
+	}
+	return tag, nil
+}
+
+// runArgs 构建 docker run 参数
+func (ds *DockerSandbox) runArgs(image string) ([]string, error) {
+	args := []string{"run", "-d", "--label", "aster.sandbox=docker", "--label", "aster.workspace=" + ds.workDir}
+
+	dc := ds.devContainer
+	if dc != nil && dc.WorkspaceMount != "" {
+		args = append(args, "--mount", dc.WorkspaceMount)
+	} else {
+		args = append(args, "-v", ds.workDir+":"+ds.containerWorkDir)
+	}
+	args = append(args, "-w", ds.containerWorkDir)
+	if ds.config.Network != "" {
+		args = append(args, "--network", ds.config.Network)
+	}
+	if r := ds.config.Resources; r != nil {
+		if r.CPUQuota > 0 {
+			args = append(args, "--cpus", strconv.FormatFloat(r.CPUQuota, 'f', -1, 64))
+		}
+		if r.MemoryLimit > 0 {
+			args = append(args, "--memory", strconv.FormatInt(r.MemoryLimit, 10))
+		}
+		if r.MaxProcesses > 0 {
+			args = append(args, "--pids-limit", strconv.Itoa(r.MaxProcesses))
+		}
+	}
+
+	overrideCommand := true
+	if dc != nil {
+		for _, k := range slices.Sorted(maps.Keys(dc.ContainerEnv)) {
+			args = append(args, "-e", k+"="+dc.ContainerEnv[k])
+		}
+		if dc.ContainerUser != "" {
+			args = append(args, "-u", dc.ContainerUser)
+		}
+		mounts, err := dc.MountArgs()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, mounts...)
+		args = append(args, dc.RunArgs...)
+		if dc.OverrideCommand != nil {
+			overrideCommand = *dc.OverrideCommand
+		}
+	}
+
+	if overrideCommand {
+		return append(args, "--entrypoint", "/bin/sh", image, "-c", keepAliveScript), nil
+	}
+	return append(args, image), nil
+}
+
+// runLifecycle 依次执行 devcontainer 生命周期命令
+func (ds *DockerSandbox) runLifecycle(ctx context.Context) error {
+	dc := ds.devContainer
+	if dc == nil {
+		return nil
+	}
+	stages := []struct {
+		name    string
+		command LifecycleCommand
+	}{
+		{"onCreateCommand", dc.OnCreate},
+		{"updateContentCommand", dc.UpdateContent},
+		{"postCreateCommand", dc.PostCreate},
+		{"postStartCommand", dc.PostStart},
+	}
+	for _, stage := range stages {
+		for _, command := range stage.command.Commands() {
+			result, err := ds.docker(ctx, ds.execArgs(ds.containerWorkDir, nil, command...)...)
+			if err != nil {
+				return fmt.Errorf("%s: %w", stage.name, err)
+			}
+			if result.Code != 0 {
+				return fmt.Errorf("%s failed with exit code %d: %s", stage.name, result.Code, lastLines(result.Stderr+result.Stdout, 20))
+			}
+		}
+	}
+	return nil
+}
+
+// execArgs 构建 docker exec 参数
+func (ds *DockerSandbox) execArgs(workDir string, env map[string]string, command ...string) []string {
+	args := []string{"exec", "-w", workDir}
+	if ds.user != "" {
+		args = append(args, "-u", ds.user)
+	}
+	merged := maps.Clone(ds.env)
+	maps.Copy(merged, env)
+	for _, k := range slices.Sorted(maps.Keys(merged)) {
+		args = append(args, "-e", k+"="+merged[k])
+	}
+	args = append(args, ds.containerID)
+	return append(args, command...)
+}
+
+// containerPath 将宿主机工作区内的路径映射为容器内路径
+func (ds *DockerSandbox) containerPath(workDir string) string {
+	// 已经是容器内路径
+	if path.IsAbs(workDir) && (workDir == ds.containerWorkDir || strings.HasPrefix(workDir, ds.containerWorkDir+"/")) {
+		return workDir
+	}
+	hostDir := workDir
+	if !filepath.IsAbs(hostDir) || !pathWithin(ds.workDir, hostDir, runtime.GOOS == "windows") {
+		hostDir = ds.fs.Resolve(workDir)
+	}
+	rel, err := filepath.Rel(ds.workDir, hostDir)
+	if err != nil || rel == "." {
+		return ds.containerWorkDir
+	}
+	return path.Join(ds.containerWorkDir, filepath.ToSlash(rel))
+}
+
+// Exec 在容器内执行命令
+// 容器提供隔离，只阻止严重级别的危险命令
+func (ds *DockerSandbox) Exec(ctx context.Context, cmd string, opts *ExecOptions) (*ExecResult, error) {
+	startTime := time.Now()
+
+	if analysis := shellrisk.Analyze(cmd); analysis.Level >= shellrisk.LevelCritical {
+		reason := analysis.Reason()
+		ds.recordAudit(cmd, opts, nil, startTime, true, reason)
+		return &ExecResult{
+			Code:   1,
+			Stdout: "",
+			Stderr: "Dangerous command blocked: " + reason,
+		}, nil
+	}
+
+	if err := ds.Start(ctx); err != nil {
+		return nil, fmt.Errorf("docker sandbox: %w", err)
+	}
+
+	timeout := 120 * time.Second
+	if opts != nil && opts.Timeout > 0 {
+		timeout = opts.Timeout
+	}
+	execCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	workDir := ds.containerWorkDir
+	env := map[string]string{}
+	if opts != nil {
+		if opts.WorkDir != "" {
+			workDir = ds.containerPath(opts.WorkDir)
+		}
+		for k, v := range opts.Env {
+			if !ds.isDangerousEnvVar(k) {
+				env[k] = v
+			}
+		}
+	}
+
+	result, err := ds.docker(execCtx, ds.execArgs(workDir, env, ds.shell, "-c", cmd)...)
+	if err != nil {
+		return nil, err
+	}
+	if execCtx.Err() != nil && result.Code != 0 {
+		result.Stderr += fmt.Sprintf("\ncommand timed out after %v", timeout)
+	}
+
+	ds.recordAudit(cmd, opts, result, startTime, false, "")
+	ds.updateStats(ds.extractCommandName(cmd), result, time.Since(startTime))
+	return result, nil
+}
+
+// Dispose 停止文件监听并删除容器
+func (ds *DockerSandbox) Dispose() error {
+	err := ds.LocalSandbox.Dispose()
+	if !ds.config.KeepContainer {
+		ds.mu.Lock()
+		ds.removeContainer()
+		ds.mu.Unlock()
+	}
+	return err
+}
+
+// removeContainer 删除容器，调用方需持有 ds.mu
+func (ds *DockerSandbox) removeContainer() {
+	if ds.containerID == "" {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if result, err := ds.docker(ctx, "rm", "-f", ds.containerID); err != nil || result.Code != 0 {
+		sandboxLogger.Warn(ctx, "Failed to remove docker sandbox container", map[string]any{
+			"container": ds.containerID,
+			"error":     err,
+		})
+	}
+	ds.containerID = ""
+}
+
+// runDocker 调用 docker CLI
+func (ds *DockerSandbox) runDocker(ctx context.Context, args ...string) (*ExecResult, error) {
+	dockerPath := ds.config.DockerPath
+	if dockerPath == "" {
+		dockerPath = "docker"
+	}
+	command := exec.CommandContext(ctx, dockerPath, args...)
+	var stdout, stderr bytes.Buffer
+	command.Stdout = &stdout
+	command.Stderr = &stderr
+
+	err := command.Run()
+	result := &ExecResult{Stdout: stdout.String(), Stderr: stderr.String()}
+	if err != nil {
+		exitErr := &exec.ExitError{}
+		if !errors.As(err, &exitErr) {
+			return nil, fmt.Errorf("run docker: %w", err)
+		}
+		result.Code = exitErr.ExitCode()
+	}
+	return result, nil
+}
+
+// lastLines 返回文本的最后 n 行
+func lastLines(s string, n int) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,224 @@
+package sandbox
+
+import (
+	"context"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/astercloud/aster/pkg/types"
+)
+
+// fakeDocker 记录 docker 调用并返回预设结果
+type fakeDocker struct {
+	mu    sync.Mutex
+	calls [][]string
+	// fail 返回非零退出码的子命令前缀（如 "exec"）
+	fail string
+}
+
+func (f *fakeDocker) run(ctx context.Context, args ...string) (*ExecResult, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, args)
+	if f.fail != "" && strings.HasPrefix(strings.Join(args, " "), f.fail) {
+		return &ExecResult{Code: 1, Stderr: "boom"}, nil
+	}
+	switch args[0] {
+	case "run":
+		return &ExecResult{Stdout: "c0ffee\n"}, nil
+	case "exec":
+		if slices.Contains(args, "command -v bash") {
+			return &ExecResult{Stdout: "/usr/bin/bash\n"}, nil
+		}
+		return &ExecResult{Stdout: "ok\n"}, nil
+	}
+	return &ExecResult{}, nil
+}
+
+// find 返回第一个以指定参数开头的调用
+func (f *fakeDocker) find(prefix ...string) []string {
+	for _, call := range f.calls {
+		if len(call) >= len(prefix) && slices.Equal(call[:len(prefix)], prefix) {
+			return call
+		}
+	}
+	return nil
+}
+
+func newFakeDockerSandbox(t *testing.T, config *DockerSandboxConfig) (*DockerSandbox, *fakeDocker) {
+	t.Helper()
+	ds, err := NewDockerSandbox(config)
+	if err != nil {
+		t.Fatalf("NewDockerSandbox: %v", err)
+	}
+	fake := &fakeDocker{}
+	ds.docker = fake.run
+	return ds, fake
+}
+
+func TestDockerSandbox_RequiresImage(t *testing.T) {
+	if _, err := NewDockerSandbox(&DockerSandboxConfig{WorkDir: t.TempDir()}); err == nil {
+		t.Error("expected error without image or devcontainer.json")
+	}
+}
+
+func TestDockerSandbox_Image(t *testing.T) {
+	workDir := t.TempDir()
+	ds, fake := newFakeDockerSandbox(t, &DockerSandboxConfig{
+		WorkDir:   workDir,
+		Image:     "golang:1.23",
+		Network:   "none",
+		Env:       map[string]string{"CI": "1"},
+		Resources: &types.ResourceLimits{CPUQuota: 1.5, MemoryLimit: 512 << 20, MaxProcesses: 64},
+	})
+
+	result, err := ds.Exec(context.Background(), "go version", &ExecOptions{
+		WorkDir: "sub/dir",
+		Env:     map[string]string{"FOO": "bar", "LD_PRELOAD": "/evil.so"},
+	})
+	if err != nil || result.Code != 0 {
+		t.Fatalf("Exec: %+v %v", result, err)
+	}
+	if ds.ContainerID() != "c0ffee" {
+		t.Errorf("ContainerID = %q", ds.ContainerID())
+	}
+
+	run := strings.Join(fake.find("run"), " ")
+	for _, want := range []string{"-v " + workDir + ":/workspace", "-w /workspace", "--network none", "--cpus 1.5", "--memory 536870912", "--pids-limit 64", "--entrypoint /bin/sh golang:1.23 -c"} {
+		if !strings.Contains(run, want) {
+			t.Errorf("run args missing %q: %s", want, run)
+		}
+	}
+
+	last := fake.calls[len(fake.calls)-1]
+	want := []string{"exec", "-w", "/workspace/sub/dir", "-e", "CI=1", "-e", "FOO=bar", "c0ffee", "/usr/bin/bash", "-c", "go version"}
+	if !slices.Equal(last, want) {
+		t.Errorf("exec args = %v, want %v", last, want)
+	}
+
+	// 已启动的容器被复用
+	_, _ = ds.Exec(context.Background(), "ls", &ExecOptions{WorkDir: filepath.Join(workDir, "pkg")})
+	runs := 0
+	for _, call := range fake.calls {
+		if call[0] == "run" {
+			runs++
+		}
+	}
+	if runs != 1 {
+		t.Errorf("container started %d times", runs)
+	}
+	if got := fake.calls[len(fake.calls)-1][2]; got != "/workspace/pkg" {
+		t.Errorf("host work dir should map into the container, got %q", got)
+	}
+
+	if err := ds.Dispose(); err != nil {
+		t.Fatalf("Dispose: %v", err)
+	}
+	if fake.find("rm", "-f", "c0ffee") == nil {
+		t.Error("Dispose should remove the container")
+	}
+}
+
+func TestDockerSandbox_DevContainer(t *testing.T) {
+	workspace := filepath.Join(t.TempDir(), "proj")
+	writeDevContainer(t, workspace, `{
+  "build": { "dockerfile": "Dockerfile", "args": { "V": "1" } },
+  "workspaceFolder": "/src/${localWorkspaceFolderBasename}",
+  "containerEnv": { "GOFLAGS": "-mod=mod" },
+  "remoteEnv": { "PATH_EXTRA": "/opt/bin" },
+  "remoteUser": "dev",
+  "runArgs": ["--cap-add=SYS_PTRACE"],
+  "onCreateCommand": "make setup",
+  "postStartCommand": ["echo", "started"]
+}`)
+
+	ds, fake := newFakeDockerSandbox(t, &DockerSandboxConfig{WorkDir: workspace})
+	if ds.DevContainer() == nil || ds.ContainerWorkDir() != "/src/proj" {
+		t.Fatalf("devcontainer not applied: %+v", ds.DevContainer())
+	}
+	if err := ds.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+
+	build := fake.find("build")
+	if build == nil || !slices.Contains(build, "--build-arg") || !slices.Contains(build, "V=1") ||
+		build[len(build)-1] != filepath.Join(workspace, ".devcontainer") {
+		t.Errorf("unexpected build args: %v", build)
+	}
+	tag := build[4]
+
+	run := strings.Join(fake.find("run"), " ")
+	for _, want := range []string{"-v " + workspace + ":/src/proj", "-e GOFLAGS=-mod=mod", "--cap-add=SYS_PTRACE", tag} {
+		if !strings.Contains(run, want) {
+			t.Errorf("run args missing %q: %s", want, run)
+		}
+	}
+
+	onCreate := fake.find("exec", "-w", "/src/proj", "-u", "dev", "-e", "PATH_EXTRA=/opt/bin", "c0ffee", "/bin/sh", "-c", "make setup")
+	postStart := fake.find("exec", "-w", "/src/proj", "-u", "dev", "-e", "PATH_EXTRA=/opt/bin", "c0ffee", "echo", "started")
+	if onCreate == nil || postStart == nil {
+		t.Errorf("lifecycle commands not executed: %v", fake.calls)
+	}
+}
+
+func TestDockerSandbox_LifecycleFailureRemovesContainer(t *testing.T) {
+	workspace := t.TempDir()
+	writeDevContainer(t, workspace, `{"image": "alpine", "postCreateCommand": "false"}`)
+
+	ds, fake := newFakeDockerSandbox(t, &DockerSandboxConfig{WorkDir: workspace})
+	fake.fail = "exec"
+
+	err := ds.Start(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "postCreateCommand") {
+		t.Fatalf("expected postCreateCommand error, got %v", err)
+	}
+	if fake.find("rm", "-f", "c0ffee") == nil || ds.ContainerID() != "" {
+		t.Error("failed container should be removed")
+	}
+}
+
+func TestDockerSandbox_BlocksCriticalCommands(t *testing.T) {
+	ds, fake := newFakeDockerSandbox(t, &DockerSandboxConfig{WorkDir: t.TempDir(), Image: "alpine"})
+
+	result, err := ds.Exec(context.Background(), "rm -rf /", nil)
+	if err != nil {
+		t.Fatalf("Exec: %v", err)
+	}
+	if result.Code == 0 || len(fake.calls) != 0 {
+		t.Errorf("critical command should be blocked before starting the container: %+v", result)
+	}
+	if entries := ds.GetAuditLog(); len(entries) != 1 || !entries[0].Blocked {
+		t.Errorf("blocked command should be audited: %+v", entries)
+	}
+}
+
+func TestDockerSandbox_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping docker integration test in short mode")
+	}
+	if err := exec.Command("docker", "info").Run(); err != nil {
+		t.Skip("docker is not available")
+	}
+
+	workDir := t.TempDir()
+	ds, err := NewDockerSandbox(&DockerSandboxConfig{WorkDir: workDir, Image: "alpine:3"})
+	if err != nil {
+		t.Fatalf("NewDockerSandbox: %v", err)
+	}
+	defer func() { _ = ds.Dispose() }()
+
+	result, err := ds.Exec(context.Background(), "echo hi > hello.txt && cat /etc/os-release", nil)
+	if err != nil {
+		t.Fatalf("Exec: %v", err)
+	}
+	if result.Code != 0 || !strings.Contains(result.Stdout, "Alpine") {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+	if content, err := ds.FS().Read(context.Background(), "hello.txt"); err != nil || content != "hi\n" {
+		t.Errorf("file written in the container should be visible on the host: %q %v", content, err)
+	}
+}
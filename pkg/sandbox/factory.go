@@ -30,16 +30,41 @@ func (f *Factory) Create(config *types.SandboxConfig) (Sandbox, error) {
 
 	switch config.Kind {
 	case types.SandboxKindLocal:
-		return NewLocalSandbox(&LocalSandboxConfig{
+		// 可通过 Extra["shell"] 指定 shell（bash/sh/powershell/cmd）
+		shell, _ := config.Extra["shell"].(string)
+		localConfig := &LocalSandboxConfig{
 			WorkDir:         config.WorkDir,
 			EnforceBoundary: config.EnforceBoundary,
 			AllowPaths:      config.AllowPaths,
 			WatchFiles:      config.WatchFiles,
+			Shell:           ShellKind(shell),
 			Settings:        config.Settings,
-		})
+		}
+		if config.Resources != nil {
+			localConfig.ResourceLimits = ResourceLimitsFromConfig(config.Resources)
+		}
+		return NewLocalSandbox(localConfig)
 
 	case types.SandboxKindDocker:
-		return nil, errors.New("docker sandbox not implemented yet")
+		// Extra 支持 image、devcontainer（路径或 false 禁用）、network、keep_container
+		dockerConfig := &DockerSandboxConfig{
+			WorkDir:         config.WorkDir,
+			EnforceBoundary: config.EnforceBoundary,
+			AllowPaths:      config.AllowPaths,
+			WatchFiles:      config.WatchFiles,
+			Settings:        config.Settings,
+			Resources:       config.Resources,
+		}
+		dockerConfig.Image, _ = config.Extra["image"].(string)
+		dockerConfig.Network, _ = config.Extra["network"].(string)
+		dockerConfig.KeepContainer, _ = config.Extra["keep_container"].(bool)
+		switch v := config.Extra["devcontainer"].(type) {
+		case string:
+			dockerConfig.DevContainer = v
+		case bool:
+			dockerConfig.DisableDevContainer = !v
+		}
+		return NewDockerSandbox(dockerConfig)
 
 	case types.SandboxKindK8s:
 		return nil, errors.New("k8s sandbox not implemented yet")
@@ -76,7 +101,7 @@ func (f *Factory) Create(config *types.SandboxConfig) (Sandbox, error) {
 		})
 
 	case types.SandboxKindMock:
-		return NewMockSandbox(), nil
+		return NewScriptedMockSandbox(config.Mock)
 
 	default:
 		return nil, fmt.Errorf("unknown sandbox kind: %s", config.Kind)
@@ -0,0 +1,54 @@
+package sandbox
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+)
+
+// FileVersions 记录 Agent 最近一次读取或写入各文件时的内容摘要
+// Write/Edit 写入前据此检测文件是否在磁盘上被修改（乐观锁），避免覆盖用户的改动
+type FileVersions struct {
+	mu     sync.Mutex
+	hashes map[string]string // 绝对路径 -> 内容摘要
+}
+
+// NewFileVersions 创建文件版本记录
+func NewFileVersions() *FileVersions {
+	return &FileVersions{hashes: make(map[string]string)}
+}
+
+// ContentHash 返回内容的 SHA-256 摘要
+func ContentHash(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// Record 记录文件当前内容，path 应为 FS.Resolve 后的绝对路径
+func (v *FileVersions) Record(path, content string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.hashes[path] = ContentHash(content)
+}
+
+// Forget 移除文件记录，例如文件被删除后
+func (v *FileVersions) Forget(path string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	delete(v.hashes, path)
+}
+
+// Reset 清空所有记录，例如 Agent 被新的会话复用时
+func (v *FileVersions) Reset() {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	clear(v.hashes)
+}
+
+// Changed 文件内容是否与上次记录不同；未记录过的文件返回 false
+func (v *FileVersions) Changed(path, current string) bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	hash, ok := v.hashes[path]
+	return ok && hash != ContentHash(current)
+}
@@ -2,9 +2,18 @@ package sandbox
 
 import (
 	"context"
+	"errors"
 	"time"
+
+	"github.com/astercloud/aster/pkg/agenterr"
 )
 
+// ErrWatchDisabled 沙箱未启用文件监听（LocalSandboxConfig.WatchFiles）
+var ErrWatchDisabled = errors.New("file watching is disabled for this sandbox")
+
+// ErrOutsideSandbox 访问的路径不在沙箱工作目录内
+var ErrOutsideSandbox = agenterr.New(agenterr.CodeSandboxViolation, "path outside sandbox")
+
 // ExecOptions 命令执行选项
 type ExecOptions struct {
 	Timeout time.Duration
@@ -19,9 +28,20 @@ type ExecResult struct {
 	Stderr string
 }
 
+// FileOp 文件变更类型
+type FileOp string
+
+const (
+	FileOpCreate FileOp = "create"
+	FileOpWrite  FileOp = "write"
+	FileOpRemove FileOp = "remove"
+	FileOpRename FileOp = "rename"
+)
+
 // FileChangeEvent 文件变更事件
 type FileChangeEvent struct {
 	Path  string
+	Op    FileOp
 	Mtime time.Time
 }
 
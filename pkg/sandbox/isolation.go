@@ -0,0 +1,552 @@
+package sandbox
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/astercloud/aster/pkg/types"
+)
+
+// defaultIsolationRoot 隔离目录默认所在位置（相对 WorkDir）
+const defaultIsolationRoot = ".aster/sessions"
+
+var unsafeSessionChars = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+// IsolatedWorkDir 会话的隔离工作目录
+type IsolatedWorkDir struct {
+	SessionID string                     `json:"session_id"`
+	Mode      types.WorkDirIsolationMode `json:"mode"`
+	// Base 原工作目录（绝对路径），合并时写回这里
+	Base string `json:"base"`
+	// Path 会话使用的工作目录
+	Path string `json:"path"`
+	// Branch worktree 模式下会话的分支
+	Branch string `json:"branch,omitempty"`
+
+	cleanup types.WorkDirCleanup
+	root    string // 隔离目录（worktree 模式为 worktree 根目录）
+	state   isolationState
+}
+
+// isolationState 保存在隔离目录旁的状态，会话恢复时沿用
+type isolationState struct {
+	// Fork worktree 模式下上次合并后的提交，未合并的改动相对它计算
+	Fork string `json:"fork,omitempty"`
+	// Files dir 模式下上次复制或合并时各文件的内容哈希（相对路径）
+	Files map[string]string `json:"files,omitempty"`
+}
+
+// MergeResult 合并回原工作目录的结果
+type MergeResult struct {
+	// Applied 写入或删除的文件（相对路径）
+	Applied []string `json:"applied,omitempty"`
+	// Conflicts 原工作目录中也被修改、未合并的文件
+	Conflicts []string `json:"conflicts,omitempty"`
+	// Commit worktree 模式下合并的会话提交
+	Commit string `json:"commit,omitempty"`
+}
+
+// IsolateWorkDir 为会话在 baseDir 下创建隔离的工作目录；目录已存在时（会话恢复）直接沿用
+func IsolateWorkDir(ctx context.Context, baseDir, sessionID string, cfg *types.WorkDirIsolation) (*IsolatedWorkDir, error) {
+	if cfg == nil || cfg.Mode == types.WorkDirIsolationNone {
+		return nil, errors.New("work dir isolation is not enabled")
+	}
+	base, err := filepath.Abs(baseDir)
+	if err != nil {
+		return nil, fmt.Errorf("resolve work dir: %w", err)
+	}
+	parent := cfg.Root
+	if parent == "" {
+		parent = filepath.Join(base, defaultIsolationRoot)
+	} else if !filepath.IsAbs(parent) {
+		parent = filepath.Join(base, parent)
+	}
+	if err := os.MkdirAll(parent, 0o755); err != nil {
+		return nil, fmt.Errorf("create isolation root: %w", err)
+	}
+	// 隔离目录位于仓库内时不应出现在 git status 中
+	_ = os.WriteFile(filepath.Join(parent, ".gitignore"), []byte("*\n"), 0o644)
+
+	name := unsafeSessionChars.ReplaceAllString(sessionID, "-")
+	w := &IsolatedWorkDir{
+		SessionID: sessionID,
+		Mode:      cfg.Mode,
+		Base:      base,
+		root:      filepath.Join(parent, name),
+		cleanup:   cfg.Cleanup,
+	}
+	if w.cleanup == "" {
+		w.cleanup = types.WorkDirCleanupIfClean
+	}
+	if data, err := os.ReadFile(w.statePath()); err == nil {
+		_ = json.Unmarshal(data, &w.state)
+	}
+
+	switch cfg.Mode {
+	case types.WorkDirIsolationDir:
+		err = w.createDir()
+	case types.WorkDirIsolationWorktree:
+		err = w.createWorktree(ctx, cfg.BaseRef, name)
+	default:
+		return nil, fmt.Errorf("unknown work dir isolation mode %q", cfg.Mode)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return w, w.saveState()
+}
+
+func (w *IsolatedWorkDir) statePath() string {
+	return w.root + ".json"
+}
+
+func (w *IsolatedWorkDir) saveState() error {
+	data, err := json.Marshal(w.state)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(w.statePath(), data, 0o644)
+}
+
+// createDir 复制工作目录（不含 .git 和隔离目录本身）并记录各文件哈希
+func (w *IsolatedWorkDir) createDir() error {
+	w.Path = w.root
+	if _, err := os.Stat(w.root); err == nil && w.state.Files != nil {
+		return nil
+	}
+	w.state.Files = make(map[string]string)
+	return w.walkFiles(w.Base, func(rel, path string, info fs.FileInfo) error {
+		target := filepath.Join(w.root, rel)
+		if err := copyFile(path, target, info); err != nil {
+			return err
+		}
+		sum, err := hashFile(path, info)
+		if err != nil {
+			return err
+		}
+		w.state.Files[rel] = sum
+		return nil
+	})
+}
+
+// createWorktree 创建 git worktree 和会话分支；WorkDir 是仓库子目录时，会话工作目录为 worktree 中的同一子目录
+func (w *IsolatedWorkDir) createWorktree(ctx context.Context, baseRef, name string) error {
+	top, err := runGit(ctx, w.Base, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return fmt.Errorf("worktree isolation requires a git repository: %w", err)
+	}
+	sub, err := filepath.Rel(top, w.Base)
+	if err != nil {
+		return err
+	}
+	w.Path = filepath.Join(w.root, sub)
+	w.Branch = "aster/" + name
+
+	if _, err := os.Stat(filepath.Join(w.root, ".git")); err == nil {
+		if w.state.Fork == "" {
+			w.state.Fork, err = runGit(ctx, w.root, "rev-parse", "HEAD")
+		}
+		return err
+	}
+
+	if baseRef == "" {
+		baseRef = "HEAD"
+	}
+	if _, err := runGit(ctx, w.Base, "rev-parse", "--verify", "--quiet", "refs/heads/"+w.Branch); err == nil {
+		_, err = runGit(ctx, w.Base, "worktree", "add", w.root, w.Branch)
+		if err != nil {
+			return fmt.Errorf("add worktree: %w", err)
+		}
+	} else if _, err := runGit(ctx, w.Base, "worktree", "add", "-b", w.Branch, w.root, baseRef); err != nil {
+		return fmt.Errorf("add worktree: %w", err)
+	}
+	w.state.Fork, err = runGit(ctx, w.root, "rev-parse", "HEAD")
+	return err
+}
+
+// Changed 返回尚未合并回原工作目录的文件（相对路径，含新增和删除）
+func (w *IsolatedWorkDir) Changed(ctx context.Context) ([]string, error) {
+	if w.Mode == types.WorkDirIsolationWorktree {
+		return w.worktreeChanged(ctx)
+	}
+	current, err := w.dirHashes()
+	if err != nil {
+		return nil, err
+	}
+	var changed []string
+	for rel, sum := range current {
+		if w.state.Files[rel] != sum {
+			changed = append(changed, rel)
+		}
+	}
+	for rel := range w.state.Files {
+		if _, ok := current[rel]; !ok {
+			changed = append(changed, rel)
+		}
+	}
+	sort.Strings(changed)
+	return changed, nil
+}
+
+func (w *IsolatedWorkDir) worktreeChanged(ctx context.Context) ([]string, error) {
+	diff, err := runGit(ctx, w.root, "diff", "--name-only", w.state.Fork)
+	if err != nil {
+		return nil, err
+	}
+	untracked, err := runGit(ctx, w.root, "ls-files", "--others", "--exclude-standard")
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	var changed []string
+	for _, line := range strings.Split(diff+"\n"+untracked, "\n") {
+		if line = strings.TrimSpace(line); line != "" && !seen[line] {
+			seen[line] = true
+			changed = append(changed, line)
+		}
+	}
+	sort.Strings(changed)
+	return changed, nil
+}
+
+// Merge 将会话的改动合并回原工作目录。
+// dir 模式逐文件写回，原目录中同一文件在会话期间也被修改时记为冲突并跳过；
+// worktree 模式先提交会话分支上的改动，再合并到原仓库当前分支，冲突时中止合并
+func (w *IsolatedWorkDir) Merge(ctx context.Context) (*MergeResult, error) {
+	if w.Mode == types.WorkDirIsolationWorktree {
+		return w.mergeWorktree(ctx)
+	}
+
+	changed, err := w.Changed(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result := &MergeResult{}
+	for _, rel := range changed {
+		src, dst := filepath.Join(w.Path, rel), filepath.Join(w.Base, rel)
+		baseSum, err := hashPath(dst)
+		if err != nil {
+			return result, err
+		}
+		sessionSum, err := hashPath(src)
+		if err != nil {
+			return result, err
+		}
+		if baseSum != w.state.Files[rel] && baseSum != sessionSum {
+			result.Conflicts = append(result.Conflicts, rel)
+			continue
+		}
+		if sessionSum == "" {
+			if err := os.Remove(dst); err != nil && !os.IsNotExist(err) {
+				return result, fmt.Errorf("remove %s: %w", rel, err)
+			}
+			delete(w.state.Files, rel)
+		} else {
+			info, err := os.Lstat(src)
+			if err != nil {
+				return result, err
+			}
+			if err := copyFile(src, dst, info); err != nil {
+				return result, fmt.Errorf("write %s: %w", rel, err)
+			}
+			w.state.Files[rel] = sessionSum
+		}
+		result.Applied = append(result.Applied, rel)
+	}
+	return result, w.saveState()
+}
+
+func (w *IsolatedWorkDir) mergeWorktree(ctx context.Context) (*MergeResult, error) {
+	changed, err := w.Changed(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result := &MergeResult{}
+	if len(changed) == 0 {
+		return result, nil
+	}
+
+	if _, err := runGit(ctx, w.root, "add", "-A"); err != nil {
+		return nil, err
+	}
+	if _, err := runGit(ctx, w.root, "diff", "--cached", "--quiet"); err != nil {
+		args := []string{"commit", "-m", "aster: session " + w.SessionID}
+		if email, _ := runGit(ctx, w.root, "config", "user.email"); email == "" {
+			args = append([]string{"-c", "user.name=aster", "-c", "user.email=aster@localhost"}, args...)
+		}
+		if _, err := runGit(ctx, w.root, args...); err != nil {
+			return nil, fmt.Errorf("commit session changes: %w", err)
+		}
+	}
+	head, err := runGit(ctx, w.root, "rev-parse", "HEAD")
+	if err != nil {
+		return nil, err
+	}
+	result.Commit = head
+
+	args := []string{"merge", "--no-edit", w.Branch}
+	if email, _ := runGit(ctx, w.Base, "config", "user.email"); email == "" {
+		args = append([]string{"-c", "user.name=aster", "-c", "user.email=aster@localhost"}, args...)
+	}
+	if _, mergeErr := runGit(ctx, w.Base, args...); mergeErr != nil {
+		conflicts, _ := runGit(ctx, w.Base, "diff", "--name-only", "--diff-filter=U")
+		_, _ = runGit(ctx, w.Base, "merge", "--abort")
+		if conflicts == "" {
+			return nil, fmt.Errorf("merge %s: %w", w.Branch, mergeErr)
+		}
+		result.Conflicts = strings.Split(conflicts, "\n")
+		return result, nil
+	}
+	result.Applied = changed
+	w.state.Fork = head
+	return result, w.saveState()
+}
+
+// FileDiffStat 单个文件的增删行数，二进制文件不统计行数
+type FileDiffStat struct {
+	Path    string `json:"path"`
+	Added   int    `json:"added"`
+	Deleted int    `json:"deleted"`
+	Binary  bool   `json:"binary,omitempty"`
+}
+
+// WorkDirDiff 工作目录之间的差异
+type WorkDirDiff struct {
+	Files   []FileDiffStat `json:"files"`
+	Added   int            `json:"added"`
+	Deleted int            `json:"deleted"`
+	// Patch unified diff 文本
+	Patch string `json:"patch,omitempty"`
+}
+
+// Diff 返回会话相对起点（上次合并后的提交）尚未合并的改动，含未跟踪文件，仅支持 worktree 模式
+func (w *IsolatedWorkDir) Diff(ctx context.Context) (*WorkDirDiff, error) {
+	if w.Mode != types.WorkDirIsolationWorktree {
+		return nil, errors.New("diff requires worktree isolation")
+	}
+	tree, err := w.snapshot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return diffTrees(ctx, w.root, w.state.Fork, tree)
+}
+
+// CompareWorkDirs 比较同一仓库中两个 worktree 会话当前的内容（a → b），用于比较并行运行的结果
+func CompareWorkDirs(ctx context.Context, a, b *IsolatedWorkDir) (*WorkDirDiff, error) {
+	if a.Mode != types.WorkDirIsolationWorktree || b.Mode != types.WorkDirIsolationWorktree {
+		return nil, errors.New("compare requires worktree isolation")
+	}
+	treeA, err := a.snapshot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	treeB, err := b.snapshot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return diffTrees(ctx, a.root, treeA, treeB)
+}
+
+// snapshot 把 worktree 当前内容（含未跟踪文件）写入 worktree 的索引并返回对应的 tree 对象，不产生提交
+func (w *IsolatedWorkDir) snapshot(ctx context.Context) (string, error) {
+	if _, err := runGit(ctx, w.root, "add", "-A"); err != nil {
+		return "", err
+	}
+	return runGit(ctx, w.root, "write-tree")
+}
+
+// diffTrees 在 dir 所在仓库中比较两个 tree-ish
+func diffTrees(ctx context.Context, dir, from, to string) (*WorkDirDiff, error) {
+	numstat, err := runGit(ctx, dir, "diff", "--numstat", from, to)
+	if err != nil {
+		return nil, err
+	}
+	patch, err := runGit(ctx, dir, "diff", from, to)
+	if err != nil {
+		return nil, err
+	}
+	diff := &WorkDirDiff{Files: []FileDiffStat{}, Patch: patch}
+	for _, line := range strings.Split(numstat, "\n") {
+		fields := strings.SplitN(line, "\t", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		stat := FileDiffStat{Path: fields[2]}
+		if fields[0] == "-" {
+			stat.Binary = true
+		} else {
+			stat.Added, _ = strconv.Atoi(fields[0])
+			stat.Deleted, _ = strconv.Atoi(fields[1])
+		}
+		diff.Added += stat.Added
+		diff.Deleted += stat.Deleted
+		diff.Files = append(diff.Files, stat)
+	}
+	return diff, nil
+}
+
+// Cleanup 按清理策略处理隔离目录（Agent 关闭时调用），返回目录是否已删除
+func (w *IsolatedWorkDir) Cleanup(ctx context.Context) (bool, error) {
+	switch w.cleanup {
+	case types.WorkDirCleanupNever:
+		return false, nil
+	case types.WorkDirCleanupIfClean:
+		changed, err := w.Changed(ctx)
+		if err != nil || len(changed) > 0 {
+			return false, err
+		}
+	}
+	return true, w.Remove(ctx)
+}
+
+// Remove 删除隔离目录（worktree 模式同时删除会话分支），未合并的改动会丢失
+func (w *IsolatedWorkDir) Remove(ctx context.Context) error {
+	if w.Mode == types.WorkDirIsolationWorktree {
+		if _, err := runGit(ctx, w.Base, "worktree", "remove", "--force", w.root); err != nil {
+			return fmt.Errorf("remove worktree: %w", err)
+		}
+		_, _ = runGit(ctx, w.Base, "branch", "-D", w.Branch)
+	} else if err := os.RemoveAll(w.root); err != nil {
+		return err
+	}
+	if err := os.Remove(w.statePath()); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// walkFiles 遍历 dir 中的普通文件和符号链接，跳过 .git 和隔离目录
+func (w *IsolatedWorkDir) walkFiles(dir string, fn func(rel, path string, info fs.FileInfo) error) error {
+	isolationRoot := filepath.Dir(w.root)
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != dir && (d.Name() == ".git" || path == isolationRoot) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() && info.Mode()&fs.ModeSymlink == 0 {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		return fn(filepath.ToSlash(rel), path, info)
+	})
+}
+
+// dirHashes 计算会话目录中各文件的哈希
+func (w *IsolatedWorkDir) dirHashes() (map[string]string, error) {
+	hashes := make(map[string]string)
+	err := w.walkFiles(w.Path, func(rel, path string, info fs.FileInfo) error {
+		sum, err := hashFile(path, info)
+		hashes[rel] = sum
+		return err
+	})
+	return hashes, err
+}
+
+// hashPath 返回文件哈希，文件不存在时返回空字符串
+func hashPath(path string) (string, error) {
+	info, err := os.Lstat(path)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return hashFile(path, info)
+}
+
+// hashFile 计算文件内容哈希，符号链接按链接目标计算，权限变化也视为改动
+func hashFile(path string, info fs.FileInfo) (string, error) {
+	h := sha256.New()
+	fmt.Fprintf(h, "%o\x00", info.Mode().Perm())
+	if info.Mode()&fs.ModeSymlink != 0 {
+		target, err := os.Readlink(path)
+		if err != nil {
+			return "", err
+		}
+		h.Write([]byte("link:" + target))
+		return hex.EncodeToString(h.Sum(nil)), nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = f.Close() }()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// copyFile 复制文件或符号链接，保留权限
+func copyFile(src, dst string, info fs.FileInfo) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+	if info.Mode()&fs.ModeSymlink != 0 {
+		target, err := os.Readlink(src)
+		if err != nil {
+			return err
+		}
+		_ = os.Remove(dst)
+		return os.Symlink(target, dst)
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = in.Close() }()
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Chmod(dst, info.Mode().Perm())
+}
+
+// runGit 在 dir 中执行 git 命令，返回去掉首尾空白的标准输出
+func runGit(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			return "", fmt.Errorf("git %s: %w", args[0], err)
+		}
+		return "", fmt.Errorf("git %s: %s", args[0], msg)
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
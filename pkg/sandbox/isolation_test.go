@@ -0,0 +1,205 @@
+package sandbox
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/astercloud/aster/pkg/types"
+)
+
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readTestFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestIsolateWorkDir_Dir(t *testing.T) {
+	ctx := context.Background()
+	base := t.TempDir()
+	writeTestFile(t, filepath.Join(base, "main.go"), "package main\n")
+	writeTestFile(t, filepath.Join(base, "docs/a.md"), "a\n")
+	writeTestFile(t, filepath.Join(base, "shared.txt"), "v1\n")
+	writeTestFile(t, filepath.Join(base, ".git/HEAD"), "ref: refs/heads/main\n")
+
+	cfg := &types.WorkDirIsolation{Mode: types.WorkDirIsolationDir}
+	s1, err := IsolateWorkDir(ctx, base, "agt-1", cfg)
+	if err != nil {
+		t.Fatalf("IsolateWorkDir: %v", err)
+	}
+	s2, err := IsolateWorkDir(ctx, base, "agt-2", cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s1.Path == s2.Path || readTestFile(t, filepath.Join(s1.Path, "docs/a.md")) != "a\n" {
+		t.Fatalf("sessions not isolated: %s, %s", s1.Path, s2.Path)
+	}
+	if _, err := os.Stat(filepath.Join(s1.Path, ".git")); !os.IsNotExist(err) {
+		t.Error(".git should not be copied")
+	}
+
+	// 两个会话修改同一文件互不影响
+	writeTestFile(t, filepath.Join(s1.Path, "shared.txt"), "session 1\n")
+	writeTestFile(t, filepath.Join(s1.Path, "new.txt"), "added\n")
+	_ = os.Remove(filepath.Join(s1.Path, "docs/a.md"))
+	writeTestFile(t, filepath.Join(s2.Path, "shared.txt"), "session 2\n")
+
+	changed, _ := s1.Changed(ctx)
+	if !slices.Equal(changed, []string{"docs/a.md", "new.txt", "shared.txt"}) {
+		t.Fatalf("changed = %v", changed)
+	}
+
+	result, err := s1.Merge(ctx)
+	if err != nil || len(result.Applied) != 3 || len(result.Conflicts) != 0 {
+		t.Fatalf("merge = %+v, %v", result, err)
+	}
+	if readTestFile(t, filepath.Join(base, "shared.txt")) != "session 1\n" || readTestFile(t, filepath.Join(base, "new.txt")) != "added\n" {
+		t.Error("session 1 changes not merged")
+	}
+	if _, err := os.Stat(filepath.Join(base, "docs/a.md")); !os.IsNotExist(err) {
+		t.Error("deletion not merged")
+	}
+
+	// 原目录中已被会话 1 修改的文件对会话 2 是冲突
+	result, _ = s2.Merge(ctx)
+	if !slices.Equal(result.Conflicts, []string{"shared.txt"}) || readTestFile(t, filepath.Join(base, "shared.txt")) != "session 1\n" {
+		t.Errorf("merge = %+v", result)
+	}
+
+	// 已合并的会话是干净的，按 if_clean 删除；有冲突未合并的会话保留
+	if removed, err := s1.Cleanup(ctx); err != nil || !removed {
+		t.Errorf("cleanup s1 = %v, %v", removed, err)
+	}
+	if removed, _ := s2.Cleanup(ctx); removed {
+		t.Error("session with unmerged changes was removed")
+	}
+	if _, err := os.Stat(s1.Path); !os.IsNotExist(err) {
+		t.Error("s1 dir still exists")
+	}
+
+	// 恢复会话时沿用已有目录
+	again, err := IsolateWorkDir(ctx, base, "agt-2", cfg)
+	if err != nil || readTestFile(t, filepath.Join(again.Path, "shared.txt")) != "session 2\n" {
+		t.Errorf("resumed session lost changes: %v", err)
+	}
+}
+
+func TestIsolateWorkDir_Worktree(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	ctx := context.Background()
+	repo := t.TempDir()
+	git := func(dir string, args ...string) {
+		t.Helper()
+		args = append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)
+		if out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	git(repo, "init", "-q", "-b", "main")
+	writeTestFile(t, filepath.Join(repo, "app/main.go"), "package main\n")
+	git(repo, "add", "-A")
+	git(repo, "commit", "-q", "-m", "init")
+
+	cfg := &types.WorkDirIsolation{Mode: types.WorkDirIsolationWorktree}
+	w, err := IsolateWorkDir(ctx, filepath.Join(repo, "app"), "agt-1", cfg)
+	if err != nil {
+		t.Fatalf("IsolateWorkDir: %v", err)
+	}
+	if w.Branch != "aster/agt-1" || readTestFile(t, filepath.Join(w.Path, "main.go")) != "package main\n" {
+		t.Fatalf("worktree = %+v", w)
+	}
+	if removed, _ := w.Cleanup(ctx); !removed {
+		t.Fatal("clean worktree was not removed")
+	}
+
+	w, err = IsolateWorkDir(ctx, filepath.Join(repo, "app"), "agt-1", cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, filepath.Join(w.Path, "util.go"), "package main\n\nfunc util() {}\n")
+	if changed, _ := w.Changed(ctx); !slices.Equal(changed, []string{"app/util.go"}) {
+		t.Fatalf("changed = %v", changed)
+	}
+	if removed, _ := w.Cleanup(ctx); removed {
+		t.Fatal("worktree with changes was removed")
+	}
+
+	result, err := w.Merge(ctx)
+	if err != nil || result.Commit == "" || len(result.Conflicts) != 0 {
+		t.Fatalf("merge = %+v, %v", result, err)
+	}
+	if readTestFile(t, filepath.Join(repo, "app/util.go")) == "" {
+		t.Error("merged file missing from repo")
+	}
+	if changed, _ := w.Changed(ctx); len(changed) != 0 {
+		t.Errorf("changed after merge = %v", changed)
+	}
+	if err := w.Remove(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCompareWorkDirs(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	ctx := context.Background()
+	repo := t.TempDir()
+	for _, args := range [][]string{{"init", "-q", "-b", "main"}, {"add", "-A"}, {"commit", "-q", "--allow-empty", "-m", "init"}} {
+		args = append([]string{"-C", repo, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)
+		if out, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+
+	cfg := &types.WorkDirIsolation{Mode: types.WorkDirIsolationWorktree}
+	a, err := IsolateWorkDir(ctx, repo, "run-1", cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := IsolateWorkDir(ctx, repo, "run-2", cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, filepath.Join(a.Path, "answer.txt"), "one\ntwo\n")
+	writeTestFile(t, filepath.Join(b.Path, "answer.txt"), "one\n")
+
+	diff, err := a.Diff(ctx)
+	if err != nil {
+		t.Fatalf("Diff: %v", err)
+	}
+	if diff.Added != 2 || len(diff.Files) != 1 || diff.Files[0].Path != "answer.txt" {
+		t.Errorf("diff = %+v", diff)
+	}
+
+	cmp, err := CompareWorkDirs(ctx, a, b)
+	if err != nil {
+		t.Fatalf("CompareWorkDirs: %v", err)
+	}
+	if cmp.Added != 0 || cmp.Deleted != 1 || !strings.Contains(cmp.Patch, "-two") {
+		t.Errorf("compare = %+v", cmp)
+	}
+	// 比较不产生提交，会话的改动仍未合并
+	if changed, _ := a.Changed(ctx); !slices.Equal(changed, []string{"answer.txt"}) {
+		t.Errorf("changed = %v", changed)
+	}
+}
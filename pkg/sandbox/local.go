@@ -1,11 +1,13 @@
 package sandbox
 
 import (
+	"bytes"
 	"context"
 	"crypto/rand"
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"math"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -17,21 +19,13 @@ import (
 	"time"
 
 	"github.com/astercloud/aster/pkg/logging"
+	"github.com/astercloud/aster/pkg/sandbox/shellrisk"
 	"github.com/astercloud/aster/pkg/types"
 	"github.com/fsnotify/fsnotify"
 )
 
 var sandboxLogger = logging.ForComponent("sandbox")
 
-// getShell returns the preferred shell for command execution.
-// Prefers bash (which supports all ulimit options), falls back to /bin/sh.
-func getShell() string {
-	if shell, err := exec.LookPath("bash"); err == nil {
-		return shell
-	}
-	return "/bin/sh"
-}
-
 // SecurityLevel 安全级别
 type SecurityLevel int
 
@@ -46,89 +40,6 @@ const (
 	SecurityLevelParanoid
 )
 
-// 危险命令模式 - 增强版，更难绕过
-var dangerousPatterns = []*regexp.Regexp{
-	// 文件系统破坏
-	regexp.MustCompile(`(?i)\brm\s+(-[a-z]*r[a-z]*\s+)*(/|/\*|~|\$HOME)`),            // rm -rf / 及变体
-	regexp.MustCompile(`(?i)\brm\s+(-[a-z]*f[a-z]*\s+)*(-[a-z]*r[a-z]*\s+)*(/|/\*)`), // rm -fr /
-	regexp.MustCompile(`(?i)\brmdir\s+(/|/\*)`),                                      // rmdir /
-	regexp.MustCompile(`(?i)\bfind\s+/\s+.*-delete`),                                 // find / -delete
-	regexp.MustCompile(`(?i)\bfind\s+/\s+.*-exec\s+rm`),                              // find / -exec rm
-
-	// 权限提升
-	regexp.MustCompile(`(?i)(^|\s|;|&&|\|\||\|)(sudo|doas|pkexec)\s`), // sudo 及替代品
-	regexp.MustCompile(`(?i)(^|\s|;|&&|\|\||\|)/usr/(s)?bin/sudo\s`),  // 绝对路径 sudo
-	regexp.MustCompile(`(?i)\bsu\s+(-\s+)?root`),                      // su root
-	regexp.MustCompile(`(?i)\bchmod\s+[0-7]*[4-7][0-7]{2}\s+/`),       // chmod 危险权限到根目录
-	regexp.MustCompile(`(?i)\bchown\s+.*\s+/`),                        // chown 根目录
-	regexp.MustCompile(`(?i)\bsetuid\b`),                              // setuid
-	regexp.MustCompile(`(?i)\bsetcap\b`),                              // setcap
-
-	// 系统控制
-	regexp.MustCompile(`(?i)(^|\s|;|&&|\|\||\|)(shutdown|poweroff|halt|reboot|init\s+[06])\b`),
-	regexp.MustCompile(`(?i)\bsystemctl\s+(poweroff|reboot|halt)`),
-	regexp.MustCompile(`(?i)\btelinit\s+[06]`),
-
-	// 磁盘/分区操作
-	regexp.MustCompile(`(?i)\b(mkfs|mke2fs|mkswap|fdisk|parted|gdisk)\b`),
-	regexp.MustCompile(`(?i)\bdd\s+.*\bof=/dev/`),                 // dd 写入设备
-	regexp.MustCompile(`(?i)\b(>\s*|tee\s+)/dev/(sd|hd|nvme|vd)`), // 重定向到磁盘设备
-	regexp.MustCompile(`(?i)\bswapon\b`),
-	regexp.MustCompile(`(?i)\bswapoff\b`),
-	regexp.MustCompile(`(?i)\bmount\s`),
-	regexp.MustCompile(`(?i)\bumount\s`),
-
-	// Fork 炸弹和资源耗尽
-	regexp.MustCompile(`:\s*\(\s*\)\s*\{\s*:\s*\|\s*:\s*&\s*\}\s*;\s*:`), // fork bomb
-	regexp.MustCompile(`(?i)\byes\s*\|`),                                 // yes | 无限输出
-	regexp.MustCompile(`(?i)\bwhile\s+true\s*;\s*do`),                    // 无限循环
-	regexp.MustCompile(`(?i)\bfor\s*\(\s*;\s*;\s*\)`),                    // C风格无限循环
-
-	// 远程代码执行
-	regexp.MustCompile(`(?i)\bcurl\s+.*\|\s*(ba)?sh`),                       // curl | sh
-	regexp.MustCompile(`(?i)\bwget\s+.*\|\s*(ba)?sh`),                       // wget | sh
-	regexp.MustCompile(`(?i)\bcurl\s+.*-o\s*/tmp/.*&&.*sh\s+/tmp/`),         // curl下载执行
-	regexp.MustCompile(`(?i)\beval\s+\$\(`),                                 // eval $(...)
-	regexp.MustCompile(`(?i)\bsource\s+<\(`),                                // source <(...)
-	regexp.MustCompile(`(?i)\b\.\s+<\(`),                                    // . <(...)
-	regexp.MustCompile(`(?i)(python|perl|ruby|node)\s+-e\s+.*\bexec\b`),     // 脚本语言 exec
-	regexp.MustCompile(`(?i)(python|perl|ruby|node)\s+-c\s+.*\bos\.system`), // 脚本语言系统调用
-
-	// 网络攻击
-	regexp.MustCompile(`(?i)\bnc\s+(-[a-z]*l[a-z]*\s+)*-[a-z]*e\s`), // nc -e 反向shell
-	regexp.MustCompile(`(?i)\bnetcat\s+.*-e\s`),
-	regexp.MustCompile(`(?i)\bnmap\s`),
-	regexp.MustCompile(`(?i)\biptables\s`),
-	regexp.MustCompile(`(?i)\bip6tables\s`),
-	regexp.MustCompile(`(?i)\bufw\s`),
-	regexp.MustCompile(`(?i)\bfirewall-cmd\s`),
-
-	// 敏感文件访问
-	regexp.MustCompile(`(?i)\bcat\s+.*/etc/(passwd|shadow|sudoers)`),
-	regexp.MustCompile(`(?i)\bcp\s+.*/etc/(passwd|shadow)`),
-	regexp.MustCompile(`(?i)\b(vi|vim|nano|emacs)\s+/etc/(passwd|shadow|sudoers)`),
-
-	// 内核/系统修改
-	regexp.MustCompile(`(?i)\binsmod\b`),
-	regexp.MustCompile(`(?i)\brmmod\b`),
-	regexp.MustCompile(`(?i)\bmodprobe\b`),
-	regexp.MustCompile(`(?i)\bsysctl\s+-w`),
-	regexp.MustCompile(`(?i)\becho\s+.*>\s*/proc/`),
-	regexp.MustCompile(`(?i)\becho\s+.*>\s*/sys/`),
-
-	// 容器逃逸
-	regexp.MustCompile(`(?i)\bdocker\s+run\s+.*--privileged`),
-	regexp.MustCompile(`(?i)\bdocker\s+run\s+.*-v\s+/:/`),
-	regexp.MustCompile(`(?i)\bnsenter\b`),
-	regexp.MustCompile(`(?i)\bunshare\b`),
-
-	// 历史/日志清除
-	regexp.MustCompile(`(?i)\bhistory\s+-c`),
-	regexp.MustCompile(`(?i)\brm\s+.*\.(bash_history|zsh_history)`),
-	regexp.MustCompile(`(?i)\b>\s*/var/log/`),
-	regexp.MustCompile(`(?i)\btruncate\s+.*(/var/log/|\.history)`),
-}
-
 // 敏感路径前缀
 var sensitivePaths = []string{
 	"/etc/passwd",
@@ -145,6 +56,21 @@ var sensitivePaths = []string{
 	"/run",
 }
 
+// Windows 敏感路径前缀（不区分大小写）
+var windowsSensitivePaths = []string{
+	`C:\Windows\System32\config`,
+	`C:\Windows\System32\drivers`,
+	`C:\Windows\System32\GroupPolicy`,
+	`C:\Windows\SysWOW64`,
+	`C:\ProgramData\Microsoft\Crypto`,
+	`C:\System Volume Information`,
+	`C:\$Recycle.Bin`,
+	`C:\pagefile.sys`,
+	`C:\hiberfil.sys`,
+	`C:\bootmgr`,
+	`C:\Boot`,
+}
+
 // 允许的命令白名单（严格模式）
 var allowedCommands = map[string]bool{
 	// 文件操作
@@ -172,6 +98,23 @@ var allowedCommands = map[string]bool{
 	"ps": true, "top": true, "htop": true,
 }
 
+// Windows 额外允许的命令（严格模式，命令名已转为小写）
+var windowsAllowedCommands = map[string]bool{
+	// cmd 内置命令和系统工具
+	"dir": true, "type": true, "copy": true, "move": true, "md": true, "mkdir": true,
+	"cd": true, "echo": true, "where": true, "findstr": true, "find": true, "fc": true,
+	"tree": true, "more": true, "sort": true, "hostname": true, "whoami": true, "ver": true,
+	"tasklist": true, "tar": true,
+	// PowerShell cmdlet 和别名
+	"get-childitem": true, "get-content": true, "set-content": true, "add-content": true,
+	"get-item": true, "copy-item": true, "move-item": true, "new-item": true,
+	"select-string": true, "get-location": true, "set-location": true, "test-path": true,
+	"resolve-path": true, "write-output": true, "write-host": true, "get-date": true,
+	"get-process": true, "compare-object": true, "measure-object": true,
+	"expand-archive": true, "compress-archive": true,
+	"ls": true, "cat": true, "gci": true, "gc": true, "sls": true, "pwd": true,
+}
+
 // LocalSandbox 本地沙箱实现
 type LocalSandbox struct {
 	workDir         string
@@ -188,15 +131,27 @@ type LocalSandbox struct {
 	ignoreViolations *types.SandboxIgnoreViolations
 	excludedCommands []string
 
+	// 命令执行使用的 shell
+	shell shellSpec
+
+	// 操作系统级隔离，未启用时为 nil
+	osPolicy *osPolicy
+	osStatus OSIsolationStatus
+
 	// 增强安全配置
 	securityLevel   SecurityLevel
 	auditLog        []AuditEntry
 	auditMu         sync.RWMutex
 	maxAuditEntries int
+	auditListeners  []AuditListener
 	resourceLimits  *ResourceLimits
 	blockedCommands map[string]bool
 	commandStats    map[string]*CommandStats
 	statsMu         sync.RWMutex
+
+	// 资源事件监听器
+	resourceListeners []ResourceEventListener
+	resourceMu        sync.RWMutex
 }
 
 // AuditEntry 审计日志条目
@@ -213,14 +168,24 @@ type AuditEntry struct {
 	Metadata    map[string]string `json:"metadata,omitempty"`
 }
 
+// AuditListener 审计日志监听器，每条审计记录产生时同步调用
+type AuditListener func(entry AuditEntry)
+
+// AuditReporter 支持审计日志监听的沙箱
+type AuditReporter interface {
+	OnAudit(listener AuditListener)
+}
+
 // ResourceLimits 资源限制配置
 type ResourceLimits struct {
-	MaxCPUTime     time.Duration // 最大 CPU 时间
-	MaxMemoryMB    int           // 最大内存 (MB)
+	MaxCPUTime     time.Duration // 最大执行时间
+	MaxMemoryMB    int           // 进程树最大常驻内存 (MB)，超出时终止
 	MaxFileSizeMB  int           // 最大文件大小 (MB)
-	MaxProcesses   int           // 最大进程数
+	MaxProcesses   int           // 进程树最大进程数，超出时终止
 	MaxOpenFiles   int           // 最大打开文件数
 	MaxOutputBytes int           // 最大输出字节数
+	CPUTimeLimit   time.Duration // 进程树累计 CPU 时间，超出时终止
+	Nice           int           // 进程优先级调整（Unix nice 值，Windows 映射为低优先级）
 }
 
 // CommandStats 命令统计
@@ -234,7 +199,7 @@ type CommandStats struct {
 // DefaultResourceLimits 默认资源限制
 var DefaultResourceLimits = &ResourceLimits{
 	MaxCPUTime:     5 * time.Minute,
-	MaxMemoryMB:    512,
+	MaxMemoryMB:    0, // 默认不限制，构建和测试命令常超过固定值
 	MaxFileSizeMB:  100,
 	MaxProcesses:   50,
 	MaxOpenFiles:   1024,
@@ -256,6 +221,9 @@ type LocalSandboxConfig struct {
 	AllowPaths      []string
 	WatchFiles      bool
 
+	// Shell 命令执行使用的 shell，默认按操作系统自动选择
+	Shell ShellKind
+
 	// Claude Agent SDK 风格的安全配置
 	Settings *types.SandboxSettings
 
@@ -325,6 +293,7 @@ func NewLocalSandbox(config *LocalSandboxConfig) (*LocalSandbox, error) {
 		allowPaths:      allowPaths,
 		watchEnabled:    config.WatchFiles,
 		watchers:        make(map[string]*fileWatcher),
+		shell:           detectShell(runtime.GOOS, config.Shell, exec.LookPath),
 		settings:        config.Settings,
 		securityLevel:   securityLevel,
 		auditLog:        make([]AuditEntry, 0),
@@ -341,6 +310,12 @@ func NewLocalSandbox(config *LocalSandboxConfig) (*LocalSandbox, error) {
 		ls.excludedCommands = config.Settings.ExcludedCommands
 	}
 
+	// 操作系统级隔离
+	ls.osPolicy, ls.osStatus, err = newOSPolicy(config.Settings, workDir, allowPaths)
+	if err != nil {
+		return nil, err
+	}
+
 	ls.fs = &LocalFS{
 		workDir:         workDir,
 		enforceBoundary: config.EnforceBoundary,
@@ -351,6 +326,8 @@ func NewLocalSandbox(config *LocalSandboxConfig) (*LocalSandbox, error) {
 		"workDir":         workDir,
 		"securityLevel":   securityLevel,
 		"enforceBoundary": config.EnforceBoundary,
+		"osIsolation":     ls.osStatus.Active,
+		"shell":           ls.shell.kind,
 	})
 
 	return ls, nil
@@ -361,6 +338,11 @@ func (ls *LocalSandbox) Kind() string {
 	return "local"
 }
 
+// Shell 返回命令执行使用的 shell 类型
+func (ls *LocalSandbox) Shell() ShellKind {
+	return ls.shell.kind
+}
+
 // WorkDir 返回工作目录
 func (ls *LocalSandbox) WorkDir() string {
 	return ls.workDir
@@ -398,7 +380,7 @@ func (ls *LocalSandbox) Exec(ctx context.Context, cmd string, opts *ExecOptions)
 
 	// 3. 严格模式：检查命令白名单
 	if ls.securityLevel >= SecurityLevelStrict {
-		if !allowedCommands[cmdName] && !ls.isExcludedCommand(cmd) {
+		if !ls.isAllowedCommand(cmdName) && !ls.isExcludedCommand(cmd) {
 			ls.recordAudit(cmd, opts, nil, startTime, true, "command not in whitelist")
 			return &ExecResult{
 				Code:   1,
@@ -456,10 +438,6 @@ func (ls *LocalSandbox) execWithLimits(ctx context.Context, cmd string, opts *Ex
 	execCtx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
-	// 构建命令（带资源限制）
-	shellCmd := ls.buildSecureCommand(cmd)
-	command := exec.CommandContext(execCtx, getShell(), "-c", shellCmd)
-
 	// 设置工作目录
 	workDir := ls.workDir
 	if opts != nil && opts.WorkDir != "" {
@@ -483,14 +461,26 @@ func (ls *LocalSandbox) execWithLimits(ctx context.Context, cmd string, opts *Ex
 		})
 	}
 
+	// 构建命令（带资源限制），启用 OS 级隔离时在隔离中执行
+	shellCmd := ls.buildSecureCommand(cmd)
+	var command *exec.Cmd
+	if ls.osPolicy != nil {
+		command = ls.osPolicy.command(execCtx, ls.shell.path, shellCmd, workDir)
+	} else {
+		command = ls.shell.command(execCtx, shellCmd)
+	}
 	command.Dir = workDir
 
 	// 设置安全环境变量
 	env := ls.buildSecureEnv(opts)
 	command.Env = env
 
-	// 执行并捕获输出
-	output, err := command.CombinedOutput()
+	// 执行并捕获输出，超出资源限制时终止整个进程树
+	var buf bytes.Buffer
+	command.Stdout = &buf
+	command.Stderr = &buf
+	_, violation, err := ls.runWithResourceControl(command, cmd)
+	output := buf.Bytes()
 
 	// 限制输出大小
 	if ls.resourceLimits != nil && ls.resourceLimits.MaxOutputBytes > 0 {
@@ -500,6 +490,15 @@ func (ls *LocalSandbox) execWithLimits(ctx context.Context, cmd string, opts *Ex
 		}
 	}
 
+	if violation != nil {
+		output = append(output, []byte("\n[killed: "+violation.Error()+"]")...)
+		return &ExecResult{
+			Code:   137,
+			Stdout: string(output),
+			Stderr: string(output),
+		}
+	}
+
 	if err != nil {
 		exitErr := &exec.ExitError{}
 		if errors.As(err, &exitErr) {
@@ -525,7 +524,7 @@ func (ls *LocalSandbox) execWithLimits(ctx context.Context, cmd string, opts *Ex
 
 // buildSecureCommand 构建带资源限制的命令
 func (ls *LocalSandbox) buildSecureCommand(cmd string) string {
-	if ls.resourceLimits == nil || runtime.GOOS == "windows" {
+	if ls.resourceLimits == nil || ls.shell.isWindows() {
 		return cmd
 	}
 
@@ -547,6 +546,12 @@ func (ls *LocalSandbox) buildSecureCommand(cmd string) string {
 		limits = append(limits, fmt.Sprintf("ulimit -n %d", ls.resourceLimits.MaxOpenFiles))
 	}
 
+	if ls.resourceLimits.CPUTimeLimit > 0 {
+		// 限制单个进程的 CPU 时间（秒），进程树总量由资源监控检查
+		secs := int(math.Ceil(ls.resourceLimits.CPUTimeLimit.Seconds()))
+		limits = append(limits, fmt.Sprintf("ulimit -t %d", secs))
+	}
+
 	if len(limits) > 0 {
 		return strings.Join(limits, " && ") + " && " + cmd
 	}
@@ -556,6 +561,10 @@ func (ls *LocalSandbox) buildSecureCommand(cmd string) string {
 
 // buildSecureEnv 构建安全环境变量
 func (ls *LocalSandbox) buildSecureEnv(opts *ExecOptions) []string {
+	if ls.shell.isWindows() {
+		return ls.appendUserEnv(ls.buildWindowsEnv(), opts)
+	}
+
 	// 构建 PATH：包含常用路径，支持 macOS (Intel/Apple Silicon) 和 Linux
 	// 优先级：用户本地 > Homebrew > 系统路径
 	pathDirs := []string{
@@ -592,16 +601,44 @@ func (ls *LocalSandbox) buildSecureEnv(opts *ExecOptions) []string {
 		}
 	}
 
-	// 添加用户指定的环境变量
+	return ls.appendUserEnv(env, opts)
+}
+
+// buildWindowsEnv 构建 Windows 安全环境变量
+// Windows 的可执行文件位置因安装而异，PATH 和系统目录变量从主机继承
+func (ls *LocalSandbox) buildWindowsEnv() []string {
+	env := []string{
+		"HOME=" + ls.workDir,
+		"USERPROFILE=" + ls.workDir,
+	}
+
+	// 进程启动和 PowerShell 运行必需的系统变量
+	systemVars := []string{
+		"PATH", "PATHEXT", "SystemRoot", "SystemDrive", "windir", "ComSpec",
+		"TEMP", "TMP", "ProgramFiles", "ProgramFiles(x86)", "ProgramW6432",
+		"ProgramData", "CommonProgramFiles", "PROCESSOR_ARCHITECTURE", "NUMBER_OF_PROCESSORS",
+	}
+	if ls.securityLevel < SecurityLevelStrict {
+		systemVars = append(systemVars, "APPDATA", "LOCALAPPDATA", "USERNAME", "USERDOMAIN",
+			"COMPUTERNAME", "PSModulePath", "GOPATH", "GOROOT", "NODE_PATH")
+	}
+	for _, key := range systemVars {
+		if val := os.Getenv(key); val != "" {
+			env = append(env, key+"="+val)
+		}
+	}
+	return env
+}
+
+// appendUserEnv 添加用户指定的环境变量，过滤危险环境变量
+func (ls *LocalSandbox) appendUserEnv(env []string, opts *ExecOptions) []string {
 	if opts != nil && len(opts.Env) > 0 {
 		for k, v := range opts.Env {
-			// 过滤危险环境变量
 			if !ls.isDangerousEnvVar(k) {
 				env = append(env, fmt.Sprintf("%s=%s", k, v))
 			}
 		}
 	}
-
 	return env
 }
 
@@ -620,109 +657,55 @@ func (ls *LocalSandbox) isDangerousEnvVar(key string) bool {
 		"ENV":                   true,
 		"CDPATH":                true,
 		"IFS":                   true,
+		"COMSPEC":               true,
+		"PATHEXT":               true,
+		"PSMODULEPATH":          true,
+		"COR_ENABLE_PROFILING":  true,
+		"COR_PROFILER":          true,
+		"COR_PROFILER_PATH":     true,
+	}
+	if ls.shell.isWindows() {
+		// Windows 环境变量名不区分大小写
+		key = strings.ToUpper(key)
 	}
 	return dangerousVars[key]
 }
 
 // checkDangerousCommand 检查危险命令
+// 使用 shell 语法分析逐段分类，高风险及以上的命令被阻止
 func (ls *LocalSandbox) checkDangerousCommand(cmd string) string {
-	// 规范化命令（移除多余空格）
-	normalizedCmd := strings.Join(strings.Fields(cmd), " ")
-
-	for _, pattern := range dangerousPatterns {
-		if pattern.MatchString(normalizedCmd) {
-			return "matches dangerous pattern: " + pattern.String()[:min(50, len(pattern.String()))]
-		}
+	analysis := ls.shell.analyze(cmd)
+	if analysis.Dangerous() {
+		return analysis.Reason()
 	}
 
-	// 检查命令注入
-	if ls.hasCommandInjection(cmd) {
-		return "potential command injection detected"
+	// 偏执模式下不允许任何命令替换
+	if ls.securityLevel >= SecurityLevelParanoid && analysis.HasSubstitution {
+		return "command substitution not allowed (paranoid mode)"
 	}
 
 	return ""
 }
 
-// hasCommandInjection 检测命令注入
-func (ls *LocalSandbox) hasCommandInjection(cmd string) bool {
-	// 检测常见的命令注入模式
-	injectionPatterns := []string{
-		"`",    // 反引号命令替换
-		"$(",   // 命令替换
-		"$((",  // 算术扩展
-		"${",   // 参数扩展（可能危险）
-		"\n",   // 换行符注入
-		"\r",   // 回车符注入
-		"\x00", // 空字节注入
-	}
-
-	for _, pattern := range injectionPatterns {
-		// 允许在引号内使用这些字符
-		if strings.Contains(cmd, pattern) {
-			// 简单检查：如果不在引号内，则可能是注入
-			if !ls.isInQuotes(cmd, strings.Index(cmd, pattern)) {
-				// 对于 $( 和 ${ 做更宽松的检查，因为它们在脚本中很常见
-				if pattern == "$(" || pattern == "${" {
-					// 只在偏执模式下阻止
-					if ls.securityLevel >= SecurityLevelParanoid {
-						return true
-					}
-				} else {
-					return true
-				}
-			}
-		}
-	}
-
-	return false
-}
-
-// isInQuotes 检查位置是否在引号内
-func (ls *LocalSandbox) isInQuotes(s string, pos int) bool {
-	if pos < 0 || pos >= len(s) {
-		return false
-	}
-
-	inSingle := false
-	inDouble := false
-
-	for i := 0; i < pos; i++ {
-		switch s[i] {
-		case '\'':
-			if !inDouble {
-				inSingle = !inSingle
-			}
-		case '"':
-			if !inSingle {
-				inDouble = !inDouble
-			}
-		case '\\':
-			if i+1 < pos {
-				i++ // 跳过转义字符
-			}
-		}
-	}
-
-	return inSingle || inDouble
-}
-
 // checkPathSecurity 检查路径安全
 func (ls *LocalSandbox) checkPathSecurity(cmd string) string {
 	// 提取命令中的路径
 	paths := ls.extractPaths(cmd)
 
 	for _, path := range paths {
-		// 规范化路径
-		absPath, err := filepath.Abs(path)
-		if err != nil {
-			continue
+		// 规范化路径（Windows 盘符和 UNC 路径本身即为绝对路径）
+		absPath := path
+		if !ls.shell.isWindows() || !windowsAbsPathPattern.MatchString(path) {
+			abs, err := filepath.Abs(path)
+			if err != nil {
+				continue
+			}
+			absPath = abs
 		}
 
 		// 检查是否访问敏感路径
-		for _, sensitive := range sensitivePaths {
-			if strings.HasPrefix(absPath, sensitive) {
-				return "access to sensitive path: " + sensitive
-			}
+		if sensitive := ls.sensitivePathOf(absPath); sensitive != "" {
+			return "access to sensitive path: " + sensitive
 		}
 
 		// 检查路径遍历攻击
@@ -737,12 +720,39 @@ func (ls *LocalSandbox) checkPathSecurity(cmd string) string {
 	return ""
 }
 
+// sensitivePathOf 返回路径命中的敏感路径前缀，Windows 下不区分大小写
+func (ls *LocalSandbox) sensitivePathOf(absPath string) string {
+	if ls.shell.isWindows() {
+		for _, sensitive := range windowsSensitivePaths {
+			if pathWithin(sensitive, absPath, true) {
+				return sensitive
+			}
+		}
+		return ""
+	}
+	for _, sensitive := range sensitivePaths {
+		if strings.HasPrefix(absPath, sensitive) {
+			return sensitive
+		}
+	}
+	return ""
+}
+
+// 路径提取模式：Unix 查找以 / 或 ./ 或 ../ 开头的词，Windows 额外识别盘符、UNC 和反斜杠路径
+var (
+	unixPathPattern       = regexp.MustCompile(`(?:^|\s)((?:/|\.\.?/)[^\s;|&<>]+)`)
+	windowsAbsPathPattern = regexp.MustCompile(`^(?:[A-Za-z]:[\\/]|\\\\)`)
+	windowsPathPattern    = regexp.MustCompile(`(?:^|[\s"'])((?:[A-Za-z]:[\\/]|\\\\|\.\.?[\\/]|[\\/])[^\s;|&<>"']+)`)
+)
+
 // extractPaths 从命令中提取路径
 func (ls *LocalSandbox) extractPaths(cmd string) []string {
 	var paths []string
 
-	// 简单的路径提取：查找以 / 或 ./ 或 ../ 开头的词
-	pathPattern := regexp.MustCompile(`(?:^|\s)((?:/|\.\.?/)[^\s;|&<>]+)`)
+	pathPattern := unixPathPattern
+	if ls.shell.isWindows() {
+		pathPattern = windowsPathPattern
+	}
 	matches := pathPattern.FindAllStringSubmatch(cmd, -1)
 
 	for _, match := range matches {
@@ -778,18 +788,13 @@ func (ls *LocalSandbox) extractCommandName(cmd string) string {
 		return ""
 	}
 
-	cmdName := fields[0]
-
 	// 移除路径前缀
-	cmdName = filepath.Base(cmdName)
-
-	return cmdName
+	return ls.shell.normalizeCommandName(fields[0])
 }
 
 // recordAudit 记录审计日志
 func (ls *LocalSandbox) recordAudit(cmd string, opts *ExecOptions, result *ExecResult, startTime time.Time, blocked bool, blockReason string) {
 	ls.auditMu.Lock()
-	defer ls.auditMu.Unlock()
 
 	entry := AuditEntry{
 		Timestamp:   startTime,
@@ -816,6 +821,12 @@ func (ls *LocalSandbox) recordAudit(cmd string, opts *ExecOptions, result *ExecR
 	if len(ls.auditLog) > ls.maxAuditEntries {
 		ls.auditLog = ls.auditLog[len(ls.auditLog)-ls.maxAuditEntries:]
 	}
+	listeners := ls.auditListeners
+	ls.auditMu.Unlock()
+
+	for _, listener := range listeners {
+		listener(entry)
+	}
 
 	// 记录到结构化日志
 	if blocked {
@@ -852,6 +863,13 @@ func (ls *LocalSandbox) updateStats(cmdName string, result *ExecResult, duration
 	}
 }
 
+// OnAudit 注册审计日志监听器
+func (ls *LocalSandbox) OnAudit(listener AuditListener) {
+	ls.auditMu.Lock()
+	defer ls.auditMu.Unlock()
+	ls.auditListeners = append(ls.auditListeners, listener)
+}
+
 // GetAuditLog 获取审计日志
 func (ls *LocalSandbox) GetAuditLog() []AuditEntry {
 	ls.auditMu.RLock()
@@ -899,9 +917,10 @@ func (ls *LocalSandbox) RemoveBlockedCommand(cmd string) {
 }
 
 // Watch 监听文件变更
+// 目录会递归监听（跳过 .git、node_modules 等目录），监听期间新建的子目录自动加入
 func (ls *LocalSandbox) Watch(paths []string, listener FileChangeListener) (string, error) {
 	if !ls.watchEnabled {
-		return fmt.Sprintf("watch-disabled-%d", time.Now().UnixNano()), nil
+		return "", ErrWatchDisabled
 	}
 
 	ls.watcherMu.Lock()
@@ -917,15 +936,18 @@ func (ls *LocalSandbox) Watch(paths []string, listener FileChangeListener) (stri
 	watchID := fmt.Sprintf("watch-%d-%s", time.Now().UnixNano(), randomString(8))
 
 	// 添加监听路径
+	added := 0
 	for _, path := range paths {
 		resolved := ls.fs.Resolve(path)
 		if !ls.fs.IsInside(resolved) {
 			continue
 		}
-		if err := watcher.Add(resolved); err != nil {
-			// 忽略单个路径的错误
-			continue
-		}
+		// 忽略单个路径的错误
+		added += addWatchPath(watcher, resolved)
+	}
+	if added == 0 {
+		_ = watcher.Close()
+		return "", fmt.Errorf("no watchable paths in %v", paths)
 	}
 
 	// 创建fileWatcher
@@ -944,6 +966,54 @@ func (ls *LocalSandbox) Watch(paths []string, listener FileChangeListener) (stri
 	return watchID, nil
 }
 
+// skipWatchDirs 递归监听时跳过的目录
+var skipWatchDirs = map[string]bool{".git": true, ".hg": true, ".svn": true, "node_modules": true, ".venv": true, "__pycache__": true}
+
+// addWatchPath 添加监听路径，目录递归添加子目录，返回成功添加的数量
+func addWatchPath(watcher *fsnotify.Watcher, root string) int {
+	info, err := os.Stat(root)
+	if err != nil {
+		return 0
+	}
+	if !info.IsDir() {
+		if watcher.Add(root) != nil {
+			return 0
+		}
+		return 1
+	}
+
+	added := 0
+	_ = filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return nil
+		}
+		if path != root && skipWatchDirs[d.Name()] {
+			return filepath.SkipDir
+		}
+		if watcher.Add(path) == nil {
+			added++
+		}
+		return nil
+	})
+	return added
+}
+
+// fileOpOf 将 fsnotify 事件转换为文件变更类型，忽略权限变更
+func fileOpOf(op fsnotify.Op) (FileOp, bool) {
+	switch {
+	case op&fsnotify.Create != 0:
+		return FileOpCreate, true
+	case op&fsnotify.Write != 0:
+		return FileOpWrite, true
+	case op&fsnotify.Remove != 0:
+		return FileOpRemove, true
+	case op&fsnotify.Rename != 0:
+		return FileOpRename, true
+	default:
+		return "", false
+	}
+}
+
 // watchLoop 文件监听循环
 func (ls *LocalSandbox) watchLoop(watchID string, fw *fileWatcher) {
 	defer func() { _ = fw.watcher.Close() }()
@@ -954,21 +1024,26 @@ func (ls *LocalSandbox) watchLoop(watchID string, fw *fileWatcher) {
 			if !ok {
 				return
 			}
-			// 只处理写入和创建事件
-			if event.Op&(fsnotify.Write|fsnotify.Create) != 0 {
-				// 获取文件修改时间
-				var mtime time.Time
-				if stat, err := os.Stat(event.Name); err == nil {
-					mtime = stat.ModTime()
-				} else {
-					mtime = time.Now()
-				}
+			op, ok := fileOpOf(event.Op)
+			if !ok {
+				continue
+			}
 
-				fw.listener(FileChangeEvent{
-					Path:  event.Name,
-					Mtime: mtime,
-				})
+			// 获取文件修改时间
+			mtime := time.Now()
+			if stat, err := os.Stat(event.Name); err == nil {
+				mtime = stat.ModTime()
+				// 新建的目录加入监听
+				if op == FileOpCreate && stat.IsDir() && !skipWatchDirs[stat.Name()] {
+					addWatchPath(fw.watcher, event.Name)
+				}
 			}
+
+			fw.listener(FileChangeEvent{
+				Path:  event.Name,
+				Op:    op,
+				Mtime: mtime,
+			})
 		case err, ok := <-fw.watcher.Errors:
 			if !ok {
 				return
@@ -1029,6 +1104,14 @@ func randomString(n int) string {
 	return hex.EncodeToString(b)[:n]
 }
 
+// isAllowedCommand 检查命令是否在严格模式白名单中
+func (ls *LocalSandbox) isAllowedCommand(cmdName string) bool {
+	if allowedCommands[cmdName] {
+		return true
+	}
+	return ls.shell.isWindows() && windowsAllowedCommands[cmdName]
+}
+
 // isExcludedCommand 检查命令是否在排除列表中
 func (ls *LocalSandbox) isExcludedCommand(cmd string) bool {
 	if len(ls.excludedCommands) == 0 {
@@ -1050,6 +1133,10 @@ func (ls *LocalSandbox) isExcludedCommand(cmd string) bool {
 		if strings.HasSuffix(cmdName, "/"+excluded) {
 			return true
 		}
+		// Windows 下忽略大小写、路径和扩展名
+		if ls.shell.isWindows() && ls.shell.normalizeCommandName(cmdName) == ls.shell.normalizeCommandName(excluded) {
+			return true
+		}
 	}
 
 	return false
@@ -1057,21 +1144,13 @@ func (ls *LocalSandbox) isExcludedCommand(cmd string) bool {
 
 // execDirect 直接执行命令（排除命令，仍有基本安全检查）
 func (ls *LocalSandbox) execDirect(ctx context.Context, cmd string, opts *ExecOptions) (*ExecResult, error) {
-	// 即使是排除命令，也要检查最危险的模式
-	criticalPatterns := []*regexp.Regexp{
-		regexp.MustCompile(`(?i)\brm\s+(-[a-z]*r[a-z]*\s+)*(/|/\*)`),
-		regexp.MustCompile(`:\s*\(\s*\)\s*\{\s*:\s*\|\s*:\s*&\s*\}\s*;\s*:`),
-		regexp.MustCompile(`(?i)\bdd\s+.*\bof=/dev/`),
-	}
-
-	for _, pattern := range criticalPatterns {
-		if pattern.MatchString(cmd) {
-			return &ExecResult{
-				Code:   1,
-				Stdout: "",
-				Stderr: "Critical dangerous command blocked even for excluded commands",
-			}, nil
-		}
+	// 即使是排除命令，也要阻止最危险的命令
+	if analysis := ls.shell.analyze(cmd); analysis.Level >= shellrisk.LevelCritical {
+		return &ExecResult{
+			Code:   1,
+			Stdout: "",
+			Stderr: "Critical dangerous command blocked even for excluded commands: " + analysis.Reason(),
+		}, nil
 	}
 
 	timeout := 120 * time.Second
@@ -1082,7 +1161,7 @@ func (ls *LocalSandbox) execDirect(ctx context.Context, cmd string, opts *ExecOp
 	execCtx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
-	command := exec.CommandContext(execCtx, getShell(), "-c", cmd)
+	command := ls.shell.command(execCtx, cmd)
 
 	workDir := ls.workDir
 	if opts != nil && opts.WorkDir != "" {
@@ -1167,12 +1246,21 @@ func (ls *LocalSandbox) ShouldIgnoreViolation(violationType, path string) bool {
 	return false
 }
 
+// OSIsolation 返回操作系统级隔离状态
+func (ls *LocalSandbox) OSIsolation() OSIsolationStatus {
+	return ls.osStatus
+}
+
 // CheckNetworkAccess 检查网络访问权限
 func (ls *LocalSandbox) CheckNetworkAccess(host string, port int) bool {
 	if ls.networkConfig == nil {
 		return true // 默认允许
 	}
 
+	if ls.networkConfig.Disabled {
+		return false
+	}
+
 	// 检查本地绑定
 	if port > 0 && (host == "localhost" || host == "127.0.0.1" || host == "0.0.0.0") {
 		if !ls.networkConfig.AllowLocalBinding {
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
 	"time"
 
@@ -23,13 +24,32 @@ type LocalFS struct {
 func (lfs *LocalFS) Resolve(path string) string {
 	// 如果是绝对路径，将其转换为相对于 workDir 的路径
 	// 这防止 Agent 使用 /tmp 等系统目录
-	if filepath.IsAbs(path) {
+	// Windows 下同时移除盘符（C:）和 UNC 前缀（\\server\share）
+	if filepath.IsAbs(path) || filepath.VolumeName(path) != "" {
 		// 移除开头的 / 使其成为相对路径
-		path = strings.TrimPrefix(path, "/")
+		path = strings.TrimLeft(path[len(filepath.VolumeName(path)):], `/\`)
 	}
 	return filepath.Join(lfs.workDir, path)
 }
 
+// pathWithin 检查绝对路径 target 是否位于 base 内（含 base 本身）
+// windows 为 true 时按 Windows 规则比较：盘符和路径不区分大小写，/ 与 \ 等价，不同盘符不相交
+func pathWithin(base, target string, windows bool) bool {
+	if windows {
+		normalize := func(p string) string {
+			p = strings.ToLower(strings.ReplaceAll(p, "/", `\`))
+			return strings.TrimRight(p, `\`)
+		}
+		base, target = normalize(base), normalize(target)
+		return target == base || strings.HasPrefix(target, base+`\`)
+	}
+	rel, err := filepath.Rel(base, target)
+	if err != nil || filepath.IsAbs(rel) {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
 // IsInside 检查路径是否在沙箱内
 // 如果传入的是绝对路径，直接检查该路径是否在 workDir 或白名单内
 // 如果传入的是相对路径，先解析为绝对路径再检查
@@ -47,10 +67,11 @@ func (lfs *LocalFS) IsInside(path string) bool {
 		return false
 	}
 
+	windows := runtime.GOOS == "windows"
+
 	// 1. 检查是否在workDir内
 	workDirAbs, _ := filepath.Abs(lfs.workDir)
-	relativeToWork, err := filepath.Rel(workDirAbs, resolved)
-	if err == nil && !strings.HasPrefix(relativeToWork, "..") && !filepath.IsAbs(relativeToWork) {
+	if pathWithin(workDirAbs, resolved, windows) {
 		return true
 	}
 
@@ -65,8 +86,7 @@ func (lfs *LocalFS) IsInside(path string) bool {
 		if err != nil {
 			continue
 		}
-		relative, err := filepath.Rel(resolvedAllowed, resolved)
-		if err == nil && !strings.HasPrefix(relative, "..") && !filepath.IsAbs(relative) {
+		if pathWithin(resolvedAllowed, resolved, windows) {
 			return true
 		}
 	}
@@ -78,7 +98,7 @@ func (lfs *LocalFS) IsInside(path string) bool {
 func (lfs *LocalFS) Read(ctx context.Context, path string) (string, error) {
 	resolved := lfs.Resolve(path)
 	if !lfs.IsInside(resolved) {
-		return "", fmt.Errorf("path outside sandbox: %s", path)
+		return "", fmt.Errorf("%w: %s", ErrOutsideSandbox, path)
 	}
 
 	data, err := os.ReadFile(resolved)
@@ -93,7 +113,7 @@ func (lfs *LocalFS) Read(ctx context.Context, path string) (string, error) {
 func (lfs *LocalFS) Write(ctx context.Context, path string, content string) error {
 	resolved := lfs.Resolve(path)
 	if !lfs.IsInside(resolved) {
-		return fmt.Errorf("path outside sandbox: %s", path)
+		return fmt.Errorf("%w: %s", ErrOutsideSandbox, path)
 	}
 
 	// 确保目录存在
@@ -124,7 +144,7 @@ func (lfs *LocalFS) Temp(name string) string {
 func (lfs *LocalFS) Stat(ctx context.Context, path string) (FileInfo, error) {
 	resolved := lfs.Resolve(path)
 	if !lfs.IsInside(resolved) {
-		return FileInfo{}, fmt.Errorf("path outside sandbox: %s", path)
+		return FileInfo{}, fmt.Errorf("%w: %s", ErrOutsideSandbox, path)
 	}
 
 	info, err := os.Stat(resolved)
@@ -3,13 +3,23 @@ package sandbox
 import (
 	"context"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"runtime"
 	"testing"
 	"time"
 
 	"github.com/astercloud/aster/pkg/types"
 )
 
+// skipUnlessPOSIXShell 跳过依赖 POSIX shell 语法的测试
+func skipUnlessPOSIXShell(t *testing.T) {
+	t.Helper()
+	if detectShell(runtime.GOOS, ShellAuto, exec.LookPath).isWindows() {
+		t.Skip("requires a POSIX shell")
+	}
+}
+
 func TestLocalSandbox_Basic(t *testing.T) {
 	tmpDir, err := os.MkdirTemp("", "sandbox-test-*")
 	if err != nil {
@@ -35,6 +45,8 @@ func TestLocalSandbox_Basic(t *testing.T) {
 }
 
 func TestLocalSandbox_Exec(t *testing.T) {
+	skipUnlessPOSIXShell(t)
+
 	tmpDir, err := os.MkdirTemp("", "sandbox-test-*")
 	if err != nil {
 		t.Fatalf("failed to create temp dir: %v", err)
@@ -65,6 +77,8 @@ func TestLocalSandbox_Exec(t *testing.T) {
 }
 
 func TestLocalSandbox_DangerousCommand(t *testing.T) {
+	skipUnlessPOSIXShell(t)
+
 	tmpDir, err := os.MkdirTemp("", "sandbox-test-*")
 	if err != nil {
 		t.Fatalf("failed to create temp dir: %v", err)
@@ -227,6 +241,8 @@ func TestLocalSandbox_IgnoreViolations(t *testing.T) {
 }
 
 func TestLocalSandbox_Timeout(t *testing.T) {
+	skipUnlessPOSIXShell(t)
+
 	tmpDir, err := os.MkdirTemp("", "sandbox-test-*")
 	if err != nil {
 		t.Fatalf("failed to create temp dir: %v", err)
@@ -396,6 +412,8 @@ func TestLocalSandbox_SecurityLevels(t *testing.T) {
 }
 
 func TestLocalSandbox_EnhancedDangerousPatterns(t *testing.T) {
+	skipUnlessPOSIXShell(t)
+
 	tmpDir, err := os.MkdirTemp("", "sandbox-test-*")
 	if err != nil {
 		t.Fatalf("failed to create temp dir: %v", err)
@@ -2,24 +2,115 @@ package sandbox
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
 	"time"
+
+	"github.com/bmatcuk/doublestar/v4"
+
+	"github.com/astercloud/aster/pkg/types"
 )
 
+const defaultMockWorkDir = "/mock/workspace"
+
+// ErrUnscriptedCommand Strict 模式下没有脚本匹配的命令
+var ErrUnscriptedCommand = errors.New("mock sandbox: unscripted command")
+
+// MockExecRecord 一次命令执行的记录，用于在测试中断言
+type MockExecRecord struct {
+	Command string
+	WorkDir string
+	Env     map[string]string
+	// Script 匹配的脚本下标，-1 表示没有脚本匹配
+	Script int
+	Result *ExecResult
+	Error  string
+	Time   time.Time
+}
+
+// mockScript 一条命令脚本及其已匹配次数
+type mockScript struct {
+	types.MockCommand
+	re   *regexp.Regexp
+	used int
+}
+
 // MockSandbox 模拟沙箱(用于测试)
+// 可通过 types.MockSandboxConfig 脚本化命令的输出、退出码、延迟和虚拟文件，
+// 并记录所有执行过的命令
 type MockSandbox struct {
 	kind    string
 	workDir string
 	fs      *MockFS
+
+	mu         sync.Mutex
+	scripts    []*mockScript
+	defaultCmd *types.MockCommand
+	strict     bool
+	history    []MockExecRecord
 }
 
 // NewMockSandbox 创建模拟沙箱
 func NewMockSandbox() *MockSandbox {
 	return &MockSandbox{
 		kind:    "mock",
-		workDir: "/mock/workspace",
-		fs:      NewMockFS(),
+		workDir: defaultMockWorkDir,
+		fs:      newMockFS(defaultMockWorkDir),
+	}
+}
+
+// NewScriptedMockSandbox 按配置创建脚本化的模拟沙箱，config 为 nil 时等同 NewMockSandbox
+func NewScriptedMockSandbox(config *types.MockSandboxConfig) (*MockSandbox, error) {
+	ms := NewMockSandbox()
+	if config == nil {
+		return ms, nil
+	}
+	if config.WorkDir != "" {
+		ms.workDir = path.Clean(config.WorkDir)
+		ms.fs.root = ms.workDir
+	}
+	for p, content := range config.Files {
+		ms.fs.set(p, content)
+	}
+	ms.defaultCmd = config.Default
+	ms.strict = config.Strict
+	if err := ms.AddCommands(config.Commands...); err != nil {
+		return nil, err
 	}
+	return ms, nil
+}
+
+// AddCommands 追加命令脚本
+func (ms *MockSandbox) AddCommands(commands ...types.MockCommand) error {
+	scripts := make([]*mockScript, 0, len(commands))
+	for _, c := range commands {
+		re, err := compileMockMatch(c.Match)
+		if err != nil {
+			return fmt.Errorf("mock command %q: %w", c.Match, err)
+		}
+		scripts = append(scripts, &mockScript{MockCommand: c, re: re})
+	}
+	ms.mu.Lock()
+	ms.scripts = append(ms.scripts, scripts...)
+	ms.mu.Unlock()
+	return nil
+}
+
+// compileMockMatch 将匹配规则转为正则：re: 前缀为正则，含 * 为通配，否则精确匹配
+func compileMockMatch(match string) (*regexp.Regexp, error) {
+	if expr, ok := strings.CutPrefix(match, "re:"); ok {
+		return regexp.Compile(expr)
+	}
+	parts := strings.Split(match, "*")
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+	return regexp.Compile("(?s)^" + strings.Join(parts, ".*") + "$")
 }
 
 func (ms *MockSandbox) Kind() string {
@@ -35,12 +126,147 @@ func (ms *MockSandbox) FS() SandboxFS {
 }
 
 func (ms *MockSandbox) Exec(ctx context.Context, cmd string, opts *ExecOptions) (*ExecResult, error) {
-	// 模拟命令执行
-	return &ExecResult{
-		Code:   0,
-		Stdout: "Mock output for: " + cmd,
-		Stderr: "",
-	}, nil
+	if opts == nil {
+		opts = &ExecOptions{}
+	}
+	record := MockExecRecord{Command: cmd, WorkDir: ms.workDir, Env: opts.Env, Script: -1, Time: time.Now()}
+	if opts.WorkDir != "" {
+		record.WorkDir = ms.fs.Resolve(opts.WorkDir)
+	}
+
+	ms.mu.Lock()
+	script := ms.defaultCmd
+	for i, s := range ms.scripts {
+		if (s.Times == 0 || s.used < s.Times) && s.re.MatchString(cmd) {
+			s.used++
+			record.Script = i
+			script = &s.MockCommand
+			break
+		}
+	}
+	ms.mu.Unlock()
+
+	result, err := ms.run(ctx, cmd, script, record.Script >= 0, opts.Timeout)
+	record.Result = result
+	if err != nil {
+		record.Error = err.Error()
+	}
+	ms.mu.Lock()
+	ms.history = append(ms.history, record)
+	ms.mu.Unlock()
+	return result, err
+}
+
+// run 按脚本生成执行结果
+func (ms *MockSandbox) run(ctx context.Context, cmd string, script *types.MockCommand, matched bool, timeout time.Duration) (*ExecResult, error) {
+	if !matched && ms.strict {
+		return nil, fmt.Errorf("%w: %s", ErrUnscriptedCommand, cmd)
+	}
+	if script == nil {
+		// 模拟命令执行
+		return &ExecResult{
+			Code:   0,
+			Stdout: "Mock output for: " + cmd,
+			Stderr: "",
+		}, nil
+	}
+
+	if latency := time.Duration(script.LatencyMs) * time.Millisecond; latency > 0 {
+		wait, timedOut := latency, false
+		if timeout > 0 && timeout < latency {
+			wait, timedOut = timeout, true
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+		if timedOut {
+			return &ExecResult{Code: 124, Stderr: fmt.Sprintf("command timed out after %s", timeout)}, nil
+		}
+	}
+
+	if script.Error != "" {
+		return nil, errors.New(script.Error)
+	}
+	for p, content := range script.WriteFiles {
+		_ = ms.fs.Write(ctx, p, content)
+	}
+	for _, p := range script.RemoveFiles {
+		ms.fs.Remove(p)
+	}
+	return &ExecResult{Code: script.ExitCode, Stdout: script.Stdout, Stderr: script.Stderr}, nil
+}
+
+// History 返回所有执行记录，按执行顺序排列
+func (ms *MockSandbox) History() []MockExecRecord {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	return append([]MockExecRecord(nil), ms.history...)
+}
+
+// Commands 返回所有执行过的命令
+func (ms *MockSandbox) Commands() []string {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	commands := make([]string, len(ms.history))
+	for i, r := range ms.history {
+		commands[i] = r.Command
+	}
+	return commands
+}
+
+// Executed 返回与 match 匹配的已执行命令数，match 语法同 types.MockCommand.Match
+func (ms *MockSandbox) Executed(match string) int {
+	re, err := compileMockMatch(match)
+	if err != nil {
+		return 0
+	}
+	n := 0
+	for _, cmd := range ms.Commands() {
+		if re.MatchString(cmd) {
+			n++
+		}
+	}
+	return n
+}
+
+// Unmatched 返回没有脚本匹配的已执行命令
+func (ms *MockSandbox) Unmatched() []string {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	var commands []string
+	for _, r := range ms.history {
+		if r.Script < 0 {
+			commands = append(commands, r.Command)
+		}
+	}
+	return commands
+}
+
+// UnusedCommands 返回从未被匹配的脚本的 Match，可用于断言预期的命令都执行过
+func (ms *MockSandbox) UnusedCommands() []string {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	var unused []string
+	for _, s := range ms.scripts {
+		if s.used == 0 {
+			unused = append(unused, s.Match)
+		}
+	}
+	return unused
+}
+
+// ResetHistory 清空执行记录和脚本的匹配次数
+func (ms *MockSandbox) ResetHistory() {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.history = nil
+	for _, s := range ms.scripts {
+		s.used = 0
+	}
 }
 
 func (ms *MockSandbox) Watch(paths []string, listener FileChangeListener) (string, error) {
@@ -55,19 +281,29 @@ func (ms *MockSandbox) Dispose() error {
 	return nil
 }
 
-// MockFS 模拟文件系统
+// MockFS 模拟文件系统，相对路径基于沙箱工作目录
 type MockFS struct {
+	mu    sync.RWMutex
+	root  string
 	files map[string]string
 }
 
 func NewMockFS() *MockFS {
+	return newMockFS("")
+}
+
+func newMockFS(root string) *MockFS {
 	return &MockFS{
+		root:  root,
 		files: make(map[string]string),
 	}
 }
 
-func (mfs *MockFS) Resolve(path string) string {
-	return path
+func (mfs *MockFS) Resolve(p string) string {
+	if mfs.root == "" || path.IsAbs(p) {
+		return p
+	}
+	return path.Join(mfs.root, p)
 }
 
 func (mfs *MockFS) IsInside(path string) bool {
@@ -75,23 +311,52 @@ func (mfs *MockFS) IsInside(path string) bool {
 }
 
 func (mfs *MockFS) Read(ctx context.Context, path string) (string, error) {
-	if content, ok := mfs.files[path]; ok {
+	mfs.mu.RLock()
+	defer mfs.mu.RUnlock()
+	if content, ok := mfs.files[mfs.Resolve(path)]; ok {
 		return content, nil
 	}
 	return "", fmt.Errorf("file not found: %s", path)
 }
 
 func (mfs *MockFS) Write(ctx context.Context, path string, content string) error {
-	mfs.files[path] = content
+	mfs.set(path, content)
 	return nil
 }
 
+func (mfs *MockFS) set(path, content string) {
+	mfs.mu.Lock()
+	defer mfs.mu.Unlock()
+	mfs.files[mfs.Resolve(path)] = content
+}
+
+// Remove 删除文件，文件不存在时忽略
+func (mfs *MockFS) Remove(path string) {
+	mfs.mu.Lock()
+	defer mfs.mu.Unlock()
+	delete(mfs.files, mfs.Resolve(path))
+}
+
+// Files 返回所有文件内容的副本，键为解析后的路径
+func (mfs *MockFS) Files() map[string]string {
+	mfs.mu.RLock()
+	defer mfs.mu.RUnlock()
+	files := make(map[string]string, len(mfs.files))
+	for p, content := range mfs.files {
+		files[p] = content
+	}
+	return files
+}
+
 func (mfs *MockFS) Temp(name string) string {
 	return "/tmp/" + name
 }
 
 func (mfs *MockFS) Stat(ctx context.Context, path string) (FileInfo, error) {
-	if content, ok := mfs.files[path]; ok {
+	mfs.mu.RLock()
+	defer mfs.mu.RUnlock()
+	resolved := mfs.Resolve(path)
+	if content, ok := mfs.files[resolved]; ok {
 		return FileInfo{
 			Path:    path,
 			Size:    int64(len(content)),
@@ -100,14 +365,66 @@ func (mfs *MockFS) Stat(ctx context.Context, path string) (FileInfo, error) {
 			Mode:    0644,
 		}, nil
 	}
+	// 有文件位于其下的路径视为目录
+	prefix := strings.TrimSuffix(resolved, "/") + "/"
+	for p := range mfs.files {
+		if strings.HasPrefix(p, prefix) {
+			return FileInfo{Path: path, ModTime: time.Now(), IsDir: true, Mode: 0755}, nil
+		}
+	}
 	return FileInfo{}, fmt.Errorf("file not found: %s", path)
 }
 
+// Glob 在 opts.CWD（默认工作目录）下按 doublestar 模式匹配文件，结果已排序；
+// 未设置工作目录的 MockFS 返回所有文件
 func (mfs *MockFS) Glob(ctx context.Context, pattern string, opts *GlobOptions) ([]string, error) {
-	// 简单返回所有文件
+	if opts == nil {
+		opts = &GlobOptions{}
+	}
+	mfs.mu.RLock()
+	defer mfs.mu.RUnlock()
+
 	results := make([]string, 0, len(mfs.files))
-	for path := range mfs.files {
-		results = append(results, path)
+	if mfs.root == "" {
+		for p := range mfs.files {
+			results = append(results, p)
+		}
+		sort.Strings(results)
+		return results, nil
+	}
+
+	cwd := mfs.root
+	if opts.CWD != "" {
+		cwd = mfs.Resolve(opts.CWD)
 	}
+	prefix := strings.TrimSuffix(cwd, "/") + "/"
+	for p := range mfs.files {
+		rel, ok := strings.CutPrefix(p, prefix)
+		if !ok {
+			continue
+		}
+		if ok, err := doublestar.Match(pattern, rel); err != nil {
+			return nil, fmt.Errorf("glob pattern: %w", err)
+		} else if !ok || mockIgnored(rel, opts.Ignore) {
+			continue
+		}
+		if opts.Absolute {
+			results = append(results, p)
+		} else if r, ok := strings.CutPrefix(p, strings.TrimSuffix(mfs.root, "/")+"/"); ok {
+			results = append(results, r)
+		} else {
+			results = append(results, rel)
+		}
+	}
+	sort.Strings(results)
 	return results, nil
 }
+
+func mockIgnored(rel string, ignore []string) bool {
+	for _, pattern := range ignore {
+		if ok, _ := doublestar.Match(pattern, rel); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,128 @@
+package sandbox
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/astercloud/aster/pkg/types"
+)
+
+func TestScriptedMockSandbox(t *testing.T) {
+	ctx := context.Background()
+	sb, err := NewFactory().Create(&types.SandboxConfig{
+		Kind: types.SandboxKindMock,
+		Mock: &types.MockSandboxConfig{
+			WorkDir: "/repo",
+			Files:   map[string]string{"go.mod": "module demo\n", "/etc/hosts": "127.0.0.1 localhost\n"},
+			Commands: []types.MockCommand{
+				{Match: "go test ./...", Stdout: "FAIL\n", ExitCode: 1, Times: 1},
+				{Match: "go test ./...", Stdout: "ok\n"},
+				{Match: "go build *", WriteFiles: map[string]string{"bin/demo": "ELF"}},
+				{Match: "re:^rm -f (.+)$", RemoveFiles: []string{"go.mod"}},
+				{Match: "sleep", LatencyMs: 200},
+				{Match: "docker *", Error: "docker daemon not running"},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ms := sb.(*MockSandbox)
+	if ms.WorkDir() != "/repo" {
+		t.Errorf("WorkDir() = %q", ms.WorkDir())
+	}
+
+	// 同一命令依次返回不同结果
+	if r, _ := ms.Exec(ctx, "go test ./...", nil); r.Code != 1 || r.Stdout != "FAIL\n" {
+		t.Errorf("first go test = %+v", r)
+	}
+	if r, _ := ms.Exec(ctx, "go test ./...", nil); r.Code != 0 || r.Stdout != "ok\n" {
+		t.Errorf("second go test = %+v", r)
+	}
+
+	// 命令副作用写入虚拟文件系统
+	if _, err := ms.Exec(ctx, "go build -o bin/demo .", &ExecOptions{WorkDir: "cmd"}); err != nil {
+		t.Fatal(err)
+	}
+	if content, err := ms.FS().Read(ctx, "/repo/bin/demo"); err != nil || content != "ELF" {
+		t.Errorf("Read(bin/demo) = %q, %v", content, err)
+	}
+	if info, err := ms.FS().Stat(ctx, "bin"); err != nil || !info.IsDir {
+		t.Errorf("Stat(bin) = %+v, %v", info, err)
+	}
+	if matches, _ := ms.FS().Glob(ctx, "**/*", nil); !slices.Equal(matches, []string{"bin/demo", "go.mod"}) {
+		t.Errorf("Glob(**/*) = %v", matches)
+	}
+	if _, err := ms.Exec(ctx, "rm -f go.mod", nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ms.FS().Read(ctx, "go.mod"); err == nil {
+		t.Error("go.mod should be removed")
+	}
+
+	// 延迟超过超时时间按超时处理
+	start := time.Now()
+	if r, _ := ms.Exec(ctx, "sleep", &ExecOptions{Timeout: 20 * time.Millisecond}); r.Code != 124 {
+		t.Errorf("timed out sleep = %+v", r)
+	}
+	if time.Since(start) >= 200*time.Millisecond {
+		t.Error("timeout should cut the latency short")
+	}
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := ms.Exec(cancelled, "sleep", nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled sleep err = %v", err)
+	}
+
+	if _, err := ms.Exec(ctx, "docker ps", nil); err == nil || err.Error() != "docker daemon not running" {
+		t.Errorf("docker err = %v", err)
+	}
+	if r, _ := ms.Exec(ctx, "ls", nil); r.Stdout != "Mock output for: ls" {
+		t.Errorf("unscripted = %+v", r)
+	}
+
+	// 执行记录
+	if n := ms.Executed("go test *"); n != 2 {
+		t.Errorf("Executed(go test *) = %d", n)
+	}
+	if got := ms.Unmatched(); !slices.Equal(got, []string{"ls"}) {
+		t.Errorf("Unmatched() = %v", got)
+	}
+	if got := ms.UnusedCommands(); len(got) != 0 {
+		t.Errorf("UnusedCommands() = %v", got)
+	}
+	history := ms.History()
+	if len(history) != 8 || history[2].WorkDir != "/repo/cmd" || history[2].Script != 2 || history[6].Error == "" {
+		t.Errorf("History() = %+v", history)
+	}
+
+	ms.ResetHistory()
+	if len(ms.Commands()) != 0 || len(ms.UnusedCommands()) != 6 {
+		t.Errorf("after reset: commands %v, unused %v", ms.Commands(), ms.UnusedCommands())
+	}
+}
+
+func TestScriptedMockSandbox_Strict(t *testing.T) {
+	ms, err := NewScriptedMockSandbox(&types.MockSandboxConfig{
+		Strict:   true,
+		Commands: []types.MockCommand{{Match: "git status", Stdout: "clean"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r, err := ms.Exec(context.Background(), "git status", nil); err != nil || r.Stdout != "clean" {
+		t.Errorf("git status = %+v, %v", r, err)
+	}
+	if _, err := ms.Exec(context.Background(), "git push", nil); !errors.Is(err, ErrUnscriptedCommand) {
+		t.Errorf("unscripted err = %v", err)
+	}
+
+	if _, err := NewScriptedMockSandbox(&types.MockSandboxConfig{
+		Commands: []types.MockCommand{{Match: "re:("}},
+	}); err == nil {
+		t.Error("invalid regexp should be rejected")
+	}
+}
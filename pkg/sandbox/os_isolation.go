@@ -0,0 +1,306 @@
+package sandbox
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/astercloud/aster/pkg/types"
+)
+
+// OS 级隔离后端
+const (
+	OSBackendSeatbelt   = "seatbelt"
+	OSBackendBubblewrap = "bubblewrap"
+)
+
+// OSSandboxCapability 操作系统级隔离能力探测结果
+type OSSandboxCapability struct {
+	Backend   string `json:"backend,omitempty"`
+	Path      string `json:"path,omitempty"`
+	Available bool   `json:"available"`
+	Reason    string `json:"reason,omitempty"` // 不可用原因
+}
+
+// OSIsolationStatus LocalSandbox 的 OS 级隔离状态
+type OSIsolationStatus struct {
+	Backend string `json:"backend,omitempty"`
+	Active  bool   `json:"active"`
+	Reason  string `json:"reason,omitempty"` // 未启用原因
+}
+
+// osSandboxProbe 探测结果在进程内缓存，测试可替换
+var osSandboxProbe = sync.OnceValue(probeOSSandbox)
+
+// ProbeOSSandbox 探测当前系统的 OS 级隔离能力（结果缓存）
+func ProbeOSSandbox() OSSandboxCapability {
+	return osSandboxProbe()
+}
+
+// probeOSSandbox 查找隔离工具并执行一条空命令，确认当前环境允许创建隔离
+// （容器内常缺少创建命名空间的权限）
+func probeOSSandbox() OSSandboxCapability {
+	var capability OSSandboxCapability
+	var args []string
+	switch runtime.GOOS {
+	case "darwin":
+		capability.Backend = OSBackendSeatbelt
+		args = []string{"-p", "(version 1)(allow default)", "/usr/bin/true"}
+	case "linux":
+		capability.Backend = OSBackendBubblewrap
+		args = []string{"--ro-bind", "/", "/", "--dev", "/dev", "--unshare-net", "--", getShell(), "-c", "true"}
+	default:
+		capability.Reason = "os isolation is not supported on " + runtime.GOOS
+		return capability
+	}
+
+	name := "sandbox-exec"
+	if capability.Backend == OSBackendBubblewrap {
+		name = "bwrap"
+	}
+	path, err := exec.LookPath(name)
+	if err != nil {
+		capability.Reason = name + " not found in PATH"
+		return capability
+	}
+	capability.Path = path
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if output, err := exec.CommandContext(ctx, path, args...).CombinedOutput(); err != nil {
+		capability.Reason = fmt.Sprintf("%s probe failed: %v %s", name, err, strings.TrimSpace(string(output)))
+		return capability
+	}
+	capability.Available = true
+	return capability
+}
+
+// toolCacheDirs 主目录下包管理器和构建工具的缓存目录，隔离中允许写入
+var toolCacheDirs = []string{".cache", ".npm", "go"}
+
+// hiddenHomeDirs 主目录下存放凭据的目录，隔离中不可读
+var hiddenHomeDirs = []string{".ssh", ".aws", ".gnupg", ".azure", ".config/gcloud", ".kube", ".docker"}
+
+// hiddenSystemDirs 隔离中不可读的系统目录
+var hiddenSystemDirs = []string{"/root"}
+
+// hiddenSystemFiles 隔离中不可读的账户凭据文件
+var hiddenSystemFiles = []string{"/etc/shadow", "/etc/gshadow", "/etc/sudoers", "/etc/master.passwd"}
+
+// osPolicy OS 级隔离策略：只读整个文件系统，只允许写入指定目录，隐藏凭据路径，可禁止网络
+type osPolicy struct {
+	backend             string
+	path                string
+	writable            []string
+	hiddenDirs          []string
+	hiddenFiles         []string
+	denyNetwork         bool
+	proxyPorts          []int
+	allowLocalBinding   bool
+	allowUnixSockets    []string
+	allowAllUnixSockets bool
+	nested              bool
+}
+
+// newOSPolicy 根据沙箱设置构建隔离策略
+// 隔离不可用时，auto 模式降级为命令过滤并记录原因，required 模式返回错误
+func newOSPolicy(settings *types.SandboxSettings, workDir string, allowPaths []string) (*osPolicy, OSIsolationStatus, error) {
+	if settings == nil || !settings.Enabled {
+		return nil, OSIsolationStatus{Reason: "sandbox settings not enabled"}, nil
+	}
+	mode := settings.OSIsolation
+	if mode == "" {
+		mode = types.OSIsolationAuto
+	}
+	if mode == types.OSIsolationOff {
+		return nil, OSIsolationStatus{Reason: "os isolation disabled by configuration"}, nil
+	}
+
+	capability := osSandboxProbe()
+	if !capability.Available {
+		if mode == types.OSIsolationRequired {
+			return nil, OSIsolationStatus{}, fmt.Errorf("os isolation required but unavailable: %s", capability.Reason)
+		}
+		sandboxLogger.Warn(context.Background(), "OS isolation unavailable, falling back to command filtering", map[string]any{
+			"backend": capability.Backend,
+			"reason":  capability.Reason,
+		})
+		return nil, OSIsolationStatus{Backend: capability.Backend, Reason: capability.Reason}, nil
+	}
+
+	home, _ := os.UserHomeDir()
+	policy := &osPolicy{
+		backend:     capability.Backend,
+		path:        capability.Path,
+		writable:    writablePaths(workDir, allowPaths, home),
+		hiddenDirs:  hiddenDirs(home, append([]string{workDir}, allowPaths...)),
+		hiddenFiles: hiddenSystemFiles,
+		nested:      settings.EnableWeakerNestedSandbox,
+	}
+	if network := settings.Network; network != nil {
+		// 内核层无法按主机过滤，配置了 AllowedHosts 时禁止直连，只放行本地代理端口
+		policy.denyNetwork = network.Disabled || len(network.AllowedHosts) > 0
+		if !network.Disabled {
+			for _, port := range []int{network.HTTPProxyPort, network.SOCKSProxyPort} {
+				if port > 0 {
+					policy.proxyPorts = append(policy.proxyPorts, port)
+				}
+			}
+		}
+		policy.allowLocalBinding = network.AllowLocalBinding
+		policy.allowUnixSockets = network.AllowUnixSockets
+		policy.allowAllUnixSockets = network.AllowAllUnixSockets
+	}
+	return policy, OSIsolationStatus{Backend: capability.Backend, Active: true}, nil
+}
+
+// writablePaths 可写目录：工作目录、允许路径、临时目录和工具缓存目录，
+// 同时包含解析符号链接后的路径（macOS 的 /tmp 指向 /private/tmp）
+func writablePaths(workDir string, allowPaths []string, home string) []string {
+	candidates := append([]string{workDir}, allowPaths...)
+	candidates = append(candidates, os.TempDir(), "/tmp")
+	if home != "" {
+		for _, dir := range toolCacheDirs {
+			candidates = append(candidates, filepath.Join(home, dir))
+		}
+	}
+
+	var paths []string
+	for _, p := range candidates {
+		if p == "" {
+			continue
+		}
+		paths = append(paths, filepath.Clean(p))
+		if resolved, err := filepath.EvalSymlinks(p); err == nil {
+			paths = append(paths, resolved)
+		}
+	}
+	slices.Sort(paths)
+	return slices.Compact(paths)
+}
+
+// hiddenDirs 隔离中隐藏的凭据目录，包含工作目录或允许路径的目录不隐藏
+func hiddenDirs(home string, keep []string) []string {
+	candidates := slices.Clone(hiddenSystemDirs)
+	if home != "" {
+		for _, dir := range hiddenHomeDirs {
+			candidates = append(candidates, filepath.Join(home, dir))
+		}
+	}
+
+	var dirs []string
+	for _, dir := range candidates {
+		covers := false
+		for _, p := range keep {
+			if p != "" && isWithinDir(filepath.Clean(p), dir) {
+				covers = true
+			}
+		}
+		if !covers {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+// isWithinDir p 是否为 dir 或位于 dir 之下
+func isWithinDir(p, dir string) bool {
+	return p == dir || strings.HasPrefix(p, dir+string(filepath.Separator))
+}
+
+// command 构建在隔离中执行 shell 脚本的命令
+func (p *osPolicy) command(ctx context.Context, shell, script, workDir string) *exec.Cmd {
+	var args []string
+	switch p.backend {
+	case OSBackendSeatbelt:
+		args = []string{"-p", p.seatbeltProfile(), shell, "-c", script}
+	default:
+		args = p.bwrapArgs(shell, script, workDir)
+	}
+	return exec.CommandContext(ctx, p.path, args...)
+}
+
+// seatbeltProfile 生成 sandbox-exec 配置：默认允许，禁止读取凭据路径，禁止写入可写目录以外的路径，
+// 按网络设置禁止网络访问（后出现的规则优先）
+func (p *osPolicy) seatbeltProfile() string {
+	var b strings.Builder
+	b.WriteString("(version 1)\n(allow default)\n(deny file-read*")
+	for _, path := range p.hiddenDirs {
+		fmt.Fprintf(&b, "\n  (subpath %s)", seatbeltString(path))
+	}
+	for _, path := range p.hiddenFiles {
+		fmt.Fprintf(&b, "\n  (literal %s)", seatbeltString(path))
+	}
+	b.WriteString(")\n(allow file-read*")
+	for _, path := range p.writable {
+		fmt.Fprintf(&b, "\n  (subpath %s)", seatbeltString(path))
+	}
+	b.WriteString(")\n(deny file-write*)\n(allow file-write*")
+	for _, path := range p.writable {
+		fmt.Fprintf(&b, "\n  (subpath %s)", seatbeltString(path))
+	}
+	b.WriteString("\n  (literal \"/dev/null\")\n  (literal \"/dev/zero\")\n  (regex #\"^/dev/tty\")\n  (regex #\"^/dev/fd/\"))\n")
+
+	if p.denyNetwork {
+		b.WriteString("(deny network*)\n")
+		for _, port := range p.proxyPorts {
+			fmt.Fprintf(&b, "(allow network-outbound (remote ip \"localhost:%d\"))\n", port)
+		}
+		if p.allowLocalBinding {
+			b.WriteString("(allow network-bind (local ip \"localhost:*\"))\n")
+			b.WriteString("(allow network-inbound (local ip \"localhost:*\"))\n")
+			b.WriteString("(allow network-outbound (remote ip \"localhost:*\"))\n")
+		}
+		if p.allowAllUnixSockets {
+			b.WriteString("(allow network* (remote unix-socket))\n")
+		} else {
+			for _, socket := range p.allowUnixSockets {
+				fmt.Fprintf(&b, "(allow network-outbound (remote unix-socket (path-literal %s)))\n", seatbeltString(socket))
+			}
+		}
+	}
+	return b.String()
+}
+
+// seatbeltString 转义为 seatbelt 字符串字面量
+func seatbeltString(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return `"` + strings.ReplaceAll(s, `"`, `\"`) + `"`
+}
+
+// bwrapArgs 生成 bubblewrap 参数：根目录只读挂载，凭据目录以空 tmpfs 覆盖、凭据文件以 /dev/null 覆盖，
+// 可写目录读写挂载，禁止网络时创建独立网络命名空间（只有回环接口，Unix Socket 文件仍可访问，
+// 宿主机上的代理端口不可达）
+func (p *osPolicy) bwrapArgs(shell, script, workDir string) []string {
+	args := []string{"--ro-bind", "/", "/", "--dev", "/dev"}
+	for _, path := range p.hiddenDirs {
+		if info, err := os.Stat(path); err == nil && info.IsDir() {
+			args = append(args, "--tmpfs", path)
+		}
+	}
+	for _, path := range p.hiddenFiles {
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			args = append(args, "--ro-bind", "/dev/null", path)
+		}
+	}
+	for _, path := range p.writable {
+		if _, err := os.Stat(path); err == nil {
+			args = append(args, "--bind", path, path)
+		}
+	}
+	if !p.nested {
+		args = append(args, "--unshare-pid", "--proc", "/proc")
+	}
+	if p.denyNetwork {
+		args = append(args, "--unshare-net")
+	}
+	args = append(args, "--die-with-parent", "--chdir", workDir, "--", shell, "-c", script)
+	return args
+}
@@ -0,0 +1,239 @@
+package sandbox
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/astercloud/aster/pkg/types"
+)
+
+// stubOSSandboxProbe 替换能力探测结果
+func stubOSSandboxProbe(t *testing.T, capability OSSandboxCapability) {
+	t.Helper()
+	original := osSandboxProbe
+	osSandboxProbe = func() OSSandboxCapability { return capability }
+	t.Cleanup(func() { osSandboxProbe = original })
+}
+
+func TestNewOSPolicy_Degradation(t *testing.T) {
+	stubOSSandboxProbe(t, OSSandboxCapability{Backend: OSBackendBubblewrap, Reason: "bwrap not found in PATH"})
+	workDir := t.TempDir()
+
+	policy, status, err := newOSPolicy(&types.SandboxSettings{Enabled: true}, workDir, nil)
+	if err != nil || policy != nil {
+		t.Fatalf("auto mode should degrade without error, got policy=%v err=%v", policy, err)
+	}
+	if status.Active || status.Reason != "bwrap not found in PATH" {
+		t.Errorf("unexpected status: %+v", status)
+	}
+
+	_, _, err = newOSPolicy(&types.SandboxSettings{Enabled: true, OSIsolation: types.OSIsolationRequired}, workDir, nil)
+	if err == nil || !strings.Contains(err.Error(), "bwrap not found") {
+		t.Errorf("required mode should fail when unavailable, got %v", err)
+	}
+
+	_, err = NewLocalSandbox(&LocalSandboxConfig{
+		WorkDir:  workDir,
+		Settings: &types.SandboxSettings{Enabled: true, OSIsolation: types.OSIsolationRequired},
+	})
+	if err == nil {
+		t.Error("NewLocalSandbox should fail when os isolation is required but unavailable")
+	}
+
+	for _, settings := range []*types.SandboxSettings{nil, {Enabled: false}, {Enabled: true, OSIsolation: types.OSIsolationOff}} {
+		policy, status, err := newOSPolicy(settings, workDir, nil)
+		if err != nil || policy != nil || status.Active {
+			t.Errorf("settings %+v should not enable os isolation: policy=%v status=%+v err=%v", settings, policy, status, err)
+		}
+	}
+}
+
+func TestNewOSPolicy_Available(t *testing.T) {
+	stubOSSandboxProbe(t, OSSandboxCapability{Backend: OSBackendBubblewrap, Path: "/usr/bin/bwrap", Available: true})
+	workDir := t.TempDir()
+	extra := t.TempDir()
+
+	ls, err := NewLocalSandbox(&LocalSandboxConfig{
+		WorkDir:    workDir,
+		AllowPaths: []string{extra},
+		Settings: &types.SandboxSettings{
+			Enabled: true,
+			Network: &types.NetworkSandboxSettings{Disabled: true, AllowUnixSockets: []string{"/var/run/docker.sock"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewLocalSandbox: %v", err)
+	}
+	if status := ls.OSIsolation(); !status.Active || status.Backend != OSBackendBubblewrap {
+		t.Errorf("unexpected status: %+v", status)
+	}
+
+	policy := ls.osPolicy
+	for _, dir := range []string{workDir, extra, os.TempDir()} {
+		if !slices.Contains(policy.writable, filepath.Clean(dir)) {
+			t.Errorf("writable paths %v should contain %s", policy.writable, dir)
+		}
+	}
+	if !policy.denyNetwork || !slices.Equal(policy.allowUnixSockets, []string{"/var/run/docker.sock"}) {
+		t.Errorf("network policy not applied: %+v", policy)
+	}
+	if ls.CheckNetworkAccess("example.com", 443) {
+		t.Error("CheckNetworkAccess should deny when network is disabled")
+	}
+
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	policy, _, err = newOSPolicy(&types.SandboxSettings{
+		Enabled: true,
+		Network: &types.NetworkSandboxSettings{AllowedHosts: []string{"github.com"}, HTTPProxyPort: 8080},
+	}, workDir, nil)
+	if err != nil {
+		t.Fatalf("newOSPolicy: %v", err)
+	}
+	if !slices.Contains(policy.writable, filepath.Join(home, ".cache")) || !slices.Contains(policy.writable, filepath.Join(home, "go")) {
+		t.Errorf("tool caches should be writable: %v", policy.writable)
+	}
+	if !slices.Contains(policy.hiddenDirs, filepath.Join(home, ".ssh")) || !slices.Contains(policy.hiddenDirs, "/root") {
+		t.Errorf("credential directories should be hidden: %v", policy.hiddenDirs)
+	}
+	if !policy.denyNetwork || !slices.Equal(policy.proxyPorts, []int{8080}) {
+		t.Errorf("allowed hosts should deny direct network access except the proxy: %+v", policy)
+	}
+
+	if dirs := hiddenDirs(home, []string{filepath.Join(home, ".docker", "project")}); slices.Contains(dirs, filepath.Join(home, ".docker")) {
+		t.Errorf("directory containing the work dir must stay visible: %v", dirs)
+	}
+}
+
+func TestOSPolicy_SeatbeltProfile(t *testing.T) {
+	policy := &osPolicy{
+		backend:           OSBackendSeatbelt,
+		writable:          []string{"/work", `/tmp/we"ird`},
+		hiddenDirs:        []string{"/Users/me/.ssh"},
+		hiddenFiles:       []string{"/etc/master.passwd"},
+		denyNetwork:       true,
+		proxyPorts:        []int{8080},
+		allowLocalBinding: true,
+		allowUnixSockets:  []string{"/var/run/docker.sock"},
+	}
+	profile := policy.seatbeltProfile()
+
+	for _, want := range []string{
+		"(deny file-write*)",
+		`(subpath "/work")`,
+		`(subpath "/tmp/we\"ird")`,
+		"(deny file-read*",
+		`(subpath "/Users/me/.ssh")`,
+		`(literal "/etc/master.passwd")`,
+		"(deny network*)",
+		`(allow network-outbound (remote ip "localhost:8080"))`,
+		`(allow network-bind (local ip "localhost:*"))`,
+		`(remote unix-socket (path-literal "/var/run/docker.sock"))`,
+	} {
+		if !strings.Contains(profile, want) {
+			t.Errorf("profile missing %q:\n%s", want, profile)
+		}
+	}
+	if strings.Index(profile, "(deny file-write*)") > strings.Index(profile, "(allow file-write*") {
+		t.Error("write allowances must follow the write denial")
+	}
+
+	policy.denyNetwork = false
+	if strings.Contains(policy.seatbeltProfile(), "network") {
+		t.Error("network rules should be omitted when network is allowed")
+	}
+}
+
+func TestOSPolicy_BwrapArgs(t *testing.T) {
+	workDir := t.TempDir()
+	secrets := t.TempDir()
+	secretFile := filepath.Join(secrets, "shadow")
+	if err := os.WriteFile(secretFile, []byte("x"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	policy := &osPolicy{
+		backend:     OSBackendBubblewrap,
+		writable:    []string{workDir, filepath.Join(workDir, "missing")},
+		hiddenDirs:  []string{secrets, filepath.Join(secrets, "missing")},
+		hiddenFiles: []string{secretFile},
+		denyNetwork: true,
+	}
+	args := policy.bwrapArgs("/bin/bash", "echo hi", workDir)
+	joined := strings.Join(args, " ")
+
+	hide := strings.Index(joined, "--tmpfs "+secrets)
+	if hide < 0 || !strings.Contains(joined, "--ro-bind /dev/null "+secretFile) {
+		t.Errorf("credential paths should be hidden: %s", joined)
+	}
+	if hide > strings.Index(joined, "--bind "+workDir) {
+		t.Errorf("hidden directories must be mounted before writable paths: %s", joined)
+	}
+
+	if !strings.HasPrefix(joined, "--ro-bind / /") {
+		t.Errorf("root must be mounted read-only first: %s", joined)
+	}
+	if !strings.Contains(joined, "--bind "+workDir+" "+workDir) {
+		t.Errorf("work dir should be writable: %s", joined)
+	}
+	if strings.Contains(joined, "missing") {
+		t.Errorf("missing paths should not be bound: %s", joined)
+	}
+	for _, want := range []string{"--unshare-net", "--unshare-pid", "--chdir " + workDir, "-- /bin/bash -c echo hi"} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("args missing %q: %s", want, joined)
+		}
+	}
+
+	policy.denyNetwork, policy.nested = false, true
+	joined = strings.Join(policy.bwrapArgs("/bin/bash", "true", workDir), " ")
+	if strings.Contains(joined, "--unshare-net") || strings.Contains(joined, "--unshare-pid") {
+		t.Errorf("nested sandbox with network should not unshare pid or net: %s", joined)
+	}
+}
+
+func TestLocalSandbox_OSIsolationEnforced(t *testing.T) {
+	capability := probeOSSandbox()
+	if !capability.Available {
+		t.Skipf("os isolation unavailable: %s", capability.Reason)
+	}
+	stubOSSandboxProbe(t, capability)
+
+	workDir := t.TempDir()
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skip("no home directory")
+	}
+	outside, err := os.MkdirTemp(home, ".aster-os-isolation-*")
+	if err != nil {
+		t.Skipf("cannot create directory outside the sandbox: %v", err)
+	}
+	defer func() { _ = os.RemoveAll(outside) }()
+
+	ls, err := NewLocalSandbox(&LocalSandboxConfig{
+		WorkDir:  workDir,
+		Settings: &types.SandboxSettings{Enabled: true, OSIsolation: types.OSIsolationRequired},
+	})
+	if err != nil {
+		t.Fatalf("NewLocalSandbox: %v", err)
+	}
+
+	result, err := ls.Exec(context.Background(), "echo inside > inside.txt", nil)
+	if err != nil || result.Code != 0 {
+		t.Fatalf("write inside work dir should succeed: %+v %v", result, err)
+	}
+
+	result, err = ls.Exec(context.Background(), "echo outside > "+filepath.Join(outside, "outside.txt"), nil)
+	if err != nil {
+		t.Fatalf("Exec: %v", err)
+	}
+	if result.Code == 0 {
+		t.Error("write outside writable paths should be denied by the os sandbox")
+	}
+	if _, err := os.Stat(filepath.Join(outside, "outside.txt")); err == nil {
+		t.Error("file outside the sandbox was created")
+	}
+}
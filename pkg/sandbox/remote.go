@@ -9,6 +9,8 @@ import (
 	"io"
 	"net/http"
 	"time"
+
+	"github.com/astercloud/aster/pkg/httpclient"
 )
 
 // RemoteClient 远程沙箱客户端
@@ -27,13 +29,11 @@ func NewRemoteClient(config *RemoteClientConfig) *RemoteClient {
 	}
 
 	return &RemoteClient{
-		baseURL:   config.BaseURL,
-		apiKey:    config.APIKey,
-		apiSecret: config.APISecret,
-		httpClient: &http.Client{
-			Timeout: config.Timeout,
-		},
-		headers: config.Headers,
+		baseURL:    config.BaseURL,
+		apiKey:     config.APIKey,
+		apiSecret:  config.APISecret,
+		httpClient: httpclient.New(config.Timeout),
+		headers:    config.Headers,
 	}
 }
 
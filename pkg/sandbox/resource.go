@@ -0,0 +1,289 @@
+package sandbox
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/astercloud/aster/pkg/types"
+)
+
+// 受监控的资源
+const (
+	ResourceMemory    = "memory"
+	ResourceProcesses = "processes"
+	ResourceCPUTime   = "cpu_time"
+)
+
+// resourceSampleInterval 进程树资源采样间隔
+var resourceSampleInterval = 200 * time.Millisecond
+
+// ResourceUsage 命令的资源用量
+type ResourceUsage struct {
+	CPUTime    time.Duration `json:"cpu_time"`
+	PeakMemory int64         `json:"peak_memory"` // 字节
+	PeakProcs  int           `json:"peak_procs"`
+}
+
+// ResourceViolation 超出的资源限制
+type ResourceViolation struct {
+	Resource string `json:"resource"`
+	Limit    int64  `json:"limit"`
+	Value    int64  `json:"value"`
+}
+
+// Error 返回追加到命令输出的说明
+func (v *ResourceViolation) Error() string {
+	switch v.Resource {
+	case ResourceMemory:
+		return fmt.Sprintf("memory usage %d MB exceeded limit %d MB", v.Value>>20, v.Limit>>20)
+	case ResourceCPUTime:
+		return fmt.Sprintf("cpu time %v exceeded limit %v", time.Duration(v.Value), time.Duration(v.Limit))
+	default:
+		return fmt.Sprintf("%s %d exceeded limit %d", v.Resource, v.Value, v.Limit)
+	}
+}
+
+// ResourceEvent 命令资源事件
+type ResourceEvent struct {
+	Kind      string // types.ResourceEventUsage / types.ResourceEventLimitExceeded
+	Command   string
+	PID       int
+	Usage     ResourceUsage
+	Violation *ResourceViolation
+	Duration  time.Duration
+}
+
+// ToMonitorEvent 转换为 Monitor 通道事件
+func (e ResourceEvent) ToMonitorEvent() *types.MonitorResourceEvent {
+	event := &types.MonitorResourceEvent{
+		Kind:       e.Kind,
+		Command:    truncate(e.Command, 200),
+		PID:        e.PID,
+		CPUTimeMs:  e.Usage.CPUTime.Milliseconds(),
+		PeakMemory: e.Usage.PeakMemory,
+		PeakProcs:  e.Usage.PeakProcs,
+		DurationMs: e.Duration.Milliseconds(),
+	}
+	if e.Violation != nil {
+		event.Resource = e.Violation.Resource
+		event.Limit = e.Violation.Limit
+		event.Value = e.Violation.Value
+	}
+	return event
+}
+
+// ResourceEventListener 资源事件监听器
+type ResourceEventListener func(event ResourceEvent)
+
+// ResourceReporter 支持资源事件的沙箱
+type ResourceReporter interface {
+	OnResourceEvent(listener ResourceEventListener)
+}
+
+// ResourceLimitsFromConfig 将 SandboxConfig.Resources 合并到默认资源限制
+func ResourceLimitsFromConfig(cfg *types.ResourceLimits) *ResourceLimits {
+	limits := *DefaultResourceLimits
+	if cfg == nil {
+		return &limits
+	}
+	if cfg.MemoryLimit > 0 {
+		limits.MaxMemoryMB = int((cfg.MemoryLimit + 1<<20 - 1) >> 20)
+	}
+	if cfg.MaxProcesses > 0 {
+		limits.MaxProcesses = cfg.MaxProcesses
+	}
+	if cfg.CPUTime > 0 {
+		limits.CPUTimeLimit = cfg.CPUTime
+	}
+	if cfg.Timeout > 0 {
+		limits.MaxCPUTime = cfg.Timeout
+	}
+	limits.Nice = cfg.Nice
+	return &limits
+}
+
+// resourceSample 进程树资源采样
+type resourceSample struct {
+	memory  int64
+	procs   int
+	cpuTime time.Duration
+}
+
+// processControl 平台相关的进程树控制（Unix 进程组、Windows Job Object）
+// 由 newProcessControl 在进程启动前创建
+type processControl interface {
+	// attach 进程启动后应用限制
+	attach()
+	// sample 采样进程树资源使用，平台不支持时 ok 为 false
+	sample() (s resourceSample, ok bool)
+	// kill 终止整个进程树
+	kill() error
+	// usage 进程结束后的资源用量
+	usage() ResourceUsage
+	// close 释放资源
+	close()
+}
+
+// check 检查采样是否超出限制
+func (l *ResourceLimits) check(s resourceSample) *ResourceViolation {
+	if l == nil {
+		return nil
+	}
+	if l.MaxMemoryMB > 0 && s.memory > int64(l.MaxMemoryMB)<<20 {
+		return &ResourceViolation{Resource: ResourceMemory, Limit: int64(l.MaxMemoryMB) << 20, Value: s.memory}
+	}
+	if l.MaxProcesses > 0 && s.procs > l.MaxProcesses {
+		return &ResourceViolation{Resource: ResourceProcesses, Limit: int64(l.MaxProcesses), Value: int64(s.procs)}
+	}
+	if l.CPUTimeLimit > 0 && s.cpuTime > l.CPUTimeLimit {
+		return &ResourceViolation{Resource: ResourceCPUTime, Limit: int64(l.CPUTimeLimit), Value: int64(s.cpuTime)}
+	}
+	return nil
+}
+
+// resourceMonitor 周期采样进程树，超出限制时终止整个进程树
+type resourceMonitor struct {
+	control processControl
+	limits  *ResourceLimits
+
+	mu        sync.Mutex
+	peak      resourceSample
+	violation *ResourceViolation
+
+	stopCh chan struct{}
+	done   chan struct{}
+}
+
+// startResourceMonitor 启动监控，进程结束后调用 stop
+func startResourceMonitor(control processControl, limits *ResourceLimits) *resourceMonitor {
+	m := &resourceMonitor{
+		control: control,
+		limits:  limits,
+		stopCh:  make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go m.run()
+	return m
+}
+
+func (m *resourceMonitor) run() {
+	defer close(m.done)
+	ticker := time.NewTicker(resourceSampleInterval)
+	defer ticker.Stop()
+	for {
+		if m.sampleOnce() {
+			return
+		}
+		select {
+		case <-m.stopCh:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// sampleOnce 采样一次，返回是否应停止监控
+func (m *resourceMonitor) sampleOnce() bool {
+	s, ok := m.control.sample()
+	if !ok {
+		return true
+	}
+
+	m.mu.Lock()
+	m.peak.memory = max(m.peak.memory, s.memory)
+	m.peak.procs = max(m.peak.procs, s.procs)
+	m.peak.cpuTime = max(m.peak.cpuTime, s.cpuTime)
+	violation := m.limits.check(s)
+	if violation != nil {
+		m.violation = violation
+	}
+	m.mu.Unlock()
+
+	if violation != nil {
+		if err := m.control.kill(); err != nil {
+			sandboxLogger.Warn(context.Background(), "Failed to kill process tree exceeding resource limit", map[string]any{
+				"resource": violation.Resource,
+				"error":    err.Error(),
+			})
+		}
+		return true
+	}
+	return false
+}
+
+// stop 停止监控，返回峰值采样和超出的限制
+func (m *resourceMonitor) stop() (resourceSample, *ResourceViolation) {
+	close(m.stopCh)
+	<-m.done
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.peak, m.violation
+}
+
+// mergeUsage 合并监控峰值与进程结束后的系统统计
+func mergeUsage(peak resourceSample, final ResourceUsage) ResourceUsage {
+	return ResourceUsage{
+		CPUTime:    max(peak.cpuTime, final.CPUTime),
+		PeakMemory: max(peak.memory, final.PeakMemory),
+		PeakProcs:  max(peak.procs, final.PeakProcs, 1),
+	}
+}
+
+// OnResourceEvent 注册资源事件监听器
+func (ls *LocalSandbox) OnResourceEvent(listener ResourceEventListener) {
+	ls.resourceMu.Lock()
+	defer ls.resourceMu.Unlock()
+	ls.resourceListeners = append(ls.resourceListeners, listener)
+}
+
+// emitResourceEvent 通知资源事件监听器
+func (ls *LocalSandbox) emitResourceEvent(event ResourceEvent) {
+	ls.resourceMu.RLock()
+	listeners := ls.resourceListeners
+	ls.resourceMu.RUnlock()
+	for _, listener := range listeners {
+		listener(event)
+	}
+}
+
+// runWithResourceControl 启动命令并在进程树上执行资源限制，返回资源用量和超出的限制
+func (ls *LocalSandbox) runWithResourceControl(command *exec.Cmd, cmd string) (ResourceUsage, *ResourceViolation, error) {
+	control := newProcessControl(command, ls.resourceLimits)
+	defer control.close()
+	// 超时取消时终止整个进程树，而不仅是 shell
+	command.Cancel = control.kill
+
+	start := time.Now()
+	if err := command.Start(); err != nil {
+		return ResourceUsage{}, nil, err
+	}
+	control.attach()
+
+	monitor := startResourceMonitor(control, ls.resourceLimits)
+	err := command.Wait()
+	peak, violation := monitor.stop()
+	usage := mergeUsage(peak, control.usage())
+
+	event := ResourceEvent{
+		Kind:      types.ResourceEventUsage,
+		Command:   cmd,
+		PID:       command.Process.Pid,
+		Usage:     usage,
+		Violation: violation,
+		Duration:  time.Since(start),
+	}
+	if violation != nil {
+		event.Kind = types.ResourceEventLimitExceeded
+		sandboxLogger.Warn(context.Background(), "Command killed for exceeding resource limit", map[string]any{
+			"command":  truncate(cmd, 200),
+			"resource": violation.Resource,
+			"limit":    violation.Limit,
+			"value":    violation.Value,
+		})
+	}
+	ls.emitResourceEvent(event)
+	return usage, violation, err
+}
@@ -0,0 +1,24 @@
+//go:build !unix && !windows
+
+package sandbox
+
+import "os/exec"
+
+// basicProcessControl 不支持进程树控制的平台只能终止主进程
+type basicProcessControl struct {
+	cmd *exec.Cmd
+}
+
+func newProcessControl(command *exec.Cmd, limits *ResourceLimits) processControl {
+	return &basicProcessControl{cmd: command}
+}
+
+func (c *basicProcessControl) attach() {}
+
+func (c *basicProcessControl) sample() (resourceSample, bool) { return resourceSample{}, false }
+
+func (c *basicProcessControl) kill() error { return c.cmd.Process.Kill() }
+
+func (c *basicProcessControl) usage() ResourceUsage { return ResourceUsage{} }
+
+func (c *basicProcessControl) close() {}
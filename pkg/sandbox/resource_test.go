@@ -0,0 +1,152 @@
+package sandbox
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/astercloud/aster/pkg/types"
+)
+
+func TestResourceLimits_Check(t *testing.T) {
+	limits := &ResourceLimits{MaxMemoryMB: 100, MaxProcesses: 4, CPUTimeLimit: time.Second}
+
+	if v := limits.check(resourceSample{memory: 50 << 20, procs: 4, cpuTime: time.Second}); v != nil {
+		t.Errorf("sample within limits reported %+v", v)
+	}
+	cases := []struct {
+		sample   resourceSample
+		resource string
+	}{
+		{resourceSample{memory: 101 << 20}, ResourceMemory},
+		{resourceSample{procs: 5}, ResourceProcesses},
+		{resourceSample{cpuTime: 2 * time.Second}, ResourceCPUTime},
+	}
+	for _, tc := range cases {
+		v := limits.check(tc.sample)
+		if v == nil || v.Resource != tc.resource {
+			t.Errorf("check(%+v) = %+v, want %s", tc.sample, v, tc.resource)
+		}
+	}
+	if v := (*ResourceLimits)(nil).check(resourceSample{procs: 100}); v != nil {
+		t.Errorf("nil limits should not report violations: %+v", v)
+	}
+}
+
+func TestResourceLimitsFromConfig(t *testing.T) {
+	limits := ResourceLimitsFromConfig(&types.ResourceLimits{
+		MemoryLimit:  256<<20 + 1,
+		MaxProcesses: 8,
+		CPUTime:      30 * time.Second,
+		Timeout:      time.Minute,
+		Nice:         10,
+	})
+	if limits.MaxMemoryMB != 257 || limits.MaxProcesses != 8 || limits.CPUTimeLimit != 30*time.Second ||
+		limits.MaxCPUTime != time.Minute || limits.Nice != 10 {
+		t.Errorf("unexpected limits: %+v", limits)
+	}
+	// 未设置的字段保留默认值
+	if limits.MaxOutputBytes != DefaultResourceLimits.MaxOutputBytes {
+		t.Errorf("MaxOutputBytes = %d", limits.MaxOutputBytes)
+	}
+	if DefaultResourceLimits.MaxProcesses == 8 {
+		t.Error("DefaultResourceLimits must not be modified")
+	}
+}
+
+// fakeProcessControl 按顺序返回预设采样
+type fakeProcessControl struct {
+	mu      sync.Mutex
+	samples []resourceSample
+	killed  bool
+}
+
+func (f *fakeProcessControl) attach() {}
+
+func (f *fakeProcessControl) sample() (resourceSample, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.samples) == 0 {
+		return resourceSample{}, false
+	}
+	s := f.samples[0]
+	if len(f.samples) > 1 {
+		f.samples = f.samples[1:]
+	}
+	return s, true
+}
+
+func (f *fakeProcessControl) kill() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.killed = true
+	return nil
+}
+
+func (f *fakeProcessControl) usage() ResourceUsage { return ResourceUsage{} }
+
+func (f *fakeProcessControl) close() {}
+
+func TestResourceMonitor_KillsOnViolation(t *testing.T) {
+	old := resourceSampleInterval
+	resourceSampleInterval = time.Millisecond
+	defer func() { resourceSampleInterval = old }()
+
+	control := &fakeProcessControl{samples: []resourceSample{
+		{memory: 10 << 20, procs: 1},
+		{memory: 20 << 20, procs: 3},
+		{memory: 300 << 20, procs: 2},
+	}}
+	monitor := startResourceMonitor(control, &ResourceLimits{MaxMemoryMB: 256})
+	<-monitor.done
+	peak, violation := monitor.stop()
+
+	if violation == nil || violation.Resource != ResourceMemory || !control.killed {
+		t.Fatalf("expected memory violation and kill, got %+v killed=%v", violation, control.killed)
+	}
+	if peak.memory != 300<<20 || peak.procs != 3 {
+		t.Errorf("peak = %+v", peak)
+	}
+	if !strings.Contains(violation.Error(), "300 MB") {
+		t.Errorf("violation message = %q", violation.Error())
+	}
+}
+
+func TestResourceEvent_ToMonitorEvent(t *testing.T) {
+	event := ResourceEvent{
+		Kind:      types.ResourceEventLimitExceeded,
+		Command:   "make -j",
+		PID:       42,
+		Usage:     ResourceUsage{CPUTime: 1500 * time.Millisecond, PeakMemory: 1 << 20, PeakProcs: 9},
+		Violation: &ResourceViolation{Resource: ResourceProcesses, Limit: 8, Value: 9},
+		Duration:  2 * time.Second,
+	}
+	got := event.ToMonitorEvent()
+	if got.EventType() != "resource" || got.Kind != types.ResourceEventLimitExceeded || got.Resource != ResourceProcesses ||
+		got.Limit != 8 || got.Value != 9 || got.CPUTimeMs != 1500 || got.PeakProcs != 9 || got.DurationMs != 2000 {
+		t.Errorf("unexpected monitor event: %+v", got)
+	}
+}
+
+func TestLocalSandbox_ResourceUsageEvent(t *testing.T) {
+	skipUnlessPOSIXShell(t)
+	ls, err := NewLocalSandbox(&LocalSandboxConfig{WorkDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("NewLocalSandbox: %v", err)
+	}
+	defer func() { _ = ls.Dispose() }()
+
+	var events []ResourceEvent
+	ls.OnResourceEvent(func(e ResourceEvent) { events = append(events, e) })
+
+	result, err := ls.Exec(context.Background(), "echo hello", nil)
+	if err != nil || result.Code != 0 {
+		t.Fatalf("Exec: %+v %v", result, err)
+	}
+	if len(events) != 1 || events[0].Kind != types.ResourceEventUsage || events[0].Command != "echo hello" ||
+		events[0].PID == 0 || events[0].Usage.PeakProcs < 1 {
+		t.Errorf("unexpected events: %+v", events)
+	}
+}
@@ -0,0 +1,131 @@
+//go:build unix
+
+package sandbox
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// clockTicks /proc/<pid>/stat 中 CPU 时间的单位（USER_HZ，Linux 上固定为 100）
+const clockTicks = 100
+
+// unixProcessControl 以进程组管理进程树
+// Linux 通过 /proc 采样进程组资源，其他系统依靠 ulimit 限制并在结束后读取 rusage
+type unixProcessControl struct {
+	cmd  *exec.Cmd
+	nice int
+}
+
+// newProcessControl 让命令在独立进程组中运行，便于整体终止
+func newProcessControl(command *exec.Cmd, limits *ResourceLimits) processControl {
+	if command.SysProcAttr == nil {
+		command.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	command.SysProcAttr.Setpgid = true
+	c := &unixProcessControl{cmd: command}
+	if limits != nil {
+		c.nice = limits.Nice
+	}
+	return c
+}
+
+func (c *unixProcessControl) attach() {
+	if c.nice == 0 {
+		return
+	}
+	// 调整整个进程组的优先级，之后创建的子进程继承该值（降低优先级以外的调整需要特权）
+	if err := syscall.Setpriority(syscall.PRIO_PGRP, c.cmd.Process.Pid, c.nice); err != nil {
+		sandboxLogger.Debug(context.Background(), "Failed to set process priority", map[string]any{
+			"nice":  c.nice,
+			"error": err.Error(),
+		})
+	}
+}
+
+func (c *unixProcessControl) sample() (resourceSample, bool) {
+	if runtime.GOOS != "linux" {
+		return resourceSample{}, false
+	}
+	return sampleProcessGroup("/proc", c.cmd.Process.Pid)
+}
+
+func (c *unixProcessControl) kill() error {
+	err := syscall.Kill(-c.cmd.Process.Pid, syscall.SIGKILL)
+	if errors.Is(err, syscall.ESRCH) {
+		return os.ErrProcessDone
+	}
+	return err
+}
+
+func (c *unixProcessControl) usage() ResourceUsage {
+	if c.cmd.ProcessState == nil {
+		return ResourceUsage{}
+	}
+	rusage, ok := c.cmd.ProcessState.SysUsage().(*syscall.Rusage)
+	if !ok || rusage == nil {
+		return ResourceUsage{}
+	}
+	// Linux 的 ru_maxrss 单位为 KB，macOS 为字节
+	maxRSS := int64(rusage.Maxrss)
+	if runtime.GOOS != "darwin" {
+		maxRSS *= 1024
+	}
+	return ResourceUsage{
+		CPUTime:    time.Duration(rusage.Utime.Nano() + rusage.Stime.Nano()),
+		PeakMemory: maxRSS,
+	}
+}
+
+func (c *unixProcessControl) close() {}
+
+// sampleProcessGroup 汇总进程组内所有进程的常驻内存、进程数和 CPU 时间
+// CPU 时间包含已回收子进程的用时（cutime/cstime）
+func sampleProcessGroup(procRoot string, pgid int) (resourceSample, bool) {
+	entries, err := os.ReadDir(procRoot)
+	if err != nil {
+		return resourceSample{}, false
+	}
+	pageSize := int64(os.Getpagesize())
+
+	var s resourceSample
+	for _, entry := range entries {
+		if _, err := strconv.Atoi(entry.Name()); err != nil {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(procRoot, entry.Name(), "stat"))
+		if err != nil {
+			continue
+		}
+		// comm 字段可能包含空格和括号，从最后一个 ')' 之后开始解析
+		stat := string(data)
+		end := strings.LastIndexByte(stat, ')')
+		if end < 0 {
+			continue
+		}
+		fields := strings.Fields(stat[end+1:])
+		// fields[0]=state [2]=pgrp [11..14]=utime,stime,cutime,cstime [21]=rss
+		if len(fields) < 22 || fields[2] != strconv.Itoa(pgid) || fields[0] == "Z" {
+			continue
+		}
+		var ticks int64
+		for _, f := range fields[11:15] {
+			v, _ := strconv.ParseInt(f, 10, 64)
+			ticks += v
+		}
+		rss, _ := strconv.ParseInt(fields[21], 10, 64)
+
+		s.procs++
+		s.memory += rss * pageSize
+		s.cpuTime += time.Duration(ticks) * time.Second / clockTicks
+	}
+	return s, true
+}
@@ -0,0 +1,80 @@
+//go:build unix
+
+package sandbox
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/astercloud/aster/pkg/types"
+)
+
+func writeProcStat(t *testing.T, root, pid, stat string) {
+	t.Helper()
+	dir := filepath.Join(root, pid)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "stat"), []byte(stat), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSampleProcessGroup(t *testing.T) {
+	root := t.TempDir()
+	// 字段：pid (comm) state ppid pgrp session tty tpgid flags minflt cminflt majflt cmajflt utime stime cutime cstime ... rss
+	writeProcStat(t, root, "100", "100 (bash) S 1 100 100 0 -1 0 0 0 0 0 50 50 100 0 20 0 1 0 0 0 10 0")
+	writeProcStat(t, root, "101", "101 (my (odd) cmd) R 100 100 100 0 -1 0 0 0 0 0 100 0 0 0 20 0 1 0 0 0 20 0")
+	writeProcStat(t, root, "102", "102 (zombie) Z 100 100 100 0 -1 0 0 0 0 0 900 0 0 0 20 0 1 0 0 0 0 0")
+	writeProcStat(t, root, "200", "200 (other) S 1 200 200 0 -1 0 0 0 0 0 999 0 0 0 20 0 1 0 0 0 999 0")
+	if err := os.MkdirAll(filepath.Join(root, "self"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	s, ok := sampleProcessGroup(root, 100)
+	if !ok {
+		t.Fatal("sampleProcessGroup failed")
+	}
+	pageSize := int64(os.Getpagesize())
+	if s.procs != 2 || s.memory != 30*pageSize || s.cpuTime != 3*time.Second {
+		t.Errorf("sample = %+v", s)
+	}
+}
+
+func TestLocalSandbox_KillsProcessTreeOverLimit(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("process tree sampling requires /proc")
+	}
+	skipUnlessPOSIXShell(t)
+
+	limits := *DefaultResourceLimits
+	limits.MaxMemoryMB = 1
+	ls, err := NewLocalSandbox(&LocalSandboxConfig{WorkDir: t.TempDir(), ResourceLimits: &limits})
+	if err != nil {
+		t.Fatalf("NewLocalSandbox: %v", err)
+	}
+	defer func() { _ = ls.Dispose() }()
+
+	var events []ResourceEvent
+	ls.OnResourceEvent(func(e ResourceEvent) { events = append(events, e) })
+
+	start := time.Now()
+	result, err := ls.Exec(context.Background(), "sleep 10 & sleep 10 & wait", nil)
+	if err != nil {
+		t.Fatalf("Exec: %v", err)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Error("process tree should be killed before the commands finish")
+	}
+	if result.Code == 0 || !strings.Contains(result.Stderr, "[killed: memory usage") {
+		t.Errorf("unexpected result: %+v", result)
+	}
+	if len(events) != 1 || events[0].Kind != types.ResourceEventLimitExceeded || events[0].Violation.Resource != ResourceMemory {
+		t.Errorf("unexpected events: %+v", events)
+	}
+}
@@ -0,0 +1,128 @@
+package sandbox
+
+import (
+	"context"
+	"os/exec"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// jobBasicAccounting JOBOBJECT_BASIC_ACCOUNTING_INFORMATION
+type jobBasicAccounting struct {
+	TotalUserTime             int64
+	TotalKernelTime           int64
+	ThisPeriodTotalUserTime   int64
+	ThisPeriodTotalKernelTime int64
+	TotalPageFaultCount       uint32
+	TotalProcesses            uint32
+	ActiveProcesses           uint32
+	TotalTerminatedProcesses  uint32
+}
+
+// jobProcessControl 以 Job Object 管理进程树
+// 内存、进程数和 CPU 时间限制由系统强制执行，关闭 Job 时终止残留进程
+type jobProcessControl struct {
+	cmd *exec.Cmd
+	job windows.Handle
+}
+
+// newProcessControl 创建带资源限制的 Job Object，创建失败时退化为只终止主进程
+func newProcessControl(command *exec.Cmd, limits *ResourceLimits) processControl {
+	c := &jobProcessControl{cmd: command}
+	job, err := windows.CreateJobObject(nil, nil)
+	if err != nil {
+		sandboxLogger.Warn(context.Background(), "Failed to create job object", map[string]any{"error": err.Error()})
+		return c
+	}
+
+	var info windows.JOBOBJECT_EXTENDED_LIMIT_INFORMATION
+	info.BasicLimitInformation.LimitFlags = windows.JOB_OBJECT_LIMIT_KILL_ON_JOB_CLOSE
+	if limits != nil {
+		if limits.MaxMemoryMB > 0 {
+			info.BasicLimitInformation.LimitFlags |= windows.JOB_OBJECT_LIMIT_JOB_MEMORY
+			info.JobMemoryLimit = uintptr(limits.MaxMemoryMB) << 20
+		}
+		if limits.MaxProcesses > 0 {
+			info.BasicLimitInformation.LimitFlags |= windows.JOB_OBJECT_LIMIT_ACTIVE_PROCESS
+			info.BasicLimitInformation.ActiveProcessLimit = uint32(limits.MaxProcesses)
+		}
+		if limits.CPUTimeLimit > 0 {
+			// 单位为 100 纳秒
+			info.BasicLimitInformation.LimitFlags |= windows.JOB_OBJECT_LIMIT_JOB_TIME
+			info.BasicLimitInformation.PerJobUserTimeLimit = int64(limits.CPUTimeLimit / 100)
+		}
+		if limits.Nice > 0 {
+			info.BasicLimitInformation.LimitFlags |= windows.JOB_OBJECT_LIMIT_PRIORITY_CLASS
+			info.BasicLimitInformation.PriorityClass = windows.BELOW_NORMAL_PRIORITY_CLASS
+			if limits.Nice >= 10 {
+				info.BasicLimitInformation.PriorityClass = windows.IDLE_PRIORITY_CLASS
+			}
+		}
+	}
+	if _, err := windows.SetInformationJobObject(job, windows.JobObjectExtendedLimitInformation,
+		uintptr(unsafe.Pointer(&info)), uint32(unsafe.Sizeof(info))); err != nil {
+		sandboxLogger.Warn(context.Background(), "Failed to set job object limits", map[string]any{"error": err.Error()})
+		_ = windows.CloseHandle(job)
+		return c
+	}
+	c.job = job
+	return c
+}
+
+func (c *jobProcessControl) attach() {
+	if c.job == 0 {
+		return
+	}
+	process, err := windows.OpenProcess(windows.PROCESS_SET_QUOTA|windows.PROCESS_TERMINATE, false, uint32(c.cmd.Process.Pid))
+	if err == nil {
+		err = windows.AssignProcessToJobObject(c.job, process)
+		_ = windows.CloseHandle(process)
+	}
+	if err != nil {
+		sandboxLogger.Warn(context.Background(), "Failed to assign process to job object", map[string]any{"error": err.Error()})
+		_ = windows.CloseHandle(c.job)
+		c.job = 0
+	}
+}
+
+func (c *jobProcessControl) sample() (resourceSample, bool) {
+	if c.job == 0 {
+		return resourceSample{}, false
+	}
+	var accounting jobBasicAccounting
+	if err := windows.QueryInformationJobObject(c.job, windows.JobObjectBasicAccountingInformation,
+		uintptr(unsafe.Pointer(&accounting)), uint32(unsafe.Sizeof(accounting)), nil); err != nil {
+		return resourceSample{}, false
+	}
+	var limits windows.JOBOBJECT_EXTENDED_LIMIT_INFORMATION
+	if err := windows.QueryInformationJobObject(c.job, windows.JobObjectExtendedLimitInformation,
+		uintptr(unsafe.Pointer(&limits)), uint32(unsafe.Sizeof(limits)), nil); err != nil {
+		return resourceSample{}, false
+	}
+	return resourceSample{
+		memory:  int64(limits.PeakJobMemoryUsed),
+		procs:   int(accounting.ActiveProcesses),
+		cpuTime: time.Duration(accounting.TotalUserTime+accounting.TotalKernelTime) * 100,
+	}, true
+}
+
+func (c *jobProcessControl) kill() error {
+	if c.job != 0 {
+		return windows.TerminateJobObject(c.job, 1)
+	}
+	return c.cmd.Process.Kill()
+}
+
+func (c *jobProcessControl) usage() ResourceUsage {
+	s, _ := c.sample()
+	return ResourceUsage{CPUTime: s.cpuTime, PeakMemory: s.memory}
+}
+
+func (c *jobProcessControl) close() {
+	if c.job != 0 {
+		_ = windows.CloseHandle(c.job)
+		c.job = 0
+	}
+}
@@ -0,0 +1,135 @@
+package sandbox
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+
+	"github.com/astercloud/aster/pkg/sandbox/shellrisk"
+)
+
+// ShellKind 命令执行使用的 shell 类型
+type ShellKind string
+
+const (
+	// ShellAuto 按操作系统自动选择：Unix 优先 bash，Windows 优先 PowerShell
+	ShellAuto       ShellKind = ""
+	ShellBash       ShellKind = "bash"
+	ShellSh         ShellKind = "sh"
+	ShellPowerShell ShellKind = "powershell"
+	ShellCmd        ShellKind = "cmd"
+)
+
+// shellSpec 解析后的 shell
+type shellSpec struct {
+	kind ShellKind
+	path string
+}
+
+// getShell 返回 Unix 下执行命令的 shell，优先 bash（支持全部 ulimit 选项），否则使用 /bin/sh
+func getShell() string {
+	if shell, err := exec.LookPath("bash"); err == nil {
+		return shell
+	}
+	return "/bin/sh"
+}
+
+// detectShell 根据操作系统和偏好选择 shell
+// Windows 依次尝试 pwsh、powershell，最后回退到 cmd；其他系统依次尝试 bash、/bin/sh
+func detectShell(goos string, preferred ShellKind, lookPath func(string) (string, error)) shellSpec {
+	find := func(names ...string) string {
+		for _, name := range names {
+			if path, err := lookPath(name); err == nil {
+				return path
+			}
+		}
+		return ""
+	}
+
+	if goos == "windows" {
+		switch preferred {
+		case ShellCmd:
+		case ShellAuto, ShellPowerShell:
+			if path := find("pwsh", "powershell"); path != "" {
+				return shellSpec{kind: ShellPowerShell, path: path}
+			}
+		default:
+			// Git Bash / WSL 等 POSIX shell
+			if path := find(string(preferred)); path != "" {
+				return shellSpec{kind: preferred, path: path}
+			}
+		}
+		if path := find("cmd"); path != "" {
+			return shellSpec{kind: ShellCmd, path: path}
+		}
+		return shellSpec{kind: ShellCmd, path: "cmd.exe"}
+	}
+
+	switch preferred {
+	case ShellPowerShell:
+		if path := find("pwsh"); path != "" {
+			return shellSpec{kind: ShellPowerShell, path: path}
+		}
+	case ShellSh:
+		return shellSpec{kind: ShellSh, path: "/bin/sh"}
+	}
+	if path := find("bash"); path != "" {
+		return shellSpec{kind: ShellBash, path: path}
+	}
+	return shellSpec{kind: ShellSh, path: "/bin/sh"}
+}
+
+// isWindows 是否为 Windows 风格的 shell（cmd/PowerShell）
+func (s shellSpec) isWindows() bool {
+	return s.kind == ShellCmd || s.kind == ShellPowerShell
+}
+
+// args 执行脚本的参数（不含 shell 本身）
+func (s shellSpec) args(script string) []string {
+	switch s.kind {
+	case ShellPowerShell:
+		return []string{"-NoLogo", "-NoProfile", "-NonInteractive", "-Command", script}
+	case ShellCmd:
+		return []string{"/d", "/s", "/c", script}
+	default:
+		return []string{"-c", script}
+	}
+}
+
+// command 构建执行脚本的命令
+// cmd.exe 不使用 C 运行时的参数转义规则，需要原样传递命令行
+func (s shellSpec) command(ctx context.Context, script string) *exec.Cmd {
+	command := exec.CommandContext(ctx, s.path, s.args(script)...)
+	if s.kind == ShellCmd {
+		setRawCmdLine(command, `"`+s.path+`" /d /s /c "`+script+`"`)
+	}
+	return command
+}
+
+// analyze 按 shell 语法分析命令风险
+func (s shellSpec) analyze(cmd string) *shellrisk.Analysis {
+	if s.isWindows() {
+		return shellrisk.AnalyzeWindows(cmd)
+	}
+	return shellrisk.Analyze(cmd)
+}
+
+// normalizeCommandName 规范化命令名：去掉路径，Windows 下忽略大小写和可执行文件扩展名
+func (s shellSpec) normalizeCommandName(name string) string {
+	if s.isWindows() {
+		if i := strings.LastIndexAny(name, `\/`); i >= 0 {
+			name = name[i+1:]
+		}
+		name = strings.ToLower(name)
+		for _, ext := range []string{".exe", ".com", ".cmd", ".bat", ".ps1"} {
+			if trimmed, ok := strings.CutSuffix(name, ext); ok {
+				return trimmed
+			}
+		}
+		return name
+	}
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	return name
+}
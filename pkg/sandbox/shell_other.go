@@ -0,0 +1,8 @@
+//go:build !windows
+
+package sandbox
+
+import "os/exec"
+
+// setRawCmdLine 仅 Windows 需要原样传递命令行
+func setRawCmdLine(command *exec.Cmd, line string) {}
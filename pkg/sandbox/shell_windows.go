@@ -0,0 +1,14 @@
+package sandbox
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// setRawCmdLine 原样设置进程命令行，绕过 Go 的参数转义
+func setRawCmdLine(command *exec.Cmd, line string) {
+	if command.SysProcAttr == nil {
+		command.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	command.SysProcAttr.CmdLine = line
+}
@@ -0,0 +1,46 @@
+package sandbox
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLocalSandbox_WindowsExec(t *testing.T) {
+	for _, kind := range []ShellKind{ShellPowerShell, ShellCmd} {
+		t.Run(string(kind), func(t *testing.T) {
+			sb, err := NewLocalSandbox(&LocalSandboxConfig{WorkDir: t.TempDir(), Shell: kind})
+			if err != nil {
+				t.Fatalf("failed to create sandbox: %v", err)
+			}
+
+			result, err := sb.Exec(context.Background(), `echo "hello world"`, nil)
+			if err != nil {
+				t.Fatalf("exec failed: %v", err)
+			}
+			if result.Code != 0 || !strings.Contains(result.Stdout, "hello world") {
+				t.Errorf("unexpected result: %+v", result)
+			}
+		})
+	}
+}
+
+func TestLocalFS_WindowsPaths(t *testing.T) {
+	workDir := t.TempDir()
+	fs := &LocalFS{workDir: workDir, enforceBoundary: true}
+
+	if got, want := fs.Resolve(`C:\Windows\System32`), filepath.Join(workDir, `Windows\System32`); got != want {
+		t.Errorf("Resolve drive path = %q, want %q", got, want)
+	}
+	if got, want := fs.Resolve(`D:data`), filepath.Join(workDir, "data"); got != want {
+		t.Errorf("Resolve drive-relative path = %q, want %q", got, want)
+	}
+
+	if !fs.IsInside(strings.ToUpper(workDir) + `\src`) {
+		t.Error("IsInside should ignore case on Windows")
+	}
+	if fs.IsInside(`C:\Windows\System32`) {
+		t.Error("system directory should be outside the sandbox")
+	}
+}
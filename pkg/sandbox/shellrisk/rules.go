@@ -0,0 +1,929 @@
+package shellrisk
+
+import (
+	"path"
+	"slices"
+	"strings"
+
+	"mvdan.cc/sh/v3/syntax"
+)
+
+// wrappers 执行其参数中命令的包装程序，值为需要取值的选项
+var wrappers = map[string]map[string]bool{
+	"env":     {"-u": true, "--unset": true, "-C": true, "--chdir": true},
+	"nohup":   {},
+	"time":    {"-f": true, "-o": true},
+	"command": {},
+	"builtin": {},
+	"exec":    {"-a": true},
+	"nice":    {"-n": true},
+	"ionice":  {"-c": true, "-n": true},
+	"setsid":  {},
+	"stdbuf":  {"-i": true, "-o": true, "-e": true},
+	"timeout": {"-s": true, "-k": true, "--signal": true, "--kill-after": true},
+	"xargs":   {"-I": true, "-n": true, "-P": true, "-d": true, "-L": true, "-s": true, "-E": true, "-a": true},
+	"watch":   {"-n": true, "--interval": true},
+	"sudo":    {"-u": true, "-g": true, "-h": true, "-p": true, "-C": true, "-D": true, "-r": true, "-t": true, "-U": true},
+	"doas":    {"-u": true, "-C": true},
+	"pkexec":  {"--user": true},
+}
+
+// wrapperOperands 包装程序在被包装命令之前的位置参数个数
+var wrapperOperands = map[string]int{"timeout": 1}
+
+// privilegeWrappers 以更高权限执行命令的包装程序
+var privilegeWrappers = map[string]bool{"sudo": true, "doas": true, "pkexec": true}
+
+// shells 会执行脚本文本的 shell
+var shells = map[string]bool{
+	"sh": true, "bash": true, "zsh": true, "dash": true, "ksh": true, "mksh": true,
+	"ash": true, "fish": true, "csh": true, "tcsh": true, "busybox": true,
+}
+
+// interpreters 支持内联代码的脚本语言，值为内联代码选项
+var interpreters = map[string]string{
+	"python": "-c", "python2": "-c", "python3": "-c",
+	"perl": "-e", "ruby": "-e", "node": "-e", "php": "-r", "lua": "-e",
+}
+
+// downloaders 下载远程内容的命令
+var downloaders = map[string]bool{
+	"curl": true, "wget": true, "fetch": true, "aria2c": true, "http": true, "https": true, "lwp-download": true,
+}
+
+// decoders 还原编码内容的命令，常用于隐藏要执行的命令
+var decoders = map[string]bool{
+	"base64": true, "base32": true, "xxd": true, "openssl": true, "uudecode": true,
+	"rev": true, "gunzip": true, "zcat": true, "bunzip2": true, "xz": true, "unxz": true,
+}
+
+// netcats 可建立原始网络连接的命令
+var netcats = map[string]bool{"nc": true, "ncat": true, "netcat": true, "socat": true}
+
+// inlineExecMarkers 内联脚本中派生进程的调用
+var inlineExecMarkers = []string{"os.system", "subprocess", "os.exec", "exec(", "system(", "child_process", "popen", "spawn", "`"}
+
+// systemDirs 递归删除或修改权限即可破坏系统的目录
+var systemDirs = map[string]bool{
+	"/": true, "/bin": true, "/boot": true, "/dev": true, "/etc": true, "/home": true,
+	"/lib": true, "/lib64": true, "/opt": true, "/proc": true, "/root": true, "/sbin": true,
+	"/srv": true, "/sys": true, "/usr": true, "/var": true, "~": true,
+}
+
+// homeWords 指向用户主目录的动态参数
+var homeWords = map[string]bool{"$HOME": true, "${HOME}": true}
+
+// accountFiles 账户和凭据文件
+var accountFiles = []string{"/etc/passwd", "/etc/shadow", "/etc/gshadow", "/etc/sudoers", "/etc/master.passwd"}
+
+// diskDevices 块设备路径前缀
+var diskDevices = []string{"/dev/sd", "/dev/hd", "/dev/nvme", "/dev/vd", "/dev/xvd", "/dev/mmcblk", "/dev/disk"}
+
+// command 解析后的简单命令
+type command struct {
+	name       string         // 程序名，已去掉路径和包装程序
+	nameWord   *syntax.Word   // 命令名单词，动态命令名时用于进一步分析
+	words      []*syntax.Word // 参数单词
+	args       []string       // 参数静态值，动态参数为空字符串
+	privileged string         // 提权包装程序名
+}
+
+// resolve 去掉包装程序并解析命令名，命令名无法静态确定时 ok 为 false
+func resolve(call *syntax.CallExpr) (cmd command, ok bool) {
+	words := call.Args
+	for len(words) > 0 {
+		name, lit := literal(words[0])
+		if !lit {
+			cmd.nameWord, cmd.words = words[0], words[1:]
+			cmd.args = literals(cmd.words)
+			return cmd, false
+		}
+		base := path.Base(name)
+		if privilegeWrappers[base] {
+			cmd.privileged = base
+		}
+
+		opts, isWrapper := wrappers[base]
+		if isWrapper && (base == "command" || base == "builtin") && len(words) > 1 {
+			// command -v / -V 只查找命令，不执行
+			if flag, _ := literal(words[1]); flag == "-v" || flag == "-V" {
+				isWrapper = false
+			}
+		}
+		i := 1
+		if isWrapper {
+			for i < len(words) {
+				arg, lit := literal(words[i])
+				if !lit {
+					break
+				}
+				if arg == "--" {
+					i++
+					break
+				}
+				if base == "env" && strings.Contains(arg, "=") && !strings.HasPrefix(arg, "-") {
+					i++
+					continue
+				}
+				if !strings.HasPrefix(arg, "-") || arg == "-" {
+					break
+				}
+				i++
+				if opts[arg] {
+					i++
+				}
+			}
+			i += wrapperOperands[base]
+		}
+		if !isWrapper || i >= len(words) {
+			cmd.name, cmd.nameWord = base, words[0]
+			if len(words) > 1 {
+				cmd.words = words[1:]
+			}
+			cmd.args = literals(cmd.words)
+			return cmd, true
+		}
+		words = words[i:]
+	}
+	return cmd, false
+}
+
+// literal 返回单词的静态值，包含参数展开、命令替换等动态内容时 ok 为 false
+func literal(word *syntax.Word) (string, bool) {
+	var sb strings.Builder
+	for _, part := range word.Parts {
+		if !writeLiteral(&sb, part, false) {
+			return "", false
+		}
+	}
+	return sb.String(), true
+}
+
+// writeLiteral 将静态单词片段写入 sb
+func writeLiteral(sb *strings.Builder, part syntax.WordPart, quoted bool) bool {
+	switch p := part.(type) {
+	case *syntax.Lit:
+		if quoted {
+			sb.WriteString(p.Value)
+		} else {
+			sb.WriteString(unescape(p.Value))
+		}
+	case *syntax.SglQuoted:
+		if p.Dollar {
+			return false
+		}
+		sb.WriteString(p.Value)
+	case *syntax.DblQuoted:
+		if p.Dollar {
+			return false
+		}
+		for _, q := range p.Parts {
+			if !writeLiteral(sb, q, true) {
+				return false
+			}
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+// unescape 去掉未加引号文本中的反斜杠转义，r\m 与 rm 等价
+func unescape(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+		}
+		sb.WriteByte(s[i])
+	}
+	return sb.String()
+}
+
+// literals 返回各单词的静态值
+func literals(words []*syntax.Word) []string {
+	out := make([]string, len(words))
+	for i, w := range words {
+		out[i], _ = literal(w)
+	}
+	return out
+}
+
+// hasFlag 是否包含短选项字符（可合并，如 -rf）或长选项
+func hasFlag(args []string, short byte, long string) bool {
+	for _, a := range args {
+		if a == "--" {
+			return false
+		}
+		if long != "" && a == long {
+			return true
+		}
+		if short != 0 && len(a) > 1 && a[0] == '-' && a[1] != '-' && strings.IndexByte(a[1:], short) >= 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// operands 返回非选项参数的下标
+func operands(args []string) []int {
+	var idx []int
+	dashdash := false
+	for i, a := range args {
+		switch {
+		case dashdash:
+			idx = append(idx, i)
+		case a == "--":
+			dashdash = true
+		case len(a) > 1 && a[0] == '-':
+		default:
+			idx = append(idx, i)
+		}
+	}
+	return idx
+}
+
+// isSystemDir 路径是否为系统目录或其通配展开
+func isSystemDir(p string) bool {
+	p = strings.TrimSuffix(p, "*")
+	if p != "/" {
+		p = strings.TrimRight(p, "/")
+	}
+	return systemDirs[p]
+}
+
+// hasPrefixAny 是否以任一前缀开头
+func hasPrefixAny(s string, prefixes []string) bool {
+	for _, p := range prefixes {
+		if strings.HasPrefix(s, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// isHistoryFile 是否为 shell 历史文件
+func isHistoryFile(p string) bool {
+	base := path.Base(p)
+	return strings.HasSuffix(base, "_history") || base == ".history"
+}
+
+// isInterpreter 是否为支持内联代码的脚本语言，返回内联代码选项
+func isInterpreter(name string) (string, bool) {
+	if flag, ok := interpreters[name]; ok {
+		return flag, true
+	}
+	if strings.HasPrefix(name, "python") {
+		return "-c", true
+	}
+	return "", false
+}
+
+// readsStdin 命令是否把标准输入当作脚本执行
+func readsStdin(cmd command) bool {
+	if cmd.name == "source" || cmd.name == "." {
+		return len(cmd.args) > 0 && (cmd.args[0] == "/dev/stdin" || cmd.args[0] == "-")
+	}
+	flag, interp := isInterpreter(cmd.name)
+	switch {
+	case shells[cmd.name]:
+		if hasFlag(cmd.args, 'c', "") {
+			return false
+		}
+		if hasFlag(cmd.args, 's', "") {
+			return true
+		}
+	case interp:
+		if slices.Contains(cmd.args, flag) {
+			return false
+		}
+	default:
+		return false
+	}
+	for _, i := range operands(cmd.args) {
+		if cmd.args[i] != "-" {
+			return false
+		}
+	}
+	return true
+}
+
+// call 分类一个简单命令
+func (w *walker) call(call *syntax.CallExpr) {
+	if len(call.Args) == 0 {
+		w.assign(call.Assigns)
+		return
+	}
+	text := w.text(call)
+	cmd, ok := resolve(call)
+	w.an.a.Segments = append(w.an.a.Segments, Segment{
+		Name:    cmd.name,
+		Args:    cmd.args,
+		Text:    text,
+		Dynamic: !ok,
+		Nested:  w.inSubst(call),
+	})
+
+	if cmd.privileged != "" {
+		w.an.add(LevelHigh, "privilege_escalation", "runs with elevated privileges via "+cmd.privileged, text)
+	}
+	if !ok {
+		if name, known := w.varValue(cmd.nameWord); known {
+			// x=rm; $x -rf / 按 rm 分类
+			cmd.name = path.Base(name)
+			w.rules(cmd, text)
+			return
+		}
+		w.dynamicName(cmd, text)
+		return
+	}
+	w.rules(cmd, text)
+}
+
+// assign 记录赋值为静态值的变量，值无法静态确定的变量视为未知
+func (w *walker) assign(assigns []*syntax.Assign) {
+	for _, as := range assigns {
+		if as.Name == nil || as.Naked {
+			continue
+		}
+		name := as.Name.Value
+		value, ok := "", true
+		if as.Value != nil {
+			value, ok = literal(as.Value)
+		}
+		if !ok || as.Append || as.Index != nil || as.Array != nil {
+			delete(w.an.vars, name)
+			continue
+		}
+		w.an.vars[name] = value
+	}
+}
+
+// varValue 命令名为单个变量引用且变量有静态值时返回该值
+// 值含空白时会被拆成多个单词，不在此解析
+func (w *walker) varValue(word *syntax.Word) (string, bool) {
+	if word == nil || len(word.Parts) != 1 {
+		return "", false
+	}
+	part := word.Parts[0]
+	if dq, ok := part.(*syntax.DblQuoted); ok && len(dq.Parts) == 1 {
+		part = dq.Parts[0]
+	}
+	pe, ok := part.(*syntax.ParamExp)
+	if !ok || pe.Param == nil || pe.Excl || pe.Length || pe.Width || pe.Index != nil ||
+		pe.Slice != nil || pe.Repl != nil || pe.Names != 0 || pe.Exp != nil {
+		return "", false
+	}
+	value, ok := w.an.vars[pe.Param.Value]
+	if !ok || value == "" || strings.ContainsAny(value, " \t\n") {
+		return "", false
+	}
+	return value, true
+}
+
+// dynamicName 处理运行时才能确定的命令名
+func (w *walker) dynamicName(cmd command, text string) {
+	word := cmd.nameWord
+	if word == nil {
+		return
+	}
+	targetsSystem := false
+	for _, i := range operands(cmd.args) {
+		targetsSystem = targetsSystem || isSystemDir(cmd.args[i])
+	}
+	switch {
+	case containsCommand(word, downloaders):
+		w.an.add(LevelCritical, "remote_exec", "command name is downloaded at runtime", text)
+	case containsCommand(word, decoders):
+		w.an.add(LevelCritical, "obfuscated_exec", "command name is decoded at runtime", text)
+	case hasSubstitution(word):
+		w.an.add(LevelHigh, "dynamic_command", "command name is computed by a substitution", text)
+	case hasANSIQuote(word):
+		w.an.add(LevelHigh, "obfuscated_command", "command name is hidden in escape sequences", text)
+	case targetsSystem:
+		w.an.add(LevelCritical, "variable_command", "command name comes from a variable and targets a system directory", text)
+	default:
+		w.an.add(LevelModerate, "variable_command", "command name comes from a variable", text)
+	}
+}
+
+// rules 按命令名应用规则
+func (w *walker) rules(cmd command, text string) {
+	args := cmd.args
+	w.sensitiveArgs(cmd, text)
+
+	switch name := cmd.name; {
+	case name == "su":
+		w.an.add(LevelHigh, "privilege_escalation", "switches user", text)
+
+	case name == "rm":
+		w.rm(cmd, text)
+	case name == "rmdir":
+		for _, i := range operands(args) {
+			if isSystemDir(args[i]) {
+				w.an.add(LevelCritical, "destroy_filesystem", "removes a system directory", text)
+			}
+		}
+	case name == "find":
+		w.find(args, text)
+	case name == "chmod" || name == "chown" || name == "chgrp":
+		w.chmod(cmd, text)
+	case name == "setcap":
+		w.an.add(LevelHigh, "privilege_escalation", "grants file capabilities", text)
+
+	case name == "shutdown" || name == "poweroff" || name == "halt" || name == "reboot" || name == "kexec":
+		w.an.add(LevelCritical, "system_power", "shuts down or restarts the system", text)
+	case name == "init" || name == "telinit":
+		if len(args) > 0 && (args[0] == "0" || args[0] == "6") {
+			w.an.add(LevelCritical, "system_power", "shuts down or restarts the system", text)
+		}
+	case name == "systemctl":
+		for _, a := range args {
+			if a == "poweroff" || a == "reboot" || a == "halt" || a == "kexec" {
+				w.an.add(LevelCritical, "system_power", "shuts down or restarts the system", text)
+			}
+		}
+
+	case strings.HasPrefix(name, "mkfs") || name == "mke2fs" || name == "mkswap" || name == "fdisk" ||
+		name == "sfdisk" || name == "cfdisk" || name == "parted" || name == "gdisk" || name == "wipefs" ||
+		name == "format" || name == "format.com" || name == "diskpart":
+		w.an.add(LevelCritical, "disk_format", "formats or repartitions a disk", text)
+	case name == "mount" || name == "umount" || name == "swapon" || name == "swapoff":
+		w.an.add(LevelHigh, "mount", "changes mounted filesystems", text)
+	case name == "dd":
+		for _, a := range args {
+			if target, ok := strings.CutPrefix(a, "of="); ok {
+				w.write(target, text)
+			}
+		}
+	case name == "tee" || name == "truncate" || name == "shred":
+		for _, i := range operands(args) {
+			w.write(args[i], text)
+		}
+	case name == "cp" || name == "mv" || name == "install" || name == "ln":
+		if ops := operands(args); len(ops) > 1 {
+			w.write(args[ops[len(ops)-1]], text)
+		}
+
+	case name == "eval":
+		w.eval(cmd, text)
+	case name == "source" || name == ".":
+		if len(cmd.words) > 0 {
+			w.dynamicScript(cmd.words[0], name, text)
+		}
+	case shells[name]:
+		w.shell(cmd, text)
+	case netcats[name]:
+		if hasFlag(args, 'e', "") || hasFlag(args, 'c', "") || name == "socat" && strings.Contains(strings.Join(args, " "), "exec:") {
+			w.an.add(LevelCritical, "reverse_shell", "connects a shell to the network", text)
+		}
+	case downloaders[name]:
+		w.an.add(LevelModerate, "network_download", "downloads remote content", text)
+		w.recordDownload(cmd)
+
+	case name == "nmap" || name == "iptables" || name == "ip6tables" || name == "nft" || name == "ufw" || name == "firewall-cmd":
+		w.an.add(LevelHigh, "network_admin", "scans the network or changes firewall rules", text)
+	case name == "insmod" || name == "rmmod" || name == "modprobe":
+		w.an.add(LevelHigh, "kernel_module", "loads or unloads kernel modules", text)
+	case name == "sysctl":
+		if hasFlag(args, 'w', "--write") || strings.Contains(strings.Join(args, " "), "=") {
+			w.an.add(LevelHigh, "kernel_write", "changes kernel parameters", text)
+		}
+	case name == "docker" || name == "podman":
+		w.container(args, text)
+	case name == "nsenter" || name == "unshare" || name == "chroot":
+		w.an.add(LevelHigh, "container_escape", "enters or creates namespaces", text)
+	case name == "history":
+		if hasFlag(args, 'c', "") {
+			w.an.add(LevelHigh, "history_tamper", "clears shell history", text)
+		}
+
+	default:
+		if flag, ok := isInterpreter(name); ok {
+			w.interpreter(cmd, flag, text)
+		}
+	}
+}
+
+// sensitiveArgs 检查对账户凭据文件的访问
+func (w *walker) sensitiveArgs(cmd command, text string) {
+	for _, a := range cmd.args {
+		if hasPrefixAny(a, accountFiles) {
+			w.an.add(LevelHigh, "sensitive_file", "accesses account credentials", text)
+			return
+		}
+	}
+}
+
+// rm 检查删除范围
+func (w *walker) rm(cmd command, text string) {
+	args := cmd.args
+	recursive := hasFlag(args, 'r', "--recursive") || hasFlag(args, 'R', "")
+	if hasFlag(args, 0, "--no-preserve-root") {
+		w.an.add(LevelCritical, "destroy_filesystem", "disables root protection", text)
+	}
+	for _, i := range operands(args) {
+		target := args[i]
+		if target == "" {
+			target = strings.Trim(w.text(cmd.words[i]), `"`)
+			target = strings.TrimSuffix(strings.TrimSuffix(target, "*"), "/")
+			if !homeWords[target] {
+				continue
+			}
+			target = "~"
+		}
+		switch {
+		case isSystemDir(target) && (recursive || strings.HasSuffix(target, "*")):
+			w.an.add(LevelCritical, "destroy_filesystem", "recursively deletes a system directory", text)
+		case isHistoryFile(target):
+			w.an.add(LevelHigh, "history_tamper", "deletes shell history", text)
+		case isSystemDir(target):
+			w.an.add(LevelHigh, "destroy_filesystem", "deletes a system path", text)
+		case recursive:
+			w.an.add(LevelModerate, "recursive_delete", "deletes recursively", text)
+		}
+	}
+}
+
+// find 检查从系统目录开始的删除
+func (w *walker) find(args []string, text string) {
+	if len(args) == 0 || !isSystemDir(args[0]) {
+		return
+	}
+	for i, a := range args {
+		deletes := a == "-delete"
+		if (a == "-exec" || a == "-execdir") && i+1 < len(args) {
+			deletes = path.Base(args[i+1]) == "rm" || path.Base(args[i+1]) == "shred"
+		}
+		if deletes {
+			w.an.add(LevelCritical, "destroy_filesystem", "deletes files across a system directory", text)
+			return
+		}
+	}
+}
+
+// chmod 检查系统目录权限变更和 setuid
+func (w *walker) chmod(cmd command, text string) {
+	args := cmd.args
+	for _, i := range operands(args) {
+		if isSystemDir(args[i]) {
+			w.an.add(LevelHigh, "permission_change", "changes ownership or permissions of a system directory", text)
+		}
+	}
+	if cmd.name != "chmod" {
+		return
+	}
+	for _, a := range args {
+		if strings.Contains(a, "+s") || len(a) == 4 && strings.Trim(a, "01234567") == "" && strings.ContainsAny(a[:1], "246") {
+			w.an.add(LevelHigh, "setuid", "sets the setuid or setgid bit", text)
+		}
+	}
+}
+
+// container 检查特权容器和挂载根目录
+func (w *walker) container(args []string, text string) {
+	for i, a := range args {
+		mount := ""
+		switch {
+		case a == "--privileged" || a == "--pid=host" || a == "--cap-add=ALL" || a == "--cap-add=SYS_ADMIN":
+			w.an.add(LevelHigh, "container_escape", "runs a privileged container", text)
+		case (a == "-v" || a == "--volume") && i+1 < len(args):
+			mount = args[i+1]
+		case strings.HasPrefix(a, "--volume="):
+			mount = strings.TrimPrefix(a, "--volume=")
+		}
+		if strings.HasPrefix(mount, "/:") {
+			w.an.add(LevelHigh, "container_escape", "mounts the host root filesystem", text)
+		}
+	}
+}
+
+// eval 分析 eval 的参数
+func (w *walker) eval(cmd command, text string) {
+	if script, ok := joinLiterals(cmd.words); ok {
+		w.nestedScript(script, text)
+		return
+	}
+	for _, word := range cmd.words {
+		if _, ok := literal(word); !ok {
+			w.dynamicScript(word, "eval", text)
+		}
+	}
+}
+
+// shell 分析 sh -c 脚本和进程替换脚本
+func (w *walker) shell(cmd command, text string) {
+	inline, skip := false, false
+	for i, a := range cmd.args {
+		if skip {
+			skip = false
+			continue
+		}
+		if len(a) > 1 && (a[0] == '-' || a[0] == '+') && a[1] != '-' {
+			inline = inline || strings.IndexByte(a, 'c') > 0
+			// -o pipefail、-O extglob 的取值不是脚本
+			skip = a == "-o" || a == "+o" || a == "-O" || a == "+O"
+			continue
+		}
+		if script, ok := literal(cmd.words[i]); ok {
+			if inline {
+				w.nestedScript(script, text)
+			}
+			w.runsDownload(script, cmd.name, text)
+			return
+		}
+		w.dynamicScript(cmd.words[i], cmd.name, text)
+		return
+	}
+}
+
+// interpreter 分析脚本语言的内联代码
+func (w *walker) interpreter(cmd command, flag, text string) {
+	for i, a := range cmd.args {
+		if a != flag || i+1 >= len(cmd.args) {
+			continue
+		}
+		code, ok := literal(cmd.words[i+1])
+		if !ok {
+			w.dynamicScript(cmd.words[i+1], cmd.name, text)
+			return
+		}
+		for _, marker := range inlineExecMarkers {
+			if strings.Contains(code, marker) {
+				w.an.add(LevelHigh, "script_exec", "inline "+cmd.name+" code spawns processes", text)
+				return
+			}
+		}
+		return
+	}
+	if ops := operands(cmd.args); len(ops) > 0 {
+		w.runsDownload(cmd.args[ops[0]], cmd.name, text)
+	}
+}
+
+// dynamicScript 处理由 shell、eval 或 source 执行的动态内容
+func (w *walker) dynamicScript(word *syntax.Word, runner, text string) {
+	if _, ok := literal(word); ok {
+		return
+	}
+	switch {
+	case containsCommand(word, downloaders):
+		w.an.add(LevelCritical, "remote_exec", runner+" executes downloaded code", text)
+	case containsCommand(word, decoders):
+		w.an.add(LevelCritical, "obfuscated_exec", runner+" executes decoded code", text)
+	case hasSubstitution(word):
+		w.an.add(LevelHigh, "dynamic_exec", runner+" executes code computed at runtime", text)
+	default:
+		w.an.add(LevelModerate, "dynamic_exec", runner+" executes code from a variable", text)
+	}
+}
+
+// nestedScript 递归分析以字符串形式传给 shell 的脚本
+func (w *walker) nestedScript(script, text string) {
+	if w.depth+1 >= maxDepth {
+		w.an.add(LevelHigh, "deep_nesting", "shell invocations are nested too deeply", text)
+		return
+	}
+	w.an.analyze(script, w.depth+1, w.nested)
+}
+
+// recordDownload 记录下载到本地的文件，之后执行该文件视为远程代码执行
+func (w *walker) recordDownload(cmd command) {
+	for i, a := range cmd.args {
+		if i+1 >= len(cmd.args) {
+			break
+		}
+		if a == "-o" || a == "--output" || a == "-O" || a == "--output-document" {
+			w.an.downloads[path.Clean(cmd.args[i+1])] = true
+		}
+	}
+}
+
+// runsDownload 检查是否执行了此前下载的文件
+func (w *walker) runsDownload(script, runner, text string) {
+	if script != "" && w.an.downloads[path.Clean(script)] {
+		w.an.add(LevelCritical, "remote_exec", runner+" executes a downloaded file", text)
+	}
+}
+
+// write 检查写入目标
+func (w *walker) write(target, text string) {
+	switch {
+	case hasPrefixAny(target, diskDevices):
+		w.an.add(LevelCritical, "disk_write", "writes directly to a block device", text)
+	case hasPrefixAny(target, accountFiles):
+		w.an.add(LevelCritical, "account_tamper", "overwrites account credentials", text)
+	case strings.HasPrefix(target, "/proc/") || strings.HasPrefix(target, "/sys/"):
+		w.an.add(LevelHigh, "kernel_write", "writes kernel parameters", text)
+	case strings.HasPrefix(target, "/var/log/"):
+		w.an.add(LevelHigh, "log_tamper", "overwrites system logs", text)
+	case isHistoryFile(target):
+		w.an.add(LevelHigh, "history_tamper", "overwrites shell history", text)
+	}
+}
+
+// redirect 检查重定向的写入目标和读取来源
+func (w *walker) redirect(r *syntax.Redirect) {
+	if r.Word == nil || r.Op == syntax.Hdoc || r.Op == syntax.DashHdoc || r.Op == syntax.WordHdoc {
+		return
+	}
+	target, ok := literal(r.Word)
+	if !ok {
+		return
+	}
+	text := w.text(r)
+	if strings.HasPrefix(target, "/dev/tcp/") || strings.HasPrefix(target, "/dev/udp/") {
+		w.an.add(LevelCritical, "reverse_shell", "redirects to a network socket", text)
+		return
+	}
+	if r.Op != syntax.DplIn && r.Op != syntax.DplOut {
+		w.an.a.Redirects = append(w.an.a.Redirects, target)
+	}
+	switch r.Op {
+	case syntax.RdrIn:
+		if hasPrefixAny(target, accountFiles) {
+			w.an.add(LevelHigh, "sensitive_file", "reads account credentials", text)
+		}
+	case syntax.RdrOut, syntax.AppOut, syntax.RdrInOut, syntax.ClbOut, syntax.RdrAll, syntax.AppAll, syntax.DplOut:
+		w.write(target, text)
+	}
+}
+
+// pipeline 检查把下载或解码内容交给解释器执行的管道
+func (w *walker) pipeline(stages []*syntax.Stmt) {
+	for i, st := range stages {
+		call, ok := st.Cmd.(*syntax.CallExpr)
+		if !ok || len(call.Args) == 0 {
+			continue
+		}
+		cmd, ok := resolve(call)
+		if !ok {
+			continue
+		}
+		text := w.span(stages[0], st)
+		if cmd.name == "yes" && i < len(stages)-1 {
+			w.an.add(LevelHigh, "resource_exhaustion", "pipes unbounded output into another command", text)
+		}
+		if i == 0 || !readsStdin(cmd) {
+			continue
+		}
+
+		upstream := stages[:i]
+		switch {
+		case anyContains(upstream, downloaders):
+			w.an.add(LevelCritical, "remote_exec", "pipes downloaded content into "+cmd.name, text)
+		case anyContains(upstream, decoders):
+			w.an.add(LevelCritical, "obfuscated_exec", "pipes decoded content into "+cmd.name, text)
+		case anyContains(upstream, netcats):
+			w.an.add(LevelCritical, "reverse_shell", "pipes network input into "+cmd.name, text)
+		default:
+			if script, ok := echoedScript(upstream); ok {
+				w.nestedScript(script, text)
+				continue
+			}
+			w.an.add(LevelModerate, "pipe_to_shell", "pipes generated text into "+cmd.name, text)
+		}
+	}
+}
+
+// echoedScript 返回 echo/printf 输出的静态脚本
+func echoedScript(stages []*syntax.Stmt) (string, bool) {
+	if len(stages) != 1 {
+		return "", false
+	}
+	call, ok := stages[0].Cmd.(*syntax.CallExpr)
+	if !ok {
+		return "", false
+	}
+	cmd, ok := resolve(call)
+	if !ok || cmd.name != "echo" && cmd.name != "printf" {
+		return "", false
+	}
+	words := cmd.words
+	for len(words) > 0 {
+		if flag, _ := literal(words[0]); !strings.HasPrefix(flag, "-") {
+			break
+		}
+		words = words[1:]
+	}
+	return joinLiterals(words)
+}
+
+// funcDecl 检测 fork 炸弹：函数在后台或管道中调用自身
+func (w *walker) funcDecl(f *syntax.FuncDecl) {
+	if f.Name == nil || f.Body == nil {
+		return
+	}
+	recursive, concurrent := false, false
+	syntax.Walk(f.Body, func(node syntax.Node) bool {
+		switch n := node.(type) {
+		case *syntax.CallExpr:
+			if len(n.Args) > 0 {
+				if name, ok := literal(n.Args[0]); ok && name == f.Name.Value {
+					recursive = true
+				}
+			}
+		case *syntax.Stmt:
+			concurrent = concurrent || n.Background
+		case *syntax.BinaryCmd:
+			concurrent = concurrent || n.Op == syntax.Pipe || n.Op == syntax.PipeAll
+		}
+		return true
+	})
+	if recursive && concurrent {
+		w.an.add(LevelCritical, "fork_bomb", "function spawns copies of itself", w.text(f))
+	}
+}
+
+// whileClause 检测没有退出条件的无限循环
+func (w *walker) whileClause(c *syntax.WhileClause) {
+	if len(c.Cond) != 1 {
+		return
+	}
+	call, ok := c.Cond[0].Cmd.(*syntax.CallExpr)
+	if !ok || len(call.Args) != 1 {
+		return
+	}
+	cond, _ := literal(call.Args[0])
+	endless := !c.Until && (cond == "true" || cond == ":") || c.Until && cond == "false"
+	if !endless {
+		return
+	}
+	exits := false
+	for _, st := range c.Do {
+		syntax.Walk(st, func(node syntax.Node) bool {
+			if call, ok := node.(*syntax.CallExpr); ok && len(call.Args) > 0 {
+				if name, _ := literal(call.Args[0]); name == "break" || name == "exit" || name == "return" {
+					exits = true
+				}
+			}
+			return !exits
+		})
+	}
+	if !exits {
+		w.an.add(LevelHigh, "infinite_loop", "loop never terminates", w.text(c))
+	}
+}
+
+// joinLiterals 拼接静态单词，任一单词为动态时 ok 为 false
+func joinLiterals(words []*syntax.Word) (string, bool) {
+	parts := make([]string, 0, len(words))
+	for _, word := range words {
+		lit, ok := literal(word)
+		if !ok {
+			return "", false
+		}
+		parts = append(parts, lit)
+	}
+	return strings.Join(parts, " "), true
+}
+
+// containsCommand 节点中是否调用了集合中的命令
+func containsCommand(node syntax.Node, names map[string]bool) bool {
+	found := false
+	syntax.Walk(node, func(n syntax.Node) bool {
+		if call, ok := n.(*syntax.CallExpr); ok && len(call.Args) > 0 {
+			if cmd, ok := resolve(call); ok && names[cmd.name] {
+				found = true
+			}
+		}
+		return !found
+	})
+	return found
+}
+
+// anyContains 任一管道阶段是否调用了集合中的命令
+func anyContains(stages []*syntax.Stmt, names map[string]bool) bool {
+	for _, st := range stages {
+		if containsCommand(st, names) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasSubstitution 单词中是否包含命令替换或进程替换
+func hasSubstitution(word *syntax.Word) bool {
+	found := false
+	syntax.Walk(word, func(n syntax.Node) bool {
+		switch n.(type) {
+		case *syntax.CmdSubst, *syntax.ProcSubst:
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+// hasANSIQuote 单词中是否包含 $'...' 转义字符串
+func hasANSIQuote(word *syntax.Word) bool {
+	for _, part := range word.Parts {
+		if q, ok := part.(*syntax.SglQuoted); ok && q.Dollar {
+			return true
+		}
+	}
+	return false
+}
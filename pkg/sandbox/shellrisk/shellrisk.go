@@ -0,0 +1,222 @@
+// Package shellrisk 基于 shell 语法树对 Bash 命令做静态风险分析
+//
+// 与按子串匹配不同，分析器理解管道、命令替换、进程替换和重定向，
+// 对每个命令片段单独分类，能够识别 base64 | sh、$(...) 拼接命令名等混淆手法。
+package shellrisk
+
+import (
+	"strings"
+
+	"mvdan.cc/sh/v3/syntax"
+)
+
+// Level 命令风险级别
+type Level int
+
+const (
+	// LevelSafe 未发现风险
+	LevelSafe Level = iota
+	// LevelModerate 有副作用但通常无害（递归删除、网络下载等）
+	LevelModerate
+	// LevelHigh 可能破坏系统或越权，沙箱默认阻止
+	LevelHigh
+	// LevelCritical 明确的破坏性或远程代码执行，任何模式下都阻止
+	LevelCritical
+)
+
+// String 返回风险级别名称
+func (l Level) String() string {
+	switch l {
+	case LevelModerate:
+		return "moderate"
+	case LevelHigh:
+		return "high"
+	case LevelCritical:
+		return "critical"
+	default:
+		return "safe"
+	}
+}
+
+// Finding 一条风险发现
+type Finding struct {
+	Level   Level  `json:"level"`
+	Rule    string `json:"rule"`
+	Reason  string `json:"reason"`
+	Segment string `json:"segment"` // 触发规则的命令片段原文
+}
+
+// Segment 命令中的一个简单命令
+type Segment struct {
+	Name    string   `json:"name"`              // 程序名（去掉路径和包装命令），动态命令名为空
+	Args    []string `json:"args,omitempty"`    // 参数，无法静态确定的参数为空字符串
+	Text    string   `json:"text"`              // 片段原文
+	Dynamic bool     `json:"dynamic,omitempty"` // 命令名在运行时才能确定
+	Nested  bool     `json:"nested,omitempty"`  // 位于命令替换或进程替换内部
+}
+
+// Analysis 命令分析结果
+type Analysis struct {
+	Command         string    `json:"command"`
+	Segments        []Segment `json:"segments"`
+	Findings        []Finding `json:"findings,omitempty"`
+	Redirects       []string  `json:"redirects,omitempty"` // 重定向读写的文件路径，仅含静态可确定的路径
+	Level           Level     `json:"level"`
+	HasSubstitution bool      `json:"has_substitution,omitempty"` // 包含命令替换或进程替换
+}
+
+// Dangerous 是否达到沙箱阻止的级别
+func (a *Analysis) Dangerous() bool {
+	return a.Level >= LevelHigh
+}
+
+// Reason 返回最高级别发现的原因，无发现时返回空字符串
+func (a *Analysis) Reason() string {
+	var top *Finding
+	for i := range a.Findings {
+		if top == nil || a.Findings[i].Level > top.Level {
+			top = &a.Findings[i]
+		}
+	}
+	if top == nil {
+		return ""
+	}
+	return top.Reason + ": " + top.Segment
+}
+
+// maxDepth sh -c / eval 字符串的最大递归分析深度
+const maxDepth = 4
+
+// Analyze 解析并分析命令
+// 无法解析的命令按 LevelHigh 处理，因为 shell 实际执行的语义无法确定
+func Analyze(command string) *Analysis {
+	an := &analyzer{
+		a:         &Analysis{Command: command},
+		seen:      make(map[string]bool),
+		downloads: make(map[string]bool),
+		vars:      make(map[string]string),
+	}
+	an.analyze(command, 0, false)
+	return an.a
+}
+
+// analyzer 分析状态
+type analyzer struct {
+	a         *Analysis
+	seen      map[string]bool   // 已记录的 rule+segment，避免重复发现
+	downloads map[string]bool   // 下载到本地的文件路径
+	vars      map[string]string // 已知静态值的 shell 变量
+}
+
+// add 记录一条发现并提升整体级别
+func (an *analyzer) add(level Level, rule, reason, segment string) {
+	key := rule + "\x00" + segment
+	if an.seen[key] {
+		return
+	}
+	an.seen[key] = true
+	an.a.Findings = append(an.a.Findings, Finding{Level: level, Rule: rule, Reason: reason, Segment: segment})
+	if level > an.a.Level {
+		an.a.Level = level
+	}
+}
+
+// analyze 解析一段 shell 源码并遍历语法树
+func (an *analyzer) analyze(src string, depth int, nested bool) {
+	if strings.ContainsRune(src, 0) {
+		an.add(LevelHigh, "null_byte", "command contains a NUL byte", strings.ReplaceAll(src, "\x00", `\0`))
+		return
+	}
+	file, err := syntax.NewParser(syntax.Variant(syntax.LangBash)).Parse(strings.NewReader(src), "")
+	if err != nil {
+		an.add(LevelHigh, "unparsable", "command cannot be parsed ("+err.Error()+")", src)
+		return
+	}
+
+	w := &walker{an: an, src: src, depth: depth, nested: nested, pipes: make(map[*syntax.BinaryCmd]bool)}
+	syntax.Walk(file, w.visit)
+}
+
+// walker 遍历单段源码的语法树
+type walker struct {
+	an     *analyzer
+	src    string
+	depth  int
+	nested bool // 整段源码本身位于替换内部
+
+	substs [][2]uint                  // 命令/进程替换的源码区间
+	pipes  map[*syntax.BinaryCmd]bool // 已作为管道整体分析过的节点
+}
+
+// visit syntax.Walk 回调
+func (w *walker) visit(node syntax.Node) bool {
+	switch n := node.(type) {
+	case *syntax.CmdSubst:
+		w.an.a.HasSubstitution = true
+		w.substs = append(w.substs, [2]uint{n.Pos().Offset(), n.End().Offset()})
+	case *syntax.ProcSubst:
+		w.an.a.HasSubstitution = true
+		w.substs = append(w.substs, [2]uint{n.Pos().Offset(), n.End().Offset()})
+	case *syntax.BinaryCmd:
+		if (n.Op == syntax.Pipe || n.Op == syntax.PipeAll) && !w.pipes[n] {
+			w.pipeline(w.flattenPipe(n))
+		}
+	case *syntax.CallExpr:
+		w.call(n)
+	case *syntax.DeclClause:
+		w.assign(n.Args)
+	case *syntax.Redirect:
+		w.redirect(n)
+	case *syntax.FuncDecl:
+		w.funcDecl(n)
+	case *syntax.WhileClause:
+		w.whileClause(n)
+	case *syntax.ForClause:
+		if loop, ok := n.Loop.(*syntax.CStyleLoop); ok && loop.Cond == nil {
+			w.an.add(LevelHigh, "infinite_loop", "loop never terminates", w.text(n))
+		}
+	}
+	return true
+}
+
+// text 返回节点对应的源码
+func (w *walker) text(node syntax.Node) string {
+	return w.span(node, node)
+}
+
+// span 返回从 first 开始到 last 结束的源码
+func (w *walker) span(first, last syntax.Node) string {
+	start, end := first.Pos().Offset(), last.End().Offset()
+	if start > end || end > uint(len(w.src)) {
+		return ""
+	}
+	return w.src[start:end]
+}
+
+// inSubst 节点是否位于命令/进程替换内部
+func (w *walker) inSubst(node syntax.Node) bool {
+	if w.nested {
+		return true
+	}
+	off := node.Pos().Offset()
+	for _, r := range w.substs {
+		if off > r[0] && off < r[1] {
+			return true
+		}
+	}
+	return false
+}
+
+// flattenPipe 将嵌套的管道树展开为各阶段
+func (w *walker) flattenPipe(n *syntax.BinaryCmd) []*syntax.Stmt {
+	w.pipes[n] = true
+	var stages []*syntax.Stmt
+	for _, side := range []*syntax.Stmt{n.X, n.Y} {
+		if b, ok := side.Cmd.(*syntax.BinaryCmd); ok && (b.Op == syntax.Pipe || b.Op == syntax.PipeAll) {
+			stages = append(stages, w.flattenPipe(b)...)
+			continue
+		}
+		stages = append(stages, side)
+	}
+	return stages
+}
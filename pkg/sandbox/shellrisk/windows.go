@@ -0,0 +1,390 @@
+package shellrisk
+
+import (
+	"encoding/base64"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf16"
+)
+
+// AnalyzeWindows 分析 cmd.exe / PowerShell 命令
+//
+// Windows shell 的语法无法用 Bash 解析器处理，这里按管道和命令分隔符切分片段，
+// 逐段识别程序名（忽略大小写、路径和扩展名）并套用 Windows 规则。
+// cmd /c、powershell -Command 的参数和 -EncodedCommand 的解码内容会递归分析。
+func AnalyzeWindows(command string) *Analysis {
+	an := &analyzer{a: &Analysis{Command: command}, seen: make(map[string]bool), downloads: make(map[string]bool)}
+	an.analyzeWindows(command, 0, false)
+	return an.a
+}
+
+// winSegment Windows 命令片段
+type winSegment struct {
+	text  string
+	name  string
+	args  []string // 已去掉引号
+	piped bool     // 前一个片段通过管道输入
+}
+
+// winRemovers 删除文件的命令（含 PowerShell 别名）
+var winRemovers = map[string]bool{
+	"del": true, "erase": true, "rd": true, "rmdir": true, "rm": true, "ri": true, "remove-item": true,
+}
+
+// winRecurseFlags 递归删除选项
+var winRecurseFlags = map[string]bool{
+	"/s": true, "-r": true, "-recurse": true, "-rf": true, "-fr": true,
+}
+
+// winDownloaders 下载远程内容的命令
+var winDownloaders = map[string]bool{
+	"iwr": true, "invoke-webrequest": true, "irm": true, "invoke-restmethod": true,
+	"curl": true, "wget": true, "start-bitstransfer": true, "bitsadmin": true,
+}
+
+// winEvaluators 执行字符串或标准输入中脚本的命令
+var winEvaluators = map[string]bool{
+	"iex": true, "invoke-expression": true, "powershell": true, "pwsh": true, "cmd": true,
+}
+
+// winShells 执行其参数中命令的 shell
+var winShells = map[string]bool{"cmd": true, "powershell": true, "pwsh": true}
+
+// winLaunchers 启动其参数中程序的命令
+var winLaunchers = map[string]bool{"start": true, "call": true, "start-process": true, "saps": true}
+
+// winLOLBins 常被用于执行远程或隐藏载荷的系统程序
+var winLOLBins = map[string]bool{
+	"mshta": true, "rundll32": true, "regsvr32": true, "installutil": true, "msiexec": true, "wscript": true, "cscript": true,
+}
+
+// winDiskTools 格式化或重新分区磁盘的命令
+var winDiskTools = map[string]bool{
+	"format": true, "diskpart": true, "format-volume": true, "clear-disk": true, "initialize-disk": true, "remove-partition": true,
+}
+
+// winPower 关机或注销
+var winPower = map[string]bool{"shutdown": true, "stop-computer": true, "restart-computer": true, "logoff": true}
+
+// winRootArg 驱动器根目录、系统目录和用户目录
+var winRootArg = regexp.MustCompile(`(?i)^(?:[a-z]:[\\/]?\*?|[\\/]\*?|~|\$home|\$env:(?:systemroot|windir|userprofile|systemdrive)\\?|%(?:systemroot|windir|userprofile|systemdrive)%\\?|[a-z]:[\\/](?:windows|users|program files(?: \(x86\))?|programdata)[\\/]?\*?)$`)
+
+// winSensitivePaths 凭据和系统配置文件
+var winSensitivePaths = []string{`\system32\config\sam`, `\system32\config\security`, `\system32\config\system`, `ntds.dit`, `hklm\sam`, `hklm\security`}
+
+// analyzeWindows 分析一段 Windows 命令
+func (an *analyzer) analyzeWindows(src string, depth int, nested bool) {
+	if strings.ContainsRune(src, 0) {
+		an.add(LevelHigh, "null_byte", "command contains a NUL byte", strings.ReplaceAll(src, "\x00", `\0`))
+		return
+	}
+	lower := strings.ToLower(src)
+	if strings.Contains(lower, "$(") {
+		an.a.HasSubstitution = true
+	}
+	compact := strings.Join(strings.Fields(lower), "")
+	if strings.Contains(compact, "while($true)") || strings.Contains(compact, "for(;;)") {
+		an.add(LevelHigh, "infinite_loop", "loop never terminates", src)
+	}
+
+	segments := splitWindows(src)
+	download := false
+	for _, seg := range segments {
+		if winDownloaders[seg.name] || (seg.name == "certutil" && hasArg(seg.args, "-urlcache", "/urlcache")) {
+			download = true
+		}
+	}
+	if strings.Contains(lower, "downloadstring") || strings.Contains(lower, "downloadfile") || strings.Contains(lower, "net.webclient") {
+		download = true
+	}
+
+	for _, seg := range segments {
+		an.a.Segments = append(an.a.Segments, Segment{Name: seg.name, Args: seg.args, Text: seg.text, Nested: nested})
+		an.windowsSegment(seg, download, depth)
+	}
+}
+
+// windowsSegment 对单个片段应用规则
+func (an *analyzer) windowsSegment(seg winSegment, download bool, depth int) {
+	name, args, text := seg.name, seg.args, seg.text
+	lowerArgs := make([]string, len(args))
+	for i, a := range args {
+		lowerArgs[i] = strings.ToLower(a)
+	}
+	joined := strings.Join(lowerArgs, " ")
+
+	switch {
+	case winRemovers[name]:
+		recursive := slices.ContainsFunc(lowerArgs, func(a string) bool { return winRecurseFlags[a] })
+		if recursive && slices.ContainsFunc(args, winRootArg.MatchString) {
+			an.add(LevelCritical, "destroy_filesystem", "recursive delete of a drive root or system directory", text)
+		} else if recursive {
+			an.add(LevelModerate, "recursive_delete", "recursive delete", text)
+		}
+	case winDiskTools[name]:
+		an.add(LevelCritical, "disk_format", "disk format or partition change", text)
+	case winPower[name]:
+		an.add(LevelCritical, "system_power", "shuts down or restarts the system", text)
+	case name == "bcdedit" || name == "bootrec":
+		an.add(LevelCritical, "boot_config", "modifies boot configuration", text)
+	case name == "cipher" && hasArg(lowerArgs, "/w"):
+		an.add(LevelCritical, "disk_write", "wipes free disk space", text)
+	case (name == "vssadmin" || name == "wbadmin") && hasArg(lowerArgs, "delete"),
+		name == "wmic" && strings.Contains(joined, "shadowcopy") && strings.Contains(joined, "delete"):
+		an.add(LevelCritical, "shadow_copy_delete", "deletes backups or shadow copies", text)
+	case name == "reg" && len(lowerArgs) > 0 && slices.Contains([]string{"add", "delete", "import", "restore", "save"}, lowerArgs[0]):
+		if lowerArgs[0] == "save" {
+			an.add(LevelHigh, "sensitive_file", "exports a registry hive", text)
+		} else if strings.Contains(joined, "hklm") || strings.Contains(joined, "hkey_local_machine") || strings.Contains(joined, `\run`) {
+			an.add(LevelHigh, "registry_write", "modifies machine-wide or autorun registry keys", text)
+		}
+	case slices.Contains([]string{"set-itemproperty", "new-itemproperty", "remove-itemproperty", "sp"}, name) &&
+		(strings.Contains(joined, "hklm:") || strings.Contains(joined, `\run`)):
+		an.add(LevelHigh, "registry_write", "modifies machine-wide or autorun registry keys", text)
+	case name == "runas" || name == "gsudo" || name == "sudo",
+		winLaunchers[name] && hasArg(lowerArgs, "runas"):
+		an.add(LevelHigh, "privilege_escalation", "runs a command with elevated privileges", text)
+	case name == "net" && len(lowerArgs) > 0 && (lowerArgs[0] == "user" || lowerArgs[0] == "localgroup") &&
+		(hasArg(lowerArgs, "/add", "/delete") || strings.Contains(joined, "administrators")),
+		slices.Contains([]string{"new-localuser", "remove-localuser", "add-localgroupmember"}, name):
+		an.add(LevelHigh, "account_tamper", "creates or modifies user accounts", text)
+	case name == "schtasks" && hasArg(lowerArgs, "/create", "/change"),
+		name == "sc" && len(lowerArgs) > 0 && slices.Contains([]string{"create", "config", "delete"}, lowerArgs[0]),
+		slices.Contains([]string{"new-service", "register-scheduledtask", "set-service"}, name):
+		an.add(LevelHigh, "persistence", "creates scheduled tasks or services", text)
+	case name == "set-mppreference" && strings.Contains(joined, "-disable"),
+		name == "add-mppreference" && strings.Contains(joined, "exclusion"),
+		name == "netsh" && strings.Contains(joined, "firewall") && strings.Contains(joined, "off"),
+		name == "set-executionpolicy" && (strings.Contains(joined, "unrestricted") || strings.Contains(joined, "bypass")):
+		an.add(LevelHigh, "defense_evasion", "disables security controls", text)
+	case name == "wevtutil" && hasArg(lowerArgs, "cl", "clear-log"), name == "clear-eventlog":
+		an.add(LevelHigh, "history_tamper", "clears event logs", text)
+	case name == "takeown", name == "icacls" && hasArg(lowerArgs, "/grant") && strings.Contains(joined, "everyone"):
+		an.add(LevelHigh, "permission_tamper", "takes ownership or grants access to everyone", text)
+	case winLOLBins[name]:
+		an.add(LevelHigh, "lolbin", "system binary commonly used to run hidden payloads", text)
+	case name == "iex" || name == "invoke-expression":
+		if download {
+			an.add(LevelCritical, "remote_exec", "executes downloaded content", text)
+		} else {
+			an.add(LevelHigh, "dynamic_command", "evaluates a string as code", text)
+		}
+	case winDownloaders[name], name == "certutil" && hasArg(lowerArgs, "-urlcache", "/urlcache"):
+		an.add(LevelModerate, "download", "downloads remote content", text)
+	}
+
+	if seg.piped && download && winEvaluators[name] {
+		an.add(LevelCritical, "remote_exec", "pipes downloaded content into a shell", text)
+	}
+
+	for _, sensitive := range winSensitivePaths {
+		if strings.Contains(strings.ToLower(text), sensitive) {
+			an.add(LevelHigh, "sensitive_file", "accesses credential stores", text)
+			break
+		}
+	}
+
+	if depth >= maxDepth {
+		return
+	}
+	if winShells[name] {
+		an.windowsShellArgs(name, args, depth)
+	}
+	if winLaunchers[name] {
+		// 跳过 start 的窗口标题和选项
+		rest := args
+		for len(rest) > 0 && (rest[0] == "" || strings.HasPrefix(rest[0], "/") || strings.HasPrefix(rest[0], "-")) {
+			flag := rest[0]
+			rest = rest[1:]
+			if strings.EqualFold(flag, "-filepath") {
+				break
+			}
+		}
+		if len(rest) > 0 {
+			quoted := make([]string, len(rest))
+			for i, a := range rest {
+				quoted[i] = quoteWindows(a)
+			}
+			an.analyzeWindows(strings.Join(quoted, " "), depth+1, true)
+		}
+	}
+}
+
+// windowsShellArgs 递归分析 cmd /c 与 powershell -Command / -EncodedCommand 的参数
+func (an *analyzer) windowsShellArgs(name string, args []string, depth int) {
+	for i, arg := range args {
+		flag := strings.ToLower(arg)
+		rest := strings.Join(args[i+1:], " ")
+		if name == "cmd" {
+			if flag == "/c" || flag == "/k" {
+				an.analyzeWindows(rest, depth+1, true)
+				return
+			}
+			continue
+		}
+		switch {
+		case flag == "-command" || flag == "-c":
+			an.analyzeWindows(rest, depth+1, true)
+			return
+		case (flag == "-ec" || strings.HasPrefix(flag, "-e") && strings.HasPrefix("-encodedcommand", flag)) && i+1 < len(args):
+			an.add(LevelHigh, "obfuscated_exec", "runs an encoded PowerShell command", arg+" "+args[i+1])
+			if decoded, ok := decodePowerShell(args[i+1]); ok {
+				an.analyzeWindows(decoded, depth+1, true)
+			}
+			return
+		}
+	}
+}
+
+// decodePowerShell 解码 -EncodedCommand 参数（Base64 编码的 UTF-16LE）
+func decodePowerShell(encoded string) (string, bool) {
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(data)%2 != 0 {
+		return "", false
+	}
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		units[i] = uint16(data[2*i]) | uint16(data[2*i+1])<<8
+	}
+	return string(utf16.Decode(units)), true
+}
+
+// splitWindows 按管道和命令分隔符（& && || ; 换行）切分命令，引号内的分隔符不切分
+func splitWindows(src string) []winSegment {
+	var segments []winSegment
+	var current strings.Builder
+	var quote rune
+	piped := false
+
+	flush := func(nextPiped bool) {
+		if seg, ok := parseWindowsSegment(current.String()); ok {
+			seg.piped = piped
+			segments = append(segments, seg)
+		}
+		current.Reset()
+		piped = nextPiped
+	}
+
+	runes := []rune(src)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		if quote != 0 {
+			current.WriteRune(r)
+			if r == quote {
+				quote = 0
+			}
+			continue
+		}
+		switch r {
+		case '"', '\'':
+			quote = r
+			current.WriteRune(r)
+		case '^', '`':
+			// cmd 与 PowerShell 的转义字符
+			if i+1 < len(runes) {
+				i++
+				current.WriteRune(runes[i])
+			}
+		case '|':
+			if i+1 < len(runes) && runes[i+1] == '|' {
+				i++
+				flush(false)
+			} else {
+				flush(true)
+			}
+		case '&':
+			if i+1 < len(runes) && runes[i+1] == '&' {
+				i++
+			} else if strings.TrimSpace(current.String()) == "" {
+				// PowerShell 调用运算符：& "C:\tool.exe"
+				continue
+			}
+			flush(false)
+		case ';', '\n', '\r':
+			flush(false)
+		default:
+			current.WriteRune(r)
+		}
+	}
+	flush(false)
+	return segments
+}
+
+// parseWindowsSegment 解析片段的程序名和参数
+func parseWindowsSegment(text string) (winSegment, bool) {
+	text = strings.TrimSpace(text)
+	text = strings.TrimLeft(text, "@(")
+	words := splitWindowsWords(text)
+	if len(words) == 0 {
+		return winSegment{}, false
+	}
+	return winSegment{text: text, name: windowsProgram(words[0]), args: words[1:]}, true
+}
+
+// splitWindowsWords 按空白切分单词，去掉引号
+func splitWindowsWords(text string) []string {
+	var words []string
+	var word strings.Builder
+	var quote rune
+	inWord := false
+	for _, r := range text {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '"' || r == '\'':
+			quote, inWord = r, true
+		case r == ' ' || r == '\t':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words
+}
+
+// windowsProgram 规范化程序名：小写、去掉路径和可执行扩展名
+func windowsProgram(word string) string {
+	name := strings.ToLower(word)
+	if i := strings.LastIndexAny(name, `\/`); i >= 0 {
+		name = name[i+1:]
+	}
+	for _, ext := range []string{".exe", ".com", ".cmd", ".bat", ".ps1"} {
+		if strings.HasSuffix(name, ext) {
+			return strings.TrimSuffix(name, ext)
+		}
+	}
+	return name
+}
+
+// quoteWindows 为含空格的参数加引号
+func quoteWindows(s string) string {
+	if strings.ContainsAny(s, " \t") {
+		return `"` + s + `"`
+	}
+	return s
+}
+
+// hasArg 参数中是否包含任一选项（忽略大小写）
+func hasArg(args []string, flags ...string) bool {
+	for _, a := range args {
+		for _, f := range flags {
+			if strings.EqualFold(a, f) {
+				return true
+			}
+		}
+	}
+	return false
+}
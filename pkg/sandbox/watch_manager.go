@@ -0,0 +1,348 @@
+package sandbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+const (
+	// maxPendingChanges 单个订阅缓存的最大变更数，超出后丢弃最早的变更
+	maxPendingChanges = 500
+	// watchSettleDelay 等待变更时的静默期，合并一次保存产生的多个事件
+	watchSettleDelay = 200 * time.Millisecond
+	// ignoreWindow Ignore 后忽略同一路径变更的时长
+	ignoreWindow = 2 * time.Second
+)
+
+// FileChange 订阅收到的文件变更
+type FileChange struct {
+	WatchID string    `json:"watch_id"`
+	Path    string    `json:"path"` // 工作目录内的文件为相对路径
+	Op      FileOp    `json:"op"`
+	Time    time.Time `json:"time"`
+}
+
+// WatchOptions 文件监听订阅选项
+type WatchOptions struct {
+	// Paths 监听的文件或目录（目录递归监听）
+	Paths []string
+	// Patterns 只保留匹配的变更，支持 ** 通配，同时匹配相对路径和文件名
+	Patterns []string
+	// Notify 变更是否注入到 Agent 的上下文
+	Notify bool
+}
+
+// WatchInfo 订阅信息
+type WatchInfo struct {
+	ID        string    `json:"watch_id"`
+	Paths     []string  `json:"paths"`
+	Patterns  []string  `json:"patterns,omitempty"`
+	Notify    bool      `json:"notify"`
+	Pending   int       `json:"pending"`
+	Dropped   int       `json:"dropped,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// watchSubscription 一个文件监听订阅
+type watchSubscription struct {
+	info    WatchInfo
+	pending []FileChange
+	signal  chan struct{} // 有新变更时非阻塞写入
+}
+
+// WatchManager 管理沙箱上的文件监听订阅
+// 变更按订阅缓存，可通过 Drain/Wait 拉取，Notify 订阅的变更由 Agent 注入上下文
+type WatchManager struct {
+	sandbox Sandbox
+
+	mu       sync.Mutex
+	subs     map[string]*watchSubscription
+	ignored  map[string]time.Time // 路径 -> 忽略截止时间
+	onChange func(FileChange)
+}
+
+// NewWatchManager 创建文件监听管理器
+func NewWatchManager(sb Sandbox) *WatchManager {
+	return &WatchManager{
+		sandbox: sb,
+		subs:    make(map[string]*watchSubscription),
+		ignored: make(map[string]time.Time),
+	}
+}
+
+// OnChange 设置变更回调，每次记录变更后调用（在监听 goroutine 中执行）
+func (m *WatchManager) OnChange(fn func(FileChange)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onChange = fn
+}
+
+// Start 创建订阅
+func (m *WatchManager) Start(opts WatchOptions) (WatchInfo, error) {
+	if len(opts.Paths) == 0 {
+		return WatchInfo{}, errors.New("at least one path is required")
+	}
+	for _, p := range opts.Patterns {
+		if !doublestar.ValidatePattern(p) {
+			return WatchInfo{}, fmt.Errorf("invalid pattern %q", p)
+		}
+	}
+
+	sub := &watchSubscription{
+		info: WatchInfo{
+			Paths:     slices.Clone(opts.Paths),
+			Patterns:  slices.Clone(opts.Patterns),
+			Notify:    opts.Notify,
+			CreatedAt: time.Now(),
+		},
+		signal: make(chan struct{}, 1),
+	}
+
+	// 监听回调可能在 Watch 返回前触发，先持有锁直到订阅登记完成
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	id, err := m.sandbox.Watch(opts.Paths, func(event FileChangeEvent) {
+		m.record(sub, event)
+	})
+	if err != nil {
+		return WatchInfo{}, err
+	}
+	sub.info.ID = id
+	m.subs[id] = sub
+	return sub.info, nil
+}
+
+// Stop 取消订阅
+func (m *WatchManager) Stop(id string) error {
+	m.mu.Lock()
+	_, ok := m.subs[id]
+	delete(m.subs, id)
+	m.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("watch not found: %s", id)
+	}
+	return m.sandbox.Unwatch(id)
+}
+
+// StopAll 取消所有订阅
+func (m *WatchManager) StopAll() {
+	m.mu.Lock()
+	ids := make([]string, 0, len(m.subs))
+	for id := range m.subs {
+		ids = append(ids, id)
+	}
+	m.subs = make(map[string]*watchSubscription)
+	m.mu.Unlock()
+	for _, id := range ids {
+		_ = m.sandbox.Unwatch(id)
+	}
+}
+
+// List 列出订阅
+func (m *WatchManager) List() []WatchInfo {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	infos := make([]WatchInfo, 0, len(m.subs))
+	for _, sub := range m.subs {
+		info := sub.info
+		info.Pending = len(sub.pending)
+		infos = append(infos, info)
+	}
+	slices.SortFunc(infos, func(a, b WatchInfo) int { return a.CreatedAt.Compare(b.CreatedAt) })
+	return infos
+}
+
+// Drain 取出订阅缓存的变更，同一路径的多次变更合并为最后一次
+func (m *WatchManager) Drain(id string) ([]FileChange, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	sub, ok := m.subs[id]
+	if !ok {
+		return nil, fmt.Errorf("watch not found: %s", id)
+	}
+	return m.drain(sub), nil
+}
+
+// Wait 等待订阅出现变更，最多等待 timeout
+// 收到变更后再等待一个静默期，使一次保存产生的多个事件合并返回；超时返回空列表
+func (m *WatchManager) Wait(ctx context.Context, id string, timeout time.Duration) ([]FileChange, error) {
+	m.mu.Lock()
+	sub, ok := m.subs[id]
+	m.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("watch not found: %s", id)
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		if changes, _ := m.Drain(id); len(changes) > 0 {
+			// 静默期内的后续变更一起返回
+			select {
+			case <-time.After(watchSettleDelay):
+			case <-ctx.Done():
+				return changes, ctx.Err()
+			}
+			more, _ := m.Drain(id)
+			return coalesceChanges(append(changes, more...)), nil
+		}
+		select {
+		case <-sub.signal:
+		case <-timer.C:
+			return nil, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// DrainNotifications 取出所有 Notify 订阅的变更
+func (m *WatchManager) DrainNotifications() []FileChange {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var changes []FileChange
+	for _, sub := range m.subs {
+		if sub.info.Notify {
+			changes = append(changes, m.drain(sub)...)
+		}
+	}
+	slices.SortFunc(changes, func(a, b FileChange) int { return a.Time.Compare(b.Time) })
+	return changes
+}
+
+// Ignore 忽略某路径接下来短时间内的变更，用于过滤 Agent 自身的写入
+func (m *WatchManager) Ignore(path string) {
+	abs := m.absPath(path)
+	now := time.Now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for p, until := range m.ignored {
+		if now.After(until) {
+			delete(m.ignored, p)
+		}
+	}
+	m.ignored[abs] = now.Add(ignoreWindow)
+	for _, sub := range m.subs {
+		sub.pending = slices.DeleteFunc(sub.pending, func(c FileChange) bool {
+			return m.absPath(c.Path) == abs
+		})
+	}
+}
+
+// record 记录一次变更
+func (m *WatchManager) record(sub *watchSubscription, event FileChangeEvent) {
+	rel := m.relPath(event.Path)
+	if !matchesWatchPatterns(sub.info.Patterns, rel) {
+		return
+	}
+
+	m.mu.Lock()
+	if until, ok := m.ignored[filepath.Clean(event.Path)]; ok {
+		if time.Now().Before(until) {
+			m.mu.Unlock()
+			return
+		}
+		delete(m.ignored, filepath.Clean(event.Path))
+	}
+	change := FileChange{WatchID: sub.info.ID, Path: rel, Op: event.Op, Time: time.Now()}
+	sub.pending = append(sub.pending, change)
+	if over := len(sub.pending) - maxPendingChanges; over > 0 {
+		sub.pending = sub.pending[over:]
+		sub.info.Dropped += over
+	}
+	onChange := m.onChange
+	m.mu.Unlock()
+
+	if onChange != nil {
+		onChange(change)
+	}
+
+	select {
+	case sub.signal <- struct{}{}:
+	default:
+	}
+}
+
+// drain 取出并合并订阅缓存的变更；调用方持有 m.mu
+func (m *WatchManager) drain(sub *watchSubscription) []FileChange {
+	changes := coalesceChanges(sub.pending)
+	sub.pending = nil
+	return changes
+}
+
+// relPath 工作目录内的路径转为相对路径
+func (m *WatchManager) relPath(path string) string {
+	if rel, err := filepath.Rel(m.sandbox.WorkDir(), path); err == nil && !strings.HasPrefix(rel, "..") {
+		return filepath.ToSlash(rel)
+	}
+	return path
+}
+
+// absPath 相对路径按工作目录转为绝对路径
+func (m *WatchManager) absPath(path string) string {
+	if filepath.IsAbs(path) {
+		return filepath.Clean(path)
+	}
+	return filepath.Join(m.sandbox.WorkDir(), filepath.FromSlash(path))
+}
+
+// coalesceChanges 合并同一路径的变更，保留首次出现的顺序和最后一次的类型
+func coalesceChanges(changes []FileChange) []FileChange {
+	if len(changes) < 2 {
+		return changes
+	}
+	index := make(map[string]int, len(changes))
+	out := make([]FileChange, 0, len(changes))
+	for _, c := range changes {
+		if i, ok := index[c.Path]; ok {
+			// 新建后写入仍视为新建
+			if out[i].Op == FileOpCreate && c.Op == FileOpWrite {
+				c.Op = FileOpCreate
+			}
+			out[i] = c
+			continue
+		}
+		index[c.Path] = len(out)
+		out = append(out, c)
+	}
+	return out
+}
+
+// matchesWatchPatterns 检查路径是否匹配任一模式，无模式时全部匹配
+func matchesWatchPatterns(patterns []string, rel string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	base := filepath.Base(rel)
+	for _, p := range patterns {
+		if ok, _ := doublestar.Match(p, rel); ok {
+			return true
+		}
+		if ok, _ := doublestar.Match(p, base); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// FormatFileChanges 将变更格式化为注入上下文的文本
+func FormatFileChanges(changes []FileChange) string {
+	if len(changes) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString("<file-changes>\nFiles changed outside your tool calls since the last step:\n")
+	for _, c := range changes {
+		fmt.Fprintf(&sb, "- %s %s\n", c.Op, c.Path)
+	}
+	sb.WriteString("Re-read these files before editing them.\n</file-changes>")
+	return sb.String()
+}
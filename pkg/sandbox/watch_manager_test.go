@@ -0,0 +1,100 @@
+package sandbox
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newWatchSandbox(t *testing.T) *LocalSandbox {
+	t.Helper()
+	sb, err := NewLocalSandbox(&LocalSandboxConfig{WorkDir: t.TempDir(), WatchFiles: true})
+	if err != nil {
+		t.Fatalf("failed to create sandbox: %v", err)
+	}
+	t.Cleanup(func() { _ = sb.Dispose() })
+	return sb
+}
+
+func TestWatchManager_WaitAndPatterns(t *testing.T) {
+	sb := newWatchSandbox(t)
+	if err := os.MkdirAll(filepath.Join(sb.WorkDir(), "pkg", "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	m := NewWatchManager(sb)
+	info, err := m.Start(WatchOptions{Paths: []string{"."}, Patterns: []string{"*.go"}})
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		_ = os.WriteFile(filepath.Join(sb.WorkDir(), "notes.txt"), []byte("x"), 0o644)
+		_ = os.WriteFile(filepath.Join(sb.WorkDir(), "pkg", "sub", "a.go"), []byte("package sub"), 0o644)
+	}()
+
+	changes, err := m.Wait(context.Background(), info.ID, 5*time.Second)
+	if err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+	if len(changes) != 1 || changes[0].Path != "pkg/sub/a.go" || changes[0].Op != FileOpCreate {
+		t.Fatalf("changes = %+v, want one create of pkg/sub/a.go", changes)
+	}
+
+	// 超时返回空列表
+	changes, err = m.Wait(context.Background(), info.ID, 100*time.Millisecond)
+	if err != nil || len(changes) != 0 {
+		t.Errorf("Wait() after drain = %+v, %v", changes, err)
+	}
+
+	if err := m.Stop(info.ID); err != nil {
+		t.Errorf("Stop() error = %v", err)
+	}
+	if _, err := m.Drain(info.ID); err == nil {
+		t.Error("Drain() on stopped watch should fail")
+	}
+}
+
+func TestWatchManager_NotificationsAndIgnore(t *testing.T) {
+	sb := newWatchSandbox(t)
+	m := NewWatchManager(sb)
+	if _, err := m.Start(WatchOptions{Paths: []string{"."}, Notify: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	m.Ignore("own.txt")
+	_ = os.WriteFile(filepath.Join(sb.WorkDir(), "own.txt"), []byte("agent"), 0o644)
+	_ = os.WriteFile(filepath.Join(sb.WorkDir(), "external.txt"), []byte("user"), 0o644)
+
+	deadline := time.Now().Add(5 * time.Second)
+	var changes []FileChange
+	for time.Now().Before(deadline) {
+		changes = append(changes, m.DrainNotifications()...)
+		if len(changes) > 0 {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	time.Sleep(100 * time.Millisecond)
+	changes = coalesceChanges(append(changes, m.DrainNotifications()...))
+
+	if len(changes) != 1 || changes[0].Path != "external.txt" {
+		t.Fatalf("notifications = %+v, want only external.txt", changes)
+	}
+	if text := FormatFileChanges(changes); text == "" {
+		t.Error("FormatFileChanges() returned empty text")
+	}
+}
+
+func TestWatchManager_Disabled(t *testing.T) {
+	sb, err := NewLocalSandbox(&LocalSandboxConfig{WorkDir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewWatchManager(sb).Start(WatchOptions{Paths: []string{"."}}); err != ErrWatchDisabled {
+		t.Errorf("Start() error = %v, want ErrWatchDisabled", err)
+	}
+}
@@ -0,0 +1,200 @@
+package sdk
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+
+	"github.com/astercloud/aster/pkg/agent"
+	"github.com/astercloud/aster/pkg/provider"
+	"github.com/astercloud/aster/pkg/tools"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+// Registry 宿主侧的扩展注册表，实现 Registrar
+type Registry struct {
+	mu              sync.RWMutex
+	plugins         []PluginInfo
+	tools           []Tool
+	promptModules   []PromptModule
+	providers       map[string]ProviderConstructor
+	sessionBackends map[string]SessionBackendConstructor
+}
+
+var _ Registrar = (*Registry)(nil)
+
+// NewRegistry 创建扩展注册表
+func NewRegistry() *Registry {
+	return &Registry{
+		providers:       make(map[string]ProviderConstructor),
+		sessionBackends: make(map[string]SessionBackendConstructor),
+	}
+}
+
+// Load 与插件完成版本握手并注册其扩展
+func (r *Registry) Load(plugins ...Plugin) error {
+	for _, p := range plugins {
+		info := p.Info()
+		if info.Name == "" {
+			return fmt.Errorf("plugin name is required")
+		}
+		if err := CheckCompatibility(info.APIVersion); err != nil {
+			return fmt.Errorf("plugin %s: %w", info.Name, err)
+		}
+		if err := p.Register(r); err != nil {
+			return fmt.Errorf("plugin %s: register: %w", info.Name, err)
+		}
+
+		r.mu.Lock()
+		r.plugins = append(r.plugins, info)
+		r.mu.Unlock()
+	}
+	return nil
+}
+
+// Plugins 返回已加载的插件
+func (r *Registry) Plugins() []PluginInfo {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return slices.Clone(r.plugins)
+}
+
+// RegisterTool 注册工具
+func (r *Registry) RegisterTool(tool Tool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tools = append(r.tools, tool)
+}
+
+// RegisterPromptModule 注册 Prompt 模块
+func (r *Registry) RegisterPromptModule(module PromptModule) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.promptModules = append(r.promptModules, module)
+}
+
+// RegisterProvider 注册模型提供商，name 对应 ModelConfig.Provider
+func (r *Registry) RegisterProvider(name string, constructor ProviderConstructor) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.providers[name] = constructor
+}
+
+// RegisterSessionBackend 注册会话存储后端
+func (r *Registry) RegisterSessionBackend(name string, constructor SessionBackendConstructor) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sessionBackends[name] = constructor
+}
+
+// ToolNames 返回已注册的工具名
+func (r *Registry) ToolNames() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.tools))
+	for _, t := range r.tools {
+		names = append(names, t.Name())
+	}
+	return names
+}
+
+// SessionBackends 返回已注册的会话存储后端名称
+func (r *Registry) SessionBackends() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return slices.Sorted(maps.Keys(r.sessionBackends))
+}
+
+// NewSessionBackend 使用指定名称的后端创建会话存储
+func (r *Registry) NewSessionBackend(ctx context.Context, name string, config map[string]any) (SessionBackend, error) {
+	r.mu.RLock()
+	constructor, ok := r.sessionBackends[name]
+	r.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown session backend: %s", name)
+	}
+	return constructor(ctx, config)
+}
+
+// Apply 将已注册的扩展注入 Agent 依赖
+//   - 工具注册到 ToolRegistry，不能覆盖已有工具
+//   - 提供商包装 ProviderFactory，按 ModelConfig.Provider 优先匹配插件
+//   - Prompt 模块追加到 PromptModules
+func (r *Registry) Apply(deps *agent.Dependencies) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if len(r.tools) > 0 && deps.ToolRegistry == nil {
+		deps.ToolRegistry = tools.NewRegistry()
+	}
+	for _, t := range r.tools {
+		if deps.ToolRegistry.Has(t.Name()) {
+			return fmt.Errorf("tool %q conflicts with a registered tool", t.Name())
+		}
+		deps.ToolRegistry.Register(t.Name(), func(map[string]any) (tools.Tool, error) {
+			return t, nil
+		})
+	}
+
+	if len(r.providers) > 0 {
+		deps.ProviderFactory = &providerFactory{providers: maps.Clone(r.providers), fallback: deps.ProviderFactory}
+	}
+
+	for _, m := range r.promptModules {
+		deps.PromptModules = append(deps.PromptModules, &promptModule{module: m})
+	}
+	return nil
+}
+
+// providerFactory 优先使用插件注册的提供商，其余交给原工厂
+type providerFactory struct {
+	providers map[string]ProviderConstructor
+	fallback  provider.Factory
+}
+
+func (f *providerFactory) Create(config *types.ModelConfig) (provider.Provider, error) {
+	if constructor, ok := f.providers[config.Provider]; ok {
+		return constructor(config)
+	}
+	if f.fallback == nil {
+		return nil, fmt.Errorf("unknown provider: %s", config.Provider)
+	}
+	return f.fallback.Create(config)
+}
+
+// promptModule 将 sdk.PromptModule 适配为 agent.PromptModule
+type promptModule struct {
+	module PromptModule
+}
+
+func (m *promptModule) Name() string {
+	return m.module.Name()
+}
+
+func (m *promptModule) Priority() int {
+	return m.module.Priority()
+}
+
+func (m *promptModule) Condition(*agent.PromptContext) bool {
+	return true
+}
+
+func (m *promptModule) Build(ctx *agent.PromptContext) (string, error) {
+	info := PromptInfo{
+		Metadata:  ctx.Metadata,
+		ToolNames: slices.Sorted(maps.Keys(ctx.Tools)),
+	}
+	if ctx.Agent != nil {
+		info.AgentID = ctx.Agent.ID()
+	}
+	if ctx.Template != nil {
+		info.TemplateID = ctx.Template.ID
+	}
+	if ctx.Environment != nil {
+		info.WorkDir = ctx.Environment.WorkingDir
+		info.Platform = ctx.Environment.Platform
+	}
+	return m.module.Build(context.Background(), info)
+}
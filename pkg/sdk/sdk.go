@@ -0,0 +1,95 @@
+// Package sdk 第三方扩展 Aster 的稳定接口
+//
+// 外部 Go 模块只需依赖本包即可提供工具、Prompt 模块、模型提供商和会话存储后端，
+// 无需直接引用 agent 等内部包。扩展以 Plugin 的形式交付：
+//
+//	type myPlugin struct{}
+//
+//	func (myPlugin) Info() sdk.PluginInfo {
+//		return sdk.PluginInfo{Name: "my-plugin", Version: "0.1.0", APIVersion: sdk.APIVersion}
+//	}
+//
+//	func (myPlugin) Register(r sdk.Registrar) error {
+//		r.RegisterTool(myTool{})
+//		return nil
+//	}
+//
+// 宿主通过 Registry.Load 完成版本握手和注册，再用 Registry.Apply 注入 Agent 依赖。
+package sdk
+
+import (
+	"context"
+
+	"github.com/astercloud/aster/pkg/provider"
+	"github.com/astercloud/aster/pkg/session"
+	"github.com/astercloud/aster/pkg/tools"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+// Tool 工具接口，与 Agent 使用的工具接口一致
+type Tool = tools.Tool
+
+// ToolContext 工具执行上下文
+type ToolContext = tools.ToolContext
+
+// ToolAnnotations 工具安全注解，工具可实现 Annotations() 供权限系统使用
+type ToolAnnotations = tools.ToolAnnotations
+
+// Provider 模型提供商接口
+type Provider = provider.Provider
+
+// ModelConfig 模型配置
+type ModelConfig = types.ModelConfig
+
+// ProviderConstructor 根据模型配置创建提供商
+type ProviderConstructor func(config *ModelConfig) (Provider, error)
+
+// SessionBackend 会话存储后端接口
+type SessionBackend = session.Service
+
+// SessionBackendConstructor 根据配置创建会话存储后端
+type SessionBackendConstructor func(ctx context.Context, config map[string]any) (SessionBackend, error)
+
+// PromptModule 向 System Prompt 注入内容的模块
+type PromptModule interface {
+	// Name 模块名，模板可通过 DisabledPromptModules 按名称禁用
+	Name() string
+	// Priority 注入顺序，数值越小越靠前（内置模块使用 0-100）
+	Priority() int
+	// Build 返回注入的内容，返回空字符串时跳过
+	Build(ctx context.Context, info PromptInfo) (string, error)
+}
+
+// PromptInfo 构建 Prompt 时可用的信息
+type PromptInfo struct {
+	AgentID    string
+	TemplateID string
+	WorkDir    string
+	Platform   string
+	ToolNames  []string
+	Metadata   map[string]any
+}
+
+// Registrar 插件注册扩展时使用的接口
+type Registrar interface {
+	RegisterTool(tool Tool)
+	RegisterPromptModule(module PromptModule)
+	RegisterProvider(name string, constructor ProviderConstructor)
+	RegisterSessionBackend(name string, constructor SessionBackendConstructor)
+}
+
+// Plugin 第三方扩展
+type Plugin interface {
+	// Info 插件信息，APIVersion 用于与宿主做兼容性握手
+	Info() PluginInfo
+	// Register 注册插件提供的扩展
+	Register(r Registrar) error
+}
+
+// PluginInfo 插件信息
+type PluginInfo struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+	// APIVersion 插件编译时使用的 sdk.APIVersion
+	APIVersion string `json:"api_version"`
+}
@@ -0,0 +1,162 @@
+package sdk
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/astercloud/aster/pkg/agent"
+	"github.com/astercloud/aster/pkg/provider"
+	"github.com/astercloud/aster/pkg/sandbox"
+	"github.com/astercloud/aster/pkg/store"
+	"github.com/astercloud/aster/pkg/tools"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+func TestCheckCompatibility(t *testing.T) {
+	tests := []struct {
+		version string
+		wantErr bool
+	}{
+		{"1.0", false},
+		{"v1.0.3", false},
+		{"1.9", true},
+		{"2.0", true},
+		{"0.9", true},
+		{"", true},
+		{"one.two", true},
+	}
+	for _, tt := range tests {
+		if err := CheckCompatibility(tt.version); (err != nil) != tt.wantErr {
+			t.Errorf("CheckCompatibility(%q) error = %v, wantErr %v", tt.version, err, tt.wantErr)
+		}
+	}
+}
+
+// testPlugin 注册一个工具、一个 Prompt 模块和一个提供商
+type testPlugin struct {
+	apiVersion string
+}
+
+func (p testPlugin) Info() PluginInfo {
+	return PluginInfo{Name: "test-plugin", Version: "0.1.0", APIVersion: p.apiVersion}
+}
+
+func (p testPlugin) Register(r Registrar) error {
+	r.RegisterTool(echoTool{})
+	r.RegisterPromptModule(teamModule{})
+	r.RegisterProvider("fake", func(config *ModelConfig) (Provider, error) {
+		return &fakeProvider{config: config}, nil
+	})
+	r.RegisterSessionBackend("broken", func(context.Context, map[string]any) (SessionBackend, error) {
+		return nil, errors.New("not configured")
+	})
+	return nil
+}
+
+type echoTool struct{}
+
+func (echoTool) Name() string                { return "Echo" }
+func (echoTool) Description() string         { return "Echo the input" }
+func (echoTool) InputSchema() map[string]any { return map[string]any{"type": "object"} }
+func (echoTool) Prompt() string              { return "" }
+func (echoTool) Execute(_ context.Context, input map[string]any, _ *ToolContext) (any, error) {
+	return input, nil
+}
+
+type teamModule struct{}
+
+func (teamModule) Name() string  { return "team_conventions" }
+func (teamModule) Priority() int { return 58 }
+func (teamModule) Build(_ context.Context, info PromptInfo) (string, error) {
+	return "Team conventions apply in " + info.TemplateID + " (tools: " + strings.Join(info.ToolNames, ",") + ")", nil
+}
+
+type fakeProvider struct {
+	config *types.ModelConfig
+	system string
+}
+
+func (p *fakeProvider) Stream(context.Context, []types.Message, *provider.StreamOptions) (<-chan provider.StreamChunk, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (p *fakeProvider) Complete(context.Context, []types.Message, *provider.StreamOptions) (*provider.CompleteResponse, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (p *fakeProvider) Config() *types.ModelConfig { return p.config }
+func (p *fakeProvider) Capabilities() provider.ProviderCapabilities {
+	return provider.ProviderCapabilities{}
+}
+func (p *fakeProvider) SetSystemPrompt(prompt string) error { p.system = prompt; return nil }
+func (p *fakeProvider) GetSystemPrompt() string             { return p.system }
+func (p *fakeProvider) Close() error                        { return nil }
+
+func TestRegistryLoad(t *testing.T) {
+	r := NewRegistry()
+	if err := r.Load(testPlugin{apiVersion: "2.0"}); err == nil {
+		t.Fatal("Load() should reject an incompatible API version")
+	}
+	if len(r.ToolNames()) != 0 || len(r.Plugins()) != 0 {
+		t.Error("incompatible plugin should not register anything")
+	}
+
+	if err := r.Load(testPlugin{apiVersion: APIVersion}); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if plugins := r.Plugins(); len(plugins) != 1 || plugins[0].Name != "test-plugin" {
+		t.Errorf("Plugins() = %v", plugins)
+	}
+	if names := r.SessionBackends(); len(names) != 1 || names[0] != "broken" {
+		t.Errorf("SessionBackends() = %v", names)
+	}
+	if _, err := r.NewSessionBackend(context.Background(), "missing", nil); err == nil {
+		t.Error("NewSessionBackend() should fail for unknown backends")
+	}
+}
+
+func TestRegistryApply(t *testing.T) {
+	r := NewRegistry()
+	if err := r.Load(testPlugin{apiVersion: APIVersion}); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	jsonStore, err := store.NewJSONStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	templates := agent.NewTemplateRegistry()
+	templates.Register(&types.AgentTemplateDefinition{ID: "plugin-template", SystemPrompt: "You are a test assistant."})
+	deps := &agent.Dependencies{
+		Store:            jsonStore,
+		SandboxFactory:   sandbox.NewFactory(),
+		ToolRegistry:     tools.NewRegistry(),
+		ProviderFactory:  &provider.AnthropicFactory{},
+		TemplateRegistry: templates,
+	}
+	if err := r.Apply(deps); err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+
+	ag, err := agent.Create(context.Background(), &types.AgentConfig{
+		TemplateID:  "plugin-template",
+		ModelConfig: &types.ModelConfig{Provider: "fake", Model: "fake-1"},
+		Sandbox:     &types.SandboxConfig{Kind: types.SandboxKindMock, WorkDir: "/tmp/test"},
+		Tools:       []string{"Echo"},
+	}, deps)
+	if err != nil {
+		t.Fatalf("agent.Create() error = %v", err)
+	}
+	defer func() { _ = ag.Close() }()
+
+	if prompt := ag.GetSystemPrompt(); !strings.Contains(prompt, "Team conventions apply in plugin-template (tools: Echo)") {
+		t.Errorf("system prompt missing plugin module:\n%s", prompt)
+	}
+
+	// 工具名冲突时拒绝覆盖
+	if err := r.Apply(deps); err == nil {
+		t.Error("Apply() should reject tools that are already registered")
+	}
+}
@@ -0,0 +1,46 @@
+package sdk
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// APIVersion 当前 SDK 接口版本（主版本.次版本）
+// 主版本变化表示不兼容的接口修改；次版本只增加新能力
+const APIVersion = "1.0"
+
+// CheckCompatibility 检查插件声明的 API 版本能否在当前宿主上运行
+// 主版本必须一致，且插件的次版本不能高于宿主
+func CheckCompatibility(pluginAPIVersion string) error {
+	hostMajor, hostMinor, err := parseAPIVersion(APIVersion)
+	if err != nil {
+		return err
+	}
+	major, minor, err := parseAPIVersion(pluginAPIVersion)
+	if err != nil {
+		return err
+	}
+	if major != hostMajor {
+		return fmt.Errorf("plugin API version %s is incompatible with host API version %s", pluginAPIVersion, APIVersion)
+	}
+	if minor > hostMinor {
+		return fmt.Errorf("plugin requires API version %s, host supports up to %s", pluginAPIVersion, APIVersion)
+	}
+	return nil
+}
+
+// parseAPIVersion 解析 "主版本.次版本"，允许 "v" 前缀和补丁号
+func parseAPIVersion(v string) (major, minor int, err error) {
+	parts := strings.Split(strings.TrimPrefix(strings.TrimSpace(v), "v"), ".")
+	if len(parts) < 2 {
+		return 0, 0, fmt.Errorf("invalid API version: %q", v)
+	}
+	if major, err = strconv.Atoi(parts[0]); err != nil {
+		return 0, 0, fmt.Errorf("invalid API version: %q", v)
+	}
+	if minor, err = strconv.Atoi(parts[1]); err != nil {
+		return 0, 0, fmt.Errorf("invalid API version: %q", v)
+	}
+	return major, minor, nil
+}
@@ -0,0 +1,39 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/astercloud/aster/pkg/agent"
+	"github.com/astercloud/aster/pkg/types"
+	"github.com/astercloud/aster/pkg/uiproto/agui"
+)
+
+// AGUIHandler 返回一个 AG-UI 兼容的 HTTP handler。
+//
+// 路径示例:
+//
+//	POST /v1/agents/agui
+//
+// 请求体为 AG-UI RunAgentInput,响应为 AG-UI 事件流(text/event-stream)。
+// 每次运行使用 templateID 创建一个新的 Agent,并发送最后一条用户消息,
+// 因此任何 AG-UI 兼容前端都可以直接接入,无需定制客户端。
+func (s *Server) AGUIHandler(templateID string) http.Handler {
+	handler := agui.NewHandler(func(ctx context.Context, input *agui.RunAgentInput) (agui.Agent, func(), error) {
+		ag, err := agent.Create(ctx, &types.AgentConfig{
+			TemplateID: templateID,
+			Metadata:   map[string]any{"agui_thread_id": input.ThreadID},
+		}, s.deps)
+		if err != nil {
+			return nil, nil, err
+		}
+		return ag, func() { _ = ag.Close() }, nil
+	})
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Minute)
+		defer cancel()
+		handler.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -0,0 +1,73 @@
+package session
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+// ErrAttachmentNotFound 附件不存在，或不被该会话的任何事件引用
+var ErrAttachmentNotFound = errors.New("attachment not found")
+
+// Attachment 事件附带的二进制附件（图片、文件等）
+// 后端按内容哈希保存数据，相同内容只存一份，事件中只保存引用
+type Attachment struct {
+	// Hash 内容的 SHA-256（十六进制），追加事件时根据 Data 计算
+	Hash string `json:"hash"`
+
+	Name     string `json:"name,omitempty"`
+	MimeType string `json:"mime_type,omitempty"`
+	Size     int64  `json:"size"`
+
+	// Data 附件内容，仅在追加事件时提供；读取事件时为空，通过 Service.GetAttachment 按需加载
+	// 为空时 Hash 必须引用该会话已保存的附件
+	Data []byte `json:"-"`
+}
+
+// HashAttachment 返回附件内容的哈希
+func HashAttachment(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// PrepareAttachments 填充事件附件的 Hash 和 Size，返回需要保存的内容（按哈希去重）
+// 供后端在 AppendEvent 中使用
+func PrepareAttachments(events ...*Event) (map[string][]byte, error) {
+	var blobs map[string][]byte
+	for _, event := range events {
+		for i := range event.Attachments {
+			att := &event.Attachments[i]
+			if att.Data == nil {
+				if att.Hash == "" {
+					return nil, fmt.Errorf("attachment %q has neither data nor hash", att.Name)
+				}
+				continue
+			}
+			hash := HashAttachment(att.Data)
+			if att.Hash != "" && att.Hash != hash {
+				return nil, fmt.Errorf("attachment %q: hash mismatch", att.Name)
+			}
+			att.Hash = hash
+			att.Size = int64(len(att.Data))
+			if blobs == nil {
+				blobs = make(map[string][]byte)
+			}
+			blobs[hash] = att.Data
+		}
+	}
+	return blobs, nil
+}
+
+// AttachmentRefs 返回不含内容的附件引用，后端保存事件时使用
+func AttachmentRefs(atts []Attachment) []Attachment {
+	if len(atts) == 0 {
+		return nil
+	}
+	refs := make([]Attachment, len(atts))
+	for i, att := range atts {
+		att.Data = nil
+		refs[i] = att
+	}
+	return refs
+}
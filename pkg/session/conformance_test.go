@@ -0,0 +1,14 @@
+package session_test
+
+import (
+	"testing"
+
+	"github.com/astercloud/aster/pkg/session"
+	"github.com/astercloud/aster/pkg/session/sessiontest"
+)
+
+func TestInMemoryService_Conformance(t *testing.T) {
+	sessiontest.Run(t, func(t *testing.T) session.Service {
+		return session.NewInMemoryService()
+	})
+}
@@ -0,0 +1,59 @@
+package session
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Export 会话导出格式，包含全部事件和事件引用的附件内容（按哈希去重），可直接序列化为 JSON
+type Export struct {
+	SessionID      string         `json:"session_id"`
+	AppName        string         `json:"app_name"`
+	UserID         string         `json:"user_id"`
+	AgentID        string         `json:"agent_id"`
+	Metadata       map[string]any `json:"metadata,omitempty"`
+	LastUpdateTime time.Time      `json:"last_update_time"`
+	Events         []Event        `json:"events"`
+
+	// Attachments 附件哈希 -> 内容
+	Attachments map[string][]byte `json:"attachments,omitempty"`
+}
+
+// ExportSession 导出会话及其附件
+func ExportSession(ctx context.Context, svc Service, req *GetRequest) (*Export, error) {
+	sess, err := svc.Get(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	events, err := svc.GetEvents(ctx, sess.ID(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("get events: %w", err)
+	}
+
+	export := &Export{
+		SessionID:      sess.ID(),
+		AppName:        sess.AppName(),
+		UserID:         sess.UserID(),
+		AgentID:        sess.AgentID(),
+		Metadata:       sess.Metadata(),
+		LastUpdateTime: sess.LastUpdateTime(),
+		Events:         events,
+	}
+	for _, evt := range events {
+		for _, att := range evt.Attachments {
+			if _, ok := export.Attachments[att.Hash]; ok {
+				continue
+			}
+			data, err := svc.GetAttachment(ctx, sess.ID(), att.Hash)
+			if err != nil {
+				return nil, fmt.Errorf("get attachment %s: %w", att.Hash, err)
+			}
+			if export.Attachments == nil {
+				export.Attachments = make(map[string][]byte)
+			}
+			export.Attachments[att.Hash] = data
+		}
+	}
+	return export, nil
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"iter"
 	"maps"
+	"slices"
 	"sync"
 	"time"
 
@@ -16,12 +17,23 @@ import (
 type InMemoryService struct {
 	mu       sync.RWMutex
 	sessions map[string]*inMemorySession
+	watchers EventWatchers
+
+	// attachments 按哈希保存的附件内容，相同内容只存一份
+	attachments map[string]*inMemoryAttachment
+}
+
+// inMemoryAttachment 附件内容及引用它的会话数
+type inMemoryAttachment struct {
+	data []byte
+	refs int
 }
 
 // NewInMemoryService 创建内存 Session 服务
 func NewInMemoryService() *InMemoryService {
 	return &InMemoryService{
-		sessions: make(map[string]*inMemorySession),
+		sessions:    make(map[string]*inMemorySession),
+		attachments: make(map[string]*inMemoryAttachment),
 	}
 }
 
@@ -38,6 +50,7 @@ func (s *InMemoryService) Create(ctx context.Context, req *CreateRequest) (Sessi
 		agentID:        req.AgentID,
 		state:          newInMemoryState(),
 		events:         newInMemoryEvents(),
+		attachments:    make(map[string]struct{}),
 		metadata:       req.Metadata,
 		lastUpdateTime: time.Now(),
 	}
@@ -68,6 +81,18 @@ func (s *InMemoryService) Get(ctx context.Context, req *GetRequest) (Session, er
 	return session, nil
 }
 
+// AppNameOf 返回会话的 AppName，实现 Locator
+func (s *InMemoryService) AppNameOf(ctx context.Context, sessionID string) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	session, ok := s.sessions[sessionID]
+	if !ok {
+		return "", ErrSessionNotFound
+	}
+	return session.appName, nil
+}
+
 // Update 更新会话
 func (s *InMemoryService) Update(ctx context.Context, req *UpdateRequest) error {
 	s.mu.Lock()
@@ -91,7 +116,17 @@ func (s *InMemoryService) Delete(ctx context.Context, sessionID string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	if session, ok := s.sessions[sessionID]; ok {
+		for hash := range session.attachments {
+			if att := s.attachments[hash]; att != nil {
+				if att.refs--; att.refs <= 0 {
+					delete(s.attachments, hash)
+				}
+			}
+		}
+	}
 	delete(s.sessions, sessionID)
+	s.watchers.Close(sessionID)
 	return nil
 }
 
@@ -100,28 +135,31 @@ func (s *InMemoryService) List(ctx context.Context, req *ListRequest) ([]*Sessio
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	var results []*Session
-	count := 0
-
+	var matched []*inMemorySession
 	for _, session := range s.sessions {
-		if session.appName != req.AppName || session.userID != req.UserID {
-			continue
+		if session.appName == req.AppName && session.userID == req.UserID {
+			matched = append(matched, session)
 		}
+	}
 
-		if count < req.Offset {
-			count++
-			continue
-		}
+	// 按最近更新时间倒序
+	slices.SortFunc(matched, func(a, b *inMemorySession) int {
+		return b.lastUpdateTime.Compare(a.lastUpdateTime)
+	})
 
-		if req.Limit > 0 && len(results) >= req.Limit {
-			break
-		}
+	if req.Offset >= len(matched) {
+		return nil, nil
+	}
+	matched = matched[req.Offset:]
+	if req.Limit > 0 && req.Limit < len(matched) {
+		matched = matched[:req.Limit]
+	}
 
+	results := make([]*Session, len(matched))
+	for i, session := range matched {
 		var s Session = session
-		results = append(results, &s)
-		count++
+		results[i] = &s
 	}
-
 	return results, nil
 }
 
@@ -135,11 +173,77 @@ func (s *InMemoryService) AppendEvent(ctx context.Context, sessionID string, eve
 		return ErrSessionNotFound
 	}
 
+	blobs, err := PrepareAttachments(event)
+	if err != nil {
+		return err
+	}
+	for _, att := range event.Attachments {
+		if _, ok := blobs[att.Hash]; !ok {
+			if _, ok := session.attachments[att.Hash]; !ok {
+				return fmt.Errorf("%w: %s", ErrAttachmentNotFound, att.Hash)
+			}
+		}
+	}
+
+	now := time.Now()
+	if event.ID == "" {
+		event.ID = generateEventID()
+	}
+	if event.Timestamp.IsZero() {
+		event.Timestamp = now
+	}
+
+	if len(event.Attachments) > 0 {
+		for hash, data := range blobs {
+			if _, ok := session.attachments[hash]; ok {
+				continue
+			}
+			session.attachments[hash] = struct{}{}
+			if att := s.attachments[hash]; att != nil {
+				att.refs++
+			} else {
+				s.attachments[hash] = &inMemoryAttachment{data: slices.Clone(data), refs: 1}
+			}
+		}
+		// 只保存引用，内容由 GetAttachment 读取
+		stored := *event
+		stored.Attachments = AttachmentRefs(event.Attachments)
+		event = &stored
+	}
+
 	session.events.append(event)
-	session.lastUpdateTime = time.Now()
+	session.lastUpdateTime = now
+	s.watchers.Notify(sessionID)
 	return nil
 }
 
+// Watch 订阅会话新追加的事件
+func (s *InMemoryService) Watch(ctx context.Context, sessionID string) (<-chan Event, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	session, ok := s.sessions[sessionID]
+	if !ok {
+		return nil, ErrSessionNotFound
+	}
+	return s.watchers.Follow(ctx, sessionID, session.events, 0), nil
+}
+
+// GetAttachment 读取会话事件引用的附件内容
+func (s *InMemoryService) GetAttachment(ctx context.Context, sessionID, hash string) ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	session, ok := s.sessions[sessionID]
+	if !ok {
+		return nil, ErrSessionNotFound
+	}
+	if _, ok := session.attachments[hash]; !ok {
+		return nil, ErrAttachmentNotFound
+	}
+	return slices.Clone(s.attachments[hash].data), nil
+}
+
 // GetEvents 获取事件列表
 func (s *InMemoryService) GetEvents(ctx context.Context, sessionID string, filter *EventFilter) ([]Event, error) {
 	s.mu.RLock()
@@ -224,6 +328,7 @@ type inMemorySession struct {
 	agentID        string
 	state          *inMemoryState
 	events         *inMemoryEvents
+	attachments    map[string]struct{} // 事件引用的附件哈希
 	metadata       map[string]any
 	lastUpdateTime time.Time
 }
@@ -328,11 +433,13 @@ func (s *inMemoryState) Has(key string) bool {
 type inMemoryEvents struct {
 	mu     sync.RWMutex
 	events []*Event
+	index  map[string]int // 事件 ID -> 位置，用于游标分页
 }
 
 func newInMemoryEvents() *inMemoryEvents {
 	return &inMemoryEvents{
 		events: make([]*Event, 0),
+		index:  make(map[string]int),
 	}
 }
 
@@ -397,9 +504,40 @@ func (e *inMemoryEvents) append(event *Event) {
 	e.mu.Lock()
 	defer e.mu.Unlock()
 
+	// ID 重复时游标指向第一个事件
+	if _, ok := e.index[event.ID]; !ok {
+		e.index[event.ID] = len(e.events)
+	}
 	e.events = append(e.events, event)
 }
 
+func (e *inMemoryEvents) EventsPage(after string, limit int) (*EventPage, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	if limit <= 0 {
+		limit = DefaultEventsPageSize
+	}
+	start := 0
+	if after != "" {
+		i, ok := e.index[after]
+		if !ok {
+			return nil, ErrInvalidCursor
+		}
+		start = i + 1
+	}
+	end := min(start+limit, len(e.events))
+
+	page := &EventPage{Events: make([]Event, 0, end-start)}
+	for _, evt := range e.events[start:end] {
+		page.Events = append(page.Events, *evt)
+	}
+	if end < len(e.events) {
+		page.NextCursor = e.events[end-1].ID
+	}
+	return page, nil
+}
+
 func (e *inMemoryEvents) filter(predicate func(*Event) bool) []Event {
 	e.mu.RLock()
 	defer e.mu.RUnlock()
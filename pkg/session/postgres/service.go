@@ -200,6 +200,8 @@ func (s *Service) Delete(ctx context.Context, sessionID string) error {
 	if result.RowsAffected == 0 {
 		return session.ErrSessionNotFound
 	}
+	// 空事件 ID 通知 Watch 会话已删除
+	_ = notifyEvent(s.db.WithContext(ctx), sessionID, "")
 	return nil
 }
 
@@ -249,6 +251,11 @@ func (s *Service) AppendEvent(ctx context.Context, sessionID string, event *sess
 			return fmt.Errorf("create event: %w", err)
 		}
 
+		// 通知 Watch，随事务提交才会投递
+		if err := notifyEvent(tx, sessionID, eventModel.ID); err != nil {
+			return err
+		}
+
 		// 5. 应用状态变更（StateDelta）
 		if len(event.Actions.StateDelta) > 0 {
 			for key, value := range event.Actions.StateDelta {
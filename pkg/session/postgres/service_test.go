@@ -9,6 +9,7 @@ import (
 
 	"github.com/astercloud/aster/pkg/session"
 	"github.com/astercloud/aster/pkg/types"
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/testcontainers/testcontainers-go"
@@ -320,3 +321,55 @@ func TestPostgresService_Concurrency(t *testing.T) {
 	require.NoError(t, err)
 	assert.Len(t, events, numGoroutines*eventsPerGoroutine)
 }
+
+// TestPostgresService_Watch 测试通过 LISTEN/NOTIFY 订阅新事件
+func TestPostgresService_Watch(t *testing.T) {
+	service, cleanup := setupPostgresContainer(t)
+	defer cleanup()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, err := service.Watch(ctx, uuid.NewString())
+	assert.ErrorIs(t, err, session.ErrSessionNotFound)
+
+	sess, err := service.Create(ctx, &session.CreateRequest{
+		AppName: "test-app",
+		UserID:  "user-001",
+		AgentID: "agent-001",
+	})
+	require.NoError(t, err)
+
+	events, err := service.Watch(ctx, sess.ID)
+	require.NoError(t, err)
+
+	ids := []string{uuid.NewString(), uuid.NewString()}
+	for _, id := range ids {
+		require.NoError(t, service.AppendEvent(ctx, sess.ID, &session.Event{
+			ID:           id,
+			Timestamp:    time.Now(),
+			InvocationID: "inv-001",
+			AgentID:      "agent-001",
+			Branch:       "root",
+			Author:       "assistant",
+			Content:      types.Message{Role: types.RoleAssistant, Content: "hi"},
+		}))
+	}
+	for _, id := range ids {
+		select {
+		case evt := <-events:
+			assert.Equal(t, id, evt.ID)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for event %s", id)
+		}
+	}
+
+	// 删除会话后通道关闭
+	require.NoError(t, service.Delete(ctx, sess.ID))
+	select {
+	case _, ok := <-events:
+		assert.False(t, ok)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Watch channel not closed after Delete")
+	}
+}
@@ -0,0 +1,107 @@
+package postgres
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/stdlib"
+	"gorm.io/gorm"
+
+	"github.com/astercloud/aster/pkg/session"
+)
+
+// eventsChannel 追加事件时 NOTIFY 的频道，payload 为 "<session_id> <event_id>"
+const eventsChannel = "aster_session_events"
+
+// notifyEvent 发送事件通知，eventID 为空表示会话已删除
+func notifyEvent(db *gorm.DB, sessionID, eventID string) error {
+	if err := db.Exec("SELECT pg_notify(?, ?)", eventsChannel, sessionID+" "+eventID).Error; err != nil {
+		return fmt.Errorf("notify event: %w", err)
+	}
+	return nil
+}
+
+// Watch 订阅会话新追加的事件
+// 基于 LISTEN/NOTIFY，任何进程通过 AppendEvent 写入的事件都会在事务提交后送达
+// 每个订阅独占一个连接，结束时该连接被丢弃而不是放回连接池
+func (s *Service) Watch(ctx context.Context, sessionID string) (<-chan session.Event, error) {
+	var count int64
+	if err := s.db.WithContext(ctx).Model(&SessionModel{}).Where("id = ?", sessionID).Count(&count).Error; err != nil {
+		return nil, fmt.Errorf("get session: %w", err)
+	}
+	if count == 0 {
+		return nil, session.ErrSessionNotFound
+	}
+
+	sqlDB, err := s.db.DB()
+	if err != nil {
+		return nil, err
+	}
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get connection: %w", err)
+	}
+	// 丢弃处于 LISTEN 状态的连接
+	discard := func() {
+		_ = conn.Raw(func(any) error { return driver.ErrBadConn })
+		_ = conn.Close()
+	}
+	err = conn.Raw(func(dc any) error {
+		c, ok := dc.(*stdlib.Conn)
+		if !ok {
+			return errors.New("watch requires the pgx driver")
+		}
+		_, err := c.Conn().Exec(ctx, "LISTEN "+eventsChannel)
+		return err
+	})
+	if err != nil {
+		discard()
+		return nil, fmt.Errorf("listen: %w", err)
+	}
+
+	out := make(chan session.Event, 16)
+	go func() {
+		defer close(out)
+		defer discard()
+		for {
+			var n *pgconn.Notification
+			err := conn.Raw(func(dc any) error {
+				var err error
+				n, err = dc.(*stdlib.Conn).Conn().WaitForNotification(ctx)
+				return err
+			})
+			if err != nil {
+				return
+			}
+			id, eventID, _ := strings.Cut(n.Payload, " ")
+			if id != sessionID {
+				continue
+			}
+			if eventID == "" {
+				return // 会话已删除
+			}
+
+			var model EventModel
+			if err := s.db.WithContext(ctx).First(&model, "id = ?", eventID).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					continue // 已随会话删除
+				}
+				return
+			}
+			event, err := s.modelToEvent(&model)
+			if err != nil {
+				continue
+			}
+			select {
+			case out <- *event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, nil
+}
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/astercloud/aster/pkg/types"
+	"github.com/google/uuid"
 )
 
 // Session 表示用户与 Agent 之间的一系列交互
@@ -80,6 +81,22 @@ type Events interface {
 
 	// Last 返回最后一个事件
 	Last() *Event
+
+	// EventsPage 按追加顺序返回游标 after 之后的最多 limit 个事件
+	// after 为空时从第一个事件开始，limit <= 0 时使用 DefaultEventsPageSize
+	// 游标取上一页的 NextCursor，不存在的游标返回 ErrInvalidCursor
+	EventsPage(after string, limit int) (*EventPage, error)
+}
+
+// DefaultEventsPageSize EventsPage 的默认页大小
+const DefaultEventsPageSize = 100
+
+// EventPage 一页事件
+type EventPage struct {
+	Events []Event
+
+	// NextCursor 下一页的游标（本页最后一个事件的 ID），为空表示没有更多事件
+	NextCursor string
 }
 
 // Event 表示会话中的一个交互事件
@@ -101,6 +118,9 @@ type Event struct {
 	// 推理/思考内容 (Kimi thinking, DeepSeek reasoner 等)
 	Reasoning string
 
+	// 附件（图片、文件等），后端按内容哈希去重保存
+	Attachments []Attachment
+
 	// 动作
 	Actions EventActions
 
@@ -172,6 +192,7 @@ var (
 	ErrStateKeyNotExist = errors.New("state key does not exist")
 	ErrSessionNotFound  = errors.New("session not found")
 	ErrInvalidStateKey  = errors.New("invalid state key")
+	ErrInvalidCursor    = errors.New("invalid event cursor")
 )
 
 // Service 定义 Session 服务接口
@@ -199,6 +220,15 @@ type Service interface {
 
 	// UpdateState 更新状态
 	UpdateState(ctx context.Context, sessionID string, delta map[string]any) error
+
+	// Watch 订阅会话中新追加的事件（不包含调用前已有的事件），按追加顺序送达
+	// ctx 结束或会话被删除时关闭通道；会话不存在返回 ErrSessionNotFound
+	// 持久化后端也能收到其他进程写入的事件，用于在 UI 中跟随服务端运行的会话
+	Watch(ctx context.Context, sessionID string) (<-chan Event, error)
+
+	// GetAttachment 按哈希读取会话事件引用的附件内容
+	// 附件不被该会话引用时返回 ErrAttachmentNotFound
+	GetAttachment(ctx context.Context, sessionID, hash string) ([]byte, error)
 }
 
 // CreateRequest 创建会话请求
@@ -284,8 +314,7 @@ func NewEvent(invocationID string) *Event {
 
 // generateEventID 生成事件 ID
 func generateEventID() string {
-	// 实现 ID 生成逻辑
-	return "evt_" + time.Now().Format("20060102150405")
+	return "evt_" + uuid.NewString()
 }
 
 // IsAppKey 判断是否为应用级 key
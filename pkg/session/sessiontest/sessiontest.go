@@ -0,0 +1,779 @@
+// Package sessiontest 提供 session.Service 的一致性测试套件
+//
+// 任何会话后端（内置或通过 sdk.RegisterSessionBackend 注册的）都应通过 Run：
+//
+//	func TestConformance(t *testing.T) {
+//		sessiontest.Run(t, func(t *testing.T) session.Service {
+//			svc, err := mybackend.New(t.TempDir())
+//			if err != nil {
+//				t.Fatal(err)
+//			}
+//			t.Cleanup(func() { _ = svc.Close() })
+//			return svc
+//		})
+//	}
+//
+// 套件约定的语义：
+//   - 会话按 (AppName, UserID) 隔离，不匹配时 Get 返回 session.ErrSessionNotFound
+//   - List 按最近更新时间倒序，Limit/Offset 可单独使用，分页结果与完整列表一致
+//   - GetEvents 按追加顺序返回，与事件 Timestamp 无关；未设置的 ID、Timestamp 由后端填充
+//   - Events().EventsPage 按追加顺序游标分页，只有还有后续事件时才返回 NextCursor，未知游标返回 session.ErrInvalidCursor
+//   - EventFilter 各条件取交集，StartTime/EndTime 按事件 Timestamp 闭区间过滤，分页在过滤之后
+//   - 事件与状态以 JSON 兼容的值往返，数字可能以 float64 读回
+//   - 对不存在或已删除的会话，Update、AppendEvent、GetEvents、UpdateState 返回 session.ErrSessionNotFound，Delete 幂等
+//   - 并发追加不丢失事件，同一写入方的事件保持顺序
+//   - Watch 只送达调用之后追加的事件，按追加顺序，ctx 结束或会话删除后关闭通道
+//   - 附件按内容哈希保存，读取事件时只返回引用；GetAttachment 只能读取本会话引用的附件，删除会话不影响其他会话的相同内容
+package sessiontest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/astercloud/aster/pkg/session"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+// NewServiceFunc 为每个子测试创建一个空的后端实例，清理工作通过 t.Cleanup 注册
+type NewServiceFunc func(t *testing.T) session.Service
+
+// Run 对后端运行全部一致性测试
+func Run(t *testing.T, newService NewServiceFunc) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, svc session.Service)
+	}{
+		{"Lifecycle", testLifecycle},
+		{"MissingSession", testMissingSession},
+		{"ListOrderAndPagination", testList},
+		{"EventOrdering", testEventOrdering},
+		{"EventsPage", testEventsPage},
+		{"EventRoundTrip", testEventRoundTrip},
+		{"EventFilterProperties", testEventFilterProperties},
+		{"StateRoundTrip", testState},
+		{"ConcurrentAppends", testConcurrentAppends},
+		{"Watch", testWatch},
+		{"Attachments", testAttachments},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, newService(t))
+		})
+	}
+}
+
+const (
+	testApp  = "conformance-app"
+	testUser = "conformance-user"
+)
+
+func create(t *testing.T, svc session.Service, app, user string, metadata map[string]any) session.Session {
+	t.Helper()
+	sess, err := svc.Create(context.Background(), &session.CreateRequest{AppName: app, UserID: user, AgentID: "agent-1", Metadata: metadata})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	return sess
+}
+
+func get(t *testing.T, svc session.Service, id string) session.Session {
+	t.Helper()
+	sess, err := svc.Get(context.Background(), &session.GetRequest{AppName: testApp, UserID: testUser, SessionID: id})
+	if err != nil {
+		t.Fatalf("Get(%s) error = %v", id, err)
+	}
+	return sess
+}
+
+func appendEvent(t *testing.T, svc session.Service, sessionID string, event *session.Event) {
+	t.Helper()
+	if err := svc.AppendEvent(context.Background(), sessionID, event); err != nil {
+		t.Fatalf("AppendEvent() error = %v", err)
+	}
+}
+
+func getEvents(t *testing.T, svc session.Service, sessionID string, filter *session.EventFilter) []session.Event {
+	t.Helper()
+	events, err := svc.GetEvents(context.Background(), sessionID, filter)
+	if err != nil {
+		t.Fatalf("GetEvents(%+v) error = %v", filter, err)
+	}
+	return events
+}
+
+// normalize 按 JSON 往返规范化值，使 int 与 float64、nil 与缺省等价比较
+func normalize(t *testing.T, v any) any {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("marshal %T: %v", v, err)
+	}
+	var out any
+	if err := json.Unmarshal(data, &out); err != nil {
+		t.Fatalf("unmarshal %T: %v", v, err)
+	}
+	return out
+}
+
+func eventIDs(events []session.Event) []string {
+	ids := make([]string, len(events))
+	for i, e := range events {
+		ids[i] = e.ID
+	}
+	return ids
+}
+
+func testLifecycle(t *testing.T, svc session.Service) {
+	ctx := context.Background()
+	a := create(t, svc, testApp, testUser, map[string]any{"title": "first", "tags": []any{"x"}})
+	b := create(t, svc, testApp, testUser, nil)
+	if a.ID() == "" || a.ID() == b.ID() {
+		t.Fatalf("Create() IDs = %q, %q, want unique non-empty", a.ID(), b.ID())
+	}
+	if a.AppName() != testApp || a.UserID() != testUser || a.AgentID() != "agent-1" {
+		t.Errorf("Create() = %s/%s/%s", a.AppName(), a.UserID(), a.AgentID())
+	}
+
+	got := get(t, svc, a.ID())
+	if got.ID() != a.ID() || got.AgentID() != "agent-1" {
+		t.Errorf("Get() = %s agent %s", got.ID(), got.AgentID())
+	}
+	if m := normalize(t, got.Metadata()); !reflect.DeepEqual(m, map[string]any{"title": "first", "tags": []any{"x"}}) {
+		t.Errorf("Get() metadata = %v", m)
+	}
+
+	// 会话按 AppName/UserID 隔离
+	for _, req := range []*session.GetRequest{
+		{AppName: "other-app", UserID: testUser, SessionID: a.ID()},
+		{AppName: testApp, UserID: "other-user", SessionID: a.ID()},
+	} {
+		if _, err := svc.Get(ctx, req); !errors.Is(err, session.ErrSessionNotFound) {
+			t.Errorf("Get(%s/%s) error = %v, want ErrSessionNotFound", req.AppName, req.UserID, err)
+		}
+	}
+
+	// Update 合并元数据
+	if err := svc.Update(ctx, &session.UpdateRequest{SessionID: a.ID(), Metadata: map[string]any{"title": "renamed", "pinned": true}}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	want := map[string]any{"title": "renamed", "tags": []any{"x"}, "pinned": true}
+	if m := normalize(t, get(t, svc, a.ID()).Metadata()); !reflect.DeepEqual(m, want) {
+		t.Errorf("metadata after Update() = %v, want %v", m, want)
+	}
+
+	// 删除后不可见，重复删除不报错
+	if err := svc.Delete(ctx, a.ID()); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := svc.Get(ctx, &session.GetRequest{AppName: testApp, UserID: testUser, SessionID: a.ID()}); !errors.Is(err, session.ErrSessionNotFound) {
+		t.Errorf("Get() after Delete() error = %v", err)
+	}
+	if err := svc.Delete(ctx, a.ID()); err != nil {
+		t.Errorf("second Delete() error = %v", err)
+	}
+	get(t, svc, b.ID())
+}
+
+func testMissingSession(t *testing.T, svc session.Service) {
+	ctx := context.Background()
+	deleted := create(t, svc, testApp, testUser, nil)
+	appendEvent(t, svc, deleted.ID(), &session.Event{Author: "user"})
+	if err := svc.UpdateState(ctx, deleted.ID(), map[string]any{"k": "v"}); err != nil {
+		t.Fatalf("UpdateState() error = %v", err)
+	}
+	if err := svc.Delete(ctx, deleted.ID()); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+
+	for _, id := range []string{"missing-session", deleted.ID()} {
+		if err := svc.Update(ctx, &session.UpdateRequest{SessionID: id, Metadata: map[string]any{"k": "v"}}); !errors.Is(err, session.ErrSessionNotFound) {
+			t.Errorf("Update(%s) error = %v", id, err)
+		}
+		if err := svc.AppendEvent(ctx, id, &session.Event{Author: "user"}); !errors.Is(err, session.ErrSessionNotFound) {
+			t.Errorf("AppendEvent(%s) error = %v", id, err)
+		}
+		if events, err := svc.GetEvents(ctx, id, nil); !errors.Is(err, session.ErrSessionNotFound) {
+			t.Errorf("GetEvents(%s) = %d events, error %v", id, len(events), err)
+		}
+		if err := svc.UpdateState(ctx, id, map[string]any{"k": "v"}); !errors.Is(err, session.ErrSessionNotFound) {
+			t.Errorf("UpdateState(%s) error = %v", id, err)
+		}
+	}
+
+	// 删除不影响其他会话
+	other := create(t, svc, testApp, testUser, nil)
+	if n := len(getEvents(t, svc, other.ID(), nil)); n != 0 {
+		t.Errorf("new session has %d events", n)
+	}
+}
+
+func testList(t *testing.T, svc session.Service) {
+	ctx := context.Background()
+	var ids []string
+	for range 5 {
+		ids = append(ids, create(t, svc, testApp, testUser, nil).ID())
+		time.Sleep(2 * time.Millisecond)
+	}
+	create(t, svc, "other-app", testUser, nil)
+	create(t, svc, testApp, "other-user", nil)
+
+	// 按 0..4 顺序逐个更新，最后更新的排在最前
+	for _, id := range ids {
+		time.Sleep(2 * time.Millisecond)
+		appendEvent(t, svc, id, &session.Event{Author: "user"})
+	}
+	want := slices.Clone(ids)
+	slices.Reverse(want)
+
+	list := func(limit, offset int) []string {
+		t.Helper()
+		sessions, err := svc.List(ctx, &session.ListRequest{AppName: testApp, UserID: testUser, Limit: limit, Offset: offset})
+		if err != nil {
+			t.Fatalf("List(limit=%d, offset=%d) error = %v", limit, offset, err)
+		}
+		result := make([]string, len(sessions))
+		for i, s := range sessions {
+			result[i] = (*s).ID()
+		}
+		return result
+	}
+	if got := list(0, 0); !slices.Equal(got, want) {
+		t.Fatalf("List() = %v, want %v (most recently updated first)", got, want)
+	}
+	var paged []string
+	for offset := 0; offset < len(want); offset += 2 {
+		paged = append(paged, list(2, offset)...)
+	}
+	if !slices.Equal(paged, want) {
+		t.Errorf("paged List() = %v, want %v", paged, want)
+	}
+	if got := list(0, 3); !slices.Equal(got, want[3:]) {
+		t.Errorf("List(offset=3) = %v, want %v", got, want[3:])
+	}
+	if got := list(0, 10); len(got) != 0 {
+		t.Errorf("List(offset=10) = %v, want empty", got)
+	}
+}
+
+func testEventOrdering(t *testing.T, svc session.Service) {
+	sess := create(t, svc, testApp, testUser, nil)
+	base := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	// Timestamp 乱序，返回顺序仍为追加顺序
+	offsets := []int{5, 1, 4, 2, 3}
+	var appended []string
+	for i, off := range offsets {
+		e := &session.Event{ID: fmt.Sprintf("ord-%d", i), Timestamp: base.Add(time.Duration(off) * time.Second), Author: "user"}
+		appendEvent(t, svc, sess.ID(), e)
+		appended = append(appended, e.ID)
+	}
+
+	// 未设置 ID、Timestamp 的事件由后端填充，NewEvent 连续创建的事件 ID 不冲突
+	before := time.Now().Add(-time.Second)
+	blank := &session.Event{Author: "system"}
+	appendEvent(t, svc, sess.ID(), blank)
+	e1, e2 := session.NewEvent("inv"), session.NewEvent("inv")
+	appendEvent(t, svc, sess.ID(), e1)
+	appendEvent(t, svc, sess.ID(), e2)
+
+	events := getEvents(t, svc, sess.ID(), nil)
+	if len(events) != 8 {
+		t.Fatalf("GetEvents() = %d events, want 8", len(events))
+	}
+	if got := eventIDs(events[:5]); !slices.Equal(got, appended) {
+		t.Errorf("GetEvents() order = %v, want append order %v", got, appended)
+	}
+	for i, off := range offsets {
+		if want := base.Add(time.Duration(off) * time.Second); !events[i].Timestamp.Equal(want) {
+			t.Errorf("event %d Timestamp = %v, want %v", i, events[i].Timestamp, want)
+		}
+	}
+	filled := events[5]
+	if filled.ID == "" || filled.Author != "system" || filled.Timestamp.Before(before) {
+		t.Errorf("defaulted event = ID %q author %q time %v", filled.ID, filled.Author, filled.Timestamp)
+	}
+	if events[6].ID == events[7].ID {
+		t.Errorf("NewEvent() IDs collide: %q", events[6].ID)
+	}
+
+	// Session.Events() 与 GetEvents 一致
+	view := get(t, svc, sess.ID()).Events()
+	if view.Len() != len(events) {
+		t.Errorf("Events().Len() = %d, want %d", view.Len(), len(events))
+	}
+	var all []string
+	for e := range view.All() {
+		all = append(all, e.ID)
+	}
+	if !slices.Equal(all, eventIDs(events)) {
+		t.Errorf("Events().All() = %v, want %v", all, eventIDs(events))
+	}
+	if e := view.At(2); e == nil || e.ID != events[2].ID {
+		t.Errorf("Events().At(2) = %v, want %s", e, events[2].ID)
+	}
+	if e := view.Last(); e == nil || e.ID != events[7].ID {
+		t.Errorf("Events().Last() = %v, want %s", e, events[7].ID)
+	}
+	if got := view.Filter(func(e *session.Event) bool { return e.Author == "system" }); len(got) != 1 || got[0].ID != filled.ID {
+		t.Errorf("Events().Filter(system) = %v", eventIDs(got))
+	}
+}
+
+func testEventsPage(t *testing.T, svc session.Service) {
+	sess := create(t, svc, testApp, testUser, nil)
+	view := get(t, svc, sess.ID()).Events()
+	if page, err := view.EventsPage("", 10); err != nil || len(page.Events) != 0 || page.NextCursor != "" {
+		t.Fatalf("EventsPage() on empty session = %+v, %v", page, err)
+	}
+
+	var want []string
+	for i := range 23 {
+		e := &session.Event{ID: fmt.Sprintf("page-%02d", i), Author: "user"}
+		appendEvent(t, svc, sess.ID(), e)
+		want = append(want, e.ID)
+	}
+
+	for _, limit := range []int{1, 5, 10, 23, 50, 0} {
+		var got []string
+		cursor := ""
+		for pages := 0; ; pages++ {
+			page, err := view.EventsPage(cursor, limit)
+			if err != nil {
+				t.Fatalf("EventsPage(%q, %d) error = %v", cursor, limit, err)
+			}
+			if limit > 0 && len(page.Events) > limit {
+				t.Fatalf("EventsPage(%q, %d) = %d events", cursor, limit, len(page.Events))
+			}
+			got = append(got, eventIDs(page.Events)...)
+			if page.NextCursor == "" {
+				break
+			}
+			// 游标是本页最后一个事件，不会出现空页
+			if len(page.Events) == 0 || page.NextCursor != page.Events[len(page.Events)-1].ID || pages > len(want) {
+				t.Fatalf("EventsPage(%q, %d) NextCursor = %q after %v", cursor, limit, page.NextCursor, eventIDs(page.Events))
+			}
+			cursor = page.NextCursor
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("EventsPage walk with limit %d = %v, want %v", limit, got, want)
+		}
+	}
+
+	// 末尾的游标在追加后可以继续读取
+	page, err := view.EventsPage(want[len(want)-1], 10)
+	if err != nil || len(page.Events) != 0 || page.NextCursor != "" {
+		t.Errorf("EventsPage(last) = %+v, %v", page, err)
+	}
+	appendEvent(t, svc, sess.ID(), &session.Event{ID: "page-late", Author: "user"})
+	if page, err := view.EventsPage(want[len(want)-1], 10); err != nil || !slices.Equal(eventIDs(page.Events), []string{"page-late"}) {
+		t.Errorf("EventsPage(last) after append = %v, %v", page, err)
+	}
+
+	if _, err := view.EventsPage("no-such-event", 10); !errors.Is(err, session.ErrInvalidCursor) {
+		t.Errorf("EventsPage(unknown) error = %v, want ErrInvalidCursor", err)
+	}
+	// 其他会话的事件不能作为游标
+	other := create(t, svc, testApp, testUser, nil)
+	appendEvent(t, svc, other.ID(), &session.Event{ID: "other-evt", Author: "user"})
+	if _, err := view.EventsPage("other-evt", 10); !errors.Is(err, session.ErrInvalidCursor) {
+		t.Errorf("EventsPage(other session's event) error = %v, want ErrInvalidCursor", err)
+	}
+}
+
+func testEventRoundTrip(t *testing.T, svc session.Service) {
+	sess := create(t, svc, testApp, testUser, nil)
+	in := session.Event{
+		ID:           "evt-round-trip",
+		Timestamp:    time.Date(2025, 6, 7, 8, 9, 10, 123456789, time.FixedZone("UTC+8", 8*3600)),
+		InvocationID: "inv-1",
+		AgentID:      "agent-2",
+		Branch:       "root.child",
+		Author:       "assistant",
+		Content:      types.Message{Role: types.RoleAssistant, Content: "hello 世界"},
+		Reasoning:    "because",
+		Actions: session.EventActions{
+			StateDelta:        map[string]any{"user:lang": "zh", "count": 3},
+			ArtifactDelta:     map[string]int64{"report.md": 2},
+			SkipSummarization: true,
+			TransferToAgent:   "agent-3",
+			Escalate:          true,
+			CustomActions:     map[string]any{"nested": map[string]any{"ok": true}},
+		},
+		LongRunningToolIDs: []string{"tool-1", "tool-2"},
+		Metadata:           map[string]any{"source": "conformance", "scores": []any{1, 2.5}},
+	}
+	event := in
+	appendEvent(t, svc, sess.ID(), &event)
+
+	events := getEvents(t, svc, sess.ID(), nil)
+	if len(events) != 1 {
+		t.Fatalf("GetEvents() = %d events", len(events))
+	}
+	out := events[0]
+	if !out.Timestamp.Equal(in.Timestamp) {
+		t.Errorf("Timestamp = %v, want %v", out.Timestamp, in.Timestamp)
+	}
+	out.Timestamp, in.Timestamp = time.Time{}, time.Time{}
+	if got, want := normalize(t, out), normalize(t, in); !reflect.DeepEqual(got, want) {
+		t.Errorf("event round trip:\n got %v\nwant %v", got, want)
+	}
+}
+
+// testEventFilterProperties 随机生成事件和过滤条件，与参考实现的结果逐一比较
+func testEventFilterProperties(t *testing.T, svc session.Service) {
+	const numEvents, numFilters = 40, 300
+	rng := rand.New(rand.NewSource(1))
+	sess := create(t, svc, testApp, testUser, nil)
+
+	agents := []string{"agent-a", "agent-b", "agent-c"}
+	branches := []string{"", "root", "root.sub"}
+	authors := []string{"user", "assistant", "system"}
+	zones := []*time.Location{time.UTC, time.FixedZone("UTC+8", 8*3600), time.FixedZone("UTC-5", -5*3600)}
+	base := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	var stored []session.Event
+	for i := range numEvents {
+		e := session.Event{
+			ID:        fmt.Sprintf("prop-%02d", i),
+			Timestamp: base.Add(time.Duration(rng.Intn(numEvents)) * time.Minute).In(zones[rng.Intn(len(zones))]),
+			AgentID:   agents[rng.Intn(len(agents))],
+			Branch:    branches[rng.Intn(len(branches))],
+			Author:    authors[rng.Intn(len(authors))],
+		}
+		event := e
+		appendEvent(t, svc, sess.ID(), &event)
+		stored = append(stored, e)
+	}
+
+	pick := func(values []string) string {
+		if rng.Intn(2) == 0 {
+			return ""
+		}
+		return values[rng.Intn(len(values))]
+	}
+	pickTime := func() *time.Time {
+		if rng.Intn(2) == 0 {
+			return nil
+		}
+		// 取已有事件的时间作为边界，覆盖闭区间的端点
+		ts := stored[rng.Intn(len(stored))].Timestamp.In(zones[rng.Intn(len(zones))])
+		return &ts
+	}
+
+	for range numFilters {
+		filter := &session.EventFilter{
+			AgentID:   pick(agents),
+			Branch:    pick(branches[1:]),
+			Author:    pick(authors),
+			StartTime: pickTime(),
+			EndTime:   pickTime(),
+		}
+		if rng.Intn(2) == 0 {
+			filter.Limit = rng.Intn(numEvents/2) + 1
+		}
+		if rng.Intn(2) == 0 {
+			filter.Offset = rng.Intn(numEvents / 2)
+		}
+
+		got := eventIDs(getEvents(t, svc, sess.ID(), filter))
+		want := eventIDs(referenceFilter(stored, filter))
+		if !slices.Equal(got, want) {
+			t.Fatalf("GetEvents(%s)\n got %v\nwant %v", describeFilter(filter), got, want)
+		}
+	}
+}
+
+// referenceFilter EventFilter 的参考语义
+func referenceFilter(events []session.Event, f *session.EventFilter) []session.Event {
+	var result []session.Event
+	for _, e := range events {
+		switch {
+		case f.AgentID != "" && e.AgentID != f.AgentID,
+			f.Branch != "" && e.Branch != f.Branch,
+			f.Author != "" && e.Author != f.Author,
+			f.StartTime != nil && e.Timestamp.Before(*f.StartTime),
+			f.EndTime != nil && e.Timestamp.After(*f.EndTime):
+			continue
+		}
+		result = append(result, e)
+	}
+	if f.Offset >= len(result) {
+		return nil
+	}
+	result = result[f.Offset:]
+	if f.Limit > 0 && f.Limit < len(result) {
+		result = result[:f.Limit]
+	}
+	return result
+}
+
+func describeFilter(f *session.EventFilter) string {
+	s := fmt.Sprintf("agent=%q branch=%q author=%q limit=%d offset=%d", f.AgentID, f.Branch, f.Author, f.Limit, f.Offset)
+	if f.StartTime != nil {
+		s += " start=" + f.StartTime.Format(time.RFC3339Nano)
+	}
+	if f.EndTime != nil {
+		s += " end=" + f.EndTime.Format(time.RFC3339Nano)
+	}
+	return s
+}
+
+func testState(t *testing.T, svc session.Service) {
+	ctx := context.Background()
+	sess := create(t, svc, testApp, testUser, nil)
+	other := create(t, svc, testApp, testUser, nil)
+
+	delta := map[string]any{
+		"session:title": "demo",
+		"user:lang":     "zh",
+		"app:version":   3,
+		"flag":          true,
+		"nested":        map[string]any{"list": []any{1, "two", nil}},
+	}
+	if err := svc.UpdateState(ctx, sess.ID(), delta); err != nil {
+		t.Fatalf("UpdateState() error = %v", err)
+	}
+	if err := svc.UpdateState(ctx, sess.ID(), map[string]any{"flag": false, "extra": 1.5}); err != nil {
+		t.Fatalf("UpdateState() error = %v", err)
+	}
+
+	want := normalize(t, delta).(map[string]any)
+	want["flag"], want["extra"] = false, 1.5
+	readState := func(id string) map[string]any {
+		t.Helper()
+		result := make(map[string]any)
+		for k, v := range get(t, svc, id).State().All() {
+			result[k] = v
+		}
+		return normalize(t, result).(map[string]any)
+	}
+	if got := readState(sess.ID()); !reflect.DeepEqual(got, want) {
+		t.Errorf("State().All() = %v, want %v", got, want)
+	}
+
+	state := get(t, svc, sess.ID()).State()
+	if v, err := state.Get("user:lang"); err != nil || v != "zh" {
+		t.Errorf("State().Get(user:lang) = %v, %v", v, err)
+	}
+	if _, err := state.Get("missing"); !errors.Is(err, session.ErrStateKeyNotExist) {
+		t.Errorf("State().Get(missing) error = %v", err)
+	}
+
+	// 通过 State 接口的修改对之后获取的会话可见
+	if err := state.Set("temp:scratch", "x"); err != nil {
+		t.Fatalf("State().Set() error = %v", err)
+	}
+	if err := state.Delete("extra"); err != nil {
+		t.Fatalf("State().Delete() error = %v", err)
+	}
+	fresh := get(t, svc, sess.ID()).State()
+	if !fresh.Has("temp:scratch") || fresh.Has("extra") {
+		t.Errorf("after Set/Delete: Has(temp:scratch) = %v, Has(extra) = %v", fresh.Has("temp:scratch"), fresh.Has("extra"))
+	}
+
+	// 状态按会话隔离
+	if got := readState(other.ID()); len(got) != 0 {
+		t.Errorf("other session state = %v, want empty", got)
+	}
+}
+
+func testConcurrentAppends(t *testing.T, svc session.Service) {
+	const writers, perWriter = 8, 25
+	ctx := context.Background()
+	sess := create(t, svc, testApp, testUser, nil)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, writers*(perWriter+2))
+	for w := range writers {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for i := range perWriter {
+				err := svc.AppendEvent(ctx, sess.ID(), &session.Event{
+					Branch:       fmt.Sprintf("writer-%d", w),
+					InvocationID: fmt.Sprintf("%03d", i),
+					Author:       "user",
+				})
+				if err != nil {
+					errs <- fmt.Errorf("writer %d append %d: %w", w, i, err)
+				}
+			}
+			if err := svc.UpdateState(ctx, sess.ID(), map[string]any{fmt.Sprintf("writer-%d", w): perWriter}); err != nil {
+				errs <- err
+			}
+		}()
+		// 并发读取不报错
+		go func() {
+			defer wg.Done()
+			if _, err := svc.GetEvents(ctx, sess.ID(), &session.EventFilter{Branch: fmt.Sprintf("writer-%d", w)}); err != nil {
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	events := getEvents(t, svc, sess.ID(), nil)
+	if len(events) != writers*perWriter {
+		t.Fatalf("GetEvents() = %d events, want %d", len(events), writers*perWriter)
+	}
+	ids := make(map[string]bool, len(events))
+	last := make(map[string]string)
+	for _, e := range events {
+		if ids[e.ID] {
+			t.Fatalf("duplicate event ID %q", e.ID)
+		}
+		ids[e.ID] = true
+		if prev, ok := last[e.Branch]; ok && e.InvocationID <= prev {
+			t.Errorf("%s: event %s after %s, per-writer order not preserved", e.Branch, e.InvocationID, prev)
+		}
+		last[e.Branch] = e.InvocationID
+	}
+	state := get(t, svc, sess.ID()).State()
+	for w := range writers {
+		if !state.Has(fmt.Sprintf("writer-%d", w)) {
+			t.Errorf("state key writer-%d lost", w)
+		}
+	}
+}
+
+func testWatch(t *testing.T, svc session.Service) {
+	ctx := context.Background()
+	if _, err := svc.Watch(ctx, "missing-session"); !errors.Is(err, session.ErrSessionNotFound) {
+		t.Errorf("Watch(missing) error = %v, want ErrSessionNotFound", err)
+	}
+
+	sess := create(t, svc, testApp, testUser, nil)
+	appendEvent(t, svc, sess.ID(), &session.Event{ID: "watch-before", Author: "user"})
+
+	watchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	ch, err := svc.Watch(watchCtx, sess.ID())
+	if err != nil {
+		t.Fatalf("Watch() error = %v", err)
+	}
+	cancelled, cancelEarly := context.WithCancel(ctx)
+	early, err := svc.Watch(cancelled, sess.ID())
+	if err != nil {
+		t.Fatalf("Watch() error = %v", err)
+	}
+
+	var want []string
+	for i := range 5 {
+		e := &session.Event{ID: fmt.Sprintf("watch-%d", i), Author: "assistant"}
+		appendEvent(t, svc, sess.ID(), e)
+		want = append(want, e.ID)
+	}
+	var got []string
+	for len(got) < len(want) {
+		select {
+		case evt, ok := <-ch:
+			if !ok {
+				t.Fatalf("Watch() channel closed after %v", got)
+			}
+			got = append(got, evt.ID)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Watch() received %v, want %v", got, want)
+		}
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("Watch() = %v, want %v (events before Watch are not replayed)", got, want)
+	}
+
+	// ctx 结束后通道关闭（可能先送达已缓冲的事件）
+	cancelEarly()
+	waitClosed(t, early, "cancelled Watch()")
+
+	if err := svc.Delete(ctx, sess.ID()); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	waitClosed(t, ch, "Watch() after Delete()")
+}
+
+func waitClosed(t *testing.T, ch <-chan session.Event, what string) {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case _, ok := <-ch:
+			if !ok {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("%s channel not closed", what)
+		}
+	}
+}
+
+func testAttachments(t *testing.T, svc session.Service) {
+	ctx := context.Background()
+	image, doc := []byte("\x89PNG fake image"), []byte("%PDF fake document")
+	a := create(t, svc, testApp, testUser, nil)
+	appendEvent(t, svc, a.ID(), &session.Event{ID: "att-1", Author: "user", Attachments: []session.Attachment{
+		{Name: "a.png", MimeType: "image/png", Data: image},
+		{Name: "copy.png", MimeType: "image/png", Data: image},
+	}})
+	appendEvent(t, svc, a.ID(), &session.Event{ID: "att-2", Author: "user", Attachments: []session.Attachment{
+		{Name: "b.pdf", MimeType: "application/pdf", Data: doc},
+		{Name: "again.png", Hash: session.HashAttachment(image)}, // 引用已保存的内容
+	}})
+
+	events := getEvents(t, svc, a.ID(), nil)
+	if len(events) != 2 || len(events[0].Attachments) != 2 || len(events[1].Attachments) != 2 {
+		t.Fatalf("events = %+v", events)
+	}
+	first := events[0].Attachments[0]
+	if first.Hash != session.HashAttachment(image) || first.Size != int64(len(image)) || first.Name != "a.png" || first.MimeType != "image/png" || first.Data != nil {
+		t.Errorf("attachment = %+v, want a reference without data", first)
+	}
+	for _, want := range [][]byte{image, doc} {
+		got, err := svc.GetAttachment(ctx, a.ID(), session.HashAttachment(want))
+		if err != nil || string(got) != string(want) {
+			t.Errorf("GetAttachment() = %q, %v, want %q", got, err, want)
+		}
+	}
+
+	// 只凭哈希不能引用其他会话的附件，失败的追加不保存事件
+	b := create(t, svc, testApp, testUser, nil)
+	err := svc.AppendEvent(ctx, b.ID(), &session.Event{Author: "user", Attachments: []session.Attachment{{Hash: session.HashAttachment(doc)}}})
+	if !errors.Is(err, session.ErrAttachmentNotFound) {
+		t.Errorf("AppendEvent(foreign hash) error = %v, want ErrAttachmentNotFound", err)
+	}
+	if events := getEvents(t, svc, b.ID(), nil); len(events) != 0 {
+		t.Errorf("failed append stored %d events", len(events))
+	}
+	if _, err := svc.GetAttachment(ctx, b.ID(), session.HashAttachment(doc)); !errors.Is(err, session.ErrAttachmentNotFound) {
+		t.Errorf("GetAttachment(foreign hash) error = %v, want ErrAttachmentNotFound", err)
+	}
+	appendEvent(t, svc, b.ID(), &session.Event{Author: "user", Attachments: []session.Attachment{{Name: "same.pdf", Data: doc}}})
+
+	export, err := session.ExportSession(ctx, svc, &session.GetRequest{AppName: testApp, UserID: testUser, SessionID: a.ID()})
+	if err != nil {
+		t.Fatalf("ExportSession() error = %v", err)
+	}
+	if len(export.Events) != 2 || len(export.Attachments) != 2 || string(export.Attachments[first.Hash]) != string(image) {
+		t.Errorf("export = %d events, %d attachments", len(export.Events), len(export.Attachments))
+	}
+
+	// 删除会话后，其他会话仍能读取相同内容
+	if err := svc.Delete(ctx, a.ID()); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := svc.GetAttachment(ctx, a.ID(), first.Hash); !errors.Is(err, session.ErrSessionNotFound) {
+		t.Errorf("GetAttachment(deleted session) error = %v, want ErrSessionNotFound", err)
+	}
+	if got, err := svc.GetAttachment(ctx, b.ID(), session.HashAttachment(doc)); err != nil || string(got) != string(doc) {
+		t.Errorf("GetAttachment() after deleting the other session = %q, %v", got, err)
+	}
+}
@@ -1,6 +1,12 @@
 // Package sqlite provides a SQLite-based implementation of the session.Service interface.
 // This is ideal for desktop applications and single-user scenarios where a lightweight,
 // file-based database is preferred over PostgreSQL or MySQL.
+//
+// Writes from all sessions go through a single writer goroutine that owns the
+// only write connection. Requests that arrive while a batch is being committed
+// are grouped into the next transaction, so several agents writing at once
+// share one commit instead of serializing on a lock. Reads use a separate
+// connection pool and never wait for writers (WAL mode).
 package sqlite
 
 import (
@@ -11,6 +17,7 @@ import (
 	"fmt"
 	"iter"
 	"maps"
+	"strings"
 	"sync"
 	"time"
 
@@ -19,33 +26,116 @@ import (
 	_ "github.com/mattn/go-sqlite3"
 )
 
+// Options tunes the connection pools and write batching of a Service.
+type Options struct {
+	// ReadConns is the size of the read connection pool. Defaults to 4.
+	ReadConns int
+	// MaxBatchSize caps how many queued writes are committed in one
+	// transaction. Defaults to 128; 1 disables group commit.
+	MaxBatchSize int
+	// WatchInterval is how often Watch polls for events written by other
+	// processes. Writes through this Service are delivered immediately.
+	// Defaults to 500ms.
+	WatchInterval time.Duration
+}
+
+// DefaultOptions returns the options used by New.
+func DefaultOptions() Options {
+	return Options{ReadConns: 4, MaxBatchSize: 128, WatchInterval: 500 * time.Millisecond}
+}
+
 // Service implements session.Service using SQLite.
 type Service struct {
-	db *sql.DB
-	mu sync.RWMutex
+	writeDB *sql.DB
+	readDB  *sql.DB
+	stmts   stmtCache
+
+	watchers      session.EventWatchers
+	watchInterval time.Duration
+
+	maxBatch int
+	writes   chan *writeRequest
+	quit     chan struct{}
+	loopDone chan struct{}
+
+	closeMu sync.RWMutex
+	closed  bool
 }
 
-// New creates a new SQLite session service.
+// New creates a new SQLite session service with DefaultOptions.
 // dbPath is the path to the SQLite database file.
 // If the file doesn't exist, it will be created.
 func New(dbPath string) (*Service, error) {
-	db, err := sql.Open("sqlite3", dbPath+"?_journal_mode=WAL&_busy_timeout=5000")
-	if err != nil {
-		return nil, fmt.Errorf("open sqlite database: %w", err)
+	return NewWithOptions(dbPath, DefaultOptions())
+}
+
+// NewWithOptions creates a new SQLite session service with the given options.
+// Zero fields in opts fall back to DefaultOptions.
+func NewWithOptions(dbPath string, opts Options) (*Service, error) {
+	defaults := DefaultOptions()
+	if opts.ReadConns <= 0 {
+		opts.ReadConns = defaults.ReadConns
+	}
+	if opts.MaxBatchSize <= 0 {
+		opts.MaxBatchSize = defaults.MaxBatchSize
+	}
+	if opts.WatchInterval <= 0 {
+		opts.WatchInterval = defaults.WatchInterval
 	}
 
-	// Set connection pool settings for SQLite
-	db.SetMaxOpenConns(1) // SQLite only supports one writer
-	db.SetMaxIdleConns(1)
-	db.SetConnMaxLifetime(time.Hour)
+	sep := "?"
+	if strings.Contains(dbPath, "?") {
+		sep = "&"
+	}
+	dsn := dbPath + sep + "_journal_mode=WAL&_busy_timeout=5000"
 
-	s := &Service{db: db}
+	// BEGIN IMMEDIATE takes the write lock up front, so a batch never fails
+	// half way through on a lock upgrade.
+	writeDB, err := sql.Open("sqlite3", dsn+"&_txlock=immediate")
+	if err != nil {
+		return nil, fmt.Errorf("open sqlite database: %w", err)
+	}
+	writeDB.SetMaxOpenConns(1) // SQLite only supports one writer
+	writeDB.SetMaxIdleConns(1)
+	writeDB.SetConnMaxLifetime(0)
+
+	s := &Service{
+		writeDB:       writeDB,
+		readDB:        writeDB,
+		watchInterval: opts.WatchInterval,
+		maxBatch:      opts.MaxBatchSize,
+		writes:        make(chan *writeRequest, opts.MaxBatchSize),
+		quit:          make(chan struct{}),
+		loopDone:      make(chan struct{}),
+	}
 
 	if err := s.migrate(); err != nil {
-		_ = db.Close() // Ignore close error, migration error is more important
+		_ = writeDB.Close() // Ignore close error, migration error is more important
 		return nil, fmt.Errorf("migrate database: %w", err)
 	}
+	for _, query := range writeQueries {
+		if _, err := s.stmts.get(context.Background(), writeDB, query); err != nil {
+			_ = s.stmts.close()
+			_ = writeDB.Close()
+			return nil, err
+		}
+	}
 
+	// Every connection to an in-memory database is a separate database, so
+	// reads have to share the write connection there.
+	if dbPath != ":memory:" && !strings.Contains(dbPath, "mode=memory") {
+		readDB, err := sql.Open("sqlite3", dsn+"&_query_only=1")
+		if err != nil {
+			_ = writeDB.Close()
+			return nil, fmt.Errorf("open sqlite database: %w", err)
+		}
+		readDB.SetMaxOpenConns(opts.ReadConns)
+		readDB.SetMaxIdleConns(opts.ReadConns)
+		readDB.SetConnMaxLifetime(time.Hour)
+		s.readDB = readDB
+	}
+
+	go s.writeLoop()
 	return s, nil
 }
 
@@ -77,6 +167,7 @@ func (s *Service) migrate() error {
 		actions TEXT,
 		long_running_tool_ids TEXT,
 		metadata TEXT,
+		attachments TEXT,
 		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 		FOREIGN KEY (session_id) REFERENCES sessions(id) ON DELETE CASCADE
 	);
@@ -92,22 +183,109 @@ func (s *Service) migrate() error {
 		PRIMARY KEY (session_id, key),
 		FOREIGN KEY (session_id) REFERENCES sessions(id) ON DELETE CASCADE
 	);
+
+	-- Attachment contents are stored once per hash; event_attachments tracks
+	-- which sessions reference them so unreferenced contents can be dropped.
+	CREATE TABLE IF NOT EXISTS attachments (
+		hash TEXT PRIMARY KEY,
+		size INTEGER NOT NULL,
+		data BLOB NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS event_attachments (
+		session_id TEXT NOT NULL,
+		hash TEXT NOT NULL,
+		event_id TEXT NOT NULL,
+		PRIMARY KEY (session_id, hash, event_id)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_event_attachments_hash ON event_attachments(hash);
 	`
 
-	_, err := s.db.Exec(schema)
-	return err
+	if _, err := s.writeDB.Exec(schema); err != nil {
+		return err
+	}
+
+	// Databases created before attachments were supported lack the column.
+	var hasAttachments bool
+	err := s.writeDB.QueryRow(`SELECT COUNT(*) > 0 FROM pragma_table_info('events') WHERE name = 'attachments'`).Scan(&hasAttachments)
+	if err != nil {
+		return fmt.Errorf("inspect events table: %w", err)
+	}
+	if !hasAttachments {
+		if _, err := s.writeDB.Exec(`ALTER TABLE events ADD COLUMN attachments TEXT`); err != nil {
+			return fmt.Errorf("add attachments column: %w", err)
+		}
+	}
+	return nil
 }
 
-// Close closes the database connection.
+// Close stops the writer after it has committed every accepted write, then
+// closes the database connections.
 func (s *Service) Close() error {
-	return s.db.Close()
+	s.closeMu.Lock()
+	if s.closed {
+		s.closeMu.Unlock()
+		return nil
+	}
+	s.closed = true
+	s.closeMu.Unlock()
+
+	close(s.quit)
+	<-s.loopDone
+	s.watchers.CloseAll()
+
+	errs := []error{s.stmts.close()}
+	if s.readDB != s.writeDB {
+		errs = append(errs, s.readDB.Close())
+	}
+	errs = append(errs, s.writeDB.Close())
+	return errors.Join(errs...)
+}
+
+const (
+	insertSessionQuery = `INSERT INTO sessions (id, app_name, user_id, agent_id, metadata, created_at, updated_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?)`
+	sessionExistsQuery   = `SELECT 1 FROM sessions WHERE id = ?`
+	sessionMetadataQuery = `SELECT metadata FROM sessions WHERE id = ?`
+	updateMetadataQuery  = `UPDATE sessions SET metadata = ?, updated_at = ? WHERE id = ?`
+	touchSessionQuery    = `UPDATE sessions SET updated_at = ? WHERE id = ?`
+	deleteEventsQuery    = `DELETE FROM events WHERE session_id = ?`
+	deleteStateQuery     = `DELETE FROM session_state WHERE session_id = ?`
+	deleteSessionQuery   = `DELETE FROM sessions WHERE id = ?`
+	insertEventQuery     = `INSERT INTO events (id, session_id, invocation_id, agent_id, branch, author, content, reasoning, actions, long_running_tool_ids, metadata, attachments, created_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	upsertStateQuery = `INSERT INTO session_state (session_id, key, value, updated_at)
+		 VALUES (?, ?, ?, ?)
+		 ON CONFLICT (session_id, key) DO UPDATE SET value = excluded.value, updated_at = excluded.updated_at`
+	deleteStateKeyQuery = `DELETE FROM session_state WHERE session_id = ? AND key = ?`
+
+	insertAttachmentQuery    = `INSERT OR IGNORE INTO attachments (hash, size, data) VALUES (?, ?, ?)`
+	insertAttachmentRefQuery = `INSERT OR IGNORE INTO event_attachments (session_id, hash, event_id) VALUES (?, ?, ?)`
+	attachmentRefQuery       = `SELECT 1 FROM event_attachments WHERE session_id = ? AND hash = ? LIMIT 1`
+	// deleteOrphanAttachmentsQuery drops contents referenced only by the session being deleted.
+	deleteOrphanAttachmentsQuery = `DELETE FROM attachments
+		 WHERE hash IN (SELECT hash FROM event_attachments WHERE session_id = ?1)
+		 AND NOT EXISTS (SELECT 1 FROM event_attachments o WHERE o.hash = attachments.hash AND o.session_id != ?1)`
+	deleteAttachmentRefsQuery = `DELETE FROM event_attachments WHERE session_id = ?`
+
+	selectEventColumns = `SELECT id, invocation_id, agent_id, branch, author, content, reasoning, actions, long_running_tool_ids, metadata, COALESCE(attachments, ''), created_at
+		 FROM events`
+)
+
+// writeQueries are prepared on the write connection before the writer starts;
+// see Service.txStmt.
+var writeQueries = []string{
+	insertSessionQuery, sessionExistsQuery, sessionMetadataQuery, updateMetadataQuery,
+	touchSessionQuery, deleteEventsQuery, deleteStateQuery, deleteSessionQuery,
+	insertEventQuery, upsertStateQuery, deleteStateKeyQuery,
+	insertAttachmentQuery, insertAttachmentRefQuery, attachmentRefQuery,
+	deleteOrphanAttachmentsQuery, deleteAttachmentRefsQuery,
 }
 
 // Create creates a new session.
 func (s *Service) Create(ctx context.Context, req *session.CreateRequest) (session.Session, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
 	id := uuid.New().String()
 	now := time.Now()
 
@@ -116,11 +294,11 @@ func (s *Service) Create(ctx context.Context, req *session.CreateRequest) (sessi
 		return nil, fmt.Errorf("marshal metadata: %w", err)
 	}
 
-	_, err = s.db.ExecContext(ctx,
-		`INSERT INTO sessions (id, app_name, user_id, agent_id, metadata, created_at, updated_at)
-		 VALUES (?, ?, ?, ?, ?, ?, ?)`,
-		id, req.AppName, req.UserID, req.AgentID, string(metadata), now, now,
-	)
+	err = s.write(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		return s.txExec(ctx, tx, insertSessionQuery,
+			id, req.AppName, req.UserID, req.AgentID, string(metadata), now, now,
+		)
+	})
 	if err != nil {
 		return nil, fmt.Errorf("insert session: %w", err)
 	}
@@ -138,109 +316,112 @@ func (s *Service) Create(ctx context.Context, req *session.CreateRequest) (sessi
 
 // Get retrieves a session by ID.
 func (s *Service) Get(ctx context.Context, req *session.GetRequest) (session.Session, error) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-
-	var sess sqliteSession
-	var metadataJSON string
-	var createdAt, updatedAt time.Time
-
-	err := s.db.QueryRowContext(ctx,
+	stmt, err := s.readStmt(ctx,
 		`SELECT id, app_name, user_id, agent_id, metadata, created_at, updated_at
-		 FROM sessions WHERE id = ? AND app_name = ? AND user_id = ?`,
-		req.SessionID, req.AppName, req.UserID,
-	).Scan(&sess.id, &sess.appName, &sess.userID, &sess.agentID, &metadataJSON, &createdAt, &updatedAt)
+		 FROM sessions WHERE id = ? AND app_name = ? AND user_id = ?`)
+	if err != nil {
+		return nil, err
+	}
 
-	if err == sql.ErrNoRows {
+	sess, err := s.scanSession(stmt.QueryRowContext(ctx, req.SessionID, req.AppName, req.UserID))
+	if errors.Is(err, sql.ErrNoRows) {
 		return nil, session.ErrSessionNotFound
 	}
 	if err != nil {
 		return nil, fmt.Errorf("query session: %w", err)
 	}
-
-	if metadataJSON != "" {
-		if err := json.Unmarshal([]byte(metadataJSON), &sess.metadata); err != nil {
-			return nil, fmt.Errorf("unmarshal metadata: %w", err)
-		}
-	}
-
-	sess.service = s
-	sess.lastUpdateTime = updatedAt
-
-	return &sess, nil
+	return sess, nil
 }
 
-// Update updates a session's metadata.
-func (s *Service) Update(ctx context.Context, req *session.UpdateRequest) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	// Get existing metadata
-	var existingJSON string
-	err := s.db.QueryRowContext(ctx,
-		`SELECT metadata FROM sessions WHERE id = ?`,
-		req.SessionID,
-	).Scan(&existingJSON)
+// AppNameOf returns the app name of a session, implementing session.Locator.
+func (s *Service) AppNameOf(ctx context.Context, sessionID string) (string, error) {
+	stmt, err := s.readStmt(ctx, `SELECT app_name FROM sessions WHERE id = ?`)
+	if err != nil {
+		return "", err
+	}
 
+	var appName string
+	err = stmt.QueryRowContext(ctx, sessionID).Scan(&appName)
 	if errors.Is(err, sql.ErrNoRows) {
-		return session.ErrSessionNotFound
+		return "", session.ErrSessionNotFound
 	}
 	if err != nil {
-		return fmt.Errorf("query session: %w", err)
+		return "", fmt.Errorf("query session: %w", err)
 	}
+	return appName, nil
+}
 
-	// Merge metadata
-	existing := make(map[string]any)
-	if existingJSON != "" {
-		if err := json.Unmarshal([]byte(existingJSON), &existing); err != nil {
-			return fmt.Errorf("unmarshal existing metadata: %w", err)
+// Update updates a session's metadata.
+func (s *Service) Update(ctx context.Context, req *session.UpdateRequest) error {
+	// The read-merge-write runs on the writer, so concurrent updates never lose keys.
+	return s.write(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		stmt, err := s.txStmt(ctx, tx, sessionMetadataQuery)
+		if err != nil {
+			return err
+		}
+		var existingJSON string
+		err = stmt.QueryRowContext(ctx, req.SessionID).Scan(&existingJSON)
+		if errors.Is(err, sql.ErrNoRows) {
+			return session.ErrSessionNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("query session: %w", err)
 		}
-	}
 
-	maps.Copy(existing, req.Metadata)
+		// Merge metadata
+		var existing map[string]any
+		if existingJSON != "" {
+			if err := json.Unmarshal([]byte(existingJSON), &existing); err != nil {
+				return fmt.Errorf("unmarshal existing metadata: %w", err)
+			}
+		}
+		if existing == nil { // stored as "null" when created without metadata
+			existing = make(map[string]any)
+		}
 
-	newJSON, err := json.Marshal(existing)
-	if err != nil {
-		return fmt.Errorf("marshal metadata: %w", err)
-	}
+		maps.Copy(existing, req.Metadata)
 
-	_, err = s.db.ExecContext(ctx,
-		`UPDATE sessions SET metadata = ?, updated_at = ? WHERE id = ?`,
-		string(newJSON), time.Now(), req.SessionID,
-	)
-	return err
+		newJSON, err := json.Marshal(existing)
+		if err != nil {
+			return fmt.Errorf("marshal metadata: %w", err)
+		}
+
+		return s.txExec(ctx, tx, updateMetadataQuery, string(newJSON), time.Now(), req.SessionID)
+	})
 }
 
 // Delete deletes a session and all its events.
 func (s *Service) Delete(ctx context.Context, sessionID string) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	_, err := s.db.ExecContext(ctx, `DELETE FROM sessions WHERE id = ?`, sessionID)
+	err := s.write(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		// SQLite does not enforce foreign keys by default, so cascade by hand.
+		for _, query := range []string{deleteOrphanAttachmentsQuery, deleteAttachmentRefsQuery, deleteEventsQuery, deleteStateQuery, deleteSessionQuery} {
+			if err := s.txExec(ctx, tx, query, sessionID); err != nil {
+				return fmt.Errorf("delete session: %w", err)
+			}
+		}
+		return nil
+	})
+	if err == nil {
+		s.watchers.Close(sessionID)
+	}
 	return err
 }
 
 // List lists sessions for an app and user.
 func (s *Service) List(ctx context.Context, req *session.ListRequest) ([]*session.Session, error) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-
 	query := `SELECT id, app_name, user_id, agent_id, metadata, created_at, updated_at
 			  FROM sessions WHERE app_name = ? AND user_id = ?
 			  ORDER BY updated_at DESC`
 
 	args := []any{req.AppName, req.UserID}
 
-	if req.Limit > 0 {
-		query += " LIMIT ?"
-		args = append(args, req.Limit)
-	}
-	if req.Offset > 0 {
-		query += " OFFSET ?"
-		args = append(args, req.Offset)
-	}
+	query, args = paginate(query, args, req.Limit, req.Offset)
 
-	rows, err := s.db.QueryContext(ctx, query, args...)
+	stmt, err := s.readStmt(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := stmt.QueryContext(ctx, args...)
 	if err != nil {
 		return nil, fmt.Errorf("query sessions: %w", err)
 	}
@@ -248,23 +429,11 @@ func (s *Service) List(ctx context.Context, req *session.ListRequest) ([]*sessio
 
 	var results []*session.Session
 	for rows.Next() {
-		var sess sqliteSession
-		var metadataJSON string
-		var createdAt, updatedAt time.Time
-
-		if err := rows.Scan(&sess.id, &sess.appName, &sess.userID, &sess.agentID, &metadataJSON, &createdAt, &updatedAt); err != nil {
+		sess, err := s.scanSession(rows)
+		if err != nil {
 			return nil, fmt.Errorf("scan session: %w", err)
 		}
-
-		if metadataJSON != "" {
-			if err := json.Unmarshal([]byte(metadataJSON), &sess.metadata); err != nil {
-				return nil, fmt.Errorf("unmarshal metadata: %w", err)
-			}
-		}
-
-		sess.service = s
-		sess.lastUpdateTime = updatedAt
-		var iface session.Session = &sess
+		var iface session.Session = sess
 		results = append(results, &iface)
 	}
 
@@ -273,67 +442,187 @@ func (s *Service) List(ctx context.Context, req *session.ListRequest) ([]*sessio
 
 // AppendEvent adds an event to a session.
 func (s *Service) AppendEvent(ctx context.Context, sessionID string, event *session.Event) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	return s.AppendEvents(ctx, sessionID, []*session.Event{event})
+}
 
-	// Check session exists
-	var exists bool
-	err := s.db.QueryRowContext(ctx, `SELECT 1 FROM sessions WHERE id = ?`, sessionID).Scan(&exists)
+// AppendEvents adds several events to a session in one transaction, in order.
+// Either all events are stored or none are. Missing IDs and timestamps are
+// filled in on the passed events, as with AppendEvent.
+func (s *Service) AppendEvents(ctx context.Context, sessionID string, events []*session.Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	// Serialize and hash outside the writer so it only spends time on SQL.
+	blobs, err := session.PrepareAttachments(events...)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	rows := make([][]any, len(events))
+	for i, event := range events {
+		args, err := eventArgs(sessionID, event, now)
+		if err != nil {
+			return err
+		}
+		rows[i] = args
+	}
+
+	err = s.write(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		if err := s.checkSession(ctx, tx, sessionID); err != nil {
+			return err
+		}
+		if err := s.insertAttachments(ctx, tx, sessionID, events, blobs); err != nil {
+			return err
+		}
+		insert, err := s.txStmt(ctx, tx, insertEventQuery)
+		if err != nil {
+			return err
+		}
+		for _, args := range rows {
+			if _, err := insert.ExecContext(ctx, args...); err != nil {
+				return fmt.Errorf("insert event: %w", err)
+			}
+		}
+		// Update session timestamp
+		return s.txExec(ctx, tx, touchSessionQuery, now, sessionID)
+	})
+	if err == nil {
+		s.watchers.Notify(sessionID)
+	}
+	return err
+}
+
+// insertAttachments stores new attachment contents and records which events
+// reference them. Attachments without data must already be referenced by the
+// session, so a hash alone never grants access to another session's content.
+func (s *Service) insertAttachments(ctx context.Context, tx *sql.Tx, sessionID string, events []*session.Event, blobs map[string][]byte) error {
+	for hash, data := range blobs {
+		if err := s.txExec(ctx, tx, insertAttachmentQuery, hash, len(data), data); err != nil {
+			return fmt.Errorf("insert attachment: %w", err)
+		}
+	}
+	for _, event := range events {
+		for _, att := range event.Attachments {
+			if _, ok := blobs[att.Hash]; !ok {
+				stmt, err := s.txStmt(ctx, tx, attachmentRefQuery)
+				if err != nil {
+					return err
+				}
+				var referenced bool
+				err = stmt.QueryRowContext(ctx, sessionID, att.Hash).Scan(&referenced)
+				if errors.Is(err, sql.ErrNoRows) {
+					return fmt.Errorf("%w: %s", session.ErrAttachmentNotFound, att.Hash)
+				}
+				if err != nil {
+					return fmt.Errorf("check attachment: %w", err)
+				}
+			}
+			if err := s.txExec(ctx, tx, insertAttachmentRefQuery, sessionID, att.Hash, event.ID); err != nil {
+				return fmt.Errorf("insert attachment reference: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// GetAttachment returns the content of an attachment referenced by an event of the session.
+func (s *Service) GetAttachment(ctx context.Context, sessionID, hash string) ([]byte, error) {
+	stmt, err := s.readStmt(ctx,
+		`SELECT a.data FROM attachments a
+		 JOIN event_attachments r ON r.hash = a.hash
+		 WHERE r.session_id = ? AND a.hash = ? LIMIT 1`)
+	if err != nil {
+		return nil, err
+	}
+	var data []byte
+	err = stmt.QueryRowContext(ctx, sessionID, hash).Scan(&data)
 	if errors.Is(err, sql.ErrNoRows) {
-		return session.ErrSessionNotFound
+		exists, err := s.readStmt(ctx, sessionExistsQuery)
+		if err != nil {
+			return nil, err
+		}
+		if err := sessionExists(exists.QueryRowContext(ctx, sessionID)); err != nil {
+			return nil, err
+		}
+		return nil, session.ErrAttachmentNotFound
 	}
 	if err != nil {
-		return fmt.Errorf("check session: %w", err)
+		return nil, fmt.Errorf("query attachment: %w", err)
 	}
+	return data, nil
+}
 
-	// Serialize fields
+// Watch streams events appended to a session after the call. Events written
+// through this Service are delivered as soon as they commit; events written by
+// other processes sharing the database file are picked up by polling every
+// Options.WatchInterval.
+func (s *Service) Watch(ctx context.Context, sessionID string) (<-chan session.Event, error) {
+	stmt, err := s.readStmt(ctx, sessionExistsQuery)
+	if err != nil {
+		return nil, err
+	}
+	if err := sessionExists(stmt.QueryRowContext(ctx, sessionID)); err != nil {
+		return nil, err
+	}
+	return s.watchers.Follow(ctx, sessionID, &sqliteEvents{service: s, sessionID: sessionID}, s.watchInterval), nil
+}
+
+// eventArgs fills in a missing ID and timestamp and returns the values of the
+// event's row in insertEventQuery order.
+func eventArgs(sessionID string, event *session.Event, now time.Time) ([]any, error) {
 	contentJSON, err := json.Marshal(event.Content)
 	if err != nil {
-		return fmt.Errorf("marshal content: %w", err)
+		return nil, fmt.Errorf("marshal content: %w", err)
 	}
 
 	actionsJSON, err := json.Marshal(event.Actions)
 	if err != nil {
-		return fmt.Errorf("marshal actions: %w", err)
+		return nil, fmt.Errorf("marshal actions: %w", err)
 	}
 
 	toolIDsJSON, err := json.Marshal(event.LongRunningToolIDs)
 	if err != nil {
-		return fmt.Errorf("marshal tool ids: %w", err)
+		return nil, fmt.Errorf("marshal tool ids: %w", err)
 	}
 
 	metadataJSON, err := json.Marshal(event.Metadata)
 	if err != nil {
-		return fmt.Errorf("marshal metadata: %w", err)
+		return nil, fmt.Errorf("marshal metadata: %w", err)
+	}
+
+	var attachmentsJSON []byte
+	if len(event.Attachments) > 0 {
+		// Contents live in the attachments table; the row keeps references only.
+		if attachmentsJSON, err = json.Marshal(session.AttachmentRefs(event.Attachments)); err != nil {
+			return nil, fmt.Errorf("marshal attachments: %w", err)
+		}
 	}
 
-	now := time.Now()
 	if event.ID == "" {
 		event.ID = uuid.New().String()
 	}
-
-	_, err = s.db.ExecContext(ctx,
-		`INSERT INTO events (id, session_id, invocation_id, agent_id, branch, author, content, reasoning, actions, long_running_tool_ids, metadata, created_at)
-		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
-		event.ID, sessionID, event.InvocationID, event.AgentID, event.Branch, event.Author,
-		string(contentJSON), event.Reasoning, string(actionsJSON), string(toolIDsJSON), string(metadataJSON), now,
-	)
-	if err != nil {
-		return fmt.Errorf("insert event: %w", err)
+	if event.Timestamp.IsZero() {
+		event.Timestamp = now
 	}
 
-	// Update session timestamp
-	_, err = s.db.ExecContext(ctx, `UPDATE sessions SET updated_at = ? WHERE id = ?`, now, sessionID)
-	return err
+	return []any{
+		event.ID, sessionID, event.InvocationID, event.AgentID, event.Branch, event.Author,
+		string(contentJSON), event.Reasoning, string(actionsJSON), string(toolIDsJSON), string(metadataJSON), string(attachmentsJSON), event.Timestamp.UTC(),
+	}, nil
 }
 
 // GetEvents retrieves events for a session.
 func (s *Service) GetEvents(ctx context.Context, sessionID string, filter *session.EventFilter) ([]session.Event, error) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+	exists, err := s.readStmt(ctx, sessionExistsQuery)
+	if err != nil {
+		return nil, err
+	}
+	if err := sessionExists(exists.QueryRowContext(ctx, sessionID)); err != nil {
+		return nil, err
+	}
 
-	query := `SELECT id, invocation_id, agent_id, branch, author, content, reasoning, actions, long_running_tool_ids, metadata, created_at
-			  FROM events WHERE session_id = ?`
+	query := selectEventColumns + ` WHERE session_id = ?`
 	args := []any{sessionID}
 
 	if filter != nil {
@@ -351,28 +640,26 @@ func (s *Service) GetEvents(ctx context.Context, sessionID string, filter *sessi
 		}
 		if filter.StartTime != nil {
 			query += " AND created_at >= ?"
-			args = append(args, *filter.StartTime)
+			args = append(args, filter.StartTime.UTC())
 		}
 		if filter.EndTime != nil {
 			query += " AND created_at <= ?"
-			args = append(args, *filter.EndTime)
+			args = append(args, filter.EndTime.UTC())
 		}
 	}
 
-	query += " ORDER BY created_at ASC"
+	// Events are returned in append order, independent of their timestamps.
+	query += " ORDER BY rowid ASC"
 
 	if filter != nil {
-		if filter.Limit > 0 {
-			query += " LIMIT ?"
-			args = append(args, filter.Limit)
-		}
-		if filter.Offset > 0 {
-			query += " OFFSET ?"
-			args = append(args, filter.Offset)
-		}
+		query, args = paginate(query, args, filter.Limit, filter.Offset)
 	}
 
-	rows, err := s.db.QueryContext(ctx, query, args...)
+	stmt, err := s.readStmt(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := stmt.QueryContext(ctx, args...)
 	if err != nil {
 		return nil, fmt.Errorf("query events: %w", err)
 	}
@@ -380,40 +667,10 @@ func (s *Service) GetEvents(ctx context.Context, sessionID string, filter *sessi
 
 	var events []session.Event
 	for rows.Next() {
-		var evt session.Event
-		var contentJSON, actionsJSON, toolIDsJSON, metadataJSON string
-		var createdAt time.Time
-
-		if err := rows.Scan(
-			&evt.ID, &evt.InvocationID, &evt.AgentID, &evt.Branch, &evt.Author,
-			&contentJSON, &evt.Reasoning, &actionsJSON, &toolIDsJSON, &metadataJSON, &createdAt,
-		); err != nil {
-			return nil, fmt.Errorf("scan event: %w", err)
-		}
-
-		evt.Timestamp = createdAt
-
-		if contentJSON != "" {
-			if err := json.Unmarshal([]byte(contentJSON), &evt.Content); err != nil {
-				return nil, fmt.Errorf("unmarshal content: %w", err)
-			}
-		}
-		if actionsJSON != "" {
-			if err := json.Unmarshal([]byte(actionsJSON), &evt.Actions); err != nil {
-				return nil, fmt.Errorf("unmarshal actions: %w", err)
-			}
-		}
-		if toolIDsJSON != "" {
-			if err := json.Unmarshal([]byte(toolIDsJSON), &evt.LongRunningToolIDs); err != nil {
-				return nil, fmt.Errorf("unmarshal tool ids: %w", err)
-			}
-		}
-		if metadataJSON != "" {
-			if err := json.Unmarshal([]byte(metadataJSON), &evt.Metadata); err != nil {
-				return nil, fmt.Errorf("unmarshal metadata: %w", err)
-			}
+		evt, err := scanEvent(rows)
+		if err != nil {
+			return nil, err
 		}
-
 		events = append(events, evt)
 	}
 
@@ -422,40 +679,145 @@ func (s *Service) GetEvents(ctx context.Context, sessionID string, filter *sessi
 
 // UpdateState updates session state.
 func (s *Service) UpdateState(ctx context.Context, sessionID string, delta map[string]any) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	tx, err := s.db.BeginTx(ctx, nil)
-	if err != nil {
-		return fmt.Errorf("begin transaction: %w", err)
-	}
-	defer func() { _ = tx.Rollback() }() // Will be a no-op if tx.Commit() succeeds
-
-	now := time.Now()
+	values := make(map[string]string, len(delta))
 	for key, value := range delta {
 		valueJSON, err := json.Marshal(value)
 		if err != nil {
 			return fmt.Errorf("marshal value: %w", err)
 		}
+		values[key] = string(valueJSON)
+	}
 
-		_, err = tx.ExecContext(ctx,
-			`INSERT INTO session_state (session_id, key, value, updated_at)
-			 VALUES (?, ?, ?, ?)
-			 ON CONFLICT (session_id, key) DO UPDATE SET value = ?, updated_at = ?`,
-			sessionID, key, string(valueJSON), now, string(valueJSON), now,
-		)
+	return s.write(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		if err := s.checkSession(ctx, tx, sessionID); err != nil {
+			return err
+		}
+
+		upsert, err := s.txStmt(ctx, tx, upsertStateQuery)
 		if err != nil {
-			return fmt.Errorf("upsert state: %w", err)
+			return err
+		}
+		now := time.Now()
+		for key, value := range values {
+			if _, err := upsert.ExecContext(ctx, sessionID, key, value, now); err != nil {
+				return fmt.Errorf("upsert state: %w", err)
+			}
 		}
+
+		// Update session timestamp
+		if err := s.txExec(ctx, tx, touchSessionQuery, now, sessionID); err != nil {
+			return fmt.Errorf("update session: %w", err)
+		}
+		return nil
+	})
+}
+
+// checkSession returns session.ErrSessionNotFound if the session does not exist.
+func (s *Service) checkSession(ctx context.Context, tx *sql.Tx, sessionID string) error {
+	stmt, err := s.txStmt(ctx, tx, sessionExistsQuery)
+	if err != nil {
+		return err
 	}
+	return sessionExists(stmt.QueryRowContext(ctx, sessionID))
+}
 
-	// Update session timestamp
-	_, err = tx.ExecContext(ctx, `UPDATE sessions SET updated_at = ? WHERE id = ?`, now, sessionID)
+func sessionExists(row *sql.Row) error {
+	var exists bool
+	err := row.Scan(&exists)
+	if errors.Is(err, sql.ErrNoRows) {
+		return session.ErrSessionNotFound
+	}
 	if err != nil {
-		return fmt.Errorf("update session: %w", err)
+		return fmt.Errorf("check session: %w", err)
+	}
+	return nil
+}
+
+// scanner is implemented by *sql.Row and *sql.Rows.
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// scanSession scans a row of the sessions table.
+func (s *Service) scanSession(row scanner) (*sqliteSession, error) {
+	var sess sqliteSession
+	var metadataJSON string
+	var createdAt, updatedAt time.Time
+
+	if err := row.Scan(&sess.id, &sess.appName, &sess.userID, &sess.agentID, &metadataJSON, &createdAt, &updatedAt); err != nil {
+		return nil, err
+	}
+
+	if metadataJSON != "" {
+		if err := json.Unmarshal([]byte(metadataJSON), &sess.metadata); err != nil {
+			return nil, fmt.Errorf("unmarshal metadata: %w", err)
+		}
 	}
 
-	return tx.Commit()
+	sess.service = s
+	sess.lastUpdateTime = updatedAt
+	return &sess, nil
+}
+
+// scanEvent scans a row selected with selectEventColumns.
+func scanEvent(row scanner) (session.Event, error) {
+	var evt session.Event
+	var contentJSON, actionsJSON, toolIDsJSON, metadataJSON, attachmentsJSON string
+	var createdAt time.Time
+
+	if err := row.Scan(
+		&evt.ID, &evt.InvocationID, &evt.AgentID, &evt.Branch, &evt.Author,
+		&contentJSON, &evt.Reasoning, &actionsJSON, &toolIDsJSON, &metadataJSON, &attachmentsJSON, &createdAt,
+	); err != nil {
+		return evt, fmt.Errorf("scan event: %w", err)
+	}
+
+	evt.Timestamp = createdAt
+
+	if contentJSON != "" {
+		if err := json.Unmarshal([]byte(contentJSON), &evt.Content); err != nil {
+			return evt, fmt.Errorf("unmarshal content: %w", err)
+		}
+	}
+	if actionsJSON != "" {
+		if err := json.Unmarshal([]byte(actionsJSON), &evt.Actions); err != nil {
+			return evt, fmt.Errorf("unmarshal actions: %w", err)
+		}
+	}
+	if toolIDsJSON != "" {
+		if err := json.Unmarshal([]byte(toolIDsJSON), &evt.LongRunningToolIDs); err != nil {
+			return evt, fmt.Errorf("unmarshal tool ids: %w", err)
+		}
+	}
+	if metadataJSON != "" {
+		if err := json.Unmarshal([]byte(metadataJSON), &evt.Metadata); err != nil {
+			return evt, fmt.Errorf("unmarshal metadata: %w", err)
+		}
+	}
+	if attachmentsJSON != "" {
+		if err := json.Unmarshal([]byte(attachmentsJSON), &evt.Attachments); err != nil {
+			return evt, fmt.Errorf("unmarshal attachments: %w", err)
+		}
+	}
+	return evt, nil
+}
+
+// paginate appends LIMIT/OFFSET clauses. SQLite requires a LIMIT before OFFSET,
+// so an offset without a limit uses LIMIT -1 (no limit).
+func paginate(query string, args []any, limit, offset int) (string, []any) {
+	if limit <= 0 && offset <= 0 {
+		return query, args
+	}
+	if limit <= 0 {
+		limit = -1
+	}
+	query += " LIMIT ?"
+	args = append(args, limit)
+	if offset > 0 {
+		query += " OFFSET ?"
+		args = append(args, offset)
+	}
+	return query, args
 }
 
 // sqliteSession implements session.Session
@@ -491,16 +853,15 @@ type sqliteState struct {
 }
 
 func (s *sqliteState) Get(key string) (any, error) {
-	s.service.mu.RLock()
-	defer s.service.mu.RUnlock()
+	ctx := context.Background()
+	stmt, err := s.service.readStmt(ctx, `SELECT value FROM session_state WHERE session_id = ? AND key = ?`)
+	if err != nil {
+		return nil, err
+	}
 
 	var valueJSON string
-	err := s.service.db.QueryRow(
-		`SELECT value FROM session_state WHERE session_id = ? AND key = ?`,
-		s.sessionID, key,
-	).Scan(&valueJSON)
-
-	if err == sql.ErrNoRows {
+	err = stmt.QueryRowContext(ctx, s.sessionID, key).Scan(&valueJSON)
+	if errors.Is(err, sql.ErrNoRows) {
 		return nil, session.ErrStateKeyNotExist
 	}
 	if err != nil {
@@ -516,60 +877,54 @@ func (s *sqliteState) Get(key string) (any, error) {
 }
 
 func (s *sqliteState) Set(key string, value any) error {
-	s.service.mu.Lock()
-	defer s.service.mu.Unlock()
-
 	valueJSON, err := json.Marshal(value)
 	if err != nil {
 		return err
 	}
 
-	_, err = s.service.db.Exec(
-		`INSERT INTO session_state (session_id, key, value, updated_at)
-		 VALUES (?, ?, ?, ?)
-		 ON CONFLICT (session_id, key) DO UPDATE SET value = ?, updated_at = ?`,
-		s.sessionID, key, string(valueJSON), time.Now(), string(valueJSON), time.Now(),
-	)
-	return err
+	return s.service.write(context.Background(), func(ctx context.Context, tx *sql.Tx) error {
+		return s.service.txExec(ctx, tx, upsertStateQuery, s.sessionID, key, string(valueJSON), time.Now())
+	})
 }
 
 func (s *sqliteState) Delete(key string) error {
-	s.service.mu.Lock()
-	defer s.service.mu.Unlock()
-
-	_, err := s.service.db.Exec(
-		`DELETE FROM session_state WHERE session_id = ? AND key = ?`,
-		s.sessionID, key,
-	)
-	return err
+	return s.service.write(context.Background(), func(ctx context.Context, tx *sql.Tx) error {
+		return s.service.txExec(ctx, tx, deleteStateKeyQuery, s.sessionID, key)
+	})
 }
 
 func (s *sqliteState) All() iter.Seq2[string, any] {
 	return func(yield func(string, any) bool) {
-		s.service.mu.RLock()
-		defer s.service.mu.RUnlock()
-
-		rows, err := s.service.db.Query(
-			`SELECT key, value FROM session_state WHERE session_id = ?`,
-			s.sessionID,
-		)
+		ctx := context.Background()
+		stmt, err := s.service.readStmt(ctx, `SELECT key, value FROM session_state WHERE session_id = ?`)
+		if err != nil {
+			return
+		}
+		rows, err := stmt.QueryContext(ctx, s.sessionID)
 		if err != nil {
 			return
 		}
-		defer func() { _ = rows.Close() }()
 
+		// Read everything before yielding so a slow consumer doesn't hold a read connection.
+		var keys []string
+		var values []any
 		for rows.Next() {
 			var key, valueJSON string
 			if err := rows.Scan(&key, &valueJSON); err != nil {
-				return
+				break
 			}
 
 			var value any
 			if err := json.Unmarshal([]byte(valueJSON), &value); err != nil {
-				return
+				break
 			}
+			keys = append(keys, key)
+			values = append(values, value)
+		}
+		_ = rows.Close()
 
-			if !yield(key, value) {
+		for i, key := range keys {
+			if !yield(key, values[i]) {
 				return
 			}
 		}
@@ -577,14 +932,14 @@ func (s *sqliteState) All() iter.Seq2[string, any] {
 }
 
 func (s *sqliteState) Has(key string) bool {
-	s.service.mu.RLock()
-	defer s.service.mu.RUnlock()
+	ctx := context.Background()
+	stmt, err := s.service.readStmt(ctx, `SELECT 1 FROM session_state WHERE session_id = ? AND key = ?`)
+	if err != nil {
+		return false
+	}
 
 	var exists bool
-	_ = s.service.db.QueryRow(
-		`SELECT 1 FROM session_state WHERE session_id = ? AND key = ?`,
-		s.sessionID, key,
-	).Scan(&exists) // Ignore error, returns false on error
+	_ = stmt.QueryRowContext(ctx, s.sessionID, key).Scan(&exists) // Ignore error, returns false on error
 	return exists
 }
 
@@ -594,30 +949,90 @@ type sqliteEvents struct {
 	sessionID string
 }
 
+// All streams the events a page at a time instead of loading the whole history.
 func (e *sqliteEvents) All() iter.Seq[*session.Event] {
 	return func(yield func(*session.Event) bool) {
-		events, err := e.service.GetEvents(context.Background(), e.sessionID, nil)
+		cursor := ""
+		for {
+			page, err := e.EventsPage(cursor, session.DefaultEventsPageSize)
+			if err != nil {
+				return
+			}
+			for i := range page.Events {
+				if !yield(&page.Events[i]) {
+					return
+				}
+			}
+			if page.NextCursor == "" {
+				return
+			}
+			cursor = page.NextCursor
+		}
+	}
+}
+
+// EventsPage returns up to limit events after the event with ID after, in
+// append order. The cursor is resolved to its rowid so each page is an index
+// range scan, however deep into the history it is.
+func (e *sqliteEvents) EventsPage(after string, limit int) (*session.EventPage, error) {
+	ctx := context.Background()
+	if limit <= 0 {
+		limit = session.DefaultEventsPageSize
+	}
+
+	var afterRowID int64
+	if after != "" {
+		stmt, err := e.service.readStmt(ctx, `SELECT rowid FROM events WHERE id = ? AND session_id = ?`)
 		if err != nil {
-			return
+			return nil, err
+		}
+		err = stmt.QueryRowContext(ctx, after, e.sessionID).Scan(&afterRowID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, session.ErrInvalidCursor
 		}
+		if err != nil {
+			return nil, fmt.Errorf("query cursor: %w", err)
+		}
+	}
 
-		for i := range events {
-			if !yield(&events[i]) {
-				return
-			}
+	stmt, err := e.service.readStmt(ctx, selectEventColumns+` WHERE session_id = ? AND rowid > ? ORDER BY rowid ASC LIMIT ?`)
+	if err != nil {
+		return nil, err
+	}
+	// Fetch one extra event to learn whether another page exists
+	rows, err := stmt.QueryContext(ctx, e.sessionID, afterRowID, limit+1)
+	if err != nil {
+		return nil, fmt.Errorf("query events: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	page := &session.EventPage{Events: make([]session.Event, 0, min(limit, session.DefaultEventsPageSize))}
+	for rows.Next() {
+		evt, err := scanEvent(rows)
+		if err != nil {
+			return nil, err
 		}
+		page.Events = append(page.Events, evt)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(page.Events) > limit {
+		page.Events = page.Events[:limit]
+		page.NextCursor = page.Events[limit-1].ID
+	}
+	return page, nil
 }
 
 func (e *sqliteEvents) Len() int {
-	e.service.mu.RLock()
-	defer e.service.mu.RUnlock()
+	ctx := context.Background()
+	stmt, err := e.service.readStmt(ctx, `SELECT COUNT(*) FROM events WHERE session_id = ?`)
+	if err != nil {
+		return 0
+	}
 
 	var count int
-	_ = e.service.db.QueryRow(
-		`SELECT COUNT(*) FROM events WHERE session_id = ?`,
-		e.sessionID,
-	).Scan(&count) // Ignore error, returns 0 on error
+	_ = stmt.QueryRowContext(ctx, e.sessionID).Scan(&count) // Ignore error, returns 0 on error
 	return count
 }
 
@@ -633,49 +1048,31 @@ func (e *sqliteEvents) At(i int) *session.Event {
 }
 
 func (e *sqliteEvents) Filter(predicate func(*session.Event) bool) []session.Event {
-	events, err := e.service.GetEvents(context.Background(), e.sessionID, nil)
-	if err != nil {
-		return nil
-	}
-
 	var result []session.Event
-	for _, evt := range events {
-		if predicate(&evt) {
-			result = append(result, evt)
+	for evt := range e.All() {
+		if predicate(evt) {
+			result = append(result, *evt)
 		}
 	}
 	return result
 }
 
 func (e *sqliteEvents) Last() *session.Event {
-	e.service.mu.RLock()
-	defer e.service.mu.RUnlock()
-
-	var evt session.Event
-	var contentJSON, actionsJSON, toolIDsJSON, metadataJSON string
-	var createdAt time.Time
-
-	err := e.service.db.QueryRow(
-		`SELECT id, invocation_id, agent_id, branch, author, content, reasoning, actions, long_running_tool_ids, metadata, created_at
-		 FROM events WHERE session_id = ? ORDER BY created_at DESC LIMIT 1`,
-		e.sessionID,
-	).Scan(
-		&evt.ID, &evt.InvocationID, &evt.AgentID, &evt.Branch, &evt.Author,
-		&contentJSON, &evt.Reasoning, &actionsJSON, &toolIDsJSON, &metadataJSON, &createdAt,
-	)
-
+	ctx := context.Background()
+	stmt, err := e.service.readStmt(ctx, selectEventColumns+` WHERE session_id = ? ORDER BY rowid DESC LIMIT 1`)
 	if err != nil {
 		return nil
 	}
 
-	evt.Timestamp = createdAt
-	_ = json.Unmarshal([]byte(contentJSON), &evt.Content)
-	_ = json.Unmarshal([]byte(actionsJSON), &evt.Actions)
-	_ = json.Unmarshal([]byte(toolIDsJSON), &evt.LongRunningToolIDs)
-	_ = json.Unmarshal([]byte(metadataJSON), &evt.Metadata)
-
+	evt, err := scanEvent(stmt.QueryRowContext(ctx, e.sessionID))
+	if err != nil {
+		return nil
+	}
 	return &evt
 }
 
 // Verify Service implements session.Service
-var _ session.Service = (*Service)(nil)
+var (
+	_ session.Service = (*Service)(nil)
+	_ session.Locator = (*Service)(nil)
+)
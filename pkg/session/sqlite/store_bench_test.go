@@ -0,0 +1,135 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/astercloud/aster/pkg/session"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+func newBenchService(b *testing.B, opts Options) (*Service, []string) {
+	b.Helper()
+	svc, err := NewWithOptions(filepath.Join(b.TempDir(), "bench.db"), opts)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() { _ = svc.Close() })
+
+	// One session per simulated agent.
+	ids := make([]string, 8)
+	for i := range ids {
+		sess, err := svc.Create(context.Background(), &session.CreateRequest{
+			AppName: "bench",
+			UserID:  "user",
+			AgentID: fmt.Sprintf("agent-%d", i),
+		})
+		if err != nil {
+			b.Fatal(err)
+		}
+		ids[i] = sess.ID()
+	}
+	return svc, ids
+}
+
+func benchEvent() *session.Event {
+	return &session.Event{
+		Author:  "assistant",
+		Content: types.Message{Role: types.RoleAssistant, Content: "benchmark event payload"},
+	}
+}
+
+// BenchmarkAppendEvent_Parallel measures concurrent writers across sessions,
+// with and without group commit.
+func BenchmarkAppendEvent_Parallel(b *testing.B) {
+	for _, bc := range []struct {
+		name  string
+		batch int
+	}{
+		{"NoBatching", 1},
+		{"GroupCommit", 0},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			svc, ids := newBenchService(b, Options{MaxBatchSize: bc.batch})
+			ctx := context.Background()
+			var next atomic.Int64
+			b.SetParallelism(4)
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				id := ids[next.Add(1)%int64(len(ids))]
+				for pb.Next() {
+					if err := svc.AppendEvent(ctx, id, benchEvent()); err != nil {
+						b.Error(err)
+						return
+					}
+				}
+			})
+		})
+	}
+}
+
+// BenchmarkAppendEvents compares inserting 32 events one by one with one batched call.
+func BenchmarkAppendEvents(b *testing.B) {
+	const n = 32
+	b.Run("Single", func(b *testing.B) {
+		svc, ids := newBenchService(b, Options{})
+		ctx := context.Background()
+		for b.Loop() {
+			for range n {
+				if err := svc.AppendEvent(ctx, ids[0], benchEvent()); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+	b.Run("Batch", func(b *testing.B) {
+		svc, ids := newBenchService(b, Options{})
+		ctx := context.Background()
+		for b.Loop() {
+			events := make([]*session.Event, n)
+			for i := range events {
+				events[i] = benchEvent()
+			}
+			if err := svc.AppendEvents(ctx, ids[0], events); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// BenchmarkGetEvents_DuringWrites measures read latency while other sessions are being written.
+func BenchmarkGetEvents_DuringWrites(b *testing.B) {
+	svc, ids := newBenchService(b, Options{})
+	ctx := context.Background()
+	for range 100 {
+		if err := svc.AppendEvent(ctx, ids[0], benchEvent()); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; ; i++ {
+			select {
+			case <-stop:
+				return
+			default:
+				_ = svc.AppendEvent(ctx, ids[1+i%(len(ids)-1)], benchEvent())
+			}
+		}
+	}()
+
+	for b.Loop() {
+		if _, err := svc.GetEvents(ctx, ids[0], &session.EventFilter{Limit: 50}); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.StopTimer()
+	close(stop)
+	<-done
+}
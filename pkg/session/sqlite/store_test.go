@@ -2,9 +2,12 @@ package sqlite
 
 import (
 	"context"
+	"database/sql"
 	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"sync"
 	"testing"
 	"time"
 
@@ -484,3 +487,201 @@ func TestDatabaseFileCreation(t *testing.T) {
 		t.Error("Database file should be created")
 	}
 }
+
+func TestConcurrentWriters(t *testing.T) {
+	ctx := context.Background()
+	svc, err := NewWithOptions(filepath.Join(t.TempDir(), "concurrent.db"), Options{ReadConns: 2, MaxBatchSize: 16})
+	if err != nil {
+		t.Fatalf("NewWithOptions failed: %v", err)
+	}
+
+	const agents, perAgent = 6, 20
+	ids := make([]string, agents)
+	for i := range ids {
+		sess, err := svc.Create(ctx, &session.CreateRequest{AppName: "app", UserID: "user", AgentID: fmt.Sprintf("agent-%d", i)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids[i] = sess.ID()
+	}
+
+	var wg sync.WaitGroup
+	for i, id := range ids {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := range perAgent {
+				if err := svc.AppendEvent(ctx, id, &session.Event{Author: "assistant", Content: types.Message{Content: fmt.Sprint(n)}}); err != nil {
+					t.Errorf("AppendEvent failed: %v", err)
+				}
+				// Metadata merges run on the writer, so no key is lost.
+				if err := svc.Update(ctx, &session.UpdateRequest{SessionID: ids[0], Metadata: map[string]any{fmt.Sprintf("k%d-%d", i, n): n}}); err != nil {
+					t.Errorf("Update failed: %v", err)
+				}
+				if _, err := svc.GetEvents(ctx, id, nil); err != nil {
+					t.Errorf("GetEvents failed: %v", err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, id := range ids {
+		events, err := svc.GetEvents(ctx, id, nil)
+		if err != nil || len(events) != perAgent {
+			t.Fatalf("GetEvents() = %d events, %v", len(events), err)
+		}
+		for n, evt := range events {
+			if evt.Content.Content != fmt.Sprint(n) {
+				t.Fatalf("event %d = %q, want append order", n, evt.Content.Content)
+			}
+		}
+	}
+	sess, err := svc.Get(ctx, &session.GetRequest{AppName: "app", UserID: "user", SessionID: ids[0]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := len(sess.Metadata()); got != agents*perAgent {
+		t.Errorf("metadata keys = %d, want %d", got, agents*perAgent)
+	}
+
+	// A failing write is rolled back without affecting the rest of its batch.
+	err = svc.AppendEvents(ctx, ids[0], []*session.Event{{ID: "dup"}, {ID: "dup"}})
+	if err == nil {
+		t.Error("AppendEvents with duplicate IDs should fail")
+	}
+	if events, _ := svc.GetEvents(ctx, ids[0], nil); len(events) != perAgent {
+		t.Errorf("failed AppendEvents left %d events, want %d", len(events), perAgent)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := svc.AppendEvent(cancelled, ids[0], &session.Event{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("AppendEvent with cancelled context = %v", err)
+	}
+
+	if err := svc.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if err := svc.AppendEvent(ctx, ids[0], &session.Event{}); !errors.Is(err, ErrClosed) {
+		t.Errorf("AppendEvent after Close = %v, want ErrClosed", err)
+	}
+}
+
+func TestWatchOtherWriter(t *testing.T) {
+	ctx := context.Background()
+	dbPath := filepath.Join(t.TempDir(), "watch.db")
+
+	// Two services on one file stand in for a server process and a desktop app
+	writer, err := New(dbPath)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	defer func() { _ = writer.Close() }()
+	reader, err := NewWithOptions(dbPath, Options{WatchInterval: 20 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("NewWithOptions failed: %v", err)
+	}
+
+	sess, err := writer.Create(ctx, &session.CreateRequest{AppName: "app", UserID: "user", AgentID: "agent-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	events, err := reader.Watch(ctx, sess.ID())
+	if err != nil {
+		t.Fatalf("Watch failed: %v", err)
+	}
+
+	for _, id := range []string{"e1", "e2"} {
+		if err := writer.AppendEvent(ctx, sess.ID(), &session.Event{ID: id, Author: "assistant"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, want := range []string{"e1", "e2"} {
+		select {
+		case evt := <-events:
+			if evt.ID != want {
+				t.Errorf("received %q, want %q", evt.ID, want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for %q", want)
+		}
+	}
+
+	// Closing the service ends its watches
+	if err := reader.Close(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case _, ok := <-events:
+		if ok {
+			t.Error("unexpected event after Close")
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("Watch channel not closed by Close")
+	}
+}
+
+func TestAttachmentStorage(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "attachments.db")
+	ctx := context.Background()
+
+	// A database created before attachments were supported gains the column on open.
+	old, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := old.Exec(`CREATE TABLE events (
+		id TEXT PRIMARY KEY, session_id TEXT NOT NULL, invocation_id TEXT, agent_id TEXT, branch TEXT, author TEXT,
+		content TEXT, reasoning TEXT, actions TEXT, long_running_tool_ids TEXT, metadata TEXT,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP)`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := old.Exec(`INSERT INTO events (id, session_id, author) VALUES ('legacy', 'legacy-session', 'user')`); err != nil {
+		t.Fatal(err)
+	}
+	_ = old.Close()
+
+	svc, err := New(dbPath)
+	if err != nil {
+		t.Fatalf("New on an old database: %v", err)
+	}
+	defer func() { _ = svc.Close() }()
+
+	data := []byte("shared image bytes")
+	var ids []string
+	for range 2 {
+		sess, err := svc.Create(ctx, &session.CreateRequest{AppName: "app", UserID: "user", AgentID: "agent"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, sess.ID())
+		err = svc.AppendEvent(ctx, sess.ID(), &session.Event{Author: "user", Attachments: []session.Attachment{{Name: "img.png", Data: data}}})
+		if err != nil {
+			t.Fatalf("AppendEvent: %v", err)
+		}
+	}
+
+	countBlobs := func() int {
+		var n int
+		if err := svc.readDB.QueryRow(`SELECT COUNT(*) FROM attachments`).Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+	if n := countBlobs(); n != 1 {
+		t.Errorf("stored contents = %d, want 1 for identical attachments", n)
+	}
+	if err := svc.Delete(ctx, ids[0]); err != nil {
+		t.Fatal(err)
+	}
+	if n := countBlobs(); n != 1 {
+		t.Errorf("stored contents = %d after deleting one of two sessions", n)
+	}
+	if err := svc.Delete(ctx, ids[1]); err != nil {
+		t.Fatal(err)
+	}
+	if n := countBlobs(); n != 0 {
+		t.Errorf("stored contents = %d after deleting every session, want 0", n)
+	}
+}
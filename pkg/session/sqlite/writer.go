@@ -0,0 +1,210 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrClosed is returned by writes issued after Close.
+var ErrClosed = errors.New("sqlite: session service closed")
+
+// writeFunc performs one logical write inside the batch transaction.
+type writeFunc func(ctx context.Context, tx *sql.Tx) error
+
+// writeRequest is a write waiting in the queue.
+type writeRequest struct {
+	ctx  context.Context
+	fn   writeFunc
+	done chan error
+}
+
+// write queues fn for the write loop and waits until its batch has committed.
+// Once queued, the write is always carried out to completion so the returned
+// error reflects whether it was applied; a context cancelled while the request
+// is still queued skips it.
+func (s *Service) write(ctx context.Context, fn writeFunc) error {
+	req := &writeRequest{ctx: ctx, fn: fn, done: make(chan error, 1)}
+
+	s.closeMu.RLock()
+	if s.closed {
+		s.closeMu.RUnlock()
+		return ErrClosed
+	}
+	select {
+	case s.writes <- req:
+	case <-ctx.Done():
+		s.closeMu.RUnlock()
+		return ctx.Err()
+	}
+	s.closeMu.RUnlock()
+
+	return <-req.done
+}
+
+// writeLoop owns the write connection. It drains the queue into batches and
+// commits each batch in a single transaction (group commit), so concurrent
+// writers share one fsync instead of queueing on a lock.
+func (s *Service) writeLoop() {
+	defer close(s.loopDone)
+	for {
+		select {
+		case req := <-s.writes:
+			s.commitBatch(s.collectBatch(req))
+		case <-s.quit:
+			// Close waits for in-flight submissions, so anything still queued was accepted.
+			for {
+				select {
+				case req := <-s.writes:
+					s.commitBatch(s.collectBatch(req))
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// collectBatch takes whatever else is already queued, up to maxBatch requests.
+func (s *Service) collectBatch(first *writeRequest) []*writeRequest {
+	batch := []*writeRequest{first}
+	for len(batch) < s.maxBatch {
+		select {
+		case req := <-s.writes:
+			batch = append(batch, req)
+		default:
+			return batch
+		}
+	}
+	return batch
+}
+
+// commitBatch runs each request in its own savepoint so a failing request does
+// not affect the others, then commits the batch.
+func (s *Service) commitBatch(batch []*writeRequest) {
+	// The transaction is not tied to any caller's context: one caller giving up
+	// must not roll back writes that other callers are waiting on.
+	ctx := context.Background()
+	errs := make([]error, len(batch))
+
+	tx, err := s.writeDB.BeginTx(ctx, nil)
+	if err != nil {
+		err = fmt.Errorf("begin transaction: %w", err)
+		for _, req := range batch {
+			req.done <- err
+		}
+		return
+	}
+	for i, req := range batch {
+		if err := req.ctx.Err(); err != nil {
+			errs[i] = err
+			continue
+		}
+		errs[i] = runInSavepoint(ctx, tx, req.fn)
+	}
+	if err := tx.Commit(); err != nil {
+		err = fmt.Errorf("commit: %w", err)
+		for i := range errs {
+			if errs[i] == nil {
+				errs[i] = err
+			}
+		}
+	}
+	for i, req := range batch {
+		req.done <- errs[i]
+	}
+}
+
+func runInSavepoint(ctx context.Context, tx *sql.Tx, fn writeFunc) error {
+	if _, err := tx.ExecContext(ctx, `SAVEPOINT write_request`); err != nil {
+		return fmt.Errorf("savepoint: %w", err)
+	}
+	if err := fn(ctx, tx); err != nil {
+		if _, rbErr := tx.ExecContext(ctx, `ROLLBACK TO write_request`); rbErr != nil {
+			return errors.Join(err, fmt.Errorf("rollback to savepoint: %w", rbErr))
+		}
+		_, _ = tx.ExecContext(ctx, `RELEASE write_request`)
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `RELEASE write_request`); err != nil {
+		return fmt.Errorf("release savepoint: %w", err)
+	}
+	return nil
+}
+
+// stmtCache caches prepared statements per database handle. database/sql
+// re-prepares a cached statement transparently on each pool connection.
+type stmtCache struct {
+	mu    sync.Mutex
+	stmts map[stmtKey]*sql.Stmt
+}
+
+type stmtKey struct {
+	db    *sql.DB
+	query string
+}
+
+func (c *stmtCache) get(ctx context.Context, db *sql.DB, query string) (*sql.Stmt, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	key := stmtKey{db: db, query: query}
+	if stmt, ok := c.stmts[key]; ok {
+		return stmt, nil
+	}
+	stmt, err := db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("prepare statement: %w", err)
+	}
+	if c.stmts == nil {
+		c.stmts = make(map[stmtKey]*sql.Stmt)
+	}
+	c.stmts[key] = stmt
+	return stmt, nil
+}
+
+func (c *stmtCache) lookup(db *sql.DB, query string) (*sql.Stmt, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stmt, ok := c.stmts[stmtKey{db: db, query: query}]
+	return stmt, ok
+}
+
+func (c *stmtCache) close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var errs []error
+	for _, stmt := range c.stmts {
+		errs = append(errs, stmt.Close())
+	}
+	c.stmts = nil
+	return errors.Join(errs...)
+}
+
+// readStmt returns a cached statement on the read pool.
+func (s *Service) readStmt(ctx context.Context, query string) (*sql.Stmt, error) {
+	return s.stmts.get(ctx, s.readDB, query)
+}
+
+// txStmt returns a cached write statement bound to tx. The writer's
+// transaction holds the only write connection, so preparing on writeDB from
+// here would block forever; queries missing from writeQueries are prepared on
+// the transaction instead.
+func (s *Service) txStmt(ctx context.Context, tx *sql.Tx, query string) (*sql.Stmt, error) {
+	stmt, ok := s.stmts.lookup(s.writeDB, query)
+	if !ok {
+		return tx.PrepareContext(ctx, query)
+	}
+	return tx.StmtContext(ctx, stmt), nil
+}
+
+// txExec executes a cached write statement inside tx.
+func (s *Service) txExec(ctx context.Context, tx *sql.Tx, query string, args ...any) error {
+	stmt, err := s.txStmt(ctx, tx, query)
+	if err != nil {
+		return err
+	}
+	_, err = stmt.ExecContext(ctx, args...)
+	return err
+}
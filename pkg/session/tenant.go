@@ -0,0 +1,159 @@
+package session
+
+import (
+	"context"
+	"strings"
+
+	"github.com/astercloud/aster/pkg/multitenancy"
+)
+
+// Locator 可按会话 ID 查询会话归属的 Service
+//
+// TenantService 用它校验按 SessionID 访问的会话是否属于当前租户
+type Locator interface {
+	// AppNameOf 返回会话的 AppName，会话不存在时返回 ErrSessionNotFound
+	AppNameOf(ctx context.Context, sessionID string) (string, error)
+}
+
+// TenantService 多租户 Session 服务
+//
+// 根据 context 中的租户 ID 将 AppName 限定到 "<tenant>/<app>" 命名空间，
+// 不同租户即使使用相同的 AppName/UserID 也无法看到彼此的会话。
+// 按 SessionID 操作的方法先通过 Locator 校验会话属于当前租户，不属于时返回 ErrSessionNotFound；
+// 底层 Service 未实现 Locator 时，带租户的按 ID 访问一律拒绝。
+type TenantService struct {
+	inner Service
+}
+
+// NewTenantService 创建多租户 Session 服务
+func NewTenantService(inner Service) *TenantService {
+	return &TenantService{inner: inner}
+}
+
+func scopedAppName(ctx context.Context, appName string) string {
+	tenantID := multitenancy.GetTenantIDOrDefault(ctx, "")
+	if tenantID == "" {
+		return appName
+	}
+	return tenantID + "/" + appName
+}
+
+// checkOwner 校验 sessionID 属于 context 中的租户，没有租户时不做限制
+func (s *TenantService) checkOwner(ctx context.Context, sessionID string) error {
+	tenantID := multitenancy.GetTenantIDOrDefault(ctx, "")
+	if tenantID == "" {
+		return nil
+	}
+	locator, ok := s.inner.(Locator)
+	if !ok {
+		return ErrSessionNotFound
+	}
+	appName, err := locator.AppNameOf(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(appName, tenantID+"/") {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+func (s *TenantService) Create(ctx context.Context, req *CreateRequest) (Session, error) {
+	scoped := *req
+	scoped.AppName = scopedAppName(ctx, req.AppName)
+	if tenantID, err := multitenancy.GetTenantID(ctx); err == nil {
+		metadata := make(map[string]any, len(req.Metadata)+1)
+		for k, v := range req.Metadata {
+			metadata[k] = v
+		}
+		metadata["tenant_id"] = tenantID
+		scoped.Metadata = metadata
+	}
+	return s.inner.Create(ctx, &scoped)
+}
+
+func (s *TenantService) Get(ctx context.Context, req *GetRequest) (Session, error) {
+	scoped := *req
+	scoped.AppName = scopedAppName(ctx, req.AppName)
+	return s.inner.Get(ctx, &scoped)
+}
+
+func (s *TenantService) Update(ctx context.Context, req *UpdateRequest) error {
+	if err := s.checkOwner(ctx, req.SessionID); err != nil {
+		return err
+	}
+	return s.inner.Update(ctx, req)
+}
+
+func (s *TenantService) Delete(ctx context.Context, sessionID string) error {
+	if err := s.checkOwner(ctx, sessionID); err != nil {
+		return err
+	}
+	return s.inner.Delete(ctx, sessionID)
+}
+
+func (s *TenantService) List(ctx context.Context, req *ListRequest) ([]*Session, error) {
+	scoped := *req
+	scoped.AppName = scopedAppName(ctx, req.AppName)
+	return s.inner.List(ctx, &scoped)
+}
+
+func (s *TenantService) AppendEvent(ctx context.Context, sessionID string, event *Event) error {
+	if err := s.checkOwner(ctx, sessionID); err != nil {
+		return err
+	}
+	return s.inner.AppendEvent(ctx, sessionID, event)
+}
+
+func (s *TenantService) GetEvents(ctx context.Context, sessionID string, filter *EventFilter) ([]Event, error) {
+	if err := s.checkOwner(ctx, sessionID); err != nil {
+		return nil, err
+	}
+	return s.inner.GetEvents(ctx, sessionID, filter)
+}
+
+func (s *TenantService) UpdateState(ctx context.Context, sessionID string, delta map[string]any) error {
+	if err := s.checkOwner(ctx, sessionID); err != nil {
+		return err
+	}
+	return s.inner.UpdateState(ctx, sessionID, delta)
+}
+
+func (s *TenantService) Watch(ctx context.Context, sessionID string) (<-chan Event, error) {
+	if err := s.checkOwner(ctx, sessionID); err != nil {
+		return nil, err
+	}
+	return s.inner.Watch(ctx, sessionID)
+}
+
+func (s *TenantService) GetAttachment(ctx context.Context, sessionID, hash string) ([]byte, error) {
+	if err := s.checkOwner(ctx, sessionID); err != nil {
+		return nil, err
+	}
+	return s.inner.GetAttachment(ctx, sessionID, hash)
+}
+
+// AppNameOf 返回去掉租户前缀的 AppName，会话不属于当前租户时返回 ErrSessionNotFound
+func (s *TenantService) AppNameOf(ctx context.Context, sessionID string) (string, error) {
+	if err := s.checkOwner(ctx, sessionID); err != nil {
+		return "", err
+	}
+	locator, ok := s.inner.(Locator)
+	if !ok {
+		return "", ErrSessionNotFound
+	}
+	appName, err := locator.AppNameOf(ctx, sessionID)
+	if err != nil {
+		return "", err
+	}
+	if tenantID := multitenancy.GetTenantIDOrDefault(ctx, ""); tenantID != "" {
+		appName = strings.TrimPrefix(appName, tenantID+"/")
+	}
+	return appName, nil
+}
+
+var (
+	_ Service = (*TenantService)(nil)
+	_ Locator = (*TenantService)(nil)
+	_ Locator = (*InMemoryService)(nil)
+)
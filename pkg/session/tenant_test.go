@@ -0,0 +1,44 @@
+package session
+
+import (
+	"context"
+	"testing"
+
+	"github.com/astercloud/aster/pkg/multitenancy"
+	"github.com/astercloud/aster/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTenantService_ChecksOwnerByID(t *testing.T) {
+	service := NewTenantService(NewInMemoryService())
+	acme := multitenancy.WithTenantID(context.Background(), "acme")
+	globex := multitenancy.WithTenantID(context.Background(), "globex")
+
+	sess, err := service.Create(acme, &CreateRequest{AppName: "app", UserID: "u1"})
+	require.NoError(t, err)
+	id := sess.ID()
+	event := &Event{ID: "evt-1", Content: types.Message{Role: types.MessageRoleUser, Content: "hi"}}
+
+	// 同一租户可按 ID 访问
+	require.NoError(t, service.AppendEvent(acme, id, event))
+	events, err := service.GetEvents(acme, id, nil)
+	require.NoError(t, err)
+	assert.Len(t, events, 1)
+	appName, err := service.AppNameOf(acme, id)
+	require.NoError(t, err)
+	assert.Equal(t, "app", appName)
+
+	// 其他租户按 ID 访问一律视为不存在
+	assert.ErrorIs(t, service.AppendEvent(globex, id, event), ErrSessionNotFound)
+	_, err = service.GetEvents(globex, id, nil)
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+	assert.ErrorIs(t, service.UpdateState(globex, id, map[string]any{"k": "v"}), ErrSessionNotFound)
+	assert.ErrorIs(t, service.Update(globex, &UpdateRequest{SessionID: id}), ErrSessionNotFound)
+	_, err = service.Watch(globex, id)
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+	assert.ErrorIs(t, service.Delete(globex, id), ErrSessionNotFound)
+
+	_, err = service.Get(acme, &GetRequest{AppName: "app", UserID: "u1", SessionID: id})
+	require.NoError(t, err, "cross-tenant delete must not remove the session")
+}
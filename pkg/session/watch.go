@@ -0,0 +1,129 @@
+package session
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// watchBuffer Watch 返回通道的缓冲大小
+const watchBuffer = 16
+
+// EventWatchers 管理各会话的 Watch 订阅，供后端实现 Service.Watch
+//
+// 订阅方只持有一个唤醒信号和一个游标，收到信号后通过 Events.EventsPage 读取游标之后的新事件，
+// 因此通知不会阻塞写入方，也不会丢失或重复事件。零值可用。
+type EventWatchers struct {
+	mu    sync.Mutex
+	wakes map[string]map[chan struct{}]struct{}
+}
+
+// Follow 从 events 当前最后一个事件之后开始，按追加顺序把新事件发送到返回的通道
+// 收到 Notify 或每隔 poll（<= 0 表示不轮询）读取一次；poll 用于发现其他进程写入的事件
+// ctx 结束、会话被 Close 或读取失败（如会话已被其他进程删除）时关闭通道
+func (w *EventWatchers) Follow(ctx context.Context, sessionID string, events Events, poll time.Duration) <-chan Event {
+	// 先注册再确定起点，起点之后追加的事件一定会触发唤醒
+	wake := make(chan struct{}, 1)
+	w.mu.Lock()
+	if w.wakes == nil {
+		w.wakes = make(map[string]map[chan struct{}]struct{})
+	}
+	if w.wakes[sessionID] == nil {
+		w.wakes[sessionID] = make(map[chan struct{}]struct{})
+	}
+	w.wakes[sessionID][wake] = struct{}{}
+	w.mu.Unlock()
+
+	cursor := ""
+	if last := events.Last(); last != nil {
+		cursor = last.ID
+	}
+
+	out := make(chan Event, watchBuffer)
+	go func() {
+		defer close(out)
+		defer w.remove(sessionID, wake)
+
+		var tick <-chan time.Time
+		if poll > 0 {
+			ticker := time.NewTicker(poll)
+			defer ticker.Stop()
+			tick = ticker.C
+		}
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case _, ok := <-wake:
+				if !ok {
+					return
+				}
+			case <-tick:
+			}
+			for {
+				page, err := events.EventsPage(cursor, DefaultEventsPageSize)
+				if err != nil {
+					return
+				}
+				for _, evt := range page.Events {
+					select {
+					case out <- evt:
+					case <-ctx.Done():
+						return
+					}
+					cursor = evt.ID
+				}
+				if page.NextCursor == "" {
+					break
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// Notify 唤醒会话的所有订阅方，在事件提交后调用，不会阻塞
+func (w *EventWatchers) Notify(sessionID string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for wake := range w.wakes[sessionID] {
+		select {
+		case wake <- struct{}{}:
+		default: // 已有待处理的唤醒，订阅方会一并读取
+		}
+	}
+}
+
+// Close 结束会话的所有订阅，在会话删除时调用
+func (w *EventWatchers) Close(sessionID string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for wake := range w.wakes[sessionID] {
+		close(wake)
+	}
+	delete(w.wakes, sessionID)
+}
+
+// CloseAll 结束所有订阅，在服务关闭时调用
+func (w *EventWatchers) CloseAll() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, wakes := range w.wakes {
+		for wake := range wakes {
+			close(wake)
+		}
+	}
+	w.wakes = nil
+}
+
+func (w *EventWatchers) remove(sessionID string, wake chan struct{}) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, ok := w.wakes[sessionID][wake]; !ok {
+		return // 已被 Close
+	}
+	delete(w.wakes[sessionID], wake)
+	if len(w.wakes[sessionID]) == 0 {
+		delete(w.wakes, sessionID)
+	}
+}
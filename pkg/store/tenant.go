@@ -0,0 +1,139 @@
+package store
+
+import (
+	"context"
+	"strings"
+
+	"github.com/astercloud/aster/pkg/multitenancy"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+// TenantSeparator 租户前缀与原始 key 之间的分隔符
+const TenantSeparator = "__"
+
+// TenantStore 多租户命名空间存储
+//
+// 包装任意 Store 实现，根据 context 中的租户 ID（multitenancy.WithTenantID）
+// 为 AgentID 与 collection 添加 "<tenant>__" 前缀，实现同一后端内的数据隔离。
+// context 中没有租户 ID 时直接透传，兼容单租户数据。
+type TenantStore struct {
+	inner Store
+}
+
+// NewTenantStore 创建多租户存储
+func NewTenantStore(inner Store) *TenantStore {
+	return &TenantStore{inner: inner}
+}
+
+// Unwrap 返回被包装的底层存储
+func (s *TenantStore) Unwrap() Store {
+	return s.inner
+}
+
+// TenantKey 返回租户命名空间下的 key
+func TenantKey(tenantID, key string) string {
+	if tenantID == "" {
+		return key
+	}
+	return tenantID + TenantSeparator + key
+}
+
+func (s *TenantStore) scope(ctx context.Context, key string) string {
+	return TenantKey(multitenancy.GetTenantIDOrDefault(ctx, ""), key)
+}
+
+func (s *TenantStore) SaveMessages(ctx context.Context, agentID string, messages []types.Message) error {
+	return s.inner.SaveMessages(ctx, s.scope(ctx, agentID), messages)
+}
+
+func (s *TenantStore) LoadMessages(ctx context.Context, agentID string) ([]types.Message, error) {
+	return s.inner.LoadMessages(ctx, s.scope(ctx, agentID))
+}
+
+func (s *TenantStore) TrimMessages(ctx context.Context, agentID string, maxMessages int) error {
+	return s.inner.TrimMessages(ctx, s.scope(ctx, agentID), maxMessages)
+}
+
+func (s *TenantStore) SaveToolCallRecords(ctx context.Context, agentID string, records []types.ToolCallRecord) error {
+	return s.inner.SaveToolCallRecords(ctx, s.scope(ctx, agentID), records)
+}
+
+func (s *TenantStore) LoadToolCallRecords(ctx context.Context, agentID string) ([]types.ToolCallRecord, error) {
+	return s.inner.LoadToolCallRecords(ctx, s.scope(ctx, agentID))
+}
+
+func (s *TenantStore) SaveSnapshot(ctx context.Context, agentID string, snapshot types.Snapshot) error {
+	return s.inner.SaveSnapshot(ctx, s.scope(ctx, agentID), snapshot)
+}
+
+func (s *TenantStore) LoadSnapshot(ctx context.Context, agentID string, snapshotID string) (*types.Snapshot, error) {
+	return s.inner.LoadSnapshot(ctx, s.scope(ctx, agentID), snapshotID)
+}
+
+func (s *TenantStore) ListSnapshots(ctx context.Context, agentID string) ([]types.Snapshot, error) {
+	return s.inner.ListSnapshots(ctx, s.scope(ctx, agentID))
+}
+
+func (s *TenantStore) SaveInfo(ctx context.Context, agentID string, info types.AgentInfo) error {
+	return s.inner.SaveInfo(ctx, s.scope(ctx, agentID), info)
+}
+
+func (s *TenantStore) LoadInfo(ctx context.Context, agentID string) (*types.AgentInfo, error) {
+	return s.inner.LoadInfo(ctx, s.scope(ctx, agentID))
+}
+
+func (s *TenantStore) SaveTodos(ctx context.Context, agentID string, todos any) error {
+	return s.inner.SaveTodos(ctx, s.scope(ctx, agentID), todos)
+}
+
+func (s *TenantStore) LoadTodos(ctx context.Context, agentID string) (any, error) {
+	return s.inner.LoadTodos(ctx, s.scope(ctx, agentID))
+}
+
+func (s *TenantStore) DeleteAgent(ctx context.Context, agentID string) error {
+	return s.inner.DeleteAgent(ctx, s.scope(ctx, agentID))
+}
+
+// ListAgents 列出当前租户的 Agent，返回去掉租户前缀后的 ID
+func (s *TenantStore) ListAgents(ctx context.Context) ([]string, error) {
+	agents, err := s.inner.ListAgents(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tenantID := multitenancy.GetTenantIDOrDefault(ctx, "")
+	if tenantID == "" {
+		return agents, nil
+	}
+
+	prefix := tenantID + TenantSeparator
+	result := make([]string, 0, len(agents))
+	for _, id := range agents {
+		if rest, ok := strings.CutPrefix(id, prefix); ok {
+			result = append(result, rest)
+		}
+	}
+	return result, nil
+}
+
+func (s *TenantStore) Get(ctx context.Context, collection, key string, dest any) error {
+	return s.inner.Get(ctx, s.scope(ctx, collection), key, dest)
+}
+
+func (s *TenantStore) Set(ctx context.Context, collection, key string, value any) error {
+	return s.inner.Set(ctx, s.scope(ctx, collection), key, value)
+}
+
+func (s *TenantStore) Delete(ctx context.Context, collection, key string) error {
+	return s.inner.Delete(ctx, s.scope(ctx, collection), key)
+}
+
+func (s *TenantStore) List(ctx context.Context, collection string) ([]any, error) {
+	return s.inner.List(ctx, s.scope(ctx, collection))
+}
+
+func (s *TenantStore) Exists(ctx context.Context, collection, key string) (bool, error) {
+	return s.inner.Exists(ctx, s.scope(ctx, collection), key)
+}
+
+var _ Store = (*TenantStore)(nil)
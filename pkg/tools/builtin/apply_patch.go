@@ -0,0 +1,332 @@
+package builtin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/astercloud/aster/pkg/patch"
+	"github.com/astercloud/aster/pkg/sandbox"
+	"github.com/astercloud/aster/pkg/tools"
+)
+
+// patchFileResult 单个文件的补丁应用结果
+type patchFileResult struct {
+	Path    string             `json:"path"`
+	OldPath string             `json:"old_path,omitempty"` // 重命名前的路径
+	Status  string             `json:"status"`             // created、modified、deleted、renamed、partial、conflict、rejected、unchanged
+	Hunks   []patch.HunkResult `json:"hunks,omitempty"`
+	Error   string             `json:"error,omitempty"`
+	// Valid 修改后的文件能否解析（仅检查支持的文件类型）
+	Valid           *bool  `json:"valid,omitempty"`
+	ValidationError string `json:"validation_error,omitempty"`
+	// PreexistingError 修改前文件已无法解析
+	PreexistingError bool `json:"preexisting_error,omitempty"`
+
+	content string
+	write   bool
+	remove  string
+}
+
+// ApplyPatchTool 补丁应用工具
+// 应用模型生成的统一 diff，支持偏移、模糊匹配和三方合并，并报告被拒绝的块
+type ApplyPatchTool struct{}
+
+// NewApplyPatchTool 创建ApplyPatch工具
+func NewApplyPatchTool(config map[string]any) (tools.Tool, error) {
+	return &ApplyPatchTool{}, nil
+}
+
+func (t *ApplyPatchTool) Name() string {
+	return "ApplyPatch"
+}
+
+func (t *ApplyPatchTool) Description() string {
+	return "应用统一 diff 格式的补丁，可一次修改、新建、删除多个文件"
+}
+
+func (t *ApplyPatchTool) InputSchema() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"patch": map[string]any{
+				"type":        "string",
+				"description": "统一 diff 格式的补丁（--- a/path、+++ b/path、@@ 块），路径相对于工作目录",
+			},
+			"dry_run": map[string]any{
+				"type":        "boolean",
+				"description": "只检查补丁能否应用，不写入文件，默认为false",
+			},
+			"on_conflict": map[string]any{
+				"type":        "string",
+				"enum":        []string{"markers", "reject"},
+				"description": "三方合并冲突时写入冲突标记（markers，默认）或拒绝该块（reject）",
+			},
+			"max_fuzz": map[string]any{
+				"type":        "integer",
+				"minimum":     0,
+				"maximum":     3,
+				"description": "定位块时最多忽略的首尾上下文行数，默认为2",
+			},
+			"validate": map[string]any{
+				"type":        "boolean",
+				"description": "检查修改后的 Go、JSON、YAML 文件能否解析，默认为true",
+			},
+		},
+		"required": []string{"patch"},
+	}
+}
+
+func (t *ApplyPatchTool) Execute(ctx context.Context, input map[string]any, tc *tools.ToolContext) (any, error) {
+	if err := ValidateRequired(input, []string{"patch"}); err != nil {
+		return NewClaudeErrorResponse(err), nil
+	}
+	if tc == nil || tc.Sandbox == nil {
+		return NewClaudeErrorResponse(errors.New("sandbox not available")), nil
+	}
+
+	files, err := patch.Parse(GetStringParam(input, "patch", ""))
+	if err != nil {
+		return NewClaudeErrorResponse(
+			fmt.Errorf("invalid patch: %w", err),
+			"补丁需要包含 --- a/path 和 +++ b/path 文件头以及 @@ 块",
+			"块中每行以空格（上下文）、-（删除）或 +（新增）开头",
+		), nil
+	}
+
+	dryRun := GetBoolParam(input, "dry_run", false)
+	validate := GetBoolParam(input, "validate", true)
+	onConflict := GetStringParam(input, "on_conflict", "markers")
+	if onConflict != "markers" && onConflict != "reject" {
+		return NewClaudeErrorResponse(fmt.Errorf("invalid on_conflict: %s", onConflict), "on_conflict 只能是 markers 或 reject"), nil
+	}
+	maxFuzz := GetIntParam(input, "max_fuzz", 2)
+	if maxFuzz <= 0 {
+		maxFuzz = -1
+	}
+	opts := patch.Options{MaxFuzz: maxFuzz, ConflictMarkers: onConflict == "markers"}
+
+	fs := tc.Sandbox.FS()
+	results := make([]*patchFileResult, 0, len(files))
+	for _, fd := range files {
+		r := t.applyFile(ctx, fs, fd, opts)
+		if validate && r.write && r.Error == "" {
+			t.validate(ctx, fs, fd, r)
+		}
+		results = append(results, r)
+	}
+
+	written := 0
+	if !dryRun {
+		watches, _ := tc.Services["watch_manager"].(*sandbox.WatchManager)
+		for _, r := range results {
+			if r.Error != "" || (!r.write && r.remove == "") {
+				continue
+			}
+			if watches != nil {
+				// 工具自身的写入不作为外部变更通知
+				watches.Ignore(fs.Resolve(r.Path))
+				if r.remove != "" {
+					watches.Ignore(fs.Resolve(r.remove))
+				}
+			}
+			if err := t.commit(ctx, tc.Sandbox, r); err != nil {
+				r.Error = err.Error()
+				continue
+			}
+			if versions := fileVersions(tc); versions != nil {
+				// 补丁基于磁盘上的最新内容，写入后的内容视为已读取
+				if r.write {
+					versions.Record(fs.Resolve(r.Path), r.content)
+				}
+				if r.remove != "" {
+					versions.Forget(fs.Resolve(r.remove))
+				}
+			}
+			written++
+		}
+	}
+
+	applied, rejected, conflicts, invalid := 0, 0, 0, 0
+	for _, r := range results {
+		for _, h := range r.Hunks {
+			switch h.Status {
+			case patch.HunkApplied:
+				applied++
+			case patch.HunkConflict:
+				conflicts++
+			case patch.HunkRejected:
+				rejected++
+			}
+		}
+		if r.Error != "" {
+			rejected++
+		}
+		if r.ValidationError != "" && !r.PreexistingError {
+			invalid++
+		}
+	}
+
+	response := map[string]any{
+		"ok":        rejected == 0 && conflicts == 0 && invalid == 0,
+		"files":     results,
+		"applied":   applied,
+		"rejected":  rejected,
+		"conflicts": conflicts,
+		"written":   written,
+		"dry_run":   dryRun,
+	}
+	var recommendations []string
+	if rejected > 0 {
+		recommendations = append(recommendations, "被拒绝的块未写入：先用 Read 查看 closest 附近的当前内容，再针对这些块重新生成补丁或使用 Edit")
+	}
+	if conflicts > 0 {
+		recommendations = append(recommendations, "文件中写入了冲突标记（<<<<<<< current ... >>>>>>> patch），请用 Edit 解决后删除标记")
+	}
+	if invalid > 0 {
+		recommendations = append(recommendations, "修改后的文件无法解析，请检查 validation_error 并修复")
+	}
+	if len(recommendations) > 0 {
+		response["recommendations"] = recommendations
+	}
+	return response, nil
+}
+
+// applyFile 计算单个文件应用补丁后的内容，不写入
+func (t *ApplyPatchTool) applyFile(ctx context.Context, fs sandbox.SandboxFS, fd *patch.FileDiff, opts patch.Options) *patchFileResult {
+	r := &patchFileResult{Path: fd.Path()}
+
+	if fd.IsNew() {
+		result := patch.Apply("", fd.Hunks, opts)
+		r.Hunks = result.Hunks
+		if existing, err := fs.Read(ctx, fd.NewPath); err == nil {
+			if existing == result.Content {
+				r.Status = "unchanged"
+				return r
+			}
+			r.Status, r.Error = "rejected", "file already exists"
+			return r
+		}
+		r.Status, r.content, r.write = "created", result.Content, true
+		return r
+	}
+
+	original, err := fs.Read(ctx, fd.OldPath)
+	if err != nil {
+		r.Status, r.Error = "rejected", err.Error()
+		return r
+	}
+
+	result := patch.Apply(original, fd.Hunks, opts)
+	r.Hunks = result.Hunks
+
+	if fd.IsDelete() {
+		if len(fd.Hunks) > 0 && (strings.TrimSpace(result.Content) != "" || result.Count(patch.HunkRejected) > 0) {
+			r.Status, r.Error = "rejected", "file content does not match the deletion"
+			return r
+		}
+		r.Status, r.remove = "deleted", fd.OldPath
+		return r
+	}
+
+	r.content = result.Content
+	switch {
+	case result.Count(patch.HunkRejected) == len(result.Hunks) && len(result.Hunks) > 0:
+		r.Status = "rejected"
+		return r
+	case result.Count(patch.HunkConflict) > 0:
+		r.Status = "conflict"
+	case result.Count(patch.HunkRejected) > 0:
+		r.Status = "partial"
+	case fd.IsRename():
+		r.Status = "renamed"
+	case result.Content == original:
+		r.Status = "unchanged"
+		return r
+	default:
+		r.Status = "modified"
+	}
+	if fd.IsRename() {
+		r.OldPath, r.remove = fd.OldPath, fd.OldPath
+	}
+	r.write = true
+	return r
+}
+
+// validate 检查修改后的文件能否解析，并区分修改前已存在的错误
+func (t *ApplyPatchTool) validate(ctx context.Context, fs sandbox.SandboxFS, fd *patch.FileDiff, r *patchFileResult) {
+	checked, err := patch.Validate(r.Path, r.content)
+	if !checked {
+		return
+	}
+	valid := err == nil
+	r.Valid = &valid
+	if valid {
+		return
+	}
+	r.ValidationError = err.Error()
+	if !fd.IsNew() {
+		if original, readErr := fs.Read(ctx, fd.OldPath); readErr == nil {
+			if _, origErr := patch.Validate(fd.OldPath, original); origErr != nil {
+				r.PreexistingError = true
+			}
+		}
+	}
+}
+
+// commit 写入文件，删除和重命名需要本地沙箱
+func (t *ApplyPatchTool) commit(ctx context.Context, sb sandbox.Sandbox, r *patchFileResult) error {
+	if r.remove != "" && sb.Kind() != "local" {
+		return fmt.Errorf("deleting files is not supported by the %s sandbox", sb.Kind())
+	}
+	if r.write {
+		if err := sb.FS().Write(ctx, r.Path, r.content); err != nil {
+			return err
+		}
+	}
+	if r.remove != "" {
+		if err := os.Remove(sb.FS().Resolve(r.remove)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *ApplyPatchTool) Prompt() string {
+	return `应用统一 diff（unified diff）格式的补丁，适合一次修改多处或多个文件。
+
+补丁格式：
+--- a/path/to/file.go
++++ b/path/to/file.go
+@@ -10,6 +10,7 @@
+ 上下文行（以空格开头）
+-删除的行
++新增的行
+
+- 新建文件使用 --- /dev/null，删除文件使用 +++ /dev/null
+- 每个块保留 2-3 行上下文，@@ 中的行号不准确也可以应用
+- 路径相对于工作目录
+
+应用方式（按顺序尝试）：
+1. 按行号精确匹配，行号不准时在文件中查找
+2. 忽略空白差异匹配
+3. 模糊匹配：忽略首尾最多 max_fuzz 行上下文
+4. 三方合并：上下文已被修改时，把补丁的修改合并到当前内容；双方修改同一处时写入冲突标记
+
+结果：
+- 每个文件返回 status 和每个块的 status/method；被拒绝的块不写入，返回 expected（期望的原内容）和 closest（文件中最接近的片段）
+- 修改后的 Go、JSON、YAML 文件会检查能否解析（valid/validation_error）
+- 已应用过的块会被跳过（already_applied），重复应用是安全的
+
+注意事项：
+- 修改单处时优先使用 Edit
+- 修改前先 Read 文件，确保上下文与当前内容一致
+- 有被拒绝的块时，其余块仍会写入；不确定时先用 dry_run 检查`
+}
+
+// Annotations 返回工具安全注解
+func (t *ApplyPatchTool) Annotations() *tools.ToolAnnotations {
+	return tools.AnnotationsSafeWrite
+}
@@ -0,0 +1,129 @@
+package builtin
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/astercloud/aster/pkg/patch"
+	"github.com/astercloud/aster/pkg/sandbox"
+	"github.com/astercloud/aster/pkg/tools"
+)
+
+func newPatchContext(t *testing.T, files map[string]string) (*tools.ToolContext, string) {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sb, err := sandbox.NewLocalSandbox(&sandbox.LocalSandboxConfig{WorkDir: dir})
+	if err != nil {
+		t.Fatalf("NewLocalSandbox() error = %v", err)
+	}
+	t.Cleanup(func() { _ = sb.Dispose() })
+	return &tools.ToolContext{Sandbox: sb}, dir
+}
+
+func TestApplyPatchTool_MultiFile(t *testing.T) {
+	tc, dir := newPatchContext(t, map[string]string{
+		"main.go":   "package main\n\nfunc main() {\n\tprintln(\"a\")\n}\n",
+		"old.txt":   "remove me\n",
+		"conf.json": "{\"debug\": false}\n",
+	})
+	tool, _ := NewApplyPatchTool(nil)
+
+	diff := `--- a/main.go
++++ b/main.go
+@@ -3,3 +3,3 @@
+ func main() {
+-	println("a")
++	println("b")
+ }
+--- /dev/null
++++ b/new.txt
+@@ -0,0 +1 @@
++hello
+--- a/old.txt
++++ /dev/null
+@@ -1 +0,0 @@
+-remove me
+--- a/conf.json
++++ b/conf.json
+@@ -1 +1 @@
+-{"debug": false}
++{"debug": true,}
+--- a/missing.go
++++ b/missing.go
+@@ -1 +1 @@
+-x
++y
+`
+	result, err := tool.Execute(context.Background(), map[string]any{"patch": diff}, tc)
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	res := result.(map[string]any)
+	if res["ok"] != false || res["written"] != 4 {
+		t.Fatalf("Execute() = %v", res)
+	}
+	files := res["files"].([]*patchFileResult)
+	want := []string{"modified", "created", "deleted", "modified", "rejected"}
+	for i, status := range want {
+		if files[i].Status != status {
+			t.Errorf("files[%d] (%s) status = %s, want %s", i, files[i].Path, files[i].Status, status)
+		}
+	}
+	if files[3].Valid == nil || *files[3].Valid || files[3].ValidationError == "" {
+		t.Errorf("invalid JSON should be reported: %+v", files[3])
+	}
+
+	if data, _ := os.ReadFile(filepath.Join(dir, "main.go")); string(data) != "package main\n\nfunc main() {\n\tprintln(\"b\")\n}\n" {
+		t.Errorf("main.go = %q", data)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "new.txt")); string(data) != "hello\n" {
+		t.Errorf("new.txt = %q", data)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "old.txt")); !os.IsNotExist(err) {
+		t.Errorf("old.txt should be deleted, stat error = %v", err)
+	}
+
+	// 重复应用时已应用的块被跳过
+	result, _ = tool.Execute(context.Background(), map[string]any{"patch": diff[:len("--- a/main.go\n+++ b/main.go\n@@ -3,3 +3,3 @@\n func main() {\n-\tprintln(\"a\")\n+\tprintln(\"b\")\n }\n")]}, tc)
+	res = result.(map[string]any)
+	files = res["files"].([]*patchFileResult)
+	if res["ok"] != true || files[0].Status != "unchanged" || files[0].Hunks[0].Method != patch.MethodAlreadyApplied {
+		t.Errorf("re-apply = %v, %+v", res, files[0])
+	}
+}
+
+func TestApplyPatchTool_RejectAndDryRun(t *testing.T) {
+	original := "line one\nline two\nline three\n"
+	tc, dir := newPatchContext(t, map[string]string{"a.txt": original})
+	tool, _ := NewApplyPatchTool(nil)
+
+	conflict := "--- a/a.txt\n+++ b/a.txt\n@@ -1,3 +1,3 @@\n line one\n-line 2\n+line TWO\n line three\n"
+	result, _ := tool.Execute(context.Background(), map[string]any{"patch": conflict, "on_conflict": "reject", "max_fuzz": float64(0)}, tc)
+	res := result.(map[string]any)
+	files := res["files"].([]*patchFileResult)
+	h := files[0].Hunks[0]
+	if res["ok"] != false || h.Status != patch.HunkRejected || h.ClosestLine != 1 || len(h.Expected) != 3 {
+		t.Fatalf("Execute() = %v, hunk = %+v", res, h)
+	}
+
+	result, _ = tool.Execute(context.Background(), map[string]any{"patch": conflict, "max_fuzz": float64(0), "dry_run": true}, tc)
+	res = result.(map[string]any)
+	if res["conflicts"] != 1 || res["written"] != 0 {
+		t.Fatalf("dry run = %v", res)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "a.txt")); string(data) != original {
+		t.Errorf("file changed: %q", data)
+	}
+
+	result, _ = tool.Execute(context.Background(), map[string]any{"patch": "not a diff"}, tc)
+	if res = result.(map[string]any); res["ok"] != false {
+		t.Errorf("invalid patch = %v", res)
+	}
+}
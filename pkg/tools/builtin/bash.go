@@ -4,46 +4,25 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"regexp"
 	"strings"
 	"time"
 
 	"github.com/astercloud/aster/pkg/sandbox"
+	"github.com/astercloud/aster/pkg/sandbox/shellrisk"
 	"github.com/astercloud/aster/pkg/tools"
 )
 
 // BashTool 增强的Bash命令执行工具
 // 支持持久化shell会话功能
 type BashTool struct {
-	dangerousPatterns []*regexp.Regexp
-	defaultTimeout    time.Duration
+	defaultTimeout time.Duration
 }
 
 // NewBashTool 创建Bash执行工具
 func NewBashTool(config map[string]any) (tools.Tool, error) {
-	tool := &BashTool{
+	return &BashTool{
 		defaultTimeout: 2 * time.Minute,
-	}
-
-	// 编译危险命令模式
-	dangerousCommands := []string{
-		`rm -rf /`,
-		`sudo rm`,
-		`:(){ :|:& };:`,
-		`chmod 777 /`,
-		`dd if=/dev/zero`,
-		`format`,
-		`fdisk`,
-	}
-
-	for _, pattern := range dangerousCommands {
-		regex, err := regexp.Compile(pattern)
-		if err == nil {
-			tool.dangerousPatterns = append(tool.dangerousPatterns, regex)
-		}
-	}
-
-	return tool, nil
+	}, nil
 }
 
 func (t *BashTool) Name() string {
@@ -78,9 +57,13 @@ func (t *BashTool) InputSchema() map[string]any {
 				"type":        "string",
 				"description": "在指定的shell会话中执行命令，如果未提供则创建新会话",
 			},
+			"run_in_background": map[string]any{
+				"type":        "boolean",
+				"description": "在后台运行命令并立即返回task_id，之后用TaskOutput读取输出、TaskStatus查看状态、KillShell终止。适用于开发服务器、长时间构建等",
+			},
 			"background": map[string]any{
 				"type":        "boolean",
-				"description": "是否在后台运行命令，默认为false",
+				"description": "已废弃，等同于run_in_background",
 			},
 			"capture_output": map[string]any{
 				"type":        "boolean",
@@ -113,7 +96,7 @@ func (t *BashTool) Execute(ctx context.Context, input map[string]any, tc *tools.
 	description := GetStringParam(input, "description", "")
 	workingDir := GetStringParam(input, "working_dir", "")
 	shellID := GetStringParam(input, "shell_id", "")
-	background := GetBoolParam(input, "background", false)
+	background := GetBoolParam(input, "run_in_background", GetBoolParam(input, "background", false))
 	captureOutput := GetBoolParam(input, "capture_output", true)
 	shellType := GetStringParam(input, "shell", "bash")
 
@@ -181,6 +164,10 @@ func (t *BashTool) Execute(ctx context.Context, input map[string]any, tc *tools.
 
 	start := time.Now()
 
+	if background {
+		return t.startBackground(ctx, tc, command, description, workingDir, shellType, environment, captureOutput, start), nil
+	}
+
 	// 构建完整命令
 	fullCommand := t.buildFullCommand(command, environment, shellType)
 
@@ -190,57 +177,16 @@ func (t *BashTool) Execute(ctx context.Context, input map[string]any, tc *tools.
 		timeout = t.getCommandTimeout(command)
 	}
 
-	var taskID string
-	var result *sandbox.ExecResult
-	var err error
-
-	if background {
-		// 后台任务模式：使用TaskManager
-		taskManager := GetGlobalTaskManager()
-		taskOpts := &TaskOptions{
-			WorkDir:       workingDir,
-			Env:           environment,
-			Timeout:       timeout,
-			Background:    true,
-			Shell:         shellType,
-			CaptureOutput: captureOutput,
-		}
-
-		taskInfo, taskErr := taskManager.StartTask(ctx, command, taskOpts)
-		if taskErr != nil {
-			return map[string]any{
-				"ok":    false,
-				"error": fmt.Sprintf("failed to start background task: %v", taskErr),
-				"recommendations": []string{
-					"检查命令语法是否正确",
-					"确认工作目录是否存在",
-					"验证是否有执行权限",
-				},
-				"command":     command,
-				"background":  true,
-				"duration_ms": time.Since(start).Milliseconds(),
-			}, nil
-		}
-
-		// 后台任务启动成功
-		taskID = taskInfo.ID
-		result = &sandbox.ExecResult{
-			Code:   0,  // 后台任务通常立即返回，实际状态由TaskManager管理
-			Stdout: "", // 初始输出为空，通过BashOutput获取
-			Stderr: "",
-		}
-	} else {
-		// 前台任务模式：直接执行
-		result, err = tc.Sandbox.Exec(ctx, fullCommand, &sandbox.ExecOptions{
-			Timeout: timeout,
-			WorkDir: workingDir,
-			Env:     environment,
-		})
-	}
+	// 前台任务模式：直接执行
+	result, err := tc.Sandbox.Exec(ctx, fullCommand, &sandbox.ExecOptions{
+		Timeout: timeout,
+		WorkDir: workingDir,
+		Env:     environment,
+	})
 
 	duration := time.Since(start)
 
-	if err != nil && !background {
+	if err != nil {
 		return map[string]any{
 			"ok":    false,
 			"error": fmt.Sprintf("command execution failed: %v", err),
@@ -295,6 +241,15 @@ func (t *BashTool) Execute(ctx context.Context, input map[string]any, tc *tools.
 		response["environment_set"] = false
 	}
 
+	// 配置了 Agent 身份时为新提交签名，签名失败不影响命令结果
+	if result.Code == 0 {
+		if sig, err := signCommit(ctx, tc, command, workingDir); err != nil {
+			response["commit_signature_error"] = err.Error()
+		} else if sig != nil {
+			response["commit_signature"] = sig
+		}
+	}
+
 	// 如果执行失败，添加错误信息
 	if result.Code != 0 {
 		response["error"] = fmt.Sprintf("command exited with code %d", result.Code)
@@ -305,42 +260,102 @@ func (t *BashTool) Execute(ctx context.Context, input map[string]any, tc *tools.
 		}
 	}
 
-	// 如果是后台任务，添加task_id到响应中
-	if background && taskID != "" {
-		response["bash_id"] = taskID
-		response["task_id"] = taskID
-	}
-
 	return response, nil
 }
 
-func (t *BashTool) validateCommand(cmd string) error {
-	// 检查危险命令模式
-	lowerCmd := strings.ToLower(cmd)
-	for _, pattern := range t.dangerousPatterns {
-		if pattern.MatchString(lowerCmd) {
-			return fmt.Errorf("dangerous command blocked: %s", cmd)
+// maxBackgroundRuntime 后台命令的最长运行时间，超时后进程被终止
+const maxBackgroundRuntime = 30 * time.Minute
+
+// startBackground 通过全局 TaskManager 在后台启动命令，立即返回任务句柄
+// TaskManager 直接在本机执行，因此只允许未启用 OS 隔离的本地沙箱使用
+// 未指定工作目录时使用沙箱工作目录
+func (t *BashTool) startBackground(ctx context.Context, tc *tools.ToolContext, command, description, workingDir, shellType string, environment map[string]string, captureOutput bool, start time.Time) map[string]any {
+	var sb sandbox.Sandbox
+	if tc != nil {
+		sb = tc.Sandbox
+	}
+	if err := hostBackgroundAllowed(sb); err != nil {
+		return map[string]any{
+			"ok":    false,
+			"error": err.Error(),
+			"recommendations": []string{
+				"去掉 run_in_background，在沙箱中前台执行命令",
+				"长时间运行的命令可设置 timeout 参数",
+			},
+			"command":     command,
+			"background":  true,
+			"duration_ms": time.Since(start).Milliseconds(),
 		}
 	}
-
-	// 检查其他危险命令
-	dangerousCommands := []string{
-		"rm -rf /",
-		"sudo rm",
-		":(){ :|:& };:",
-		"chmod 777 /",
-		"dd if=/dev/zero",
-		"mkfs",
-		"format",
-		"fdisk",
+	if workingDir == "" && sb != nil {
+		workingDir = sb.WorkDir()
 	}
 
-	for _, dangerous := range dangerousCommands {
-		if strings.Contains(lowerCmd, dangerous) {
-			return fmt.Errorf("dangerous command blocked: %s", cmd)
+	taskInfo, err := GetGlobalTaskManager().StartTask(ctx, command, &TaskOptions{
+		WorkDir:       workingDir,
+		Env:           environment,
+		Timeout:       maxBackgroundRuntime,
+		Background:    true,
+		Shell:         shellType,
+		CaptureOutput: captureOutput,
+	})
+	if err != nil {
+		return map[string]any{
+			"ok":    false,
+			"error": fmt.Sprintf("failed to start background task: %v", err),
+			"recommendations": []string{
+				"检查命令语法是否正确",
+				"确认工作目录是否存在",
+				"验证是否有执行权限",
+			},
+			"command":     command,
+			"background":  true,
+			"duration_ms": time.Since(start).Milliseconds(),
 		}
 	}
 
+	response := map[string]any{
+		"ok":          true,
+		"command":     command,
+		"background":  true,
+		"task_id":     taskInfo.ID,
+		"bash_id":     taskInfo.ID,
+		"pid":         taskInfo.PID,
+		"status":      taskInfo.Status,
+		"shell_type":  shellType,
+		"duration_ms": time.Since(start).Milliseconds(),
+		"start_time":  start.Unix(),
+		"hint":        "命令正在后台运行。使用 TaskOutput 读取新输出（block=true 可等待结束），TaskStatus 查看状态，KillShell 终止任务",
+	}
+	if description != "" {
+		response["description"] = description
+	}
+	if workingDir != "" {
+		response["working_dir"] = workingDir
+	}
+	return response
+}
+
+// hostBackgroundAllowed 判断后台命令能否直接在本机运行
+// 容器、远程沙箱以及启用 OS 隔离的本地沙箱在本机执行会绕过其隔离
+func hostBackgroundAllowed(sb sandbox.Sandbox) error {
+	if sb == nil {
+		return nil
+	}
+	if kind := sb.Kind(); kind != "local" {
+		return fmt.Errorf("run_in_background is not supported in %s sandbox", kind)
+	}
+	if ls, ok := sb.(*sandbox.LocalSandbox); ok && ls.OSIsolation().Active {
+		return errors.New("run_in_background is not supported while OS isolation is active")
+	}
+	return nil
+}
+
+// validateCommand 通过 shell 语法分析阻止高风险命令
+func (t *BashTool) validateCommand(cmd string) error {
+	if analysis := shellrisk.Analyze(cmd); analysis.Dangerous() {
+		return fmt.Errorf("dangerous command blocked: %s", analysis.Reason())
+	}
 	return nil
 }
 
@@ -408,7 +423,7 @@ func (t *BashTool) Prompt() string {
 - timeout: 可选参数，超时时间（毫秒）
 - working_dir: 可选参数，工作目录
 - environment: 可选参数，环境变量设置
-- background: 可选参数，是否后台运行
+- run_in_background: 可选参数，后台运行并立即返回 task_id；仅本地沙箱可用，最长运行 30 分钟
 - shell: 可选参数，shell类型
 
 安全特性：
@@ -418,7 +433,7 @@ func (t *BashTool) Prompt() string {
 - 命令注入防护
 
 注意事项：
-- 默认超时时间为2分钟
+- 默认超时时间为2分钟，后台命令不受超时限制，需要时用 KillShell 终止
 - 长时间运行的命令会自动调整超时
 - 危险命令（如rm -rf /）会被阻止
 - 所有命令都在沙箱环境中执行`
@@ -441,11 +456,10 @@ func (t *BashTool) Examples() []tools.ToolExample {
 			},
 		},
 		{
-			Description: "在后台运行构建命令",
+			Description: "在后台启动开发服务器，之后用 TaskOutput 查看输出",
 			Input: map[string]any{
-				"command":    "go build -v ./...",
-				"background": true,
-				"timeout":    300000,
+				"command":           "npm run dev",
+				"run_in_background": true,
 			},
 		},
 		{
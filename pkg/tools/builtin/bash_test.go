@@ -3,8 +3,10 @@ package builtin
 import (
 	"errors"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestNewBashTool(t *testing.T) {
@@ -276,6 +278,27 @@ func TestBashTool_BackgroundExecution(t *testing.T) {
 	}
 }
 
+func TestBashTool_BackgroundRequiresLocalSandbox(t *testing.T) {
+	tool, err := NewBashTool(nil)
+	if err != nil {
+		t.Fatalf("Failed to create Bash tool: %v", err)
+	}
+
+	marker := filepath.Join(t.TempDir(), "ran-on-host")
+	result := ExecuteToolWithInput(t, tool, map[string]any{
+		"command":           "touch " + marker,
+		"run_in_background": true,
+	})
+	if ok, _ := result["ok"].(bool); ok {
+		t.Fatalf("background command under mock sandbox should be refused, got: %v", result)
+	}
+
+	time.Sleep(200 * time.Millisecond)
+	if _, err := os.Stat(marker); !os.IsNotExist(err) {
+		t.Fatalf("background command must not run on the host, stat err: %v", err)
+	}
+}
+
 func TestBashTool_ConcurrentExecution(t *testing.T) {
 	tool, err := NewBashTool(nil)
 	if err != nil {
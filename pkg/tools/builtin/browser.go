@@ -0,0 +1,250 @@
+package builtin
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/astercloud/aster/pkg/artifact"
+	"github.com/astercloud/aster/pkg/browser"
+	"github.com/astercloud/aster/pkg/sandbox"
+	"github.com/astercloud/aster/pkg/tools"
+)
+
+const (
+	// defaultBrowserMaxLength extract 默认返回的最大字符数
+	defaultBrowserMaxLength = 20000
+	// maxBrowserMaxLength extract 最多返回的字符数
+	maxBrowserMaxLength = 200000
+)
+
+// browserAnnotations 浏览器可以提交表单、触发页面中的任意操作，按高风险处理
+var browserAnnotations = tools.AnnotationsNetworkWrite.Clone().WithRiskLevel(tools.RiskLevelHigh)
+
+// BrowserTool 浏览器自动化工具
+// 通过无头 Chrome 打开网页、点击、填写表单、截图和提取内容，访问范围受沙箱网络策略限制
+type BrowserTool struct{}
+
+// NewBrowserTool 创建Browser工具
+func NewBrowserTool(config map[string]any) (tools.Tool, error) {
+	return &BrowserTool{}, nil
+}
+
+func (t *BrowserTool) Name() string {
+	return "Browser"
+}
+
+func (t *BrowserTool) Description() string {
+	return "使用无头浏览器打开网页、点击、填写表单、截图和提取页面内容"
+}
+
+func (t *BrowserTool) InputSchema() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"action": map[string]any{
+				"type":        "string",
+				"enum":        []string{"navigate", "click", "fill", "screenshot", "extract", "close"},
+				"description": "navigate 打开网址，click 点击元素，fill 填写输入框，screenshot 截图，extract 提取内容，close 关闭浏览器",
+			},
+			"url": map[string]any{
+				"type":        "string",
+				"description": "navigate: 要打开的 http(s) 地址",
+			},
+			"selector": map[string]any{
+				"type":        "string",
+				"description": "click/fill: 目标元素的 CSS 选择器；screenshot/extract: 只处理该元素（默认整个页面）",
+			},
+			"value": map[string]any{
+				"type":        "string",
+				"description": "fill: 输入的内容",
+			},
+			"submit": map[string]any{
+				"type":        "boolean",
+				"description": "fill: 填写后提交所在的表单，默认为false",
+			},
+			"wait_for": map[string]any{
+				"type":        "string",
+				"description": "navigate/click: 完成后等待该 CSS 选择器的元素出现",
+			},
+			"format": map[string]any{
+				"type":        "string",
+				"enum":        []string{"text", "html"},
+				"description": "extract: 提取可见文本（text，默认）或 HTML",
+			},
+			"max_length": map[string]any{
+				"type":        "integer",
+				"description": fmt.Sprintf("extract: 最多返回的字符数，默认为%d", defaultBrowserMaxLength),
+			},
+			"name": map[string]any{
+				"type":        "string",
+				"description": "screenshot: 截图文件名（不含目录），默认按时间生成",
+			},
+		},
+		"required": []string{"action"},
+	}
+}
+
+func (t *BrowserTool) Execute(ctx context.Context, input map[string]any, tc *tools.ToolContext) (any, error) {
+	if err := ValidateRequired(input, []string{"action"}); err != nil {
+		return NewClaudeErrorResponse(err), nil
+	}
+	var manager *browser.Manager
+	if tc != nil && tc.Services != nil {
+		manager, _ = tc.Services["browser_manager"].(*browser.Manager)
+	}
+	if manager == nil {
+		return NewClaudeErrorResponse(errors.New("browser is not enabled"), "使用 WebFetch 获取网页内容"), nil
+	}
+
+	action := GetStringParam(input, "action", "")
+	selector := GetStringParam(input, "selector", "")
+	if (action == "click" || action == "fill") && selector == "" {
+		return NewClaudeErrorResponse(fmt.Errorf("selector is required for %s", action)), nil
+	}
+
+	var (
+		page   *browser.Page
+		err    error
+		result = map[string]any{"ok": true, "action": action}
+	)
+	switch action {
+	case "navigate":
+		if err := ValidateRequired(input, []string{"url"}); err != nil {
+			return NewClaudeErrorResponse(err), nil
+		}
+		page, err = manager.Navigate(ctx, GetStringParam(input, "url", ""), GetStringParam(input, "wait_for", ""))
+
+	case "click":
+		page, err = manager.Click(ctx, selector, GetStringParam(input, "wait_for", ""))
+
+	case "fill":
+		page, err = manager.Fill(ctx, selector, GetStringParam(input, "value", ""), GetBoolParam(input, "submit", false))
+
+	case "screenshot":
+		var png []byte
+		png, page, err = manager.Screenshot(ctx, selector)
+		if err == nil {
+			var file string
+			file, err = t.saveScreenshot(ctx, tc, manager.ScreenshotDir(), GetStringParam(input, "name", ""), png)
+			result["artifact"] = file
+			result["bytes"] = len(png)
+			if sink, ok := tc.Services["artifact_sink"].(artifact.Sink); ok && err == nil {
+				// 同时保存为产出物，供会话的产出物接口下载
+				saved, saveErr := sink.Save(ctx, artifact.SaveRequest{
+					Name:     path.Base(file),
+					Kind:     artifact.KindImage,
+					MIMEType: "image/png",
+					Source:   t.Name(),
+					Metadata: map[string]any{"url": page.URL, "title": page.Title},
+				}, bytes.NewReader(png))
+				if saveErr == nil {
+					result["artifact_id"] = saved.ID
+				}
+			}
+		}
+
+	case "extract":
+		var content string
+		content, page, err = manager.Extract(ctx, selector, GetStringParam(input, "format", "text"))
+		if err == nil {
+			maxLength := min(GetIntParam(input, "max_length", defaultBrowserMaxLength), maxBrowserMaxLength)
+			if maxLength <= 0 {
+				maxLength = defaultBrowserMaxLength
+			}
+			runes := []rune(content)
+			if len(runes) > maxLength {
+				content = string(runes[:maxLength])
+				result["truncated"] = true
+				result["total_length"] = len(runes)
+			}
+			result["content"] = content
+		}
+
+	case "close":
+		_ = manager.Close()
+		return result, nil
+
+	default:
+		return NewClaudeErrorResponse(fmt.Errorf("unknown action: %s", action), "action 只能是 navigate、click、fill、screenshot、extract、close"), nil
+	}
+
+	if err != nil {
+		return t.errorResponse(err), nil
+	}
+	result["url"] = page.URL
+	result["title"] = page.Title
+	if len(page.Blocked) > 0 {
+		result["blocked_requests"] = page.Blocked
+	}
+	return result, nil
+}
+
+// saveScreenshot 将截图保存到工作目录，返回相对路径
+func (t *BrowserTool) saveScreenshot(ctx context.Context, tc *tools.ToolContext, dir, name string, png []byte) (string, error) {
+	if tc == nil || tc.Sandbox == nil {
+		return "", errors.New("sandbox not available")
+	}
+	name = path.Base(strings.ReplaceAll(name, "\\", "/"))
+	if name == "" || name == "." || name == "/" {
+		name = "screenshot-" + time.Now().Format("20060102-150405.000")
+	}
+	if !strings.HasSuffix(strings.ToLower(name), ".png") {
+		name += ".png"
+	}
+	file := path.Join(dir, name)
+	if watches, ok := tc.Services["watch_manager"].(*sandbox.WatchManager); ok {
+		watches.Ignore(tc.Sandbox.FS().Resolve(file))
+	}
+	if err := tc.Sandbox.FS().Write(ctx, file, string(png)); err != nil {
+		return "", fmt.Errorf("save screenshot: %w", err)
+	}
+	return file, nil
+}
+
+// errorResponse 按错误类型给出建议
+func (t *BrowserTool) errorResponse(err error) map[string]any {
+	switch {
+	case errors.Is(err, browser.ErrBlocked):
+		return NewClaudeErrorResponse(err, "该地址被沙箱网络策略阻止（sandbox.settings.network），不要尝试绕过")
+	case errors.Is(err, browser.ErrNoBrowser):
+		return NewClaudeErrorResponse(err, "本机没有安装 Chrome，使用 WebFetch 获取网页内容")
+	case errors.Is(err, context.DeadlineExceeded):
+		return NewClaudeErrorResponse(err, "检查选择器是否正确，或先用 extract 查看页面内容")
+	default:
+		return NewClaudeErrorResponse(err)
+	}
+}
+
+func (t *BrowserTool) Prompt() string {
+	return `使用无头浏览器（Chrome）完成网页任务，适合需要执行 JavaScript、登录、填写表单或截图的页面。
+
+操作：
+- navigate: 打开 url，可用 wait_for 等待某个元素出现
+- click: 点击 selector 对应的元素，点击后跳转时用 wait_for 等待新页面的元素
+- fill: 清空 selector 对应的输入框并输入 value，submit 为 true 时提交表单
+- screenshot: 截取整个页面或 selector 对应的元素，保存为 PNG 文件，返回 artifact 路径（配置了产出物存储时还返回 artifact_id）
+- extract: 提取整个页面或 selector 对应元素的文本（text）或 HTML
+- close: 关闭浏览器
+
+所有操作共用同一个标签页，每次操作返回当前页面的 url 和 title。
+
+使用指南：
+- selector 使用 CSS 选择器，如 "#login"、"input[name=q]"、"button[type=submit]"
+- 不确定页面结构时先 extract（format=html 并指定较小的 selector）查看
+- 只需要静态页面内容时优先使用 WebFetch
+
+安全限制：
+- 只能访问 http(s) 地址，且受沙箱网络策略（允许/阻止的主机）限制
+- 页面中被阻止的请求会在 blocked_requests 中列出
+- 不要在页面中输入用户未提供的密码、密钥等敏感信息`
+}
+
+// Annotations 返回工具安全注解
+func (t *BrowserTool) Annotations() *tools.ToolAnnotations {
+	return browserAnnotations
+}
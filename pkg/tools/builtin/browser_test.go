@@ -0,0 +1,55 @@
+package builtin
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/astercloud/aster/pkg/browser"
+	"github.com/astercloud/aster/pkg/tools"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+func TestBrowserTool_Errors(t *testing.T) {
+	tool, err := NewBrowserTool(nil)
+	if err != nil {
+		t.Fatalf("NewBrowserTool() error = %v", err)
+	}
+	manager := browser.NewManager(browser.Config{
+		ExecPath: "/nonexistent/chrome",
+		Policy:   browser.NewPolicy(&types.NetworkSandboxSettings{AllowedHosts: []string{"example.com"}}),
+	})
+	defer manager.Close()
+	tc := &tools.ToolContext{Services: map[string]any{"browser_manager": manager}}
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		tc      *tools.ToolContext
+		input   map[string]any
+		wantErr string
+	}{
+		{"not enabled", &tools.ToolContext{}, map[string]any{"action": "navigate", "url": "https://example.com"}, "not enabled"},
+		{"missing url", tc, map[string]any{"action": "navigate"}, "url"},
+		{"missing selector", tc, map[string]any{"action": "click"}, "selector is required"},
+		{"unknown action", tc, map[string]any{"action": "scroll"}, "unknown action"},
+		{"blocked host", tc, map[string]any{"action": "navigate", "url": "https://evil.test"}, "blocked by network policy"},
+		{"blocked scheme", tc, map[string]any{"action": "navigate", "url": "file:///etc/passwd"}, "blocked by network policy"},
+		{"no browser installed", tc, map[string]any{"action": "navigate", "url": "https://example.com"}, "chrome or chromium not found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := tool.Execute(ctx, tt.input, tt.tc)
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			m := result.(map[string]any)
+			if m["ok"] != false {
+				t.Fatalf("result = %v, want ok=false", m)
+			}
+			if msg, _ := m["error"].(string); !strings.Contains(msg, tt.wantErr) {
+				t.Errorf("error = %q, want it to contain %q", msg, tt.wantErr)
+			}
+		})
+	}
+}
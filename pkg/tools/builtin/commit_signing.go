@@ -0,0 +1,53 @@
+package builtin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/astercloud/aster/pkg/identity"
+	"github.com/astercloud/aster/pkg/sandbox"
+	"github.com/astercloud/aster/pkg/tools"
+)
+
+// gitCommitPattern 匹配 git commit，允许中间出现全局参数（如 git -C dir commit）
+var gitCommitPattern = regexp.MustCompile(`\bgit(\s+-[-\w]+(\s+[^-\s]\S*)?)*\s+commit([\s;&|)]|$)`)
+
+// signCommit 命令中包含 git commit 时，用 Agent 身份对新的 HEAD 提交签名
+// 签名对象是提交 ID（提交 ID 由树、父提交、作者和提交信息计算得到），
+// 签名以 JSON 保存在 identity.GitNotesRef 的 git note 中，不改变提交本身
+func signCommit(ctx context.Context, tc *tools.ToolContext, command, workingDir string) (*identity.Signature, error) {
+	signer, ok := tc.Services["identity_signer"].(*identity.Signer)
+	if !ok || signer == nil || !gitCommitPattern.MatchString(command) {
+		return nil, nil
+	}
+	opts := &sandbox.ExecOptions{Timeout: 30 * time.Second, WorkDir: workingDir}
+
+	head, err := tc.Sandbox.Exec(ctx, "git rev-parse HEAD", opts)
+	if err != nil {
+		return nil, err
+	}
+	if head.Code != 0 {
+		return nil, fmt.Errorf("git rev-parse HEAD: %s", strings.TrimSpace(head.Stderr))
+	}
+	commitID := strings.TrimSpace(head.Stdout)
+
+	sig := signer.Sign(identity.KindCommit, []byte(commitID))
+	note, err := json.Marshal(sig)
+	if err != nil {
+		return nil, err
+	}
+	cmd := fmt.Sprintf("git notes --ref=%s add -f -m '%s' %s",
+		identity.GitNotesRef, strings.ReplaceAll(string(note), "'", `'\''`), commitID)
+	result, err := tc.Sandbox.Exec(ctx, cmd, opts)
+	if err != nil {
+		return nil, err
+	}
+	if result.Code != 0 {
+		return nil, fmt.Errorf("git notes: %s", strings.TrimSpace(result.Stderr))
+	}
+	return sig, nil
+}
@@ -0,0 +1,82 @@
+package builtin
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/astercloud/aster/pkg/identity"
+	"github.com/astercloud/aster/pkg/sandbox"
+	"github.com/astercloud/aster/pkg/store"
+	"github.com/astercloud/aster/pkg/tools"
+)
+
+// gitSandbox 记录执行的命令，git rev-parse 返回固定的提交 ID
+type gitSandbox struct {
+	*sandbox.MockSandbox
+	commands []string
+}
+
+func (s *gitSandbox) Exec(ctx context.Context, cmd string, opts *sandbox.ExecOptions) (*sandbox.ExecResult, error) {
+	s.commands = append(s.commands, cmd)
+	if cmd == "git rev-parse HEAD" {
+		return &sandbox.ExecResult{Stdout: "9fceb02d0ae598e95dc970b74767f19372d61af8\n"}, nil
+	}
+	return &sandbox.ExecResult{}, nil
+}
+
+func TestGitCommitPattern(t *testing.T) {
+	for command, want := range map[string]bool{
+		`git commit -m "fix"`:                 true,
+		`git add -A && git commit -m "fix"`:   true,
+		`git -C repo commit --amend`:          true,
+		`git -c user.name=bot commit -m "x"`:  true,
+		`git log --oneline`:                   false,
+		`echo "commit"`:                       false,
+		`git commit-graph write`:              false,
+		`git status && echo git commit later`: true,
+	} {
+		if got := gitCommitPattern.MatchString(command); got != want {
+			t.Errorf("match(%q) = %v, want %v", command, got, want)
+		}
+	}
+}
+
+func TestSignCommit(t *testing.T) {
+	ctx := context.Background()
+	st, err := store.NewJSONStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	reg := identity.NewRegistry(st)
+	signer, err := reg.Signer(ctx, "agt-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sb := &gitSandbox{MockSandbox: sandbox.NewMockSandbox()}
+	tc := &tools.ToolContext{Sandbox: sb, Services: map[string]any{"identity_signer": signer}}
+
+	sig, err := signCommit(ctx, tc, `git commit -m "fix"`, "")
+	if err != nil {
+		t.Fatalf("sign commit: %v", err)
+	}
+	if sig == nil {
+		t.Fatal("expected a signature")
+	}
+	if _, err := reg.Verify(ctx, sig, []byte("9fceb02d0ae598e95dc970b74767f19372d61af8")); err != nil {
+		t.Fatalf("verify commit signature: %v", err)
+	}
+	if len(sb.commands) != 2 || !strings.HasPrefix(sb.commands[1], "git notes --ref="+identity.GitNotesRef+" add -f") {
+		t.Errorf("commands = %v", sb.commands)
+	}
+
+	// 非提交命令和未配置身份时不签名
+	if sig, _ := signCommit(ctx, tc, "git status", ""); sig != nil {
+		t.Error("non-commit command should not be signed")
+	}
+	tc.Services = map[string]any{}
+	if sig, _ := signCommit(ctx, tc, `git commit -m "fix"`, ""); sig != nil {
+		t.Error("commit without identity should not be signed")
+	}
+}
@@ -121,6 +121,11 @@ func (t *EditTool) Execute(ctx context.Context, input map[string]any, tc *tools.
 		}, nil
 	}
 
+	// 文件在上次读取后被修改时拒绝编辑，避免覆盖用户的改动
+	if conflict := checkFileConflict(tc, filePath, originalContent); conflict != nil {
+		return conflict, nil
+	}
+
 	// 创建备份
 	var backupPath string
 	if backup {
@@ -195,6 +200,7 @@ func (t *EditTool) Execute(ctx context.Context, input map[string]any, tc *tools.
 			"backup_path": backupPath,
 		}, nil
 	}
+	recordFileVersion(tc, filePath, modifiedContent)
 
 	// 计算统计信息
 	originalLines := strings.Count(originalContent, "\n") + 1
@@ -347,6 +353,7 @@ func (t *EditTool) Prompt() string {
 - 建议先使用Read工具确认要替换的内容
 - 启用备份可以防止意外的编辑错误
 - 缩进保护可以保持代码格式的一致性
+- 文件在上次读取后被修改时拒绝编辑，返回 file_conflict 错误和最新内容（current_content），请基于最新内容重试
 
 安全性：
 - 路径遍历攻击防护
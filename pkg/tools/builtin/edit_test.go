@@ -1,8 +1,15 @@
 package builtin
 
 import (
+	"context"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/astercloud/aster/pkg/agenterr"
+	"github.com/astercloud/aster/pkg/sandbox"
+	"github.com/astercloud/aster/pkg/tools"
 )
 
 func TestNewEditTool(t *testing.T) {
@@ -251,3 +258,54 @@ func BenchmarkEditTool_SimpleEdit(b *testing.B) {
 
 	BenchmarkTool(b, tool, input)
 }
+
+func TestEditTool_FileConflict(t *testing.T) {
+	dir := t.TempDir()
+	sb, err := sandbox.NewLocalSandbox(&sandbox.LocalSandboxConfig{WorkDir: dir})
+	if err != nil {
+		t.Fatalf("NewLocalSandbox() error = %v", err)
+	}
+	t.Cleanup(func() { _ = sb.Dispose() })
+	tc := &tools.ToolContext{
+		Sandbox:  sb,
+		Services: map[string]any{"file_versions": sandbox.NewFileVersions()},
+	}
+	ctx := context.Background()
+	path := filepath.Join(dir, "main.go")
+	if err := os.WriteFile(path, []byte("package main\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	readTool, _ := NewReadTool(nil)
+	editTool, _ := NewEditTool(nil)
+	if _, err := readTool.Execute(ctx, map[string]any{"file_path": "main.go"}, tc); err != nil {
+		t.Fatalf("Read error = %v", err)
+	}
+
+	// 读取后文件被外部修改
+	if err := os.WriteFile(path, []byte("package app\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	edit := map[string]any{"file_path": "main.go", "old_string": "package", "new_string": "// edited\npackage"}
+	result, _ := editTool.Execute(ctx, edit, tc)
+	res := result.(map[string]any)
+	if res["ok"] != false || res["code"] != agenterr.CodeFileConflict || res["current_content"] != "package app\n" {
+		t.Fatalf("Execute() = %v, want file_conflict", res)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "package app\n" {
+		t.Errorf("file was overwritten: %q", data)
+	}
+
+	// 冲突响应已返回最新内容，重试即可成功
+	result, _ = editTool.Execute(ctx, edit, tc)
+	if res := result.(map[string]any); res["ok"] != true {
+		t.Fatalf("retry Execute() = %v", res)
+	}
+
+	// 自身写入不应被视为冲突
+	writeTool, _ := NewWriteTool(nil)
+	result, _ = writeTool.Execute(ctx, map[string]any{"file_path": "main.go", "content": "package main\n"}, tc)
+	if res := result.(map[string]any); res["ok"] != true {
+		t.Fatalf("Write Execute() = %v", res)
+	}
+}
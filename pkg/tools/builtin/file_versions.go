@@ -0,0 +1,52 @@
+package builtin
+
+import (
+	"fmt"
+
+	"github.com/astercloud/aster/pkg/agenterr"
+	"github.com/astercloud/aster/pkg/sandbox"
+	"github.com/astercloud/aster/pkg/tools"
+)
+
+// fileVersions 返回 Agent 注入的文件版本记录，未注入时返回 nil
+func fileVersions(tc *tools.ToolContext) *sandbox.FileVersions {
+	if tc == nil {
+		return nil
+	}
+	v, _ := tc.Services["file_versions"].(*sandbox.FileVersions)
+	return v
+}
+
+// recordFileVersion 记录工具读取或写入后的文件内容
+func recordFileVersion(tc *tools.ToolContext, path, content string) {
+	if v := fileVersions(tc); v != nil {
+		v.Record(tc.Sandbox.FS().Resolve(path), content)
+	}
+}
+
+// checkFileConflict 文件自 Agent 上次读取后在磁盘上被修改时返回冲突响应，否则返回 nil
+// 冲突响应附带最新内容，并记录为已读取，模型据此重新修改后即可重试
+func checkFileConflict(tc *tools.ToolContext, path, current string) map[string]any {
+	v := fileVersions(tc)
+	if v == nil {
+		return nil
+	}
+	resolved := tc.Sandbox.FS().Resolve(path)
+	if !v.Changed(resolved, current) {
+		return nil
+	}
+	v.Record(resolved, current)
+
+	return map[string]any{
+		"ok":              false,
+		"error":           fmt.Sprintf("%s was modified on disk since it was last read; the write was not applied", path),
+		"code":            agenterr.CodeFileConflict,
+		"file_path":       path,
+		"current_content": current,
+		"current_hash":    sandbox.ContentHash(current),
+		"recommendations": []string{
+			"文件可能被用户修改过，请基于 current_content 重新确定修改内容",
+			"保留用户的改动，不要直接用旧内容覆盖",
+		},
+	}
+}
@@ -90,7 +90,7 @@ func (t *KillShellTool) Execute(ctx context.Context, input map[string]any, tc *t
 			"recommendations": []string{
 				"确认shell_id是否正确",
 				"检查任务是否已经被终止",
-				"使用BashOutput工具查看可用的后台任务",
+				"使用TaskStatus工具查看可用的后台任务",
 			},
 			"shell_id":    shellID,
 			"duration_ms": time.Since(start).Milliseconds(),
@@ -109,7 +109,7 @@ func (t *KillShellTool) Execute(ctx context.Context, input map[string]any, tc *t
 			"duration_ms": time.Since(start).Milliseconds(),
 			"recommendations": []string{
 				"任务已经完成或失败，无需终止",
-				"使用BashOutput工具查看任务结果",
+				"使用TaskOutput工具查看任务结果",
 			},
 		}, nil
 	}
@@ -0,0 +1,538 @@
+package builtin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/astercloud/aster/pkg/lsp"
+	"github.com/astercloud/aster/pkg/sandbox"
+	"github.com/astercloud/aster/pkg/tools"
+)
+
+const (
+	// defaultMaxReferences FindReferences 默认返回的最大引用数
+	defaultMaxReferences = 100
+	// defaultDiagnosticsWait Diagnostics 等待服务器分析的默认秒数
+	defaultDiagnosticsWait = 5
+	// maxDiagnosticsWait Diagnostics 最长等待秒数
+	maxDiagnosticsWait = 60
+)
+
+// lspPositionProperties 代码智能工具共用的位置参数
+func lspPositionProperties() map[string]any {
+	return map[string]any{
+		"file_path": map[string]any{
+			"type":        "string",
+			"description": "符号所在文件的路径",
+		},
+		"line": map[string]any{
+			"type":        "integer",
+			"description": "符号所在行号（从1开始，与 Read 输出一致）",
+		},
+		"symbol": map[string]any{
+			"type":        "string",
+			"description": "该行中的符号名称，用于定位列（推荐）",
+		},
+		"occurrence": map[string]any{
+			"type":        "integer",
+			"description": "符号在该行出现多次时取第几次，默认为1",
+		},
+		"column": map[string]any{
+			"type":        "integer",
+			"description": "符号所在列（从1开始），未提供 symbol 时使用",
+		},
+	}
+}
+
+// lspTarget 代码智能请求的目标位置
+type lspTarget struct {
+	manager *lsp.Manager
+	client  *lsp.Client
+	path    string
+	pos     lsp.Position
+	symbol  string
+}
+
+// resolveLSPTarget 解析文件和位置并确保语言服务器已同步该文件
+// 失败时返回错误响应
+func resolveLSPTarget(ctx context.Context, input map[string]any, tc *tools.ToolContext) (*lspTarget, map[string]any) {
+	if err := ValidateRequired(input, []string{"file_path", "line"}); err != nil {
+		return nil, NewClaudeErrorResponse(err)
+	}
+	manager, errResp := lspManager(tc)
+	if errResp != nil {
+		return nil, errResp
+	}
+
+	path := manager.Resolve(GetStringParam(input, "file_path", ""))
+	client, err := manager.Client(ctx, path)
+	if errors.Is(err, lsp.ErrNoServer) {
+		return nil, NewClaudeErrorResponse(err, "使用 Grep 搜索该符号")
+	}
+	if err != nil {
+		return nil, NewClaudeErrorResponse(err, "确认对应的语言服务器已安装，或使用 Grep 搜索该符号")
+	}
+	text, _, err := client.Sync(path)
+	if err != nil {
+		return nil, NewClaudeErrorResponse(err, "确认文件路径正确")
+	}
+
+	line := GetIntParam(input, "line", 0)
+	symbol := GetStringParam(input, "symbol", "")
+	var pos lsp.Position
+	switch {
+	case symbol != "":
+		pos, err = lsp.FindSymbol(text, line, symbol, GetIntParam(input, "occurrence", 1))
+	case GetIntParam(input, "column", 0) > 0:
+		pos, err = lsp.PositionOf(text, line, GetIntParam(input, "column", 0))
+		symbol = identifierAt(lsp.LineText(text, line-1), pos)
+	default:
+		err = errors.New("either symbol or column is required")
+	}
+	if err != nil {
+		return nil, NewClaudeErrorResponse(err, "使用 Read 确认该行的内容和行号")
+	}
+	return &lspTarget{manager: manager, client: client, path: path, pos: pos, symbol: symbol}, nil
+}
+
+// lspManager 从工具上下文获取语言服务器管理器
+func lspManager(tc *tools.ToolContext) (*lsp.Manager, map[string]any) {
+	if tc != nil && tc.Services != nil {
+		if m, ok := tc.Services["lsp_manager"].(*lsp.Manager); ok && m != nil {
+			return m, nil
+		}
+	}
+	return nil, NewClaudeErrorResponse(errors.New("code intelligence is not available in this environment"), "使用 Grep 搜索符号")
+}
+
+// identifierAt 返回位置处的标识符，用于结果展示
+func identifierAt(line string, pos lsp.Position) string {
+	runes := []rune(line)
+	start := lsp.RuneColumn(line, pos.Character)
+	end := start
+	for end < len(runes) && isSymbolRune(runes[end]) {
+		end++
+	}
+	return string(runes[start:end])
+}
+
+func isSymbolRune(r rune) bool {
+	return r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r > 0x7f
+}
+
+// formatLocations 将位置转为带源码行的结果，行号和列号从1开始
+func formatLocations(manager *lsp.Manager, locs []lsp.Location, limit int) []map[string]any {
+	texts := make(map[string]string)
+	results := make([]map[string]any, 0, min(len(locs), limit))
+	for _, loc := range locs {
+		if len(results) >= limit {
+			break
+		}
+		path := lsp.URIToPath(loc.URI)
+		text, ok := texts[path]
+		if !ok {
+			if data, err := os.ReadFile(path); err == nil {
+				text = string(data)
+			}
+			texts[path] = text
+		}
+		line := lsp.LineText(text, loc.Range.Start.Line)
+		results = append(results, map[string]any{
+			"file":   manager.Rel(path),
+			"line":   loc.Range.Start.Line + 1,
+			"column": lsp.RuneColumn(line, loc.Range.Start.Character) + 1,
+			"text":   strings.TrimSpace(line),
+		})
+	}
+	return results
+}
+
+// FindDefinitionTool 跳转到定义工具
+type FindDefinitionTool struct{}
+
+// NewFindDefinitionTool 创建FindDefinition工具
+func NewFindDefinitionTool(config map[string]any) (tools.Tool, error) {
+	return &FindDefinitionTool{}, nil
+}
+
+func (t *FindDefinitionTool) Name() string {
+	return "FindDefinition"
+}
+
+func (t *FindDefinitionTool) Description() string {
+	return "通过语言服务器查找符号的定义位置"
+}
+
+func (t *FindDefinitionTool) InputSchema() map[string]any {
+	return map[string]any{
+		"type":       "object",
+		"properties": lspPositionProperties(),
+		"required":   []string{"file_path", "line"},
+	}
+}
+
+func (t *FindDefinitionTool) Execute(ctx context.Context, input map[string]any, tc *tools.ToolContext) (any, error) {
+	target, errResp := resolveLSPTarget(ctx, input, tc)
+	if errResp != nil {
+		return errResp, nil
+	}
+	locs, err := target.client.Definition(ctx, target.path, target.pos)
+	if err != nil {
+		return NewClaudeErrorResponse(err), nil
+	}
+	definitions := formatLocations(target.manager, locs, len(locs))
+	return map[string]any{
+		"ok":          true,
+		"symbol":      target.symbol,
+		"definitions": definitions,
+		"count":       len(definitions),
+	}, nil
+}
+
+func (t *FindDefinitionTool) Prompt() string {
+	return `通过语言服务器（gopls、typescript-language-server、pyright 等）查找符号的定义。
+
+使用方法：
+- file_path 和 line 指定符号出现的位置，symbol 指定该行中的符号名称
+- 返回定义所在的文件、行号和该行源码
+
+与 Grep 相比，能区分同名符号、方法接收者和导入别名，结果更准确。
+没有配置对应语言服务器的文件类型请使用 Grep。`
+}
+
+// Annotations 返回工具安全注解
+func (t *FindDefinitionTool) Annotations() *tools.ToolAnnotations {
+	return tools.AnnotationsSafeReadOnly
+}
+
+// FindReferencesTool 查找引用工具
+type FindReferencesTool struct{}
+
+// NewFindReferencesTool 创建FindReferences工具
+func NewFindReferencesTool(config map[string]any) (tools.Tool, error) {
+	return &FindReferencesTool{}, nil
+}
+
+func (t *FindReferencesTool) Name() string {
+	return "FindReferences"
+}
+
+func (t *FindReferencesTool) Description() string {
+	return "通过语言服务器查找符号的所有引用"
+}
+
+func (t *FindReferencesTool) InputSchema() map[string]any {
+	props := lspPositionProperties()
+	props["include_declaration"] = map[string]any{
+		"type":        "boolean",
+		"description": "结果是否包含声明本身，默认为true",
+	}
+	props["max_results"] = map[string]any{
+		"type":        "integer",
+		"description": fmt.Sprintf("最多返回的引用数，默认为%d", defaultMaxReferences),
+	}
+	return map[string]any{
+		"type":       "object",
+		"properties": props,
+		"required":   []string{"file_path", "line"},
+	}
+}
+
+func (t *FindReferencesTool) Execute(ctx context.Context, input map[string]any, tc *tools.ToolContext) (any, error) {
+	target, errResp := resolveLSPTarget(ctx, input, tc)
+	if errResp != nil {
+		return errResp, nil
+	}
+	locs, err := target.client.References(ctx, target.path, target.pos, GetBoolParam(input, "include_declaration", true))
+	if err != nil {
+		return NewClaudeErrorResponse(err), nil
+	}
+
+	limit := GetIntParam(input, "max_results", defaultMaxReferences)
+	if limit <= 0 {
+		limit = defaultMaxReferences
+	}
+	references := formatLocations(target.manager, locs, limit)
+	files := make(map[string]bool)
+	for _, loc := range locs {
+		files[lsp.URIToPath(loc.URI)] = true
+	}
+	result := map[string]any{
+		"ok":         true,
+		"symbol":     target.symbol,
+		"references": references,
+		"count":      len(locs),
+		"files":      len(files),
+	}
+	if len(locs) > len(references) {
+		result["truncated"] = true
+	}
+	return result, nil
+}
+
+func (t *FindReferencesTool) Prompt() string {
+	return `通过语言服务器查找符号的所有引用。
+
+使用方法：
+- file_path 和 line 指定符号出现的位置（定义或任一引用处均可），symbol 指定该行中的符号名称
+- 返回每处引用的文件、行号和该行源码，count 为引用总数
+
+修改函数签名、删除或重命名符号前，先用本工具确认所有调用点。
+与 Grep 相比，不会匹配到注释、字符串或其他作用域中的同名符号。`
+}
+
+// Annotations 返回工具安全注解
+func (t *FindReferencesTool) Annotations() *tools.ToolAnnotations {
+	return tools.AnnotationsSafeReadOnly
+}
+
+// RenameTool 符号重命名工具
+type RenameTool struct{}
+
+// NewRenameTool 创建Rename工具
+func NewRenameTool(config map[string]any) (tools.Tool, error) {
+	return &RenameTool{}, nil
+}
+
+func (t *RenameTool) Name() string {
+	return "Rename"
+}
+
+func (t *RenameTool) Description() string {
+	return "通过语言服务器在整个工作区中安全地重命名符号"
+}
+
+func (t *RenameTool) InputSchema() map[string]any {
+	props := lspPositionProperties()
+	props["new_name"] = map[string]any{
+		"type":        "string",
+		"description": "新的符号名称",
+	}
+	props["dry_run"] = map[string]any{
+		"type":        "boolean",
+		"description": "只返回将修改的文件，不写入，默认为false",
+	}
+	return map[string]any{
+		"type":       "object",
+		"properties": props,
+		"required":   []string{"file_path", "line", "new_name"},
+	}
+}
+
+func (t *RenameTool) Execute(ctx context.Context, input map[string]any, tc *tools.ToolContext) (any, error) {
+	if err := ValidateRequired(input, []string{"new_name"}); err != nil {
+		return NewClaudeErrorResponse(err), nil
+	}
+	newName := GetStringParam(input, "new_name", "")
+	if newName == "" {
+		return NewClaudeErrorResponse(errors.New("new_name cannot be empty")), nil
+	}
+	target, errResp := resolveLSPTarget(ctx, input, tc)
+	if errResp != nil {
+		return errResp, nil
+	}
+
+	edit, err := target.client.Rename(ctx, target.path, target.pos, newName)
+	if err != nil {
+		return NewClaudeErrorResponse(err, "确认位置处是可重命名的符号，且新名称合法且未被占用"), nil
+	}
+	dryRun := GetBoolParam(input, "dry_run", false)
+	if !dryRun {
+		// 工具自身的写入不作为外部变更通知
+		if watches, ok := tc.Services["watch_manager"].(*sandbox.WatchManager); ok {
+			if files, err := edit.FileEdits(); err == nil {
+				for path := range files {
+					watches.Ignore(path)
+				}
+			}
+		}
+	}
+	counts, err := target.manager.ApplyEdit(edit, dryRun)
+	if err != nil {
+		return NewClaudeErrorResponse(err), nil
+	}
+
+	files := make([]string, 0, len(counts))
+	total := 0
+	for file, n := range counts {
+		files = append(files, file)
+		total += n
+	}
+	slices.Sort(files)
+	if !dryRun {
+		// 同步修改后的文件，使后续查询基于新内容
+		for _, file := range files {
+			_, _, _ = target.client.Sync(target.manager.Resolve(file))
+		}
+	}
+
+	return map[string]any{
+		"ok":       true,
+		"symbol":   target.symbol,
+		"new_name": newName,
+		"files":    files,
+		"edits":    total,
+		"dry_run":  dryRun,
+	}, nil
+}
+
+func (t *RenameTool) Prompt() string {
+	return `通过语言服务器重命名符号，同时更新工作区中的所有引用。
+
+使用方法：
+- file_path 和 line 指定符号出现的位置，symbol 指定该行中的当前名称，new_name 为新名称
+- dry_run 为 true 时只返回将修改的文件
+
+与逐个文件 Edit 相比，不会遗漏引用，也不会误改注释、字符串或同名的其他符号。
+修改涉及工作目录外的文件时整体拒绝，不会写入任何文件。
+重命名后可使用 Diagnostics 确认没有引入错误。`
+}
+
+// Annotations 返回工具安全注解
+func (t *RenameTool) Annotations() *tools.ToolAnnotations {
+	return tools.AnnotationsSafeWrite
+}
+
+// DiagnosticsTool 代码诊断工具
+type DiagnosticsTool struct{}
+
+// NewDiagnosticsTool 创建Diagnostics工具
+func NewDiagnosticsTool(config map[string]any) (tools.Tool, error) {
+	return &DiagnosticsTool{}, nil
+}
+
+func (t *DiagnosticsTool) Name() string {
+	return "Diagnostics"
+}
+
+func (t *DiagnosticsTool) Description() string {
+	return "获取语言服务器报告的编译错误和警告"
+}
+
+func (t *DiagnosticsTool) InputSchema() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"file_path": map[string]any{
+				"type":        "string",
+				"description": "要检查的文件；省略时返回已分析文件的全部诊断",
+			},
+			"severity": map[string]any{
+				"type":        "string",
+				"enum":        []string{"error", "warning", "all"},
+				"description": "最低级别，默认为warning（错误和警告）",
+			},
+			"wait_seconds": map[string]any{
+				"type":        "integer",
+				"minimum":     0,
+				"maximum":     maxDiagnosticsWait,
+				"description": fmt.Sprintf("等待语言服务器分析的秒数，默认为%d", defaultDiagnosticsWait),
+			},
+		},
+	}
+}
+
+func (t *DiagnosticsTool) Execute(ctx context.Context, input map[string]any, tc *tools.ToolContext) (any, error) {
+	manager, errResp := lspManager(tc)
+	if errResp != nil {
+		return errResp, nil
+	}
+
+	var maxSeverity lsp.DiagnosticSeverity
+	switch severity := GetStringParam(input, "severity", "warning"); severity {
+	case "error":
+		maxSeverity = lsp.SeverityError
+	case "warning":
+		maxSeverity = lsp.SeverityWarning
+	case "all":
+		maxSeverity = lsp.SeverityHint
+	default:
+		return NewClaudeErrorResponse(fmt.Errorf("invalid severity: %s", severity), "severity 只能是 error、warning、all"), nil
+	}
+
+	byFile := make(map[string][]lsp.Diagnostic)
+	if filePath := GetStringParam(input, "file_path", ""); filePath != "" {
+		path := manager.Resolve(filePath)
+		client, err := manager.Client(ctx, path)
+		if errors.Is(err, lsp.ErrNoServer) {
+			return NewClaudeErrorResponse(err, "使用 Bash 运行编译器或 linter 检查该文件"), nil
+		}
+		if err != nil {
+			return NewClaudeErrorResponse(err, "确认对应的语言服务器已安装，或使用 Bash 运行编译器检查"), nil
+		}
+		wait := min(max(GetIntParam(input, "wait_seconds", defaultDiagnosticsWait), 0), maxDiagnosticsWait)
+		diags, err := client.Diagnostics(ctx, path, time.Duration(wait)*time.Second)
+		if err != nil {
+			return NewClaudeErrorResponse(err), nil
+		}
+		byFile[path] = diags
+	} else {
+		for _, client := range manager.Clients() {
+			for path, diags := range client.AllDiagnostics() {
+				byFile[path] = append(byFile[path], diags...)
+			}
+		}
+	}
+
+	paths := make([]string, 0, len(byFile))
+	for path := range byFile {
+		paths = append(paths, path)
+	}
+	slices.Sort(paths)
+
+	results := make([]map[string]any, 0)
+	counts := map[string]int{}
+	for _, path := range paths {
+		text := ""
+		if data, err := os.ReadFile(path); err == nil {
+			text = string(data)
+		}
+		for _, d := range byFile[path] {
+			severity := d.Severity
+			if severity == 0 {
+				severity = lsp.SeverityError
+			}
+			if severity > maxSeverity {
+				continue
+			}
+			counts[severity.String()]++
+			results = append(results, map[string]any{
+				"file":     manager.Rel(path),
+				"line":     d.Range.Start.Line + 1,
+				"column":   lsp.RuneColumn(lsp.LineText(text, d.Range.Start.Line), d.Range.Start.Character) + 1,
+				"severity": severity.String(),
+				"source":   d.Source,
+				"message":  d.Message,
+			})
+		}
+	}
+
+	return map[string]any{
+		"ok":          true,
+		"diagnostics": results,
+		"count":       len(results),
+		"errors":      counts["error"],
+		"warnings":    counts["warning"],
+	}, nil
+}
+
+func (t *DiagnosticsTool) Prompt() string {
+	return `获取语言服务器报告的编译错误、类型错误和警告。
+
+使用方法：
+- 指定 file_path 时同步该文件的最新内容并等待服务器分析（wait_seconds），返回该文件的诊断
+- 省略 file_path 时返回所有已分析文件的诊断（不触发新的分析）
+
+编辑代码后用本工具快速确认没有引入错误，比运行完整构建更快。
+诊断为空不代表代码完全正确，重要修改仍应运行测试。`
+}
+
+// Annotations 返回工具安全注解
+func (t *DiagnosticsTool) Annotations() *tools.ToolAnnotations {
+	return tools.AnnotationsSafeReadOnly
+}
@@ -0,0 +1,68 @@
+package builtin
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/astercloud/aster/pkg/lsp"
+	"github.com/astercloud/aster/pkg/tools"
+)
+
+func TestCodeIntelTools_Errors(t *testing.T) {
+	dir := t.TempDir()
+	_ = os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("hello world\n"), 0o644)
+	_ = os.WriteFile(filepath.Join(dir, "main.none"), []byte("package main\n"), 0o644)
+	manager := lsp.NewManager(dir, []lsp.ServerConfig{{Name: "none", Command: "aster-no-such-lsp", Extensions: []string{".none"}}})
+	defer manager.Close()
+	withManager := &tools.ToolContext{Services: map[string]any{"lsp_manager": manager}}
+
+	definition, _ := NewFindDefinitionTool(nil)
+	rename, _ := NewRenameTool(nil)
+	diagnostics, _ := NewDiagnosticsTool(nil)
+
+	tests := []struct {
+		name  string
+		tool  tools.Tool
+		input map[string]any
+		tc    *tools.ToolContext
+		want  string
+	}{
+		{"no manager", definition, map[string]any{"file_path": "notes.txt", "line": float64(1), "symbol": "hello"}, &tools.ToolContext{}, "not available"},
+		{"missing line", definition, map[string]any{"file_path": "notes.txt"}, withManager, "missing required parameter: line"},
+		{"no server", definition, map[string]any{"file_path": "notes.txt", "line": float64(1), "symbol": "hello"}, withManager, "no language server"},
+		{"server not installed", definition, map[string]any{"file_path": "main.none", "line": float64(1), "symbol": "main"}, withManager, "not found"},
+		{"missing new_name", rename, map[string]any{"file_path": "notes.txt", "line": float64(1)}, withManager, "new_name"},
+		{"invalid severity", diagnostics, map[string]any{"severity": "fatal"}, withManager, "invalid severity"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := tt.tool.Execute(context.Background(), tt.input, tt.tc)
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			res := result.(map[string]any)
+			if res["ok"] != false || !strings.Contains(res["error"].(string), tt.want) {
+				t.Errorf("Execute() = %v, want error containing %q", res, tt.want)
+			}
+		})
+	}
+
+	// 未指定文件时返回已有诊断（没有运行中的服务器时为空）
+	result, _ := diagnostics.Execute(context.Background(), map[string]any{}, withManager)
+	if res := result.(map[string]any); res["ok"] != true || res["count"] != 0 {
+		t.Errorf("Execute() = %v", res)
+	}
+}
+
+func TestIdentifierAt(t *testing.T) {
+	line := "\tx := 你好World(arg)"
+	if got := identifierAt(line, lsp.Position{Character: 6}); got != "你好World" {
+		t.Errorf("identifierAt() = %q", got)
+	}
+	if got := identifierAt(line, lsp.Position{Character: 100}); got != "" {
+		t.Errorf("identifierAt() past end = %q", got)
+	}
+}
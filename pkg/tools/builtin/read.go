@@ -117,6 +117,7 @@ func (t *ReadTool) Execute(ctx context.Context, input map[string]any, tc *tools.
 			"duration_ms": time.Since(start).Milliseconds(),
 		}, nil
 	}
+	recordFileVersion(tc, filePath, content)
 
 	// 如果文件为空
 	if content == "" {
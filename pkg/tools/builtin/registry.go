@@ -3,18 +3,28 @@ package builtin
 import "github.com/astercloud/aster/pkg/tools"
 
 // RegisterAll 注册所有内置工具 （重要：克制，未经严格的讨论禁止再增加）
-// 保持精简（约18个工具）
+// 保持精简（约28个工具）
 func RegisterAll(registry *tools.Registry) {
-	// 文件操作工具 (5)
+	// 文件操作工具 (7)
 	registry.Register("Read", NewReadTool)
 	registry.Register("Write", NewWriteTool)
 	registry.Register("Edit", NewEditTool)
+	registry.Register("ApplyPatch", NewApplyPatchTool)
 	registry.Register("Glob", NewGlobTool)
 	registry.Register("Grep", NewGrepTool)
+	registry.Register("Watch", NewWatchTool)
 
-	// 命令行执行工具 (3)
+	// 代码智能工具 (4)
+	registry.Register("FindDefinition", NewFindDefinitionTool)
+	registry.Register("FindReferences", NewFindReferencesTool)
+	registry.Register("Rename", NewRenameTool)
+	registry.Register("Diagnostics", NewDiagnosticsTool)
+
+	// 命令行执行工具 (5)
 	registry.Register("Bash", NewBashTool)
 	registry.Register("BashOutput", NewBashOutputTool)
+	registry.Register("TaskOutput", NewTaskOutputTool)
+	registry.Register("TaskStatus", NewTaskStatusTool)
 	registry.Register("KillShell", NewKillShellTool)
 
 	// 智能代理工具 (1)
@@ -25,12 +35,14 @@ func RegisterAll(registry *tools.Registry) {
 	registry.Register("EnterPlanMode", NewEnterPlanModeTool)
 	registry.Register("ExitPlanMode", NewExitPlanModeTool)
 
-	// 用户交互工具 (1)
+	// 用户交互工具 (2)
 	registry.Register("AskUserQuestion", NewAskUserQuestionTool)
+	registry.Register("RenderUI", NewRenderUITool)
 
-	// 网络工具 (2)
+	// 网络工具 (3)
 	registry.Register("WebFetch", NewWebFetchTool)
 	registry.Register("WebSearch", NewWebSearchTool)
+	registry.Register("Browser", NewBrowserTool)
 
 	// MCP 资源工具 (2)
 	registry.Register("ListMcpResources", NewListMcpResourcesTool)
@@ -42,12 +54,17 @@ func RegisterAll(registry *tools.Registry) {
 
 // FileSystemTools 返回文件系统工具列表
 func FileSystemTools() []string {
-	return []string{"Read", "Write", "Edit", "Glob", "Grep"}
+	return []string{"Read", "Write", "Edit", "ApplyPatch", "Glob", "Grep", "Watch"}
+}
+
+// CodeIntelTools 返回代码智能工具列表
+func CodeIntelTools() []string {
+	return []string{"FindDefinition", "FindReferences", "Rename", "Diagnostics"}
 }
 
 // ExecutionTools 返回执行工具列表
 func ExecutionTools() []string {
-	return []string{"Bash", "BashOutput", "KillShell"}
+	return []string{"Bash", "BashOutput", "TaskOutput", "TaskStatus", "KillShell"}
 }
 
 // AgentTools 返回智能代理工具列表
@@ -62,12 +79,12 @@ func PlanningTools() []string {
 
 // InteractionTools 返回用户交互工具列表
 func InteractionTools() []string {
-	return []string{"AskUserQuestion"}
+	return []string{"AskUserQuestion", "RenderUI"}
 }
 
 // NetworkTools 返回网络工具列表
 func NetworkTools() []string {
-	return []string{"WebFetch", "WebSearch"}
+	return []string{"WebFetch", "WebSearch", "Browser"}
 }
 
 // McpTools 返回 MCP 资源工具列表
@@ -80,9 +97,10 @@ func SkillTools() []string {
 	return []string{"Skill"}
 }
 
-// AllTools 返回所有内置工具列表（共18个）
+// AllTools 返回所有内置工具列表（共28个）
 func AllTools() []string {
 	tools := FileSystemTools()
+	tools = append(tools, CodeIntelTools()...)
 	tools = append(tools, ExecutionTools()...)
 	tools = append(tools, AgentTools()...)
 	tools = append(tools, PlanningTools()...)
@@ -0,0 +1,246 @@
+package builtin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/astercloud/aster/pkg/tools"
+	"github.com/astercloud/aster/pkg/types"
+	"github.com/astercloud/aster/pkg/uiproto"
+)
+
+// DefaultRenderUIWaitTimeout RenderUI 等待用户动作的默认超时
+const DefaultRenderUIWaitTimeout = 30 * time.Minute
+
+// RenderUITool 声明式 UI 渲染工具
+// 模型通过 AsterUIMessage 在桌面端/Web 前端创建表单、表格、进度视图，
+// 用户动作以 ControlUIActionEvent 回到 Agent，也可由本工具等待后作为结果返回
+type RenderUITool struct {
+	// fallback 未注入 ui_surface_manager 时使用的本地管理器（仅做校验和状态维护）
+	fallback *uiproto.SurfaceManager
+}
+
+// NewRenderUITool 创建RenderUI工具
+// config 支持 custom_types: 允许的 Custom 组件类型列表
+func NewRenderUITool(config map[string]any) (tools.Tool, error) {
+	managerConfig := &uiproto.ManagerConfig{Catalogs: uiproto.DefaultCatalogs}
+	if config != nil {
+		if list, ok := config["custom_types"].([]string); ok {
+			managerConfig.CustomTypes = list
+		} else {
+			managerConfig.CustomTypes = GetStringSliceParam(config, "custom_types")
+		}
+	}
+	return &RenderUITool{fallback: uiproto.NewSurfaceManager(managerConfig)}, nil
+}
+
+func (t *RenderUITool) Name() string {
+	return "RenderUI"
+}
+
+func (t *RenderUITool) Description() string {
+	return "在前端渲染声明式 UI（表单、表格、进度视图），并可等待用户操作"
+}
+
+func (t *RenderUITool) InputSchema() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"messages": map[string]any{
+				"type":        "array",
+				"minItems":    1,
+				"description": "按顺序应用的 AsterUIMessage 列表，每条消息只能包含 createSurface、surfaceUpdate、dataModelUpdate、beginRendering、deleteSurface 之一",
+				"items": map[string]any{
+					"type": "object",
+				},
+			},
+			"wait_for_action": map[string]any{
+				"type":        "boolean",
+				"default":     false,
+				"description": "是否等待用户在 surface_id 上的下一次操作，并把操作作为工具结果返回",
+			},
+			"surface_id": map[string]any{
+				"type":        "string",
+				"description": "wait_for_action 时等待的 Surface；省略时使用消息中的最后一个 Surface",
+			},
+			"timeout_seconds": map[string]any{
+				"type":        "integer",
+				"minimum":     1,
+				"description": "等待用户操作的超时秒数（默认1800）",
+			},
+		},
+		"required": []string{"messages"},
+	}
+}
+
+func (t *RenderUITool) Execute(ctx context.Context, input map[string]any, tc *tools.ToolContext) (any, error) {
+	if err := ValidateRequired(input, []string{"messages"}); err != nil {
+		return NewClaudeErrorResponse(err), nil
+	}
+
+	msgs, err := parseUIMessages(input["messages"])
+	if err != nil {
+		return NewClaudeErrorResponse(err, "每条消息必须是合法的 AsterUIMessage，组件类型只能使用目录中的组件"), nil
+	}
+
+	manager := t.fallback
+	if tc != nil && tc.Services != nil {
+		if m, ok := tc.Services["ui_surface_manager"].(*uiproto.SurfaceManager); ok {
+			manager = m
+		}
+	}
+
+	// 逐条应用：校验通过的消息才发送给前端
+	var lastSurface string
+	for i, msg := range msgs {
+		if err := manager.Apply(msg); err != nil {
+			return map[string]any{
+				"ok":              false,
+				"error":           fmt.Sprintf("messages[%d]: %v", i, err),
+				"applied":         i,
+				"recommendations": []string{"已应用的消息不会回滚，修正后只需重新发送失败及之后的消息"},
+			}, nil
+		}
+		if id := uiMessageSurfaceID(msg); id != "" {
+			lastSurface = id
+		}
+		if tc != nil && tc.Reporter != nil {
+			tc.Reporter.Intermediate("ui", msg)
+		}
+	}
+
+	result := map[string]any{
+		"ok":      true,
+		"applied": len(msgs),
+	}
+	if lastSurface != "" {
+		result["surface_id"] = lastSurface
+	}
+
+	if !GetBoolParam(input, "wait_for_action", false) {
+		return result, nil
+	}
+
+	surfaceID := GetStringParam(input, "surface_id", lastSurface)
+	if _, ok := manager.Surface(surfaceID); !ok {
+		return NewClaudeErrorResponse(fmt.Errorf("cannot wait on unknown surface %q", surfaceID)), nil
+	}
+
+	timeout := DefaultRenderUIWaitTimeout
+	if secs := GetIntParam(input, "timeout_seconds", 0); secs > 0 {
+		timeout = time.Duration(secs) * time.Second
+	}
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	result["surface_id"] = surfaceID
+	action, err := manager.WaitAction(waitCtx, surfaceID)
+	switch {
+	case err == nil:
+		result["action"] = map[string]any{
+			"name":         action.Name,
+			"component_id": action.SourceComponentID,
+			"context":      action.Context,
+			"timestamp":    action.Timestamp,
+		}
+	case errors.Is(err, uiproto.ErrSurfaceDeleted):
+		result["deleted"] = true
+		result["message"] = "Surface 已被删除，用户未进行操作"
+	case errors.Is(err, context.DeadlineExceeded):
+		result["timeout"] = true
+		result["message"] = "用户未在规定时间内操作，请继续执行；之后的操作会以 UI 动作事件送达"
+	default:
+		return NewClaudeErrorResponse(err), nil
+	}
+	return result, nil
+}
+
+// parseUIMessages 严格解析 AsterUIMessage 列表，拒绝目录之外的组件与字段
+func parseUIMessages(value any) ([]*types.AsterUIMessage, error) {
+	raw, ok := value.([]any)
+	if !ok {
+		return nil, errors.New("messages must be an array")
+	}
+	if len(raw) == 0 {
+		return nil, errors.New("messages cannot be empty")
+	}
+
+	msgs := make([]*types.AsterUIMessage, 0, len(raw))
+	for i, item := range raw {
+		data, err := json.Marshal(item)
+		if err != nil {
+			return nil, fmt.Errorf("messages[%d]: %w", i, err)
+		}
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		var msg types.AsterUIMessage
+		if err := dec.Decode(&msg); err != nil {
+			return nil, fmt.Errorf("messages[%d]: %w", i, err)
+		}
+		msgs = append(msgs, &msg)
+	}
+	return msgs, nil
+}
+
+// uiMessageSurfaceID 返回消息作用的 Surface ID
+func uiMessageSurfaceID(msg *types.AsterUIMessage) string {
+	switch {
+	case msg.CreateSurface != nil:
+		return msg.CreateSurface.SurfaceID
+	case msg.SurfaceUpdate != nil:
+		return msg.SurfaceUpdate.SurfaceID
+	case msg.DataModelUpdate != nil:
+		return msg.DataModelUpdate.SurfaceID
+	case msg.BeginRendering != nil:
+		return msg.BeginRendering.SurfaceID
+	}
+	return ""
+}
+
+func (t *RenderUITool) Prompt() string {
+	return `在用户的桌面端/Web 前端渲染声明式 UI，用于表单收集、表格展示和进度视图。
+
+消息格式（AsterUIMessage，每条只含一个操作，按顺序应用）:
+- createSurface: {"surfaceId": "...", "catalogId": "standard"} 创建 Surface；指定 catalogId 后组件须属于该目录（"id" 为最新版本，"id@version" 固定版本）
+- surfaceUpdate: {"surfaceId": "...", "components": [{"id": "...", "component": {"<类型>": {...}}}]} 定义/更新组件（邻接表，子组件通过 children 引用 ID）
+- dataModelUpdate: {"surfaceId": "...", "path": "/form", "op": "replace|add|remove", "contents": ...} 更新数据模型
+- beginRendering: {"surfaceId": "...", "root": "<根组件ID>"} 开始渲染（所有子组件引用必须已定义）
+- deleteSurface: {"surfaceId": "..."} 删除 Surface
+
+可用组件: Text, Image, Icon, Video, AudioPlayer, Button, Row, Column, Card, List, TextField,
+Checkbox, Select, DateTimeInput, Slider, MultipleChoice, Divider, Modal, Tabs, Custom
+（Custom 组件的 type 与 props 须符合所引用目录中的自定义组件定义）
+
+属性值: {"literalString": "..."}、{"literalNumber": 1}、{"literalBoolean": true} 或数据绑定 {"path": "/form/name"}
+
+使用场景:
+- 表单: Column + TextField/Select/Checkbox（value 必须绑定 path）+ Button（action 必填，actionContext 可引用表单数据）
+- 表格: List 使用 template 绑定数组数据，模板组件为 Row
+- 进度视图: Text/Slider 绑定 path，之后用 dataModelUpdate 更新进度
+
+使用示例:
+{
+  "messages": [
+    {"createSurface": {"surfaceId": "deploy-form", "catalogId": "standard"}},
+    {"surfaceUpdate": {"surfaceId": "deploy-form", "components": [
+      {"id": "root", "component": {"Column": {"children": {"explicitList": ["env", "submit"]}}}},
+      {"id": "env", "component": {"TextField": {"label": {"literalString": "环境"}, "value": {"path": "/env"}}}},
+      {"id": "submit", "component": {"Button": {"label": {"literalString": "部署"}, "action": "deploy",
+        "actionContext": {"env": {"path": "/env"}}}}}
+    ]}},
+    {"dataModelUpdate": {"surfaceId": "deploy-form", "path": "/env", "contents": "staging"}},
+    {"beginRendering": {"surfaceId": "deploy-form", "root": "root"}}
+  ],
+  "wait_for_action": true
+}
+
+重要规则:
+1. 校验失败时返回 applied（已应用的消息数），只需修正并重发失败及之后的消息
+2. wait_for_action 为 true 时返回用户操作 action（name、component_id、context）
+3. 返回 timeout: true 表示用户未操作，之后的操作会以 UI 动作事件送达
+4. 更新已有 Surface 时不要重复 createSurface`
+}
@@ -0,0 +1,189 @@
+package builtin
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/astercloud/aster/pkg/tools"
+	"github.com/astercloud/aster/pkg/types"
+	"github.com/astercloud/aster/pkg/uiproto"
+)
+
+type recordingReporter struct {
+	labels []string
+	data   []any
+}
+
+func (r *recordingReporter) Progress(float64, string, int, int, map[string]any, int64) {}
+
+func (r *recordingReporter) Intermediate(label string, data any) {
+	r.labels = append(r.labels, label)
+	r.data = append(r.data, data)
+}
+
+// uiInput 把 JSON 文本解析为工具输入，模拟模型生成的参数
+func uiInput(t *testing.T, raw string) map[string]any {
+	t.Helper()
+	var input map[string]any
+	if err := json.Unmarshal([]byte(raw), &input); err != nil {
+		t.Fatalf("invalid test input: %v", err)
+	}
+	return input
+}
+
+const renderUIForm = `{
+  "messages": [
+    {"createSurface": {"surfaceId": "form"}},
+    {"surfaceUpdate": {"surfaceId": "form", "components": [
+      {"id": "root", "component": {"Column": {"children": {"explicitList": ["env", "submit"]}}}},
+      {"id": "env", "component": {"TextField": {"value": {"path": "/env"}}}},
+      {"id": "submit", "component": {"Button": {"label": {"literalString": "Deploy"}, "action": "deploy",
+        "actionContext": {"env": {"path": "/env"}}}}}
+    ]}},
+    {"dataModelUpdate": {"surfaceId": "form", "path": "/env", "contents": "staging"}},
+    {"beginRendering": {"surfaceId": "form", "root": "root"}}
+  ]%s
+}`
+
+func newRenderUIContext() (*tools.ToolContext, *uiproto.SurfaceManager, *recordingReporter) {
+	manager := uiproto.NewSurfaceManager(nil)
+	reporter := &recordingReporter{}
+	return &tools.ToolContext{
+		Reporter: reporter,
+		Services: map[string]any{"ui_surface_manager": manager},
+	}, manager, reporter
+}
+
+func TestRenderUITool_Render(t *testing.T) {
+	tool, err := NewRenderUITool(nil)
+	if err != nil {
+		t.Fatalf("NewRenderUITool() error = %v", err)
+	}
+	tc, manager, reporter := newRenderUIContext()
+
+	result, err := tool.Execute(context.Background(), uiInput(t, strings.Replace(renderUIForm, "%s", "", 1)), tc)
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	res := result.(map[string]any)
+	if res["ok"] != true || res["applied"] != 4 || res["surface_id"] != "form" {
+		t.Fatalf("Execute() = %v", res)
+	}
+
+	s, ok := manager.Surface("form")
+	if !ok || !s.Rendering || s.DataModel["env"] != "staging" {
+		t.Errorf("surface = %+v", s)
+	}
+	if len(reporter.data) != 4 {
+		t.Fatalf("reported %d UI messages, want 4", len(reporter.data))
+	}
+	if _, ok := reporter.data[0].(*types.AsterUIMessage); !ok || reporter.labels[0] != "ui" {
+		t.Errorf("reported %q %T, want ui *types.AsterUIMessage", reporter.labels[0], reporter.data[0])
+	}
+}
+
+func TestRenderUITool_Validation(t *testing.T) {
+	tool, _ := NewRenderUITool(nil)
+
+	tests := []struct {
+		name    string
+		input   string
+		applied any
+	}{
+		{
+			name:  "unknown component type",
+			input: `{"messages": [{"createSurface": {"surfaceId": "s"}}, {"surfaceUpdate": {"surfaceId": "s", "components": [{"id": "t", "component": {"Table": {}}}]}}]}`,
+		},
+		{
+			name:    "button without action",
+			input:   `{"messages": [{"createSurface": {"surfaceId": "s"}}, {"surfaceUpdate": {"surfaceId": "s", "components": [{"id": "b", "component": {"Button": {"label": {"literalString": "Go"}}}}]}}]}`,
+			applied: 1,
+		},
+		{
+			name:    "unknown surface",
+			input:   `{"messages": [{"beginRendering": {"surfaceId": "missing", "root": "root"}}]}`,
+			applied: 0,
+		},
+		{
+			name:  "messages not an array",
+			input: `{"messages": {"createSurface": {"surfaceId": "s"}}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc, _, reporter := newRenderUIContext()
+			result, err := tool.Execute(context.Background(), uiInput(t, tt.input), tc)
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			res := result.(map[string]any)
+			if res["ok"] != false || res["error"] == "" {
+				t.Fatalf("Execute() = %v, want validation error", res)
+			}
+			if res["applied"] != tt.applied {
+				t.Errorf("applied = %v, want %v", res["applied"], tt.applied)
+			}
+			if len(reporter.data) != res["applied"] && tt.applied != nil {
+				t.Errorf("reported %d messages, want %v", len(reporter.data), tt.applied)
+			}
+		})
+	}
+}
+
+func TestRenderUITool_WaitForAction(t *testing.T) {
+	tool, _ := NewRenderUITool(nil)
+	tc, manager, _ := newRenderUIContext()
+
+	// 模拟前端：Surface 开始渲染后点击按钮
+	go func() {
+		deadline := time.Now().Add(2 * time.Second)
+		for time.Now().Before(deadline) {
+			if s, ok := manager.Surface("form"); ok && s.Rendering {
+				err := manager.HandleClientMessage(context.Background(), &types.ClientMessage{
+					UserAction: &types.UserActionMessage{Name: "deploy", SurfaceID: "form", SourceComponentID: "submit"},
+				})
+				if err == nil {
+					return
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}()
+
+	input := uiInput(t, strings.Replace(renderUIForm, "%s", `, "wait_for_action": true, "timeout_seconds": 5`, 1))
+	result, err := tool.Execute(context.Background(), input, tc)
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	res := result.(map[string]any)
+	action, ok := res["action"].(map[string]any)
+	if !ok {
+		t.Fatalf("Execute() = %v, want action", res)
+	}
+	if action["name"] != "deploy" || action["component_id"] != "submit" {
+		t.Errorf("action = %v", action)
+	}
+	if ctx, _ := action["context"].(map[string]any); ctx["env"] != "staging" {
+		t.Errorf("action context = %v, want env resolved from data model", action["context"])
+	}
+}
+
+func TestRenderUITool_WaitTimeout(t *testing.T) {
+	tool, _ := NewRenderUITool(nil)
+	tc, _, _ := newRenderUIContext()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	input := uiInput(t, strings.Replace(renderUIForm, "%s", `, "wait_for_action": true`, 1))
+	result, err := tool.Execute(ctx, input, tc)
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if res := result.(map[string]any); res["timeout"] != true {
+		t.Errorf("Execute() = %v, want timeout", res)
+	}
+}
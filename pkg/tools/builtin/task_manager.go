@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -27,6 +28,9 @@ type TaskManager interface {
 	// GetTaskOutput 获取任务输出
 	GetTaskOutput(taskID string, filter string, lines int) (string, string, error)
 
+	// ReadNewOutput 读取上次读取之后的新输出并推进读取位置，fromStart 为 true 时从头读取
+	ReadNewOutput(taskID string, fromStart bool) (string, string, error)
+
 	// KillTask 终止任务
 	KillTask(taskID string, signal string, timeout int) error
 
@@ -66,6 +70,8 @@ type TaskInfo struct {
 	Options    *TaskOptions      `json:"options"`
 	Metadata   map[string]string `json:"metadata"`
 	LastUpdate time.Time         `json:"last_update"`
+	StdoutRead int64             `json:"stdout_read"` // ReadNewOutput 已读取的 stdout 字节数
+	StderrRead int64             `json:"stderr_read"` // ReadNewOutput 已读取的 stderr 字节数
 }
 
 // FileTaskManager 基于文件系统的任务管理器实现
@@ -142,8 +148,12 @@ func (tm *FileTaskManager) StartTask(ctx context.Context, cmd string, opts *Task
 	// 构建命令
 	fullCmd := tm.buildCommand(cmd, opts)
 
-	// 启动命令
-	cmdObj := exec.CommandContext(ctx, "bash", "-c", fullCmd)
+	// 启动命令，后台进程不随当前工具调用的取消而终止，但不超过 Timeout
+	runCtx, cancel := context.WithoutCancel(ctx), context.CancelFunc(func() {})
+	if opts.Timeout > 0 {
+		runCtx, cancel = context.WithTimeout(runCtx, opts.Timeout)
+	}
+	cmdObj := exec.CommandContext(runCtx, "bash", "-c", fullCmd)
 	cmdObj.Dir = opts.WorkDir
 
 	// 设置环境变量
@@ -162,28 +172,29 @@ func (tm *FileTaskManager) StartTask(ctx context.Context, cmd string, opts *Task
 	if opts.CaptureOutput {
 		outFile, err := os.Create(outputFile)
 		if err != nil {
+			cancel()
 			return nil, fmt.Errorf("failed to create output file: %w", err)
 		}
 
 		errFile, err := os.Create(errorFile)
 		if err != nil {
 			_ = outFile.Close()
+			cancel()
 			return nil, fmt.Errorf("failed to create error file: %w", err)
 		}
 
 		cmdObj.Stdout = outFile
 		cmdObj.Stderr = errFile
 
-		// 延迟关闭文件
-		go func() {
-			defer func() { _ = outFile.Close() }()
-			defer func() { _ = errFile.Close() }()
-		}()
+		// 子进程持有文件描述符的副本，启动后即可关闭
+		defer func() { _ = outFile.Close() }()
+		defer func() { _ = errFile.Close() }()
 	}
 
 	// 启动进程
 	err := cmdObj.Start()
 	if err != nil {
+		cancel()
 		return nil, fmt.Errorf("failed to start command: %w", err)
 	}
 
@@ -196,15 +207,18 @@ func (tm *FileTaskManager) StartTask(ctx context.Context, cmd string, opts *Task
 	_ = tm.saveTask(taskInfo)
 
 	// 启动监控协程
-	go tm.monitorTask(ctx, taskInfo, cmdObj)
+	go func() {
+		defer cancel()
+		tm.monitorTask(ctx, taskInfo, cmdObj)
+	}()
 
 	return taskInfo, nil
 }
 
-// GetTask 获取任务信息
+// GetTask 获取任务信息的快照
 func (tm *FileTaskManager) GetTask(taskID string) (*TaskInfo, error) {
-	tm.mu.RLock()
-	defer tm.mu.RUnlock()
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
 
 	task, exists := tm.tasks[taskID]
 	if !exists {
@@ -216,7 +230,8 @@ func (tm *FileTaskManager) GetTask(taskID string) (*TaskInfo, error) {
 		tm.updateTaskStatus(task)
 	}
 
-	return task, nil
+	snapshot := *task
+	return &snapshot, nil
 }
 
 // GetTaskOutput 获取任务输出
@@ -259,6 +274,67 @@ func (tm *FileTaskManager) GetTaskOutput(taskID string, filter string, lines int
 	return stdoutStr, stderrStr, nil
 }
 
+// ReadNewOutput 读取上次读取之后的新输出并推进读取位置，fromStart 为 true 时从头读取
+// 输出文件被清空（如 clear_cache）后从头读取
+func (tm *FileTaskManager) ReadNewOutput(taskID string, fromStart bool) (string, string, error) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	task, exists := tm.tasks[taskID]
+	if !exists {
+		return "", "", fmt.Errorf("task not found: %s", taskID)
+	}
+	if task.Options == nil {
+		return "", "", nil
+	}
+	if fromStart {
+		task.StdoutRead, task.StderrRead = 0, 0
+	}
+
+	stdout, stdoutRead, err := readFileFrom(filepath.Join(task.Options.OutputDir, taskID+".stdout"), task.StdoutRead)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read stdout: %w", err)
+	}
+	stderr, stderrRead, err := readFileFrom(filepath.Join(task.Options.OutputDir, taskID+".stderr"), task.StderrRead)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read stderr: %w", err)
+	}
+
+	if stdoutRead != task.StdoutRead || stderrRead != task.StderrRead {
+		task.StdoutRead, task.StderrRead = stdoutRead, stderrRead
+		_ = tm.saveTask(task)
+	}
+	return stdout, stderr, nil
+}
+
+// readFileFrom 从 offset 读取到文件末尾，返回内容和新的读取位置；文件不存在时返回空
+func readFileFrom(path string, offset int64) (string, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", offset, nil
+		}
+		return "", offset, err
+	}
+	defer func() { _ = f.Close() }()
+
+	info, err := f.Stat()
+	if err != nil {
+		return "", offset, err
+	}
+	if info.Size() < offset {
+		offset = 0
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return "", offset, err
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return "", offset, err
+	}
+	return string(data), offset + int64(len(data)), nil
+}
+
 // KillTask 终止任务
 func (tm *FileTaskManager) KillTask(taskID string, signal string, timeout int) error {
 	tm.mu.Lock()
@@ -300,10 +376,10 @@ func (tm *FileTaskManager) KillTask(taskID string, signal string, timeout int) e
 	return nil
 }
 
-// ListTasks 列出所有任务
+// ListTasks 列出所有任务的快照
 func (tm *FileTaskManager) ListTasks() ([]*TaskInfo, error) {
-	tm.mu.RLock()
-	defer tm.mu.RUnlock()
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
 
 	tasks := make([]*TaskInfo, 0, len(tm.tasks))
 	for _, task := range tm.tasks {
@@ -311,7 +387,8 @@ func (tm *FileTaskManager) ListTasks() ([]*TaskInfo, error) {
 		if task.Status == "running" {
 			tm.updateTaskStatus(task)
 		}
-		tasks = append(tasks, task)
+		snapshot := *task
+		tasks = append(tasks, &snapshot)
 	}
 
 	return tasks, nil
@@ -378,15 +455,8 @@ func (tm *FileTaskManager) buildCommand(cmd string, opts *TaskOptions) string {
 }
 
 // monitorTask 监控任务执行
+// 任务结束后保留输出文件供读取，由 cleanupCompletedTasks 定期清理
 func (tm *FileTaskManager) monitorTask(ctx context.Context, task *TaskInfo, cmd *exec.Cmd) {
-	defer func() {
-		// 发送清理信号
-		select {
-		case tm.cleanupChan <- task.ID:
-		default:
-		}
-	}()
-
 	// 等待命令完成
 	err := cmd.Wait()
 
@@ -399,7 +469,13 @@ func (tm *FileTaskManager) monitorTask(ctx context.Context, task *TaskInfo, cmd
 	task.Duration = now.Sub(task.StartTime)
 	task.LastUpdate = now
 
-	if err != nil {
+	if task.Status == "killed" {
+		// 保留 KillTask 设置的状态
+		exitErr := &exec.ExitError{}
+		if errors.As(err, &exitErr) {
+			task.ExitCode = exitErr.ExitCode()
+		}
+	} else if err != nil {
 		exitErr := &exec.ExitError{}
 		if errors.As(err, &exitErr) {
 			task.ExitCode = exitErr.ExitCode()
@@ -525,13 +601,17 @@ func (tm *FileTaskManager) cleanupCompletedTasks() {
 	defer tm.mu.Unlock()
 
 	for _, task := range tm.tasks {
-		if task.Status == "completed" || task.Status == "failed" {
-			// 清理超过1小时的已完成任务
-			if time.Since(*task.EndTime) > time.Hour {
+		if task.Status == "completed" || task.Status == "failed" || task.Status == "killed" {
+			// 清理超过1小时的已结束任务
+			if task.EndTime != nil && time.Since(*task.EndTime) > time.Hour {
 				delete(tm.tasks, task.ID)
 
 				taskFile := filepath.Join(tm.dataDir, task.ID+".json")
 				_ = os.Remove(taskFile)
+				if task.Options != nil {
+					_ = os.Remove(filepath.Join(task.Options.OutputDir, task.ID+".stdout"))
+					_ = os.Remove(filepath.Join(task.Options.OutputDir, task.ID+".stderr"))
+				}
 			}
 		}
 	}
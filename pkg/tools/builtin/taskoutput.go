@@ -0,0 +1,268 @@
+package builtin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/astercloud/aster/pkg/tools"
+)
+
+const (
+	// maxTaskOutputChars 单次返回的输出上限，超出时保留末尾
+	maxTaskOutputChars = 30000
+	// maxTaskOutputWait block 模式的最长等待时间
+	maxTaskOutputWait = 10 * time.Minute
+)
+
+// taskOutputPollInterval block 模式下检查任务状态的间隔
+var taskOutputPollInterval = 200 * time.Millisecond
+
+// TaskOutputTool 后台任务增量输出读取工具
+// 每次调用返回上次读取之后的新输出，可选等待任务结束
+type TaskOutputTool struct{}
+
+// NewTaskOutputTool 创建TaskOutput工具
+func NewTaskOutputTool(config map[string]any) (tools.Tool, error) {
+	return &TaskOutputTool{}, nil
+}
+
+func (t *TaskOutputTool) Name() string {
+	return "TaskOutput"
+}
+
+func (t *TaskOutputTool) Description() string {
+	return "读取后台任务自上次读取以来的新输出"
+}
+
+func (t *TaskOutputTool) InputSchema() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"task_id": map[string]any{
+				"type":        "string",
+				"description": "Bash以run_in_background启动时返回的task_id",
+			},
+			"block": map[string]any{
+				"type":        "boolean",
+				"description": "是否等待任务结束（或超时）后再返回，默认为false",
+			},
+			"timeout": map[string]any{
+				"type":        "integer",
+				"description": "block为true时的最长等待时间（毫秒），默认30000，最大600000",
+			},
+			"filter": map[string]any{
+				"type":        "string",
+				"description": "可选的正则表达式，只返回匹配的输出行",
+			},
+			"from_start": map[string]any{
+				"type":        "boolean",
+				"description": "从头返回全部输出，而不是只返回上次读取之后的新输出，默认为false",
+			},
+		},
+		"required": []string{"task_id"},
+	}
+}
+
+func (t *TaskOutputTool) Execute(ctx context.Context, input map[string]any, tc *tools.ToolContext) (any, error) {
+	if err := ValidateRequired(input, []string{"task_id"}); err != nil {
+		return NewClaudeErrorResponse(err), nil
+	}
+
+	taskID := GetStringParam(input, "task_id", "")
+	block := GetBoolParam(input, "block", false)
+	timeoutMs := GetIntParam(input, "timeout", 30000)
+	filter := GetStringParam(input, "filter", "")
+	fromStart := GetBoolParam(input, "from_start", false)
+
+	if taskID == "" {
+		return NewClaudeErrorResponse(errors.New("task_id cannot be empty")), nil
+	}
+
+	var filterRe *regexp.Regexp
+	if filter != "" {
+		re, err := regexp.Compile(filter)
+		if err != nil {
+			return NewClaudeErrorResponse(fmt.Errorf("invalid filter: %w", err)), nil
+		}
+		filterRe = re
+	}
+
+	start := time.Now()
+	taskManager := GetGlobalTaskManager()
+
+	task, err := taskManager.GetTask(taskID)
+	if err != nil {
+		return map[string]any{
+			"ok":    false,
+			"error": "background task not found: " + taskID,
+			"recommendations": []string{
+				"确认task_id是否正确",
+				"使用TaskStatus列出所有后台任务",
+			},
+			"task_id":     taskID,
+			"duration_ms": time.Since(start).Milliseconds(),
+		}, nil
+	}
+
+	timedOut := false
+	if block && task.Status == "running" {
+		wait := min(time.Duration(timeoutMs)*time.Millisecond, maxTaskOutputWait)
+		task, timedOut = t.waitForTask(ctx, taskManager, task, wait)
+	}
+
+	stdout, stderr, err := taskManager.ReadNewOutput(taskID, fromStart)
+	if err != nil {
+		return map[string]any{
+			"ok":          false,
+			"error":       fmt.Sprintf("failed to read task output: %v", err),
+			"task_id":     taskID,
+			"duration_ms": time.Since(start).Milliseconds(),
+		}, nil
+	}
+
+	if filterRe != nil {
+		stdout = filterLines(stdout, filterRe)
+		stderr = filterLines(stderr, filterRe)
+	}
+	stdout, stdoutTruncated := tailChars(stdout, maxTaskOutputChars)
+	stderr, stderrTruncated := tailChars(stderr, maxTaskOutputChars)
+
+	running := task.Status == "running"
+	response := map[string]any{
+		"ok":             true,
+		"task_id":        taskID,
+		"command":        task.Command,
+		"status":         task.Status,
+		"running":        running,
+		"stdout":         stdout,
+		"stderr":         stderr,
+		"has_new_output": stdout != "" || stderr != "",
+		"truncated":      stdoutTruncated || stderrTruncated,
+		"elapsed_ms":     time.Since(task.StartTime).Milliseconds(),
+		"duration_ms":    time.Since(start).Milliseconds(),
+	}
+	if task.PID > 0 {
+		response["pid"] = task.PID
+	}
+	if !running {
+		response["exit_code"] = task.ExitCode
+	}
+	if block {
+		response["timed_out"] = timedOut
+	}
+	if running {
+		response["hint"] = "任务仍在运行，稍后再次调用 TaskOutput 获取新输出，或使用 block=true 等待结束"
+	}
+
+	return response, nil
+}
+
+// waitForTask 等待任务结束，返回最新任务信息以及是否超时
+func (t *TaskOutputTool) waitForTask(ctx context.Context, taskManager TaskManager, task *TaskInfo, wait time.Duration) (*TaskInfo, bool) {
+	deadline := time.NewTimer(wait)
+	defer deadline.Stop()
+	ticker := time.NewTicker(taskOutputPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return task, false
+		case <-deadline.C:
+			return task, true
+		case <-ticker.C:
+		}
+
+		latest, err := taskManager.GetTask(task.ID)
+		if err != nil {
+			return task, false
+		}
+		task = latest
+		if task.Status != "running" {
+			return task, false
+		}
+	}
+}
+
+// filterLines 只保留匹配正则的行
+func filterLines(output string, re *regexp.Regexp) string {
+	if output == "" {
+		return output
+	}
+	var matched []string
+	for line := range strings.SplitSeq(output, "\n") {
+		if re.MatchString(line) {
+			matched = append(matched, line)
+		}
+	}
+	return strings.Join(matched, "\n")
+}
+
+// tailChars 超出上限时保留末尾 limit 个字符
+func tailChars(output string, limit int) (string, bool) {
+	runes := []rune(output)
+	if len(runes) <= limit {
+		return output, false
+	}
+	return string(runes[len(runes)-limit:]), true
+}
+
+func (t *TaskOutputTool) Prompt() string {
+	return `读取后台任务的输出。
+
+功能特性：
+- 增量读取：每次只返回上次读取之后的新输出
+- 可等待任务结束后再返回（block）
+- 支持正则表达式过滤输出行
+- 返回任务状态和退出码
+
+使用指南：
+- task_id: 必需参数，Bash 以 run_in_background 启动时返回的 task_id
+- block: 可选参数，等待任务结束或超时后返回
+- timeout: 可选参数，block 的最长等待时间（毫秒）
+- filter: 可选参数，正则表达式过滤器
+- from_start: 可选参数，从头返回全部输出
+
+注意事项：
+- 启动开发服务器或长时间构建后可以继续其他工作，稍后再读取输出
+- 单次返回的输出超过30000字符时只保留末尾部分（truncated 为 true）
+- 使用 TaskStatus 列出所有后台任务，KillShell 终止任务`
+}
+
+// Examples 返回 TaskOutput 工具的使用示例
+// 实现 ExampleableTool 接口，帮助 LLM 更准确地调用工具
+func (t *TaskOutputTool) Examples() []tools.ToolExample {
+	return []tools.ToolExample{
+		{
+			Description: "读取后台任务的新输出",
+			Input: map[string]any{
+				"task_id": "task_123456",
+			},
+		},
+		{
+			Description: "等待构建结束（最多5分钟）",
+			Input: map[string]any{
+				"task_id": "task_123456",
+				"block":   true,
+				"timeout": 300000,
+			},
+		},
+		{
+			Description: "只查看错误行",
+			Input: map[string]any{
+				"task_id":    "task_123456",
+				"filter":     "(?i)error",
+				"from_start": true,
+			},
+		},
+	}
+}
+
+// Annotations 返回工具安全注解
+func (t *TaskOutputTool) Annotations() *tools.ToolAnnotations {
+	return tools.AnnotationsSafeReadOnly
+}
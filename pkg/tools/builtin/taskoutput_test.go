@@ -0,0 +1,164 @@
+package builtin
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/astercloud/aster/pkg/sandbox"
+	"github.com/astercloud/aster/pkg/tools"
+)
+
+// startBackgroundCommand 通过 Bash 的 run_in_background 启动命令，返回 task_id
+func startBackgroundCommand(t *testing.T, command string) string {
+	t.Helper()
+	bashTool, err := NewBashTool(nil)
+	if err != nil {
+		t.Fatalf("Failed to create Bash tool: %v", err)
+	}
+
+	// 后台任务只允许在本地沙箱中启动
+	sb, err := sandbox.NewLocalSandbox(&sandbox.LocalSandboxConfig{WorkDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("Failed to create local sandbox: %v", err)
+	}
+	output, err := bashTool.Execute(context.Background(), map[string]any{
+		"command":           command,
+		"run_in_background": true,
+	}, &tools.ToolContext{Signal: context.Background(), Sandbox: sb})
+	if err != nil {
+		t.Fatalf("Tool execution failed: %v", err)
+	}
+	result, _ := output.(map[string]any)
+	result = AssertToolSuccess(t, result)
+
+	taskID, _ := result["task_id"].(string)
+	if taskID == "" {
+		t.Fatalf("Expected task_id in background result, got: %v", result)
+	}
+	if result["status"] != "running" {
+		t.Errorf("Expected status 'running', got: %v", result["status"])
+	}
+	t.Cleanup(func() {
+		_ = GetGlobalTaskManager().KillTask(taskID, "SIGKILL", 0)
+		_ = GetGlobalTaskManager().CleanupTask(taskID)
+	})
+	return taskID
+}
+
+func TestTaskOutputTool_IncrementalOutput(t *testing.T) {
+	taskID := startBackgroundCommand(t, "echo first; sleep 0.3; echo second")
+
+	tool, err := NewTaskOutputTool(nil)
+	if err != nil {
+		t.Fatalf("Failed to create TaskOutput tool: %v", err)
+	}
+
+	// 等待第一行输出
+	var first map[string]any
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		first = AssertToolSuccess(t, ExecuteToolWithInput(t, tool, map[string]any{"task_id": taskID}))
+		if first["stdout"] != "" {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if first["stdout"] != "first\n" {
+		t.Fatalf("Expected first poll to return 'first', got: %q", first["stdout"])
+	}
+
+	second := AssertToolSuccess(t, ExecuteToolWithInput(t, tool, map[string]any{
+		"task_id": taskID,
+		"block":   true,
+		"timeout": float64(5000),
+	}))
+	if second["stdout"] != "second\n" {
+		t.Errorf("Expected only new output 'second', got: %q", second["stdout"])
+	}
+	if second["status"] != "completed" || second["exit_code"] != 0 {
+		t.Errorf("Expected completed with exit code 0, got status=%v exit_code=%v", second["status"], second["exit_code"])
+	}
+	if second["timed_out"] != false {
+		t.Errorf("Expected timed_out=false, got %v", second["timed_out"])
+	}
+
+	third := AssertToolSuccess(t, ExecuteToolWithInput(t, tool, map[string]any{"task_id": taskID}))
+	if third["has_new_output"] != false {
+		t.Errorf("Expected no new output, got stdout=%q", third["stdout"])
+	}
+
+	all := AssertToolSuccess(t, ExecuteToolWithInput(t, tool, map[string]any{
+		"task_id":    taskID,
+		"from_start": true,
+		"filter":     "sec",
+	}))
+	if all["stdout"] != "second" {
+		t.Errorf("Expected filtered output 'second', got: %q", all["stdout"])
+	}
+}
+
+func TestTaskOutputTool_BlockTimeout(t *testing.T) {
+	taskID := startBackgroundCommand(t, "sleep 5")
+
+	tool, _ := NewTaskOutputTool(nil)
+	start := time.Now()
+	result := AssertToolSuccess(t, ExecuteToolWithInput(t, tool, map[string]any{
+		"task_id": taskID,
+		"block":   true,
+		"timeout": float64(300),
+	}))
+	if time.Since(start) > 3*time.Second {
+		t.Errorf("Expected block to stop at timeout, waited %v", time.Since(start))
+	}
+	if result["timed_out"] != true || result["running"] != true {
+		t.Errorf("Expected running task with timed_out=true, got: %v", result)
+	}
+	if _, ok := result["hint"]; !ok {
+		t.Error("Expected hint for running task")
+	}
+}
+
+func TestTaskOutputTool_NotFound(t *testing.T) {
+	tool, _ := NewTaskOutputTool(nil)
+	result := ExecuteToolWithInput(t, tool, map[string]any{"task_id": "task_missing"})
+	AssertToolError(t, result)
+
+	result = ExecuteToolWithInput(t, tool, map[string]any{"task_id": "task_missing", "filter": "("})
+	AssertToolError(t, result)
+}
+
+func TestTaskStatusTool(t *testing.T) {
+	taskID := startBackgroundCommand(t, "sleep 5")
+
+	tool, err := NewTaskStatusTool(nil)
+	if err != nil {
+		t.Fatalf("Failed to create TaskStatus tool: %v", err)
+	}
+
+	single := AssertToolSuccess(t, ExecuteToolWithInput(t, tool, map[string]any{"task_id": taskID}))
+	if single["status"] != "running" || single["running"] != true {
+		t.Errorf("Expected running task, got: %v", single)
+	}
+	if !strings.Contains(single["command"].(string), "sleep 5") {
+		t.Errorf("Unexpected command: %v", single["command"])
+	}
+
+	list := AssertToolSuccess(t, ExecuteToolWithInput(t, tool, map[string]any{"status": "running"}))
+	found := false
+	for _, task := range list["tasks"].([]map[string]any) {
+		if task["task_id"] == taskID {
+			found = true
+		}
+		if task["status"] != "running" {
+			t.Errorf("Status filter returned task with status %v", task["status"])
+		}
+	}
+	if !found {
+		t.Errorf("Expected task %s in running task list", taskID)
+	}
+
+	missing := ExecuteToolWithInput(t, tool, map[string]any{"task_id": "task_missing"})
+	AssertToolError(t, missing)
+}
@@ -0,0 +1,169 @@
+package builtin
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/astercloud/aster/pkg/tools"
+)
+
+// TaskStatusTool 后台任务状态查询工具
+// 指定task_id时返回单个任务状态，否则列出所有后台任务
+type TaskStatusTool struct{}
+
+// NewTaskStatusTool 创建TaskStatus工具
+func NewTaskStatusTool(config map[string]any) (tools.Tool, error) {
+	return &TaskStatusTool{}, nil
+}
+
+func (t *TaskStatusTool) Name() string {
+	return "TaskStatus"
+}
+
+func (t *TaskStatusTool) Description() string {
+	return "查询后台任务的运行状态，不指定task_id时列出所有后台任务"
+}
+
+func (t *TaskStatusTool) InputSchema() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"task_id": map[string]any{
+				"type":        "string",
+				"description": "要查询的后台任务ID，不指定时列出所有后台任务",
+			},
+			"status": map[string]any{
+				"type":        "string",
+				"enum":        []string{"running", "completed", "failed", "killed"},
+				"description": "列出任务时只返回指定状态的任务",
+			},
+		},
+	}
+}
+
+func (t *TaskStatusTool) Execute(ctx context.Context, input map[string]any, tc *tools.ToolContext) (any, error) {
+	taskID := GetStringParam(input, "task_id", "")
+	statusFilter := GetStringParam(input, "status", "")
+
+	start := time.Now()
+	taskManager := GetGlobalTaskManager()
+
+	if taskID != "" {
+		task, err := taskManager.GetTask(taskID)
+		if err != nil {
+			return map[string]any{
+				"ok":    false,
+				"error": "background task not found: " + taskID,
+				"recommendations": []string{
+					"确认task_id是否正确",
+					"不带task_id调用TaskStatus列出所有后台任务",
+				},
+				"task_id":     taskID,
+				"duration_ms": time.Since(start).Milliseconds(),
+			}, nil
+		}
+		response := taskStatusSummary(task)
+		response["ok"] = true
+		response["duration_ms"] = time.Since(start).Milliseconds()
+		return response, nil
+	}
+
+	list, err := taskManager.ListTasks()
+	if err != nil {
+		return NewClaudeErrorResponse(err), nil
+	}
+	slices.SortFunc(list, func(a, b *TaskInfo) int {
+		return a.StartTime.Compare(b.StartTime)
+	})
+
+	summaries := make([]map[string]any, 0, len(list))
+	running := 0
+	for _, task := range list {
+		if task.Status == "running" {
+			running++
+		}
+		if statusFilter != "" && task.Status != statusFilter {
+			continue
+		}
+		summaries = append(summaries, taskStatusSummary(task))
+	}
+
+	return map[string]any{
+		"ok":          true,
+		"tasks":       summaries,
+		"count":       len(summaries),
+		"running":     running,
+		"duration_ms": time.Since(start).Milliseconds(),
+	}, nil
+}
+
+// taskStatusSummary 任务状态摘要
+func taskStatusSummary(task *TaskInfo) map[string]any {
+	summary := map[string]any{
+		"task_id":    task.ID,
+		"command":    task.Command,
+		"status":     task.Status,
+		"running":    task.Status == "running",
+		"start_time": task.StartTime.Unix(),
+	}
+	if task.PID > 0 {
+		summary["pid"] = task.PID
+	}
+	if task.WorkDir != "" {
+		summary["working_dir"] = task.WorkDir
+	}
+	if task.EndTime != nil {
+		summary["exit_code"] = task.ExitCode
+		summary["end_time"] = task.EndTime.Unix()
+		summary["elapsed_ms"] = task.EndTime.Sub(task.StartTime).Milliseconds()
+	} else {
+		summary["elapsed_ms"] = time.Since(task.StartTime).Milliseconds()
+	}
+	return summary
+}
+
+func (t *TaskStatusTool) Prompt() string {
+	return `查询后台任务的运行状态。
+
+功能特性：
+- 查询单个任务的状态、进程ID、退出码和运行时长
+- 不指定 task_id 时列出所有后台任务
+- 支持按状态筛选任务列表
+
+使用指南：
+- task_id: 可选参数，要查询的任务ID
+- status: 可选参数，列出任务时的状态筛选（running/completed/failed/killed）
+
+注意事项：
+- 后台任务由 Bash 的 run_in_background 参数启动
+- 使用 TaskOutput 读取任务输出，KillShell 终止任务`
+}
+
+// Examples 返回 TaskStatus 工具的使用示例
+// 实现 ExampleableTool 接口，帮助 LLM 更准确地调用工具
+func (t *TaskStatusTool) Examples() []tools.ToolExample {
+	return []tools.ToolExample{
+		{
+			Description: "列出所有后台任务",
+			Input:       map[string]any{},
+		},
+		{
+			Description: "查询单个任务状态",
+			Input: map[string]any{
+				"task_id": "task_123456",
+			},
+		},
+		{
+			Description: "只列出运行中的任务",
+			Input: map[string]any{
+				"status": "running",
+			},
+		},
+	}
+}
+
+// Annotations 返回工具安全注解
+func (t *TaskStatusTool) Annotations() *tools.ToolAnnotations {
+	return tools.AnnotationsSafeReadOnly
+}
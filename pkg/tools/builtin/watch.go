@@ -0,0 +1,214 @@
+package builtin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/astercloud/aster/pkg/sandbox"
+	"github.com/astercloud/aster/pkg/tools"
+)
+
+const (
+	// defaultWatchWaitSeconds poll 默认等待秒数（0 表示不等待）
+	defaultWatchWaitSeconds = 0
+	// maxWatchWaitSeconds poll 最长等待秒数
+	maxWatchWaitSeconds = 600
+)
+
+// WatchTool 文件监听工具
+// 订阅沙箱内的文件变更，变更可通过 poll 拉取（支持阻塞等待），
+// notify 订阅的变更会在之后的步骤中自动注入到上下文
+type WatchTool struct {
+	// fallback 未注入 watch_manager 时按沙箱创建的本地管理器
+	mu       sync.Mutex
+	fallback map[sandbox.Sandbox]*sandbox.WatchManager
+}
+
+// NewWatchTool 创建Watch工具
+func NewWatchTool(config map[string]any) (tools.Tool, error) {
+	return &WatchTool{fallback: make(map[sandbox.Sandbox]*sandbox.WatchManager)}, nil
+}
+
+func (t *WatchTool) Name() string {
+	return "Watch"
+}
+
+func (t *WatchTool) Description() string {
+	return "监听工作目录中的文件变更，可等待变更发生（如文件修改后重新运行测试）"
+}
+
+func (t *WatchTool) InputSchema() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"action": map[string]any{
+				"type":        "string",
+				"enum":        []string{"start", "poll", "stop", "list"},
+				"description": "start 创建订阅，poll 获取变更（可等待），stop 取消订阅，list 列出订阅",
+			},
+			"paths": map[string]any{
+				"type":        "array",
+				"items":       map[string]any{"type": "string"},
+				"description": "start: 监听的文件或目录，目录递归监听（默认工作目录）",
+			},
+			"patterns": map[string]any{
+				"type":        "array",
+				"items":       map[string]any{"type": "string"},
+				"description": "start: 只关注匹配的文件，如 [\"*.go\", \"src/**/*.ts\"]",
+			},
+			"notify": map[string]any{
+				"type":        "boolean",
+				"description": "start: 是否在之后的步骤中自动告知变更，默认为true",
+			},
+			"watch_id": map[string]any{
+				"type":        "string",
+				"description": "poll/stop: 订阅ID",
+			},
+			"wait_seconds": map[string]any{
+				"type":        "integer",
+				"minimum":     0,
+				"maximum":     maxWatchWaitSeconds,
+				"description": "poll: 没有变更时最多等待的秒数，默认为0（立即返回）",
+			},
+		},
+		"required": []string{"action"},
+	}
+}
+
+func (t *WatchTool) Execute(ctx context.Context, input map[string]any, tc *tools.ToolContext) (any, error) {
+	if err := ValidateRequired(input, []string{"action"}); err != nil {
+		return NewClaudeErrorResponse(err), nil
+	}
+
+	manager, err := t.manager(tc)
+	if err != nil {
+		return NewClaudeErrorResponse(err), nil
+	}
+
+	action := GetStringParam(input, "action", "")
+	watchID := GetStringParam(input, "watch_id", "")
+	if (action == "poll" || action == "stop") && watchID == "" {
+		return NewClaudeErrorResponse(fmt.Errorf("watch_id is required for %s", action), "使用 action=list 查看可用的订阅"), nil
+	}
+
+	switch action {
+	case "start":
+		paths := GetStringSliceParam(input, "paths")
+		if len(paths) == 0 {
+			paths = []string{"."}
+		}
+		info, err := manager.Start(sandbox.WatchOptions{
+			Paths:    paths,
+			Patterns: GetStringSliceParam(input, "patterns"),
+			Notify:   GetBoolParam(input, "notify", true),
+		})
+		if errors.Is(err, sandbox.ErrWatchDisabled) {
+			return NewClaudeErrorResponse(err, "当前沙箱未启用文件监听（watch_files），请改用 Bash 定期检查文件"), nil
+		}
+		if err != nil {
+			return NewClaudeErrorResponse(err, "确认路径存在且位于工作目录内"), nil
+		}
+		return map[string]any{
+			"ok":       true,
+			"watch_id": info.ID,
+			"paths":    info.Paths,
+			"patterns": info.Patterns,
+			"notify":   info.Notify,
+		}, nil
+
+	case "poll":
+		wait := min(max(GetIntParam(input, "wait_seconds", defaultWatchWaitSeconds), 0), maxWatchWaitSeconds)
+		var changes []sandbox.FileChange
+		if wait > 0 {
+			if tc != nil && tc.Reporter != nil {
+				tc.Reporter.Progress(0, fmt.Sprintf("waiting up to %ds for file changes", wait), 0, 0, map[string]any{"watch_id": watchID}, int64(wait)*1000)
+			}
+			changes, err = manager.Wait(ctx, watchID, time.Duration(wait)*time.Second)
+		} else {
+			changes, err = manager.Drain(watchID)
+		}
+		if err != nil && len(changes) == 0 {
+			return NewClaudeErrorResponse(err), nil
+		}
+		if tc != nil && tc.Reporter != nil {
+			for _, c := range changes {
+				tc.Reporter.Intermediate("file_change", c)
+			}
+		}
+		result := map[string]any{
+			"ok":       true,
+			"watch_id": watchID,
+			"changes":  changes,
+			"count":    len(changes),
+		}
+		if wait > 0 && len(changes) == 0 {
+			result["timeout"] = true
+			result["message"] = fmt.Sprintf("no changes within %d seconds", wait)
+		}
+		return result, nil
+
+	case "stop":
+		if err := manager.Stop(watchID); err != nil {
+			return NewClaudeErrorResponse(err), nil
+		}
+		return map[string]any{"ok": true, "watch_id": watchID}, nil
+
+	case "list":
+		watches := manager.List()
+		return map[string]any{"ok": true, "watches": watches, "count": len(watches)}, nil
+
+	default:
+		return NewClaudeErrorResponse(fmt.Errorf("unknown action: %s", action), "action 只能是 start、poll、stop、list"), nil
+	}
+}
+
+// manager 返回 Agent 注入的监听管理器，未注入时使用按沙箱创建的本地管理器
+func (t *WatchTool) manager(tc *tools.ToolContext) (*sandbox.WatchManager, error) {
+	if tc != nil && tc.Services != nil {
+		if m, ok := tc.Services["watch_manager"].(*sandbox.WatchManager); ok {
+			return m, nil
+		}
+	}
+	if tc == nil || tc.Sandbox == nil {
+		return nil, errors.New("sandbox not available")
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	m, ok := t.fallback[tc.Sandbox]
+	if !ok {
+		m = sandbox.NewWatchManager(tc.Sandbox)
+		t.fallback[tc.Sandbox] = m
+	}
+	return m, nil
+}
+
+func (t *WatchTool) Prompt() string {
+	return `监听工作目录中的文件变更。
+
+操作：
+- start: 创建订阅，返回 watch_id。paths 默认为工作目录（递归监听，跳过 .git、node_modules），patterns 过滤文件
+- poll: 获取订阅自上次 poll 以来的变更；wait_seconds > 0 时在没有变更的情况下阻塞等待
+- stop: 取消订阅
+- list: 列出当前订阅及待处理的变更数
+
+变更类型：create、write、remove、rename；同一文件的多次变更合并为一条。
+
+notify 为 true（默认）时，用户或其他进程在会话中修改的文件会在下一步自动以 <file-changes> 告知，
+你自己通过 Write/Edit 做的修改不会被报告。
+
+使用场景：
+- 文件修改后重新运行测试: start(patterns=["*.go"], notify=false) → 循环 poll(wait_seconds=300) → Bash 运行测试
+- 检测用户在会话中的外部编辑: start(notify=true)，之后编辑前先重新读取被告知变更的文件
+
+注意事项：
+- 订阅在 Agent 关闭时自动取消，不再需要时请主动 stop
+- 远程沙箱或未启用 watch_files 的沙箱不支持监听`
+}
+
+// Annotations 返回工具安全注解
+func (t *WatchTool) Annotations() *tools.ToolAnnotations {
+	return tools.AnnotationsSafeReadOnly
+}
@@ -0,0 +1,96 @@
+package builtin
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/astercloud/aster/pkg/sandbox"
+	"github.com/astercloud/aster/pkg/tools"
+)
+
+func newWatchContext(t *testing.T, watch bool) (*tools.ToolContext, string) {
+	t.Helper()
+	dir := t.TempDir()
+	sb, err := sandbox.NewLocalSandbox(&sandbox.LocalSandboxConfig{WorkDir: dir, WatchFiles: watch})
+	if err != nil {
+		t.Fatalf("NewLocalSandbox() error = %v", err)
+	}
+	t.Cleanup(func() { _ = sb.Dispose() })
+	manager := sandbox.NewWatchManager(sb)
+	t.Cleanup(manager.StopAll)
+	return &tools.ToolContext{
+		Sandbox:  sb,
+		Reporter: &recordingReporter{},
+		Services: map[string]any{"watch_manager": manager},
+	}, dir
+}
+
+func TestWatchTool_StartPollStop(t *testing.T) {
+	tool, err := NewWatchTool(nil)
+	if err != nil {
+		t.Fatalf("NewWatchTool() error = %v", err)
+	}
+	tc, dir := newWatchContext(t, true)
+	ctx := context.Background()
+
+	result, err := tool.Execute(ctx, map[string]any{"action": "start", "patterns": []any{"*.go"}, "notify": false}, tc)
+	if err != nil {
+		t.Fatalf("Execute(start) error = %v", err)
+	}
+	res := result.(map[string]any)
+	id, _ := res["watch_id"].(string)
+	if res["ok"] != true || id == "" {
+		t.Fatalf("Execute(start) = %v", res)
+	}
+
+	go func() {
+		_ = os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("x"), 0o644)
+		_ = os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main"), 0o644)
+	}()
+	result, _ = tool.Execute(ctx, map[string]any{"action": "poll", "watch_id": id, "wait_seconds": float64(5)}, tc)
+	res = result.(map[string]any)
+	changes, _ := res["changes"].([]sandbox.FileChange)
+	if len(changes) != 1 || changes[0].Path != "main.go" {
+		t.Fatalf("Execute(poll) = %v", res)
+	}
+	if labels := tc.Reporter.(*recordingReporter).labels; len(labels) != 1 || labels[0] != "file_change" {
+		t.Errorf("reporter labels = %v", labels)
+	}
+
+	result, _ = tool.Execute(ctx, map[string]any{"action": "list"}, tc)
+	if res = result.(map[string]any); res["count"] != 1 {
+		t.Errorf("Execute(list) = %v", res)
+	}
+	result, _ = tool.Execute(ctx, map[string]any{"action": "stop", "watch_id": id}, tc)
+	if res = result.(map[string]any); res["ok"] != true {
+		t.Errorf("Execute(stop) = %v", res)
+	}
+	result, _ = tool.Execute(ctx, map[string]any{"action": "poll", "watch_id": id}, tc)
+	if res = result.(map[string]any); res["ok"] != false {
+		t.Errorf("Execute(poll) after stop = %v", res)
+	}
+}
+
+func TestWatchTool_Errors(t *testing.T) {
+	tool, _ := NewWatchTool(nil)
+	tc, _ := newWatchContext(t, false)
+	ctx := context.Background()
+
+	tests := []map[string]any{
+		{"action": "start"},
+		{"action": "poll"},
+		{"action": "bogus"},
+		{},
+	}
+	for _, input := range tests {
+		result, err := tool.Execute(ctx, input, tc)
+		if err != nil {
+			t.Fatalf("Execute(%v) error = %v", input, err)
+		}
+		if res := result.(map[string]any); res["ok"] != false {
+			t.Errorf("Execute(%v) = %v, want error response", input, res)
+		}
+	}
+}
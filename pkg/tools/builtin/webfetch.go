@@ -11,6 +11,7 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/astercloud/aster/pkg/httpclient"
 	"github.com/astercloud/aster/pkg/tools"
 )
 
@@ -29,9 +30,7 @@ func NewWebFetchTool(config map[string]any) (tools.Tool, error) {
 
 	return &WebFetchTool{
 		defaultTimeout: timeout,
-		client: &http.Client{
-			Timeout: timeout,
-		},
+		client:         httpclient.New(timeout),
 	}, nil
 }
 
@@ -111,9 +110,7 @@ func (t *WebFetchTool) Execute(ctx context.Context, input map[string]any, tc *to
 
 	client := t.client
 	if timeoutSec, ok := input["timeout"].(float64); ok && timeoutSec > 0 {
-		client = &http.Client{
-			Timeout: time.Duration(timeoutSec) * time.Second,
-		}
+		client = httpclient.New(time.Duration(timeoutSec) * time.Second)
 	}
 
 	resp, err := client.Do(req)
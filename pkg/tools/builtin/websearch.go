@@ -10,6 +10,7 @@ import (
 	"os"
 	"time"
 
+	"github.com/astercloud/aster/pkg/httpclient"
 	"github.com/astercloud/aster/pkg/tools"
 )
 
@@ -30,9 +31,7 @@ func NewWebSearchTool(config map[string]any) (tools.Tool, error) {
 
 	return &WebSearchTool{
 		apiKey: apiKey,
-		client: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+		client: httpclient.New(30 * time.Second),
 	}, nil
 }
 
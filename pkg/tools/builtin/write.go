@@ -95,6 +95,13 @@ func (t *WriteTool) Execute(ctx context.Context, input map[string]any, tc *tools
 		fileExists = true
 	}
 
+	// 文件在上次读取后被修改时拒绝写入，避免覆盖用户的改动
+	if fileExists {
+		if conflict := checkFileConflict(tc, filePath, existingContent); conflict != nil {
+			return conflict, nil
+		}
+	}
+
 	// 如果是追加模式且文件存在，在内容前添加换行符（如果需要）
 	var writeContent string
 	if append && fileExists && existingContent != "" && !strings.HasSuffix(existingContent, "\n") {
@@ -142,6 +149,8 @@ func (t *WriteTool) Execute(ctx context.Context, input map[string]any, tc *tools
 		}, nil
 	}
 
+	recordFileVersion(tc, filePath, writeContent)
+
 	// 获取文件信息
 	fileSize := len(writeContent)
 	lines := 0
@@ -258,7 +267,8 @@ func (t *WriteTool) Prompt() string {
 - 路径遍历攻击防护
 - 沙箱环境隔离
 - 文件备份保护
-- 写入权限检查`
+- 写入权限检查
+- 文件在上次读取后被修改时拒绝覆盖，返回 file_conflict 错误和最新内容（current_content）`
 }
 
 // Examples 返回 Write 工具的使用示例
@@ -2,10 +2,11 @@ package tools
 
 import (
 	"context"
-	"fmt"
+	"errors"
 	"sync"
 	"time"
 
+	"github.com/astercloud/aster/pkg/agenterr"
 	"github.com/astercloud/aster/pkg/types"
 )
 
@@ -13,6 +14,9 @@ import (
 type ExecutorConfig struct {
 	MaxConcurrency int           // 最大并发数
 	DefaultTimeout time.Duration // 默认超时时间
+
+	// Fault 可选的故障注入钩子（如 chaos.Injector.ToolFault），返回错误时不执行工具并以该错误作为结果
+	Fault func(ctx context.Context, tool string) error
 }
 
 // Executor 工具执行器
@@ -82,8 +86,19 @@ func (e *Executor) Execute(ctx context.Context, req *ExecuteRequest) *ExecuteRes
 	defer cancel()
 
 	// 执行工具
-	output, err := req.Tool.Execute(execCtx, req.Input, req.Context)
+	var output any
+	var err error
+	if e.config.Fault != nil {
+		err = e.config.Fault(execCtx, req.Tool.Name())
+	}
+	if err == nil {
+		output, err = req.Tool.Execute(execCtx, req.Input, req.Context)
+	}
 	endTime := time.Now()
+	if err != nil && ctx.Err() == nil && errors.Is(execCtx.Err(), context.DeadlineExceeded) {
+		// 超过执行超时（而非调用方取消）
+		err = &agenterr.Error{Code: agenterr.CodeToolTimeout, Message: err.Error(), Err: err}
+	}
 
 	result := &ExecuteResult{
 		Success:    err == nil,
@@ -119,25 +134,13 @@ func (e *Executor) Wait() {
 	e.running.Wait()
 }
 
-// ValidateInput 验证工具输入
+// ValidateInput 按工具的输入 Schema 校验参数，不符合时返回 *InputValidationError
 func ValidateInput(tool Tool, input map[string]any) error {
-	schema := tool.InputSchema()
-	if schema == nil {
-		return nil // 没有schema,跳过验证
+	issues := ValidateSchema(tool.InputSchema(), input)
+	if len(issues) == 0 {
+		return nil
 	}
-
-	// TODO: 使用jsonschema库进行验证
-	// 这里先做简单的required字段检查
-	if required, ok := schema["required"].([]any); ok {
-		for _, field := range required {
-			fieldName := field.(string)
-			if _, exists := input[fieldName]; !exists {
-				return fmt.Errorf("missing required field: %s", fieldName)
-			}
-		}
-	}
-
-	return nil
+	return &InputValidationError{Tool: tool.Name(), Issues: issues}
 }
 
 // ToolCallRecordBuilder 工具调用记录构建器
@@ -3,6 +3,7 @@ package tools
 import (
 	"context"
 
+	"github.com/astercloud/aster/pkg/agenterr"
 	"github.com/astercloud/aster/pkg/sandbox"
 )
 
@@ -139,6 +140,14 @@ type DeferrableTool interface {
 	DeferConfig() *DeferrableConfig
 }
 
+// TrustRestrictedTool 在未受信任的工作区中禁用的工具
+// 内置工具按名称判断（见 permission.RequiresWorkspaceTrust），动态注册的工具通过此接口声明
+type TrustRestrictedTool interface {
+	Tool
+	// RequiresWorkspaceTrust 是否需要工作区受信任才能执行
+	RequiresWorkspaceTrust() bool
+}
+
 // ToolConfig 工具配置(用于持久化)
 type ToolConfig struct {
 	Name       string         `json:"name"`
@@ -199,3 +208,7 @@ type ToolNotFoundError struct {
 func (e *ToolNotFoundError) Error() string {
 	return "tool not found: " + e.Name
 }
+
+func (e *ToolNotFoundError) ErrorCode() agenterr.Code {
+	return agenterr.CodeToolNotFound
+}
@@ -36,6 +36,24 @@ type LongRunningTool interface {
 	Cancel(ctx context.Context, taskID string) error
 }
 
+// AsyncTool 后台运行的长时工具
+// RunInBackground 返回 true 时调用立即返回任务句柄，不阻塞当前轮次；
+// 任务结束后结果作为新消息送达模型
+type AsyncTool interface {
+	LongRunningTool
+
+	RunInBackground() bool
+}
+
+// ResumableTool 进程重启后可恢复的后台任务
+// 任务状态保存在外部系统时直接返回 nil，由 GetStatus 继续查询；
+// 需要重新执行时使用同一任务 ID 重启（工具需保证幂等）
+type ResumableTool interface {
+	LongRunningTool
+
+	Resume(ctx context.Context, taskID string, args map[string]any) error
+}
+
 // TaskStatus 任务状态
 type TaskStatus struct {
 	TaskID    string         // 任务 ID
@@ -100,10 +118,27 @@ func (e *LongRunningExecutor) StartAsync(
 	tool Tool,
 	args map[string]any,
 ) (string, error) {
-	// 1. 生成任务 ID
 	taskID := generateTaskID()
+	e.start(ctx, taskID, tool, args)
+	return taskID, nil
+}
+
+// ResumeAsync 使用已有的任务 ID 重新启动工具，用于进程重启后恢复后台任务
+func (e *LongRunningExecutor) ResumeAsync(
+	ctx context.Context,
+	taskID string,
+	tool Tool,
+	args map[string]any,
+) error {
+	if value, ok := e.tasks.Load(taskID); ok && !value.(*TaskStatus).State.IsTerminal() {
+		return fmt.Errorf("task already running: %s", taskID)
+	}
+	e.start(ctx, taskID, tool, args)
+	return nil
+}
 
-	// 2. 创建任务状态
+// start 创建任务状态并异步执行
+func (e *LongRunningExecutor) start(ctx context.Context, taskID string, tool Tool, args map[string]any) {
 	status := &TaskStatus{
 		TaskID:    taskID,
 		State:     TaskStatePending,
@@ -113,11 +148,10 @@ func (e *LongRunningExecutor) StartAsync(
 	}
 	e.tasks.Store(taskID, status)
 
-	// 3. 创建可取消的 context
+	// 创建可取消的 context
 	taskCtx, cancel := context.WithCancel(ctx)
 	e.cancels.Store(taskID, cancel)
 
-	// 4. 异步执行
 	go func() {
 		defer cancel()
 
@@ -155,8 +189,6 @@ func (e *LongRunningExecutor) StartAsync(
 		// 清理取消函数
 		e.cancels.Delete(taskID)
 	}()
-
-	return taskID, nil
 }
 
 // GetStatus 获取任务状态
@@ -303,6 +335,9 @@ func generateTaskID() string {
 type BaseLongRunningTool struct {
 	BaseTool
 
+	// Background 为 true 时在后台运行，调用立即返回任务句柄（见 AsyncTool）
+	Background bool
+
 	executor *LongRunningExecutor
 }
 
@@ -322,6 +357,11 @@ func (t *BaseLongRunningTool) IsLongRunning() bool {
 	return true
 }
 
+// RunInBackground 实现 AsyncTool 接口
+func (t *BaseLongRunningTool) RunInBackground() bool {
+	return t.Background
+}
+
 // StartAsync 实现 LongRunningTool 接口
 func (t *BaseLongRunningTool) StartAsync(ctx context.Context, args map[string]any) (string, error) {
 	return t.executor.StartAsync(ctx, t, args)
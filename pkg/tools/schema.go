@@ -0,0 +1,499 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"math"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/astercloud/aster/pkg/agenterr"
+)
+
+// maxValidationIssues 单次校验最多报告的问题数，避免错误信息过长
+const maxValidationIssues = 5
+
+// ValidationIssue 单个参数校验问题，Expected 和 Example 帮助模型修正参数
+type ValidationIssue struct {
+	Field    string `json:"field"`
+	Message  string `json:"message"`
+	Expected string `json:"expected,omitempty"`
+	Example  any    `json:"example,omitempty"`
+}
+
+func (i ValidationIssue) String() string {
+	return i.Field + ": " + i.Message
+}
+
+// InputValidationError 工具参数不符合输入 Schema
+type InputValidationError struct {
+	Tool   string
+	Issues []ValidationIssue
+}
+
+func (e *InputValidationError) Error() string {
+	msgs := make([]string, len(e.Issues))
+	for i, issue := range e.Issues {
+		msgs[i] = issue.String()
+	}
+	return fmt.Sprintf("invalid input for tool %s: %s", e.Tool, strings.Join(msgs, "; "))
+}
+
+func (e *InputValidationError) ErrorCode() agenterr.Code {
+	return agenterr.CodeToolInvalidInput
+}
+
+// ValidateSchema 按 JSON Schema 子集校验参数
+// 支持 type/properties/required/additionalProperties/items/enum/const、数值与长度范围、
+// pattern、anyOf/oneOf/allOf 以及本地 $ref；未识别的关键字忽略
+func ValidateSchema(schema map[string]any, input map[string]any) []ValidationIssue {
+	if schema == nil {
+		return nil
+	}
+	// 经 JSON 往返，统一数值和切片类型
+	var value any = map[string]any{}
+	if input != nil {
+		data, err := json.Marshal(input)
+		if err != nil {
+			return []ValidationIssue{{Field: "input", Message: "is not valid JSON: " + err.Error()}}
+		}
+		if err := json.Unmarshal(data, &value); err != nil {
+			return []ValidationIssue{{Field: "input", Message: "is not valid JSON: " + err.Error()}}
+		}
+	}
+	root := normalizeSchema(schema)
+	v := &schemaValidator{root: root}
+	v.validate(root, value, "", 0)
+	return v.issues
+}
+
+type schemaValidator struct {
+	root   map[string]any
+	issues []ValidationIssue
+}
+
+// fail 记录问题，schema 为该字段期望的 Schema，用于生成期望类型和示例
+func (v *schemaValidator) fail(path string, schema map[string]any, format string, args ...any) {
+	if len(v.issues) >= maxValidationIssues {
+		return
+	}
+	if path == "" {
+		path = "input"
+	}
+	issue := ValidationIssue{Field: path, Message: fmt.Sprintf(format, args...)}
+	if schema != nil {
+		issue.Expected = describeSchema(v.root, schema)
+		issue.Example = exampleValue(v.root, schema, 0)
+	}
+	v.issues = append(v.issues, issue)
+}
+
+// check 在独立的校验器中校验，用于 anyOf/oneOf 分支
+func (v *schemaValidator) check(schema map[string]any, value any, path string, depth int) bool {
+	sub := &schemaValidator{root: v.root}
+	sub.validate(schema, value, path, depth)
+	return len(sub.issues) == 0
+}
+
+func (v *schemaValidator) validate(schema map[string]any, value any, path string, depth int) {
+	if depth > 32 {
+		return
+	}
+	if ref, ok := schema["$ref"].(string); ok {
+		if resolved, ok := resolveSchemaRef(v.root, ref); ok {
+			v.validate(resolved, value, path, depth+1)
+		}
+		return
+	}
+
+	if t, ok := schema["type"]; ok && !matchesType(t, value) {
+		if value == nil && schema["nullable"] == true {
+			return
+		}
+		v.fail(path, schema, "expected %s, got %s", typeNames(t), jsonType(value))
+		return
+	}
+	if enum, ok := schema["enum"].([]any); ok && !containsValue(enum, value) {
+		v.fail(path, schema, "must be one of %s", formatValues(enum))
+	}
+	if c, ok := schema["const"]; ok && !reflect.DeepEqual(c, value) {
+		v.fail(path, schema, "must be %s", formatValues([]any{c}))
+	}
+
+	for _, s := range schemaList(schema["allOf"]) {
+		v.validate(s, value, path, depth+1)
+	}
+	if anyOf := schemaList(schema["anyOf"]); len(anyOf) > 0 {
+		matched := false
+		for _, s := range anyOf {
+			if v.check(s, value, path, depth+1) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			v.fail(path, schema, "does not match any allowed schema")
+		}
+	}
+	if oneOf := schemaList(schema["oneOf"]); len(oneOf) > 0 {
+		matched := 0
+		for _, s := range oneOf {
+			if v.check(s, value, path, depth+1) {
+				matched++
+			}
+		}
+		if matched != 1 {
+			v.fail(path, schema, "must match exactly one allowed schema, matched %d", matched)
+		}
+	}
+
+	switch val := value.(type) {
+	case map[string]any:
+		v.validateObject(schema, val, path, depth)
+	case []any:
+		if n, ok := schemaNumber(schema["minItems"]); ok && float64(len(val)) < n {
+			v.fail(path, schema, "must have at least %v items", n)
+		}
+		if n, ok := schemaNumber(schema["maxItems"]); ok && float64(len(val)) > n {
+			v.fail(path, schema, "must have at most %v items", n)
+		}
+		if items, ok := schema["items"].(map[string]any); ok {
+			for i, item := range val {
+				v.validate(items, item, fmt.Sprintf("%s[%d]", path, i), depth+1)
+			}
+		}
+	case string:
+		length := float64(utf8.RuneCountInString(val))
+		if n, ok := schemaNumber(schema["minLength"]); ok && length < n {
+			v.fail(path, schema, "must be at least %v characters", n)
+		}
+		if n, ok := schemaNumber(schema["maxLength"]); ok && length > n {
+			v.fail(path, schema, "must be at most %v characters", n)
+		}
+		if pattern, ok := schema["pattern"].(string); ok {
+			if re, err := regexp.Compile(pattern); err == nil && !re.MatchString(val) {
+				v.fail(path, schema, "must match pattern %s", pattern)
+			}
+		}
+	case float64:
+		if n, ok := schemaNumber(schema["minimum"]); ok && val < n {
+			v.fail(path, schema, "must be >= %v", n)
+		}
+		if n, ok := schemaNumber(schema["maximum"]); ok && val > n {
+			v.fail(path, schema, "must be <= %v", n)
+		}
+		if n, ok := schemaNumber(schema["exclusiveMinimum"]); ok && val <= n {
+			v.fail(path, schema, "must be > %v", n)
+		}
+		if n, ok := schemaNumber(schema["exclusiveMaximum"]); ok && val >= n {
+			v.fail(path, schema, "must be < %v", n)
+		}
+	}
+}
+
+func (v *schemaValidator) validateObject(schema map[string]any, val map[string]any, path string, depth int) {
+	props, _ := schema["properties"].(map[string]any)
+	for _, name := range stringList(schema["required"]) {
+		if _, ok := val[name]; !ok {
+			prop, _ := props[name].(map[string]any)
+			v.fail(joinPath(path, name), prop, "is required")
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(val)) {
+		if prop, ok := props[name].(map[string]any); ok {
+			v.validate(prop, val[name], joinPath(path, name), depth+1)
+			continue
+		}
+		switch extra := schema["additionalProperties"].(type) {
+		case bool:
+			if !extra {
+				v.fail(joinPath(path, name), nil, "is not an allowed property")
+			}
+		case map[string]any:
+			v.validate(extra, val[name], joinPath(path, name), depth+1)
+		}
+	}
+}
+
+// normalizeSchema 经 JSON 往返统一 Schema 中的 []string、int 等类型
+func normalizeSchema(schema map[string]any) map[string]any {
+	data, err := json.Marshal(schema)
+	if err != nil {
+		return schema
+	}
+	var out map[string]any
+	if err := json.Unmarshal(data, &out); err != nil {
+		return schema
+	}
+	return out
+}
+
+func resolveSchemaRef(root map[string]any, ref string) (map[string]any, bool) {
+	if !strings.HasPrefix(ref, "#") {
+		return nil, false
+	}
+	var cur any = root
+	for _, part := range strings.Split(strings.TrimPrefix(strings.TrimPrefix(ref, "#"), "/"), "/") {
+		if part == "" {
+			continue
+		}
+		part = strings.ReplaceAll(strings.ReplaceAll(part, "~1", "/"), "~0", "~")
+		m, ok := cur.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		cur = m[part]
+	}
+	m, ok := cur.(map[string]any)
+	return m, ok
+}
+
+func matchesType(t any, value any) bool {
+	switch tt := t.(type) {
+	case string:
+		return matchesTypeName(tt, value)
+	case []any:
+		for _, name := range tt {
+			if s, ok := name.(string); ok && matchesTypeName(s, value) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func matchesTypeName(name string, value any) bool {
+	switch name {
+	case "object":
+		_, ok := value.(map[string]any)
+		return ok
+	case "array":
+		_, ok := value.([]any)
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	case "integer":
+		n, ok := value.(float64)
+		return ok && n == math.Trunc(n)
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "null":
+		return value == nil
+	}
+	return true
+}
+
+func jsonType(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func typeNames(t any) string {
+	if list, ok := t.([]any); ok {
+		names := make([]string, 0, len(list))
+		for _, n := range list {
+			names = append(names, fmt.Sprint(n))
+		}
+		return strings.Join(names, " or ")
+	}
+	return fmt.Sprint(t)
+}
+
+func containsValue(values []any, value any) bool {
+	for _, v := range values {
+		if reflect.DeepEqual(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func formatValues(values []any) string {
+	data, err := json.Marshal(values)
+	if err != nil {
+		return fmt.Sprint(values)
+	}
+	s := string(data)
+	if len(values) == 1 {
+		s = strings.TrimSuffix(strings.TrimPrefix(s, "["), "]")
+	}
+	return s
+}
+
+func schemaList(v any) []map[string]any {
+	list, ok := v.([]any)
+	if !ok {
+		return nil
+	}
+	out := make([]map[string]any, 0, len(list))
+	for _, item := range list {
+		if m, ok := item.(map[string]any); ok {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+func stringList(v any) []string {
+	list, ok := v.([]any)
+	if !ok {
+		return nil
+	}
+	out := make([]string, 0, len(list))
+	for _, item := range list {
+		if s, ok := item.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func schemaNumber(v any) (float64, bool) {
+	n, ok := v.(float64)
+	return n, ok
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// ExampleInput 根据输入 Schema 生成包含必填字段的示例参数
+func ExampleInput(schema map[string]any) map[string]any {
+	if schema == nil {
+		return nil
+	}
+	root := normalizeSchema(schema)
+	example, _ := exampleValue(root, root, 0).(map[string]any)
+	return example
+}
+
+// describeSchema 描述 Schema 期望的值，例如 "string" 或 "one of [\"a\",\"b\"]"
+func describeSchema(root, schema map[string]any) string {
+	if ref, ok := schema["$ref"].(string); ok {
+		if resolved, ok := resolveSchemaRef(root, ref); ok {
+			return describeSchema(root, resolved)
+		}
+	}
+	if enum, ok := schema["enum"].([]any); ok {
+		return "one of " + formatValues(enum)
+	}
+	if c, ok := schema["const"]; ok {
+		return formatValues([]any{c})
+	}
+	if t, ok := schema["type"]; ok {
+		desc := typeNames(t)
+		if items, ok := schema["items"].(map[string]any); ok && desc == "array" {
+			if inner := describeSchema(root, items); inner != "" {
+				desc += " of " + inner
+			}
+		}
+		return desc
+	}
+	var alts []string
+	for _, s := range append(schemaList(schema["anyOf"]), schemaList(schema["oneOf"])...) {
+		if d := describeSchema(root, s); d != "" {
+			alts = append(alts, d)
+		}
+	}
+	return strings.Join(alts, " or ")
+}
+
+// exampleValue 生成符合 Schema 的示例值，优先使用 examples/default/enum
+func exampleValue(root, schema map[string]any, depth int) any {
+	if depth > 8 {
+		return nil
+	}
+	if ref, ok := schema["$ref"].(string); ok {
+		if resolved, ok := resolveSchemaRef(root, ref); ok {
+			return exampleValue(root, resolved, depth+1)
+		}
+		return nil
+	}
+	if examples, ok := schema["examples"].([]any); ok && len(examples) > 0 {
+		return examples[0]
+	}
+	if def, ok := schema["default"]; ok {
+		return def
+	}
+	if c, ok := schema["const"]; ok {
+		return c
+	}
+	if enum, ok := schema["enum"].([]any); ok && len(enum) > 0 {
+		return enum[0]
+	}
+	for _, key := range []string{"anyOf", "oneOf", "allOf"} {
+		if alts := schemaList(schema[key]); len(alts) > 0 && schema["type"] == nil {
+			return exampleValue(root, alts[0], depth+1)
+		}
+	}
+
+	t := schema["type"]
+	if list, ok := t.([]any); ok {
+		t = nil
+		for _, name := range list {
+			if name != "null" {
+				t = name
+				break
+			}
+		}
+	}
+	switch t {
+	case "string":
+		return "string"
+	case "integer":
+		if n, ok := schemaNumber(schema["minimum"]); ok {
+			return math.Ceil(n)
+		}
+		return 1
+	case "number":
+		if n, ok := schemaNumber(schema["minimum"]); ok {
+			return n
+		}
+		return 1.0
+	case "boolean":
+		return true
+	case "array":
+		if items, ok := schema["items"].(map[string]any); ok {
+			if item := exampleValue(root, items, depth+1); item != nil {
+				return []any{item}
+			}
+		}
+		return []any{}
+	case "object":
+		out := map[string]any{}
+		props, _ := schema["properties"].(map[string]any)
+		for _, name := range stringList(schema["required"]) {
+			if prop, ok := props[name].(map[string]any); ok {
+				out[name] = exampleValue(root, prop, depth+1)
+			}
+		}
+		return out
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package tools
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/astercloud/aster/pkg/agenterr"
+)
+
+func TestValidateSchema(t *testing.T) {
+	schema := map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"path":  map[string]any{"type": "string", "minLength": 1},
+			"limit": map[string]any{"type": "integer", "minimum": 1, "maximum": 100},
+			"mode":  map[string]any{"enum": []string{"fast", "full"}},
+			"tags":  map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+			"range": map[string]any{"$ref": "#/$defs/range"},
+			"id":    map[string]any{"anyOf": []any{map[string]any{"type": "string"}, map[string]any{"type": "integer"}}},
+		},
+		"required":             []string{"path"},
+		"additionalProperties": false,
+		"$defs": map[string]any{
+			"range": map[string]any{"type": "object", "required": []any{"start"}},
+		},
+	}
+
+	tests := []struct {
+		name  string
+		input map[string]any
+		want  []string
+	}{
+		{"valid", map[string]any{"path": "a.go", "limit": 10, "tags": []string{"x"}, "id": 3, "range": map[string]any{"start": 1}}, nil},
+		{"missing required", map[string]any{}, []string{"path: is required"}},
+		{"wrong type", map[string]any{"path": 1}, []string{"path: expected string, got number"}},
+		{"integer", map[string]any{"path": "a", "limit": 1.5}, []string{"limit: expected integer"}},
+		{"range", map[string]any{"path": "a", "limit": 0}, []string{"limit: must be >= 1"}},
+		{"enum", map[string]any{"path": "a", "mode": "slow"}, []string{`mode: must be one of ["fast","full"]`}},
+		{"items", map[string]any{"path": "a", "tags": []any{"x", 2}}, []string{"tags[1]: expected string"}},
+		{"ref", map[string]any{"path": "a", "range": map[string]any{}}, []string{"range.start: is required"}},
+		{"anyOf", map[string]any{"path": "a", "id": true}, []string{"id: does not match any allowed schema"}},
+		{"additional", map[string]any{"path": "a", "extra": 1}, []string{"extra: is not an allowed property"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issues := ValidateSchema(schema, tt.input)
+			if len(issues) != len(tt.want) {
+				t.Fatalf("ValidateSchema() = %v, want %v", issues, tt.want)
+			}
+			for i, want := range tt.want {
+				if !strings.HasPrefix(issues[i].String(), want) {
+					t.Errorf("issue[%d] = %q, want prefix %q", i, issues[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestValidationIssueHints(t *testing.T) {
+	schema := map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"query": map[string]any{"type": "string", "examples": []any{"golang"}},
+			"limit": map[string]any{"type": "integer", "minimum": 5},
+			"mode":  map[string]any{"enum": []any{"fast", "full"}},
+			"tags":  map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+		},
+		"required": []string{"query", "limit", "tags"},
+	}
+	issues := ValidateSchema(schema, map[string]any{"limit": "ten", "mode": "slow"})
+	want := map[string]ValidationIssue{
+		"query": {Expected: "string", Example: "golang"},
+		"limit": {Expected: "integer", Example: 5.0},
+		"mode":  {Expected: `one of ["fast","full"]`, Example: "fast"},
+		"tags":  {Expected: "array of string", Example: []any{"string"}},
+	}
+	if len(issues) != len(want) {
+		t.Fatalf("ValidateSchema() = %v", issues)
+	}
+	for _, issue := range issues {
+		w := want[issue.Field]
+		if issue.Expected != w.Expected || !reflect.DeepEqual(issue.Example, w.Example) {
+			t.Errorf("%s: expected=%q example=%v, want %q %v", issue.Field, issue.Expected, issue.Example, w.Expected, w.Example)
+		}
+	}
+
+	example := ExampleInput(schema)
+	if !reflect.DeepEqual(example, map[string]any{"query": "golang", "limit": 5.0, "tags": []any{"string"}}) {
+		t.Errorf("ExampleInput() = %v", example)
+	}
+}
+
+func TestValidateInput(t *testing.T) {
+	tool := &schemaTool{schema: map[string]any{"type": "object", "required": []any{"q"}}}
+	if err := ValidateInput(tool, map[string]any{"q": "x"}); err != nil {
+		t.Fatalf("ValidateInput() error = %v", err)
+	}
+	err := ValidateInput(tool, nil)
+	var inputErr *InputValidationError
+	if !errors.As(err, &inputErr) || inputErr.Tool != "search" {
+		t.Fatalf("ValidateInput() error = %v, want *InputValidationError", err)
+	}
+	if agenterr.From(err).Code != agenterr.CodeToolInvalidInput {
+		t.Errorf("code = %v, want %v", agenterr.From(err).Code, agenterr.CodeToolInvalidInput)
+	}
+}
+
+type schemaTool struct {
+	MockTool
+	schema map[string]any
+}
+
+func (s *schemaTool) Name() string                { return "search" }
+func (s *schemaTool) InputSchema() map[string]any { return s.schema }
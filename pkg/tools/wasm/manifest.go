@@ -0,0 +1,125 @@
+// Package wasm 基于 wazero 的 WASM 插件运行时
+//
+// 插件目录包含 plugin.yaml 清单和编译为 wasip1 的 .wasm 模块，放在 ExtensionsDir 下即可加载，无需重新编译 Aster。
+// 每次工具调用都会实例化一个新的模块：参数为 [插件名, 工具名]，stdin 为 JSON 格式的工具输入，
+// stdout 为工具输出（合法 JSON 会被解析），非零退出码视为执行失败。
+// 插件只能访问清单中声明的能力，没有网络访问。
+package wasm
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ManifestFile 插件清单文件名
+const ManifestFile = "plugin.yaml"
+
+// Capability 插件能力
+type Capability string
+
+const (
+	// CapabilityFSRead 以只读方式挂载沙箱工作目录到 /workspace
+	CapabilityFSRead Capability = "fs_read"
+	// CapabilityFSWrite 以读写方式挂载沙箱工作目录到 /workspace
+	CapabilityFSWrite Capability = "fs_write"
+	// CapabilityClock 使用真实的系统时钟（默认为确定性的伪时钟）
+	CapabilityClock Capability = "clock"
+	// CapabilityRandom 使用加密安全的随机源（默认为确定性的伪随机源）
+	CapabilityRandom Capability = "random"
+)
+
+var (
+	knownCapabilities = map[Capability]bool{
+		CapabilityFSRead:  true,
+		CapabilityFSWrite: true,
+		CapabilityClock:   true,
+		CapabilityRandom:  true,
+	}
+	namePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_-]{0,63}$`)
+)
+
+// Manifest 插件清单
+type Manifest struct {
+	Name         string       `yaml:"name" json:"name"`
+	Version      string       `yaml:"version,omitempty" json:"version,omitempty"`
+	Description  string       `yaml:"description,omitempty" json:"description,omitempty"`
+	Module       string       `yaml:"module" json:"module"`                                 // .wasm 文件路径，相对于插件目录
+	Capabilities []Capability `yaml:"capabilities,omitempty" json:"capabilities,omitempty"` // 插件需要的能力
+	Timeout      int          `yaml:"timeout,omitempty" json:"timeout,omitempty"`           // 单次调用超时（秒）
+	Tools        []ToolSpec   `yaml:"tools" json:"tools"`
+}
+
+// ToolSpec 插件提供的工具
+type ToolSpec struct {
+	Name        string         `yaml:"name" json:"name"`
+	Description string         `yaml:"description" json:"description"`
+	InputSchema map[string]any `yaml:"input_schema,omitempty" json:"input_schema,omitempty"`
+	Prompt      string         `yaml:"prompt,omitempty" json:"prompt,omitempty"`
+}
+
+// LoadManifest 读取并校验插件目录中的清单
+func LoadManifest(dir string) (*Manifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, ManifestFile))
+	if err != nil {
+		return nil, fmt.Errorf("read manifest: %w", err)
+	}
+
+	var m Manifest
+	if err := yaml.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("parse manifest: %w", err)
+	}
+	if err := m.Validate(); err != nil {
+		return nil, fmt.Errorf("validate manifest: %w", err)
+	}
+	return &m, nil
+}
+
+// Validate 校验清单
+func (m *Manifest) Validate() error {
+	if !namePattern.MatchString(m.Name) {
+		return fmt.Errorf("invalid plugin name: %q", m.Name)
+	}
+	if m.Module == "" {
+		return errors.New("module is required")
+	}
+	if filepath.IsAbs(m.Module) || !filepath.IsLocal(m.Module) {
+		return fmt.Errorf("module must be a path inside the plugin directory: %s", m.Module)
+	}
+	if m.Timeout < 0 {
+		return errors.New("timeout must not be negative")
+	}
+	for _, c := range m.Capabilities {
+		if !knownCapabilities[c] {
+			return fmt.Errorf("unknown capability: %s", c)
+		}
+	}
+	if len(m.Tools) == 0 {
+		return errors.New("at least one tool is required")
+	}
+
+	seen := make(map[string]bool, len(m.Tools))
+	for _, t := range m.Tools {
+		if !namePattern.MatchString(t.Name) {
+			return fmt.Errorf("invalid tool name: %q", t.Name)
+		}
+		if t.Description == "" {
+			return fmt.Errorf("tool %s: description is required", t.Name)
+		}
+		if seen[t.Name] {
+			return fmt.Errorf("duplicate tool: %s", t.Name)
+		}
+		seen[t.Name] = true
+	}
+	return nil
+}
+
+// Has 是否声明了指定能力
+func (m *Manifest) Has(c Capability) bool {
+	return slices.Contains(m.Capabilities, c)
+}
@@ -0,0 +1,143 @@
+package wasm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+
+	"github.com/astercloud/aster/pkg/logging"
+	"github.com/astercloud/aster/pkg/tools"
+)
+
+var wasmLog = logging.ForComponent("WASMPlugin")
+
+// DefaultMemoryLimitPages 默认内存上限（每页 64KiB，共 256MiB）
+const DefaultMemoryLimitPages = 4096
+
+// RuntimeConfig 运行时配置
+type RuntimeConfig struct {
+	// MemoryLimitPages 单个插件实例的内存上限（页），为 0 时使用 DefaultMemoryLimitPages
+	MemoryLimitPages uint32
+}
+
+// Runtime WASM 插件运行时，负责编译插件并为每次调用创建隔离的实例
+type Runtime struct {
+	runtime wazero.Runtime
+}
+
+// NewRuntime 创建插件运行时
+func NewRuntime(ctx context.Context, config *RuntimeConfig) (*Runtime, error) {
+	limit := uint32(DefaultMemoryLimitPages)
+	if config != nil && config.MemoryLimitPages > 0 {
+		limit = config.MemoryLimitPages
+	}
+
+	r := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfig().
+		WithCloseOnContextDone(true). // 超时或取消时终止正在执行的插件
+		WithMemoryLimitPages(limit))
+	if _, err := wasi_snapshot_preview1.Instantiate(ctx, r); err != nil {
+		_ = r.Close(ctx)
+		return nil, fmt.Errorf("instantiate wasi: %w", err)
+	}
+	return &Runtime{runtime: r}, nil
+}
+
+// Close 关闭运行时并释放所有已编译的插件
+func (r *Runtime) Close(ctx context.Context) error {
+	return r.runtime.Close(ctx)
+}
+
+// Load 加载单个插件目录
+func (r *Runtime) Load(ctx context.Context, dir string) (*Plugin, error) {
+	manifest, err := LoadManifest(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	code, err := os.ReadFile(filepath.Join(dir, manifest.Module))
+	if err != nil {
+		return nil, fmt.Errorf("read module: %w", err)
+	}
+	compiled, err := r.runtime.CompileModule(ctx, code)
+	if err != nil {
+		return nil, fmt.Errorf("compile module: %w", err)
+	}
+	if _, ok := compiled.ExportedFunctions()["_start"]; !ok {
+		_ = compiled.Close(ctx)
+		return nil, errors.New("module must be a WASI command exporting _start")
+	}
+
+	return &Plugin{Manifest: manifest, Dir: dir, runtime: r.runtime, compiled: compiled}, nil
+}
+
+// LoadDir 加载目录下所有包含 plugin.yaml 的子目录
+// 单个插件加载失败不影响其他插件，失败原因合并在返回的 error 中
+func (r *Runtime) LoadDir(ctx context.Context, dir string) ([]*Plugin, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var plugins []*Plugin
+	var errs []error
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		pluginDir := filepath.Join(dir, entry.Name())
+		if _, err := os.Stat(filepath.Join(pluginDir, ManifestFile)); err != nil {
+			continue
+		}
+
+		plugin, err := r.Load(ctx, pluginDir)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("plugin %s: %w", entry.Name(), err))
+			continue
+		}
+		wasmLog.Info(ctx, "plugin loaded", map[string]any{"name": plugin.Manifest.Name, "tools": len(plugin.Manifest.Tools)})
+		plugins = append(plugins, plugin)
+	}
+	return plugins, errors.Join(errs...)
+}
+
+// Plugin 已编译的插件
+type Plugin struct {
+	Manifest *Manifest
+	Dir      string
+
+	runtime  wazero.Runtime
+	compiled wazero.CompiledModule
+}
+
+// Tools 创建插件提供的所有工具
+func (p *Plugin) Tools() []tools.Tool {
+	result := make([]tools.Tool, 0, len(p.Manifest.Tools))
+	for _, spec := range p.Manifest.Tools {
+		result = append(result, &pluginTool{plugin: p, spec: spec})
+	}
+	return result
+}
+
+// RegisterTools 将插件工具注册到 Registry 并返回工具名
+// 插件工具不能覆盖已注册的工具
+func (p *Plugin) RegisterTools(registry *tools.Registry) ([]string, error) {
+	names := make([]string, 0, len(p.Manifest.Tools))
+	for _, tool := range p.Tools() {
+		if registry.Has(tool.Name()) {
+			return names, fmt.Errorf("plugin %s: tool %q conflicts with a registered tool", p.Manifest.Name, tool.Name())
+		}
+		registry.Register(tool.Name(), func(map[string]any) (tools.Tool, error) {
+			return tool, nil
+		})
+		names = append(names, tool.Name())
+	}
+	return names, nil
+}
@@ -0,0 +1,42 @@
+// demo 测试用插件，使用 GOOS=wasip1 GOARCH=wasm 编译
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+func main() {
+	var input map[string]any
+	if err := json.NewDecoder(os.Stdin).Decode(&input); err != nil {
+		fmt.Fprintln(os.Stderr, "invalid input:", err)
+		os.Exit(2)
+	}
+
+	switch tool := os.Args[1]; tool {
+	case "echo":
+		_ = json.NewEncoder(os.Stdout).Encode(map[string]any{"tool": tool, "input": input})
+	case "cat":
+		path, _ := input["path"].(string)
+		data, err := os.ReadFile(filepath.Join(os.Getenv("ASTER_WORKSPACE"), path))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Print(string(data))
+	case "touch":
+		path, _ := input["path"].(string)
+		if err := os.WriteFile(filepath.Join(os.Getenv("ASTER_WORKSPACE"), path), []byte("x"), 0o644); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	case "spin":
+		for {
+		}
+	default:
+		fmt.Fprintln(os.Stderr, "unknown tool:", tool)
+		os.Exit(3)
+	}
+}
@@ -0,0 +1,162 @@
+package wasm
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/sys"
+
+	"github.com/astercloud/aster/pkg/tools"
+)
+
+const (
+	// defaultTimeout 插件未配置超时时的单次调用超时
+	defaultTimeout = 30 * time.Second
+	// maxOutputBytes 插件 stdout/stderr 的最大字节数
+	maxOutputBytes = 1 << 20
+	// workspaceMount 沙箱工作目录在插件内的挂载点
+	workspaceMount = "/workspace"
+)
+
+// pluginTool 将插件声明的工具适配为 aster Tool 接口
+type pluginTool struct {
+	plugin *Plugin
+	spec   ToolSpec
+}
+
+func (t *pluginTool) Name() string {
+	return t.spec.Name
+}
+
+func (t *pluginTool) Description() string {
+	return t.spec.Description
+}
+
+func (t *pluginTool) InputSchema() map[string]any {
+	if t.spec.InputSchema != nil {
+		return t.spec.InputSchema
+	}
+	return map[string]any{"type": "object", "properties": map[string]any{}}
+}
+
+func (t *pluginTool) Prompt() string {
+	return t.spec.Prompt
+}
+
+// Annotations 插件没有网络访问，风险取决于是否能写入工作目录
+func (t *pluginTool) Annotations() *tools.ToolAnnotations {
+	if t.plugin.Manifest.Has(CapabilityFSWrite) {
+		return tools.AnnotationsSafeWrite.Clone().WithCategory(tools.CategoryCustom)
+	}
+	return tools.AnnotationsSafeReadOnly.Clone().WithCategory(tools.CategoryCustom)
+}
+
+func (t *pluginTool) Execute(ctx context.Context, input map[string]any, tc *tools.ToolContext) (any, error) {
+	manifest := t.plugin.Manifest
+	stdin, err := json.Marshal(input)
+	if err != nil {
+		return nil, fmt.Errorf("encode input: %w", err)
+	}
+
+	timeout := defaultTimeout
+	if manifest.Timeout > 0 {
+		timeout = time.Duration(manifest.Timeout) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	stdout := &limitedBuffer{limit: maxOutputBytes}
+	stderr := &limitedBuffer{limit: maxOutputBytes}
+	config := wazero.NewModuleConfig().
+		WithName(""). // 允许同一插件并发创建多个实例
+		WithArgs(manifest.Name, t.spec.Name).
+		WithEnv("ASTER_TOOL", t.spec.Name).
+		WithStdin(bytes.NewReader(stdin)).
+		WithStdout(stdout).
+		WithStderr(stderr)
+
+	fsConfig, err := t.fsConfig(tc)
+	if err != nil {
+		return nil, err
+	}
+	if fsConfig != nil {
+		config = config.WithFSConfig(fsConfig).WithEnv("ASTER_WORKSPACE", workspaceMount)
+	}
+	if manifest.Has(CapabilityClock) {
+		config = config.WithSysWalltime().WithSysNanotime().WithSysNanosleep()
+	}
+	if manifest.Has(CapabilityRandom) {
+		config = config.WithRandSource(rand.Reader)
+	}
+
+	mod, err := t.plugin.runtime.InstantiateModule(ctx, t.plugin.compiled, config)
+	if mod != nil {
+		_ = mod.Close(context.Background())
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("plugin %s: %s timed out or was canceled: %w", manifest.Name, t.spec.Name, ctx.Err())
+		}
+		var exitErr *sys.ExitError
+		if errors.As(err, &exitErr) {
+			return nil, fmt.Errorf("plugin %s: %s exited with code %d: %s",
+				manifest.Name, t.spec.Name, exitErr.ExitCode(), strings.TrimSpace(stderr.String()))
+		}
+		return nil, fmt.Errorf("plugin %s: %s: %w", manifest.Name, t.spec.Name, err)
+	}
+
+	output := bytes.TrimSpace(stdout.Bytes())
+	var result any
+	if json.Valid(output) && len(output) > 0 {
+		if err := json.Unmarshal(output, &result); err == nil {
+			return result, nil
+		}
+	}
+	return map[string]any{"ok": true, "output": stdout.String(), "truncated": stdout.truncated}, nil
+}
+
+// fsConfig 根据插件能力挂载沙箱工作目录，仅支持本地沙箱
+func (t *pluginTool) fsConfig(tc *tools.ToolContext) (wazero.FSConfig, error) {
+	manifest := t.plugin.Manifest
+	write := manifest.Has(CapabilityFSWrite)
+	if !write && !manifest.Has(CapabilityFSRead) {
+		return nil, nil
+	}
+	if tc == nil || tc.Sandbox == nil {
+		return nil, fmt.Errorf("plugin %s requires a sandbox for filesystem access", manifest.Name)
+	}
+	if kind := tc.Sandbox.Kind(); kind != "local" {
+		return nil, fmt.Errorf("plugin %s: filesystem access is not supported by the %s sandbox", manifest.Name, kind)
+	}
+
+	workDir := tc.Sandbox.WorkDir()
+	if write {
+		return wazero.NewFSConfig().WithDirMount(workDir, workspaceMount), nil
+	}
+	return wazero.NewFSConfig().WithReadOnlyDirMount(workDir, workspaceMount), nil
+}
+
+// limitedBuffer 超过上限后丢弃后续输出
+type limitedBuffer struct {
+	bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if remaining := b.limit - b.Len(); len(p) > remaining {
+		b.truncated = true
+		if remaining > 0 {
+			b.Buffer.Write(p[:remaining])
+		}
+		return len(p), nil
+	}
+	return b.Buffer.Write(p)
+}
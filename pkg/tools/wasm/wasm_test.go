@@ -0,0 +1,187 @@
+package wasm
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/astercloud/aster/pkg/sandbox"
+	"github.com/astercloud/aster/pkg/tools"
+)
+
+var (
+	demoOnce sync.Once
+	demoWasm []byte
+	demoErr  error
+)
+
+// buildDemo 将 testdata/demo 编译为 wasip1 模块
+func buildDemo(t *testing.T) []byte {
+	t.Helper()
+	demoOnce.Do(func() {
+		dir, err := os.MkdirTemp("", "aster-wasm-demo")
+		if err != nil {
+			demoErr = err
+			return
+		}
+		defer func() { _ = os.RemoveAll(dir) }()
+		out := filepath.Join(dir, "demo.wasm")
+		cmd := exec.Command("go", "build", "-o", out, "./testdata/demo")
+		cmd.Env = append(os.Environ(), "GOOS=wasip1", "GOARCH=wasm")
+		if output, err := cmd.CombinedOutput(); err != nil {
+			demoErr = err
+			demoWasm = output
+			return
+		}
+		demoWasm, demoErr = os.ReadFile(out)
+	})
+	if demoErr != nil {
+		t.Skipf("cannot build wasip1 test plugin: %v\n%s", demoErr, demoWasm)
+	}
+	return demoWasm
+}
+
+// writePlugin 在 dir 下创建名为 name 的插件目录
+func writePlugin(t *testing.T, dir, name, manifest string) {
+	t.Helper()
+	pluginDir := filepath.Join(dir, name)
+	if err := os.MkdirAll(pluginDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(pluginDir, "demo.wasm"), buildDemo(t), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(pluginDir, ManifestFile), []byte(manifest), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestManifestValidate(t *testing.T) {
+	valid := Manifest{Name: "demo", Module: "demo.wasm", Tools: []ToolSpec{{Name: "echo", Description: "Echo input"}}}
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+
+	tests := map[string]func(m *Manifest){
+		"bad name":           func(m *Manifest) { m.Name = "my plugin" },
+		"module escapes dir": func(m *Manifest) { m.Module = "../demo.wasm" },
+		"unknown capability": func(m *Manifest) { m.Capabilities = []Capability{"network"} },
+		"no tools":           func(m *Manifest) { m.Tools = nil },
+		"duplicate tool": func(m *Manifest) {
+			m.Tools = append(m.Tools, ToolSpec{Name: "echo", Description: "again"})
+		},
+	}
+	for name, mutate := range tests {
+		t.Run(name, func(t *testing.T) {
+			m := valid
+			m.Tools = append([]ToolSpec(nil), valid.Tools...)
+			mutate(&m)
+			if err := m.Validate(); err == nil {
+				t.Error("Validate() should fail")
+			}
+		})
+	}
+}
+
+func TestPluginTools(t *testing.T) {
+	ctx := context.Background()
+	extDir := t.TempDir()
+	writePlugin(t, extDir, "reader", `
+name: reader
+module: demo.wasm
+capabilities: [fs_read]
+timeout: 2
+tools:
+  - {name: echo, description: Echo the input}
+  - {name: cat, description: Read a workspace file}
+  - {name: touch, description: Create a workspace file}
+  - {name: spin, description: Never returns}
+`)
+	writePlugin(t, extDir, "writer", `
+name: writer
+module: demo.wasm
+capabilities: [fs_write]
+tools:
+  - {name: write_file, description: Create a workspace file}
+`)
+	writePlugin(t, extDir, "broken", "name: broken\nmodule: missing.wasm\ntools: [{name: x, description: x}]\n")
+
+	rt, err := NewRuntime(ctx, nil)
+	if err != nil {
+		t.Fatalf("NewRuntime() error = %v", err)
+	}
+	defer func() { _ = rt.Close(ctx) }()
+
+	plugins, err := rt.LoadDir(ctx, extDir)
+	if err == nil || !strings.Contains(err.Error(), "broken") {
+		t.Errorf("LoadDir() error = %v, want broken plugin error", err)
+	}
+	if len(plugins) != 2 {
+		t.Fatalf("LoadDir() loaded %d plugins, want 2", len(plugins))
+	}
+
+	registry := tools.NewRegistry()
+	for _, p := range plugins {
+		if _, err := p.RegisterTools(registry); err != nil {
+			t.Fatalf("RegisterTools() error = %v", err)
+		}
+	}
+
+	workDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(workDir, "hello.txt"), []byte("hello from host"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	sb, err := sandbox.NewLocalSandbox(&sandbox.LocalSandboxConfig{WorkDir: workDir})
+	if err != nil {
+		t.Fatalf("NewLocalSandbox() error = %v", err)
+	}
+	tc := &tools.ToolContext{Sandbox: sb}
+
+	run := func(name string, input map[string]any) (any, error) {
+		tool, err := registry.Create(name, nil)
+		if err != nil {
+			t.Fatalf("Create(%s) error = %v", name, err)
+		}
+		return tool.Execute(ctx, input, tc)
+	}
+
+	result, err := run("echo", map[string]any{"text": "hi"})
+	if err != nil {
+		t.Fatalf("echo error = %v", err)
+	}
+	if res, _ := result.(map[string]any); res["tool"] != "echo" || res["input"].(map[string]any)["text"] != "hi" {
+		t.Errorf("echo = %v", result)
+	}
+
+	result, err = run("cat", map[string]any{"path": "hello.txt"})
+	if err != nil || result.(map[string]any)["output"] != "hello from host" {
+		t.Errorf("cat = %v, %v", result, err)
+	}
+
+	// 只读挂载下不能写入
+	if _, err := run("touch", map[string]any{"path": "new.txt"}); err == nil {
+		t.Error("touch should fail without fs_write")
+	}
+
+	if _, err := run("spin", map[string]any{}); err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("spin error = %v, want timeout", err)
+	}
+
+	// 工具名与清单中的名字不同时按未知工具退出
+	if _, err := run("write_file", map[string]any{"path": "new.txt"}); err == nil || !strings.Contains(err.Error(), "exited with code 3") {
+		t.Errorf("write_file error = %v, want exit code 3", err)
+	}
+
+	tool, _ := registry.Create("echo", nil)
+	if !tools.IsToolSafeForAutoApproval(tool) {
+		t.Error("read-only plugin tools should be safe for auto approval")
+	}
+	tool, _ = registry.Create("write_file", nil)
+	if tools.IsToolSafeForAutoApproval(tool) {
+		t.Error("plugin tools with fs_write should require approval")
+	}
+}
@@ -1,6 +1,10 @@
 package types
 
-import "time"
+import (
+	"time"
+
+	"github.com/astercloud/aster/pkg/agenterr"
+)
 
 // PermissionMode 权限模式
 type PermissionMode string
@@ -205,13 +209,15 @@ type VectorStoreConfig struct {
 type EmbedderConfig struct {
 	// Provider 嵌入模型提供商
 	// - "openai": OpenAI Embeddings
-	// - "gemini": Google Gemini Embeddings
-	// - "local": 本地嵌入模型
+	// - "voyage": Voyage AI Embeddings
+	// - "ollama" / "local": 本地 Ollama 嵌入模型
+	// - "mock": 测试用伪向量
 	Provider string `json:"provider" yaml:"provider"`
 
 	// Model 嵌入模型名称
 	// OpenAI: "text-embedding-3-small", "text-embedding-3-large", "text-embedding-ada-002"
-	// Gemini: "text-embedding-004", "text-multilingual-embedding-002"
+	// Voyage: "voyage-3", "voyage-3-lite", "voyage-code-3"
+	// Ollama: "nomic-embed-text", "mxbai-embed-large"
 	Model string `json:"model" yaml:"model"`
 
 	// APIKey API 密钥
@@ -222,7 +228,9 @@ type EmbedderConfig struct {
 	// text-embedding-3-large: 可选 256, 1024, 3072
 	Dimensions int `json:"dimensions,omitempty" yaml:"dimensions,omitempty"`
 
-	// Config 特定提供商的额外配置
+	// Config 特定提供商的额外配置（如 base_url），以及通用配置:
+	// - cache_dir: 以内容哈希为键的磁盘缓存目录
+	// - batch_size / batch_concurrency: 批量请求的大小和并发数
 	Config map[string]any `json:"config,omitempty" yaml:"config,omitempty"`
 }
 
@@ -258,6 +266,43 @@ type AgentTemplateRuntime struct {
 	PromptCompression       *PromptCompressionConfig       `json:"prompt_compression,omitempty"`
 	ConversationCompression *ConversationCompressionConfig `json:"conversation_compression,omitempty"`
 	DisabledPromptModules   []string                       `json:"disabled_prompt_modules,omitempty"` // 要禁用的 prompt 模块列表
+	Safety                  *SafetyConfig                  `json:"safety,omitempty"`
+}
+
+// SafetyAction 安全策略命中后的处理方式
+type SafetyAction string
+
+const (
+	// SafetyActionBlock 拦截整段内容
+	SafetyActionBlock SafetyAction = "block"
+	// SafetyActionRedact 替换命中的片段
+	SafetyActionRedact SafetyAction = "redact"
+)
+
+// SafetyRule 正则安全规则
+type SafetyRule struct {
+	Name    string       `json:"name"`
+	Pattern string       `json:"pattern"`
+	Action  SafetyAction `json:"action,omitempty"` // 默认 redact
+	// Replacement 脱敏替换文本，默认 "[REDACTED]"；支持 $1 等分组引用
+	Replacement string `json:"replacement,omitempty"`
+}
+
+// SafetyPolicy 单个阶段的安全策略
+type SafetyPolicy struct {
+	Rules []SafetyRule `json:"rules,omitempty"`
+	// Classifiers 按名称引用的分类器（guardrails.Guardrail），在 agent.Dependencies.SafetyClassifiers 中注册
+	Classifiers []string `json:"classifiers,omitempty"`
+	// BlockMessage 内容被拦截时展示的文本
+	BlockMessage string `json:"block_message,omitempty"`
+}
+
+// SafetyConfig 内容安全过滤配置
+type SafetyConfig struct {
+	// PreSend 用户消息发送给模型前检查
+	PreSend *SafetyPolicy `json:"pre_send,omitempty"`
+	// PreDisplay 模型输出展示给用户前检查；启用后文本按块整体输出，不再逐字流式
+	PreDisplay *SafetyPolicy `json:"pre_display,omitempty"`
 }
 
 // AgentTemplateDefinition Agent模板定义
@@ -269,6 +314,11 @@ type AgentTemplateDefinition struct {
 	Tools        any                   `json:"tools"` // []string or "*"
 	Permission   *PermissionConfig     `json:"permission,omitempty"`
 	Runtime      *AgentTemplateRuntime `json:"runtime,omitempty"`
+
+	// AllowedTools 工具调用白名单，支持 "Bash(npm run *)" 形式的参数模式；为空时不限制
+	AllowedTools []string `json:"allowed_tools,omitempty"`
+	// DisallowedTools 工具调用黑名单，优先于白名单
+	DisallowedTools []string `json:"disallowed_tools,omitempty"`
 }
 
 // ModelConfig 模型配置
@@ -278,6 +328,39 @@ type ModelConfig struct {
 	APIKey        string        `json:"api_key,omitempty" yaml:"api_key,omitempty"`
 	BaseURL       string        `json:"base_url,omitempty" yaml:"base_url,omitempty"`
 	ExecutionMode ExecutionMode `json:"execution_mode,omitempty" yaml:"execution_mode,omitempty"` // 执行模式：streaming/non-streaming/auto
+
+	// ContextWindow 上下文窗口大小（token），0 表示使用 provider 包内置的模型登记表
+	ContextWindow int `json:"context_window,omitempty" yaml:"context_window,omitempty"`
+
+	// Reasoning 推理模式配置（Anthropic extended thinking / OpenAI reasoning effort）
+	Reasoning *ReasoningConfig `json:"reasoning,omitempty" yaml:"reasoning,omitempty"`
+}
+
+// ReasoningStoreMode 推理内容在会话中的存储方式
+type ReasoningStoreMode string
+
+const (
+	// ReasoningStoreNone 不持久化推理内容（默认）
+	ReasoningStoreNone ReasoningStoreMode = ""
+	// ReasoningStoreFull 完整持久化推理内容
+	ReasoningStoreFull ReasoningStoreMode = "full"
+	// ReasoningStoreRedacted 只保留推理块占位，内容被清除
+	ReasoningStoreRedacted ReasoningStoreMode = "redacted"
+)
+
+// ReasoningConfig 推理模式配置
+type ReasoningConfig struct {
+	// Enabled 是否启用推理模式（Anthropic extended thinking）
+	Enabled bool `json:"enabled" yaml:"enabled"`
+
+	// BudgetTokens 思考 token 预算（Anthropic），0 表示使用默认值
+	BudgetTokens int `json:"budget_tokens,omitempty" yaml:"budget_tokens,omitempty"`
+
+	// Effort 推理强度（OpenAI reasoning_effort）: "low", "medium", "high"
+	Effort string `json:"effort,omitempty" yaml:"effort,omitempty"`
+
+	// Store 推理内容在会话中的存储方式: "" (不存储), "full", "redacted"
+	Store ReasoningStoreMode `json:"store,omitempty" yaml:"store,omitempty"`
 }
 
 // SandboxKind 沙箱类型
@@ -302,6 +385,9 @@ type SandboxConfig struct {
 	WatchFiles      bool           `json:"watch_files,omitempty"`
 	Extra           map[string]any `json:"extra,omitempty"` // 云平台特定配置
 
+	// Resources 命令执行的资源限制，超出限制的进程会被终止
+	Resources *ResourceLimits `json:"resources,omitempty"`
+
 	// === Claude Agent SDK 风格的安全配置 ===
 
 	// Settings 沙箱安全设置（可选，提供更细粒度的控制）
@@ -309,6 +395,80 @@ type SandboxConfig struct {
 
 	// PermissionMode 沙箱权限模式
 	PermissionMode SandboxPermissionMode `json:"permission_mode,omitempty"`
+
+	// Isolation 为每个会话在 WorkDir 下创建独立的工作目录（仅 local/docker），
+	// 避免同一仓库上的并发会话互相覆盖文件
+	Isolation *WorkDirIsolation `json:"isolation,omitempty"`
+
+	// Mock mock 沙箱的脚本化行为，仅 Kind 为 mock 时生效
+	Mock *MockSandboxConfig `json:"mock,omitempty"`
+}
+
+// WorkDirIsolationMode 会话工作目录隔离方式
+type WorkDirIsolationMode string
+
+const (
+	// WorkDirIsolationNone 不隔离，直接使用 WorkDir
+	WorkDirIsolationNone WorkDirIsolationMode = ""
+	// WorkDirIsolationDir 复制 WorkDir（不含 .git）到会话子目录
+	WorkDirIsolationDir WorkDirIsolationMode = "dir"
+	// WorkDirIsolationWorktree 为会话创建 git worktree 和分支，WorkDir 须在 git 仓库中
+	WorkDirIsolationWorktree WorkDirIsolationMode = "worktree"
+)
+
+// WorkDirCleanup 会话结束（Agent 关闭）时隔离目录的清理策略
+type WorkDirCleanup string
+
+const (
+	// WorkDirCleanupIfClean 没有未合并的改动时删除（默认）
+	WorkDirCleanupIfClean WorkDirCleanup = "if_clean"
+	// WorkDirCleanupAlways 总是删除，未合并的改动会丢失
+	WorkDirCleanupAlways WorkDirCleanup = "always"
+	// WorkDirCleanupNever 保留，供之后恢复会话或手动合并
+	WorkDirCleanupNever WorkDirCleanup = "never"
+)
+
+// WorkDirIsolation 会话工作目录隔离配置
+type WorkDirIsolation struct {
+	Mode WorkDirIsolationMode `json:"mode"`
+	// Root 隔离目录的父目录，默认 <WorkDir>/.aster/sessions
+	Root    string         `json:"root,omitempty"`
+	Cleanup WorkDirCleanup `json:"cleanup,omitempty"`
+	// BaseRef worktree 模式下新分支的起点，默认 HEAD
+	BaseRef string `json:"base_ref,omitempty"`
+}
+
+// MockSandboxConfig mock 沙箱的脚本化行为，用于编写确定性的 Agent 集成测试
+type MockSandboxConfig struct {
+	// WorkDir 虚拟工作目录，默认 /mock/workspace
+	WorkDir string `json:"work_dir,omitempty"`
+	// Commands 命令脚本，按顺序取第一个匹配且未用完次数的脚本
+	Commands []MockCommand `json:"commands,omitempty"`
+	// Files 初始虚拟文件，相对路径基于 WorkDir
+	Files map[string]string `json:"files,omitempty"`
+	// Default 没有脚本匹配时的结果，为空时输出 "Mock output for: <命令>"
+	Default *MockCommand `json:"default,omitempty"`
+	// Strict 为 true 时没有脚本匹配的命令返回执行错误（不使用 Default）
+	Strict bool `json:"strict,omitempty"`
+}
+
+// MockCommand 一条命令脚本
+type MockCommand struct {
+	// Match 匹配完整命令：精确匹配；含 * 时按通配匹配；以 "re:" 开头时按正则匹配
+	Match    string `json:"match"`
+	Stdout   string `json:"stdout,omitempty"`
+	Stderr   string `json:"stderr,omitempty"`
+	ExitCode int    `json:"exit_code,omitempty"`
+	// LatencyMs 返回前的延迟，超过 ExecOptions.Timeout 时按超时处理
+	LatencyMs int `json:"latency_ms,omitempty"`
+	// Error 非空时 Exec 返回该错误，模拟沙箱本身的故障
+	Error string `json:"error,omitempty"`
+	// Times 最多匹配的次数，0 表示不限；用于让同一命令依次返回不同结果
+	Times int `json:"times,omitempty"`
+	// WriteFiles 命令执行后写入虚拟文件系统，模拟命令的副作用
+	WriteFiles map[string]string `json:"write_files,omitempty"`
+	// RemoveFiles 命令执行后从虚拟文件系统删除的文件
+	RemoveFiles []string `json:"remove_files,omitempty"`
 }
 
 // CloudCredentials 云平台凭证
@@ -320,10 +480,13 @@ type CloudCredentials struct {
 
 // ResourceLimits 资源限制
 type ResourceLimits struct {
-	CPUQuota    float64       `json:"cpu_quota,omitempty"`    // CPU配额(核数)
-	MemoryLimit int64         `json:"memory_limit,omitempty"` // 内存限制(字节)
-	Timeout     time.Duration `json:"timeout,omitempty"`      // 超时时间
-	DiskQuota   int64         `json:"disk_quota,omitempty"`   // 磁盘配额(字节)
+	CPUQuota     float64       `json:"cpu_quota,omitempty"`     // CPU配额(核数)
+	MemoryLimit  int64         `json:"memory_limit,omitempty"`  // 内存限制(字节)
+	Timeout      time.Duration `json:"timeout,omitempty"`       // 超时时间
+	DiskQuota    int64         `json:"disk_quota,omitempty"`    // 磁盘配额(字节)
+	MaxProcesses int           `json:"max_processes,omitempty"` // 单条命令的最大进程数
+	CPUTime      time.Duration `json:"cpu_time,omitempty"`      // 单条命令累计 CPU 时间上限
+	Nice         int           `json:"nice,omitempty"`          // 进程优先级调整（Unix nice 值，Windows 映射为低优先级）
 }
 
 // CloudSandboxConfig 云沙箱配置
@@ -371,6 +534,45 @@ type AgentConfig struct {
 	SkillsPackage  *SkillsPackageConfig   `json:"skills_package,omitempty" yaml:"skills_package,omitempty"` // Skills 包配置
 	Metadata       map[string]any         `json:"metadata,omitempty" yaml:"metadata,omitempty"`
 
+	// Escalation 按轮模型升级策略：从便宜模型起步，遇到失败信号时自动升级
+	Escalation *ModelEscalationConfig `json:"escalation,omitempty" yaml:"escalation,omitempty"`
+
+	// ContextGuard 上下文窗口保护：请求超出模型窗口前主动压缩或拒绝
+	ContextGuard *ContextGuardConfig `json:"context_guard,omitempty" yaml:"context_guard,omitempty"`
+
+	// LoopGuard 循环保护：检测重复工具调用、来回修改和无进展步骤并介入
+	LoopGuard *LoopGuardConfig `json:"loop_guard,omitempty" yaml:"loop_guard,omitempty"`
+
+	// MaxInputCorrections 工具参数校验失败后允许模型按反馈修正重试的次数，按工具计，调用通过校验后重置；默认 3
+	MaxInputCorrections int `json:"max_input_corrections,omitempty" yaml:"max_input_corrections,omitempty"`
+
+	// 单次运行（一条用户消息触发的处理过程）的限制，0 表示不限制；
+	// 超出时 Agent 停止运行，在完成事件和 Chat 结果中给出终止原因和部分结果摘要
+	// MaxTurns 最多模型调用次数
+	MaxTurns int `json:"max_turns,omitempty" yaml:"max_turns,omitempty"`
+	// MaxOutputTokens 累计输出 Token 上限，单次调用的输出上限也会收紧到剩余额度
+	MaxOutputTokens int64 `json:"max_output_tokens,omitempty" yaml:"max_output_tokens,omitempty"`
+	// MaxDuration 运行时长上限，超时会取消进行中的模型调用和工具
+	MaxDuration time.Duration `json:"max_duration,omitempty" yaml:"max_duration,omitempty"`
+
+	// Budget 会话预算，决定成本 HUD 的告警阈值
+	Budget *BudgetConfig `json:"budget,omitempty" yaml:"budget,omitempty"`
+
+	// AutoTitle 首轮对话完成后用低成本模型生成会话标题，保存到 Agent 元信息的 title 字段
+	AutoTitle bool `json:"auto_title,omitempty" yaml:"auto_title,omitempty"`
+
+	// SeedSummary 恢复或分叉会话时的起点摘要，注入 System Prompt
+	SeedSummary *ConversationSummary `json:"seed_summary,omitempty" yaml:"seed_summary,omitempty"`
+
+	// SeedConversation 示例对话（few-shot），每次请求时注入在真实对话之前，不写入消息历史
+	SeedConversation *SeedConversationConfig `json:"seed_conversation,omitempty" yaml:"seed_conversation,omitempty"`
+
+	// LSP 代码智能（语言服务器）配置，仅本地沙箱可用
+	LSP *LSPConfig `json:"lsp,omitempty" yaml:"lsp,omitempty"`
+
+	// Browser 浏览器自动化（无头 Chrome）配置，访问范围受沙箱网络策略限制
+	Browser *BrowserConfig `json:"browser,omitempty" yaml:"browser,omitempty"`
+
 	// === 多租户支持 ===
 
 	// Multitenancy 多租户配置
@@ -383,15 +585,137 @@ type AgentConfig struct {
 
 	// === Claude Agent SDK 风格的权限控制 ===
 
+	// AllowedTools 工具调用白名单，设置后替换模板的白名单，支持 "Bash(npm run *)" 形式的参数模式
+	AllowedTools []string `json:"allowed_tools,omitempty" yaml:"allowed_tools,omitempty"`
+
+	// DisallowedTools 工具调用黑名单，与模板的黑名单合并，优先于白名单
+	DisallowedTools []string `json:"disallowed_tools,omitempty" yaml:"disallowed_tools,omitempty"`
+
 	// CanUseTool 自定义权限检查回调（不序列化）
 	// 应用层可以通过此回调完全控制工具权限
 	CanUseTool CanUseToolFunc `json:"-"`
 
 	// AllowDangerouslySkipPermissions 允许绕过权限检查
-	// 必须显式设置为 true 才能使用 PermissionMode: "bypassPermissions"
+	// 必须显式设置为 true 才能使用 PermissionMode: "bypassPermissions" 或切换到 bypass 审批模式
 	AllowDangerouslySkipPermissions bool `json:"allow_dangerously_skip_permissions,omitempty"`
 }
 
+// ModelEscalationConfig 模型升级策略配置
+type ModelEscalationConfig struct {
+	Enabled bool `json:"enabled" yaml:"enabled"`
+	// Ladder 模型阶梯（逻辑别名或 provider/model），从便宜到强，Agent 从第一级起步
+	Ladder []string `json:"ladder" yaml:"ladder"`
+	// MaxToolFailures 连续工具失败达到该次数时升级，默认 2
+	MaxToolFailures int `json:"max_tool_failures,omitempty" yaml:"max_tool_failures,omitempty"`
+	// LowConfidencePhrases 低置信度回答的识别短语，为空时使用内置列表
+	LowConfidencePhrases []string `json:"low_confidence_phrases,omitempty" yaml:"low_confidence_phrases,omitempty"`
+	// DisableRetryDetection 关闭用户重试检测
+	DisableRetryDetection bool `json:"disable_retry_detection,omitempty" yaml:"disable_retry_detection,omitempty"`
+	// ResetEachTurn 每条新的（非重试）用户消息回到阶梯第一级
+	ResetEachTurn bool `json:"reset_each_turn,omitempty" yaml:"reset_each_turn,omitempty"`
+}
+
+// LSPConfig 代码智能配置（默认启用内置的常用语言服务器）
+type LSPConfig struct {
+	// Disabled 关闭代码智能工具
+	Disabled bool `json:"disabled,omitempty" yaml:"disabled,omitempty"`
+	// Servers 自定义语言服务器，与内置服务器处理相同扩展名时优先
+	Servers []LSPServerConfig `json:"servers,omitempty" yaml:"servers,omitempty"`
+	// DisableDefaults 不使用内置的语言服务器配置
+	DisableDefaults bool `json:"disable_defaults,omitempty" yaml:"disable_defaults,omitempty"`
+}
+
+// LSPServerConfig 语言服务器配置
+type LSPServerConfig struct {
+	Name                  string            `json:"name" yaml:"name"`
+	Command               string            `json:"command" yaml:"command"`
+	Args                  []string          `json:"args,omitempty" yaml:"args,omitempty"`
+	Extensions            []string          `json:"extensions" yaml:"extensions"`                         // 如 [".go"]
+	RootMarkers           []string          `json:"root_markers,omitempty" yaml:"root_markers,omitempty"` // 如 ["go.mod"]
+	Env                   map[string]string `json:"env,omitempty" yaml:"env,omitempty"`
+	InitializationOptions map[string]any    `json:"initialization_options,omitempty" yaml:"initialization_options,omitempty"`
+}
+
+// BrowserConfig 浏览器自动化配置（默认启用，首次使用时才启动浏览器）
+type BrowserConfig struct {
+	// Disabled 关闭浏览器工具
+	Disabled bool `json:"disabled,omitempty" yaml:"disabled,omitempty"`
+	// ExecPath Chrome/Chromium 可执行文件路径，为空时自动查找
+	ExecPath string `json:"exec_path,omitempty" yaml:"exec_path,omitempty"`
+	// Headful 显示浏览器窗口（调试用），默认无头运行
+	Headful bool `json:"headful,omitempty" yaml:"headful,omitempty"`
+	// TimeoutSeconds 单个操作的超时秒数，默认 30
+	TimeoutSeconds int `json:"timeout_seconds,omitempty" yaml:"timeout_seconds,omitempty"`
+	// ScreenshotDir 截图保存目录（相对于工作目录），默认 .aster/screenshots
+	ScreenshotDir string `json:"screenshot_dir,omitempty" yaml:"screenshot_dir,omitempty"`
+}
+
+// ContextGuardConfig 上下文窗口保护配置（默认启用）
+type ContextGuardConfig struct {
+	// Disabled 关闭上下文窗口检查
+	Disabled bool `json:"disabled,omitempty" yaml:"disabled,omitempty"`
+	// OutputReserveTokens 为模型输出预留的 token 数，默认 min(8192, 窗口/4)
+	OutputReserveTokens int `json:"output_reserve_tokens,omitempty" yaml:"output_reserve_tokens,omitempty"`
+	// RefuseOnly 超出窗口时直接拒绝请求，不自动压缩历史消息
+	RefuseOnly bool `json:"refuse_only,omitempty" yaml:"refuse_only,omitempty"`
+	// KeepRecentMessages 压缩时完整保留的最近消息数，默认 4
+	KeepRecentMessages int `json:"keep_recent_messages,omitempty" yaml:"keep_recent_messages,omitempty"`
+}
+
+// BudgetConfig 会话预算与成本 HUD 配置
+type BudgetConfig struct {
+	// MaxCost 会话费用预算（按价格表币种，默认美元），0 表示不设预算
+	MaxCost float64 `json:"max_cost,omitempty" yaml:"max_cost,omitempty"`
+	// MaxTokens 会话 Token 预算，0 表示不设预算
+	MaxTokens int64 `json:"max_tokens,omitempty" yaml:"max_tokens,omitempty"`
+	// WarnPercent 预算或上下文占用达到该百分比时显示警告色，默认 70
+	WarnPercent float64 `json:"warn_percent,omitempty" yaml:"warn_percent,omitempty"`
+	// CriticalPercent 达到该百分比时显示危险色，默认 90
+	CriticalPercent float64 `json:"critical_percent,omitempty" yaml:"critical_percent,omitempty"`
+	// HUDInterval 成本事件的最短发送间隔，默认每次模型调用后发送
+	HUDInterval time.Duration `json:"hud_interval,omitempty" yaml:"hud_interval,omitempty"`
+}
+
+// SeedConversationConfig 示例对话配置
+type SeedConversationConfig struct {
+	// Turns 按顺序注入的示例轮次
+	Turns []SeedTurn `json:"turns" yaml:"turns"`
+	// MaxTokens 示例的 Token 预算（估算），超出时从最后一轮起丢弃；0 表示不限制
+	MaxTokens int `json:"max_tokens,omitempty" yaml:"max_tokens,omitempty"`
+	// ExcludeFromCompaction 示例不参与上下文压缩：始终完整发送，窗口不足时只压缩真实对话；
+	// 否则示例视为最早的历史，窗口不足时最先被丢弃
+	ExcludeFromCompaction bool `json:"exclude_from_compaction,omitempty" yaml:"exclude_from_compaction,omitempty"`
+}
+
+// SeedTurn 一轮示例对话
+type SeedTurn struct {
+	User      string `json:"user" yaml:"user"`
+	Assistant string `json:"assistant" yaml:"assistant"`
+}
+
+// 循环保护介入方式
+const (
+	LoopInterventionReflect  = "reflect"  // 注入反思提示
+	LoopInterventionEscalate = "escalate" // 升级模型（需启用 Escalation）
+	LoopInterventionPause    = "pause"    // 暂停并等待用户确认
+)
+
+// LoopGuardConfig 循环保护配置
+type LoopGuardConfig struct {
+	Enabled bool `json:"enabled" yaml:"enabled"`
+	// MaxRepeatedCalls 窗口内相同工具调用（名称与参数均相同）达到该次数视为重复，默认 3
+	MaxRepeatedCalls int `json:"max_repeated_calls,omitempty" yaml:"max_repeated_calls,omitempty"`
+	// MaxOscillations 同一文件被来回修改（A→B→A）达到该次数视为振荡，默认 2
+	MaxOscillations int `json:"max_oscillations,omitempty" yaml:"max_oscillations,omitempty"`
+	// MaxStallSteps 连续无进展的步骤数（没有成功的新调用），默认 5
+	MaxStallSteps int `json:"max_stall_steps,omitempty" yaml:"max_stall_steps,omitempty"`
+	// Window 重复检测考察的最近工具调用数，默认 10
+	Window int `json:"window,omitempty" yaml:"window,omitempty"`
+	// Interventions 依次采用的介入方式，重复检测时逐级加重，取得进展后回到第一级；
+	// 默认 reflect → escalate → pause
+	Interventions []string `json:"interventions,omitempty" yaml:"interventions,omitempty"`
+}
+
 // ResumeStrategy 恢复策略
 type ResumeStrategy string
 
@@ -439,6 +763,12 @@ type CompleteResult struct {
 	Text          string    `json:"text,omitempty"`
 	Last          *Bookmark `json:"last,omitempty"`
 	PermissionIDs []string  `json:"permission_ids,omitempty"`
+	// TerminationReason 运行结束原因，达到运行限制时为 max_turns 等
+	TerminationReason TerminationReason `json:"termination_reason,omitempty"`
+	// Summary 因运行限制提前停止时的部分结果摘要
+	Summary string `json:"summary,omitempty"`
+	// Error 运行失败或达到限制时的结构化错误
+	Error *agenterr.Error `json:"error,omitempty"`
 }
 
 // ExecutionMode 执行模式
@@ -0,0 +1,242 @@
+package types
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/astercloud/aster/pkg/requestctx"
+)
+
+// EventSchemaVersion 当前事件封装的 Schema 版本
+//
+//	v1: {cursor, bookmark, event}，事件类型只能从字段推断，反序列化后为 map
+//	v2: 增加 version、type、channel，事件按类型解码为具体结构
+const EventSchemaVersion = 2
+
+// ErrUnsupportedSchemaVersion 消息版本高于当前实现支持的版本
+var ErrUnsupportedSchemaVersion = errors.New("unsupported schema version")
+
+var (
+	eventRegistryMu sync.RWMutex
+	eventRegistry   = make(map[string]func() EventType)
+)
+
+// eventUpgrades 按版本逐级升级事件字段，键为升级前的版本
+// 返回升级后的事件类型，修改 fields 时 changed 为 true
+var eventUpgrades = map[int]func(eventType string, fields map[string]any) (upgradedType string, changed bool){
+	1: upgradeEventV1,
+}
+
+func init() {
+	RegisterEventTypes(
+		func() EventType { return &ProgressThinkChunkStartEvent{} },
+		func() EventType { return &ProgressThinkChunkEvent{} },
+		func() EventType { return &ProgressReasoningEvent{} },
+		func() EventType { return &ProgressThinkChunkEndEvent{} },
+		func() EventType { return &ProgressTextChunkStartEvent{} },
+		func() EventType { return &ProgressTextChunkEvent{} },
+		func() EventType { return &ProgressTextChunkEndEvent{} },
+		func() EventType { return &ProgressToolStartEvent{} },
+		func() EventType { return &ProgressToolEndEvent{} },
+		func() EventType { return &ProgressToolProgressEvent{} },
+		func() EventType { return &ProgressToolIntermediateEvent{} },
+		func() EventType { return &ProgressToolCancelledEvent{} },
+		func() EventType { return &ProgressToolErrorEvent{} },
+		func() EventType { return &ProgressBackgroundTaskEvent{} },
+		func() EventType { return &ProgressDoneEvent{} },
+		func() EventType { return &ProgressSessionSummarizedEvent{} },
+		func() EventType { return &ProgressTodoUpdateEvent{} },
+		func() EventType { return &ProgressUISurfaceUpdateEvent{} },
+		func() EventType { return &ProgressUIDataUpdateEvent{} },
+		func() EventType { return &ProgressUIDeleteSurfaceEvent{} },
+		func() EventType { return &ControlPermissionRequiredEvent{} },
+		func() EventType { return &ControlPermissionDecidedEvent{} },
+		func() EventType { return &ControlWorkspaceTrustRequiredEvent{} },
+		func() EventType { return &ControlWorkspaceTrustDecidedEvent{} },
+		func() EventType { return &ControlIterationLimitEvent{} },
+		func() EventType { return &ControlLoopGuardPauseEvent{} },
+		func() EventType { return &ControlToolControlEvent{} },
+		func() EventType { return &ControlToolControlResponseEvent{} },
+		func() EventType { return &ControlModelSwitchEvent{} },
+		func() EventType { return &ControlModelSwitchResponseEvent{} },
+		func() EventType { return &ControlSteerEvent{} },
+		func() EventType { return &ControlSteerAppliedEvent{} },
+		func() EventType { return &ControlPlanStepDraftedEvent{} },
+		func() EventType { return &ControlPlanResolvedEvent{} },
+		func() EventType { return &ControlPlanUpdatedEvent{} },
+		func() EventType { return &ControlToolFilterUpdatedEvent{} },
+		func() EventType { return &ControlAskUserEvent{} },
+		func() EventType { return &ControlUserAnswerEvent{} },
+		func() EventType { return &ControlUIActionEvent{} },
+		func() EventType { return &MonitorStateChangedEvent{} },
+		func() EventType { return &MonitorStepCompleteEvent{} },
+		func() EventType { return &MonitorErrorEvent{} },
+		func() EventType { return &MonitorTokenUsageEvent{} },
+		func() EventType { return &MonitorSessionCostEvent{} },
+		func() EventType { return &MonitorLoopDetectedEvent{} },
+		func() EventType { return &MonitorSessionTitleEvent{} },
+		func() EventType { return &MonitorModelEscalatedEvent{} },
+		func() EventType { return &MonitorToolExecutedEvent{} },
+		func() EventType { return &MonitorAgentResumedEvent{} },
+		func() EventType { return &MonitorBreakpointChangedEvent{} },
+		func() EventType { return &MonitorFileChangedEvent{} },
+		func() EventType { return &MonitorArtifactCreatedEvent{} },
+		func() EventType { return &MonitorReminderSentEvent{} },
+		func() EventType { return &MonitorContextCompressionEvent{} },
+		func() EventType { return &MonitorSchedulerTriggeredEvent{} },
+		func() EventType { return &MonitorResourceEvent{} },
+		func() EventType { return &MonitorToolManualUpdatedEvent{} },
+		func() EventType { return &MonitorSafetyInterventionEvent{} },
+	)
+}
+
+// RegisterEventTypes 注册事件类型，反序列化封装时按 EventType() 解码为具体结构
+// 同名类型后注册的覆盖先注册的，扩展包可以用它注册自定义事件
+func RegisterEventTypes(factories ...func() EventType) {
+	eventRegistryMu.Lock()
+	defer eventRegistryMu.Unlock()
+	for _, factory := range factories {
+		eventRegistry[factory().EventType()] = factory
+	}
+}
+
+// RegisteredEventTypes 返回已注册的事件类型名
+func RegisteredEventTypes() []string {
+	eventRegistryMu.RLock()
+	defer eventRegistryMu.RUnlock()
+	names := make([]string, 0, len(eventRegistry))
+	for name := range eventRegistry {
+		names = append(names, name)
+	}
+	return names
+}
+
+// NewEvent 按类型名创建空事件，未注册时返回 false
+func NewEvent(eventType string) (EventType, bool) {
+	eventRegistryMu.RLock()
+	factory, ok := eventRegistry[eventType]
+	eventRegistryMu.RUnlock()
+	if !ok {
+		return nil, false
+	}
+	return factory(), true
+}
+
+// NewAgentEventEnvelope 创建当前版本的事件封装，类型和通道取自事件本身
+func NewAgentEventEnvelope(cursor int64, bookmark Bookmark, event any) AgentEventEnvelope {
+	env := AgentEventEnvelope{
+		Version:  EventSchemaVersion,
+		Cursor:   cursor,
+		Bookmark: bookmark,
+		Event:    event,
+	}
+	if ev, ok := event.(EventType); ok {
+		env.Type = ev.EventType()
+		env.Channel = ev.Channel()
+	}
+	return env
+}
+
+// MarshalJSON 始终按当前版本输出，补齐缺失的 version、type 和 channel
+func (e AgentEventEnvelope) MarshalJSON() ([]byte, error) {
+	type wire AgentEventEnvelope
+	w := wire(e)
+	w.Version = EventSchemaVersion
+	if ev, ok := e.Event.(EventType); ok {
+		w.Type = ev.EventType()
+		w.Channel = ev.Channel()
+	}
+	return json.Marshal(w)
+}
+
+// UnmarshalJSON 解码任意已知版本的封装并升级到当前版本
+// 已注册类型的事件解码为具体结构，其余保留为 map[string]any
+func (e *AgentEventEnvelope) UnmarshalJSON(data []byte) error {
+	var w struct {
+		Version  int                  `json:"version"`
+		Type     string               `json:"type"`
+		Channel  AgentChannel         `json:"channel"`
+		Cursor   int64                `json:"cursor"`
+		Bookmark Bookmark             `json:"bookmark"`
+		Event    json.RawMessage      `json:"event"`
+		Request  *requestctx.Metadata `json:"request"`
+	}
+	if err := json.Unmarshal(data, &w); err != nil {
+		return err
+	}
+	if w.Version > EventSchemaVersion {
+		return fmt.Errorf("%w: event envelope v%d, supported up to v%d", ErrUnsupportedSchemaVersion, w.Version, EventSchemaVersion)
+	}
+	if w.Version == 0 {
+		w.Version = 1
+	}
+
+	*e = AgentEventEnvelope{
+		Version:  EventSchemaVersion,
+		Type:     w.Type,
+		Channel:  w.Channel,
+		Cursor:   w.Cursor,
+		Bookmark: w.Bookmark,
+		Request:  w.Request,
+	}
+	if len(w.Event) == 0 || string(w.Event) == "null" {
+		return nil
+	}
+
+	raw := []byte(w.Event)
+	var fields map[string]any
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		// 非对象事件无法升级或按类型解码，原样保留
+		return json.Unmarshal(raw, &e.Event)
+	}
+	changed := false
+	for v := w.Version; v < EventSchemaVersion; v++ {
+		upgrade, ok := eventUpgrades[v]
+		if !ok {
+			continue
+		}
+		var c bool
+		e.Type, c = upgrade(e.Type, fields)
+		changed = changed || c
+	}
+	if changed {
+		var err error
+		if raw, err = json.Marshal(fields); err != nil {
+			return err
+		}
+	}
+
+	// 未注册或字段与当前结构不兼容时保留为 map，与 v1 的解码结果一致
+	event, ok := NewEvent(e.Type)
+	if !ok || json.Unmarshal(raw, event) != nil {
+		e.Event = fields
+		return nil
+	}
+	e.Event = event
+	if e.Channel == "" {
+		e.Channel = event.Channel()
+	}
+	return nil
+}
+
+// upgradeEventV1 v1 → v2
+// v1 封装没有 type 和 channel，部分发送方把它们写在事件字段里；
+// v1 的 ui:data_update 使用 A2UI 键值条目列表表示数据
+func upgradeEventV1(eventType string, fields map[string]any) (string, bool) {
+	if eventType == "" {
+		if t, ok := fields["type"].(string); ok {
+			if _, known := NewEvent(t); known {
+				eventType = t
+			}
+		}
+	}
+	if eventType == "ui:data_update" {
+		if contents, ok := upgradeDataEntries(fields["contents"]); ok {
+			fields["contents"] = contents
+			return eventType, true
+		}
+	}
+	return eventType, false
+}
@@ -0,0 +1,195 @@
+package types
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func readSchemaFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", "schema", name))
+	if err != nil {
+		t.Fatalf("read fixture: %v", err)
+	}
+	return data
+}
+
+// 每个注册的事件类型都能按当前版本往返编解码为同一具体类型
+func TestEventRegistryRoundTrip(t *testing.T) {
+	names := RegisteredEventTypes()
+	if len(names) < 40 {
+		t.Fatalf("expected built-in events to be registered, got %d", len(names))
+	}
+	for _, name := range names {
+		event, _ := NewEvent(name)
+		data, err := json.Marshal(NewAgentEventEnvelope(1, Bookmark{Cursor: 1}, event))
+		if err != nil {
+			t.Fatalf("%s: marshal: %v", name, err)
+		}
+		var env AgentEventEnvelope
+		if err := json.Unmarshal(data, &env); err != nil {
+			t.Fatalf("%s: unmarshal: %v", name, err)
+		}
+		if reflect.TypeOf(env.Event) != reflect.TypeOf(event) {
+			t.Errorf("%s: decoded %T, want %T", name, env.Event, event)
+		}
+		if env.Version != EventSchemaVersion || env.Type != name || env.Channel != event.Channel() {
+			t.Errorf("%s: envelope header = v%d %q %q", name, env.Version, env.Type, env.Channel)
+		}
+	}
+}
+
+func TestAgentEventEnvelopeMarshalCurrentVersion(t *testing.T) {
+	// 未通过 NewAgentEventEnvelope 构造的封装也按当前版本输出
+	data, err := json.Marshal(AgentEventEnvelope{Cursor: 3, Event: &ProgressTextChunkEvent{Step: 1, Delta: "hi"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var header map[string]any
+	if err := json.Unmarshal(data, &header); err != nil {
+		t.Fatal(err)
+	}
+	if header["version"] != float64(EventSchemaVersion) || header["type"] != "text_chunk" || header["channel"] != "progress" {
+		t.Errorf("header = %v", header)
+	}
+}
+
+func TestAgentEventEnvelopeConformance(t *testing.T) {
+	tests := []struct {
+		fixture string
+		cursor  int64
+		typ     string
+		channel AgentChannel
+		event   any
+	}{
+		{
+			// v1 没有类型信息，保持 map 形式，由调用方推断通道
+			fixture: "envelope_v1.json",
+			cursor:  7,
+			event:   map[string]any{"step": float64(1), "delta": "hello"},
+		},
+		{
+			fixture: "envelope_v1_typed.json",
+			cursor:  8,
+			typ:     "state_changed",
+			channel: ChannelMonitor,
+			event:   &MonitorStateChangedEvent{State: AgentStateWorking},
+		},
+		{
+			fixture: "envelope_v1_ui_data.json",
+			cursor:  9,
+			typ:     "ui:data_update",
+			channel: ChannelProgress,
+			event: &ProgressUIDataUpdateEvent{SurfaceID: "form", Path: "/", Contents: map[string]any{
+				"name":    "Alice",
+				"age":     float64(30),
+				"address": map[string]any{"city": "Hangzhou"},
+			}},
+		},
+		{
+			fixture: "envelope_v2.json",
+			cursor:  10,
+			typ:     "text_chunk",
+			channel: ChannelProgress,
+			event:   &ProgressTextChunkEvent{Step: 2, Delta: "world"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			var env AgentEventEnvelope
+			if err := json.Unmarshal(readSchemaFixture(t, tt.fixture), &env); err != nil {
+				t.Fatalf("unmarshal: %v", err)
+			}
+			if env.Version != EventSchemaVersion {
+				t.Errorf("Version = %d, want %d", env.Version, EventSchemaVersion)
+			}
+			if env.Cursor != tt.cursor || env.Bookmark.Cursor != tt.cursor {
+				t.Errorf("cursor = %d/%d, want %d", env.Cursor, env.Bookmark.Cursor, tt.cursor)
+			}
+			if env.Type != tt.typ || env.Channel != tt.channel {
+				t.Errorf("header = %q %q, want %q %q", env.Type, env.Channel, tt.typ, tt.channel)
+			}
+			if !reflect.DeepEqual(env.Event, tt.event) {
+				t.Errorf("Event = %#v, want %#v", env.Event, tt.event)
+			}
+
+			// 升级后的封装再次编解码保持不变
+			data, err := json.Marshal(env)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var again AgentEventEnvelope
+			if err := json.Unmarshal(data, &again); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(again, env) {
+				t.Errorf("round trip = %#v, want %#v", again, env)
+			}
+		})
+	}
+}
+
+func TestAgentEventEnvelopeRejectsNewerVersion(t *testing.T) {
+	var env AgentEventEnvelope
+	err := json.Unmarshal(readSchemaFixture(t, "envelope_v3.json"), &env)
+	if !errors.Is(err, ErrUnsupportedSchemaVersion) {
+		t.Fatalf("err = %v, want ErrUnsupportedSchemaVersion", err)
+	}
+}
+
+func TestDecodeClientMessageConformance(t *testing.T) {
+	want := &UserActionMessage{
+		Name:              "submit-form",
+		SurfaceID:         "form",
+		SourceComponentID: "submit",
+		Timestamp:         "2026-01-01T00:00:00Z",
+		Context:           map[string]any{"formId": "form-1", "userName": "Alice"},
+	}
+	for _, fixture := range []string{"client_action_v1.json", "client_action_v2.json"} {
+		t.Run(fixture, func(t *testing.T) {
+			msg, err := DecodeClientMessage(readSchemaFixture(t, fixture))
+			if err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if msg.Version != UIProtocolVersion {
+				t.Errorf("Version = %d, want %d", msg.Version, UIProtocolVersion)
+			}
+			if !reflect.DeepEqual(msg.UserAction, want) {
+				t.Errorf("UserAction = %#v, want %#v", msg.UserAction, want)
+			}
+		})
+	}
+
+	if _, err := DecodeClientMessage([]byte(`{"version":3}`)); !errors.Is(err, ErrUnsupportedSchemaVersion) {
+		t.Errorf("err = %v, want ErrUnsupportedSchemaVersion", err)
+	}
+}
+
+func TestDecodeUIMessageConformance(t *testing.T) {
+	msg, err := DecodeUIMessage(readSchemaFixture(t, "ui_data_update_v1.json"))
+	if err != nil {
+		t.Fatalf("decode v1: %v", err)
+	}
+	want := map[string]any{"name": "Alice", "subscribed": true}
+	if msg.Version != UIProtocolVersion || !reflect.DeepEqual(msg.DataModelUpdate.Contents, want) {
+		t.Errorf("v1 upgraded to v%d %#v, want %#v", msg.Version, msg.DataModelUpdate.Contents, want)
+	}
+
+	// v2 的数组内容是普通 JSON 值，不做转换
+	msg, err = DecodeUIMessage(readSchemaFixture(t, "ui_data_update_v2.json"))
+	if err != nil {
+		t.Fatalf("decode v2: %v", err)
+	}
+	if _, ok := msg.DataModelUpdate.Contents.([]any); !ok {
+		t.Errorf("v2 contents = %#v, want unchanged list", msg.DataModelUpdate.Contents)
+	}
+
+	if _, err := DecodeUIMessage([]byte(`{"version":3,"deleteSurface":{"surfaceId":"x"}}`)); !errors.Is(err, ErrUnsupportedSchemaVersion) {
+		t.Errorf("err = %v, want ErrUnsupportedSchemaVersion", err)
+	}
+}
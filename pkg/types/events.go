@@ -1,6 +1,12 @@
 package types
 
-import "time"
+import (
+	"fmt"
+	"time"
+
+	"github.com/astercloud/aster/pkg/agenterr"
+	"github.com/astercloud/aster/pkg/requestctx"
+)
 
 // AgentChannel 事件通道类型
 type AgentChannel string
@@ -17,11 +23,23 @@ type EventType interface {
 	EventType() string
 }
 
+// RunTraceID 一次运行的追踪 ID，由 Agent ID 和运行首个事件的 cursor 组成；
+// Dashboard 追踪与日志关联 ID 使用同一规则
+func RunTraceID(agentID string, cursor int64) string {
+	return fmt.Sprintf("%s-%d", agentID, cursor)
+}
+
 // AgentEventEnvelope 事件封装(带Bookmark)
+// JSON 编解码见 event_schema.go，旧版本封装在反序列化时自动升级
 type AgentEventEnvelope struct {
-	Cursor   int64    `json:"cursor"`
-	Bookmark Bookmark `json:"bookmark"`
-	Event    any      `json:"event"`
+	Version  int          `json:"version,omitempty"` // Schema 版本，缺省为 v1
+	Type     string       `json:"type,omitempty"`
+	Channel  AgentChannel `json:"channel,omitempty"`
+	Cursor   int64        `json:"cursor"`
+	Bookmark Bookmark     `json:"bookmark"`
+	Event    any          `json:"event"`
+	// Request 产生该事件的请求元数据（用户、租户、关联 ID），Agent 运行之外的事件为空
+	Request *requestctx.Metadata `json:"request,omitempty"`
 }
 
 // ===================
@@ -71,6 +89,16 @@ const (
 	ThinkingStageSummary       = "结果总结"
 )
 
+// ProgressReasoningEvent 模型原生推理增量事件
+// 来自 Anthropic extended thinking、OpenAI/DeepSeek reasoning 等，与 think_chunk 同时发送
+type ProgressReasoningEvent struct {
+	Step  int    `json:"step"`
+	Delta string `json:"delta"`
+}
+
+func (e *ProgressReasoningEvent) Channel() AgentChannel { return ChannelProgress }
+func (e *ProgressReasoningEvent) EventType() string     { return "reasoning" }
+
 // ProgressThinkChunkEndEvent 思考块结束事件
 type ProgressThinkChunkEndEvent struct {
 	Step int `json:"step"`
@@ -160,15 +188,50 @@ func (e *ProgressToolCancelledEvent) EventType() string     { return "tool:cance
 type ProgressToolErrorEvent struct {
 	Call  ToolCallSnapshot `json:"call"`
 	Error string           `json:"error"`
+	Code  agenterr.Code    `json:"code,omitempty"`
+	Class agenterr.Class   `json:"class,omitempty"`
 }
 
 func (e *ProgressToolErrorEvent) Channel() AgentChannel { return ChannelProgress }
 func (e *ProgressToolErrorEvent) EventType() string     { return "tool:error" }
 
+// ProgressBackgroundTaskEvent 后台工具任务状态变化事件（启动、进度、结束、结果送达）
+type ProgressBackgroundTaskEvent struct {
+	Task BackgroundTask `json:"task"`
+}
+
+func (e *ProgressBackgroundTaskEvent) Channel() AgentChannel { return ChannelProgress }
+func (e *ProgressBackgroundTaskEvent) EventType() string     { return "tool:background" }
+
+// TerminationReason 运行结束原因
+type TerminationReason string
+
+const (
+	TerminationCompleted       TerminationReason = "completed"
+	TerminationInterrupted     TerminationReason = "interrupted"
+	TerminationMaxTurns        TerminationReason = "max_turns"
+	TerminationMaxOutputTokens TerminationReason = "max_output_tokens"
+	TerminationMaxDuration     TerminationReason = "max_duration"
+	TerminationError           TerminationReason = "error"
+)
+
+// IsLimit 是否因运行限制（轮数、输出 Token、时长）而停止
+func (r TerminationReason) IsLimit() bool {
+	switch r {
+	case TerminationMaxTurns, TerminationMaxOutputTokens, TerminationMaxDuration:
+		return true
+	}
+	return false
+}
+
 // ProgressDoneEvent 单轮完成事件
 type ProgressDoneEvent struct {
-	Step   int    `json:"step"`
-	Reason string `json:"reason"` // "completed" or "interrupted"
+	Step   int               `json:"step"`
+	Reason TerminationReason `json:"reason"`
+	// Summary 因运行限制提前停止时的部分结果摘要
+	Summary string `json:"summary,omitempty"`
+	// Error 运行失败或达到限制时的结构化错误
+	Error *agenterr.Error `json:"error,omitempty"`
 }
 
 func (e *ProgressDoneEvent) Channel() AgentChannel { return ChannelProgress }
@@ -216,6 +279,25 @@ type ControlPermissionDecidedEvent struct {
 func (e *ControlPermissionDecidedEvent) Channel() AgentChannel { return ChannelControl }
 func (e *ControlPermissionDecidedEvent) EventType() string     { return "permission_decided" }
 
+// ControlWorkspaceTrustRequiredEvent 工作区信任请求事件
+// 在未受信任的工作区调用 Bash/网络类工具时发出，通过 Agent.RespondToWorkspaceTrust 响应
+type ControlWorkspaceTrustRequiredEvent struct {
+	WorkDir string           `json:"work_dir"`
+	Call    ToolCallSnapshot `json:"call"`
+}
+
+func (e *ControlWorkspaceTrustRequiredEvent) Channel() AgentChannel { return ChannelControl }
+func (e *ControlWorkspaceTrustRequiredEvent) EventType() string     { return "workspace_trust_required" }
+
+// ControlWorkspaceTrustDecidedEvent 工作区信任决策事件
+type ControlWorkspaceTrustDecidedEvent struct {
+	WorkDir string `json:"work_dir"`
+	Trusted bool   `json:"trusted"`
+}
+
+func (e *ControlWorkspaceTrustDecidedEvent) Channel() AgentChannel { return ChannelControl }
+func (e *ControlWorkspaceTrustDecidedEvent) EventType() string     { return "workspace_trust_decided" }
+
 // ControlIterationLimitEvent 迭代限制事件
 type ControlIterationLimitEvent struct {
 	CurrentIteration int    `json:"current_iteration"`
@@ -226,6 +308,17 @@ type ControlIterationLimitEvent struct {
 func (e *ControlIterationLimitEvent) Channel() AgentChannel { return ChannelControl }
 func (e *ControlIterationLimitEvent) EventType() string     { return "iteration_limit" }
 
+// ControlLoopGuardPauseEvent 循环保护暂停事件，通过 RespondToLoopGuard 决定是否继续
+type ControlLoopGuardPauseEvent struct {
+	Kind    string `json:"kind"` // repeated_call|oscillation|stall
+	Detail  string `json:"detail"`
+	Step    int    `json:"step"`
+	Message string `json:"message"`
+}
+
+func (e *ControlLoopGuardPauseEvent) Channel() AgentChannel { return ChannelControl }
+func (e *ControlLoopGuardPauseEvent) EventType() string     { return "loop_guard_pause" }
+
 // ControlToolControlEvent 工具控制指令事件（入站）
 type ControlToolControlEvent struct {
 	CallID string `json:"call_id"`
@@ -247,6 +340,98 @@ type ControlToolControlResponseEvent struct {
 func (e *ControlToolControlResponseEvent) Channel() AgentChannel { return ChannelControl }
 func (e *ControlToolControlResponseEvent) EventType() string     { return "tool_control_response" }
 
+// ControlModelSwitchEvent 模型切换指令事件（入站）
+type ControlModelSwitchEvent struct {
+	Model string `json:"model"` // 逻辑别名或 provider/model
+	Note  string `json:"note,omitempty"`
+}
+
+func (e *ControlModelSwitchEvent) Channel() AgentChannel { return ChannelControl }
+func (e *ControlModelSwitchEvent) EventType() string     { return "model_switch" }
+
+// ControlModelSwitchResponseEvent 模型切换响应事件（出站）
+type ControlModelSwitchResponseEvent struct {
+	Requested string `json:"requested"`
+	From      string `json:"from,omitempty"` // provider/model
+	To        string `json:"to,omitempty"`   // provider/model
+	OK        bool   `json:"ok"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+func (e *ControlModelSwitchResponseEvent) Channel() AgentChannel { return ChannelControl }
+func (e *ControlModelSwitchResponseEvent) EventType() string     { return "model_switch_response" }
+
+// ControlSteerEvent 引导消息事件：Agent 执行中途收到用户的新指令
+type ControlSteerEvent struct {
+	Message     string `json:"message"`
+	Mode        string `json:"mode"`                  // finish, abort
+	Interrupted string `json:"interrupted,omitempty"` // model, tool
+	CallID      string `json:"call_id,omitempty"`     // 被取消的工具调用
+}
+
+func (e *ControlSteerEvent) Channel() AgentChannel { return ChannelControl }
+func (e *ControlSteerEvent) EventType() string     { return "steer" }
+
+// ControlSteerAppliedEvent 引导消息已注入对话，Agent 继续执行
+type ControlSteerAppliedEvent struct {
+	Messages     int      `json:"messages"`
+	Phase        string   `json:"phase"` // model, tools, turn_end
+	SkippedTools []string `json:"skipped_tools,omitempty"`
+}
+
+func (e *ControlSteerAppliedEvent) Channel() AgentChannel { return ChannelControl }
+func (e *ControlSteerAppliedEvent) EventType() string     { return "steer_applied" }
+
+// ControlPlanStepDraftedEvent 计划模式下工具调用未执行，记入待审批的计划
+type ControlPlanStepDraftedEvent struct {
+	PlanID      string         `json:"plan_id"`
+	StepID      string         `json:"step_id"`
+	Index       int            `json:"index"`
+	CallID      string         `json:"call_id"`
+	ToolName    string         `json:"tool_name"`
+	Description string         `json:"description"`
+	Arguments   map[string]any `json:"arguments,omitempty"`
+}
+
+func (e *ControlPlanStepDraftedEvent) Channel() AgentChannel { return ChannelControl }
+func (e *ControlPlanStepDraftedEvent) EventType() string     { return "plan_step_drafted" }
+
+// ControlPlanResolvedEvent 计划模式累积的计划被审批执行或拒绝
+type ControlPlanResolvedEvent struct {
+	PlanID    string `json:"plan_id"`
+	Approved  bool   `json:"approved"`
+	Status    string `json:"status"`
+	Completed int    `json:"completed,omitempty"`
+	Failed    int    `json:"failed,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+func (e *ControlPlanResolvedEvent) Channel() AgentChannel { return ChannelControl }
+func (e *ControlPlanResolvedEvent) EventType() string     { return "plan_resolved" }
+
+// ControlPlanUpdatedEvent 规划-执行模式下规划模型生成或更新了执行计划，或计划执行结束
+type ControlPlanUpdatedEvent struct {
+	PlanID string `json:"plan_id"`
+	// Revision 计划版本，首次生成为 1，每次重新规划加 1
+	Revision int    `json:"revision"`
+	Status   string `json:"status"`
+	Steps    int    `json:"steps"`
+	// Reason 重新规划的原因，如连续失败的步骤及错误
+	Reason string `json:"reason,omitempty"`
+}
+
+func (e *ControlPlanUpdatedEvent) Channel() AgentChannel { return ChannelControl }
+func (e *ControlPlanUpdatedEvent) EventType() string     { return "plan_updated" }
+
+// ControlToolFilterUpdatedEvent 运行时修改了工具白名单或黑名单
+type ControlToolFilterUpdatedEvent struct {
+	AllowedTools    []string `json:"allowed_tools,omitempty"`
+	DisallowedTools []string `json:"disallowed_tools,omitempty"`
+}
+
+func (e *ControlToolFilterUpdatedEvent) Channel() AgentChannel { return ChannelControl }
+func (e *ControlToolFilterUpdatedEvent) EventType() string     { return "tool_filter_updated" }
+
 // ===================
 // Monitor Channel Events
 // ===================
@@ -270,10 +455,13 @@ func (e *MonitorStepCompleteEvent) EventType() string     { return "step_complet
 
 // MonitorErrorEvent 错误事件
 type MonitorErrorEvent struct {
-	Severity string         `json:"severity"` // "info", "warn", "error"
-	Phase    string         `json:"phase"`    // "model", "tool", "system", "lifecycle"
-	Message  string         `json:"message"`
-	Detail   map[string]any `json:"detail,omitempty"`
+	Severity  string         `json:"severity"` // "info", "warn", "error"
+	Phase     string         `json:"phase"`    // "model", "tool", "system", "lifecycle"
+	Message   string         `json:"message"`
+	Detail    map[string]any `json:"detail,omitempty"`
+	Code      agenterr.Code  `json:"code,omitempty"`
+	Class     agenterr.Class `json:"class,omitempty"`
+	Retryable bool           `json:"retryable,omitempty"`
 }
 
 func (e *MonitorErrorEvent) Channel() AgentChannel { return ChannelMonitor }
@@ -289,6 +477,86 @@ type MonitorTokenUsageEvent struct {
 func (e *MonitorTokenUsageEvent) Channel() AgentChannel { return ChannelMonitor }
 func (e *MonitorTokenUsageEvent) EventType() string     { return "token_usage" }
 
+// 成本 HUD 告警级别
+const (
+	CostLevelOK       = "ok"
+	CostLevelWarning  = "warning"
+	CostLevelCritical = "critical"
+)
+
+// MonitorSessionCostEvent 会话成本 HUD：累计 Token、费用和上下文占用，告警阈值来自 BudgetConfig
+type MonitorSessionCostEvent struct {
+	InputTokens  int64   `json:"input_tokens"`
+	OutputTokens int64   `json:"output_tokens"`
+	TotalTokens  int64   `json:"total_tokens"`
+	Cost         float64 `json:"cost"`
+	Currency     string  `json:"currency"`
+
+	ContextPercent float64 `json:"context_percent"` // 上下文窗口占用百分比（估算）
+	ContextUsed    int     `json:"context_used"`
+	ContextWindow  int     `json:"context_window"`
+
+	BudgetPercent float64 `json:"budget_percent,omitempty"` // 费用或 Token 预算的最大使用百分比，未设预算时为 0
+	Level         string  `json:"level"`                    // 综合告警级别：ok / warning / critical
+	CostLevel     string  `json:"cost_level"`
+	ContextLevel  string  `json:"context_level"`
+}
+
+func (e *MonitorSessionCostEvent) Channel() AgentChannel { return ChannelMonitor }
+func (e *MonitorSessionCostEvent) EventType() string     { return "session_cost" }
+
+// 模型升级原因
+const (
+	EscalationReasonToolFailures  = "tool_failures"
+	EscalationReasonLowConfidence = "low_confidence"
+	EscalationReasonUserRetry     = "user_retry"
+	EscalationReasonLoopDetected  = "loop_detected"
+)
+
+// 循环检测类型
+const (
+	LoopKindRepeatedCall = "repeated_call"
+	LoopKindOscillation  = "oscillation"
+	LoopKindStall        = "stall"
+)
+
+// MonitorLoopDetectedEvent 循环检测事件
+type MonitorLoopDetectedEvent struct {
+	Kind         string `json:"kind"`
+	Detail       string `json:"detail"`
+	Step         int    `json:"step"`
+	Intervention string `json:"intervention"` // 实际采用的介入方式
+}
+
+func (e *MonitorLoopDetectedEvent) Channel() AgentChannel { return ChannelMonitor }
+func (e *MonitorLoopDetectedEvent) EventType() string     { return "loop_detected" }
+
+// MonitorSessionTitleEvent 会话标题生成事件
+type MonitorSessionTitleEvent struct {
+	Title string `json:"title"`
+}
+
+func (e *MonitorSessionTitleEvent) Channel() AgentChannel { return ChannelMonitor }
+func (e *MonitorSessionTitleEvent) EventType() string     { return "session_title" }
+
+// MonitorModelEscalatedEvent 模型升级事件
+type MonitorModelEscalatedEvent struct {
+	From   string `json:"from"` // provider/model
+	To     string `json:"to"`   // provider/model
+	Reason string `json:"reason"`
+	Level  int    `json:"level"` // 升级后所在阶梯级别（0 为起点）
+	Detail string `json:"detail,omitempty"`
+
+	// 成本差异（按每百万 Token 单价计算；Estimated 按最近一次调用的 Token 用量估算）
+	InputPriceDeltaPerM  float64 `json:"input_price_delta_per_m"`
+	OutputPriceDeltaPerM float64 `json:"output_price_delta_per_m"`
+	EstimatedCostDelta   float64 `json:"estimated_cost_delta"`
+	Currency             string  `json:"currency"`
+}
+
+func (e *MonitorModelEscalatedEvent) Channel() AgentChannel { return ChannelMonitor }
+func (e *MonitorModelEscalatedEvent) EventType() string     { return "model_escalated" }
+
 // MonitorToolExecutedEvent 工具执行完成事件
 type MonitorToolExecutedEvent struct {
 	Call ToolCallSnapshot `json:"call"`
@@ -318,13 +586,28 @@ func (e *MonitorBreakpointChangedEvent) EventType() string     { return "breakpo
 
 // MonitorFileChangedEvent 文件变更事件
 type MonitorFileChangedEvent struct {
-	Path  string    `json:"path"`
-	Mtime time.Time `json:"mtime"`
+	Path    string    `json:"path"`
+	Op      string    `json:"op,omitempty"`       // create/write/remove/rename
+	WatchID string    `json:"watch_id,omitempty"` // 触发事件的 Watch 订阅
+	Mtime   time.Time `json:"mtime"`
 }
 
 func (e *MonitorFileChangedEvent) Channel() AgentChannel { return ChannelMonitor }
 func (e *MonitorFileChangedEvent) EventType() string     { return "file_changed" }
 
+// MonitorArtifactCreatedEvent 产出物创建事件，内容通过 ArtifactID 获取
+type MonitorArtifactCreatedEvent struct {
+	ArtifactID string `json:"artifact_id"`
+	Name       string `json:"name"`
+	Kind       string `json:"kind"`
+	MIMEType   string `json:"mime_type"`
+	Size       int64  `json:"size"`
+	Source     string `json:"source,omitempty"` // 产生该产出物的工具
+}
+
+func (e *MonitorArtifactCreatedEvent) Channel() AgentChannel { return ChannelMonitor }
+func (e *MonitorArtifactCreatedEvent) EventType() string     { return "artifact_created" }
+
 // MonitorReminderSentEvent 系统提醒事件
 type MonitorReminderSentEvent struct {
 	Category string `json:"category"` // "file", "todo", "security", "performance", "general"
@@ -355,6 +638,30 @@ type MonitorSchedulerTriggeredEvent struct {
 func (e *MonitorSchedulerTriggeredEvent) Channel() AgentChannel { return ChannelMonitor }
 func (e *MonitorSchedulerTriggeredEvent) EventType() string     { return "scheduler_triggered" }
 
+// 资源事件类型
+const (
+	ResourceEventUsage         = "usage"
+	ResourceEventLimitExceeded = "limit_exceeded"
+)
+
+// MonitorResourceEvent 沙箱命令资源使用事件
+// Kind 为 usage 时报告命令结束后的资源用量，为 limit_exceeded 时表示命令超出限制已被终止
+type MonitorResourceEvent struct {
+	Kind       string `json:"kind"`
+	Command    string `json:"command"`
+	PID        int    `json:"pid,omitempty"`
+	Resource   string `json:"resource,omitempty"` // memory/processes/cpu_time，仅 limit_exceeded
+	Limit      int64  `json:"limit,omitempty"`
+	Value      int64  `json:"value,omitempty"`
+	CPUTimeMs  int64  `json:"cpu_time_ms"`
+	PeakMemory int64  `json:"peak_memory"` // 字节
+	PeakProcs  int    `json:"peak_procs"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+func (e *MonitorResourceEvent) Channel() AgentChannel { return ChannelMonitor }
+func (e *MonitorResourceEvent) EventType() string     { return "resource" }
+
 // MonitorToolManualUpdatedEvent 工具手册更新事件
 type MonitorToolManualUpdatedEvent struct {
 	Tools     []string  `json:"tools"`
@@ -364,6 +671,28 @@ type MonitorToolManualUpdatedEvent struct {
 func (e *MonitorToolManualUpdatedEvent) Channel() AgentChannel { return ChannelMonitor }
 func (e *MonitorToolManualUpdatedEvent) EventType() string     { return "tool_manual_updated" }
 
+// 安全策略检查阶段
+const (
+	SafetyStagePreSend    = "pre_send"
+	SafetyStagePreDisplay = "pre_display"
+)
+
+// MonitorSafetyInterventionEvent 内容安全策略介入事件
+type MonitorSafetyInterventionEvent struct {
+	Stage  string       `json:"stage"`  // "pre_send", "pre_display"
+	Action SafetyAction `json:"action"` // "block", "redact"
+	// Source 命中的规则名或分类器名
+	Source  string `json:"source"`
+	Trigger string `json:"trigger,omitempty"`
+	Message string `json:"message,omitempty"`
+	// Matches 正则规则命中的次数
+	Matches int `json:"matches,omitempty"`
+	Step    int `json:"step,omitempty"`
+}
+
+func (e *MonitorSafetyInterventionEvent) Channel() AgentChannel { return ChannelMonitor }
+func (e *MonitorSafetyInterventionEvent) EventType() string     { return "safety_intervention" }
+
 // ===================
 // AskUserQuestion Events (Control Channel)
 // ===================
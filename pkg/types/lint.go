@@ -0,0 +1,51 @@
+package types
+
+import "fmt"
+
+// LintSeverity 检查问题的级别
+type LintSeverity string
+
+const (
+	LintError   LintSeverity = "error"
+	LintWarning LintSeverity = "warning"
+	LintInfo    LintSeverity = "info"
+)
+
+// LintIssue 模板或 Recipe 检查发现的问题
+type LintIssue struct {
+	Severity LintSeverity `json:"severity"`
+	// Code 问题类别，例如 unknown_tool、unreachable_module
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (i LintIssue) String() string {
+	return fmt.Sprintf("%s [%s] %s", i.Severity, i.Code, i.Message)
+}
+
+// LintReport 一个模板或 Recipe 的检查结果
+type LintReport struct {
+	// Name 模板 ID 或 Recipe 标题
+	Name   string      `json:"name"`
+	Issues []LintIssue `json:"issues"`
+	// PromptTokens 基础 System Prompt 的估算 token 数
+	PromptTokens int `json:"prompt_tokens"`
+	// ToolTokens 工具定义的估算 token 数
+	ToolTokens int `json:"tool_tokens,omitempty"`
+}
+
+// Add 记录一个问题
+func (r *LintReport) Add(severity LintSeverity, code, format string, args ...any) {
+	r.Issues = append(r.Issues, LintIssue{Severity: severity, Code: code, Message: fmt.Sprintf(format, args...)})
+}
+
+// Count 返回指定级别的问题数
+func (r *LintReport) Count(severity LintSeverity) int {
+	n := 0
+	for _, issue := range r.Issues {
+		if issue.Severity == severity {
+			n++
+		}
+	}
+	return n
+}
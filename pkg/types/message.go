@@ -3,6 +3,8 @@ package types
 import (
 	"encoding/json"
 	"time"
+
+	"github.com/astercloud/aster/pkg/agenterr"
 )
 
 // Role 定义消息角色
@@ -153,6 +155,17 @@ type ToolResultReference struct {
 
 func (t *ToolResultBlock) IsContentBlock() {}
 
+// ThinkingBlock 推理/思考内容块
+// Anthropic extended thinking 在工具调用循环中需要原样回传带签名的思考块
+type ThinkingBlock struct {
+	Thinking  string `json:"thinking"`
+	Signature string `json:"signature,omitempty"`
+	// Redacted 存储时内容已被清除，不能再回传给模型
+	Redacted bool `json:"redacted,omitempty"`
+}
+
+func (t *ThinkingBlock) IsContentBlock() {}
+
 // Message 表示一条消息
 type Message struct {
 	// Role 消息角色
@@ -286,6 +299,9 @@ type ToolCallSnapshot struct {
 	// Error 错误信息
 	Error string `json:"error,omitempty"`
 
+	// ErrorCode 失败时的结构化错误码
+	ErrorCode agenterr.Code `json:"error_code,omitempty"`
+
 	// Intermediate 中间结果
 	Intermediate map[string]any `json:"intermediate,omitempty"`
 
@@ -389,6 +405,9 @@ type contentBlockJSON struct {
 	ToolUseID string         `json:"tool_use_id,omitempty"`
 	Content   string         `json:"content,omitempty"`
 	IsError   bool           `json:"is_error,omitempty"`
+	Thinking  string         `json:"thinking,omitempty"`
+	Signature string         `json:"signature,omitempty"`
+	Redacted  bool           `json:"redacted,omitempty"`
 }
 
 // messageJSON 用于 JSON 序列化的消息结构
@@ -437,6 +456,13 @@ func (m Message) MarshalJSON() ([]byte, error) {
 					Content:   b.Content,
 					IsError:   b.IsError,
 				})
+			case *ThinkingBlock:
+				msg.ContentBlocks = append(msg.ContentBlocks, contentBlockJSON{
+					Type:      "thinking",
+					Thinking:  b.Thinking,
+					Signature: b.Signature,
+					Redacted:  b.Redacted,
+				})
 			}
 		}
 	}
@@ -477,6 +503,12 @@ func (m *Message) UnmarshalJSON(data []byte) error {
 					Content:   b.Content,
 					IsError:   b.IsError,
 				})
+			case "thinking":
+				m.ContentBlocks = append(m.ContentBlocks, &ThinkingBlock{
+					Thinking:  b.Thinking,
+					Signature: b.Signature,
+					Redacted:  b.Redacted,
+				})
 			}
 		}
 	}
@@ -224,6 +224,37 @@ func TestMessage_JSONSerialization(t *testing.T) {
 	}
 }
 
+func TestMessage_JSONSerialization_ThinkingBlock(t *testing.T) {
+	original := Message{
+		Role: RoleAssistant,
+		ContentBlocks: []ContentBlock{
+			&ThinkingBlock{Thinking: "let me think", Signature: "sig"},
+			&ThinkingBlock{Redacted: true},
+			&TextBlock{Text: "answer"},
+		},
+	}
+
+	data, err := json.Marshal(original)
+	if err != nil {
+		t.Fatalf("Failed to marshal: %v", err)
+	}
+	var restored Message
+	if err := json.Unmarshal(data, &restored); err != nil {
+		t.Fatalf("Failed to unmarshal: %v", err)
+	}
+
+	if len(restored.ContentBlocks) != 3 {
+		t.Fatalf("ContentBlocks length mismatch: %d", len(restored.ContentBlocks))
+	}
+	tb, ok := restored.ContentBlocks[0].(*ThinkingBlock)
+	if !ok || tb.Thinking != "let me think" || tb.Signature != "sig" {
+		t.Errorf("ThinkingBlock mismatch: %+v", restored.ContentBlocks[0])
+	}
+	if rb, ok := restored.ContentBlocks[1].(*ThinkingBlock); !ok || !rb.Redacted {
+		t.Errorf("redacted ThinkingBlock mismatch: %+v", restored.ContentBlocks[1])
+	}
+}
+
 func TestMessage_JSONSerialization_NoMetadata(t *testing.T) {
 	// 测试没有 Metadata 的消息序列化（向后兼容）
 	original := Message{
@@ -23,6 +23,11 @@ type SandboxSettings struct {
 	// 这些请求会回退到权限系统进行审批
 	AllowUnsandboxedCommands bool `json:"allow_unsandboxed_commands,omitempty"`
 
+	// LearnCommands 命令白名单学习模式
+	// 记录用户批准过的命令前缀（如 npm test、go build）到项目本地的 .aster/allowed-commands.yaml，
+	// 之后匹配前缀的命令自动批准，新命令仍需审批
+	LearnCommands bool `json:"learn_commands,omitempty"`
+
 	// Network 网络沙箱配置
 	Network *NetworkSandboxSettings `json:"network,omitempty"`
 
@@ -30,11 +35,34 @@ type SandboxSettings struct {
 	IgnoreViolations *SandboxIgnoreViolations `json:"ignore_violations,omitempty"`
 
 	// EnableWeakerNestedSandbox 启用较弱的嵌套沙箱（兼容性）
+	// OS 级隔离在容器内运行时不创建 PID 命名空间
 	EnableWeakerNestedSandbox bool `json:"enable_weaker_nested_sandbox,omitempty"`
+
+	// OSIsolation 操作系统级隔离模式，Enabled 为 true 时生效，默认 auto
+	// macOS 使用 sandbox-exec（seatbelt），Linux 使用 bubblewrap，
+	// 由内核强制文件写入范围（工作目录、允许路径、临时目录）和网络策略
+	OSIsolation OSIsolationMode `json:"os_isolation,omitempty"`
 }
 
+// OSIsolationMode 操作系统级隔离模式
+type OSIsolationMode string
+
+const (
+	// OSIsolationAuto 可用时启用，不可用时降级为命令过滤
+	OSIsolationAuto OSIsolationMode = "auto"
+
+	// OSIsolationRequired 必须启用，不可用时创建沙箱失败
+	OSIsolationRequired OSIsolationMode = "required"
+
+	// OSIsolationOff 不使用操作系统级隔离
+	OSIsolationOff OSIsolationMode = "off"
+)
+
 // NetworkSandboxSettings 网络沙箱配置
 type NetworkSandboxSettings struct {
+	// Disabled 禁止命令访问网络，OS 级隔离启用时由内核强制
+	Disabled bool `json:"disabled,omitempty"`
+
 	// AllowLocalBinding 允许进程绑定本地端口（如开发服务器）
 	AllowLocalBinding bool `json:"allow_local_binding,omitempty"`
 
@@ -52,6 +80,7 @@ type NetworkSandboxSettings struct {
 	SOCKSProxyPort int `json:"socks_proxy_port,omitempty"`
 
 	// AllowedHosts 允许访问的主机列表
+	// OS 级隔离无法按主机过滤，配置后隔离中禁止直连网络，只放行本地代理端口
 	AllowedHosts []string `json:"allowed_hosts,omitempty"`
 
 	// BlockedHosts 禁止访问的主机列表
@@ -159,7 +188,7 @@ const (
 	// SandboxPermissionAcceptEdits 自动接受文件编辑
 	SandboxPermissionAcceptEdits SandboxPermissionMode = "acceptEdits"
 
-	// SandboxPermissionBypass 绕过所有权限检查
+	// SandboxPermissionBypass 跳过审批，命令分析和绕过模式底线规则仍然生效
 	SandboxPermissionBypass SandboxPermissionMode = "bypassPermissions"
 
 	// SandboxPermissionPlan 规划模式 - 不执行
@@ -1,5 +1,10 @@
 package types
 
+import (
+	"strings"
+	"time"
+)
+
 // SessionState 会话状态信息
 type SessionState struct {
 	// HasHistory 是否有历史消息
@@ -51,3 +56,48 @@ type RecoveryResult struct {
 // RecoveryHook 恢复钩子函数类型
 // 应用层实现这个函数来定义自己的恢复逻辑
 type RecoveryHook func(ctx *RecoveryContext) *RecoveryResult
+
+// ConversationSummary 会话的结构化摘要，可作为恢复或分叉会话的起点
+type ConversationSummary struct {
+	// Overview 一段话概述
+	Overview string `json:"overview"`
+	// Goals 用户目标
+	Goals []string `json:"goals"`
+	// Decisions 已做出的决定
+	Decisions []string `json:"decisions"`
+	// FilesTouched 读取或修改过的文件
+	FilesTouched []string `json:"files_touched"`
+	// OpenTODOs 尚未完成的事项
+	OpenTODOs []string `json:"open_todos"`
+	// MessageCount 参与摘要的消息数
+	MessageCount int `json:"message_count"`
+	// Model 生成摘要的模型（provider/model）
+	Model string `json:"model,omitempty"`
+	// GeneratedAt 生成时间
+	GeneratedAt time.Time `json:"generated_at"`
+}
+
+// SeedPrompt 渲染为注入新会话 System Prompt 的文本
+func (s *ConversationSummary) SeedPrompt() string {
+	var sb strings.Builder
+	sb.WriteString("<conversation-summary>\n")
+	sb.WriteString("This session continues earlier work. Summary of the previous conversation:\n")
+	if s.Overview != "" {
+		sb.WriteString("\n" + s.Overview + "\n")
+	}
+	writeList := func(title string, items []string) {
+		if len(items) == 0 {
+			return
+		}
+		sb.WriteString("\n" + title + ":\n")
+		for _, item := range items {
+			sb.WriteString("- " + item + "\n")
+		}
+	}
+	writeList("Goals", s.Goals)
+	writeList("Decisions", s.Decisions)
+	writeList("Files touched", s.FilesTouched)
+	writeList("Open TODOs", s.OpenTODOs)
+	sb.WriteString("</conversation-summary>")
+	return sb.String()
+}
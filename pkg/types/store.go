@@ -2,6 +2,8 @@ package types
 
 import (
 	"time"
+
+	"github.com/astercloud/aster/pkg/agenterr"
 )
 
 // ToolCallRecord 工具调用记录
@@ -13,6 +15,7 @@ type ToolCallRecord struct {
 	Output       any                  `json:"output"`                 // 输出结果
 	Result       any                  `json:"result"`                 // 执行结果（新字段）
 	Error        string               `json:"error,omitempty"`        // 错误信息
+	ErrorCode    agenterr.Code        `json:"error_code,omitempty"`   // 结构化错误码
 	IsError      bool                 `json:"is_error"`               // 是否有错误（新字段）
 	Progress     float64              `json:"progress"`               // 执行进度 0-1
 	Intermediate map[string]any       `json:"intermediate,omitempty"` // 中间结果
@@ -40,6 +43,27 @@ const (
 	ToolCallStatusCancelled ToolCallStatus = "canceled"  // 已取消
 )
 
+// BackgroundTask 后台运行的工具任务，持久化以便进程重启后恢复并送达结果
+type BackgroundTask struct {
+	ID        string         `json:"id"`                 // 工具返回的任务 ID
+	AgentID   string         `json:"agent_id"`           // 所属 Agent
+	ToolUseID string         `json:"tool_use_id"`        // 发起任务的工具调用 ID
+	ToolName  string         `json:"tool_name"`          // 工具名称
+	Input     map[string]any `json:"input,omitempty"`    // 调用参数（恢复任务时使用）
+	Status    ToolCallStatus `json:"status"`             // running/completed/failed/canceled
+	Progress  float64        `json:"progress"`           // 执行进度 0-1
+	Result    any            `json:"result,omitempty"`   // 执行结果
+	Error     string         `json:"error,omitempty"`    // 错误信息
+	Delivered bool           `json:"delivered"`          // 结果是否已送达模型
+	StartedAt time.Time      `json:"started_at"`         // 开始时间
+	EndedAt   *time.Time     `json:"ended_at,omitempty"` // 结束时间
+}
+
+// Done 任务是否已结束
+func (t *BackgroundTask) Done() bool {
+	return t.Status != ToolCallStatusRunning && t.Status != ToolCallStatusPending
+}
+
 // Snapshot Agent 状态快照
 type Snapshot struct {
 	ID          string           `json:"id"`                    // 快照 ID
@@ -68,13 +92,20 @@ const (
 
 // AgentStatus Agent 实时状态
 type AgentStatus struct {
-	AgentID      string            `json:"agent_id"`       // Agent ID
-	State        AgentRuntimeState `json:"state"`          // 运行时状态
-	StepCount    int               `json:"step_count"`     // 步骤数
-	LastSfpIndex int               `json:"last_sfp_index"` // 最后 SFP 索引
-	LastBookmark *Bookmark         `json:"last_bookmark"`  // 最后书签
-	Cursor       int64             `json:"cursor"`         // 游标
-	Breakpoint   BreakpointState   `json:"breakpoint"`     // 断点状态
+	AgentID      string            `json:"agent_id"`        // Agent ID
+	State        AgentRuntimeState `json:"state"`           // 运行时状态
+	StepCount    int               `json:"step_count"`      // 步骤数
+	LastSfpIndex int               `json:"last_sfp_index"`  // 最后 SFP 索引
+	LastBookmark *Bookmark         `json:"last_bookmark"`   // 最后书签
+	Cursor       int64             `json:"cursor"`          // 游标
+	Breakpoint   BreakpointState   `json:"breakpoint"`      // 断点状态
+	Model        string            `json:"model,omitempty"` // 当前主模型 (provider/model)
+	Title        string            `json:"title,omitempty"` // 会话标题（AutoTitle）
+
+	// 上下文窗口使用情况（token，估算值）
+	ContextWindow    int `json:"context_window,omitempty"`
+	ContextUsed      int `json:"context_used,omitempty"`
+	ContextRemaining int `json:"context_remaining"`
 }
 
 // AgentInfo Agent 元信息
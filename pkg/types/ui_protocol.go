@@ -38,6 +38,8 @@ const (
 // AsterUIMessage Aster UI 协议主消息结构
 // 支持五种操作类型，每次消息只包含一种操作
 type AsterUIMessage struct {
+	// Version 协议版本，缺省为 v1，见 DecodeUIMessage
+	Version int `json:"version,omitempty"`
 	// CreateSurface 创建 Surface 消息
 	CreateSurface *CreateSurfaceMessage `json:"createSurface,omitempty"`
 	// SurfaceUpdate Surface 更新消息
@@ -664,6 +666,8 @@ type UIActionEvent struct {
 
 // ClientMessage 客户端到服务端消息
 type ClientMessage struct {
+	// Version 协议版本，缺省为 v1，见 DecodeClientMessage
+	Version int `json:"version,omitempty"`
 	// UserAction 用户动作消息
 	UserAction *UserActionMessage `json:"userAction,omitempty"`
 	// Error 错误消息
@@ -710,6 +714,14 @@ type ProtocolError struct {
 	Details map[string]any `json:"details,omitempty"`
 }
 
+// Error 实现 error 接口
+func (e *ProtocolError) Error() string {
+	if e.Path != "" {
+		return e.Code + ": " + e.Message + " (surface " + e.SurfaceID + ", path " + e.Path + ")"
+	}
+	return e.Code + ": " + e.Message + " (surface " + e.SurfaceID + ")"
+}
+
 // IsValidationError 判断是否为验证错误
 func (e *ProtocolError) IsValidationError() bool {
 	return e.Code == string(ValidationErrorCodeValidationFailed)
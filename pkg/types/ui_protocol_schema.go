@@ -0,0 +1,109 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// UIProtocolVersion 当前 UI 协议消息版本
+//
+//	v1: dataModelUpdate.contents 可为 A2UI 键值条目列表 [{key, valueString...}]；
+//	    客户端直接发送 UIActionEvent {surfaceId, componentId, action, payload}
+//	v2: contents 为普通 JSON 值；客户端动作包装在 userAction 中
+const UIProtocolVersion = 2
+
+// DecodeUIMessage 解码任意已知版本的服务端 UI 消息并升级到当前版本
+func DecodeUIMessage(data []byte) (*AsterUIMessage, error) {
+	var msg AsterUIMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return nil, err
+	}
+	if msg.Version > UIProtocolVersion {
+		return nil, fmt.Errorf("%w: ui message v%d, supported up to v%d", ErrUnsupportedSchemaVersion, msg.Version, UIProtocolVersion)
+	}
+	if msg.Version < 2 && msg.DataModelUpdate != nil {
+		if contents, ok := upgradeDataEntries(msg.DataModelUpdate.Contents); ok {
+			msg.DataModelUpdate.Contents = contents
+		}
+	}
+	msg.Version = UIProtocolVersion
+	return &msg, nil
+}
+
+// DecodeClientMessage 解码任意已知版本的客户端 UI 消息并升级到当前版本
+func DecodeClientMessage(data []byte) (*ClientMessage, error) {
+	var msg ClientMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return nil, err
+	}
+	if msg.Version > UIProtocolVersion {
+		return nil, fmt.Errorf("%w: client message v%d, supported up to v%d", ErrUnsupportedSchemaVersion, msg.Version, UIProtocolVersion)
+	}
+	if msg.Version < 2 && msg.UserAction == nil && msg.Error == nil {
+		var legacy UIActionEvent
+		if err := json.Unmarshal(data, &legacy); err != nil {
+			return nil, err
+		}
+		if legacy.Action != "" {
+			msg.UserAction = legacy.userAction()
+		}
+	}
+	msg.Version = UIProtocolVersion
+	return &msg, nil
+}
+
+// userAction 把 v1 的 UIActionEvent 转换为 UserActionMessage
+// Payload 并入 Context，同名键以 Context 为准
+func (e *UIActionEvent) userAction() *UserActionMessage {
+	merged := make(map[string]any, len(e.Payload)+len(e.Context))
+	for k, v := range e.Payload {
+		merged[k] = v
+	}
+	for k, v := range e.Context {
+		merged[k] = v
+	}
+	return &UserActionMessage{
+		Name:              e.Action,
+		SurfaceID:         e.SurfaceID,
+		SourceComponentID: e.ComponentID,
+		Timestamp:         e.Timestamp,
+		Context:           merged,
+	}
+}
+
+// upgradeDataEntries 把 A2UI 键值条目列表转换为普通对象
+// 不是条目列表时返回 false，原值保持不变
+func upgradeDataEntries(v any) (map[string]any, bool) {
+	entries, ok := v.([]any)
+	if !ok || len(entries) == 0 {
+		return nil, false
+	}
+	out := make(map[string]any, len(entries))
+	for _, item := range entries {
+		entry, ok := item.(map[string]any)
+		if !ok || len(entry) != 2 {
+			return nil, false
+		}
+		key, ok := entry["key"].(string)
+		if !ok {
+			return nil, false
+		}
+		switch {
+		case entry["valueString"] != nil:
+			out[key] = entry["valueString"]
+		case entry["valueNumber"] != nil:
+			out[key] = entry["valueNumber"]
+		case entry["valueBoolean"] != nil:
+			out[key] = entry["valueBoolean"]
+		case entry["valueMap"] != nil:
+			nested, ok := upgradeDataEntries(entry["valueMap"])
+			if !ok {
+				return nil, false
+			}
+			out[key] = nested
+		default:
+			return nil, false
+		}
+	}
+	return out, true
+}
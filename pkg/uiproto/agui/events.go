@@ -0,0 +1,133 @@
+// Package agui 把 Aster Agent 事件流转换为 AG-UI 标准事件
+// 兼容 AG-UI 的前端无需定制客户端即可渲染 Aster Agent；
+// 声明式 UI（A2UI 风格的 AsterUIMessage）以 CUSTOM 事件透传
+package agui
+
+import "time"
+
+// EventType AG-UI 事件类型
+type EventType string
+
+const (
+	EventRunStarted   EventType = "RUN_STARTED"
+	EventRunFinished  EventType = "RUN_FINISHED"
+	EventRunError     EventType = "RUN_ERROR"
+	EventStepStarted  EventType = "STEP_STARTED"
+	EventStepFinished EventType = "STEP_FINISHED"
+
+	EventTextMessageStart   EventType = "TEXT_MESSAGE_START"
+	EventTextMessageContent EventType = "TEXT_MESSAGE_CONTENT"
+	EventTextMessageEnd     EventType = "TEXT_MESSAGE_END"
+
+	EventToolCallStart  EventType = "TOOL_CALL_START"
+	EventToolCallArgs   EventType = "TOOL_CALL_ARGS"
+	EventToolCallEnd    EventType = "TOOL_CALL_END"
+	EventToolCallResult EventType = "TOOL_CALL_RESULT"
+
+	EventThinkingStart              EventType = "THINKING_START"
+	EventThinkingEnd                EventType = "THINKING_END"
+	EventThinkingTextMessageStart   EventType = "THINKING_TEXT_MESSAGE_START"
+	EventThinkingTextMessageContent EventType = "THINKING_TEXT_MESSAGE_CONTENT"
+	EventThinkingTextMessageEnd     EventType = "THINKING_TEXT_MESSAGE_END"
+
+	EventStateSnapshot    EventType = "STATE_SNAPSHOT"
+	EventStateDelta       EventType = "STATE_DELTA"
+	EventMessagesSnapshot EventType = "MESSAGES_SNAPSHOT"
+
+	EventRaw    EventType = "RAW"
+	EventCustom EventType = "CUSTOM"
+)
+
+// CUSTOM 事件名称
+const (
+	// CustomUIMessage 声明式 UI 消息，value 为 AsterUIMessage
+	CustomUIMessage = "a2ui"
+	// CustomPermissionRequired 工具调用等待审批，value 为工具调用快照
+	CustomPermissionRequired = "aster.permission_required"
+	// CustomWorkspaceTrustRequired 工作区未受信任，value 为 ControlWorkspaceTrustRequiredEvent
+	CustomWorkspaceTrustRequired = "aster.workspace_trust_required"
+	// CustomError 非致命错误，value 为 MonitorErrorEvent
+	CustomError = "aster.error"
+)
+
+// Event AG-UI 事件
+// 所有类型共用一个结构，按类型填充字段，序列化时省略空字段
+type Event struct {
+	Type      EventType `json:"type"`
+	Timestamp int64     `json:"timestamp,omitempty"`
+
+	// 运行生命周期
+	ThreadID string `json:"threadId,omitempty"`
+	RunID    string `json:"runId,omitempty"`
+	Result   any    `json:"result,omitempty"`
+	Message  string `json:"message,omitempty"`
+	Code     string `json:"code,omitempty"`
+	StepName string `json:"stepName,omitempty"`
+
+	// 文本与工具
+	MessageID       string `json:"messageId,omitempty"`
+	Role            string `json:"role,omitempty"`
+	Delta           any    `json:"delta,omitempty"`
+	ToolCallID      string `json:"toolCallId,omitempty"`
+	ToolCallName    string `json:"toolCallName,omitempty"`
+	ParentMessageID string `json:"parentMessageId,omitempty"`
+	Content         string `json:"content,omitempty"`
+
+	// 状态
+	Snapshot any       `json:"snapshot,omitempty"`
+	Messages []Message `json:"messages,omitempty"`
+
+	// CUSTOM / RAW
+	Name     string `json:"name,omitempty"`
+	Value    any    `json:"value,omitempty"`
+	RawEvent any    `json:"event,omitempty"`
+	Source   string `json:"source,omitempty"`
+}
+
+// Message AG-UI 消息
+type Message struct {
+	ID         string     `json:"id"`
+	Role       string     `json:"role"`
+	Content    string     `json:"content,omitempty"`
+	Name       string     `json:"name,omitempty"`
+	ToolCallID string     `json:"toolCallId,omitempty"`
+	ToolCalls  []ToolCall `json:"toolCalls,omitempty"`
+}
+
+// ToolCall 助手消息中的工具调用
+type ToolCall struct {
+	ID       string       `json:"id"`
+	Type     string       `json:"type"`
+	Function FunctionCall `json:"function"`
+}
+
+// FunctionCall 工具调用的函数名与 JSON 参数
+type FunctionCall struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+}
+
+// RunAgentInput AG-UI 运行请求
+type RunAgentInput struct {
+	ThreadID       string    `json:"threadId"`
+	RunID          string    `json:"runId"`
+	State          any       `json:"state,omitempty"`
+	Messages       []Message `json:"messages"`
+	Tools          []any     `json:"tools,omitempty"`
+	Context        []any     `json:"context,omitempty"`
+	ForwardedProps any       `json:"forwardedProps,omitempty"`
+}
+
+// LastUserMessage 返回最后一条用户消息
+func (in *RunAgentInput) LastUserMessage() (Message, bool) {
+	for i := len(in.Messages) - 1; i >= 0; i-- {
+		if in.Messages[i].Role == "user" {
+			return in.Messages[i], true
+		}
+	}
+	return Message{}, false
+}
+
+func now() int64 {
+	return time.Now().UnixMilli()
+}
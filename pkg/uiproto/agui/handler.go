@@ -0,0 +1,132 @@
+package agui
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/astercloud/aster/pkg/types"
+	"github.com/google/uuid"
+)
+
+// Agent 被 AG-UI 驱动的 Agent，*agent.Agent 满足该接口
+type Agent interface {
+	Subscribe(channels []types.AgentChannel, opts *types.SubscribeOptions) <-chan types.AgentEventEnvelope
+	Unsubscribe(ch <-chan types.AgentEventEnvelope)
+	Send(ctx context.Context, text string) error
+}
+
+// AgentResolver 根据运行请求（通常是 threadId）查找或创建 Agent
+// release 非空时在运行结束后调用，用于关闭按请求创建的 Agent
+type AgentResolver func(ctx context.Context, input *RunAgentInput) (ag Agent, release func(), err error)
+
+// NewHandler 创建 AG-UI HTTP 端点
+// POST RunAgentInput，以 SSE 返回 AG-UI 事件，直到 RUN_FINISHED/RUN_ERROR 或客户端断开
+func NewHandler(resolve AgentResolver) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+			return
+		}
+
+		var input RunAgentInput
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			http.Error(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		msg, ok := input.LastUserMessage()
+		if !ok {
+			http.Error(w, "no user message in request", http.StatusBadRequest)
+			return
+		}
+		if input.ThreadID == "" {
+			input.ThreadID = uuid.NewString()
+		}
+		if input.RunID == "" {
+			input.RunID = uuid.NewString()
+		}
+
+		ctx := r.Context()
+		ag, release, err := resolve(ctx, &input)
+		if err != nil {
+			http.Error(w, "resolve agent failed: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if release != nil {
+			defer release()
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+
+		// 响应头已发出，流中的错误（通常是客户端断开）无法再报告
+		_ = Stream(ctx, ag, &input, msg.Content, func(evt Event) error {
+			if err := WriteSSE(w, evt); err != nil {
+				return err
+			}
+			flusher.Flush()
+			return nil
+		})
+	})
+}
+
+// Stream 向 Agent 发送文本并把本次运行的事件依次交给 emit
+// 先订阅后发送，保证不丢失运行开始时的事件
+func Stream(ctx context.Context, ag Agent, input *RunAgentInput, text string, emit func(Event) error) error {
+	ch := ag.Subscribe([]types.AgentChannel{types.ChannelProgress, types.ChannelControl, types.ChannelMonitor}, nil)
+	defer ag.Unsubscribe(ch)
+
+	tr := NewTranslator(input.ThreadID, input.RunID)
+	if err := emit(tr.Start()); err != nil {
+		return err
+	}
+
+	if err := ag.Send(ctx, text); err != nil {
+		for _, evt := range tr.Fail(err.Error(), "send_failed") {
+			if err := emit(evt); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for !tr.Finished() {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case env, ok := <-ch:
+			if !ok {
+				return nil
+			}
+			for _, evt := range tr.Translate(env) {
+				if err := emit(evt); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// WriteSSE 以 SSE data 帧写出一个事件
+func WriteSSE(w io.Writer, evt Event) error {
+	data, err := json.Marshal(evt)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write([]byte("data: ")); err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	_, err = w.Write([]byte("\n\n"))
+	return err
+}
@@ -0,0 +1,116 @@
+package agui
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/astercloud/aster/pkg/types"
+)
+
+// fakeAgent 在 Send 时按顺序发出预设事件
+type fakeAgent struct {
+	ch      chan types.AgentEventEnvelope
+	events  []any
+	sendErr error
+	sent    string
+}
+
+func newFakeAgent(events ...any) *fakeAgent {
+	return &fakeAgent{ch: make(chan types.AgentEventEnvelope, len(events)), events: events}
+}
+
+func (f *fakeAgent) Subscribe([]types.AgentChannel, *types.SubscribeOptions) <-chan types.AgentEventEnvelope {
+	return f.ch
+}
+
+func (f *fakeAgent) Unsubscribe(<-chan types.AgentEventEnvelope) {}
+
+func (f *fakeAgent) Send(_ context.Context, text string) error {
+	if f.sendErr != nil {
+		return f.sendErr
+	}
+	f.sent = text
+	for i, e := range f.events {
+		f.ch <- types.AgentEventEnvelope{Cursor: int64(i), Event: e}
+	}
+	return nil
+}
+
+func runHandler(t *testing.T, ag Agent, body string) (*httptest.ResponseRecorder, []Event) {
+	t.Helper()
+	released := false
+	h := NewHandler(func(context.Context, *RunAgentInput) (Agent, func(), error) {
+		return ag, func() { released = true }, nil
+	})
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/agui", strings.NewReader(body)))
+	if rec.Code == http.StatusOK && !released {
+		t.Error("agent was not released after the run")
+	}
+
+	var events []Event
+	scanner := bufio.NewScanner(rec.Body)
+	for scanner.Scan() {
+		line, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		var evt Event
+		if err := json.Unmarshal([]byte(line), &evt); err != nil {
+			t.Fatalf("invalid SSE payload %q: %v", line, err)
+		}
+		events = append(events, evt)
+	}
+	return rec, events
+}
+
+func TestHandlerStreamsRun(t *testing.T) {
+	ag := newFakeAgent(
+		&types.ProgressTextChunkEvent{Delta: "hi"},
+		&types.ProgressDoneEvent{Reason: "completed"},
+	)
+	body := `{"threadId": "th", "runId": "run", "messages": [
+		{"id": "1", "role": "user", "content": "first"},
+		{"id": "2", "role": "assistant", "content": "ok"},
+		{"id": "3", "role": "user", "content": "second"}
+	]}`
+	rec, events := runHandler(t, ag, body)
+
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/event-stream") {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	if ag.sent != "second" {
+		t.Errorf("sent %q, want last user message", ag.sent)
+	}
+	want := []EventType{EventRunStarted, EventTextMessageStart, EventTextMessageContent, EventTextMessageEnd, EventRunFinished}
+	if got := eventTypes(events); !reflect.DeepEqual(got, want) {
+		t.Fatalf("events = %v, want %v", got, want)
+	}
+	if events[0].ThreadID != "th" || events[0].RunID != "run" {
+		t.Errorf("RUN_STARTED = %+v", events[0])
+	}
+}
+
+func TestHandlerErrors(t *testing.T) {
+	rec, _ := runHandler(t, newFakeAgent(), `{"messages": [{"id": "1", "role": "assistant", "content": "x"}]}`)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("no user message: status = %d", rec.Code)
+	}
+
+	ag := newFakeAgent()
+	ag.sendErr = errors.New("agent busy")
+	_, events := runHandler(t, ag, `{"messages": [{"id": "1", "role": "user", "content": "x"}]}`)
+	if len(events) != 2 || events[1].Type != EventRunError || events[1].Message != "agent busy" {
+		t.Errorf("events = %+v, want RUN_STARTED, RUN_ERROR", events)
+	}
+	if events[0].ThreadID == "" || events[0].RunID == "" {
+		t.Errorf("missing generated ids: %+v", events[0])
+	}
+}
@@ -0,0 +1,252 @@
+package agui
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/astercloud/aster/pkg/types"
+	"github.com/astercloud/aster/pkg/uiproto"
+	"github.com/google/uuid"
+)
+
+// Translator 把一次运行的 Agent 事件转换为 AG-UI 事件
+// AG-UI 要求消息按 START/CONTENT/END 成对出现，Translator 负责补齐缺失的开始与结束事件；
+// 非并发安全，每次运行使用一个实例
+type Translator struct {
+	threadID string
+	runID    string
+
+	textID     string // 当前打开的文本消息
+	lastTextID string // 最近的文本消息，作为工具调用的 parentMessageId
+	thinking   bool
+
+	// toolCalls 已发出 TOOL_CALL_START 的调用，值表示是否已发出结果
+	toolCalls map[string]bool
+
+	runError string
+	finished bool
+}
+
+// NewTranslator 创建转换器
+func NewTranslator(threadID, runID string) *Translator {
+	return &Translator{
+		threadID:  threadID,
+		runID:     runID,
+		toolCalls: make(map[string]bool),
+	}
+}
+
+// Start 返回 RUN_STARTED 事件
+func (t *Translator) Start() Event {
+	return Event{Type: EventRunStarted, Timestamp: now(), ThreadID: t.threadID, RunID: t.runID}
+}
+
+// Finished 运行是否已结束（已发出 RUN_FINISHED 或 RUN_ERROR）
+func (t *Translator) Finished() bool {
+	return t.finished
+}
+
+// Fail 结束运行并返回 RUN_ERROR 前需要的收尾事件
+func (t *Translator) Fail(message, code string) []Event {
+	if t.finished {
+		return nil
+	}
+	events := t.closeOpen()
+	t.finished = true
+	return append(events, Event{Type: EventRunError, Timestamp: now(), Message: message, Code: code})
+}
+
+// Translate 转换一个 Agent 事件；不对应任何 AG-UI 事件时返回 nil
+func (t *Translator) Translate(env types.AgentEventEnvelope) []Event {
+	if t.finished {
+		return nil
+	}
+
+	if msgs, ok := uiproto.EventMessages(env.Event); ok {
+		events := make([]Event, 0, len(msgs))
+		for _, msg := range msgs {
+			events = append(events, t.custom(CustomUIMessage, msg))
+		}
+		return events
+	}
+
+	switch e := env.Event.(type) {
+	case *types.ProgressThinkChunkStartEvent:
+		return t.openThinking(nil)
+	case *types.ProgressThinkChunkEvent:
+		delta := e.Delta
+		if delta == "" {
+			delta = e.Reasoning
+		}
+		if delta == "" {
+			return nil
+		}
+		return t.openThinking([]Event{{Type: EventThinkingTextMessageContent, Timestamp: now(), Delta: delta}})
+	case *types.ProgressThinkChunkEndEvent:
+		return t.closeThinking(nil)
+
+	case *types.ProgressTextChunkStartEvent:
+		return t.openText(nil)
+	case *types.ProgressTextChunkEvent:
+		if e.Delta == "" {
+			return nil
+		}
+		return t.openText(nil, Event{Type: EventTextMessageContent, Timestamp: now(), Delta: e.Delta})
+	case *types.ProgressTextChunkEndEvent:
+		return t.closeText(nil)
+
+	case *types.ProgressToolStartEvent:
+		return t.startTool(t.closeOpen(), e.Call)
+	case *types.ProgressToolIntermediateEvent:
+		if e.UI == nil {
+			return nil
+		}
+		return []Event{t.custom(CustomUIMessage, e.UI)}
+	case *types.ProgressToolEndEvent:
+		content := e.Call.Error
+		if content == "" {
+			content = stringify(e.Call.Result)
+		}
+		return t.toolResult(e.Call, content)
+	case *types.ProgressToolErrorEvent:
+		return t.toolResult(e.Call, e.Error)
+	case *types.ProgressToolCancelledEvent:
+		return t.toolResult(e.Call, "canceled: "+e.Reason)
+
+	case *types.ProgressTodoUpdateEvent:
+		return []Event{{Type: EventStateSnapshot, Timestamp: now(), Snapshot: map[string]any{"todos": e.Todos}}}
+
+	case *types.ControlPermissionRequiredEvent:
+		return []Event{t.custom(CustomPermissionRequired, e.Call)}
+
+	case *types.ControlWorkspaceTrustRequiredEvent:
+		return []Event{t.custom(CustomWorkspaceTrustRequired, e)}
+
+	case *types.MonitorErrorEvent:
+		// 模型错误之后仍会发出 done，记录下来在结束时转换为 RUN_ERROR
+		if e.Severity == "error" && e.Phase == "model" {
+			t.runError = e.Message
+		}
+		return []Event{t.custom(CustomError, e)}
+
+	case *types.ProgressDoneEvent:
+		if t.runError != "" {
+			return t.Fail(t.runError, "model_error")
+		}
+		events := t.closeOpen()
+		t.finished = true
+		return append(events, Event{
+			Type:      EventRunFinished,
+			Timestamp: now(),
+			ThreadID:  t.threadID,
+			RunID:     t.runID,
+			Result:    map[string]any{"reason": e.Reason, "step": e.Step},
+		})
+	}
+	return nil
+}
+
+func (t *Translator) custom(name string, value any) Event {
+	return Event{Type: EventCustom, Timestamp: now(), Name: name, Value: value}
+}
+
+func (t *Translator) openThinking(events []Event) []Event {
+	if t.thinking {
+		return events
+	}
+	opened := t.closeText(nil)
+	t.thinking = true
+	opened = append(opened,
+		Event{Type: EventThinkingStart, Timestamp: now()},
+		Event{Type: EventThinkingTextMessageStart, Timestamp: now()},
+	)
+	return append(opened, events...)
+}
+
+func (t *Translator) closeThinking(events []Event) []Event {
+	if !t.thinking {
+		return events
+	}
+	t.thinking = false
+	return append(events,
+		Event{Type: EventThinkingTextMessageEnd, Timestamp: now()},
+		Event{Type: EventThinkingEnd, Timestamp: now()},
+	)
+}
+
+func (t *Translator) openText(events []Event, content ...Event) []Event {
+	if t.textID == "" {
+		events = t.closeThinking(events)
+		t.textID = uuid.NewString()
+		t.lastTextID = t.textID
+		events = append(events, Event{Type: EventTextMessageStart, Timestamp: now(), MessageID: t.textID, Role: "assistant"})
+	}
+	for _, evt := range content {
+		evt.MessageID = t.textID
+		events = append(events, evt)
+	}
+	return events
+}
+
+func (t *Translator) closeText(events []Event) []Event {
+	if t.textID == "" {
+		return events
+	}
+	events = append(events, Event{Type: EventTextMessageEnd, Timestamp: now(), MessageID: t.textID})
+	t.textID = ""
+	return events
+}
+
+func (t *Translator) closeOpen() []Event {
+	return t.closeText(t.closeThinking(nil))
+}
+
+func (t *Translator) startTool(events []Event, call types.ToolCallSnapshot) []Event {
+	if _, ok := t.toolCalls[call.ID]; ok {
+		return events
+	}
+	t.toolCalls[call.ID] = false
+	events = append(events, Event{
+		Type:            EventToolCallStart,
+		Timestamp:       now(),
+		ToolCallID:      call.ID,
+		ToolCallName:    call.Name,
+		ParentMessageID: t.lastTextID,
+	})
+	if len(call.Arguments) > 0 {
+		events = append(events, Event{Type: EventToolCallArgs, Timestamp: now(), ToolCallID: call.ID, Delta: stringify(call.Arguments)})
+	}
+	return append(events, Event{Type: EventToolCallEnd, Timestamp: now(), ToolCallID: call.ID})
+}
+
+// toolResult 发出工具结果；未经 tool:start 就失败的调用先补齐调用事件，每个调用只发一次结果
+func (t *Translator) toolResult(call types.ToolCallSnapshot, content string) []Event {
+	if t.toolCalls[call.ID] {
+		return nil
+	}
+	events := t.startTool(t.closeOpen(), call)
+	t.toolCalls[call.ID] = true
+	return append(events, Event{
+		Type:       EventToolCallResult,
+		Timestamp:  now(),
+		MessageID:  uuid.NewString(),
+		ToolCallID: call.ID,
+		Role:       "tool",
+		Content:    content,
+	})
+}
+
+// stringify 把工具参数或结果编码为 AG-UI 需要的字符串
+func stringify(v any) string {
+	switch s := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return s
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
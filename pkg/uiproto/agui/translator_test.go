@@ -0,0 +1,121 @@
+package agui
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/astercloud/aster/pkg/types"
+)
+
+func translateAll(tr *Translator, events ...any) []Event {
+	var out []Event
+	for _, e := range events {
+		out = append(out, tr.Translate(types.AgentEventEnvelope{Event: e})...)
+	}
+	return out
+}
+
+func eventTypes(events []Event) []EventType {
+	out := make([]EventType, len(events))
+	for i, e := range events {
+		out[i] = e.Type
+	}
+	return out
+}
+
+func TestTranslatorTextAndTools(t *testing.T) {
+	tr := NewTranslator("thread-1", "run-1")
+	events := translateAll(tr,
+		&types.ProgressThinkChunkStartEvent{},
+		&types.ProgressThinkChunkEvent{Delta: "plan"},
+		&types.ProgressTextChunkEvent{Delta: "Hello"},
+		&types.ProgressTextChunkEvent{Delta: ""},
+		&types.ProgressTextChunkEvent{Delta: " world"},
+		&types.ProgressToolStartEvent{Call: types.ToolCallSnapshot{ID: "call-1", Name: "Read", Arguments: map[string]any{"path": "a.go"}}},
+		&types.ProgressToolEndEvent{Call: types.ToolCallSnapshot{ID: "call-1", Name: "Read", Result: map[string]any{"ok": true}}},
+		&types.ProgressToolErrorEvent{Call: types.ToolCallSnapshot{ID: "call-2", Name: "Missing"}, Error: "tool not found"},
+		&types.ProgressDoneEvent{Step: 2, Reason: "completed"},
+		&types.ProgressTextChunkEvent{Delta: "after done"},
+	)
+
+	want := []EventType{
+		EventThinkingStart, EventThinkingTextMessageStart, EventThinkingTextMessageContent,
+		EventThinkingTextMessageEnd, EventThinkingEnd,
+		EventTextMessageStart, EventTextMessageContent, EventTextMessageContent, EventTextMessageEnd,
+		EventToolCallStart, EventToolCallArgs, EventToolCallEnd, EventToolCallResult,
+		EventToolCallStart, EventToolCallEnd, EventToolCallResult,
+		EventRunFinished,
+	}
+	if got := eventTypes(events); !reflect.DeepEqual(got, want) {
+		t.Fatalf("event types =\n%v\nwant\n%v", got, want)
+	}
+
+	textID := events[5].MessageID
+	if textID == "" || events[6].MessageID != textID || events[8].MessageID != textID {
+		t.Errorf("text events do not share a message id: %+v", events[5:9])
+	}
+	if events[9].ParentMessageID != textID || events[9].ToolCallName != "Read" {
+		t.Errorf("TOOL_CALL_START = %+v", events[9])
+	}
+	if events[10].Delta != `{"path":"a.go"}` {
+		t.Errorf("TOOL_CALL_ARGS delta = %v", events[10].Delta)
+	}
+	if events[12].Content != `{"ok":true}` || events[12].Role != "tool" || events[12].MessageID == "" {
+		t.Errorf("TOOL_CALL_RESULT = %+v", events[12])
+	}
+	if events[15].Content != "tool not found" {
+		t.Errorf("error result = %+v", events[15])
+	}
+	if last := events[len(events)-1]; last.ThreadID != "thread-1" || last.RunID != "run-1" {
+		t.Errorf("RUN_FINISHED = %+v", last)
+	}
+	if !tr.Finished() {
+		t.Error("Finished() = false after done")
+	}
+}
+
+func TestTranslatorUIAndControl(t *testing.T) {
+	tr := NewTranslator("t", "r")
+	ui := &types.AsterUIMessage{DeleteSurface: &types.DeleteSurfaceMessage{SurfaceID: "s"}}
+	events := translateAll(tr,
+		&types.ProgressUISurfaceUpdateEvent{SurfaceID: "s", Root: "root"},
+		&types.ProgressToolIntermediateEvent{Call: types.ToolCallSnapshot{ID: "c"}, UI: ui},
+		&types.ProgressToolIntermediateEvent{Call: types.ToolCallSnapshot{ID: "c"}, Label: "log", Data: "x"},
+		&types.ControlPermissionRequiredEvent{Call: types.ToolCallSnapshot{ID: "c", Name: "Bash"}},
+		&types.ProgressTodoUpdateEvent{Todos: []types.TodoItem{{Content: "write tests"}}},
+	)
+
+	want := []EventType{EventCustom, EventCustom, EventCustom, EventCustom, EventStateSnapshot}
+	if got := eventTypes(events); !reflect.DeepEqual(got, want) {
+		t.Fatalf("event types = %v, want %v", got, want)
+	}
+	// surface 更新携带 root 时拆分为 surfaceUpdate + beginRendering
+	first, _ := events[0].Value.(*types.AsterUIMessage)
+	second, _ := events[1].Value.(*types.AsterUIMessage)
+	if events[0].Name != CustomUIMessage || first == nil || first.SurfaceUpdate == nil || second == nil || second.BeginRendering == nil {
+		t.Errorf("surface update events = %+v", events[:2])
+	}
+	if events[2].Value != ui {
+		t.Errorf("tool intermediate UI = %+v", events[2])
+	}
+	if events[3].Name != CustomPermissionRequired {
+		t.Errorf("permission event = %+v", events[3])
+	}
+}
+
+func TestTranslatorModelError(t *testing.T) {
+	tr := NewTranslator("t", "r")
+	events := translateAll(tr,
+		&types.ProgressTextChunkStartEvent{},
+		&types.MonitorErrorEvent{Severity: "error", Phase: "model", Message: "rate limited"},
+		&types.ProgressDoneEvent{Reason: "completed"},
+	)
+
+	want := []EventType{EventTextMessageStart, EventCustom, EventTextMessageEnd, EventRunError}
+	if got := eventTypes(events); !reflect.DeepEqual(got, want) {
+		t.Fatalf("event types = %v, want %v", got, want)
+	}
+	if last := events[len(events)-1]; last.Message != "rate limited" || last.Code != "model_error" {
+		t.Errorf("RUN_ERROR = %+v", last)
+	}
+}
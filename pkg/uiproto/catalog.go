@@ -0,0 +1,476 @@
+package uiproto
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/astercloud/aster/pkg/types"
+)
+
+const (
+	// StandardCatalogID 标准组件目录 ID
+	StandardCatalogID = "standard"
+	// StandardCatalogVersion 标准组件目录版本
+	StandardCatalogVersion = "1.0.0"
+)
+
+// ErrCatalogNotFound 引用的组件目录不存在
+var ErrCatalogNotFound = errors.New("catalog not found")
+
+// DefaultCatalogs 默认组件目录注册表，已注册标准目录
+// 未显式配置注册表的 Agent 与桌面端共享此实例
+var DefaultCatalogs = NewCatalogRegistry()
+
+// ComponentSchema 目录中的一个组件
+type ComponentSchema struct {
+	// Name 组件名称；标准组件为 ComponentSpec 中的类型名，自定义组件为 Custom.Type
+	Name string `json:"name"`
+	// Description 组件说明
+	Description string `json:"description,omitempty"`
+	// Custom 是否为自定义组件（通过 Custom 组件渲染）
+	Custom bool `json:"custom,omitempty"`
+	// Schema 组件属性的 JSON Schema（可选）
+	Schema map[string]any `json:"schema,omitempty"`
+}
+
+// Catalog 组件目录：一组可用组件及其属性 Schema
+type Catalog struct {
+	ID          string                      `json:"id"`
+	Version     string                      `json:"version"`
+	Description string                      `json:"description,omitempty"`
+	Components  map[string]*ComponentSchema `json:"components"`
+}
+
+// Ref 返回带版本的目录引用 "id@version"
+func (c *Catalog) Ref() string {
+	return c.ID + "@" + c.Version
+}
+
+// ValidateComponent 校验组件是否属于目录且属性符合 Schema
+func (c *Catalog) ValidateComponent(spec types.ComponentSpec) error {
+	name := spec.GetTypeName()
+	var props any
+	if spec.Custom != nil {
+		name = spec.Custom.Type
+		props = spec.Custom.Props
+	} else {
+		v := reflect.ValueOf(spec)
+		for i := range v.NumField() {
+			if !v.Field(i).IsNil() {
+				props = v.Field(i).Interface()
+				break
+			}
+		}
+	}
+
+	comp, ok := c.Components[name]
+	if !ok || comp.Custom != (spec.Custom != nil) {
+		return fmt.Errorf("component %q is not in catalog %s", name, c.Ref())
+	}
+	if comp.Schema == nil {
+		return nil
+	}
+	value, err := normalize(props)
+	if err != nil {
+		return err
+	}
+	if value == nil {
+		value = map[string]any{}
+	}
+	return validateSchema(value, comp.Schema, "")
+}
+
+// clone 返回目录的深拷贝
+func (c *Catalog) clone() *Catalog {
+	out := *c
+	out.Components = make(map[string]*ComponentSchema, len(c.Components))
+	for name, comp := range c.Components {
+		copied := *comp
+		if comp.Schema != nil {
+			copied.Schema = cloneValue(comp.Schema).(map[string]any)
+		}
+		out.Components[name] = &copied
+	}
+	return &out
+}
+
+// CatalogRegistry 组件目录注册表，同一目录可注册多个版本
+type CatalogRegistry struct {
+	mu       sync.RWMutex
+	catalogs map[string][]*Catalog // id -> 按版本升序
+}
+
+// NewCatalogRegistry 创建注册表并注册标准目录
+func NewCatalogRegistry() *CatalogRegistry {
+	r := &CatalogRegistry{catalogs: make(map[string][]*Catalog)}
+	_ = r.Register(StandardCatalog())
+	return r
+}
+
+// Register 注册目录；同一 ID 的同一版本只能注册一次
+// Schema 会经 JSON 规范化，注册后修改入参不影响注册表
+func (r *CatalogRegistry) Register(catalog *Catalog) error {
+	if catalog == nil {
+		return errors.New("catalog is nil")
+	}
+	if catalog.ID == "" || strings.ContainsAny(catalog.ID, "@/?#") {
+		return fmt.Errorf("invalid catalog id %q", catalog.ID)
+	}
+	if _, err := parseVersion(catalog.Version); err != nil {
+		return err
+	}
+	if len(catalog.Components) == 0 {
+		return fmt.Errorf("catalog %s has no components", catalog.Ref())
+	}
+
+	c := &Catalog{
+		ID:          catalog.ID,
+		Version:     catalog.Version,
+		Description: catalog.Description,
+		Components:  make(map[string]*ComponentSchema, len(catalog.Components)),
+	}
+	for name, comp := range catalog.Components {
+		if comp == nil {
+			return fmt.Errorf("catalog %s: component %q is nil", c.Ref(), name)
+		}
+		copied := *comp
+		if copied.Name == "" {
+			copied.Name = name
+		}
+		if copied.Name != name {
+			return fmt.Errorf("catalog %s: component key %q does not match name %q", c.Ref(), name, copied.Name)
+		}
+		if !copied.Custom && !isStandardComponent(name) {
+			return fmt.Errorf("catalog %s: %q is not a standard component; set Custom for custom components", c.Ref(), name)
+		}
+		if comp.Schema != nil {
+			schema, err := normalize(comp.Schema)
+			if err != nil {
+				return fmt.Errorf("catalog %s: component %q: %w", c.Ref(), name, err)
+			}
+			copied.Schema, _ = schema.(map[string]any)
+		}
+		c.Components[name] = &copied
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	versions := r.catalogs[c.ID]
+	for _, existing := range versions {
+		if compareVersions(existing.Version, c.Version) == 0 {
+			return fmt.Errorf("catalog %s already registered", c.Ref())
+		}
+	}
+	versions = append(versions, c)
+	slices.SortFunc(versions, func(a, b *Catalog) int { return compareVersions(a.Version, b.Version) })
+	r.catalogs[c.ID] = versions
+	return nil
+}
+
+// Get 按引用查找目录："id" 返回最新版本，"id@version" 返回指定版本
+func (r *CatalogRegistry) Get(ref string) (*Catalog, error) {
+	id, version, pinned := strings.Cut(ref, "@")
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	versions := r.catalogs[id]
+	if len(versions) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrCatalogNotFound, ref)
+	}
+	if !pinned {
+		return versions[len(versions)-1].clone(), nil
+	}
+	for _, c := range versions {
+		if compareVersions(c.Version, version) == 0 {
+			return c.clone(), nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrCatalogNotFound, ref)
+}
+
+// Versions 返回目录的所有版本（升序）
+func (r *CatalogRegistry) Versions(id string) []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]string, 0, len(r.catalogs[id]))
+	for _, c := range r.catalogs[id] {
+		out = append(out, c.Version)
+	}
+	return out
+}
+
+// List 返回每个目录的最新版本（按 ID 排序）
+func (r *CatalogRegistry) List() []*Catalog {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]*Catalog, 0, len(r.catalogs))
+	for _, id := range slices.Sorted(maps.Keys(r.catalogs)) {
+		versions := r.catalogs[id]
+		out = append(out, versions[len(versions)-1].clone())
+	}
+	return out
+}
+
+// StandardCatalog 返回标准组件目录，属性 Schema 由 ComponentSpec 的类型生成
+func StandardCatalog() *Catalog {
+	c := &Catalog{
+		ID:          StandardCatalogID,
+		Version:     StandardCatalogVersion,
+		Description: "Aster UI standard components",
+		Components:  make(map[string]*ComponentSchema),
+	}
+	specType := reflect.TypeFor[types.ComponentSpec]()
+	for i := range specType.NumField() {
+		field := specType.Field(i)
+		if field.Name == "Custom" {
+			continue
+		}
+		c.Components[field.Name] = &ComponentSchema{
+			Name:   field.Name,
+			Schema: typeSchema(field.Type),
+		}
+	}
+	return c
+}
+
+func isStandardComponent(name string) bool {
+	if name == "Custom" {
+		return false
+	}
+	_, ok := reflect.TypeFor[types.ComponentSpec]().FieldByName(name)
+	return ok
+}
+
+var propertyValueType = reflect.TypeFor[types.PropertyValue]()
+
+// typeSchema 由 Go 类型生成 JSON Schema；非 omitempty 字段视为必填
+func typeSchema(t reflect.Type) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch {
+	case t == propertyValueType:
+		return map[string]any{
+			"type":        "object",
+			"description": "literalString / literalNumber / literalBoolean / path",
+		}
+	case t.Kind() == reflect.String:
+		return map[string]any{"type": "string"}
+	case t.Kind() == reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Float64:
+		return map[string]any{"type": "number"}
+	case t.Kind() == reflect.Slice || t.Kind() == reflect.Array:
+		return map[string]any{"type": "array", "items": typeSchema(t.Elem())}
+	case t.Kind() == reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": typeSchema(t.Elem())}
+	case t.Kind() != reflect.Struct:
+		return map[string]any{}
+	}
+
+	props := make(map[string]any)
+	var required []any
+	for i := range t.NumField() {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		props[name] = typeSchema(f.Type)
+		if !strings.Contains(opts, "omitempty") {
+			required = append(required, name)
+		}
+	}
+	schema := map[string]any{
+		"type":                 "object",
+		"properties":           props,
+		"additionalProperties": false,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// validateSchema 按 JSON Schema 子集校验规范化后的 JSON 值
+// 支持 type、properties、required、additionalProperties、items、enum；
+// 属性也可以是对应字面值或 path 绑定形式的 PropertyValue
+func validateSchema(value any, schema map[string]any, path string) error {
+	if enum, ok := schema["enum"].([]any); ok && !slices.ContainsFunc(enum, func(e any) bool {
+		return reflect.DeepEqual(e, literalOf(value))
+	}) {
+		return fmt.Errorf("%s: value is not one of %v", pathOrRoot(path), enum)
+	}
+
+	// 属性可以绑定到数据模型，绑定值在渲染时才确定
+	if obj, ok := value.(map[string]any); ok && path != "" && isPathBinding(obj) {
+		return nil
+	}
+
+	typ, _ := schema["type"].(string)
+	// Go 的 nil 切片/映射序列化为 null
+	if value == nil && path != "" && (typ == "array" || typ == "object") {
+		return nil
+	}
+	switch typ {
+	case "string", "number", "integer", "boolean":
+		v := literalOf(value)
+		if !matchesType(v, typ) {
+			return fmt.Errorf("%s: expected %s, got %s", pathOrRoot(path), typ, jsonType(v))
+		}
+	case "array":
+		arr, ok := value.([]any)
+		if !ok {
+			return fmt.Errorf("%s: expected array, got %s", pathOrRoot(path), jsonType(value))
+		}
+		if items, ok := schema["items"].(map[string]any); ok {
+			for i, item := range arr {
+				if err := validateSchema(item, items, path+"/"+strconv.Itoa(i)); err != nil {
+					return err
+				}
+			}
+		}
+	case "object":
+		obj, ok := value.(map[string]any)
+		if !ok {
+			return fmt.Errorf("%s: expected object, got %s", pathOrRoot(path), jsonType(value))
+		}
+		if required, ok := schema["required"].([]any); ok {
+			for _, key := range required {
+				if k, _ := key.(string); k != "" {
+					if _, ok := obj[k]; !ok {
+						return fmt.Errorf("%s: missing required property %q", pathOrRoot(path), k)
+					}
+				}
+			}
+		}
+		props, _ := schema["properties"].(map[string]any)
+		for _, key := range slices.Sorted(maps.Keys(obj)) {
+			child := path + "/" + key
+			if sub, ok := props[key].(map[string]any); ok {
+				if err := validateSchema(obj[key], sub, child); err != nil {
+					return err
+				}
+				continue
+			}
+			switch extra := schema["additionalProperties"].(type) {
+			case bool:
+				if !extra {
+					return fmt.Errorf("%s: unknown property", child)
+				}
+			case map[string]any:
+				if err := validateSchema(obj[key], extra, child); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// literalOf 把 PropertyValue 形式的字面值展开为基本值，其他值原样返回
+func literalOf(value any) any {
+	obj, ok := value.(map[string]any)
+	if !ok || len(obj) != 1 {
+		return value
+	}
+	for _, key := range []string{"literalString", "literalNumber", "literalBoolean"} {
+		if v, ok := obj[key]; ok {
+			return v
+		}
+	}
+	return value
+}
+
+func isPathBinding(obj map[string]any) bool {
+	_, ok := obj["path"].(string)
+	return ok && len(obj) == 1
+}
+
+func matchesType(v any, typ string) bool {
+	switch typ {
+	case "string":
+		_, ok := v.(string)
+		return ok
+	case "boolean":
+		_, ok := v.(bool)
+		return ok
+	case "number":
+		_, ok := v.(float64)
+		return ok
+	case "integer":
+		f, ok := v.(float64)
+		return ok && f == float64(int64(f))
+	}
+	return true
+}
+
+func jsonType(v any) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+func pathOrRoot(path string) string {
+	if path == "" {
+		return "/"
+	}
+	return path
+}
+
+// parseVersion 解析 "MAJOR[.MINOR[.PATCH]]" 形式的版本号
+func parseVersion(v string) ([3]int, error) {
+	var out [3]int
+	parts := strings.Split(v, ".")
+	if v == "" || len(parts) > 3 {
+		return out, fmt.Errorf("invalid catalog version %q", v)
+	}
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return out, fmt.Errorf("invalid catalog version %q", v)
+		}
+		out[i] = n
+	}
+	return out, nil
+}
+
+// compareVersions 比较两个版本号；无法解析的版本按字符串比较
+func compareVersions(a, b string) int {
+	va, errA := parseVersion(a)
+	vb, errB := parseVersion(b)
+	if errA != nil || errB != nil {
+		return strings.Compare(a, b)
+	}
+	for i := range va {
+		if c := cmp.Compare(va[i], vb[i]); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
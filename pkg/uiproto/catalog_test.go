@@ -0,0 +1,166 @@
+package uiproto
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/astercloud/aster/pkg/types"
+)
+
+func chartCatalog(version string) *Catalog {
+	return &Catalog{
+		ID:      "charts",
+		Version: version,
+		Components: map[string]*ComponentSchema{
+			"Text": {},
+			"Chart": {
+				Custom: true,
+				Schema: map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"kind":   map[string]any{"type": "string", "enum": []any{"bar", "line"}},
+						"series": map[string]any{"type": "array"},
+						"height": map[string]any{"type": "number"},
+					},
+					"required":             []string{"kind", "series"},
+					"additionalProperties": false,
+				},
+			},
+		},
+	}
+}
+
+func chartComponent(props map[string]types.PropertyValue) types.ComponentDefinition {
+	return types.ComponentDefinition{ID: "chart", Component: types.ComponentSpec{
+		Custom: &types.CustomProps{Type: "Chart", Props: props},
+	}}
+}
+
+func TestCatalogRegistryVersions(t *testing.T) {
+	r := NewCatalogRegistry()
+	if _, err := r.Get(StandardCatalogID); err != nil {
+		t.Fatalf("standard catalog not registered: %v", err)
+	}
+
+	for _, v := range []string{"1.2.0", "1.10.0", "1.9"} {
+		if err := r.Register(chartCatalog(v)); err != nil {
+			t.Fatalf("Register(%s) error = %v", v, err)
+		}
+	}
+	if err := r.Register(chartCatalog("1.2")); err == nil {
+		t.Error("duplicate version 1.2 == 1.2.0 was accepted")
+	}
+
+	if got := r.Versions("charts"); strings.Join(got, ",") != "1.2.0,1.9,1.10.0" {
+		t.Errorf("Versions() = %v", got)
+	}
+	latest, err := r.Get("charts")
+	if err != nil || latest.Version != "1.10.0" {
+		t.Errorf("Get(charts) = %+v, %v, want latest 1.10.0", latest, err)
+	}
+	pinned, err := r.Get("charts@1.9")
+	if err != nil || pinned.Ref() != "charts@1.9" {
+		t.Errorf("Get(charts@1.9) = %+v, %v", pinned, err)
+	}
+	if _, err := r.Get("charts@2.0.0"); !errors.Is(err, ErrCatalogNotFound) {
+		t.Errorf("Get(charts@2.0.0) error = %v, want ErrCatalogNotFound", err)
+	}
+	if list := r.List(); len(list) != 2 || list[0].ID != "charts" || list[1].ID != StandardCatalogID {
+		t.Errorf("List() = %v", list)
+	}
+
+	// 返回的是副本
+	latest.Components["Chart"].Schema["type"] = "array"
+	if again, _ := r.Get("charts"); again.Components["Chart"].Schema["type"] != "object" {
+		t.Error("Get() result shares state with the registry")
+	}
+
+	invalid := []*Catalog{
+		{ID: "bad@id", Version: "1.0.0", Components: map[string]*ComponentSchema{"Text": {}}},
+		{ID: "x", Version: "v1", Components: map[string]*ComponentSchema{"Text": {}}},
+		{ID: "x", Version: "1.0.0"},
+		{ID: "x", Version: "1.0.0", Components: map[string]*ComponentSchema{"Table": {}}},
+	}
+	for _, c := range invalid {
+		if err := r.Register(c); err == nil {
+			t.Errorf("Register(%+v) accepted", c)
+		}
+	}
+}
+
+func TestSurfaceValidatedAgainstCatalog(t *testing.T) {
+	r := NewCatalogRegistry()
+	if err := r.Register(chartCatalog("1.0.0")); err != nil {
+		t.Fatal(err)
+	}
+	m := NewSurfaceManager(&ManagerConfig{Catalogs: r})
+
+	if err := m.Apply(&types.AsterUIMessage{CreateSurface: &types.CreateSurfaceMessage{SurfaceID: "x", CatalogID: "missing"}}); err == nil {
+		t.Error("surface with unknown catalog was created")
+	}
+	if err := m.Apply(&types.AsterUIMessage{CreateSurface: &types.CreateSurfaceMessage{SurfaceID: "s", CatalogID: "charts@1.0.0"}}); err != nil {
+		t.Fatalf("createSurface error = %v", err)
+	}
+
+	valid := chartComponent(map[string]types.PropertyValue{
+		"kind":   types.NewLiteralString("bar"),
+		"series": types.NewPathReference("/series"),
+	})
+	tests := []struct {
+		name      string
+		component types.ComponentDefinition
+		wantErr   string
+	}{
+		{"valid custom component", valid, ""},
+		{"standard component in catalog", textComponent("t", "hi"), ""},
+		{"standard component not in catalog", types.ComponentDefinition{ID: "d", Component: types.ComponentSpec{Divider: &types.DividerProps{}}}, "not in catalog"},
+		{"missing required prop", chartComponent(map[string]types.PropertyValue{"kind": types.NewLiteralString("bar")}), "series"},
+		{"enum mismatch", chartComponent(map[string]types.PropertyValue{"kind": types.NewLiteralString("pie"), "series": types.NewPathReference("/s")}), "not one of"},
+		{"wrong literal type", chartComponent(map[string]types.PropertyValue{"kind": types.NewLiteralString("bar"), "series": types.NewPathReference("/s"), "height": types.NewLiteralString("tall")}), "expected number"},
+		{"unknown prop", chartComponent(map[string]types.PropertyValue{"kind": types.NewLiteralString("bar"), "series": types.NewPathReference("/s"), "color": types.NewLiteralString("red")}), "unknown property"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := m.Apply(&types.AsterUIMessage{SurfaceUpdate: &types.SurfaceUpdateMessage{
+				SurfaceID: "s", Components: []types.ComponentDefinition{tt.component},
+			}})
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Apply() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Apply() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	// beginRendering 切换目录时已有组件须符合新目录
+	err := m.Apply(&types.AsterUIMessage{BeginRendering: &types.BeginRenderingMessage{SurfaceID: "s", Root: "t", CatalogID: StandardCatalogID}})
+	if err == nil || !strings.Contains(err.Error(), "Chart") {
+		t.Errorf("switching to a catalog without Chart: error = %v", err)
+	}
+	if err := m.Apply(&types.AsterUIMessage{BeginRendering: &types.BeginRenderingMessage{SurfaceID: "s", Root: "t"}}); err != nil {
+		t.Errorf("beginRendering error = %v", err)
+	}
+}
+
+func TestStandardCatalogAcceptsStandardComponents(t *testing.T) {
+	m := NewSurfaceManager(&ManagerConfig{Catalogs: NewCatalogRegistry()})
+	newFormSurface(t, m)
+	if err := m.Apply(&types.AsterUIMessage{CreateSurface: &types.CreateSurfaceMessage{SurfaceID: "std", CatalogID: StandardCatalogID}}); err != nil {
+		t.Fatal(err)
+	}
+	err := m.Apply(&types.AsterUIMessage{SurfaceUpdate: &types.SurfaceUpdateMessage{SurfaceID: "std", Components: formComponents()}})
+	if err != nil {
+		t.Errorf("standard components rejected by standard catalog: %v", err)
+	}
+	err = m.Apply(&types.AsterUIMessage{SurfaceUpdate: &types.SurfaceUpdateMessage{SurfaceID: "std", Components: []types.ComponentDefinition{
+		chartComponent(map[string]types.PropertyValue{"kind": types.NewLiteralString("bar")}),
+	}}})
+	if err == nil {
+		t.Error("custom component accepted by standard catalog")
+	}
+}
@@ -0,0 +1,907 @@
+package uiproto
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/astercloud/aster/pkg/types"
+)
+
+// ExportFormat Surface 导出格式
+type ExportFormat string
+
+const (
+	// ExportHTML 静态 HTML，适合邮件报告与嵌入网页
+	ExportHTML ExportFormat = "html"
+	// ExportReact React 函数组件（JSX）源码
+	ExportReact ExportFormat = "react"
+)
+
+// ErrNoRoot Surface 尚未通过 beginRendering 指定根组件
+var ErrNoRoot = errors.New("surface has no root component")
+
+// ExportOptions 导出选项
+type ExportOptions struct {
+	// Fragment 只输出 Surface 根元素，不包含 HTML 文档结构与默认样式表
+	Fragment bool
+	// Title HTML 文档标题，默认为 Surface ID
+	Title string
+	// ComponentName React 组件名，默认由 Surface ID 生成
+	ComponentName string
+}
+
+// Export 把 Surface 的当前状态（组件与数据模型）导出为静态代码
+// 数据绑定按当前数据模型求值，表单控件以禁用状态展示当前值，按钮保留 data-action
+func Export(s *Surface, format ExportFormat, opts *ExportOptions) (string, error) {
+	switch format {
+	case ExportHTML:
+		return RenderHTML(s, opts)
+	case ExportReact:
+		return RenderReact(s, opts)
+	default:
+		return "", fmt.Errorf("unsupported export format %q", format)
+	}
+}
+
+// RenderHTML 把 Surface 渲染为静态 HTML
+func RenderHTML(s *Surface, opts *ExportOptions) (string, error) {
+	if opts == nil {
+		opts = &ExportOptions{}
+	}
+	root, err := buildExportTree(s)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	if opts.Fragment {
+		writeHTML(&b, root, 0)
+		return b.String(), nil
+	}
+
+	title := opts.Title
+	if title == "" {
+		title = s.ID
+	}
+	b.WriteString("<!DOCTYPE html>\n<html>\n<head>\n")
+	b.WriteString("<meta charset=\"utf-8\">\n")
+	b.WriteString("<meta name=\"viewport\" content=\"width=device-width, initial-scale=1\">\n")
+	b.WriteString("<title>" + html.EscapeString(title) + "</title>\n")
+	b.WriteString("<style>\n" + exportStylesheet + "</style>\n")
+	b.WriteString("</head>\n<body>\n")
+	writeHTML(&b, root, 0)
+	b.WriteString("</body>\n</html>\n")
+	return b.String(), nil
+}
+
+// RenderReact 把 Surface 渲染为 React 函数组件源码
+func RenderReact(s *Surface, opts *ExportOptions) (string, error) {
+	if opts == nil {
+		opts = &ExportOptions{}
+	}
+	root, err := buildExportTree(s)
+	if err != nil {
+		return "", err
+	}
+
+	name := opts.ComponentName
+	if name == "" {
+		name = componentName(s.ID)
+	}
+
+	var b strings.Builder
+	b.WriteString("import React from \"react\";\n\n")
+	if !opts.Fragment {
+		b.WriteString("const styles = `\n" + exportStylesheet + "`;\n\n")
+	}
+	b.WriteString("export default function " + name + "() {\n")
+	b.WriteString("  return (\n")
+	if opts.Fragment {
+		writeJSX(&b, root, 2)
+	} else {
+		b.WriteString("    <>\n")
+		b.WriteString("      <style>{styles}</style>\n")
+		writeJSX(&b, root, 3)
+		b.WriteString("    </>\n")
+	}
+	b.WriteString("  );\n}\n")
+	return b.String(), nil
+}
+
+// exportStylesheet 导出时使用的默认样式，类名与组件类型一一对应
+const exportStylesheet = `.aster-surface { font-family: var(--font-family, system-ui, sans-serif); color: var(--text-color, #1f2328); }
+.aster-row { display: flex; flex-direction: row; }
+.aster-column { display: flex; flex-direction: column; }
+.aster-card { border: 1px solid #d0d7de; border-radius: 8px; padding: 16px; }
+.aster-card-subtitle, .aster-caption { color: #656d76; font-size: 0.875em; }
+.aster-list { list-style: none; margin: 0; padding: 0; }
+.aster-list-dividers > li + li { border-top: 1px solid #d0d7de; }
+.aster-field { display: flex; flex-direction: column; gap: 4px; }
+.aster-button { border-radius: 6px; padding: 6px 16px; border: 1px solid #d0d7de; }
+.aster-button-primary { background: var(--primary-color, #1f6feb); border-color: var(--primary-color, #1f6feb); color: #fff; }
+.aster-button-text { background: none; border: none; }
+.aster-tab-active { font-weight: 600; border-bottom: 2px solid var(--primary-color, #1f6feb); }
+.aster-tabs-nav { display: flex; gap: 16px; margin-bottom: 8px; }
+`
+
+// exportNode 导出用的中间节点树，HTML 与 JSX 共用
+// tag 为空表示文本节点
+type exportNode struct {
+	tag      string
+	attrs    []exportAttr
+	children []*exportNode
+	text     string
+}
+
+// exportAttr 元素属性；flag 为 true 表示布尔属性
+type exportAttr struct {
+	name  string
+	value string
+	flag  bool
+}
+
+func element(tag string, attrs ...exportAttr) *exportNode {
+	return &exportNode{tag: tag, attrs: attrs}
+}
+
+func textNode(text string) *exportNode {
+	return &exportNode{text: text}
+}
+
+func attr(name, value string) exportAttr {
+	return exportAttr{name: name, value: value}
+}
+
+func flagAttr(name string) exportAttr {
+	return exportAttr{name: name, flag: true}
+}
+
+func (n *exportNode) add(children ...*exportNode) *exportNode {
+	for _, c := range children {
+		if c != nil {
+			n.children = append(n.children, c)
+		}
+	}
+	return n
+}
+
+func (n *exportNode) set(a exportAttr) {
+	if a.flag || a.value != "" {
+		n.attrs = append(n.attrs, a)
+	}
+}
+
+// exportBuilder 把组件邻接表展开为节点树
+type exportBuilder struct {
+	surface *Surface
+	// visiting 当前路径上的组件，防止循环引用
+	visiting map[string]bool
+}
+
+func buildExportTree(s *Surface) (*exportNode, error) {
+	if s == nil {
+		return nil, errors.New("surface is nil")
+	}
+	if s.Root == "" {
+		return nil, fmt.Errorf("%w: %s", ErrNoRoot, s.ID)
+	}
+
+	b := &exportBuilder{surface: s, visiting: make(map[string]bool)}
+	content, err := b.component(s.Root, "")
+	if err != nil {
+		return nil, err
+	}
+
+	root := element("div", attr("class", "aster-surface"), attr("data-surface-id", s.ID))
+	if len(s.Styles) > 0 {
+		decls := make([]string, 0, len(s.Styles))
+		for _, key := range slices.Sorted(maps.Keys(s.Styles)) {
+			name := key
+			if !strings.HasPrefix(name, "--") {
+				name = "--" + name
+			}
+			decls = append(decls, name+": "+s.Styles[key])
+		}
+		root.set(attr("style", strings.Join(decls, "; ")))
+	}
+	return root.add(content), nil
+}
+
+// component 渲染一个组件；scope 为模板实例的数据路径，相对路径基于它解析
+func (b *exportBuilder) component(id, scope string) (*exportNode, error) {
+	def, ok := b.surface.Components[id]
+	if !ok {
+		return nil, fmt.Errorf("component %q not found", id)
+	}
+	if b.visiting[id] {
+		return nil, fmt.Errorf("component %q references itself", id)
+	}
+	b.visiting[id] = true
+	defer delete(b.visiting, id)
+
+	node, err := b.spec(def.Component, scope)
+	if err != nil {
+		return nil, fmt.Errorf("component %q: %w", id, err)
+	}
+	if node != nil && node.tag != "" {
+		node.attrs = append([]exportAttr{attr("data-component-id", id)}, node.attrs...)
+	}
+	return node, nil
+}
+
+func (b *exportBuilder) spec(spec types.ComponentSpec, scope string) (*exportNode, error) {
+	switch {
+	case spec.Text != nil:
+		tag, class := "p", "aster-text"
+		switch hint := spec.Text.UsageHint; hint {
+		case types.TextUsageHintH1, types.TextUsageHintH2, types.TextUsageHintH3, types.TextUsageHintH4, types.TextUsageHintH5:
+			tag = string(hint)
+		case types.TextUsageHintCaption:
+			tag, class = "small", "aster-caption"
+		}
+		return element(tag, attr("class", class)).add(textNode(b.str(&spec.Text.Text, scope))), nil
+
+	case spec.Image != nil:
+		img := element("img", attr("class", "aster-image"), attr("src", b.str(&spec.Image.Src, scope)))
+		img.set(attr("alt", b.str(spec.Image.Alt, scope)))
+		if spec.Image.UsageHint != "" {
+			img.attrs[0].value += " aster-image-" + string(spec.Image.UsageHint)
+		}
+		return img, nil
+
+	case spec.Icon != nil:
+		name := b.str(&spec.Icon.Name, scope)
+		icon := element("span", attr("class", "aster-icon"), attr("data-icon", name), attr("title", name))
+		var style []string
+		if size := b.str(spec.Icon.Size, scope); size != "" {
+			if _, err := strconv.ParseFloat(size, 64); err == nil {
+				size += "px"
+			}
+			style = append(style, "font-size: "+size)
+		}
+		if color := b.str(spec.Icon.Color, scope); color != "" {
+			style = append(style, "color: "+color)
+		}
+		icon.set(attr("style", strings.Join(style, "; ")))
+		return icon, nil
+
+	case spec.Video != nil:
+		v := spec.Video
+		video := element("video", attr("class", "aster-video"), attr("src", b.str(&v.Src, scope)))
+		video.set(attr("poster", b.str(v.Poster, scope)))
+		if v.Controls == nil || b.truthy(v.Controls, scope) {
+			video.set(flagAttr("controls"))
+		}
+		if b.truthy(v.Autoplay, scope) {
+			video.set(flagAttr("autoplay"))
+		}
+		if b.truthy(v.Loop, scope) {
+			video.set(flagAttr("loop"))
+		}
+		if b.truthy(v.Muted, scope) {
+			video.set(flagAttr("muted"))
+		}
+		return video, nil
+
+	case spec.AudioPlayer != nil:
+		a := spec.AudioPlayer
+		figure := element("figure", attr("class", "aster-audio"))
+		if title := b.str(a.Title, scope); title != "" {
+			figure.add(element("figcaption").add(textNode(title)))
+		}
+		audio := element("audio", attr("src", b.str(&a.Src, scope)), flagAttr("controls"))
+		if b.truthy(a.Autoplay, scope) {
+			audio.set(flagAttr("autoplay"))
+		}
+		if b.truthy(a.Loop, scope) {
+			audio.set(flagAttr("loop"))
+		}
+		return figure.add(audio), nil
+
+	case spec.Button != nil:
+		bt := spec.Button
+		class := "aster-button"
+		if bt.Variant != "" {
+			class += " aster-button-" + string(bt.Variant)
+		}
+		button := element("button", attr("type", "button"), attr("class", class), attr("data-action", bt.Action))
+		if b.truthy(bt.Disabled, scope) {
+			button.set(flagAttr("disabled"))
+		}
+		return button.add(textNode(b.str(&bt.Label, scope))), nil
+
+	case spec.Row != nil:
+		style := flexStyle(spec.Row.Gap, spec.Row.Align)
+		if spec.Row.Wrap != nil && *spec.Row.Wrap {
+			style = append(style, "flex-wrap: wrap")
+		}
+		return b.container(element("div", attr("class", "aster-row")), style, spec.Row.Children, scope, "")
+
+	case spec.Column != nil:
+		return b.container(element("div", attr("class", "aster-column")), flexStyle(spec.Column.Gap, spec.Column.Align), spec.Column.Children, scope, "")
+
+	case spec.Card != nil:
+		card := element("section", attr("class", "aster-card"))
+		card.set(attr("data-action", spec.Card.Action))
+		if title := b.str(spec.Card.Title, scope); title != "" {
+			card.add(element("h3", attr("class", "aster-card-title")).add(textNode(title)))
+		}
+		if subtitle := b.str(spec.Card.Subtitle, scope); subtitle != "" {
+			card.add(element("p", attr("class", "aster-card-subtitle")).add(textNode(subtitle)))
+		}
+		return b.container(card, nil, spec.Card.Children, scope, "")
+
+	case spec.List != nil:
+		class := "aster-list"
+		if spec.List.Dividers != nil && *spec.List.Dividers {
+			class += " aster-list-dividers"
+		}
+		return b.container(element("ul", attr("class", class)), nil, spec.List.Children, scope, "li")
+
+	case spec.TextField != nil:
+		tf := spec.TextField
+		var input *exportNode
+		if tf.Multiline != nil && *tf.Multiline {
+			input = element("textarea").add(textNode(b.str(&tf.Value, scope)))
+		} else {
+			inputType := string(tf.InputType)
+			if inputType == "" {
+				inputType = "text"
+			}
+			input = element("input", attr("type", inputType), attr("value", b.str(&tf.Value, scope)))
+		}
+		input.set(attr("placeholder", b.str(tf.Placeholder, scope)))
+		if tf.MaxLength != nil {
+			input.set(attr("maxlength", strconv.Itoa(*tf.MaxLength)))
+		}
+		input.set(flagAttr("disabled"))
+		return b.field(tf.Label, scope, input), nil
+
+	case spec.Checkbox != nil:
+		input := element("input", attr("type", "checkbox"))
+		if b.truthy(&spec.Checkbox.Checked, scope) {
+			input.set(flagAttr("checked"))
+		}
+		input.set(flagAttr("disabled"))
+		label := element("label", attr("class", "aster-checkbox")).add(input)
+		if text := b.str(spec.Checkbox.Label, scope); text != "" {
+			label.add(textNode(" " + text))
+		}
+		return label, nil
+
+	case spec.Select != nil:
+		sel := element("select")
+		if spec.Select.Multiple != nil && *spec.Select.Multiple {
+			sel.set(flagAttr("multiple"))
+		}
+		sel.set(flagAttr("disabled"))
+		selected := selectedValues(b.value(&spec.Select.Value, scope))
+		if placeholder := b.str(spec.Select.Placeholder, scope); placeholder != "" && len(selected) == 0 {
+			sel.add(element("option", attr("value", ""), flagAttr("selected")).add(textNode(placeholder)))
+		}
+		for _, opt := range choiceOptions(b.value(&spec.Select.Options, scope)) {
+			option := element("option", attr("value", opt.value))
+			if selected[opt.value] {
+				option.set(flagAttr("selected"))
+			}
+			sel.add(option.add(textNode(opt.label)))
+		}
+		return b.field(spec.Select.Label, scope, sel), nil
+
+	case spec.DateTimeInput != nil:
+		dt := spec.DateTimeInput
+		inputType := "date"
+		switch dt.Type {
+		case types.DateTimeInputTypeTime:
+			inputType = "time"
+		case types.DateTimeInputTypeDatetime:
+			inputType = "datetime-local"
+		}
+		input := element("input", attr("type", inputType), attr("value", b.str(&dt.Value, scope)))
+		input.set(attr("min", b.str(dt.Min, scope)))
+		input.set(attr("max", b.str(dt.Max, scope)))
+		input.set(flagAttr("disabled"))
+		return b.field(dt.Label, scope, input), nil
+
+	case spec.Slider != nil:
+		sl := spec.Slider
+		value := b.str(&sl.Value, scope)
+		input := element("input", attr("type", "range"))
+		for _, bound := range []struct {
+			name  string
+			value *float64
+		}{{"min", sl.Min}, {"max", sl.Max}, {"step", sl.Step}} {
+			if bound.value != nil {
+				input.set(attr(bound.name, formatNumber(*bound.value)))
+			}
+		}
+		input.set(attr("value", value))
+		input.set(flagAttr("disabled"))
+		field := b.field(sl.Label, scope, input)
+		if sl.ShowValue != nil && *sl.ShowValue {
+			field.add(element("output").add(textNode(value)))
+		}
+		return field, nil
+
+	case spec.MultipleChoice != nil:
+		mc := spec.MultipleChoice
+		class := "aster-choice"
+		if mc.Direction == types.MultipleChoiceDirectionHorizontal {
+			class += " aster-row"
+		} else {
+			class += " aster-column"
+		}
+		fieldset := element("fieldset", attr("class", class))
+		if label := b.str(mc.Label, scope); label != "" {
+			fieldset.add(element("legend").add(textNode(label)))
+		}
+		selected := selectedValues(b.value(&mc.Value, scope))
+		for _, opt := range choiceOptions(b.value(&mc.Options, scope)) {
+			input := element("input", attr("type", "checkbox"), attr("value", opt.value))
+			if selected[opt.value] {
+				input.set(flagAttr("checked"))
+			}
+			input.set(flagAttr("disabled"))
+			label := element("label").add(input, textNode(" "+opt.label))
+			if opt.description != "" {
+				label.add(element("small", attr("class", "aster-caption")).add(textNode(" " + opt.description)))
+			}
+			fieldset.add(label)
+		}
+		return fieldset, nil
+
+	case spec.Divider != nil:
+		hr := element("hr", attr("class", "aster-divider"))
+		if spec.Divider.Orientation == types.DividerOrientationVertical {
+			hr.set(attr("aria-orientation", "vertical"))
+		}
+		return hr, nil
+
+	case spec.Modal != nil:
+		// 只导出处于打开状态的模态框
+		if !b.truthy(&spec.Modal.Open, scope) {
+			return nil, nil
+		}
+		dialog := element("dialog", attr("class", "aster-modal"), flagAttr("open"))
+		if title := b.str(spec.Modal.Title, scope); title != "" {
+			dialog.add(element("h2").add(textNode(title)))
+		}
+		return b.container(dialog, nil, spec.Modal.Children, scope, "")
+
+	case spec.Tabs != nil:
+		// 标签栏完整导出，内容只导出当前激活的标签页
+		tabs := spec.Tabs.Tabs
+		if len(tabs) == 0 {
+			return element("div", attr("class", "aster-tabs")), nil
+		}
+		active := b.str(&spec.Tabs.ActiveTab, scope)
+		activeIdx := slices.IndexFunc(tabs, func(t types.TabDefinition) bool { return t.ID == active })
+		if activeIdx < 0 {
+			activeIdx = 0
+		}
+		nav := element("div", attr("class", "aster-tabs-nav"), attr("role", "tablist"))
+		for i, tab := range tabs {
+			class := "aster-tab"
+			if i == activeIdx {
+				class += " aster-tab-active"
+			}
+			nav.add(element("span", attr("class", class), attr("role", "tab"), attr("data-tab-id", tab.ID)).add(textNode(b.str(&tab.Label, scope))))
+		}
+		panel := element("div", attr("class", "aster-tab-panel"), attr("role", "tabpanel"), attr("data-tab-id", tabs[activeIdx].ID))
+		panel, err := b.container(panel, nil, tabs[activeIdx].Content, scope, "")
+		if err != nil {
+			return nil, err
+		}
+		return element("div", attr("class", "aster-tabs")).add(nav, panel), nil
+
+	case spec.Custom != nil:
+		// 自定义组件无法静态渲染，保留类型与解析后的属性供宿主页面接管
+		props := make(map[string]any, len(spec.Custom.Props))
+		for key, pv := range spec.Custom.Props {
+			props[key] = b.value(&pv, scope)
+		}
+		data, err := json.Marshal(props)
+		if err != nil {
+			return nil, err
+		}
+		return element("div", attr("class", "aster-custom"), attr("data-component", spec.Custom.Type), attr("data-props", string(data))), nil
+	}
+	return nil, errors.New("component has no type")
+}
+
+// container 追加子组件；wrap 非空时每个子组件包裹在该标签中
+func (b *exportBuilder) container(n *exportNode, style []string, ref types.ComponentArrayReference, scope, wrap string) (*exportNode, error) {
+	n.set(attr("style", strings.Join(style, "; ")))
+	children, err := b.children(ref, scope)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range children {
+		if wrap != "" {
+			c = element(wrap).add(c)
+		}
+		n.add(c)
+	}
+	return n, nil
+}
+
+// children 展开显式列表与模板；模板按数据数组逐项实例化
+func (b *exportBuilder) children(ref types.ComponentArrayReference, scope string) ([]*exportNode, error) {
+	var out []*exportNode
+	for _, id := range ref.ExplicitList {
+		c, err := b.component(id, scope)
+		if err != nil {
+			return nil, err
+		}
+		if c != nil {
+			out = append(out, c)
+		}
+	}
+	if t := ref.Template; t != nil {
+		binding := b.path(t.DataBinding, scope)
+		items, _ := Resolve(b.surface.DataModel, binding)
+		list, _ := items.([]any)
+		for i := range list {
+			c, err := b.component(t.ComponentID, binding+"/"+strconv.Itoa(i))
+			if err != nil {
+				return nil, err
+			}
+			if c != nil {
+				out = append(out, c)
+			}
+		}
+	}
+	return out, nil
+}
+
+// field 生成带标签的表单控件
+func (b *exportBuilder) field(label *types.PropertyValue, scope string, control *exportNode) *exportNode {
+	n := element("label", attr("class", "aster-field"))
+	if text := b.str(label, scope); text != "" {
+		n.add(element("span").add(textNode(text)))
+	}
+	return n.add(control)
+}
+
+// path 把模板内的相对路径解析为绝对路径，与前端渲染器一致
+func (b *exportBuilder) path(p, scope string) string {
+	if scope == "" || strings.HasPrefix(p, "/") {
+		return p
+	}
+	if p == "" || p == "." {
+		return scope
+	}
+	return scope + "/" + p
+}
+
+func (b *exportBuilder) value(pv *types.PropertyValue, scope string) any {
+	if pv == nil {
+		return nil
+	}
+	if pv.IsPathReference() {
+		v, err := Resolve(b.surface.DataModel, b.path(*pv.Path, scope))
+		if err != nil {
+			return nil
+		}
+		return v
+	}
+	return resolveProperty(nil, *pv)
+}
+
+func (b *exportBuilder) str(pv *types.PropertyValue, scope string) string {
+	return displayValue(b.value(pv, scope))
+}
+
+func (b *exportBuilder) truthy(pv *types.PropertyValue, scope string) bool {
+	v, _ := b.value(pv, scope).(bool)
+	return v
+}
+
+func flexStyle(gap *int, align types.Alignment) []string {
+	var style []string
+	if gap != nil {
+		style = append(style, "gap: "+strconv.Itoa(*gap)+"px")
+	}
+	switch align {
+	case types.AlignmentStart:
+		style = append(style, "align-items: flex-start")
+	case types.AlignmentEnd:
+		style = append(style, "align-items: flex-end")
+	case types.AlignmentCenter, types.AlignmentStretch:
+		style = append(style, "align-items: "+string(align))
+	}
+	return style
+}
+
+type choiceOption struct {
+	value, label, description string
+}
+
+// choiceOptions 解析 Select/MultipleChoice 的选项：字符串数组或 {value, label} 对象数组
+func choiceOptions(v any) []choiceOption {
+	list, _ := v.([]any)
+	out := make([]choiceOption, 0, len(list))
+	for _, item := range list {
+		switch o := item.(type) {
+		case map[string]any:
+			opt := choiceOption{value: displayValue(o["value"]), label: displayValue(o["label"]), description: displayValue(o["description"])}
+			if opt.label == "" {
+				opt.label = opt.value
+			}
+			out = append(out, opt)
+		default:
+			s := displayValue(o)
+			out = append(out, choiceOption{value: s, label: s})
+		}
+	}
+	return out
+}
+
+func selectedValues(v any) map[string]bool {
+	out := make(map[string]bool)
+	if list, ok := v.([]any); ok {
+		for _, item := range list {
+			out[displayValue(item)] = true
+		}
+	} else if v != nil {
+		out[displayValue(v)] = true
+	}
+	return out
+}
+
+// displayValue 把数据模型中的值转换为展示文本
+func displayValue(v any) string {
+	switch x := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return x
+	case float64:
+		return formatNumber(x)
+	case bool:
+		return strconv.FormatBool(x)
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+func formatNumber(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// componentName 由 Surface ID 生成合法的 React 组件名
+func componentName(surfaceID string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range surfaceID {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	name := b.String()
+	if name == "" || !unicode.IsLetter([]rune(name)[0]) {
+		name = "Surface" + name
+	}
+	return name
+}
+
+// voidElements 没有结束标签的 HTML 元素
+var voidElements = map[string]bool{"img": true, "input": true, "hr": true}
+
+func writeHTML(b *strings.Builder, n *exportNode, depth int) {
+	indent := strings.Repeat("  ", depth)
+	if n.tag == "" {
+		b.WriteString(indent + html.EscapeString(n.text) + "\n")
+		return
+	}
+	b.WriteString(indent + "<" + n.tag)
+	for _, a := range n.attrs {
+		if a.flag {
+			b.WriteString(" " + a.name)
+		} else {
+			b.WriteString(" " + a.name + "=\"" + html.EscapeString(a.value) + "\"")
+		}
+	}
+	b.WriteString(">")
+	if voidElements[n.tag] {
+		b.WriteString("\n")
+		return
+	}
+	if inlineChildren(n) {
+		for _, c := range n.children {
+			b.WriteString(html.EscapeString(c.text))
+		}
+		b.WriteString("</" + n.tag + ">\n")
+		return
+	}
+	b.WriteString("\n")
+	for _, c := range n.children {
+		writeHTML(b, c, depth+1)
+	}
+	b.WriteString(indent + "</" + n.tag + ">\n")
+}
+
+// inlineChildren 只有文本子节点的元素写在同一行
+func inlineChildren(n *exportNode) bool {
+	for _, c := range n.children {
+		if c.tag != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// jsxAttrNames HTML 属性到 React 属性名的映射
+var jsxAttrNames = map[string]string{
+	"class":     "className",
+	"maxlength": "maxLength",
+	"autoplay":  "autoPlay",
+	"checked":   "defaultChecked",
+}
+
+func writeJSX(b *strings.Builder, n *exportNode, depth int) {
+	indent := strings.Repeat("  ", depth)
+	if n.tag == "" {
+		b.WriteString(indent + jsxText(n.text) + "\n")
+		return
+	}
+
+	attrs := n.attrs
+	children := n.children
+	switch n.tag {
+	case "textarea":
+		// React 不允许 textarea 子节点，内容改为 defaultValue
+		if len(children) > 0 {
+			attrs = append(slices.Clone(attrs), attr("value", children[0].text))
+		}
+		children = nil
+	case "select":
+		// 选中状态由 select 的 defaultValue 表示
+		var selected []string
+		children = make([]*exportNode, 0, len(n.children))
+		for _, c := range n.children {
+			opt := *c
+			opt.attrs = slices.DeleteFunc(slices.Clone(c.attrs), func(a exportAttr) bool {
+				if a.name == "selected" {
+					selected = append(selected, attrValue(c.attrs, "value"))
+					return true
+				}
+				return false
+			})
+			children = append(children, &opt)
+		}
+		if len(selected) > 0 {
+			attrs = slices.Clone(attrs)
+			if slices.ContainsFunc(attrs, func(a exportAttr) bool { return a.name == "multiple" }) {
+				data, _ := json.Marshal(selected)
+				attrs = append(attrs, exportAttr{name: "value", value: "{" + string(data) + "}", flag: true})
+			} else {
+				attrs = append(attrs, attr("value", selected[0]))
+			}
+		}
+	}
+
+	// 表单控件的当前值是非受控初始值
+	uncontrolled := n.tag == "textarea" || n.tag == "select" ||
+		(n.tag == "input" && attrValue(attrs, "type") != "checkbox")
+
+	b.WriteString(indent + "<" + n.tag)
+	for _, a := range attrs {
+		b.WriteString(" " + jsxAttr(a, uncontrolled))
+	}
+	if len(children) == 0 {
+		b.WriteString(" />\n")
+		return
+	}
+	b.WriteString(">")
+	if inlineChildren(&exportNode{children: children}) {
+		for _, c := range children {
+			b.WriteString(jsxText(c.text))
+		}
+		b.WriteString("</" + n.tag + ">\n")
+		return
+	}
+	b.WriteString("\n")
+	for _, c := range children {
+		writeJSX(b, c, depth+1)
+	}
+	b.WriteString(indent + "</" + n.tag + ">\n")
+}
+
+func jsxAttr(a exportAttr, uncontrolled bool) string {
+	name := a.name
+	if mapped, ok := jsxAttrNames[name]; ok {
+		name = mapped
+	} else if name == "value" && uncontrolled {
+		name = "defaultValue"
+	}
+	switch {
+	case a.flag && a.value != "":
+		// 预先生成的表达式
+		return name + "=" + a.value
+	case a.flag:
+		return name
+	case name == "style":
+		return name + "={" + jsxStyle(a.value) + "}"
+	}
+	if jsxSafe(a.value) {
+		return name + "=\"" + a.value + "\""
+	}
+	return name + "=" + jsxString(a.value)
+}
+
+// jsxStyle 把 CSS 声明转换为 React style 对象字面量
+func jsxStyle(css string) string {
+	var parts []string
+	for _, decl := range strings.Split(css, ";") {
+		prop, value, ok := strings.Cut(decl, ":")
+		if !ok {
+			continue
+		}
+		prop = strings.TrimSpace(prop)
+		key := strconv.Quote(prop)
+		if !strings.HasPrefix(prop, "--") {
+			key = camelCase(prop)
+		}
+		parts = append(parts, key+": "+strconv.Quote(strings.TrimSpace(value)))
+	}
+	return "{" + strings.Join(parts, ", ") + "}"
+}
+
+func camelCase(s string) string {
+	words := strings.Split(s, "-")
+	for i := 1; i < len(words); i++ {
+		if words[i] != "" {
+			words[i] = strings.ToUpper(words[i][:1]) + words[i][1:]
+		}
+	}
+	return strings.Join(words, "")
+}
+
+// jsxString 生成 JSX 字符串表达式，JSON 编码保证任意文本都安全
+func jsxString(s string) string {
+	var buf strings.Builder
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(s)
+	return "{" + strings.TrimSuffix(buf.String(), "\n") + "}"
+}
+
+// jsxText 生成文本子节点；含特殊字符或首尾空白（JSX 会裁剪）时使用表达式
+func jsxText(s string) string {
+	if jsxSafe(s) && !strings.ContainsAny(s, "\"\t\r") && strings.TrimSpace(s) == s {
+		return s
+	}
+	return jsxString(s)
+}
+
+// jsxSafe 文本可原样写入 JSX 字符串属性
+// JSX 属性与文本会解码 HTML 实体，且不能包含花括号与尖括号
+func jsxSafe(s string) bool {
+	return !strings.ContainsAny(s, "\"{}<>&\\\n")
+}
+
+func attrValue(attrs []exportAttr, name string) string {
+	for _, a := range attrs {
+		if a.name == name {
+			return a.value
+		}
+	}
+	return ""
+}
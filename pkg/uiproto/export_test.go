@@ -0,0 +1,111 @@
+package uiproto
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/astercloud/aster/pkg/types"
+)
+
+// reportSurface 表格报告：List 模板绑定数组，模板内使用相对路径
+func reportSurface(t *testing.T) *Surface {
+	t.Helper()
+	m := NewSurfaceManager(nil)
+	newFormSurface(t, m)
+
+	b := true
+	msgs := []*types.AsterUIMessage{
+		{SurfaceUpdate: &types.SurfaceUpdateMessage{SurfaceID: "s1", Components: []types.ComponentDefinition{
+			{ID: "root", Component: types.ComponentSpec{Column: &types.ColumnProps{
+				Children: types.ComponentArrayReference{ExplicitList: []string{"title", "name", "rows", "submit"}},
+			}}},
+			{ID: "title", Component: types.ComponentSpec{Text: &types.TextProps{
+				Text: types.NewLiteralString("Q3 <Report>"), UsageHint: types.TextUsageHintH1,
+			}}},
+			{ID: "rows", Component: types.ComponentSpec{List: &types.ListProps{
+				Dividers: &b,
+				Children: types.ComponentArrayReference{Template: &types.ComponentTemplate{ComponentID: "row", DataBinding: "/rows"}},
+			}}},
+			{ID: "row", Component: types.ComponentSpec{Row: &types.RowProps{
+				Children: types.ComponentArrayReference{ExplicitList: []string{"cell"}},
+			}}},
+			{ID: "cell", Component: types.ComponentSpec{Text: &types.TextProps{Text: types.NewPathReference("region")}}},
+		}}},
+		{DataModelUpdate: &types.DataModelUpdateMessage{SurfaceID: "s1", Path: "/rows", Contents: []any{
+			map[string]any{"region": "EMEA"},
+			map[string]any{"region": "APAC"},
+		}}},
+	}
+	for _, msg := range msgs {
+		if err := m.Apply(msg); err != nil {
+			t.Fatalf("Apply() error = %v", err)
+		}
+	}
+	s, _ := m.Surface("s1")
+	s.Styles = map[string]string{"primary-color": "#ff0000"}
+	return s
+}
+
+func TestRenderHTML(t *testing.T) {
+	out, err := RenderHTML(reportSurface(t), &ExportOptions{Title: "Weekly"})
+	if err != nil {
+		t.Fatalf("RenderHTML() error = %v", err)
+	}
+	for _, want := range []string{
+		"<!DOCTYPE html>",
+		"<title>Weekly</title>",
+		`<div class="aster-surface" data-surface-id="s1" style="--primary-color: #ff0000">`,
+		`<h1 data-component-id="title" class="aster-text">Q3 &lt;Report&gt;</h1>`,
+		`<input type="text" value="Ada" disabled>`,
+		`<ul data-component-id="rows" class="aster-list aster-list-dividers">`,
+		`<p data-component-id="cell" class="aster-text">EMEA</p>`,
+		`<p data-component-id="cell" class="aster-text">APAC</p>`,
+		`<button data-component-id="submit" type="button" class="aster-button" data-action="save">Save</button>`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("HTML missing %q\n%s", want, out)
+		}
+	}
+
+	fragment, err := RenderHTML(reportSurface(t), &ExportOptions{Fragment: true})
+	if err != nil || strings.Contains(fragment, "<html>") || !strings.HasPrefix(fragment, `<div class="aster-surface"`) {
+		t.Errorf("fragment = %q, %v", fragment, err)
+	}
+}
+
+func TestRenderReact(t *testing.T) {
+	out, err := Export(reportSurface(t), ExportReact, nil)
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	for _, want := range []string{
+		"export default function S1() {",
+		"<style>{styles}</style>",
+		`<div className="aster-surface" data-surface-id="s1" style={{"--primary-color": "#ff0000"}}>`,
+		`<h1 data-component-id="title" className="aster-text">{"Q3 <Report>"}</h1>`,
+		`<input type="text" defaultValue="Ada" disabled />`,
+		`<p data-component-id="cell" className="aster-text">APAC</p>`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("JSX missing %q\n%s", want, out)
+		}
+	}
+}
+
+func TestExportErrors(t *testing.T) {
+	m := NewSurfaceManager(nil)
+	if err := m.Apply(&types.AsterUIMessage{CreateSurface: &types.CreateSurfaceMessage{SurfaceID: "draft"}}); err != nil {
+		t.Fatal(err)
+	}
+	s, _ := m.Surface("draft")
+	if _, err := RenderHTML(s, nil); !errors.Is(err, ErrNoRoot) {
+		t.Errorf("RenderHTML() without root error = %v, want ErrNoRoot", err)
+	}
+	if _, err := Export(reportSurface(t), "pdf", nil); err == nil {
+		t.Error("Export() accepted unsupported format")
+	}
+	if got := componentName("2024-q3 report"); got != "Surface2024Q3Report" {
+		t.Errorf("componentName() = %q", got)
+	}
+}
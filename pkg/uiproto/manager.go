@@ -0,0 +1,524 @@
+// Package uiproto 提供 Aster UI 协议的服务端运行时
+// SurfaceManager 校验服务端下发的 UI 消息，维护每个 Surface 的组件与数据模型，
+// 并把客户端的 UserAction 分发回 Agent
+package uiproto
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/astercloud/aster/pkg/events"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+// ErrSurfaceDeleted 等待动作期间 Surface 被删除
+var ErrSurfaceDeleted = errors.New("surface deleted")
+
+// ActionHandler 处理校验通过的用户动作
+type ActionHandler func(ctx context.Context, action *types.UserActionMessage) error
+
+// ClientErrorHandler 处理客户端上报的协议错误
+type ClientErrorHandler func(ctx context.Context, err *types.ProtocolError)
+
+// UpdateHandler 在服务端消息成功应用后调用，用于把消息转发给渲染端
+type UpdateHandler func(msg *types.AsterUIMessage)
+
+// ManagerConfig SurfaceManager 配置
+type ManagerConfig struct {
+	// OnAction 用户动作回调（通常使用 EventBusActionHandler 转发给 Agent）
+	OnAction ActionHandler
+	// OnClientError 客户端错误回调（可选）
+	OnClientError ClientErrorHandler
+	// OnUpdate 消息应用成功后的回调（可选）
+	OnUpdate UpdateHandler
+	// CustomTypes 允许的自定义组件类型；为空时不限制
+	CustomTypes []string
+	// Catalogs 组件目录注册表（可选）
+	// 设置后 Surface 引用的 CatalogID 必须已注册，组件须属于该目录并符合其 Schema
+	Catalogs *CatalogRegistry
+}
+
+// Surface 一个 Surface 的当前状态
+type Surface struct {
+	ID         string                               `json:"surfaceId"`
+	CatalogID  string                               `json:"catalogId,omitempty"`
+	Components map[string]types.ComponentDefinition `json:"components"`
+	Root       string                               `json:"root,omitempty"`
+	Styles     map[string]string                    `json:"styles,omitempty"`
+	Rendering  bool                                 `json:"rendering"`
+	DataModel  map[string]any                       `json:"dataModel"`
+	// Version 每次成功应用消息后递增
+	Version uint64 `json:"version"`
+}
+
+// clone 返回可安全交给调用方的副本
+func (s *Surface) clone() *Surface {
+	out := *s
+	out.Components = maps.Clone(s.Components)
+	out.Styles = maps.Clone(s.Styles)
+	out.DataModel = cloneValue(s.DataModel).(map[string]any)
+	return &out
+}
+
+// SurfaceManager 维护服务端驱动 UI 会话的状态
+type SurfaceManager struct {
+	mu       sync.RWMutex
+	surfaces map[string]*Surface
+	waiters  map[string][]chan *types.UserActionMessage // surfaceID -> WaitAction 等待者
+	config   ManagerConfig
+}
+
+// NewSurfaceManager 创建 SurfaceManager
+func NewSurfaceManager(config *ManagerConfig) *SurfaceManager {
+	m := &SurfaceManager{
+		surfaces: make(map[string]*Surface),
+		waiters:  make(map[string][]chan *types.UserActionMessage),
+	}
+	if config != nil {
+		m.config = *config
+	}
+	return m
+}
+
+// ApplyJSON 解析并应用一条服务端 UI 消息
+func (m *SurfaceManager) ApplyJSON(data []byte) error {
+	msg, err := types.DecodeUIMessage(data)
+	if err != nil {
+		return types.NewValidationError("", "", fmt.Sprintf("invalid message: %v", err))
+	}
+	return m.Apply(msg)
+}
+
+// Apply 校验并应用一条服务端 UI 消息
+// 校验失败时返回 *types.ProtocolError，Surface 状态保持不变
+func (m *SurfaceManager) Apply(msg *types.AsterUIMessage) error {
+	if msg == nil {
+		return types.NewValidationError("", "", "message is nil")
+	}
+	if n := countOperations(msg); n != 1 {
+		return types.NewValidationError("", "", fmt.Sprintf("message must contain exactly one operation, got %d", n))
+	}
+
+	m.mu.Lock()
+	var err error
+	switch {
+	case msg.CreateSurface != nil:
+		err = m.createSurfaceLocked(msg.CreateSurface)
+	case msg.SurfaceUpdate != nil:
+		err = m.surfaceUpdateLocked(msg.SurfaceUpdate, false)
+	case msg.DataModelUpdate != nil:
+		err = m.dataModelUpdateLocked(msg.DataModelUpdate, false)
+	case msg.BeginRendering != nil:
+		err = m.beginRenderingLocked(msg.BeginRendering)
+	case msg.DeleteSurface != nil:
+		err = m.deleteSurfaceLocked(msg.DeleteSurface.SurfaceID)
+	}
+	m.mu.Unlock()
+
+	if err == nil && m.config.OnUpdate != nil {
+		m.config.OnUpdate(msg)
+	}
+	return err
+}
+
+// ApplyEvent 应用 Agent 发出的 UI 进度事件
+// 事件没有 createSurface 阶段，缺失的 Surface 会自动创建；非 UI 事件返回 false
+func (m *SurfaceManager) ApplyEvent(event any) (bool, error) {
+	msgs, ok := EventMessages(event)
+	if !ok {
+		return false, nil
+	}
+
+	for _, msg := range msgs {
+		m.mu.Lock()
+		var err error
+		switch {
+		case msg.SurfaceUpdate != nil:
+			err = m.surfaceUpdateLocked(msg.SurfaceUpdate, true)
+		case msg.DataModelUpdate != nil:
+			err = m.dataModelUpdateLocked(msg.DataModelUpdate, true)
+		case msg.BeginRendering != nil:
+			err = m.beginRenderingLocked(msg.BeginRendering)
+		case msg.DeleteSurface != nil:
+			err = m.deleteSurfaceLocked(msg.DeleteSurface.SurfaceID)
+		}
+		m.mu.Unlock()
+		if err != nil {
+			return true, err
+		}
+		if m.config.OnUpdate != nil {
+			m.config.OnUpdate(msg)
+		}
+	}
+	return true, nil
+}
+
+// EventMessages 把 Agent 的 UI 进度事件转换为等价的服务端 UI 消息
+// 非 UI 事件返回 false
+func EventMessages(event any) ([]*types.AsterUIMessage, bool) {
+	var msgs []*types.AsterUIMessage
+	switch e := event.(type) {
+	case *types.ProgressUISurfaceUpdateEvent:
+		msgs = append(msgs, &types.AsterUIMessage{SurfaceUpdate: &types.SurfaceUpdateMessage{
+			SurfaceID:  e.SurfaceID,
+			Components: e.Components,
+		}})
+		if e.Root != "" {
+			msgs = append(msgs, &types.AsterUIMessage{BeginRendering: &types.BeginRenderingMessage{
+				SurfaceID: e.SurfaceID,
+				Root:      e.Root,
+				Styles:    e.Styles,
+			}})
+		}
+	case *types.ProgressUIDataUpdateEvent:
+		msgs = append(msgs, &types.AsterUIMessage{DataModelUpdate: &types.DataModelUpdateMessage{
+			SurfaceID: e.SurfaceID,
+			Path:      e.Path,
+			Contents:  e.Contents,
+		}})
+	case *types.ProgressUIDeleteSurfaceEvent:
+		msgs = append(msgs, &types.AsterUIMessage{DeleteSurface: &types.DeleteSurfaceMessage{SurfaceID: e.SurfaceID}})
+	default:
+		return nil, false
+	}
+	return msgs, true
+}
+
+func countOperations(msg *types.AsterUIMessage) int {
+	n := 0
+	for _, set := range []bool{
+		msg.CreateSurface != nil,
+		msg.SurfaceUpdate != nil,
+		msg.DataModelUpdate != nil,
+		msg.BeginRendering != nil,
+		msg.DeleteSurface != nil,
+	} {
+		if set {
+			n++
+		}
+	}
+	return n
+}
+
+func (m *SurfaceManager) createSurfaceLocked(msg *types.CreateSurfaceMessage) error {
+	if msg.SurfaceID == "" {
+		return types.NewValidationError("", "/surfaceId", "surfaceId is required")
+	}
+	if _, ok := m.surfaces[msg.SurfaceID]; ok {
+		return types.NewValidationError(msg.SurfaceID, "/surfaceId", "surface already exists")
+	}
+	if _, err := m.catalog(msg.SurfaceID, msg.CatalogID, "/catalogId"); err != nil {
+		return err
+	}
+	m.surfaces[msg.SurfaceID] = newSurface(msg.SurfaceID, msg.CatalogID)
+	return nil
+}
+
+func newSurface(id, catalogID string) *Surface {
+	return &Surface{
+		ID:         id,
+		CatalogID:  catalogID,
+		Components: make(map[string]types.ComponentDefinition),
+		DataModel:  make(map[string]any),
+		Version:    1,
+	}
+}
+
+// surfaceLocked 返回 Surface；create 为 true 时自动创建
+func (m *SurfaceManager) surfaceLocked(id string, create bool) (*Surface, error) {
+	if id == "" {
+		return nil, types.NewValidationError("", "/surfaceId", "surfaceId is required")
+	}
+	s, ok := m.surfaces[id]
+	if !ok {
+		if !create {
+			return nil, types.NewValidationError(id, "/surfaceId", "unknown surface")
+		}
+		s = newSurface(id, "")
+		m.surfaces[id] = s
+	}
+	return s, nil
+}
+
+func (m *SurfaceManager) surfaceUpdateLocked(msg *types.SurfaceUpdateMessage, create bool) error {
+	// 先校验再创建，避免无效消息留下空 Surface
+	var catalog *Catalog
+	if s, ok := m.surfaces[msg.SurfaceID]; ok {
+		c, err := m.catalog(s.ID, s.CatalogID, "/catalogId")
+		if err != nil {
+			return err
+		}
+		catalog = c
+	}
+	if err := m.validateComponents(msg.SurfaceID, msg.Components, catalog); err != nil {
+		return err
+	}
+	s, err := m.surfaceLocked(msg.SurfaceID, create)
+	if err != nil {
+		return err
+	}
+	for _, c := range msg.Components {
+		s.Components[c.ID] = c
+	}
+	s.Version++
+	return nil
+}
+
+func (m *SurfaceManager) dataModelUpdateLocked(msg *types.DataModelUpdateMessage, create bool) error {
+	s, err := m.surfaceLocked(msg.SurfaceID, create)
+	if err != nil {
+		return err
+	}
+	updated, err := applyOperation(s.DataModel, msg.Path, msg.Op, msg.Contents)
+	if err != nil {
+		return types.NewValidationError(s.ID, msg.Path, err.Error())
+	}
+	s.DataModel = updated
+	s.Version++
+	return nil
+}
+
+func (m *SurfaceManager) beginRenderingLocked(msg *types.BeginRenderingMessage) error {
+	s, err := m.surfaceLocked(msg.SurfaceID, false)
+	if err != nil {
+		return err
+	}
+	if msg.Root == "" {
+		return types.NewValidationError(s.ID, "/root", "root is required")
+	}
+	if _, ok := s.Components[msg.Root]; !ok {
+		return types.NewValidationError(s.ID, "/root", fmt.Sprintf("root component %q not defined", msg.Root))
+	}
+	// 渲染前所有子组件引用必须可解析
+	for _, id := range slices.Sorted(maps.Keys(s.Components)) {
+		for _, ref := range childReferences(s.Components[id].Component) {
+			if _, ok := s.Components[ref]; !ok {
+				return types.NewValidationError(s.ID, "/components/"+id,
+					fmt.Sprintf("component %q references undefined component %q", id, ref))
+			}
+		}
+	}
+
+	// 覆盖 CatalogID 时已有组件须符合新目录
+	if msg.CatalogID != "" && msg.CatalogID != s.CatalogID {
+		catalog, err := m.catalog(s.ID, msg.CatalogID, "/catalogId")
+		if err != nil {
+			return err
+		}
+		if catalog != nil {
+			for _, id := range slices.Sorted(maps.Keys(s.Components)) {
+				if err := catalog.ValidateComponent(s.Components[id].Component); err != nil {
+					return types.NewValidationError(s.ID, "/components/"+id, err.Error())
+				}
+			}
+		}
+	}
+
+	s.Root = msg.Root
+	s.Styles = maps.Clone(msg.Styles)
+	if msg.CatalogID != "" {
+		s.CatalogID = msg.CatalogID
+	}
+	s.Rendering = true
+	s.Version++
+	return nil
+}
+
+// catalog 解析 Surface 引用的组件目录；未配置注册表或未引用目录时返回 nil
+func (m *SurfaceManager) catalog(surfaceID, ref, path string) (*Catalog, error) {
+	if m.config.Catalogs == nil || ref == "" {
+		return nil, nil
+	}
+	c, err := m.config.Catalogs.Get(ref)
+	if err != nil {
+		return nil, types.NewValidationError(surfaceID, path, err.Error())
+	}
+	return c, nil
+}
+
+func (m *SurfaceManager) deleteSurfaceLocked(id string) error {
+	if _, err := m.surfaceLocked(id, false); err != nil {
+		return err
+	}
+	delete(m.surfaces, id)
+	// 唤醒等待该 Surface 的调用方
+	for _, ch := range m.waiters[id] {
+		close(ch)
+	}
+	delete(m.waiters, id)
+	return nil
+}
+
+// Surface 返回 Surface 的快照
+func (m *SurfaceManager) Surface(id string) (*Surface, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	s, ok := m.surfaces[id]
+	if !ok {
+		return nil, false
+	}
+	return s.clone(), true
+}
+
+// SurfaceIDs 返回所有 Surface ID（已排序）
+func (m *SurfaceManager) SurfaceIDs() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return slices.Sorted(maps.Keys(m.surfaces))
+}
+
+// DataModel 读取 Surface 数据模型中 path 指向的值（返回副本）
+func (m *SurfaceManager) DataModel(surfaceID, path string) (any, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	s, ok := m.surfaces[surfaceID]
+	if !ok {
+		return nil, types.NewValidationError(surfaceID, "/surfaceId", "unknown surface")
+	}
+	v, err := Resolve(s.DataModel, path)
+	if err != nil {
+		return nil, err
+	}
+	return cloneValue(v), nil
+}
+
+// HandleClientMessage 处理客户端消息：校验并分发 UserAction，转交客户端错误
+func (m *SurfaceManager) HandleClientMessage(ctx context.Context, msg *types.ClientMessage) error {
+	switch {
+	case msg == nil || (msg.UserAction == nil && msg.Error == nil):
+		return types.NewValidationError("", "", "client message must contain userAction or error")
+	case msg.UserAction != nil && msg.Error != nil:
+		return types.NewValidationError("", "", "client message must contain exactly one of userAction or error")
+	case msg.Error != nil:
+		if m.config.OnClientError != nil {
+			m.config.OnClientError(ctx, msg.Error)
+		}
+		return nil
+	}
+	return m.dispatchAction(ctx, msg.UserAction)
+}
+
+func (m *SurfaceManager) dispatchAction(ctx context.Context, action *types.UserActionMessage) error {
+	resolved, err := m.validateAction(action)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	waiters := m.waiters[resolved.SurfaceID]
+	delete(m.waiters, resolved.SurfaceID)
+	m.mu.Unlock()
+	for _, ch := range waiters {
+		ch <- resolved
+	}
+
+	if m.config.OnAction == nil {
+		return nil
+	}
+	return m.config.OnAction(ctx, resolved)
+}
+
+// WaitAction 阻塞等待 Surface 上的下一个用户动作
+// 动作仍会交给 OnAction；Surface 被删除时返回 ErrSurfaceDeleted
+func (m *SurfaceManager) WaitAction(ctx context.Context, surfaceID string) (*types.UserActionMessage, error) {
+	ch := make(chan *types.UserActionMessage, 1)
+	m.mu.Lock()
+	if _, ok := m.surfaces[surfaceID]; !ok {
+		m.mu.Unlock()
+		return nil, types.NewValidationError(surfaceID, "/surfaceId", "unknown surface")
+	}
+	m.waiters[surfaceID] = append(m.waiters[surfaceID], ch)
+	m.mu.Unlock()
+
+	select {
+	case action, ok := <-ch:
+		if !ok {
+			return nil, ErrSurfaceDeleted
+		}
+		return action, nil
+	case <-ctx.Done():
+		m.mu.Lock()
+		m.waiters[surfaceID] = slices.DeleteFunc(m.waiters[surfaceID], func(c chan *types.UserActionMessage) bool {
+			return c == ch
+		})
+		if len(m.waiters[surfaceID]) == 0 {
+			delete(m.waiters, surfaceID)
+		}
+		m.mu.Unlock()
+		return nil, ctx.Err()
+	}
+}
+
+// validateAction 校验动作来源并在客户端未提供 context 时按 actionContext 解析数据绑定
+func (m *SurfaceManager) validateAction(action *types.UserActionMessage) (*types.UserActionMessage, error) {
+	if action.Name == "" {
+		return nil, types.NewValidationError(action.SurfaceID, "/name", "action name is required")
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	s, ok := m.surfaces[action.SurfaceID]
+	if !ok {
+		return nil, types.NewValidationError(action.SurfaceID, "/surfaceId", "unknown surface")
+	}
+	def, ok := s.Components[action.SourceComponentID]
+	if !ok {
+		return nil, types.NewValidationError(s.ID, "/sourceComponentId",
+			fmt.Sprintf("unknown component %q", action.SourceComponentID))
+	}
+	if declared := declaredActions(def.Component); len(declared) > 0 && !slices.Contains(declared, action.Name) {
+		return nil, types.NewValidationError(s.ID, "/name",
+			fmt.Sprintf("component %q does not declare action %q", def.ID, action.Name))
+	}
+
+	out := *action
+	if out.Timestamp == "" {
+		out.Timestamp = time.Now().UTC().Format(time.RFC3339)
+	}
+	if out.Context == nil && def.Component.Button != nil && len(def.Component.Button.ActionContext) > 0 {
+		out.Context = make(map[string]any, len(def.Component.Button.ActionContext))
+		for key, pv := range def.Component.Button.ActionContext {
+			out.Context[key] = resolveProperty(s.DataModel, pv)
+		}
+	}
+	return &out, nil
+}
+
+// resolveProperty 解析属性值；路径不存在时返回 nil
+func resolveProperty(model map[string]any, pv types.PropertyValue) any {
+	switch {
+	case pv.IsPathReference():
+		v, err := Resolve(model, *pv.Path)
+		if err != nil {
+			return nil
+		}
+		return cloneValue(v)
+	case pv.IsLiteralString():
+		return *pv.LiteralString
+	case pv.IsLiteralNumber():
+		return *pv.LiteralNumber
+	case pv.IsLiteralBoolean():
+		return *pv.LiteralBoolean
+	default:
+		return nil
+	}
+}
+
+// EventBusActionHandler 把用户动作作为 ControlUIActionEvent 发到 Agent 的事件总线
+func EventBusActionHandler(bus *events.EventBus) ActionHandler {
+	return func(_ context.Context, action *types.UserActionMessage) error {
+		bus.EmitControl(&types.ControlUIActionEvent{
+			SurfaceID:   action.SurfaceID,
+			ComponentID: action.SourceComponentID,
+			Action:      action.Name,
+			Payload:     action.Context,
+		})
+		return nil
+	}
+}
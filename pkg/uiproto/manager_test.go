@@ -0,0 +1,322 @@
+package uiproto
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/astercloud/aster/pkg/events"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+func textComponent(id, text string) types.ComponentDefinition {
+	return types.ComponentDefinition{ID: id, Component: types.ComponentSpec{
+		Text: &types.TextProps{Text: types.NewLiteralString(text)},
+	}}
+}
+
+func formComponents() []types.ComponentDefinition {
+	return []types.ComponentDefinition{
+		{ID: "root", Component: types.ComponentSpec{Column: &types.ColumnProps{
+			Children: types.ComponentArrayReference{ExplicitList: []string{"title", "name", "submit"}},
+		}}},
+		textComponent("title", "Profile"),
+		{ID: "name", Component: types.ComponentSpec{TextField: &types.TextFieldProps{
+			Value: types.NewPathReference("/form/name"),
+		}}},
+		{ID: "submit", Component: types.ComponentSpec{Button: &types.ButtonProps{
+			Label:         types.NewLiteralString("Save"),
+			Action:        "save",
+			ActionContext: map[string]types.PropertyValue{"name": types.NewPathReference("/form/name")},
+		}}},
+	}
+}
+
+func newFormSurface(t *testing.T, m *SurfaceManager) {
+	t.Helper()
+	msgs := []*types.AsterUIMessage{
+		{CreateSurface: &types.CreateSurfaceMessage{SurfaceID: "s1"}},
+		{SurfaceUpdate: &types.SurfaceUpdateMessage{SurfaceID: "s1", Components: formComponents()}},
+		{DataModelUpdate: &types.DataModelUpdateMessage{SurfaceID: "s1", Path: "/form", Contents: map[string]any{"name": "Ada"}}},
+		{BeginRendering: &types.BeginRenderingMessage{SurfaceID: "s1", Root: "root"}},
+	}
+	for _, msg := range msgs {
+		if err := m.Apply(msg); err != nil {
+			t.Fatalf("Apply() error = %v", err)
+		}
+	}
+}
+
+func TestSurfaceLifecycle(t *testing.T) {
+	var updates int
+	m := NewSurfaceManager(&ManagerConfig{OnUpdate: func(*types.AsterUIMessage) { updates++ }})
+	newFormSurface(t, m)
+
+	s, ok := m.Surface("s1")
+	if !ok || !s.Rendering || s.Root != "root" || len(s.Components) != 4 {
+		t.Fatalf("surface = %+v", s)
+	}
+	if updates != 4 {
+		t.Errorf("OnUpdate called %d times, want 4", updates)
+	}
+
+	// Snapshots are independent of the live state
+	s.DataModel["form"] = "tampered"
+	if v, _ := m.DataModel("s1", "/form/name"); v != "Ada" {
+		t.Errorf("DataModel() = %v, want Ada", v)
+	}
+
+	if err := m.Apply(&types.AsterUIMessage{DeleteSurface: &types.DeleteSurfaceMessage{SurfaceID: "s1"}}); err != nil {
+		t.Fatalf("delete error = %v", err)
+	}
+	if ids := m.SurfaceIDs(); len(ids) != 0 {
+		t.Errorf("SurfaceIDs() = %v after delete", ids)
+	}
+}
+
+func TestApplyValidation(t *testing.T) {
+	m := NewSurfaceManager(nil)
+	_ = m.Apply(&types.AsterUIMessage{CreateSurface: &types.CreateSurfaceMessage{SurfaceID: "s1"}})
+
+	tests := []struct {
+		name string
+		msg  *types.AsterUIMessage
+	}{
+		{"no operation", &types.AsterUIMessage{}},
+		{"two operations", &types.AsterUIMessage{
+			CreateSurface: &types.CreateSurfaceMessage{SurfaceID: "s2"},
+			DeleteSurface: &types.DeleteSurfaceMessage{SurfaceID: "s1"},
+		}},
+		{"duplicate surface", &types.AsterUIMessage{CreateSurface: &types.CreateSurfaceMessage{SurfaceID: "s1"}}},
+		{"unknown surface", &types.AsterUIMessage{SurfaceUpdate: &types.SurfaceUpdateMessage{SurfaceID: "nope"}}},
+		{"missing component id", &types.AsterUIMessage{SurfaceUpdate: &types.SurfaceUpdateMessage{
+			SurfaceID: "s1", Components: []types.ComponentDefinition{textComponent("", "x")},
+		}}},
+		{"duplicate component id", &types.AsterUIMessage{SurfaceUpdate: &types.SurfaceUpdateMessage{
+			SurfaceID: "s1", Components: []types.ComponentDefinition{textComponent("a", "x"), textComponent("a", "y")},
+		}}},
+		{"no component type", &types.AsterUIMessage{SurfaceUpdate: &types.SurfaceUpdateMessage{
+			SurfaceID: "s1", Components: []types.ComponentDefinition{{ID: "a"}},
+		}}},
+		{"literal two-way binding", &types.AsterUIMessage{SurfaceUpdate: &types.SurfaceUpdateMessage{
+			SurfaceID: "s1", Components: []types.ComponentDefinition{{ID: "a", Component: types.ComponentSpec{
+				Checkbox: &types.CheckboxProps{Checked: types.NewLiteralBoolean(true)},
+			}}},
+		}}},
+		{"root not defined", &types.AsterUIMessage{BeginRendering: &types.BeginRenderingMessage{SurfaceID: "s1", Root: "missing"}}},
+		{"bad data path", &types.AsterUIMessage{DataModelUpdate: &types.DataModelUpdateMessage{SurfaceID: "s1", Path: "no-slash"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := m.Apply(tt.msg)
+			var perr *types.ProtocolError
+			if !errors.As(err, &perr) || !perr.IsValidationError() {
+				t.Errorf("Apply() error = %v, want validation error", err)
+			}
+		})
+	}
+}
+
+func TestBeginRenderingRequiresResolvableChildren(t *testing.T) {
+	m := NewSurfaceManager(nil)
+	_ = m.Apply(&types.AsterUIMessage{CreateSurface: &types.CreateSurfaceMessage{SurfaceID: "s1"}})
+	_ = m.Apply(&types.AsterUIMessage{SurfaceUpdate: &types.SurfaceUpdateMessage{SurfaceID: "s1", Components: formComponents()[:2]}})
+
+	err := m.Apply(&types.AsterUIMessage{BeginRendering: &types.BeginRenderingMessage{SurfaceID: "s1", Root: "root"}})
+	var perr *types.ProtocolError
+	if !errors.As(err, &perr) || perr.Path != "/components/root" {
+		t.Fatalf("BeginRendering error = %v, want unresolved child error", err)
+	}
+
+	// Streaming the missing components in a later update makes it renderable
+	_ = m.Apply(&types.AsterUIMessage{SurfaceUpdate: &types.SurfaceUpdateMessage{SurfaceID: "s1", Components: formComponents()[2:]}})
+	if err := m.Apply(&types.AsterUIMessage{BeginRendering: &types.BeginRenderingMessage{SurfaceID: "s1", Root: "root"}}); err != nil {
+		t.Errorf("BeginRendering error = %v", err)
+	}
+}
+
+func TestCustomTypeAllowList(t *testing.T) {
+	m := NewSurfaceManager(&ManagerConfig{CustomTypes: []string{"Chart"}})
+	_ = m.Apply(&types.AsterUIMessage{CreateSurface: &types.CreateSurfaceMessage{SurfaceID: "s1"}})
+
+	custom := func(typ string) *types.AsterUIMessage {
+		return &types.AsterUIMessage{SurfaceUpdate: &types.SurfaceUpdateMessage{SurfaceID: "s1", Components: []types.ComponentDefinition{
+			{ID: "c", Component: types.ComponentSpec{Custom: &types.CustomProps{Type: typ}}},
+		}}}
+	}
+	if err := m.Apply(custom("Chart")); err != nil {
+		t.Errorf("allowed custom type rejected: %v", err)
+	}
+	if err := m.Apply(custom("Script")); err == nil {
+		t.Error("expected error for custom type outside the allow list")
+	}
+}
+
+func TestUserActionDispatch(t *testing.T) {
+	var got *types.UserActionMessage
+	m := NewSurfaceManager(&ManagerConfig{OnAction: func(_ context.Context, a *types.UserActionMessage) error {
+		got = a
+		return nil
+	}})
+	newFormSurface(t, m)
+	ctx := context.Background()
+
+	err := m.HandleClientMessage(ctx, &types.ClientMessage{UserAction: &types.UserActionMessage{
+		Name: "save", SurfaceID: "s1", SourceComponentID: "submit",
+	}})
+	if err != nil {
+		t.Fatalf("HandleClientMessage() error = %v", err)
+	}
+	if got == nil || got.Context["name"] != "Ada" || got.Timestamp == "" {
+		t.Errorf("dispatched action = %+v, want context resolved from data model", got)
+	}
+
+	invalid := []*types.UserActionMessage{
+		{Name: "save", SurfaceID: "nope", SourceComponentID: "submit"},
+		{Name: "save", SurfaceID: "s1", SourceComponentID: "nope"},
+		{Name: "delete", SurfaceID: "s1", SourceComponentID: "submit"},
+		{SurfaceID: "s1", SourceComponentID: "submit"},
+	}
+	for _, a := range invalid {
+		got = nil
+		if err := m.HandleClientMessage(ctx, &types.ClientMessage{UserAction: a}); err == nil || got != nil {
+			t.Errorf("action %+v: error = %v, dispatched = %v", a, err, got != nil)
+		}
+	}
+}
+
+func TestWaitAction(t *testing.T) {
+	m := NewSurfaceManager(nil)
+	newFormSurface(t, m)
+	ctx := context.Background()
+
+	done := make(chan *types.UserActionMessage, 1)
+	go func() {
+		a, err := m.WaitAction(ctx, "s1")
+		if err != nil {
+			t.Errorf("WaitAction() error = %v", err)
+		}
+		done <- a
+	}()
+
+	// 等待者注册后再发送动作
+	deadline := time.Now().Add(time.Second)
+	for {
+		m.mu.RLock()
+		n := len(m.waiters["s1"])
+		m.mu.RUnlock()
+		if n > 0 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	err := m.HandleClientMessage(ctx, &types.ClientMessage{UserAction: &types.UserActionMessage{
+		Name: "save", SurfaceID: "s1", SourceComponentID: "submit",
+	}})
+	if err != nil {
+		t.Fatalf("HandleClientMessage() error = %v", err)
+	}
+	select {
+	case a := <-done:
+		if a == nil || a.Name != "save" || a.Context["name"] != "Ada" {
+			t.Errorf("WaitAction() = %+v", a)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("WaitAction() did not return")
+	}
+
+	if _, err := m.WaitAction(ctx, "nope"); err == nil {
+		t.Error("WaitAction() on unknown surface should fail")
+	}
+
+	cctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if _, err := m.WaitAction(cctx, "s1"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("WaitAction() error = %v, want deadline exceeded", err)
+	}
+	if len(m.waiters["s1"]) != 0 {
+		t.Error("cancelled waiter was not removed")
+	}
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		_ = m.Apply(&types.AsterUIMessage{DeleteSurface: &types.DeleteSurfaceMessage{SurfaceID: "s1"}})
+	}()
+	if _, err := m.WaitAction(ctx, "s1"); !errors.Is(err, ErrSurfaceDeleted) {
+		t.Errorf("WaitAction() error = %v, want ErrSurfaceDeleted", err)
+	}
+}
+
+func TestClientErrorForwarded(t *testing.T) {
+	var reported *types.ProtocolError
+	m := NewSurfaceManager(&ManagerConfig{OnClientError: func(_ context.Context, e *types.ProtocolError) { reported = e }})
+
+	clientErr := types.NewValidationError("s1", "/components/0", "unknown component type")
+	if err := m.HandleClientMessage(context.Background(), &types.ClientMessage{Error: clientErr}); err != nil {
+		t.Fatalf("HandleClientMessage() error = %v", err)
+	}
+	if reported != clientErr {
+		t.Errorf("reported = %+v", reported)
+	}
+	if err := m.HandleClientMessage(context.Background(), &types.ClientMessage{}); err == nil {
+		t.Error("expected error for empty client message")
+	}
+}
+
+func TestApplyAgentEvents(t *testing.T) {
+	m := NewSurfaceManager(nil)
+
+	handled, err := m.ApplyEvent(&types.ProgressUISurfaceUpdateEvent{
+		SurfaceID:  "s1",
+		Components: formComponents(),
+		Root:       "root",
+	})
+	if !handled || err != nil {
+		t.Fatalf("ApplyEvent(surface update) = %v, %v", handled, err)
+	}
+	if s, _ := m.Surface("s1"); !s.Rendering {
+		t.Error("surface not rendering after event with root")
+	}
+
+	if _, err := m.ApplyEvent(&types.ProgressUIDataUpdateEvent{SurfaceID: "s1", Path: "/form/name", Contents: "Grace"}); err != nil {
+		t.Fatalf("ApplyEvent(data update) error = %v", err)
+	}
+	if v, _ := m.DataModel("s1", "/form/name"); v != "Grace" {
+		t.Errorf("name = %v, want Grace", v)
+	}
+
+	if handled, _ := m.ApplyEvent(&types.ProgressTextChunkEvent{}); handled {
+		t.Error("non-UI event reported as handled")
+	}
+	if _, err := m.ApplyEvent(&types.ProgressUIDeleteSurfaceEvent{SurfaceID: "s1"}); err != nil {
+		t.Fatalf("ApplyEvent(delete) error = %v", err)
+	}
+	if _, ok := m.Surface("s1"); ok {
+		t.Error("surface still present after delete event")
+	}
+}
+
+func TestEventBusActionHandler(t *testing.T) {
+	bus := events.NewEventBus()
+	defer bus.Close()
+	ch := bus.Subscribe([]types.AgentChannel{types.ChannelControl}, nil)
+
+	m := NewSurfaceManager(&ManagerConfig{OnAction: EventBusActionHandler(bus)})
+	newFormSurface(t, m)
+	if err := m.HandleClientMessage(context.Background(), &types.ClientMessage{UserAction: &types.UserActionMessage{
+		Name: "save", SurfaceID: "s1", SourceComponentID: "submit",
+	}}); err != nil {
+		t.Fatalf("HandleClientMessage() error = %v", err)
+	}
+
+	select {
+	case env := <-ch:
+		e, ok := env.Event.(*types.ControlUIActionEvent)
+		if !ok || e.Action != "save" || e.ComponentID != "submit" || e.Payload["name"] != "Ada" {
+			t.Errorf("event = %#v", env.Event)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no ui:action event on the bus")
+	}
+}
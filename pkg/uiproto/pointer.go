@@ -0,0 +1,265 @@
+package uiproto
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/astercloud/aster/pkg/types"
+)
+
+// ErrPathNotFound 路径指向的值不存在
+var ErrPathNotFound = errors.New("path not found")
+
+// ParsePointer 解析 JSON Pointer（RFC 6901）为路径片段
+// "" 与 "/" 都表示根路径，返回空切片
+func ParsePointer(path string) ([]string, error) {
+	if path == "" || path == "/" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(path, "/") {
+		return nil, fmt.Errorf("json pointer %q must start with /", path)
+	}
+	tokens := strings.Split(path[1:], "/")
+	for i, tok := range tokens {
+		// 先替换 ~1 再替换 ~0，避免 "~01" 被错误解码为 "/"
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(tok, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+// Resolve 读取数据模型中 path 指向的值
+func Resolve(model any, path string) (any, error) {
+	tokens, err := ParsePointer(path)
+	if err != nil {
+		return nil, err
+	}
+	cur := model
+	for _, tok := range tokens {
+		switch node := cur.(type) {
+		case map[string]any:
+			v, ok := node[tok]
+			if !ok {
+				return nil, fmt.Errorf("%w: %s", ErrPathNotFound, path)
+			}
+			cur = v
+		case []any:
+			idx, err := arrayIndex(tok, len(node))
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s", ErrPathNotFound, path)
+			}
+			cur = node[idx]
+		default:
+			return nil, fmt.Errorf("%w: %s", ErrPathNotFound, path)
+		}
+	}
+	return cur, nil
+}
+
+// applyOperation 在数据模型副本上执行一次 add/replace/remove 操作，返回新的数据模型
+// 出错时原数据模型保持不变
+func applyOperation(model map[string]any, path string, op types.DataModelOperation, contents any) (map[string]any, error) {
+	tokens, err := ParsePointer(path)
+	if err != nil {
+		return nil, err
+	}
+	if op == "" {
+		op = types.DataModelOperationReplace
+	}
+	value, err := normalize(contents)
+	if err != nil {
+		return nil, err
+	}
+
+	// 根路径
+	if len(tokens) == 0 {
+		switch op {
+		case types.DataModelOperationRemove:
+			return map[string]any{}, nil
+		case types.DataModelOperationAdd:
+			obj, ok := value.(map[string]any)
+			if !ok {
+				return nil, errors.New("add at root requires an object")
+			}
+			merged := cloneValue(model).(map[string]any)
+			maps.Copy(merged, obj)
+			return merged, nil
+		case types.DataModelOperationReplace:
+			obj, ok := value.(map[string]any)
+			if !ok {
+				return nil, errors.New("replace at root requires an object")
+			}
+			return obj, nil
+		default:
+			return nil, fmt.Errorf("unknown data model operation %q", op)
+		}
+	}
+
+	var leaf leafFunc
+	switch op {
+	case types.DataModelOperationReplace:
+		leaf = func(any, bool) (any, bool, error) { return value, false, nil }
+	case types.DataModelOperationAdd:
+		leaf = func(cur any, exists bool) (any, bool, error) {
+			if !exists {
+				return value, false, nil
+			}
+			switch c := cur.(type) {
+			case []any:
+				return append(c, value), false, nil
+			case map[string]any:
+				if obj, ok := value.(map[string]any); ok {
+					maps.Copy(c, obj)
+					return c, false, nil
+				}
+			}
+			return value, false, nil
+		}
+	case types.DataModelOperationRemove:
+		leaf = func(_ any, exists bool) (any, bool, error) {
+			if !exists {
+				return nil, false, fmt.Errorf("%w: %s", ErrPathNotFound, path)
+			}
+			return nil, true, nil
+		}
+	default:
+		return nil, fmt.Errorf("unknown data model operation %q", op)
+	}
+
+	// remove 不自动创建中间节点
+	create := op != types.DataModelOperationRemove
+	updated, err := update(cloneValue(model), tokens, create, leaf)
+	if err != nil {
+		return nil, err
+	}
+	return updated.(map[string]any), nil
+}
+
+// leafFunc 根据叶子位置的当前值计算新值；remove 为 true 时删除该位置
+type leafFunc func(cur any, exists bool) (value any, remove bool, err error)
+
+// update 递归定位 tokens 指向的位置并在叶子处调用 leaf，返回更新后的节点
+// create 为 true 时自动创建缺失的中间对象
+func update(node any, tokens []string, create bool, leaf leafFunc) (any, error) {
+	tok := tokens[0]
+	last := len(tokens) == 1
+
+	switch n := node.(type) {
+	case map[string]any:
+		if last {
+			cur, exists := n[tok]
+			v, remove, err := leaf(cur, exists)
+			if err != nil {
+				return nil, err
+			}
+			if remove {
+				delete(n, tok)
+			} else {
+				n[tok] = v
+			}
+			return n, nil
+		}
+		child, exists := n[tok]
+		if !exists {
+			if !create {
+				return nil, fmt.Errorf("%w: /%s", ErrPathNotFound, tok)
+			}
+			child = map[string]any{}
+		}
+		v, err := update(child, tokens[1:], create, leaf)
+		if err != nil {
+			return nil, err
+		}
+		n[tok] = v
+		return n, nil
+
+	case []any:
+		// "-" 表示数组末尾，仅用于追加
+		if tok == "-" && last {
+			v, remove, err := leaf(nil, false)
+			if err != nil {
+				return nil, err
+			}
+			if remove {
+				return n, nil
+			}
+			return append(n, v), nil
+		}
+		idx, err := arrayIndex(tok, len(n))
+		if err != nil {
+			return nil, err
+		}
+		if last {
+			v, remove, err := leaf(n[idx], true)
+			if err != nil {
+				return nil, err
+			}
+			if remove {
+				return slices.Delete(n, idx, idx+1), nil
+			}
+			n[idx] = v
+			return n, nil
+		}
+		v, err := update(n[idx], tokens[1:], create, leaf)
+		if err != nil {
+			return nil, err
+		}
+		n[idx] = v
+		return n, nil
+
+	default:
+		return nil, fmt.Errorf("cannot traverse into %T at %q", node, tok)
+	}
+}
+
+func arrayIndex(tok string, length int) (int, error) {
+	idx, err := strconv.Atoi(tok)
+	if err != nil || idx < 0 || (len(tok) > 1 && tok[0] == '0') {
+		return 0, fmt.Errorf("invalid array index %q", tok)
+	}
+	if idx >= length {
+		return 0, fmt.Errorf("array index %d out of range (len %d)", idx, length)
+	}
+	return idx, nil
+}
+
+// normalize 通过 JSON 往返将任意 Go 值转换为 map[string]any / []any / 基本类型
+func normalize(v any) (any, error) {
+	switch v.(type) {
+	case nil, string, bool, float64:
+		return v, nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("contents must be JSON-serializable: %w", err)
+	}
+	var out any
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// cloneValue 深拷贝已规范化的 JSON 值
+func cloneValue(v any) any {
+	switch n := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(n))
+		for k, child := range n {
+			out[k] = cloneValue(child)
+		}
+		return out
+	case []any:
+		out := make([]any, len(n))
+		for i, child := range n {
+			out[i] = cloneValue(child)
+		}
+		return out
+	default:
+		return v
+	}
+}
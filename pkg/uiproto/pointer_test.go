@@ -0,0 +1,100 @@
+package uiproto
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/astercloud/aster/pkg/types"
+)
+
+func TestParsePointer(t *testing.T) {
+	tests := []struct {
+		path    string
+		want    []string
+		wantErr bool
+	}{
+		{"", nil, false},
+		{"/", nil, false},
+		{"/user/name", []string{"user", "name"}, false},
+		{"/a~1b/c~0d", []string{"a/b", "c~d"}, false},
+		{"/~01", []string{"~1"}, false},
+		{"user", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := ParsePointer(tt.path)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParsePointer(%q) error = %v, wantErr %v", tt.path, err, tt.wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParsePointer(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestApplyOperation(t *testing.T) {
+	base := func() map[string]any {
+		return map[string]any{
+			"user":  map[string]any{"name": "Ada"},
+			"items": []any{"a", "b"},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		path     string
+		op       types.DataModelOperation
+		contents any
+		check    string
+		want     any
+		wantErr  bool
+	}{
+		{"replace leaf", "/user/name", "", "Grace", "/user/name", "Grace", false},
+		{"replace creates parents", "/settings/theme", types.DataModelOperationReplace, "dark", "/settings/theme", "dark", false},
+		{"replace array index", "/items/1", types.DataModelOperationReplace, "B", "/items", []any{"a", "B"}, false},
+		{"replace array out of range", "/items/5", types.DataModelOperationReplace, "x", "", nil, true},
+		{"add appends to array", "/items", types.DataModelOperationAdd, "c", "/items", []any{"a", "b", "c"}, false},
+		{"add with dash appends", "/items/-", types.DataModelOperationAdd, "c", "/items/2", "c", false},
+		{"add merges objects", "/user", types.DataModelOperationAdd, map[string]any{"age": 36}, "/user", map[string]any{"name": "Ada", "age": float64(36)}, false},
+		{"remove key", "/user/name", types.DataModelOperationRemove, nil, "/user", map[string]any{}, false},
+		{"remove array element", "/items/0", types.DataModelOperationRemove, nil, "/items", []any{"b"}, false},
+		{"remove missing", "/nope/x", types.DataModelOperationRemove, nil, "", nil, true},
+		{"traverse scalar", "/user/name/first", types.DataModelOperationReplace, "x", "", nil, true},
+		{"replace root", "/", types.DataModelOperationReplace, map[string]any{"only": true}, "/only", true, false},
+		{"replace root with scalar", "/", types.DataModelOperationReplace, "x", "", nil, true},
+		{"unknown op", "/user", "move", nil, "", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			model := base()
+			got, err := applyOperation(model, tt.path, tt.op, tt.contents)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("applyOperation() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(model, base()) {
+				t.Errorf("original model mutated: %v", model)
+			}
+			if tt.wantErr {
+				return
+			}
+			v, err := Resolve(got, tt.check)
+			if err != nil {
+				t.Fatalf("Resolve(%q) error = %v", tt.check, err)
+			}
+			if !reflect.DeepEqual(v, tt.want) {
+				t.Errorf("Resolve(%q) = %#v, want %#v", tt.check, v, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveNotFound(t *testing.T) {
+	model := map[string]any{"items": []any{"a"}}
+	for _, path := range []string{"/missing", "/items/1", "/items/x", "/items/0/deep"} {
+		if _, err := Resolve(model, path); !errors.Is(err, ErrPathNotFound) {
+			t.Errorf("Resolve(%q) error = %v, want ErrPathNotFound", path, err)
+		}
+	}
+}
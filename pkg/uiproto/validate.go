@@ -0,0 +1,165 @@
+package uiproto
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+
+	"github.com/astercloud/aster/pkg/types"
+)
+
+// validateComponents 校验 surfaceUpdate 中的组件定义；catalog 非空时同时按目录校验
+func (m *SurfaceManager) validateComponents(surfaceID string, components []types.ComponentDefinition, catalog *Catalog) error {
+	seen := make(map[string]bool, len(components))
+	for i, c := range components {
+		path := fmt.Sprintf("/components/%d", i)
+		if c.ID == "" {
+			return types.NewValidationError(surfaceID, path+"/id", "component id is required")
+		}
+		if seen[c.ID] {
+			return types.NewValidationError(surfaceID, path+"/id", fmt.Sprintf("duplicate component id %q", c.ID))
+		}
+		seen[c.ID] = true
+
+		if c.Weight != "" && c.Weight != types.ComponentWeightInitial && c.Weight != types.ComponentWeightFinal {
+			return types.NewValidationError(surfaceID, path+"/weight", fmt.Sprintf("invalid weight %q", c.Weight))
+		}
+		if n := setProps(c.Component); n != 1 {
+			return types.NewValidationError(surfaceID, path+"/component",
+				fmt.Sprintf("component must have exactly one type, got %d", n))
+		}
+		if err := m.validateProps(c.Component); err != nil {
+			return types.NewValidationError(surfaceID, path+"/component/"+c.Component.GetTypeName(), err.Error())
+		}
+		if catalog != nil {
+			if err := catalog.ValidateComponent(c.Component); err != nil {
+				return types.NewValidationError(surfaceID, path+"/component/"+c.Component.GetTypeName(), err.Error())
+			}
+		}
+	}
+	return nil
+}
+
+// validateProps 校验组件类型相关的约束
+func (m *SurfaceManager) validateProps(spec types.ComponentSpec) error {
+	// 双向绑定的输入组件必须使用 path 引用
+	var bound *types.PropertyValue
+	switch {
+	case spec.TextField != nil:
+		bound = &spec.TextField.Value
+	case spec.Checkbox != nil:
+		bound = &spec.Checkbox.Checked
+	case spec.Select != nil:
+		bound = &spec.Select.Value
+	case spec.DateTimeInput != nil:
+		bound = &spec.DateTimeInput.Value
+	case spec.Slider != nil:
+		bound = &spec.Slider.Value
+	case spec.MultipleChoice != nil:
+		bound = &spec.MultipleChoice.Value
+	case spec.Modal != nil:
+		bound = &spec.Modal.Open
+	case spec.Tabs != nil:
+		bound = &spec.Tabs.ActiveTab
+	}
+	if bound != nil {
+		if !bound.IsPathReference() {
+			return fmt.Errorf("%s value must be a path reference", spec.GetTypeName())
+		}
+		if _, err := ParsePointer(*bound.Path); err != nil {
+			return err
+		}
+	}
+
+	switch {
+	case spec.Button != nil && spec.Button.Action == "":
+		return fmt.Errorf("button action is required")
+	case spec.Custom != nil:
+		if spec.Custom.Type == "" {
+			return fmt.Errorf("custom component type is required")
+		}
+		if len(m.config.CustomTypes) > 0 && !slices.Contains(m.config.CustomTypes, spec.Custom.Type) {
+			return fmt.Errorf("custom component type %q is not allowed", spec.Custom.Type)
+		}
+	}
+
+	for _, ref := range templateReferences(spec) {
+		if ref.ComponentID == "" {
+			return fmt.Errorf("template componentId is required")
+		}
+		if _, err := ParsePointer(ref.DataBinding); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setProps 统计 ComponentSpec 中设置的组件类型数量
+func setProps(spec types.ComponentSpec) int {
+	v := reflect.ValueOf(spec)
+	n := 0
+	for i := range v.NumField() {
+		if !v.Field(i).IsNil() {
+			n++
+		}
+	}
+	return n
+}
+
+// childArrays 返回组件的子组件引用
+func childArrays(spec types.ComponentSpec) []types.ComponentArrayReference {
+	switch {
+	case spec.Row != nil:
+		return []types.ComponentArrayReference{spec.Row.Children}
+	case spec.Column != nil:
+		return []types.ComponentArrayReference{spec.Column.Children}
+	case spec.Card != nil:
+		return []types.ComponentArrayReference{spec.Card.Children}
+	case spec.List != nil:
+		return []types.ComponentArrayReference{spec.List.Children}
+	case spec.Modal != nil:
+		return []types.ComponentArrayReference{spec.Modal.Children}
+	case spec.Tabs != nil:
+		refs := make([]types.ComponentArrayReference, 0, len(spec.Tabs.Tabs))
+		for _, tab := range spec.Tabs.Tabs {
+			refs = append(refs, tab.Content)
+		}
+		return refs
+	}
+	return nil
+}
+
+// childReferences 返回组件引用的所有子组件 ID（含模板组件）
+func childReferences(spec types.ComponentSpec) []string {
+	var ids []string
+	for _, ref := range childArrays(spec) {
+		ids = append(ids, ref.ExplicitList...)
+		if ref.Template != nil {
+			ids = append(ids, ref.Template.ComponentID)
+		}
+	}
+	return ids
+}
+
+func templateReferences(spec types.ComponentSpec) []*types.ComponentTemplate {
+	var refs []*types.ComponentTemplate
+	for _, ref := range childArrays(spec) {
+		if ref.Template != nil {
+			refs = append(refs, ref.Template)
+		}
+	}
+	return refs
+}
+
+// declaredActions 返回组件声明的动作；为空表示不限制
+func declaredActions(spec types.ComponentSpec) []string {
+	switch {
+	case spec.Button != nil:
+		return []string{spec.Button.Action}
+	case spec.Card != nil && spec.Card.Action != "":
+		return []string{spec.Card.Action}
+	case spec.Modal != nil && spec.Modal.CloseAction != "":
+		return []string{spec.Modal.CloseAction}
+	}
+	return nil
+}
@@ -0,0 +1,282 @@
+// Package update 定义桌面应用的发布清单：版本通道、分阶段发布和安装包签名
+// 服务端托管清单，桌面端按通道、平台和安装 ID 选出可用的新版本，下载后校验摘要和签名再安装
+package update
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/mod/semver"
+)
+
+// ManifestFile 发布目录中清单文件的文件名
+const ManifestFile = "manifest.json"
+
+var (
+	// ErrInvalidSignature 安装包的摘要或签名与发布清单不匹配
+	ErrInvalidSignature = errors.New("invalid update signature")
+	// ErrInvalidManifest 发布清单格式错误
+	ErrInvalidManifest = errors.New("invalid update manifest")
+)
+
+// Channel 发布通道
+type Channel string
+
+const (
+	// ChannelStable 正式版
+	ChannelStable Channel = "stable"
+	// ChannelBeta 测试版，同时接收正式版
+	ChannelBeta Channel = "beta"
+)
+
+// ParseChannel 解析通道名，空字符串视为 stable
+func ParseChannel(s string) (Channel, error) {
+	switch c := Channel(strings.ToLower(strings.TrimSpace(s))); c {
+	case "":
+		return ChannelStable, nil
+	case ChannelStable, ChannelBeta:
+		return c, nil
+	default:
+		return "", fmt.Errorf("unknown update channel %q", s)
+	}
+}
+
+// includes 订阅 c 的安装是否接收发布到 release 通道的版本
+func (c Channel) includes(release Channel) bool {
+	return release == ChannelStable || release == c
+}
+
+// Manifest 发布清单
+type Manifest struct {
+	Releases []Release `json:"releases"`
+}
+
+// Release 一个发布版本
+type Release struct {
+	// Version 语义化版本号，可带 v 前缀
+	Version string    `json:"version"`
+	Channel Channel   `json:"channel"`
+	Notes   string    `json:"notes,omitempty"`
+	Date    time.Time `json:"date,omitempty"`
+
+	// Rollout 分阶段发布时收到该版本的安装比例（1-99），0 或 100 表示全部
+	Rollout int `json:"rollout,omitempty"`
+
+	// Paused 暂停发布，已发现问题的版本不再推送给新的安装
+	Paused bool `json:"paused,omitempty"`
+
+	// Mandatory 强制更新，前端不应允许用户忽略
+	Mandatory bool `json:"mandatory,omitempty"`
+
+	Assets []Asset `json:"assets"`
+}
+
+// Asset 某个平台的安装包
+type Asset struct {
+	// Platform 目标平台，格式为 GOOS/GOARCH，如 darwin/arm64
+	Platform string `json:"platform"`
+
+	// URL 下载地址，相对地址按清单地址解析
+	URL  string `json:"url"`
+	Size int64  `json:"size,omitempty"`
+
+	// SHA256 安装包内容的 SHA-256（十六进制）
+	SHA256 string `json:"sha256"`
+
+	// Signature 发布私钥对版本、平台和摘要的 ed25519 签名（base64）
+	Signature string `json:"signature"`
+}
+
+// Asset 返回指定平台的安装包
+func (r *Release) Asset(platform string) *Asset {
+	for i := range r.Assets {
+		if r.Assets[i].Platform == platform {
+			return &r.Assets[i]
+		}
+	}
+	return nil
+}
+
+// LoadManifest 从文件读取并校验发布清单
+func LoadManifest(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseManifest(data)
+}
+
+// ParseManifest 解析并校验发布清单
+func ParseManifest(data []byte) (*Manifest, error) {
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidManifest, err)
+	}
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// Validate 校验版本号、通道、发布比例和安装包字段
+func (m *Manifest) Validate() error {
+	for _, r := range m.Releases {
+		if !ValidVersion(r.Version) {
+			return fmt.Errorf("%w: invalid version %q", ErrInvalidManifest, r.Version)
+		}
+		if r.Channel != ChannelStable && r.Channel != ChannelBeta {
+			return fmt.Errorf("%w: release %s: unknown channel %q", ErrInvalidManifest, r.Version, r.Channel)
+		}
+		if r.Rollout < 0 || r.Rollout > 100 {
+			return fmt.Errorf("%w: release %s: rollout must be between 0 and 100", ErrInvalidManifest, r.Version)
+		}
+		for _, a := range r.Assets {
+			if a.Platform == "" || a.URL == "" {
+				return fmt.Errorf("%w: release %s: asset platform and url are required", ErrInvalidManifest, r.Version)
+			}
+			if sum, err := hex.DecodeString(a.SHA256); err != nil || len(sum) != sha256.Size {
+				return fmt.Errorf("%w: release %s: asset %s: malformed sha256", ErrInvalidManifest, r.Version, a.Platform)
+			}
+		}
+	}
+	return nil
+}
+
+// Query 一次更新检查的条件
+type Query struct {
+	Channel Channel `json:"channel"`
+
+	// Platform 安装所在平台，格式为 GOOS/GOARCH
+	Platform string `json:"platform"`
+
+	// Version 当前安装的版本
+	Version string `json:"version"`
+
+	// InstallID 安装的稳定标识，决定分阶段发布时落在哪个分桶
+	InstallID string `json:"install_id,omitempty"`
+}
+
+// Result 更新检查结果
+type Result struct {
+	Release *Release `json:"release"`
+	Asset   *Asset   `json:"asset"`
+
+	// Mandatory 跳过的版本中有强制更新时也为 true
+	Mandatory bool `json:"mandatory"`
+}
+
+// Check 返回该安装可以升级到的最高版本，没有可用更新时返回 nil
+// 暂停的版本、没有对应平台安装包的版本和未覆盖该安装的分阶段版本会被跳过
+func (m *Manifest) Check(q Query) *Result {
+	channel := q.Channel
+	if channel == "" {
+		channel = ChannelStable
+	}
+
+	var result *Result
+	mandatory := false
+	for i := range m.Releases {
+		r := &m.Releases[i]
+		if r.Paused || !channel.includes(r.Channel) || CompareVersions(r.Version, q.Version) <= 0 {
+			continue
+		}
+		asset := r.Asset(q.Platform)
+		if asset == nil || !InRollout(q.InstallID, r.Version, r.Rollout) {
+			continue
+		}
+		mandatory = mandatory || r.Mandatory
+		if result == nil || CompareVersions(r.Version, result.Release.Version) > 0 {
+			result = &Result{Release: r, Asset: asset}
+		}
+	}
+	if result != nil {
+		result.Mandatory = mandatory
+	}
+	return result
+}
+
+// ValidVersion 判断是否为合法的语义化版本号
+func ValidVersion(v string) bool {
+	return semver.IsValid(canonical(v))
+}
+
+// CompareVersions 比较两个语义化版本号，返回 -1、0 或 1；非法版本号小于任何合法版本号
+func CompareVersions(a, b string) int {
+	return semver.Compare(canonical(a), canonical(b))
+}
+
+func canonical(v string) string {
+	v = strings.TrimSpace(v)
+	if v != "" && !strings.HasPrefix(v, "v") {
+		v = "v" + v
+	}
+	return v
+}
+
+// InRollout 判断安装是否落在分阶段发布的比例内
+// 分桶由安装 ID 和版本号决定：同一安装对同一版本结果稳定，不同版本的首批用户不同
+func InRollout(installID, version string, percent int) bool {
+	if percent <= 0 || percent >= 100 {
+		return true
+	}
+	sum := sha256.Sum256([]byte(canonical(version) + ":" + installID))
+	return int(binary.BigEndian.Uint32(sum[:4])%100) < percent
+}
+
+// signedMessage 实际被签名的字节，版本和平台参与签名，防止安装包被挪用到其他版本或平台
+func signedMessage(version string, a *Asset) []byte {
+	return []byte(strings.Join([]string{
+		"aster-update-v1",
+		canonical(version),
+		a.Platform,
+		strings.ToLower(a.SHA256),
+	}, "\n"))
+}
+
+// SignAsset 根据安装包内容填充 Size、SHA256 并签名，发布工具使用
+func SignAsset(key ed25519.PrivateKey, version string, a *Asset, data []byte) {
+	sum := sha256.Sum256(data)
+	a.SHA256 = hex.EncodeToString(sum[:])
+	a.Size = int64(len(data))
+	a.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(key, signedMessage(version, a)))
+}
+
+// VerifyAsset 校验下载的安装包与清单中的大小、摘要一致，且签名来自发布公钥
+func VerifyAsset(pub ed25519.PublicKey, version string, a *Asset, data []byte) error {
+	if len(pub) != ed25519.PublicKeySize {
+		return fmt.Errorf("%w: malformed public key", ErrInvalidSignature)
+	}
+	if a.Size > 0 && int64(len(data)) != a.Size {
+		return fmt.Errorf("%w: size mismatch", ErrInvalidSignature)
+	}
+	sum := sha256.Sum256(data)
+	if !strings.EqualFold(hex.EncodeToString(sum[:]), a.SHA256) {
+		return fmt.Errorf("%w: digest mismatch", ErrInvalidSignature)
+	}
+	sig, err := base64.StdEncoding.DecodeString(a.Signature)
+	if err != nil {
+		return fmt.Errorf("%w: malformed signature", ErrInvalidSignature)
+	}
+	if !ed25519.Verify(pub, signedMessage(version, a), sig) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// ParsePublicKey 解析 base64 编码的发布公钥
+func ParsePublicKey(s string) (ed25519.PublicKey, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return nil, errors.New("update public key must be a base64 ed25519 public key")
+	}
+	return ed25519.PublicKey(key), nil
+}
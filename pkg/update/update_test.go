@@ -0,0 +1,110 @@
+package update
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestManifestCheck(t *testing.T) {
+	m := &Manifest{Releases: []Release{
+		{Version: "1.1.0", Channel: ChannelStable, Mandatory: true, Assets: []Asset{{Platform: "darwin/arm64", URL: "a"}}},
+		{Version: "1.2.0", Channel: ChannelStable, Assets: []Asset{{Platform: "darwin/arm64", URL: "b"}, {Platform: "linux/amd64", URL: "c"}}},
+		{Version: "1.3.0-beta.1", Channel: ChannelBeta, Assets: []Asset{{Platform: "darwin/arm64", URL: "d"}}},
+		{Version: "1.4.0", Channel: ChannelStable, Paused: true, Assets: []Asset{{Platform: "darwin/arm64", URL: "e"}}},
+	}}
+
+	res := m.Check(Query{Channel: ChannelStable, Platform: "darwin/arm64", Version: "1.0.0"})
+	if res == nil || res.Release.Version != "1.2.0" || res.Asset.URL != "b" {
+		t.Fatalf("stable check = %+v", res)
+	}
+	if !res.Mandatory {
+		t.Error("skipping a mandatory release should make the update mandatory")
+	}
+
+	res = m.Check(Query{Channel: ChannelBeta, Platform: "darwin/arm64", Version: "1.2.0"})
+	if res == nil || res.Release.Version != "1.3.0-beta.1" || res.Mandatory {
+		t.Fatalf("beta check = %+v", res)
+	}
+
+	if res := m.Check(Query{Platform: "windows/amd64", Version: "1.0.0"}); res != nil {
+		t.Errorf("no asset for platform, got %s", res.Release.Version)
+	}
+	if res := m.Check(Query{Platform: "linux/amd64", Version: "v1.2.0"}); res != nil {
+		t.Errorf("already up to date, got %s", res.Release.Version)
+	}
+}
+
+func TestInRollout(t *testing.T) {
+	in := 0
+	for i := range 1000 {
+		id := fmt.Sprintf("install-%d", i)
+		if InRollout(id, "1.2.0", 20) {
+			in++
+		}
+		if InRollout(id, "1.2.0", 20) != InRollout(id, "v1.2.0", 20) {
+			t.Fatal("rollout bucket should be stable")
+		}
+	}
+	if in < 150 || in > 250 {
+		t.Errorf("20%% rollout reached %d of 1000 installs", in)
+	}
+	if !InRollout("any", "1.2.0", 0) || !InRollout("any", "1.2.0", 100) {
+		t.Error("0 and 100 should mean full rollout")
+	}
+
+	m := &Manifest{Releases: []Release{{Version: "2.0.0", Channel: ChannelStable, Rollout: 20, Assets: []Asset{{Platform: "linux/amd64", URL: "x"}}}}}
+	for i := range 1000 {
+		id := fmt.Sprintf("install-%d", i)
+		offered := m.Check(Query{Platform: "linux/amd64", Version: "1.0.0", InstallID: id}) != nil
+		if offered != InRollout(id, "2.0.0", 20) {
+			t.Fatalf("Check and InRollout disagree for %s", id)
+		}
+	}
+}
+
+func TestSignAndVerifyAsset(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := []byte("installer bytes")
+	asset := &Asset{Platform: "darwin/arm64", URL: "aster.dmg"}
+	SignAsset(priv, "1.2.0", asset, data)
+
+	if err := VerifyAsset(pub, "1.2.0", asset, data); err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	if err := VerifyAsset(pub, "1.2.0", asset, []byte("tampered bytes!")); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("tampered data: err = %v", err)
+	}
+	if err := VerifyAsset(pub, "1.3.0", asset, data); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("signature reused for another version: err = %v", err)
+	}
+	other, _, _ := ed25519.GenerateKey(nil)
+	if err := VerifyAsset(other, "1.2.0", asset, data); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("wrong key: err = %v", err)
+	}
+
+	key, err := ParsePublicKey(base64.StdEncoding.EncodeToString(pub))
+	if err != nil || !key.Equal(pub) {
+		t.Errorf("ParsePublicKey = %v, %v", key, err)
+	}
+}
+
+func TestParseManifest(t *testing.T) {
+	_, err := ParseManifest([]byte(`{"releases":[{"version":"1.0.0","channel":"nightly","assets":[]}]}`))
+	if !errors.Is(err, ErrInvalidManifest) {
+		t.Errorf("unknown channel: err = %v", err)
+	}
+	_, err = ParseManifest([]byte(`{"releases":[{"version":"latest","channel":"stable"}]}`))
+	if !errors.Is(err, ErrInvalidManifest) {
+		t.Errorf("invalid version: err = %v", err)
+	}
+	_, err = ParseManifest([]byte(`{"releases":[{"version":"1.0.0","channel":"stable","assets":[{"platform":"linux/amd64","url":"x","sha256":"abc"}]}]}`))
+	if !errors.Is(err, ErrInvalidManifest) {
+		t.Errorf("malformed sha256: err = %v", err)
+	}
+}
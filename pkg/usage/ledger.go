@@ -0,0 +1,109 @@
+// Package usage 持久化记录模型调用的 Token 用量，并生成按租户/Agent 的月度用量与成本报表
+// 用量记录按月分区保存在 store.Store 中，报表成本按记录时间生效的定价计算（见 dashboard.CostCalculator）
+package usage
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/astercloud/aster/pkg/multitenancy"
+	"github.com/astercloud/aster/pkg/store"
+	"github.com/google/uuid"
+)
+
+// CollectionPrefix 用量记录集合名前缀，完整集合名为 usage_records_YYYY_MM
+const CollectionPrefix = "usage_records_"
+
+// monthLayout 报表月份格式
+const monthLayout = "2006-01"
+
+// Record 一次模型调用的用量记录
+type Record struct {
+	ID        string    `json:"id"`
+	TenantID  string    `json:"tenant_id,omitempty"`
+	AgentID   string    `json:"agent_id"`
+	Provider  string    `json:"provider,omitempty"`
+	Model     string    `json:"model"`
+	Timestamp time.Time `json:"timestamp"`
+
+	InputTokens      int64 `json:"input_tokens"`
+	OutputTokens     int64 `json:"output_tokens"`
+	CacheReadTokens  int64 `json:"cache_read_tokens,omitempty"`
+	CacheWriteTokens int64 `json:"cache_write_tokens,omitempty"`
+	// Batch 是否通过批处理 API 调用
+	Batch bool `json:"batch,omitempty"`
+}
+
+// Recorder 用量记录入口，Agent 通过 Dependencies.Usage 注入
+type Recorder interface {
+	Record(ctx context.Context, rec Record) error
+}
+
+// Ledger 基于 store.Store 的用量账本
+type Ledger struct {
+	store store.Store
+	now   func() time.Time
+}
+
+// NewLedger 创建用量账本
+// 传入 store.TenantStore 时使用其底层存储，租户通过 Record.TenantID 区分，报表才能覆盖所有租户
+func NewLedger(st store.Store) *Ledger {
+	if ts, ok := st.(*store.TenantStore); ok {
+		st = ts.Unwrap()
+	}
+	return &Ledger{store: st, now: time.Now}
+}
+
+// Record 保存一条用量记录，未设置的 ID、时间和租户自动补全
+func (l *Ledger) Record(ctx context.Context, rec Record) error {
+	if rec.ID == "" {
+		rec.ID = uuid.New().String()
+	}
+	if rec.Timestamp.IsZero() {
+		rec.Timestamp = l.now()
+	}
+	rec.Timestamp = rec.Timestamp.UTC()
+	if rec.TenantID == "" {
+		rec.TenantID = multitenancy.GetTenantIDOrDefault(ctx, "")
+	}
+	if err := l.store.Set(ctx, collection(rec.Timestamp), rec.ID, rec); err != nil {
+		return fmt.Errorf("save usage record: %w", err)
+	}
+	return nil
+}
+
+// List 返回 month 所在月份（UTC）的全部用量记录，按时间升序
+func (l *Ledger) List(ctx context.Context, month time.Time) ([]Record, error) {
+	items, err := l.store.List(ctx, collection(month))
+	if err != nil {
+		return nil, fmt.Errorf("list usage records: %w", err)
+	}
+	records := make([]Record, 0, len(items))
+	for _, item := range items {
+		var rec Record
+		if err := store.DecodeValue(item, &rec); err != nil || rec.ID == "" {
+			continue
+		}
+		records = append(records, rec)
+	}
+	slices.SortFunc(records, func(a, b Record) int {
+		return a.Timestamp.Compare(b.Timestamp)
+	})
+	return records, nil
+}
+
+// ParseMonth 解析 YYYY-MM 格式的月份
+func ParseMonth(s string) (time.Time, error) {
+	t, err := time.Parse(monthLayout, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid month %q, want YYYY-MM", s)
+	}
+	return t, nil
+}
+
+// collection 返回 t 所在月份（UTC）的集合名
+func collection(t time.Time) string {
+	return CollectionPrefix + t.UTC().Format("2006_01")
+}
@@ -0,0 +1,214 @@
+package usage
+
+import (
+	"cmp"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/astercloud/aster/pkg/dashboard"
+)
+
+// GroupBy 报表分组维度，逐级细分：租户 > Agent > 模型
+type GroupBy string
+
+const (
+	GroupByTenant GroupBy = "tenant"
+	GroupByAgent  GroupBy = "agent"
+	GroupByModel  GroupBy = "model"
+)
+
+// Valid 是否为支持的分组维度，空值表示默认按租户
+func (g GroupBy) Valid() bool {
+	switch g {
+	case "", GroupByTenant, GroupByAgent, GroupByModel:
+		return true
+	}
+	return false
+}
+
+// Format 报表导出格式
+type Format string
+
+const (
+	FormatJSON Format = "json"
+	FormatCSV  Format = "csv"
+)
+
+// ReportOptions 报表参数
+type ReportOptions struct {
+	// Month 报表月份（UTC），只使用年和月
+	Month   time.Time
+	GroupBy GroupBy // 默认按租户
+	// TenantID 非空时只统计该租户
+	TenantID string
+	// Calculator 成本计算器，为空时使用默认定价表
+	Calculator *dashboard.CostCalculator
+}
+
+// Row 报表行，按 GroupBy 未使用的维度为空
+type Row struct {
+	TenantID string `json:"tenant_id"`
+	AgentID  string `json:"agent_id,omitempty"`
+	Model    string `json:"model,omitempty"`
+
+	Requests         int64   `json:"requests"`
+	InputTokens      int64   `json:"input_tokens"`
+	OutputTokens     int64   `json:"output_tokens"`
+	CacheReadTokens  int64   `json:"cache_read_tokens"`
+	CacheWriteTokens int64   `json:"cache_write_tokens"`
+	Cost             float64 `json:"cost"`
+	// UnpricedRequests 成本无法换算为报表币种的请求数，其成本不计入 Cost
+	UnpricedRequests int64 `json:"unpriced_requests,omitempty"`
+}
+
+func (r *Row) add(rec Record, cost dashboard.CostAmount, currency string) {
+	r.Requests++
+	r.InputTokens += rec.InputTokens
+	r.OutputTokens += rec.OutputTokens
+	r.CacheReadTokens += rec.CacheReadTokens
+	r.CacheWriteTokens += rec.CacheWriteTokens
+	if cost.Currency == currency {
+		r.Cost += cost.Amount
+	} else {
+		r.UnpricedRequests++
+	}
+}
+
+// Report 月度用量与成本报表
+type Report struct {
+	Month       string    `json:"month"`
+	GroupBy     GroupBy   `json:"group_by"`
+	TenantID    string    `json:"tenant_id,omitempty"`
+	Currency    string    `json:"currency"`
+	GeneratedAt time.Time `json:"generated_at"`
+	Rows        []Row     `json:"rows"`
+	Total       Row       `json:"total"`
+}
+
+// Generate 根据账本生成月度报表
+func Generate(ctx context.Context, ledger *Ledger, opts ReportOptions) (*Report, error) {
+	if !opts.GroupBy.Valid() {
+		return nil, fmt.Errorf("invalid group_by %q, want tenant, agent or model", opts.GroupBy)
+	}
+	groupBy := cmp.Or(opts.GroupBy, GroupByTenant)
+	calc := opts.Calculator
+	if calc == nil {
+		calc = dashboard.NewCostCalculator(nil)
+	}
+
+	records, err := ledger.List(ctx, opts.Month)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{
+		Month:       opts.Month.UTC().Format(monthLayout),
+		GroupBy:     groupBy,
+		TenantID:    opts.TenantID,
+		Currency:    calc.Currency(),
+		GeneratedAt: ledger.now().UTC(),
+		Rows:        []Row{},
+	}
+	type rowKey struct{ tenant, agent, model string }
+	rows := make(map[rowKey]*Row)
+	for _, rec := range records {
+		if opts.TenantID != "" && rec.TenantID != opts.TenantID {
+			continue
+		}
+		// 按记录时间生效的定价计费，月中调价也能正确计算
+		cost := calc.CalculateUsageAt(rec.Model, dashboard.Usage{
+			InputTokens:      rec.InputTokens,
+			OutputTokens:     rec.OutputTokens,
+			CacheReadTokens:  rec.CacheReadTokens,
+			CacheWriteTokens: rec.CacheWriteTokens,
+			Batch:            rec.Batch,
+		}, rec.Timestamp).TotalCost
+
+		key := rowKey{tenant: rec.TenantID}
+		if groupBy != GroupByTenant {
+			key.agent = rec.AgentID
+		}
+		if groupBy == GroupByModel {
+			key.model = rec.Model
+		}
+		row, ok := rows[key]
+		if !ok {
+			row = &Row{TenantID: key.tenant, AgentID: key.agent, Model: key.model}
+			rows[key] = row
+		}
+		row.add(rec, cost, report.Currency)
+		report.Total.add(rec, cost, report.Currency)
+	}
+
+	for _, row := range rows {
+		report.Rows = append(report.Rows, *row)
+	}
+	slices.SortFunc(report.Rows, func(a, b Row) int {
+		return cmp.Or(
+			cmp.Compare(a.TenantID, b.TenantID),
+			cmp.Compare(a.AgentID, b.AgentID),
+			cmp.Compare(a.Model, b.Model),
+		)
+	})
+	return report, nil
+}
+
+// Write 按 format 导出报表
+func (r *Report) Write(w io.Writer, format Format) error {
+	switch format {
+	case FormatJSON, "":
+		return r.WriteJSON(w)
+	case FormatCSV:
+		return r.WriteCSV(w)
+	default:
+		return fmt.Errorf("unsupported format %q, want json or csv", format)
+	}
+}
+
+// WriteJSON 以 JSON 导出报表
+func (r *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// csvHeader CSV 列固定不变，便于导入计费系统
+var csvHeader = []string{
+	"month", "tenant_id", "agent_id", "model", "requests",
+	"input_tokens", "output_tokens", "cache_read_tokens", "cache_write_tokens",
+	"cost", "currency", "unpriced_requests",
+}
+
+// WriteCSV 以 CSV 导出报表，每个分组一行，不含合计行
+func (r *Report) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, row := range r.Rows {
+		if err := cw.Write([]string{
+			r.Month,
+			row.TenantID,
+			row.AgentID,
+			row.Model,
+			strconv.FormatInt(row.Requests, 10),
+			strconv.FormatInt(row.InputTokens, 10),
+			strconv.FormatInt(row.OutputTokens, 10),
+			strconv.FormatInt(row.CacheReadTokens, 10),
+			strconv.FormatInt(row.CacheWriteTokens, 10),
+			strconv.FormatFloat(row.Cost, 'f', 6, 64),
+			r.Currency,
+			strconv.FormatInt(row.UnpricedRequests, 10),
+		}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,245 @@
+package usage
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/astercloud/aster/pkg/dashboard"
+	"github.com/astercloud/aster/pkg/multitenancy"
+	"github.com/astercloud/aster/pkg/store"
+	"github.com/google/uuid"
+)
+
+// ToolCollectionPrefix 工具调用记录集合名前缀，完整集合名为 tool_usage_YYYY_MM
+const ToolCollectionPrefix = "tool_usage_"
+
+// 持续失败工具的判定阈值
+const (
+	// FailingToolMinCalls 调用次数不足时不下结论
+	FailingToolMinCalls = 5
+	// FailingToolMaxSuccessRate 成功率低于该值视为持续失败
+	FailingToolMaxSuccessRate = 0.5
+)
+
+// ToolRecord 一次工具调用记录
+type ToolRecord struct {
+	ID         string    `json:"id"`
+	TenantID   string    `json:"tenant_id,omitempty"`
+	AgentID    string    `json:"agent_id"`
+	TemplateID string    `json:"template_id"`
+	Tool       string    `json:"tool"`
+	Timestamp  time.Time `json:"timestamp"`
+
+	Success    bool   `json:"success"`
+	ErrorCode  string `json:"error_code,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+	// ResultTokens 工具结果进入上下文的估算 Token 数，按 Model 的输入价格计入成本
+	ResultTokens int64  `json:"result_tokens"`
+	Model        string `json:"model,omitempty"`
+}
+
+// ToolRecorder 工具调用记录入口，Agent 通过 Dependencies.ToolUsage 注入
+type ToolRecorder interface {
+	RecordTool(ctx context.Context, rec ToolRecord) error
+}
+
+// RecordTool 保存一条工具调用记录，未设置的 ID、时间和租户自动补全
+func (l *Ledger) RecordTool(ctx context.Context, rec ToolRecord) error {
+	if rec.ID == "" {
+		rec.ID = uuid.New().String()
+	}
+	if rec.Timestamp.IsZero() {
+		rec.Timestamp = l.now()
+	}
+	rec.Timestamp = rec.Timestamp.UTC()
+	if rec.TenantID == "" {
+		rec.TenantID = multitenancy.GetTenantIDOrDefault(ctx, "")
+	}
+	if err := l.store.Set(ctx, toolCollection(rec.Timestamp), rec.ID, rec); err != nil {
+		return fmt.Errorf("save tool usage record: %w", err)
+	}
+	return nil
+}
+
+// ListTools 返回 [since, until) 内的工具调用记录，按时间升序
+func (l *Ledger) ListTools(ctx context.Context, since, until time.Time) ([]ToolRecord, error) {
+	var records []ToolRecord
+	for month := monthStart(since); month.Before(until); month = month.AddDate(0, 1, 0) {
+		items, err := l.store.List(ctx, toolCollection(month))
+		if err != nil {
+			return nil, fmt.Errorf("list tool usage records: %w", err)
+		}
+		for _, item := range items {
+			var rec ToolRecord
+			if err := store.DecodeValue(item, &rec); err != nil || rec.ID == "" {
+				continue
+			}
+			if rec.Timestamp.Before(since) || !rec.Timestamp.Before(until) {
+				continue
+			}
+			records = append(records, rec)
+		}
+	}
+	slices.SortFunc(records, func(a, b ToolRecord) int {
+		return a.Timestamp.Compare(b.Timestamp)
+	})
+	return records, nil
+}
+
+// ToolStatsOptions 工具统计参数
+type ToolStatsOptions struct {
+	Since time.Time
+	Until time.Time
+	// TemplateID/TenantID 非空时只统计对应模板/租户
+	TemplateID string
+	TenantID   string
+	// Calculator 成本计算器，为空时使用默认定价表
+	Calculator *dashboard.CostCalculator
+}
+
+// ToolStat 单个模板下单个工具的统计
+type ToolStat struct {
+	TemplateID  string                       `json:"template_id"`
+	Tool        string                       `json:"tool"`
+	Calls       int64                        `json:"calls"`
+	Failures    int64                        `json:"failures"`
+	SuccessRate float64                      `json:"success_rate"`
+	Latency     dashboard.LatencyPercentiles `json:"latency_ms"`
+	// ResultTokens 工具结果累计进入上下文的估算 Token 数
+	ResultTokens int64   `json:"result_tokens"`
+	Cost         float64 `json:"cost"`
+	// TopErrors 失败时的错误码及次数
+	TopErrors map[string]int64 `json:"top_errors,omitempty"`
+	LastUsed  time.Time        `json:"last_used"`
+}
+
+// Failing 是否为持续失败的工具
+func (s *ToolStat) Failing() bool {
+	return s.Calls >= FailingToolMinCalls && s.SuccessRate < FailingToolMaxSuccessRate
+}
+
+// ToolReport 工具使用统计，按模板和工具分组
+type ToolReport struct {
+	Since       time.Time  `json:"since"`
+	Until       time.Time  `json:"until"`
+	TemplateID  string     `json:"template_id,omitempty"`
+	Currency    string     `json:"currency"`
+	GeneratedAt time.Time  `json:"generated_at"`
+	Tools       []ToolStat `json:"tools"`
+}
+
+// GenerateToolStats 汇总工具调用次数、成功率、延迟和结果 Token 成本
+func GenerateToolStats(ctx context.Context, ledger *Ledger, opts ToolStatsOptions) (*ToolReport, error) {
+	calc := opts.Calculator
+	if calc == nil {
+		calc = dashboard.NewCostCalculator(nil)
+	}
+	until := opts.Until
+	if until.IsZero() {
+		until = ledger.now()
+	}
+
+	records, err := ledger.ListTools(ctx, opts.Since, until)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ToolReport{
+		Since:       opts.Since.UTC(),
+		Until:       until.UTC(),
+		TemplateID:  opts.TemplateID,
+		Currency:    calc.Currency(),
+		GeneratedAt: ledger.now().UTC(),
+		Tools:       []ToolStat{},
+	}
+	type statKey struct{ template, tool string }
+	stats := make(map[statKey]*ToolStat)
+	latencies := make(map[statKey][]int64)
+	for _, rec := range records {
+		if opts.TemplateID != "" && rec.TemplateID != opts.TemplateID {
+			continue
+		}
+		if opts.TenantID != "" && rec.TenantID != opts.TenantID {
+			continue
+		}
+		key := statKey{template: rec.TemplateID, tool: rec.Tool}
+		stat, ok := stats[key]
+		if !ok {
+			stat = &ToolStat{TemplateID: rec.TemplateID, Tool: rec.Tool}
+			stats[key] = stat
+		}
+		stat.Calls++
+		if !rec.Success {
+			stat.Failures++
+			if rec.ErrorCode != "" {
+				if stat.TopErrors == nil {
+					stat.TopErrors = make(map[string]int64)
+				}
+				stat.TopErrors[rec.ErrorCode]++
+			}
+		}
+		stat.ResultTokens += rec.ResultTokens
+		// 工具结果在下一轮作为输入 Token 计费
+		if cost := calc.CalculateUsageAt(rec.Model, dashboard.Usage{InputTokens: rec.ResultTokens}, rec.Timestamp).TotalCost; cost.Currency == report.Currency {
+			stat.Cost += cost.Amount
+		}
+		stat.LastUsed = rec.Timestamp
+		latencies[key] = append(latencies[key], rec.DurationMs)
+	}
+
+	for key, stat := range stats {
+		stat.SuccessRate = float64(stat.Calls-stat.Failures) / float64(stat.Calls)
+		stat.Latency = dashboard.CalculatePercentiles(latencies[key])
+		report.Tools = append(report.Tools, *stat)
+	}
+	slices.SortFunc(report.Tools, func(a, b ToolStat) int {
+		return cmp.Or(
+			cmp.Compare(a.TemplateID, b.TemplateID),
+			cmp.Compare(b.Calls, a.Calls),
+			cmp.Compare(a.Tool, b.Tool),
+		)
+	})
+	return report, nil
+}
+
+// ToolInsights 为持续失败的工具生成改进建议，提示模板作者修复或移除
+func ToolInsights(report *ToolReport) []dashboard.Insight {
+	var insights []dashboard.Insight
+	for _, stat := range report.Tools {
+		if !stat.Failing() {
+			continue
+		}
+		insights = append(insights, dashboard.Insight{
+			ID:          "failing_tool_" + stat.TemplateID + "_" + stat.Tool,
+			Type:        dashboard.InsightTypeReliability,
+			Severity:    "warning",
+			Title:       "工具持续失败: " + stat.Tool,
+			Description: fmt.Sprintf("模板 %s 中的工具 %s 调用 %d 次，成功率仅 %.0f%%", stat.TemplateID, stat.Tool, stat.Calls, stat.SuccessRate*100),
+			Suggestion:  "检查工具配置和错误码，修复工具实现或从模板中移除该工具",
+			Data: map[string]any{
+				"template_id":  stat.TemplateID,
+				"tool_name":    stat.Tool,
+				"calls":        stat.Calls,
+				"failures":     stat.Failures,
+				"success_rate": stat.SuccessRate,
+				"top_errors":   stat.TopErrors,
+			},
+			CreatedAt: report.GeneratedAt,
+		})
+	}
+	return insights
+}
+
+// toolCollection 返回 t 所在月份（UTC）的工具调用记录集合名
+func toolCollection(t time.Time) string {
+	return ToolCollectionPrefix + t.UTC().Format("2006_01")
+}
+
+// monthStart 返回 t 所在月份（UTC）的第一天
+func monthStart(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
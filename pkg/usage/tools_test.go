@@ -0,0 +1,59 @@
+package usage
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestGenerateToolStats(t *testing.T) {
+	l := newTestLedger(t)
+	ctx := context.Background()
+	jan := time.Date(2025, 1, 31, 12, 0, 0, 0, time.UTC)
+	feb := time.Date(2025, 2, 1, 12, 0, 0, 0, time.UTC)
+
+	records := []ToolRecord{
+		{TemplateID: "coder", Tool: "Read", Success: true, DurationMs: 10, ResultTokens: 1_000_000, Model: "gpt-4o", Timestamp: jan},
+		{TemplateID: "coder", Tool: "Read", Success: true, DurationMs: 30, ResultTokens: 1_000_000, Model: "gpt-4o", Timestamp: feb},
+		{TemplateID: "other", Tool: "Read", Success: true, DurationMs: 20, Timestamp: feb},
+		// 超出统计区间
+		{TemplateID: "coder", Tool: "Read", Success: true, Timestamp: feb.Add(48 * time.Hour)},
+	}
+	for range FailingToolMinCalls {
+		records = append(records, ToolRecord{TemplateID: "coder", Tool: "WebFetch", ErrorCode: "tool_timeout", DurationMs: 5000, Timestamp: feb})
+	}
+	for _, rec := range records {
+		if err := l.RecordTool(ctx, rec); err != nil {
+			t.Fatalf("RecordTool() error = %v", err)
+		}
+	}
+
+	report, err := GenerateToolStats(ctx, l, ToolStatsOptions{
+		Since:      jan.Add(-time.Hour),
+		Until:      feb.Add(time.Hour),
+		TemplateID: "coder",
+	})
+	if err != nil {
+		t.Fatalf("GenerateToolStats() error = %v", err)
+	}
+	if len(report.Tools) != 2 {
+		t.Fatalf("Tools = %+v, want Read and WebFetch for coder", report.Tools)
+	}
+
+	fetch, read := report.Tools[0], report.Tools[1]
+	if fetch.Tool != "WebFetch" || fetch.Calls != int64(FailingToolMinCalls) || fetch.SuccessRate != 0 || fetch.TopErrors["tool_timeout"] != int64(FailingToolMinCalls) {
+		t.Errorf("WebFetch stat = %+v", fetch)
+	}
+	if read.Tool != "Read" || read.Calls != 2 || read.SuccessRate != 1 || read.Latency.Max != 30 || read.ResultTokens != 2_000_000 {
+		t.Errorf("Read stat = %+v", read)
+	}
+	// gpt-4o 输入 $2.5/M
+	if read.Cost != 5 {
+		t.Errorf("Read cost = %v, want 5", read.Cost)
+	}
+
+	insights := ToolInsights(report)
+	if len(insights) != 1 || insights[0].ID != "failing_tool_coder_WebFetch" {
+		t.Errorf("ToolInsights() = %+v", insights)
+	}
+}
@@ -0,0 +1,115 @@
+package usage
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/astercloud/aster/pkg/dashboard"
+	"github.com/astercloud/aster/pkg/multitenancy"
+	"github.com/astercloud/aster/pkg/store"
+)
+
+func newTestLedger(t *testing.T) *Ledger {
+	t.Helper()
+	st, err := store.NewJSONStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewJSONStore() error = %v", err)
+	}
+	// 租户包装的存储也写入同一个账本
+	return NewLedger(store.NewTenantStore(st))
+}
+
+func TestLedger_RecordAndList(t *testing.T) {
+	l := newTestLedger(t)
+	ctx := multitenancy.WithTenantID(context.Background(), "acme")
+
+	jan := time.Date(2025, 1, 31, 23, 0, 0, 0, time.UTC)
+	feb := time.Date(2025, 2, 1, 1, 0, 0, 0, time.UTC)
+	for _, rec := range []Record{
+		{AgentID: "a1", Model: "gpt-4o", InputTokens: 10, Timestamp: jan},
+		{AgentID: "a1", Model: "gpt-4o", InputTokens: 20, Timestamp: feb},
+	} {
+		if err := l.Record(ctx, rec); err != nil {
+			t.Fatalf("Record() error = %v", err)
+		}
+	}
+
+	records, err := l.List(context.Background(), jan)
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(records) != 1 || records[0].TenantID != "acme" || records[0].InputTokens != 10 || records[0].ID == "" {
+		t.Errorf("List(2025-01) = %+v", records)
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	l := newTestLedger(t)
+	ctx := context.Background()
+	at := time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC)
+
+	records := []Record{
+		{TenantID: "acme", AgentID: "a1", Model: "gpt-4o", InputTokens: 1_000_000, OutputTokens: 1_000_000, Timestamp: at},
+		{TenantID: "acme", AgentID: "a1", Model: "gpt-4o", InputTokens: 1_000_000, CacheReadTokens: 1_000_000, Batch: true, Timestamp: at},
+		{TenantID: "acme", AgentID: "a2", Model: "deepseek-chat", InputTokens: 1_000_000, OutputTokens: 1_000_000, Timestamp: at},
+		{TenantID: "globex", AgentID: "b1", Model: "euro-model", InputTokens: 1_000_000, Timestamp: at},
+	}
+	for _, rec := range records {
+		if err := l.Record(ctx, rec); err != nil {
+			t.Fatalf("Record() error = %v", err)
+		}
+	}
+
+	calc := dashboard.NewCostCalculator(nil)
+	calc.SetPricing("euro-model", dashboard.ModelPricing{Model: "euro-model", InputPricePerM: 1, Currency: "EUR"})
+
+	report, err := Generate(ctx, l, ReportOptions{Month: at, GroupBy: GroupByAgent, Calculator: calc})
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if report.Month != "2025-01" || report.Currency != "USD" || len(report.Rows) != 3 {
+		t.Fatalf("report = %+v", report)
+	}
+
+	// a1: 2.5 + 10，批处理半价 (2.5 + 1.25) / 2；a2: deepseek 3 CNY 折合 0.42 USD
+	want := map[string]float64{"a1": 12.5 + 1.875, "a2": 0.42, "b1": 0}
+	for _, row := range report.Rows {
+		if math.Abs(row.Cost-want[row.AgentID]) > 1e-9 {
+			t.Errorf("row %s cost = %v, want %v", row.AgentID, row.Cost, want[row.AgentID])
+		}
+	}
+	if b1 := report.Rows[2]; b1.TenantID != "globex" || b1.UnpricedRequests != 1 {
+		t.Errorf("globex row = %+v, want one unpriced request", b1)
+	}
+	if report.Total.Requests != 4 || report.Total.CacheReadTokens != 1_000_000 {
+		t.Errorf("total = %+v", report.Total)
+	}
+
+	byTenant, err := Generate(ctx, l, ReportOptions{Month: at, TenantID: "acme", Calculator: calc})
+	if err != nil {
+		t.Fatalf("Generate(tenant) error = %v", err)
+	}
+	if len(byTenant.Rows) != 1 || byTenant.Rows[0].Requests != 3 || byTenant.Rows[0].AgentID != "" {
+		t.Errorf("tenant report rows = %+v", byTenant.Rows)
+	}
+
+	var buf bytes.Buffer
+	if err := report.Write(&buf, FormatCSV); err != nil {
+		t.Fatalf("WriteCSV() error = %v", err)
+	}
+	lines, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("read csv: %v", err)
+	}
+	if len(lines) != 4 || lines[0][0] != "month" || lines[1][2] != "a1" || lines[1][9] != "14.375000" {
+		t.Errorf("csv = %v", lines)
+	}
+
+	if _, err := Generate(ctx, l, ReportOptions{Month: at, GroupBy: "day"}); err == nil {
+		t.Error("Generate() should reject an unknown group_by")
+	}
+}
@@ -0,0 +1,61 @@
+package vector
+
+import (
+	"context"
+	"fmt"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// DefaultEmbedBatchSize 单次请求默认发送的文本数量。
+// OpenAI 单次最多 2048 条、Voyage 最多 1000 条, 这里取较保守的值以控制请求体大小。
+const DefaultEmbedBatchSize = 128
+
+// BatchEmbedder 把大量文本拆分成多个批次调用底层 Embedder, 结果按输入顺序拼接。
+type BatchEmbedder struct {
+	Embedder Embedder
+	// Size 每批文本数量, 默认 DefaultEmbedBatchSize
+	Size int
+	// Concurrency 并发请求的批次数, 默认 1(顺序执行)
+	Concurrency int
+}
+
+// NewBatchEmbedder 创建 BatchEmbedder。
+func NewBatchEmbedder(inner Embedder, size, concurrency int) *BatchEmbedder {
+	if size <= 0 {
+		size = DefaultEmbedBatchSize
+	}
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	return &BatchEmbedder{Embedder: inner, Size: size, Concurrency: concurrency}
+}
+
+// EmbedText 分批生成向量。
+func (e *BatchEmbedder) EmbedText(ctx context.Context, texts []string) ([][]float32, error) {
+	if len(texts) <= e.Size {
+		return e.Embedder.EmbedText(ctx, texts)
+	}
+
+	out := make([][]float32, len(texts))
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(e.Concurrency)
+	for start := 0; start < len(texts); start += e.Size {
+		end := min(start+e.Size, len(texts))
+		g.Go(func() error {
+			vecs, err := e.Embedder.EmbedText(gctx, texts[start:end])
+			if err != nil {
+				return fmt.Errorf("embed batch %d-%d: %w", start, end, err)
+			}
+			if len(vecs) != end-start {
+				return fmt.Errorf("embed batch %d-%d: got %d vectors", start, end, len(vecs))
+			}
+			copy(out[start:end], vecs)
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
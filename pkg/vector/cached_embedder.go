@@ -0,0 +1,136 @@
+package vector
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// CachedEmbedder 以内容哈希为键把向量缓存到磁盘, 相同文本不会重复调用底层 Embedder。
+// 缓存文件位于 {Dir}/{Namespace}/{hash[:2]}/{hash}.vec, 内容为小端序 float32 数组。
+// Namespace 应区分提供商、模型和维度, 不同模型的向量不能混用。
+type CachedEmbedder struct {
+	embedder  Embedder
+	dir       string
+	namespace string
+}
+
+var unsafeNamespaceChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// NewCachedEmbedder 创建 CachedEmbedder。
+// namespace 通常为 "provider/model" 形式, 非法路径字符会被替换。
+func NewCachedEmbedder(inner Embedder, dir, namespace string) (*CachedEmbedder, error) {
+	if dir == "" {
+		return nil, errors.New("cache dir is required for CachedEmbedder")
+	}
+	if namespace == "" {
+		namespace = "default"
+	}
+	namespace = unsafeNamespaceChars.ReplaceAllString(namespace, "_")
+	if err := os.MkdirAll(filepath.Join(dir, namespace), 0o755); err != nil {
+		return nil, fmt.Errorf("create embedding cache dir: %w", err)
+	}
+	return &CachedEmbedder{embedder: inner, dir: dir, namespace: namespace}, nil
+}
+
+// EmbedText 先读取缓存, 未命中的文本去重后一次性交给底层 Embedder, 结果写回缓存。
+// 缓存写入失败不影响返回结果。
+func (e *CachedEmbedder) EmbedText(ctx context.Context, texts []string) ([][]float32, error) {
+	out := make([][]float32, len(texts))
+	missing := make(map[string][]int)
+	var pending []string
+	for i, text := range texts {
+		key := contentHash(text)
+		if vec, ok := e.load(key); ok {
+			out[i] = vec
+			continue
+		}
+		if _, seen := missing[key]; !seen {
+			pending = append(pending, text)
+		}
+		missing[key] = append(missing[key], i)
+	}
+	if len(pending) == 0 {
+		return out, nil
+	}
+
+	vecs, err := e.embedder.EmbedText(ctx, pending)
+	if err != nil {
+		return nil, err
+	}
+	if len(vecs) != len(pending) {
+		return nil, fmt.Errorf("embedding response mismatch: got %d vectors, want %d", len(vecs), len(pending))
+	}
+	for i, text := range pending {
+		key := contentHash(text)
+		_ = e.store(key, vecs[i])
+		for _, idx := range missing[key] {
+			out[idx] = vecs[i]
+		}
+	}
+	return out, nil
+}
+
+func contentHash(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])
+}
+
+func (e *CachedEmbedder) path(key string) string {
+	return filepath.Join(e.dir, e.namespace, key[:2], key+".vec")
+}
+
+func (e *CachedEmbedder) load(key string) ([]float32, bool) {
+	data, err := os.ReadFile(e.path(key))
+	if err != nil || len(data) == 0 || len(data)%4 != 0 {
+		return nil, false
+	}
+	vec := make([]float32, len(data)/4)
+	for i := range vec {
+		vec[i] = math.Float32frombits(binary.LittleEndian.Uint32(data[i*4:]))
+	}
+	return vec, true
+}
+
+// store 先写临时文件再重命名, 避免并发读到不完整的向量。
+func (e *CachedEmbedder) store(key string, vec []float32) error {
+	path := e.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	data := make([]byte, len(vec)*4)
+	for i, v := range vec {
+		binary.LittleEndian.PutUint32(data[i*4:], math.Float32bits(v))
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), key+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Purge 删除当前命名空间的全部缓存。
+func (e *CachedEmbedder) Purge() error {
+	err := os.RemoveAll(filepath.Join(e.dir, e.namespace))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,67 @@
+package vector
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/astercloud/aster/pkg/httpclient"
+)
+
+// CohereReranker 基于 Cohere rerank 接口的 Reranker 实现。
+// 调用 POST {BaseURL}/v2/rerank, 请求格式:
+//
+//	{ "model": "rerank-v3.5", "query": "...", "documents": [...], "top_n": 5 }
+type CohereReranker struct {
+	BaseURL string
+	APIKey  string
+	Model   string
+	Client  *http.Client
+}
+
+// NewCohereReranker 创建 CohereReranker。
+func NewCohereReranker(baseURL, apiKey, model string) *CohereReranker {
+	if baseURL == "" {
+		baseURL = "https://api.cohere.com"
+	}
+	if model == "" {
+		model = "rerank-v3.5"
+	}
+	return &CohereReranker{
+		BaseURL: baseURL,
+		APIKey:  apiKey,
+		Model:   model,
+		Client:  httpclient.New(30 * time.Second),
+	}
+}
+
+// Rerank 调用 Cohere rerank 接口。
+func (r *CohereReranker) Rerank(ctx context.Context, query string, documents []string, topN int) ([]RerankResult, error) {
+	if len(documents) == 0 {
+		return []RerankResult{}, nil
+	}
+	if r.APIKey == "" {
+		return nil, errors.New("API key is required for CohereReranker")
+	}
+
+	body := map[string]any{"model": r.Model, "query": query, "documents": documents}
+	if topN > 0 {
+		body["top_n"] = topN
+	}
+	var resp struct {
+		Results []struct {
+			Index          int     `json:"index"`
+			RelevanceScore float64 `json:"relevance_score"`
+		} `json:"results"`
+	}
+	if err := postRerank(ctx, r.Client, r.BaseURL+"/v2/rerank", r.APIKey, body, &resp); err != nil {
+		return nil, err
+	}
+
+	results := make([]RerankResult, len(resp.Results))
+	for i, item := range resp.Results {
+		results[i] = RerankResult{Index: item.Index, Score: item.RelevanceScore}
+	}
+	return sortRerankResults(results, len(documents), topN)
+}
@@ -0,0 +1,56 @@
+package vector
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/astercloud/aster/pkg/httpclient"
+)
+
+// CrossEncoderReranker 调用本地部署的交叉编码器服务的 Reranker 实现。
+// 兼容 text-embeddings-inference / infinity 的 rerank 接口, 这些服务可以直接加载
+// ONNX 格式的 cross-encoder 模型(如 BAAI/bge-reranker-v2-m3), 数据不出内网。
+// 调用 POST {BaseURL}/rerank, 请求格式:
+//
+//	{ "query": "...", "texts": [...] }
+type CrossEncoderReranker struct {
+	BaseURL string
+	// APIKey 可选, 服务启用鉴权时使用
+	APIKey string
+	Client *http.Client
+}
+
+// NewCrossEncoderReranker 创建 CrossEncoderReranker。
+// baseURL 默认为 "http://localhost:8080"。
+func NewCrossEncoderReranker(baseURL string) *CrossEncoderReranker {
+	if baseURL == "" {
+		baseURL = "http://localhost:8080"
+	}
+	return &CrossEncoderReranker{
+		BaseURL: baseURL,
+		Client:  httpclient.New(30 * time.Second),
+	}
+}
+
+// Rerank 调用本地 rerank 接口, 服务返回全部文档的分数, 截断在本地完成。
+func (r *CrossEncoderReranker) Rerank(ctx context.Context, query string, documents []string, topN int) ([]RerankResult, error) {
+	if len(documents) == 0 {
+		return []RerankResult{}, nil
+	}
+
+	var resp []struct {
+		Index int     `json:"index"`
+		Score float64 `json:"score"`
+	}
+	body := map[string]any{"query": query, "texts": documents}
+	if err := postRerank(ctx, r.Client, r.BaseURL+"/rerank", r.APIKey, body, &resp); err != nil {
+		return nil, err
+	}
+
+	results := make([]RerankResult, len(resp))
+	for i, item := range resp {
+		results[i] = RerankResult{Index: item.Index, Score: item.Score}
+	}
+	return sortRerankResults(results, len(documents), topN)
+}
@@ -0,0 +1,120 @@
+package vector
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+type countingEmbedder struct {
+	calls atomic.Int32
+	texts atomic.Int32
+}
+
+func (c *countingEmbedder) EmbedText(ctx context.Context, texts []string) ([][]float32, error) {
+	c.calls.Add(1)
+	c.texts.Add(int32(len(texts)))
+	out := make([][]float32, len(texts))
+	for i, t := range texts {
+		out[i] = []float32{float32(len(t)), 0.5}
+	}
+	return out, nil
+}
+
+func TestCachedEmbedder(t *testing.T) {
+	ctx := context.Background()
+	inner := &countingEmbedder{}
+	dir := t.TempDir()
+	e, err := NewCachedEmbedder(inner, dir, "openai/text-embedding-3-small")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vecs, err := e.EmbedText(ctx, []string{"a", "bb", "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vecs) != 3 || vecs[0][0] != 1 || vecs[1][0] != 2 || vecs[2][0] != 1 {
+		t.Fatalf("vecs = %v", vecs)
+	}
+	if inner.texts.Load() != 2 {
+		t.Errorf("duplicate texts should be embedded once, sent %d", inner.texts.Load())
+	}
+
+	// 新实例从磁盘读取缓存
+	e, _ = NewCachedEmbedder(inner, dir, "openai/text-embedding-3-small")
+	vecs, _ = e.EmbedText(ctx, []string{"bb", "ccc"})
+	if inner.calls.Load() != 2 || inner.texts.Load() != 3 {
+		t.Errorf("calls = %d, texts = %d", inner.calls.Load(), inner.texts.Load())
+	}
+	if vecs[0][0] != 2 || vecs[0][1] != 0.5 || vecs[1][0] != 3 {
+		t.Errorf("vecs = %v", vecs)
+	}
+
+	// 不同命名空间不共享缓存
+	other, _ := NewCachedEmbedder(inner, dir, "voyage/voyage-3")
+	_, _ = other.EmbedText(ctx, []string{"a"})
+	if inner.calls.Load() != 3 {
+		t.Errorf("namespaces must not share cache entries")
+	}
+}
+
+func TestBatchEmbedder(t *testing.T) {
+	inner := &countingEmbedder{}
+	e := NewBatchEmbedder(inner, 2, 3)
+	vecs, err := e.EmbedText(context.Background(), []string{"a", "bb", "ccc", "dddd", "eeeee"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inner.calls.Load() != 3 {
+		t.Errorf("calls = %d, want 3", inner.calls.Load())
+	}
+	for i, v := range vecs {
+		if int(v[0]) != i+1 {
+			t.Errorf("vecs[%d] = %v, order not preserved", i, v)
+		}
+	}
+}
+
+func TestRemoteEmbedders(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Input []string `json:"input"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		switch r.URL.Path {
+		case "/v1/embeddings":
+			// 故意倒序返回, 验证按 index 还原顺序
+			data := make([]map[string]any, len(body.Input))
+			for i := range body.Input {
+				data[len(body.Input)-1-i] = map[string]any{"index": i, "embedding": []float64{float64(i)}}
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{"data": data})
+		case "/api/embed":
+			embeddings := make([][]float64, len(body.Input))
+			for i := range body.Input {
+				embeddings[i] = []float64{float64(i)}
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{"embeddings": embeddings})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	for name, e := range map[string]Embedder{
+		"voyage": NewVoyageEmbedder(srv.URL, "key", ""),
+		"ollama": NewOllamaEmbedder(srv.URL, ""),
+	} {
+		vecs, err := e.EmbedText(context.Background(), []string{"x", "y"})
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if len(vecs) != 2 || vecs[0][0] != 0 || vecs[1][0] != 1 {
+			t.Errorf("%s: vecs = %v", name, vecs)
+		}
+	}
+}
@@ -3,7 +3,9 @@ package factory
 import (
 	"errors"
 	"fmt"
+	"maps"
 
+	"github.com/astercloud/aster/pkg/types"
 	"github.com/astercloud/aster/pkg/vector"
 	"github.com/astercloud/aster/pkg/vector/weaviate"
 )
@@ -24,6 +26,7 @@ func NewStoreFactory() *StoreFactory {
 
 	// 注册内置存储类型
 	factory.Register("memory", createMemoryStore)
+	factory.Register("file", createFileStore)
 	factory.Register("weaviate", createWeaviateStore)
 
 	return factory
@@ -49,6 +52,15 @@ func createMemoryStore(config map[string]any) (vector.VectorStore, error) {
 	return vector.NewMemoryStore(), nil
 }
 
+// createFileStore 创建本地文件存储
+func createFileStore(config map[string]any) (vector.VectorStore, error) {
+	path, _ := config["path"].(string)
+	if path == "" {
+		return nil, errors.New("path is required for file vector store")
+	}
+	return vector.OpenFileStore(path)
+}
+
 // createWeaviateStore 创建 Weaviate 存储
 func createWeaviateStore(config map[string]any) (vector.VectorStore, error) {
 	cfg := &weaviate.Config{}
@@ -89,6 +101,10 @@ func NewEmbedderFactory() *EmbedderFactory {
 
 	// 注册内置嵌入器
 	factory.Register("openai", createOpenAIEmbedder)
+	factory.Register("voyage", createVoyageEmbedder)
+	factory.Register("ollama", createOllamaEmbedder)
+	factory.Register("local", createOllamaEmbedder)
+	factory.Register("mock", createMockEmbedder)
 
 	return factory
 }
@@ -99,13 +115,69 @@ func (f *EmbedderFactory) Register(provider string, creator EmbedderCreator) {
 }
 
 // Create 创建嵌入器实例
+// 通用配置对所有提供商生效:
+//   - batch_size / batch_concurrency: 按批次拆分请求
+//   - cache_dir: 以内容哈希为键把向量缓存到磁盘
 func (f *EmbedderFactory) Create(provider string, config map[string]any) (vector.Embedder, error) {
 	creator, ok := f.creators[provider]
 	if !ok {
 		return nil, fmt.Errorf("unknown embedder provider: %s", provider)
 	}
 
-	return creator(config)
+	embedder, err := creator(config)
+	if err != nil {
+		return nil, err
+	}
+
+	if size, concurrency := intValue(config["batch_size"]), intValue(config["batch_concurrency"]); size > 0 || concurrency > 0 {
+		embedder = vector.NewBatchEmbedder(embedder, size, concurrency)
+	}
+	if dir, ok := config["cache_dir"].(string); ok && dir != "" {
+		namespace := provider
+		if model, ok := config["model"].(string); ok && model != "" {
+			namespace += "-" + model
+		}
+		if dims := intValue(config["dimensions"]); dims > 0 {
+			namespace += fmt.Sprintf("-%d", dims)
+		}
+		embedder, err = vector.NewCachedEmbedder(embedder, dir, namespace)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return embedder, nil
+}
+
+// CreateFromConfig 根据 types.EmbedderConfig 创建嵌入器, Model/APIKey/Dimensions 会合并到 Config 中
+func (f *EmbedderFactory) CreateFromConfig(cfg *types.EmbedderConfig) (vector.Embedder, error) {
+	if cfg == nil {
+		return nil, errors.New("embedder config is nil")
+	}
+	config := make(map[string]any, len(cfg.Config)+3)
+	maps.Copy(config, cfg.Config)
+	if cfg.Model != "" {
+		config["model"] = cfg.Model
+	}
+	if cfg.APIKey != "" {
+		config["api_key"] = cfg.APIKey
+	}
+	if cfg.Dimensions > 0 {
+		config["dimensions"] = cfg.Dimensions
+	}
+	return f.Create(cfg.Provider, config)
+}
+
+// intValue 读取数值配置, 兼容 JSON 解码得到的 float64
+func intValue(v any) int {
+	switch n := v.(type) {
+	case int:
+		return n
+	case int64:
+		return int(n)
+	case float64:
+		return int(n)
+	}
+	return 0
 }
 
 // createOpenAIEmbedder 创建 OpenAI 嵌入器
@@ -126,5 +198,119 @@ func createOpenAIEmbedder(config map[string]any) (vector.Embedder, error) {
 		return nil, errors.New("api_key is required for OpenAI embedder")
 	}
 
-	return vector.NewOpenAIEmbedder(baseURL, apiKey, model), nil
+	embedder := vector.NewOpenAIEmbedder(baseURL, apiKey, model)
+	embedder.Dimensions = intValue(config["dimensions"])
+	return embedder, nil
+}
+
+// createVoyageEmbedder 创建 Voyage 嵌入器
+func createVoyageEmbedder(config map[string]any) (vector.Embedder, error) {
+	var baseURL, apiKey, model string
+
+	if v, ok := config["base_url"].(string); ok {
+		baseURL = v
+	}
+	if v, ok := config["api_key"].(string); ok {
+		apiKey = v
+	}
+	if v, ok := config["model"].(string); ok {
+		model = v
+	}
+
+	if apiKey == "" {
+		return nil, errors.New("api_key is required for Voyage embedder")
+	}
+
+	embedder := vector.NewVoyageEmbedder(baseURL, apiKey, model)
+	if v, ok := config["input_type"].(string); ok {
+		embedder.InputType = v
+	}
+	return embedder, nil
+}
+
+// createOllamaEmbedder 创建本地 Ollama 嵌入器
+func createOllamaEmbedder(config map[string]any) (vector.Embedder, error) {
+	var baseURL, model string
+
+	if v, ok := config["base_url"].(string); ok {
+		baseURL = v
+	}
+	if v, ok := config["model"].(string); ok {
+		model = v
+	}
+
+	return vector.NewOllamaEmbedder(baseURL, model), nil
+}
+
+// createMockEmbedder 创建测试用嵌入器
+func createMockEmbedder(config map[string]any) (vector.Embedder, error) {
+	return vector.NewMockEmbedder(intValue(config["dimensions"])), nil
+}
+
+// RerankerFactory 重排序器工厂
+type RerankerFactory struct {
+	creators map[string]RerankerCreator
+}
+
+// RerankerCreator 重排序器创建器函数
+type RerankerCreator func(config map[string]any) (vector.Reranker, error)
+
+// NewRerankerFactory 创建重排序器工厂
+func NewRerankerFactory() *RerankerFactory {
+	factory := &RerankerFactory{
+		creators: make(map[string]RerankerCreator),
+	}
+
+	// 注册内置重排序器
+	factory.Register("cohere", createCohereReranker)
+	factory.Register("voyage", createVoyageReranker)
+	factory.Register("cross_encoder", createCrossEncoderReranker)
+	factory.Register("local", createCrossEncoderReranker)
+
+	return factory
+}
+
+// Register 注册新的重排序器类型
+func (f *RerankerFactory) Register(provider string, creator RerankerCreator) {
+	f.creators[provider] = creator
+}
+
+// Create 创建重排序器实例
+func (f *RerankerFactory) Create(provider string, config map[string]any) (vector.Reranker, error) {
+	creator, ok := f.creators[provider]
+	if !ok {
+		return nil, fmt.Errorf("unknown reranker provider: %s", provider)
+	}
+
+	return creator(config)
+}
+
+// createCohereReranker 创建 Cohere 重排序器
+func createCohereReranker(config map[string]any) (vector.Reranker, error) {
+	baseURL, _ := config["base_url"].(string)
+	apiKey, _ := config["api_key"].(string)
+	model, _ := config["model"].(string)
+	if apiKey == "" {
+		return nil, errors.New("api_key is required for Cohere reranker")
+	}
+	return vector.NewCohereReranker(baseURL, apiKey, model), nil
+}
+
+// createVoyageReranker 创建 Voyage 重排序器
+func createVoyageReranker(config map[string]any) (vector.Reranker, error) {
+	baseURL, _ := config["base_url"].(string)
+	apiKey, _ := config["api_key"].(string)
+	model, _ := config["model"].(string)
+	if apiKey == "" {
+		return nil, errors.New("api_key is required for Voyage reranker")
+	}
+	return vector.NewVoyageReranker(baseURL, apiKey, model), nil
+}
+
+// createCrossEncoderReranker 创建本地交叉编码器重排序器
+func createCrossEncoderReranker(config map[string]any) (vector.Reranker, error) {
+	baseURL, _ := config["base_url"].(string)
+	reranker := vector.NewCrossEncoderReranker(baseURL)
+	reranker.APIKey, _ = config["api_key"].(string)
+	return reranker, nil
 }
@@ -0,0 +1,72 @@
+package vector
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// FileStore 在 MemoryStore 基础上把文档持久化到本地 JSON 文件,
+// 适合 CLI 和单机小规模知识库。Save/Close 时整体写回文件。
+type FileStore struct {
+	*MemoryStore
+	path string
+}
+
+// OpenFileStore 打开(或新建)文件向量存储。
+func OpenFileStore(path string) (*FileStore, error) {
+	s := &FileStore{MemoryStore: NewMemoryStore(), path: path}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var docs []Document
+	if err := json.Unmarshal(data, &docs); err != nil {
+		return nil, fmt.Errorf("decode vector store %s: %w", path, err)
+	}
+	if err := s.Upsert(context.Background(), docs); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Save 把全部文档写回文件(先写临时文件再重命名)。
+func (s *FileStore) Save() error {
+	s.mu.RLock()
+	ids := make([]string, 0, len(s.docs))
+	for id := range s.docs {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	docs := make([]Document, len(ids))
+	for i, id := range ids {
+		docs[i] = s.docs[id]
+	}
+	s.mu.RUnlock()
+
+	data, err := json.Marshal(docs)
+	if err != nil {
+		return fmt.Errorf("encode vector store: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// Close 保存文档。
+func (s *FileStore) Close() error {
+	return s.Save()
+}
@@ -0,0 +1,91 @@
+package vector
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/astercloud/aster/pkg/httpclient"
+)
+
+// OllamaEmbedder 基于本地 Ollama 服务的 Embedder 实现, 不需要 API Key。
+// 调用 POST {BaseURL}/api/embed, 请求格式:
+//
+//	{ "model": "nomic-embed-text", "input": [...] }
+type OllamaEmbedder struct {
+	BaseURL string
+	Model   string
+	Client  *http.Client
+}
+
+// NewOllamaEmbedder 创建 OllamaEmbedder。
+// baseURL 默认为 "http://localhost:11434"。
+func NewOllamaEmbedder(baseURL, model string) *OllamaEmbedder {
+	if baseURL == "" {
+		baseURL = "http://localhost:11434"
+	}
+	if model == "" {
+		model = "nomic-embed-text"
+	}
+	return &OllamaEmbedder{
+		BaseURL: baseURL,
+		Model:   model,
+		// 本地模型首次加载较慢, 超时比远程接口长
+		Client: httpclient.New(2 * time.Minute),
+	}
+}
+
+type ollamaEmbedRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+type ollamaEmbedResponse struct {
+	Embeddings [][]float64 `json:"embeddings"`
+}
+
+// EmbedText 调用 Ollama embed 接口。
+func (e *OllamaEmbedder) EmbedText(ctx context.Context, texts []string) ([][]float32, error) {
+	if len(texts) == 0 {
+		return [][]float32{}, nil
+	}
+
+	data, err := json.Marshal(ollamaEmbedRequest{Model: e.Model, Input: texts})
+	if err != nil {
+		return nil, fmt.Errorf("marshal request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.BaseURL+"/api/embed", bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := e.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("send request: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("ollama embed API error: %s: %s", resp.Status, bytes.TrimSpace(body))
+	}
+
+	var apiResp ollamaEmbedResponse
+	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+	if len(apiResp.Embeddings) != len(texts) {
+		return nil, fmt.Errorf("embedding response mismatch: got %d vectors, want %d", len(apiResp.Embeddings), len(texts))
+	}
+
+	out := make([][]float32, len(texts))
+	for i, v := range apiResp.Embeddings {
+		out[i] = toFloat32(v)
+	}
+	return out, nil
+}
@@ -8,6 +8,8 @@ import (
 	"fmt"
 	"net/http"
 	"time"
+
+	"github.com/astercloud/aster/pkg/httpclient"
 )
 
 // OpenAIEmbedder 基于 OpenAI 兼容接口的 Embedder 实现。
@@ -18,7 +20,9 @@ type OpenAIEmbedder struct {
 	BaseURL string
 	APIKey  string
 	Model   string
-	Client  *http.Client
+	// Dimensions 可选的输出维度, 仅 text-embedding-3 系列模型支持
+	Dimensions int
+	Client     *http.Client
 }
 
 // NewOpenAIEmbedder 创建 OpenAIEmbedder。
@@ -36,13 +40,14 @@ func NewOpenAIEmbedder(baseURL, apiKey, model string) *OpenAIEmbedder {
 		BaseURL: baseURL,
 		APIKey:  apiKey,
 		Model:   model,
-		Client:  &http.Client{Timeout: 30 * time.Second},
+		Client:  httpclient.New(30 * time.Second),
 	}
 }
 
 type openAIEmbeddingRequest struct {
-	Input []string `json:"input"`
-	Model string   `json:"model"`
+	Input      []string `json:"input"`
+	Model      string   `json:"model"`
+	Dimensions int      `json:"dimensions,omitempty"`
 }
 
 type openAIEmbeddingResponse struct {
@@ -61,8 +66,9 @@ func (e *OpenAIEmbedder) EmbedText(ctx context.Context, texts []string) ([][]flo
 	}
 
 	reqBody := openAIEmbeddingRequest{
-		Input: texts,
-		Model: e.Model,
+		Input:      texts,
+		Model:      e.Model,
+		Dimensions: e.Dimensions,
 	}
 	data, err := json.Marshal(reqBody)
 	if err != nil {
@@ -98,11 +104,7 @@ func (e *OpenAIEmbedder) EmbedText(ctx context.Context, texts []string) ([][]flo
 
 	out := make([][]float32, len(apiResp.Data))
 	for i, d := range apiResp.Data {
-		vec := make([]float32, len(d.Embedding))
-		for j, v := range d.Embedding {
-			vec[j] = float32(v)
-		}
-		out[i] = vec
+		out[i] = toFloat32(d.Embedding)
 	}
 
 	return out, nil
@@ -0,0 +1,70 @@
+package vector
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+)
+
+// RerankResult 重排序结果, Index 对应输入 documents 的下标。
+type RerankResult struct {
+	Index int
+	Score float64
+}
+
+// Reranker 对向量检索的候选结果按与查询的相关性重新打分的抽象接口。
+// 通常由交叉编码器(cross-encoder)实现, 比向量相似度更准确但更慢,
+// 因此只对向量检索返回的少量候选执行。
+type Reranker interface {
+	// Rerank 返回按分数降序排列的结果, topN <= 0 时返回全部
+	Rerank(ctx context.Context, query string, documents []string, topN int) ([]RerankResult, error)
+}
+
+// postRerank 发送 JSON 请求并解码响应, 供各 Reranker 实现复用。
+func postRerank(ctx context.Context, client *http.Client, url, apiKey string, body, dest any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("marshal request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("send request: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("rerank API error: %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(dest); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	return nil
+}
+
+// sortRerankResults 校验下标并按分数降序截取前 topN 个。
+func sortRerankResults(results []RerankResult, n, topN int) ([]RerankResult, error) {
+	for _, r := range results {
+		if r.Index < 0 || r.Index >= n {
+			return nil, fmt.Errorf("rerank result index %d out of range", r.Index)
+		}
+	}
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Score > results[j].Score })
+	if topN > 0 && len(results) > topN {
+		results = results[:topN]
+	}
+	return results, nil
+}
@@ -0,0 +1,45 @@
+package vector
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRerankers(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/rerank":
+			_, _ = io.WriteString(w, `{"results":[{"index":2,"relevance_score":0.8},{"index":0,"relevance_score":0.3}]}`)
+		case "/v1/rerank":
+			_, _ = io.WriteString(w, `{"data":[{"index":2,"relevance_score":0.8},{"index":0,"relevance_score":0.3}]}`)
+		case "/rerank":
+			// 本地服务返回全部文档且不保证顺序
+			_, _ = io.WriteString(w, `[{"index":0,"score":0.3},{"index":1,"score":0.1},{"index":2,"score":0.8}]`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	docs := []string{"a", "b", "c"}
+	for name, r := range map[string]Reranker{
+		"cohere":        NewCohereReranker(srv.URL, "key", ""),
+		"voyage":        NewVoyageReranker(srv.URL, "key", ""),
+		"cross_encoder": NewCrossEncoderReranker(srv.URL),
+	} {
+		results, err := r.Rerank(context.Background(), "q", docs, 2)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if len(results) != 2 || results[0].Index != 2 || results[1].Index != 0 {
+			t.Errorf("%s: results = %+v", name, results)
+		}
+	}
+
+	if _, err := sortRerankResults([]RerankResult{{Index: 5}}, 3, 0); err == nil {
+		t.Error("out of range index should fail")
+	}
+}
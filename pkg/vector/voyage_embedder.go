@@ -0,0 +1,115 @@
+package vector
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/astercloud/aster/pkg/httpclient"
+)
+
+// VoyageEmbedder 基于 Voyage AI embeddings 接口的 Embedder 实现。
+// 调用 POST {BaseURL}/v1/embeddings, 请求格式:
+//
+//	{ "input": [...], "model": "voyage-3", "input_type": "document" }
+type VoyageEmbedder struct {
+	BaseURL string
+	APIKey  string
+	Model   string
+	// InputType 可选 "document" 或 "query", 为空时由服务端按通用文本处理
+	InputType string
+	Client    *http.Client
+}
+
+// NewVoyageEmbedder 创建 VoyageEmbedder。
+func NewVoyageEmbedder(baseURL, apiKey, model string) *VoyageEmbedder {
+	if baseURL == "" {
+		baseURL = "https://api.voyageai.com"
+	}
+	if model == "" {
+		model = "voyage-3"
+	}
+	return &VoyageEmbedder{
+		BaseURL: baseURL,
+		APIKey:  apiKey,
+		Model:   model,
+		Client:  httpclient.New(30 * time.Second),
+	}
+}
+
+type voyageEmbeddingRequest struct {
+	Input     []string `json:"input"`
+	Model     string   `json:"model"`
+	InputType string   `json:"input_type,omitempty"`
+}
+
+type voyageEmbeddingResponse struct {
+	Data []struct {
+		Embedding []float64 `json:"embedding"`
+		Index     int       `json:"index"`
+	} `json:"data"`
+}
+
+// EmbedText 调用 Voyage embeddings 接口。
+func (e *VoyageEmbedder) EmbedText(ctx context.Context, texts []string) ([][]float32, error) {
+	if len(texts) == 0 {
+		return [][]float32{}, nil
+	}
+	if e.APIKey == "" {
+		return nil, errors.New("API key is required for VoyageEmbedder")
+	}
+
+	data, err := json.Marshal(voyageEmbeddingRequest{Input: texts, Model: e.Model, InputType: e.InputType})
+	if err != nil {
+		return nil, fmt.Errorf("marshal request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.BaseURL+"/v1/embeddings", bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+e.APIKey)
+
+	resp, err := e.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("send request: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("voyage embeddings API error: %s: %s", resp.Status, bytes.TrimSpace(body))
+	}
+
+	var apiResp voyageEmbeddingResponse
+	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+	if len(apiResp.Data) != len(texts) {
+		return nil, fmt.Errorf("embedding response mismatch: got %d vectors, want %d", len(apiResp.Data), len(texts))
+	}
+
+	out := make([][]float32, len(texts))
+	for i, d := range apiResp.Data {
+		idx := d.Index
+		if idx < 0 || idx >= len(out) {
+			idx = i
+		}
+		out[idx] = toFloat32(d.Embedding)
+	}
+	return out, nil
+}
+
+// toFloat32 把接口返回的 float64 向量转为 float32。
+func toFloat32(v []float64) []float32 {
+	vec := make([]float32, len(v))
+	for i, x := range v {
+		vec[i] = float32(x)
+	}
+	return vec
+}
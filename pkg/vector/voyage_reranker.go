@@ -0,0 +1,67 @@
+package vector
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/astercloud/aster/pkg/httpclient"
+)
+
+// VoyageReranker 基于 Voyage AI rerank 接口的 Reranker 实现。
+// 调用 POST {BaseURL}/v1/rerank, 请求格式:
+//
+//	{ "model": "rerank-2", "query": "...", "documents": [...], "top_k": 5 }
+type VoyageReranker struct {
+	BaseURL string
+	APIKey  string
+	Model   string
+	Client  *http.Client
+}
+
+// NewVoyageReranker 创建 VoyageReranker。
+func NewVoyageReranker(baseURL, apiKey, model string) *VoyageReranker {
+	if baseURL == "" {
+		baseURL = "https://api.voyageai.com"
+	}
+	if model == "" {
+		model = "rerank-2"
+	}
+	return &VoyageReranker{
+		BaseURL: baseURL,
+		APIKey:  apiKey,
+		Model:   model,
+		Client:  httpclient.New(30 * time.Second),
+	}
+}
+
+// Rerank 调用 Voyage rerank 接口。
+func (r *VoyageReranker) Rerank(ctx context.Context, query string, documents []string, topN int) ([]RerankResult, error) {
+	if len(documents) == 0 {
+		return []RerankResult{}, nil
+	}
+	if r.APIKey == "" {
+		return nil, errors.New("API key is required for VoyageReranker")
+	}
+
+	body := map[string]any{"model": r.Model, "query": query, "documents": documents}
+	if topN > 0 {
+		body["top_k"] = topN
+	}
+	var resp struct {
+		Data []struct {
+			Index          int     `json:"index"`
+			RelevanceScore float64 `json:"relevance_score"`
+		} `json:"data"`
+	}
+	if err := postRerank(ctx, r.Client, r.BaseURL+"/v1/rerank", r.APIKey, body, &resp); err != nil {
+		return nil, err
+	}
+
+	results := make([]RerankResult, len(resp.Data))
+	for i, item := range resp.Data {
+		results[i] = RerankResult{Index: item.Index, Score: item.RelevanceScore}
+	}
+	return sortRerankResults(results, len(documents), topN)
+}
@@ -0,0 +1,178 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	rpprof "runtime/pprof"
+	"time"
+
+	aster "github.com/astercloud/aster"
+	"github.com/astercloud/aster/pkg/events"
+)
+
+// diagnosticsCollection is the store collection used by the store latency probe
+const diagnosticsCollection = "_diagnostics"
+
+// processStart is when the process started, reported as uptime
+var processStart = time.Now()
+
+// AdminHandler returns the diagnostics handler served on the admin port:
+// pprof profiles, a goroutine dump, runtime stats, EventBus queue depths and
+// store latencies. It carries no authentication, so the admin port must only
+// be bound to a trusted interface.
+func (s *Server) AdminHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.HandleFunc("/debug/goroutines", s.adminGoroutines)
+	mux.HandleFunc("/debug/runtime", s.adminRuntime)
+	mux.HandleFunc("/debug/eventbus", s.adminEventBus)
+	mux.HandleFunc("/debug/store", s.adminStore)
+	return mux
+}
+
+// adminGoroutines writes a full goroutine dump with stack traces
+func (s *Server) adminGoroutines(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_ = rpprof.Lookup("goroutine").WriteTo(w, 2)
+}
+
+// adminRuntime reports Go runtime and memory statistics
+func (s *Server) adminRuntime(w http.ResponseWriter, r *http.Request) {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	writeAdminJSON(w, http.StatusOK, map[string]any{
+		"version":    aster.Version,
+		"go_version": runtime.Version(),
+		"goroutines": runtime.NumGoroutine(),
+		"cpus":       runtime.NumCPU(),
+		"uptime":     time.Since(processStart).Round(time.Second).String(),
+		"memory": map[string]any{
+			"heap_alloc":   mem.HeapAlloc,
+			"heap_inuse":   mem.HeapInuse,
+			"heap_objects": mem.HeapObjects,
+			"sys":          mem.Sys,
+		},
+		"gc": map[string]any{
+			"num_gc":         mem.NumGC,
+			"pause_total_ns": mem.PauseTotalNs,
+			"last_gc":        time.Unix(0, int64(mem.LastGC)).UTC(),
+		},
+	})
+}
+
+// agentBusStats is the EventBus state of one registered agent
+type agentBusStats struct {
+	AgentID string          `json:"agent_id"`
+	Remote  bool            `json:"remote"`
+	Stats   events.BusStats `json:"stats"`
+}
+
+// adminEventBus reports timeline size, subscriber backlog and publish queue
+// depth for every registered agent's EventBus
+func (s *Server) adminEventBus(w http.ResponseWriter, r *http.Request) {
+	buses := make([]agentBusStats, 0)
+	var backlog, queued int
+	add := func(agentID string, remote bool, eb *events.EventBus) {
+		if eb == nil {
+			return
+		}
+		stats := eb.Stats()
+		backlog += stats.SubscriberBacklog
+		queued += stats.PublishQueueDepth
+		buses = append(buses, agentBusStats{AgentID: agentID, Remote: remote, Stats: stats})
+	}
+	for _, ag := range s.agentRegistry.List() {
+		add(ag.ID(), false, ag.GetEventBus())
+	}
+	for _, ra := range s.agentRegistry.ListRemoteAgents() {
+		add(ra.ID(), true, ra.GetEventBus())
+	}
+	writeAdminJSON(w, http.StatusOK, map[string]any{
+		"agents":              len(buses),
+		"subscriber_backlog":  backlog,
+		"publish_queue_depth": queued,
+		"buses":               buses,
+	})
+}
+
+// adminStore measures the latency of each store operation with a throwaway record
+func (s *Server) adminStore(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	key := fmt.Sprintf("probe-%d", time.Now().UnixNano())
+	latencies := make(map[string]string)
+	probe := func(op string, fn func() error) error {
+		start := time.Now()
+		err := fn()
+		latencies[op] = time.Since(start).String()
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		return nil
+	}
+
+	err := probe("set", func() error {
+		return s.store.Set(ctx, diagnosticsCollection, key, map[string]any{"probe": true})
+	})
+	if err == nil {
+		err = probe("get", func() error {
+			var v map[string]any
+			return s.store.Get(ctx, diagnosticsCollection, key, &v)
+		})
+	}
+	if err == nil {
+		err = probe("list", func() error {
+			_, err := s.store.List(ctx, diagnosticsCollection)
+			return err
+		})
+	}
+	if err == nil {
+		err = probe("delete", func() error {
+			return s.store.Delete(ctx, diagnosticsCollection, key)
+		})
+	}
+
+	body := map[string]any{
+		"store":     fmt.Sprintf("%T", s.store),
+		"healthy":   err == nil,
+		"latencies": latencies,
+	}
+	status := http.StatusOK
+	if err != nil {
+		body["error"] = err.Error()
+		status = http.StatusServiceUnavailable
+	}
+	writeAdminJSON(w, status, body)
+}
+
+// startAdmin serves the diagnostics endpoints on the admin address
+func (s *Server) startAdmin() {
+	s.adminServer = &http.Server{
+		Addr:              s.config.Admin.Addr,
+		Handler:           s.AdminHandler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	fmt.Printf("🩺 Admin diagnostics: http://%s/debug/\n", s.config.Admin.Addr)
+	go func() {
+		if err := s.adminServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			fmt.Printf("⚠️  Admin server error: %v\n", err)
+		}
+	}()
+}
+
+func writeAdminJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(body)
+}
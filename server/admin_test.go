@@ -0,0 +1,50 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/astercloud/aster/pkg/agent"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdminHandler(t *testing.T) {
+	srv, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	srv.agentRegistry.RegisterRemoteAgent(agent.NewRemoteAgent("remote-1", "coder", nil))
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		srv.AdminHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	w := serve("/debug/eventbus")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"agent_id": "remote-1"`)
+	assert.Contains(t, w.Body.String(), `"publish_queue_depth"`)
+
+	w = serve("/debug/store")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"healthy": true`)
+	assert.Contains(t, w.Body.String(), `"delete"`)
+
+	w = serve("/debug/runtime")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"goroutines"`)
+
+	w = serve("/debug/goroutines")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "goroutine ")
+
+	w = serve("/debug/pprof/")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Diagnostics are not exposed on the main router
+	w = httptest.NewRecorder()
+	srv.Router().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/debug/eventbus", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,92 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/astercloud/aster/pkg/artifact"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArtifactRoutes(t *testing.T) {
+	srv, cleanup := setupTestServerWithConfig(t, func(c *Config) {
+		c.Artifacts.Enabled = true
+		c.Artifacts.Dir = t.TempDir()
+	})
+	defer cleanup()
+	require.NotNil(t, srv.artifacts)
+
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		srv.Router().ServeHTTP(w, req)
+		return w
+	}
+
+	w := serve(http.MethodPost, "/v1/sessions", `{"agent_id":"artifact-agent"}`)
+	require.Equal(t, http.StatusCreated, w.Code)
+	var created struct {
+		Data struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	sessionID := created.Data.ID
+
+	a, err := srv.artifacts.Save(context.Background(), artifact.SaveRequest{
+		AgentID: "artifact-agent",
+		Name:    "result.csv",
+		Source:  "Bash",
+	}, strings.NewReader("a,b\n1,2\n"))
+	require.NoError(t, err)
+	_, err = srv.artifacts.Save(context.Background(), artifact.SaveRequest{
+		AgentID: "someone-else",
+		Name:    "other.txt",
+	}, strings.NewReader("other"))
+	require.NoError(t, err)
+
+	w = serve(http.MethodGet, "/v1/sessions/"+sessionID+"/artifacts", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var listed struct {
+		Data []artifact.Artifact `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &listed))
+	require.Len(t, listed.Data, 1)
+	assert.Equal(t, a.ID, listed.Data[0].ID)
+	assert.Equal(t, artifact.KindData, listed.Data[0].Kind)
+
+	w = serve(http.MethodGet, "/v1/artifacts/"+a.ID+"/download", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "a,b\n1,2\n", w.Body.String())
+	assert.Contains(t, w.Header().Get("Content-Disposition"), `attachment; filename=result.csv`)
+	assert.NotEmpty(t, w.Header().Get("ETag"))
+	assert.Equal(t, "sandbox", w.Header().Get("Content-Security-Policy"))
+
+	svg, err := srv.artifacts.Save(context.Background(), artifact.SaveRequest{
+		AgentID: "artifact-agent",
+		Name:    "chart.svg",
+	}, strings.NewReader(`<svg xmlns="http://www.w3.org/2000/svg"><script>alert(1)</script></svg>`))
+	require.NoError(t, err)
+	w = serve(http.MethodGet, "/v1/artifacts/"+svg.ID+"/download?inline=true", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Disposition"), "attachment")
+
+	w = serve(http.MethodPatch, "/v1/artifacts/"+a.ID, `{"pinned":true}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"pinned":true`)
+
+	w = serve(http.MethodDelete, "/v1/artifacts/"+a.ID, "")
+	require.Equal(t, http.StatusOK, w.Code)
+
+	w = serve(http.MethodGet, "/v1/artifacts/"+a.ID+"/download", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = serve(http.MethodGet, "/v1/sessions/missing/artifacts", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
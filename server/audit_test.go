@@ -0,0 +1,90 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/astercloud/aster/pkg/audit"
+	"github.com/astercloud/aster/pkg/multitenancy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditRoutes(t *testing.T) {
+	srv, cleanup := setupTestServerWithConfig(t, func(c *Config) {
+		c.Audit.Enabled = true
+		c.Audit.Dir = t.TempDir()
+	})
+	defer cleanup()
+	require.NotNil(t, srv.audit)
+	require.Same(t, srv.audit, srv.deps.AgentDeps.Audit)
+
+	ctx := multitenancy.WithTenantID(context.Background(), "acme")
+	require.NoError(t, srv.audit.Record(ctx, audit.SourcePermission, "denied", "agt-1", map[string]any{"tool": "Bash"}))
+	require.NoError(t, srv.audit.Record(ctx, audit.SourceSandbox, "command_executed", "agt-1", map[string]any{"command": "ls"}))
+
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		srv.Router().ServeHTTP(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return w
+	}
+
+	w := serve(http.MethodGet, "/v1/audit/export?tenant_id=acme&from=2000-01-01", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "2", w.Header().Get("X-Audit-Entries"))
+	exported := w.Body.String()
+
+	w = serve(http.MethodPost, "/v1/audit/verify", exported)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"valid":true`)
+
+	w = serve(http.MethodPost, "/v1/audit/verify", strings.Replace(exported, "denied", "allowed", 1))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"valid":false`)
+
+	w = serve(http.MethodGet, "/v1/audit/export?from=yesterday", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestAuditExportScopedToPrincipal(t *testing.T) {
+	srv, cleanup := setupTestServerWithConfig(t, func(c *Config) {
+		c.Audit.Enabled = true
+		c.Audit.Dir = t.TempDir()
+		c.Auth.APIKey.Enabled = true
+		c.Auth.APIKey.Entries = []APIKeyEntry{
+			{Key: "key-admin", Name: "admin", Roles: []string{"admin"}},
+			{Key: "key-globex", Name: "globex", Roles: []string{"viewer"}, TenantID: "globex"},
+			{Key: "key-viewer", Name: "viewer", Roles: []string{"viewer"}},
+		}
+	})
+	defer cleanup()
+
+	for _, tenant := range []string{"acme", "globex"} {
+		ctx := multitenancy.WithTenantID(context.Background(), tenant)
+		require.NoError(t, srv.audit.Record(ctx, audit.SourcePermission, "denied", "agt-"+tenant, nil))
+	}
+
+	export := func(key, query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/v1/audit/export?from=2000-01-01"+query, nil)
+		req.Header.Set("X-API-Key", key)
+		srv.Router().ServeHTTP(w, req)
+		return w
+	}
+
+	w := export("key-admin", "&tenant_id=acme")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "agt-acme")
+
+	// A tenant-bound principal cannot pick another tenant
+	w = export("key-globex", "&tenant_id=acme")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "agt-globex")
+	assert.NotContains(t, w.Body.String(), "agt-acme")
+
+	w = export("key-viewer", "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
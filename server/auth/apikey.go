@@ -30,6 +30,7 @@ type APIKeyInfo struct {
 	UserID    string         `json:"user_id"`
 	Name      string         `json:"name"`
 	Roles     []string       `json:"roles"`
+	TenantID  string         `json:"tenant_id,omitempty"`
 	ExpiresAt *time.Time     `json:"expires_at,omitempty"`
 	CreatedAt time.Time      `json:"created_at"`
 	LastUsed  *time.Time     `json:"last_used,omitempty"`
@@ -88,8 +89,9 @@ func (a *APIKeyAuthenticator) Validate(ctx context.Context, key string) (*User,
 	}()
 
 	return &User{
-		ID:    info.UserID,
-		Roles: info.Roles,
+		ID:       info.UserID,
+		Roles:    info.Roles,
+		TenantID: info.TenantID,
 		Metadata: map[string]any{
 			"api_key_name": info.Name,
 		},
@@ -16,6 +16,7 @@ type JWTClaims struct {
 	Username string   `json:"username"`
 	Email    string   `json:"email"`
 	Roles    []string `json:"roles"`
+	TenantID string   `json:"tenant_id,omitempty"`
 }
 
 // JWTAuthenticator JWT 认证器
@@ -100,6 +101,7 @@ func (a *JWTAuthenticator) Validate(ctx context.Context, tokenString string) (*U
 		Username: claims.Username,
 		Email:    claims.Email,
 		Roles:    claims.Roles,
+		TenantID: claims.TenantID,
 	}, nil
 }
 
@@ -112,6 +114,7 @@ func (a *JWTAuthenticator) GenerateToken(user *User) (string, time.Time, error)
 		Username: user.Username,
 		Email:    user.Email,
 		Roles:    user.Roles,
+		TenantID: user.TenantID,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(expiresAt),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -3,6 +3,7 @@ package auth
 import (
 	"context"
 	"errors"
+	"slices"
 	"time"
 )
 
@@ -20,6 +21,7 @@ const (
 	AuthMethodAPIKey AuthMethod = "apikey"
 	AuthMethodJWT    AuthMethod = "jwt"
 	AuthMethodOAuth  AuthMethod = "oauth"
+	AuthMethodOIDC   AuthMethod = "oidc"
 )
 
 // User 用户信息
@@ -28,9 +30,15 @@ type User struct {
 	Username string         `json:"username"`
 	Email    string         `json:"email"`
 	Roles    []string       `json:"roles"`
+	TenantID string         `json:"tenant_id,omitempty"`
 	Metadata map[string]any `json:"metadata,omitempty"`
 }
 
+// HasRole 检查用户是否拥有指定角色
+func (u *User) HasRole(role string) bool {
+	return u != nil && slices.Contains(u.Roles, role)
+}
+
 // Authenticator 认证器接口
 type Authenticator interface {
 	// Authenticate 验证凭证并返回用户信息
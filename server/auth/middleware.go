@@ -0,0 +1,181 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// ContextKeyUser gin 上下文中保存已认证用户的 key
+	ContextKeyUser = "auth.user"
+	// ContextKeyTenant gin 上下文中保存租户 ID 的 key
+	ContextKeyTenant = "auth.tenant"
+)
+
+type userContextKey struct{}
+
+// WithUser 将用户信息写入 context
+func WithUser(ctx context.Context, user *User) context.Context {
+	return context.WithValue(ctx, userContextKey{}, user)
+}
+
+// UserFrom 从 context 中读取用户信息
+func UserFrom(ctx context.Context) (*User, bool) {
+	user, ok := ctx.Value(userContextKey{}).(*User)
+	return user, ok && user != nil
+}
+
+// TenantFrom 从 context 中读取租户 ID
+func TenantFrom(ctx context.Context) string {
+	if user, ok := UserFrom(ctx); ok {
+		return user.TenantID
+	}
+	return ""
+}
+
+// CurrentUser 从 gin 上下文中读取已认证用户
+func CurrentUser(c *gin.Context) (*User, bool) {
+	v, exists := c.Get(ContextKeyUser)
+	if !exists {
+		return nil, false
+	}
+	user, ok := v.(*User)
+	return user, ok && user != nil
+}
+
+// MiddlewareConfig 认证中间件配置
+type MiddlewareConfig struct {
+	// APIKeyHeader API Key 所在请求头，默认 X-API-Key
+	APIKeyHeader string
+	// AllowQueryToken 允许通过 ?token= 传递令牌（用于 WebSocket 等无法设置请求头的场景）
+	AllowQueryToken bool
+}
+
+// Middleware 创建认证中间件
+//
+// 依次尝试 API Key 请求头与 Bearer 令牌（JWT，其次 OIDC），
+// 成功后将用户写入 gin 上下文与 request context。
+func Middleware(manager *Manager, config MiddlewareConfig) gin.HandlerFunc {
+	if config.APIKeyHeader == "" {
+		config.APIKeyHeader = "X-API-Key"
+	}
+
+	return func(c *gin.Context) {
+		user, err := authenticateRequest(c, manager, config)
+		if err != nil {
+			code := "unauthorized"
+			switch {
+			case errors.Is(err, ErrExpiredToken):
+				code = "expired_token"
+			case errors.Is(err, ErrInvalidToken):
+				code = "invalid_token"
+			case errors.Is(err, ErrInvalidCredentials):
+				code = "invalid_credentials"
+			}
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"success": false,
+				"error":   gin.H{"code": code, "message": err.Error()},
+			})
+			c.Abort()
+			return
+		}
+
+		c.Set("authenticated", true)
+		c.Set(ContextKeyUser, user)
+		c.Set(ContextKeyTenant, user.TenantID)
+		c.Request = c.Request.WithContext(WithUser(c.Request.Context(), user))
+		c.Next()
+	}
+}
+
+func authenticateRequest(c *gin.Context, manager *Manager, config MiddlewareConfig) (*User, error) {
+	ctx := c.Request.Context()
+
+	if key := c.GetHeader(config.APIKeyHeader); key != "" {
+		return manager.Validate(ctx, AuthMethodAPIKey, key)
+	}
+
+	token := ""
+	if header := c.GetHeader("Authorization"); header != "" {
+		parts := strings.SplitN(header, " ", 2)
+		if len(parts) != 2 || !strings.EqualFold(parts[0], "Bearer") {
+			return nil, ErrInvalidToken
+		}
+		token = strings.TrimSpace(parts[1])
+	} else if config.AllowQueryToken {
+		token = c.Query("token")
+	}
+	if token == "" {
+		return nil, ErrUnauthorized
+	}
+
+	// API Key 也允许以 Bearer 方式传递
+	if strings.HasPrefix(token, "sk_") {
+		if _, ok := manager.GetAuthenticator(AuthMethodAPIKey); ok {
+			return manager.Validate(ctx, AuthMethodAPIKey, token)
+		}
+	}
+
+	lastErr := ErrInvalidToken
+	for _, method := range []AuthMethod{AuthMethodJWT, AuthMethodOIDC, AuthMethodAPIKey} {
+		if _, ok := manager.GetAuthenticator(method); !ok {
+			continue
+		}
+		user, err := manager.Validate(ctx, method, token)
+		if err == nil {
+			return user, nil
+		}
+		if errors.Is(err, ErrExpiredToken) {
+			lastErr = err
+		}
+	}
+	return nil, lastErr
+}
+
+// RequirePermission 创建授权中间件，检查当前用户对资源的操作权限
+// action 为空时根据 HTTP 方法推断（GET→read, POST→create, ...）
+func RequirePermission(rbac *RBAC, resource string, action string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		act := action
+		if act == "" {
+			act = ActionForMethod(c.Request.Method)
+		}
+
+		user, _ := CurrentUser(c)
+		if err := rbac.CheckPermission(c.Request.Context(), user, resource, act); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{
+				"success": false,
+				"error": gin.H{
+					"code":    "forbidden",
+					"message": "permission denied: " + resource + ":" + act,
+				},
+			})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireRole 创建授权中间件，只允许拥有指定角色的用户访问
+func RequireRole(role string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, _ := CurrentUser(c)
+		if !user.HasRole(role) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"success": false,
+				"error": gin.H{
+					"code":    "forbidden",
+					"message": "role required: " + role,
+				},
+			})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,89 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRouter(t *testing.T) (*gin.Engine, *JWTAuthenticator) {
+	gin.SetMode(gin.TestMode)
+
+	keys := NewMemoryAPIKeyStore()
+	require.NoError(t, keys.Create(context.Background(), &APIKeyInfo{Key: "viewer-key", UserID: "v", Roles: []string{"viewer"}, TenantID: "acme"}))
+	require.NoError(t, keys.Create(context.Background(), &APIKeyInfo{Key: "operator-key", UserID: "o", Roles: []string{"operator"}}))
+
+	jwtAuth := NewJWTAuthenticator(JWTConfig{SecretKey: "secret"})
+	manager := NewManager(AuthMethodAPIKey)
+	manager.Register(NewAPIKeyAuthenticator(keys))
+	manager.Register(jwtAuth)
+	rbac := NewRBAC()
+
+	r := gin.New()
+	g := r.Group("/v1", Middleware(manager, MiddlewareConfig{}))
+	sessions := g.Group("/sessions", RequirePermission(rbac, "sessions", ""))
+	sessions.GET("", func(c *gin.Context) {
+		c.String(http.StatusOK, TenantFrom(c.Request.Context()))
+	})
+	sessions.POST("", func(c *gin.Context) { c.Status(http.StatusCreated) })
+	g.PUT("/pricing", RequirePermission(rbac, "pricing", ""), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	return r, jwtAuth
+}
+
+func doRequest(r http.Handler, method, path string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestMiddleware_MissingCredentials(t *testing.T) {
+	r, _ := newTestRouter(t)
+	w := doRequest(r, http.MethodGet, "/v1/sessions", nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestMiddleware_APIKeyRoles(t *testing.T) {
+	r, _ := newTestRouter(t)
+
+	w := doRequest(r, http.MethodGet, "/v1/sessions", map[string]string{"X-API-Key": "viewer-key"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "acme", w.Body.String())
+
+	w = doRequest(r, http.MethodPost, "/v1/sessions", map[string]string{"X-API-Key": "viewer-key"})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = doRequest(r, http.MethodPost, "/v1/sessions", map[string]string{"X-API-Key": "operator-key"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	w = doRequest(r, http.MethodPut, "/v1/pricing", map[string]string{"X-API-Key": "operator-key"})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = doRequest(r, http.MethodGet, "/v1/sessions", map[string]string{"X-API-Key": "unknown"})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestMiddleware_JWTBearer(t *testing.T) {
+	r, jwtAuth := newTestRouter(t)
+
+	token, _, err := jwtAuth.GenerateToken(&User{ID: "u1", Roles: []string{"admin"}, TenantID: "t1"})
+	require.NoError(t, err)
+
+	w := doRequest(r, http.MethodPut, "/v1/pricing", map[string]string{"Authorization": "Bearer " + token})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = doRequest(r, http.MethodGet, "/v1/sessions", map[string]string{"Authorization": "Bearer " + token})
+	assert.Equal(t, "t1", w.Body.String())
+
+	w = doRequest(r, http.MethodGet, "/v1/sessions", map[string]string{"Authorization": "Bearer garbage"})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
@@ -0,0 +1,275 @@
+package auth
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/sync/singleflight"
+
+	"github.com/astercloud/aster/pkg/httpclient"
+)
+
+// OIDCConfig OIDC 配置
+type OIDCConfig struct {
+	// Issuer 身份提供方地址，例如 https://accounts.example.com
+	Issuer string
+	// Audience 期望的 aud（通常为 client_id），为空时不校验
+	Audience string
+	// JWKSURL 公钥地址，为空时通过 discovery 文档获取
+	JWKSURL string
+	// RolesClaim 角色声明名称，默认 "roles"
+	RolesClaim string
+	// TenantClaim 租户声明名称，默认 "tenant_id"
+	TenantClaim string
+	// CacheTTL JWKS 缓存时间，默认 1 小时
+	CacheTTL time.Duration
+	// RefreshCooldown 两次拉取 JWKS 的最小间隔，期间未知的 kid 直接拒绝，默认 1 分钟
+	RefreshCooldown time.Duration
+	// HTTPClient 自定义 HTTP 客户端
+	HTTPClient *http.Client
+}
+
+// OIDCAuthenticator 基于 OIDC ID Token / Access Token 的认证器
+// 使用身份提供方的 JWKS 验证 RS256 签名
+type OIDCAuthenticator struct {
+	config OIDCConfig
+
+	mu          sync.RWMutex
+	keys        map[string]*rsa.PublicKey
+	fetchedAt   time.Time
+	refreshedAt time.Time // 最近一次尝试拉取的时间，失败也计入
+
+	refresh singleflight.Group
+}
+
+// NewOIDCAuthenticator 创建 OIDC 认证器
+func NewOIDCAuthenticator(config OIDCConfig) *OIDCAuthenticator {
+	if config.RolesClaim == "" {
+		config.RolesClaim = "roles"
+	}
+	if config.TenantClaim == "" {
+		config.TenantClaim = "tenant_id"
+	}
+	if config.CacheTTL == 0 {
+		config.CacheTTL = time.Hour
+	}
+	if config.RefreshCooldown == 0 {
+		config.RefreshCooldown = time.Minute
+	}
+	if config.HTTPClient == nil {
+		config.HTTPClient = httpclient.New(10 * time.Second)
+	}
+	config.Issuer = strings.TrimSuffix(config.Issuer, "/")
+
+	return &OIDCAuthenticator{
+		config: config,
+		keys:   make(map[string]*rsa.PublicKey),
+	}
+}
+
+// Method 返回认证方法类型
+func (a *OIDCAuthenticator) Method() AuthMethod {
+	return AuthMethodOIDC
+}
+
+// Authenticate 验证 OIDC 令牌
+func (a *OIDCAuthenticator) Authenticate(ctx context.Context, credentials any) (*User, error) {
+	token, ok := credentials.(string)
+	if !ok {
+		return nil, ErrInvalidCredentials
+	}
+	return a.Validate(ctx, token)
+}
+
+// Validate 验证 OIDC 令牌并返回用户信息
+func (a *OIDCAuthenticator) Validate(ctx context.Context, tokenString string) (*User, error) {
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512"}),
+		jwt.WithIssuer(a.config.Issuer),
+	}
+	if a.config.Audience != "" {
+		opts = append(opts, jwt.WithAudience(a.config.Audience))
+	}
+
+	claims := jwt.MapClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (any, error) {
+		kid, _ := token.Header["kid"].(string)
+		return a.publicKey(ctx, kid)
+	}, opts...)
+	if err != nil {
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return nil, ErrExpiredToken
+		}
+		return nil, ErrInvalidToken
+	}
+	if !token.Valid {
+		return nil, ErrInvalidToken
+	}
+
+	subject, _ := claims.GetSubject()
+	user := &User{
+		ID:       subject,
+		Roles:    stringsClaim(claims[a.config.RolesClaim]),
+		Metadata: map[string]any{"issuer": a.config.Issuer},
+	}
+	if v, ok := claims["preferred_username"].(string); ok {
+		user.Username = v
+	}
+	if v, ok := claims["email"].(string); ok {
+		user.Email = v
+	}
+	if v, ok := claims[a.config.TenantClaim].(string); ok {
+		user.TenantID = v
+	}
+
+	return user, nil
+}
+
+// publicKey 根据 kid 查找公钥，缓存未命中或过期时重新拉取 JWKS
+// 并发的拉取合并为一次，冷却期内不重复拉取，避免伪造 kid 的令牌放大对身份提供方的请求
+func (a *OIDCAuthenticator) publicKey(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	a.mu.RLock()
+	key, ok := a.lookup(kid)
+	fresh := time.Since(a.fetchedAt) < a.config.CacheTTL
+	cooling := time.Since(a.refreshedAt) < a.config.RefreshCooldown
+	a.mu.RUnlock()
+	if ok && (fresh || cooling) {
+		return key, nil
+	}
+	if cooling {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+
+	if _, err, _ := a.refresh.Do("jwks", func() (any, error) {
+		// 其他请求可能刚完成一次拉取
+		a.mu.RLock()
+		recent := time.Since(a.refreshedAt) < a.config.RefreshCooldown
+		a.mu.RUnlock()
+		if recent {
+			return nil, nil
+		}
+		return nil, a.refreshKeys(ctx)
+	}); err != nil {
+		return nil, err
+	}
+
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if key, ok := a.lookup(kid); ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// lookup 调用方需持有读锁
+func (a *OIDCAuthenticator) lookup(kid string) (*rsa.PublicKey, bool) {
+	if kid == "" && len(a.keys) == 1 {
+		for _, k := range a.keys {
+			return k, true
+		}
+	}
+	key, ok := a.keys[kid]
+	return key, ok
+}
+
+// refreshKeys 拉取 JWKS 并替换缓存
+func (a *OIDCAuthenticator) refreshKeys(ctx context.Context) error {
+	a.mu.Lock()
+	a.refreshedAt = time.Now()
+	a.mu.Unlock()
+
+	jwksURL := a.config.JWKSURL
+	if jwksURL == "" {
+		var discovery struct {
+			JWKSURI string `json:"jwks_uri"`
+		}
+		if err := a.getJSON(ctx, a.config.Issuer+"/.well-known/openid-configuration", &discovery); err != nil {
+			return fmt.Errorf("oidc discovery: %w", err)
+		}
+		jwksURL = discovery.JWKSURI
+	}
+
+	var jwks struct {
+		Keys []struct {
+			Kid string `json:"kid"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := a.getJSON(ctx, jwksURL, &jwks); err != nil {
+		return fmt.Errorf("fetch jwks: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(jwks.Keys))
+	for _, k := range jwks.Keys {
+		if k.Kty != "RSA" {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			continue
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			continue
+		}
+		keys[k.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+
+	a.mu.Lock()
+	a.keys = keys
+	a.fetchedAt = time.Now()
+	a.mu.Unlock()
+	return nil
+}
+
+func (a *OIDCAuthenticator) getJSON(ctx context.Context, url string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := a.config.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d from %s", resp.StatusCode, url)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// stringsClaim 将声明值转换为字符串列表，兼容数组与空格分隔字符串
+func stringsClaim(v any) []string {
+	switch val := v.(type) {
+	case []any:
+		out := make([]string, 0, len(val))
+		for _, item := range val {
+			if s, ok := item.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	case []string:
+		return val
+	case string:
+		return strings.Fields(val)
+	default:
+		return nil
+	}
+}
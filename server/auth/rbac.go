@@ -2,6 +2,7 @@ package auth
 
 import (
 	"context"
+	"net/http"
 	"sync"
 )
 
@@ -52,14 +53,30 @@ func (r *RBAC) RegisterDefaultRoles() {
 		Name:        "user",
 		Description: "Regular user with basic access",
 		Permissions: []Permission{
-			{Resource: "agents", Actions: []string{"create", "read", "update", "delete"}},
+			{Resource: "agents", Actions: []string{"create", "read", "update", "delete", "execute"}},
 			{Resource: "memory", Actions: []string{"create", "read", "update", "delete"}},
-			{Resource: "sessions", Actions: []string{"create", "read", "update", "delete"}},
+			{Resource: "sessions", Actions: []string{"create", "read", "update", "delete", "execute"}},
+			{Resource: "artifacts", Actions: []string{"read", "update", "delete"}},
 			{Resource: "workflows", Actions: []string{"read", "execute"}},
 			{Resource: "tools", Actions: []string{"read", "execute"}},
 		},
 	})
 
+	// Operator 角色 - 运维权限（可操作 Agent/Session，不能修改计费与系统配置）
+	r.AddRole(&Role{
+		Name:        "operator",
+		Description: "Operator who can run agents and manage sessions",
+		Permissions: []Permission{
+			{Resource: "*", Actions: []string{"read"}},
+			{Resource: "agents", Actions: []string{"create", "update", "delete", "execute"}},
+			{Resource: "sessions", Actions: []string{"create", "update", "delete", "execute"}},
+			{Resource: "artifacts", Actions: []string{"update", "delete"}},
+			{Resource: "workflows", Actions: []string{"execute"}},
+			{Resource: "tools", Actions: []string{"execute"}},
+			{Resource: "memory", Actions: []string{"create", "update", "delete"}},
+		},
+	})
+
 	// Viewer 角色 - 只读权限
 	r.AddRole(&Role{
 		Name:        "viewer",
@@ -78,6 +95,8 @@ func (r *RBAC) RegisterDefaultRoles() {
 			{Resource: "workflows", Actions: []string{"*"}},
 			{Resource: "tools", Actions: []string{"*"}},
 			{Resource: "eval", Actions: []string{"*"}},
+			{Resource: "experiments", Actions: []string{"*"}},
+			{Resource: "templates", Actions: []string{"*"}},
 		},
 	})
 }
@@ -137,6 +156,22 @@ func (r *RBAC) roleHasPermission(role *Role, resource string, action string) boo
 	return false
 }
 
+// ActionForMethod 将 HTTP 方法映射为 RBAC 操作
+func ActionForMethod(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return "read"
+	case http.MethodPost:
+		return "create"
+	case http.MethodPut, http.MethodPatch:
+		return "update"
+	case http.MethodDelete:
+		return "delete"
+	default:
+		return "execute"
+	}
+}
+
 // CheckPermission 检查权限，返回错误
 func (r *RBAC) CheckPermission(ctx context.Context, user *User, resource string, action string) error {
 	if !r.HasPermission(ctx, user, resource, action) {
@@ -0,0 +1,164 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/astercloud/aster/pkg/types"
+	"github.com/astercloud/aster/server/handlers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// liveStream reads collaborative session events from an SSE response
+func liveStream(t *testing.T, resp *http.Response) <-chan handlers.CollabEvent {
+	t.Helper()
+	events := make(chan handlers.CollabEvent, 64)
+	go func() {
+		defer close(events)
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			line := scanner.Text()
+			if !strings.HasPrefix(line, "data:") {
+				continue
+			}
+			var event handlers.CollabEvent
+			if json.Unmarshal([]byte(strings.TrimPrefix(line, "data:")), &event) == nil && event.Type != "" {
+				events <- event
+			}
+		}
+	}()
+	return events
+}
+
+func nextEvent(t *testing.T, events <-chan handlers.CollabEvent, eventType string) handlers.CollabEvent {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case event, ok := <-events:
+			require.True(t, ok, "stream closed before %s", eventType)
+			if event.Type == eventType {
+				return event
+			}
+		case <-timeout:
+			t.Fatalf("timed out waiting for %s", eventType)
+		}
+	}
+}
+
+func TestCollaborativeSession(t *testing.T) {
+	srv, cleanup := setupTestServerWithConfig(t, func(cfg *Config) {
+		cfg.Auth.APIKey.Enabled = true
+		cfg.Auth.APIKey.Entries = []APIKeyEntry{
+			{Key: "key-alice", Name: "alice", Roles: []string{"admin"}},
+			{Key: "key-bob", Name: "bob", Roles: []string{"admin"}},
+		}
+	})
+	defer cleanup()
+	ts := httptest.NewServer(srv.Router())
+	defer ts.Close()
+
+	do := func(key, method, path, body string) *http.Response {
+		req, err := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-API-Key", key)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		return resp
+	}
+	decode := func(resp *http.Response, v any) {
+		defer func() { _ = resp.Body.Close() }()
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&struct {
+			Data any `json:"data"`
+		}{Data: v}))
+	}
+
+	resp := do("key-alice", http.MethodPost, "/v1/agents", `{"template_id":"chat"}`)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	var agentRecord handlers.AgentRecord
+	decode(resp, &agentRecord)
+
+	resp = do("key-alice", http.MethodPost, "/v1/sessions", `{"agent_id":"`+agentRecord.ID+`"}`)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	var session handlers.SessionRecord
+	decode(resp, &session)
+	base := "/v1/sessions/" + session.ID
+
+	// 未加入的用户不能发送消息
+	resp = do("key-bob", http.MethodPost, base+"/live/messages", `{"message":"hi"}`)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	_ = resp.Body.Close()
+
+	aliceResp := do("key-alice", http.MethodGet, base+"/live", "")
+	defer func() { _ = aliceResp.Body.Close() }()
+	alice := liveStream(t, aliceResp)
+	assert.Equal(t, "apikey-alice", nextEvent(t, alice, handlers.CollabEventJoined).UserID)
+
+	bobResp := do("key-bob", http.MethodGet, base+"/live", "")
+	bob := liveStream(t, bobResp)
+	joined := nextEvent(t, alice, handlers.CollabEventJoined)
+	assert.Equal(t, "bob", joined.Username)
+
+	resp = do("key-alice", http.MethodGet, base+"/participants", "")
+	var snapshot struct {
+		Participants []handlers.CollabParticipant `json:"participants"`
+	}
+	decode(resp, &snapshot)
+	assert.Len(t, snapshot.Participants, 2)
+
+	// Bob 发送的消息和 Agent 回复对两位参与者都可见
+	resp = do("key-bob", http.MethodPost, base+"/live/messages", `{"message":"Summarize the plan"}`)
+	require.Equal(t, http.StatusAccepted, resp.StatusCode)
+	_ = resp.Body.Close()
+	for _, stream := range []<-chan handlers.CollabEvent{alice, bob} {
+		msg := nextEvent(t, stream, handlers.CollabEventMessage)
+		assert.Equal(t, "apikey-bob", msg.UserID)
+		assert.Equal(t, "Summarize the plan", msg.Text)
+		done := nextEvent(t, stream, handlers.CollabEventTurnCompleted)
+		assert.Equal(t, msg.TurnID, done.TurnID)
+		assert.Equal(t, "apikey-bob", done.UserID)
+		assert.Equal(t, "Mock response", done.Text)
+	}
+
+	_ = bobResp.Body.Close()
+	left := nextEvent(t, alice, handlers.CollabEventLeft)
+	assert.Equal(t, "apikey-bob", left.UserID)
+
+	// 事件按顺序持久化并带有用户归属
+	want := []string{
+		"participant_joined:apikey-alice",
+		"participant_joined:apikey-bob",
+		"user_message:apikey-bob",
+		"turn_completed:apikey-bob",
+		"participant_left:apikey-bob",
+	}
+	var events []handlers.CollabEvent
+	var kinds []string
+	require.Eventually(t, func() bool {
+		decode(do("key-alice", http.MethodGet, base+"/events", ""), &events)
+		kinds = kinds[:0]
+		for _, e := range events {
+			kinds = append(kinds, e.Type+":"+e.UserID)
+		}
+		return len(kinds) == len(want)
+	}, 2*time.Second, 20*time.Millisecond)
+	assert.Equal(t, want, kinds)
+
+	resp = do("key-alice", http.MethodGet, base+"/events?after="+strconv.FormatInt(events[2].Seq, 10), "")
+	decode(resp, &events)
+	assert.Len(t, events, 2)
+
+	resp = do("key-alice", http.MethodGet, base, "")
+	decode(resp, &session)
+	require.Len(t, session.Messages, 2)
+	assert.Equal(t, types.MessageRoleUser, session.Messages[0].Role)
+	assert.Equal(t, "bob", session.Messages[0].Name)
+}
@@ -4,6 +4,8 @@ import (
 	"time"
 
 	aster "github.com/astercloud/aster"
+	"github.com/astercloud/aster/pkg/artifact"
+	"github.com/astercloud/aster/pkg/logging"
 )
 
 // Config holds all configuration for the aster production server
@@ -19,6 +21,17 @@ type Config struct {
 	Observability ObservabilityConfig
 	Database      DatabaseConfig
 	Redis         RedisConfig
+	Multitenancy  MultitenancyConfig
+	AgentPool     AgentPoolConfig
+	Artifacts     ArtifactsConfig
+	Usage         UsageConfig
+	Experiments   ExperimentsConfig
+	Templates     TemplatesConfig
+	Identity      IdentityConfig
+	Audit         AuditConfig
+	Traces        TracesConfig
+	Admin         AdminConfig
+	Updates       UpdatesConfig
 
 	ReadTimeout  time.Duration
 	WriteTimeout time.Duration
@@ -42,13 +55,46 @@ type CORSConfig struct {
 type AuthConfig struct {
 	APIKey APIKeyConfig
 	JWT    JWTConfig
+	OIDC   OIDCConfig
+
+	// ProtectDashboard requires authentication for /v1/dashboard when any
+	// authenticator is enabled. Studio must then send credentials.
+	ProtectDashboard bool
+}
+
+// Enabled reports whether any authentication method is enabled
+func (c AuthConfig) Enabled() bool {
+	return c.APIKey.Enabled || c.JWT.Enabled || c.OIDC.Enabled
 }
 
 // APIKeyConfig holds API key authentication settings
 type APIKeyConfig struct {
 	Enabled    bool
 	HeaderName string
-	Keys       []string
+	// Keys are plain keys granted DefaultRoles
+	Keys []string
+	// DefaultRoles applied to plain Keys (defaults to admin)
+	DefaultRoles []string
+	// Entries are keys with explicit roles and tenant
+	Entries []APIKeyEntry
+}
+
+// APIKeyEntry describes a statically configured API key
+type APIKeyEntry struct {
+	Key      string
+	Name     string
+	Roles    []string
+	TenantID string
+}
+
+// OIDCConfig holds OpenID Connect authentication settings
+type OIDCConfig struct {
+	Enabled     bool
+	Issuer      string
+	Audience    string
+	JWKSURL     string
+	RolesClaim  string
+	TenantClaim string
 }
 
 // JWTConfig holds JWT authentication settings
@@ -62,6 +108,150 @@ type JWTConfig struct {
 	ExpiryMinutes int
 }
 
+// MultitenancyConfig holds tenant isolation settings
+type MultitenancyConfig struct {
+	Enabled bool
+	// HeaderName lets tenant-less principals (e.g. global admins) pick a tenant
+	HeaderName string
+	// DefaultTenant is used when neither the principal nor the header names one.
+	// Empty means requests without a tenant are rejected.
+	DefaultTenant string
+	// DefaultQuota applies to tenants without an entry in Quotas
+	DefaultQuota TenantQuota
+	Quotas       map[string]TenantQuota
+}
+
+// TenantQuota holds per-tenant limits. Zero values mean unlimited.
+type TenantQuota struct {
+	RequestsPerMinute int
+	BurstSize         int
+	MaxAgents         int
+	MaxSessions       int
+}
+
+// QuotaFor returns the quota for tenantID
+func (c MultitenancyConfig) QuotaFor(tenantID string) TenantQuota {
+	if q, ok := c.Quotas[tenantID]; ok {
+		return q
+	}
+	return c.DefaultQuota
+}
+
+// AgentPoolConfig holds warm agent pool settings for chat endpoints
+type AgentPoolConfig struct {
+	Enabled         bool
+	Templates       []string
+	SizePerTemplate int
+	MaxUses         int
+	MaxIdle         time.Duration
+}
+
+// ArtifactsConfig holds artifact storage and retention settings.
+// Zero limits mean unlimited.
+type ArtifactsConfig struct {
+	Enabled bool
+	// Dir stores artifact content (defaults to <DataDir>/artifacts)
+	Dir              string
+	DefaultTTL       time.Duration
+	MaxAge           time.Duration
+	MaxPerSession    int
+	MaxTotalBytes    int64
+	MaxArtifactBytes int64
+	// GCInterval runs the retention policy periodically (0 disables the loop)
+	GCInterval time.Duration
+}
+
+// Policy returns the retention policy for the artifact store
+func (c ArtifactsConfig) Policy() artifact.Policy {
+	return artifact.Policy{
+		DefaultTTL:       c.DefaultTTL,
+		MaxAge:           c.MaxAge,
+		MaxPerSession:    c.MaxPerSession,
+		MaxTotalBytes:    c.MaxTotalBytes,
+		MaxArtifactBytes: c.MaxArtifactBytes,
+	}
+}
+
+// UsageConfig holds token usage recording settings. Recorded usage feeds
+// the monthly usage and cost reports and the dashboard tool analytics.
+type UsageConfig struct {
+	Enabled bool
+}
+
+// ExperimentsConfig holds A/B experiment settings. When enabled, new agents
+// whose template has a running experiment are routed to its variants.
+type ExperimentsConfig struct {
+	Enabled bool
+}
+
+// IdentityConfig holds agent identity settings. When enabled, every agent
+// gets its own signing keypair, kept in the store; artifacts, commits made
+// through the Bash tool and exported reports are signed, and signatures can be
+// checked at /v1/identities/verify.
+type IdentityConfig struct {
+	Enabled bool
+}
+
+// AuditConfig holds audit trail settings. When enabled, permission
+// decisions, sandbox commands and session events are written to a per-tenant
+// hash-chained JSONL trail that can be exported and verified.
+type AuditConfig struct {
+	Enabled bool
+	// Dir stores the trail (defaults to <DataDir>/audit). Each server instance
+	// needs its own directory.
+	Dir string
+	// Retention drops daily files older than this (0 keeps everything)
+	Retention time.Duration
+	// RetentionInterval runs the retention policy periodically (defaults to an hour)
+	RetentionInterval time.Duration
+}
+
+// TracesConfig holds dashboard trace persistence settings. When enabled,
+// every agent run is recorded as a trace and written to the store according
+// to the sampling policy, so trace details stay available after the agent's
+// in-memory event timeline is gone.
+type TracesConfig struct {
+	Enabled bool
+	// SampleRate is the fraction (0-1) of runs kept by head-based sampling
+	SampleRate float64
+	// AlwaysSampleErrors keeps every failed run regardless of SampleRate
+	AlwaysSampleErrors bool
+	// Retention deletes traces older than this (0 keeps everything)
+	Retention time.Duration
+	// CompactAfter strips span attributes such as tool arguments from
+	// traces older than this (0 disables compaction)
+	CompactAfter time.Duration
+	// CompactionInterval runs retention and compaction periodically
+	// (defaults to an hour)
+	CompactionInterval time.Duration
+}
+
+// AdminConfig holds the opt-in diagnostics listener. When Addr is set, pprof,
+// a goroutine dump, EventBus queue depths and store latencies are served on
+// that separate address without authentication, so it should be bound to
+// localhost or a private interface.
+type AdminConfig struct {
+	Addr string
+}
+
+// TemplatesConfig holds agent template loading settings. Templates are read
+// from YAML/JSON files in Dir at startup; with Watch, edits to the directory
+// are picked up without a restart. Templates updated through the REST API are
+// written back to Dir. An empty Dir keeps templates in memory only.
+type TemplatesConfig struct {
+	Dir   string
+	Watch bool
+}
+
+// UpdatesConfig holds desktop release hosting. Dir contains the release
+// manifest (update.ManifestFile) and the installer files it references; the
+// manifest is re-read when it changes, so publishing, pausing or widening a
+// rollout needs no restart. Update routes are public because desktop installs
+// check for updates without credentials. An empty Dir disables them.
+type UpdatesConfig struct {
+	Dir string
+}
+
 // RateLimitConfig holds rate limiting configuration
 type RateLimitConfig struct {
 	Enabled       bool
@@ -72,9 +262,14 @@ type RateLimitConfig struct {
 
 // LoggingConfig holds logging configuration
 type LoggingConfig struct {
-	Level      string
-	Format     string
+	// Level is a level spec applied to pkg/logging: a default level optionally
+	// followed by per-component overrides, e.g. "info,Dashboard=debug"
+	Level string
+	// Format is "json" or "console" ("text" is accepted as console)
+	Format string
+	// Output is "stdout", "stderr" or a file path, rotated per Rotation
 	Output     string
+	Rotation   logging.RotationConfig
 	Structured bool
 }
 
@@ -150,9 +345,10 @@ func DefaultConfig() *Config {
 		},
 		Auth: AuthConfig{
 			APIKey: APIKeyConfig{
-				Enabled:    false, // Disabled for local development
-				HeaderName: "X-API-Key",
-				Keys:       []string{"dev-key-12345"},
+				Enabled:      false, // Disabled for local development
+				HeaderName:   "X-API-Key",
+				Keys:         []string{"dev-key-12345"},
+				DefaultRoles: []string{"admin"},
 			},
 			JWT: JWTConfig{
 				Enabled:       false,
@@ -161,6 +357,7 @@ func DefaultConfig() *Config {
 				Audience:      "aster-api",
 				ExpiryMinutes: 60,
 			},
+			ProtectDashboard: true,
 		},
 		RateLimit: RateLimitConfig{
 			Enabled:       true,
@@ -172,6 +369,7 @@ func DefaultConfig() *Config {
 			Level:      "info",
 			Format:     "json",
 			Output:     "stdout",
+			Rotation:   logging.DefaultRotationConfig(),
 			Structured: true,
 		},
 		Observability: ObservabilityConfig{
@@ -204,6 +402,23 @@ func DefaultConfig() *Config {
 		Redis: RedisConfig{
 			Enabled: false,
 		},
+		Multitenancy: MultitenancyConfig{
+			Enabled:    false,
+			HeaderName: "X-Tenant-ID",
+		},
+		Usage: UsageConfig{
+			Enabled: true,
+		},
+		Experiments: ExperimentsConfig{
+			Enabled: true,
+		},
+		Traces: TracesConfig{
+			Enabled:            true,
+			SampleRate:         1.0,
+			AlwaysSampleErrors: true,
+			Retention:          7 * 24 * time.Hour,
+			CompactAfter:       24 * time.Hour,
+		},
 		ReadTimeout:  30 * time.Second,
 		WriteTimeout: 30 * time.Second,
 		IdleTimeout:  120 * time.Second,
@@ -0,0 +1,65 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/astercloud/aster/pkg/experiments"
+	"github.com/astercloud/aster/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExperimentRoutes(t *testing.T) {
+	srv, cleanup := setupTestServer(t)
+	defer cleanup()
+	require.NotNil(t, srv.experiments)
+	require.NotNil(t, srv.deps.AgentDeps.Experiments)
+
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		srv.Router().ServeHTTP(w, req)
+		return w
+	}
+
+	w := serve(http.MethodPost, "/v1/experiments", `{"name":"model-swap","template_id":"base","status":"running","variants":[{"name":"small","percent":100,"model":"gpt-4o-mini"}]}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var created struct {
+		Data experiments.Experiment `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	assert.Equal(t, 1, created.Data.Version)
+
+	w = serve(http.MethodPost, "/v1/experiments", `{"name":"bad","template_id":"base","variants":[{"name":"x","percent":150,"model":"m"}]}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// 100% 分配到 small 变体
+	a, err := srv.experiments.Assign(context.Background(), &types.AgentConfig{AgentID: "agt-1", TemplateID: "base"})
+	require.NoError(t, err)
+	require.Equal(t, "small", a.Variant)
+	require.NoError(t, srv.experiments.RecordRun(context.Background(), experiments.Run{
+		ExperimentID: created.Data.ID, Variant: "small", AgentID: "agt-1", Model: "gpt-4o-mini", Completed: true, DurationMs: 120,
+	}))
+
+	w = serve(http.MethodPost, "/v1/experiments/grades", `{"agent_id":"agt-1","grader":"keyword_coverage","keywords":["paris"],"answer":"The capital is Paris."}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"passed":true`)
+	w = serve(http.MethodPost, "/v1/experiments/grades", `{"agent_id":"agt-unknown","grader":"manual","score":1}`)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = serve(http.MethodGet, "/v1/dashboard/experiments/"+created.Data.ID, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"variant":"small"`)
+	assert.Contains(t, w.Body.String(), `"success_rate":1`)
+
+	w = serve(http.MethodDelete, "/v1/experiments/"+created.Data.ID, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = serve(http.MethodGet, "/v1/experiments/"+created.Data.ID, "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
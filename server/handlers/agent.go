@@ -1,15 +1,18 @@
 package handlers
 
 import (
+	"context"
 	"errors"
 	"io"
 	"net/http"
-	"os"
 	"strings"
 	"time"
 
 	"github.com/astercloud/aster/pkg/agent"
+	"github.com/astercloud/aster/pkg/agenterr"
+	"github.com/astercloud/aster/pkg/config"
 	"github.com/astercloud/aster/pkg/logging"
+	"github.com/astercloud/aster/pkg/multitenancy"
 	"github.com/astercloud/aster/pkg/store"
 	"github.com/astercloud/aster/pkg/types"
 	"github.com/gin-gonic/gin"
@@ -19,6 +22,7 @@ import (
 type AgentHandler struct {
 	store *store.Store
 	deps  *agent.Dependencies
+	pool  *WarmPool
 }
 
 // NewAgentHandler creates a new AgentHandler
@@ -29,6 +33,39 @@ func NewAgentHandler(st store.Store, deps *agent.Dependencies) *AgentHandler {
 	}
 }
 
+// NewAgentHandlerWithPool creates an AgentHandler that serves chat requests
+// for pooled templates from a WarmPool
+func NewAgentHandlerWithPool(st store.Store, deps *agent.Dependencies, pool *WarmPool) *AgentHandler {
+	h := NewAgentHandler(st, deps)
+	h.pool = pool
+	return h
+}
+
+// acquireChatAgent returns a warm agent when the request uses the plain template
+// configuration, otherwise creates a dedicated one. release must be called when done.
+func (h *AgentHandler) acquireChatAgent(ctx context.Context, cfg *types.AgentConfig) (ag *agent.Agent, release func(), err error) {
+	pooled := h.pool != nil && h.pool.Handles(cfg.TemplateID) &&
+		cfg.ModelConfig == nil && cfg.Sandbox == nil && len(cfg.Middlewares) == 0 && len(cfg.Metadata) == 0
+	if pooled {
+		ag, warm, err := h.pool.Acquire(ctx, cfg.TemplateID)
+		if err != nil {
+			return nil, nil, err
+		}
+		logging.Debug(ctx, "agent.pool.acquired", map[string]any{
+			"agent_id":    ag.ID(),
+			"template_id": cfg.TemplateID,
+			"warm":        warm,
+		})
+		return ag, func() { h.pool.Release(context.WithoutCancel(ctx), ag) }, nil
+	}
+
+	ag, err = agent.Create(ctx, cfg, h.deps)
+	if err != nil {
+		return nil, nil, err
+	}
+	return ag, func() { _ = ag.Close() }, nil
+}
+
 // Create creates a new agent
 func (h *AgentHandler) Create(c *gin.Context) {
 	var req struct {
@@ -65,6 +102,9 @@ func (h *AgentHandler) Create(c *gin.Context) {
 		Metadata:         req.Metadata,
 		SkillsPackage:    req.SkillsPackage,
 	}
+	if tenantID, err := multitenancy.GetTenantID(ctx); err == nil {
+		config.Multitenancy = &types.MultitenancyConfig{Enabled: true, TenantID: tenantID}
+	}
 
 	// 创建 Agent 实例
 	ag, err := agent.Create(ctx, config, h.deps)
@@ -627,24 +667,14 @@ func (h *AgentHandler) Chat(c *gin.Context) {
 	// If ModelConfig is provided but missing API key, try to fill from environment
 	if cfg.ModelConfig != nil && cfg.ModelConfig.APIKey == "" {
 		provider := cfg.ModelConfig.Provider
-		var apiKey string
-		switch provider {
-		case "deepseek":
-			apiKey = os.Getenv("DEEPSEEK_API_KEY")
-		case "anthropic":
-			apiKey = os.Getenv("ANTHROPIC_API_KEY")
-		case "openai":
-			apiKey = os.Getenv("OPENAI_API_KEY")
-		default:
-			apiKey = os.Getenv(strings.ToUpper(provider) + "_API_KEY")
-		}
+		apiKey := config.ProviderAPIKey(provider)
 		if apiKey != "" {
 			cfg.ModelConfig.APIKey = apiKey
 		}
 	}
 
-	// Create agent instance
-	ag, err := agent.Create(ctx, cfg, h.deps)
+	// Create agent instance (warm pool when possible)
+	ag, release, err := h.acquireChatAgent(ctx, cfg)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
@@ -655,6 +685,7 @@ func (h *AgentHandler) Chat(c *gin.Context) {
 		})
 		return
 	}
+	defer release()
 
 	// Execute chat
 	var result *types.CompleteResult
@@ -685,16 +716,15 @@ func (h *AgentHandler) Chat(c *gin.Context) {
 	}
 
 	if err != nil {
+		agentErr := agenterr.From(err)
 		logging.Error(ctx, "chat.failed", map[string]any{
 			"agent_id": ag.ID(),
 			"error":    err.Error(),
+			"code":     agentErr.Code,
 		})
-		c.JSON(http.StatusInternalServerError, gin.H{
+		c.JSON(agentErr.Code.HTTPStatus(), gin.H{
 			"success": false,
-			"error": gin.H{
-				"code":    "chat_failed",
-				"message": err.Error(),
-			},
+			"error":   agentErr,
 		})
 		return
 	}
@@ -703,16 +733,30 @@ func (h *AgentHandler) Chat(c *gin.Context) {
 		"agent_id":    ag.ID(),
 		"text_length": len(result.Text),
 		"status":      result.Status,
+		"termination": result.TerminationReason,
 	})
 
-	// Return response
-	c.JSON(http.StatusOK, gin.H{
-		"success":  true,
-		"agent_id": ag.ID(),
-		"text":     result.Text,
-		"output":   result.Text,
-		"status":   result.Status,
-	})
+	// Return response. Runs stopped by a limit still succeed with a partial
+	// result; other failures are reported with the error's status and code.
+	status := http.StatusOK
+	if result.Error != nil && !result.TerminationReason.IsLimit() {
+		status = result.Error.Code.HTTPStatus()
+	}
+	resp := gin.H{
+		"success":            status == http.StatusOK,
+		"agent_id":           ag.ID(),
+		"text":               result.Text,
+		"output":             result.Text,
+		"status":             result.Status,
+		"termination_reason": result.TerminationReason,
+	}
+	if result.Summary != "" {
+		resp["summary"] = result.Summary
+	}
+	if result.Error != nil {
+		resp["error"] = result.Error
+	}
+	c.JSON(status, resp)
 }
 
 // StreamChat handles streaming chat requests
@@ -751,24 +795,14 @@ func (h *AgentHandler) StreamChat(c *gin.Context) {
 	// Fill API key from environment if missing
 	if cfg.ModelConfig != nil && cfg.ModelConfig.APIKey == "" {
 		provider := cfg.ModelConfig.Provider
-		var apiKey string
-		switch provider {
-		case "deepseek":
-			apiKey = os.Getenv("DEEPSEEK_API_KEY")
-		case "anthropic":
-			apiKey = os.Getenv("ANTHROPIC_API_KEY")
-		case "openai":
-			apiKey = os.Getenv("OPENAI_API_KEY")
-		default:
-			apiKey = os.Getenv(strings.ToUpper(provider) + "_API_KEY")
-		}
+		apiKey := config.ProviderAPIKey(provider)
 		if apiKey != "" {
 			cfg.ModelConfig.APIKey = apiKey
 		}
 	}
 
-	// Create agent instance
-	ag, err := agent.Create(ctx, cfg, h.deps)
+	// Create agent instance (warm pool when possible)
+	ag, release, err := h.acquireChatAgent(ctx, cfg)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
@@ -779,7 +813,7 @@ func (h *AgentHandler) StreamChat(c *gin.Context) {
 		})
 		return
 	}
-	defer func() { _ = ag.Close() }()
+	defer release()
 
 	// Set SSE headers
 	c.Header("Content-Type", "text/event-stream")
@@ -812,10 +846,7 @@ func (h *AgentHandler) StreamChat(c *gin.Context) {
 				"agent_id": ag.ID(),
 				"error":    err.Error(),
 			})
-			c.SSEvent("error", gin.H{
-				"code":    "stream_error",
-				"message": err.Error(),
-			})
+			c.SSEvent("error", agenterr.From(err))
 			flusher.Flush()
 			break
 		}
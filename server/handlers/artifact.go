@@ -0,0 +1,208 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/astercloud/aster/pkg/artifact"
+	"github.com/astercloud/aster/pkg/identity"
+	"github.com/astercloud/aster/pkg/logging"
+	"github.com/astercloud/aster/pkg/store"
+	"github.com/gin-gonic/gin"
+)
+
+// ArtifactHandler handles artifact listing, download and lifecycle requests
+type ArtifactHandler struct {
+	store     *store.Store
+	artifacts *artifact.Store
+}
+
+// NewArtifactHandler creates a new ArtifactHandler
+func NewArtifactHandler(st store.Store, artifacts *artifact.Store) *ArtifactHandler {
+	return &ArtifactHandler{store: &st, artifacts: artifacts}
+}
+
+// ListForSession lists the artifacts produced in a session,
+// including those recorded against the session's agent
+func (h *ArtifactHandler) ListForSession(c *gin.Context) {
+	ctx := c.Request.Context()
+	id := c.Param("id")
+
+	var session SessionRecord
+	if err := (*h.store).Get(ctx, "sessions", id, &session); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			artifactError(c, http.StatusNotFound, "not_found", "Session not found")
+			return
+		}
+		artifactError(c, http.StatusInternalServerError, "internal_error", "Failed to get session: "+err.Error())
+		return
+	}
+
+	artifacts, err := h.artifacts.List(ctx, artifact.Filter{
+		SessionID: id,
+		AgentID:   session.AgentID,
+		Kind:      artifact.Kind(c.Query("kind")),
+	})
+	if err != nil {
+		artifactError(c, http.StatusInternalServerError, "internal_error", "Failed to list artifacts: "+err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    artifacts,
+	})
+}
+
+// List lists artifacts filtered by session_id, agent_id and kind
+func (h *ArtifactHandler) List(c *gin.Context) {
+	artifacts, err := h.artifacts.List(c.Request.Context(), artifact.Filter{
+		SessionID: c.Query("session_id"),
+		AgentID:   c.Query("agent_id"),
+		Kind:      artifact.Kind(c.Query("kind")),
+	})
+	if err != nil {
+		artifactError(c, http.StatusInternalServerError, "internal_error", "Failed to list artifacts: "+err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    artifacts,
+	})
+}
+
+// Get returns artifact metadata
+func (h *ArtifactHandler) Get(c *gin.Context) {
+	a, err := h.artifacts.Get(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    a,
+	})
+}
+
+// Download streams the artifact content. Images (except SVG) and PDFs are
+// served inline when ?inline=true, everything else as an attachment. Signed artifacts carry
+// their signature in the identity.HeaderSignature header.
+func (h *ArtifactHandler) Download(c *gin.Context) {
+	rc, a, err := h.artifacts.Open(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	defer func() { _ = rc.Close() }()
+
+	etag := fmt.Sprintf("%q", a.SHA256)
+	if c.GetHeader("If-None-Match") == etag {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	disposition := "attachment"
+	if c.Query("inline") == "true" && inlineSafe(a) {
+		disposition = "inline"
+	}
+	headers := map[string]string{
+		"Content-Disposition":     mime.FormatMediaType(disposition, map[string]string{"filename": a.Name}),
+		"ETag":                    etag,
+		"X-Content-Type-Options":  "nosniff",
+		"Content-Security-Policy": "sandbox",
+		"Cache-Control":           "private, max-age=3600",
+	}
+	if a.Signature != nil {
+		headers[identity.HeaderSignature] = a.Signature.EncodeHeader()
+	}
+	c.DataFromReader(http.StatusOK, a.Size, a.MIMEType, rc, headers)
+}
+
+// inlineSafe reports whether an artifact can be rendered inline. SVG is an
+// image that can carry script, so it is always downloaded as an attachment.
+func inlineSafe(a *artifact.Artifact) bool {
+	mediaType, _, _ := mime.ParseMediaType(a.MIMEType)
+	if mediaType == "image/svg+xml" || strings.EqualFold(path.Ext(a.Name), ".svg") {
+		return false
+	}
+	return a.Kind == artifact.KindImage || mediaType == "application/pdf"
+}
+
+// Update pins or unpins an artifact. Pinned artifacts never expire.
+func (h *ArtifactHandler) Update(c *gin.Context) {
+	var req struct {
+		Pinned *bool `json:"pinned"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || req.Pinned == nil {
+		artifactError(c, http.StatusBadRequest, "bad_request", "pinned is required")
+		return
+	}
+
+	a, err := h.artifacts.SetPinned(c.Request.Context(), c.Param("id"), *req.Pinned)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    a,
+	})
+}
+
+// Delete removes an artifact and its content
+func (h *ArtifactHandler) Delete(c *gin.Context) {
+	ctx := c.Request.Context()
+	id := c.Param("id")
+
+	if err := h.artifacts.Delete(ctx, id); err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	logging.Info(ctx, "artifact.deleted", map[string]any{
+		"artifact_id": id,
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+	})
+}
+
+// GC applies the retention policy immediately
+func (h *ArtifactHandler) GC(c *gin.Context) {
+	result, err := h.artifacts.GC(c.Request.Context())
+	if err != nil {
+		artifactError(c, http.StatusInternalServerError, "internal_error", "Failed to collect artifacts: "+err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    result,
+	})
+}
+
+func (h *ArtifactHandler) handleError(c *gin.Context, err error) {
+	if errors.Is(err, artifact.ErrNotFound) {
+		artifactError(c, http.StatusNotFound, "not_found", "Artifact not found")
+		return
+	}
+	artifactError(c, http.StatusInternalServerError, "internal_error", err.Error())
+}
+
+func artifactError(c *gin.Context, status int, code, message string) {
+	c.JSON(status, gin.H{
+		"success": false,
+		"error": gin.H{
+			"code":    code,
+			"message": message,
+		},
+	})
+}
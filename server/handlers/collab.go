@@ -0,0 +1,691 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/astercloud/aster/pkg/agent"
+	"github.com/astercloud/aster/pkg/agenterr"
+	"github.com/astercloud/aster/pkg/logging"
+	"github.com/astercloud/aster/pkg/multitenancy"
+	"github.com/astercloud/aster/pkg/store"
+	"github.com/astercloud/aster/pkg/types"
+	"github.com/astercloud/aster/server/auth"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Collaborative session event types
+const (
+	CollabEventJoined        = "participant_joined"
+	CollabEventLeft          = "participant_left"
+	CollabEventMessage       = "user_message"
+	CollabEventTextDelta     = "text_delta"
+	CollabEventTurnCompleted = "turn_completed"
+	CollabEventTurnFailed    = "turn_failed"
+)
+
+// sessionEventsCollection stores the attributed events of collaborative sessions
+const sessionEventsCollection = "session_events"
+
+// collabHeartbeat keeps idle live streams from being closed by proxies
+const collabHeartbeat = 25 * time.Second
+
+// CollabEvent is an event of a live collaborative session. Presence changes,
+// user messages and completed turns carry the acting user and are persisted;
+// text deltas are only broadcast to connected participants.
+type CollabEvent struct {
+	ID        string         `json:"id,omitempty"`
+	SessionID string         `json:"session_id"`
+	Seq       int64          `json:"seq,omitempty"`
+	Type      string         `json:"type"`
+	UserID    string         `json:"user_id,omitempty"`
+	Username  string         `json:"username,omitempty"`
+	TurnID    string         `json:"turn_id,omitempty"`
+	Text      string         `json:"text,omitempty"`
+	Data      map[string]any `json:"data,omitempty"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+// CollabParticipant is a user connected to a live session
+type CollabParticipant struct {
+	UserID      string    `json:"user_id"`
+	Username    string    `json:"username,omitempty"`
+	JoinedAt    time.Time `json:"joined_at"`
+	Connections int       `json:"connections"`
+}
+
+// CollabTurn is the agent turn currently being run for a participant
+type CollabTurn struct {
+	ID        string    `json:"id"`
+	UserID    string    `json:"user_id"`
+	Username  string    `json:"username,omitempty"`
+	StartedAt time.Time `json:"started_at"`
+}
+
+// liveSession is the in-memory state of a session with connected participants
+type liveSession struct {
+	id           string
+	participants map[string]*CollabParticipant
+	subscribers  map[chan CollabEvent]struct{}
+	agent        *agent.Agent
+	turn         *CollabTurn
+	seq          int64
+}
+
+// CollabHub lets several authenticated users join the same session, share
+// one agent and its streamed events, and take turns sending messages.
+// Live state is kept per server instance.
+type CollabHub struct {
+	store    *store.Store
+	deps     *agent.Dependencies
+	mu       sync.Mutex
+	sessions map[string]*liveSession // tenant/session ID -> live session
+}
+
+// NewCollabHub creates a new CollabHub
+func NewCollabHub(st store.Store, deps *agent.Dependencies) *CollabHub {
+	return &CollabHub{
+		store:    &st,
+		deps:     deps,
+		sessions: make(map[string]*liveSession),
+	}
+}
+
+// Close closes the agents of all live sessions
+func (h *CollabHub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for key, ls := range h.sessions {
+		if ls.agent != nil {
+			_ = ls.agent.Close()
+		}
+		for ch := range ls.subscribers {
+			close(ch)
+		}
+		delete(h.sessions, key)
+	}
+}
+
+// Live joins the session and streams its events as Server-Sent Events until
+// the client disconnects. The first event is a snapshot of the participants
+// and the running turn.
+func (h *CollabHub) Live(c *gin.Context) {
+	user, ok := collabUser(c)
+	if !ok {
+		return
+	}
+	ctx := c.Request.Context()
+	id := c.Param("id")
+	if _, ok := h.loadSession(c, id); !ok {
+		return
+	}
+
+	flusher, ok := c.Writer.(http.Flusher)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "streaming_not_supported",
+				"message": "Streaming not supported",
+			},
+		})
+		return
+	}
+
+	sub, snapshot, joined := h.join(ctx, id, user)
+	defer h.leave(context.WithoutCancel(ctx), id, user, sub)
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	c.SSEvent("snapshot", snapshot)
+	flusher.Flush()
+	if joined != nil {
+		h.persist(ctx, joined)
+	}
+
+	ticker := time.NewTicker(collabHeartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case event, ok := <-sub:
+			if !ok {
+				return
+			}
+			c.SSEvent(event.Type, event)
+			flusher.Flush()
+		case <-ticker.C:
+			_, _ = io.WriteString(c.Writer, ": ping\n\n")
+			flusher.Flush()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// SendMessage sends a message to the shared agent on behalf of the current
+// user. Only participants connected to the live stream may send, and only
+// one turn runs at a time; the reply is streamed to all participants.
+func (h *CollabHub) SendMessage(c *gin.Context) {
+	user, ok := collabUser(c)
+	if !ok {
+		return
+	}
+	var req struct {
+		Message string `json:"message" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "bad_request",
+				"message": err.Error(),
+			},
+		})
+		return
+	}
+	ctx := c.Request.Context()
+	id := c.Param("id")
+	session, ok := h.loadSession(c, id)
+	if !ok {
+		return
+	}
+
+	key := h.key(ctx, id)
+	turn := &CollabTurn{ID: uuid.New().String(), UserID: user.ID, Username: username(user), StartedAt: time.Now()}
+	h.mu.Lock()
+	ls := h.sessions[key]
+	switch {
+	case ls == nil || ls.participants[user.ID] == nil:
+		h.mu.Unlock()
+		c.JSON(http.StatusForbidden, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "not_a_participant",
+				"message": "Join the live session before sending messages",
+			},
+		})
+		return
+	case ls.turn != nil:
+		current := *ls.turn
+		h.mu.Unlock()
+		c.JSON(http.StatusConflict, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "turn_in_progress",
+				"message": "Another participant's turn is still running",
+				"details": current,
+			},
+		})
+		return
+	}
+	ls.turn = turn
+	ag := ls.agent
+	h.mu.Unlock()
+
+	// The turn runs after the request returns; keep the tenant and user values
+	runCtx := context.WithoutCancel(ctx)
+	if ag == nil {
+		var err error
+		if ag, err = h.createAgent(runCtx, session); err == nil && !h.attachAgent(key, turn, ag) {
+			_ = ag.Close()
+			err = errors.New("session is no longer live")
+		}
+		if err != nil {
+			h.endTurn(key, turn)
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"success": false,
+				"error": gin.H{
+					"code":    "agent_unavailable",
+					"message": "Failed to start session agent: " + err.Error(),
+				},
+			})
+			return
+		}
+	}
+
+	message := h.publish(key, CollabEvent{
+		SessionID: id,
+		Type:      CollabEventMessage,
+		UserID:    user.ID,
+		Username:  turn.Username,
+		TurnID:    turn.ID,
+		Text:      req.Message,
+	})
+	h.persist(ctx, &message)
+
+	go h.runTurn(runCtx, key, id, turn, ag, req.Message)
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"success": true,
+		"data":    turn,
+	})
+}
+
+// Participants lists the users connected to the live session and the running turn
+func (h *CollabHub) Participants(c *gin.Context) {
+	ctx := c.Request.Context()
+	id := c.Param("id")
+	if _, ok := h.loadSession(c, id); !ok {
+		return
+	}
+
+	h.mu.Lock()
+	snapshot := h.snapshot(h.sessions[h.key(ctx, id)], id)
+	h.mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    snapshot,
+	})
+}
+
+// Events lists the persisted, attributed events of a session in order.
+// ?after=<seq> returns only later events, for clients catching up after a reconnect.
+func (h *CollabHub) Events(c *gin.Context) {
+	ctx := c.Request.Context()
+	id := c.Param("id")
+	if _, ok := h.loadSession(c, id); !ok {
+		return
+	}
+	after, _ := strconv.ParseInt(c.Query("after"), 10, 64)
+
+	events, err := h.listEvents(ctx, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "internal_error",
+				"message": "Failed to list session events: " + err.Error(),
+			},
+		})
+		return
+	}
+	events = slices.DeleteFunc(events, func(e CollabEvent) bool { return e.Seq <= after })
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    events,
+	})
+}
+
+// join registers a connection of user and returns its event channel, the
+// session snapshot and the presence event when this is the user's first connection
+func (h *CollabHub) join(ctx context.Context, id string, user *auth.User) (chan CollabEvent, gin.H, *CollabEvent) {
+	key := h.key(ctx, id)
+	h.mu.Lock()
+	_, live := h.sessions[key]
+	h.mu.Unlock()
+	var seq int64
+	if !live {
+		seq = h.lastSeq(ctx, id)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	ls := h.sessions[key]
+	if ls == nil {
+		ls = &liveSession{
+			id:           id,
+			participants: make(map[string]*CollabParticipant),
+			subscribers:  make(map[chan CollabEvent]struct{}),
+			seq:          seq,
+		}
+		h.sessions[key] = ls
+	}
+
+	sub := make(chan CollabEvent, 256)
+	ls.subscribers[sub] = struct{}{}
+
+	var joined *CollabEvent
+	p := ls.participants[user.ID]
+	if p == nil {
+		p = &CollabParticipant{UserID: user.ID, Username: username(user), JoinedAt: time.Now()}
+		ls.participants[user.ID] = p
+		event := h.broadcastLocked(ls, CollabEvent{SessionID: id, Type: CollabEventJoined, UserID: user.ID, Username: p.Username})
+		joined = &event
+	}
+	p.Connections++
+	return sub, h.snapshot(ls, id), joined
+}
+
+// leave unregisters a connection, announces the user's departure after their
+// last connection closes and releases the session once nobody is left
+func (h *CollabHub) leave(ctx context.Context, id string, user *auth.User, sub chan CollabEvent) {
+	key := h.key(ctx, id)
+	h.mu.Lock()
+	ls := h.sessions[key]
+	if ls == nil {
+		h.mu.Unlock()
+		return
+	}
+	if _, ok := ls.subscribers[sub]; ok {
+		delete(ls.subscribers, sub)
+		close(sub)
+	}
+
+	var left *CollabEvent
+	if p := ls.participants[user.ID]; p != nil {
+		p.Connections--
+		if p.Connections <= 0 {
+			delete(ls.participants, user.ID)
+			event := h.broadcastLocked(ls, CollabEvent{SessionID: id, Type: CollabEventLeft, UserID: user.ID, Username: p.Username})
+			left = &event
+		}
+	}
+	h.releaseLocked(key, ls)
+	h.mu.Unlock()
+
+	if left != nil {
+		h.persist(ctx, left)
+	}
+}
+
+// runTurn streams the agent reply to all participants and records it in the session
+func (h *CollabHub) runTurn(ctx context.Context, key, id string, turn *CollabTurn, ag *agent.Agent, input string) {
+	// The agent re-sends each complete message after streaming its deltas;
+	// streamed holds the text since the last complete message so it is not repeated
+	var reply, streamed strings.Builder
+	var turnErr error
+	reader := ag.Stream(ctx, input)
+	for {
+		event, err := reader.Recv()
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				turnErr = err
+			}
+			break
+		}
+		if event == nil {
+			continue
+		}
+		var text strings.Builder
+		blocks := 0
+		for _, block := range event.Content.ContentBlocks {
+			if tb, ok := block.(*types.TextBlock); ok {
+				text.WriteString(tb.Text)
+				blocks++
+			}
+		}
+		if text.Len() == 0 {
+			continue
+		}
+		if s := streamed.String(); s != "" && (text.String() == s || blocks > 1 && strings.HasSuffix(s, text.String())) {
+			streamed.Reset()
+			continue
+		}
+		streamed.WriteString(text.String())
+		reply.WriteString(text.String())
+		h.publish(key, CollabEvent{
+			SessionID: id,
+			Type:      CollabEventTextDelta,
+			UserID:    turn.UserID,
+			Username:  turn.Username,
+			TurnID:    turn.ID,
+			Text:      text.String(),
+		})
+	}
+
+	done := CollabEvent{
+		SessionID: id,
+		Type:      CollabEventTurnCompleted,
+		UserID:    turn.UserID,
+		Username:  turn.Username,
+		TurnID:    turn.ID,
+		Text:      reply.String(),
+		Data:      map[string]any{"agent_id": ag.ID()},
+	}
+	if turnErr != nil {
+		logging.Error(ctx, "session.collab.turn.error", map[string]any{
+			"session_id": id,
+			"turn_id":    turn.ID,
+			"error":      turnErr.Error(),
+		})
+		done.Type = CollabEventTurnFailed
+		done.Data["error"] = agenterr.From(turnErr)
+	} else {
+		h.appendMessages(ctx, id, turn, input, reply.String())
+	}
+
+	done = h.publish(key, done)
+	h.persist(ctx, &done)
+	h.endTurn(key, turn)
+}
+
+// attachAgent keeps a newly created agent in the session for later turns
+func (h *CollabHub) attachAgent(key string, turn *CollabTurn, ag *agent.Agent) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	ls := h.sessions[key]
+	if ls == nil || ls.turn != turn {
+		return false
+	}
+	ls.agent = ag
+	return true
+}
+
+// endTurn clears the running turn, releasing the session if everyone has left
+func (h *CollabHub) endTurn(key string, turn *CollabTurn) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	ls := h.sessions[key]
+	if ls == nil || ls.turn != turn {
+		return
+	}
+	ls.turn = nil
+	h.releaseLocked(key, ls)
+}
+
+// releaseLocked drops a session with no participants and no running turn. Caller holds h.mu.
+func (h *CollabHub) releaseLocked(key string, ls *liveSession) {
+	if len(ls.participants) > 0 || ls.turn != nil {
+		return
+	}
+	if ls.agent != nil {
+		_ = ls.agent.Close()
+	}
+	delete(h.sessions, key)
+}
+
+// publish assigns the next sequence number to event and broadcasts it
+func (h *CollabHub) publish(key string, event CollabEvent) CollabEvent {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	ls := h.sessions[key]
+	if ls == nil {
+		event.CreatedAt = time.Now()
+		return event
+	}
+	return h.broadcastLocked(ls, event)
+}
+
+// broadcastLocked sends event to every connection of ls. Caller holds h.mu.
+func (h *CollabHub) broadcastLocked(ls *liveSession, event CollabEvent) CollabEvent {
+	event.CreatedAt = time.Now()
+	if event.Type != CollabEventTextDelta {
+		ls.seq++
+		event.Seq = ls.seq
+		event.ID = fmt.Sprintf("%s:%010d", ls.id, event.Seq)
+	}
+	for sub := range ls.subscribers {
+		select {
+		case sub <- event:
+		default:
+			logging.Warn(context.Background(), "session.collab.event.dropped", map[string]any{
+				"session_id": ls.id,
+				"type":       event.Type,
+			})
+		}
+	}
+	return event
+}
+
+// persist stores an attributed event in the session event log
+func (h *CollabHub) persist(ctx context.Context, event *CollabEvent) {
+	if event.ID == "" {
+		return
+	}
+	if err := (*h.store).Set(ctx, sessionEventsCollection, event.ID, event); err != nil {
+		logging.Warn(ctx, "session.collab.event.persist_failed", map[string]any{
+			"session_id": event.SessionID,
+			"type":       event.Type,
+			"error":      err.Error(),
+		})
+	}
+}
+
+// listEvents returns the persisted events of a session ordered by sequence number
+func (h *CollabHub) listEvents(ctx context.Context, id string) ([]CollabEvent, error) {
+	records, err := (*h.store).List(ctx, sessionEventsCollection)
+	if err != nil {
+		return nil, err
+	}
+	events := make([]CollabEvent, 0)
+	for _, record := range records {
+		var event CollabEvent
+		if err := store.DecodeValue(record, &event); err != nil || event.SessionID != id {
+			continue
+		}
+		events = append(events, event)
+	}
+	slices.SortFunc(events, func(a, b CollabEvent) int { return int(a.Seq - b.Seq) })
+	return events, nil
+}
+
+// lastSeq returns the sequence number of the last persisted event so a
+// session rejoined after a restart continues its event log
+func (h *CollabHub) lastSeq(ctx context.Context, id string) int64 {
+	events, err := h.listEvents(ctx, id)
+	if err != nil || len(events) == 0 {
+		return 0
+	}
+	return events[len(events)-1].Seq
+}
+
+// appendMessages records the exchange in the session, naming the user who sent it
+func (h *CollabHub) appendMessages(ctx context.Context, id string, turn *CollabTurn, input, reply string) {
+	var session SessionRecord
+	if err := (*h.store).Get(ctx, "sessions", id, &session); err != nil {
+		return
+	}
+	session.Messages = append(session.Messages,
+		types.Message{Role: types.MessageRoleUser, Content: input, Name: turn.Username},
+		types.Message{Role: types.MessageRoleAssistant, Content: reply},
+	)
+	session.UpdatedAt = time.Now()
+	if err := (*h.store).Set(ctx, "sessions", id, &session); err != nil {
+		logging.Warn(ctx, "session.collab.messages.save_failed", map[string]any{
+			"session_id": id,
+			"error":      err.Error(),
+		})
+	}
+}
+
+// createAgent starts the session agent from its stored agent record
+func (h *CollabHub) createAgent(ctx context.Context, session *SessionRecord) (*agent.Agent, error) {
+	if h.deps == nil {
+		return nil, errors.New("agent dependencies are not configured")
+	}
+	var record AgentRecord
+	if err := (*h.store).Get(ctx, "agents", session.AgentID, &record); err != nil {
+		return nil, fmt.Errorf("agent %s: %w", session.AgentID, err)
+	}
+	if record.Config == nil {
+		return nil, fmt.Errorf("agent %s has no config", session.AgentID)
+	}
+	cfg := *record.Config
+	cfg.AgentID = record.ID
+	if cfg.SeedSummary == nil {
+		cfg.SeedSummary = session.Summary
+	}
+	return agent.Create(ctx, &cfg, h.deps)
+}
+
+// snapshot describes the participants and running turn of a session. Caller holds h.mu.
+func (h *CollabHub) snapshot(ls *liveSession, id string) gin.H {
+	participants := make([]CollabParticipant, 0)
+	var turn *CollabTurn
+	var seq int64
+	if ls != nil {
+		for _, p := range ls.participants {
+			participants = append(participants, *p)
+		}
+		slices.SortFunc(participants, func(a, b CollabParticipant) int { return a.JoinedAt.Compare(b.JoinedAt) })
+		turn, seq = ls.turn, ls.seq
+	}
+	return gin.H{
+		"session_id":   id,
+		"participants": participants,
+		"turn":         turn,
+		"seq":          seq,
+	}
+}
+
+// loadSession fetches the session record, writing a 404 or 500 response on failure
+func (h *CollabHub) loadSession(c *gin.Context, id string) (*SessionRecord, bool) {
+	var session SessionRecord
+	if err := (*h.store).Get(c.Request.Context(), "sessions", id, &session); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"success": false,
+				"error": gin.H{
+					"code":    "not_found",
+					"message": "Session not found",
+				},
+			})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "internal_error",
+				"message": "Failed to get session: " + err.Error(),
+			},
+		})
+		return nil, false
+	}
+	return &session, true
+}
+
+// key scopes live sessions by tenant
+func (h *CollabHub) key(ctx context.Context, id string) string {
+	return multitenancy.GetTenantIDOrDefault(ctx, "") + "/" + id
+}
+
+// collabUser returns the authenticated user, writing a 401 response when there is none
+func collabUser(c *gin.Context) (*auth.User, bool) {
+	user, ok := auth.CurrentUser(c)
+	if !ok || user.ID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "unauthorized",
+				"message": "Collaborative sessions require an authenticated user",
+			},
+		})
+		return nil, false
+	}
+	return user, true
+}
+
+// username is the display name used to attribute a user's events
+func username(user *auth.User) string {
+	if user.Username != "" {
+		return user.Username
+	}
+	if name, _ := user.Metadata["api_key_name"].(string); name != "" {
+		return name
+	}
+	return user.ID
+}
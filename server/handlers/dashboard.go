@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"context"
 	"net/http"
 	"sort"
 	"strconv"
@@ -8,9 +9,13 @@ import (
 	"time"
 
 	"github.com/astercloud/aster/pkg/dashboard"
+	"github.com/astercloud/aster/pkg/events"
 	"github.com/astercloud/aster/pkg/logging"
+	"github.com/astercloud/aster/pkg/multitenancy"
+	"github.com/astercloud/aster/pkg/provider"
 	"github.com/astercloud/aster/pkg/store"
 	"github.com/astercloud/aster/pkg/types"
+	"github.com/astercloud/aster/pkg/usage"
 	"github.com/gin-gonic/gin"
 )
 
@@ -19,6 +24,8 @@ type DashboardHandler struct {
 	aggregator *dashboard.Aggregator
 	registry   *RuntimeAgentRegistry
 	store      *store.Store
+	usage      *usage.Ledger
+	keys       *provider.KeyManager
 }
 
 // NewDashboardHandler creates a new DashboardHandler
@@ -39,6 +46,33 @@ func NewDashboardHandlerWithRegistry(registry *RuntimeAgentRegistry, st store.St
 	}
 }
 
+// tenantEventBuses limits the registry's EventBuses to the agents of one tenant
+type tenantEventBuses struct {
+	registry *RuntimeAgentRegistry
+	tenantID string
+}
+
+func (t tenantEventBuses) GetEventBuses() []*events.EventBus {
+	return t.registry.GetEventBusesForTenant(t.tenantID)
+}
+
+// SetUsageLedger enables tool analytics backed by the usage ledger
+func (h *DashboardHandler) SetUsageLedger(ledger *usage.Ledger) {
+	h.usage = ledger
+}
+
+// SetKeyManager enables per-key usage and health reporting for providers
+// configured with several API keys
+func (h *DashboardHandler) SetKeyManager(keys *provider.KeyManager) {
+	h.keys = keys
+}
+
+// SetTraceStore makes persisted traces available to the trace list and
+// detail endpoints
+func (h *DashboardHandler) SetTraceStore(ts *dashboard.TraceStore) {
+	h.aggregator.SetTraceStore(ts)
+}
+
 // GetOverview returns overview statistics
 func (h *DashboardHandler) GetOverview(c *gin.Context) {
 	ctx := c.Request.Context()
@@ -49,7 +83,8 @@ func (h *DashboardHandler) GetOverview(c *gin.Context) {
 
 	// 如果有 registry，从所有 Agent 的 EventBus 聚合数据
 	if h.registry != nil {
-		stats, err = h.aggregator.GetOverviewStatsFromEventBuses(ctx, period, h.registry)
+		buses := tenantEventBuses{registry: h.registry, tenantID: multitenancy.GetTenantIDOrDefault(ctx, "")}
+		stats, err = h.aggregator.GetOverviewStatsFromEventBuses(ctx, period, buses)
 	} else {
 		// 否则使用默认方法（从 Store 读取）
 		stats, err = h.aggregator.GetOverviewStats(ctx, period)
@@ -289,6 +324,13 @@ func (h *DashboardHandler) GetInsights(c *gin.Context) {
 	ctx := c.Request.Context()
 
 	insights, err := h.aggregator.GetInsights(ctx)
+	if err == nil && h.usage != nil {
+		var report *usage.ToolReport
+		report, err = h.toolReport(c, "7d")
+		if err == nil {
+			insights = append(insights, usage.ToolInsights(report)...)
+		}
+	}
 	if err != nil {
 		logging.Error(ctx, "dashboard.insights.error", map[string]any{
 			"error": err.Error(),
@@ -309,6 +351,67 @@ func (h *DashboardHandler) GetInsights(c *gin.Context) {
 	})
 }
 
+// GetTools returns per-tool invocation counts, success rates, latency and
+// result token cost, grouped by template
+func (h *DashboardHandler) GetTools(c *gin.Context) {
+	ctx := c.Request.Context()
+	if h.usage == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "usage_disabled",
+				"message": "usage recording is not enabled",
+			},
+		})
+		return
+	}
+
+	report, err := h.toolReport(c, c.DefaultQuery("period", "7d"))
+	if err != nil {
+		logging.Error(ctx, "dashboard.tools.error", map[string]any{
+			"error": err.Error(),
+		})
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "internal_error",
+				"message": err.Error(),
+			},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    report,
+	})
+}
+
+// toolReport aggregates tool usage over period, optionally filtered by the
+// template_id query parameter
+func (h *DashboardHandler) toolReport(c *gin.Context, period string) (*usage.ToolReport, error) {
+	now := time.Now()
+	return usage.GenerateToolStats(c.Request.Context(), h.usage, usage.ToolStatsOptions{
+		Since:      now.Add(-periodDuration(period)),
+		Until:      now,
+		TemplateID: c.Query("template_id"),
+	})
+}
+
+// periodDuration maps dashboard period names to durations, defaulting to 24h
+func periodDuration(period string) time.Duration {
+	switch period {
+	case "hour":
+		return time.Hour
+	case "week", "7d":
+		return 7 * 24 * time.Hour
+	case "month", "30d":
+		return 30 * 24 * time.Hour
+	default:
+		return 24 * time.Hour
+	}
+}
+
 // GetRecentEvents returns recent events from the timeline
 func (h *DashboardHandler) GetRecentEvents(c *gin.Context) {
 	ctx := c.Request.Context()
@@ -337,7 +440,7 @@ func (h *DashboardHandler) GetRecentEvents(c *gin.Context) {
 
 	// 从所有 Agent 的 EventBus 聚合事件
 	var allEvents []types.AgentEventEnvelope
-	for _, eb := range h.registry.GetEventBuses() {
+	for _, eb := range h.registry.GetEventBusesForTenant(multitenancy.GetTenantIDOrDefault(ctx, "")) {
 		if eb != nil {
 			evts := eb.GetTimelineRange(0, limit)
 			allEvents = append(allEvents, evts...)
@@ -416,7 +519,7 @@ func (h *DashboardHandler) GetEventsSince(c *gin.Context) {
 	// 从所有 Agent 的 EventBus 聚合事件
 	var allEvents []types.AgentEventEnvelope
 	maxCursor := cursor
-	for _, eb := range h.registry.GetEventBuses() {
+	for _, eb := range h.registry.GetEventBusesForTenant(multitenancy.GetTenantIDOrDefault(ctx, "")) {
 		if eb != nil {
 			evts := eb.GetTimelineSince(cursor)
 			allEvents = append(allEvents, evts...)
@@ -458,7 +561,7 @@ func (h *DashboardHandler) GetEventsSince(c *gin.Context) {
 
 // GetPricing returns model pricing information
 func (h *DashboardHandler) GetPricing(c *gin.Context) {
-	pricing := dashboard.DefaultModelPricing
+	pricing := dashboard.DefaultPricingTable().Current(time.Now())
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
@@ -469,10 +572,15 @@ func (h *DashboardHandler) GetPricing(c *gin.Context) {
 // UpdatePricing updates model pricing (for custom pricing)
 func (h *DashboardHandler) UpdatePricing(c *gin.Context) {
 	var req struct {
-		Model           string  `json:"model" binding:"required"`
-		InputPricePerM  float64 `json:"input_price_per_m" binding:"required"`
-		OutputPricePerM float64 `json:"output_price_per_m" binding:"required"`
-		Currency        string  `json:"currency"`
+		Model               string                  `json:"model" binding:"required"`
+		Provider            string                  `json:"provider"`
+		InputPricePerM      float64                 `json:"input_price_per_m" binding:"required"`
+		OutputPricePerM     float64                 `json:"output_price_per_m" binding:"required"`
+		CacheReadPricePerM  float64                 `json:"cache_read_price_per_m"`
+		CacheWritePricePerM float64                 `json:"cache_write_price_per_m"`
+		Tiers               []dashboard.PricingTier `json:"tiers"`
+		BatchDiscount       float64                 `json:"batch_discount"`
+		Currency            string                  `json:"currency"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -486,24 +594,34 @@ func (h *DashboardHandler) UpdatePricing(c *gin.Context) {
 		return
 	}
 
-	currency := req.Currency
-	if currency == "" {
-		currency = "USD"
+	// 更新定价（替换该模型的历史定价，之后创建的计算器生效）
+	pricing := dashboard.ModelPricing{
+		Model:               req.Model,
+		Provider:            req.Provider,
+		InputPricePerM:      req.InputPricePerM,
+		OutputPricePerM:     req.OutputPricePerM,
+		CacheReadPricePerM:  req.CacheReadPricePerM,
+		CacheWritePricePerM: req.CacheWritePricePerM,
+		Tiers:               req.Tiers,
+		BatchDiscount:       req.BatchDiscount,
+		Currency:            req.Currency,
 	}
-
-	// 更新定价
-	dashboard.DefaultModelPricing[req.Model] = dashboard.ModelPricing{
-		Model:           req.Model,
-		InputPricePerM:  req.InputPricePerM,
-		OutputPricePerM: req.OutputPricePerM,
-		Currency:        currency,
+	if err := dashboard.SetDefaultPricing(pricing); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "bad_request",
+				"message": err.Error(),
+			},
+		})
+		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"data": gin.H{
 			"model":   req.Model,
-			"pricing": dashboard.DefaultModelPricing[req.Model],
+			"pricing": dashboard.DefaultPricingTable().Current(time.Now())[req.Model],
 		},
 	})
 }
@@ -511,6 +629,7 @@ func (h *DashboardHandler) UpdatePricing(c *gin.Context) {
 // SessionSummary represents a session summary for dashboard
 type SessionSummary struct {
 	ID           string         `json:"id"`
+	Title        string         `json:"title,omitempty"`
 	AgentID      string         `json:"agent_id,omitempty"`
 	AgentName    string         `json:"agent_name,omitempty"`
 	Status       string         `json:"status"`
@@ -595,30 +714,7 @@ func (h *DashboardHandler) ListSessions(c *gin.Context) {
 			continue
 		}
 
-		// Token usage from session metadata
-		var tokenUsage TokenCount
-		if record.Metadata != nil {
-			if usage, ok := record.Metadata["token_usage"].(map[string]any); ok {
-				if input, ok := usage["input"].(float64); ok {
-					tokenUsage.Input = int64(input)
-				}
-				if output, ok := usage["output"].(float64); ok {
-					tokenUsage.Output = int64(output)
-				}
-			}
-		}
-		tokenUsage.Total = tokenUsage.Input + tokenUsage.Output
-
-		sessions = append(sessions, SessionSummary{
-			ID:           record.ID,
-			AgentID:      record.AgentID,
-			Status:       record.Status,
-			MessageCount: len(record.Messages),
-			TokenUsage:   tokenUsage,
-			CreatedAt:    record.CreatedAt,
-			UpdatedAt:    record.UpdatedAt,
-			Metadata:     record.Metadata,
-		})
+		sessions = append(sessions, h.summarizeSession(ctx, &record))
 	}
 
 	c.JSON(http.StatusOK, gin.H{
@@ -691,7 +787,18 @@ func (h *DashboardHandler) GetSession(c *gin.Context) {
 		})
 	}
 
-	// Token usage from session metadata
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": SessionDetail{
+			SessionSummary: h.summarizeSession(ctx, &record),
+			Messages:       messages,
+		},
+	})
+}
+
+// summarizeSession builds the dashboard summary of a stored session, reading
+// token usage from the session metadata
+func (h *DashboardHandler) summarizeSession(ctx context.Context, record *SessionRecord) SessionSummary {
 	var tokenUsage TokenCount
 	if record.Metadata != nil {
 		if usage, ok := record.Metadata["token_usage"].(map[string]any); ok {
@@ -705,20 +812,125 @@ func (h *DashboardHandler) GetSession(c *gin.Context) {
 	}
 	tokenUsage.Total = tokenUsage.Input + tokenUsage.Output
 
+	return SessionSummary{
+		ID:           record.ID,
+		Title:        fillSessionTitle(ctx, *h.store, record),
+		AgentID:      record.AgentID,
+		Status:       record.Status,
+		MessageCount: len(record.Messages),
+		TokenUsage:   tokenUsage,
+		CreatedAt:    record.CreatedAt,
+		UpdatedAt:    record.UpdatedAt,
+		Metadata:     record.Metadata,
+	}
+}
+
+// AgentDrillDown is the per-agent dashboard page: runtime status, metric time
+// series, tool mix, recent runs and the agent's active sessions
+type AgentDrillDown struct {
+	*dashboard.AgentDetail
+
+	Status         *types.AgentStatus `json:"status"`
+	Remote         bool               `json:"remote"`
+	ActiveSessions []SessionSummary   `json:"active_sessions"`
+}
+
+// GetAgent returns the drill-down view of a single running agent
+func (h *DashboardHandler) GetAgent(c *gin.Context) {
+	ctx := c.Request.Context()
+	agentID := c.Param("id")
+
+	var status *types.AgentStatus
+	var eb *events.EventBus
+	remote := false
+	if h.registry != nil && h.registry.VisibleTo(agentID, multitenancy.GetTenantIDOrDefault(ctx, "")) {
+		if ag := h.registry.Get(agentID); ag != nil {
+			status, eb = ag.Status(), ag.GetEventBus()
+		} else if ra := h.registry.GetRemoteAgent(agentID); ra != nil {
+			status, eb, remote = ra.Status(), ra.GetEventBus(), true
+		}
+	}
+	if status == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "not_found",
+				"message": "Agent not found",
+			},
+		})
+		return
+	}
+
+	opts := dashboard.AgentDetailOpts{
+		AgentID: agentID,
+		Period:  c.DefaultQuery("period", "24h"),
+		// Status reports provider/model; pricing is keyed by the model name
+		Model: status.Model[strings.LastIndex(status.Model, "/")+1:],
+	}
+	if limitStr := c.Query("trace_limit"); limitStr != "" {
+		if limit, err := strconv.Atoi(limitStr); err == nil && limit > 0 {
+			opts.TraceLimit = min(limit, 200)
+		}
+	}
+
+	detail, err := h.aggregator.GetAgentDetail(ctx, eb, opts)
+	if err != nil {
+		logging.Error(ctx, "dashboard.agent.get.error", map[string]any{
+			"agent_id": agentID,
+			"error":    err.Error(),
+		})
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "internal_error",
+				"message": err.Error(),
+			},
+		})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
-		"data": SessionDetail{
-			SessionSummary: SessionSummary{
-				ID:           record.ID,
-				AgentID:      record.AgentID,
-				Status:       record.Status,
-				MessageCount: len(record.Messages),
-				TokenUsage:   tokenUsage,
-				CreatedAt:    record.CreatedAt,
-				UpdatedAt:    record.UpdatedAt,
-				Metadata:     record.Metadata,
-			},
-			Messages: messages,
+		"data": AgentDrillDown{
+			AgentDetail:    detail,
+			Status:         status,
+			Remote:         remote,
+			ActiveSessions: h.activeSessions(ctx, agentID),
 		},
 	})
 }
+
+// activeSessions lists the stored sessions of an agent that are still active
+func (h *DashboardHandler) activeSessions(ctx context.Context, agentID string) []SessionSummary {
+	sessions := []SessionSummary{}
+	items, err := (*h.store).List(ctx, "sessions")
+	if err != nil {
+		return sessions
+	}
+	for _, item := range items {
+		var record SessionRecord
+		if err := store.DecodeValue(item, &record); err != nil {
+			continue
+		}
+		if record.AgentID == agentID && record.Status == "active" {
+			sessions = append(sessions, h.summarizeSession(ctx, &record))
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].UpdatedAt.After(sessions[j].UpdatedAt)
+	})
+	return sessions
+}
+
+// GetKeys returns usage and health status of every pooled provider API key.
+// Keys are masked; an empty list means no key pools are configured.
+func (h *DashboardHandler) GetKeys(c *gin.Context) {
+	keys := []provider.KeyUsage{}
+	if h.keys != nil {
+		keys = append(keys, h.keys.Usage()...)
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    keys,
+	})
+}
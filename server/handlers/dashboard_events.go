@@ -12,6 +12,7 @@ import (
 
 	"github.com/astercloud/aster/pkg/agent"
 	"github.com/astercloud/aster/pkg/logging"
+	"github.com/astercloud/aster/pkg/multitenancy"
 	"github.com/astercloud/aster/pkg/types"
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
@@ -41,6 +42,7 @@ type DashboardEventConnection struct {
 	Send          chan []byte
 	ctx           context.Context
 	cancel        context.CancelFunc
+	tenantID      string // only agents of this tenant are streamed; "" streams all
 	filters       *EventStreamFilters
 	subscriptions map[string]*agentSubscription // agentID -> subscription
 	subMu         sync.Mutex
@@ -91,6 +93,9 @@ func (h *DashboardEventHandler) onAgentRegistryChange(agentID string, ag *agent.
 	// Notify all connections about the change
 	for _, wsConn := range h.connections {
 		if registered && ag != nil {
+			if !h.registry.VisibleTo(agentID, wsConn.tenantID) {
+				continue
+			}
 			// Subscribe the connection to the new agent if filters match
 			wsConn.subscribeToAgent(ag)
 		} else {
@@ -114,6 +119,10 @@ func (h *DashboardEventHandler) onRemoteAgentRegistryChange(agentID string, ra *
 	// Notify all connections about the change
 	for _, wsConn := range h.connections {
 		if registered && ra != nil {
+			// Remote agents are not owned by any tenant
+			if wsConn.tenantID != "" {
+				continue
+			}
 			// Subscribe the connection to the new remote agent if filters match
 			logging.Info(context.Background(), "dashboard.subscribing_to_remote_agent", map[string]any{
 				"agent_id":      agentID,
@@ -140,6 +149,7 @@ func (h *DashboardEventHandler) HandleEventStream(c *gin.Context) {
 
 	// Create connection context
 	ctx, cancel := context.WithCancel(context.Background())
+	tenantID := multitenancy.GetTenantIDOrDefault(c.Request.Context(), "")
 
 	// Create connection object
 	wsConn := &DashboardEventConnection{
@@ -148,6 +158,7 @@ func (h *DashboardEventHandler) HandleEventStream(c *gin.Context) {
 		Send:          make(chan []byte, 256),
 		ctx:           ctx,
 		cancel:        cancel,
+		tenantID:      tenantID,
 		subscriptions: make(map[string]*agentSubscription),
 		handler:       h,
 		filters: &EventStreamFilters{
@@ -273,12 +284,14 @@ func (h *DashboardEventHandler) handleSubscribe(wsConn *DashboardEventConnection
 	// Subscribe to all existing agents (local and remote)
 	if h.registry != nil {
 		// Local agents
-		for _, ag := range h.registry.List() {
+		for _, ag := range h.registry.ListForTenant(wsConn.tenantID) {
 			wsConn.subscribeToAgent(ag)
 		}
-		// Remote agents
-		for _, ra := range h.registry.ListRemoteAgents() {
-			wsConn.subscribeToRemoteAgent(ra)
+		// Remote agents are not owned by any tenant
+		if wsConn.tenantID == "" {
+			for _, ra := range h.registry.ListRemoteAgents() {
+				wsConn.subscribeToRemoteAgent(ra)
+			}
 		}
 	}
 
@@ -0,0 +1,203 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/astercloud/aster/pkg/evals"
+	"github.com/astercloud/aster/pkg/experiments"
+	"github.com/gin-gonic/gin"
+)
+
+// defaultPassScore is the score at or above which a scored answer counts as
+// a success when the request does not set pass_score
+const defaultPassScore = 0.7
+
+// ExperimentHandler handles A/B experiment management and grading requests
+type ExperimentHandler struct {
+	manager *experiments.Manager
+}
+
+// NewExperimentHandler creates a new ExperimentHandler
+func NewExperimentHandler(manager *experiments.Manager) *ExperimentHandler {
+	return &ExperimentHandler{manager: manager}
+}
+
+// Create creates an experiment. Experiments start as drafts unless a status
+// is given; only running experiments assign new agents.
+func (h *ExperimentHandler) Create(c *gin.Context) {
+	var exp experiments.Experiment
+	if err := c.ShouldBindJSON(&exp); err != nil {
+		experimentError(c, http.StatusBadRequest, err)
+		return
+	}
+	if err := h.manager.Create(c.Request.Context(), &exp); err != nil {
+		experimentError(c, http.StatusBadRequest, err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    exp,
+	})
+}
+
+// List lists all experiments
+func (h *ExperimentHandler) List(c *gin.Context) {
+	list, err := h.manager.List(c.Request.Context())
+	if err != nil {
+		experimentError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    list,
+	})
+}
+
+// Get returns one experiment
+func (h *ExperimentHandler) Get(c *gin.Context) {
+	exp, err := h.manager.Get(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		experimentError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    exp,
+	})
+}
+
+// Update replaces an experiment's configuration. Changing the base template
+// or the variants bumps the experiment version.
+func (h *ExperimentHandler) Update(c *gin.Context) {
+	var exp experiments.Experiment
+	if err := c.ShouldBindJSON(&exp); err != nil {
+		experimentError(c, http.StatusBadRequest, err)
+		return
+	}
+	exp.ID = c.Param("id")
+	if err := h.manager.Update(c.Request.Context(), &exp); err != nil {
+		experimentError(c, http.StatusBadRequest, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    exp,
+	})
+}
+
+// Delete deletes an experiment. Recorded assignments, runs and grades are kept.
+func (h *ExperimentHandler) Delete(c *gin.Context) {
+	if err := h.manager.Delete(c.Request.Context(), c.Param("id")); err != nil {
+		experimentError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+	})
+}
+
+// Results compares the experiment's variants: eval success rate, completion
+// rate, cost and latency
+func (h *ExperimentHandler) Results(c *gin.Context) {
+	results, err := h.manager.Results(c.Request.Context(), c.Param("id"), nil)
+	if err != nil {
+		experimentError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"results": results,
+			"best":    results.Best(),
+		},
+	})
+}
+
+// Grade records an eval grade for an agent taking part in an experiment.
+//
+// Either pass a precomputed score (score, optionally passed), or an answer to
+// score with a built-in grader:
+//   - keyword_coverage: fraction of keywords found in answer
+//   - lexical_similarity: word overlap between answer and reference
+func (h *ExperimentHandler) Grade(c *gin.Context) {
+	var req struct {
+		AgentID   string   `json:"agent_id" binding:"required"`
+		Grader    string   `json:"grader" binding:"required"`
+		Score     *float64 `json:"score"`
+		Passed    *bool    `json:"passed"`
+		PassScore *float64 `json:"pass_score"`
+		Answer    string   `json:"answer"`
+		Reference string   `json:"reference"`
+		Keywords  []string `json:"keywords"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		experimentError(c, http.StatusBadRequest, err)
+		return
+	}
+	ctx := c.Request.Context()
+
+	passScore := defaultPassScore
+	if req.PassScore != nil {
+		passScore = *req.PassScore
+	}
+
+	var (
+		grade *experiments.Grade
+		err   error
+	)
+	if req.Score != nil {
+		passed := *req.Score >= passScore
+		if req.Passed != nil {
+			passed = *req.Passed
+		}
+		grade, err = h.manager.RecordGrade(ctx, experiments.Grade{
+			AgentID: req.AgentID,
+			Grader:  req.Grader,
+			Score:   *req.Score,
+			Passed:  passed,
+		})
+	} else {
+		var scorer evals.Scorer
+		switch req.Grader {
+		case "keyword_coverage":
+			scorer = evals.NewKeywordCoverageScorer(evals.KeywordCoverageConfig{Keywords: req.Keywords, CaseInsensitive: true})
+		case "lexical_similarity":
+			scorer = evals.NewLexicalSimilarityScorer(evals.LexicalSimilarityConfig{})
+		default:
+			experimentError(c, http.StatusBadRequest, errors.New("score is required for grader "+req.Grader))
+			return
+		}
+		grade, err = h.manager.Grade(ctx, req.AgentID, scorer, &evals.TextEvalInput{
+			Answer:    req.Answer,
+			Reference: req.Reference,
+		}, passScore)
+	}
+	if err != nil {
+		experimentError(c, http.StatusBadRequest, err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    grade,
+	})
+}
+
+// experimentError writes an error response; unknown experiments and agents
+// outside any experiment map to 404
+func experimentError(c *gin.Context, status int, err error) {
+	code := "bad_request"
+	switch {
+	case errors.Is(err, experiments.ErrNotFound):
+		status, code = http.StatusNotFound, "not_found"
+	case status >= http.StatusInternalServerError:
+		code = "internal_error"
+	}
+	c.JSON(status, gin.H{
+		"success": false,
+		"error": gin.H{
+			"code":    code,
+			"message": err.Error(),
+		},
+	})
+}
@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/astercloud/aster/pkg/artifact"
+	"github.com/astercloud/aster/pkg/identity"
+	"github.com/gin-gonic/gin"
+)
+
+// IdentityHandler exposes agent public keys and verifies signatures on
+// commits, webhooks, artifacts and exported reports
+type IdentityHandler struct {
+	registry  *identity.Registry
+	artifacts *artifact.Store
+}
+
+// NewIdentityHandler creates a new IdentityHandler. artifacts may be nil, in
+// which case signatures cannot be checked by artifact ID.
+func NewIdentityHandler(registry *identity.Registry, artifacts *artifact.Store) *IdentityHandler {
+	return &IdentityHandler{registry: registry, artifacts: artifacts}
+}
+
+// Get returns the public identity of an agent (or identity.ServerID)
+func (h *IdentityHandler) Get(c *gin.Context) {
+	id, err := h.registry.Get(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		if errors.Is(err, identity.ErrNotFound) {
+			identityError(c, http.StatusNotFound, "not_found", "Identity not found")
+			return
+		}
+		identityError(c, http.StatusInternalServerError, "internal_error", err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    id,
+	})
+}
+
+// VerifyRequest is the body of a verification request. The signature is given
+// either as JSON or as the header value sent with webhooks and reports. The
+// signed content is optional: without it only the signature over the digest
+// is checked.
+type VerifyRequest struct {
+	Signature       *identity.Signature `json:"signature,omitempty"`
+	SignatureHeader string              `json:"signature_header,omitempty"`
+	// Content is the signed content as text, e.g. a commit ID or a webhook body
+	Content *string `json:"content,omitempty"`
+	// ContentBase64 is the signed content for binary payloads
+	ContentBase64 string `json:"content_base64,omitempty"`
+	// ArtifactID verifies a stored artifact against its own signature, or
+	// against Signature when one is given
+	ArtifactID string `json:"artifact_id,omitempty"`
+}
+
+// VerifyResult reports the outcome of a verification
+type VerifyResult struct {
+	Valid bool `json:"valid"`
+	// ContentVerified is true when the content digest was checked as well
+	ContentVerified bool                `json:"content_verified"`
+	Signature       *identity.Signature `json:"signature,omitempty"`
+	Signer          *identity.Identity  `json:"signer,omitempty"`
+	Reason          string              `json:"reason,omitempty"`
+}
+
+// Verify checks a signature against the signer's registered public key.
+// Signatures that do not verify are reported with valid=false rather than an
+// error status.
+func (h *IdentityHandler) Verify(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var req VerifyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		identityError(c, http.StatusBadRequest, "bad_request", err.Error())
+		return
+	}
+
+	sig := req.Signature
+	if sig == nil && req.SignatureHeader != "" {
+		parsed, err := identity.ParseHeader(req.SignatureHeader)
+		if err != nil {
+			identityError(c, http.StatusBadRequest, "bad_request", err.Error())
+			return
+		}
+		sig = parsed
+	}
+
+	var digest string
+	switch {
+	case req.ArtifactID != "":
+		if h.artifacts == nil {
+			identityError(c, http.StatusBadRequest, "bad_request", "Artifact storage is not enabled")
+			return
+		}
+		a, sum, err := h.artifactDigest(c, req.ArtifactID)
+		if err != nil {
+			if errors.Is(err, artifact.ErrNotFound) {
+				identityError(c, http.StatusNotFound, "not_found", "Artifact not found")
+				return
+			}
+			identityError(c, http.StatusInternalServerError, "internal_error", err.Error())
+			return
+		}
+		if sig == nil {
+			sig = a.Signature
+		}
+		digest = sum
+	case req.Content != nil:
+		digest = identity.Digest([]byte(*req.Content))
+	case req.ContentBase64 != "":
+		content, err := base64.StdEncoding.DecodeString(req.ContentBase64)
+		if err != nil {
+			identityError(c, http.StatusBadRequest, "bad_request", "content_base64 is not valid base64")
+			return
+		}
+		digest = identity.Digest(content)
+	}
+	if sig == nil {
+		identityError(c, http.StatusBadRequest, "bad_request", "signature or signature_header is required")
+		return
+	}
+
+	result := VerifyResult{Signature: sig, ContentVerified: digest != ""}
+	signer, err := h.registry.Get(ctx, sig.Signer)
+	switch {
+	case errors.Is(err, identity.ErrNotFound):
+		result.Reason = "unknown signer " + sig.Signer
+	case err != nil:
+		identityError(c, http.StatusInternalServerError, "internal_error", err.Error())
+		return
+	case digest != "" && digest != sig.Digest:
+		result.Signer = signer
+		result.Reason = "content digest does not match the signature"
+	default:
+		result.Signer = signer
+		if err := identity.VerifyDigest(signer, sig); err != nil {
+			result.Reason = err.Error()
+		} else {
+			result.Valid = true
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    result,
+	})
+}
+
+// artifactDigest recomputes the digest of the stored artifact content rather
+// than trusting the recorded SHA256
+func (h *IdentityHandler) artifactDigest(c *gin.Context, id string) (*artifact.Artifact, string, error) {
+	rc, a, err := h.artifacts.Open(c.Request.Context(), id)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rc.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, rc); err != nil {
+		return nil, "", err
+	}
+	return a, hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func identityError(c *gin.Context, status int, code, message string) {
+	c.JSON(status, gin.H{
+		"success": false,
+		"error": gin.H{
+			"code":    code,
+			"message": message,
+		},
+	})
+}
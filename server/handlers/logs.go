@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/astercloud/aster/pkg/logging"
+	"github.com/gin-gonic/gin"
+)
+
+// maxLogTail caps the number of recent records returned before streaming
+const maxLogTail = 1000
+
+// LogHandler serves recent and live server logs, so the dashboard and
+// desktop apps can show them without shell access
+type LogHandler struct {
+	logs *logging.Broadcaster
+}
+
+// NewLogHandler creates a new LogHandler
+func NewLogHandler(logs *logging.Broadcaster) *LogHandler {
+	return &LogHandler{logs: logs}
+}
+
+// Recent returns the most recent log records.
+//
+// Query parameters:
+//   - level: minimum level (debug, info, warn, error)
+//   - component: comma-separated component names, case-insensitive
+//   - tail: number of records, default 100
+func (h *LogHandler) Recent(c *gin.Context) {
+	filter, tail, ok := parseLogQuery(c)
+	if !ok {
+		return
+	}
+	records := h.logs.Recent(filter, tail)
+	if records == nil {
+		records = []*logging.LogRecord{}
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": records})
+}
+
+// Stream sends log records as Server-Sent Events: first up to tail recent
+// records, then new records as they are written. Each record is a "log"
+// event; records dropped because the client reads too slowly are skipped.
+// Takes the same query parameters as Recent, with tail defaulting to 100.
+func (h *LogHandler) Stream(c *gin.Context) {
+	filter, tail, ok := parseLogQuery(c)
+	if !ok {
+		return
+	}
+	flusher, ok := c.Writer.(http.Flusher)
+	if !ok {
+		logError(c, http.StatusInternalServerError, "streaming_not_supported", "Streaming not supported")
+		return
+	}
+
+	// Subscribe before reading history so no record falls in between
+	sub, cancel := h.logs.Subscribe(filter, 0)
+	defer cancel()
+	recent := h.logs.Recent(filter, tail)
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	for _, rec := range recent {
+		c.SSEvent("log", rec)
+	}
+	_, _ = io.WriteString(c.Writer, ": connected\n\n")
+	flusher.Flush()
+
+	ticker := time.NewTicker(collabHeartbeat)
+	defer ticker.Stop()
+	ctx := c.Request.Context()
+	for {
+		select {
+		case rec, ok := <-sub:
+			if !ok {
+				return
+			}
+			// Already sent as part of the history
+			if slices.Contains(recent, rec) {
+				continue
+			}
+			c.SSEvent("log", rec)
+			flusher.Flush()
+		case <-ticker.C:
+			_, _ = io.WriteString(c.Writer, ": ping\n\n")
+			flusher.Flush()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// parseLogQuery reads the level, component and tail query parameters
+func parseLogQuery(c *gin.Context) (logging.LogFilter, int, bool) {
+	var filter logging.LogFilter
+	if raw := c.Query("level"); raw != "" {
+		level, err := logging.ParseLevel(raw)
+		if err != nil {
+			logError(c, http.StatusBadRequest, "bad_request", err.Error())
+			return filter, 0, false
+		}
+		filter.Level = level
+	}
+	for _, name := range strings.Split(c.Query("component"), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			filter.Components = append(filter.Components, name)
+		}
+	}
+
+	tail := 100
+	if raw := c.Query("tail"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 {
+			logError(c, http.StatusBadRequest, "bad_request", "tail must be a non-negative integer")
+			return filter, 0, false
+		}
+		tail = min(n, maxLogTail)
+	}
+	return filter, tail, true
+}
+
+func logError(c *gin.Context, status int, code, message string) {
+	c.JSON(status, gin.H{
+		"success": false,
+		"error": gin.H{
+			"code":    code,
+			"message": message,
+		},
+	})
+}
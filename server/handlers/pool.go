@@ -1,10 +1,12 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/astercloud/aster/pkg/agent"
 	"github.com/astercloud/aster/pkg/core"
+	"github.com/astercloud/aster/pkg/lock"
 	"github.com/astercloud/aster/pkg/logging"
 	"github.com/astercloud/aster/pkg/store"
 	"github.com/astercloud/aster/pkg/types"
@@ -20,10 +22,17 @@ type PoolHandler struct {
 
 // NewPoolHandler creates a new PoolHandler
 func NewPoolHandler(st store.Store, deps *agent.Dependencies) *PoolHandler {
+	return NewPoolHandlerWithLocker(st, deps, nil)
+}
+
+// NewPoolHandlerWithLocker creates a PoolHandler whose pool holds a lock on
+// every agent it runs, so another server instance cannot resume the same agent
+func NewPoolHandlerWithLocker(st store.Store, deps *agent.Dependencies, locker lock.Locker) *PoolHandler {
 	// Create pool with dependencies
 	pool := core.NewPool(&core.PoolOptions{
 		Dependencies: deps,
 		MaxAgents:    100,
+		Locker:       locker,
 	})
 
 	return &PoolHandler{
@@ -71,6 +80,10 @@ func (h *PoolHandler) CreateAgent(c *gin.Context) {
 
 	// Create agent in pool
 	ag, err := h.pool.Create(ctx, config)
+	if errors.Is(err, core.ErrAgentLocked) {
+		respondAgentLocked(c, err)
+		return
+	}
 	if err != nil {
 		logging.Error(ctx, "pool.create.error", map[string]any{
 			"error": err.Error(),
@@ -183,6 +196,10 @@ func (h *PoolHandler) ResumeAgent(c *gin.Context) {
 
 	// Resume agent
 	ag, err := h.pool.Resume(ctx, id, config)
+	if errors.Is(err, core.ErrAgentLocked) {
+		respondAgentLocked(c, err)
+		return
+	}
 	if err != nil {
 		logging.Error(ctx, "pool.resume.error", map[string]any{
 			"agent_id": id,
@@ -248,3 +265,14 @@ func (h *PoolHandler) GetStats(c *gin.Context) {
 		},
 	})
 }
+
+// respondAgentLocked reports that another server instance is running the agent
+func respondAgentLocked(c *gin.Context, err error) {
+	c.JSON(http.StatusConflict, gin.H{
+		"success": false,
+		"error": gin.H{
+			"code":    "agent_locked",
+			"message": err.Error(),
+		},
+	})
+}
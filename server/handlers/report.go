@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/astercloud/aster/pkg/identity"
+	"github.com/astercloud/aster/pkg/multitenancy"
+	"github.com/astercloud/aster/pkg/usage"
+	"github.com/gin-gonic/gin"
+)
+
+// ReportHandler handles usage and cost report requests
+type ReportHandler struct {
+	ledger *usage.Ledger
+	signer *identity.Signer
+}
+
+// NewReportHandler creates a new ReportHandler
+func NewReportHandler(ledger *usage.Ledger) *ReportHandler {
+	return &ReportHandler{ledger: ledger}
+}
+
+// SetSigner signs exported reports. The signature over the response body is
+// sent in the identity.HeaderSignature header.
+func (h *ReportHandler) SetSigner(signer *identity.Signer) {
+	h.signer = signer
+}
+
+// Usage generates the monthly usage and cost report.
+//
+// Query parameters:
+//   - month: YYYY-MM, defaults to the current month (UTC)
+//   - group_by: tenant (default), agent or model
+//   - format: json (default) or csv
+//   - tenant_id: restrict to one tenant; ignored for tenant-scoped requests,
+//     which always see only their own tenant
+func (h *ReportHandler) Usage(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	month := time.Now().UTC()
+	if m := c.Query("month"); m != "" {
+		parsed, err := usage.ParseMonth(m)
+		if err != nil {
+			reportError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		month = parsed
+	}
+
+	tenantID := c.Query("tenant_id")
+	if scoped := multitenancy.GetTenantIDOrDefault(ctx, ""); scoped != "" {
+		tenantID = scoped
+	}
+
+	format := usage.Format(c.DefaultQuery("format", string(usage.FormatJSON)))
+	if format != usage.FormatJSON && format != usage.FormatCSV {
+		reportError(c, http.StatusBadRequest, fmt.Sprintf("unsupported format %q, want json or csv", format))
+		return
+	}
+
+	groupBy := usage.GroupBy(c.Query("group_by"))
+	if !groupBy.Valid() {
+		reportError(c, http.StatusBadRequest, fmt.Sprintf("invalid group_by %q, want tenant, agent or model", groupBy))
+		return
+	}
+
+	report, err := usage.Generate(ctx, h.ledger, usage.ReportOptions{
+		Month:    month,
+		GroupBy:  groupBy,
+		TenantID: tenantID,
+	})
+	if err != nil {
+		reportError(c, http.StatusInternalServerError, "Failed to generate report: "+err.Error())
+		return
+	}
+
+	if format == usage.FormatCSV {
+		var buf bytes.Buffer
+		if err := report.WriteCSV(&buf); err != nil {
+			reportError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=usage-%s.csv", report.Month))
+		h.sign(c, buf.Bytes())
+		c.Data(http.StatusOK, "text/csv; charset=utf-8", buf.Bytes())
+		return
+	}
+
+	if h.signer == nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data":    report,
+		})
+		return
+	}
+	// Marshal the body here so the signature covers the exact bytes sent
+	body, err := json.Marshal(gin.H{
+		"success": true,
+		"data":    report,
+	})
+	if err != nil {
+		reportError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.sign(c, body)
+	c.Data(http.StatusOK, "application/json; charset=utf-8", body)
+}
+
+func (h *ReportHandler) sign(c *gin.Context, body []byte) {
+	if h.signer != nil {
+		c.Header(identity.HeaderSignature, h.signer.Sign(identity.KindReport, body).EncodeHeader())
+	}
+}
+
+func reportError(c *gin.Context, status int, message string) {
+	code := "bad_request"
+	if status >= http.StatusInternalServerError {
+		code = "internal_error"
+	}
+	c.JSON(status, gin.H{
+		"success": false,
+		"error": gin.H{
+			"code":    code,
+			"message": message,
+		},
+	})
+}
@@ -15,6 +15,7 @@ type RuntimeAgentRegistry struct {
 	mu              sync.RWMutex
 	agents          map[string]*agent.Agent
 	remoteAgents    map[string]*agent.RemoteAgent // 远程 Agent 注册表
+	tenants         map[string]string             // agentID -> tenantID
 	listeners       []RegistryEventListener
 	remoteListeners []RemoteAgentEventListener
 }
@@ -23,17 +24,26 @@ func NewRuntimeAgentRegistry() *RuntimeAgentRegistry {
 	return &RuntimeAgentRegistry{
 		agents:          make(map[string]*agent.Agent),
 		remoteAgents:    make(map[string]*agent.RemoteAgent),
+		tenants:         make(map[string]string),
 		listeners:       make([]RegistryEventListener, 0),
 		remoteListeners: make([]RemoteAgentEventListener, 0),
 	}
 }
 
 func (r *RuntimeAgentRegistry) Register(ag *agent.Agent) {
+	r.RegisterForTenant("", ag)
+}
+
+// RegisterForTenant 注册归属于指定租户的 Agent
+func (r *RuntimeAgentRegistry) RegisterForTenant(tenantID string, ag *agent.Agent) {
 	if ag == nil {
 		return
 	}
 	r.mu.Lock()
 	r.agents[ag.ID()] = ag
+	if tenantID != "" {
+		r.tenants[ag.ID()] = tenantID
+	}
 	listeners := make([]RegistryEventListener, len(r.listeners))
 	copy(listeners, r.listeners)
 	r.mu.Unlock()
@@ -51,6 +61,7 @@ func (r *RuntimeAgentRegistry) Unregister(agentID string) {
 	r.mu.Lock()
 	ag := r.agents[agentID]
 	delete(r.agents, agentID)
+	delete(r.tenants, agentID)
 	listeners := make([]RegistryEventListener, len(r.listeners))
 	copy(listeners, r.listeners)
 	r.mu.Unlock()
@@ -85,6 +96,61 @@ func (r *RuntimeAgentRegistry) Count() int {
 	return len(r.agents)
 }
 
+// TenantOf returns the tenant that owns agentID ("" for shared agents)
+func (r *RuntimeAgentRegistry) TenantOf(agentID string) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.tenants[agentID]
+}
+
+// ListForTenant returns agents owned by tenantID. An empty tenantID returns all agents.
+func (r *RuntimeAgentRegistry) ListForTenant(tenantID string) []*agent.Agent {
+	if tenantID == "" {
+		return r.List()
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	agents := make([]*agent.Agent, 0)
+	for id, ag := range r.agents {
+		if r.tenants[id] == tenantID {
+			agents = append(agents, ag)
+		}
+	}
+	return agents
+}
+
+// CountForTenant returns the number of agents owned by tenantID
+func (r *RuntimeAgentRegistry) CountForTenant(tenantID string) int {
+	return len(r.ListForTenant(tenantID))
+}
+
+// VisibleTo reports whether tenantID may see agentID. Every agent is visible
+// to an empty tenantID; a tenant sees only the local agents it owns, never
+// shared or remote ones.
+func (r *RuntimeAgentRegistry) VisibleTo(agentID, tenantID string) bool {
+	if tenantID == "" {
+		return true
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.tenants[agentID] == tenantID
+}
+
+// GetEventBusesForTenant returns the EventBuses of agents owned by tenantID.
+// An empty tenantID returns all EventBuses, including remote agents.
+func (r *RuntimeAgentRegistry) GetEventBusesForTenant(tenantID string) []*events.EventBus {
+	if tenantID == "" {
+		return r.GetEventBuses()
+	}
+	buses := make([]*events.EventBus, 0)
+	for _, ag := range r.ListForTenant(tenantID) {
+		if eb := ag.GetEventBus(); eb != nil {
+			buses = append(buses, eb)
+		}
+	}
+	return buses
+}
+
 // AddListener adds a listener that will be notified when agents are registered/unregistered
 func (r *RuntimeAgentRegistry) AddListener(listener RegistryEventListener) {
 	r.mu.Lock()
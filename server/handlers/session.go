@@ -1,10 +1,16 @@
 package handlers
 
 import (
+	"context"
 	"errors"
+	"maps"
 	"net/http"
 	"time"
 
+	"github.com/astercloud/aster/pkg/agent"
+	"github.com/astercloud/aster/pkg/agenterr"
+	"github.com/astercloud/aster/pkg/config"
+	"github.com/astercloud/aster/pkg/lock"
 	"github.com/astercloud/aster/pkg/logging"
 	"github.com/astercloud/aster/pkg/store"
 	"github.com/astercloud/aster/pkg/types"
@@ -23,12 +29,15 @@ type CheckpointRecord struct {
 
 // SessionHandler handles session-related requests
 type SessionHandler struct {
-	store *store.Store
+	store  *store.Store
+	deps   *agent.Dependencies
+	locker lock.Locker
 }
 
-// NewSessionHandler creates a new SessionHandler
-func NewSessionHandler(st store.Store) *SessionHandler {
-	return &SessionHandler{store: &st}
+// NewSessionHandler creates a new SessionHandler. deps is used to create the
+// model provider for summaries and may be nil when summaries are not needed.
+func NewSessionHandler(st store.Store, deps *agent.Dependencies) *SessionHandler {
+	return &SessionHandler{store: &st, deps: deps}
 }
 
 // Create creates a new session
@@ -117,6 +126,7 @@ func (h *SessionHandler) List(c *gin.Context) {
 			continue
 		}
 
+		fillSessionTitle(ctx, *h.store, &session)
 		sessions = append(sessions, &session)
 	}
 
@@ -336,11 +346,42 @@ func (h *SessionHandler) GetCheckpoints(c *gin.Context) {
 	})
 }
 
-// Resume resumes a session
+// SetLocker makes Resume hold a lock on the session, so two server
+// instances sharing a store cannot resume the same session concurrently
+func (h *SessionHandler) SetLocker(locker lock.Locker) {
+	h.locker = locker
+}
+
+// Resume resumes a session. With ?summarize=true the session summary is
+// regenerated first so the resumed agent can be seeded with it.
 func (h *SessionHandler) Resume(c *gin.Context) {
 	ctx := c.Request.Context()
 	id := c.Param("id")
 
+	if h.locker != nil {
+		lease, err := h.locker.TryAcquire(ctx, "session:"+id, lock.DefaultTTL)
+		if err != nil {
+			status, code := http.StatusInternalServerError, "internal_error"
+			if errors.Is(err, lock.ErrNotAcquired) {
+				status, code = http.StatusConflict, "session_locked"
+			}
+			c.JSON(status, gin.H{
+				"success": false,
+				"error": gin.H{
+					"code":    code,
+					"message": "Failed to lock session: " + err.Error(),
+				},
+			})
+			return
+		}
+		// Summarizing can outlast the lease, keep it alive until the handler returns
+		stop := lock.KeepAlive(lease, lock.DefaultTTL, nil)
+		defer func() {
+			stop()
+			_ = lease.Release(context.WithoutCancel(ctx))
+		}()
+	}
+
 	var session SessionRecord
 	if err := (*h.store).Get(ctx, "sessions", id, &session); err != nil {
 		if errors.Is(err, store.ErrNotFound) {
@@ -363,6 +404,11 @@ func (h *SessionHandler) Resume(c *gin.Context) {
 		return
 	}
 
+	// The summary seeds the resumed agent (AgentConfig.SeedSummary); refresh it on request
+	if c.Query("summarize") == "true" && !h.refreshSummary(c, &session, summaryRequest{}) {
+		return
+	}
+
 	session.Status = "active"
 	session.UpdatedAt = time.Now()
 
@@ -387,6 +433,221 @@ func (h *SessionHandler) Resume(c *gin.Context) {
 	})
 }
 
+// summaryRequest is the optional body of the summary, fork and resume endpoints
+type summaryRequest struct {
+	MaxMessages int                `json:"max_messages"`
+	Focus       string             `json:"focus"`
+	ModelConfig *types.ModelConfig `json:"model_config"`
+}
+
+// Summarize generates a structured summary of the session (goals, decisions,
+// files touched, open TODOs) and stores it on the session record
+func (h *SessionHandler) Summarize(c *gin.Context) {
+	id := c.Param("id")
+
+	var req summaryRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error": gin.H{
+					"code":    "bad_request",
+					"message": err.Error(),
+				},
+			})
+			return
+		}
+	}
+
+	session, ok := h.loadSession(c, id)
+	if !ok {
+		return
+	}
+	if !h.refreshSummary(c, session, req) {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    session.Summary,
+	})
+}
+
+// Fork creates a new session for the same agent that starts from a summary
+// of this one. A summary is generated first when the session has none.
+func (h *SessionHandler) Fork(c *gin.Context) {
+	ctx := c.Request.Context()
+	id := c.Param("id")
+
+	var req summaryRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error": gin.H{
+					"code":    "bad_request",
+					"message": err.Error(),
+				},
+			})
+			return
+		}
+	}
+
+	session, ok := h.loadSession(c, id)
+	if !ok {
+		return
+	}
+	if session.Summary == nil && !h.refreshSummary(c, session, req) {
+		return
+	}
+
+	now := time.Now()
+	fork := &SessionRecord{
+		ID:        uuid.New().String(),
+		AgentID:   session.AgentID,
+		Status:    "active",
+		Messages:  []types.Message{},
+		Context:   maps.Clone(session.Context),
+		CreatedAt: now,
+		UpdatedAt: now,
+		Metadata:  map[string]any{"forked_from": session.ID},
+		Summary:   session.Summary,
+	}
+	if err := (*h.store).Set(ctx, "sessions", fork.ID, fork); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "internal_error",
+				"message": "Failed to fork session: " + err.Error(),
+			},
+		})
+		return
+	}
+
+	logging.Info(ctx, "session.forked", map[string]any{
+		"session_id":  fork.ID,
+		"forked_from": session.ID,
+	})
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    fork,
+	})
+}
+
+// loadSession loads a session record, writing the error response on failure
+func (h *SessionHandler) loadSession(c *gin.Context, id string) (*SessionRecord, bool) {
+	var session SessionRecord
+	if err := (*h.store).Get(c.Request.Context(), "sessions", id, &session); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"success": false,
+				"error": gin.H{
+					"code":    "not_found",
+					"message": "Session not found",
+				},
+			})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "internal_error",
+				"message": "Failed to get session: " + err.Error(),
+			},
+		})
+		return nil, false
+	}
+	return &session, true
+}
+
+// refreshSummary generates a new summary for the session and persists it,
+// writing the error response on failure
+func (h *SessionHandler) refreshSummary(c *gin.Context, session *SessionRecord, req summaryRequest) bool {
+	ctx := c.Request.Context()
+	if h.deps == nil || h.deps.ProviderFactory == nil {
+		c.JSON(http.StatusNotImplemented, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "not_implemented",
+				"message": "Session summaries are not configured on this server",
+			},
+		})
+		return false
+	}
+
+	messages := session.Messages
+	if len(messages) == 0 && session.AgentID != "" && h.deps.Store != nil {
+		if stored, err := h.deps.Store.LoadMessages(ctx, session.AgentID); err == nil {
+			messages = stored
+		}
+	}
+	if len(messages) == 0 {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "empty_session",
+				"message": "Session has no messages to summarize",
+			},
+		})
+		return false
+	}
+
+	summary, err := agent.SummarizeStored(ctx, h.deps, h.summaryModel(ctx, session, req.ModelConfig), messages, agent.SummarizeOptions{
+		MaxMessages: req.MaxMessages,
+		Focus:       req.Focus,
+	})
+	if err != nil {
+		agentErr := agenterr.From(err)
+		logging.Error(ctx, "session.summary.failed", map[string]any{
+			"session_id": session.ID,
+			"error":      err.Error(),
+			"code":       agentErr.Code,
+		})
+		c.JSON(agentErr.Code.HTTPStatus(), gin.H{
+			"success": false,
+			"error":   agentErr,
+		})
+		return false
+	}
+
+	session.Summary = summary
+	session.UpdatedAt = time.Now()
+	if err := (*h.store).Set(ctx, "sessions", session.ID, session); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "internal_error",
+				"message": "Failed to save session summary: " + err.Error(),
+			},
+		})
+		return false
+	}
+
+	logging.Info(ctx, "session.summarized", map[string]any{
+		"session_id":    session.ID,
+		"message_count": summary.MessageCount,
+		"model":         summary.Model,
+	})
+	return true
+}
+
+// summaryModel picks the model used for a summary: the request override,
+// otherwise the model of the session's agent
+func (h *SessionHandler) summaryModel(ctx context.Context, session *SessionRecord, override *types.ModelConfig) *types.ModelConfig {
+	model := override
+	if model == nil && session.AgentID != "" {
+		var agentRecord AgentRecord
+		if err := (*h.store).Get(ctx, "agents", session.AgentID, &agentRecord); err == nil && agentRecord.Config != nil {
+			model = agentRecord.Config.ModelConfig
+		}
+	}
+	if model != nil && model.APIKey == "" {
+		model.APIKey = config.ProviderAPIKey(model.Provider)
+	}
+	return model
+}
+
 // GetStats retrieves session statistics
 func (h *SessionHandler) GetStats(c *gin.Context) {
 	id := c.Param("id")
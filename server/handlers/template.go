@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/astercloud/aster/pkg/agent"
+	"github.com/astercloud/aster/pkg/types"
+	"github.com/gin-gonic/gin"
+)
+
+// TemplateHandler handles agent template listing and updates
+type TemplateHandler struct {
+	registry *agent.TemplateRegistry
+	// dir persists updated templates; empty keeps updates in memory only
+	dir string
+}
+
+// NewTemplateHandler creates a new TemplateHandler
+func NewTemplateHandler(registry *agent.TemplateRegistry, dir string) *TemplateHandler {
+	return &TemplateHandler{registry: registry, dir: dir}
+}
+
+// templateInfo is a template together with its registered versions and the
+// file it was loaded from
+type templateInfo struct {
+	*types.AgentTemplateDefinition
+	Versions []string `json:"versions,omitempty"`
+	Source   string   `json:"source,omitempty"`
+}
+
+func (h *TemplateHandler) info(t *types.AgentTemplateDefinition) templateInfo {
+	return templateInfo{
+		AgentTemplateDefinition: t,
+		Versions:                h.registry.Versions(t.ID),
+		Source:                  h.registry.Source(t.ID),
+	}
+}
+
+// List lists the current version of every registered template
+func (h *TemplateHandler) List(c *gin.Context) {
+	templates := h.registry.List()
+	list := make([]templateInfo, 0, len(templates))
+	for _, t := range templates {
+		list = append(list, h.info(t))
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    list,
+	})
+}
+
+// Get returns one template. The id may pin a version as "id@version".
+func (h *TemplateHandler) Get(c *gin.Context) {
+	t, err := h.registry.Get(c.Param("id"))
+	if err != nil {
+		templateError(c, http.StatusNotFound, "not_found", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    h.info(t),
+	})
+}
+
+// Update creates or replaces a template. New agents use it immediately;
+// running agents keep the template they were created with.
+func (h *TemplateHandler) Update(c *gin.Context) {
+	var t types.AgentTemplateDefinition
+	if err := c.ShouldBindJSON(&t); err != nil {
+		templateError(c, http.StatusBadRequest, "bad_request", err)
+		return
+	}
+	if t.ID != "" && t.ID != c.Param("id") {
+		templateError(c, http.StatusBadRequest, "bad_request", errors.New("template id does not match path"))
+		return
+	}
+	t.ID = c.Param("id")
+	if err := agent.ValidateTemplate(&t); err != nil {
+		templateError(c, http.StatusBadRequest, "bad_request", err)
+		return
+	}
+
+	if h.dir != "" {
+		if err := h.registry.Save(h.dir, &t); err != nil {
+			templateError(c, http.StatusInternalServerError, "internal_error", err)
+			return
+		}
+	} else {
+		h.registry.Register(&t)
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    h.info(&t),
+	})
+}
+
+func templateError(c *gin.Context, status int, code string, err error) {
+	c.JSON(status, gin.H{
+		"success": false,
+		"error": gin.H{
+			"code":    code,
+			"message": err.Error(),
+		},
+	})
+}
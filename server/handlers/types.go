@@ -1,8 +1,10 @@
 package handlers
 
 import (
+	"context"
 	"time"
 
+	"github.com/astercloud/aster/pkg/store"
 	"github.com/astercloud/aster/pkg/types"
 )
 
@@ -27,6 +29,36 @@ type SessionRecord struct {
 	UpdatedAt   time.Time       `json:"updated_at"`
 	CompletedAt *time.Time      `json:"completed_at,omitempty"`
 	Metadata    map[string]any  `json:"metadata,omitempty"`
+	// Summary is the latest structured summary, used to seed resumed or forked sessions
+	Summary *types.ConversationSummary `json:"summary,omitempty"`
+}
+
+// sessionTitleKey is the metadata key holding the session title
+const sessionTitleKey = "title"
+
+// fillSessionTitle returns the session title and records it in the session
+// metadata. Sessions without a title of their own fall back to the title
+// generated for their agent (AgentConfig.AutoTitle).
+func fillSessionTitle(ctx context.Context, st store.Store, session *SessionRecord) string {
+	if title, _ := session.Metadata[sessionTitleKey].(string); title != "" {
+		return title
+	}
+	if session.AgentID == "" {
+		return ""
+	}
+	info, err := st.LoadInfo(ctx, session.AgentID)
+	if err != nil {
+		return ""
+	}
+	title, _ := info.Metadata[sessionTitleKey].(string)
+	if title == "" {
+		return ""
+	}
+	if session.Metadata == nil {
+		session.Metadata = make(map[string]any)
+	}
+	session.Metadata[sessionTitleKey] = title
+	return title
 }
 
 // WorkflowRecord Workflow 持久化记录
@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/astercloud/aster/pkg/update"
+	"github.com/gin-gonic/gin"
+)
+
+// UpdateHandler hosts the desktop release manifest and installer files
+type UpdateHandler struct {
+	dir string
+
+	mu       sync.Mutex
+	manifest *update.Manifest
+	modTime  time.Time
+}
+
+// NewUpdateHandler creates a new UpdateHandler serving releases from dir
+func NewUpdateHandler(dir string) *UpdateHandler {
+	return &UpdateHandler{dir: dir}
+}
+
+// load returns the manifest, re-reading it when the file changed
+func (h *UpdateHandler) load() (*update.Manifest, error) {
+	path := filepath.Join(h.dir, update.ManifestFile)
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.manifest != nil && info.ModTime().Equal(h.modTime) {
+		return h.manifest, nil
+	}
+	m, err := update.LoadManifest(path)
+	if err != nil {
+		return nil, err
+	}
+	h.manifest, h.modTime = m, info.ModTime()
+	return m, nil
+}
+
+// Manifest returns the release manifest as published, for clients that pick
+// a release themselves
+func (h *UpdateHandler) Manifest(c *gin.Context) {
+	m, ok := h.loadOrFail(c)
+	if !ok {
+		return
+	}
+	c.Header("Cache-Control", "no-cache")
+	c.JSON(http.StatusOK, m)
+}
+
+// Check returns the release a given install should update to, or null data
+// when it is up to date. Query parameters: channel, platform, version and
+// install_id (used for staged rollouts).
+func (h *UpdateHandler) Check(c *gin.Context) {
+	channel, err := update.ParseChannel(c.Query("channel"))
+	if err != nil {
+		updateError(c, http.StatusBadRequest, "bad_request", err.Error())
+		return
+	}
+	q := update.Query{
+		Channel:   channel,
+		Platform:  c.Query("platform"),
+		Version:   c.Query("version"),
+		InstallID: c.Query("install_id"),
+	}
+	if q.Platform == "" || !update.ValidVersion(q.Version) {
+		updateError(c, http.StatusBadRequest, "bad_request", "platform and a valid version are required")
+		return
+	}
+
+	m, ok := h.loadOrFail(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    m.Check(q),
+	})
+}
+
+// Asset serves an installer file from the release directory
+func (h *UpdateHandler) Asset(c *gin.Context) {
+	c.FileFromFS(c.Param("name"), http.Dir(h.dir))
+}
+
+func (h *UpdateHandler) loadOrFail(c *gin.Context) (*update.Manifest, bool) {
+	m, err := h.load()
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			updateError(c, http.StatusNotFound, "not_found", "No release manifest published")
+			return nil, false
+		}
+		updateError(c, http.StatusInternalServerError, "internal_error", err.Error())
+		return nil, false
+	}
+	return m, true
+}
+
+func updateError(c *gin.Context, status int, code, message string) {
+	c.JSON(status, gin.H{
+		"success": false,
+		"error": gin.H{
+			"code":    code,
+			"message": message,
+		},
+	})
+}
@@ -0,0 +1,308 @@
+package handlers
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/astercloud/aster/pkg/agent"
+	"github.com/astercloud/aster/pkg/logging"
+	"github.com/astercloud/aster/pkg/requestctx"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+// WarmPoolConfig 预热 Agent 池配置
+type WarmPoolConfig struct {
+	// Templates 需要预热的模板 ID
+	Templates []string
+	// SizePerTemplate 每个模板保持的未使用空闲 Agent 数量；归还的 Agent 另外最多保留同样数量
+	SizePerTemplate int
+	// MaxUses 单个 Agent 最多被复用的次数，超过后关闭并重建（0 表示不限制）
+	MaxUses int
+	// MaxIdle 空闲 Agent 的最长保留时间，超时后重建以避免连接过期（0 表示不限制）
+	MaxIdle time.Duration
+}
+
+// WarmPoolStats 预热池统计
+type WarmPoolStats struct {
+	Idle      map[string]int `json:"idle"`
+	InUse     int64          `json:"in_use"`
+	WarmHits  int64          `json:"warm_hits"`
+	ColdStart int64          `json:"cold_starts"`
+	Recycled  int64          `json:"recycled"`
+	Retired   int64          `json:"retired"`
+}
+
+type pooledAgent struct {
+	ag         *agent.Agent
+	templateID string
+	uses       int
+	idleSince  time.Time
+	// owner 首次分配时的租户和用户；用过的 Agent 只会再分配给同一租户的同一用户
+	owner string
+}
+
+// reusableBy 未使用过的 Agent 可分配给任何请求，用过的只分配给原来的租户和用户
+func (e *pooledAgent) reusableBy(owner string) bool {
+	return e.uses == 0 || e.owner == owner
+}
+
+// poolOwner 返回请求的租户和用户，作为归还后复用 Agent 的归属
+func poolOwner(ctx context.Context) string {
+	meta, _ := requestctx.From(ctx)
+	return meta.TenantID + "/" + meta.UserID
+}
+
+// WarmPool 按模板预先创建 Agent（系统提示词与 Provider 已就绪），
+// 请求到来时直接分配，完成后清空对话并放回池中，缩短首 token 时间。
+// 归还的 Agent 仍保留沙箱工作目录、待办和文件快照，因此只会再分配给同一租户的同一用户。
+type WarmPool struct {
+	deps   *agent.Dependencies
+	config WarmPoolConfig
+
+	mu      sync.Mutex
+	idle    map[string][]*pooledAgent
+	inUse   map[string]*pooledAgent // agentID -> entry
+	closed  bool
+	filling map[string]bool
+	fillers sync.WaitGroup
+
+	warmHits  atomic.Int64
+	coldStart atomic.Int64
+	recycled  atomic.Int64
+	retired   atomic.Int64
+}
+
+// NewWarmPool 创建预热池
+func NewWarmPool(deps *agent.Dependencies, config WarmPoolConfig) *WarmPool {
+	if config.SizePerTemplate <= 0 {
+		config.SizePerTemplate = 2
+	}
+	return &WarmPool{
+		deps:    deps,
+		config:  config,
+		idle:    make(map[string][]*pooledAgent),
+		inUse:   make(map[string]*pooledAgent),
+		filling: make(map[string]bool),
+	}
+}
+
+// Start 异步为所有配置的模板填充空闲 Agent
+func (p *WarmPool) Start(ctx context.Context) {
+	for _, templateID := range p.config.Templates {
+		p.refill(ctx, templateID)
+	}
+}
+
+// Handles 判断模板是否由预热池管理
+func (p *WarmPool) Handles(templateID string) bool {
+	for _, t := range p.config.Templates {
+		if t == templateID {
+			return true
+		}
+	}
+	return false
+}
+
+// Acquire 获取一个 Agent；池中无空闲时同步冷启动创建
+// 返回值 warm 表示是否命中预热 Agent
+func (p *WarmPool) Acquire(ctx context.Context, templateID string) (ag *agent.Agent, warm bool, err error) {
+	owner := poolOwner(ctx)
+
+	p.mu.Lock()
+	var entry *pooledAgent
+	list := p.idle[templateID]
+	for i := len(list) - 1; i >= 0 && entry == nil; i-- {
+		candidate := list[i]
+		expired := p.config.MaxIdle > 0 && time.Since(candidate.idleSince) > p.config.MaxIdle
+		if !expired && !candidate.reusableBy(owner) {
+			continue
+		}
+		list = append(list[:i], list[i+1:]...)
+		if expired {
+			go p.retire(candidate)
+			continue
+		}
+		entry = candidate
+	}
+	p.idle[templateID] = list
+	if entry != nil {
+		entry.owner = owner
+		p.inUse[entry.ag.ID()] = entry
+	}
+	p.mu.Unlock()
+
+	defer p.refill(context.WithoutCancel(ctx), templateID)
+
+	if entry != nil {
+		p.warmHits.Add(1)
+		return entry.ag, true, nil
+	}
+
+	ag, err = p.create(ctx, templateID)
+	if err != nil {
+		return nil, false, err
+	}
+	p.coldStart.Add(1)
+
+	p.mu.Lock()
+	p.inUse[ag.ID()] = &pooledAgent{ag: ag, templateID: templateID, owner: owner}
+	p.mu.Unlock()
+	return ag, false, nil
+}
+
+// Release 归还 Agent：清空对话后放回池中留给同一用户复用，或在超出容量/复用次数时关闭
+func (p *WarmPool) Release(ctx context.Context, ag *agent.Agent) {
+	if ag == nil {
+		return
+	}
+
+	p.mu.Lock()
+	entry, ok := p.inUse[ag.ID()]
+	delete(p.inUse, ag.ID())
+	p.mu.Unlock()
+	if !ok {
+		_ = ag.Close()
+		return
+	}
+
+	entry.uses++
+	if p.config.MaxUses > 0 && entry.uses >= p.config.MaxUses {
+		p.retire(entry)
+		p.refill(ctx, entry.templateID)
+		return
+	}
+
+	if err := ag.ResetConversation(ctx); err != nil {
+		logging.Warn(ctx, "warm_pool.reset.failed", map[string]any{
+			"agent_id": ag.ID(),
+			"error":    err.Error(),
+		})
+		p.retire(entry)
+		p.refill(ctx, entry.templateID)
+		return
+	}
+
+	p.mu.Lock()
+	if p.closed || p.countIdleLocked(entry.templateID, true) >= p.config.SizePerTemplate {
+		p.mu.Unlock()
+		p.retire(entry)
+		return
+	}
+	entry.idleSince = time.Now()
+	p.idle[entry.templateID] = append(p.idle[entry.templateID], entry)
+	p.mu.Unlock()
+	p.recycled.Add(1)
+}
+
+// Stats 返回统计信息
+func (p *WarmPool) Stats() WarmPoolStats {
+	p.mu.Lock()
+	idle := make(map[string]int, len(p.idle))
+	for templateID, list := range p.idle {
+		idle[templateID] = len(list)
+	}
+	inUse := int64(len(p.inUse))
+	p.mu.Unlock()
+
+	return WarmPoolStats{
+		Idle:      idle,
+		InUse:     inUse,
+		WarmHits:  p.warmHits.Load(),
+		ColdStart: p.coldStart.Load(),
+		Recycled:  p.recycled.Load(),
+		Retired:   p.retired.Load(),
+	}
+}
+
+// Close 等待补齐任务结束并关闭所有空闲 Agent；使用中的 Agent 在 Release 时关闭
+func (p *WarmPool) Close() {
+	p.mu.Lock()
+	p.closed = true
+	p.mu.Unlock()
+	p.fillers.Wait()
+
+	p.mu.Lock()
+	idle := p.idle
+	p.idle = make(map[string][]*pooledAgent)
+	p.mu.Unlock()
+
+	for _, list := range idle {
+		for _, entry := range list {
+			p.retire(entry)
+		}
+	}
+}
+
+func (p *WarmPool) create(ctx context.Context, templateID string) (*agent.Agent, error) {
+	return agent.Create(ctx, &types.AgentConfig{TemplateID: templateID}, p.deps)
+}
+
+func (p *WarmPool) retire(entry *pooledAgent) {
+	p.retired.Add(1)
+	_ = entry.ag.Close()
+}
+
+// countIdleLocked 统计模板的空闲 Agent，used 为 true 时统计归还的，否则统计未使用的；调用方需持有 p.mu
+func (p *WarmPool) countIdleLocked(templateID string, used bool) int {
+	n := 0
+	for _, entry := range p.idle[templateID] {
+		if (entry.uses > 0) == used {
+			n++
+		}
+	}
+	return n
+}
+
+// refill 在后台补齐模板未使用的空闲 Agent，同一模板同时只有一个补齐任务
+func (p *WarmPool) refill(ctx context.Context, templateID string) {
+	p.mu.Lock()
+	if p.closed || p.filling[templateID] || p.countIdleLocked(templateID, false) >= p.config.SizePerTemplate {
+		p.mu.Unlock()
+		return
+	}
+	p.filling[templateID] = true
+	p.fillers.Add(1)
+	p.mu.Unlock()
+
+	go func() {
+		defer p.fillers.Done()
+		defer func() {
+			p.mu.Lock()
+			delete(p.filling, templateID)
+			p.mu.Unlock()
+		}()
+
+		for {
+			p.mu.Lock()
+			full := p.closed || p.countIdleLocked(templateID, false) >= p.config.SizePerTemplate
+			p.mu.Unlock()
+			if full {
+				return
+			}
+
+			ag, err := p.create(ctx, templateID)
+			if err != nil {
+				logging.Warn(ctx, "warm_pool.create.failed", map[string]any{
+					"template_id": templateID,
+					"error":       err.Error(),
+				})
+				return
+			}
+
+			p.mu.Lock()
+			if p.closed {
+				p.mu.Unlock()
+				_ = ag.Close()
+				return
+			}
+			p.idle[templateID] = append(p.idle[templateID], &pooledAgent{
+				ag:         ag,
+				templateID: templateID,
+				idleSince:  time.Now(),
+			})
+			p.mu.Unlock()
+		}
+	}()
+}
@@ -7,13 +7,18 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"os"
+	"slices"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/astercloud/aster/pkg/agent"
+	"github.com/astercloud/aster/pkg/agenterr"
+	"github.com/astercloud/aster/pkg/config"
 	"github.com/astercloud/aster/pkg/logging"
+	"github.com/astercloud/aster/pkg/multitenancy"
+	"github.com/astercloud/aster/pkg/permission"
+	"github.com/astercloud/aster/pkg/session"
 	"github.com/astercloud/aster/pkg/store"
 	"github.com/astercloud/aster/pkg/tools/builtin"
 	"github.com/astercloud/aster/pkg/types"
@@ -44,6 +49,9 @@ type WebSocketHandler struct {
 
 	// HITL Manager for Human-in-the-Loop approval
 	hitlManager *HITLManager
+
+	// Optional session service that records chats naming a session_id
+	sessions session.Service
 }
 
 // WebSocketConnection represents a single WebSocket connection
@@ -79,6 +87,13 @@ func NewWebSocketHandler(st store.Store, deps *agent.Dependencies, reg *RuntimeA
 	return h
 }
 
+// SetSessionService records the chats of new agents in the session named by
+// the chat's session_id. The session must already exist and, on tenant-scoped
+// services, belong to the connection's tenant.
+func (h *WebSocketHandler) SetSessionService(svc session.Service) {
+	h.sessions = svc
+}
+
 // HandleWebSocket handles WebSocket upgrade and communication
 func (h *WebSocketHandler) HandleWebSocket(c *gin.Context) {
 	// Upgrade HTTP connection to WebSocket
@@ -90,8 +105,8 @@ func (h *WebSocketHandler) HandleWebSocket(c *gin.Context) {
 		return
 	}
 
-	// Create connection context
-	ctx, cancel := context.WithCancel(context.Background())
+	// Create connection context (detached from the request but keeps its values, e.g. tenant)
+	ctx, cancel := context.WithCancel(context.WithoutCancel(c.Request.Context()))
 
 	// Create connection object
 	wsConn := &WebSocketConnection{
@@ -213,8 +228,18 @@ func (h *WebSocketHandler) handleMessage(wsConn *WebSocketConnection, msg *WebSo
 		h.handleTodoDelete(wsConn, msg.Payload)
 	case "tool:control", "tool_control":
 		h.handleToolControl(wsConn, msg.Payload)
+	case "model:switch", "model_switch":
+		h.handleModelSwitch(wsConn, msg.Payload)
+	case "steer":
+		h.handleSteer(wsConn, msg.Payload)
 	case "permission_decision":
 		h.handlePermissionDecision(wsConn, msg.Payload)
+	case "permission_mode":
+		h.handlePermissionMode(wsConn, msg.Payload)
+	case "plan_decision":
+		h.handlePlanDecision(wsConn, msg.Payload)
+	case "tool_filter":
+		h.handleToolFilter(wsConn, msg.Payload)
 	default:
 		h.sendError(wsConn, "unknown_message_type", "Unknown message type: "+msg.Type)
 	}
@@ -267,17 +292,7 @@ func (h *WebSocketHandler) handleChat(wsConn *WebSocketConnection, payload map[s
 
 			// Fill API key from environment if missing
 			if modelConfig.APIKey == "" && modelConfig.Provider != "" {
-				var envKey string
-				switch modelConfig.Provider {
-				case "deepseek":
-					envKey = os.Getenv("DEEPSEEK_API_KEY")
-				case "anthropic":
-					envKey = os.Getenv("ANTHROPIC_API_KEY")
-				case "openai":
-					envKey = os.Getenv("OPENAI_API_KEY")
-				default:
-					envKey = os.Getenv(strings.ToUpper(modelConfig.Provider) + "_API_KEY")
-				}
+				envKey := config.ProviderAPIKey(modelConfig.Provider)
 				if envKey != "" {
 					modelConfig.APIKey = envKey
 				}
@@ -286,14 +301,29 @@ func (h *WebSocketHandler) handleChat(wsConn *WebSocketConnection, payload map[s
 			cfg.ModelConfig = modelConfig
 		}
 
+		var opts []agent.CreateOption
+		if sessionID, _ := payload["session_id"].(string); sessionID != "" {
+			if h.sessions == nil {
+				h.sendError(wsConn, "sessions_unavailable", "Session service is not configured")
+				return
+			}
+			if locator, ok := h.sessions.(session.Locator); ok {
+				if _, err := locator.AppNameOf(wsConn.ctx, sessionID); err != nil {
+					h.sendError(wsConn, "session_not_found", err.Error())
+					return
+				}
+			}
+			opts = append(opts, agent.WithSessionService(h.sessions, sessionID))
+		}
+
 		// Create agent instance
-		ag, err = agent.Create(wsConn.ctx, cfg, h.deps)
+		ag, err = agent.Create(wsConn.ctx, cfg, h.deps, opts...)
 		if err != nil {
 			h.sendError(wsConn, "agent_creation_failed", err.Error())
 			return
 		}
 		wsConn.Agent = ag
-		h.registry.Register(ag)
+		h.registry.RegisterForTenant(multitenancy.GetTenantIDOrDefault(wsConn.ctx, ""), ag)
 		logging.Info(wsConn.ctx, "websocket.agent.created", map[string]any{
 			"agent_id": ag.ID(),
 		})
@@ -316,15 +346,10 @@ func (h *WebSocketHandler) handleChat(wsConn *WebSocketConnection, payload map[s
 					if !ok {
 						return
 					}
-					channel := ""
-					eventType := ""
-					if ev, ok := envelope.Event.(types.EventType); ok {
-						channel = string(ev.Channel())
-						eventType = ev.EventType()
-					}
 					h.sendMessage(wsConn, "agent_event", map[string]any{
-						"channel":  channel,
-						"type":     eventType,
+						"version":  envelope.Version,
+						"channel":  envelope.Channel,
+						"type":     envelope.Type,
 						"cursor":   envelope.Cursor,
 						"bookmark": envelope.Bookmark,
 						"event":    envelope.Event,
@@ -366,7 +391,7 @@ func (h *WebSocketHandler) handleChat(wsConn *WebSocketConnection, payload map[s
 					"agent_id": ag.ID(),
 					"error":    err.Error(),
 				})
-				h.sendError(wsConn, "stream_error", err.Error())
+				h.sendError(wsConn, string(agenterr.From(err).Code), err.Error())
 				break
 			}
 
@@ -796,6 +821,144 @@ func (h *WebSocketHandler) handleToolControl(wsConn *WebSocketConnection, payloa
 	}
 }
 
+// handleModelSwitch handles mid-session model hot-swap requests
+func (h *WebSocketHandler) handleModelSwitch(wsConn *WebSocketConnection, payload map[string]any) {
+	if wsConn.Agent == nil {
+		h.sendError(wsConn, "agent_not_ready", "agent is not initialized")
+		return
+	}
+
+	model, _ := payload["model"].(string)
+	note, _ := payload["note"].(string)
+	if model == "" {
+		h.sendError(wsConn, "invalid_model_switch", "model is required")
+		return
+	}
+
+	if err := wsConn.Agent.SwitchModel(wsConn.ctx, model, note); err != nil {
+		h.sendError(wsConn, "model_switch_failed", err.Error())
+		return
+	}
+}
+
+// handleSteer injects a new instruction into the agent's current turn
+func (h *WebSocketHandler) handleSteer(wsConn *WebSocketConnection, payload map[string]any) {
+	if wsConn.Agent == nil {
+		h.sendError(wsConn, "agent_not_ready", "agent is not initialized")
+		return
+	}
+
+	message, _ := payload["message"].(string)
+	mode, _ := payload["mode"].(string)
+	if strings.TrimSpace(message) == "" {
+		h.sendError(wsConn, "invalid_steer", "message is required")
+		return
+	}
+
+	if err := wsConn.Agent.Steer(wsConn.ctx, message, agent.SteerMode(mode)); err != nil {
+		h.sendError(wsConn, "steer_failed", err.Error())
+		return
+	}
+}
+
+// handlePermissionMode switches the agent's permission mode, e.g. into plan mode
+func (h *WebSocketHandler) handlePermissionMode(wsConn *WebSocketConnection, payload map[string]any) {
+	if wsConn.Agent == nil {
+		h.sendError(wsConn, "agent_not_ready", "agent is not initialized")
+		return
+	}
+
+	mode, _ := payload["mode"].(string)
+	switch permission.Mode(mode) {
+	case permission.ModeAutoApprove, permission.ModeSmartApprove, permission.ModeAlwaysAsk, permission.ModePlan, permission.ModeBypass:
+	default:
+		h.sendError(wsConn, "invalid_permission_mode", "unknown permission mode: "+mode)
+		return
+	}
+
+	wsConn.Agent.SetPermissionMode(permission.Mode(mode))
+	if wsConn.Agent.GetPermissionMode() != permission.Mode(mode) {
+		// Bypass mode must be allowed in the agent config
+		h.sendError(wsConn, "permission_mode_refused", "permission mode not allowed for this agent: "+mode)
+		return
+	}
+	h.sendMessage(wsConn, "permission_mode", map[string]any{"mode": mode})
+}
+
+// handlePlanDecision approves or rejects the plan drafted in plan mode
+func (h *WebSocketHandler) handlePlanDecision(wsConn *WebSocketConnection, payload map[string]any) {
+	if wsConn.Agent == nil {
+		h.sendError(wsConn, "agent_not_ready", "agent is not initialized")
+		return
+	}
+
+	decision, _ := payload["decision"].(string)
+	reason, _ := payload["reason"].(string)
+	switch decision {
+	case "approve":
+		if _, err := wsConn.Agent.ApproveDraftPlan(wsConn.ctx, "websocket"); err != nil {
+			h.sendError(wsConn, "plan_decision_failed", err.Error())
+		}
+	case "reject":
+		if err := wsConn.Agent.RejectDraftPlan(reason); err != nil {
+			h.sendError(wsConn, "plan_decision_failed", err.Error())
+		}
+	default:
+		h.sendError(wsConn, "invalid_plan_decision", "decision must be approve or reject")
+	}
+}
+
+// handleToolFilter updates the agent's allowed/disallowed tool lists.
+// The payload either replaces the lists ("allowed_tools", "disallowed_tools")
+// or toggles a single tool ("tool" plus "enabled").
+func (h *WebSocketHandler) handleToolFilter(wsConn *WebSocketConnection, payload map[string]any) {
+	if wsConn.Agent == nil {
+		h.sendError(wsConn, "agent_not_ready", "agent is not initialized")
+		return
+	}
+
+	allowed, disallowed := wsConn.Agent.ToolFilter()
+	if v, ok := payload["allowed_tools"]; ok {
+		allowed = stringList(v)
+	}
+	if v, ok := payload["disallowed_tools"]; ok {
+		disallowed = stringList(v)
+	}
+	if tool, _ := payload["tool"].(string); tool != "" {
+		enabled, ok := payload["enabled"].(bool)
+		if !ok {
+			h.sendError(wsConn, "invalid_tool_filter", "enabled is required when toggling a tool")
+			return
+		}
+		disallowed = slices.DeleteFunc(disallowed, func(p string) bool { return p == tool })
+		if !enabled {
+			disallowed = append(disallowed, tool)
+		}
+	}
+
+	if err := wsConn.Agent.SetToolFilter(allowed, disallowed); err != nil {
+		h.sendError(wsConn, "invalid_tool_filter", err.Error())
+		return
+	}
+	allowed, disallowed = wsConn.Agent.ToolFilter()
+	h.sendMessage(wsConn, "tool_filter", map[string]any{
+		"allowed_tools":    allowed,
+		"disallowed_tools": disallowed,
+	})
+}
+
+// stringList converts a JSON array payload value into strings
+func stringList(v any) []string {
+	items, _ := v.([]any)
+	list := make([]string, 0, len(items))
+	for _, item := range items {
+		if s, ok := item.(string); ok && s != "" {
+			list = append(list, s)
+		}
+	}
+	return list
+}
+
 // broadcastToAll broadcasts a message to all connected WebSocket clients
 func (h *WebSocketHandler) broadcastToAll(messageType string, payload map[string]any) {
 	h.mu.RLock()
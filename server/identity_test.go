@@ -0,0 +1,105 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/astercloud/aster/pkg/artifact"
+	"github.com/astercloud/aster/pkg/identity"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdentityRoutes(t *testing.T) {
+	srv, cleanup := setupTestServerWithConfig(t, func(c *Config) {
+		c.Identity.Enabled = true
+		c.Artifacts.Enabled = true
+		c.Artifacts.Dir = t.TempDir()
+	})
+	defer cleanup()
+	require.NotNil(t, srv.identities)
+	require.Same(t, srv.identities, srv.deps.AgentDeps.Identity)
+
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		srv.Router().ServeHTTP(w, req)
+		return w
+	}
+	type verifyResponse struct {
+		Data struct {
+			Valid           bool   `json:"valid"`
+			ContentVerified bool   `json:"content_verified"`
+			Reason          string `json:"reason"`
+		} `json:"data"`
+	}
+	verify := func(body any) verifyResponse {
+		t.Helper()
+		data, err := json.Marshal(body)
+		require.NoError(t, err)
+		w := serve(http.MethodPost, "/v1/identities/verify", string(data))
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var resp verifyResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp
+	}
+
+	ctx := context.Background()
+	signer, err := srv.identities.Signer(ctx, "agt-1")
+	require.NoError(t, err)
+
+	w := serve(http.MethodGet, "/v1/identities/agt-1", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"key_id":"`+signer.Identity().KeyID+`"`)
+	assert.NotContains(t, w.Body.String(), "private_key")
+	w = serve(http.MethodGet, "/v1/identities/missing", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// Webhook body with the signature header value
+	body := `{"event":"deployed"}`
+	header := signer.Sign(identity.KindWebhook, []byte(body)).EncodeHeader()
+	resp := verify(map[string]any{"signature_header": header, "content": body})
+	assert.True(t, resp.Data.Valid, resp.Data.Reason)
+	assert.True(t, resp.Data.ContentVerified)
+	resp = verify(map[string]any{"signature_header": header, "content": `{"event":"rolled back"}`})
+	assert.False(t, resp.Data.Valid)
+
+	// Artifacts are checked against their stored content
+	a, err := srv.artifacts.Save(ctx, artifact.SaveRequest{
+		AgentID: "agt-1",
+		Name:    "report.csv",
+		Signer:  signer,
+	}, strings.NewReader("a,b\n1,2\n"))
+	require.NoError(t, err)
+	require.NotNil(t, a.Signature)
+	resp = verify(map[string]any{"artifact_id": a.ID})
+	assert.True(t, resp.Data.Valid, resp.Data.Reason)
+	assert.True(t, resp.Data.ContentVerified)
+
+	w = serve(http.MethodGet, "/v1/artifacts/"+a.ID+"/download", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, a.Signature.EncodeHeader(), w.Header().Get(identity.HeaderSignature))
+
+	// Exported reports are signed by the server identity over the exact body
+	w = serve(http.MethodGet, "/v1/reports/usage?format=csv", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	resp = verify(map[string]any{
+		"signature_header": w.Header().Get(identity.HeaderSignature),
+		"content":          w.Body.String(),
+	})
+	assert.True(t, resp.Data.Valid, resp.Data.Reason)
+	w = serve(http.MethodGet, "/v1/reports/usage", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	sig, err := identity.ParseHeader(w.Header().Get(identity.HeaderSignature))
+	require.NoError(t, err)
+	assert.Equal(t, identity.ServerID, sig.Signer)
+	assert.Equal(t, identity.Digest(w.Body.Bytes()), sig.Digest)
+
+	w = serve(http.MethodPost, "/v1/identities/verify", `{"content":"x"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
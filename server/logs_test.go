@@ -0,0 +1,85 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/astercloud/aster/pkg/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogRoutes(t *testing.T) {
+	srv, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	logging.ForComponent("LogRouteTest").Warn(ctx, "history record", nil)
+	logging.ForComponent("Other").Warn(ctx, "other component", nil)
+
+	w := httptest.NewRecorder()
+	srv.Router().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/logs?component=logroutetest", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"message":"history record"`)
+	assert.NotContains(t, w.Body.String(), "other component")
+
+	w = httptest.NewRecorder()
+	srv.Router().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/logs/stream?level=verbose", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	ts := httptest.NewServer(srv.Router())
+	defer ts.Close()
+	reqCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(reqCtx, http.MethodGet, ts.URL+"/v1/logs/stream?component=LogRouteTest&level=warn", nil)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer func() { _ = resp.Body.Close() }()
+	require.Contains(t, resp.Header.Get("Content-Type"), "text/event-stream")
+
+	reader := bufio.NewReader(resp.Body)
+	readUntil := func(marker string) string {
+		var seen strings.Builder
+		for {
+			line, err := reader.ReadString('\n')
+			require.NoError(t, err, "stream so far: %s", seen.String())
+			seen.WriteString(line)
+			if strings.Contains(line, marker) {
+				return seen.String()
+			}
+		}
+	}
+
+	history := readUntil(": connected")
+	assert.Contains(t, history, "history record")
+
+	logging.ForComponent("LogRouteTest").Info(ctx, "below level", nil)
+	logging.ForComponent("LogRouteTest").Error(ctx, "live record", map[string]any{"code": 7})
+	live := readUntil("live record")
+	assert.Contains(t, live, "event:log")
+	assert.NotContains(t, live, "below level")
+}
+
+func TestLogRoutesRequireAdmin(t *testing.T) {
+	srv, cleanup := setupTestServerWithConfig(t, func(c *Config) {
+		c.Auth.APIKey.Enabled = true
+		c.Auth.APIKey.Entries = []APIKeyEntry{
+			{Key: "key-admin", Name: "admin", Roles: []string{"admin"}},
+			{Key: "key-viewer", Name: "viewer", Roles: []string{"viewer"}, TenantID: "acme"},
+		}
+	})
+	defer cleanup()
+
+	for key, want := range map[string]int{"key-admin": http.StatusOK, "key-viewer": http.StatusForbidden} {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/v1/logs", nil)
+		req.Header.Set("X-API-Key", key)
+		srv.Router().ServeHTTP(w, req)
+		assert.Equal(t, want, w.Code, key)
+	}
+}
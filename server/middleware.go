@@ -1,16 +1,20 @@
 package server
 
 import (
-	"fmt"
 	"net/http"
-	"slices"
 	"strings"
 	"time"
 
+	"github.com/astercloud/aster/pkg/logging"
+	"github.com/astercloud/aster/pkg/multitenancy"
+	"github.com/astercloud/aster/pkg/requestctx"
+	"github.com/astercloud/aster/server/auth"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
 
+var httpLog = logging.ForComponent("HTTP")
+
 // requestIDMiddleware adds a unique request ID to each request
 func requestIDMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -20,6 +24,32 @@ func requestIDMiddleware() gin.HandlerFunc {
 		}
 		c.Set("requestID", requestID)
 		c.Header("X-Request-ID", requestID)
+		// Logs written with the request context carry the request ID; the
+		// correlation ID defaults to it unless the caller sent one
+		meta := requestctx.FromRequest(c.Request, requestID)
+		c.Header(requestctx.HeaderCorrelationID, meta.CorrelationID)
+		ctx := logging.WithCorrelation(c.Request.Context(), logging.Correlation{RequestID: requestID})
+		c.Request = c.Request.WithContext(requestctx.With(ctx, meta))
+		c.Next()
+	}
+}
+
+// requestIdentityMiddleware adds the authenticated user and resolved tenant to
+// the request metadata, so agent events, tool calls, model calls and logs made
+// for this request can be attributed. Runs after authentication and tenancy.
+func requestIdentityMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var meta requestctx.Metadata
+		if user, ok := auth.CurrentUser(c); ok {
+			meta.UserID = user.ID
+			meta.TenantID = user.TenantID
+		}
+		if tenantID := multitenancy.GetTenantIDOrDefault(c.Request.Context(), ""); tenantID != "" {
+			meta.TenantID = tenantID
+		}
+		if !meta.IsZero() {
+			c.Request = c.Request.WithContext(requestctx.With(c.Request.Context(), meta))
+		}
 		c.Next()
 	}
 }
@@ -32,14 +62,13 @@ func structuredLoggingMiddleware(config LoggingConfig) gin.HandlerFunc {
 
 		c.Next()
 
-		latency := time.Since(start)
-		statusCode := c.Writer.Status()
-		method := c.Request.Method
-		requestID := c.GetString("requestID")
-
 		if config.Structured {
-			fmt.Printf(`{"time":"%s","request_id":"%s","method":"%s","path":"%s","status":%d,"latency":"%v"}%s`,
-				start.Format(time.RFC3339), requestID, method, path, statusCode, latency, "\n")
+			httpLog.Info(c.Request.Context(), "request", map[string]any{
+				"method":  c.Request.Method,
+				"path":    path,
+				"status":  c.Writer.Status(),
+				"latency": time.Since(start).String(),
+			})
 		}
 	}
 }
@@ -77,42 +106,3 @@ func corsMiddleware(config CORSConfig) gin.HandlerFunc {
 		c.Next()
 	}
 }
-
-// apiKeyAuthMiddleware validates API key
-func apiKeyAuthMiddleware(config APIKeyConfig) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		apiKey := c.GetHeader(config.HeaderName)
-		if apiKey == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"success": false, "error": gin.H{"code": "missing_api_key"}})
-			c.Abort()
-			return
-		}
-		if !slices.Contains(config.Keys, apiKey) {
-			c.JSON(http.StatusUnauthorized, gin.H{"success": false, "error": gin.H{"code": "invalid_api_key"}})
-			c.Abort()
-			return
-		}
-		c.Set("authenticated", true)
-		c.Next()
-	}
-}
-
-// jwtAuthMiddleware validates JWT tokens
-func jwtAuthMiddleware(_ JWTConfig) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"success": false, "error": gin.H{"code": "missing_token"}})
-			c.Abort()
-			return
-		}
-		parts := strings.SplitN(authHeader, " ", 2)
-		if len(parts) != 2 || parts[0] != "Bearer" {
-			c.JSON(http.StatusUnauthorized, gin.H{"success": false, "error": gin.H{"code": "invalid_token_format"}})
-			c.Abort()
-			return
-		}
-		c.Set("authenticated", true)
-		c.Next()
-	}
-}
@@ -0,0 +1,38 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/astercloud/aster/pkg/requestctx"
+	"github.com/astercloud/aster/server/auth"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestMetadataMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(requestIDMiddleware(), func(c *gin.Context) {
+		c.Set(auth.ContextKeyUser, &auth.User{ID: "u1", TenantID: "acme"})
+	}, requestIdentityMiddleware())
+
+	var got requestctx.Metadata
+	router.GET("/", func(c *gin.Context) {
+		got, _ = requestctx.From(c.Request.Context())
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Request-ID", "req-1")
+	req.Header.Set("Accept-Language", "de-DE,de;q=0.9")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, requestctx.Metadata{UserID: "u1", TenantID: "acme", CorrelationID: "req-1", Locale: "de-DE"}, got)
+	assert.Equal(t, "req-1", w.Header().Get(requestctx.HeaderCorrelationID))
+
+	req.Header.Set(requestctx.HeaderCorrelationID, "upstream-7")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+	assert.Equal(t, "upstream-7", got.CorrelationID)
+}
@@ -1,6 +1,11 @@
 package server
 
-import "github.com/gin-gonic/gin"
+import (
+	"github.com/astercloud/aster/pkg/artifact"
+	"github.com/astercloud/aster/pkg/lock"
+	"github.com/astercloud/aster/pkg/provider"
+	"github.com/gin-gonic/gin"
+)
 
 // Option is a function that configures a Server
 type Option func(*Server)
@@ -20,3 +25,31 @@ func WithMiddleware(middlewares ...gin.HandlerFunc) Option {
 		}
 	}
 }
+
+// WithArtifactStore uses st for artifacts instead of the local directory
+// store, e.g. one backed by object storage. It takes effect even when
+// Config.Artifacts.Enabled is false.
+func WithArtifactStore(st *artifact.Store) Option {
+	return func(s *Server) {
+		s.artifacts = st
+	}
+}
+
+// WithLocker coordinates server instances that share a store: session resume
+// and the agent pools take a lock per session or agent, so two instances never
+// resume the same one concurrently. Use a lock backend all instances can reach,
+// such as lock.RedisLocker or lock.SQLLocker.
+func WithLocker(locker lock.Locker) Option {
+	return func(s *Server) {
+		s.locker = locker
+	}
+}
+
+// WithKeyManager reports usage and health of pooled provider API keys on the
+// dashboard. The agent dependencies' provider factory should be wrapped with
+// the same manager so requests rotate keys.
+func WithKeyManager(keys *provider.KeyManager) Option {
+	return func(s *Server) {
+		s.keys = keys
+	}
+}
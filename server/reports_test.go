@@ -0,0 +1,131 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/astercloud/aster/pkg/agent"
+	"github.com/astercloud/aster/pkg/types"
+	"github.com/astercloud/aster/pkg/usage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUsageReportRoute(t *testing.T) {
+	srv, cleanup := setupTestServer(t)
+	defer cleanup()
+	require.NotNil(t, srv.usage)
+	require.NotNil(t, srv.deps.AgentDeps.Usage)
+
+	at := time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC)
+	require.NoError(t, srv.usage.Record(context.Background(), usage.Record{
+		TenantID: "acme", AgentID: "agt-1", Model: "gpt-4o", InputTokens: 1000, OutputTokens: 500, Timestamp: at,
+	}))
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		srv.Router().ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	w := serve("/v1/reports/usage?month=2025-01&group_by=agent")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"agent_id":"agt-1"`)
+	assert.Contains(t, w.Body.String(), `"requests":1`)
+
+	w = serve("/v1/reports/usage?month=2025-01&format=csv")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "attachment; filename=usage-2025-01.csv", w.Header().Get("Content-Disposition"))
+	assert.True(t, strings.HasPrefix(w.Body.String(), "month,tenant_id,"))
+	assert.Contains(t, w.Body.String(), "2025-01,acme,")
+
+	w = serve("/v1/reports/usage?month=2025-13")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = serve("/v1/reports/usage?group_by=day")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestDashboardToolsRoute(t *testing.T) {
+	srv, cleanup := setupTestServer(t)
+	defer cleanup()
+	require.NotNil(t, srv.deps.AgentDeps.ToolUsage)
+
+	ctx := context.Background()
+	for i := range 6 {
+		require.NoError(t, srv.usage.RecordTool(ctx, usage.ToolRecord{
+			AgentID: "agt-1", TemplateID: "coder", Tool: "Bash", Success: i == 0, ErrorCode: "tool_failed", DurationMs: 100, ResultTokens: 50,
+		}))
+	}
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		srv.Router().ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	w := serve("/v1/dashboard/tools?template_id=coder")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"tool":"Bash"`)
+	assert.Contains(t, w.Body.String(), `"calls":6`)
+
+	w = serve("/v1/dashboard/insights")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"id":"failing_tool_coder_Bash"`)
+}
+
+func TestDashboardAgentRoute(t *testing.T) {
+	srv, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	ra := agent.NewRemoteAgent("remote-1", "coder", nil)
+	srv.agentRegistry.RegisterRemoteAgent(ra)
+	require.NoError(t, ra.PushEvent(types.AgentEventEnvelope{Event: &types.MonitorTokenUsageEvent{InputTokens: 100, OutputTokens: 20}}))
+	require.NoError(t, ra.PushEvent(types.AgentEventEnvelope{Event: &types.ProgressToolEndEvent{Call: types.ToolCallSnapshot{Name: "Bash"}}}))
+	require.NoError(t, ra.PushEvent(types.AgentEventEnvelope{Event: &types.ProgressDoneEvent{Reason: types.TerminationCompleted}}))
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		srv.Router().ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	w := serve("/v1/dashboard/agents/remote-1?period=1h")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"remote":true`)
+	assert.Contains(t, w.Body.String(), `"requests":1`)
+	assert.Contains(t, w.Body.String(), `"name":"Bash"`)
+	assert.Contains(t, w.Body.String(), `"status":"ok"`)
+	assert.Contains(t, w.Body.String(), `"active_sessions":[]`)
+
+	w = serve("/v1/dashboard/agents/missing")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestDashboardPersistedTraceRoute(t *testing.T) {
+	srv, cleanup := setupTestServer(t)
+	defer cleanup()
+	require.NotNil(t, srv.traces)
+
+	ra := agent.NewRemoteAgent("remote-1", "coder", nil)
+	srv.agentRegistry.RegisterRemoteAgent(ra)
+	require.NoError(t, ra.PushEvent(types.AgentEventEnvelope{Event: &types.MonitorTokenUsageEvent{InputTokens: 100, OutputTokens: 20}}))
+	require.NoError(t, ra.PushEvent(types.AgentEventEnvelope{Event: &types.ProgressDoneEvent{Reason: types.TerminationCompleted}}))
+
+	// The run is recorded asynchronously from the agent's event stream
+	require.Eventually(t, func() bool {
+		detail, err := srv.traces.Get(context.Background(), "remote-1-1")
+		return err == nil && detail != nil
+	}, 2*time.Second, 10*time.Millisecond)
+
+	// Once the agent is gone only the store still knows the trace
+	srv.agentRegistry.UnregisterRemoteAgent("remote-1")
+	w := httptest.NewRecorder()
+	srv.Router().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/dashboard/traces/remote-1-1", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"agent_id":"remote-1"`)
+	assert.Contains(t, w.Body.String(), `"total":120`)
+}
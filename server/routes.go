@@ -10,9 +10,9 @@ import (
 // registerAgentRoutes registers all agent-related routes
 func (s *Server) registerAgentRoutes(rg *gin.RouterGroup) {
 	// Create agent handler
-	h := handlers.NewAgentHandler(s.store, s.deps.AgentDeps)
+	h := handlers.NewAgentHandlerWithPool(s.store, s.deps.AgentDeps, s.warmPool)
 
-	agents := rg.Group("/agents")
+	agents := rg.Group("/agents", s.authorize("agents", ""), s.tenantQuotaMiddleware("agents"))
 	{
 		agents.POST("", h.Create)
 		agents.GET("", h.List)
@@ -47,7 +47,7 @@ func (s *Server) registerMemoryRoutes(rg *gin.RouterGroup) {
 	// Create memory handler
 	h := handlers.NewMemoryHandler(s.store)
 
-	memory := rg.Group("/memory")
+	memory := rg.Group("/memory", s.authorize("memory", ""))
 	{
 		// Working memory
 		working := memory.Group("/working")
@@ -64,7 +64,6 @@ func (s *Server) registerMemoryRoutes(rg *gin.RouterGroup) {
 		semantic := memory.Group("/semantic")
 		{
 			semantic.POST("", h.CreateSemanticMemory)
-			semantic.POST("/search", h.SearchSemanticMemory)
 		}
 
 		// Provenance
@@ -73,14 +72,20 @@ func (s *Server) registerMemoryRoutes(rg *gin.RouterGroup) {
 		// Consolidation
 		memory.POST("/consolidate", h.ConsolidateMemory)
 	}
+	// Search is a POST but only reads
+	rg.POST("/memory/semantic/search", s.authorize("memory", "read"), h.SearchSemanticMemory)
 }
 
 // registerSessionRoutes registers all session-related routes
 func (s *Server) registerSessionRoutes(rg *gin.RouterGroup) {
 	// Create session handler
-	h := handlers.NewSessionHandler(s.store)
+	h := handlers.NewSessionHandler(s.store, s.deps.AgentDeps)
+	if s.locker != nil {
+		h.SetLocker(s.locker)
+	}
+	s.collab = handlers.NewCollabHub(s.store, s.deps.AgentDeps)
 
-	sessions := rg.Group("/sessions")
+	sessions := rg.Group("/sessions", s.authorize("sessions", ""), s.tenantQuotaMiddleware("sessions"))
 	{
 		sessions.POST("", h.Create)
 		sessions.GET("", h.List)
@@ -90,7 +95,128 @@ func (s *Server) registerSessionRoutes(rg *gin.RouterGroup) {
 		sessions.GET("/:id/messages", h.GetMessages)
 		sessions.GET("/:id/checkpoints", h.GetCheckpoints)
 		sessions.POST("/:id/resume", h.Resume)
+		sessions.POST("/:id/summary", h.Summarize)
+		sessions.POST("/:id/fork", h.Fork)
 		sessions.GET("/:id/stats", h.GetStats)
+		sessions.GET("/:id/live", s.collab.Live)
+		sessions.POST("/:id/live/messages", s.collab.SendMessage)
+		sessions.GET("/:id/participants", s.collab.Participants)
+		sessions.GET("/:id/events", s.collab.Events)
+		if s.artifacts != nil {
+			sessions.GET("/:id/artifacts", handlers.NewArtifactHandler(s.store, s.artifacts).ListForSession)
+		}
+	}
+}
+
+// registerArtifactRoutes registers artifact download and lifecycle routes
+func (s *Server) registerArtifactRoutes(rg *gin.RouterGroup) {
+	if s.artifacts == nil {
+		return
+	}
+	h := handlers.NewArtifactHandler(s.store, s.artifacts)
+
+	artifacts := rg.Group("/artifacts", s.authorize("artifacts", ""))
+	{
+		artifacts.GET("", h.List)
+		artifacts.GET("/:id", h.Get)
+		artifacts.GET("/:id/download", h.Download)
+		artifacts.PATCH("/:id", h.Update)
+		artifacts.DELETE("/:id", h.Delete)
+	}
+	// GC spans all tenants, so it is restricted to roles with full access
+	rg.POST("/artifacts/gc", s.authorize("artifacts", "gc"), h.GC)
+}
+
+// registerReportRoutes registers usage and cost reporting routes
+func (s *Server) registerReportRoutes(rg *gin.RouterGroup) {
+	if s.usage == nil {
+		return
+	}
+	h := handlers.NewReportHandler(s.usage)
+	if s.serverSigner != nil {
+		h.SetSigner(s.serverSigner)
+	}
+
+	reports := rg.Group("/reports", s.authorize("reports", ""))
+	{
+		reports.GET("/usage", h.Usage)
+	}
+}
+
+// registerIdentityRoutes registers agent public key lookup and signature
+// verification routes
+func (s *Server) registerIdentityRoutes(rg *gin.RouterGroup) {
+	if s.identities == nil {
+		return
+	}
+	h := handlers.NewIdentityHandler(s.identities, s.artifacts)
+
+	identities := rg.Group("/identities", s.authorize("identities", ""))
+	{
+		identities.POST("/verify", h.Verify)
+		identities.GET("/:id", h.Get)
+	}
+}
+
+// registerUpdateRoutes registers the public desktop release routes. They sit
+// outside the authenticated v1 group: installs check for updates without
+// credentials, and installers are verified by signature on the client.
+func (s *Server) registerUpdateRoutes(rg *gin.RouterGroup) {
+	if s.config.Updates.Dir == "" {
+		return
+	}
+	h := handlers.NewUpdateHandler(s.config.Updates.Dir)
+
+	rg.GET("/manifest", h.Manifest)
+	rg.GET("/check", h.Check)
+	rg.GET("/assets/*name", h.Asset)
+}
+
+// registerAuditRoutes registers audit trail export and verification routes
+func (s *Server) registerAuditRoutes(rg *gin.RouterGroup) {
+	if s.audit == nil {
+		return
+	}
+	h := handlers.NewAuditHandler(s.audit)
+
+	trail := rg.Group("/audit", s.authorize("audit", ""))
+	{
+		trail.GET("/export", h.Export)
+		trail.POST("/verify", h.Verify)
+	}
+}
+
+// registerExperimentRoutes registers A/B experiment management and grading routes
+func (s *Server) registerExperimentRoutes(rg *gin.RouterGroup) {
+	if s.experiments == nil {
+		return
+	}
+	h := handlers.NewExperimentHandler(s.experiments)
+
+	exps := rg.Group("/experiments", s.authorize("experiments", ""))
+	{
+		exps.POST("", h.Create)
+		exps.GET("", h.List)
+		exps.POST("/grades", h.Grade)
+		exps.GET("/:id", h.Get)
+		exps.PUT("/:id", h.Update)
+		exps.DELETE("/:id", h.Delete)
+		exps.GET("/:id/results", h.Results)
+	}
+}
+
+// registerTemplateRoutes registers agent template listing and update routes
+func (s *Server) registerTemplateRoutes(rg *gin.RouterGroup) {
+	if s.deps.AgentDeps == nil || s.deps.AgentDeps.TemplateRegistry == nil {
+		return
+	}
+	h := handlers.NewTemplateHandler(s.deps.AgentDeps.TemplateRegistry, s.config.Templates.Dir)
+
+	templates := rg.Group("/templates", s.authorize("templates", ""))
+	{
+		templates.GET("", h.List)
+		templates.GET("/:id", h.Get)
+		templates.PUT("/:id", h.Update)
 	}
 }
 
@@ -99,19 +225,23 @@ func (s *Server) registerWorkflowRoutes(rg *gin.RouterGroup) {
 	// Create workflow handler
 	h := handlers.NewWorkflowHandler(s.store)
 
-	workflows := rg.Group("/workflows")
+	workflows := rg.Group("/workflows", s.authorize("workflows", ""))
 	{
 		workflows.POST("", h.Create)
 		workflows.GET("", h.List)
 		workflows.GET("/:id", h.Get)
 		workflows.PATCH("/:id", h.Update)
 		workflows.DELETE("/:id", h.Delete)
-		workflows.POST("/:id/execute", h.Execute)
-		workflows.POST("/:id/suspend", h.Suspend)
-		workflows.POST("/:id/resume", h.Resume)
 		workflows.GET("/:id/executions", h.GetExecutions)
 		workflows.GET("/:id/executions/:eid", h.GetExecutionDetails)
 	}
+
+	runs := rg.Group("/workflows", s.authorize("workflows", "execute"))
+	{
+		runs.POST("/:id/execute", h.Execute)
+		runs.POST("/:id/suspend", h.Suspend)
+		runs.POST("/:id/resume", h.Resume)
+	}
 }
 
 // registerToolRoutes registers all tool-related routes
@@ -120,17 +250,17 @@ func (s *Server) registerToolRoutes(rg *gin.RouterGroup) {
 	h := handlers.NewToolHandler(s.store)
 	rt := handlers.NewToolRuntimeHandler(s.store, s.agentRegistry)
 
-	tools := rg.Group("/tools")
+	tools := rg.Group("/tools", s.authorize("tools", ""))
 	{
 		tools.POST("", h.Create)
 		tools.GET("", h.List)
 		tools.GET("/:id", h.Get)
 		tools.PATCH("/:id", h.Update)
 		tools.DELETE("/:id", h.Delete)
-		tools.POST("/:id/execute", h.Execute)
 	}
+	rg.POST("/tools/:id/execute", s.authorize("tools", "execute"), h.Execute)
 
-	toolCalls := rg.Group("/tool-calls")
+	toolCalls := rg.Group("/tool-calls", s.authorize("tools", ""))
 	{
 		toolCalls.GET("/running", rt.ListRunning)
 		toolCalls.GET("/:id/status", rt.GetStatus)
@@ -143,7 +273,7 @@ func (s *Server) registerMiddlewareRoutes(rg *gin.RouterGroup) {
 	// Create middleware handler
 	h := handlers.NewMiddlewareHandler(s.store)
 
-	middlewares := rg.Group("/middlewares")
+	middlewares := rg.Group("/middlewares", s.authorize("middlewares", ""))
 	{
 		// Basic CRUD
 		middlewares.POST("", h.Create)
@@ -175,7 +305,7 @@ func (s *Server) registerTelemetryRoutes(rg *gin.RouterGroup) {
 	// Create telemetry handler
 	h := handlers.NewTelemetryHandler(s.store)
 
-	telemetry := rg.Group("/telemetry")
+	telemetry := rg.Group("/telemetry", s.authorize("telemetry", ""))
 	{
 		// Metrics
 		telemetry.POST("/metrics", h.RecordMetric)
@@ -183,11 +313,16 @@ func (s *Server) registerTelemetryRoutes(rg *gin.RouterGroup) {
 
 		// Traces
 		telemetry.POST("/traces", h.RecordTrace)
-		telemetry.POST("/traces/query", h.QueryTraces)
 
 		// Logs
 		telemetry.POST("/logs", h.RecordLog)
-		telemetry.POST("/logs/query", h.QueryLogs)
+	}
+
+	// Queries are POSTs but only read
+	queries := rg.Group("/telemetry", s.authorize("telemetry", "read"))
+	{
+		queries.POST("/traces/query", h.QueryTraces)
+		queries.POST("/logs/query", h.QueryLogs)
 	}
 }
 
@@ -196,7 +331,7 @@ func (s *Server) registerEvalRoutes(rg *gin.RouterGroup) {
 	// Create eval handler
 	h := handlers.NewEvalHandler(s.store)
 
-	eval := rg.Group("/eval")
+	eval := rg.Group("/eval", s.authorize("eval", ""))
 	{
 		// Evaluation runs
 		eval.POST("/text", h.RunTextEval)
@@ -229,7 +364,7 @@ func (s *Server) registerMCPRoutes(rg *gin.RouterGroup) {
 	// Create MCP handler
 	h := handlers.NewMCPHandler(s.store)
 
-	mcp := rg.Group("/mcp")
+	mcp := rg.Group("/mcp", s.authorize("mcp", ""))
 	{
 		servers := mcp.Group("/servers")
 		{
@@ -249,7 +384,7 @@ func (s *Server) registerSystemRoutes(rg *gin.RouterGroup) {
 	// Create system handler
 	h := handlers.NewSystemHandler(s.store)
 
-	system := rg.Group("/system")
+	system := rg.Group("/system", s.authorize("system", ""))
 	{
 		// Configuration management
 		config := system.Group("/config")
@@ -276,11 +411,23 @@ func (s *Server) registerSystemRoutes(rg *gin.RouterGroup) {
 	s.registerRoomRoutes(rg)
 }
 
+// registerLogRoutes registers recent and live server log routes
+func (s *Server) registerLogRoutes(rg *gin.RouterGroup) {
+	h := handlers.NewLogHandler(s.logs)
+
+	// Logs are server-wide and can mention any tenant, so only admins may read them
+	logs := rg.Group("/logs", s.requireAdmin())
+	{
+		logs.GET("", h.Recent)
+		logs.GET("/stream", h.Stream)
+	}
+}
+
 // registerPoolRoutes registers pool-related routes
 func (s *Server) registerPoolRoutes(rg *gin.RouterGroup) {
-	h := handlers.NewPoolHandler(s.store, s.deps.AgentDeps)
+	h := handlers.NewPoolHandlerWithLocker(s.store, s.deps.AgentDeps, s.locker)
 
-	pool := rg.Group("/pool")
+	pool := rg.Group("/pool", s.authorize("agents", ""))
 	{
 		pool.POST("/agents", h.CreateAgent)
 		pool.GET("/agents", h.ListAgents)
@@ -288,6 +435,7 @@ func (s *Server) registerPoolRoutes(rg *gin.RouterGroup) {
 		pool.POST("/agents/:id/resume", h.ResumeAgent)
 		pool.DELETE("/agents/:id", h.RemoveAgent)
 		pool.GET("/stats", h.GetStats)
+		pool.GET("/warm", s.warmPoolStats)
 	}
 }
 
@@ -297,11 +445,12 @@ func (s *Server) registerRoomRoutes(rg *gin.RouterGroup) {
 	pool := core.NewPool(&core.PoolOptions{
 		Dependencies: s.deps.AgentDeps,
 		MaxAgents:    100,
+		Locker:       s.locker,
 	})
 
 	h := handlers.NewRoomHandler(s.store, pool)
 
-	rooms := rg.Group("/rooms")
+	rooms := rg.Group("/rooms", s.authorize("agents", ""))
 	{
 		rooms.POST("", h.Create)
 		rooms.GET("", h.List)
@@ -324,71 +473,27 @@ func (s *Server) registerA2ARoutes(rg *gin.RouterGroup) {
 	// 创建 A2A handler
 	h := a2a.NewHandler(s.a2aServer)
 
-	// 注册路由 (直接在 v1 group 上注册以获取正确的路径)
-	h.RegisterRoutes(rg)
+	// 注册路由 (在 v1 下的空前缀分组上注册以获取正确的路径)
+	h.RegisterRoutes(rg.Group("", s.authorize("agents", "")))
 }
 
-// registerDashboardRoutes registers all dashboard-related routes (with auth).
-// Reserved for future use when dashboard authentication is enabled.
-//
-//nolint:unused
-func (s *Server) registerDashboardRoutes(rg *gin.RouterGroup) {
+// registerDashboardRoutes registers dashboard routes. Authentication is applied
+// by the caller; pricing updates additionally require the pricing permission.
+func (s *Server) registerDashboardRoutes(dashboard *gin.RouterGroup) {
 	// Create dashboard handler with agent registry
 	h := handlers.NewDashboardHandlerWithRegistry(s.agentRegistry, s.store)
-
-	// Create dashboard event stream handler
-	eventHandler := handlers.NewDashboardEventHandler(s.agentRegistry)
-
-	dashboard := rg.Group("/dashboard")
-	{
-		// Overview
-		dashboard.GET("/overview", h.GetOverview)
-
-		// Traces
-		dashboard.GET("/traces", h.ListTraces)
-		dashboard.GET("/traces/:id", h.GetTrace)
-
-		// Metrics
-		metrics := dashboard.Group("/metrics")
-		{
-			metrics.GET("/tokens", h.GetTokenUsage)
-			metrics.GET("/costs", h.GetCosts)
-			metrics.GET("/performance", h.GetPerformance)
-		}
-
-		// Events
-		events := dashboard.Group("/events")
-		{
-			events.GET("", h.GetRecentEvents)
-			events.GET("/since/:cursor", h.GetEventsSince)
-			// WebSocket endpoint for real-time event streaming
-			events.GET("/stream", eventHandler.HandleEventStream)
-			events.GET("/stats", eventHandler.GetStats)
-		}
-
-		// Insights
-		dashboard.GET("/insights", h.GetInsights)
-
-		// Pricing configuration
-		pricing := dashboard.Group("/pricing")
-		{
-			pricing.GET("", h.GetPricing)
-			pricing.PUT("", h.UpdatePricing)
-		}
-
-		// Sessions
-		sessions := dashboard.Group("/sessions")
-		{
-			sessions.GET("", h.ListSessions)
-			sessions.GET("/:id", h.GetSession)
-		}
+	if s.usage != nil {
+		h.SetUsageLedger(s.usage)
+	}
+	if s.traces != nil {
+		h.SetTraceStore(s.traces)
+	}
+	if s.keys != nil {
+		h.SetKeyManager(s.keys)
+	}
+	if s.experiments != nil {
+		dashboard.GET("/experiments/:id", handlers.NewExperimentHandler(s.experiments).Results)
 	}
-}
-
-// registerDashboardRoutesNoAuth registers dashboard routes without authentication (for Studio UI)
-func (s *Server) registerDashboardRoutesNoAuth(dashboard *gin.RouterGroup) {
-	// Create dashboard handler with agent registry
-	h := handlers.NewDashboardHandlerWithRegistry(s.agentRegistry, s.store)
 
 	// Create dashboard event stream handler
 	eventHandler := handlers.NewDashboardEventHandler(s.agentRegistry)
@@ -396,6 +501,9 @@ func (s *Server) registerDashboardRoutesNoAuth(dashboard *gin.RouterGroup) {
 	// Overview
 	dashboard.GET("/overview", h.GetOverview)
 
+	// Per-agent drill-down
+	dashboard.GET("/agents/:id", h.GetAgent)
+
 	// Traces
 	dashboard.GET("/traces", h.ListTraces)
 	dashboard.GET("/traces/:id", h.GetTrace)
@@ -418,11 +526,17 @@ func (s *Server) registerDashboardRoutesNoAuth(dashboard *gin.RouterGroup) {
 		events.GET("/stats", eventHandler.GetStats)
 	}
 
+	// Tool analytics by template
+	dashboard.GET("/tools", h.GetTools)
+
 	// Insights
 	dashboard.GET("/insights", h.GetInsights)
 
+	// Provider API key usage and health
+	dashboard.GET("/keys", h.GetKeys)
+
 	// Pricing configuration
-	pricing := dashboard.Group("/pricing")
+	pricing := dashboard.Group("/pricing", s.authorize("pricing", ""))
 	{
 		pricing.GET("", h.GetPricing)
 		pricing.PUT("", h.UpdatePricing)
@@ -441,9 +555,10 @@ func (s *Server) registerRemoteAgentRoutes(rg *gin.RouterGroup) {
 	// Create remote agent handler with store for persistence
 	h := handlers.NewRemoteAgentHandler(s.agentRegistry, s.store)
 
-	remoteAgents := rg.Group("/remote-agents")
+	remoteAgents := rg.Group("/remote-agents", s.authorize("agents", ""))
 	{
-		remoteAgents.GET("/connect", h.HandleConnect)
 		remoteAgents.GET("/stats", h.GetStats)
 	}
+	// Connecting registers an agent, so the GET upgrade needs write access
+	rg.GET("/remote-agents/connect", s.authorize("agents", "create"), h.HandleConnect)
 }
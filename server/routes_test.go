@@ -0,0 +1,54 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestViewerCannotMutate checks every mutating v1 route, plus the GET upgrades
+// that start agents, rejects a read-only key
+func TestViewerCannotMutate(t *testing.T) {
+	srv, cleanup := setupTestServerWithConfig(t, func(c *Config) {
+		c.RateLimit.Enabled = false
+		c.Auth.APIKey.Enabled = true
+		c.Auth.APIKey.Entries = []APIKeyEntry{
+			{Key: "key-viewer", Name: "viewer", Roles: []string{"viewer"}},
+		}
+	})
+	defer cleanup()
+
+	// Searches and queries use POST but only read
+	readOnly := map[string]bool{
+		"POST /v1/memory/semantic/search": true,
+		"POST /v1/telemetry/traces/query": true,
+		"POST /v1/telemetry/logs/query":   true,
+	}
+	params := regexp.MustCompile(`[:*][^/]+`)
+	cases := map[string]string{
+		"GET /v1/ws":                    "/v1/ws",
+		"GET /v1/remote-agents/connect": "/v1/remote-agents/connect",
+	}
+	for _, route := range srv.Router().Routes() {
+		name := route.Method + " " + route.Path
+		if route.Method == http.MethodGet || route.Method == http.MethodHead || readOnly[name] || !strings.HasPrefix(route.Path, "/v1/") {
+			continue
+		}
+		cases[name] = params.ReplaceAllString(route.Path, "x")
+	}
+	assert.Greater(t, len(cases), 50, "expected the full route table")
+
+	for name, path := range cases {
+		method, _, _ := strings.Cut(name, " ")
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, strings.NewReader("{}"))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-API-Key", "key-viewer")
+		srv.Router().ServeHTTP(w, req)
+		assert.Equal(t, http.StatusForbidden, w.Code, name)
+	}
+}
@@ -5,13 +5,26 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"os"
+	"path/filepath"
 	"time"
 
 	aster "github.com/astercloud/aster"
 	"github.com/astercloud/aster/pkg/a2a"
 	"github.com/astercloud/aster/pkg/actor"
 	"github.com/astercloud/aster/pkg/agent"
+	"github.com/astercloud/aster/pkg/artifact"
+	"github.com/astercloud/aster/pkg/audit"
+	"github.com/astercloud/aster/pkg/config"
+	"github.com/astercloud/aster/pkg/dashboard"
+	"github.com/astercloud/aster/pkg/experiments"
+	"github.com/astercloud/aster/pkg/identity"
+	"github.com/astercloud/aster/pkg/lock"
+	"github.com/astercloud/aster/pkg/logging"
+	"github.com/astercloud/aster/pkg/provider"
+	"github.com/astercloud/aster/pkg/session"
 	"github.com/astercloud/aster/pkg/store"
+	"github.com/astercloud/aster/pkg/usage"
 	"github.com/astercloud/aster/server/auth"
 	"github.com/astercloud/aster/server/handlers"
 	"github.com/astercloud/aster/server/observability"
@@ -32,6 +45,9 @@ type Server struct {
 	// Dependencies (will be injected)
 	deps *Dependencies
 
+	// Session service for chat transcripts, tenant-scoped when multitenancy is on
+	sessions session.Service
+
 	// A2A Protocol Support
 	actorSystem *actor.System
 	a2aServer   *a2a.Server
@@ -43,12 +59,63 @@ type Server struct {
 	healthChecker *observability.HealthChecker
 	tracing       *observability.TracingManager
 	rateLimiter   ratelimit.Limiter
+
+	// Multitenancy
+	tenantLimiters *tenantLimiters
+
+	// Warm agent pool for chat endpoints
+	warmPool *handlers.WarmPool
+
+	// Live collaborative sessions shared by several users
+	collab *handlers.CollabHub
+
+	// Artifact store and its background GC loop
+	artifacts *artifact.Store
+	stopGC    context.CancelFunc
+
+	// Coordinates session resume and agent pools across instances
+	locker lock.Locker
+
+	// Stops the template directory watcher
+	stopTemplateWatch context.CancelFunc
+
+	// Durable token usage ledger behind the usage reports
+	usage *usage.Ledger
+
+	// A/B experiment assignments and results
+	experiments *experiments.Manager
+
+	// Agent signing keys, and the server's own signer for exported reports
+	identities   *identity.Registry
+	serverSigner *identity.Signer
+
+	// Hash-chained audit trail and its retention loop
+	audit     *audit.Trail
+	stopAudit context.CancelFunc
+
+	// Persisted dashboard traces, the agent subscriptions feeding them and
+	// the compaction loop
+	traces     *dashboard.TraceStore
+	stopTraces context.CancelFunc
+
+	// Opt-in diagnostics listener on a separate admin port
+	adminServer *http.Server
+
+	// Recent and live log records behind the log streaming API
+	logs *logging.Broadcaster
+
+	// Pooled provider API keys shown on the dashboard
+	keys *provider.KeyManager
 }
 
 // Dependencies holds all dependencies for the server
 type Dependencies struct {
 	Store     store.Store
 	AgentDeps *agent.Dependencies
+
+	// Sessions optionally records WebSocket chats that name a session_id.
+	// It is scoped by tenant when multitenancy is enabled.
+	Sessions session.Service
 }
 
 // New creates a new Server instance with the given configuration
@@ -74,7 +141,37 @@ func New(config *Config, deps *Dependencies, opts ...Option) (*Server, error) {
 		store:         deps.Store,
 		deps:          deps,
 		agentRegistry: handlers.NewRuntimeAgentRegistry(),
+		sessions:      deps.Sessions,
+	}
+
+	// Scope all persisted data by tenant
+	if config.Multitenancy.Enabled {
+		s.store = store.NewTenantStore(deps.Store)
+		scoped := *deps
+		scoped.Store = s.store
+		if deps.AgentDeps != nil {
+			agentDeps := *deps.AgentDeps
+			agentDeps.Store = s.store
+			scoped.AgentDeps = &agentDeps
+		}
+		s.deps = &scoped
+		if deps.Sessions != nil {
+			s.sessions = session.NewTenantService(deps.Sessions)
+		}
+		s.tenantLimiters = newTenantLimiters(config.Multitenancy)
+	}
+
+	// Apply log level, format and output to pkg/logging
+	if err := logging.Default.Configure(logging.Config{
+		Level:    config.Logging.Level,
+		Format:   config.Logging.Format,
+		Output:   config.Logging.Output,
+		Rotation: config.Logging.Rotation,
+	}); err != nil {
+		return nil, fmt.Errorf("configure logging: %w", err)
 	}
+	s.logs = logging.NewBroadcaster(0)
+	logging.Default.AddTransport(s.logs)
 
 	// Initialize auth and observability
 	s.initializeAuthAndObservability()
@@ -87,6 +184,46 @@ func New(config *Config, deps *Dependencies, opts ...Option) (*Server, error) {
 		opt(s)
 	}
 
+	// Initialize artifact store (after options, which may supply object storage)
+	if err := s.initializeArtifacts(); err != nil {
+		return nil, err
+	}
+
+	// Initialize agent identities
+	if err := s.initializeIdentity(); err != nil {
+		return nil, err
+	}
+
+	// Initialize the audit trail
+	if err := s.initializeAudit(); err != nil {
+		return nil, err
+	}
+
+	// Initialize usage recording
+	s.initializeUsage()
+
+	// Initialize trace persistence
+	s.initializeTraces()
+
+	// Initialize A/B experiments
+	s.initializeExperiments()
+
+	// Load templates from disk
+	if err := s.initializeTemplates(); err != nil {
+		return nil, err
+	}
+
+	// Initialize warm agent pool
+	if config.AgentPool.Enabled && s.deps.AgentDeps != nil {
+		s.warmPool = handlers.NewWarmPool(s.deps.AgentDeps, handlers.WarmPoolConfig{
+			Templates:       config.AgentPool.Templates,
+			SizePerTemplate: config.AgentPool.SizePerTemplate,
+			MaxUses:         config.AgentPool.MaxUses,
+			MaxIdle:         config.AgentPool.MaxIdle,
+		})
+		s.warmPool.Start(context.Background())
+	}
+
 	// Setup middleware
 	s.setupMiddleware()
 
@@ -99,26 +236,46 @@ func New(config *Config, deps *Dependencies, opts ...Option) (*Server, error) {
 // initializeAuthAndObservability initializes authentication and observability components
 func (s *Server) initializeAuthAndObservability() {
 	// Initialize Auth Manager
-	if s.config.Auth.APIKey.Enabled || s.config.Auth.JWT.Enabled {
+	if s.config.Auth.Enabled() {
 		s.authManager = auth.NewManager(auth.AuthMethodAPIKey)
 
 		// Register API Key authenticator
 		if s.config.Auth.APIKey.Enabled {
 			apiKeyStore := auth.NewMemoryAPIKeyStore()
+			seedAPIKeys(apiKeyStore, s.config.Auth.APIKey)
 			apiKeyAuth := auth.NewAPIKeyAuthenticator(apiKeyStore)
 			s.authManager.Register(apiKeyAuth)
 		}
 
 		// Register JWT authenticator
 		if s.config.Auth.JWT.Enabled {
+			expiry := time.Duration(s.config.Auth.JWT.Expiry) * time.Second
+			if expiry == 0 && s.config.Auth.JWT.ExpiryMinutes > 0 {
+				expiry = time.Duration(s.config.Auth.JWT.ExpiryMinutes) * time.Minute
+			}
+			issuer := s.config.Auth.JWT.Issuer
+			if issuer == "" {
+				issuer = "aster"
+			}
 			jwtAuth := auth.NewJWTAuthenticator(auth.JWTConfig{
 				SecretKey:      s.config.Auth.JWT.Secret,
-				Issuer:         "aster",
-				ExpiryDuration: time.Duration(s.config.Auth.JWT.Expiry) * time.Second,
+				Issuer:         issuer,
+				ExpiryDuration: expiry,
 			})
 			s.authManager.Register(jwtAuth)
 		}
 
+		// Register OIDC authenticator
+		if s.config.Auth.OIDC.Enabled {
+			s.authManager.Register(auth.NewOIDCAuthenticator(auth.OIDCConfig{
+				Issuer:      s.config.Auth.OIDC.Issuer,
+				Audience:    s.config.Auth.OIDC.Audience,
+				JWKSURL:     s.config.Auth.OIDC.JWKSURL,
+				RolesClaim:  s.config.Auth.OIDC.RolesClaim,
+				TenantClaim: s.config.Auth.OIDC.TenantClaim,
+			}))
+		}
+
 		// Initialize RBAC
 		s.rbac = auth.NewRBAC()
 	}
@@ -175,6 +332,185 @@ func (s *Server) initializeAuthAndObservability() {
 	}
 }
 
+// initializeArtifacts creates the artifact store when enabled, hands it to
+// agents and starts the GC loop
+func (s *Server) initializeArtifacts() error {
+	cfg := s.config.Artifacts
+	if s.artifacts == nil {
+		if !cfg.Enabled {
+			return nil
+		}
+		dir := cfg.Dir
+		if dir == "" {
+			dir = filepath.Join(config.DataDir(), "artifacts")
+		}
+		blobs, err := artifact.NewFileBlobStore(dir)
+		if err != nil {
+			return err
+		}
+		// Metadata lives in the unscoped store; artifacts carry their own tenant
+		meta := s.store
+		if ts, ok := meta.(*store.TenantStore); ok {
+			meta = ts.Unwrap()
+		}
+		s.artifacts = artifact.NewStore(meta, blobs, cfg.Policy())
+	}
+
+	if s.deps.AgentDeps != nil && s.deps.AgentDeps.Artifacts == nil {
+		agentDeps := *s.deps.AgentDeps
+		agentDeps.Artifacts = s.artifacts
+		scoped := *s.deps
+		scoped.AgentDeps = &agentDeps
+		s.deps = &scoped
+	}
+
+	if cfg.GCInterval > 0 {
+		ctx, cancel := context.WithCancel(context.Background())
+		s.stopGC = cancel
+		go s.artifacts.RunGC(ctx, cfg.GCInterval)
+	}
+	return nil
+}
+
+// initializeIdentity creates the identity registry when enabled and hands it
+// to agents
+func (s *Server) initializeIdentity() error {
+	if !s.config.Identity.Enabled {
+		return nil
+	}
+	// Keys live in the unscoped store so an agent keeps one identity everywhere
+	st := s.store
+	if ts, ok := st.(*store.TenantStore); ok {
+		st = ts.Unwrap()
+	}
+	s.identities = identity.NewRegistry(st)
+	signer, err := s.identities.Signer(context.Background(), identity.ServerID)
+	if err != nil {
+		return err
+	}
+	s.serverSigner = signer
+
+	if s.deps.AgentDeps != nil && s.deps.AgentDeps.Identity == nil {
+		agentDeps := *s.deps.AgentDeps
+		agentDeps.Identity = s.identities
+		scoped := *s.deps
+		scoped.AgentDeps = &agentDeps
+		s.deps = &scoped
+	}
+	return nil
+}
+
+// initializeAudit opens the audit trail when enabled, hands it to agents and
+// starts the retention loop
+func (s *Server) initializeAudit() error {
+	cfg := s.config.Audit
+	if !cfg.Enabled {
+		return nil
+	}
+	dir := cfg.Dir
+	if dir == "" {
+		dir = filepath.Join(config.DataDir(), "audit")
+	}
+	trail, err := audit.Open(dir, audit.Options{Retention: cfg.Retention})
+	if err != nil {
+		return err
+	}
+	s.audit = trail
+
+	if s.deps.AgentDeps != nil && s.deps.AgentDeps.Audit == nil {
+		agentDeps := *s.deps.AgentDeps
+		agentDeps.Audit = s.audit
+		scoped := *s.deps
+		scoped.AgentDeps = &agentDeps
+		s.deps = &scoped
+	}
+
+	if cfg.Retention > 0 {
+		interval := cfg.RetentionInterval
+		if interval <= 0 {
+			interval = time.Hour
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		s.stopAudit = cancel
+		go s.audit.RunRetention(ctx, interval)
+	}
+	return nil
+}
+
+// initializeUsage creates the usage ledger when enabled and hands it to agents
+// for both model token usage and tool invocation analytics
+func (s *Server) initializeUsage() {
+	if !s.config.Usage.Enabled {
+		return
+	}
+	s.usage = usage.NewLedger(s.store)
+
+	if s.deps.AgentDeps != nil && (s.deps.AgentDeps.Usage == nil || s.deps.AgentDeps.ToolUsage == nil) {
+		agentDeps := *s.deps.AgentDeps
+		if agentDeps.Usage == nil {
+			agentDeps.Usage = s.usage
+		}
+		if agentDeps.ToolUsage == nil {
+			agentDeps.ToolUsage = s.usage
+		}
+		scoped := *s.deps
+		scoped.AgentDeps = &agentDeps
+		s.deps = &scoped
+	}
+}
+
+// initializeExperiments creates the experiment manager when enabled and lets
+// agents pick up variant assignments at creation time
+func (s *Server) initializeExperiments() {
+	if !s.config.Experiments.Enabled {
+		return
+	}
+	s.experiments = experiments.NewManager(s.store)
+
+	if s.deps.AgentDeps != nil && s.deps.AgentDeps.Experiments == nil {
+		agentDeps := *s.deps.AgentDeps
+		agentDeps.Experiments = s.experiments
+		scoped := *s.deps
+		scoped.AgentDeps = &agentDeps
+		s.deps = &scoped
+	}
+}
+
+// initializeTemplates loads agent templates from the templates directory and
+// starts watching it for changes when configured
+func (s *Server) initializeTemplates() error {
+	cfg := s.config.Templates
+	if cfg.Dir == "" || s.deps.AgentDeps == nil || s.deps.AgentDeps.TemplateRegistry == nil {
+		return nil
+	}
+	registry := s.deps.AgentDeps.TemplateRegistry
+	if err := os.MkdirAll(cfg.Dir, 0o755); err != nil {
+		return fmt.Errorf("create templates dir: %w", err)
+	}
+	// Broken template files are reported but do not prevent startup
+	n, err := registry.LoadDir(cfg.Dir)
+	if err != nil {
+		fmt.Printf("⚠️  Template load errors in %s: %v\n", cfg.Dir, err)
+	}
+	fmt.Printf("✅ Loaded %d templates from %s\n", n, cfg.Dir)
+
+	if cfg.Watch {
+		ctx, cancel := context.WithCancel(context.Background())
+		if err := registry.WatchDir(ctx, cfg.Dir, func(n int, err error) {
+			if err != nil {
+				fmt.Printf("⚠️  Template reload errors in %s: %v\n", cfg.Dir, err)
+				return
+			}
+			fmt.Printf("🔄 Reloaded %d templates from %s\n", n, cfg.Dir)
+		}); err != nil {
+			cancel()
+			return err
+		}
+		s.stopTemplateWatch = cancel
+	}
+	return nil
+}
+
 // initializeA2A initializes A2A protocol support
 func (s *Server) initializeA2A() {
 	// 创建 Actor System
@@ -237,25 +573,55 @@ func (s *Server) setupRoutes() {
 		s.router.GET(s.config.Observability.Metrics.Endpoint, s.metricsHandler)
 	}
 
-	// WebSocket endpoint (no auth middleware - handles auth internally)
+	// WebSocket chat endpoint. Browsers cannot set headers on the handshake,
+	// so credentials may also be passed as ?token=
 	wsHandler := handlers.NewWebSocketHandler(s.store, s.deps.AgentDeps, s.agentRegistry)
-	s.router.GET("/v1/ws", wsHandler.HandleWebSocket)
+	if s.sessions != nil {
+		wsHandler.SetSessionService(s.sessions)
+	}
+	ws := s.router.Group("/v1/ws")
+	if s.authManager != nil {
+		ws.Use(auth.Middleware(s.authManager, auth.MiddlewareConfig{
+			APIKeyHeader:    s.config.Auth.APIKey.HeaderName,
+			AllowQueryToken: true,
+		}))
+	}
+	if s.config.Multitenancy.Enabled {
+		ws.Use(s.tenantMiddleware(), s.tenantRateLimitMiddleware())
+	}
+	// Chat over the socket runs agents, so the upgrade needs execute
+	ws.Use(s.authorize("agents", "execute"), requestIdentityMiddleware())
+	ws.GET("", wsHandler.HandleWebSocket)
 
-	// Dashboard routes (no auth required for Studio UI)
+	// Dashboard routes (auth only when ProtectDashboard is set, Studio UI otherwise)
 	dashboardGroup := s.router.Group("/v1/dashboard")
-	s.registerDashboardRoutesNoAuth(dashboardGroup)
+	if s.authManager != nil && s.config.Auth.ProtectDashboard {
+		dashboardGroup.Use(s.authenticate(), s.authorize("dashboard", "read"))
+	}
+	if s.config.Multitenancy.Enabled {
+		dashboardGroup.Use(s.tenantMiddleware())
+	}
+	s.registerDashboardRoutes(dashboardGroup)
+
+	// Desktop release manifest and installers (no auth required)
+	s.registerUpdateRoutes(s.router.Group("/v1/updates"))
 
 	// API v1 routes (with authentication)
 	v1 := s.router.Group("/v1")
 
 	// Apply authentication middleware
-	if s.config.Auth.APIKey.Enabled {
-		v1.Use(apiKeyAuthMiddleware(s.config.Auth.APIKey))
+	if s.authManager != nil {
+		v1.Use(s.authenticate())
 	}
-	if s.config.Auth.JWT.Enabled {
-		v1.Use(jwtAuthMiddleware(s.config.Auth.JWT))
+
+	// Resolve tenant and apply per-tenant request quotas
+	if s.config.Multitenancy.Enabled {
+		v1.Use(s.tenantMiddleware(), s.tenantRateLimitMiddleware())
 	}
 
+	// Attribute agent work to the authenticated user and tenant
+	v1.Use(requestIdentityMiddleware())
+
 	// Apply rate limiting
 	if s.config.RateLimit.Enabled && s.rateLimiter != nil {
 		v1.Use(ratelimit.Middleware(ratelimit.Config{
@@ -270,16 +636,23 @@ func (s *Server) setupRoutes() {
 	s.registerAgentRoutes(v1)
 	s.registerMemoryRoutes(v1)
 	s.registerSessionRoutes(v1)
+	s.registerArtifactRoutes(v1)
+	s.registerReportRoutes(v1)
+	s.registerIdentityRoutes(v1)
+	s.registerAuditRoutes(v1)
+	s.registerExperimentRoutes(v1)
+	s.registerTemplateRoutes(v1)
 	s.registerWorkflowRoutes(v1)
 	s.registerToolRoutes(v1)
 	s.registerMiddlewareRoutes(v1)
 	s.registerSystemRoutes(v1)
+	s.registerLogRoutes(v1)
 	s.registerTelemetryRoutes(v1)
 	s.registerEvalRoutes(v1)
 	s.registerMCPRoutes(v1)
 	s.registerA2ARoutes(v1)
 	s.registerRemoteAgentRoutes(v1)
-	// Dashboard routes are registered without auth above for Studio UI
+	// Dashboard and update routes are registered without auth above
 
 	// Register Studio routes (embedded dashboard UI)
 	studio.RegisterRoutes(s.router)
@@ -306,6 +679,10 @@ func (s *Server) Start() error {
 		fmt.Printf("🎨 Studio: http://%s/studio\n", addr)
 	}
 
+	if s.config.Admin.Addr != "" {
+		s.startAdmin()
+	}
+
 	// Start server
 	if s.config.TLS.Enabled {
 		return s.server.ListenAndServeTLS(s.config.TLS.CertFile, s.config.TLS.KeyFile)
@@ -315,6 +692,37 @@ func (s *Server) Start() error {
 
 // Stop gracefully stops the HTTP server
 func (s *Server) Stop(ctx context.Context) error {
+	// Release warm agents
+	if s.warmPool != nil {
+		s.warmPool.Close()
+	}
+	if s.collab != nil {
+		s.collab.Close()
+	}
+
+	if s.stopGC != nil {
+		s.stopGC()
+	}
+	if s.stopAudit != nil {
+		s.stopAudit()
+	}
+	if s.audit != nil {
+		_ = s.audit.Close()
+	}
+	if s.stopTraces != nil {
+		s.stopTraces()
+	}
+	if s.stopTemplateWatch != nil {
+		s.stopTemplateWatch()
+	}
+
+	if s.adminServer != nil {
+		_ = s.adminServer.Shutdown(ctx)
+	}
+	if s.logs != nil {
+		logging.Default.RemoveTransport(s.logs)
+	}
+
 	if s.server == nil {
 		return nil
 	}
@@ -337,6 +745,63 @@ func (s *Server) Stop(ctx context.Context) error {
 	return nil
 }
 
+// authenticate returns the authentication middleware for the configured methods
+func (s *Server) authenticate() gin.HandlerFunc {
+	return auth.Middleware(s.authManager, auth.MiddlewareConfig{
+		APIKeyHeader: s.config.Auth.APIKey.HeaderName,
+	})
+}
+
+// authorize returns an RBAC check for resource, or a no-op when auth is disabled.
+// An empty action is derived from the HTTP method.
+func (s *Server) authorize(resource, action string) gin.HandlerFunc {
+	if s.rbac == nil {
+		return func(c *gin.Context) { c.Next() }
+	}
+	return auth.RequirePermission(s.rbac, resource, action)
+}
+
+// requireAdmin restricts a route to admins, or is a no-op when auth is disabled.
+// Used for server-wide data that is not scoped to a tenant.
+func (s *Server) requireAdmin() gin.HandlerFunc {
+	if s.rbac == nil {
+		return func(c *gin.Context) { c.Next() }
+	}
+	return auth.RequireRole("admin")
+}
+
+// seedAPIKeys loads statically configured API keys into the key store
+func seedAPIKeys(keyStore auth.APIKeyStore, config APIKeyConfig) {
+	ctx := context.Background()
+	roles := config.DefaultRoles
+	if len(roles) == 0 {
+		roles = []string{"admin"}
+	}
+	for i, key := range config.Keys {
+		_ = keyStore.Create(ctx, &auth.APIKeyInfo{
+			Key:       key,
+			UserID:    fmt.Sprintf("apikey-%d", i),
+			Name:      fmt.Sprintf("static-%d", i),
+			Roles:     roles,
+			CreatedAt: time.Now(),
+		})
+	}
+	for _, entry := range config.Entries {
+		name := entry.Name
+		if name == "" {
+			name = "static"
+		}
+		_ = keyStore.Create(ctx, &auth.APIKeyInfo{
+			Key:       entry.Key,
+			UserID:    "apikey-" + name,
+			Name:      name,
+			Roles:     entry.Roles,
+			TenantID:  entry.TenantID,
+			CreatedAt: time.Now(),
+		})
+	}
+}
+
 // Router returns the underlying Gin router for advanced customization
 func (s *Server) Router() *gin.Engine {
 	return s.router
@@ -367,3 +832,18 @@ func (s *Server) metricsHandler(c *gin.Context) {
 		})
 	}
 }
+
+// warmPoolStats reports warm agent pool utilization
+func (s *Server) warmPoolStats(c *gin.Context) {
+	if s.warmPool == nil {
+		c.JSON(http.StatusOK, gin.H{"success": true, "data": gin.H{"enabled": false}})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"enabled": true,
+			"stats":   s.warmPool.Stats(),
+		},
+	})
+}
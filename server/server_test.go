@@ -19,6 +19,10 @@ import (
 )
 
 func setupTestServer(t *testing.T) (*Server, func()) {
+	return setupTestServerWithConfig(t, nil)
+}
+
+func setupTestServerWithConfig(t *testing.T, configure func(*Config)) (*Server, func()) {
 	// Create test store
 	st, err := store.NewJSONStore(t.TempDir())
 	require.NoError(t, err)
@@ -65,6 +69,9 @@ func setupTestServer(t *testing.T) (*Server, func()) {
 	// Create server with test config
 	config := DefaultConfig()
 	config.Auth.APIKey.Enabled = false // Disable auth for tests
+	if configure != nil {
+		configure(config)
+	}
 
 	srv, err := New(config, deps)
 	require.NoError(t, err)
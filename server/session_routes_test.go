@@ -0,0 +1,146 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/astercloud/aster/pkg/lock"
+	"github.com/astercloud/aster/pkg/types"
+	"github.com/astercloud/aster/server/handlers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionSummaryRoutes(t *testing.T) {
+	srv, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		srv.Router().ServeHTTP(w, req)
+		return w
+	}
+
+	w := serve(http.MethodPost, "/v1/sessions/missing/summary", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = serve(http.MethodPost, "/v1/sessions", `{"agent_id":"summary-agent"}`)
+	require.Equal(t, http.StatusCreated, w.Code)
+	var created struct {
+		Data handlers.SessionRecord `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	session := created.Data
+
+	w = serve(http.MethodPost, "/v1/sessions/"+session.ID+"/summary", "")
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+	session.Messages = []types.Message{
+		{Role: types.MessageRoleUser, Content: "Rename the config package"},
+		{Role: types.MessageRoleAssistant, ContentBlocks: []types.ContentBlock{
+			&types.ToolUseBlock{ID: "1", Name: "Edit", Input: map[string]any{"file_path": "pkg/config/config.go"}},
+		}},
+	}
+	require.NoError(t, srv.store.Set(context.Background(), "sessions", session.ID, &session))
+
+	w = serve(http.MethodPost, "/v1/sessions/"+session.ID+"/summary", `{"model_config":{"provider":"mock","model":"test-model"}}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var summarized struct {
+		Data types.ConversationSummary `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &summarized))
+	assert.Equal(t, "Mock response", summarized.Data.Overview)
+	assert.Equal(t, []string{"pkg/config/config.go"}, summarized.Data.FilesTouched)
+	assert.Equal(t, 2, summarized.Data.MessageCount)
+
+	// 分叉复用已保存的摘要
+	w = serve(http.MethodPost, "/v1/sessions/"+session.ID+"/fork", "")
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var forked struct {
+		Data handlers.SessionRecord `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &forked))
+	assert.NotEqual(t, session.ID, forked.Data.ID)
+	assert.Empty(t, forked.Data.Messages)
+	assert.Equal(t, session.ID, forked.Data.Metadata["forked_from"])
+	require.NotNil(t, forked.Data.Summary)
+	assert.Equal(t, "Mock response", forked.Data.Summary.Overview)
+}
+
+func TestSessionTitleFromAgent(t *testing.T) {
+	srv, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	require.NoError(t, srv.store.SaveInfo(ctx, "titled-agent", types.AgentInfo{
+		AgentID:  "titled-agent",
+		Metadata: map[string]any{"title": "Fix the broken build"},
+	}))
+
+	for _, body := range []string{`{"agent_id":"titled-agent"}`, `{"agent_id":"other","metadata":{"title":"Own title"}}`} {
+		req := httptest.NewRequest(http.MethodPost, "/v1/sessions", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		srv.Router().ServeHTTP(w, req)
+		require.Equal(t, http.StatusCreated, w.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/sessions", nil)
+	w := httptest.NewRecorder()
+	srv.Router().ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	var listed struct {
+		Data []handlers.SessionRecord `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &listed))
+
+	titles := map[string]any{}
+	for _, s := range listed.Data {
+		titles[s.AgentID] = s.Metadata["title"]
+	}
+	assert.Equal(t, "Fix the broken build", titles["titled-agent"])
+	assert.Equal(t, "Own title", titles["other"])
+}
+
+func TestSessionResumeLocked(t *testing.T) {
+	base, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	locker := lock.NewMemoryLocker()
+	srv, err := New(base.config, base.deps, WithLocker(locker))
+	require.NoError(t, err)
+	defer func() { _ = srv.Stop(context.Background()) }()
+
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		srv.Router().ServeHTTP(w, req)
+		return w
+	}
+
+	w := serve(http.MethodPost, "/v1/sessions", `{"agent_id":"locked-agent"}`)
+	require.Equal(t, http.StatusCreated, w.Code)
+	var created struct {
+		Data handlers.SessionRecord `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+
+	// Another instance is resuming the session
+	lease, err := locker.TryAcquire(context.Background(), "session:"+created.Data.ID, time.Minute)
+	require.NoError(t, err)
+	w = serve(http.MethodPost, "/v1/sessions/"+created.Data.ID+"/resume", "")
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), "session_locked")
+
+	require.NoError(t, lease.Release(context.Background()))
+	w = serve(http.MethodPost, "/v1/sessions/"+created.Data.ID+"/resume", "")
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+}
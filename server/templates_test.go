@@ -0,0 +1,50 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTemplateRoutes(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "review.yaml"), []byte("id: review\nsystem_prompt: Review code.\ntools: [Read]\n"), 0o644))
+	srv, cleanup := setupTestServerWithConfig(t, func(c *Config) {
+		c.Templates = TemplatesConfig{Dir: dir}
+	})
+	defer cleanup()
+
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		srv.Router().ServeHTTP(w, req)
+		return w
+	}
+
+	w := serve(http.MethodGet, "/v1/templates", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"id":"chat"`)
+	assert.Contains(t, w.Body.String(), `"id":"review"`)
+
+	w = serve(http.MethodPut, "/v1/templates/review", `{"version":"2","system_prompt":"Review code carefully.","tools":["Read","Grep"]}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	data, err := os.ReadFile(filepath.Join(dir, "review.yaml"))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "Review code carefully.")
+
+	w = serve(http.MethodGet, "/v1/templates/review@2", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"versions":["2"]`)
+
+	w = serve(http.MethodPut, "/v1/templates/bad", `{"system_prompt":"x","tools":"all"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = serve(http.MethodGet, "/v1/templates/missing", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,181 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/astercloud/aster/pkg/multitenancy"
+	"github.com/astercloud/aster/server/auth"
+	"github.com/astercloud/aster/server/ratelimit"
+	"github.com/gin-gonic/gin"
+)
+
+// tenantLimiters lazily creates one rate limiter per tenant from its quota
+type tenantLimiters struct {
+	mu       sync.Mutex
+	config   MultitenancyConfig
+	limiters map[string]ratelimit.Limiter
+}
+
+func newTenantLimiters(config MultitenancyConfig) *tenantLimiters {
+	return &tenantLimiters{
+		config:   config,
+		limiters: make(map[string]ratelimit.Limiter),
+	}
+}
+
+// get returns the limiter for tenantID, or nil when the tenant has no request quota
+func (t *tenantLimiters) get(tenantID string) ratelimit.Limiter {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if l, ok := t.limiters[tenantID]; ok {
+		return l
+	}
+
+	quota := t.config.QuotaFor(tenantID)
+	var l ratelimit.Limiter
+	if quota.RequestsPerMinute > 0 {
+		l = ratelimit.NewLimiterFromConfig(ratelimit.Config{
+			Enabled:       true,
+			RequestsPerIP: quota.RequestsPerMinute,
+			WindowSize:    time.Minute,
+			BurstSize:     quota.BurstSize,
+		})
+	}
+	t.limiters[tenantID] = l
+	return l
+}
+
+// tenantMiddleware resolves the request tenant and stores it in the request context.
+//
+// Resolution order: authenticated principal, tenant header, configured default.
+// A principal bound to a tenant can never switch tenants through the header, and
+// only admins (or any caller when auth is disabled) may choose one with it.
+func (s *Server) tenantMiddleware() gin.HandlerFunc {
+	cfg := s.config.Multitenancy
+	header := cfg.HeaderName
+	if header == "" {
+		header = "X-Tenant-ID"
+	}
+
+	return func(c *gin.Context) {
+		tenantID := ""
+		user, authenticated := auth.CurrentUser(c)
+		if authenticated && user.TenantID != "" {
+			tenantID = user.TenantID
+			if requested := c.GetHeader(header); requested != "" && requested != tenantID {
+				c.JSON(http.StatusForbidden, gin.H{
+					"success": false,
+					"error":   gin.H{"code": "tenant_mismatch", "message": "principal is bound to a different tenant"},
+				})
+				c.Abort()
+				return
+			}
+		} else if requested := c.GetHeader(header); requested != "" {
+			if authenticated && !user.HasRole("admin") {
+				c.JSON(http.StatusForbidden, gin.H{
+					"success": false,
+					"error":   gin.H{"code": "tenant_forbidden", "message": "only admins can choose a tenant"},
+				})
+				c.Abort()
+				return
+			}
+			tenantID = requested
+		}
+		if tenantID == "" {
+			tenantID = cfg.DefaultTenant
+		}
+		if tenantID == "" {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error":   gin.H{"code": "missing_tenant", "message": header + " header is required"},
+			})
+			c.Abort()
+			return
+		}
+
+		c.Set(auth.ContextKeyTenant, tenantID)
+		c.Request = c.Request.WithContext(multitenancy.WithTenantID(c.Request.Context(), tenantID))
+		c.Next()
+	}
+}
+
+// tenantRateLimitMiddleware enforces RequestsPerMinute per tenant
+func (s *Server) tenantRateLimitMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tenantID := multitenancy.GetTenantIDOrDefault(c.Request.Context(), "")
+		limiter := s.tenantLimiters.get(tenantID)
+		if limiter == nil {
+			c.Next()
+			return
+		}
+
+		if !limiter.Allow(tenantID) {
+			info := limiter.GetInfo(tenantID)
+			retryAfter := int(time.Until(info.ResetAt).Seconds())
+			c.Header("X-RateLimit-Limit", strconv.Itoa(info.Limit))
+			c.Header("X-RateLimit-Remaining", "0")
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+			c.JSON(http.StatusTooManyRequests, gin.H{
+				"success": false,
+				"error": gin.H{
+					"code":        "tenant_rate_limit_exceeded",
+					"message":     "Tenant request quota exceeded. Please try again later.",
+					"retry_after": retryAfter,
+				},
+			})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// tenantQuotaMiddleware rejects resource creation once the tenant reaches its quota.
+// kind is "agents" or "sessions"; only POST requests to the collection root are checked.
+func (s *Server) tenantQuotaMiddleware(kind string) gin.HandlerFunc {
+	if !s.config.Multitenancy.Enabled {
+		return func(c *gin.Context) { c.Next() }
+	}
+
+	return func(c *gin.Context) {
+		if c.Request.Method != http.MethodPost || c.Param("id") != "" {
+			c.Next()
+			return
+		}
+
+		ctx := c.Request.Context()
+		tenantID := multitenancy.GetTenantIDOrDefault(ctx, "")
+		quota := s.config.Multitenancy.QuotaFor(tenantID)
+
+		limit := 0
+		switch kind {
+		case "agents":
+			limit = quota.MaxAgents
+		case "sessions":
+			limit = quota.MaxSessions
+		}
+		if limit <= 0 {
+			c.Next()
+			return
+		}
+
+		// s.store is tenant-scoped, so List only sees this tenant's records
+		items, err := s.store.List(ctx, kind)
+		if err == nil && len(items) >= limit {
+			c.JSON(http.StatusForbidden, gin.H{
+				"success": false,
+				"error": gin.H{
+					"code":    "quota_exceeded",
+					"message": "tenant " + tenantID + " reached its " + kind + " quota (" + strconv.Itoa(limit) + ")",
+				},
+			})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
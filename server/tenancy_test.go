@@ -0,0 +1,96 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func tenantRequest(srv *Server, method, path, tenant, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if tenant != "" {
+		req.Header.Set("X-Tenant-ID", tenant)
+	}
+	w := httptest.NewRecorder()
+	srv.Router().ServeHTTP(w, req)
+	return w
+}
+
+func TestMultitenancy_SessionIsolation(t *testing.T) {
+	srv, cleanup := setupTestServerWithConfig(t, func(c *Config) {
+		c.Multitenancy.Enabled = true
+		c.Multitenancy.Quotas = map[string]TenantQuota{"small": {MaxSessions: 1}}
+	})
+	defer cleanup()
+
+	w := tenantRequest(srv, http.MethodPost, "/v1/sessions", "acme", `{"agent_id":"agt-1"}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	w = tenantRequest(srv, http.MethodGet, "/v1/sessions", "acme", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "agt-1")
+
+	w = tenantRequest(srv, http.MethodGet, "/v1/sessions", "globex", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "agt-1")
+
+	w = tenantRequest(srv, http.MethodGet, "/v1/sessions", "", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestMultitenancy_SessionQuota(t *testing.T) {
+	srv, cleanup := setupTestServerWithConfig(t, func(c *Config) {
+		c.Multitenancy.Enabled = true
+		c.Multitenancy.Quotas = map[string]TenantQuota{"small": {MaxSessions: 1}}
+	})
+	defer cleanup()
+
+	w := tenantRequest(srv, http.MethodPost, "/v1/sessions", "small", `{"agent_id":"a"}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	w = tenantRequest(srv, http.MethodPost, "/v1/sessions", "small", `{"agent_id":"b"}`)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "quota_exceeded")
+}
+
+func TestMultitenancy_OnlyAdminsChooseTenant(t *testing.T) {
+	srv, cleanup := setupTestServerWithConfig(t, func(c *Config) {
+		c.Multitenancy.Enabled = true
+		c.Auth.APIKey.Enabled = true
+		c.Auth.APIKey.Entries = []APIKeyEntry{
+			{Key: "key-admin", Name: "admin", Roles: []string{"admin"}},
+			{Key: "key-user", Name: "user", Roles: []string{"user"}},
+		}
+	})
+	defer cleanup()
+
+	request := func(key, tenant string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/v1/sessions", nil)
+		req.Header.Set("X-API-Key", key)
+		req.Header.Set("X-Tenant-ID", tenant)
+		w := httptest.NewRecorder()
+		srv.Router().ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusOK, request("key-admin", "acme").Code)
+	w := request("key-user", "acme")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "tenant_forbidden")
+
+	// The WebSocket endpoint goes through the same authentication and tenancy
+	req := httptest.NewRequest(http.MethodGet, "/v1/ws", nil)
+	w = httptest.NewRecorder()
+	srv.Router().ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	req = httptest.NewRequest(http.MethodGet, "/v1/ws?token=key-user", nil)
+	req.Header.Set("X-Tenant-ID", "acme")
+	w = httptest.NewRecorder()
+	srv.Router().ServeHTTP(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
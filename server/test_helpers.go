@@ -30,6 +30,7 @@ func (m *MockProvider) Stream(ctx context.Context, messages []types.Message, opt
 	ch := make(chan provider.StreamChunk, 1)
 	ch <- provider.StreamChunk{
 		Type:         "content_block_delta",
+		Delta:        map[string]any{"type": "text_delta", "text": "Mock response"},
 		TextDelta:    "Mock response",
 		FinishReason: "end_turn",
 	}
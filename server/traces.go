@@ -0,0 +1,123 @@
+package server
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/astercloud/aster/pkg/agent"
+	"github.com/astercloud/aster/pkg/dashboard"
+	"github.com/astercloud/aster/pkg/types"
+)
+
+// traceChannels are the event channels a run trace is built from
+var traceChannels = []types.AgentChannel{types.ChannelProgress, types.ChannelMonitor}
+
+// traceFeed subscribes the trace recorder to every registered agent's events
+type traceFeed struct {
+	recorder *dashboard.TraceRecorder
+
+	mu   sync.Mutex
+	subs map[string]context.CancelFunc // key: agent ID
+}
+
+// eventSource is the subscription API shared by local and remote agents
+type eventSource interface {
+	Subscribe(channels []types.AgentChannel, opts *types.SubscribeOptions) <-chan types.AgentEventEnvelope
+	Unsubscribe(ch <-chan types.AgentEventEnvelope)
+}
+
+// initializeTraces creates the trace store when enabled, feeds it from every
+// registered agent and starts the retention and compaction loop
+func (s *Server) initializeTraces() {
+	cfg := s.config.Traces
+	if !cfg.Enabled {
+		return
+	}
+	s.traces = dashboard.NewTraceStore(s.store, dashboard.TraceRetentionPolicy{
+		SampleRate:         cfg.SampleRate,
+		AlwaysSampleErrors: cfg.AlwaysSampleErrors,
+		Retention:          cfg.Retention,
+		CompactAfter:       cfg.CompactAfter,
+	})
+
+	feed := &traceFeed{
+		recorder: dashboard.NewTraceRecorder(s.traces),
+		subs:     make(map[string]context.CancelFunc),
+	}
+	s.agentRegistry.AddListener(func(agentID string, ag *agent.Agent, registered bool) {
+		if registered && ag != nil {
+			feed.subscribe(agentID, ag)
+		} else {
+			feed.unsubscribe(agentID)
+		}
+	})
+	s.agentRegistry.AddRemoteAgentListener(func(agentID string, ra *agent.RemoteAgent, registered bool) {
+		if registered && ra != nil {
+			feed.subscribe(agentID, ra)
+		} else {
+			feed.unsubscribe(agentID)
+		}
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.stopTraces = func() {
+		cancel()
+		feed.close()
+	}
+	if cfg.Retention > 0 || cfg.CompactAfter > 0 {
+		interval := cfg.CompactionInterval
+		if interval <= 0 {
+			interval = time.Hour
+		}
+		go s.traces.RunCompaction(ctx, interval)
+	}
+}
+
+// subscribe starts recording an agent's runs
+func (f *traceFeed) subscribe(agentID string, src eventSource) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.subs[agentID]; ok {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	f.subs[agentID] = cancel
+
+	ch := src.Subscribe(traceChannels, nil)
+	go func() {
+		defer f.recorder.Discard(agentID)
+		defer src.Unsubscribe(ch)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case env, ok := <-ch:
+				if !ok {
+					return
+				}
+				f.recorder.Observe(ctx, agentID, env)
+			}
+		}
+	}()
+}
+
+// unsubscribe stops recording an agent; its unfinished run is dropped
+func (f *traceFeed) unsubscribe(agentID string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if cancel, ok := f.subs[agentID]; ok {
+		cancel()
+		delete(f.subs, agentID)
+	}
+}
+
+// close stops all subscriptions
+func (f *traceFeed) close() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for id, cancel := range f.subs {
+		cancel()
+		delete(f.subs, id)
+	}
+}
@@ -0,0 +1,85 @@
+package server
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/astercloud/aster/pkg/update"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdateRoutes(t *testing.T) {
+	dir := t.TempDir()
+	srv, cleanup := setupTestServerWithConfig(t, func(c *Config) {
+		c.Updates = UpdatesConfig{Dir: dir}
+	})
+	defer cleanup()
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		srv.Router().ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	w := get("/v1/updates/manifest")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	_, key, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	installer := []byte("aster 1.2.0 installer")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "aster-1.2.0.dmg"), installer, 0o644))
+	asset := update.Asset{Platform: "darwin/arm64", URL: "assets/aster-1.2.0.dmg"}
+	update.SignAsset(key, "1.2.0", &asset, installer)
+	writeManifest := func(m update.Manifest) {
+		data, err := json.Marshal(m)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, update.ManifestFile), data, 0o644))
+	}
+	writeManifest(update.Manifest{Releases: []update.Release{
+		{Version: "1.2.0", Channel: update.ChannelStable, Assets: []update.Asset{asset}},
+	}})
+
+	w = get("/v1/updates/manifest")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"version":"1.2.0"`)
+
+	w = get("/v1/updates/check?channel=stable&platform=darwin/arm64&version=1.1.0&install_id=abc")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var resp struct {
+		Data *update.Result `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.NotNil(t, resp.Data)
+	assert.Equal(t, "1.2.0", resp.Data.Release.Version)
+	assert.Equal(t, asset.Signature, resp.Data.Asset.Signature)
+
+	w = get("/v1/updates/check?platform=darwin/arm64&version=1.2.0")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"success":true,"data":null}`, w.Body.String())
+
+	w = get("/v1/updates/check?channel=nightly&platform=darwin/arm64&version=1.0.0")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = get("/v1/updates/assets/aster-1.2.0.dmg")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, installer, w.Body.Bytes())
+	w = get("/v1/updates/assets/missing.dmg")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// Pausing the release is picked up without a restart
+	writeManifest(update.Manifest{Releases: []update.Release{
+		{Version: "1.2.0", Channel: update.ChannelStable, Paused: true, Assets: []update.Asset{asset}},
+	}})
+	later := time.Now().Add(time.Second)
+	require.NoError(t, os.Chtimes(filepath.Join(dir, update.ManifestFile), later, later))
+	w = get("/v1/updates/check?platform=darwin/arm64&version=1.1.0")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"success":true,"data":null}`, w.Body.String())
+}
@@ -0,0 +1,72 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/astercloud/aster/pkg/requestctx"
+)
+
+func TestWarmPool_AcquireRelease(t *testing.T) {
+	srv, cleanup := setupTestServerWithConfig(t, func(c *Config) {
+		c.AgentPool = AgentPoolConfig{Enabled: true, Templates: []string{"chat"}, SizePerTemplate: 1}
+	})
+	defer cleanup()
+
+	pool := srv.warmPool
+	require.NotNil(t, pool)
+
+	require.Eventually(t, func() bool {
+		return pool.Stats().Idle["chat"] == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	ctx := context.Background()
+	ag, warm, err := pool.Acquire(ctx, "chat")
+	require.NoError(t, err)
+	assert.True(t, warm)
+	assert.Equal(t, int64(1), pool.Stats().InUse)
+
+	pool.Release(ctx, ag)
+	stats := pool.Stats()
+	assert.Equal(t, int64(0), stats.InUse)
+	assert.Equal(t, int64(1), stats.WarmHits)
+
+	// The released agent is either recycled or retired because the refill already topped up the pool
+	assert.Equal(t, int64(1), stats.Recycled+stats.Retired)
+}
+
+func TestWarmPool_ReusesAgentsOnlyForSameUser(t *testing.T) {
+	srv, cleanup := setupTestServerWithConfig(t, func(c *Config) {
+		c.AgentPool = AgentPoolConfig{Enabled: true, Templates: []string{"chat"}, SizePerTemplate: 1}
+	})
+	defer cleanup()
+
+	pool := srv.warmPool
+	require.NotNil(t, pool)
+	alice := requestctx.With(context.Background(), requestctx.Metadata{TenantID: "acme", UserID: "alice"})
+	bob := requestctx.With(context.Background(), requestctx.Metadata{TenantID: "acme", UserID: "bob"})
+
+	first, _, err := pool.Acquire(alice, "chat")
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		return pool.Stats().Idle["chat"] == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	pool.Release(alice, first)
+	require.Equal(t, int64(1), pool.Stats().Recycled)
+
+	// Another user never receives the agent alice used, even from the same tenant
+	other, warm, err := pool.Acquire(bob, "chat")
+	require.NoError(t, err)
+	assert.True(t, warm)
+	assert.NotEqual(t, first.ID(), other.ID())
+	pool.Release(bob, other)
+
+	again, _, err := pool.Acquire(alice, "chat")
+	require.NoError(t, err)
+	assert.Equal(t, first.ID(), again.ID())
+	pool.Release(alice, again)
+}
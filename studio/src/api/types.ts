@@ -216,6 +216,7 @@ export type SessionStatus = 'active' | 'completed' | 'suspended';
 
 export interface SessionSummary {
   id: string;
+  title?: string;
   agent_id?: string;
   agent_name?: string;
   status: SessionStatus;
@@ -29,7 +29,7 @@ export function SessionDetail() {
   return (
     <div className="flex flex-col h-full">
       <Header
-        title={`Session: ${id.slice(0, 8)}...`}
+        title={session?.title || `Session: ${id.slice(0, 8)}...`}
         subtitle={session?.agent_name || session?.agent_id}
         isRefreshing={isFetching}
         onRefresh={() => refetch()}
@@ -31,6 +31,7 @@ export function Sessions() {
     if (!searchQuery) return true;
     return (
       session.id.toLowerCase().includes(searchQuery.toLowerCase()) ||
+      session.title?.toLowerCase().includes(searchQuery.toLowerCase()) ||
       session.agent_id?.toLowerCase().includes(searchQuery.toLowerCase()) ||
       session.agent_name?.toLowerCase().includes(searchQuery.toLowerCase())
     );
@@ -102,9 +103,10 @@ export function Sessions() {
                       <StatusIcon status={session.status} />
                       <div>
                         <p className="font-medium text-[var(--color-text-primary)]">
-                          {session.agent_name || session.agent_id || 'Unknown Agent'}
+                          {session.title || session.agent_name || session.agent_id || 'Unknown Agent'}
                         </p>
                         <p className="text-sm text-[var(--color-text-muted)]">
+                          {session.title && `${session.agent_name || session.agent_id || 'Unknown Agent'} · `}
                           {session.id.slice(0, 8)}...
                         </p>
                       </div>